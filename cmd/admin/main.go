@@ -0,0 +1,191 @@
+// Command admin performs operational tasks against the playground database.
+//
+// USAGE:
+//
+//	go run ./cmd/admin [-db path] <command> [arguments]
+//
+//	promote <login>          grant the admin role to a user
+//	demote <login>           revoke the admin role
+//	set-role <login> <role>  set any role (user, author, admin)
+//	suspend <login>          make a user read-only (no edits or runs)
+//	unsuspend <login>        lift a suspension
+//	delete-user <login>      delete a user and the snippets they own
+//	backup <file>            write a consistent copy of the database to file
+//
+// WHY A SEPARATE BINARY?
+// These are rare, privileged operations. Keeping them out of the HTTP API means
+// there's no endpoint to attack — you need shell access to the server. The CLI
+// reuses the same service and repository layers as the server, so the rules
+// (e.g. valid role names) are enforced in one place.
+//
+// It's safe to run while the server is up: SQLite (in WAL mode) handles
+// several processes using the same database file.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	sqliteRepo "github.com/sakif/coding-playground/internal/repository/sqlite"
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+const usage = `Usage: admin [-db path] <command> [arguments]
+
+Commands:
+  promote <login>          grant the admin role to a user
+  demote <login>           revoke the admin role
+  set-role <login> <role>  set any role (user, author, admin)
+  suspend <login>          make a user read-only (no edits or runs)
+  unsuspend <login>        lift a suspension
+  delete-user <login>      delete a user and the snippets they own
+  backup <file>            write a consistent copy of the database to file
+
+Flags:
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// run is main without the os.Exit, so the exit path is in one place.
+func run(args []string, stdout, stderr io.Writer) error {
+	defaultDB := "data/playground.db"
+	if envDB := os.Getenv("DB_PATH"); envDB != "" {
+		defaultDB = envDB
+	}
+
+	fs := flag.NewFlagSet("admin", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dbPath := fs.String("db", defaultDB, "path to the SQLite database (overrides $DB_PATH)")
+	verbose := fs.Bool("v", false, "log at debug level")
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no command given")
+	}
+
+	level := slog.LevelWarn
+	if *verbose {
+		level = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level}))
+
+	// Ctrl+C cancels the context, so long operations (backup) stop cleanly.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	db, err := sqliteRepo.New(*dbPath)
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer db.Close()
+
+	users := service.NewUserService(db, logger)
+
+	cmd, cmdArgs := fs.Arg(0), fs.Args()[1:]
+	switch cmd {
+	case "promote", "demote":
+		login, err := oneArg(cmd, cmdArgs, "login")
+		if err != nil {
+			return err
+		}
+		role := model.RoleAdmin
+		if cmd == "demote" {
+			role = model.RoleUser
+		}
+		user, err := users.SetRole(ctx, login, role)
+		if err != nil {
+			return describe(err)
+		}
+		fmt.Fprintf(stdout, "%s is now %s\n", user.Login, user.Role)
+
+	case "set-role":
+		if len(cmdArgs) != 2 {
+			return fmt.Errorf("usage: admin %s <login> <role>", cmd)
+		}
+		user, err := users.SetRole(ctx, cmdArgs[0], cmdArgs[1])
+		if err != nil {
+			return describe(err)
+		}
+		fmt.Fprintf(stdout, "%s is now %s\n", user.Login, user.Role)
+
+	case "suspend", "unsuspend":
+		login, err := oneArg(cmd, cmdArgs, "login")
+		if err != nil {
+			return err
+		}
+		user, err := users.SetSuspended(ctx, login, cmd == "suspend")
+		if err != nil {
+			return describe(err)
+		}
+		if user.Suspended {
+			fmt.Fprintf(stdout, "%s is suspended\n", user.Login)
+		} else {
+			fmt.Fprintf(stdout, "%s is no longer suspended\n", user.Login)
+		}
+
+	case "delete-user":
+		login, err := oneArg(cmd, cmdArgs, "login")
+		if err != nil {
+			return err
+		}
+		if err := users.Delete(ctx, login); err != nil {
+			return describe(err)
+		}
+		fmt.Fprintf(stdout, "deleted %s\n", login)
+
+	case "backup":
+		path, err := oneArg(cmd, cmdArgs, "file")
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists; refusing to overwrite", path)
+		}
+		if err := db.Backup(ctx, path); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "backed up %s to %s\n", *dbPath, path)
+
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+
+	return nil
+}
+
+// oneArg checks that a command got exactly one argument.
+func oneArg(cmd string, args []string, name string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("usage: admin %s <%s>", cmd, name)
+	}
+	return args[0], nil
+}
+
+// describe turns domain errors into their human-readable message.
+func describe(err error) error {
+	var appErr *apperror.AppError
+	if errors.As(err, &appErr) {
+		return errors.New(appErr.Message)
+	}
+	return err
+}
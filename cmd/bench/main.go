@@ -0,0 +1,369 @@
+// Command bench load-tests a running playground server.
+//
+// USAGE:
+//
+//	go run ./cmd/bench                                 # 30s against localhost:8080
+//	go run ./cmd/bench -url https://staging.example -c 50 -duration 2m
+//	go run ./cmd/bench -mix list=8,get=8,create=2,execute=1
+//	go run ./cmd/bench -n 1000 -token $JWT             # 1000 requests, signed in
+//
+// Each of -c workers picks an operation at random, weighted by -mix, sends
+// it, and records how long the answer took. At the end it prints, per
+// operation, the request count, the error rate and the p50/p95/p99
+// latencies — enough to see where a server tops out before buying one.
+//
+// OPERATIONS:
+//
+//	list     GET    /api/v1/snippets?limit=20
+//	get      GET    /api/v1/snippets/{id}
+//	create   POST   /api/v1/snippets
+//	update   PUT    /api/v1/snippets/{id}
+//	delete   DELETE /api/v1/snippets/{id}
+//	execute  POST   /api/v1/execute
+//
+// get, update and delete work on snippets the run itself created (a few
+// are made before timing starts), and whatever is left is deleted at the
+// end, so a run leaves the database as it found it.
+//
+// READING THE RESULTS:
+// A 429 is the server's rate limiter doing its job, not a failure to keep
+// up: it's reported in its own column. Every request here comes from one
+// IP, so against a server with rate limits on, raise them (RATE_LIMIT_*)
+// or the numbers measure the limiter. Errors are any other 4xx/5xx or a
+// request that didn't get an answer within -timeout; the statuses behind
+// them are listed under the table. A few 404s on get and update are
+// expected: they raced a delete of the same snippet.
+//
+// Point it at a server you own: it writes snippets and runs code.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// run is main without the os.Exit, so the exit path is in one place.
+func run(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	baseURL := fs.String("url", "http://localhost:8080", "server to load")
+	concurrency := fs.Int("c", 10, "requests in flight at once")
+	duration := fs.Duration("duration", 30*time.Second, "how long to run (ignored when -n is set)")
+	total := fs.Int("n", 0, "stop after this many requests")
+	mixFlag := fs.String("mix", defaultMix, "operations and their weights")
+	token := fs.String("token", os.Getenv("BENCH_TOKEN"), "bearer token to send (default $BENCH_TOKEN)")
+	code := fs.String("code", `print(sum(range(1000)))`, "Python code for execute requests")
+	timeout := fs.Duration("timeout", 30*time.Second, "per-request timeout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	mix, err := parseMix(*mixFlag)
+	if err != nil {
+		return err
+	}
+	if *concurrency < 1 {
+		return errors.New("-c must be at least 1")
+	}
+
+	// Ctrl+C stops sending and still prints what was measured.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	b := &bench{
+		baseURL: strings.TrimRight(*baseURL, "/"),
+		token:   *token,
+		code:    *code,
+		client: &http.Client{
+			Timeout: *timeout,
+			// One connection per worker, kept open, like a busy client.
+			Transport: &http.Transport{MaxIdleConnsPerHost: *concurrency},
+		},
+	}
+
+	// Snippets for get, update and delete to work on.
+	for range max(*concurrency, 5) {
+		if _, err := b.do(ctx, "create"); err != nil {
+			return fmt.Errorf("creating the first snippets (is %s up?): %w", b.baseURL, err)
+		}
+	}
+
+	if *total > 0 {
+		fmt.Fprintf(stdout, "sending %d requests to %s, %d at a time\n", *total, b.baseURL, *concurrency)
+	} else {
+		fmt.Fprintf(stdout, "loading %s for %s, %d requests at a time\n", b.baseURL, *duration, *concurrency)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+
+	results := newResults()
+	var sent sync.WaitGroup
+	var budget chan struct{}
+	if *total > 0 {
+		budget = make(chan struct{}, *total)
+		for range *total {
+			budget <- struct{}{}
+		}
+		close(budget)
+	}
+	start := time.Now()
+	for range *concurrency {
+		sent.Add(1)
+		go func() {
+			defer sent.Done()
+			for ctx.Err() == nil {
+				if budget != nil {
+					if _, ok := <-budget; !ok {
+						return
+					}
+				}
+				op := mix.pick()
+				if op != "list" && op != "create" && op != "execute" && b.snippets() == 0 {
+					// Deletes used them all up; make one instead.
+					op = "create"
+				}
+				began := time.Now()
+				status, err := b.do(ctx, op)
+				if ctx.Err() != nil && err != nil {
+					return // cut short by the end of the run, not the server
+				}
+				results.record(op, time.Since(began), status, err)
+			}
+		}()
+	}
+	sent.Wait()
+	elapsed := time.Since(start)
+
+	results.print(stdout, elapsed)
+
+	// Clean up with a fresh context: the run's may be done.
+	cleanup, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if left := b.deleteAll(cleanup); left > 0 {
+		fmt.Fprintf(stderr, "warning: %d snippets created by the run couldn't be deleted\n", left)
+	}
+	return nil
+}
+
+// defaultMix leans on reads, as real traffic does.
+const defaultMix = "list=4,get=4,create=1,update=1,delete=1,execute=1"
+
+// operations are the ones -mix can name.
+var operations = []string{"list", "get", "create", "update", "delete", "execute"}
+
+// mix is a weighted choice of operations.
+type mix struct {
+	ops     []string
+	weights []int
+	total   int
+}
+
+// parseMix reads "op=weight,..."; a missing weight means 1.
+func parseMix(s string) (mix, error) {
+	var m mix
+	for part := range strings.SplitSeq(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		op, weightStr, hasWeight := strings.Cut(part, "=")
+		weight := 1
+		if hasWeight {
+			w, err := strconv.Atoi(weightStr)
+			if err != nil || w < 0 {
+				return mix{}, fmt.Errorf("-mix: %q: weight must be a whole number", part)
+			}
+			weight = w
+		}
+		if !slices.Contains(operations, op) {
+			return mix{}, fmt.Errorf("-mix: unknown operation %q (want %s)", op, strings.Join(operations, ", "))
+		}
+		if weight > 0 {
+			m.ops = append(m.ops, op)
+			m.weights = append(m.weights, weight)
+			m.total += weight
+		}
+	}
+	if m.total == 0 {
+		return mix{}, errors.New("-mix: no operations with a weight above 0")
+	}
+	return m, nil
+}
+
+func (m mix) pick() string {
+	n := rand.IntN(m.total)
+	for i, w := range m.weights {
+		if n < w {
+			return m.ops[i]
+		}
+		n -= w
+	}
+	return m.ops[len(m.ops)-1]
+}
+
+// bench sends the requests and keeps track of the snippets it created.
+type bench struct {
+	baseURL string
+	token   string
+	code    string
+	client  *http.Client
+
+	mu  sync.Mutex
+	ids []string
+}
+
+// do sends one op and returns the response status. A status of 400 or
+// more is returned as an error too.
+func (b *bench) do(ctx context.Context, op string) (int, error) {
+	switch op {
+	case "list":
+		return b.send(ctx, http.MethodGet, "/api/v1/snippets?limit=20", nil, nil)
+	case "get":
+		return b.send(ctx, http.MethodGet, "/api/v1/snippets/"+b.anyID(), nil, nil)
+	case "create":
+		var created struct {
+			ID string `json:"id"`
+		}
+		status, err := b.send(ctx, http.MethodPost, "/api/v1/snippets", b.snippetBody(), &created)
+		if err == nil && created.ID != "" {
+			b.mu.Lock()
+			b.ids = append(b.ids, created.ID)
+			b.mu.Unlock()
+		}
+		return status, err
+	case "update":
+		return b.send(ctx, http.MethodPut, "/api/v1/snippets/"+b.anyID(), b.snippetBody(), nil)
+	case "delete":
+		id := b.takeID()
+		status, err := b.send(ctx, http.MethodDelete, "/api/v1/snippets/"+id, nil, nil)
+		if err != nil && status != http.StatusNotFound {
+			b.putID(id) // try again at cleanup
+		}
+		return status, err
+	case "execute":
+		return b.send(ctx, http.MethodPost, "/api/v1/execute", map[string]string{"code": b.code}, nil)
+	}
+	return 0, fmt.Errorf("unknown operation %q", op)
+}
+
+func (b *bench) snippetBody() map[string]string {
+	return map[string]string{
+		"name":        fmt.Sprintf("bench %d", rand.IntN(1_000_000)),
+		"code":        b.code,
+		"description": "created by cmd/bench",
+	}
+}
+
+// send makes a request with body as JSON, decoding the response into out
+// if it's non-nil.
+func (b *bench) send(ctx context.Context, method, path string, body, out any) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if b.token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode, fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	if out != nil {
+		return resp.StatusCode, json.NewDecoder(resp.Body).Decode(out)
+	}
+	// Read it all: the response isn't done until the body is.
+	_, err = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, err
+}
+
+// snippets returns how many of the run's snippets are left.
+func (b *bench) snippets() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.ids)
+}
+
+// anyID returns one of the run's snippets, at random.
+func (b *bench) anyID() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.ids) == 0 {
+		return "none"
+	}
+	return b.ids[rand.IntN(len(b.ids))]
+}
+
+// takeID removes one of the run's snippets from the set and returns it.
+func (b *bench) takeID() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.ids) == 0 {
+		return "none"
+	}
+	i := rand.IntN(len(b.ids))
+	id := b.ids[i]
+	b.ids[i] = b.ids[len(b.ids)-1]
+	b.ids = b.ids[:len(b.ids)-1]
+	return id
+}
+
+func (b *bench) putID(id string) {
+	b.mu.Lock()
+	b.ids = append(b.ids, id)
+	b.mu.Unlock()
+}
+
+// deleteAll deletes the snippets the run created, returning how many it
+// couldn't.
+func (b *bench) deleteAll(ctx context.Context) int {
+	b.mu.Lock()
+	ids := b.ids
+	b.ids = nil
+	b.mu.Unlock()
+
+	left := 0
+	for _, id := range ids {
+		status, err := b.send(ctx, http.MethodDelete, "/api/v1/snippets/"+id, nil, nil)
+		if err != nil && status != http.StatusNotFound {
+			left++
+		}
+	}
+	return left
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// results collects every request's latency and outcome, by operation.
+// Keeping every latency (rather than a histogram) costs 8 bytes a request,
+// which is nothing next to the requests themselves, and makes the
+// percentiles exact.
+type results struct {
+	mu  sync.Mutex
+	ops map[string]*opResults
+}
+
+type opResults struct {
+	latencies []time.Duration
+	errors    int // failed requests other than 429s
+	limited   int // 429 Too Many Requests
+	statuses  map[int]int
+}
+
+func newResults() *results {
+	return &results{ops: make(map[string]*opResults)}
+}
+
+// record adds one request. status is 0 if there was no response.
+func (r *results) record(op string, latency time.Duration, status int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o := r.ops[op]
+	if o == nil {
+		o = &opResults{statuses: make(map[int]int)}
+		r.ops[op] = o
+	}
+	o.latencies = append(o.latencies, latency)
+	switch {
+	case status == http.StatusTooManyRequests:
+		o.limited++
+	case err != nil:
+		o.errors++
+	}
+	o.statuses[status]++
+}
+
+// print writes a table of the results, one row per operation and a total.
+func (r *results) print(w io.Writer, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var all opResults
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "\nop\trequests\treq/s\terrors\t429s\tp50\tp95\tp99\tmax\t")
+	for _, op := range operations {
+		o := r.ops[op]
+		if o == nil {
+			continue
+		}
+		o.row(tw, op, elapsed)
+		all.latencies = append(all.latencies, o.latencies...)
+		all.errors += o.errors
+		all.limited += o.limited
+	}
+	all.row(tw, "total", elapsed)
+	tw.Flush()
+
+	// Which statuses the errors were, so "errors 3%" can be chased down.
+	for _, op := range operations {
+		o := r.ops[op]
+		if o == nil || o.errors == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s errors:", op)
+		codes := make([]int, 0, len(o.statuses))
+		for status := range o.statuses {
+			if status == 0 || status >= 400 && status != http.StatusTooManyRequests {
+				codes = append(codes, status)
+			}
+		}
+		slices.Sort(codes)
+		for _, status := range codes {
+			label := "no response"
+			if status != 0 {
+				label = fmt.Sprint(status)
+			}
+			fmt.Fprintf(w, " %s ×%d", label, o.statuses[status])
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func (o *opResults) row(w io.Writer, name string, elapsed time.Duration) {
+	n := len(o.latencies)
+	if n == 0 {
+		return
+	}
+	sorted := slices.Clone(o.latencies)
+	slices.Sort(sorted)
+	fmt.Fprintf(w, "%s\t%d\t%.1f\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+		name, n, float64(n)/elapsed.Seconds(),
+		percent(o.errors, n), percent(o.limited, n),
+		round(percentile(sorted, 50)), round(percentile(sorted, 95)),
+		round(percentile(sorted, 99)), round(sorted[n-1]))
+}
+
+// percentile returns the p-th percentile of sorted (nearest rank).
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100 // ceil(p/100 × n)
+	return sorted[max(rank, 1)-1]
+}
+
+func percent(count, n int) string {
+	return fmt.Sprintf("%.1f%%", 100*float64(count)/float64(n))
+}
+
+// round keeps latencies readable: 12.3ms, not 12.345678ms.
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(100 * time.Microsecond)
+	}
+	return d.Round(time.Microsecond)
+}
@@ -0,0 +1,138 @@
+package main
+
+import "github.com/sakif/coding-playground/internal/model"
+
+// demoUsers are made-up GitHub accounts. Nobody can sign in as them; they
+// exist so admin tooling and user lists have something to show.
+var demoUsers = []model.User{
+	{GitHubID: 900000001, Login: "demo-admin", Email: "admin@example.com", Role: model.RoleAdmin},
+	{GitHubID: 900000002, Login: "ada", Email: "ada@example.com", Role: model.RoleUser},
+	{GitHubID: 900000003, Login: "grace", Email: "grace@example.com", Role: model.RoleUser},
+}
+
+// demoSnippet is one example program.
+type demoSnippet struct {
+	Name        string
+	Description string
+	Code        string
+}
+
+// demoSnippets covers the kinds of programs people try first: basics, data
+// structures, algorithms, the standard library and an intentional error.
+// Every example runs in the sandbox (no network, no third-party packages).
+var demoSnippets = []demoSnippet{
+	{
+		Name:        "Hello, world",
+		Description: "The classic first program.",
+		Code:        "print(\"Hello, world!\")\n",
+	},
+	{
+		Name:        "FizzBuzz",
+		Description: "Loops, conditionals and the modulo operator.",
+		Code: `for i in range(1, 31):
+    if i % 15 == 0:
+        print("FizzBuzz")
+    elif i % 3 == 0:
+        print("Fizz")
+    elif i % 5 == 0:
+        print("Buzz")
+    else:
+        print(i)
+`,
+	},
+	{
+		Name:        "Fibonacci generator",
+		Description: "A generator function that yields values lazily.",
+		Code: `def fib():
+    a, b = 0, 1
+    while True:
+        yield a
+        a, b = b, a + b
+
+gen = fib()
+print([next(gen) for _ in range(15)])
+`,
+	},
+	{
+		Name:        "Word frequency",
+		Description: "Counting with collections.Counter.",
+		Code: `from collections import Counter
+
+text = """the quick brown fox jumps over the lazy dog
+the dog barks and the fox runs"""
+
+for word, count in Counter(text.split()).most_common(5):
+    print(f"{word:>6} {count}")
+`,
+	},
+	{
+		Name:        "Binary search",
+		Description: "Classic divide-and-conquer search on a sorted list.",
+		Code: `def binary_search(items, target):
+    lo, hi = 0, len(items) - 1
+    while lo <= hi:
+        mid = (lo + hi) // 2
+        if items[mid] == target:
+            return mid
+        if items[mid] < target:
+            lo = mid + 1
+        else:
+            hi = mid - 1
+    return -1
+
+data = list(range(0, 100, 3))
+print(binary_search(data, 42))
+print(binary_search(data, 43))
+`,
+	},
+	{
+		Name:        "Dataclasses",
+		Description: "Concise classes with @dataclass and sorting by a key.",
+		Code: `from dataclasses import dataclass
+
+@dataclass
+class Book:
+    title: str
+    year: int
+
+books = [Book("Dune", 1965), Book("Neuromancer", 1984), Book("Foundation", 1951)]
+for book in sorted(books, key=lambda b: b.year):
+    print(book)
+`,
+	},
+	{
+		Name:        "Prime sieve",
+		Description: "Sieve of Eratosthenes with list slicing.",
+		Code: `def primes(limit):
+    sieve = [True] * (limit + 1)
+    sieve[0:2] = [False, False]
+    for n in range(2, int(limit ** 0.5) + 1):
+        if sieve[n]:
+            sieve[n*n::n] = [False] * len(sieve[n*n::n])
+    return [n for n, is_prime in enumerate(sieve) if is_prime]
+
+print(primes(100))
+`,
+	},
+	{
+		Name:        "JSON round trip",
+		Description: "Encoding and decoding JSON with the standard library.",
+		Code: `import json
+
+config = {"name": "playground", "tags": ["go", "python"], "debug": False}
+encoded = json.dumps(config, indent=2)
+print(encoded)
+print(json.loads(encoded)["tags"])
+`,
+	},
+	{
+		Name:        "Traceback demo",
+		Description: "An intentional error, to show how stderr and exit codes look.",
+		Code: `def divide(a, b):
+    return a / b
+
+print("about to divide by zero...")
+divide(1, 0)
+`,
+	},
+}
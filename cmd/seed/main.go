@@ -0,0 +1,95 @@
+// Command seed fills a database with demo users and example snippets.
+//
+// USAGE:
+//
+//	go run ./cmd/seed            # seed data/playground.db (or $DB_PATH)
+//	go run ./cmd/seed -db dev.db # seed another database
+//	go run ./cmd/seed -force     # add snippets even if some already exist
+//
+// Demo users are upserted, so re-running never duplicates them. Snippets are
+// only added to a database that has none, unless -force is given.
+//
+// Seeding goes through the same service layer as the API, so example snippets
+// are validated exactly like user-submitted ones.
+//
+// Development only — never seed a production database.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/rs/xid"
+
+	"github.com/sakif/coding-playground/internal/repository"
+	sqliteRepo "github.com/sakif/coding-playground/internal/repository/sqlite"
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	defaultDB := "data/playground.db"
+	if envDB := os.Getenv("DB_PATH"); envDB != "" {
+		defaultDB = envDB
+	}
+
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	dbPath := fs.String("db", defaultDB, "path to the SQLite database (overrides $DB_PATH)")
+	force := fs.Bool("force", false, "add example snippets even if the database already has some")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	db, err := sqliteRepo.New(*dbPath)
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer db.Close()
+
+	users := service.NewUserService(db, logger)
+	for _, u := range demoUsers {
+		user := u
+		user.ID = xid.New().String()
+		user.AvatarURL = "https://avatars.githubusercontent.com/u/0?v=4"
+		if err := db.Upsert(ctx, &user); err != nil {
+			return fmt.Errorf("creating user %s: %w", u.Login, err)
+		}
+		// Upsert never changes roles, so set it explicitly.
+		if _, err := users.SetRole(ctx, user.Login, u.Role); err != nil {
+			return fmt.Errorf("setting role for %s: %w", u.Login, err)
+		}
+	}
+	fmt.Fprintf(stdout, "upserted %d demo users (admin: %s)\n", len(demoUsers), demoUsers[0].Login)
+
+	existing, err := db.List(ctx, repository.ListOptions{Limit: 1})
+	if err != nil {
+		return fmt.Errorf("checking existing snippets: %w", err)
+	}
+	if len(existing) > 0 && !*force {
+		fmt.Fprintln(stdout, "database already has snippets; skipping examples (use -force to add them anyway)")
+		return nil
+	}
+
+	snippets := service.NewSnippetService(db, logger)
+	for _, s := range demoSnippets {
+		if _, err := snippets.Create(ctx, s.Name, s.Code, s.Description); err != nil {
+			return fmt.Errorf("creating snippet %q: %w", s.Name, err)
+		}
+	}
+	fmt.Fprintf(stdout, "created %d example snippets in %s\n", len(demoSnippets), *dbPath)
+
+	return nil
+}
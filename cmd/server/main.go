@@ -17,41 +17,85 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"strconv"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/sakif/coding-playground/internal/abuse"
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/blob"
+	"github.com/sakif/coding-playground/internal/config"
+	"github.com/sakif/coding-playground/internal/executor"
 	"github.com/sakif/coding-playground/internal/executor/docker"
+	"github.com/sakif/coding-playground/internal/executor/local"
+	"github.com/sakif/coding-playground/internal/feature"
+	"github.com/sakif/coding-playground/internal/handler"
+	"github.com/sakif/coding-playground/internal/i18n"
+	"github.com/sakif/coding-playground/internal/middleware"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/redis"
+	"github.com/sakif/coding-playground/internal/repository/sqlite"
+	"github.com/sakif/coding-playground/internal/retry"
 	"github.com/sakif/coding-playground/internal/server"
+	"github.com/sakif/coding-playground/internal/service"
 )
 
 func main() {
-	// === 1. SET UP LOGGING ===
-	// slog.New creates a structured logger. slog.NewTextHandler outputs human-readable logs.
-	// os.Stdout means logs go to the terminal. slog.LevelDebug enables all log levels.
+	// === 1. READ CONFIGURATION ===
+	// Every setting is named by its environment variable (PORT, DB_PATH, ...)
+	// and comes from, in order of precedence (see internal/config):
+	//   1. a command-line flag: --port, --db-path, --log-level
+	//   2. the environment
+	//   3. the config file named by --config or CONFIG_FILE (KEY=VALUE lines, like .env)
+	//   4. the default given where it's read below
 	//
-	// Log levels (from least to most severe): Debug → Info → Warn → Error
-	// In production, you'd use LevelInfo or LevelWarn to reduce noise.
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelDebug,
-	}))
+	// A value that doesn't parse is reported with every other problem
+	// before anything starts (section 22), rather than one run at a time.
+	conf, err := config.Load(os.Args[1:], os.LookupEnv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration: %v\n", err)
+		os.Exit(2)
+	}
 
-	// === 2. READ CONFIGURATION ===
-	// We read the port from the PORT environment variable, defaulting to 8080.
-	// os.Getenv returns "" if the variable isn't set, so we check and provide a default.
+	// The server listens on PORT (default 8080). LISTEN replaces the TCP
+	// port with another listener: a specific address ("127.0.0.1:8080"), a
+	// Unix socket ("unix:/run/playground.sock") for use behind a reverse
+	// proxy, or "systemd" for systemd socket activation.
+	port := conf.Int("PORT", 8080)
+	listen := conf.String("LISTEN", "")
+
+	// === 2. SET UP LOGGING ===
+	// slog.New creates a structured logger from a "handler" that decides the output format:
+	//   - slog.NewTextHandler: human-readable key=value lines (great in a terminal)
+	//   - slog.NewJSONHandler: one JSON object per line (what log pipelines like
+	//     Loki, Elasticsearch or CloudWatch expect)
 	//
-	// In a larger app, you'd use a config library (like viper) or a config struct
-	// loaded from a YAML/TOML file. For learning, env vars are simple and standard.
-	port := 8080
-	if portStr := os.Getenv("PORT"); portStr != "" {
-		var err error
-		port, err = strconv.Atoi(portStr) // Atoi = ASCII to Integer
-		if err != nil {
-			logger.Error("invalid PORT value", slog.String("value", portStr))
-			os.Exit(1)
-		}
+	// LOG_FORMAT picks the handler (text|json, default text).
+	// LOG_LEVEL picks the minimum level (debug|info|warn|error, default debug).
+	//
+	// Log levels (from least to most severe): Debug → Info → Warn → Error
+	// In production, you'd use LevelInfo or LevelWarn to reduce noise.
+	logger, err := newLogger(os.Stdout, conf.String("LOG_FORMAT", ""), conf.String("LOG_LEVEL", ""))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid logging configuration: %v\n", err)
+		os.Exit(1)
 	}
+	// Code without a logger of its own (handler.writeError's 500 log lines)
+	// uses slog's default; make that this one.
+	slog.SetDefault(logger)
 
 	// === 3. RESOLVE FILE PATHS ===
 	// We need to find the template and static file directories relative to
@@ -59,20 +103,24 @@ func main() {
 	//
 	// The "web" directory is at the project root, so we go up from cmd/server/.
 	// However, when running with `go run`, the working directory is usually the project root,
-	// so "web/templates" and "web/static" work directly.
-	templateDir, _ := filepath.Abs("web/templates")
-	staticDir, _ := filepath.Abs("web/static")
+	// so "web/templates" and "web/static" work directly. TEMPLATE_DIR and
+	// STATIC_DIR point elsewhere, for a binary installed away from the source.
+	templateDir, _ := filepath.Abs(conf.String("TEMPLATE_DIR", "web/templates"))
+	staticDir, _ := filepath.Abs(conf.String("STATIC_DIR", "web/static"))
+
+	// DEV_MODE=true re-parses templates on every request (edit HTML, refresh browser).
+	devMode := conf.Bool("DEV_MODE", false)
+	if devMode {
+		logger.Warn("development mode enabled — templates are reloaded on every request")
+	}
 
 	// === 4. DATABASE PATH ===
 	// Default to "data/playground.db" in the project root.
 	// The "data" directory will be created automatically by os.MkdirAll if it doesn't exist.
 	//
-	// DB_PATH env var allows overriding for production deployments.
+	// DB_PATH (or --db-path) allows overriding for production deployments.
 	// Example: DB_PATH=/var/lib/playground/prod.db
-	dbPath := "data/playground.db"
-	if envDB := os.Getenv("DB_PATH"); envDB != "" {
-		dbPath = envDB
-	}
+	dbPath := conf.String("DB_PATH", "data/playground.db")
 
 	// Ensure the data directory exists.
 	// os.MkdirAll creates all parent directories if needed (like `mkdir -p`).
@@ -86,54 +134,659 @@ func main() {
 		os.Exit(1)
 	}
 
-	// === 5. INITIALIZE EXECUTOR ===
-	// Docker executor is optional — server starts without it but /api/execute will be unavailable.
-	exec, err := docker.New(docker.DefaultConfig(), logger)
-	if err != nil {
-		logger.Warn("Docker executor unavailable — /api/execute will return errors",
-			slog.String("error", err.Error()),
-		)
-		exec = nil
-	} else {
-		defer exec.Close()
+	// === 5. EXECUTOR SETTINGS ===
+	// Code runs in DOCKER_IMAGE containers (default python:3.12-alpine). The
+	// executor itself is started in section 23, once the whole configuration
+	// has been checked.
+	//
+	// Servers sharing one Docker host can cap the host's totals between them:
+	// POOL_MAX_WARM_TOTAL pre-warmed containers and POOL_MAX_RUNNING_TOTAL
+	// runs at once (0 = no cap). They agree through Redis if REDIS_URL is set
+	// (section 19), or else through the database they already share.
+	//
+	// POOL_SIZE containers are kept warm. With POOL_MAX_SIZE set, the pool
+	// resizes itself between POOL_MIN_SIZE and POOL_MAX_SIZE, reconsidering
+	// every POOL_RESIZE_INTERVAL, as runs wait for sandboxes or leave them
+	// unused.
+	//
+	// CHECKPOINT_AFTER (0 = off) freezes a run that reads input once it has
+	// sat idle that long, and restores it at the next input. It needs an
+	// experimental Docker daemon with CRIU (see docker/checkpoint.go).
+	//
+	// RETRIES:
+	// Writes that meet a busy database and container operations that meet a
+	// restarting Docker daemon are tried RETRY_ATTEMPTS times in all, waiting
+	// RETRY_BASE_DELAY before the first retry and doubling up to
+	// RETRY_MAX_DELAY (see internal/retry).
+	retryPolicy := retry.Policy{
+		Attempts: conf.Int("RETRY_ATTEMPTS", 3),
+		Base:     conf.Duration("RETRY_BASE_DELAY", 10*time.Millisecond),
+		Max:      conf.Duration("RETRY_MAX_DELAY", time.Second),
+	}
+	dockerConfig := docker.DefaultConfig()
+	dockerConfig.Image = conf.String("DOCKER_IMAGE", dockerConfig.Image)
+	dockerConfig.Retry = retryPolicy
+	dockerConfig.PoolSize = conf.Int("POOL_SIZE", dockerConfig.PoolSize)
+	dockerConfig.MinPoolSize = conf.Int("POOL_MIN_SIZE", 1)
+	dockerConfig.MaxPoolSize = conf.Int("POOL_MAX_SIZE", 0)
+	dockerConfig.ResizeEvery = conf.Duration("POOL_RESIZE_INTERVAL", 30*time.Second)
+	dockerConfig.CheckpointAfter = conf.Duration("CHECKPOINT_AFTER", 0)
+	dockerConfig.MaxWarmTotal = conf.Int("POOL_MAX_WARM_TOTAL", 0)
+	dockerConfig.MaxRunningTotal = conf.Int("POOL_MAX_RUNNING_TOTAL", 0)
+	localFallback := conf.Bool("EXECUTOR_LOCAL_FALLBACK", false)
+
+	// PACKAGES:
+	// Snippets and runs may list packages to install, requirements.txt
+	// style, from PACKAGE_ALLOWLIST (comma-separated; empty allows none).
+	// The sandbox has no network, so pip installs them from WHEELHOUSE, a
+	// directory of wheels in DOCKER_IMAGE — build one FROM
+	// python:3.12-alpine with RUN pip wheel --wheel-dir /wheels numpy — into
+	// a tmpfs of PACKAGE_LIMIT_MB (default 64) that counts towards each
+	// container's memory. Each set installed is packed up and kept, up to
+	// ENV_CACHE_MB (default 128; 0 turns it off) in all, so the next run
+	// with the same requirements unpacks it instead of running pip.
+	packages := executor.NewPackageAllowlist(conf.List("PACKAGE_ALLOWLIST"))
+	dockerConfig.Wheelhouse = conf.String("WHEELHOUSE", "")
+	dockerConfig.PackageLimit = int64(conf.Int("PACKAGE_LIMIT_MB", 64)) << 20
+	dockerConfig.EnvCacheLimit = int64(conf.Int("ENV_CACHE_MB", 128)) << 20
+	conf.Check(len(packages) == 0 || dockerConfig.Wheelhouse != "", "WHEELHOUSE",
+		"must be set when PACKAGE_ALLOWLIST is", "Point it at the directory of wheels in DOCKER_IMAGE.")
+
+	// AUTOCOMPLETE AND HOVER DOCS:
+	// The editor's completions and docs run in a pool of their own, so they
+	// never wait behind runs: COMPLETION_POOL_SIZE warm containers (default
+	// 1) of COMPLETION_IMAGE (default DOCKER_IMAGE), each request stopped
+	// after COMPLETION_TIMEOUT. Completions come from jedi if the image has
+	// it — build one FROM python:3.12-alpine with RUN pip install jedi — and
+	// from a basic built-in completer otherwise. Docs are cached for
+	// DOCS_CACHE_TTL (default 24h; 0 looks every one up).
+	completerConfig := dockerConfig
+	completerConfig.Image = conf.String("COMPLETION_IMAGE", dockerConfig.Image)
+	completerConfig.PoolSize = conf.Int("COMPLETION_POOL_SIZE", 1)
+	completerConfig.MinPoolSize, completerConfig.MaxPoolSize = 0, 0
+	completerConfig.Timeout = conf.Duration("COMPLETION_TIMEOUT", 3*time.Second)
+	completerConfig.ArtifactLimit = 0
+	completerConfig.Wheelhouse, completerConfig.EnvCacheLimit = "", 0
+	completerConfig.MaxWarmTotal, completerConfig.MaxRunningTotal = 0, 0 // the POOL_*_TOTAL caps are for runs
+
+	// DEBUGGING:
+	// A debugging session holds its sandbox while the learner thinks, so it
+	// runs on an executor of its own, stopped after DEBUG_TIMEOUT (default
+	// 10m) rather than a run's few seconds. Sessions are rare and long, so
+	// there's no warm pool unless DEBUG_POOL_SIZE asks for one. A session
+	// reads its commands as input, so CHECKPOINT_AFTER freezes it while the
+	// learner thinks.
+	debugConfig := dockerConfig
+	debugConfig.PoolSize = conf.Int("DEBUG_POOL_SIZE", 0)
+	debugConfig.MinPoolSize, debugConfig.MaxPoolSize = 0, 0
+	debugConfig.Timeout = conf.Duration("DEBUG_TIMEOUT", 10*time.Minute)
+	debugConfig.ArtifactLimit = 0
+	debugConfig.Wheelhouse, debugConfig.EnvCacheLimit = "", 0
+	debugConfig.MaxWarmTotal, debugConfig.MaxRunningTotal = 0, 0
+
+	// GPUS:
+	// Deployments with GPUs can lend them to runs that ask ({"gpu": true}),
+	// to teach numpy and torch with acceleration. Setting GPU_IMAGE turns
+	// it on: an image with CUDA and the libraries baked in, since GPU runs
+	// don't install requirements — build one FROM pytorch/pytorch. Each
+	// container gets GPU_COUNT of the host's GPUs (default 1; -1 for all,
+	// like --gpus all), GPU_MEMORY_MB of memory (default 4096) and
+	// GPU_TIMEOUT (default 30s), from a pool of GPU_POOL_SIZE (default 1).
+	// Only signed-in users with one of GPU_ROLES (default author,admin) may
+	// use them. The host needs the NVIDIA Container Toolkit.
+	gpuConfig := dockerConfig
+	gpuConfig.Image = conf.String("GPU_IMAGE", "")
+	gpuConfig.GPUs = conf.Int("GPU_COUNT", 1)
+	gpuConfig.MemoryLimit = int64(conf.Int("GPU_MEMORY_MB", 4096)) << 20
+	gpuConfig.Timeout = conf.Duration("GPU_TIMEOUT", 30*time.Second)
+	gpuConfig.PoolSize = conf.Int("GPU_POOL_SIZE", 1)
+	gpuConfig.MinPoolSize, gpuConfig.MaxPoolSize = 0, 0
+	gpuConfig.Wheelhouse, gpuConfig.EnvCacheLimit = "", 0
+	gpuConfig.MaxWarmTotal, gpuConfig.MaxRunningTotal = 0, 0
+	gpuRoles := conf.List("GPU_ROLES")
+	if len(gpuRoles) == 0 {
+		gpuRoles = []string{model.RoleAuthor, model.RoleAdmin}
+	}
+	conf.Check(gpuConfig.GPUs != 0 && gpuConfig.GPUs >= -1, "GPU_COUNT",
+		"must be a number of GPUs, or -1 for all of them", "Set it to 1, say, or -1.")
+	for _, role := range gpuRoles {
+		conf.Check(slices.Contains(model.Roles, role), "GPU_ROLES",
+			fmt.Sprintf("%q isn't a role", role), "Use "+strings.Join(model.Roles, ", ")+".")
 	}
 
 	// === 6. AUTH CONFIGURATION ===
 	// JWT_SECRET must be a long random string. Generate one with:
 	//   openssl rand -hex 32
 	// If unset, auth is disabled (server still starts, OAuth routes won't exist).
-	jwtSecret := os.Getenv("JWT_SECRET")
-	githubClientID := os.Getenv("GITHUB_CLIENT_ID")
-	githubClientSecret := os.Getenv("GITHUB_CLIENT_SECRET")
-	githubCallbackURL := os.Getenv("GITHUB_CALLBACK_URL")
+	// In production, pass the secrets as files (JWT_SECRET_FILE,
+	// GITHUB_CLIENT_SECRET_FILE — see internal/config) rather than in the
+	// environment, where anyone who can list processes can read them.
+	//
+	// ROTATION:
+	// JWT_PREVIOUS_SECRETS (comma-separated) are still accepted for sessions
+	// but no longer sign them. Sending the server SIGHUP rereads the config
+	// file and secret files and switches to the JWT secrets and GitHub
+	// credentials there, without a restart or signing anyone out (see
+	// internal/server/secrets.go).
+	secrets := readSecrets(conf)
+	githubCallbackURL := conf.String("GITHUB_CALLBACK_URL", "")
 
-	if jwtSecret == "" {
+	if secrets.JWTSecret == "" {
 		logger.Warn("JWT_SECRET not set — authentication will be disabled")
 	}
 
-	// === 7. CREATE AND START THE SERVER ===
-	// We create the server config, build the server, and start it.
-	// If anything fails, we log the error and exit with code 1 (non-zero = error).
+	// === 7. TLS CONFIGURATION ===
+	// HTTPS is optional. Either point TLS_CERT_FILE/TLS_KEY_FILE at a PEM pair,
+	// or list the public host names in AUTOCERT_DOMAINS to get free certificates
+	// from Let's Encrypt. HTTP_REDIRECT_PORT (e.g. 80) enables http → https redirects.
+	httpRedirectPort := conf.Int("HTTP_REDIRECT_PORT", 0)
+
+	// === 8. METRICS ===
+	// Prometheus metrics are served at /metrics unless METRICS_ENABLED=false.
+	// Set METRICS_USERNAME/METRICS_PASSWORD to require basic auth for scrapes.
+	metricsEnabled := conf.Bool("METRICS_ENABLED", true)
+
+	// === 9. RATE LIMITS ===
+	// Per-IP token buckets: *_RPS is the sustained rate, *_BURST the bucket size.
+	// Set an RPS to 0 to disable limiting for that route group. The execute
+	// limit also applies to the routes that run code (/execute, submissions,
+	// live runs). With REDIS_URL set (section 19) all instances share buckets.
+	apiRateLimit := middleware.RateLimitConfig{
+		Rate:  conf.Float("RATE_LIMIT_API_RPS", 10),
+		Burst: conf.Int("RATE_LIMIT_API_BURST", 40),
+	}
+	authRateLimit := middleware.RateLimitConfig{
+		Rate:  conf.Float("RATE_LIMIT_AUTH_RPS", 0.2),
+		Burst: conf.Int("RATE_LIMIT_AUTH_BURST", 10),
+	}
+	executeRateLimit := middleware.RateLimitConfig{
+		Rate:  conf.Float("RATE_LIMIT_EXECUTE_RPS", 0.5),
+		Burst: conf.Int("RATE_LIMIT_EXECUTE_BURST", 10),
+	}
+
+	// === 10. REQUEST BODY LIMITS AND TIMEOUTS ===
+	// Snippets and code are capped well above service.MaxCodeLength (100KB) so the
+	// service can still return its friendlier validation error for long code.
+	// Auth endpoints never need a body, so their cap is tiny.
+	apiMaxBody := int64(conf.Int("API_MAX_BODY_BYTES", 1<<20))   // 1 MB
+	authMaxBody := int64(conf.Int("AUTH_MAX_BODY_BYTES", 4<<10)) // 4 KB
+
+	// Deadlines per route group (Go duration syntax: "2s", "500ms"; 0 disables).
+	// Keep them below the server's 15s WriteTimeout, or the connection is cut
+	// before the 504 can be sent. Auth waits on GitHub, so it gets longer.
+	apiTimeout := conf.Duration("API_TIMEOUT", 2*time.Second)
+	executeTimeout := conf.Duration("EXECUTE_TIMEOUT", 10*time.Second)
+	authTimeout := conf.Duration("AUTH_TIMEOUT", 10*time.Second)
+
+	// Deadlines per call, within a request (0 disables): each database call a
+	// service makes gets DB_DEADLINE, and each code run RUN_DEADLINE — the
+	// executor's own time limit plus a few seconds to get a sandbox. A stuck
+	// lock or a hung Docker daemon fails that call with a 503 naming it,
+	// instead of running the request into its 504.
+	deadlines := service.Deadlines{
+		DB:      conf.Duration("DB_DEADLINE", 2*time.Second),
+		Execute: conf.Duration("RUN_DEADLINE", dockerConfig.Timeout+3*time.Second),
+	}
+
+	// === 11. ACCESS LOG ===
+	// ACCESS_LOG=stdout or a file path (e.g. logs/access.log) separates per-request
+	// logs from application logs. Files rotate by size/backups/age.
+	accessLogPath := conf.String("ACCESS_LOG", "")
+
+	// === 12. FEATURE FLAGS ===
+	// FEATURE_FLAGS overrides flag defaults for this deployment, e.g.
+	// "execution=off,api_docs=on". Admins can still toggle flags at runtime via
+	// /api/v1/admin/features; those toggles win over this setting.
+	//
+	// The body_logging flag logs API request/response bodies for debugging.
+	// BODY_LOG_SAMPLE_RATE is the fraction of requests logged while it's on,
+	// BODY_LOG_MAX_BYTES how much of each body is kept.
+	bodyLog := middleware.BodyLogConfig{
+		SampleRate: conf.Float("BODY_LOG_SAMPLE_RATE", 0.1),
+		MaxBytes:   conf.Int("BODY_LOG_MAX_BYTES", middleware.DefaultBodyLogMaxBytes),
+	}
+	featureFlags, err := feature.ParseOverrides(conf.String("FEATURE_FLAGS", ""))
+	if err != nil {
+		conf.Report("FEATURE_FLAGS", "is invalid: "+err.Error(), `List flag=on or flag=off pairs, e.g. "execution=off,api_docs=on".`)
+	}
+
+	// === 13. MAINTENANCE SCHEDULE ===
+	// Cron expressions (evaluated in UTC) for the built-in maintenance tasks.
+	// Set a schedule to "off" to disable that task. Anonymous snippets are only
+	// purged when ANONYMOUS_SNIPPET_TTL_DAYS is set.
+	snippetPurgeSchedule := conf.String("SCHEDULE_SNIPPET_PURGE", "0 3 * * *")
+	walCheckpointSchedule := conf.String("SCHEDULE_WAL_CHECKPOINT", "*/15 * * * *")
+	idempotencyPurgeSchedule := conf.String("SCHEDULE_IDEMPOTENCY_PURGE", "0 * * * *")
+	leaderboardSchedule := conf.String("SCHEDULE_LEADERBOARD_REFRESH", "*/10 * * * *")
+	anonymousSnippetTTL := time.Duration(conf.Int("ANONYMOUS_SNIPPET_TTL_DAYS", 0)) * 24 * time.Hour
+	// Run history retention: runs older than *_DAYS, or beyond a user's
+	// newest *_KEEP, are deleted by the run_purge task. 0 keeps them; with
+	// all four at 0 the task isn't registered.
+	runRetention := server.RunRetention{
+		MaxAge: time.Duration(conf.Int("RUN_RETENTION_DAYS", 0)) * 24 * time.Hour,
+		Keep:   conf.Int("RUN_RETENTION_KEEP", 0),
+	}
+	anonymousRunRetention := server.RunRetention{
+		MaxAge: time.Duration(conf.Int("ANONYMOUS_RUN_RETENTION_DAYS", 0)) * 24 * time.Hour,
+		Keep:   conf.Int("ANONYMOUS_RUN_RETENTION_KEEP", 0),
+	}
+	runPurgeSchedule := conf.String("SCHEDULE_RUN_PURGE", "30 3 * * *")
+
+	// === 14. ERROR REPORTING ===
+	// SENTRY_DSN (from the project settings of Sentry or a compatible service
+	// like GlitchTip) sends panics and 500s to the error tracker, tagged with
+	// SENTRY_ENVIRONMENT. Unset, errors only appear in the logs.
+	// ERROR_STACKS records where internal errors were wrapped (apperror.Wrap),
+	// for the 500 log lines and the tracker, at the cost of a stack walk each.
+	sentryDSN := conf.String("SENTRY_DSN", "")
+	sentryEnvironment := conf.String("SENTRY_ENVIRONMENT", "production")
+	apperror.SetCaptureStacks(conf.Bool("ERROR_STACKS", false))
+	// Error messages are translated by Accept-Language (see internal/i18n);
+	// TRANSLATIONS_DIR adds languages, or rewords built-in ones, from
+	// <tag>.json files.
+	if dir := conf.String("TRANSLATIONS_DIR", ""); dir != "" {
+		if err := i18n.AddDir(dir); err != nil {
+			conf.Report("TRANSLATIONS_DIR", "can't be loaded: "+err.Error(), "Each file must be <language tag>.json, mapping error codes to messages.")
+		}
+	}
+
+	// === 15. EMAIL ===
+	// Sign-in links, grading results and the weekly digest are emailed through
+	// SMTP_HOST. SMTP_PORT defaults to 587 (STARTTLS); set SMTP_IMPLICIT_TLS for
+	// port 465. PUBLIC_URL (e.g. https://play.example.com) is where links in
+	// emails point. Without SMTP_HOST, no email is sent.
+	smtpHost := conf.String("SMTP_HOST", "")
+	smtpFrom := conf.String("SMTP_FROM", "PyPlayground <noreply@localhost>")
+	digestSchedule := conf.String("SCHEDULE_WEEKLY_DIGEST", "0 8 * * 1")
+
+	// === 16. ANALYTICS ===
+	// Product events (snippet_created, execution_run, login) are batched into
+	// the analytics_events table unless ANALYTICS_ENABLED=false. ANALYTICS_FILE
+	// also appends them to a JSON Lines file; ANALYTICS_URL POSTs each batch to
+	// a collector, with ANALYTICS_TOKEN as a bearer token.
+	analyticsEnabled := conf.Bool("ANALYTICS_ENABLED", true)
+
+	// === 17. ORG QUOTAS ===
+	// Each org may keep ORG_SNIPPET_QUOTA snippets and start ORG_RUNS_PER_DAY
+	// live runs of them a day, unless an admin sets its own. 0 means no limit.
+	orgSnippetQuota := conf.Int("ORG_SNIPPET_QUOTA", 500)
+	orgRunQuota := conf.Int("ORG_RUNS_PER_DAY", 1000)
+
+	// === 18. ABUSE DETECTION ===
+	// Code is checked for miners, fork bombs, long sleeps and repeated heavy
+	// runs before it runs (see internal/abuse). ABUSE_DETECTION is off,
+	// monitor (log only), standard (block the clear-cut cases) or strict.
+	abuseDetection, err := abuse.ParseStrictness(conf.String("ABUSE_DETECTION", ""))
+	if err != nil {
+		conf.Report("ABUSE_DETECTION", "is invalid: "+err.Error(), "Use off, monitor, standard or strict.")
+	}
+
+	// === 19. REDIS AND CACHING ===
+	// REDIS_URL (redis://[user:password@]host:port[/db], or rediss:// for TLS)
+	// is shared by every app server: rate limits are counted there, so they
+	// hold across instances behind a load balancer. Public snippets are cached for
+	// SNIPPET_CACHE_TTL (0 turns the cache off): in Redis when it's set, in
+	// memory otherwise. The rendered public pages and feeds are kept for
+	// RESPONSE_CACHE_TTL on top of that, and dropped on any change.
+	redisURL := conf.String("REDIS_URL", "")
+	snippetCacheTTL := conf.Duration("SNIPPET_CACHE_TTL", 30*time.Second)
+	responseCacheTTL := conf.Duration("RESPONSE_CACHE_TTL", 10*time.Second)
+	// A CDN in front may keep public snippets, their embeds and pages for
+	// CDN_MAX_AGE (0: browsers and CDNs revalidate every time). Changes are
+	// purged by POSTing their surrogate keys to CDN_PURGE_URL.
+	cdnMaxAge := conf.Duration("CDN_MAX_AGE", 0)
+	cdnPurgeURL := conf.String("CDN_PURGE_URL", "")
+	cdnPurgeToken := conf.String("CDN_PURGE_TOKEN", "")
+
+	// === 20. BLOB STORAGE ===
+	// Files a run writes to $ARTIFACTS_DIR (plots, generated data) are kept
+	// outside the database: under BLOB_DIR (default: "blobs" beside the
+	// database) with BLOB_BACKEND=fs, or in an S3-compatible bucket with
+	// BLOB_BACKEND=s3 and S3_ENDPOINT, S3_BUCKET, S3_REGION, S3_ACCESS_KEY_ID,
+	// S3_SECRET_ACCESS_KEY and optionally S3_PREFIX. BLOB_BACKEND=off means
+	// runs can't keep files.
+	blobConfig := blob.Config{
+		Backend: conf.String("BLOB_BACKEND", blob.BackendFS),
+		Dir:     conf.String("BLOB_DIR", filepath.Join(dbDir, "blobs")),
+		S3: blob.S3Config{
+			Endpoint:        conf.String("S3_ENDPOINT", ""),
+			Region:          conf.String("S3_REGION", ""),
+			Bucket:          conf.String("S3_BUCKET", ""),
+			AccessKeyID:     conf.String("S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: conf.String("S3_SECRET_ACCESS_KEY", ""),
+			Prefix:          conf.String("S3_PREFIX", ""),
+		},
+	}
+	if blobConfig.Backend == "off" {
+		blobConfig = blob.Config{}
+	}
+
+	// === 21. API DEPRECATION ===
+	// The unversioned /api prefix answers with Deprecation (since
+	// LEGACY_API_DEPRECATED, a YYYY-MM-DD date) and, once a date is picked,
+	// Sunset (LEGACY_API_SUNSET) headers, and every use of it is logged.
+	// LEGACY_API_DOCS links the migration notes. See handler/deprecation.go.
+	legacyAPI := handler.Deprecation{
+		Since:   conf.Date("LEGACY_API_DEPRECATED", time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)),
+		Sunset:  conf.Date("LEGACY_API_SUNSET", time.Time{}),
+		Link:    conf.String("LEGACY_API_DOCS", ""),
+		Message: "The unversioned /api prefix is deprecated: use /api/v1.",
+	}
+
+	// === 22. CHECK THE CONFIGURATION ===
+	// The settings are gathered into the server's config and checked as a
+	// whole before anything starts (see validate.go): the web/ directories
+	// are there, the port is a port, the JWT secret is long enough, GitHub's
+	// credentials come as a set, the Docker image name is one. Every problem
+	// found — these, and values that didn't parse — is printed at once, each
+	// with how to fix it, rather than one per failed start.
 	cfg := server.Config{
-		Port:               port,
-		TemplateDir:        templateDir,
-		StaticDir:          staticDir,
-		DBPath:             dbPath,
-		JWTSecret:          jwtSecret,
-		GitHubClientID:     githubClientID,
-		GitHubClientSecret: githubClientSecret,
-		GitHubCallbackURL:  githubCallbackURL,
+		Port:                     port,
+		Listen:                   listen,
+		TemplateDir:              templateDir,
+		StaticDir:                staticDir,
+		DBPath:                   dbPath,
+		JWTSecret:                secrets.JWTSecret,
+		JWTPreviousSecrets:       secrets.JWTPreviousSecrets,
+		GitHubClientID:           secrets.GitHubClientID,
+		GitHubClientSecret:       secrets.GitHubClientSecret,
+		GitHubCallbackURL:        githubCallbackURL,
+		TLSCertFile:              conf.String("TLS_CERT_FILE", ""),
+		TLSKeyFile:               conf.String("TLS_KEY_FILE", ""),
+		AutocertDomains:          conf.List("AUTOCERT_DOMAINS"),
+		AutocertCacheDir:         conf.String("AUTOCERT_CACHE_DIR", ""),
+		AutocertEmail:            conf.String("AUTOCERT_EMAIL", ""),
+		HTTPRedirectPort:         httpRedirectPort,
+		MetricsEnabled:           metricsEnabled,
+		MetricsUsername:          conf.String("METRICS_USERNAME", ""),
+		MetricsPassword:          conf.String("METRICS_PASSWORD", ""),
+		APIRateLimit:             apiRateLimit,
+		AuthRateLimit:            authRateLimit,
+		ExecuteRateLimit:         executeRateLimit,
+		APIMaxBodyBytes:          apiMaxBody,
+		AuthMaxBodyBytes:         authMaxBody,
+		APITimeout:               apiTimeout,
+		ExecuteTimeout:           executeTimeout,
+		AuthTimeout:              authTimeout,
+		AccessLogPath:            accessLogPath,
+		AccessLogMaxSizeMB:       conf.Int("ACCESS_LOG_MAX_SIZE_MB", 0),
+		AccessLogMaxBackups:      conf.Int("ACCESS_LOG_MAX_BACKUPS", 0),
+		AccessLogMaxAgeDays:      conf.Int("ACCESS_LOG_MAX_AGE_DAYS", 0),
+		FeatureFlags:             featureFlags,
+		BodyLog:                  bodyLog,
+		JobWorkers:               conf.Int("JOB_WORKERS", 2),
+		IdempotencyTTL:           conf.Duration("IDEMPOTENCY_TTL", 24*time.Hour),
+		SnippetPurgeSchedule:     snippetPurgeSchedule,
+		WALCheckpointSchedule:    walCheckpointSchedule,
+		IdempotencyPurgeSchedule: idempotencyPurgeSchedule,
+		LeaderboardSchedule:      leaderboardSchedule,
+		AnonymousSnippetTTL:      anonymousSnippetTTL,
+		RunRetention:             runRetention,
+		AnonymousRunRetention:    anonymousRunRetention,
+		RunPurgeSchedule:         runPurgeSchedule,
+		PprofEnabled:             conf.Bool("PPROF_ENABLED", false),
+		SMTPHost:                 smtpHost,
+		SMTPPort:                 conf.Int("SMTP_PORT", 0),
+		SMTPUsername:             conf.String("SMTP_USERNAME", ""),
+		SMTPPassword:             conf.String("SMTP_PASSWORD", ""),
+		SMTPFrom:                 smtpFrom,
+		SMTPImplicitTLS:          conf.Bool("SMTP_IMPLICIT_TLS", false),
+		PublicURL:                conf.String("PUBLIC_URL", ""),
+		DigestSchedule:           digestSchedule,
+		AnalyticsEnabled:         analyticsEnabled,
+		AnalyticsFile:            conf.String("ANALYTICS_FILE", ""),
+		AnalyticsURL:             conf.String("ANALYTICS_URL", ""),
+		AnalyticsToken:           conf.String("ANALYTICS_TOKEN", ""),
+		AnalyticsFlushInterval:   conf.Duration("ANALYTICS_FLUSH_INTERVAL", 0),
+		OrgSnippetQuota:          orgSnippetQuota,
+		OrgRunQuota:              orgRunQuota,
+		AbuseDetection:           abuseDetection,
+		RedisURL:                 redisURL,
+		SnippetCacheTTL:          snippetCacheTTL,
+		ResponseCacheTTL:         responseCacheTTL,
+		CDNMaxAge:                cdnMaxAge,
+		CDNPurgeURL:              cdnPurgeURL,
+		CDNPurgeToken:            cdnPurgeToken,
+		LegacyAPI:                legacyAPI,
+		SentryDSN:                sentryDSN,
+		SentryEnvironment:        sentryEnvironment,
+		Blobs:                    blobConfig,
+		Retry:                    retryPolicy,
+		Deadlines:                deadlines,
+		DocsCacheTTL:             conf.Duration("DOCS_CACHE_TTL", 24*time.Hour),
+		Packages:                 packages,
 	}
 
+	validate(conf, cfg, dockerConfig, completerConfig)
+	checkConfig(logger, conf)
+
+	// === 23. START THE EXECUTOR ===
+	// Docker executor is optional — server starts without it but /api/execute will be unavailable.
+	//
+	// NIL INTERFACES:
+	// We declare exec as the executor.Executor INTERFACE, not *docker.Executor.
+	// An interface holding a nil *docker.Executor is itself non-nil, so the
+	// server's `s.exec != nil` check would wrongly pass. Only assign on success.
+	var leaseCloser io.Closer
+	if dockerConfig.MaxWarmTotal > 0 || dockerConfig.MaxRunningTotal > 0 {
+		dockerConfig.Leases, leaseCloser, err = newLeaseStore(conf.String("REDIS_URL", ""), dbPath)
+		if err != nil {
+			logger.Error("failed to open the sandbox lease store", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	// FALLBACK:
+	// Runs go through an executor.Chain: Docker first and, with
+	// EXECUTOR_LOCAL_FALLBACK=true, the host's own python3 while Docker is
+	// down. The local interpreter is NOT sandboxed — only enable it where
+	// everyone who can run code is trusted. When no backend can take a run,
+	// /api/execute answers 503 instead of 500.
+	var backends []executor.Backend
+	dockerExec, err := docker.New(dockerConfig, logger)
+	if err != nil {
+		logger.Warn("Docker executor unavailable",
+			slog.String("error", err.Error()),
+		)
+	} else {
+		backends = append(backends, executor.Backend{Name: "docker", Executor: dockerExec})
+	}
+	if localFallback {
+		localExec, err := local.New(local.Config{Timeout: dockerConfig.Timeout})
+		if err != nil {
+			logger.Warn("local executor fallback unavailable", slog.String("error", err.Error()))
+		} else {
+			logger.Warn("local executor fallback enabled: code runs unsandboxed while Docker is down")
+			backends = append(backends, executor.Backend{Name: "local", Executor: localExec})
+		}
+	}
+	var exec executor.Executor
+	if len(backends) > 0 {
+		exec = executor.NewChain(logger, backends...)
+	} else {
+		logger.Warn("no code executor available — /api/execute is disabled")
+	}
+
+	// Autocomplete's pool, alongside the runs' (see section 5). Without
+	// Docker, completions share the local fallback, if that's on.
+	var completer *docker.Executor
+	if dockerExec != nil {
+		completer, err = docker.New(completerConfig, logger)
+		if err != nil {
+			logger.Warn("autocomplete executor unavailable; completions share the code runs' sandboxes",
+				slog.String("error", err.Error()),
+			)
+		} else {
+			cfg.Completer = completer
+		}
+	}
+
+	// The debugger's executor (see section 5). Without Docker, sessions
+	// share the local fallback and its timeout.
+	var debugger *docker.Executor
+	if dockerExec != nil {
+		debugger, err = docker.New(debugConfig, logger)
+		if err != nil {
+			logger.Warn("debugging executor unavailable; debugging sessions share the code runs' sandboxes",
+				slog.String("error", err.Error()),
+			)
+		} else {
+			cfg.Debugger = debugger
+		}
+	}
+
+	// The GPU executor (see section 5), when GPU_IMAGE is set. Without
+	// Docker there are no GPUs to lend.
+	var gpuExec *docker.Executor
+	if dockerExec != nil && gpuConfig.Image != "" {
+		gpuExec, err = docker.New(gpuConfig, logger)
+		if err != nil {
+			logger.Warn("GPU executor unavailable; runs that ask for a GPU are refused",
+				slog.String("error", err.Error()),
+			)
+		} else {
+			cfg.GPU = gpuExec
+			cfg.GPURoles = gpuRoles
+		}
+	}
+
+	// === 24. CREATE AND START THE SERVER ===
+	// We build the server and start it. If anything fails, we log the error
+	// and exit with code 1 (non-zero = error).
 	srv, err := server.New(cfg, logger, exec)
 	if err != nil {
 		logger.Error("failed to create server", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
 
+	// The executor is created here rather than by the server, so it's
+	// registered for teardown here too. Its sandbox containers are removed
+	// after HTTP requests have drained and the server's live runs,
+	// background executions and debugging sessions have stopped using them;
+	// the lease store closes after the executors have released their leases.
+	// (A defer wouldn't run: os.Exit skips deferred calls.)
+	if leaseCloser != nil {
+		srv.AfterShutdown("sandbox leases", func(context.Context) error { return leaseCloser.Close() })
+	}
+	if dockerExec != nil {
+		srv.AfterShutdown("executor", func(context.Context) error { return dockerExec.Close() })
+	}
+	if completer != nil {
+		srv.AfterShutdown("autocomplete executor", func(context.Context) error { return completer.Close() })
+	}
+	if debugger != nil {
+		srv.AfterShutdown("debugging executor", func(context.Context) error { return debugger.Close() })
+	}
+	if gpuExec != nil {
+		srv.AfterShutdown("GPU executor", func(context.Context) error { return gpuExec.Close() })
+	}
+
+	go reloadSecretsOnSIGHUP(logger, srv)
+
 	// Start() blocks until the server is shut down (via Ctrl+C or SIGTERM)
 	if err := srv.Start(); err != nil {
 		logger.Error("server error", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
 }
+
+// newLogger builds the application logger from the LOG_FORMAT and LOG_LEVEL values.
+// Empty values fall back to the development defaults: text output at debug level.
+func newLogger(w io.Writer, format, level string) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	if level != "" {
+		// slog.Level understands "debug", "info", "warn", "error" (case-insensitive)
+		// and offsets like "info+2".
+		var lvl slog.Level
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("LOG_LEVEL: %w", err)
+		}
+		opts.Level = lvl
+	}
+
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "", "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("LOG_FORMAT: unknown format %q (want text or json)", format)
+	}
+
+	// Lines logged with a request context get that request's ID attached.
+	return slog.New(middleware.WithRequestID(h)), nil
+}
+
+// readSecrets reads the settings that can be reloaded while the server runs.
+func readSecrets(conf *config.Config) server.Secrets {
+	return server.Secrets{
+		JWTSecret:          conf.String("JWT_SECRET", ""),
+		JWTPreviousSecrets: conf.List("JWT_PREVIOUS_SECRETS"),
+		GitHubClientID:     conf.String("GITHUB_CLIENT_ID", ""),
+		GitHubClientSecret: conf.String("GITHUB_CLIENT_SECRET", ""),
+	}
+}
+
+// reloadSecretsOnSIGHUP rereads the configuration each time the process
+// gets SIGHUP, and hands srv its secrets. The environment can't change
+// under a running process, but the config file and secret files can. A
+// configuration with problems is logged and ignored, leaving the secrets
+// as they were.
+func reloadSecretsOnSIGHUP(logger *slog.Logger, srv *server.Server) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		logger.Info("SIGHUP received: reloading secrets")
+		conf, err := config.Load(os.Args[1:], os.LookupEnv, io.Discard)
+		if err != nil {
+			logger.Error("reloading secrets", slog.String("error", err.Error()))
+			continue
+		}
+		secrets := readSecrets(conf)
+		if err := conf.Err(); err != nil {
+			logger.Error("reloading secrets", slog.String("error", err.Error()))
+			continue
+		}
+		if err := srv.ReloadSecrets(secrets); err != nil {
+			logger.Error("reloading secrets", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// checkConfig exits, printing every problem found in conf and how to fix
+// it, if there are any.
+func checkConfig(logger *slog.Logger, conf *config.Config) {
+	problems := conf.Problems()
+	if len(problems) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\nThe server can't start: %d configuration problem(s).\n\n", len(problems))
+	for _, p := range problems {
+		fmt.Fprintf(os.Stderr, "  ✗ %s\n", p.Error())
+		if p.Hint != "" {
+			fmt.Fprintf(os.Stderr, "    → %s\n", p.Hint)
+		}
+	}
+	fmt.Fprintln(os.Stderr)
+	logger.Error("invalid configuration", slog.String("error", conf.Err().Error()))
+	os.Exit(1)
+}
+
+// newLeaseStore opens where sandbox leases are kept: Redis if there is one,
+// or else the database, which every server on the host shares anyway.
+func newLeaseStore(redisURL, dbPath string) (executor.LeaseStore, io.Closer, error) {
+	if redisURL != "" {
+		opts, err := redis.ParseURL(redisURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		client := redis.New(opts)
+		return executor.NewRedisLeaseStore(client, "playground:leases:"), client, nil
+	}
+	db, err := sqlite.New(dbPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, db, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		name, format, level string
+		enabled, disabled   slog.Level // levels the logger should and shouldn't log
+		json                bool
+		wantErr             string // "" for valid
+	}{
+		{name: "defaults", enabled: slog.LevelDebug, disabled: slog.LevelDebug - 1},
+		{name: "text", format: "text", level: "info", enabled: slog.LevelInfo, disabled: slog.LevelDebug},
+		{name: "json", format: "json", level: "warn", enabled: slog.LevelWarn, disabled: slog.LevelInfo, json: true},
+		{name: "upper case", format: "JSON", level: "ERROR", enabled: slog.LevelError, disabled: slog.LevelWarn, json: true},
+		{name: "offset level", level: "info+2", enabled: slog.LevelInfo + 2, disabled: slog.LevelInfo + 1},
+		{name: "bad level", level: "loud", wantErr: "LOG_LEVEL"},
+		{name: "bad format", format: "xml", wantErr: "LOG_FORMAT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			logger, err := newLogger(&out, tt.format, tt.level)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("newLogger() error = %v, want one naming %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newLogger() error = %v", err)
+			}
+
+			ctx := context.Background()
+			if !logger.Enabled(ctx, tt.enabled) || logger.Enabled(ctx, tt.disabled) {
+				t.Errorf("logger logs %v: %v, %v: %v; want only the first",
+					tt.enabled, logger.Enabled(ctx, tt.enabled), tt.disabled, logger.Enabled(ctx, tt.disabled))
+			}
+			logger.Log(ctx, tt.enabled, "hello")
+			isJSON := json.Valid(bytes.TrimSpace(out.Bytes()))
+			if isJSON != tt.json {
+				t.Errorf("output %q is JSON: %v, want %v", out.String(), isJSON, tt.json)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/distribution/reference"
+
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/config"
+	"github.com/sakif/coding-playground/internal/executor/docker"
+	"github.com/sakif/coding-playground/internal/server"
+)
+
+// validate checks the assembled configuration as a whole, recording each
+// problem in conf with a hint at the fix. Values that didn't parse are
+// already there; these are values that parse but won't work.
+//
+// It only checks what can be known without starting anything: whether
+// Docker is running, say, is found out when the executor starts, and the
+// server copes without it.
+func validate(conf *config.Config, cfg server.Config, dockerConfig, completerConfig docker.Config) {
+	// The pages and their assets are read from web/, relative to the
+	// working directory, unless TEMPLATE_DIR and STATIC_DIR say otherwise.
+	for _, dir := range []struct{ key, path string }{
+		{"TEMPLATE_DIR", cfg.TemplateDir},
+		{"STATIC_DIR", cfg.StaticDir},
+	} {
+		if info, err := os.Stat(dir.path); err != nil || !info.IsDir() {
+			conf.Report(dir.key, fmt.Sprintf("is %s, which isn't a directory", dir.path),
+				"Start the server from the repository root, where the web/ directory is, or set "+dir.key+".")
+		}
+	}
+
+	if cfg.Listen == "" {
+		conf.Check(cfg.Port >= 1 && cfg.Port <= 65535, "PORT",
+			fmt.Sprintf("is %d, which isn't a TCP port", cfg.Port),
+			"Use a port from 1 to 65535; 8080 is the default. Below 1024 needs root.")
+	}
+	conf.Check(cfg.HTTPRedirectPort >= 0 && cfg.HTTPRedirectPort <= 65535, "HTTP_REDIRECT_PORT",
+		fmt.Sprintf("is %d, which isn't a TCP port", cfg.HTTPRedirectPort),
+		"Use 80 for HTTP → HTTPS redirects, or 0 for none.")
+
+	if cfg.JWTSecret != "" {
+		conf.Check(len(cfg.JWTSecret) >= auth.MinSecretLength, "JWT_SECRET",
+			fmt.Sprintf("is %d characters; it must be at least %d", len(cfg.JWTSecret), auth.MinSecretLength),
+			"Generate one with: openssl rand -hex 32")
+	}
+	for i, secret := range cfg.JWTPreviousSecrets {
+		conf.Check(len(secret) >= auth.MinSecretLength, "JWT_PREVIOUS_SECRETS",
+			fmt.Sprintf("has a secret (number %d) of %d characters; each must be at least %d", i+1, len(secret), auth.MinSecretLength),
+			"List only secrets that were once JWT_SECRET, separated by commas.")
+	}
+	conf.Check(len(cfg.JWTPreviousSecrets) == 0 || cfg.JWTSecret != "", "JWT_PREVIOUS_SECRETS",
+		"is set, but JWT_SECRET isn't", "Set JWT_SECRET to the secret to sign with.")
+
+	// GitHub sign-in needs all three OAuth settings, and a JWT secret to
+	// sign the session with.
+	oauth := map[string]string{
+		"GITHUB_CLIENT_ID":     cfg.GitHubClientID,
+		"GITHUB_CLIENT_SECRET": cfg.GitHubClientSecret,
+		"GITHUB_CALLBACK_URL":  cfg.GitHubCallbackURL,
+	}
+	var set, missing []string
+	for _, key := range []string{"GITHUB_CLIENT_ID", "GITHUB_CLIENT_SECRET", "GITHUB_CALLBACK_URL"} {
+		if oauth[key] != "" {
+			set = append(set, key)
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	if len(set) > 0 {
+		for _, key := range missing {
+			conf.Report(key, "is missing, though "+strings.Join(set, " and ")+" is set",
+				"Copy it from your GitHub OAuth app (https://github.com/settings/developers), or unset all three to turn GitHub sign-in off.")
+		}
+		conf.Check(cfg.JWTSecret != "", "JWT_SECRET", "is missing, so GitHub sign-in can't be turned on",
+			"Generate one with: openssl rand -hex 32")
+	}
+	if cfg.GitHubCallbackURL != "" {
+		u, err := url.Parse(cfg.GitHubCallbackURL)
+		conf.Check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "GITHUB_CALLBACK_URL",
+			fmt.Sprintf("is %q, which isn't an http(s) URL", cfg.GitHubCallbackURL),
+			"Use the server's address followed by /auth/github/callback, e.g. http://localhost:8080/auth/github/callback.")
+	}
+
+	images := []struct{ key, name string }{{"DOCKER_IMAGE", dockerConfig.Image}}
+	if completerConfig.Image != dockerConfig.Image {
+		images = append(images, struct{ key, name string }{"COMPLETION_IMAGE", completerConfig.Image})
+	}
+	for _, image := range images {
+		if _, err := reference.ParseNormalizedNamed(image.name); err != nil {
+			conf.Report(image.key, fmt.Sprintf("is %q, which isn't an image name (%v)", image.name, err),
+				`Use a name like "python:3.12-alpine" or "registry.example.com/team/python:3.12".`)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sakif/coding-playground/internal/config"
+	"github.com/sakif/coding-playground/internal/executor/docker"
+	"github.com/sakif/coding-playground/internal/server"
+)
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	valid := server.Config{
+		Port:               8080,
+		TemplateDir:        dir,
+		StaticDir:          dir,
+		JWTSecret:          strings.Repeat("k", 32),
+		GitHubClientID:     "id",
+		GitHubClientSecret: "secret",
+		GitHubCallbackURL:  "http://localhost:8080/auth/github/callback",
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*server.Config, *docker.Config)
+		want   []string // the settings with problems, in order
+	}{
+		{"valid", func(*server.Config, *docker.Config) {}, nil},
+		{"no auth at all", func(c *server.Config, _ *docker.Config) {
+			c.JWTSecret, c.GitHubClientID, c.GitHubClientSecret, c.GitHubCallbackURL = "", "", "", ""
+		}, nil},
+		{"a missing template directory", func(c *server.Config, _ *docker.Config) {
+			c.TemplateDir += "/missing"
+		}, []string{"TEMPLATE_DIR"}},
+		{"a port out of range", func(c *server.Config, _ *docker.Config) {
+			c.Port, c.HTTPRedirectPort = 0, 70000
+		}, []string{"PORT", "HTTP_REDIRECT_PORT"}},
+		{"any port with LISTEN", func(c *server.Config, _ *docker.Config) {
+			c.Port, c.Listen = 0, "unix:/run/playground.sock"
+		}, nil},
+		{"a short JWT secret", func(c *server.Config, _ *docker.Config) {
+			c.JWTSecret = "short"
+		}, []string{"JWT_SECRET"}},
+		{"half the OAuth settings", func(c *server.Config, _ *docker.Config) {
+			c.JWTSecret, c.GitHubClientSecret, c.GitHubCallbackURL = "", "", "localhost:8080/callback"
+		}, []string{"GITHUB_CLIENT_SECRET", "JWT_SECRET", "GITHUB_CALLBACK_URL"}},
+		{"a bad image name", func(_ *server.Config, d *docker.Config) {
+			d.Image = "Python 3"
+		}, []string{"DOCKER_IMAGE"}},
+		{"everything at once", func(c *server.Config, d *docker.Config) {
+			c.StaticDir += "/missing"
+			c.Port = -1
+			c.GitHubClientID = ""
+			d.Image = ""
+		}, []string{"STATIC_DIR", "PORT", "GITHUB_CLIENT_ID", "DOCKER_IMAGE"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, err := config.Load(nil, func(string) (string, bool) { return "", false }, io.Discard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg, dockerConfig := valid, docker.DefaultConfig()
+			tt.mutate(&cfg, &dockerConfig)
+
+			validate(conf, cfg, dockerConfig, dockerConfig)
+			var got []string
+			for _, p := range conf.Problems() {
+				got = append(got, p.Setting)
+				if p.Hint == "" {
+					t.Errorf("%s has no hint: %s", p.Setting, p.Error())
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("problems with %v, want %v\n%v", got, tt.want, conf.Err())
+			}
+		})
+	}
+}
+
+func TestValidate_CompletionImage(t *testing.T) {
+	dir := t.TempDir()
+	cfg := server.Config{Port: 8080, TemplateDir: dir, StaticDir: dir}
+	conf, err := config.Load(nil, func(string) (string, bool) { return "", false }, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dockerConfig := docker.DefaultConfig()
+	completerConfig := dockerConfig
+	completerConfig.Image = "jedi:"
+
+	validate(conf, cfg, dockerConfig, completerConfig)
+	if p := conf.Problems(); len(p) != 1 || p[0].Setting != "COMPLETION_IMAGE" {
+		t.Errorf("problems = %v, want one with COMPLETION_IMAGE", conf.Err())
+	}
+}
@@ -0,0 +1,305 @@
+// Package abuse spots code that misuses the sandbox — mining cryptocurrency,
+// fork bombs, sleeping for hours, running the same heavy program over and
+// over — before it runs, and flags or refuses it.
+//
+// WHY CHECK CODE THE SANDBOX ALREADY CONTAINS?
+// The sandbox limits what one run can do (CPU, memory, time, no network),
+// but not what it's for. A miner that fits in the time limit still burns
+// the CPU we pay for, and a fork bomb still fills the process table until
+// the pids limit stops it, slowing every other run on the host. Catching
+// the obvious cases before they start is cheap; the sandbox remains the
+// real defence.
+//
+// RULES:
+//
+//	cryptominer        mining pool URLs and miner names (xmrig, stratum+tcp://…)   severe
+//	fork_bomb          os.fork() in an endless loop, the shell :(){ :|:& };:        severe
+//	long_sleep         sleep() with a literal longer than MaxSleep
+//	repeated_heavy_run the same code, from the same user, that took HeavyRun or
+//	                   longer RepeatLimit times within RepeatWindow
+//
+// These are heuristics on the source text: they're easy to get around, and
+// they'll now and then match honest code (a lesson about fork bombs). That's
+// why the response is configurable.
+//
+// STRICTNESS:
+//
+//	off      no checks
+//	monitor  report findings, run the code anyway
+//	standard refuse code with a severe finding, report the rest (default)
+//	strict   refuse code with any finding
+//
+// Every finding is an audit event: a warning in the log and
+// model.EventExecutionFlagged to the publisher (see PublishEvents), with the
+// code's hash but never the code.
+package abuse
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/model"
+)
+
+// Strictness says what to do about code that trips a check.
+type Strictness string
+
+// Strictness levels, from most to least forgiving.
+const (
+	Off      Strictness = "off"
+	Monitor  Strictness = "monitor"
+	Standard Strictness = "standard"
+	Strict   Strictness = "strict"
+)
+
+// ParseStrictness checks a strictness level from configuration. "" is
+// Standard.
+func ParseStrictness(s string) (Strictness, error) {
+	switch st := Strictness(strings.ToLower(strings.TrimSpace(s))); st {
+	case "":
+		return Standard, nil
+	case Off, Monitor, Standard, Strict:
+		return st, nil
+	default:
+		return "", fmt.Errorf("abuse detection must be off, monitor, standard or strict, not %q", s)
+	}
+}
+
+// Where code is run from, for AbuseReport.Source.
+const (
+	SourceExecute = "execute"
+	SourceLiveRun = "live_run"
+	SourceGrading = "grading"
+	SourceDebug   = "debug"
+)
+
+// Rule names, for AbuseFinding.Rule.
+const (
+	RuleCryptominer      = "cryptominer"
+	RuleForkBomb         = "fork_bomb"
+	RuleLongSleep        = "long_sleep"
+	RuleRepeatedHeavyRun = "repeated_heavy_run"
+)
+
+// Options configures a Detector. Zero values fall back to the defaults below.
+type Options struct {
+	Strictness   Strictness    // default Standard
+	MaxSleep     time.Duration // longest literal sleep allowed (default 60s)
+	HeavyRun     time.Duration // a run at least this long is heavy (default 5s)
+	RepeatLimit  int           // heavy runs of the same code before it's flagged (default 5)
+	RepeatWindow time.Duration // how far back heavy runs count (default 10m)
+}
+
+// Publisher receives audit events. service.EventPublisher satisfies it.
+type Publisher interface {
+	Publish(ctx context.Context, event string, data any)
+}
+
+// Detector checks code before it runs. It's safe for concurrent use.
+type Detector struct {
+	opts   Options
+	logger *slog.Logger
+	events Publisher // optional; see PublishEvents
+	now    func() time.Time
+
+	mu   sync.Mutex
+	runs map[string][]time.Time // user ID + code hash → recent heavy runs, oldest first
+}
+
+// New creates a Detector.
+func New(opts Options, logger *slog.Logger) *Detector {
+	if opts.Strictness == "" {
+		opts.Strictness = Standard
+	}
+	if opts.MaxSleep <= 0 {
+		opts.MaxSleep = time.Minute
+	}
+	if opts.HeavyRun <= 0 {
+		opts.HeavyRun = 5 * time.Second
+	}
+	if opts.RepeatLimit <= 0 {
+		opts.RepeatLimit = 5
+	}
+	if opts.RepeatWindow <= 0 {
+		opts.RepeatWindow = 10 * time.Minute
+	}
+	return &Detector{
+		opts:   opts,
+		logger: logger,
+		now:    time.Now,
+		runs:   make(map[string][]time.Time),
+	}
+}
+
+// PublishEvents makes the detector report model.EventExecutionFlagged to p.
+// Call it before serving requests.
+func (d *Detector) PublishEvents(p Publisher) {
+	d.events = p
+}
+
+// Check looks at code about to be run from source for the signed-in user
+// in ctx (or an anonymous one). It returns an ErrForbidden AppError if the
+// code must not run; findings that don't block are reported and nil is
+// returned.
+func (d *Detector) Check(ctx context.Context, source, code string) error {
+	if d.opts.Strictness == Off {
+		return nil
+	}
+	userID, _ := auth.UserIDFromContext(ctx)
+	hash := codeHash(code)
+
+	findings := scan(code, d.opts.MaxSleep)
+	if n := d.heavyRuns(userID, hash); n >= d.opts.RepeatLimit {
+		findings = append(findings, model.AbuseFinding{
+			Rule:   RuleRepeatedHeavyRun,
+			Detail: fmt.Sprintf("run %d times in %s, each taking %s or more", n, d.opts.RepeatWindow, d.opts.HeavyRun),
+		})
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+
+	var blockedBy *model.AbuseFinding
+	for i, f := range findings {
+		if d.opts.Strictness == Strict || (d.opts.Strictness == Standard && f.Severe) {
+			blockedBy = &findings[i]
+			break
+		}
+	}
+	report := &model.AbuseReport{
+		UserID:   userID,
+		Source:   source,
+		CodeHash: hash,
+		Findings: findings,
+		Blocked:  blockedBy != nil,
+	}
+	d.audit(ctx, report)
+	if blockedBy == nil {
+		return nil
+	}
+	return &apperror.AppError{
+		Err:     apperror.ErrForbidden,
+		Message: fmt.Sprintf("this code wasn't run: it looks like abuse of the sandbox (%s)", blockedBy.Detail),
+		Code:    apperror.CodeAbuseDetected,
+	}
+}
+
+// Observe records that code ran for took, so repeated heavy runs can be
+// spotted. Call it after every run that Check let through.
+func (d *Detector) Observe(ctx context.Context, code string, took time.Duration) {
+	if d.opts.Strictness == Off || took < d.opts.HeavyRun {
+		return
+	}
+	userID, _ := auth.UserIDFromContext(ctx)
+	key := userID + ":" + codeHash(code)
+	now := d.now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.runs[key] = append(d.recent(key, now), now)
+	// Forget code nobody has run heavily for a while, so the map doesn't grow
+	// forever.
+	if len(d.runs) > 10_000 {
+		for k := range d.runs {
+			if len(d.recent(k, now)) == 0 {
+				delete(d.runs, k)
+			}
+		}
+	}
+}
+
+// heavyRuns counts the heavy runs of code by userID within the window.
+func (d *Detector) heavyRuns(userID, hash string) int {
+	key := userID + ":" + hash
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	recent := d.recent(key, d.now())
+	if len(recent) == 0 {
+		delete(d.runs, key)
+	} else {
+		d.runs[key] = recent
+	}
+	return len(recent)
+}
+
+// recent returns the runs under key still within the window. d.mu must be
+// held.
+func (d *Detector) recent(key string, now time.Time) []time.Time {
+	runs := d.runs[key]
+	cutoff := now.Add(-d.opts.RepeatWindow)
+	i := 0
+	for i < len(runs) && !runs[i].After(cutoff) {
+		i++
+	}
+	return runs[i:]
+}
+
+// audit logs a report and publishes it.
+func (d *Detector) audit(ctx context.Context, report *model.AbuseReport) {
+	rules := make([]string, len(report.Findings))
+	for i, f := range report.Findings {
+		rules[i] = f.Rule
+	}
+	d.logger.WarnContext(ctx, "execution flagged as possible abuse",
+		slog.Bool("audit", true),
+		slog.String("user_id", report.UserID),
+		slog.String("source", report.Source),
+		slog.String("code_sha256", report.CodeHash),
+		slog.String("rules", strings.Join(rules, ",")),
+		slog.Bool("blocked", report.Blocked),
+	)
+	if d.events != nil {
+		d.events.Publish(ctx, model.EventExecutionFlagged, report)
+	}
+}
+
+var (
+	minerPattern = regexp.MustCompile(`(?i)stratum\+(?:tcp|ssl|tls)://|\bxmrig\b|\bcpuminer\b|\bminerd\b|cryptonight|coinhive|nicehash|\bminexmr\b|\bsupportxmr\b`)
+	// os.fork() (or forkpty) after an endless loop starts, in the same
+	// program; no attempt to check it's really inside the loop.
+	forkLoopPattern  = regexp.MustCompile(`(?s)\bwhile\s*\(?\s*(?:True|1)\s*\)?\s*:.*\bos\.fork(?:pty)?\s*\(`)
+	shellBombPattern = regexp.MustCompile(`:\s*\(\s*\)\s*\{\s*:\s*\|\s*:\s*&\s*\}\s*;\s*:`)
+	sleepPattern     = regexp.MustCompile(`\bsleep\s*\(\s*([0-9][0-9_]*(?:\.[0-9_]*)?(?:[eE][+-]?[0-9]+)?)\s*\)`)
+)
+
+// scan runs the rules that only need the code.
+func scan(code string, maxSleep time.Duration) []model.AbuseFinding {
+	var findings []model.AbuseFinding
+	if m := minerPattern.FindString(code); m != "" {
+		findings = append(findings, model.AbuseFinding{
+			Rule: RuleCryptominer, Detail: fmt.Sprintf("mentions cryptocurrency mining (%q)", m), Severe: true,
+		})
+	}
+	if forkLoopPattern.MatchString(code) || shellBombPattern.MatchString(code) {
+		findings = append(findings, model.AbuseFinding{
+			Rule: RuleForkBomb, Detail: "forks processes in an endless loop", Severe: true,
+		})
+	}
+	for _, m := range sleepPattern.FindAllStringSubmatch(code, -1) {
+		secs, err := strconv.ParseFloat(strings.ReplaceAll(m[1], "_", ""), 64)
+		if err != nil || secs <= maxSleep.Seconds() {
+			continue
+		}
+		findings = append(findings, model.AbuseFinding{
+			Rule: RuleLongSleep, Detail: fmt.Sprintf("sleeps for %gs, longer than %s", secs, maxSleep),
+		})
+		break
+	}
+	return findings
+}
+
+// codeHash identifies code without keeping it.
+func codeHash(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
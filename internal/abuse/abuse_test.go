@@ -0,0 +1,161 @@
+package abuse
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/model"
+)
+
+// recorder keeps the reports a Detector publishes.
+type recorder struct {
+	reports []*model.AbuseReport
+}
+
+func (r *recorder) Publish(_ context.Context, event string, data any) {
+	if event == model.EventExecutionFlagged {
+		r.reports = append(r.reports, data.(*model.AbuseReport))
+	}
+}
+
+func newTestDetector(strictness Strictness) (*Detector, *recorder) {
+	d := New(Options{Strictness: strictness, MaxSleep: time.Minute, HeavyRun: time.Second, RepeatLimit: 3}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	rec := &recorder{}
+	d.PublishEvents(rec)
+	return d, rec
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want []string
+	}{
+		{"honest code", "for i in range(10):\n    print(i)", nil},
+		{"short sleep", "import time\ntime.sleep(2)", nil},
+		{"fork without a loop", "import os\npid = os.fork()", nil},
+		{"pool url", `pool = "stratum+tcp://pool.example.com:3333"`, []string{RuleCryptominer}},
+		{"miner binary", `subprocess.run(["./XMRig", "-o", url])`, []string{RuleCryptominer}},
+		{"fork bomb", "import os\nwhile True:\n    os.fork()", []string{RuleForkBomb}},
+		{"fork bomb, while 1", "import os\nwhile(1):\n  os.fork()", []string{RuleForkBomb}},
+		{"shell fork bomb", `os.system(":(){ :|:& };:")`, []string{RuleForkBomb}},
+		{"long sleep", "time.sleep(3600)", []string{RuleLongSleep}},
+		{"long sleep, scientific", "await asyncio.sleep(1e6)", []string{RuleLongSleep}},
+		{"long sleep, underscores", "sleep(86_400)", []string{RuleLongSleep}},
+		{"several", "while True:\n    os.fork()\n    time.sleep(9999)", []string{RuleForkBomb, RuleLongSleep}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range scan(tt.code, time.Minute) {
+				got = append(got, f.Rule)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("scan() rules = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("scan() rules = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDetector_Strictness(t *testing.T) {
+	ctx := auth.WithUserID(context.Background(), "ann-id")
+	forkBomb := "import os\nwhile True:\n    os.fork()"
+	longSleep := "import time\ntime.sleep(3600)"
+
+	tests := []struct {
+		strictness  Strictness
+		code        string
+		wantBlocked bool
+		wantReports int
+	}{
+		{Off, forkBomb, false, 0},
+		{Monitor, forkBomb, false, 1},
+		{Standard, forkBomb, true, 1},
+		{Standard, longSleep, false, 1},
+		{Strict, longSleep, true, 1},
+		{Strict, "print('hi')", false, 0},
+	}
+	for _, tt := range tests {
+		d, rec := newTestDetector(tt.strictness)
+		err := d.Check(ctx, SourceExecute, tt.code)
+		if blocked := errors.Is(err, apperror.ErrForbidden); blocked != tt.wantBlocked {
+			t.Errorf("%s: Check(%q) error = %v, want blocked %v", tt.strictness, tt.code, err, tt.wantBlocked)
+		}
+		if len(rec.reports) != tt.wantReports {
+			t.Fatalf("%s: %d reports published, want %d", tt.strictness, len(rec.reports), tt.wantReports)
+		}
+		if tt.wantReports > 0 {
+			r := rec.reports[0]
+			if r.UserID != "ann-id" || r.Source != SourceExecute || r.Blocked != tt.wantBlocked || len(r.CodeHash) != 64 {
+				t.Errorf("%s: report = %+v", tt.strictness, r)
+			}
+		}
+	}
+}
+
+func TestDetector_RepeatedHeavyRuns(t *testing.T) {
+	d, rec := newTestDetector(Strict)
+	now := time.Now()
+	d.now = func() time.Time { return now }
+	ann := auth.WithUserID(context.Background(), "ann-id")
+	bob := auth.WithUserID(context.Background(), "bob-id")
+	code := "sum(i * i for i in range(10**8))"
+
+	// Quick runs don't count.
+	for range 5 {
+		d.Observe(ann, code, 10*time.Millisecond)
+	}
+	if err := d.Check(ann, SourceExecute, code); err != nil {
+		t.Fatalf("Check(after quick runs) error = %v", err)
+	}
+
+	for range 3 {
+		if err := d.Check(ann, SourceExecute, code); err != nil {
+			t.Fatalf("Check(under the limit) error = %v", err)
+		}
+		d.Observe(ann, code, 2*time.Second)
+	}
+	err := d.Check(ann, SourceExecute, code)
+	if !errors.Is(err, apperror.ErrForbidden) {
+		t.Fatalf("Check(at the limit) error = %v, want forbidden", err)
+	}
+	if len(rec.reports) != 1 || rec.reports[0].Findings[0].Rule != RuleRepeatedHeavyRun {
+		t.Errorf("reports = %+v, want one repeated_heavy_run", rec.reports)
+	}
+
+	// Other users and other code aren't affected.
+	if err := d.Check(bob, SourceExecute, code); err != nil {
+		t.Errorf("Check(other user) error = %v", err)
+	}
+	if err := d.Check(ann, SourceExecute, code+" # changed"); err != nil {
+		t.Errorf("Check(other code) error = %v", err)
+	}
+
+	// The runs age out of the window.
+	now = now.Add(d.opts.RepeatWindow)
+	if err := d.Check(ann, SourceExecute, code); err != nil {
+		t.Errorf("Check(after the window) error = %v", err)
+	}
+}
+
+func TestParseStrictness(t *testing.T) {
+	for in, want := range map[string]Strictness{"": Standard, "off": Off, " Strict ": Strict, "monitor": Monitor} {
+		if got, err := ParseStrictness(in); err != nil || got != want {
+			t.Errorf("ParseStrictness(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseStrictness("paranoid"); err == nil {
+		t.Error("ParseStrictness(paranoid) error = nil, want an error")
+	}
+}
@@ -0,0 +1,233 @@
+// Package analytics records product events — a snippet was created, code
+// was run, someone signed in — so usage can be analysed later without
+// digging through logs.
+//
+// WHY NOT JUST LOG?
+// Log lines are written for people debugging one request. Questions like
+// "how many runs per active user last week?" need every occurrence of an
+// event, each with the same fields, in one place. An Event is that record:
+//
+//	{"name":"execution_run","userId":"ann-id","properties":{"exit_code":0,"duration_ms":120},"time":"…"}
+//
+// BATCHING:
+// Record never waits on the database. Events go into a buffer, and a single
+// goroutine writes them out in batches: when BatchSize have piled up, every
+// FlushInterval otherwise, and once more at Shutdown. If the buffer is full
+// (the database is stuck), new events are dropped and counted rather than
+// slowing requests down — analytics are useful, not essential.
+//
+// EXPORTING:
+// Every batch is saved to the local analytics_events table, then handed to
+// each Exporter: a JSON Lines file (File) or an HTTP collector (HTTP). An
+// exporter that fails misses that batch — the local table still has it.
+package analytics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Event names.
+const (
+	EventSnippetCreated   = "snippet_created"
+	EventExecutionRun     = "execution_run"
+	EventLogin            = "login"
+	EventExecutionFlagged = "execution_flagged"
+)
+
+// Event is one thing that happened in the product.
+type Event struct {
+	Name       string         `json:"name"`
+	UserID     string         `json:"userId,omitempty"` // "" for anonymous users
+	Properties map[string]any `json:"properties,omitempty"`
+	Time       time.Time      `json:"time"`
+}
+
+// Store saves batches of events. The SQLite repository implements it.
+type Store interface {
+	SaveAnalyticsEvents(ctx context.Context, events []Event) error
+}
+
+// Exporter sends batches of events somewhere else for analysis.
+type Exporter interface {
+	Export(ctx context.Context, events []Event) error
+}
+
+// Options configures a Recorder. Zero values fall back to the defaults below.
+type Options struct {
+	BatchSize     int           // events per write (default 100)
+	FlushInterval time.Duration // longest an event waits to be written (default 5s)
+	BufferSize    int           // events held before new ones are dropped (default 10000)
+	Exporters     []Exporter
+}
+
+func (o Options) withDefaults() Options {
+	if o.BatchSize <= 0 {
+		o.BatchSize = 100
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = 5 * time.Second
+	}
+	if o.BufferSize <= 0 {
+		o.BufferSize = 10000
+	}
+	return o
+}
+
+// Recorder batches events and writes them to a Store and its exporters.
+//
+// LIFECYCLE:
+//
+//	r := analytics.New(db, logger, analytics.Options{})
+//	r.Start()
+//	r.Record(analytics.EventLogin, userID, nil)   // any time, from any goroutine
+//	r.Shutdown(ctx)                               // writes what's left
+type Recorder struct {
+	store  Store
+	logger *slog.Logger
+	opts   Options
+
+	events  chan Event
+	stop    chan struct{} // closed by Shutdown
+	done    chan struct{} // closed when the loop has written its last batch
+	started sync.Once
+	stopped sync.Once
+
+	mu      sync.Mutex
+	dropped int // since the last batch
+
+	now func() time.Time // injectable for tests
+}
+
+// New creates a Recorder. Events recorded before Start wait in the buffer.
+func New(store Store, logger *slog.Logger, opts Options) *Recorder {
+	opts = opts.withDefaults()
+	return &Recorder{
+		store:  store,
+		logger: logger,
+		opts:   opts,
+		events: make(chan Event, opts.BufferSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		now:    time.Now,
+	}
+}
+
+// Start begins writing batches in the background. Calling it again does nothing.
+func (r *Recorder) Start() {
+	r.started.Do(func() { go r.loop() })
+}
+
+// Record queues an event. It never blocks: when the buffer is full the
+// event is dropped.
+func (r *Recorder) Record(name, userID string, props map[string]any) {
+	e := Event{Name: name, UserID: userID, Properties: props, Time: r.now().UTC()}
+	select {
+	case <-r.stop:
+		r.drop()
+		return
+	default:
+	}
+	select {
+	case r.events <- e:
+	default:
+		r.drop()
+	}
+}
+
+func (r *Recorder) drop() {
+	r.mu.Lock()
+	r.dropped++
+	r.mu.Unlock()
+}
+
+// Shutdown stops accepting events and writes the ones still buffered,
+// giving up when ctx is done. That works even if Start was never called.
+func (r *Recorder) Shutdown(ctx context.Context) error {
+	r.stopped.Do(func() { close(r.stop) })
+	r.Start()
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// loop collects events into batches until Shutdown.
+func (r *Recorder) loop() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, r.opts.BatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			r.write(batch)
+			batch = make([]Event, 0, r.opts.BatchSize)
+		}
+	}
+	for {
+		select {
+		case e := <-r.events:
+			batch = append(batch, e)
+			if len(batch) >= r.opts.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-r.stop:
+			// Drain what was recorded before Shutdown.
+			for {
+				select {
+				case e := <-r.events:
+					batch = append(batch, e)
+					if len(batch) >= r.opts.BatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// write saves a batch and exports it. Failures are logged; the batch isn't
+// retried.
+func (r *Recorder) write(batch []Event) {
+	// Shutdown may be what's flushing, so this doesn't use its context.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	r.mu.Lock()
+	dropped := r.dropped
+	r.dropped = 0
+	r.mu.Unlock()
+	if dropped > 0 {
+		r.logger.WarnContext(ctx, "analytics buffer full, events dropped", slog.Int("count", dropped))
+	}
+
+	if err := r.store.SaveAnalyticsEvents(ctx, batch); err != nil {
+		r.logger.ErrorContext(ctx, "failed to save analytics events",
+			slog.Int("count", len(batch)),
+			slog.String("error", err.Error()),
+		)
+	}
+	var errs []error
+	for _, exp := range r.opts.Exporters {
+		if err := exp.Export(ctx, batch); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		r.logger.ErrorContext(ctx, "failed to export analytics events",
+			slog.Int("count", len(batch)),
+			slog.String("error", err.Error()),
+		)
+	}
+}
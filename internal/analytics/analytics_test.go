@@ -0,0 +1,115 @@
+package analytics
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memStore records the batches it's given.
+type memStore struct {
+	mu      sync.Mutex
+	batches [][]Event
+}
+
+func (s *memStore) SaveAnalyticsEvents(_ context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, append([]Event(nil), events...))
+	return nil
+}
+
+func (s *memStore) sizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var sizes []int
+	for _, b := range s.batches {
+		sizes = append(sizes, len(b))
+	}
+	return sizes
+}
+
+// Export makes memStore an Exporter too.
+func (s *memStore) Export(ctx context.Context, events []Event) error {
+	return s.SaveAnalyticsEvents(ctx, events)
+}
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRecorder_BatchesBySize(t *testing.T) {
+	store, exported := &memStore{}, &memStore{}
+	r := New(store, discard, Options{BatchSize: 3, FlushInterval: time.Hour, Exporters: []Exporter{exported}})
+	r.Start()
+	for range 7 {
+		r.Record(EventLogin, "ann-id", map[string]any{"method": "github"})
+	}
+	waitFor(t, func() bool { return len(store.sizes()) == 2 })
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	for name, s := range map[string]*memStore{"saved": store, "exported": exported} {
+		if got := s.sizes(); len(got) != 3 || got[0] != 3 || got[1] != 3 || got[2] != 1 {
+			t.Errorf("%s batch sizes = %v, want [3 3 1]", name, got)
+		}
+	}
+	if e := store.batches[0][0]; e.Name != EventLogin || e.UserID != "ann-id" || e.Properties["method"] != "github" || e.Time.IsZero() {
+		t.Errorf("first event = %+v", e)
+	}
+}
+
+func TestRecorder_FlushesOnInterval(t *testing.T) {
+	store := &memStore{}
+	r := New(store, discard, Options{BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+	r.Start()
+	defer r.Shutdown(context.Background())
+	r.Record(EventSnippetCreated, "", nil)
+	waitFor(t, func() bool { return len(store.sizes()) == 1 })
+}
+
+func TestRecorder_ShutdownWithoutStart(t *testing.T) {
+	store := &memStore{}
+	r := New(store, discard, Options{})
+	r.Record(EventExecutionRun, "", nil)
+	r.Record(EventExecutionRun, "", nil)
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if got := store.sizes(); len(got) != 1 || got[0] != 2 {
+		t.Errorf("batch sizes = %v, want [2]", got)
+	}
+
+	// Afterwards events are dropped, not written or blocked on.
+	r.Record(EventExecutionRun, "", nil)
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatalf("second Shutdown() error = %v", err)
+	}
+	if got := store.sizes(); len(got) != 1 {
+		t.Errorf("batch sizes after shutdown = %v, want no new batch", got)
+	}
+}
+
+func TestRecorder_DropsWhenFull(t *testing.T) {
+	store := &memStore{}
+	r := New(store, discard, Options{BufferSize: 2})
+	for range 5 {
+		r.Record(EventLogin, "", nil) // never blocks, though nothing is draining
+	}
+	r.Shutdown(context.Background())
+	if got := store.sizes(); len(got) != 1 || got[0] != 2 {
+		t.Errorf("batch sizes = %v, want [2]", got)
+	}
+}
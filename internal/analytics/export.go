@@ -0,0 +1,87 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// File is an Exporter that appends events to a JSON Lines file, one event
+// per line — a format most analysis tools (DuckDB, pandas, jq) read as is.
+type File struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// Export appends events to the file, creating it if needed.
+func (f *File) Export(_ context.Context, events []Event) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("analytics: encoding event: %w", err)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("analytics: opening %s: %w", f.Path, err)
+	}
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return fmt.Errorf("analytics: writing %s: %w", f.Path, err)
+	}
+	return file.Close()
+}
+
+// HTTP is an Exporter that POSTs each batch to a collector as
+//
+//	{"events": [ ... ]}
+//
+// with the Token, if set, as a bearer token. Any 2xx response is success.
+type HTTP struct {
+	URL    string
+	Token  string
+	Client *http.Client // default: 10s timeout
+}
+
+// Export sends events to the collector.
+func (h *HTTP) Export(ctx context.Context, events []Event) error {
+	body, err := json.Marshal(struct {
+		Events []Event `json:"events"`
+	}{events})
+	if err != nil {
+		return fmt.Errorf("analytics: encoding events: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("analytics: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.Token)
+	}
+	client := h.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("analytics: posting to collector: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // lets the connection be reused
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("analytics: collector answered %s", resp.Status)
+	}
+	return nil
+}
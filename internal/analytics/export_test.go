@@ -0,0 +1,71 @@
+package analytics
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var batch = []Event{
+	{Name: EventLogin, UserID: "ann-id", Properties: map[string]any{"method": "email"}, Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+	{Name: EventSnippetCreated, Time: time.Date(2024, 5, 1, 12, 0, 1, 0, time.UTC)},
+}
+
+func TestFile_Export(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	f := &File{Path: path}
+	for range 2 {
+		if err := f.Export(context.Background(), batch); err != nil {
+			t.Fatalf("Export() error = %v", err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var lines []Event
+	sc := bufio.NewScanner(file)
+	for sc.Scan() {
+		var e Event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		lines = append(lines, e)
+	}
+	if len(lines) != 4 || lines[0].Properties["method"] != "email" || lines[3].Name != EventSnippetCreated {
+		t.Errorf("file has %+v, want both batches appended", lines)
+	}
+}
+
+func TestHTTP_Export(t *testing.T) {
+	var got struct{ Events []Event }
+	var auth string
+	status := http.StatusAccepted
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+	}))
+	defer collector.Close()
+
+	h := &HTTP{URL: collector.URL, Token: "s3cret"}
+	if err := h.Export(context.Background(), batch); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if len(got.Events) != 2 || got.Events[0].UserID != "ann-id" || auth != "Bearer s3cret" {
+		t.Errorf("collector got %+v with Authorization %q", got, auth)
+	}
+
+	status = http.StatusServiceUnavailable
+	if err := h.Export(context.Background(), batch); err == nil {
+		t.Error("Export() to a failing collector succeeded, want an error")
+	}
+}
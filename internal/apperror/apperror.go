@@ -3,6 +3,7 @@ package apperror
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 )
 
 var (
@@ -10,15 +11,28 @@ var (
 	ErrValidation = errors.New("Validation Error")
 	ErrConflict   = errors.New("conflict")
 	ErrForbidden  = errors.New("forbidden")
+	// ErrUnavailable means a dependency is down for now; trying again
+	// later may work.
+	ErrUnavailable = errors.New("unavailable")
 )
 
 type AppError struct {
 	Err     error  // actual error
 	Message string // Human-readable error message
 	Field   string // Optional: field causing the error
+	Code    Code   // Optional: machine-readable code (see codes.go); CodeOf fills in a generic one
+
+	meta   []slog.Attr       // logged with the error, never sent (see wrap.go)
+	stack  []uintptr         // where Wrap was called, if stack capture is on
+	params map[string]string // fill in translated messages (see params.go)
 }
 
+// Error returns the message, or for an error from Wrap without one, the
+// wrapped error's.
 func (e *AppError) Error() string {
+	if e.Message == "" && e.Err != nil {
+		return e.Err.Error()
+	}
 	return e.Message
 }
 
@@ -26,10 +40,21 @@ func (e *AppError) Unwrap() error {
 	return e.Err
 }
 
+// WithCode returns a copy of e with a more specific code, e.g.
+//
+//	apperror.ValidationFailed("name", "name is too long").WithCode(apperror.CodeNameTooLong)
+func (e *AppError) WithCode(code Code) *AppError {
+	c := *e
+	c.Code = code
+	return &c
+}
+
 func NotFound(resource, id string) *AppError {
 	return &AppError{
 		Err:     ErrNotFound,
 		Message: fmt.Sprintf("%s not found with id %s", resource, id),
+		Code:    codeFor(resource, "NOT_FOUND", CodeNotFound),
+		params:  map[string]string{"resource": resource, "id": id},
 	}
 }
 
@@ -38,6 +63,8 @@ func ValidationFailed(field, message string) *AppError {
 		Err:     ErrValidation,
 		Message: message,
 		Field:   field,
+		Code:    CodeValidation,
+		params:  map[string]string{"field": field},
 	}
 }
 
@@ -45,5 +72,18 @@ func Conflict(resource, id string) *AppError {
 	return &AppError{
 		Err:     ErrConflict,
 		Message: fmt.Sprintf("%s conflict with id %s", resource, id),
+		Code:    codeFor(resource, "CONFLICT", CodeConflict),
+		params:  map[string]string{"resource": resource, "id": id},
+	}
+}
+
+// Unavailable reports that what (e.g. "code execution") can't be used right
+// now, though it may be back shortly.
+func Unavailable(what string) *AppError {
+	return &AppError{
+		Err:     ErrUnavailable,
+		Message: fmt.Sprintf("%s is temporarily unavailable", what),
+		Code:    CodeUnavailable,
+		params:  map[string]string{"what": what},
 	}
 }
@@ -7,6 +7,8 @@ package apperror
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -44,6 +46,12 @@ func TestErrorsIs(t *testing.T) {
 			target:    ErrConflict,
 			wantMatch: true,
 		},
+		{
+			name:      "Unavailable wraps ErrUnavailable",
+			err:       Unavailable("code execution"),
+			target:    ErrUnavailable,
+			wantMatch: true,
+		},
 		{
 			name:      "NotFound does NOT match ErrValidation",
 			err:       NotFound("snippet", "abc123"),
@@ -93,6 +101,11 @@ func TestErrorMessages(t *testing.T) {
 			err:         Conflict("snippet", "abc123"),
 			wantMessage: "snippet conflict with id abc123",
 		},
+		{
+			name:        "Unavailable message names what is down",
+			err:         Unavailable("code execution"),
+			wantMessage: "code execution is temporarily unavailable",
+		},
 	}
 
 	for _, tt := range tests {
@@ -125,3 +138,147 @@ func TestValidationFailedField(t *testing.T) {
 		t.Errorf("Field = %q, want %q", err.Field, "email")
 	}
 }
+
+func TestValidationErrors(t *testing.T) {
+	var verrs ValidationErrors
+	if verrs.Err() != nil {
+		t.Fatal("Err() with no fields should be nil")
+	}
+
+	verrs.Add("name", "name is required")
+	verrs.Add("code", "code is too long")
+	err := verrs.Err()
+
+	// Callers that only know about AppError still get a sensible error.
+	var appErr *AppError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("Err() = %T, want *AppError", err)
+	}
+	if appErr.Field != "name" || appErr.Message != "name is required; code is too long" {
+		t.Errorf("AppError = {Field: %q, Message: %q}", appErr.Field, appErr.Message)
+	}
+	if !errors.Is(err, ErrValidation) {
+		t.Error("errors.Is(err, ErrValidation) = false")
+	}
+
+	var got *ValidationErrors
+	if !errors.As(err, &got) || len(got.Fields) != 2 {
+		t.Errorf("errors.As(*ValidationErrors) = %v, want both fields", got)
+	}
+}
+
+func TestCodeOf(t *testing.T) {
+	unknown := &AppError{Err: ErrConflict, Message: "x", Code: "NOT_IN_THE_CATALOG"}
+	tests := []struct {
+		name string
+		err  error
+		want Code
+	}{
+		{"catalogued resource", NotFound("snippet", "abc123"), CodeSnippetNotFound},
+		{"multi-word resource", NotFound("org member", "u1"), CodeOrgMemberNotFound},
+		{"uncatalogued resource", NotFound("widget", "w1"), CodeNotFound},
+		{"wrapped", fmt.Errorf("getting snippet: %w", NotFound("snippet", "abc123")), CodeSnippetNotFound},
+		{"WithCode", ValidationFailed("name", "too long").WithCode(CodeNameTooLong), CodeNameTooLong},
+		{"unknown code falls back to its kind", unknown, CodeConflict},
+		{"validation errors", func() error { var v ValidationErrors; v.Add("a", "b"); return v.Err() }(), CodeValidation},
+		{"not an AppError", errors.New("disk on fire"), CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CodeOf(tt.err); got != tt.want {
+				t.Errorf("CodeOf(%v) = %s, want %s", tt.err, got, tt.want)
+			}
+		})
+	}
+
+	base := ValidationFailed("name", "required")
+	if base.WithCode(CodeNameRequired); base.Code != CodeValidation {
+		t.Errorf("WithCode changed the original's code to %s", base.Code)
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	entries := Catalog()
+	for i, e := range entries {
+		if i > 0 && entries[i-1].Code >= e.Code {
+			t.Errorf("Catalog() not sorted at %s", e.Code)
+		}
+		if e.Description == "" {
+			t.Errorf("%s has no description", e.Code)
+		}
+		if got, ok := Lookup(e.Code); !ok || got.Code != e.Code {
+			t.Errorf("Lookup(%s) = %v, %v", e.Code, got, ok)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Define() accepted a duplicate code")
+		}
+	}()
+	Define(CodeNotFound, ErrNotFound, "again")
+}
+
+func TestWrap(t *testing.T) {
+	cause := errors.New("database is locked")
+	err := Wrap(fmt.Errorf("updating snippet: %w", cause)).WithField("code").WithMeta("snippet_id", "abc")
+
+	if !errors.Is(err, cause) || err.Error() != "updating snippet: database is locked" {
+		t.Errorf("Wrap() = %q, want the wrapped error's message and chain", err)
+	}
+	if err.Field != "code" || CodeOf(err) != CodeInternal {
+		t.Errorf("Field = %q, CodeOf = %s", err.Field, CodeOf(err))
+	}
+
+	// An AppError keeps its kind, message and code.
+	notFound := Wrap(NotFound("snippet", "abc")).WithMeta("user_id", "u1")
+	if !errors.Is(notFound, ErrNotFound) || notFound.Message != "snippet not found with id abc" || CodeOf(notFound) != CodeSnippetNotFound {
+		t.Errorf("Wrap(NotFound) = %+v", notFound)
+	}
+
+	// Metadata is collected along the chain; the outer value wins.
+	outer := Wrap(fmt.Errorf("forking: %w", err.WithMeta("user_id", "inner"))).WithMeta("user_id", "outer")
+	got := map[string]string{}
+	for _, a := range Meta(outer) {
+		got[a.Key] = a.Value.String()
+	}
+	if len(got) != 2 || got["snippet_id"] != "abc" || got["user_id"] != "outer" {
+		t.Errorf("Meta() = %v", got)
+	}
+	if len(Meta(err)) != 1 {
+		t.Errorf("WithMeta changed the original: %v", Meta(err))
+	}
+
+	if Stack(err) != nil {
+		t.Error("a stack was captured with capture off")
+	}
+	SetCaptureStacks(true)
+	defer SetCaptureStacks(false)
+	withStack := Wrap(cause)
+	if trace := StackTrace(withStack); !strings.Contains(trace, "apperror.TestWrap") {
+		t.Errorf("StackTrace() = %q, want it to start at the caller of Wrap", trace)
+	}
+	if Stack(Wrap(NotFound("snippet", "abc"))) != nil {
+		t.Error("a stack was captured for a NotFound")
+	}
+}
+
+func TestParams(t *testing.T) {
+	err := fmt.Errorf("saving: %w", Wrap(ValidationFailed("name", "too long").WithParam("max", "100")).WithParam("max", "50"))
+	params := Params(err)
+	if params["field"] != "name" || params["max"] != "50" {
+		t.Errorf("Params() = %v, want field=name and the outer max=50", params)
+	}
+
+	base := NotFound("snippet", "abc")
+	base.WithParam("id", "changed")
+	if Params(base)["id"] != "abc" {
+		t.Error("WithParam changed the original")
+	}
+
+	var verrs ValidationErrors
+	verrs.AddCode(CodeNameTooLong, "name", "too long", "max", "100")
+	if got := verrs.Fields[0].Params; got["max"] != "100" || got["field"] != "name" {
+		t.Errorf("AddCode params = %v", got)
+	}
+}
@@ -0,0 +1,138 @@
+package apperror
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ERROR CODES:
+// Messages are for people: they get reworded, and they embed IDs. A client
+// that wants to react to "that snippet is gone" shouldn't have to match
+// "snippet not found with id ...". Every error response also carries a
+// Code — SNIPPET_NOT_FOUND, NAME_TOO_LONG, EXECUTION_TIMEOUT — that never
+// changes once released.
+//
+// THE CATALOG:
+// Codes are declared with Define, which records them in a catalog served at
+// GET /api/v1/errors, so clients can see every code they may meet. Only
+// catalogued codes ever reach a response: an AppError with an unknown code
+// is reported with its kind's generic code (NOT_FOUND, CONFLICT, ...)
+// instead, so a typo can't leak an undocumented code.
+
+// Code is a stable, machine-readable error identifier, in SCREAMING_SNAKE_CASE.
+type Code string
+
+// Entry describes a catalogued code.
+type Entry struct {
+	Code        Code
+	Kind        error // the sentinel it's a case of: ErrNotFound, ErrValidation, ... or nil
+	Description string
+}
+
+var catalog = map[Code]Entry{}
+
+// Define adds a code to the catalog and returns it. kind is the sentinel
+// error the code is a case of (nil for errors outside AppError, like a
+// malformed body); it decides the HTTP status. Define panics on a
+// duplicate, so it's for package-level vars.
+func Define(code Code, kind error, description string) Code {
+	if _, dup := catalog[code]; dup {
+		panic("apperror: code defined twice: " + string(code))
+	}
+	catalog[code] = Entry{Code: code, Kind: kind, Description: description}
+	return code
+}
+
+// Lookup returns a code's catalog entry.
+func Lookup(code Code) (Entry, bool) {
+	e, ok := catalog[code]
+	return e, ok
+}
+
+// Catalog returns every defined code, sorted.
+func Catalog() []Entry {
+	entries := make([]Entry, 0, len(catalog))
+	for _, e := range catalog {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Code < entries[j].Code })
+	return entries
+}
+
+// Generic codes, one per kind of AppError.
+var (
+	CodeNotFound    = Define("NOT_FOUND", ErrNotFound, "The resource doesn't exist, or you can't see it.")
+	CodeValidation  = Define("VALIDATION_FAILED", ErrValidation, "The request has invalid fields; see errors[] for each one.")
+	CodeConflict    = Define("CONFLICT", ErrConflict, "The request conflicts with the resource's current state.")
+	CodeForbidden   = Define("FORBIDDEN", ErrForbidden, "You're not allowed to do this.")
+	CodeUnavailable = Define("UNAVAILABLE", ErrUnavailable, "A dependency is down for now; retry after Retry-After seconds.")
+	CodeInternal    = Define("INTERNAL_ERROR", nil, "Something went wrong on the server. Quote the requestId when reporting it.")
+)
+
+// Specific codes.
+var (
+	CodeSnippetNotFound   = Define("SNIPPET_NOT_FOUND", ErrNotFound, "No snippet has that ID, or it's private.")
+	CodeUserNotFound      = Define("USER_NOT_FOUND", ErrNotFound, "No user has that ID or login.")
+	CodeOrgNotFound       = Define("ORG_NOT_FOUND", ErrNotFound, "No organization has that ID.")
+	CodeOrgMemberNotFound = Define("ORG_MEMBER_NOT_FOUND", ErrNotFound, "The user isn't a member of the organization.")
+	CodeInviteNotFound    = Define("INVITE_NOT_FOUND", ErrNotFound, "The invite doesn't exist, was used, or expired.")
+	CodeCommentNotFound   = Define("COMMENT_NOT_FOUND", ErrNotFound, "No comment has that ID.")
+	CodeTransferNotFound  = Define("TRANSFER_NOT_FOUND", ErrNotFound, "No pending transfer has that ID.")
+	CodeChallengeNotFound = Define("CHALLENGE_NOT_FOUND", ErrNotFound, "No challenge has that ID.")
+	CodeExerciseNotFound  = Define("EXERCISE_NOT_FOUND", ErrNotFound, "No exercise has that ID.")
+	CodeClassNotFound     = Define("CLASS_NOT_FOUND", ErrNotFound, "No class has that ID.")
+	CodeArtifactNotFound  = Define("ARTIFACT_NOT_FOUND", ErrNotFound, "No run left a file by that name.")
+	CodeSymbolNotFound    = Define("SYMBOL_NOT_FOUND", ErrNotFound, "No Python module or builtin has that name.")
+
+	CodeNameRequired = Define("NAME_REQUIRED", ErrValidation, "A name is required.")
+	CodeNameTooLong  = Define("NAME_TOO_LONG", ErrValidation, "The name is longer than allowed.")
+	CodeCodeTooLong  = Define("CODE_TOO_LONG", ErrValidation, "The code is longer than allowed.")
+
+	CodeExecutionTimeout     = Define("EXECUTION_TIMEOUT", nil, "The code ran past its time limit and was stopped. Reported in a run's result, not as an error response.")
+	CodeExecutionUnavailable = Define("EXECUTION_UNAVAILABLE", ErrUnavailable, "No executor can run code right now.")
+	CodeAbuseDetected        = Define("ABUSE_DETECTED", ErrForbidden, "The code looks like abuse of the sandbox and wasn't run.")
+
+	CodeInvalidBody      = Define("INVALID_BODY", nil, "The request body isn't valid JSON (or MessagePack).")
+	CodeRequestTooLarge  = Define("REQUEST_TOO_LARGE", nil, "The request body is over the size limit.")
+	CodeRouteNotFound    = Define("ROUTE_NOT_FOUND", nil, "No endpoint has that path.")
+	CodeMethodNotAllowed = Define("METHOD_NOT_ALLOWED", nil, "The endpoint doesn't accept that HTTP method.")
+	CodeUnsupportedAPI   = Define("UNSUPPORTED_VERSION", nil, "The requested API version doesn't exist.")
+	CodeUnauthorized     = Define("UNAUTHORIZED", nil, "The request isn't authenticated, or its signature is wrong.")
+	CodeRateLimited      = Define("RATE_LIMITED", nil, "Too many requests; retry after Retry-After seconds.")
+	CodeRequestTimeout   = Define("REQUEST_TIMEOUT", nil, "The request took longer than its deadline.")
+)
+
+// codeFor is the code for an error of the given kind about resource, like
+// SNIPPET_NOT_FOUND, if the catalog has it, or else the generic code.
+func codeFor(resource, suffix string, generic Code) Code {
+	code := Code(strings.ToUpper(strings.ReplaceAll(resource, " ", "_")) + "_" + suffix)
+	if _, ok := catalog[code]; ok {
+		return code
+	}
+	return generic
+}
+
+// CodeOf returns the code to report for err: its AppError's code if that's
+// catalogued, else the generic code for its kind, else INTERNAL_ERROR.
+func CodeOf(err error) Code {
+	var appErr *AppError
+	if errors.As(err, &appErr) && appErr.Code != "" {
+		if _, ok := catalog[appErr.Code]; ok {
+			return appErr.Code
+		}
+	}
+	switch {
+	case errors.Is(err, ErrValidation):
+		return CodeValidation
+	case errors.Is(err, ErrForbidden):
+		return CodeForbidden
+	case errors.Is(err, ErrNotFound):
+		return CodeNotFound
+	case errors.Is(err, ErrConflict):
+		return CodeConflict
+	case errors.Is(err, ErrUnavailable):
+		return CodeUnavailable
+	}
+	return CodeInternal
+}
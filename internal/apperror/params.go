@@ -0,0 +1,45 @@
+package apperror
+
+import "errors"
+
+// MESSAGE PARAMETERS:
+// A message like "code must be 10000 characters or less" can't be
+// translated by its code alone: the translation needs the 10000 too.
+// Params carry those values, by name, for translated messages (see
+// internal/i18n) to fill in:
+//
+//	apperror.ValidationFailed("code", fmt.Sprintf("code must be %d characters or less", max)).
+//		WithCode(apperror.CodeCodeTooLong).WithParam("max", strconv.Itoa(max))
+//
+// The constructors set the ones they know: NotFound and Conflict "resource"
+// and "id", ValidationFailed "field", Unavailable "what". Unlike metadata,
+// params end up in responses — only set what the client may see.
+
+// WithParam returns a copy of e with the message parameter key set to value.
+func (e *AppError) WithParam(key, value string) *AppError {
+	c := *e
+	c.params = make(map[string]string, len(e.params)+1)
+	for k, v := range e.params {
+		c.params[k] = v
+	}
+	c.params[key] = value
+	return &c
+}
+
+// Params returns the message parameters of every AppError in err's chain.
+// When two set the same key, the outer one wins.
+func Params(err error) map[string]string {
+	params := map[string]string{}
+	for ; err != nil; err = errors.Unwrap(err) {
+		appErr, ok := err.(*AppError)
+		if !ok {
+			continue
+		}
+		for k, v := range appErr.params {
+			if _, set := params[k]; !set {
+				params[k] = v
+			}
+		}
+	}
+	return params
+}
@@ -0,0 +1,76 @@
+package apperror
+
+import "strings"
+
+// FieldError is a single invalid field.
+type FieldError struct {
+	Field   string
+	Message string
+	Code    Code // optional, e.g. CodeNameTooLong
+	// Params fill in the message's translations (see params.go).
+	Params map[string]string
+}
+
+// ValidationErrors collects every invalid field of an input, so a client can
+// fix them all in one round trip instead of discovering them one at a time.
+//
+// Usage:
+//
+//	var verrs apperror.ValidationErrors
+//	if name == "" {
+//		verrs.Add("name", "snippet name is required")
+//	}
+//	if len(code) > max {
+//		verrs.AddCode(apperror.CodeCodeTooLong, "code", "code is too long", "max", "10000")
+//	}
+//	if err := verrs.Err(); err != nil {
+//		return nil, err
+//	}
+//
+// Err wraps the collection in an *AppError whose Message joins all the
+// messages, so code that only knows about AppError keeps working. errors.As
+// with a *ValidationErrors target gets at the individual fields.
+type ValidationErrors struct {
+	Fields []FieldError
+}
+
+// Add records an invalid field.
+func (v *ValidationErrors) Add(field, message string) {
+	v.Fields = append(v.Fields, FieldError{Field: field, Message: message})
+}
+
+// AddCode records an invalid field with a specific code, and optionally
+// message parameters as key, value pairs.
+func (v *ValidationErrors) AddCode(code Code, field, message string, params ...string) {
+	f := FieldError{Field: field, Message: message, Code: code, Params: map[string]string{"field": field}}
+	for i := 0; i+1 < len(params); i += 2 {
+		f.Params[params[i]] = params[i+1]
+	}
+	v.Fields = append(v.Fields, f)
+}
+
+// Err returns nil if nothing was added, or an *AppError wrapping v.
+func (v *ValidationErrors) Err() error {
+	if len(v.Fields) == 0 {
+		return nil
+	}
+	return &AppError{
+		Err:     v,
+		Message: v.Error(),
+		Field:   v.Fields[0].Field,
+		Code:    CodeValidation,
+	}
+}
+
+func (v *ValidationErrors) Error() string {
+	msgs := make([]string, len(v.Fields))
+	for i, f := range v.Fields {
+		msgs[i] = f.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap makes errors.Is(err, ErrValidation) true.
+func (v *ValidationErrors) Unwrap() error {
+	return ErrValidation
+}
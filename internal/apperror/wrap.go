@@ -0,0 +1,123 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// CONTEXT FOR 500s:
+// A 500 tells the client nothing on purpose: "An internal error occurred"
+// and a requestId. Whoever reads the logs for that requestId needs more —
+// which snippet, which user, which step failed. Wrap lets the code that hit
+// the error attach that context, and it goes to the logs only:
+//
+//	if err := s.repo.Update(ctx, snippet); err != nil {
+//		return apperror.Wrap(err).WithField("code").WithMeta("snippet_id", snippet.ID)
+//	}
+//
+// handler.writeError logs Meta(err) and StackTrace(err) with every 500 it
+// sends; the response body stays the generic one.
+//
+// Wrapping an error that's already an AppError keeps its kind, message and
+// code, so a NotFound stays a 404 with its message — the metadata is extra.
+//
+// STACKS:
+// Capturing a stack costs a runtime.Callers call per error, so it's off
+// until SetCaptureStacks(true) (ERROR_STACKS=true). Only internal errors
+// get one: a NotFound or a ValidationFailed is explained by its message.
+
+var captureStacks atomic.Bool
+
+// SetCaptureStacks turns stack capture in Wrap on or off.
+func SetCaptureStacks(on bool) {
+	captureStacks.Store(on)
+}
+
+// Wrap returns an *AppError wrapping err, to add a field or metadata to.
+// err must not be nil.
+func Wrap(err error) *AppError {
+	w := &AppError{Err: err}
+	var inner *AppError
+	if errors.As(err, &inner) {
+		w.Message, w.Field, w.Code = inner.Message, inner.Field, inner.Code
+		return w
+	}
+	if captureStacks.Load() {
+		pcs := make([]uintptr, 32)
+		n := runtime.Callers(2, pcs) // skip Callers and Wrap
+		w.stack = pcs[:n]
+	}
+	return w
+}
+
+// WithField returns a copy of e naming the field involved.
+func (e *AppError) WithField(field string) *AppError {
+	c := *e
+	c.Field = field
+	return &c
+}
+
+// WithMeta returns a copy of e with key=value added to its metadata. The
+// metadata is logged with the error, never sent to the client.
+func (e *AppError) WithMeta(key string, value any) *AppError {
+	c := *e
+	c.meta = append(e.meta[:len(e.meta):len(e.meta)], slog.Any(key, value))
+	return &c
+}
+
+// Meta returns the metadata of every AppError in err's chain, outermost
+// first. When two set the same key, the outer one wins.
+func Meta(err error) []slog.Attr {
+	var attrs []slog.Attr
+	seen := map[string]bool{}
+	for ; err != nil; err = errors.Unwrap(err) {
+		appErr, ok := err.(*AppError)
+		if !ok {
+			continue
+		}
+		for _, a := range appErr.meta {
+			if !seen[a.Key] {
+				seen[a.Key] = true
+				attrs = append(attrs, a)
+			}
+		}
+	}
+	return attrs
+}
+
+// Stack returns the frames captured where err was wrapped, innermost first,
+// or nil if stack capture was off.
+func Stack(err error) []runtime.Frame {
+	var pcs []uintptr
+	for ; err != nil; err = errors.Unwrap(err) {
+		if appErr, ok := err.(*AppError); ok && appErr.stack != nil {
+			pcs = appErr.stack // keep going: the deepest one is closest to the cause
+		}
+	}
+	if pcs == nil {
+		return nil
+	}
+	var out []runtime.Frame
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		out = append(out, f)
+		if !more {
+			break
+		}
+	}
+	return out
+}
+
+// StackTrace formats Stack(err) the way a panic does, or returns "".
+func StackTrace(err error) string {
+	var b strings.Builder
+	for _, f := range Stack(err) {
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+	}
+	return b.String()
+}
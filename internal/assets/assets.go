@@ -0,0 +1,142 @@
+// Package assets fingerprints static files for long-lived browser caching.
+//
+// THE CACHING DILEMMA:
+// We want browsers to cache CSS and JS for a long time (fast repeat visits),
+// but also to pick up a new version the moment we deploy one. Both are
+// possible if the URL changes whenever the content changes:
+//
+//	/static/css/style.css               → no-cache (always revalidate)
+//	/static/css/style.3f2a9c1b7d.css    → cache for a year, "immutable"
+//
+// The hash is the first 10 hex characters of the file's SHA-256. Templates ask
+// for the logical name with {{asset "css/style.css"}} and get the hashed URL;
+// a deploy that changes style.css changes the hash, so the page (which is
+// itself served no-cache) points at a URL the browser has never seen.
+//
+// There's no build step: the manifest is computed by hashing the static
+// directory at startup. In dev mode hashing is skipped — every asset is served
+// no-cache under its plain name, so edits show up on refresh.
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// URLPrefix is where the static directory is mounted.
+const URLPrefix = "/static/"
+
+// Cache-Control values for fingerprinted and plain assets.
+const (
+	cacheImmutable = "public, max-age=31536000, immutable"
+	cacheNone      = "no-cache"
+)
+
+// Manifest maps logical asset names to content-hashed names and serves both.
+type Manifest struct {
+	dir    string
+	hashed map[string]string // "css/style.css" → "css/style.3f2a9c1b7d.css"
+	source map[string]string // the reverse
+}
+
+// NewManifest hashes every file under dir. With dev set, no hashing is done
+// and Path returns plain URLs.
+func NewManifest(dir string, dev bool) (*Manifest, error) {
+	m := &Manifest{
+		dir:    dir,
+		hashed: make(map[string]string),
+		source: make(map[string]string),
+	}
+	if dev {
+		return m, nil
+	}
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		sum, err := hashFile(p)
+		if err != nil {
+			return err
+		}
+
+		hashedName := withHash(name, sum)
+		m.hashed[name] = hashedName
+		m.source[hashedName] = name
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("assets: hashing %s: %w", dir, err)
+	}
+
+	return m, nil
+}
+
+// Path returns the URL for a logical asset name such as "css/style.css".
+// Unknown names (and every name in dev mode) get the plain, uncached URL.
+func (m *Manifest) Path(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if hashed, ok := m.hashed[name]; ok {
+		return URLPrefix + hashed
+	}
+	return URLPrefix + name
+}
+
+// Handler serves the static directory. Mount it with the URL prefix stripped.
+// Fingerprinted names are cached forever; everything else must revalidate.
+func (m *Manifest) Handler() http.Handler {
+	files := http.FileServer(http.Dir(m.dir))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+
+		source, ok := m.source[name]
+		if !ok {
+			w.Header().Set("Cache-Control", cacheNone)
+			files.ServeHTTP(w, r)
+			return
+		}
+
+		// Serve the real file under its original name.
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = "/" + source
+		w.Header().Set("Cache-Control", cacheImmutable)
+		files.ServeHTTP(w, r2)
+	})
+}
+
+// withHash inserts a hash before the file extension: "js/app.js" → "js/app.<hash>.js".
+func withHash(name, hash string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
+
+// hashFile returns the first 10 hex characters of the file's SHA-256.
+func hashFile(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil))[:10], nil
+}
@@ -0,0 +1,89 @@
+package assets
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	p := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func serve(h http.Handler, path string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+	return rr
+}
+
+func TestManifest_HashedPathsAreImmutable(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "css/style.css", "body{}")
+
+	m, err := NewManifest(dir, false)
+	if err != nil {
+		t.Fatalf("NewManifest() error = %v", err)
+	}
+
+	url := m.Path("css/style.css")
+	if !regexp.MustCompile(`^/static/css/style\.[0-9a-f]{10}\.css$`).MatchString(url) {
+		t.Fatalf("Path() = %q, want a fingerprinted URL", url)
+	}
+
+	h := m.Handler()
+
+	rr := serve(h, url[len(URLPrefix)-1:])
+	if rr.Code != http.StatusOK || rr.Body.String() != "body{}" {
+		t.Fatalf("hashed asset: status %d body %q", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Cache-Control"); got != cacheImmutable {
+		t.Errorf("hashed asset Cache-Control = %q, want %q", got, cacheImmutable)
+	}
+
+	rr = serve(h, "/css/style.css")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("plain asset: status %d", rr.Code)
+	}
+	if got := rr.Header().Get("Cache-Control"); got != cacheNone {
+		t.Errorf("plain asset Cache-Control = %q, want %q", got, cacheNone)
+	}
+}
+
+func TestManifest_HashChangesWithContent(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "js/app.js", "v1")
+	m1, _ := NewManifest(dir, false)
+
+	writeFile(t, dir, "js/app.js", "v2")
+	m2, _ := NewManifest(dir, false)
+
+	if m1.Path("js/app.js") == m2.Path("js/app.js") {
+		t.Error("hash did not change with content")
+	}
+}
+
+func TestManifest_DevModeUsesPlainPaths(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "js/app.js", "v1")
+
+	m, err := NewManifest(dir, true)
+	if err != nil {
+		t.Fatalf("NewManifest() error = %v", err)
+	}
+	if got := m.Path("js/app.js"); got != "/static/js/app.js" {
+		t.Errorf("Path() = %q, want plain URL in dev mode", got)
+	}
+	if got := m.Path("missing.js"); got != "/static/missing.js" {
+		t.Errorf("Path(unknown) = %q", got)
+	}
+}
@@ -3,8 +3,11 @@
 package auth
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -15,6 +18,10 @@ const (
 	DefaultTokenDuration = 1 * time.Hour // access tokens expire after 1 hour
 )
 
+// MinSecretLength is the shortest JWT secret accepted: 32 bytes, the size of
+// an HMAC-SHA256 key.
+const MinSecretLength = 32
+
 // Custom claims embedded in every JWT.
 type Claims struct {
 	jwt.RegisteredClaims
@@ -27,17 +34,112 @@ type Claims struct {
 // - Uses HMAC-SHA256 (symmetric) — the same secret signs and verifies.
 // - Tokens are stored in HttpOnly cookies, not localStorage (XSS safe).
 // - 1-hour expiry with no refresh token — user simply re-authenticates.
+//
+// KEY ROTATION:
+// Tokens are signed with the current secret, and name it in their "kid"
+// header (a hash of it, not the secret). Validate also accepts tokens
+// signed with the previous secrets, so the secret can be changed without
+// signing everyone out:
+//
+//	SetSecrets(new, old)  sign with new, still accept old — for a fleet,
+//	                      until every server has new
+//	SetSecrets(new)       old is retired: its tokens are accepted until
+//	                      they expire, and no others
+//
+// A retired secret stops working when the last token signed with it runs
+// out (an hour, for Generate), rather than at once or never: a token forged
+// with a leaked old secret is rejected from then on, whatever expiry it
+// claims. Tokens from before key IDs, with no kid, are checked against
+// every secret still accepted.
 type TokenService struct {
+	mu   sync.RWMutex
+	keys []*signingKey // the current one first
+}
+
+// signingKey is a secret tokens are signed or accepted with.
+type signingKey struct {
+	id     string // the kid header of tokens it signs
 	secret []byte
+	// lastExpiry is when the last token signed with it expires (as far as
+	// this process knows). retireAt is when it stops being accepted: zero
+	// while it's configured, lastExpiry once it's been removed.
+	lastExpiry time.Time
+	retireAt   time.Time
 }
 
-// NewTokenService creates a TokenService. The secret must be at least 32 bytes
-// for HMAC-SHA256 security.
-func NewTokenService(secret string) (*TokenService, error) {
-	if len(secret) < 32 {
-		return nil, errors.New("auth: JWT secret must be at least 32 characters")
+// NewTokenService creates a TokenService signing with secret and also
+// accepting tokens signed with previous. Every secret must be at least
+// MinSecretLength bytes for HMAC-SHA256 security.
+func NewTokenService(secret string, previous ...string) (*TokenService, error) {
+	ts := &TokenService{}
+	if err := ts.SetSecrets(secret, previous...); err != nil {
+		return nil, err
 	}
-	return &TokenService{secret: []byte(secret)}, nil
+	return ts, nil
+}
+
+// SetSecrets changes the secrets while tokens are being issued: secret
+// signs from now on, and tokens signed with it or with previous are
+// accepted. A secret that was in use but is in neither is retired (see
+// KEY ROTATION). If any secret is too short, nothing changes.
+func (ts *TokenService) SetSecrets(secret string, previous ...string) error {
+	secrets := append([]string{secret}, previous...)
+	for _, s := range secrets {
+		if len(s) < MinSecretLength {
+			return errors.New("auth: JWT secret must be at least 32 characters")
+		}
+	}
+
+	now := time.Now()
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	configured := map[string]bool{}
+	var keys []*signingKey
+	for _, s := range secrets {
+		id := keyID(s)
+		if configured[id] {
+			continue
+		}
+		configured[id] = true
+		k := ts.key(id)
+		if k == nil {
+			// Tokens signed with it by an earlier run of the server may
+			// still be around.
+			k = &signingKey{id: id, secret: []byte(s), lastExpiry: now.Add(DefaultTokenDuration)}
+		}
+		k.retireAt = time.Time{}
+		keys = append(keys, k)
+	}
+	for _, k := range ts.keys {
+		if configured[k.id] {
+			continue
+		}
+		if k.retireAt.IsZero() {
+			k.retireAt = k.lastExpiry
+		}
+		if now.Before(k.retireAt) {
+			keys = append(keys, k)
+		}
+	}
+	ts.keys = keys
+	return nil
+}
+
+// key returns the key with id, or nil. Callers hold ts.mu.
+func (ts *TokenService) key(id string) *signingKey {
+	for _, k := range ts.keys {
+		if k.id == id {
+			return k
+		}
+	}
+	return nil
+}
+
+// keyID names secret in the tokens it signs: the start of its SHA-256,
+// which says which secret it is without saying anything about it.
+func keyID(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:8])
 }
 
 // Generate creates a signed JWT for the given user ID with the default 1-hour expiry.
@@ -57,8 +159,16 @@ func (ts *TokenService) GenerateWithDuration(userID string, duration time.Durati
 		UserID: userID,
 	}
 
+	ts.mu.Lock()
+	key := ts.keys[0]
+	if exp := claims.ExpiresAt.Time; exp.After(key.lastExpiry) {
+		key.lastExpiry = exp
+	}
+	ts.mu.Unlock()
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(ts.secret)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.secret)
 }
 
 // Validate parses and validates a JWT string. Returns the claims if valid,
@@ -69,7 +179,7 @@ func (ts *TokenService) Validate(tokenStr string) (*Claims, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("auth: unexpected signing method: %v", t.Header["alg"])
 		}
-		return ts.secret, nil
+		return ts.verificationKeys(t)
 	})
 	if err != nil {
 		return nil, fmt.Errorf("auth: invalid token: %w", err)
@@ -82,3 +192,27 @@ func (ts *TokenService) Validate(tokenStr string) (*Claims, error) {
 
 	return claims, nil
 }
+
+// verificationKeys returns the secret t may have been signed with: the one
+// its kid names, or for a token without one, every secret accepted.
+func (ts *TokenService) verificationKeys(t *jwt.Token) (interface{}, error) {
+	now := time.Now()
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	accepted := func(k *signingKey) bool {
+		return k.retireAt.IsZero() || now.Before(k.retireAt)
+	}
+	if kid, ok := t.Header["kid"].(string); ok {
+		if k := ts.key(kid); k != nil && accepted(k) {
+			return k.secret, nil
+		}
+		return nil, errors.New("auth: token signed with an unknown or retired key")
+	}
+	var set jwt.VerificationKeySet
+	for _, k := range ts.keys {
+		if accepted(k) {
+			set.Keys = append(set.Keys, k.secret)
+		}
+	}
+	return set, nil
+}
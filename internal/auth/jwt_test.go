@@ -3,6 +3,8 @@ package auth
 import (
 	"testing"
 	"time"
+
+	"github.com/golang-jwt/jwt/v5"
 )
 
 const testSecret = "this-is-a-test-secret-for-jwt-testing-32ch"
@@ -89,3 +91,81 @@ func TestTokenService_ShortSecret(t *testing.T) {
 		t.Error("NewTokenService: expected error for short secret, got nil")
 	}
 }
+
+func TestTokenService_Rotation(t *testing.T) {
+	const newSecret = "the-new-secret-that-is-also-at-least-32-chars"
+	ts, err := NewTokenService(testSecret)
+	if err != nil {
+		t.Fatalf("NewTokenService: %v", err)
+	}
+	oldToken, err := ts.Generate("user-123")
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	// Step one, for a fleet: the new secret is accepted but doesn't sign yet.
+	if err := ts.SetSecrets(testSecret, newSecret); err != nil {
+		t.Fatalf("SetSecrets: %v", err)
+	}
+	other, _ := NewTokenService(newSecret)
+	fromOther, _ := other.Generate("user-456")
+	if _, err := ts.Validate(fromOther); err != nil {
+		t.Errorf("a token signed with the new secret elsewhere: %v", err)
+	}
+
+	// Step two: sign with the new secret. The old one is retired, but its
+	// tokens stay valid until they expire.
+	if err := ts.SetSecrets(newSecret); err != nil {
+		t.Fatalf("SetSecrets: %v", err)
+	}
+	if _, err := ts.Validate(oldToken); err != nil {
+		t.Errorf("a session from before the rotation was signed out: %v", err)
+	}
+	newToken, _ := ts.Generate("user-123")
+	if _, err := other.Validate(newToken); err != nil {
+		t.Errorf("a new token isn't signed with the new secret: %v", err)
+	}
+
+	// Once they have, the old secret is no use, even for a token claiming a
+	// later expiry.
+	ts.mu.Lock()
+	for _, k := range ts.keys {
+		if k.id == keyID(testSecret) {
+			k.retireAt = time.Now().Add(-time.Second)
+		}
+	}
+	ts.mu.Unlock()
+	forger, _ := NewTokenService(testSecret)
+	forged, _ := forger.GenerateWithDuration("admin-id", 24*time.Hour)
+	if _, err := ts.Validate(forged); err == nil {
+		t.Error("a token signed with a retired secret was accepted")
+	}
+
+	if err := ts.SetSecrets(newSecret, "short"); err == nil {
+		t.Error("SetSecrets accepted a short previous secret")
+	}
+	if _, err := ts.Validate(newToken); err != nil {
+		t.Errorf("a failed SetSecrets changed the secrets: %v", err)
+	}
+}
+
+func TestTokenService_TokensWithoutKeyID(t *testing.T) {
+	// Tokens issued before key IDs have no kid header.
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+		UserID:           "user-123",
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts, _ := NewTokenService("the-new-secret-that-is-also-at-least-32-chars", testSecret)
+	if got, err := ts.Validate(token); err != nil || got.UserID != "user-123" {
+		t.Errorf("Validate() = %v, %v; want the token accepted with a previous secret", got, err)
+	}
+	other, _ := NewTokenService("yet-another-secret-that-is-32-chars-long")
+	if _, err := other.Validate(token); err == nil {
+		t.Error("a token without a kid was accepted by the wrong secret")
+	}
+}
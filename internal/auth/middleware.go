@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"net/http"
+	"strings"
 )
 
 // contextKey is an unexported type to prevent collisions in context values.
@@ -13,18 +14,45 @@ const userIDKey contextKey = "userID"
 // CookieName is the name of the HttpOnly cookie that holds the JWT.
 const CookieName = "pyplayground_token"
 
-// RequireAuth is middleware that rejects requests without a valid JWT cookie.
-// Returns 401 Unauthorized if the token is missing or invalid.
+// TOKENS FROM COOKIES OR HEADERS:
+// Browsers send the JWT in the CookieName cookie, set at sign-in. Programs
+// (the Go client in pkg/client, scripts, CI jobs) can't easily keep cookies,
+// so they send the same token in a header instead:
+//
+//	Authorization: Bearer eyJhbGciOi...
+//
+// A signed-in user gets such a token from POST /api/v1/me/token. The header
+// wins when both are present.
+
+// tokenFromRequest returns the JWT from the Authorization header or the cookie.
+func tokenFromRequest(r *http.Request) (string, bool) {
+	if h := r.Header.Get("Authorization"); h != "" {
+		scheme, token, ok := strings.Cut(h, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") && token != "" {
+			return strings.TrimSpace(token), true
+		}
+		return "", false
+	}
+	cookie, err := r.Cookie(CookieName)
+	if err != nil {
+		return "", false
+	}
+	return cookie.Value, true
+}
+
+// RequireAuth is middleware that rejects requests without a valid JWT, sent
+// as a cookie or a bearer token. Returns 401 Unauthorized if the token is
+// missing or invalid.
 func RequireAuth(ts *TokenService) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			cookie, err := r.Cookie(CookieName)
-			if err != nil {
+			token, ok := tokenFromRequest(r)
+			if !ok {
 				http.Error(w, `{"error":"authentication required"}`, http.StatusUnauthorized)
 				return
 			}
 
-			claims, err := ts.Validate(cookie.Value)
+			claims, err := ts.Validate(token)
 			if err != nil {
 				http.Error(w, `{"error":"invalid or expired token"}`, http.StatusUnauthorized)
 				return
@@ -38,14 +66,13 @@ func RequireAuth(ts *TokenService) func(http.Handler) http.Handler {
 }
 
 // OptionalAuth is middleware that injects the user ID into the context
-// if a valid JWT cookie is present, but does NOT reject the request otherwise.
+// if a valid JWT is present, but does NOT reject the request otherwise.
 // Use this on routes that work for both anonymous and authenticated users.
 func OptionalAuth(ts *TokenService) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			cookie, err := r.Cookie(CookieName)
-			if err == nil {
-				if claims, err := ts.Validate(cookie.Value); err == nil {
+			if token, ok := tokenFromRequest(r); ok {
+				if claims, err := ts.Validate(token); err == nil {
 					ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
 					r = r.WithContext(ctx)
 				}
@@ -61,3 +88,91 @@ func UserIDFromContext(ctx context.Context) (string, bool) {
 	uid, ok := ctx.Value(userIDKey).(string)
 	return uid, ok
 }
+
+// WithUserID returns a copy of ctx carrying userID, as RequireAuth and
+// OptionalAuth do for a signed-in request. Code acting for a user outside an
+// HTTP request (and tests) can use it.
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+// RoleLookup returns the role of a user. The user repository satisfies it via
+// a small adapter in the server package, keeping auth free of storage imports.
+type RoleLookup func(ctx context.Context, userID string) (string, error)
+
+// RequireRole is middleware that only admits users with the given role.
+// It must run AFTER RequireAuth, which puts the user ID into the context.
+//
+// WHY LOOK THE ROLE UP PER REQUEST?
+// The JWT only carries the user ID. Reading the role from the database means a
+// demoted admin loses access immediately instead of when their token expires.
+func RequireRole(lookup RoleLookup, role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			uid, ok := UserIDFromContext(r.Context())
+			if !ok {
+				http.Error(w, `{"error":"authentication required"}`, http.StatusUnauthorized)
+				return
+			}
+
+			got, err := lookup(r.Context(), uid)
+			if err != nil || got != role {
+				http.Error(w, `{"error":"forbidden"}`, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SuspensionLookup reports whether a user is suspended. The server backs it
+// with a short-lived cache, so it's cheap to call on every request.
+type SuspensionLookup func(ctx context.Context, userID string) (bool, error)
+
+// RejectSuspended is middleware that keeps suspended users read-only: they
+// can still sign in, look at and export their data (GET, HEAD, OPTIONS), but
+// anything that changes something or runs code gets 403 Forbidden. readOnly,
+// if not nil, lets through other requests that only read, such as GraphQL
+// queries sent with POST.
+//
+// WHY NOT AFTER RequireAuth?
+// Routes pick RequireAuth or OptionalAuth one by one, so this middleware
+// reads the token itself and can wrap a whole router. It doesn't put the
+// user ID into the context: routes that don't ask for auth stay anonymous.
+// Requests without a valid token aren't its business and pass through.
+func RejectSuspended(ts *TokenService, lookup SuspensionLookup, readOnly func(*http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+			if readOnly != nil && readOnly(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			token, ok := tokenFromRequest(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			claims, err := ts.Validate(token)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			suspended, err := lookup(r.Context(), claims.UserID)
+			if err != nil {
+				http.Error(w, `{"error":"internal server error"}`, http.StatusInternalServerError)
+				return
+			}
+			if suspended {
+				http.Error(w, `{"error":"your account is suspended: you can view and export your data, but not change or run anything"}`, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
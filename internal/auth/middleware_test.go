@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAuth_TokenSources(t *testing.T) {
+	ts, err := NewTokenService(testSecret)
+	if err != nil {
+		t.Fatalf("NewTokenService: %v", err)
+	}
+	token, err := ts.Generate("user-123")
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	handler := RequireAuth(ts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uid, _ := UserIDFromContext(r.Context())
+		w.Write([]byte(uid))
+	}))
+
+	tests := []struct {
+		name     string
+		header   string
+		cookie   string
+		wantCode int
+	}{
+		{"cookie", "", token, http.StatusOK},
+		{"bearer header", "Bearer " + token, "", http.StatusOK},
+		{"scheme is case-insensitive", "bearer " + token, "", http.StatusOK},
+		{"header wins over cookie", "Bearer " + token, "garbage", http.StatusOK},
+		{"bad header isn't rescued by cookie", "Bearer garbage", token, http.StatusUnauthorized},
+		{"other scheme", "Basic dXNlcjpwYXNz", "", http.StatusUnauthorized},
+		{"nothing", "", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: CookieName, Value: tt.cookie})
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", rr.Code, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusOK && rr.Body.String() != "user-123" {
+				t.Errorf("user ID = %q, want user-123", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestRejectSuspended(t *testing.T) {
+	ts, err := NewTokenService(testSecret)
+	if err != nil {
+		t.Fatalf("NewTokenService: %v", err)
+	}
+	suspended, _ := ts.Generate("suspended-id")
+	active, _ := ts.Generate("active-id")
+
+	lookup := func(_ context.Context, userID string) (bool, error) {
+		return userID == "suspended-id", nil
+	}
+	readOnly := func(r *http.Request) bool { return r.URL.Path == "/graphql" }
+	handler := RejectSuspended(ts, lookup, readOnly)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := UserIDFromContext(r.Context()); ok {
+			t.Error("RejectSuspended put a user ID into the context")
+		}
+	}))
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		token    string
+		wantCode int
+	}{
+		{"suspended read", http.MethodGet, "/snippets", suspended, http.StatusOK},
+		{"suspended write", http.MethodPost, "/snippets", suspended, http.StatusForbidden},
+		{"suspended delete", http.MethodDelete, "/snippets/1", suspended, http.StatusForbidden},
+		{"suspended read-only POST", http.MethodPost, "/graphql", suspended, http.StatusOK},
+		{"active write", http.MethodPost, "/snippets", active, http.StatusOK},
+		{"anonymous write", http.MethodPost, "/snippets", "", http.StatusOK},
+		{"bad token", http.MethodPost, "/snippets", "garbage", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rr.Code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sync/atomic"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/github"
@@ -27,31 +28,43 @@ type GitHubUser struct {
 //  2. User authorizes → GitHub redirects back with ?code=...&state=...
 //  3. Exchange(code) → swap the code for an access token
 //  4. GetUser(token) → call GitHub API to fetch user profile
+//
+// The credentials can be changed while it's in use (SetCredentials). The
+// config is replaced whole rather than edited, so no call mixes an old ID
+// with a new secret.
 type GitHubProvider struct {
-	config *oauth2.Config
+	config atomic.Pointer[oauth2.Config]
 }
 
 // NewGitHubProvider creates a GitHubProvider with the given credentials.
 func NewGitHubProvider(clientID, clientSecret, callbackURL string) *GitHubProvider {
-	return &GitHubProvider{
-		config: &oauth2.Config{
-			ClientID:     clientID,
-			ClientSecret: clientSecret,
-			RedirectURL:  callbackURL,
-			Scopes:       []string{"read:user", "user:email"},
-			Endpoint:     github.Endpoint,
-		},
-	}
+	p := &GitHubProvider{}
+	p.config.Store(&oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  callbackURL,
+		Scopes:       []string{"read:user", "user:email"},
+		Endpoint:     github.Endpoint,
+	})
+	return p
+}
+
+// SetCredentials replaces the OAuth app's client ID and secret, as when
+// the secret is rotated in GitHub's settings.
+func (p *GitHubProvider) SetCredentials(clientID, clientSecret string) {
+	config := *p.config.Load()
+	config.ClientID, config.ClientSecret = clientID, clientSecret
+	p.config.Store(&config)
 }
 
 // AuthURL generates the GitHub authorization URL with the given CSRF state.
 func (p *GitHubProvider) AuthURL(state string) string {
-	return p.config.AuthCodeURL(state)
+	return p.config.Load().AuthCodeURL(state)
 }
 
 // Exchange swaps an authorization code for an OAuth2 token.
 func (p *GitHubProvider) Exchange(ctx context.Context, code string) (*oauth2.Token, error) {
-	token, err := p.config.Exchange(ctx, code)
+	token, err := p.config.Load().Exchange(ctx, code)
 	if err != nil {
 		return nil, fmt.Errorf("auth: github code exchange failed: %w", err)
 	}
@@ -60,7 +73,7 @@ func (p *GitHubProvider) Exchange(ctx context.Context, code string) (*oauth2.Tok
 
 // GetUser fetches the authenticated user's profile from the GitHub API.
 func (p *GitHubProvider) GetUser(ctx context.Context, token *oauth2.Token) (*GitHubUser, error) {
-	client := p.config.Client(ctx, token)
+	client := p.config.Load().Client(ctx, token)
 
 	resp, err := client.Get("https://api.github.com/user")
 	if err != nil {
@@ -0,0 +1,108 @@
+// Package blob keeps large binary objects — the files a run leaves behind,
+// like plots — outside the database.
+//
+// WHY NOT SQLITE:
+// SQLite copes with blobs, but every megabyte stored there is a megabyte in
+// the database file, its backups and its WAL, and a page that reads one
+// holds the database while it does. Objects are written once, read whole
+// and never queried, which is what object storage is for.
+//
+// BACKENDS:
+// A Store is either a directory on disk (FS), fine for a single server, or
+// an S3-compatible bucket (S3) — AWS, MinIO, Cloudflare R2, … — which every
+// server sharing the database can reach. Open picks one from Config.
+//
+// KEYS:
+// Keys are slash-separated paths, like "artifacts/<run>/plot.png", made of
+// letters, digits, '.', '-' and '_'. A key's extension decides the
+// Content-Type it's served with.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strings"
+	"time"
+)
+
+// Store keeps objects by key.
+type Store interface {
+	// Put stores r's contents under key, replacing anything there. size is
+	// the number of bytes r holds, or -1 if that isn't known up front.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Get opens the object under key, or returns ErrNotFound. The caller
+	// closes it.
+	Get(ctx context.Context, key string) (io.ReadCloser, Info, error)
+	// Delete removes the object under key. Deleting a missing one is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Info describes a stored object.
+type Info struct {
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+var (
+	// ErrNotFound means there's no object under the key.
+	ErrNotFound = errors.New("blob: not found")
+	// ErrInvalidKey means a key isn't a clean relative path (see checkKey).
+	ErrInvalidKey = errors.New("blob: invalid key")
+)
+
+// Backend names for Config.
+const (
+	BackendFS = "fs"
+	BackendS3 = "s3"
+)
+
+// Config chooses and configures a Store.
+type Config struct {
+	Backend string // BackendFS or BackendS3
+	Dir     string // BackendFS: the directory objects are kept in
+	S3      S3Config
+}
+
+// Open creates the Store cfg describes.
+func Open(cfg Config) (Store, error) {
+	switch cfg.Backend {
+	case BackendFS:
+		return NewFS(cfg.Dir)
+	case BackendS3:
+		return NewS3(cfg.S3)
+	}
+	return nil, fmt.Errorf("blob: unknown backend %q (want %q or %q)", cfg.Backend, BackendFS, BackendS3)
+}
+
+// checkKey rejects keys that could escape a directory or an S3 prefix:
+// empty segments, "." and "..", and anything outside [A-Za-z0-9._-/].
+func checkKey(key string) error {
+	if key == "" || len(key) > 1024 {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
+		for _, r := range segment {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_') {
+				return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+			}
+		}
+	}
+	return nil
+}
+
+// contentType is the Content-Type for key, from its extension.
+func contentType(key string) string {
+	if t := mime.TypeByExtension(path.Ext(key)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,35 @@
+// Package cache keeps hot data out of the database: a Store of byte values
+// with a time to live, in memory or in Redis, and a SnippetRepository that
+// reads public snippets through it.
+//
+// WHY CACHE PUBLIC SNIPPETS?
+// A snippet linked from social media can get thousands of views a minute,
+// every one of them the same SELECT on the same row. SQLite copes, but it
+// serializes writers behind those readers, and with several app servers
+// each would do the work. Public snippets are the safe thing to cache: the
+// same for every viewer, and changed only through the app.
+//
+// WHICH STORE?
+//
+//	Memory  one process; nothing to run, gone on restart (default)
+//	Redis   shared by every app server, so a write on one invalidates all
+//
+// Either way the cache is an optimization: if it fails, reads go to the
+// database and the error is logged.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Store holds values by key for up to a time to live.
+type Store interface {
+	// Get returns the value under key; ok is false if there's none (or it
+	// expired).
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Set stores value under key for ttl, or until evicted if ttl is 0.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes keys; missing ones are ignored.
+	Delete(ctx context.Context, keys ...string) error
+}
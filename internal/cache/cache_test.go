@@ -0,0 +1,202 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/redis/redistest"
+	"github.com/sakif/coding-playground/internal/repository"
+	"github.com/sakif/coding-playground/internal/repository/sqlite"
+)
+
+// testStore checks the behaviour every Store shares.
+func testStore(t *testing.T, store Store) {
+	ctx := context.Background()
+	if _, ok, err := store.Get(ctx, "missing"); err != nil || ok {
+		t.Errorf("Get(missing) = ok %v, %v; want a miss", ok, err)
+	}
+	if err := store.Set(ctx, "a", []byte("1"), time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := store.Set(ctx, "b", []byte("2"), 0); err != nil {
+		t.Fatalf("Set(no TTL) error = %v", err)
+	}
+	if v, ok, err := store.Get(ctx, "a"); err != nil || !ok || string(v) != "1" {
+		t.Errorf("Get(a) = %q, %v, %v; want 1", v, ok, err)
+	}
+	if err := store.Delete(ctx, "a", "b", "missing"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, ok, _ := store.Get(ctx, "b"); ok {
+		t.Error("Get(b) after Delete: still there")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testStore(t, NewMemory(0))
+
+	t.Run("expiry", func(t *testing.T) {
+		m := NewMemory(0)
+		now := time.Now()
+		m.now = func() time.Time { return now }
+		ctx := context.Background()
+		m.Set(ctx, "k", []byte("v"), time.Second)
+		now = now.Add(time.Second)
+		if _, ok, _ := m.Get(ctx, "k"); ok {
+			t.Error("Get() after the TTL: still there")
+		}
+	})
+
+	t.Run("evicts the least recently used", func(t *testing.T) {
+		m := NewMemory(2)
+		ctx := context.Background()
+		m.Set(ctx, "a", []byte("1"), 0)
+		m.Set(ctx, "b", []byte("2"), 0)
+		m.Get(ctx, "a") // now b is the least recently used
+		m.Set(ctx, "c", []byte("3"), 0)
+		if _, ok, _ := m.Get(ctx, "b"); ok {
+			t.Error("b wasn't evicted")
+		}
+		if _, ok, _ := m.Get(ctx, "a"); !ok {
+			t.Error("a was evicted, but it was used more recently than b")
+		}
+		if m.Len() != 2 {
+			t.Errorf("Len() = %d, want 2", m.Len())
+		}
+	})
+}
+
+func TestRedis(t *testing.T) {
+	srv := redistest.NewServer(t)
+	store := NewRedis(srv.Client(t), "test:")
+	testStore(t, store)
+
+	// Keys are prefixed.
+	ctx := context.Background()
+	store.Set(ctx, "k", []byte("v"), time.Minute)
+	other := NewRedis(srv.Client(t), "")
+	if v, ok, _ := other.Get(ctx, "test:k"); !ok || string(v) != "v" {
+		t.Errorf("Get(test:k) without a prefix = %q, %v; want the prefixed key", v, ok)
+	}
+}
+
+// countingRepo counts reads that reach the database.
+type countingRepo struct {
+	repository.SnippetRepository
+	gets, lists int
+}
+
+func (c *countingRepo) GetByID(ctx context.Context, id string) (*model.Snippet, error) {
+	c.gets++
+	return c.SnippetRepository.GetByID(ctx, id)
+}
+
+func (c *countingRepo) List(ctx context.Context, opts repository.ListOptions) ([]model.Snippet, error) {
+	c.lists++
+	return c.SnippetRepository.List(ctx, opts)
+}
+
+func (c *countingRepo) ListPublic(ctx context.Context, userID string, limit int) ([]model.Snippet, error) {
+	c.lists++
+	return c.SnippetRepository.ListPublic(ctx, userID, limit)
+}
+
+func TestSnippetRepository(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	srv := redistest.NewServer(t)
+	stores := map[string]Store{
+		"memory": NewMemory(0),
+		"redis":  NewRedis(srv.Client(t), "test:"),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			counted := &countingRepo{SnippetRepository: db}
+			repo := NewSnippetRepository(counted, store, time.Minute, logger)
+			changes := 0
+			repo.OnChange(func(context.Context) { changes++ })
+
+			public := &model.Snippet{Name: "hello-" + name, Code: "print('hi')"}
+			private := &model.Snippet{Name: "secret-" + name}
+			for _, s := range []*model.Snippet{public, private} {
+				if err := repo.Create(ctx, s); err != nil {
+					t.Fatalf("Create() error = %v", err)
+				}
+			}
+			now := time.Now().UTC()
+			public.Public, public.PublishedAt = true, &now
+			if err := repo.SetPublic(ctx, public); err != nil {
+				t.Fatalf("SetPublic() error = %v", err)
+			}
+
+			// Public snippets are read from the database once.
+			for range 3 {
+				got, err := repo.GetByID(ctx, public.ID)
+				if err != nil || got.Code != "print('hi')" {
+					t.Fatalf("GetByID(public) = %+v, %v", got, err)
+				}
+			}
+			if counted.gets != 1 {
+				t.Errorf("public snippet read from the database %d times, want 1", counted.gets)
+			}
+			// Private ones every time.
+			counted.gets = 0
+			repo.GetByID(ctx, private.ID)
+			repo.GetByID(ctx, private.ID)
+			if counted.gets != 2 {
+				t.Errorf("private snippet read from the database %d times, want 2", counted.gets)
+			}
+
+			// An update is seen straight away.
+			public.Code = "print('bye')"
+			if err := repo.Update(ctx, public); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if got, _ := repo.GetByID(ctx, public.ID); got.Code != "print('bye')" {
+				t.Errorf("GetByID() after Update = %q, want the new code", got.Code)
+			}
+
+			// Lists of public snippets are cached until a write.
+			counted.lists = 0
+			opts := repository.ListOptions{PublicOnly: true, Limit: 10}
+			for range 2 {
+				if list, err := repo.List(ctx, opts); err != nil || len(list) == 0 {
+					t.Fatalf("List(public) = %v, %v", list, err)
+				}
+				repo.ListPublic(ctx, "", 10)
+			}
+			repo.List(ctx, repository.ListOptions{Limit: 10}) // not public: not cached
+			if counted.lists != 3 {
+				t.Errorf("lists read from the database %d times, want 3", counted.lists)
+			}
+
+			if err := repo.Delete(ctx, public.ID); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			// SetPublic, Update and Delete each tell the hooks.
+			if changes != 3 {
+				t.Errorf("OnChange hooks called %d times, want 3", changes)
+			}
+			if _, err := repo.GetByID(ctx, public.ID); err == nil {
+				t.Error("GetByID() after Delete: still found")
+			}
+			list, _ := repo.ListPublic(ctx, "", 10)
+			for _, s := range list {
+				if s.ID == public.ID {
+					t.Error("ListPublic() after Delete still has the snippet")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory is a Store in this process's memory. When it holds maxEntries
+// values, setting another evicts the least recently used.
+type Memory struct {
+	max int
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // values are *memoryEntry
+	lru     *list.List               // most recently used at the front
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time // zero: never
+}
+
+var _ Store = (*Memory)(nil)
+
+// NewMemory creates a Memory store holding at most maxEntries values
+// (default 10,000).
+func NewMemory(maxEntries int) *Memory {
+	if maxEntries <= 0 {
+		maxEntries = 10_000
+	}
+	return &Memory{
+		max:     maxEntries,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Get returns a copy of the value under key.
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := el.Value.(*memoryEntry)
+	if !e.expires.IsZero() && !m.now().Before(e.expires) {
+		m.remove(el)
+		return nil, false, nil
+	}
+	m.lru.MoveToFront(el)
+	return append([]byte(nil), e.value...), true, nil
+}
+
+// Set stores a copy of value, so the caller may reuse it.
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	e := &memoryEntry{key: key, value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		e.expires = m.now().Add(ttl)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.entries[key]; ok {
+		el.Value = e
+		m.lru.MoveToFront(el)
+		return nil
+	}
+	m.entries[key] = m.lru.PushFront(e)
+	for m.lru.Len() > m.max {
+		m.remove(m.lru.Back())
+	}
+	return nil
+}
+
+// Delete removes keys.
+func (m *Memory) Delete(_ context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		if el, ok := m.entries[key]; ok {
+			m.remove(el)
+		}
+	}
+	return nil
+}
+
+// Len is how many values are stored, counting expired ones not yet removed.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lru.Len()
+}
+
+// remove drops an entry. m.mu must be held.
+func (m *Memory) remove(el *list.Element) {
+	m.lru.Remove(el)
+	delete(m.entries, el.Value.(*memoryEntry).key)
+}
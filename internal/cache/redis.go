@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/redis"
+)
+
+// Redis is a Store in a Redis server, shared by every app server using it.
+// Keys are prefixed so the app can share a server with other things.
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+var _ Store = (*Redis)(nil)
+
+// NewRedis creates a Redis store putting prefix before every key
+// (e.g. "playground:cache:").
+func NewRedis(client *redis.Client, prefix string) *Redis {
+	return &Redis{client: client, prefix: prefix}
+}
+
+// Get reads a key with GET.
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	v, ok, err := redis.String(r.client.Do(ctx, "GET", r.prefix+key))
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return []byte(v), true, nil
+}
+
+// Set writes a key with SET, and PX for the time to live.
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", r.prefix + key, string(value)}
+	if ttl > 0 {
+		// Redis counts whole milliseconds, and PX 0 is an error.
+		args = append(args, "PX", strconv.FormatInt(max(ttl.Milliseconds(), 1), 10))
+	}
+	_, err := r.client.Do(ctx, args...)
+	return err
+}
+
+// Delete removes keys with one DEL.
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := make([]string, 0, len(keys)+1)
+	args = append(args, "DEL")
+	for _, key := range keys {
+		args = append(args, r.prefix+key)
+	}
+	_, err := r.client.Do(ctx, args...)
+	return err
+}
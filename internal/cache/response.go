@@ -0,0 +1,211 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+// Responses caches whole responses to public GET requests: the rendered
+// snippet page, the feeds, the sitemap. SnippetRepository already saves
+// those pages their database reads; this saves rendering them too
+// (highlighting a long snippet costs more than reading it).
+//
+// THE KEY:
+// A response is cached under its path and query and the requester's auth
+// state ("anon", or the signed-in user's ID; see NewResponses), so a page
+// that differs for signed-in users is never served to the wrong one. The
+// scheme and host are part of it too: pages link to themselves absolutely.
+//
+// WHAT ISN'T CACHED:
+// Anything but a 200, responses that set a cookie or say Cache-Control
+// private or no-store, and bodies over maxCachedResponse.
+//
+// PURGING:
+// Every key includes a generation, like SnippetRepository's lists. Purge
+// starts a new one, abandoning every cached response at once; hook it to
+// anything that changes what the pages show (see SnippetRepository.OnChange).
+type Responses struct {
+	store  Store
+	ttl    time.Duration
+	logger *slog.Logger
+	vary   func(*http.Request) string
+}
+
+// NewResponses caches responses in store for ttl. vary returns the
+// requester's auth state for the key; nil treats everyone as "anon".
+func NewResponses(store Store, ttl time.Duration, vary func(*http.Request) string, logger *slog.Logger) *Responses {
+	if vary == nil {
+		vary = func(*http.Request) string { return "anon" }
+	}
+	return &Responses{store: store, ttl: ttl, logger: logger, vary: vary}
+}
+
+const (
+	responseGenerationKey = "responses:generation"
+	// maxCachedResponse keeps one huge page from crowding out the rest.
+	maxCachedResponse = 1 << 20
+)
+
+// cachedHeaders are the response headers kept with a cached body.
+var cachedHeaders = []string{"Content-Type", "Cache-Control", "ETag", "Last-Modified", "Content-Language", "Vary", "Surrogate-Key"}
+
+// cachedResponse is how a response is stored.
+type cachedResponse struct {
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// Middleware serves GET and HEAD requests from the cache, and caches what
+// next answers on a miss. Hits are marked X-Cache: HIT. Conditional and
+// Range requests are answered from the cached copy as well.
+func (c *Responses) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := r.Context()
+		gen := c.generation(ctx)
+		if gen == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		scheme := "http"
+		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		sum := sha256.Sum256([]byte(c.vary(r) + " " + scheme + "://" + r.Host + r.URL.RequestURI()))
+		key := "responses:" + gen + ":" + hex.EncodeToString(sum[:16])
+
+		if cached, ok := c.load(ctx, key); ok {
+			c.serve(w, r, cached)
+			return
+		}
+
+		// HEAD responses have no body to keep, and a conditional request
+		// may get a 304: let those through without caching.
+		if r.Method == http.MethodHead || r.Header.Get("If-None-Match") != "" ||
+			r.Header.Get("If-Modified-Since") != "" || r.Header.Get("Range") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if !rec.cacheable() {
+			return
+		}
+		entry := cachedResponse{Header: make(http.Header), Body: rec.body.Bytes()}
+		for _, name := range cachedHeaders {
+			if v := rec.Header().Values(name); len(v) > 0 {
+				entry.Header[http.CanonicalHeaderKey(name)] = v
+			}
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return
+		}
+		if err := c.store.Set(ctx, key, data, c.ttl); err != nil {
+			c.logger.WarnContext(ctx, "response cache: writing", slog.String("path", r.URL.Path), slog.Any("error", err))
+		}
+	})
+}
+
+// Purge abandons every cached response.
+func (c *Responses) Purge(ctx context.Context) {
+	if err := c.store.Set(ctx, responseGenerationKey, []byte(xid.New().String()), 0); err != nil {
+		c.logger.WarnContext(ctx, "response cache: purging", slog.Any("error", err))
+	}
+}
+
+// serve writes a cached response. http.ServeContent does the conditional
+// and Range handling, using the ETag and Last-Modified it was stored with.
+func (c *Responses) serve(w http.ResponseWriter, r *http.Request, cached cachedResponse) {
+	for name, values := range cached.Header {
+		w.Header()[http.CanonicalHeaderKey(name)] = values
+	}
+	w.Header().Set("X-Cache", "HIT")
+	modified, _ := http.ParseTime(cached.Header.Get("Last-Modified"))
+	http.ServeContent(w, r, "", modified, bytes.NewReader(cached.Body))
+}
+
+// generation returns the current generation, starting one if there's none,
+// or "" if the store can't be reached (requests then skip the cache).
+func (c *Responses) generation(ctx context.Context) string {
+	gen, ok, err := c.store.Get(ctx, responseGenerationKey)
+	if err != nil {
+		c.logger.WarnContext(ctx, "response cache: reading generation", slog.Any("error", err))
+		return ""
+	}
+	if ok {
+		return string(gen)
+	}
+	next := xid.New().String()
+	if err := c.store.Set(ctx, responseGenerationKey, []byte(next), 0); err != nil {
+		c.logger.WarnContext(ctx, "response cache: starting generation", slog.Any("error", err))
+		return ""
+	}
+	return next
+}
+
+func (c *Responses) load(ctx context.Context, key string) (cachedResponse, bool) {
+	var cached cachedResponse
+	data, ok, err := c.store.Get(ctx, key)
+	if err != nil {
+		c.logger.WarnContext(ctx, "response cache: reading", slog.Any("error", err))
+		return cached, false
+	}
+	return cached, ok && json.Unmarshal(data, &cached) == nil
+}
+
+// responseRecorder passes a response through while keeping a copy of it,
+// up to maxCachedResponse.
+type responseRecorder struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	tooLarge bool
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(p []byte) (int, error) {
+	if !rec.tooLarge {
+		if rec.body.Len()+len(p) > maxCachedResponse {
+			rec.tooLarge = true
+			rec.body = bytes.Buffer{}
+		} else {
+			rec.body.Write(p)
+		}
+	}
+	return rec.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the real writer.
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// cacheable reports whether the recorded response may be shared.
+func (rec *responseRecorder) cacheable() bool {
+	if rec.status != http.StatusOK || rec.tooLarge {
+		return false
+	}
+	h := rec.Header()
+	if h.Get("Set-Cookie") != "" {
+		return false
+	}
+	cc := strings.ToLower(h.Get("Cache-Control"))
+	return !strings.Contains(cc, "private") && !strings.Contains(cc, "no-store")
+}
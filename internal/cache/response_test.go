@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResponses(t *testing.T) {
+	calls := 0
+	page := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/private":
+			w.Header().Set("Cache-Control", "private")
+		case "/cookie":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "x"})
+		case "/missing":
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprintf(w, "%s for %s, render %d", r.URL.RequestURI(), r.Header.Get("X-User"), calls)
+	})
+	byUser := func(r *http.Request) string {
+		if u := r.Header.Get("X-User"); u != "" {
+			return "user:" + u
+		}
+		return "anon"
+	}
+	responses := NewResponses(NewMemory(100), time.Minute, byUser, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	h := responses.Middleware(page)
+
+	get := func(target string, header ...string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	rendersOf := func(target string, header ...string) int {
+		t.Helper()
+		before := calls
+		get(target, header...)
+		get(target, header...)
+		return calls - before
+	}
+
+	first := get("/s/1")
+	second := get("/s/1")
+	if calls != 1 || second.Body.String() != first.Body.String() {
+		t.Fatalf("second GET rendered again (%d renders) or differed: %q vs %q", calls, second.Body, first.Body)
+	}
+	if second.Header().Get("X-Cache") != "HIT" || second.Header().Get("ETag") != `"v1"` {
+		t.Errorf("hit headers = %v", second.Header())
+	}
+
+	t.Run("key", func(t *testing.T) {
+		if n := rendersOf("/s/1?theme=dark"); n != 1 {
+			t.Errorf("a different query rendered %d times, want 1", n)
+		}
+		if n := rendersOf("/s/1", "X-User", "ada"); n != 1 {
+			t.Errorf("a signed-in user rendered %d times, want 1", n)
+		}
+		if got := get("/s/1", "X-User", "ada").Body.String(); got != "/s/1 for ada, render 3" {
+			t.Errorf("signed-in user got %q", got)
+		}
+	})
+
+	t.Run("not cached", func(t *testing.T) {
+		for _, path := range []string{"/private", "/cookie", "/missing"} {
+			if n := rendersOf(path); n != 2 {
+				t.Errorf("%s rendered %d times, want 2 (uncached)", path, n)
+			}
+		}
+		before := calls
+		req := httptest.NewRequest(http.MethodPost, "/s/1", nil)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		if calls != before+1 {
+			t.Error("a POST was answered from the cache")
+		}
+	})
+
+	t.Run("conditional hit", func(t *testing.T) {
+		if rec := get("/s/1", "If-None-Match", `"v1"`); rec.Code != http.StatusNotModified {
+			t.Errorf("If-None-Match on a hit = %d, want 304", rec.Code)
+		}
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		responses.Purge(context.Background())
+		if n := rendersOf("/s/1"); n != 1 {
+			t.Errorf("after Purge rendered %d times, want 1", n)
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// SnippetRepository reads public snippets through a Store, and everything
+// else straight from the repository it wraps.
+//
+// WHAT'S CACHED:
+//
+//	GetByID     the snippet, if it's public
+//	List        pages of public snippets (ListOptions.PublicOnly)
+//	ListPublic  the feeds
+//
+// Private snippets aren't cached at all: they're read by one person, and
+// keeping them out means a cache can't leak one.
+//
+// INVALIDATION:
+// Update, Delete and SetPublic drop the snippet's entry. They also bump a
+// "generation" stored in the cache, which is part of every list key — so all
+// the cached lists are abandoned at once, without having to find them, and
+// expire on their own. Writes that bypass this repository (deleting an org
+// or a user, the nightly purge, the other side of a transfer — see
+// Invalidate) show up when the entries expire, so keep the TTL short.
+type SnippetRepository struct {
+	repository.SnippetRepository
+	store    Store
+	ttl      time.Duration
+	logger   *slog.Logger
+	onChange []func(context.Context)
+}
+
+// NewSnippetRepository caches repo's public snippets in store for ttl.
+func NewSnippetRepository(repo repository.SnippetRepository, store Store, ttl time.Duration, logger *slog.Logger) *SnippetRepository {
+	return &SnippetRepository{SnippetRepository: repo, store: store, ttl: ttl, logger: logger}
+}
+
+var _ repository.SnippetRepository = (*SnippetRepository)(nil)
+
+// OnChange calls fn whenever the cached lists are abandoned: whenever a
+// public page might show something different. Responses.Purge is meant
+// for it. Call it before serving requests.
+func (r *SnippetRepository) OnChange(fn func(context.Context)) {
+	r.onChange = append(r.onChange, fn)
+}
+
+const generationKey = "snippets:generation"
+
+// GetByID returns a public snippet from the cache if it's there.
+func (r *SnippetRepository) GetByID(ctx context.Context, id string) (*model.Snippet, error) {
+	key := "snippet:" + id
+	var cached model.Snippet
+	if r.load(ctx, key, &cached) {
+		return &cached, nil
+	}
+	snippet, err := r.SnippetRepository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if snippet.Public {
+		r.save(ctx, key, snippet)
+	}
+	return snippet, nil
+}
+
+// List caches lists of public snippets only.
+func (r *SnippetRepository) List(ctx context.Context, opts repository.ListOptions) ([]model.Snippet, error) {
+	if !opts.PublicOnly {
+		return r.SnippetRepository.List(ctx, opts)
+	}
+	return r.cachedList(ctx, "list", opts, func() ([]model.Snippet, error) {
+		return r.SnippetRepository.List(ctx, opts)
+	})
+}
+
+// ListPublic caches the feeds.
+func (r *SnippetRepository) ListPublic(ctx context.Context, userID string, limit int) ([]model.Snippet, error) {
+	query := struct {
+		UserID string
+		Limit  int
+	}{userID, limit}
+	return r.cachedList(ctx, "feed", query, func() ([]model.Snippet, error) {
+		return r.SnippetRepository.ListPublic(ctx, userID, limit)
+	})
+}
+
+// Create passes through; a new snippet is only listed once made public,
+// unless it's created public.
+func (r *SnippetRepository) Create(ctx context.Context, snippet *model.Snippet) error {
+	if err := r.SnippetRepository.Create(ctx, snippet); err != nil {
+		return err
+	}
+	if snippet.Public {
+		r.changed(ctx)
+	}
+	return nil
+}
+
+// Update saves the snippet and drops it from the cache.
+func (r *SnippetRepository) Update(ctx context.Context, snippet *model.Snippet) error {
+	if err := r.SnippetRepository.Update(ctx, snippet); err != nil {
+		return err
+	}
+	r.Invalidate(ctx, snippet.ID)
+	return nil
+}
+
+// SetPublic publishes or unpublishes the snippet and drops it from the cache.
+func (r *SnippetRepository) SetPublic(ctx context.Context, snippet *model.Snippet) error {
+	if err := r.SnippetRepository.SetPublic(ctx, snippet); err != nil {
+		return err
+	}
+	r.Invalidate(ctx, snippet.ID)
+	return nil
+}
+
+// Delete removes the snippet and drops it from the cache.
+func (r *SnippetRepository) Delete(ctx context.Context, id string) error {
+	if err := r.SnippetRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.Invalidate(ctx, id)
+	return nil
+}
+
+// Invalidate drops snippet id, and every cached list, after it was changed
+// some other way (a transfer, say).
+func (r *SnippetRepository) Invalidate(ctx context.Context, id string) {
+	if err := r.store.Delete(ctx, "snippet:"+id); err != nil {
+		r.logger.WarnContext(ctx, "snippet cache: invalidating", slog.String("id", id), slog.Any("error", err))
+	}
+	r.changed(ctx)
+}
+
+// changed abandons every cached list and tells the OnChange hooks.
+func (r *SnippetRepository) changed(ctx context.Context) {
+	r.bumpGeneration(ctx)
+	for _, fn := range r.onChange {
+		fn(ctx)
+	}
+}
+
+// cachedList returns the list under kind and query in the current
+// generation, or loads it and caches it.
+func (r *SnippetRepository) cachedList(ctx context.Context, kind string, query any, load func() ([]model.Snippet, error)) ([]model.Snippet, error) {
+	gen := r.generation(ctx)
+	if gen == "" {
+		return load()
+	}
+	q, err := json.Marshal(query)
+	if err != nil {
+		return load()
+	}
+	sum := sha256.Sum256(q)
+	key := "snippets:" + kind + ":" + gen + ":" + hex.EncodeToString(sum[:16])
+
+	var cached []model.Snippet
+	if r.load(ctx, key, &cached) {
+		return cached, nil
+	}
+	snippets, err := load()
+	if err != nil {
+		return nil, err
+	}
+	r.save(ctx, key, snippets)
+	return snippets, nil
+}
+
+// generation returns the current list generation, starting one if there's
+// none. It returns "" if the store can't be reached, and lists then skip
+// the cache.
+func (r *SnippetRepository) generation(ctx context.Context) string {
+	gen, ok, err := r.store.Get(ctx, generationKey)
+	if err != nil {
+		r.logger.WarnContext(ctx, "snippet cache: reading generation", slog.Any("error", err))
+		return ""
+	}
+	if ok {
+		return string(gen)
+	}
+	return r.bumpGeneration(ctx)
+}
+
+// bumpGeneration abandons every cached list. It has no TTL: it must
+// outlive the lists that use it.
+func (r *SnippetRepository) bumpGeneration(ctx context.Context) string {
+	gen := xid.New().String()
+	if err := r.store.Set(ctx, generationKey, []byte(gen), 0); err != nil {
+		r.logger.WarnContext(ctx, "snippet cache: bumping generation", slog.Any("error", err))
+		return ""
+	}
+	return gen
+}
+
+// load decodes the value under key into v, reporting whether it was there.
+func (r *SnippetRepository) load(ctx context.Context, key string, v any) bool {
+	data, ok, err := r.store.Get(ctx, key)
+	if err != nil {
+		r.logger.WarnContext(ctx, "snippet cache: reading", slog.String("key", key), slog.Any("error", err))
+		return false
+	}
+	return ok && json.Unmarshal(data, v) == nil
+}
+
+// save encodes v and stores it under key.
+func (r *SnippetRepository) save(ctx context.Context, key string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	if err := r.store.Set(ctx, key, data, r.ttl); err != nil {
+		r.logger.WarnContext(ctx, "snippet cache: writing", slog.String("key", key), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,95 @@
+// Package cdn lets a CDN in front of the server absorb the traffic to
+// public snippets: it sets the headers a CDN caches by, and tells the CDN
+// to drop a snippet when it changes.
+//
+// WHAT A CDN SEES:
+// A public snippet, its embed (/api/v1/snippets/{id}/html) and its page
+// (/s/{id}) are sent with
+//
+//	Cache-Control: public, max-age=0, s-maxage=300
+//	Surrogate-Key: snippet-cn0a…
+//
+// s-maxage is for shared caches only: the CDN serves its copy for up to
+// five minutes (CDN_MAX_AGE) without asking the server, while browsers,
+// told max-age=0, still revalidate every time — with the ETag, so usually
+// for a 304 from the CDN. Private snippets keep Cache-Control: no-cache.
+//
+// PURGING:
+// Surrogate-Key tags every cached response for a snippet with one key, so
+// one purge request clears them all, whatever the URL and query string.
+// SnippetRepository purges a snippet's key whenever it's updated,
+// published, unpublished or deleted; with CDN_PURGE_URL unset, nothing is
+// purged and an edit shows once s-maxage runs out. Like the snippet cache,
+// writes that bypass the repository (deleting a user, the nightly purge)
+// only show when entries expire.
+//
+// Fastly reads Surrogate-Key as is; other CDNs can be pointed at it (a
+// Cloudflare Cache-Tag rule, say) or at a small purge proxy.
+package cdn
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SnippetKey is the surrogate key of every response cached for snippet id.
+func SnippetKey(id string) string {
+	return "snippet-" + id
+}
+
+// SetHeaders lets shared caches keep the response for maxAge, tagged with
+// keys. Browsers revalidate it every time.
+func SetHeaders(h http.Header, maxAge time.Duration, keys ...string) {
+	h.Set("Cache-Control", fmt.Sprintf("public, max-age=0, s-maxage=%d", int(maxAge.Seconds())))
+	h.Set("Surrogate-Key", strings.Join(keys, " "))
+}
+
+// Purger drops every cached response tagged with any of keys.
+type Purger interface {
+	Purge(ctx context.Context, keys ...string) error
+}
+
+// HTTPPurger purges by POSTing to a URL: the keys go in a Surrogate-Key
+// header, space-separated, and in the body as {"keys": [...]}. Token, if
+// set, is sent as a bearer token.
+type HTTPPurger struct {
+	URL    string
+	Token  string
+	Client *http.Client // nil uses a client with a 10s timeout
+}
+
+// Purge asks the CDN to drop keys. Any status but 2xx is an error.
+func (p *HTTPPurger) Purge(ctx context.Context, keys ...string) error {
+	body, err := json.Marshal(map[string][]string{"keys": keys})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("cdn: purge request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Surrogate-Key", strings.Join(keys, " "))
+	if p.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	}
+
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cdn: purge: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("cdn: purge: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package cdn
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository/sqlite"
+)
+
+func TestSetHeaders(t *testing.T) {
+	h := http.Header{}
+	SetHeaders(h, 5*time.Minute, SnippetKey("abc"), "snippets")
+	if got := h.Get("Cache-Control"); got != "public, max-age=0, s-maxage=300" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	if got := h.Get("Surrogate-Key"); got != "snippet-abc snippets" {
+		t.Errorf("Surrogate-Key = %q", got)
+	}
+}
+
+func TestHTTPPurger(t *testing.T) {
+	var got struct {
+		header, auth string
+		keys         []string
+	}
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.header, got.auth = r.Header.Get("Surrogate-Key"), r.Header.Get("Authorization")
+		var body struct{ Keys []string }
+		json.NewDecoder(r.Body).Decode(&body)
+		got.keys = body.Keys
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+
+	p := &HTTPPurger{URL: srv.URL, Token: "secret"}
+	if err := p.Purge(context.Background(), "snippet-a", "snippet-b"); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if got.header != "snippet-a snippet-b" || got.auth != "Bearer secret" || !slices.Equal(got.keys, []string{"snippet-a", "snippet-b"}) {
+		t.Errorf("the CDN got %+v", got)
+	}
+
+	status = http.StatusForbidden
+	if err := p.Purge(context.Background(), "snippet-a"); err == nil {
+		t.Error("Purge() = nil for a 403")
+	}
+}
+
+// purgeLog records purges instead of making them.
+type purgeLog []string
+
+func (l *purgeLog) Purge(_ context.Context, keys ...string) error {
+	*l = append(*l, keys...)
+	return nil
+}
+
+func TestSnippetRepository(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	var purged purgeLog
+	repo := NewSnippetRepository(db, &purged, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	snippet := &model.Snippet{Name: "viral", Code: "print(1)"}
+	if err := repo.Create(ctx, snippet); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if len(purged) != 0 {
+		t.Errorf("a new snippet was purged: %v", purged)
+	}
+
+	snippet.Code = "print(2)"
+	if err := repo.Update(ctx, snippet); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	snippet.Public = true
+	if err := repo.SetPublic(ctx, snippet); err != nil {
+		t.Fatalf("SetPublic() error = %v", err)
+	}
+	if err := repo.Delete(ctx, snippet.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	key := SnippetKey(snippet.ID)
+	if !slices.Equal(purged, []string{key, key, key}) {
+		t.Errorf("purged %v, want %s after each change", purged, key)
+	}
+
+	purged = nil
+	if err := repo.Delete(ctx, "missing"); err == nil || len(purged) != 0 {
+		t.Errorf("Delete(missing) = %v, purged %v; want an error and no purge", err, purged)
+	}
+}
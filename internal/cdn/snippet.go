@@ -0,0 +1,94 @@
+package cdn
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/sakif/coding-playground/internal/jobs"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// JobPurge is the job kind that runs a queued purge.
+const JobPurge = "cdn_purge"
+
+// Queued returns a Purger that queues each purge as a job run by p, so a
+// CDN that's slow or down is retried with backoff rather than slowing
+// down the write that triggered it.
+func Queued(queue *jobs.Queue, p Purger) Purger {
+	queue.Register(JobPurge, func(ctx context.Context, job *jobs.Job) error {
+		var keys []string
+		if err := job.Decode(&keys); err != nil {
+			return err
+		}
+		return p.Purge(ctx, keys...)
+	})
+	return &queued{queue: queue}
+}
+
+type queued struct {
+	queue *jobs.Queue
+}
+
+func (q *queued) Purge(ctx context.Context, keys ...string) error {
+	_, err := q.queue.Enqueue(ctx, JobPurge, keys)
+	return err
+}
+
+// SnippetRepository purges a snippet from the CDN whenever it changes
+// through the repository it wraps.
+type SnippetRepository struct {
+	repository.SnippetRepository
+	purger Purger
+	logger *slog.Logger
+}
+
+// NewSnippetRepository wraps repo, purging changed snippets with purger.
+func NewSnippetRepository(repo repository.SnippetRepository, purger Purger, logger *slog.Logger) *SnippetRepository {
+	return &SnippetRepository{SnippetRepository: repo, purger: purger, logger: logger}
+}
+
+// Update saves the snippet and purges it.
+func (r *SnippetRepository) Update(ctx context.Context, snippet *model.Snippet) error {
+	if err := r.SnippetRepository.Update(ctx, snippet); err != nil {
+		return err
+	}
+	r.purge(ctx, snippet.ID)
+	return nil
+}
+
+// SetPublic publishes or unpublishes the snippet and purges it.
+func (r *SnippetRepository) SetPublic(ctx context.Context, snippet *model.Snippet) error {
+	if err := r.SnippetRepository.SetPublic(ctx, snippet); err != nil {
+		return err
+	}
+	r.purge(ctx, snippet.ID)
+	return nil
+}
+
+// Delete removes the snippet and purges it.
+func (r *SnippetRepository) Delete(ctx context.Context, id string) error {
+	if err := r.SnippetRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.purge(ctx, id)
+	return nil
+}
+
+// Invalidate purges snippet id after it was changed some other way (a
+// transfer, say), and passes the news on to the repository it wraps if
+// that caches snippets too.
+func (r *SnippetRepository) Invalidate(ctx context.Context, id string) {
+	if c, ok := r.SnippetRepository.(interface{ Invalidate(context.Context, string) }); ok {
+		c.Invalidate(ctx, id)
+	}
+	r.purge(ctx, id)
+}
+
+// purge never fails the write: the snippet is saved, and the CDN catches
+// up when its copy expires.
+func (r *SnippetRepository) purge(ctx context.Context, id string) {
+	if err := r.purger.Purge(context.WithoutCancel(ctx), SnippetKey(id)); err != nil {
+		r.logger.WarnContext(ctx, "cdn: purging snippet", slog.String("id", id), slog.Any("error", err))
+	}
+}
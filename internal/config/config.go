@@ -0,0 +1,343 @@
+// Package config reads the server's settings from command-line flags, the
+// environment and a config file.
+//
+// PRECEDENCE:
+// Each setting is named by its environment variable (PORT, DB_PATH, ...)
+// and looked up in four places, the first that has it winning:
+//
+//  1. a command-line flag: --port, --db-path, --log-level (only the
+//     settings most often changed for a single run have one)
+//  2. the environment: PORT=9090
+//  3. the config file named by --config (or CONFIG_FILE): a line PORT=9090
+//  4. the default in the code that reads it
+//
+// So a config file holds a deployment's settings, the environment (set by
+// systemd, Docker or Kubernetes) overrides them per machine, and a flag
+// overrides everything for one run:
+//
+//	playground --config /etc/playground.env --port 9090
+//
+// THE CONFIG FILE:
+// The same KEY=VALUE lines as .env.example — .env itself works. Blank lines
+// and lines starting with # are skipped, and a value may be quoted:
+//
+//	# /etc/playground.env
+//	DB_PATH=/var/lib/playground/playground.db
+//	SMTP_FROM="PyPlayground <noreply@play.example.com>"
+//
+// SECRETS IN FILES:
+// Any setting can instead be read from a file, named by the setting with
+// _FILE on the end:
+//
+//	JWT_SECRET_FILE=/run/secrets/jwt_secret
+//
+// That's how Docker and Kubernetes hand secrets to a container (mounted as
+// files), and it keeps them out of the environment, which other users can
+// read in process listings (ps e, /proc/<pid>/environ) and which ends up in
+// crash reports. Trailing newlines in the file are dropped. In each place a
+// setting is looked up, the setting itself comes before its _FILE; setting
+// both in the same place is a problem, as one would be silently ignored.
+//
+// PROBLEMS:
+// A value that doesn't parse (PORT=eighty) doesn't stop the reading: the
+// getter returns its default and the mistake is kept as a Problem, naming
+// where the value came from. Settings that parse but don't make sense
+// (a 10-character JWT_SECRET, half the GitHub credentials) are added with
+// Check. Problems then returns them all, each with a hint at the fix, so one
+// failed start lists everything there is to fix rather than the first.
+package config
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Flags maps each command-line flag (without its dashes) to the setting it
+// sets.
+var Flags = map[string]string{
+	"port":      "PORT",
+	"db-path":   "DB_PATH",
+	"log-level": "LOG_LEVEL",
+}
+
+// Config is the server's settings, looked up by name.
+type Config struct {
+	flags    map[string]string           // settings given as flags
+	env      func(string) (string, bool) // os.LookupEnv, in production
+	file     map[string]string           // the config file's settings
+	filePath string
+	problems []Problem
+}
+
+// Problem is a setting that's wrong, and what to do about it.
+type Problem struct {
+	Setting string // its name: "JWT_SECRET"
+	Source  string // where its value came from; "" if it isn't set
+	Message string // what's wrong: "is 12 characters; it must be at least 32"
+	Hint    string // how to fix it, if there's more to say than Message
+}
+
+func (p Problem) Error() string {
+	msg := p.Setting
+	if p.Source != "" {
+		msg += " (from " + p.Source + ")"
+	}
+	return msg + " " + p.Message
+}
+
+// Load parses the command-line arguments args (os.Args[1:]) and reads the
+// config file they name, looking up environment variables with lookupEnv
+// (os.LookupEnv). It fails on a bad flag or a config file that can't be read;
+// -h and --help print the usage to usage and return flag.ErrHelp.
+func Load(args []string, lookupEnv func(string) (string, bool), usage io.Writer) (*Config, error) {
+	c := &Config{flags: map[string]string{}, env: lookupEnv}
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.SetOutput(usage)
+	values := map[string]*string{}
+	for name, key := range Flags {
+		values[name] = fs.String(name, "", "overrides $"+key)
+	}
+	configPath := fs.String("config", "", "read settings from this KEY=VALUE file (overrides $CONFIG_FILE)")
+	fs.Usage = func() {
+		fmt.Fprint(usage, "Usage: server [flags]\n\n"+
+			"Settings come from flags, then environment variables, then the config\n"+
+			"file, then built-in defaults; the first that sets a value wins.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if key, ok := Flags[f.Name]; ok {
+			c.flags[key] = *values[f.Name]
+		}
+	})
+
+	if *configPath == "" {
+		*configPath, _ = lookupEnv("CONFIG_FILE")
+	}
+	if *configPath != "" {
+		file, err := readFile(*configPath)
+		if err != nil {
+			return nil, err
+		}
+		c.file, c.filePath = file, *configPath
+	}
+	return c, nil
+}
+
+// readFile reads a config file's KEY=VALUE lines.
+func readFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	defer f.Close()
+
+	settings := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(strings.TrimPrefix(key, "export "))
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s:%d: want KEY=VALUE, got %q", path, n, line)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		settings[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	return settings, nil
+}
+
+// Lookup returns setting key's value and where it came from ("the --port
+// flag", "the environment", "config file /etc/playground.env", or for a
+// secret in a file, "file /run/secrets/jwt_secret (JWT_SECRET_FILE, from the
+// environment)"). An empty value counts as unset, as an empty environment
+// variable always has.
+func (c *Config) Lookup(key string) (value, source string, ok bool) {
+	if v, ok := c.flags[key]; ok && v != "" {
+		for name, k := range Flags {
+			if k == key {
+				return v, "the --" + name + " flag", true
+			}
+		}
+	}
+	places := []struct {
+		from string
+		get  func(string) (string, bool)
+	}{
+		{"the environment", c.env},
+		{"config file " + c.filePath, func(key string) (string, bool) {
+			v, ok := c.file[key]
+			return v, ok
+		}},
+	}
+	for _, place := range places {
+		v, _ := place.get(key)
+		path, _ := place.get(key + "_FILE")
+		if v != "" {
+			if path != "" {
+				c.reportOnce(key, place.from, "is set both directly and with "+key+"_FILE",
+					"Unset one of them: "+key+" is used, and "+key+"_FILE ignored.")
+			}
+			return v, place.from, true
+		}
+		if path == "" {
+			continue
+		}
+		source := fmt.Sprintf("file %s (%s_FILE, from %s)", path, key, place.from)
+		secret, err := readSecret(path)
+		if err != nil {
+			c.reportOnce(key, source, "can't be read: "+err.Error(),
+				"Check that the secret is mounted at that path and readable by the server's user.")
+			return "", "", false
+		}
+		return secret, source, true
+	}
+	return "", "", false
+}
+
+// readSecret reads a setting's value from the file at path.
+func readSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimRight(string(data), "\r\n")
+	if secret == "" {
+		return "", errors.New("the file is empty")
+	}
+	return secret, nil
+}
+
+// String returns setting key, or def if it's unset.
+func (c *Config) String(key, def string) string {
+	if v, _, ok := c.Lookup(key); ok {
+		return v
+	}
+	return def
+}
+
+// List returns setting key split at commas, with the items trimmed and the
+// empty ones dropped: "a.com, b.com," is [a.com b.com].
+func (c *Config) List(key string) []string {
+	var items []string
+	for _, item := range strings.Split(c.String(key, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Int returns integer setting key, or def if it's unset or invalid.
+func (c *Config) Int(key string, def int) int {
+	return get(c, key, def, "an integer", strconv.Atoi)
+}
+
+// Float returns number setting key, or def if it's unset or invalid.
+func (c *Config) Float(key string, def float64) float64 {
+	return get(c, key, def, "a number", func(v string) (float64, error) {
+		return strconv.ParseFloat(v, 64)
+	})
+}
+
+// Bool returns boolean setting key ("true", "false", "1", "0", ...), or def
+// if it's unset or invalid.
+func (c *Config) Bool(key string, def bool) bool {
+	return get(c, key, def, "true or false", strconv.ParseBool)
+}
+
+// Duration returns duration setting key ("2s", "1m30s"), or def if it's
+// unset or invalid.
+func (c *Config) Duration(key string, def time.Duration) time.Duration {
+	return get(c, key, def, `a duration such as "2s"`, time.ParseDuration)
+}
+
+// Date returns date setting key ("2027-05-01", read as midnight UTC), or def
+// if it's unset or invalid.
+func (c *Config) Date(key string, def time.Time) time.Time {
+	return get(c, key, def, "a date (YYYY-MM-DD)", func(v string) (time.Time, error) {
+		return time.Parse(time.DateOnly, v)
+	})
+}
+
+// Invalid records that setting key is set but isn't want ("an integer"),
+// for settings parsed outside this package.
+func (c *Config) Invalid(key, want string) {
+	v, _, _ := c.Lookup(key)
+	c.Report(key, fmt.Sprintf("is %q, which isn't %s", v, want), "")
+}
+
+// Check records a problem with setting key unless ok: message says what's
+// wrong, hint (optional) how to fix it.
+func (c *Config) Check(ok bool, key, message, hint string) {
+	if !ok {
+		c.Report(key, message, hint)
+	}
+}
+
+// Report records a problem with setting key, found outside this package.
+func (c *Config) Report(key, message, hint string) {
+	_, source, _ := c.Lookup(key)
+	c.problems = append(c.problems, Problem{Setting: key, Source: source, Message: message, Hint: hint})
+}
+
+// reportOnce records a problem found looking key up, which every lookup of
+// it would find again.
+func (c *Config) reportOnce(key, source, message, hint string) {
+	p := Problem{Setting: key, Source: source, Message: message, Hint: hint}
+	for _, reported := range c.problems {
+		if reported == p {
+			return
+		}
+	}
+	c.problems = append(c.problems, p)
+}
+
+// Problems returns every problem found so far, in the order found.
+func (c *Config) Problems() []Problem {
+	return c.problems
+}
+
+// Err returns the problems found so far as one error, or nil.
+func (c *Config) Err() error {
+	errs := make([]error, len(c.problems))
+	for i, p := range c.problems {
+		errs[i] = p
+	}
+	return errors.Join(errs...)
+}
+
+func get[T any](c *Config, key string, def T, want string, parse func(string) (T, error)) T {
+	v, _, ok := c.Lookup(key)
+	if !ok {
+		return def
+	}
+	parsed, err := parse(v)
+	if err != nil {
+		c.Invalid(key, want)
+		return def
+	}
+	return parsed
+}
@@ -0,0 +1,248 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// env returns a lookupEnv for a fixed environment.
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "playground.env")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPrecedence(t *testing.T) {
+	path := writeFile(t, `
+# a deployment's settings
+PORT=7000
+DB_PATH=/srv/file.db
+LOG_LEVEL=warn
+export SMTP_FROM="PyPlayground <noreply@play.example.com>"
+`)
+	c, err := Load(
+		[]string{"--config", path, "--port", "9090"},
+		env(map[string]string{"PORT": "8000", "DB_PATH": "/srv/env.db", "LOG_LEVEL": ""}),
+		io.Discard,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key, want, source string
+	}{
+		{"PORT", "9090", "the --port flag"},           // flag over env and file
+		{"DB_PATH", "/srv/env.db", "the environment"}, // env over file
+		{"LOG_LEVEL", "warn", "config file " + path},  // an empty env var is unset
+		{"SMTP_FROM", "PyPlayground <noreply@play.example.com>", "config file " + path},
+	}
+	for _, tt := range tests {
+		v, source, ok := c.Lookup(tt.key)
+		if !ok || v != tt.want || source != tt.source {
+			t.Errorf("Lookup(%s) = %q, %q, %v; want %q from %q", tt.key, v, source, ok, tt.want, tt.source)
+		}
+	}
+	if got := c.String("LISTEN", "default"); got != "default" {
+		t.Errorf("an unset setting = %q, want its default", got)
+	}
+	if got := c.Int("PORT", 8080); got != 9090 {
+		t.Errorf("Int(PORT) = %d, want 9090", got)
+	}
+}
+
+func TestConfigFileFromEnvironment(t *testing.T) {
+	path := writeFile(t, "DB_PATH=/srv/file.db\n")
+	c, err := Load(nil, env(map[string]string{"CONFIG_FILE": path}), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.String("DB_PATH", ""); got != "/srv/file.db" {
+		t.Errorf("DB_PATH = %q, want the CONFIG_FILE's", got)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	noEnv := env(nil)
+	if _, err := Load([]string{"--help"}, noEnv, io.Discard); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("--help: err = %v, want flag.ErrHelp", err)
+	}
+	if _, err := Load([]string{"--verbose"}, noEnv, io.Discard); err == nil {
+		t.Error("an unknown flag was accepted")
+	}
+	if _, err := Load([]string{"serve"}, noEnv, io.Discard); err == nil {
+		t.Error("a stray argument was accepted")
+	}
+	if _, err := Load([]string{"--config", filepath.Join(t.TempDir(), "missing.env")}, noEnv, io.Discard); err == nil {
+		t.Error("a missing config file was accepted")
+	}
+	path := writeFile(t, "PORT=8080\nnot a setting\n")
+	if _, err := Load([]string{"--config", path}, noEnv, io.Discard); err == nil || !strings.Contains(err.Error(), ":2:") {
+		t.Errorf("a malformed line: err = %v, want one naming line 2", err)
+	}
+}
+
+func TestTypedGetters(t *testing.T) {
+	c, err := Load([]string{"--port", "eighty"}, env(map[string]string{
+		"RATE":     "0.5",
+		"ENABLED":  "1",
+		"TIMEOUT":  "1m30s",
+		"SUNSET":   "2027-05-01",
+		"DOMAINS":  "a.com, b.com,",
+		"BURST":    "lots",
+		"DEADLINE": "soon",
+	}), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.Float("RATE", 0); got != 0.5 {
+		t.Errorf("Float = %v", got)
+	}
+	if got := c.Bool("ENABLED", false); !got {
+		t.Errorf("Bool = %v", got)
+	}
+	if got := c.Duration("TIMEOUT", 0); got != 90*time.Second {
+		t.Errorf("Duration = %v", got)
+	}
+	if got := c.Date("SUNSET", time.Time{}); !got.Equal(time.Date(2027, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Date = %v", got)
+	}
+	if got := c.List("DOMAINS"); len(got) != 2 || got[0] != "a.com" || got[1] != "b.com" {
+		t.Errorf("List = %q", got)
+	}
+	if c.Err() != nil {
+		t.Fatalf("valid values gave errors: %v", c.Err())
+	}
+
+	// Invalid values give the default, and are all reported together.
+	if got := c.Int("PORT", 8080); got != 8080 {
+		t.Errorf("invalid Int = %d, want the default", got)
+	}
+	if got := c.Int("BURST", 40); got != 40 {
+		t.Errorf("invalid Int = %d, want the default", got)
+	}
+	if got := c.Duration("DEADLINE", time.Second); got != time.Second {
+		t.Errorf("invalid Duration = %v, want the default", got)
+	}
+	err = c.Err()
+	for _, want := range []string{
+		`PORT (from the --port flag) is "eighty", which isn't an integer`,
+		`BURST (from the environment) is "lots"`,
+		`DEADLINE (from the environment) is "soon"`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Err() = %v, want it to include %s", err, want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	c, err := Load(nil, env(map[string]string{"JWT_SECRET": "short"}), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Check(true, "PORT", "is fine", "")
+	c.Check(false, "JWT_SECRET", "is 5 characters; it must be at least 32", "Generate one with: openssl rand -hex 32")
+	c.Check(false, "GITHUB_CLIENT_ID", "is missing", "")
+
+	want := []Problem{
+		{Setting: "JWT_SECRET", Source: "the environment", Message: "is 5 characters; it must be at least 32", Hint: "Generate one with: openssl rand -hex 32"},
+		{Setting: "GITHUB_CLIENT_ID", Message: "is missing"},
+	}
+	got := c.Problems()
+	if len(got) != len(want) {
+		t.Fatalf("Problems() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("problem %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if msg := got[1].Error(); msg != "GITHUB_CLIENT_ID is missing" {
+		t.Errorf("an unset setting's problem reads %q", msg)
+	}
+}
+
+func TestSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "jwt_secret")
+	if err := os.WriteFile(secret, []byte("s3cret-from-a-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	path := writeFile(t, "GITHUB_CLIENT_SECRET_FILE="+secret+"\nSMTP_PASSWORD=from-the-file\n")
+
+	c, err := Load([]string{"--config", path}, env(map[string]string{
+		"JWT_SECRET_FILE":           secret,
+		"SMTP_PASSWORD_FILE":        secret, // the environment beats the config file
+		"METRICS_PASSWORD":          "direct",
+		"METRICS_PASSWORD_FILE":     secret,
+		"SENTRY_DSN_FILE":           filepath.Join(dir, "missing"),
+		"S3_SECRET_ACCESS_KEY_FILE": empty,
+	}), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"JWT_SECRET", "GITHUB_CLIENT_SECRET", "SMTP_PASSWORD"} {
+		if got := c.String(key, ""); got != "s3cret-from-a-file" {
+			t.Errorf("%s = %q, want the file's contents without the newline", key, got)
+		}
+	}
+	if _, source, _ := c.Lookup("JWT_SECRET"); source != "file "+secret+" (JWT_SECRET_FILE, from the environment)" {
+		t.Errorf("JWT_SECRET's source = %q", source)
+	}
+	if c.Err() != nil {
+		t.Fatalf("problems before any were looked up: %v", c.Err())
+	}
+
+	// Both ways at once, or a file that can't be read, are problems —
+	// reported once, however often the setting is read.
+	if got := c.String("METRICS_PASSWORD", ""); got != "direct" {
+		t.Errorf("METRICS_PASSWORD = %q, want the value set directly", got)
+	}
+	for range 2 {
+		if got := c.String("SENTRY_DSN", "default"); got != "default" {
+			t.Errorf("an unreadable SENTRY_DSN_FILE gave %q", got)
+		}
+	}
+	c.String("S3_SECRET_ACCESS_KEY", "")
+
+	var got []string
+	for _, p := range c.Problems() {
+		got = append(got, p.Setting+": "+p.Message)
+	}
+	want := []string{
+		"METRICS_PASSWORD: is set both directly and with METRICS_PASSWORD_FILE",
+		"SENTRY_DSN: can't be read",
+		"S3_SECRET_ACCESS_KEY: can't be read: the file is empty",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("problems = %q, want %q", got, want)
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("problem %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Package errreport sends unexpected errors to an external error tracker.
+//
+// WHY?
+// Logs tell you what happened if you go looking. An error tracker (Sentry and
+// compatible services such as GlitchTip) comes to YOU: it groups identical
+// crashes, counts them, keeps the stack trace and request, and alerts when a
+// new one appears. In production that's the difference between finding a
+// crash today and finding it when a user complains.
+//
+// WHAT GETS REPORTED:
+// Only errors that are our fault — panics caught by middleware.Recoverer and
+// 500 responses from handler.writeError. A 404 or a validation error is the
+// client's problem and would only drown the real crashes in noise.
+//
+// The reporter travels in the request context (NewContext/FromContext) so the
+// response helpers can reach it without every handler holding a reference.
+package errreport
+
+import (
+	"context"
+	"net/http"
+)
+
+// Reporter delivers errors to an error tracker.
+type Reporter interface {
+	// Report records err. req is the request being served, or nil when the
+	// error didn't come from an HTTP request. Report must not block the caller
+	// on network I/O.
+	Report(ctx context.Context, err error, req *http.Request)
+
+	// Flush waits until queued reports are delivered or ctx is done.
+	Flush(ctx context.Context) error
+}
+
+// Nop is a Reporter that discards everything. It's used when no error
+// tracker is configured.
+type Nop struct{}
+
+func (Nop) Report(context.Context, error, *http.Request) {}
+func (Nop) Flush(context.Context) error                  { return nil }
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying rep.
+func NewContext(ctx context.Context, rep Reporter) context.Context {
+	return context.WithValue(ctx, contextKey{}, rep)
+}
+
+// FromContext returns the Reporter stored in ctx, or Nop if there is none.
+func FromContext(ctx context.Context) Reporter {
+	if rep, ok := ctx.Value(contextKey{}).(Reporter); ok {
+		return rep
+	}
+	return Nop{}
+}
@@ -0,0 +1,90 @@
+package errreport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// Entry is one error kept by Recent.
+type Entry struct {
+	Time      time.Time
+	Message   string
+	Method    string // "" when the error didn't come from a request
+	Path      string
+	RequestID string
+}
+
+// Recent is a Reporter that keeps the last few errors in memory, for the
+// admin dashboard. It works without an external tracker, and forgets
+// everything on restart.
+type Recent struct {
+	mu      sync.Mutex
+	entries []Entry // ring buffer; next is where the next entry goes
+	next    int
+	full    bool
+}
+
+// NewRecent keeps the last size errors.
+func NewRecent(size int) *Recent {
+	return &Recent{entries: make([]Entry, size)}
+}
+
+func (r *Recent) Report(ctx context.Context, err error, req *http.Request) {
+	e := Entry{
+		Time:      time.Now(),
+		Message:   err.Error(),
+		RequestID: chimiddleware.GetReqID(ctx),
+	}
+	if req != nil {
+		e.Method, e.Path = req.Method, req.URL.Path
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (r *Recent) Flush(context.Context) error { return nil }
+
+// Entries returns the kept errors, newest first.
+func (r *Recent) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := r.next
+	if r.full {
+		n = len(r.entries)
+	}
+	out := make([]Entry, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, r.entries[(r.next-i+len(r.entries))%len(r.entries)])
+	}
+	return out
+}
+
+// Multi reports to each of its Reporters in turn.
+type Multi []Reporter
+
+func (m Multi) Report(ctx context.Context, err error, req *http.Request) {
+	for _, rep := range m {
+		rep.Report(ctx, err, req)
+	}
+}
+
+// Flush flushes every Reporter, returning their errors joined.
+func (m Multi) Flush(ctx context.Context) error {
+	var errs []error
+	for _, rep := range m {
+		errs = append(errs, rep.Flush(ctx))
+	}
+	return errors.Join(errs...)
+}
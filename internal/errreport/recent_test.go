@@ -0,0 +1,46 @@
+package errreport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecent(t *testing.T) {
+	r := NewRecent(3)
+	if got := r.Entries(); len(got) != 0 {
+		t.Fatalf("Entries() on an empty buffer = %v", got)
+	}
+
+	for i := 1; i <= 4; i++ {
+		r.Report(context.Background(), fmt.Errorf("boom %d", i), httptest.NewRequest("GET", "/api/v1/snippets", nil))
+	}
+
+	got := r.Entries()
+	if len(got) != 3 {
+		t.Fatalf("len(Entries()) = %d, want 3", len(got))
+	}
+	for i, want := range []string{"boom 4", "boom 3", "boom 2"} {
+		if got[i].Message != want {
+			t.Errorf("Entries()[%d].Message = %q, want %q (newest first, oldest dropped)", i, got[i].Message, want)
+		}
+	}
+	if got[0].Method != "GET" || got[0].Path != "/api/v1/snippets" {
+		t.Errorf("Entries()[0] = %+v, want the request's method and path", got[0])
+	}
+}
+
+func TestMulti(t *testing.T) {
+	a, b := NewRecent(1), NewRecent(1)
+	m := Multi{a, b}
+	m.Report(context.Background(), errors.New("boom"), nil)
+
+	if len(a.Entries()) != 1 || len(b.Entries()) != 1 {
+		t.Error("Multi didn't report to every Reporter")
+	}
+	if err := m.Flush(context.Background()); err != nil {
+		t.Errorf("Flush() = %v", err)
+	}
+}
@@ -0,0 +1,364 @@
+package errreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+)
+
+// SENTRY PROTOCOL:
+// Rather than pulling in the Sentry SDK, we speak the small part of the
+// protocol we need: one HTTP POST per event to the project's "envelope"
+// endpoint. Anything that accepts Sentry envelopes (Sentry itself, GlitchTip,
+// self-hosted relays) works.
+//
+// A DSN (Data Source Name) tells the client where to send events:
+//
+//	https://<public key>@<host>/<project id>
+//
+// Events are built on the caller's goroutine (that's where the stack trace
+// is) but sent by a background goroutine, so a slow tracker never slows down
+// a response. If the queue is full, the report is dropped and logged.
+
+// SentryOptions configures a Sentry reporter. All fields are optional.
+type SentryOptions struct {
+	Environment string // e.g. "production", "staging"
+	Release     string // version or commit of the running build
+	ServerName  string // defaults to the hostname
+	QueueSize   int    // reports waiting to be sent (default 64)
+	HTTPClient  *http.Client
+	Logger      *slog.Logger
+}
+
+// Sentry is a Reporter that sends events to a Sentry-compatible service.
+type Sentry struct {
+	endpoint string
+	auth     string
+	opts     SentryOptions
+	module   string // module path, used to mark our own stack frames
+	queue    chan queued
+}
+
+// queued is either an event to send or, when flushed is set, a marker that
+// Flush uses to learn that everything before it has been sent.
+type queued struct {
+	body    []byte
+	flushed chan struct{}
+}
+
+// NewSentry creates a reporter for the given DSN and starts its sender.
+func NewSentry(dsn string, opts SentryOptions) (*Sentry, error) {
+	endpoint, key, err := parseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 64
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if opts.ServerName == "" {
+		opts.ServerName, _ = os.Hostname()
+	}
+
+	s := &Sentry{
+		endpoint: endpoint,
+		auth:     fmt.Sprintf("Sentry sentry_version=7, sentry_client=coding-playground/1.0, sentry_key=%s", key),
+		opts:     opts,
+		queue:    make(chan queued, opts.QueueSize),
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		s.module = info.Main.Path
+	}
+
+	go s.send()
+	return s, nil
+}
+
+// parseDSN turns a DSN into the envelope endpoint URL and the public key.
+func parseDSN(dsn string) (endpoint, key string, err error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid Sentry DSN: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", "", fmt.Errorf("invalid Sentry DSN: scheme must be http or https")
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return "", "", fmt.Errorf("invalid Sentry DSN: missing public key")
+	}
+
+	// The project ID is the last path segment; anything before it is a path
+	// prefix for Sentry installations served below the domain root.
+	path := strings.TrimSuffix(u.Path, "/")
+	i := strings.LastIndex(path, "/")
+	prefix, project := path[:i+1], path[i+1:]
+	if project == "" {
+		return "", "", fmt.Errorf("invalid Sentry DSN: missing project ID")
+	}
+
+	endpoint = fmt.Sprintf("%s://%s%sapi/%s/envelope/", u.Scheme, u.Host, prefix, project)
+	return endpoint, u.User.Username(), nil
+}
+
+// Report queues err for delivery. It never blocks.
+func (s *Sentry) Report(ctx context.Context, err error, req *http.Request) {
+	event := s.newEvent(ctx, err, req)
+
+	body, mErr := envelope(event)
+	if mErr != nil {
+		s.opts.Logger.ErrorContext(ctx, "failed to encode error report", slog.String("error", mErr.Error()))
+		return
+	}
+
+	select {
+	case s.queue <- queued{body: body}:
+	default:
+		s.opts.Logger.WarnContext(ctx, "error report dropped: queue full", slog.String("event_id", event.EventID))
+	}
+}
+
+// Flush waits until every report queued before the call has been sent.
+func (s *Sentry) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case s.queue <- queued{flushed: done}:
+	case <-ctx.Done():
+		return fmt.Errorf("errreport: flush: %w", ctx.Err())
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("errreport: flush: %w", ctx.Err())
+	}
+}
+
+// send delivers queued events one at a time, in order.
+func (s *Sentry) send() {
+	for item := range s.queue {
+		if item.flushed != nil {
+			close(item.flushed)
+			continue
+		}
+		if err := s.post(item.body); err != nil {
+			s.opts.Logger.Warn("failed to send error report", slog.String("error", err.Error()))
+		}
+	}
+}
+
+func (s *Sentry) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+
+	resp, err := s.opts.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("error tracker responded %s", resp.Status)
+	}
+	return nil
+}
+
+// event is the subset of the Sentry event payload we fill in.
+type event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Level       string            `json:"level"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Extra       map[string]any    `json:"extra,omitempty"` // metadata from apperror.Wrap
+	Request     *eventRequest     `json:"request,omitempty"`
+	Exception   struct {
+		Values []exception `json:"values"`
+	} `json:"exception"`
+}
+
+type eventRequest struct {
+	URL         string            `json:"url"`
+	Method      string            `json:"method"`
+	QueryString string            `json:"query_string,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+type exception struct {
+	Type       string     `json:"type"`
+	Value      string     `json:"value"`
+	Stacktrace stacktrace `json:"stacktrace"`
+}
+
+type stacktrace struct {
+	Frames []frame `json:"frames"`
+}
+
+type frame struct {
+	Function string `json:"function"`
+	Filename string `json:"filename"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+// sensitiveHeaders are never sent to the error tracker.
+var sensitiveHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+}
+
+func (s *Sentry) newEvent(ctx context.Context, err error, req *http.Request) event {
+	ev := event{
+		EventID:     newEventID(),
+		Timestamp:   time.Now().UTC(),
+		Platform:    "go",
+		Level:       "error",
+		Environment: s.opts.Environment,
+		Release:     s.opts.Release,
+		ServerName:  s.opts.ServerName,
+	}
+
+	if id := chimiddleware.GetReqID(ctx); id != "" {
+		ev.Tags = map[string]string{"request_id": id}
+	}
+
+	if req != nil {
+		ev.Request = &eventRequest{
+			URL:         requestURL(req),
+			Method:      req.Method,
+			QueryString: req.URL.RawQuery,
+			Headers:     make(map[string]string),
+		}
+		for name := range req.Header {
+			if !sensitiveHeaders[name] {
+				ev.Request.Headers[name] = req.Header.Get(name)
+			}
+		}
+	}
+
+	if meta := apperror.Meta(err); len(meta) > 0 {
+		ev.Extra = make(map[string]any, len(meta))
+		for _, a := range meta {
+			ev.Extra[a.Key] = a.Value.Resolve().Any()
+		}
+	}
+
+	// The stack where the error was wrapped, if it was captured, says more
+	// than the stack of the handler reporting it.
+	frames := s.frames(apperror.Stack(err))
+	if frames == nil {
+		frames = s.callers()
+	}
+	ev.Exception.Values = []exception{{
+		Type:       errorType(err),
+		Value:      err.Error(),
+		Stacktrace: stacktrace{Frames: frames},
+	}}
+	return ev
+}
+
+// callers captures the stack of whoever called Report.
+func (s *Sentry) callers() []frame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(4, pcs) // skip Callers, callers, newEvent, Report
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack []runtime.Frame
+	for {
+		f, more := frames.Next()
+		stack = append(stack, f)
+		if !more {
+			break
+		}
+	}
+	return s.frames(stack)
+}
+
+// frames converts a stack, innermost first, to Sentry frames. Sentry wants
+// the outermost frame first, the reverse of runtime.Callers.
+func (s *Sentry) frames(stack []runtime.Frame) []frame {
+	if len(stack) == 0 {
+		return nil
+	}
+	out := make([]frame, len(stack))
+	for i, f := range stack {
+		out[len(stack)-1-i] = frame{
+			Function: f.Function,
+			Filename: f.File,
+			Lineno:   f.Line,
+			InApp:    s.module != "" && strings.HasPrefix(f.Function, s.module),
+		}
+	}
+	return out
+}
+
+// errorType names the innermost error in the chain, which is usually the most
+// useful thing to group by (e.g. "*fs.PathError" rather than "*fmt.wrapError").
+func errorType(err error) string {
+	for {
+		inner := errors.Unwrap(err)
+		if inner == nil {
+			return fmt.Sprintf("%T", err)
+		}
+		err = inner
+	}
+}
+
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.Path
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// envelope wraps an event in the envelope format: a header line, an item
+// header line and the event itself, each terminated by a newline.
+func envelope(ev event) ([]byte, error) {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `{"event_id":%q,"sent_at":%q}`+"\n", ev.EventID, time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(&buf, `{"type":"event","length":%d}`+"\n", len(payload))
+	buf.Write(payload)
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,133 @@
+package errreport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+)
+
+func TestParseDSN(t *testing.T) {
+	tests := []struct {
+		dsn      string
+		endpoint string
+		key      string
+		wantErr  bool
+	}{
+		{dsn: "https://abc@o1.ingest.sentry.io/42", endpoint: "https://o1.ingest.sentry.io/api/42/envelope/", key: "abc"},
+		{dsn: "http://abc@localhost:9000/sentry/7", endpoint: "http://localhost:9000/sentry/api/7/envelope/", key: "abc"},
+		{dsn: "https://o1.ingest.sentry.io/42", wantErr: true},   // no key
+		{dsn: "https://abc@o1.ingest.sentry.io/", wantErr: true}, // no project
+		{dsn: "ftp://abc@example.com/1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		endpoint, key, err := parseDSN(tt.dsn)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseDSN(%q): expected an error", tt.dsn)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseDSN(%q): %v", tt.dsn, err)
+			continue
+		}
+		if endpoint != tt.endpoint || key != tt.key {
+			t.Errorf("parseDSN(%q) = %q, %q; want %q, %q", tt.dsn, endpoint, key, tt.endpoint, tt.key)
+		}
+	}
+}
+
+func TestSentry_ReportSendsEnvelope(t *testing.T) {
+	type received struct {
+		path, auth string
+		body       []byte
+	}
+	got := make(chan received, 1)
+	tracker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- received{r.URL.Path, r.Header.Get("X-Sentry-Auth"), body}
+	}))
+	defer tracker.Close()
+
+	dsn := strings.Replace(tracker.URL, "http://", "http://pubkey@", 1) + "/5"
+	rep, err := NewSentry(dsn, SentryOptions{Environment: "test"})
+	if err != nil {
+		t.Fatalf("NewSentry: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/snippets?limit=5", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("User-Agent", "test-agent")
+	ctx := context.WithValue(req.Context(), chimiddleware.RequestIDKey, "req-7")
+
+	rep.Report(ctx, apperror.Wrap(errors.New("database is locked")).WithMeta("snippet_id", "abc"), req)
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := rep.Flush(flushCtx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	var r received
+	select {
+	case r = <-got:
+	default:
+		t.Fatal("Flush returned before the report was sent")
+	}
+
+	if r.path != "/api/5/envelope/" {
+		t.Errorf("posted to %q, want /api/5/envelope/", r.path)
+	}
+	if !strings.Contains(r.auth, "sentry_key=pubkey") {
+		t.Errorf("X-Sentry-Auth = %q, want the DSN's key", r.auth)
+	}
+
+	// Envelope: header line, item header line, event.
+	lines := bufio.NewScanner(bytes.NewReader(r.body))
+	var parts [][]byte
+	for lines.Scan() {
+		parts = append(parts, append([]byte(nil), lines.Bytes()...))
+	}
+	if len(parts) != 3 {
+		t.Fatalf("envelope has %d lines, want 3:\n%s", len(parts), r.body)
+	}
+
+	var ev event
+	if err := json.Unmarshal(parts[2], &ev); err != nil {
+		t.Fatalf("decoding event: %v", err)
+	}
+	if ev.Environment != "test" || ev.Tags["request_id"] != "req-7" {
+		t.Errorf("environment = %q, request_id tag = %q", ev.Environment, ev.Tags["request_id"])
+	}
+	if len(ev.Exception.Values) != 1 || ev.Exception.Values[0].Value != "database is locked" {
+		t.Fatalf("exception = %+v", ev.Exception.Values)
+	}
+	if len(ev.Exception.Values[0].Stacktrace.Frames) == 0 {
+		t.Error("exception has no stack frames")
+	}
+	if ev.Extra["snippet_id"] != "abc" {
+		t.Errorf("extra = %v, want the error's metadata", ev.Extra)
+	}
+	if ev.Request == nil || ev.Request.QueryString != "limit=5" {
+		t.Fatalf("request = %+v", ev.Request)
+	}
+	if _, leaked := ev.Request.Headers["Authorization"]; leaked {
+		t.Error("Authorization header was sent to the tracker")
+	}
+	if ev.Request.Headers["User-Agent"] != "test-agent" {
+		t.Errorf("User-Agent header = %q", ev.Request.Headers["User-Agent"])
+	}
+}
@@ -0,0 +1,147 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+)
+
+// FALLBACK CHAIN:
+// A Chain tries backends in order — typically Docker, then a local
+// interpreter — so a Docker daemon restart degrades the playground instead
+// of turning every run into a 500. A backend is skipped when it says it's
+// unhealthy (HealthChecker) and abandoned when Execute fails; the next one
+// gets the run. When none is left the error wraps apperror.Unavailable,
+// which the API reports as 503 with a Retry-After.
+//
+// A run is never started twice once the user has seen it: if a backend
+// fails after streaming some output, that failure is returned as it is.
+// And a failure caused by the caller's own context (client gone, deadline
+// passed) ends the chain — another backend wouldn't do better.
+
+// errUnavailable is what a Chain returns when no backend could run the code.
+var errUnavailable = apperror.Unavailable("code execution").WithCode(apperror.CodeExecutionUnavailable)
+
+// Backend is one executor in a Chain.
+type Backend struct {
+	Name     string // for logs, e.g. "docker"
+	Executor Executor
+}
+
+// Chain is an Executor that falls back through its backends.
+type Chain struct {
+	backends []Backend
+	logger   *slog.Logger
+}
+
+var (
+	_ Executor      = (*Chain)(nil)
+	_ Streamer      = (*Chain)(nil)
+	_ StatsProvider = (*Chain)(nil)
+	_ HealthChecker = (*Chain)(nil)
+)
+
+// NewChain creates a Chain trying backends in the order given. A Chain with
+// no backends fails every run as unavailable.
+func NewChain(logger *slog.Logger, backends ...Backend) *Chain {
+	return &Chain{
+		backends: backends,
+		logger:   logger,
+	}
+}
+
+// Execute runs req on the first backend that can.
+func (c *Chain) Execute(ctx context.Context, req ExecutionRequest) (*ExecutionResult, error) {
+	return c.ExecuteStream(ctx, req, nil)
+}
+
+// ExecuteStream runs req on the first backend that can, passing output to
+// out as it arrives. Backends that can't stream send theirs when they finish.
+// out may be nil.
+func (c *Chain) ExecuteStream(ctx context.Context, req ExecutionRequest, out func(Output)) (*ExecutionResult, error) {
+	var lastErr error
+	for _, b := range c.backends {
+		if hc, ok := b.Executor.(HealthChecker); ok {
+			if err := hc.Healthy(ctx); err != nil {
+				c.logger.WarnContext(ctx, "executor backend unhealthy, skipping",
+					slog.String("backend", b.Name),
+					slog.String("error", err.Error()),
+				)
+				lastErr = err
+				continue
+			}
+		}
+
+		wrote := false
+		result, err := run(ctx, b.Executor, req, func(o Output) {
+			wrote = true
+			if out != nil {
+				out(o)
+			}
+		})
+		if err == nil {
+			return result, nil
+		}
+		if wrote || ctx.Err() != nil {
+			return nil, err
+		}
+		c.logger.WarnContext(ctx, "executor backend failed, trying the next",
+			slog.String("backend", b.Name),
+			slog.String("error", err.Error()),
+		)
+		lastErr = err
+	}
+
+	if lastErr == nil {
+		return nil, errUnavailable
+	}
+	return nil, fmt.Errorf("executor: no backend could run the code (last: %v): %w", lastErr, errUnavailable)
+}
+
+// run is ExecuteStream for any executor: one that can't stream has its
+// output passed to out in one piece per stream once it's done.
+func run(ctx context.Context, e Executor, req ExecutionRequest, out func(Output)) (*ExecutionResult, error) {
+	if s, ok := e.(Streamer); ok {
+		return s.ExecuteStream(ctx, req, out)
+	}
+	result, err := e.Execute(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if result.Stdout != "" {
+		out(Output{Stream: "stdout", Text: result.Stdout})
+	}
+	if result.Stderr != "" {
+		out(Output{Stream: "stderr", Text: result.Stderr})
+	}
+	return result, nil
+}
+
+// Stats reports the first backend that keeps statistics — the sandbox pool,
+// not the fallbacks behind it.
+func (c *Chain) Stats() Stats {
+	for _, b := range c.backends {
+		if sp, ok := b.Executor.(StatsProvider); ok {
+			return sp.Stats()
+		}
+	}
+	return Stats{}
+}
+
+// Healthy returns nil if any backend is healthy, or else the last one's
+// reason.
+func (c *Chain) Healthy(ctx context.Context) error {
+	var err error = errUnavailable
+	for _, b := range c.backends {
+		hc, ok := b.Executor.(HealthChecker)
+		if !ok {
+			return nil
+		}
+		if err = hc.Healthy(ctx); err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,115 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+)
+
+// stubBackend answers every run the same way, counting them.
+type stubBackend struct {
+	healthErr error
+	output    []Output // streamed before failing or succeeding
+	err       error
+	stats     Stats
+	runs      int
+}
+
+func (b *stubBackend) Execute(ctx context.Context, req ExecutionRequest) (*ExecutionResult, error) {
+	return b.ExecuteStream(ctx, req, nil)
+}
+
+func (b *stubBackend) ExecuteStream(_ context.Context, req ExecutionRequest, out func(Output)) (*ExecutionResult, error) {
+	b.runs++
+	for _, o := range b.output {
+		if out != nil {
+			out(o)
+		}
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	return &ExecutionResult{Stdout: req.Code}, nil
+}
+
+func (b *stubBackend) Healthy(context.Context) error { return b.healthErr }
+
+func (b *stubBackend) Stats() Stats { return b.stats }
+
+// plainBackend can't stream, report health or keep stats.
+type plainBackend struct{}
+
+func (plainBackend) Execute(_ context.Context, req ExecutionRequest) (*ExecutionResult, error) {
+	return &ExecutionResult{Stdout: "plain: " + req.Code}, nil
+}
+
+func TestChain(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+	req := ExecutionRequest{Code: "print(1)"}
+
+	t.Run("first healthy backend runs", func(t *testing.T) {
+		down := &stubBackend{healthErr: errors.New("daemon restarting")}
+		up := &stubBackend{}
+		res, err := NewChain(logger, Backend{"docker", down}, Backend{"local", up}).Execute(ctx, req)
+		if err != nil || res.Stdout != "print(1)" {
+			t.Fatalf("Execute() = %+v, %v", res, err)
+		}
+		if down.runs != 0 || up.runs != 1 {
+			t.Errorf("runs = %d, %d; want the unhealthy backend skipped", down.runs, up.runs)
+		}
+	})
+
+	t.Run("a failure falls through", func(t *testing.T) {
+		var streamed []Output
+		chain := NewChain(logger, Backend{"docker", &stubBackend{err: errors.New("exec create failed")}}, Backend{"local", plainBackend{}})
+		res, err := chain.ExecuteStream(ctx, req, func(o Output) { streamed = append(streamed, o) })
+		if err != nil || res.Stdout != "plain: print(1)" {
+			t.Fatalf("ExecuteStream() = %+v, %v", res, err)
+		}
+		if len(streamed) != 1 || streamed[0] != (Output{Stream: "stdout", Text: "plain: print(1)"}) {
+			t.Errorf("streamed %+v, want the non-streaming backend's output in one piece", streamed)
+		}
+	})
+
+	t.Run("output already sent is not repeated", func(t *testing.T) {
+		next := &stubBackend{}
+		chain := NewChain(logger,
+			Backend{"docker", &stubBackend{output: []Output{{"stdout", "half"}}, err: errors.New("connection reset")}},
+			Backend{"local", next})
+		if _, err := chain.Execute(ctx, req); err == nil || errors.Is(err, apperror.ErrUnavailable) {
+			t.Errorf("Execute() error = %v, want the backend's own", err)
+		}
+		if next.runs != 0 {
+			t.Error("the run was started again on the next backend")
+		}
+	})
+
+	t.Run("nothing left is unavailable", func(t *testing.T) {
+		for _, chain := range []*Chain{
+			NewChain(logger),
+			NewChain(logger, Backend{"docker", &stubBackend{healthErr: errors.New("down")}}, Backend{"local", &stubBackend{err: errors.New("no python")}}),
+		} {
+			if _, err := chain.Execute(ctx, req); !errors.Is(err, apperror.ErrUnavailable) {
+				t.Errorf("Execute() error = %v, want unavailable", err)
+			}
+		}
+		if err := NewChain(logger).Healthy(ctx); !errors.Is(err, apperror.ErrUnavailable) {
+			t.Errorf("Healthy() with no backends = %v, want unavailable", err)
+		}
+	})
+
+	t.Run("stats come from the first backend with them", func(t *testing.T) {
+		chain := NewChain(logger, Backend{"local", plainBackend{}}, Backend{"docker", &stubBackend{stats: Stats{PoolSize: 3}}})
+		if got := chain.Stats(); got.PoolSize != 3 {
+			t.Errorf("Stats() = %+v", got)
+		}
+		if err := chain.Healthy(ctx); err != nil {
+			t.Errorf("Healthy() = %v; a backend without a health check counts as healthy", err)
+		}
+	})
+}
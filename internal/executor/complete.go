@@ -0,0 +1,108 @@
+package executor
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// COMPLETION MODE:
+// The editor's autocomplete asks what could come next at the cursor. The
+// answer needs Python — jedi, or at least the interpreter's own builtins and
+// modules — so like RunTests, Complete wraps the code in a harness
+// (complete.py) and runs that in the sandbox. The harness only reads the
+// code; nothing in it runs, so a half-typed `while True:` can't hang it.
+//
+// Completions come from jedi if the sandbox image has it installed, and
+// otherwise from a basic engine in the harness: keywords, builtins, the
+// names the code defines and imports, and the attributes of the modules it
+// imports. Completions.Engine says which answered.
+
+//go:embed complete.py
+var completer string
+
+// MaxCompletions caps how many completions Complete returns.
+const MaxCompletions = 50
+
+// ErrCompletionProgramTooLarge is returned when the code doesn't fit in the
+// harness, even compressed.
+var ErrCompletionProgramTooLarge = errors.New("executor: code is too large to complete")
+
+// Completion is one thing that could be typed at the cursor.
+type Completion struct {
+	Name     string `json:"name"`     // the whole name: "append"
+	Complete string `json:"complete"` // what's left to type of it: "pend" after "ap"
+	// Type is what the name is: "module", "class", "function", "instance",
+	// "keyword", "statement" (a variable), and with jedi also "param",
+	// "property" and "path".
+	Type string `json:"type"`
+}
+
+// Completions are the completions at a position, best first.
+type Completions struct {
+	Engine      string       `json:"engine"` // "jedi" or "basic"
+	Completions []Completion `json:"completions"`
+}
+
+// Complete returns the completions in code at line (1-based) and column
+// (0-based, in characters), running the harness on exec.
+func Complete(ctx context.Context, exec Executor, code string, line, column int) (*Completions, error) {
+	marker, err := newMarker("completions")
+	if err != nil {
+		return nil, err
+	}
+	program, err := completeProgram(code, line, column, marker)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := exec.Execute(ctx, ExecutionRequest{Code: program})
+	if err != nil {
+		return nil, err
+	}
+	return parseCompletions(result, marker)
+}
+
+// completeProgram fills the harness in with the code, the position and
+// marker.
+func completeProgram(code string, line, column int, marker string) (string, error) {
+	encoded, err := compress(code)
+	if err != nil {
+		return "", err
+	}
+	program := strings.NewReplacer(
+		"__MARKER__", marker,
+		"__SOURCE__", encoded,
+		"__LINE__", strconv.Itoa(line),
+		"__COLUMN__", strconv.Itoa(column),
+		"__LIMIT__", strconv.Itoa(MaxCompletions),
+	).Replace(completer)
+	if len(program) > MaxTestProgramBytes {
+		return "", ErrCompletionProgramTooLarge
+	}
+	return program, nil
+}
+
+// parseCompletions finds the harness's completions in its output.
+func parseCompletions(result *ExecutionResult, marker string) (*Completions, error) {
+	i := strings.LastIndex(result.Stdout, "\n"+marker)
+	if i < 0 {
+		if result.ExitCode == 124 {
+			return nil, errors.New("executor: completion timed out")
+		}
+		return nil, fmt.Errorf("executor: completion failed: %s", lastLine(result.Stderr))
+	}
+	var c Completions
+	line, _, _ := strings.Cut(result.Stdout[i+1+len(marker):], "\n")
+	if err := json.Unmarshal([]byte(line), &c); err != nil {
+		return nil, fmt.Errorf("executor: reading completions: %w", err)
+	}
+	if c.Completions == nil {
+		c.Completions = []Completion{}
+	}
+	return &c, nil
+}
@@ -0,0 +1,146 @@
+# Completion harness, filled in and run by Complete (complete.go).
+#
+# The code being edited arrives zlib-compressed and base64-encoded, like the
+# solution in testrunner.py, with the cursor's position: LINE is 1-based and
+# COLUMN a 0-based count of characters, as in jedi. The code is read, never
+# run.
+#
+# jedi does the work when the sandbox image has it (pip install jedi). The
+# stock image doesn't, so there's a basic engine to fall back on: keywords,
+# builtins, the names the code defines or imports, and the attributes of
+# modules it imports — enough for a learner's editor, with no type inference.
+#
+# The completions are one JSON line after a marker, printed last.
+
+import base64
+import builtins
+import importlib
+import json
+import keyword
+import re
+import sys
+import zlib
+
+MARKER = "__MARKER__"
+SOURCE = zlib.decompress(base64.b64decode("__SOURCE__")).decode()
+LINE = __LINE__
+COLUMN = __COLUMN__
+LIMIT = __LIMIT__
+
+_DEFINITIONS = [
+    (re.compile(r"^\s*(?:async\s+)?def\s+([A-Za-z_]\w*)", re.M), "function"),
+    (re.compile(r"^\s*class\s+([A-Za-z_]\w*)", re.M), "class"),
+    (re.compile(r"^\s*([A-Za-z_]\w*)\s*(?::[^=\n]*)?=(?!=)", re.M), "statement"),
+    (re.compile(r"^\s*(?:async\s+)?for\s+([A-Za-z_]\w*)", re.M), "statement"),
+    (re.compile(r"\bas\s+([A-Za-z_]\w*)"), "statement"),
+]
+_IMPORT = re.compile(r"^[ \t]*import[ \t]+([\w., \t]+)$", re.M)
+_FROM_IMPORT = re.compile(r"^[ \t]*from[ \t]+([\w.]+)[ \t]+import[ \t]+(?:\(([^)]*)\)|([\w, \t]+))", re.M)
+
+
+def _jedi(limit):
+    import jedi
+
+    script = jedi.Script(SOURCE, path="main.py")
+    return [
+        {"name": c.name, "complete": c.complete, "type": c.type}
+        for c in script.complete(LINE, COLUMN)[:limit]
+    ]
+
+
+def _kind(value):
+    if isinstance(value, type):
+        return "class"
+    if callable(value):
+        return "function"
+    if type(value).__name__ == "module":
+        return "module"
+    return "instance"
+
+
+def _imports():
+    # Maps each name an import statement binds to the module it names.
+    modules = {}
+    for m in _IMPORT.finditer(SOURCE):
+        for spec in m.group(1).split(","):
+            name, _, alias = spec.strip().partition(" as ")
+            if name:
+                root = name.split(".")[0]
+                modules[alias.strip() or root] = name if alias else root
+    for m in _FROM_IMPORT.finditer(SOURCE):
+        for spec in (m.group(2) or m.group(3)).split(","):
+            name, _, alias = spec.strip().partition(" as ")
+            if name:
+                modules.setdefault(alias.strip() or name, m.group(1) + "." + name)
+    return modules
+
+
+def _attributes(path, modules):
+    # The attributes of a module the code imports: "os.pa" completes to
+    # os.path. Importing only ever loads installed modules, not the code.
+    root, _, rest = path.partition(".")
+    if root not in modules:
+        return {}
+    try:
+        obj = importlib.import_module(modules[root])
+    except Exception:
+        parent, _, attr = modules[root].rpartition(".")
+        try:
+            obj = getattr(importlib.import_module(parent), attr)
+        except Exception:
+            return {}
+    for attr in filter(None, rest.split(".")):
+        try:
+            obj = getattr(obj, attr)
+        except AttributeError:
+            return {}
+    names = {}
+    for name in dir(obj):
+        try:
+            names[name] = _kind(getattr(obj, name))
+        except Exception:
+            names[name] = "instance"
+    return names
+
+
+def _basic(limit):
+    lines = SOURCE.split("\n")
+    before = lines[LINE - 1][:COLUMN] if LINE <= len(lines) else ""
+    m = re.search(r"([A-Za-z_][\w.]*)$", before)
+    word = m.group(1) if m else ""
+    if before.endswith(".") and not word:
+        return []  # after a literal, say "abc".; that needs type inference
+    modules = _imports()
+
+    if "." in word:
+        path, _, prefix = word.rpartition(".")
+        names = _attributes(path, modules)
+    else:
+        prefix = word
+        names = {}
+        for name in dir(builtins):
+            names[name] = _kind(getattr(builtins, name))
+        for name in modules:
+            names[name] = "module"
+        for pattern, kind in _DEFINITIONS:
+            for d in pattern.finditer(SOURCE):
+                names.setdefault(d.group(1), kind)
+        for name in keyword.kwlist:
+            names[name] = "keyword"
+
+    matches = [n for n in names if n.startswith(prefix)]
+    # Public names first; _private, then __dunder__, only when asked for.
+    matches.sort(key=lambda n: (n.startswith("__"), n.startswith("_"), n.lower()))
+    return [{"name": n, "complete": n[len(prefix):], "type": names[n]} for n in matches[:limit]]
+
+
+def main():
+    try:
+        result = {"engine": "jedi", "completions": _jedi(LIMIT)}
+    except Exception:  # no jedi in the image, or it choked on the code
+        result = {"engine": "basic", "completions": _basic(LIMIT)}
+    sys.stdout.flush()
+    print("\n" + MARKER + json.dumps(result))
+
+
+main()
@@ -0,0 +1,105 @@
+package executor
+
+import (
+	"context"
+	"os/exec"
+	"slices"
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not installed")
+	}
+	engines := map[string]Executor{
+		"basic": withoutJedi{localPython{python}},
+	}
+	if exec.Command(python, "-c", "import jedi").Run() == nil {
+		engines["jedi"] = localPython{python}
+	}
+	for engine, py := range engines {
+		t.Run(engine, func(t *testing.T) { testComplete(t, py, engine) })
+	}
+}
+
+// withoutJedi runs programs as if jedi weren't installed.
+type withoutJedi struct{ Executor }
+
+func (e withoutJedi) Execute(ctx context.Context, req ExecutionRequest) (*ExecutionResult, error) {
+	req.Code = "import sys; sys.modules['jedi'] = None\n" + req.Code
+	return e.Executor.Execute(ctx, req)
+}
+
+// testComplete checks what both engines should agree on.
+func testComplete(t *testing.T, py Executor, engine string) {
+	names := func(c *Completions) []string {
+		var names []string
+		for _, completion := range c.Completions {
+			names = append(names, completion.Name)
+		}
+		return names
+	}
+	complete := func(t *testing.T, code string, line, column int) *Completions {
+		t.Helper()
+		c, err := Complete(context.Background(), py, code, line, column)
+		if err != nil {
+			t.Fatalf("Complete() error = %v", err)
+		}
+		if c.Engine != engine {
+			t.Fatalf("Engine = %q, want %q", c.Engine, engine)
+		}
+		return c
+	}
+
+	t.Run("names the code defines", func(t *testing.T) {
+		code := "def greet(name):\n    return 'hi ' + name\n\ngreeting = greet('ada')\ngre"
+		c := complete(t, code, 5, 3)
+		got := names(c)
+		if !slices.Contains(got, "greet") || !slices.Contains(got, "greeting") {
+			t.Fatalf("completions = %q, want greet and greeting", got)
+		}
+		for _, completion := range c.Completions {
+			if completion.Name == "greet" && (completion.Complete != "et" || completion.Type != "function") {
+				t.Errorf("greet = %+v", completion)
+			}
+		}
+	})
+
+	t.Run("builtins and keywords", func(t *testing.T) {
+		c := complete(t, "pri", 1, 3)
+		if got := names(c); !slices.Contains(got, "print") {
+			t.Errorf("completions = %q, want print", got)
+		}
+		c = complete(t, "whi", 1, 3)
+		if len(c.Completions) == 0 || c.Completions[0] != (Completion{Name: "while", Complete: "le", Type: "keyword"}) {
+			t.Errorf("completions = %+v, want while first", c.Completions)
+		}
+	})
+
+	t.Run("attributes of an imported module", func(t *testing.T) {
+		c := complete(t, "import os.path as p, math\nmath.sq", 2, 7)
+		if got := names(c); !slices.Equal(got, []string{"sqrt"}) {
+			t.Errorf("completions = %q, want [sqrt]", got)
+		}
+		c = complete(t, "from os import path\npath.jo", 2, 7)
+		if got := names(c); !slices.Equal(got, []string{"join"}) {
+			t.Errorf("completions = %q, want [join]", got)
+		}
+	})
+
+	t.Run("code isn't run", func(t *testing.T) {
+		c := complete(t, "while True:\n    pass\nTr", 3, 2)
+		if got := names(c); !slices.Contains(got, "True") {
+			t.Errorf("completions = %q, want True", got)
+		}
+	})
+
+	t.Run("private names last", func(t *testing.T) {
+		c := complete(t, "import math\nmath.", 2, 5)
+		got := names(c)
+		if len(got) == 0 || got[0] == "__doc__" || len(got) > MaxCompletions {
+			t.Errorf("completions = %q, want public names first, at most %d", got, MaxCompletions)
+		}
+	})
+}
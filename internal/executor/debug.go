@@ -0,0 +1,173 @@
+package executor
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DEBUG MODE:
+// A learner sets breakpoints in the editor and steps through the program,
+// watching the variables change. Debug runs the code under pdb in the
+// sandbox (see debug.py) with commands — "next", "step", "p total" — read
+// from stdin, and turns what comes back into DebugEvents:
+//
+//	{"kind":"paused","line":3,"function":"add","locals":{"a":"1"},"stack":[…]}
+//	{"kind":"debugger","text":"> <main>(3)add()\n-> return a + b\n"}
+//	{"kind":"output","stream":"stdout","text":"x is 3\n"}
+//	{"kind":"running"}
+//
+// Commands have to reach a program that's already running, so the
+// executor needs to support ExecutionRequest.Stdin; to see events as they
+// happen, it needs to be a Streamer too. One that isn't gets them all at
+// the end, which is only useful when the commands were all known up front.
+
+//go:embed debug.py
+var debugHarness string
+
+// MaxDebugProgramBytes caps the harness with the code in it, like
+// MaxTestProgramBytes.
+const MaxDebugProgramBytes = MaxTestProgramBytes
+
+// ErrDebugProgramTooLarge is returned when the code doesn't fit in
+// MaxDebugProgramBytes.
+var ErrDebugProgramTooLarge = errors.New("executor: the code is too large to debug")
+
+// DebugEvent is something that happened in a debugging session.
+type DebugEvent struct {
+	// Kind is "paused" (pdb is waiting for a command), "running" (a
+	// command let the program go on), "debugger" (pdb said something) or
+	// "output" (the program did).
+	Kind   string `json:"kind"`
+	Stream string `json:"stream,omitempty"` // "stdout" or "stderr", for output
+	Text   string `json:"text,omitempty"`   // for output and debugger
+
+	// Where the program is paused, for paused.
+	Line     int               `json:"line,omitempty"`
+	Function string            `json:"function,omitempty"`
+	Locals   map[string]string `json:"locals,omitempty"` // name → repr, cut at 80 characters
+	Stack    []DebugFrame      `json:"stack,omitempty"`  // outermost first
+	// Exception is set when the program stopped on an uncaught exception:
+	// "ZeroDivisionError: division by zero".
+	Exception string `json:"exception,omitempty"`
+}
+
+// DebugFrame is a call on the stack of a paused program.
+type DebugFrame struct {
+	Function string `json:"function"`
+	Line     int    `json:"line"`
+}
+
+// Debug runs code under pdb on exec, stopping at breakpoints (1-based line
+// numbers) or, without any, on the first line. Commands are read from
+// commands a line at a time; closing it — or cancelling ctx — ends the
+// session. Every event goes to events, from one goroutine at a time. The
+// result's Stdout is the program's output alone.
+func Debug(ctx context.Context, exec Executor, code string, breakpoints []int, commands io.Reader, events func(DebugEvent)) (*ExecutionResult, error) {
+	marker, err := newMarker("debug")
+	if err != nil {
+		return nil, err
+	}
+	source, err := compress(code)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, len(breakpoints))
+	for i, line := range breakpoints {
+		lines[i] = strconv.Itoa(line)
+	}
+	program := strings.NewReplacer(
+		"__MARKER__", marker,
+		"__SOURCE__", source,
+		"__BREAKPOINTS__", strings.Join(lines, ", "),
+	).Replace(debugHarness)
+	if len(program) > MaxDebugProgramBytes {
+		return nil, ErrDebugProgramTooLarge
+	}
+
+	parser := &debugParser{marker: "\n" + marker, events: events}
+	out := func(o Output) {
+		if o.Stream == "stdout" {
+			parser.write(o.Text)
+		} else if o.Text != "" {
+			events(DebugEvent{Kind: "output", Stream: o.Stream, Text: o.Text})
+		}
+	}
+	req := ExecutionRequest{Code: program, Stdin: commands}
+	var result *ExecutionResult
+	if s, ok := exec.(Streamer); ok {
+		result, err = s.ExecuteStream(ctx, req, out)
+	} else if result, err = exec.Execute(ctx, req); err == nil {
+		out(Output{Stream: "stdout", Text: result.Stdout})
+		out(Output{Stream: "stderr", Text: result.Stderr})
+	}
+	if err != nil {
+		return nil, err
+	}
+	parser.close()
+	result.Stdout = parser.stdout.String()
+	return result, nil
+}
+
+// debugParser splits the harness's stdout into the program's output and
+// the harness's events, which are each "\n"+marker+JSON+"\n". Output
+// arrives in pieces of any size, so a piece that ends partway into a
+// marker is held back until the next one says whether it was one.
+type debugParser struct {
+	marker  string
+	events  func(DebugEvent)
+	pending string
+	stdout  strings.Builder
+}
+
+func (p *debugParser) write(text string) {
+	p.pending += text
+	for {
+		i := strings.Index(p.pending, p.marker)
+		if i < 0 {
+			break
+		}
+		end := strings.IndexByte(p.pending[i+len(p.marker):], '\n')
+		if end < 0 {
+			// The event's line isn't all here yet.
+			p.output(p.pending[:i])
+			p.pending = p.pending[i:]
+			return
+		}
+		p.output(p.pending[:i])
+		line := p.pending[i+len(p.marker) : i+len(p.marker)+end]
+		p.pending = p.pending[i+len(p.marker)+end+1:]
+		var event DebugEvent
+		if err := json.Unmarshal([]byte(line), &event); err == nil {
+			p.events(event)
+		}
+	}
+	// Keep back whatever could be the start of a marker.
+	keep := 0
+	for n := min(len(p.pending), len(p.marker)-1); n > 0; n-- {
+		if strings.HasSuffix(p.pending, p.marker[:n]) {
+			keep = n
+			break
+		}
+	}
+	p.output(p.pending[:len(p.pending)-keep])
+	p.pending = p.pending[len(p.pending)-keep:]
+}
+
+// close passes on what's left once the program has ended.
+func (p *debugParser) close() {
+	p.output(p.pending)
+	p.pending = ""
+}
+
+func (p *debugParser) output(text string) {
+	if text == "" {
+		return
+	}
+	p.stdout.WriteString(text)
+	p.events(DebugEvent{Kind: "output", Stream: "stdout", Text: text})
+}
@@ -0,0 +1,157 @@
+# Debugger harness, filled in and run by Debug (debug.go).
+#
+# The code arrives zlib-compressed and base64-encoded, like the solution in
+# testrunner.py, and runs under pdb as "<main>". pdb reads its commands from
+# stdin, one per line, exactly as typed at a (Pdb) prompt: "next", "step",
+# "continue", "p total", "break 12". A line sent while the program runs
+# rather than sits at a prompt is the program's own input().
+#
+# Everything pdb says goes out as events, not as program output: each is a
+# JSON line after a marker, so what the program prints and what the
+# debugger says can be told apart:
+#
+#   {"kind":"paused","line":3,"function":"<module>","locals":{…},"stack":[…]}
+#   {"kind":"debugger","text":"> <main>(3)<module>()\n-> x = 1\n"}
+#   {"kind":"running"}
+#
+# With BREAKPOINTS the program runs straight to the first of them; without,
+# it stops on its first line. An uncaught exception stops it there too
+# (post-mortem), with "exception" set, before it ends.
+
+import base64
+import builtins
+import json
+import linecache
+import pdb
+import reprlib
+import sys
+import traceback
+import zlib
+
+MARKER = "__MARKER__"
+SOURCE = zlib.decompress(base64.b64decode("__SOURCE__")).decode()
+BREAKPOINTS = [__BREAKPOINTS__]
+FILENAME = "<main>"
+MAX_LOCALS = 50
+
+# The program may replace sys.stdout; events always go to the real one.
+_OUT = sys.stdout
+
+_repr = reprlib.Repr()
+_repr.maxstring = 80
+_repr.maxother = 80
+
+
+def _event(event):
+    _OUT.write("\n" + MARKER + json.dumps(event) + "\n")
+    _OUT.flush()
+
+
+class _DebuggerOutput:
+    # What pdb prints, sent on as one event per command rather than one per
+    # write: pdb flushes before it waits for the next.
+
+    def __init__(self):
+        self._pending = ""
+
+    def write(self, text):
+        self._pending += text
+        return len(text)
+
+    def flush(self):
+        if self._pending:
+            _event({"kind": "debugger", "text": self._pending})
+            self._pending = ""
+
+
+def _safe_repr(value):
+    try:
+        return _repr.repr(value)
+    except Exception as e:  # a broken __repr__ in the program's own class
+        return "<repr failed: %s>" % type(e).__name__
+
+
+class _Debugger(pdb.Pdb):
+    def __init__(self):
+        super().__init__(stdin=sys.stdin, stdout=_DebuggerOutput(), nosigint=True)
+        self.prompt = ""  # the "paused" event says pdb is waiting
+        self.exception = None
+        self._starting = True
+
+    def user_line(self, frame):
+        if self._starting:
+            self._starting = False
+            if BREAKPOINTS and frame.f_lineno not in BREAKPOINTS:
+                self.set_continue()
+                return
+        super().user_line(frame)
+
+    def preloop(self):
+        super().preloop()
+        _OUT.flush()
+        frame = self.curframe
+        stack = [
+            {"function": f.f_code.co_name, "line": line}
+            for f, line in self.stack
+            if f.f_code.co_filename == FILENAME
+        ]
+        event = {
+            "kind": "paused",
+            "line": self.stack[self.curindex][1],
+            "function": frame.f_code.co_name,
+            "locals": _locals(frame),
+            "stack": stack,
+        }
+        if self.exception:
+            event["exception"] = self.exception
+        self.stdout.flush()
+        _event(event)
+
+    def postcmd(self, stop, line):
+        self.stdout.flush()
+        if stop and not self.quitting:
+            _event({"kind": "running"})
+        return stop
+
+
+def _locals(frame):
+    names = [n for n in frame.f_locals if not (n.startswith("__") and n.endswith("__"))]
+    out = {}
+    for name in names[:MAX_LOCALS]:
+        value = frame.f_locals[name]
+        if type(value).__name__ == "module":
+            continue
+        out[name] = _safe_repr(value)
+    return out
+
+
+def main():
+    code = compile(SOURCE, FILENAME, "exec")
+    linecache.cache[FILENAME] = (len(SOURCE), None, SOURCE.splitlines(True), FILENAME)
+    debugger = _Debugger()
+    for line in BREAKPOINTS:
+        error = debugger.set_break(FILENAME, line)
+        if error:
+            debugger.message("*** line %d: %s" % (line, error))
+    debugger.stdout.flush()
+
+    namespace = {"__name__": "__main__", "__builtins__": builtins}
+    try:
+        debugger.run(code, namespace)
+    except SystemExit:
+        raise
+    except BaseException as e:
+        # Show the traceback from the program's first frame, not ours.
+        tb = e.__traceback__
+        while tb is not None and tb.tb_frame.f_code.co_filename != FILENAME:
+            tb = tb.tb_next
+        sys.stdout.flush()
+        traceback.print_exception(type(e), e, tb)
+        sys.stderr.flush()
+        debugger.exception = "".join(traceback.format_exception_only(type(e), e)).strip()
+        debugger.reset()
+        debugger.interaction(None, tb)
+        sys.exit(1)
+
+
+main()
@@ -0,0 +1,131 @@
+package executor
+
+import (
+	"context"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDebug(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not installed")
+	}
+	py := localPython{python}
+
+	const code = `def add(a, b):
+    total = a + b
+    return total
+
+x = add(1, 2)
+print("x is", x)
+name = input()
+print("hello", name)
+`
+	debug := func(t *testing.T, breakpoints []int, commands string) ([]DebugEvent, *ExecutionResult) {
+		t.Helper()
+		var events []DebugEvent
+		result, err := Debug(context.Background(), py, code, breakpoints, strings.NewReader(commands), func(e DebugEvent) {
+			events = append(events, e)
+		})
+		if err != nil {
+			t.Fatalf("Debug() error = %v", err)
+		}
+		return events, result
+	}
+	paused := func(events []DebugEvent) []DebugEvent {
+		var out []DebugEvent
+		for _, e := range events {
+			if e.Kind == "paused" {
+				out = append(out, e)
+			}
+		}
+		return out
+	}
+
+	t.Run("stops on the first line and steps", func(t *testing.T) {
+		events, result := debug(t, nil, "next\nstep\nnext\ncontinue\nAda\n")
+		var lines []int
+		for _, e := range paused(events) {
+			lines = append(lines, e.Line)
+		}
+		if want := []int{1, 5, 1, 2}; !reflect.DeepEqual(lines, want) {
+			t.Errorf("paused on lines %v, want %v", lines, want)
+		}
+		if result.Stdout != "x is 3\nhello Ada\n" || result.ExitCode != 0 {
+			t.Errorf("result = %+v, want only the program's output", result)
+		}
+	})
+
+	t.Run("runs to a breakpoint", func(t *testing.T) {
+		events, _ := debug(t, []int{3}, "p total\ncontinue\nAda\n")
+		stops := paused(events)
+		if len(stops) != 1 {
+			t.Fatalf("paused %d times, want 1: %+v", len(stops), events)
+		}
+		want := DebugEvent{
+			Kind:     "paused",
+			Line:     3,
+			Function: "add",
+			Locals:   map[string]string{"a": "1", "b": "2", "total": "3"},
+			Stack:    []DebugFrame{{"<module>", 5}, {"add", 3}},
+		}
+		if !reflect.DeepEqual(stops[0], want) {
+			t.Errorf("paused = %+v, want %+v", stops[0], want)
+		}
+		var said string
+		for _, e := range events {
+			if e.Kind == "debugger" {
+				said += e.Text
+			}
+		}
+		if !strings.Contains(said, "3\n") {
+			t.Errorf("debugger said %q, want the value of total", said)
+		}
+	})
+
+	t.Run("stops on an uncaught exception", func(t *testing.T) {
+		var events []DebugEvent
+		result, err := Debug(context.Background(), py, "x = 0\ny = 1 / x\n", []int{99}, strings.NewReader("p x\n"), func(e DebugEvent) {
+			events = append(events, e)
+		})
+		if err != nil {
+			t.Fatalf("Debug() error = %v", err)
+		}
+		stops := paused(events)
+		if len(stops) != 1 || stops[0].Line != 2 || stops[0].Exception != "ZeroDivisionError: division by zero" {
+			t.Errorf("paused = %+v, want a stop on the exception", stops)
+		}
+		if result.ExitCode != 1 || !strings.Contains(result.Stderr, `File "<main>", line 2`) {
+			t.Errorf("result = %+v, want the program's traceback", result)
+		}
+	})
+}
+
+func TestDebugParser(t *testing.T) {
+	const marker = "\n@@debug-1@@"
+	stream := "partial @@debug line\n" + marker + `{"kind":"paused","line":2}` + "\n" + "done\n" + marker + `{"kind":"running"}` + "\n" + "end"
+
+	// Output can arrive cut anywhere.
+	var events []DebugEvent
+	p := &debugParser{marker: marker, events: func(e DebugEvent) { events = append(events, e) }}
+	for i := range len(stream) {
+		p.write(stream[i : i+1])
+	}
+	p.close()
+
+	var kinds []string
+	for _, e := range events {
+		if e.Kind != "output" {
+			kinds = append(kinds, e.Kind)
+		}
+	}
+	if want := []string{"paused", "running"}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("events = %v, want %v", kinds, want)
+	}
+	if got, want := p.stdout.String(), "partial @@debug line\ndone\nend"; got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+}
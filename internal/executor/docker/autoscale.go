@@ -0,0 +1,100 @@
+package docker
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/executor"
+)
+
+// ADAPTIVE POOL SIZING:
+// A fixed PoolSize is a guess. Too small, and a burst of runs waits for
+// cold containers; too big, and idle containers hold memory all night.
+// With Config.MaxPoolSize set, the executor reconsiders the size every
+// ResizeEvery using the numbers it already exports as metrics (Stats):
+//
+//   - executions waited for a sandbox (some are waiting right now, or the
+//     average wait since the last look was over growAfterWait): grow by one
+//   - otherwise, if at least shrinkAfterSpare warm containers went unused
+//     even at the busiest moment since the last look: shrink by one
+//   - otherwise, keep the size
+//
+// One step at a time, once per interval, is a deliberately simple
+// controller: it can't overshoot or oscillate faster than ResizeEvery, and
+// each decision is logged with the numbers behind it.
+//
+// A warm container is an idle process with nothing in it, so shrinking the
+// pool, not checkpointing (see checkpoint.go), is how it gives idle memory
+// back.
+
+const (
+	// growAfterWait is the average wait that counts as waiting. A warm
+	// container is handed out in microseconds; a cold one takes ~500ms.
+	growAfterWait = 50 * time.Millisecond
+	// shrinkAfterSpare is how many containers must have gone unused before
+	// one is retired. It's two, not one, so a pool that's just big enough
+	// keeps its one container of headroom.
+	shrinkAfterSpare = 2
+)
+
+// autoscaler decides pool sizes from successive Stats.
+type autoscaler struct {
+	min, max int
+	last     executor.Stats
+}
+
+// next returns the size the pool should have, given its size, the
+// executor's stats now and the containers that went unused (Pool.takeSpare).
+// reason explains a change; it's empty when the size stays.
+func (a *autoscaler) next(size int, stats executor.Stats, spare int) (newSize int, reason string) {
+	waits := stats.Waits - a.last.Waits
+	var avgWait time.Duration
+	if waits > 0 {
+		avgWait = (stats.WaitTime - a.last.WaitTime) / time.Duration(waits)
+	}
+	a.last = stats
+
+	switch {
+	case size < a.max && stats.Waiting > 0:
+		return size + 1, fmt.Sprintf("%d executions waiting for a sandbox", stats.Waiting)
+	case size < a.max && avgWait > growAfterWait:
+		return size + 1, fmt.Sprintf("executions waited %v on average", avgWait.Round(time.Millisecond))
+	case size > a.min && stats.Waiting == 0 && avgWait <= growAfterWait && spare >= shrinkAfterSpare:
+		return size - 1, fmt.Sprintf("%d warm containers went unused", spare)
+	}
+	return size, ""
+}
+
+// autoscale resizes the pool every ResizeEvery until the pool stops.
+func (e *Executor) autoscale() {
+	defer e.pool.wg.Done()
+
+	cfg := e.pool.config
+	a := &autoscaler{min: cfg.MinPoolSize, max: cfg.MaxPoolSize, last: e.Stats()}
+	ticker := time.NewTicker(cfg.ResizeEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.pool.done:
+			return
+		case <-ticker.C:
+		}
+
+		size := e.pool.Size()
+		stats := e.Stats()
+		newSize, reason := a.next(size, stats, e.pool.takeSpare())
+		if newSize == size {
+			continue
+		}
+		e.pool.SetSize(newSize)
+		e.logger.Info("resizing sandbox pool",
+			slog.Int("from", size),
+			slog.Int("to", newSize),
+			slog.String("reason", reason),
+			slog.Int("waiting", stats.Waiting),
+			slog.Int("available", stats.Available),
+		)
+	}
+}
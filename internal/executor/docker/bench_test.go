@@ -0,0 +1,119 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/executor"
+)
+
+// BENCHMARKS:
+// These time the executor and pool against fakeClient, whose calls sleep
+// for about as long as a local Docker host takes (see newFakeClient). The
+// numbers are the executor's own overhead plus those sleeps, so they're
+// stable enough to compare before and after a change:
+//
+//	go test ./internal/executor/docker -run '^$' -bench . -benchtime 2s
+//
+// A change that makes the pool slower to refill, or runs wait on something
+// they didn't before, shows up here without needing Docker.
+
+func benchLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// BenchmarkExecute compares a run on a container created for it ("cold",
+// PoolSize 0) with one on a pre-warmed container.
+func BenchmarkExecute(b *testing.B) {
+	req := executor.ExecutionRequest{Code: `print("hello")`}
+	for _, bc := range []struct {
+		name     string
+		poolSize int
+	}{
+		{"cold", 0},
+		{"pooled", 1},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			cfg := DefaultConfig()
+			cfg.PoolSize = bc.poolSize
+			exec := newExecutor(newFakeClient(), cfg, benchLogger())
+			defer exec.Close()
+			ctx := context.Background()
+
+			for b.Loop() {
+				// Give the pool a moment to refill, as it would have
+				// between a user's runs; only the run itself is timed.
+				if bc.poolSize > 0 {
+					b.StopTimer()
+					for exec.pool.Available() < bc.poolSize {
+						time.Sleep(time.Millisecond)
+					}
+					b.StartTimer()
+				}
+				if _, err := exec.Execute(ctx, req); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkPoolRefill times filling an empty pool, reporting containers
+// made ready per second.
+func BenchmarkPoolRefill(b *testing.B) {
+	for _, size := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			cfg := DefaultConfig()
+			cfg.PoolSize = size
+			var filled int
+			for b.Loop() {
+				pool := newPool(newFakeClient(), cfg, benchLogger())
+				pool.Start()
+				for pool.Available() < size {
+					time.Sleep(time.Millisecond)
+				}
+				filled += size
+				b.StopTimer()
+				pool.Stop()
+				b.StartTimer()
+			}
+			b.ReportMetric(float64(filled)/b.Elapsed().Seconds(), "containers/s")
+		})
+	}
+}
+
+// BenchmarkThroughput runs executions from many goroutines at once, for
+// several pool sizes, reporting completed runs per second. Once requests
+// outpace the refill rate the pool runs dry and runs wait for containers,
+// so this is where a slower refill shows.
+func BenchmarkThroughput(b *testing.B) {
+	req := executor.ExecutionRequest{Code: `print("hello")`}
+	for _, size := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("pool=%d", size), func(b *testing.B) {
+			cfg := DefaultConfig()
+			cfg.PoolSize = size
+			exec := newExecutor(newFakeClient(), cfg, benchLogger())
+			defer exec.Close()
+			for exec.pool.Available() < size {
+				time.Sleep(time.Millisecond)
+			}
+
+			ctx := context.Background()
+			b.SetParallelism(4)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := exec.Execute(ctx, req); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "runs/s")
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/checkpoint"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/executor"
+	"github.com/sakif/coding-playground/internal/retry"
+)
+
+// CHECKPOINT/RESTORE:
+// An interactive program — a debugging session (see executor.Debug), paused
+// at a breakpoint while the learner reads the variables — spends most of
+// its life waiting for input, and all that time its container holds on to
+// its memory: a classroom stepping through the same exercise is thirty
+// sandboxes, nearly all of them idle. With Config.CheckpointAfter set, an
+// execution that reads stdin and has been quiet that long — no input, no
+// output — is frozen with CRIU: "docker checkpoint create" writes the
+// container's processes and memory to disk and stops it. The next input
+// restores it ("docker start --checkpoint") and is passed on, and the
+// program carries on none the wiser.
+//
+// CRIU restores a container's own process, not one started with docker
+// exec, so these executions don't use the warm pool: each gets a container
+// of its own running the code directly, stdin open and attached. Runs
+// without stdin — nearly all of them, over in seconds — go the usual way,
+// and so do runs that install requirements, collect artifacts (a stopped
+// container's tmpfs is gone) or use GPUs (which CRIU can't save).
+//
+// Checkpoints need an experimental daemon ("experimental": true in
+// daemon.json) with CRIU installed. If a checkpoint fails, the session is
+// logged and left running, as it would be without the flag; if a restore
+// fails, the session ends with the error. A frozen session still counts
+// towards Timeout and keeps its run lease.
+
+// checkpoints reports whether req runs as a session that can be frozen.
+func (e *Executor) checkpoints(req executor.ExecutionRequest) bool {
+	return e.config.CheckpointAfter > 0 && req.Stdin != nil && !req.Artifacts &&
+		e.config.GPUs == 0 && (req.Requirements == "" || !e.config.installs())
+}
+
+// session is an execution running as its container's own process.
+type session struct {
+	e      *Executor
+	id     string // the container
+	stdout *streamWriter
+	stderr *streamWriter
+	active chan struct{} // poked when the program writes anything
+
+	// While the container runs:
+	attach  types.HijackedResponse
+	copied  chan struct{} // closed once attach's output has all been copied
+	exited  <-chan container.WaitResponse
+	waitErr <-chan error
+
+	frozen  string // the checkpoint the container is stopped at, or ""
+	taken   int    // checkpoints taken so far
+	noCRIU  bool   // a checkpoint failed; don't try again
+	started time.Time
+}
+
+// executeSession runs req in a container of its own, checkpointing it
+// whenever it sits idle for CheckpointAfter.
+func (e *Executor) executeSession(ctx context.Context, req executor.ExecutionRequest, out func(executor.Output)) (*executor.ExecutionResult, error) {
+	s := &session{e: e, active: make(chan struct{}, 1), started: time.Now()}
+	notify := func(o executor.Output) {
+		select {
+		case s.active <- struct{}{}:
+		default:
+		}
+		if out != nil {
+			out(o)
+		}
+	}
+	s.stdout = &streamWriter{stream: "stdout", out: notify}
+	s.stderr = &streamWriter{stream: "stderr", out: notify}
+
+	lease, err := e.pool.leases.acquire(ctx, executor.LeaseRunning, e.config.MaxRunningTotal)
+	if err != nil {
+		return nil, err
+	}
+	defer e.pool.leases.release(executor.LeaseRunning, lease)
+
+	executeCtx, executeCancel := context.WithTimeout(ctx, e.config.Timeout)
+	defer executeCancel()
+
+	s.id, err = e.createSession(executeCtx, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	defer e.pool.removeContainer(s.id) // its checkpoints go with it
+
+	if err := s.start(executeCtx, ""); err != nil {
+		if executeCtx.Err() != nil && ctx.Err() == nil {
+			return s.timedOut(), nil
+		}
+		return nil, err
+	}
+
+	// Input is read here and handed to the loop below, so the session can
+	// be frozen while the read waits. Closing stdin is the program's EOF;
+	// it's the caller's to close, like any reader it hands over.
+	input := make(chan []byte)
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := req.Stdin.Read(buf)
+			if n > 0 {
+				select {
+				case input <- bytes.Clone(buf[:n]):
+				case <-executeCtx.Done():
+					return
+				}
+			}
+			if err != nil {
+				close(input)
+				return
+			}
+		}
+	}()
+
+	idle := time.NewTimer(e.config.CheckpointAfter)
+	defer idle.Stop()
+	for {
+		select {
+		case chunk, ok := <-input:
+			if s.frozen != "" {
+				if err := s.start(executeCtx, s.frozen); err != nil {
+					if executeCtx.Err() != nil {
+						return s.timedOut(), nil
+					}
+					return nil, fmt.Errorf("restoring checkpoint: %w", err)
+				}
+			}
+			if !ok {
+				input = nil
+				_ = s.attach.CloseWrite()
+			} else {
+				// A program that has just exited can't take it; the wait
+				// below says so.
+				_, _ = s.attach.Conn.Write(chunk)
+			}
+			idle.Reset(e.config.CheckpointAfter)
+
+		case <-s.active:
+			idle.Reset(e.config.CheckpointAfter)
+
+		case <-idle.C:
+			// After EOF the program is on its way out: let it finish.
+			if s.frozen == "" && input != nil && !s.noCRIU {
+				s.checkpoint(executeCtx)
+			}
+
+		case res := <-s.exited:
+			<-s.copied
+			s.attach.Close()
+			return s.result(int(res.StatusCode), ""), nil
+
+		case err := <-s.waitErr:
+			if executeCtx.Err() != nil {
+				return s.timedOut(), nil
+			}
+			s.attach.Close()
+			<-s.copied
+			return nil, fmt.Errorf("waiting for container: %w", err)
+
+		case <-executeCtx.Done():
+			return s.timedOut(), nil
+		}
+	}
+}
+
+// createSession creates, but doesn't start, a container that runs code with
+// its stdin open, as a warm container's exec would (see runCommand).
+func (e *Executor) createSession(ctx context.Context, code string) (string, error) {
+	hostConfig := e.pool.hostConfig()
+	cmd, env := runCommand(code)
+	resp, err := retry.DoValue(ctx, e.config.retryPolicy(), func(ctx context.Context) (container.CreateResponse, error) {
+		return e.cli.ContainerCreate(ctx, &container.Config{
+			Image:        e.config.Image,
+			Cmd:          cmd,
+			Env:          env,
+			User:         "nobody",
+			OpenStdin:    true,
+			StdinOnce:    true, // closing the attached stdin is EOF
+			AttachStdin:  true,
+			AttachStdout: true,
+			AttachStderr: true,
+		}, hostConfig, nil, nil, "")
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create container: %w", err)
+	}
+	return resp.ID, nil
+}
+
+// start attaches to the container and starts it: afresh, or restored from
+// the checkpoint from.
+func (s *session) start(ctx context.Context, from string) error {
+	attach, err := s.e.cli.ContainerAttach(ctx, s.id, container.AttachOptions{
+		Stream: true,
+		Stdin:  true,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to attach to container: %w", err)
+	}
+	copied := make(chan struct{})
+	go func() {
+		_, _ = stdcopy.StdCopy(s.stdout, s.stderr, attach.Reader)
+		close(copied)
+	}()
+
+	if err := s.e.cli.ContainerStart(ctx, s.id, container.StartOptions{CheckpointID: from}); err != nil {
+		attach.Close()
+		<-copied
+		return fmt.Errorf("failed to start container: %w", err)
+	}
+	s.attach, s.copied, s.frozen = attach, copied, ""
+	s.exited, s.waitErr = s.e.cli.ContainerWait(ctx, s.id, container.WaitConditionNotRunning)
+	if from != "" {
+		s.e.logger.DebugContext(ctx, "restored an idle session", slog.String("id", s.id), slog.String("checkpoint", from))
+	}
+	return nil
+}
+
+// checkpoint freezes the running session. If CRIU can't, the session goes
+// on running and isn't tried again: it would fail the same way.
+func (s *session) checkpoint(ctx context.Context) {
+	name := fmt.Sprintf("idle-%d", s.taken+1)
+	err := s.e.cli.CheckpointCreate(ctx, s.id, checkpoint.CreateOptions{CheckpointID: name, Exit: true})
+	if err != nil {
+		s.e.logger.WarnContext(ctx, "checkpointing an idle session failed; it stays up",
+			slog.String("id", s.id),
+			slog.String("error", err.Error()),
+		)
+		s.noCRIU = true
+		return
+	}
+
+	// The container has stopped, so its wait returns and its streams end.
+	select {
+	case <-s.exited:
+	case <-s.waitErr:
+	}
+	s.attach.Close()
+	<-s.copied
+	s.exited, s.waitErr = nil, nil
+	s.frozen = name
+	s.taken++
+	s.e.logger.DebugContext(ctx, "checkpointed an idle session", slog.String("id", s.id), slog.String("checkpoint", name))
+}
+
+// timedOut ends a session that ran out of time.
+func (s *session) timedOut() *executor.ExecutionResult {
+	if s.frozen == "" && s.copied != nil {
+		s.attach.Close()
+		<-s.copied
+	}
+	s.stderr.Write([]byte("\nExecution timed out.\n"))
+	return s.result(124, apperror.CodeExecutionTimeout)
+}
+
+func (s *session) result(exitCode int, errorCode apperror.Code) *executor.ExecutionResult {
+	return &executor.ExecutionResult{
+		Stdout:    s.stdout.buf.String(),
+		Stderr:    s.stderr.buf.String(),
+		ExitCode:  exitCode,
+		Duration:  time.Since(s.started),
+		ErrorCode: errorCode,
+	}
+}
@@ -0,0 +1,104 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/executor"
+)
+
+func TestExecutorCheckpointsIdleSessions(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+	newSession := func(cli *fakeClient, timeout time.Duration) (commands *io.PipeWriter, result func() *executor.ExecutionResult) {
+		cfg := DefaultConfig()
+		cfg.PoolSize = 0
+		cfg.Timeout = timeout
+		cfg.CheckpointAfter = 20 * time.Millisecond
+		exec := newExecutor(cli, cfg, logger)
+		t.Cleanup(func() { exec.Close() })
+
+		stdin, commands := io.Pipe()
+		done := make(chan *executor.ExecutionResult, 1)
+		go func() {
+			res, err := exec.Execute(ctx, executor.ExecutionRequest{Code: "while True: print(input())", Stdin: stdin})
+			if err != nil {
+				t.Errorf("Execute() error = %v", err)
+			}
+			done <- res
+		}()
+		return commands, func() *executor.ExecutionResult { return <-done }
+	}
+
+	t.Run("frozen while idle, restored by input", func(t *testing.T) {
+		cli := newInstantFakeClient()
+		commands, result := newSession(cli, 10*time.Second)
+		for i, line := range []string{"one", "two"} {
+			waitFor(t, func() bool { taken, frozen := cli.checkpointed(); return taken == i+1 && frozen })
+			fmt.Fprintln(commands, line)
+		}
+		commands.Close()
+
+		res := result()
+		if res == nil || res.ExitCode != 0 || res.Stdout != "one\ntwo\n" {
+			t.Fatalf("result = %+v, want both lines echoed and exit 0", res)
+		}
+		taken, _ := cli.checkpointed()
+		if taken < 2 || cli.restores != taken {
+			t.Errorf("%d checkpoints taken and %d restored, want at least 2, all restored", taken, cli.restores)
+		}
+		if live, _ := cli.stats(); live != 0 {
+			t.Errorf("%d containers left, want the session's removed", live)
+		}
+	})
+
+	t.Run("runs without stdin use the pool", func(t *testing.T) {
+		cli := newInstantFakeClient()
+		cfg := DefaultConfig()
+		cfg.CheckpointAfter = time.Millisecond
+		exec := newExecutor(cli, cfg, logger)
+		defer exec.Close()
+		res, err := exec.Execute(ctx, executor.ExecutionRequest{Code: `print("hi")`})
+		if err != nil || res.Stdout != "print(\"hi\")\n" {
+			t.Fatalf("Execute() = %+v, %v", res, err)
+		}
+		if len(cli.procs) != 0 {
+			t.Errorf("a run without stdin got a session container")
+		}
+	})
+
+	t.Run("without CRIU the session stays up", func(t *testing.T) {
+		cli := newInstantFakeClient()
+		cli.noCRIU = true
+		commands, result := newSession(cli, 10*time.Second)
+		time.Sleep(100 * time.Millisecond) // several idle periods
+		fmt.Fprintln(commands, "one")
+		commands.Close()
+
+		if res := result(); res == nil || res.ExitCode != 0 || res.Stdout != "one\n" {
+			t.Fatalf("result = %+v, want the line echoed", res)
+		}
+		if taken, _ := cli.checkpointed(); taken != 0 || cli.restores != 0 {
+			t.Errorf("%d checkpoints and %d restores, want none", taken, cli.restores)
+		}
+	})
+
+	t.Run("times out while frozen", func(t *testing.T) {
+		cli := newInstantFakeClient()
+		commands, result := newSession(cli, 200*time.Millisecond)
+		defer commands.Close()
+
+		res := result()
+		if res == nil || res.ExitCode != 124 || !strings.Contains(res.Stderr, "timed out") {
+			t.Fatalf("result = %+v, want a timeout", res)
+		}
+		if taken, _ := cli.checkpointed(); taken == 0 {
+			t.Error("the idle session was never checkpointed")
+		}
+	})
+}
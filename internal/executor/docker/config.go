@@ -2,6 +2,9 @@ package docker
 
 import (
 	"time"
+
+	"github.com/sakif/coding-playground/internal/executor"
+	"github.com/sakif/coding-playground/internal/retry"
 )
 
 // Config holds the configuration for Docker execution.
@@ -16,6 +19,74 @@ type Config struct {
 	Timeout time.Duration
 	// PoolSize is the number of pre-warmed containers to maintain.
 	PoolSize int
+	// MaxPoolSize, if above 0, lets the pool resize itself as load changes:
+	// starting at PoolSize, it grows towards MaxPoolSize while executions
+	// wait for a sandbox and shrinks towards MinPoolSize (at least 1) while
+	// containers sit unused. ResizeEvery is how often it reconsiders
+	// (default 30s). See autoscale.go.
+	MinPoolSize int
+	MaxPoolSize int
+	ResizeEvery time.Duration
+	// ArtifactLimit is how many bytes of files a run may leave in
+	// $ARTIFACTS_DIR (a tmpfs of this size). 0 means no artifacts.
+	ArtifactLimit int64
+	// Wheelhouse is a directory in Image holding wheels for the packages
+	// runs may install (see executor.ExecutionRequest.Requirements); pip
+	// installs from it alone, the sandbox having no network. PackageLimit
+	// is the size of the tmpfs they're installed to, which counts towards
+	// MemoryLimit. With either unset, requirements are ignored.
+	Wheelhouse   string
+	PackageLimit int64
+	// EnvCacheLimit is how many bytes of installed requirement sets the
+	// executor keeps, packed, to restore instead of running pip again (see
+	// envcache.go). 0 installs every time.
+	EnvCacheLimit int64
+	// GPUs, if not 0, gives each container that many of the host's GPUs,
+	// as docker run --gpus does; -1 gives it all of them. The host needs
+	// the NVIDIA Container Toolkit, and Image the libraries to use them.
+	GPUs int
+	// CheckpointAfter, if above 0, freezes an execution that reads stdin
+	// once it has been idle that long, and restores it when input comes.
+	// The daemon needs experimental features on and CRIU installed. See
+	// checkpoint.go.
+	CheckpointAfter time.Duration
+
+	// Leases, if set, is shared with the other servers using the same Docker
+	// host, to cap totals across all of them (see executor.LeaseStore):
+	// MaxWarmTotal pre-warmed containers and MaxRunningTotal executions at
+	// once. 0 leaves that total uncapped.
+	Leases          executor.LeaseStore
+	MaxWarmTotal    int
+	MaxRunningTotal int
+
+	// Retry is how container and exec creates are retried when the daemon
+	// fails transiently (see retry.go). The zero value makes 3 attempts.
+	Retry retry.Policy
+}
+
+// adaptive reports whether the pool resizes itself.
+func (c Config) adaptive() bool {
+	return c.MaxPoolSize > 0
+}
+
+// installs reports whether runs' requirements are installed.
+func (c Config) installs() bool {
+	return c.Wheelhouse != "" && c.PackageLimit > 0
+}
+
+// withPoolBounds returns c with MinPoolSize ≤ PoolSize ≤ MaxPoolSize and
+// ResizeEvery set, if the pool is adaptive. An adaptive pool keeps at
+// least one container warm, so GetContainer always has one to wait for.
+func (c Config) withPoolBounds() Config {
+	if !c.adaptive() {
+		return c
+	}
+	c.MinPoolSize = min(max(c.MinPoolSize, 1), c.MaxPoolSize)
+	c.PoolSize = min(max(c.PoolSize, c.MinPoolSize), c.MaxPoolSize)
+	if c.ResizeEvery <= 0 {
+		c.ResizeEvery = 30 * time.Second
+	}
+	return c
 }
 
 // DefaultConfig provides sensible defaults for a Python sandbox.
@@ -30,5 +101,11 @@ func DefaultConfig() Config {
 		// 5 second default timeout
 		Timeout:  5 * time.Second,
 		PoolSize: 3,
+		// 8 MB of plots and generated files
+		ArtifactLimit: 8 * 1024 * 1024,
+		// Room for a package or two, once a Wheelhouse is set
+		PackageLimit: 64 * 1024 * 1024,
+		// A few dozen packed environments
+		EnvCacheLimit: 128 * 1024 * 1024,
 	}
 }
@@ -1,29 +1,82 @@
 package docker
 
 import (
+	"archive/tar"
 	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/checkpoint"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
 
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/sakif/coding-playground/internal/apperror"
 	"github.com/sakif/coding-playground/internal/executor"
+	"github.com/sakif/coding-playground/internal/retry"
 )
 
+// dockerClient is the part of the Docker API the executor and pool use.
+// *client.Client implements it; the tests and benchmarks swap in a fake so
+// they can run (and be timed) without a Docker daemon.
+type dockerClient interface {
+	ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error)
+	ContainerStart(ctx context.Context, containerID string, options container.StartOptions) error
+	ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error
+	ContainerExecCreate(ctx context.Context, containerID string, options container.ExecOptions) (container.ExecCreateResponse, error)
+	ContainerExecAttach(ctx context.Context, execID string, options container.ExecAttachOptions) (types.HijackedResponse, error)
+	ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error)
+	CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, container.PathStat, error)
+	ContainerAttach(ctx context.Context, containerID string, options container.AttachOptions) (types.HijackedResponse, error)
+	ContainerWait(ctx context.Context, containerID string, condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error)
+	CheckpointCreate(ctx context.Context, containerID string, options checkpoint.CreateOptions) error
+	Ping(ctx context.Context) (types.Ping, error)
+	Close() error
+}
+
+var _ dockerClient = (*client.Client)(nil)
+
 // Executor implements the executor.Executor interface using Docker.
 type Executor struct {
-	cli    *client.Client
+	cli    dockerClient
 	config Config
 	logger *slog.Logger
 	pool   *Pool
+	envs   *envCache // nil when Config.EnvCacheLimit is 0
+
+	inFlight atomic.Int64 // executions currently running (for Stats)
+	waiting  atomic.Int64 // executions waiting for a sandbox
+	waits    atomic.Int64 // executions that got one
+	waitTime atomic.Int64 // nanoseconds those spent waiting
+
+	healthMu  sync.Mutex
+	healthAt  time.Time // when healthErr was found
+	healthErr error
 }
 
+var (
+	_ executor.StatsProvider = (*Executor)(nil)
+	_ executor.HealthChecker = (*Executor)(nil)
+)
+
+// healthTTL is how long Healthy trusts its last ping of the daemon.
+const healthTTL = 2 * time.Second
+
 // New creates a new Docker Executor and initializes the connection.
 func New(cfg Config, logger *slog.Logger) (*Executor, error) {
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
@@ -45,16 +98,28 @@ func New(cfg Config, logger *slog.Logger) (*Executor, error) {
 	io.Copy(io.Discard, reader)
 	logger.Info("docker image is ready")
 
+	return newExecutor(cli, cfg, logger), nil
+}
+
+// newExecutor creates an Executor on cli and starts its pool.
+func newExecutor(cli dockerClient, cfg Config, logger *slog.Logger) *Executor {
 	exec := &Executor{
 		cli:    cli,
 		config: cfg,
 		logger: logger,
 	}
 
-	exec.pool = NewPool(cli, cfg, logger)
+	if cfg.EnvCacheLimit > 0 {
+		exec.envs = newEnvCache(cfg.EnvCacheLimit)
+	}
+	exec.pool = newPool(cli, cfg, logger)
 	exec.pool.Start()
+	if exec.pool.config.adaptive() {
+		exec.pool.wg.Add(1)
+		go exec.autoscale()
+	}
 
-	return exec, nil
+	return exec
 }
 
 // Close shuts down the executor pool and docker client.
@@ -63,15 +128,65 @@ func (e *Executor) Close() error {
 	return e.cli.Close()
 }
 
+// Stats reports pool capacity, the number of running executions, how
+// long they wait for a sandbox and how the environment cache is doing.
+func (e *Executor) Stats() executor.Stats {
+	stats := executor.Stats{
+		PoolSize:  e.pool.Size(),
+		Available: e.pool.Available(),
+		InFlight:  int(e.inFlight.Load()),
+		Waiting:   int(e.waiting.Load()),
+		Waits:     e.waits.Load(),
+		WaitTime:  time.Duration(e.waitTime.Load()),
+	}
+	if e.envs != nil {
+		stats.Environments = e.envs.Stats()
+	}
+	return stats
+}
+
+// Healthy reports whether the Docker daemon is answering. While it's down —
+// restarting, say — the pool can't refill, and a run would wait for a
+// container until its deadline; executor.Chain asks first and moves on.
+func (e *Executor) Healthy(ctx context.Context) error {
+	e.healthMu.Lock()
+	defer e.healthMu.Unlock()
+	if time.Since(e.healthAt) < healthTTL {
+		return e.healthErr
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	e.healthErr = nil
+	if _, err := e.cli.Ping(ctx); err != nil {
+		e.healthErr = fmt.Errorf("docker daemon unreachable: %w", err)
+	}
+	e.healthAt = time.Now()
+	return e.healthErr
+}
+
 // Execute runs the provided Python code in a sandboxed Docker container.
 func (e *Executor) Execute(ctx context.Context, req executor.ExecutionRequest) (*executor.ExecutionResult, error) {
+	return e.ExecuteStream(ctx, req, nil)
+}
+
+// ExecuteStream is Execute, also passing output to out as the container
+// writes it. out may be nil.
+func (e *Executor) ExecuteStream(ctx context.Context, req executor.ExecutionRequest, out func(executor.Output)) (*executor.ExecutionResult, error) {
 	start := time.Now()
 
-	// Get a pre-warmed container ID from the pool
-	containerID, err := e.pool.GetContainer(ctx)
+	e.inFlight.Add(1)
+	defer e.inFlight.Add(-1)
+
+	if e.checkpoints(req) {
+		return e.executeSession(ctx, req, out)
+	}
+
+	lease, containerID, err := e.waitForSandbox(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get container from pool: %w", err)
+		return nil, err
 	}
+	defer e.pool.leases.release(executor.LeaseRunning, lease)
 
 	// Always ensure we clean up the container that we acquired
 	defer func() {
@@ -90,15 +205,39 @@ func (e *Executor) Execute(ctx context.Context, req executor.ExecutionRequest) (
 	executeCtx, executeCancel := context.WithTimeout(ctx, e.config.Timeout)
 	defer executeCancel()
 
+	cmd, env := runCommand(req.Code)
+	if req.Requirements != "" && e.config.installs() {
+		failed, err := e.install(executeCtx, containerID, req.Requirements)
+		switch {
+		case err != nil && executeCtx.Err() != nil && ctx.Err() == nil:
+			return &executor.ExecutionResult{
+				Stderr:    "Installing requirements timed out.\n",
+				ExitCode:  124,
+				Duration:  time.Since(start),
+				ErrorCode: apperror.CodeExecutionTimeout,
+			}, nil
+		case err != nil:
+			return nil, err
+		case failed != nil:
+			failed.Duration = time.Since(start)
+			return failed, nil
+		}
+		env = append(env, "PYTHONPATH="+packagesDir)
+	}
+
 	// Copy the code into the container (using `python -c`) or by running `docker exec`.
 	// Since we already started it with `sleep 3600`, we can `docker exec` the code.
 	execConfig := container.ExecOptions{
 		AttachStdout: true,
 		AttachStderr: true,
-		Cmd:          []string{"python", "-c", req.Code},
+		Cmd:          cmd,
+		Env:          env,
+		AttachStdin:  req.Stdin != nil,
 	}
 
-	execResp, err := e.cli.ContainerExecCreate(executeCtx, containerID, execConfig)
+	execResp, err := retry.DoValue(executeCtx, e.config.retryPolicy(), func(ctx context.Context) (container.ExecCreateResponse, error) {
+		return e.cli.ContainerExecCreate(ctx, containerID, execConfig)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create exec: %w", err)
 	}
@@ -109,17 +248,29 @@ func (e *Executor) Execute(ctx context.Context, req executor.ExecutionRequest) (
 	}
 	defer attachResp.Close()
 
-	var stdout, stderr bytes.Buffer
+	if req.Stdin != nil {
+		// Closing stdin is the program's EOF. The copy ends with req.Stdin,
+		// or when the connection is closed below; it's the caller's to
+		// close, like any reader it hands over.
+		go func() {
+			_, _ = io.Copy(attachResp.Conn, req.Stdin)
+			_ = attachResp.CloseWrite()
+		}()
+	}
+
+	stdout := &streamWriter{stream: "stdout", out: out}
+	stderr := &streamWriter{stream: "stderr", out: out}
 
 	// Channels to manage sync and timeout
 	done := make(chan struct{})
 	go func() {
 		// Use stdcopy to demultiplex stdout from stderr
-		_, _ = stdcopy.StdCopy(&stdout, &stderr, attachResp.Reader)
+		_, _ = stdcopy.StdCopy(stdout, stderr, attachResp.Reader)
 		close(done)
 	}()
 
 	var finalExitCode int
+	var errorCode apperror.Code
 
 	select {
 	case <-done:
@@ -131,13 +282,284 @@ func (e *Executor) Execute(ctx context.Context, req executor.ExecutionRequest) (
 	case <-executeCtx.Done():
 		// Timeout reached
 		finalExitCode = 124 // Custom exit code for timeout (similar to unix timeout command)
-		stderr.WriteString("\nExecution timed out.\n")
+		errorCode = apperror.CodeExecutionTimeout
+		// Stop the copy before writing to stderr ourselves, so the two
+		// don't race (and streamed output stays in order).
+		attachResp.Close()
+		<-done
+		stderr.Write([]byte("\nExecution timed out.\n"))
+	}
+
+	result := &executor.ExecutionResult{
+		Stdout:    stdout.buf.String(),
+		Stderr:    stderr.buf.String(),
+		ExitCode:  finalExitCode,
+		Duration:  time.Since(start),
+		ErrorCode: errorCode,
+	}
+	if req.Artifacts && e.config.ArtifactLimit > 0 {
+		artifacts, err := e.collectArtifacts(ctx, containerID)
+		if err != nil {
+			// The run itself worked; report it without the files.
+			e.logger.WarnContext(ctx, "collecting artifacts failed", slog.String("id", containerID), slog.String("error", err.Error()))
+		}
+		result.Artifacts = artifacts
+	}
+	return result, nil
+}
+
+// runCommand is the command and environment that run code: a docker exec
+// in a warm container, or a session container's own process (see
+// checkpoint.go). Both go through it so code can't tell them apart.
+func runCommand(code string) (cmd, env []string) {
+	return []string{"python", "-c", code}, []string{"ARTIFACTS_DIR=" + artifactsDir}
+}
+
+// PACKAGES:
+// A run with requirements first has pip install them, in the same
+// container, from the image's Wheelhouse into packagesDir — a tmpfs of
+// PackageLimit bytes — which the code then finds on PYTHONPATH. pip never
+// looks beyond the wheelhouse (--no-index), so only what the image's
+// builder put there can be installed, and the requirements were already
+// checked against the server's allowlist. Requirements installed once are
+// unpacked from the environment cache after that (see envcache.go).
+
+const packagesDir = "/tmp/packages"
+
+// install pip-installs manifest in containerID, or unpacks the same
+// requirements from the environment cache if an earlier run installed
+// them. A failed install comes back as the run's result, pip's complaint
+// in Stderr; err is for an install that couldn't be run at all.
+func (e *Executor) install(ctx context.Context, containerID, manifest string) (failed *executor.ExecutionResult, err error) {
+	reqs, err := executor.ParseRequirements(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("reading requirements: %w", err)
+	}
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+
+	var key string
+	if e.envs != nil {
+		key = envKey(reqs)
+		if archive, ok := e.envs.get(key); ok {
+			var output bytes.Buffer
+			exitCode, err := e.execCommand(ctx, containerID, []string{"tar", "-xzf", "-", "-C", packagesDir}, archive, &output, &output)
+			if err != nil {
+				return nil, fmt.Errorf("restoring requirements: %w", err)
+			}
+			if exitCode == 0 {
+				return nil, nil
+			}
+			// Install them afresh, and cache that instead.
+			e.logger.WarnContext(ctx, "cached environment didn't unpack", slog.String("id", containerID), slog.String("output", output.String()))
+			e.envs.remove(key)
+		}
+	}
+
+	cmd := []string{
+		"python", "-m", "pip", "install", "--quiet", "--no-index", "--no-cache-dir",
+		"--disable-pip-version-check", "--no-warn-script-location",
+		"--find-links", e.config.Wheelhouse, "--target", packagesDir, "--",
+	}
+	for _, req := range reqs {
+		cmd = append(cmd, req.String())
+	}
+	var output bytes.Buffer
+	exitCode, err := e.execCommand(ctx, containerID, cmd, nil, &output, &output)
+	if err != nil {
+		return nil, fmt.Errorf("installing requirements: %w", err)
+	}
+	if exitCode != 0 {
+		return &executor.ExecutionResult{
+			Stderr:   "Installing requirements failed:\n" + output.String(),
+			ExitCode: exitCode,
+		}, nil
+	}
+
+	if e.envs != nil {
+		// Packing is a cache fill: if it fails, the run goes ahead anyway.
+		var archive, complaint bytes.Buffer
+		exitCode, err := e.execCommand(ctx, containerID, []string{"tar", "-czf", "-", "-C", packagesDir, "."}, nil, &archive, &complaint)
+		switch {
+		case err != nil:
+			e.logger.WarnContext(ctx, "packing environment failed", slog.String("id", containerID), slog.String("error", err.Error()))
+		case exitCode != 0:
+			e.logger.WarnContext(ctx, "packing environment failed", slog.String("id", containerID), slog.String("output", complaint.String()))
+		default:
+			e.envs.put(key, archive.Bytes())
+		}
+	}
+	return nil, nil
+}
+
+// execCommand runs cmd in containerID, feeding it stdin if there is any,
+// and returns its exit code once it's done or ctx is.
+func (e *Executor) execCommand(ctx context.Context, containerID string, cmd []string, stdin []byte, stdout, stderr io.Writer) (int, error) {
+	execResp, err := retry.DoValue(ctx, e.config.retryPolicy(), func(ctx context.Context) (container.ExecCreateResponse, error) {
+		return e.cli.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+			AttachStdout: true,
+			AttachStderr: true,
+			AttachStdin:  stdin != nil,
+			Cmd:          cmd,
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create exec: %w", err)
+	}
+	attachResp, err := e.cli.ContainerExecAttach(ctx, execResp.ID, container.ExecStartOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to attach to exec: %w", err)
+	}
+	defer attachResp.Close()
+
+	if stdin != nil {
+		go func() {
+			_, _ = attachResp.Conn.Write(stdin)
+			_ = attachResp.CloseWrite()
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_, _ = stdcopy.StdCopy(stdout, stderr, attachResp.Reader)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		attachResp.Close()
+		<-done
+		return 0, ctx.Err()
+	}
+
+	inspectResp, err := e.cli.ContainerExecInspect(ctx, execResp.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect exec: %w", err)
+	}
+	return inspectResp.ExitCode, nil
+}
+
+// ARTIFACTS:
+// Code that wants to hand back a file — a plot, a CSV — writes it to
+// $ARTIFACTS_DIR, a tmpfs of ArtifactLimit bytes (see Pool.createContainer),
+// the one writable place in the container. After the run, the files at the
+// top of it are copied out through the Docker API as a tar stream, before
+// the container is removed. Subdirectories are ignored, and so is anything
+// past the first maxArtifacts files.
+
+const (
+	artifactsDir = "/tmp/artifacts"
+	maxArtifacts = 10
+)
+
+// collectArtifacts copies the files a run left in artifactsDir out of the
+// container.
+func (e *Executor) collectArtifacts(ctx context.Context, containerID string) ([]executor.Artifact, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rc, _, err := e.cli.CopyFromContainer(ctx, containerID, artifactsDir)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	// Entries are named "artifacts/<file>", after the copied directory.
+	var artifacts []executor.Artifact
+	remaining := e.config.ArtifactLimit
+	tr := tar.NewReader(rc)
+	for len(artifacts) < maxArtifacts {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return artifacts, err
+		}
+		_, name, _ := strings.Cut(hdr.Name, "/")
+		if hdr.Typeflag != tar.TypeReg || name == "" || strings.Contains(name, "/") {
+			continue
+		}
+		if hdr.Size > remaining {
+			return artifacts, fmt.Errorf("artifact %s is over the %d byte limit", name, e.config.ArtifactLimit)
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, hdr.Size))
+		if err != nil {
+			return artifacts, err
+		}
+		remaining -= int64(len(data))
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" {
+			contentType = http.DetectContentType(data)
+		}
+		artifacts = append(artifacts, executor.Artifact{
+			Name:        name,
+			Size:        int64(len(data)),
+			ContentType: contentType,
+			Data:        data,
+		})
+	}
+	return artifacts, nil
+}
+
+// waitForSandbox takes a run lease and a container for one execution,
+// counting the time it takes towards Stats.
+func (e *Executor) waitForSandbox(ctx context.Context) (lease, containerID string, err error) {
+	start := time.Now()
+	e.waiting.Add(1)
+	defer func() {
+		e.waiting.Add(-1)
+		if err == nil {
+			e.waits.Add(1)
+			e.waitTime.Add(int64(time.Since(start)))
+		}
+	}()
+
+	// Wait for a turn if the servers sharing the host are at their total.
+	lease, err = e.pool.leases.acquire(ctx, executor.LeaseRunning, e.config.MaxRunningTotal)
+	if err != nil {
+		return "", "", err
 	}
 
-	return &executor.ExecutionResult{
-		Stdout:   stdout.String(),
-		Stderr:   stderr.String(),
-		ExitCode: finalExitCode,
-		Duration: time.Since(start),
-	}, nil
+	// Get a pre-warmed container ID from the pool (or a fresh one, if
+	// PoolSize is 0)
+	containerID, err = e.pool.GetContainer(ctx)
+	if err != nil {
+		e.pool.leases.release(executor.LeaseRunning, lease)
+		return "", "", fmt.Errorf("failed to get container from pool: %w", err)
+	}
+	return lease, containerID, nil
+}
+
+// streamWriter keeps everything written to it and passes each write on to
+// out. A character split across two writes is held back until it's whole,
+// so every Output is valid UTF-8.
+type streamWriter struct {
+	buf     bytes.Buffer
+	stream  string
+	out     func(executor.Output)
+	pending []byte // the start of a split character
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	if w.out == nil {
+		return len(p), nil
+	}
+	data := append(w.pending, p...)
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	w.pending = append([]byte(nil), data[cut:]...)
+	if cut > 0 {
+		w.out(executor.Output{Stream: w.stream, Text: string(data[:cut])})
+	}
+	return len(p), nil
 }
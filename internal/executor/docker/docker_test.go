@@ -91,4 +91,18 @@ func TestDockerExecutor(t *testing.T) {
 		assert.Equal(t, 0, res.ExitCode)
 		assert.Contains(t, res.Stdout, "5")
 	})
+
+	t.Run("streamed output", func(t *testing.T) {
+		req := executor.ExecutionRequest{
+			Code: "import sys, time\nprint('one', flush=True)\ntime.sleep(0.5)\nprint('two', file=sys.stderr)",
+		}
+
+		var streamed []executor.Output
+		res, err := exec.ExecuteStream(context.Background(), req, func(o executor.Output) {
+			streamed = append(streamed, o)
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []executor.Output{{Stream: "stdout", Text: "one\n"}, {Stream: "stderr", Text: "two\n"}}, streamed)
+		assert.Equal(t, "one\n", res.Stdout, "the result still has all the output")
+	})
 }
@@ -0,0 +1,120 @@
+package docker
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/sakif/coding-playground/internal/executor"
+)
+
+// ENVIRONMENT CACHE:
+// Installing numpy takes pip a few seconds, and every run with the same
+// requirements would pay them again, in a fresh container. So after the
+// first install, packagesDir is packed up (tar, gzip) and kept here, keyed
+// by the requirement set; the next run with that set unpacks it instead
+// of running pip, which is a fraction of the time.
+//
+// The cache lives in the server's memory, capped at Config.EnvCacheLimit
+// bytes; when a new environment doesn't fit, the least recently used ones
+// are evicted until it does. Its hits, misses and evictions are part of
+// the executor's Stats, so they show up on /metrics.
+
+// envKey identifies a requirement set: the same packages and versions, in
+// any order, with names in any case, have the same key.
+func envKey(reqs []executor.Requirement) string {
+	lines := make([]string, len(reqs))
+	for i, req := range reqs {
+		lines[i] = strings.ToLower(req.String())
+	}
+	slices.Sort(lines)
+	lines = slices.Compact(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// envCache is an LRU of packed environments, bounded by their total size.
+type envCache struct {
+	limit int64
+
+	mu      sync.Mutex
+	order   *list.List               // most recently used at the front
+	entries map[string]*list.Element // key → element holding an *envEntry
+	bytes   int64
+	stats   executor.EnvironmentStats
+}
+
+type envEntry struct {
+	key     string
+	archive []byte
+}
+
+func newEnvCache(limit int64) *envCache {
+	return &envCache{
+		limit:   limit,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the archive for key, counting a hit or a miss.
+func (c *envCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(el)
+	return el.Value.(*envEntry).archive, true
+}
+
+// put stores archive under key, evicting the least recently used entries
+// to make room. An archive bigger than the whole cache isn't kept.
+func (c *envCache) put(key string, archive []byte) {
+	size := int64(len(archive))
+	if size > c.limit {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(key)
+	for c.bytes+size > c.limit {
+		c.removeLocked(c.order.Back().Value.(*envEntry).key)
+		c.stats.Evictions++
+	}
+	c.entries[key] = c.order.PushFront(&envEntry{key: key, archive: archive})
+	c.bytes += size
+}
+
+// remove forgets key, whose archive didn't unpack.
+func (c *envCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(key)
+}
+
+func (c *envCache) removeLocked(key string) {
+	el, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	c.order.Remove(el)
+	delete(c.entries, key)
+	c.bytes -= int64(len(el.Value.(*envEntry).archive))
+}
+
+// Stats returns the cache's counters and current size.
+func (c *envCache) Stats() executor.EnvironmentStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	stats.Bytes = c.bytes
+	return stats
+}
@@ -0,0 +1,50 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/sakif/coding-playground/internal/executor"
+)
+
+func TestEnvKey(t *testing.T) {
+	key := func(manifest string) string {
+		reqs, err := executor.ParseRequirements(manifest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return envKey(reqs)
+	}
+	a := key("numpy==1.26.4\nrequests\n")
+	if b := key("Requests\nNumPy==1.26.4\nrequests\n"); b != a {
+		t.Error("envKey() differs for the same set in another order")
+	}
+	if c := key("numpy==2.0.0\nrequests\n"); c == a {
+		t.Error("envKey() is the same for another version")
+	}
+}
+
+func TestEnvCacheEvicts(t *testing.T) {
+	c := newEnvCache(10)
+	c.put("a", []byte("aaaa"))
+	c.put("b", []byte("bbbb"))
+	if _, ok := c.get("a"); !ok {
+		t.Fatal("get(a) missed")
+	}
+	c.put("c", []byte("cccc")) // b is the least recently used
+	if _, ok := c.get("b"); ok {
+		t.Error("get(b) hit, want it evicted")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Error("get(a) missed, want it kept")
+	}
+	c.put("huge", make([]byte, 11))
+	if _, ok := c.get("huge"); ok {
+		t.Error("kept an archive bigger than the cache")
+	}
+
+	stats := c.Stats()
+	want := executor.EnvironmentStats{Hits: 2, Misses: 2, Evictions: 1, Entries: 2, Bytes: 8}
+	if stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+}
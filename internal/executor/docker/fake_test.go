@@ -0,0 +1,349 @@
+package docker
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/checkpoint"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/pkg/stdcopy"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// fakeClient is a dockerClient with no daemon behind it. Each call takes as
+// long as its latency says, so timings come out roughly like a real host's
+// while staying repeatable. Every exec prints its code's first line back on
+// stdout and exits 0; code containing "fail" prints to stderr and exits 1,
+// and code containing "savefig" leaves a plot.png artifact. A container
+// created with its stdin open runs a fakeProc instead.
+type fakeClient struct {
+	createLatency time.Duration // ContainerCreate
+	startLatency  time.Duration // ContainerStart
+	execLatency   time.Duration // ContainerExecCreate + attach
+	runLatency    time.Duration // the code running
+
+	mu      sync.Mutex
+	nextID  int
+	live    map[string]bool       // containers created and not yet removed
+	execs   map[string]string     // exec ID → code
+	ran     map[string]string     // container ID → the code it last ran
+	cmds    []string              // every exec's command line, in order
+	host    *container.HostConfig // the last container created's
+	created int
+	down    bool // Ping fails, as if the daemon were restarting
+	flaky   int  // this many more ContainerCreate calls fail transiently
+
+	procs    map[string]*fakeProc // containers running their own command
+	noCRIU   bool                 // CheckpointCreate fails, as on a daemon without experimental features
+	restores int                  // containers started from a checkpoint
+}
+
+// fakeProc is a container running its own command, as a checkpointable
+// session does (see checkpoint.go). It echoes each line of its stdin back
+// on stdout and exits 0 at EOF.
+type fakeProc struct {
+	stdin       *io.PipeReader // from the last attach
+	stdout      *io.PipeWriter
+	stopped     chan int // the exit code, when the current run stops
+	running     bool
+	checkpoints []string
+}
+
+// errFrozen stops a fakeProc's run when it's checkpointed.
+var errFrozen = errors.New("checkpointed")
+
+func (p *fakeProc) run(stdin *io.PipeReader, stdout *io.PipeWriter, stopped chan int) {
+	w := stdcopy.NewStdWriter(stdout, stdcopy.Stdout)
+	lines := bufio.NewScanner(stdin)
+	for lines.Scan() {
+		w.Write([]byte(lines.Text() + "\n"))
+	}
+	stdout.Close()
+	if errors.Is(lines.Err(), errFrozen) {
+		stopped <- 137
+		return
+	}
+	stopped <- 0
+}
+
+var _ dockerClient = (*fakeClient)(nil)
+
+// newFakeClient returns a fake with latencies in the range of a local
+// Docker host running python:3.12-alpine.
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		createLatency: 40 * time.Millisecond,
+		startLatency:  60 * time.Millisecond,
+		execLatency:   5 * time.Millisecond,
+		runLatency:    10 * time.Millisecond,
+		live:          make(map[string]bool),
+		execs:         make(map[string]string),
+		ran:           make(map[string]string),
+		procs:         make(map[string]*fakeProc),
+	}
+}
+
+// newInstantFakeClient returns a fake whose calls take no time, for tests
+// that only care about behaviour.
+func newInstantFakeClient() *fakeClient {
+	f := newFakeClient()
+	f.createLatency, f.startLatency, f.execLatency, f.runLatency = 0, 0, 0, 0
+	return f
+}
+
+// stats returns how many containers exist now and have been created in all.
+func (f *fakeClient) stats() (live, created int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.live), f.created
+}
+
+func (f *fakeClient) Ping(context.Context) (types.Ping, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		return types.Ping{}, errors.New("Cannot connect to the Docker daemon")
+	}
+	return types.Ping{APIVersion: "1.45"}, nil
+}
+
+func (f *fakeClient) ContainerCreate(ctx context.Context, cfg *container.Config, host *container.HostConfig, _ *network.NetworkingConfig, _ *ocispec.Platform, _ string) (container.CreateResponse, error) {
+	if err := wait(ctx, f.createLatency); err != nil {
+		return container.CreateResponse{}, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.flaky > 0 {
+		f.flaky--
+		return container.CreateResponse{}, fmt.Errorf("daemon restarting: %w", cerrdefs.ErrUnavailable)
+	}
+	f.nextID++
+	f.created++
+	f.host = host
+	id := fmt.Sprintf("fake-%d", f.nextID)
+	f.live[id] = true
+	if cfg.OpenStdin {
+		f.procs[id] = &fakeProc{}
+	}
+	return container.CreateResponse{ID: id}, nil
+}
+
+func (f *fakeClient) ContainerStart(ctx context.Context, id string, opts container.StartOptions) error {
+	if err := wait(ctx, f.startLatency); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.live[id] {
+		return fmt.Errorf("no such container: %s", id)
+	}
+	p := f.procs[id]
+	if p == nil {
+		return nil
+	}
+	if p.running {
+		return fmt.Errorf("container %s is already running", id)
+	}
+	if opts.CheckpointID != "" {
+		if !slices.Contains(p.checkpoints, opts.CheckpointID) {
+			return fmt.Errorf("no checkpoint %s for container %s", opts.CheckpointID, id)
+		}
+		f.restores++
+	}
+	p.running, p.stopped = true, make(chan int, 1)
+	go p.run(p.stdin, p.stdout, p.stopped)
+	return nil
+}
+
+// ContainerAttach connects to a fakeProc's stdin and stdout, for its next
+// run.
+func (f *fakeClient) ContainerAttach(_ context.Context, id string, _ container.AttachOptions) (types.HijackedResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p := f.procs[id]
+	if p == nil {
+		return types.HijackedResponse{}, fmt.Errorf("container %s has no stdin to attach to", id)
+	}
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	p.stdin, p.stdout = inR, outW
+	return types.NewHijackedResponse(&fakeConn{r: outR, w: inW}, ""), nil
+}
+
+func (f *fakeClient) ContainerWait(ctx context.Context, id string, _ container.WaitCondition) (<-chan container.WaitResponse, <-chan error) {
+	f.mu.Lock()
+	p := f.procs[id]
+	f.mu.Unlock()
+	resC, errC := make(chan container.WaitResponse, 1), make(chan error, 1)
+	if p == nil {
+		errC <- fmt.Errorf("no such container: %s", id)
+		return resC, errC
+	}
+	stopped := p.stopped
+	go func() {
+		select {
+		case code := <-stopped:
+			f.mu.Lock()
+			p.running = false
+			f.mu.Unlock()
+			resC <- container.WaitResponse{StatusCode: int64(code)}
+		case <-ctx.Done():
+			errC <- ctx.Err()
+		}
+	}()
+	return resC, errC
+}
+
+// CheckpointCreate stops a running fakeProc, keeping the checkpoint.
+func (f *fakeClient) CheckpointCreate(_ context.Context, id string, opts checkpoint.CreateOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.noCRIU {
+		return errors.New("checkpoint is only supported in experimental mode")
+	}
+	p := f.procs[id]
+	if p == nil || !p.running {
+		return fmt.Errorf("container %s is not running", id)
+	}
+	p.checkpoints = append(p.checkpoints, opts.CheckpointID)
+	p.stdin.CloseWithError(errFrozen)
+	return nil
+}
+
+// checkpointed reports how many checkpoints have been taken in all, and
+// whether any fakeProc is frozen at one right now.
+func (f *fakeClient) checkpointed() (taken int, frozen bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.procs {
+		taken += len(p.checkpoints)
+		frozen = frozen || (len(p.checkpoints) > 0 && !p.running)
+	}
+	return taken, frozen
+}
+
+// fakeConn is the connection ContainerAttach hijacks: the container's
+// output to read, and its stdin to write and close.
+type fakeConn struct {
+	net.Conn // nil: nothing calls the rest
+	r        *io.PipeReader
+	w        *io.PipeWriter
+}
+
+func (c *fakeConn) Read(b []byte) (int, error)  { return c.r.Read(b) }
+func (c *fakeConn) Write(b []byte) (int, error) { return c.w.Write(b) }
+func (c *fakeConn) CloseWrite() error           { return c.w.Close() }
+func (c *fakeConn) Close() error {
+	c.w.Close()
+	return c.r.Close()
+}
+
+func (f *fakeClient) ContainerRemove(_ context.Context, id string, _ container.RemoveOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.live, id)
+	return nil
+}
+
+func (f *fakeClient) ContainerExecCreate(ctx context.Context, id string, opts container.ExecOptions) (container.ExecCreateResponse, error) {
+	if err := wait(ctx, f.execLatency); err != nil {
+		return container.ExecCreateResponse{}, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.live[id] {
+		return container.ExecCreateResponse{}, fmt.Errorf("no such container: %s", id)
+	}
+	f.cmds = append(f.cmds, strings.Join(opts.Cmd, " "))
+	execID := id + "/exec"
+	f.execs[execID] = opts.Cmd[len(opts.Cmd)-1]
+	f.ran[id] = f.execs[execID]
+	return container.ExecCreateResponse{ID: execID}, nil
+}
+
+// ContainerExecAttach answers over an in-memory pipe, multiplexing the
+// output the way the daemon does.
+func (f *fakeClient) ContainerExecAttach(_ context.Context, execID string, _ container.ExecAttachOptions) (types.HijackedResponse, error) {
+	f.mu.Lock()
+	code := f.execs[execID]
+	f.mu.Unlock()
+
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		time.Sleep(f.runLatency)
+		line, _, _ := strings.Cut(code, "\n")
+		stream := stdcopy.Stdout
+		if strings.Contains(code, "fail") {
+			stream = stdcopy.Stderr
+		}
+		stdcopy.NewStdWriter(server, stream).Write([]byte(line + "\n"))
+	}()
+	return types.NewHijackedResponse(client, ""), nil
+}
+
+func (f *fakeClient) ContainerExecInspect(_ context.Context, execID string) (container.ExecInspect, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	exitCode := 0
+	if strings.Contains(f.execs[execID], "fail") {
+		exitCode = 1
+	}
+	delete(f.execs, execID)
+	return container.ExecInspect{ExecID: execID, ExitCode: exitCode}, nil
+}
+
+// CopyFromContainer archives the artifacts directory the way the daemon
+// does, with entries named after it.
+func (f *fakeClient) CopyFromContainer(_ context.Context, id, srcPath string) (io.ReadCloser, container.PathStat, error) {
+	f.mu.Lock()
+	code, ok := f.ran[id]
+	f.mu.Unlock()
+	if !ok || !f.live[id] {
+		return nil, container.PathStat{}, fmt.Errorf("no such container: %s", id)
+	}
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	dir := path.Base(srcPath)
+	tw.WriteHeader(&tar.Header{Name: dir + "/", Typeflag: tar.TypeDir, Mode: 0o777})
+	if strings.Contains(code, "savefig") {
+		png := []byte("\x89PNG\r\n\x1a\n fake plot")
+		tw.WriteHeader(&tar.Header{Name: dir + "/plot.png", Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(png))})
+		tw.Write(png)
+		tw.WriteHeader(&tar.Header{Name: dir + "/cache/", Typeflag: tar.TypeDir, Mode: 0o777})
+		tw.WriteHeader(&tar.Header{Name: dir + "/cache/skipped.txt", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1})
+		tw.Write([]byte("x"))
+	}
+	tw.Close()
+	return io.NopCloser(&buf), container.PathStat{Name: dir, Mode: fs.ModeDir}, nil
+}
+
+func (f *fakeClient) Close() error { return nil }
+
+// wait sleeps for d, or until ctx is done.
+func wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
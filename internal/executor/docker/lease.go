@@ -0,0 +1,146 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/sakif/coding-playground/internal/executor"
+)
+
+const (
+	// leaseTTL is how long a lease outlives a server that stops renewing it.
+	leaseTTL = 30 * time.Second
+	// leaseRenewEvery is how often held leases are renewed, well inside the TTL.
+	leaseRenewEvery = leaseTTL / 3
+	// leasePollEvery is how often a server waiting for a lease tries again.
+	leasePollEvery = 100 * time.Millisecond
+)
+
+// leases holds this server's share of the cluster's sandbox capacity (see
+// executor.LeaseStore) and keeps it renewed. With no store, or a limit of
+// 0, every lease is granted without asking anyone.
+//
+// If the store can't be reached the lease is granted anyway and a warning
+// logged: running more sandboxes than planned for a while beats running
+// none.
+type leases struct {
+	store  executor.LeaseStore
+	logger *slog.Logger
+
+	mu   sync.Mutex
+	held map[string]map[string]bool // kind → lease IDs
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newLeases(store executor.LeaseStore, logger *slog.Logger) *leases {
+	l := &leases{
+		store:  store,
+		logger: logger,
+		held:   make(map[string]map[string]bool),
+		done:   make(chan struct{}),
+	}
+	if store != nil {
+		l.wg.Add(1)
+		go l.renew()
+	}
+	return l
+}
+
+// tryAcquire takes a lease of kind if one is free. It returns the lease ID
+// ("" when not coordinating) and whether it got one.
+func (l *leases) tryAcquire(ctx context.Context, kind string, limit int) (string, bool) {
+	if l.store == nil || limit <= 0 {
+		return "", true
+	}
+	id := xid.New().String()
+	ok, err := l.store.AcquireLease(ctx, kind, id, limit, leaseTTL)
+	if err != nil {
+		l.logger.Warn("lease store unavailable; not limiting sandboxes across servers",
+			slog.String("kind", kind), slog.String("error", err.Error()))
+		return "", true
+	}
+	if !ok {
+		return "", false
+	}
+	l.mu.Lock()
+	if l.held[kind] == nil {
+		l.held[kind] = make(map[string]bool)
+	}
+	l.held[kind][id] = true
+	l.mu.Unlock()
+	return id, true
+}
+
+// acquire waits until a lease of kind is free, or ctx is done.
+func (l *leases) acquire(ctx context.Context, kind string, limit int) (string, error) {
+	for {
+		if id, ok := l.tryAcquire(ctx, kind, limit); ok {
+			return id, nil
+		}
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("all %d %s sandboxes across servers are in use: %w", limit, kind, ctx.Err())
+		case <-time.After(leasePollEvery):
+		}
+	}
+}
+
+// release gives a lease back. id "" (an ungoverned lease) is ignored.
+func (l *leases) release(kind, id string) {
+	if id == "" {
+		return
+	}
+	l.mu.Lock()
+	delete(l.held[kind], id)
+	l.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := l.store.ReleaseLease(ctx, kind, id); err != nil {
+		// It expires on its own.
+		l.logger.Warn("failed to release lease", slog.String("kind", kind), slog.String("error", err.Error()))
+	}
+}
+
+// renew extends every held lease each leaseRenewEvery until stop.
+func (l *leases) renew() {
+	defer l.wg.Done()
+	ticker := time.NewTicker(leaseRenewEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-ticker.C:
+		}
+		l.mu.Lock()
+		byKind := make(map[string][]string, len(l.held))
+		for kind, ids := range l.held {
+			for id := range ids {
+				byKind[kind] = append(byKind[kind], id)
+			}
+		}
+		l.mu.Unlock()
+
+		for kind, ids := range byKind {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := l.store.RenewLeases(ctx, kind, ids, leaseTTL); err != nil {
+				l.logger.Warn("failed to renew leases", slog.String("kind", kind), slog.String("error", err.Error()))
+			}
+			cancel()
+		}
+	}
+}
+
+// stop ends renewal. Leases still held lapse after leaseTTL.
+func (l *leases) stop() {
+	close(l.done)
+	l.wg.Wait()
+}
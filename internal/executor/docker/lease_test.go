@@ -0,0 +1,143 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/executor"
+	"github.com/sakif/coding-playground/internal/repository/sqlite"
+)
+
+// These tests don't need Docker: they check how pools on different servers
+// share leases, with the SQLite store standing in for the shared database.
+func TestLeases(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	serverA, serverB := newLeases(db, logger), newLeases(db, logger)
+	t.Cleanup(serverA.stop)
+	t.Cleanup(serverB.stop)
+	ctx := context.Background()
+
+	t.Run("uncoordinated", func(t *testing.T) {
+		l := newLeases(nil, logger)
+		defer l.stop()
+		for range 3 {
+			if id, ok := l.tryAcquire(ctx, executor.LeaseRunning, 1); !ok || id != "" {
+				t.Fatalf("tryAcquire() without a store = %q, %v; want always granted", id, ok)
+			}
+		}
+		if _, ok := serverA.tryAcquire(ctx, executor.LeaseRunning, 0); !ok {
+			t.Error("tryAcquire() with no limit was refused")
+		}
+	})
+
+	t.Run("shared limit", func(t *testing.T) {
+		first, err := serverA.acquire(ctx, executor.LeaseRunning, 1)
+		if err != nil {
+			t.Fatalf("acquire() error = %v", err)
+		}
+		if _, ok := serverB.tryAcquire(ctx, executor.LeaseRunning, 1); ok {
+			t.Fatal("server B got a lease while server A held the only one")
+		}
+
+		// B waits for A's lease to come back.
+		got := make(chan error, 1)
+		go func() {
+			id, err := serverB.acquire(ctx, executor.LeaseRunning, 1)
+			if err == nil {
+				serverB.release(executor.LeaseRunning, id)
+			}
+			got <- err
+		}()
+		time.Sleep(2 * leasePollEvery)
+		serverA.release(executor.LeaseRunning, first)
+		select {
+		case err := <-got:
+			if err != nil {
+				t.Errorf("acquire() after the release: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("server B never got the released lease")
+		}
+	})
+
+	t.Run("lapsed leases are freed", func(t *testing.T) {
+		// A crashed server's lease, which nobody renews.
+		if ok, err := db.AcquireLease(ctx, "crashed", "gone", 1, 10*time.Millisecond); err != nil || !ok {
+			t.Fatalf("AcquireLease() = %v, %v", ok, err)
+		}
+		if ok, _ := db.AcquireLease(ctx, "crashed", "next", 1, time.Minute); ok {
+			t.Fatal("a lease was granted before the held one lapsed")
+		}
+		time.Sleep(20 * time.Millisecond)
+		if ok, err := db.AcquireLease(ctx, "crashed", "next", 1, time.Minute); err != nil || !ok {
+			t.Errorf("AcquireLease() after the lapse = %v, %v; want granted", ok, err)
+		}
+	})
+
+	t.Run("gives up with the context", func(t *testing.T) {
+		held, _ := serverA.tryAcquire(ctx, executor.LeaseWarm, 1)
+		defer serverA.release(executor.LeaseWarm, held)
+
+		short, cancel := context.WithTimeout(ctx, 3*leasePollEvery)
+		defer cancel()
+		if _, err := serverB.acquire(short, executor.LeaseWarm, 1); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("acquire() when full = %v, want deadline exceeded", err)
+		}
+	})
+}
+
+// An execution held back by MaxRunningTotal shows up in Stats as waiting,
+// and its wait is counted once it runs.
+func TestExecutorStatsCountWaits(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	cfg := DefaultConfig()
+	cfg.Leases = db
+	cfg.MaxRunningTotal = 1
+	exec := newExecutor(newInstantFakeClient(), cfg, logger)
+	defer exec.Close()
+
+	// Another server is running the host's one allowed execution.
+	other := newLeases(db, logger)
+	defer other.stop()
+	held, err := other.acquire(context.Background(), executor.LeaseRunning, 1)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := exec.Execute(context.Background(), executor.ExecutionRequest{Code: `print("hi")`})
+		done <- err
+	}()
+	waitFor(t, func() bool { return exec.Stats().Waiting == 1 })
+	if stats := exec.Stats(); stats.InFlight != 1 || stats.Waits != 0 {
+		t.Errorf("Stats() while waiting = %+v, want one in flight and no finished waits", stats)
+	}
+
+	time.Sleep(2 * leasePollEvery)
+	other.release(executor.LeaseRunning, held)
+	if err := <-done; err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	stats := exec.Stats()
+	if stats.Waiting != 0 || stats.Waits != 1 || stats.WaitTime < 2*leasePollEvery {
+		t.Errorf("Stats() after the run = %+v, want one wait of at least %v", stats, 2*leasePollEvery)
+	}
+}
@@ -5,37 +5,76 @@ import (
 	"fmt"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
+
+	"github.com/sakif/coding-playground/internal/executor"
+	"github.com/sakif/coding-playground/internal/retry"
 )
 
 // Pool manages a pool of pre-warmed Docker containers for fast code execution.
+//
+// With a PoolSize of 0 nothing is kept warm: each execution creates its own
+// container and waits for it to start ("cold"). That's the baseline the
+// pool is measured against in bench_test.go.
+//
+// An adaptive pool (Config.MaxPoolSize) has room for MaxPoolSize
+// containers but only keeps Size of them warm; SetSize moves that target
+// and the manager creates or removes containers to meet it.
+//
+// With Config.Leases set, each warm container holds an executor.LeaseWarm
+// lease, so the pools of every server on the host keep at most
+// Config.MaxWarmTotal between them. The lease is given back when the
+// container is handed out.
 type Pool struct {
-	cli        *client.Client
+	cli        dockerClient
 	config     Config
 	logger     *slog.Logger
-	containers chan string
+	leases     *leases
+	containers chan warmContainer
+	size       atomic.Int64 // how many containers to keep warm
+	spare      atomic.Int64 // fewest warm containers left by a GetContainer since takeSpare
 	done       chan struct{}
 	wg         sync.WaitGroup
 	startDone  sync.Once
 }
 
+// warmContainer is a container waiting in the pool, and its lease.
+type warmContainer struct {
+	id    string
+	lease string
+}
+
 // NewPool initializes a new container pool wrapper.
 func NewPool(cli *client.Client, cfg Config, logger *slog.Logger) *Pool {
-	return &Pool{
+	return newPool(cli, cfg, logger)
+}
+
+func newPool(cli dockerClient, cfg Config, logger *slog.Logger) *Pool {
+	cfg = cfg.withPoolBounds()
+	p := &Pool{
 		cli:        cli,
 		config:     cfg,
 		logger:     logger,
-		containers: make(chan string, cfg.PoolSize),
+		leases:     newLeases(cfg.Leases, logger),
+		containers: make(chan warmContainer, max(cfg.PoolSize, cfg.MaxPoolSize)),
 		done:       make(chan struct{}),
 	}
+	p.size.Store(int64(cfg.PoolSize))
+	p.spare.Store(int64(cfg.PoolSize))
+	return p
 }
 
 // Start begins filling the pool with fresh containers in the background.
 func (p *Pool) Start() {
 	p.startDone.Do(func() {
+		if cap(p.containers) == 0 {
+			p.logger.Info("docker container pool disabled; creating containers per execution")
+			return
+		}
 		p.logger.Info("starting docker container pool manager", slog.Int("poolSize", p.config.PoolSize))
 		p.wg.Add(1)
 		go p.manager()
@@ -49,10 +88,12 @@ func (p *Pool) Stop() {
 	p.wg.Wait()
 
 	// Drain channel and remove surviving containers
+	defer p.leases.stop()
 	for {
 		select {
-		case id := <-p.containers:
-			p.removeContainer(id)
+		case c := <-p.containers:
+			p.removeContainer(c.id)
+			p.leases.release(executor.LeaseWarm, c.lease)
 		default:
 			return
 		}
@@ -60,16 +101,56 @@ func (p *Pool) Stop() {
 }
 
 // GetContainer returns a ready-to-use container ID from the pool.
-// It blocks until one is available or the context is canceled.
+// It blocks until one is available or the context is canceled. A pool of
+// size 0 creates a container on the spot.
 func (p *Pool) GetContainer(ctx context.Context) (string, error) {
+	if cap(p.containers) == 0 {
+		return p.createContainer(ctx)
+	}
 	select {
-	case id := <-p.containers:
-		return id, nil
+	case c := <-p.containers:
+		p.leases.release(executor.LeaseWarm, c.lease)
+		p.noteSpare(len(p.containers))
+		return c.id, nil
 	case <-ctx.Done():
 		return "", ctx.Err()
 	}
 }
 
+// Available returns the number of warm containers ready to be handed out.
+func (p *Pool) Available() int {
+	return len(p.containers)
+}
+
+// Size returns how many containers the pool is keeping warm.
+func (p *Pool) Size() int {
+	return int(p.size.Load())
+}
+
+// SetSize changes how many containers the pool keeps warm, within the
+// room it was created with.
+func (p *Pool) SetSize(n int) {
+	p.size.Store(int64(min(max(n, 0), cap(p.containers))))
+}
+
+// noteSpare lowers the spare count to n, if n is lower.
+func (p *Pool) noteSpare(n int) {
+	for {
+		old := p.spare.Load()
+		if int64(n) >= old || p.spare.CompareAndSwap(old, int64(n)) {
+			return
+		}
+	}
+}
+
+// takeSpare returns the fewest warm containers a GetContainer has left
+// behind since the last call — the ones even the busiest moment didn't
+// need — and starts counting again.
+func (p *Pool) takeSpare() int {
+	size := p.Size()
+	return min(int(p.spare.Swap(int64(size))), size)
+}
+
 // manager continuously ensures the pool is at capacity.
 func (p *Pool) manager() {
 	defer p.wg.Done()
@@ -79,10 +160,27 @@ func (p *Pool) manager() {
 		case <-p.done:
 			return
 		default:
-			// Ensure we only try to create a container if there's room in the channel
-			if len(p.containers) < cap(p.containers) {
-				id, err := p.createContainer()
+			// Shrunk: retire a warm container.
+			if len(p.containers) > p.Size() {
+				select {
+				case c := <-p.containers:
+					p.removeContainer(c.id)
+					p.leases.release(executor.LeaseWarm, c.lease)
+				default:
+				}
+				continue
+			}
+			// Ensure we only try to create a container if the pool is short
+			if len(p.containers) < p.Size() {
+				// Other servers' pools may already hold the host's share.
+				lease, ok := p.leases.tryAcquire(context.Background(), executor.LeaseWarm, p.config.MaxWarmTotal)
+				if !ok {
+					time.Sleep(leasePollEvery)
+					continue
+				}
+				id, err := p.createContainer(context.Background())
 				if err != nil {
+					p.leases.release(executor.LeaseWarm, lease)
 					p.logger.Error("failed to create pre-warmed container", slog.String("error", err.Error()))
 					time.Sleep(1 * time.Second) // backoff on failure
 					continue
@@ -90,11 +188,12 @@ func (p *Pool) manager() {
 
 				// Try to push to channel, or delete if shutting down
 				select {
-				case p.containers <- id:
+				case p.containers <- warmContainer{id: id, lease: lease}:
 					// Successfully added to pool
 				case <-p.done:
 					// Shutting down while trying to push
 					p.removeContainer(id)
+					p.leases.release(executor.LeaseWarm, lease)
 					return
 				}
 			} else {
@@ -106,10 +205,41 @@ func (p *Pool) manager() {
 }
 
 // createContainer starts a container running `sleep infinity`.
-func (p *Pool) createContainer() (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (p *Pool) createContainer(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
+	hostConfig := p.hostConfig()
+
+	// A transient failure of either step starts over with a new container.
+	return retry.DoValue(ctx, p.config.retryPolicy(), func(ctx context.Context) (string, error) {
+		resp, err := p.cli.ContainerCreate(ctx, &container.Config{
+			Image:        p.config.Image,
+			Cmd:          []string{"sleep", "infinity"},
+			Tty:          false,
+			AttachStdout: false,
+			AttachStderr: false,
+			// We switch to nobody user or python unprivileged user, but root works for alpine by default.
+			// A more secure implementation would explicitly set User: "nobody".
+			User: "nobody",
+		}, hostConfig, nil, nil, "")
+
+		if err != nil {
+			return "", fmt.Errorf("ContainerCreate failed: %w", err)
+		}
+
+		if err := p.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+			p.removeContainer(resp.ID) // Cleanup
+			return "", fmt.Errorf("ContainerStart failed: %w", err)
+		}
+
+		return resp.ID, nil
+	})
+}
+
+// hostConfig is the sandbox every container gets: no network, capped
+// memory and CPU, and a read-only filesystem but for its tmpfs mounts.
+func (p *Pool) hostConfig() *container.HostConfig {
 	hostConfig := &container.HostConfig{
 		NetworkMode: "none",
 		Resources: container.Resources{
@@ -120,28 +250,25 @@ func (p *Pool) createContainer() (string, error) {
 		// Ensure filesystem is mostly read-only except /tmp
 		ReadonlyRootfs: true,
 	}
-
-	resp, err := p.cli.ContainerCreate(ctx, &container.Config{
-		Image:        p.config.Image,
-		Cmd:          []string{"sleep", "infinity"},
-		Tty:          false,
-		AttachStdout: false,
-		AttachStderr: false,
-		// We switch to nobody user or python unprivileged user, but root works for alpine by default.
-		// A more secure implementation would explicitly set User: "nobody".
-		User: "nobody",
-	}, hostConfig, nil, nil, "")
-
-	if err != nil {
-		return "", fmt.Errorf("ContainerCreate failed: %w", err)
+	hostConfig.Tmpfs = map[string]string{}
+	if p.config.ArtifactLimit > 0 {
+		// The one place code may write: see ARTIFACTS in docker.go.
+		hostConfig.Tmpfs[artifactsDir] = fmt.Sprintf("rw,noexec,nosuid,size=%d,mode=1777", p.config.ArtifactLimit)
 	}
-
-	if err := p.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
-		p.removeContainer(resp.ID) // Cleanup
-		return "", fmt.Errorf("ContainerStart failed: %w", err)
+	if p.config.installs() {
+		// Where requirements are installed: see PACKAGES in docker.go. It
+		// can't be noexec, since compiled extensions are loaded from it.
+		hostConfig.Tmpfs[packagesDir] = fmt.Sprintf("rw,nosuid,size=%d,mode=1777", p.config.PackageLimit)
 	}
-
-	return resp.ID, nil
+	if p.config.GPUs != 0 {
+		// What --gpus asks for: the devices come from whichever driver
+		// offers the "gpu" capability.
+		hostConfig.DeviceRequests = []container.DeviceRequest{{
+			Count:        p.config.GPUs,
+			Capabilities: [][]string{{"gpu"}},
+		}}
+	}
+	return hostConfig
 }
 
 // removeContainer force removes a container by ID.
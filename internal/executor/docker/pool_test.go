@@ -0,0 +1,332 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/executor"
+	"github.com/sakif/coding-playground/internal/retry"
+)
+
+// These run the executor against fakeClient, so they don't need Docker.
+func TestExecutorWithFakeClient(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+
+	for _, poolSize := range []int{0, 2} {
+		cli := newInstantFakeClient()
+		cfg := DefaultConfig()
+		cfg.PoolSize = poolSize
+		exec := newExecutor(cli, cfg, logger)
+
+		res, err := exec.Execute(ctx, executor.ExecutionRequest{Code: `print("hi")`})
+		if err != nil {
+			t.Fatalf("pool size %d: Execute() error = %v", poolSize, err)
+		}
+		if res.ExitCode != 0 || res.Stdout != "print(\"hi\")\n" || res.Stderr != "" {
+			t.Errorf("pool size %d: Execute() = %+v", poolSize, res)
+		}
+
+		res, err = exec.Execute(ctx, executor.ExecutionRequest{Code: "fail()"})
+		if err != nil {
+			t.Fatalf("pool size %d: Execute() error = %v", poolSize, err)
+		}
+		if res.ExitCode != 1 || res.Stderr != "fail()\n" {
+			t.Errorf("pool size %d: failing Execute() = %+v", poolSize, res)
+		}
+
+		// Used containers are removed; warm ones are removed on Close.
+		waitFor(t, func() bool { return exec.pool.Available() == poolSize })
+		if live, _ := cli.stats(); live != poolSize {
+			t.Errorf("pool size %d: %d containers left after two runs, want %d warm", poolSize, live, poolSize)
+		}
+		exec.Close()
+		if live, created := cli.stats(); live != 0 {
+			t.Errorf("pool size %d: %d of %d containers left after Close", poolSize, live, created)
+		}
+	}
+}
+
+func TestExecutorArtifacts(t *testing.T) {
+	exec := newExecutor(newInstantFakeClient(), DefaultConfig(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	defer exec.Close()
+	ctx := context.Background()
+
+	res, err := exec.Execute(ctx, executor.ExecutionRequest{Code: "plt.savefig('plot.png')", Artifacts: true})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if len(res.Artifacts) != 1 {
+		t.Fatalf("Artifacts = %+v, want just plot.png (not the subdirectory's file)", res.Artifacts)
+	}
+	if a := res.Artifacts[0]; a.Name != "plot.png" || a.ContentType != "image/png" || a.Size != int64(len(a.Data)) || a.Size == 0 {
+		t.Errorf("artifact = %+v", a)
+	}
+
+	// Not asked for, or with capture off: no artifacts.
+	res, _ = exec.Execute(ctx, executor.ExecutionRequest{Code: "plt.savefig('plot.png')"})
+	if len(res.Artifacts) != 0 {
+		t.Errorf("Artifacts without asking = %+v, want none", res.Artifacts)
+	}
+	exec.config.ArtifactLimit = 0
+	res, _ = exec.Execute(ctx, executor.ExecutionRequest{Code: "plt.savefig('plot.png')", Artifacts: true})
+	if len(res.Artifacts) != 0 {
+		t.Errorf("Artifacts with ArtifactLimit 0 = %+v, want none", res.Artifacts)
+	}
+	exec.config.ArtifactLimit = 4
+	res, _ = exec.Execute(ctx, executor.ExecutionRequest{Code: "plt.savefig('plot.png')", Artifacts: true})
+	if len(res.Artifacts) != 0 || res.ExitCode != 0 {
+		t.Errorf("artifact over the limit: result = %+v, want the run without it", res)
+	}
+}
+
+func TestExecutorRequirements(t *testing.T) {
+	exec := newExecutor(newInstantFakeClient(), DefaultConfig(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	defer exec.Close()
+	ctx := context.Background()
+
+	// Without a wheelhouse, requirements are ignored.
+	res, err := exec.Execute(ctx, executor.ExecutionRequest{Code: "import numpy", Requirements: "failing-package"})
+	if err != nil || res.ExitCode != 0 || res.Stdout != "import numpy\n" {
+		t.Fatalf("Execute() = %+v, %v; want the code run as it is", res, err)
+	}
+
+	exec.config.Wheelhouse = "/wheels"
+	res, err = exec.Execute(ctx, executor.ExecutionRequest{Code: "import numpy", Requirements: "numpy==1.26.4\n"})
+	if err != nil || res.ExitCode != 0 || res.Stdout != "import numpy\n" {
+		t.Errorf("Execute() = %+v, %v; want the code run after the install", res, err)
+	}
+
+	// The fake fails an exec whose last argument mentions "fail".
+	res, err = exec.Execute(ctx, executor.ExecutionRequest{Code: "import numpy", Requirements: "failing-package"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if res.ExitCode != 1 || res.Stderr != "Installing requirements failed:\nfailing-package\n" || res.Stdout != "" {
+		t.Errorf("failed install: result = %+v, want pip's complaint and no run", res)
+	}
+}
+
+func TestExecutorEnvironmentCache(t *testing.T) {
+	cli := newInstantFakeClient()
+	cfg := DefaultConfig()
+	cfg.Wheelhouse = "/wheels"
+	exec := newExecutor(cli, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	defer exec.Close()
+	ctx := context.Background()
+
+	pipRuns := func() int {
+		cli.mu.Lock()
+		defer cli.mu.Unlock()
+		n := 0
+		for _, cmd := range cli.cmds {
+			if strings.Contains(cmd, "pip install") {
+				n++
+			}
+		}
+		return n
+	}
+
+	for _, manifest := range []string{"numpy==1.26.4\nrequests\n", "Requests\nnumpy==1.26.4\n"} {
+		res, err := exec.Execute(ctx, executor.ExecutionRequest{Code: "import numpy", Requirements: manifest})
+		if err != nil || res.ExitCode != 0 || res.Stdout != "import numpy\n" {
+			t.Fatalf("Execute() = %+v, %v", res, err)
+		}
+	}
+	if n := pipRuns(); n != 1 {
+		t.Errorf("pip ran %d times, want once for the same requirements in another order", n)
+	}
+	stats := exec.Stats().Environments
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Entries != 1 || stats.Bytes == 0 {
+		t.Errorf("Stats().Environments = %+v, want one miss then one hit", stats)
+	}
+
+	if _, err := exec.Execute(ctx, executor.ExecutionRequest{Code: "import numpy", Requirements: "numpy==2.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := pipRuns(); n != 2 {
+		t.Errorf("pip ran %d times, want a fresh install for another version", n)
+	}
+}
+
+func TestExecutorHealthy(t *testing.T) {
+	cli := newInstantFakeClient()
+	exec := newExecutor(cli, DefaultConfig(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	defer exec.Close()
+	ctx := context.Background()
+
+	if err := exec.Healthy(ctx); err != nil {
+		t.Fatalf("Healthy() = %v", err)
+	}
+	cli.mu.Lock()
+	cli.down = true
+	cli.mu.Unlock()
+	if err := exec.Healthy(ctx); err != nil {
+		t.Errorf("Healthy() within healthTTL = %v, want the cached answer", err)
+	}
+	exec.healthAt = time.Time{}
+	if err := exec.Healthy(ctx); err == nil {
+		t.Error("Healthy() with the daemon down = nil")
+	}
+}
+
+func TestCreateContainerRetries(t *testing.T) {
+	cli := newInstantFakeClient()
+	cfg := DefaultConfig()
+	cfg.PoolSize = 0
+	cfg.Retry = retry.Policy{Attempts: 3, Base: time.Millisecond}
+	pool := newPool(cli, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	cli.flaky = 2
+	if id, err := pool.createContainer(ctx); err != nil || id == "" {
+		t.Fatalf("createContainer() after two transient failures = %q, %v", id, err)
+	}
+
+	cli.flaky = 3
+	if _, err := pool.createContainer(ctx); err == nil {
+		t.Error("createContainer() succeeded with every attempt failing")
+	}
+	if cli.flaky != 0 {
+		t.Errorf("%d attempts left untried", cli.flaky)
+	}
+}
+
+func TestCreateContainerGPUs(t *testing.T) {
+	cli := newInstantFakeClient()
+	cfg := DefaultConfig()
+	cfg.PoolSize = 0
+	pool := newPool(cli, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	if _, err := pool.createContainer(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if reqs := cli.host.DeviceRequests; len(reqs) != 0 {
+		t.Errorf("DeviceRequests = %+v, want none without GPUs", reqs)
+	}
+
+	pool.config.GPUs = -1
+	if _, err := pool.createContainer(ctx); err != nil {
+		t.Fatal(err)
+	}
+	reqs := cli.host.DeviceRequests
+	if len(reqs) != 1 || reqs[0].Count != -1 || len(reqs[0].Capabilities) != 1 || reqs[0].Capabilities[0][0] != "gpu" {
+		t.Errorf("DeviceRequests = %+v, want every GPU, as --gpus all asks", reqs)
+	}
+}
+
+func TestPoolRefills(t *testing.T) {
+	cli := newInstantFakeClient()
+	cfg := DefaultConfig()
+	cfg.PoolSize = 3
+	pool := newPool(cli, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	pool.Start()
+	defer pool.Stop()
+
+	waitFor(t, func() bool { return pool.Available() == 3 })
+	for range 3 {
+		if _, err := pool.GetContainer(context.Background()); err != nil {
+			t.Fatalf("GetContainer() error = %v", err)
+		}
+	}
+	waitFor(t, func() bool { return pool.Available() == 3 })
+	if _, created := cli.stats(); created != 6 {
+		t.Errorf("created %d containers, want 6", created)
+	}
+}
+
+func TestPoolResizes(t *testing.T) {
+	cli := newInstantFakeClient()
+	cfg := DefaultConfig()
+	cfg.PoolSize, cfg.MaxPoolSize = 2, 4
+	pool := newPool(cli, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	pool.Start()
+	defer pool.Stop()
+
+	waitFor(t, func() bool { return pool.Available() == 2 })
+	pool.SetSize(10) // more than MaxPoolSize
+	waitFor(t, func() bool { return pool.Available() == 4 })
+	pool.SetSize(1)
+	waitFor(t, func() bool { return pool.Available() == 1 })
+	if live, _ := cli.stats(); live != 1 {
+		t.Errorf("%d containers left after shrinking to 1", live)
+	}
+
+	// Nothing taken: the whole pool was spare.
+	if spare := pool.takeSpare(); spare != 1 {
+		t.Errorf("takeSpare() when idle = %d, want 1", spare)
+	}
+	if _, err := pool.GetContainer(context.Background()); err != nil {
+		t.Fatalf("GetContainer() error = %v", err)
+	}
+	if spare := pool.takeSpare(); spare != 0 {
+		t.Errorf("takeSpare() after emptying the pool = %d, want 0", spare)
+	}
+}
+
+func TestAutoscaler(t *testing.T) {
+	a := &autoscaler{min: 1, max: 5}
+	var waits int64
+	var waited time.Duration
+	stats := func(waiting, n int, each time.Duration) executor.Stats {
+		waits += int64(n)
+		waited += time.Duration(n) * each
+		return executor.Stats{Waiting: waiting, Waits: waits, WaitTime: waited}
+	}
+
+	steps := []struct {
+		name  string
+		size  int
+		stats executor.Stats
+		spare int
+		want  int
+	}{
+		{"runs queued", 3, stats(2, 0, 0), 0, 4},
+		{"slow waits", 4, stats(0, 10, 400*time.Millisecond), 0, 5},
+		{"at the maximum", 5, stats(3, 10, time.Second), 0, 5},
+		{"fast, pool used up", 5, stats(0, 10, time.Millisecond), 1, 5},
+		{"idle containers", 5, stats(0, 10, time.Millisecond), 3, 4},
+		{"no runs at all", 4, stats(0, 0, 0), 4, 3},
+		{"at the minimum", 1, stats(0, 0, 0), 1, 1},
+	}
+	for _, step := range steps {
+		got, reason := a.next(step.size, step.stats, step.spare)
+		if got != step.want {
+			t.Errorf("%s: next(%d) = %d, want %d", step.name, step.size, got, step.want)
+		}
+		if (got != step.size) != (reason != "") {
+			t.Errorf("%s: reason = %q for %d → %d", step.name, reason, step.size, got)
+		}
+	}
+}
+
+func TestPoolBounds(t *testing.T) {
+	for _, tt := range []struct{ size, lo, hi, wantSize, wantMin int }{
+		{3, 0, 0, 3, 0}, // fixed size, untouched
+		{0, 0, 4, 1, 1}, // an adaptive pool keeps one warm
+		{9, 2, 4, 4, 2}, // PoolSize capped at the maximum
+		{1, 6, 4, 4, 4}, // minimum above maximum
+	} {
+		cfg := Config{PoolSize: tt.size, MinPoolSize: tt.lo, MaxPoolSize: tt.hi}.withPoolBounds()
+		if cfg.PoolSize != tt.wantSize || cfg.MinPoolSize != tt.wantMin {
+			t.Errorf("bounds(size %d, %d..%d) = size %d, min %d; want %d, %d",
+				tt.size, tt.lo, tt.hi, cfg.PoolSize, cfg.MinPoolSize, tt.wantSize, tt.wantMin)
+		}
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 5s")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,37 @@
+package docker
+
+import (
+	"errors"
+	"io"
+	"syscall"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/client"
+
+	"github.com/sakif/coding-playground/internal/retry"
+)
+
+// TRANSIENT DAEMON ERRORS:
+// A daemon that's restarting, or a connection to it that drops, fails a
+// container create or an exec create in a way that the same call a moment
+// later won't. Those calls are retried with Config.Retry. Anything else —
+// a missing image, a container that's gone — fails at once.
+//
+// Attaching to an exec is never retried: attaching starts the code, and by
+// the time it fails the code may already have run.
+
+// retryPolicy is Config.Retry, limited to transient errors.
+func (c Config) retryPolicy() retry.Policy {
+	p := c.Retry
+	p.Retryable = transient
+	return p
+}
+
+// transient reports whether err is worth retrying.
+func transient(err error) bool {
+	return client.IsErrConnectionFailed(err) ||
+		cerrdefs.IsUnavailable(err) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,94 @@
+package executor
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DOCS MODE:
+// Hovering over os.path.join in the editor shows its signature and
+// docstring. They come from the Python in the sandbox image — the same
+// version, and the same installed packages, the code will run with — so
+// LookUpDocs runs a harness (docs.py) there that finds the name with
+// pydoc.locate and reads it with inspect.
+
+//go:embed docs.py
+var docsHarness string
+
+// MaxDocLength caps a docstring, in characters; longer ones are cut.
+const MaxDocLength = 20000
+
+// ErrSymbolNotFound is returned when no module or builtin has the name.
+var ErrSymbolNotFound = errors.New("executor: no such symbol")
+
+// symbolPattern is a dotted Python name. It's checked before the symbol is
+// put into the harness, so it can't be anything but a name there.
+var symbolPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// ValidSymbol reports whether symbol is a dotted name LookUpDocs accepts.
+func ValidSymbol(symbol string) bool {
+	return symbolPattern.MatchString(symbol)
+}
+
+// Doc is what Python knows about a name.
+type Doc struct {
+	Symbol string `json:"symbol"` // as asked for: "os.path.join"
+	// Kind is "module", "class", "function", "method", "property" or
+	// "instance" (any other value, such as os.sep).
+	Kind      string `json:"kind"`
+	Signature string `json:"signature,omitempty"` // "join(a, *p)", for what can be called and has one
+	Doc       string `json:"doc"`                 // the docstring, indentation cleaned up
+	Module    string `json:"module"`              // where it's defined: "posixpath"
+	Value     string `json:"value,omitempty"`     // an instance's repr, cut at 200 characters
+}
+
+// LookUpDocs returns the docs for symbol, running the harness on exec.
+func LookUpDocs(ctx context.Context, exec Executor, symbol string) (*Doc, error) {
+	if !ValidSymbol(symbol) {
+		return nil, fmt.Errorf("executor: %q isn't a dotted name", symbol)
+	}
+	marker, err := newMarker("docs")
+	if err != nil {
+		return nil, err
+	}
+	program := strings.NewReplacer(
+		"__MARKER__", marker,
+		"__SYMBOL__", symbol,
+		"__MAX_DOC__", strconv.Itoa(MaxDocLength),
+	).Replace(docsHarness)
+
+	result, err := exec.Execute(ctx, ExecutionRequest{Code: program})
+	if err != nil {
+		return nil, err
+	}
+	return parseDoc(result, marker)
+}
+
+// parseDoc finds the harness's result in its output.
+func parseDoc(result *ExecutionResult, marker string) (*Doc, error) {
+	i := strings.LastIndex(result.Stdout, "\n"+marker)
+	if i < 0 {
+		if result.ExitCode == 124 {
+			return nil, errors.New("executor: docs lookup timed out")
+		}
+		return nil, fmt.Errorf("executor: docs lookup failed: %s", lastLine(result.Stderr))
+	}
+	var doc struct {
+		Doc
+		Found bool `json:"found"`
+	}
+	line, _, _ := strings.Cut(result.Stdout[i+1+len(marker):], "\n")
+	if err := json.Unmarshal([]byte(line), &doc); err != nil {
+		return nil, fmt.Errorf("executor: reading docs: %w", err)
+	}
+	if !doc.Found {
+		return nil, ErrSymbolNotFound
+	}
+	return &doc.Doc, nil
+}
@@ -0,0 +1,81 @@
+# Documentation harness, filled in and run by LookUpDocs (docs.go).
+#
+# SYMBOL is a dotted name — os.path.join, print, str.split — found the way
+# pydoc finds it: the longest importable module prefix is imported, the rest
+# looked up as attributes, and names with no module are builtins. Importing
+# runs only the installed module's own code; there's no user code here.
+#
+# The result is one JSON line after a marker, printed last.
+
+import inspect
+import json
+import pydoc
+import sys
+
+MARKER = "__MARKER__"
+SYMBOL = "__SYMBOL__"
+MAX_DOC = __MAX_DOC__
+
+
+def _kind(obj):
+    if inspect.ismodule(obj):
+        return "module"
+    if inspect.isclass(obj):
+        return "class"
+    if inspect.ismethod(obj) or inspect.ismethoddescriptor(obj):
+        return "method"
+    if inspect.isroutine(obj):
+        return "function"
+    if isinstance(obj, property):
+        return "property"
+    return "instance"
+
+
+def _signature(obj):
+    # Builtins written in C often have no signature to show.
+    if not callable(obj):
+        return ""
+    try:
+        return SYMBOL.rpartition(".")[2] + str(inspect.signature(obj))
+    except (TypeError, ValueError):
+        return ""
+
+
+def _module(obj, kind):
+    # Where obj is defined. Methods of builtin types (str.split) are the
+    # type's; a plain value (os.sep) is the module holding it.
+    module = inspect.getmodule(obj) or inspect.getmodule(getattr(obj, "__objclass__", None))
+    if module is not None:
+        return module.__name__
+    if kind == "instance" and "." in SYMBOL:
+        return SYMBOL.rpartition(".")[0]
+    return "builtins"
+
+
+def main():
+    try:
+        obj = pydoc.locate(SYMBOL)
+    except pydoc.ErrorDuringImport:
+        obj = None
+    if obj is None:
+        result = {"symbol": SYMBOL, "found": False}
+    else:
+        kind = _kind(obj)
+        doc = "" if kind == "instance" else inspect.getdoc(obj) or ""
+        if len(doc) > MAX_DOC:
+            doc = doc[:MAX_DOC] + "\n…"
+        result = {
+            "symbol": SYMBOL,
+            "found": True,
+            "kind": kind,
+            "signature": _signature(obj),
+            "doc": doc,
+            "module": _module(obj, kind),
+        }
+        if kind == "instance":
+            result["value"] = repr(obj)[:200]
+    sys.stdout.flush()
+    print("\n" + MARKER + json.dumps(result))
+
+
+main()
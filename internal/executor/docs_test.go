@@ -0,0 +1,68 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestLookUpDocs(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not installed")
+	}
+	py := localPython{python}
+
+	tests := []struct {
+		symbol, kind, signature, module string
+	}{
+		{"os.path.join", "function", "join(a, *p)", "posixpath"},
+		{"collections.Counter", "class", "Counter(iterable=None, /, **kwds)", "collections"},
+		{"str.split", "method", "split(self, /, sep=None, maxsplit=-1)", "builtins"},
+		{"math", "module", "", "math"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			doc, err := LookUpDocs(context.Background(), py, tt.symbol)
+			if err != nil {
+				t.Fatalf("LookUpDocs() error = %v", err)
+			}
+			if doc.Symbol != tt.symbol || doc.Kind != tt.kind || doc.Signature != tt.signature || doc.Module != tt.module || doc.Doc == "" {
+				t.Errorf("doc = %+v", doc)
+			}
+		})
+	}
+
+	t.Run("a value", func(t *testing.T) {
+		doc, err := LookUpDocs(context.Background(), py, "os.sep")
+		if err != nil {
+			t.Fatalf("LookUpDocs() error = %v", err)
+		}
+		if doc.Kind != "instance" || doc.Value != "'/'" || doc.Doc != "" {
+			t.Errorf("doc = %+v, want the value and not str's docstring", doc)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		for _, symbol := range []string{"nosuchmodule", "os.path.nosuchfunction"} {
+			if _, err := LookUpDocs(context.Background(), py, symbol); !errors.Is(err, ErrSymbolNotFound) {
+				t.Errorf("LookUpDocs(%s) error = %v, want ErrSymbolNotFound", symbol, err)
+			}
+		}
+	})
+}
+
+func TestValidSymbol(t *testing.T) {
+	for _, symbol := range []string{"print", "os.path.join", "_private", "np2.linalg"} {
+		if !ValidSymbol(symbol) {
+			t.Errorf("ValidSymbol(%q) = false", symbol)
+		}
+	}
+	for _, symbol := range []string{"", "os.", ".path", "2to3", `os"; import shutil; "`, "os path", strings.Repeat("a.", 3) + "1"} {
+		if ValidSymbol(symbol) {
+			t.Errorf("ValidSymbol(%q) = true", symbol)
+		}
+	}
+}
@@ -2,12 +2,51 @@ package executor
 
 import (
 	"context"
+	"io"
 	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
 )
 
+// Language is what every ExecutionRequest is written in.
+const Language = "python"
+
 // ExecutionRequest represents a request to execute Python code.
 type ExecutionRequest struct {
 	Code string `json:"code"`
+	// Artifacts asks for the files the code writes to $ARTIFACTS_DIR back,
+	// in ExecutionResult.Artifacts. Executors that can't capture files
+	// ignore it.
+	Artifacts bool `json:"artifacts,omitempty"`
+	// Profile asks for the run to be profiled (see RunProfiled), and for
+	// how many hotspots. Executors run the code as it is; it's the caller's
+	// to profile it.
+	Profile *ProfileOptions `json:"profile,omitempty"`
+	// Requirements lists packages to install before the code runs, in
+	// requirements.txt format (see requirements.go). Callers check it
+	// against their PackageAllowlist; executors only install it, and
+	// those that can't install packages ignore it.
+	Requirements string `json:"requirements,omitempty"`
+	// GPU asks for the run to have a GPU. Callers send such runs to an
+	// executor whose sandboxes have one (see docker.Config.GPUs), once
+	// they've checked the user may use it; executors run the code the same
+	// either way.
+	GPU bool `json:"gpu,omitempty"`
+	// Stdin is the program's standard input, read while it runs; see
+	// Debug. Executors that can't feed a program input give it an empty
+	// one, as they do when Stdin is nil.
+	Stdin io.Reader `json:"-"`
+}
+
+// Artifact is a file a run left in $ARTIFACTS_DIR, like a saved plot.
+// Executors fill in Data; once it's stored (see service.ArtifactService)
+// Data is dropped and URL says where to fetch it.
+type Artifact struct {
+	Name        string `json:"name"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"contentType"`
+	URL         string `json:"url,omitempty"`
+	Data        []byte `json:"-"`
 }
 
 // ExecutionResult represents the output and status of the code execution.
@@ -16,9 +55,81 @@ type ExecutionResult struct {
 	Stderr   string        `json:"stderr"`
 	ExitCode int           `json:"exitCode"`
 	Duration time.Duration `json:"duration"`
+	// ErrorCode says why a run didn't finish on its own, e.g.
+	// apperror.CodeExecutionTimeout (with ExitCode 124).
+	ErrorCode apperror.Code `json:"errorCode,omitempty"`
+
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+	// Profile is where the time went, for a profiled run.
+	Profile *Profile `json:"profile,omitempty"`
+
+	// RunID is the run's ID in the run history, for replaying it; ReplayOf
+	// is the run this one replayed. Set by service.RunService, and only
+	// for signed-in users' runs.
+	RunID    string `json:"runId,omitempty"`
+	ReplayOf string `json:"replayOf,omitempty"`
 }
 
 // Executor represents the core interface for running code in an isolated environment.
 type Executor interface {
 	Execute(ctx context.Context, req ExecutionRequest) (*ExecutionResult, error)
 }
+
+// Stats is a point-in-time snapshot of an executor's capacity, used for monitoring.
+//
+// Waiting, Waits and WaitTime describe the queue in front of the sandboxes:
+// an execution waits when every sandbox is taken (or the servers sharing
+// the host are at their limit). WaitTime/Waits is the average wait, and a
+// Waiting that stays above zero means executions are about to time out.
+type Stats struct {
+	PoolSize  int           `json:"poolSize"`  // number of pre-warmed sandboxes kept ready
+	Available int           `json:"available"` // pre-warmed sandboxes ready right now
+	InFlight  int           `json:"inFlight"`  // executions currently running, waiting ones included
+	Waiting   int           `json:"waiting"`   // executions waiting for a sandbox
+	Waits     int64         `json:"waits"`     // executions that have had a sandbox, ever
+	WaitTime  time.Duration `json:"waitTime"`  // total time those spent waiting for one
+
+	// Environments is the cache of installed requirements, if the
+	// executor keeps one.
+	Environments EnvironmentStats `json:"environments"`
+}
+
+// EnvironmentStats describes an executor's cache of installed requirement
+// sets (see ExecutionRequest.Requirements). Hits/(Hits+Misses) is how
+// often a run was spared an install.
+type EnvironmentStats struct {
+	Hits      int64 `json:"hits"`      // runs whose requirements were restored from the cache
+	Misses    int64 `json:"misses"`    // runs that had to install them
+	Evictions int64 `json:"evictions"` // environments dropped to make room
+	Entries   int   `json:"entries"`   // environments cached now
+	Bytes     int64 `json:"bytes"`     // their packed size
+}
+
+// StatsProvider is implemented by executors that can report capacity statistics.
+// It is optional — callers type-assert for it rather than requiring it on Executor.
+type StatsProvider interface {
+	Stats() Stats
+}
+
+// HealthChecker is implemented by executors that can cheaply tell whether
+// they can take a run right now. Like StatsProvider it's optional.
+type HealthChecker interface {
+	// Healthy returns nil if a run would be accepted, or why not.
+	Healthy(ctx context.Context) error
+}
+
+// Output is a piece of a running program's output, as it was written.
+type Output struct {
+	Stream string `json:"stream"` // "stdout" or "stderr"
+	Text   string `json:"text"`
+}
+
+// Streamer is implemented by executors that can report output while the
+// code is still running. Like StatsProvider it's optional: callers
+// type-assert for it and fall back to Execute.
+type Streamer interface {
+	// ExecuteStream runs the code like Execute, calling out with each piece
+	// of output as it arrives. Calls to out never overlap. The result still
+	// holds all of the output.
+	ExecuteStream(ctx context.Context, req ExecutionRequest, out func(Output)) (*ExecutionResult, error)
+}
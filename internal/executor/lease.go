@@ -0,0 +1,39 @@
+package executor
+
+import (
+	"context"
+	"time"
+)
+
+// SHARING A SANDBOX HOST:
+// Each server's pool keeps its own warm containers and runs as many
+// executions as it's asked to. Put three servers on one Docker host (or in
+// front of one sandbox fleet) and the host gets three pools' worth: more
+// warm containers than it has memory for, and more runs at once than it has
+// CPUs. A LeaseStore shared by the servers caps the totals instead. Every
+// warm container and every running execution holds a lease of its kind;
+// when the cap's reached, a server waits for a lease rather than starting
+// more.
+//
+// Leases expire unless renewed, so a server that crashes gives its share
+// back after the TTL instead of holding it forever.
+
+// Lease kinds.
+const (
+	LeaseWarm    = "warm"    // a pre-warmed container waiting in a pool
+	LeaseRunning = "running" // an execution in progress
+)
+
+// LeaseStore shares a limited number of leases of each kind between every
+// server using it. The SQLite repository and RedisLeaseStore implement it.
+type LeaseStore interface {
+	// AcquireLease takes a lease of kind under id if fewer than limit
+	// unexpired ones are held, and reports whether it did. Acquiring an id
+	// that's already held renews it. The lease lapses after ttl.
+	AcquireLease(ctx context.Context, kind, id string, limit int, ttl time.Duration) (bool, error)
+	// RenewLeases extends the leases of kind held under ids to ttl from
+	// now. Ones that have lapsed stay lapsed.
+	RenewLeases(ctx context.Context, kind string, ids []string, ttl time.Duration) error
+	// ReleaseLease gives a lease back; a missing one is ignored.
+	ReleaseLease(ctx context.Context, kind, id string) error
+}
@@ -0,0 +1,86 @@
+package executor
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/redis"
+)
+
+// RedisLeaseStore keeps leases in Redis: one sorted set per kind, with each
+// lease's expiry time as its score. Every operation is a Lua script, so
+// counting the live leases and adding one happen together, and the scripts
+// read the clock with TIME so servers with skewed clocks agree.
+type RedisLeaseStore struct {
+	client *redis.Client
+	prefix string
+}
+
+var _ LeaseStore = (*RedisLeaseStore)(nil)
+
+// NewRedisLeaseStore creates a store putting prefix before every key
+// (e.g. "playground:leases:").
+func NewRedisLeaseStore(client *redis.Client, prefix string) *RedisLeaseStore {
+	return &RedisLeaseStore{client: client, prefix: prefix}
+}
+
+// leaseNow is the milliseconds-since-epoch clock the scripts share.
+const leaseNow = `
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+`
+
+// acquireLeaseScript: KEYS[1] the kind's set; ARGV id, limit, ttl (ms).
+// Returns 1 if the lease was taken.
+var acquireLeaseScript = redis.NewScript(leaseNow + `
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+if redis.call('ZSCORE', KEYS[1], ARGV[1]) or redis.call('ZCARD', KEYS[1]) < tonumber(ARGV[2]) then
+  redis.call('ZADD', KEYS[1], now + tonumber(ARGV[3]), ARGV[1])
+  redis.call('PEXPIRE', KEYS[1], ARGV[3])
+  return 1
+end
+return 0
+`)
+
+// renewLeasesScript: KEYS[1] the kind's set; ARGV ttl (ms), then ids.
+var renewLeasesScript = redis.NewScript(leaseNow + `
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+for i = 2, #ARGV do
+  redis.call('ZADD', KEYS[1], 'XX', now + tonumber(ARGV[1]), ARGV[i])
+end
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+return 0
+`)
+
+// releaseLeaseScript: KEYS[1] the kind's set; ARGV[1] the id.
+var releaseLeaseScript = redis.NewScript(`
+return redis.call('ZREM', KEYS[1], ARGV[1])
+`)
+
+// AcquireLease runs acquireLeaseScript.
+func (s *RedisLeaseStore) AcquireLease(ctx context.Context, kind, id string, limit int, ttl time.Duration) (bool, error) {
+	n, err := redis.Int(acquireLeaseScript.Run(ctx, s.client, []string{s.prefix + kind},
+		id, strconv.Itoa(limit), leaseMillis(ttl)))
+	return n == 1, err
+}
+
+// RenewLeases runs renewLeasesScript.
+func (s *RedisLeaseStore) RenewLeases(ctx context.Context, kind string, ids []string, ttl time.Duration) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := append([]string{leaseMillis(ttl)}, ids...)
+	_, err := renewLeasesScript.Run(ctx, s.client, []string{s.prefix + kind}, args...)
+	return err
+}
+
+// ReleaseLease runs releaseLeaseScript.
+func (s *RedisLeaseStore) ReleaseLease(ctx context.Context, kind, id string) error {
+	_, err := releaseLeaseScript.Run(ctx, s.client, []string{s.prefix + kind}, id)
+	return err
+}
+
+func leaseMillis(ttl time.Duration) string {
+	return strconv.FormatInt(max(ttl.Milliseconds(), 1), 10)
+}
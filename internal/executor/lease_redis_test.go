@@ -0,0 +1,116 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/redis/redistest"
+)
+
+// fakeLeaseScripts stands in for the lease scripts on redistest, which
+// can't run Lua. A kind's sorted set is kept as JSON: id → expiry (ms).
+func fakeLeaseScripts(srv *redistest.Server) {
+	load := func(tx *redistest.Tx, key string) map[string]int64 {
+		set := map[string]int64{}
+		if v, ok := tx.Get(key); ok {
+			json.Unmarshal([]byte(v), &set)
+		}
+		now := tx.Now().UnixMilli()
+		for id, expires := range set {
+			if expires <= now {
+				delete(set, id)
+			}
+		}
+		return set
+	}
+	save := func(tx *redistest.Tx, key string, set map[string]int64) {
+		data, _ := json.Marshal(set)
+		tx.Set(key, string(data), 0)
+	}
+
+	srv.HandleScript(acquireLeaseScript, func(tx *redistest.Tx, keys, args []string) any {
+		set := load(tx, keys[0])
+		limit, _ := strconv.Atoi(args[1])
+		ttl, _ := strconv.ParseInt(args[2], 10, 64)
+		if _, held := set[args[0]]; !held && len(set) >= limit {
+			return int64(0)
+		}
+		set[args[0]] = tx.Now().UnixMilli() + ttl
+		save(tx, keys[0], set)
+		return int64(1)
+	})
+	srv.HandleScript(renewLeasesScript, func(tx *redistest.Tx, keys, args []string) any {
+		set := load(tx, keys[0])
+		ttl, _ := strconv.ParseInt(args[0], 10, 64)
+		for _, id := range args[1:] {
+			if _, held := set[id]; held {
+				set[id] = tx.Now().UnixMilli() + ttl
+			}
+		}
+		save(tx, keys[0], set)
+		return int64(0)
+	})
+	srv.HandleScript(releaseLeaseScript, func(tx *redistest.Tx, keys, args []string) any {
+		set := load(tx, keys[0])
+		delete(set, args[0])
+		save(tx, keys[0], set)
+		return int64(1)
+	})
+}
+
+func TestRedisLeaseStore(t *testing.T) {
+	srv := redistest.NewServer(t)
+	fakeLeaseScripts(srv)
+	now := time.Now()
+	srv.SetNow(func() time.Time { return now })
+
+	// Two servers sharing the host, each with its own client.
+	a := NewRedisLeaseStore(srv.Client(t), "test:")
+	b := NewRedisLeaseStore(srv.Client(t), "test:")
+	ctx := context.Background()
+
+	acquire := func(s *RedisLeaseStore, id string) bool {
+		t.Helper()
+		ok, err := s.AcquireLease(ctx, LeaseRunning, id, 2, 30*time.Second)
+		if err != nil {
+			t.Fatalf("AcquireLease(%s) error = %v", id, err)
+		}
+		return ok
+	}
+
+	if !acquire(a, "a1") || !acquire(b, "b1") {
+		t.Fatal("the first two leases should be granted")
+	}
+	if acquire(b, "b2") {
+		t.Error("a third lease was granted over the limit of 2")
+	}
+	if !acquire(a, "a1") {
+		t.Error("re-acquiring a held lease was refused")
+	}
+	if ok, _ := a.AcquireLease(ctx, LeaseWarm, "w1", 2, time.Minute); !ok {
+		t.Error("leases of another kind should be counted separately")
+	}
+
+	if err := a.ReleaseLease(ctx, LeaseRunning, "a1"); err != nil {
+		t.Fatalf("ReleaseLease() error = %v", err)
+	}
+	if !acquire(b, "b2") {
+		t.Error("a released lease wasn't freed")
+	}
+
+	// b stops renewing (it crashed); a keeps renewing its lease.
+	now = now.Add(20 * time.Second)
+	if err := a.RenewLeases(ctx, LeaseWarm, []string{"w1"}, 30*time.Second); err != nil {
+		t.Fatalf("RenewLeases() error = %v", err)
+	}
+	now = now.Add(20 * time.Second)
+	if !acquire(a, "a2") || !acquire(a, "a3") {
+		t.Error("leases of a server that stopped renewing them didn't lapse")
+	}
+	if ok, _ := b.AcquireLease(ctx, LeaseWarm, "w2", 1, time.Minute); ok {
+		t.Error("the renewed warm lease lapsed")
+	}
+}
@@ -0,0 +1,117 @@
+// Package local runs code with the host's own Python interpreter.
+//
+// NO SANDBOX:
+// Unlike the Docker executor there's no isolation here beyond a fresh
+// temporary directory and a stripped environment: the code runs as the
+// server's user, with its network and its filesystem. That makes it a
+// fallback for deployments where everyone who can run code is trusted —
+// a classroom on a private network, a developer's laptop — for the minutes
+// a Docker daemon takes to restart. It's off unless asked for
+// (EXECUTOR_LOCAL_FALLBACK).
+package local
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/executor"
+)
+
+// Config configures an Executor.
+type Config struct {
+	// Python is the interpreter to run (default "python3", found on PATH).
+	Python string
+	// Timeout is the maximum amount of time the execution can take.
+	Timeout time.Duration
+}
+
+// Executor runs code as a child process.
+type Executor struct {
+	python  string
+	timeout time.Duration
+}
+
+var (
+	_ executor.Executor      = (*Executor)(nil)
+	_ executor.HealthChecker = (*Executor)(nil)
+)
+
+// New creates an Executor, failing if the interpreter can't be found.
+func New(cfg Config) (*Executor, error) {
+	if cfg.Python == "" {
+		cfg.Python = "python3"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	python, err := exec.LookPath(cfg.Python)
+	if err != nil {
+		return nil, fmt.Errorf("local executor: %w", err)
+	}
+	return &Executor{python: python, timeout: cfg.Timeout}, nil
+}
+
+// Healthy reports whether the interpreter is still there.
+func (e *Executor) Healthy(context.Context) error {
+	if _, err := os.Stat(e.python); err != nil {
+		return fmt.Errorf("local executor: %w", err)
+	}
+	return nil
+}
+
+// Execute runs req.Code with `python -I -c`, in an empty temporary
+// directory that's removed afterwards. Files it writes aren't kept:
+// req.Artifacts is ignored.
+func (e *Executor) Execute(ctx context.Context, req executor.ExecutionRequest) (*executor.ExecutionResult, error) {
+	start := time.Now()
+
+	dir, err := os.MkdirTemp("", "playground-run-")
+	if err != nil {
+		return nil, fmt.Errorf("local executor: creating a work directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	runCtx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	// -I: isolated mode — ignore PYTHON* variables and the user's
+	// site-packages, and don't put the working directory on sys.path.
+	cmd := exec.CommandContext(runCtx, e.python, "-I", "-c", req.Code)
+	cmd.Dir = dir
+	cmd.Env = []string{"PATH=" + os.Getenv("PATH"), "HOME=" + dir, "TMPDIR=" + dir, "PYTHONDONTWRITEBYTECODE=1"}
+	cmd.WaitDelay = time.Second // don't wait forever on pipes a child process kept open
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = req.Stdin
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	err = cmd.Run()
+	exitCode := 0
+	var errorCode apperror.Code
+	var exitErr *exec.ExitError
+	switch {
+	case runCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil:
+		exitCode = 124 // like the Docker executor, and the unix timeout command
+		errorCode = apperror.CodeExecutionTimeout
+		stderr.WriteString("\nExecution timed out.\n")
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case errors.As(err, &exitErr):
+		exitCode = exitErr.ExitCode()
+	case err != nil:
+		return nil, fmt.Errorf("local executor: running python: %w", err)
+	}
+
+	return &executor.ExecutionResult{
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		ExitCode:  exitCode,
+		Duration:  time.Since(start),
+		ErrorCode: errorCode,
+	}, nil
+}
@@ -0,0 +1,53 @@
+package local
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/executor"
+)
+
+func TestExecutor(t *testing.T) {
+	e, err := New(Config{Timeout: 2 * time.Second})
+	if err != nil {
+		t.Skipf("no python3 on PATH: %v", err)
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		code       string
+		wantStdout string
+		wantStderr string
+		wantExit   int
+	}{
+		{"prints", `print("hi")`, "hi\n", "", 0},
+		{"raises", `raise SystemExit("bad")`, "", "bad\n", 1},
+		{"runs in a fresh directory", `import os; print(os.listdir("."))`, "[]\n", "", 0},
+		{"times out", `import time; time.sleep(10)`, "", "Execution timed out.", 124},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantExit == 124 {
+				e.timeout = 100 * time.Millisecond
+				defer func() { e.timeout = 2 * time.Second }()
+			}
+			res, err := e.Execute(ctx, executor.ExecutionRequest{Code: tt.code})
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if res.Stdout != tt.wantStdout || !strings.Contains(res.Stderr, tt.wantStderr) || res.ExitCode != tt.wantExit {
+				t.Errorf("Execute() = %+v, want stdout %q, stderr with %q, exit %d", res, tt.wantStdout, tt.wantStderr, tt.wantExit)
+			}
+		})
+	}
+
+	if err := e.Healthy(ctx); err != nil {
+		t.Errorf("Healthy() = %v", err)
+	}
+	if _, err := New(Config{Python: "no-such-python"}); err == nil {
+		t.Error("New() with a missing interpreter succeeded")
+	}
+}
@@ -0,0 +1,113 @@
+package executor
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PROFILING MODE:
+// {"code":"…","profile":{"top":10}} runs the code under cProfile (see
+// profiler.py) and says where the time went, function by function:
+//
+//	"profile":{"totalTime":41000000,"calls":1200003,"hotspots":[
+//	  {"function":"fib","file":"<main>","line":1,"calls":1200001,"primitiveCalls":1,"selfTime":38000000,…},…]}
+//
+// Hotspots are ranked by their own time — not counting what they called,
+// which cumulativeTime does — because that's where the work is: a slow
+// sort is the sort, not main. Times are nanoseconds, like a result's
+// duration, and cProfile's overhead inflates them; compare them with each
+// other rather than with an unprofiled run. The program's output and exit
+// status are as they'd be without the profiler.
+//
+// When the executor collects artifacts (ExecutionRequest.Artifacts), the
+// whole profile comes back too, as profile.pstats.
+
+//go:embed profiler.py
+var profileHarness string
+
+const (
+	// DefaultHotspots is how many hotspots a profile has, unless asked.
+	DefaultHotspots = 20
+	// MaxHotspots caps ProfileOptions.Top.
+	MaxHotspots = 100
+)
+
+// ErrProfileProgramTooLarge is returned when the code doesn't fit in
+// MaxTestProgramBytes with the harness around it.
+var ErrProfileProgramTooLarge = errors.New("executor: the code is too large to profile")
+
+// ProfileOptions asks for a run to be profiled.
+type ProfileOptions struct {
+	Top int `json:"top,omitempty"` // how many hotspots; 0 is DefaultHotspots
+}
+
+// Profile is where a run's time went.
+type Profile struct {
+	TotalTime time.Duration `json:"totalTime"`
+	Calls     int           `json:"calls"`    // function calls, builtins included
+	Hotspots  []Hotspot     `json:"hotspots"` // the most self time first
+}
+
+// Hotspot is a function in a Profile.
+type Hotspot struct {
+	Function string `json:"function"`
+	File     string `json:"file"` // "<main>" for the code's own; "" for builtins
+	Line     int    `json:"line"`
+	Calls    int    `json:"calls"`
+	// PrimitiveCalls leaves out recursive calls: a recursive function
+	// called once from outside has one.
+	PrimitiveCalls int           `json:"primitiveCalls"`
+	SelfTime       time.Duration `json:"selfTime"`
+	CumulativeTime time.Duration `json:"cumulativeTime"` // with the time in what it called
+}
+
+// RunProfiled runs req under cProfile on exec, returning the result with
+// its Profile. A run that never got to report — it timed out, or was
+// killed — comes back without one.
+func RunProfiled(ctx context.Context, exec Executor, req ExecutionRequest) (*ExecutionResult, error) {
+	top := DefaultHotspots
+	if req.Profile != nil && req.Profile.Top > 0 {
+		top = min(req.Profile.Top, MaxHotspots)
+	}
+	marker, err := newMarker("profile")
+	if err != nil {
+		return nil, err
+	}
+	source, err := compress(req.Code)
+	if err != nil {
+		return nil, err
+	}
+	program := strings.NewReplacer(
+		"__MARKER__", marker,
+		"__SOURCE__", source,
+		"__TOP__", strconv.Itoa(top),
+	).Replace(profileHarness)
+	if len(program) > MaxTestProgramBytes {
+		return nil, ErrProfileProgramTooLarge
+	}
+
+	profiled := req
+	profiled.Code = program
+	result, err := exec.Execute(ctx, profiled)
+	if err != nil {
+		return nil, err
+	}
+	i := strings.LastIndex(result.Stdout, "\n"+marker)
+	if i < 0 {
+		return result, nil
+	}
+	var profile Profile
+	line, _, _ := strings.Cut(result.Stdout[i+1+len(marker):], "\n")
+	if err := json.Unmarshal([]byte(line), &profile); err != nil {
+		return nil, fmt.Errorf("executor: reading the profile: %w", err)
+	}
+	result.Stdout = result.Stdout[:i]
+	result.Profile = &profile
+	return result, nil
+}
@@ -0,0 +1,63 @@
+package executor
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestRunProfiled(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not installed")
+	}
+	py := localPython{python}
+
+	const code = `def fib(n):
+    return n if n < 2 else fib(n - 1) + fib(n - 2)
+
+def main():
+    print(fib(20), end="")
+
+main()
+`
+	result, err := RunProfiled(context.Background(), py, ExecutionRequest{Code: code, Profile: &ProfileOptions{Top: 3}})
+	if err != nil {
+		t.Fatalf("RunProfiled() error = %v", err)
+	}
+	if result.Stdout != "6765" || result.ExitCode != 0 {
+		t.Errorf("result = %+v, want the program's own output", result)
+	}
+	p := result.Profile
+	if p == nil || len(p.Hotspots) != 3 {
+		t.Fatalf("profile = %+v, want 3 hotspots", p)
+	}
+	fib := p.Hotspots[0]
+	if fib.Function != "fib" || fib.File != "<main>" || fib.Line != 1 || fib.Calls != 21891 || fib.PrimitiveCalls != 1 {
+		t.Errorf("top hotspot = %+v, want fib", fib)
+	}
+	if fib.SelfTime <= 0 || fib.CumulativeTime < fib.SelfTime || p.TotalTime < fib.SelfTime {
+		t.Errorf("times = %+v (total %v)", fib, p.TotalTime)
+	}
+
+	t.Run("a failing program", func(t *testing.T) {
+		result, err := RunProfiled(context.Background(), py, ExecutionRequest{Code: "print('hi')\n1 / 0\n"})
+		if err != nil {
+			t.Fatalf("RunProfiled() error = %v", err)
+		}
+		if result.Stdout != "hi\n" || result.ExitCode != 1 || result.Profile == nil {
+			t.Errorf("result = %+v, want the output, exit 1 and a profile", result)
+		}
+		if !strings.Contains(result.Stderr, `File "<main>", line 2`) || strings.Contains(result.Stderr, "cProfile") {
+			t.Errorf("stderr = %q, want the program's traceback alone", result.Stderr)
+		}
+	})
+
+	t.Run("an exit status", func(t *testing.T) {
+		result, err := RunProfiled(context.Background(), py, ExecutionRequest{Code: "import sys\nsys.exit(3)"})
+		if err != nil || result.ExitCode != 3 || result.Profile == nil {
+			t.Errorf("RunProfiled() = %+v, %v, want exit 3 with a profile", result, err)
+		}
+	})
+}
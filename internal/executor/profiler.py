@@ -0,0 +1,90 @@
+# Profiling harness, filled in and run by RunProfiled (profile.go).
+#
+# The code arrives zlib-compressed and base64-encoded, like the solution in
+# testrunner.py, and runs as "<main>" under cProfile — its output, its exit
+# status and its traceback as they'd be without the profiler. Then TOP of
+# the functions it spent the most time in (their own time, not counting
+# what they called) are one JSON line after a marker, printed last.
+#
+# When the sandbox collects artifacts, the whole profile is saved as
+# profile.pstats in $ARTIFACTS_DIR, for pstats or snakeviz to open.
+
+import base64
+import builtins
+import cProfile
+import json
+import os
+import pstats
+import sys
+import traceback
+import zlib
+
+MARKER = "__MARKER__"
+SOURCE = zlib.decompress(base64.b64decode("__SOURCE__")).decode()
+TOP = __TOP__
+FILENAME = "<main>"
+
+# The program may replace these, or move away from the artifacts.
+_OUT = sys.stdout
+_ARTIFACTS_DIR = os.environ.get("ARTIFACTS_DIR")
+
+
+def _hotspots(stats):
+    # Skip the profiler's own bookkeeping: the exec that runs the program
+    # and the call that stops the profiler.
+    rows = [
+        (key, value)
+        for key, value in stats.stats.items()
+        if not (key[0] == "~" and ("builtins.exec" in key[2] or "_lsprof.Profiler" in key[2]))
+    ]
+    rows.sort(key=lambda row: row[1][2], reverse=True)
+    return [
+        {
+            "function": function,
+            "file": "" if file == "~" else file,  # "~" is a builtin
+            "line": line,
+            "calls": calls,
+            "primitiveCalls": primitive,
+            "selfTime": round(self_time * 1e9),
+            "cumulativeTime": round(cumulative * 1e9),
+        }
+        for (file, line, function), (primitive, calls, self_time, cumulative, _) in rows[:TOP]
+    ]
+
+
+def main():
+    code = compile(SOURCE, FILENAME, "exec")
+    namespace = {"__name__": "__main__", "__builtins__": builtins}
+    profiler = cProfile.Profile()
+    status = 0
+    try:
+        profiler.runctx(code, namespace, namespace)
+    except SystemExit as e:
+        status = e.code
+    except BaseException as e:
+        # Show the traceback from the program's first frame, not ours.
+        tb = e.__traceback__
+        while tb is not None and tb.tb_frame.f_code.co_filename != FILENAME:
+            tb = tb.tb_next
+        traceback.print_exception(type(e), e, tb)
+        status = 1
+
+    stats = pstats.Stats(profiler)
+    report = {
+        "totalTime": round(stats.total_tt * 1e9),
+        "calls": stats.total_calls,
+        "hotspots": _hotspots(stats),
+    }
+    if _ARTIFACTS_DIR and os.path.isdir(_ARTIFACTS_DIR):
+        try:
+            profiler.dump_stats(os.path.join(_ARTIFACTS_DIR, "profile.pstats"))
+        except OSError:
+            pass  # the artifacts space is full; the report still goes out
+    sys.stdout.flush()
+    sys.stderr.flush()
+    _OUT.write("\n" + MARKER + json.dumps(report) + "\n")
+    _OUT.flush()
+    sys.exit(status)
+
+
+main()
@@ -0,0 +1,139 @@
+package executor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// REQUIREMENTS:
+// A snippet, or a run, can list the third-party packages it needs, in the
+// requirements.txt format:
+//
+//	numpy==1.26.4
+//	requests>=2.31,<3   # a comment
+//
+// Only that much of the format is accepted: a package name with optional
+// version specifiers, one to a line. Options (-r, -e, --index-url), URLs,
+// extras and environment markers are refused, since they'd let the code
+// choose what pip runs or where it fetches from. Names must also be on the
+// server's PackageAllowlist.
+//
+// The sandbox has no network, so an executor installs packages from a
+// wheelhouse in its image (see docker.Config.Wheelhouse) before the code
+// runs, and the install counts towards the run's time.
+
+const (
+	// MaxRequirementsBytes caps a requirements manifest.
+	MaxRequirementsBytes = 4096
+	// MaxRequirements caps how many packages one manifest installs.
+	MaxRequirements = 20
+)
+
+// Requirement is one line of a manifest: a package and which versions of it
+// will do.
+type Requirement struct {
+	Name      string // as written, e.g. "NumPy"
+	Specifier string // e.g. "==1.26.4" or ">=2.31,<3"; "" for any version
+}
+
+// String returns the requirement as pip takes it.
+func (r Requirement) String() string {
+	return r.Name + r.Specifier
+}
+
+var (
+	packageName      = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9._-]*[A-Za-z0-9])?`)
+	versionSpecifier = regexp.MustCompile(`^(===|==|!=|<=|>=|~=|<|>)\s*([A-Za-z0-9.*+!_-]+)$`)
+	nameSeparators   = regexp.MustCompile(`[-_.]+`)
+)
+
+// ParseRequirements reads a manifest, one Requirement per package line;
+// blank lines and comments are skipped. It checks the format and size, not
+// the allowlist (see PackageAllowlist.Check).
+func ParseRequirements(manifest string) ([]Requirement, error) {
+	if len(manifest) > MaxRequirementsBytes {
+		return nil, fmt.Errorf("requirements must be %d bytes or less", MaxRequirementsBytes)
+	}
+	var reqs []Requirement
+	for i, line := range strings.Split(manifest, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		req, ok := parseRequirement(line)
+		if !ok {
+			return nil, fmt.Errorf("line %d: %q isn't a package name with optional versions, like numpy==1.26.4", i+1, line)
+		}
+		reqs = append(reqs, req)
+	}
+	if len(reqs) > MaxRequirements {
+		return nil, fmt.Errorf("at most %d packages can be installed", MaxRequirements)
+	}
+	return reqs, nil
+}
+
+func parseRequirement(line string) (Requirement, bool) {
+	name := packageName.FindString(line)
+	if name == "" {
+		return Requirement{}, false
+	}
+	req := Requirement{Name: name}
+	rest := strings.TrimSpace(line[len(name):])
+	if rest == "" {
+		return req, true
+	}
+	var specs []string
+	for _, spec := range strings.Split(rest, ",") {
+		m := versionSpecifier.FindStringSubmatch(strings.TrimSpace(spec))
+		if m == nil {
+			return Requirement{}, false
+		}
+		specs = append(specs, m[1]+m[2])
+	}
+	req.Specifier = strings.Join(specs, ",")
+	return req, true
+}
+
+// PackageAllowlist is the set of packages a server will install. Names are
+// compared the way pip compares them: "Scikit_Learn" is "scikit-learn".
+// An empty allowlist installs nothing.
+type PackageAllowlist map[string]bool
+
+// NewPackageAllowlist returns an allowlist of names.
+func NewPackageAllowlist(names []string) PackageAllowlist {
+	allow := make(PackageAllowlist, len(names))
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			allow[normalizePackageName(name)] = true
+		}
+	}
+	return allow
+}
+
+// Allows reports whether the package called name may be installed.
+func (a PackageAllowlist) Allows(name string) bool {
+	return a[normalizePackageName(name)]
+}
+
+// Check parses manifest and makes sure every package in it is allowed.
+func (a PackageAllowlist) Check(manifest string) ([]Requirement, error) {
+	reqs, err := ParseRequirements(manifest)
+	if err != nil {
+		return nil, err
+	}
+	if len(reqs) > 0 && len(a) == 0 {
+		return nil, fmt.Errorf("this server doesn't install packages")
+	}
+	for _, req := range reqs {
+		if !a.Allows(req.Name) {
+			return nil, fmt.Errorf("%s isn't one of the packages this server installs", req.Name)
+		}
+	}
+	return reqs, nil
+}
+
+func normalizePackageName(name string) string {
+	return nameSeparators.ReplaceAllString(strings.ToLower(name), "-")
+}
@@ -0,0 +1,53 @@
+package executor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRequirements(t *testing.T) {
+	reqs, err := ParseRequirements("# data\nnumpy==1.26.4\n\nrequests >= 2.31, <3  # http\nscikit_learn\n")
+	if err != nil {
+		t.Fatalf("ParseRequirements() error = %v", err)
+	}
+	want := []Requirement{{"numpy", "==1.26.4"}, {"requests", ">=2.31,<3"}, {"scikit_learn", ""}}
+	if !reflect.DeepEqual(reqs, want) {
+		t.Errorf("ParseRequirements() = %+v, want %+v", reqs, want)
+	}
+	if got := reqs[1].String(); got != "requests>=2.31,<3" {
+		t.Errorf("String() = %q", got)
+	}
+
+	for _, bad := range []string{
+		"-e git+https://example.com/evil.git",
+		"--index-url https://example.com/simple",
+		"numpy @ https://example.com/numpy.whl",
+		"requests[security]",
+		"numpy; python_version > '3'",
+		"numpy==",
+		"-r other.txt",
+		strings.Repeat("a\n", MaxRequirements+1),
+		strings.Repeat("a", MaxRequirementsBytes+1),
+	} {
+		if _, err := ParseRequirements(bad); err == nil {
+			t.Errorf("ParseRequirements(%.40q) error = nil, want one", bad)
+		}
+	}
+}
+
+func TestPackageAllowlist(t *testing.T) {
+	allow := NewPackageAllowlist([]string{"numpy", " Scikit-Learn ", ""})
+	if _, err := allow.Check("NumPy>=1.26\nscikit_learn\n"); err != nil {
+		t.Errorf("Check() error = %v, want the names matched like pip's", err)
+	}
+	if _, err := allow.Check("numpy\nrequests\n"); err == nil || !strings.Contains(err.Error(), "requests") {
+		t.Errorf("Check() error = %v, want requests refused", err)
+	}
+	if reqs, err := NewPackageAllowlist(nil).Check("# nothing\n"); err != nil || reqs != nil {
+		t.Errorf("Check() of an empty manifest = %v, %v", reqs, err)
+	}
+	if _, err := NewPackageAllowlist(nil).Check("numpy"); err == nil {
+		t.Error("Check() with no allowlist: error = nil, want one")
+	}
+}
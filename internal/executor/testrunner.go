@@ -0,0 +1,193 @@
+package executor
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TEST RUNNER MODE:
+// Grading runs a learner's solution against hidden tests. Rather than teach
+// every Executor a second mode, RunTests wraps both in a small Python harness
+// (testrunner.py) and runs that like any other program: the sandbox is the
+// same, only the program differs. The harness prints its results as one JSON
+// line after a random marker, which RunTests picks out of stdout.
+//
+// With TestOptions.Coverage, the report also says which of the solution's
+// lines ran (see Coverage), so a learner can see what the tests never
+// reached.
+//
+// The marker makes it hard for a solution to forge a passing report by
+// printing one, but not impossible — the solution runs in the same process as
+// the harness. Grades are learning feedback, not proof.
+
+//go:embed testrunner.py
+var testRunner string
+
+// MaxTestProgramBytes caps the harness program. It's passed as a command-line
+// argument, and Linux refuses single arguments over 128 KiB.
+const MaxTestProgramBytes = 120 << 10
+
+// ErrTestProgramTooLarge is returned when the solution and tests don't fit
+// in one program, even compressed.
+var ErrTestProgramTooLarge = errors.New("executor: solution and tests are too large to run together")
+
+// TestResult is the outcome of one test function.
+type TestResult struct {
+	Name       string  `json:"name"`
+	Passed     bool    `json:"passed"`
+	Message    string  `json:"message,omitempty"` // why it failed
+	DurationMS float64 `json:"durationMs"`
+}
+
+// TestReport is the outcome of a test run.
+type TestReport struct {
+	Tests []TestResult `json:"tests"`
+	// Error is set when no tests could run: the solution raised at import
+	// time, the tests didn't load, or the run timed out or crashed.
+	Error    string    `json:"error,omitempty"`
+	Output   string    `json:"output"`             // what the program printed, minus the report
+	Coverage *Coverage `json:"coverage,omitempty"` // when asked for, and the harness got to report
+}
+
+// TestOptions changes how RunTests runs.
+type TestOptions struct {
+	Coverage bool // measure which lines of the solution ran
+}
+
+// Coverage is which lines of a solution ran while it loaded and while the
+// tests ran. Engine says what measured it: "coverage.py" when the sandbox
+// image has it, which also finds branches that were never taken, and
+// "trace", a plain line tracer, when it doesn't.
+type Coverage struct {
+	Engine   string `json:"engine"`
+	Executed []int  `json:"executed"` // line numbers, 1-based
+	Missing  []int  `json:"missing"`  // lines that could have run but didn't
+	// MissingBranches are [from, to] line pairs never taken; a negative to
+	// is an exit from the function starting on line -to. coverage.py only.
+	MissingBranches [][2]int `json:"missingBranches,omitempty"`
+	Percent         float64  `json:"percent"` // of the lines that could have run
+}
+
+// Passed counts the passing tests.
+func (r *TestReport) Passed() int {
+	n := 0
+	for _, t := range r.Tests {
+		if t.Passed {
+			n++
+		}
+	}
+	return n
+}
+
+// RunTests runs tests (pytest-style test functions) against code on exec.
+// It only returns an error when the run itself failed; a solution that
+// crashes or times out is reported in TestReport.Error.
+func RunTests(ctx context.Context, exec Executor, code, tests string, opts TestOptions) (*TestReport, error) {
+	marker, err := newMarker("test-report")
+	if err != nil {
+		return nil, err
+	}
+	program, err := testProgram(code, tests, marker, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := exec.Execute(ctx, ExecutionRequest{Code: program})
+	if err != nil {
+		return nil, err
+	}
+	return parseTestReport(result, marker), nil
+}
+
+// testProgram fills the harness in with the solution, the tests, marker and
+// opts.
+func testProgram(code, tests, marker string, opts TestOptions) (string, error) {
+	encodedCode, err := compress(code)
+	if err != nil {
+		return "", err
+	}
+	encodedTests, err := compress(tests)
+	if err != nil {
+		return "", err
+	}
+	program := strings.NewReplacer(
+		"__MARKER__", marker,
+		"__SOLUTION__", encodedCode,
+		"__TESTS__", encodedTests,
+		"__COVERAGE__", pythonBool(opts.Coverage),
+	).Replace(testRunner)
+	if len(program) > MaxTestProgramBytes {
+		return "", ErrTestProgramTooLarge
+	}
+	return program, nil
+}
+
+// parseTestReport finds the harness's report in the program's output. The
+// last marker wins, so anything the solution printed earlier is ignored.
+func parseTestReport(result *ExecutionResult, marker string) *TestReport {
+	output := result.Stdout
+	i := strings.LastIndex(output, "\n"+marker)
+	if i < 0 {
+		report := &TestReport{Output: output, Error: "your code did not finish"}
+		switch {
+		case result.ExitCode == 124:
+			report.Error = "your code timed out"
+		case result.Stderr != "":
+			report.Error = "your code crashed: " + lastLine(result.Stderr)
+		}
+		return report
+	}
+
+	var report TestReport
+	line, _, _ := strings.Cut(output[i+1+len(marker):], "\n")
+	if err := json.Unmarshal([]byte(line), &report); err != nil {
+		report = TestReport{Error: "the test report could not be read"}
+	}
+	report.Output = output[:i]
+	return &report
+}
+
+func pythonBool(b bool) string {
+	if b {
+		return "True"
+	}
+	return "False"
+}
+
+func compress(s string) (string, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// newMarker returns a random marker for a harness's report of kind.
+func newMarker(kind string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("executor: generating report marker: %w", err)
+	}
+	return "@@" + kind + "-" + hex.EncodeToString(b) + "@@", nil
+}
+
+func lastLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
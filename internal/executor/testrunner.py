@@ -0,0 +1,228 @@
+# Test runner harness, filled in and run by RunTests (testrunner.go).
+#
+# The learner's solution and the hidden tests arrive zlib-compressed and
+# base64-encoded, so any text survives being pasted into this file. The
+# solution runs first, as a module named "solution"; the tests then run in a
+# namespace that already holds the solution's names, as if they started with
+# "from solution import *". Every top-level function whose name starts with
+# "test" is a test, as in pytest.
+#
+# pytest itself isn't in the sandbox image, so only plain test functions and
+# a small stand-in for pytest.raises/pytest.approx are supported — no
+# fixtures or parametrize.
+#
+# With COVERAGE on, the solution's lines are measured while it loads and
+# while the tests run: by coverage.py, with branches, when the image has it
+# and somewhere to write its source file, and otherwise by a line tracer of
+# our own. The report says which did it.
+#
+# The report is one JSON line after a marker, printed last.
+
+import base64
+import contextlib
+import io
+import json
+import math
+import os
+import sys
+import threading
+import time
+import traceback
+import types
+import zlib
+
+MARKER = "__MARKER__"
+SOLUTION = zlib.decompress(base64.b64decode("__SOLUTION__")).decode()
+TESTS = zlib.decompress(base64.b64decode("__TESTS__")).decode()
+COVERAGE = __COVERAGE__
+
+
+def _pytest_shim():
+    pytest = types.ModuleType("pytest")
+
+    class raises:
+        def __init__(self, expected, match=None):
+            self.expected, self.match = expected, match
+
+        def __enter__(self):
+            return self
+
+        def __exit__(self, kind, value, tb):
+            if kind is None:
+                raise AssertionError("DID NOT RAISE " + getattr(self.expected, "__name__", str(self.expected)))
+            if not issubclass(kind, self.expected):
+                return False
+            if self.match is not None:
+                import re
+                if not re.search(self.match, str(value)):
+                    raise AssertionError("exception message %r does not match %r" % (str(value), self.match))
+            self.value = value
+            return True
+
+    def approx(expected, rel=1e-6, abs=1e-12):
+        class Approx:
+            def __eq__(self, actual):
+                return math.isclose(actual, expected, rel_tol=rel, abs_tol=abs)
+
+            def __repr__(self):
+                return "approx(%r)" % (expected,)
+
+        return Approx()
+
+    pytest.raises, pytest.approx = raises, approx
+    return pytest
+
+
+def _failure(exc):
+    # Hidden tests stay hidden: report the test author's assertion message,
+    # or else where it failed, but never the test's source.
+    if isinstance(exc, AssertionError):
+        if str(exc):
+            return str(exc)
+        frames = [f for f in traceback.extract_tb(exc.__traceback__) if f.filename == "test_solution.py"]
+        if frames:
+            return "assertion failed (line %d of the tests)" % frames[-1].lineno
+        return "assertion failed"
+    return "%s: %s" % (type(exc).__name__, exc)
+
+
+class _CoveragePy:
+    # coverage.py reads the source back from disk to work out which lines
+    # and branches could have run, so the solution needs a real file.
+    engine = "coverage.py"
+
+    def __init__(self):
+        import coverage
+        import tempfile
+
+        self.dir = tempfile.mkdtemp()
+        self.filename = os.path.join(self.dir, "solution.py")
+        with open(self.filename, "w") as f:
+            f.write(SOLUTION)
+        self.cov = coverage.Coverage(data_file=None, branch=True, config_file=False, include=[self.filename])
+
+    def start(self):
+        self.cov.start()
+
+    def stop(self):
+        self.cov.stop()
+
+    def result(self):
+        out = os.path.join(self.dir, "coverage.json")
+        with contextlib.redirect_stdout(io.StringIO()):  # "Wrote JSON report to …"
+            self.cov.json_report(morfs=[self.filename], outfile=out)
+        with open(out) as f:
+            (measured,) = json.load(f)["files"].values()
+        return {
+            "executed": measured["executed_lines"],
+            "missing": measured["missing_lines"],
+            "missingBranches": measured.get("missing_branches", []),
+        }
+
+
+class _Tracer:
+    engine = "trace"
+    filename = "solution.py"
+
+    def __init__(self):
+        self.executed = set()
+
+    def _trace(self, frame, event, arg):
+        if frame.f_code.co_filename != self.filename:
+            return None
+        if event == "line":
+            self.executed.add(frame.f_lineno)
+        return self._trace
+
+    def start(self):
+        threading.settrace(self._trace)
+        sys.settrace(self._trace)
+
+    def stop(self):
+        sys.settrace(None)
+        threading.settrace(None)
+
+    def result(self):
+        lines = set()
+        codes = [compile(SOLUTION, self.filename, "exec")]
+        while codes:
+            code = codes.pop()
+            lines.update(line for _, _, line in code.co_lines() if line)
+            codes.extend(c for c in code.co_consts if isinstance(c, types.CodeType))
+        return {
+            "executed": sorted(lines & self.executed),
+            "missing": sorted(lines - self.executed),
+        }
+
+
+def _measure():
+    try:
+        return _CoveragePy()
+    except Exception:  # not installed, or nowhere to write
+        return _Tracer()
+
+
+def _report(report, measure=None):
+    if measure is not None:
+        measure.stop()
+        try:
+            coverage = measure.result()
+        except Exception as exc:
+            coverage = None
+            print("coverage could not be measured: %s" % exc, file=sys.stderr)
+        if coverage is not None:
+            lines = len(coverage["executed"]) + len(coverage["missing"])
+            coverage["engine"] = measure.engine
+            coverage["percent"] = round(100 * len(coverage["executed"]) / lines, 1) if lines else 100.0
+            report["coverage"] = coverage
+    sys.stdout.flush()
+    print("\n" + MARKER + json.dumps(report))
+
+
+def main():
+    try:
+        import pytest  # noqa: F401
+    except ImportError:
+        sys.modules["pytest"] = _pytest_shim()
+
+    measure = _measure() if COVERAGE else None
+    filename = measure.filename if measure else "solution.py"
+
+    solution = types.ModuleType("solution")
+    solution.__file__ = "solution.py"
+    if measure:
+        measure.start()
+    try:
+        exec(compile(SOLUTION, filename, "exec"), solution.__dict__)
+    except BaseException as exc:
+        _report({"error": "your code raised " + traceback.format_exception_only(type(exc), exc)[-1].strip()}, measure)
+        return
+    sys.modules["solution"] = solution
+
+    tests = dict(vars(solution))
+    tests["__name__"] = "test_solution"
+    try:
+        exec(compile(TESTS, "test_solution.py", "exec"), tests)
+    except BaseException as exc:
+        _report({"error": "the exercise's tests could not be loaded: " + type(exc).__name__}, measure)
+        return
+
+    results = []
+    for name, fn in list(tests.items()):
+        if not name.startswith("test") or not callable(fn) or getattr(fn, "__module__", None) == "solution":
+            continue
+        start = time.perf_counter()
+        result = {"name": name, "passed": True}
+        try:
+            fn()
+        except BaseException as exc:
+            if isinstance(exc, KeyboardInterrupt):
+                raise
+            result = {"name": name, "passed": False, "message": _failure(exc)}
+        result["durationMs"] = round((time.perf_counter() - start) * 1000, 3)
+        results.append(result)
+
+    _report({"tests": results}, measure)
+
+
+main()
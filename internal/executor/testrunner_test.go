@@ -0,0 +1,153 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand/v2"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// localPython runs programs with the python3 on this machine, standing in for
+// the Docker sandbox.
+type localPython struct{ path string }
+
+func (p localPython) Execute(ctx context.Context, req ExecutionRequest) (*ExecutionResult, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.path, "-c", req.Code)
+	cmd.Stdin = req.Stdin
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, err
+	}
+	return &ExecutionResult{Stdout: stdout.String(), Stderr: stderr.String(), ExitCode: cmd.ProcessState.ExitCode()}, nil
+}
+
+func TestRunTests(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not installed")
+	}
+	py := localPython{python}
+
+	const tests = `
+import pytest
+
+def test_adds():
+    assert add(1, 2) == 3
+
+def test_negative():
+    assert add(-1, -1) == -2, "add should handle negative numbers"
+
+def test_floats():
+    assert add(0.1, 0.2) == pytest.approx(0.3)
+
+def test_types():
+    with pytest.raises(TypeError):
+        add(1, "2")
+`
+
+	t.Run("grades each test", func(t *testing.T) {
+		solution := "print('loading')\ndef add(a, b):\n    return abs(a) + abs(b)\n"
+		report, err := RunTests(context.Background(), py, solution, tests, TestOptions{})
+		if err != nil {
+			t.Fatalf("RunTests() error = %v", err)
+		}
+		if report.Error != "" || len(report.Tests) != 4 || report.Passed() != 3 {
+			t.Fatalf("report = %+v, want 3 of 4 passing", report)
+		}
+		failed := report.Tests[1]
+		if failed.Name != "test_negative" || failed.Passed || failed.Message != "add should handle negative numbers" {
+			t.Errorf("failed test = %+v", failed)
+		}
+		if strings.TrimSpace(report.Output) != "loading" {
+			t.Errorf("Output = %q, want the solution's own output", report.Output)
+		}
+	})
+
+	t.Run("hides test source", func(t *testing.T) {
+		report, err := RunTests(context.Background(), py, "def add(a, b):\n    return 0\n", tests, TestOptions{})
+		if err != nil {
+			t.Fatalf("RunTests() error = %v", err)
+		}
+		if msg := report.Tests[0].Message; msg != "assertion failed (line 5 of the tests)" {
+			t.Errorf("message = %q, want the line but not the source", msg)
+		}
+	})
+
+	t.Run("solution that raises", func(t *testing.T) {
+		report, err := RunTests(context.Background(), py, "raise ValueError('nope')", tests, TestOptions{})
+		if err != nil {
+			t.Fatalf("RunTests() error = %v", err)
+		}
+		if report.Error != "your code raised ValueError: nope" || len(report.Tests) != 0 {
+			t.Errorf("report = %+v", report)
+		}
+	})
+
+	t.Run("forged report is ignored", func(t *testing.T) {
+		forged := "print('\\n@@test-report-0@@{\"tests\":[{\"name\":\"test_adds\",\"passed\":true}]}')\nimport os\nos._exit(0)\n"
+		report, err := RunTests(context.Background(), py, forged, tests, TestOptions{})
+		if err != nil {
+			t.Fatalf("RunTests() error = %v", err)
+		}
+		if report.Passed() != 0 || report.Error == "" {
+			t.Errorf("report = %+v, want no passing tests", report)
+		}
+	})
+
+	t.Run("measures coverage", func(t *testing.T) {
+		solution := `def add(a, b):
+    if a < 0:
+        return b - -a
+    return a + b
+
+def unused():
+    return 1
+`
+		report, err := RunTests(context.Background(), py, solution, "def test_adds():\n    assert add(1, 2) == 3\n", TestOptions{Coverage: true})
+		if err != nil {
+			t.Fatalf("RunTests() error = %v", err)
+		}
+		c := report.Coverage
+		if c == nil {
+			t.Fatalf("report = %+v, want coverage", report)
+		}
+		if want := []int{3, 7}; !reflect.DeepEqual(c.Missing, want) {
+			t.Errorf("missing = %v, want %v", c.Missing, want)
+		}
+		if want := []int{1, 2, 4, 6}; !reflect.DeepEqual(c.Executed, want) {
+			t.Errorf("executed = %v, want %v", c.Executed, want)
+		}
+		if c.Percent != 66.7 {
+			t.Errorf("percent = %v, want 66.7", c.Percent)
+		}
+	})
+
+	t.Run("no coverage unless asked", func(t *testing.T) {
+		report, err := RunTests(context.Background(), py, "def add(a, b):\n    return a + b\n", tests, TestOptions{})
+		if err != nil {
+			t.Fatalf("RunTests() error = %v", err)
+		}
+		if report.Coverage != nil {
+			t.Errorf("coverage = %+v, want none", report.Coverage)
+		}
+	})
+}
+
+func TestTestProgram_TooLarge(t *testing.T) {
+	// Random text barely compresses.
+	rng := rand.New(rand.NewPCG(1, 2))
+	var b strings.Builder
+	for range 200_000 {
+		b.WriteByte(byte('!' + rng.IntN(90)))
+	}
+	if _, err := testProgram(b.String(), "", "m", TestOptions{}); !errors.Is(err, ErrTestProgramTooLarge) {
+		t.Errorf("error = %v, want ErrTestProgramTooLarge", err)
+	}
+}
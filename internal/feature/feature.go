@@ -0,0 +1,211 @@
+// Package feature implements runtime feature flags.
+//
+// WHY FEATURE FLAGS?
+// A feature flag is a named on/off switch checked at runtime. It lets us:
+//   - Merge and deploy unfinished features "dark" (off), then enable them later
+//   - Turn a misbehaving feature off without a redeploy
+//   - Run the same binary with different features per deployment
+//
+// WHERE VALUES COME FROM (lowest → highest precedence):
+//  1. The built-in default in the flag's Definition
+//  2. Deployment config (FEATURE_FLAGS="execution=off,api_docs=on")
+//  3. Admin toggles, persisted in the database so they survive restarts
+//
+// Checking a flag is a map lookup under a read lock, so it's cheap enough to do
+// on every request.
+package feature
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Flag is the stable name of a feature flag.
+type Flag string
+
+// Known flags. Add new flags here AND to the definitions slice below.
+const (
+	// Execution gates POST /api/v1/execute.
+	Execution Flag = "execution"
+	// APIDocs gates the OpenAPI document and the Swagger UI page.
+	APIDocs Flag = "api_docs"
+	// BodyLogging logs sampled API request and response bodies, for debugging
+	// client integrations. Off unless an operator turns it on.
+	BodyLogging Flag = "body_logging"
+)
+
+// Definition describes a flag and its built-in default.
+type Definition struct {
+	Name        Flag
+	Description string
+	Default     bool
+}
+
+var definitions = []Definition{
+	{Execution, "Sandboxed code execution via the API", true},
+	{APIDocs, "OpenAPI document and Swagger UI", true},
+	{BodyLogging, "Log sampled API request/response bodies (secrets redacted)", false},
+}
+
+// State is a flag's current value, as returned by Flags.All.
+type State struct {
+	Name        Flag   `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+	Source      string `json:"source"` // "default", "config" or "admin"
+}
+
+// Store persists admin toggles. The SQLite repository implements it.
+type Store interface {
+	LoadFeatureFlags(ctx context.Context) (map[string]bool, error)
+	SaveFeatureFlag(ctx context.Context, name string, enabled bool) error
+}
+
+// Flags holds the current value of every known flag.
+type Flags struct {
+	store  Store
+	mu     sync.RWMutex
+	states map[Flag]State
+}
+
+// New creates Flags from the built-in defaults and config overrides.
+// Call Load afterwards to apply toggles persisted in the store.
+// Unknown names in overrides are an error so typos don't fail silently.
+func New(store Store, overrides map[Flag]bool) (*Flags, error) {
+	f := &Flags{
+		store:  store,
+		states: make(map[Flag]State, len(definitions)),
+	}
+
+	for _, def := range definitions {
+		f.states[def.Name] = State{
+			Name:        def.Name,
+			Description: def.Description,
+			Enabled:     def.Default,
+			Source:      "default",
+		}
+	}
+
+	for name, enabled := range overrides {
+		st, ok := f.states[name]
+		if !ok {
+			return nil, fmt.Errorf("feature: unknown flag %q", name)
+		}
+		st.Enabled, st.Source = enabled, "config"
+		f.states[name] = st
+	}
+
+	return f, nil
+}
+
+// Load applies admin toggles from the store. Persisted values for flags that
+// no longer exist are ignored.
+func (f *Flags) Load(ctx context.Context) error {
+	if f.store == nil {
+		return nil
+	}
+
+	saved, err := f.store.LoadFeatureFlags(ctx)
+	if err != nil {
+		return fmt.Errorf("feature: loading flags: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for name, enabled := range saved {
+		if st, ok := f.states[Flag(name)]; ok {
+			st.Enabled, st.Source = enabled, "admin"
+			f.states[Flag(name)] = st
+		}
+	}
+	return nil
+}
+
+// Enabled reports whether a flag is on. Unknown flags are off.
+// A nil *Flags treats every flag as enabled at its default, which keeps
+// callers that don't use flags (tests, tools) simple.
+func (f *Flags) Enabled(name Flag) bool {
+	if f == nil {
+		for _, def := range definitions {
+			if def.Name == name {
+				return def.Default
+			}
+		}
+		return false
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.states[name].Enabled
+}
+
+// Known reports whether name is a defined flag.
+func (f *Flags) Known(name Flag) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	_, ok := f.states[name]
+	return ok
+}
+
+// Set toggles a flag and persists the change.
+func (f *Flags) Set(ctx context.Context, name Flag, enabled bool) (State, error) {
+	if !f.Known(name) {
+		return State{}, fmt.Errorf("feature: unknown flag %q", name)
+	}
+
+	if f.store != nil {
+		if err := f.store.SaveFeatureFlag(ctx, string(name), enabled); err != nil {
+			return State{}, fmt.Errorf("feature: saving flag %q: %w", name, err)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	st := f.states[name]
+	st.Enabled, st.Source = enabled, "admin"
+	f.states[name] = st
+	return st, nil
+}
+
+// All returns every flag's state, sorted by name.
+func (f *Flags) All() []State {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	all := make([]State, 0, len(f.states))
+	for _, st := range f.states {
+		all = append(all, st)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
+
+// ParseOverrides parses a config string such as "execution=off,api_docs=on".
+// Accepted values are on/off, true/false, 1/0 and enabled/disabled.
+func ParseOverrides(s string) (map[Flag]bool, error) {
+	overrides := make(map[Flag]bool)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("feature: %q is not name=value", pair)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "on", "true", "1", "enabled":
+			overrides[Flag(strings.TrimSpace(name))] = true
+		case "off", "false", "0", "disabled":
+			overrides[Flag(strings.TrimSpace(name))] = false
+		default:
+			return nil, fmt.Errorf("feature: invalid value %q for flag %q", value, name)
+		}
+	}
+	return overrides, nil
+}
@@ -0,0 +1,109 @@
+package feature
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// memStore is an in-memory Store for tests.
+type memStore map[string]bool
+
+func (m memStore) LoadFeatureFlags(ctx context.Context) (map[string]bool, error) {
+	return m, nil
+}
+
+func (m memStore) SaveFeatureFlag(ctx context.Context, name string, enabled bool) error {
+	m[name] = enabled
+	return nil
+}
+
+func TestFlags_Precedence(t *testing.T) {
+	store := memStore{"api_docs": true, "retired_flag": true}
+
+	f, err := New(store, map[Flag]bool{Execution: false, APIDocs: false})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := f.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// config beats default
+	if f.Enabled(Execution) {
+		t.Error("execution: want disabled by config")
+	}
+	// admin toggle beats config
+	if !f.Enabled(APIDocs) {
+		t.Error("api_docs: want enabled by admin toggle")
+	}
+	if f.Known("retired_flag") {
+		t.Error("persisted unknown flag should be ignored")
+	}
+}
+
+func TestFlags_SetPersists(t *testing.T) {
+	store := memStore{}
+	f, err := New(store, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	st, err := f.Set(context.Background(), Execution, false)
+	if err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if st.Enabled || st.Source != "admin" {
+		t.Errorf("state = %+v, want disabled from admin", st)
+	}
+	if enabled, ok := store["execution"]; !ok || enabled {
+		t.Errorf("store = %v, want execution=false", store)
+	}
+
+	if _, err := f.Set(context.Background(), "nope", true); err == nil {
+		t.Error("Set(unknown) should fail")
+	}
+}
+
+func TestNew_UnknownOverride(t *testing.T) {
+	if _, err := New(nil, map[Flag]bool{"typo": true}); err == nil {
+		t.Error("New() with unknown override should fail")
+	}
+}
+
+func TestParseOverrides(t *testing.T) {
+	got, err := ParseOverrides(" execution=off, api_docs=ON ,")
+	if err != nil {
+		t.Fatalf("ParseOverrides() error = %v", err)
+	}
+	if got[Execution] != false || got[APIDocs] != true || len(got) != 2 {
+		t.Errorf("ParseOverrides() = %v", got)
+	}
+
+	for _, bad := range []string{"execution", "execution=maybe"} {
+		if _, err := ParseOverrides(bad); err == nil {
+			t.Errorf("ParseOverrides(%q) should fail", bad)
+		}
+	}
+}
+
+func TestRequire(t *testing.T) {
+	f, _ := New(nil, map[Flag]bool{Execution: false})
+	h := Require(f, Execution)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/execute", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("disabled: status = %d, want 404", rr.Code)
+	}
+
+	f.Set(context.Background(), Execution, true)
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/execute", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("enabled: status = %d, want 200", rr.Code)
+	}
+}
@@ -0,0 +1,24 @@
+package feature
+
+import (
+	"net/http"
+)
+
+// Require returns middleware that hides a route while its flag is off.
+//
+// A disabled feature answers 404 rather than 403: from the client's point of
+// view the endpoint simply doesn't exist in this deployment, exactly as if the
+// route had never been registered.
+func Require(f *Flags, name Flag) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !f.Enabled(name) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error":"not_found","message":"this feature is not enabled"}` + "\n"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,558 @@
+// Package graphql is a small GraphQL query engine: a parser for query
+// documents and an executor that runs them against a schema of Go resolvers.
+//
+// WHY NOT A LIBRARY?
+// The API only needs read queries over a handful of types. A few hundred lines
+// we understand fully beat a large dependency with its own schema language
+// and code generator. What's supported:
+//
+//   - queries with variables, aliases, arguments and the @skip/@include directives
+//   - named fragments and inline fragments
+//   - __typename
+//
+// What isn't: mutations, subscriptions and introspection (__schema). The REST
+// API remains the way to change data.
+//
+// HOW EXECUTION WORKS:
+// The schema is a tree of Objects, each a set of named Fields. A Field either
+// returns a scalar (string, number, time…) or, when its Type is set, an object
+// whose own fields are resolved next with the returned value as their Source:
+//
+//	Query.snippet(id: "abc")  → *model.Snippet   (Source for the next level)
+//	  Snippet.name            → "hello"
+//	  Snippet.author          → *model.User
+//	    User.login            → "octocat"
+//
+// A resolver that fails sets its field to null and adds an entry to the
+// response's "errors" list; the rest of the query still runs. That's how
+// GraphQL reports partial results.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Schema is the set of types a query can select from.
+type Schema struct {
+	// Query is the root type: the fields a query starts from.
+	Query *Object
+
+	// MaxDepth limits how deeply fields may nest. Zero means DefaultMaxDepth.
+	MaxDepth int
+
+	// MaxFields limits how many fields one query may resolve in total, so a
+	// query nesting lists inside lists can't ask for millions of values. Zero
+	// means DefaultMaxFields.
+	MaxFields int
+
+	// PresentError turns a resolver error into the error reported to the
+	// client. If nil, the error's message is used as is. Errors that already
+	// are *Error (such as bad arguments) are reported unchanged.
+	PresentError func(ctx context.Context, err error) *Error
+}
+
+// Defaults for Schema limits.
+const (
+	DefaultMaxDepth  = 10
+	DefaultMaxFields = 10000
+)
+
+// Object is a GraphQL object type.
+type Object struct {
+	Name   string
+	Fields map[string]*Field
+}
+
+// Field is one field of an Object.
+type Field struct {
+	// Type is the object type the field returns, or nil for a scalar.
+	Type *Object
+	// List means the resolver returns a []any of Type (or of scalars).
+	List bool
+	// Args lists the accepted arguments with their default values (nil for
+	// none).
+	Args map[string]any
+	// Resolve computes the field's value.
+	Resolve func(p ResolveParams) (any, error)
+}
+
+// ResolveParams is what a resolver gets to work with.
+type ResolveParams struct {
+	Context context.Context
+	// Source is the value of the parent field (nil for root fields).
+	Source any
+	// Args holds the field's arguments, with defaults filled in and
+	// variables substituted.
+	Args map[string]any
+}
+
+// String returns the string argument name, or "" if it's absent or null.
+func (p ResolveParams) String(name string) (string, error) {
+	switch v := p.Args[name].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	default:
+		return "", &Error{Message: fmt.Sprintf("argument %q must be a String", name)}
+	}
+}
+
+// Int returns the integer argument name, or 0 if it's absent or null.
+// Variables arrive from JSON as float64, so whole floats are accepted too.
+func (p ResolveParams) Int(name string) (int, error) {
+	switch v := p.Args[name].(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return v, nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	}
+	return 0, &Error{Message: fmt.Sprintf("argument %q must be an Int", name)}
+}
+
+// Bool returns the boolean argument name, or nil if it's absent or null.
+func (p ResolveParams) Bool(name string) (*bool, error) {
+	switch v := p.Args[name].(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		return &v, nil
+	default:
+		return nil, &Error{Message: fmt.Sprintf("argument %q must be a Boolean", name)}
+	}
+}
+
+// Request is a GraphQL request as sent over HTTP.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Response is the result of executing a Request.
+type Response struct {
+	// Data is nil when the request failed before execution started (a syntax
+	// or validation error); it's then left out of the JSON entirely.
+	Data   *OrderedMap `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// Error is one entry of a response's "errors" list.
+type Error struct {
+	Message    string         `json:"message"`
+	Locations  []Location     `json:"locations,omitempty"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func syntaxError(loc Location, format string, args ...any) *Error {
+	return &Error{Message: "Syntax error: " + fmt.Sprintf(format, args...), Locations: []Location{loc}}
+}
+
+func validationError(loc Location, format string, args ...any) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...), Locations: []Location{loc}}
+}
+
+// Execute parses and runs a request.
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	doc, err := Parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{asError(err)}}
+	}
+
+	op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{asError(err)}}
+	}
+
+	vars, err := coerceVariables(op, req.Variables)
+	if err != nil {
+		return &Response{Errors: []*Error{asError(err)}}
+	}
+
+	e := &executor{schema: s, doc: doc, vars: vars}
+	if errs := e.validate(); len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+
+	data := e.executeSelections(ctx, s.Query, nil, op.Selections, nil)
+	return &Response{Data: data, Errors: e.errors}
+}
+
+func asError(err error) *Error {
+	var gqlErr *Error
+	if errors.As(err, &gqlErr) {
+		return gqlErr
+	}
+	return &Error{Message: err.Error()}
+}
+
+// selectOperation picks the operation to run: the named one, or the only one.
+func selectOperation(doc *Document, name string) (*Operation, error) {
+	var op *Operation
+	if name == "" {
+		if len(doc.Operations) > 1 {
+			return nil, &Error{Message: "operationName is required when the document has several operations"}
+		}
+		op = doc.Operations[0]
+	} else {
+		for _, o := range doc.Operations {
+			if o.Name == name {
+				op = o
+			}
+		}
+		if op == nil {
+			return nil, &Error{Message: fmt.Sprintf("unknown operation %q", name)}
+		}
+	}
+	if op.Type != "query" {
+		return nil, validationError(op.Loc, "%s operations are not supported; only queries are", op.Type)
+	}
+	return op, nil
+}
+
+// coerceVariables applies defaults and checks required variables are set.
+// Values aren't type-checked here; resolvers check the arguments they read.
+func coerceVariables(op *Operation, given map[string]any) (map[string]any, error) {
+	vars := make(map[string]any, len(op.Variables))
+	for _, def := range op.Variables {
+		v, ok := given[def.Name]
+		switch {
+		case ok:
+			vars[def.Name] = v
+		case def.HasDefault:
+			vars[def.Name] = def.Default
+		}
+		if strings.HasSuffix(def.Type, "!") && vars[def.Name] == nil {
+			return nil, validationError(op.Loc, "variable $%s of type %s is required", def.Name, def.Type)
+		}
+	}
+	return vars, nil
+}
+
+// === Validation ===
+
+type executor struct {
+	schema   *Schema
+	doc      *Document
+	vars     map[string]any
+	errors   []*Error
+	resolved int
+}
+
+func (e *executor) maxDepth() int {
+	if e.schema.MaxDepth > 0 {
+		return e.schema.MaxDepth
+	}
+	return DefaultMaxDepth
+}
+
+func (e *executor) maxFields() int {
+	if e.schema.MaxFields > 0 {
+		return e.schema.MaxFields
+	}
+	return DefaultMaxFields
+}
+
+// validate checks every operation against the schema before anything runs,
+// so a typo fails the whole request instead of producing a half-empty result.
+func (e *executor) validate() []*Error {
+	var errs []*Error
+	for _, op := range e.doc.Operations {
+		errs = append(errs, e.validateSelections(e.schema.Query, op.Selections, 1, map[string]bool{})...)
+	}
+	return errs
+}
+
+func (e *executor) validateSelections(obj *Object, sels []Selection, depth int, visiting map[string]bool) []*Error {
+	var errs []*Error
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *SelectedField:
+			errs = append(errs, e.validateField(obj, sel, depth, visiting)...)
+		case *InlineFragment:
+			if sel.TypeCondition != "" && sel.TypeCondition != obj.Name {
+				errs = append(errs, validationError(sel.Loc, "fragment on %q can't be used on type %q", sel.TypeCondition, obj.Name))
+				continue
+			}
+			errs = append(errs, e.validateSelections(obj, sel.Selections, depth, visiting)...)
+		case *FragmentSpread:
+			frag, ok := e.doc.Fragments[sel.Name]
+			if !ok {
+				errs = append(errs, validationError(sel.Loc, "unknown fragment %q", sel.Name))
+				continue
+			}
+			if frag.TypeCondition != obj.Name {
+				errs = append(errs, validationError(sel.Loc, "fragment %q on %q can't be used on type %q", sel.Name, frag.TypeCondition, obj.Name))
+				continue
+			}
+			if visiting[sel.Name] {
+				errs = append(errs, validationError(sel.Loc, "fragment %q spreads itself", sel.Name))
+				continue
+			}
+			visiting[sel.Name] = true
+			errs = append(errs, e.validateSelections(obj, frag.Selections, depth, visiting)...)
+			delete(visiting, sel.Name)
+		}
+	}
+	return errs
+}
+
+func (e *executor) validateField(obj *Object, f *SelectedField, depth int, visiting map[string]bool) []*Error {
+	if depth > e.maxDepth() {
+		return []*Error{validationError(f.Loc, "query is nested more than %d levels deep", e.maxDepth())}
+	}
+	if f.Name == "__typename" {
+		if len(f.Selections) > 0 {
+			return []*Error{validationError(f.Loc, "field \"__typename\" can't have a selection set")}
+		}
+		return nil
+	}
+	if strings.HasPrefix(f.Name, "__") {
+		return []*Error{validationError(f.Loc, "introspection field %q is not supported", f.Name)}
+	}
+
+	def, ok := obj.Fields[f.Name]
+	if !ok {
+		return []*Error{validationError(f.Loc, "cannot query field %q on type %q", f.Name, obj.Name)}
+	}
+	for name := range f.Arguments {
+		if _, ok := def.Args[name]; !ok {
+			return []*Error{validationError(f.Loc, "unknown argument %q on field %s.%s", name, obj.Name, f.Name)}
+		}
+	}
+	switch {
+	case def.Type == nil && len(f.Selections) > 0:
+		return []*Error{validationError(f.Loc, "field %q is a scalar and can't have a selection set", f.Name)}
+	case def.Type != nil && len(f.Selections) == 0:
+		return []*Error{validationError(f.Loc, "field %q of type %q must have a selection set", f.Name, def.Type.Name)}
+	case def.Type != nil:
+		return e.validateSelections(def.Type, f.Selections, depth+1, visiting)
+	}
+	return nil
+}
+
+// === Execution ===
+
+// collectFields flattens fragments and applies @skip/@include, grouping the
+// fields by response key. Fields selected twice under one key (e.g. once
+// directly and once through a fragment) are merged.
+func (e *executor) collectFields(obj *Object, sels []Selection, keys *[]string, groups map[string][]*SelectedField) {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *SelectedField:
+			if !e.included(sel.Directives) {
+				continue
+			}
+			key := sel.ResponseKey()
+			if _, seen := groups[key]; !seen {
+				*keys = append(*keys, key)
+			}
+			groups[key] = append(groups[key], sel)
+		case *InlineFragment:
+			if e.included(sel.Directives) && (sel.TypeCondition == "" || sel.TypeCondition == obj.Name) {
+				e.collectFields(obj, sel.Selections, keys, groups)
+			}
+		case *FragmentSpread:
+			frag := e.doc.Fragments[sel.Name]
+			if e.included(sel.Directives) && frag.TypeCondition == obj.Name {
+				e.collectFields(obj, frag.Selections, keys, groups)
+			}
+		}
+	}
+}
+
+// included evaluates @skip(if:) and @include(if:).
+func (e *executor) included(dirs []*Directive) bool {
+	for _, d := range dirs {
+		cond, _ := e.value(d.Arguments["if"]).(bool)
+		if d.Name == "skip" && cond || d.Name == "include" && !cond {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *executor) executeSelections(ctx context.Context, obj *Object, source any, sels []Selection, path []any) *OrderedMap {
+	var keys []string
+	groups := map[string][]*SelectedField{}
+	e.collectFields(obj, sels, &keys, groups)
+
+	out := &OrderedMap{}
+	for _, key := range keys {
+		fields := groups[key]
+		out.Set(key, e.executeField(ctx, obj, source, fields, append(path[:len(path):len(path)], key)))
+	}
+	return out
+}
+
+func (e *executor) executeField(ctx context.Context, obj *Object, source any, fields []*SelectedField, path []any) any {
+	f := fields[0]
+	if f.Name == "__typename" {
+		return obj.Name
+	}
+
+	e.resolved++
+	if e.resolved > e.maxFields() {
+		if e.resolved == e.maxFields()+1 {
+			e.fieldError(ctx, f, path, fmt.Errorf("query resolves more than %d fields", e.maxFields()))
+		}
+		return nil
+	}
+
+	def := obj.Fields[f.Name]
+	args := make(map[string]any, len(def.Args))
+	for name, dflt := range def.Args {
+		args[name] = dflt
+	}
+	for name, v := range f.Arguments {
+		if v = e.value(v); v != nil {
+			args[name] = v
+		}
+	}
+
+	value, err := def.Resolve(ResolveParams{Context: ctx, Source: source, Args: args})
+	if err != nil {
+		e.fieldError(ctx, f, path, err)
+		return nil
+	}
+	if isNil(value) {
+		return nil
+	}
+	if def.Type == nil && !def.List {
+		return value
+	}
+
+	// Merge the sub-selections of every field under this key.
+	var sels []Selection
+	for _, f := range fields {
+		sels = append(sels, f.Selections...)
+	}
+	complete := func(v any, path []any) any {
+		if def.Type == nil || isNil(v) {
+			return v
+		}
+		return e.executeSelections(ctx, def.Type, v, sels, path)
+	}
+
+	if !def.List {
+		return complete(value, path)
+	}
+	items, ok := value.([]any)
+	if !ok {
+		e.fieldError(ctx, f, path, fmt.Errorf("internal error: %s.%s resolved to %T, not a list", obj.Name, f.Name, value))
+		return nil
+	}
+	list := make([]any, len(items))
+	for i, item := range items {
+		list[i] = complete(item, append(path[:len(path):len(path)], i))
+	}
+	return list
+}
+
+func (e *executor) fieldError(ctx context.Context, f *SelectedField, path []any, err error) {
+	var gqlErr *Error
+	switch {
+	case errors.As(err, &gqlErr):
+		gqlErr = &Error{Message: gqlErr.Message, Extensions: gqlErr.Extensions}
+	case e.schema.PresentError != nil:
+		gqlErr = e.schema.PresentError(ctx, err)
+	default:
+		gqlErr = &Error{Message: err.Error()}
+	}
+	gqlErr.Locations = []Location{f.Loc}
+	gqlErr.Path = path
+	e.errors = append(e.errors, gqlErr)
+}
+
+// value substitutes variables in an argument value.
+func (e *executor) value(v any) any {
+	switch v := v.(type) {
+	case Variable:
+		return e.vars[string(v)]
+	case []any:
+		out := make([]any, len(v))
+		for i := range v {
+			out[i] = e.value(v[i])
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k := range v {
+			out[k] = e.value(v[k])
+		}
+		return out
+	}
+	return v
+}
+
+// isNil reports whether v is nil or a nil pointer — a resolver returning a
+// (*model.User)(nil) means null just as much as a bare nil does.
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// OrderedMap is a JSON object that keeps its keys in insertion order. GraphQL
+// results list fields in the order the query asked for them, which Go maps
+// can't do.
+type OrderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+// Set adds or replaces a key.
+func (m *OrderedMap) Set(key string, value any) {
+	if m.values == nil {
+		m.values = map[string]any{}
+	}
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// Get returns the value for key.
+func (m *OrderedMap) Get(key string) any {
+	return m.values[key]
+}
+
+// MarshalJSON writes the keys in order.
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		buf.Write(key)
+		buf.WriteByte(':')
+		val, err := json.Marshal(m.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,209 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sakif/coding-playground/internal/graphql"
+)
+
+type book struct {
+	Title  string
+	Author *author
+}
+
+type author struct {
+	Name  string
+	Books []*book
+}
+
+// newLibrarySchema builds a toy schema: books and their authors.
+func newLibrarySchema() *graphql.Schema {
+	ada := &author{Name: "Ada"}
+	books := []*book{{Title: "Notes", Author: ada}, {Title: "Sketch", Author: ada}, {Title: "Anonymous"}}
+	ada.Books = books[:2]
+
+	bookType := &graphql.Object{Name: "Book"}
+	authorType := &graphql.Object{Name: "Author"}
+	bookType.Fields = map[string]*graphql.Field{
+		"title": {Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*book).Title, nil }},
+		"author": {Type: authorType, Resolve: func(p graphql.ResolveParams) (any, error) {
+			return p.Source.(*book).Author, nil
+		}},
+		"secret": {Resolve: func(p graphql.ResolveParams) (any, error) { return nil, errors.New("forbidden") }},
+	}
+	authorType.Fields = map[string]*graphql.Field{
+		"name": {Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(*author).Name, nil }},
+		"books": {Type: bookType, List: true, Resolve: func(p graphql.ResolveParams) (any, error) {
+			var out []any
+			for _, b := range p.Source.(*author).Books {
+				out = append(out, b)
+			}
+			return out, nil
+		}},
+	}
+
+	return &graphql.Schema{
+		MaxDepth: 5,
+		Query: &graphql.Object{Name: "Query", Fields: map[string]*graphql.Field{
+			"books": {
+				Type: bookType, List: true,
+				Args: map[string]any{"first": 10},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					first, err := p.Int("first")
+					if err != nil {
+						return nil, err
+					}
+					var out []any
+					for _, b := range books[:min(first, len(books))] {
+						out = append(out, b)
+					}
+					return out, nil
+				},
+			},
+			"book": {
+				Type: bookType,
+				Args: map[string]any{"title": nil},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					title, err := p.String("title")
+					if err != nil {
+						return nil, err
+					}
+					for _, b := range books {
+						if b.Title == title {
+							return b, nil
+						}
+					}
+					return (*book)(nil), nil
+				},
+			},
+		}},
+	}
+}
+
+// run executes a query and returns the response as JSON.
+func run(t *testing.T, req graphql.Request) string {
+	t.Helper()
+	resp := newLibrarySchema().Execute(context.Background(), req)
+	out, err := json.Marshal(resp)
+	require.NoError(t, err)
+	return string(out)
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name string
+		req  graphql.Request
+		want string
+	}{
+		{
+			name: "nested fields keep query order",
+			req:  graphql.Request{Query: `{ book(title: "Notes") { title author { name } } }`},
+			want: `{"data":{"book":{"title":"Notes","author":{"name":"Ada"}}}}`,
+		},
+		{
+			name: "aliases, arguments and lists",
+			req:  graphql.Request{Query: `{ two: books(first: 2) { title } }`},
+			want: `{"data":{"two":[{"title":"Notes"},{"title":"Sketch"}]}}`,
+		},
+		{
+			name: "variables with defaults",
+			req: graphql.Request{
+				Query:     `query Q($t: String!, $n: Int = 1) { book(title: $t) { title } books(first: $n) { title } }`,
+				Variables: map[string]any{"t": "Sketch"},
+			},
+			want: `{"data":{"book":{"title":"Sketch"},"books":[{"title":"Notes"}]}}`,
+		},
+		{
+			name: "JSON numbers work as Int variables",
+			req:  graphql.Request{Query: `query($n: Int) { books(first: $n) { title } }`, Variables: map[string]any{"n": float64(1)}},
+			want: `{"data":{"books":[{"title":"Notes"}]}}`,
+		},
+		{
+			name: "fragments and __typename",
+			req: graphql.Request{Query: `
+				query { book(title: "Notes") { ...B ... on Book { author { __typename } } } }
+				fragment B on Book { title }`},
+			want: `{"data":{"book":{"title":"Notes","author":{"__typename":"Author"}}}}`,
+		},
+		{
+			name: "skip and include",
+			req: graphql.Request{
+				Query:     `query($yes: Boolean!) { book(title: "Notes") { title @skip(if: $yes) author @include(if: $yes) { name } } }`,
+				Variables: map[string]any{"yes": true},
+			},
+			want: `{"data":{"book":{"author":{"name":"Ada"}}}}`,
+		},
+		{
+			name: "null object",
+			req:  graphql.Request{Query: `{ book(title: "Anonymous") { author { name } } }`},
+			want: `{"data":{"book":{"author":null}}}`,
+		},
+		{
+			name: "resolver errors null the field and carry a path",
+			req:  graphql.Request{Query: `{ book(title: "Notes") { title secret } }`},
+			want: `{"data":{"book":{"title":"Notes","secret":null}},"errors":[{"message":"forbidden","locations":[{"line":1,"column":32}],"path":["book","secret"]}]}`,
+		},
+		{
+			name: "argument type errors",
+			req:  graphql.Request{Query: `{ books(first: "two") { title } }`},
+			want: `{"data":{"books":null},"errors":[{"message":"argument \"first\" must be an Int","locations":[{"line":1,"column":3}],"path":["books"]}]}`,
+		},
+		{
+			name: "named operation",
+			req:  graphql.Request{Query: `query A { books(first: 1) { title } } query B { book(title: "Sketch") { title } }`, OperationName: "B"},
+			want: `{"data":{"book":{"title":"Sketch"}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.JSONEq(t, tt.want, run(t, tt.req))
+		})
+	}
+}
+
+func TestExecute_RequestErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantMsg string
+	}{
+		{"syntax error", `{ book(title: "x" { title } }`, "Syntax error"},
+		{"unterminated string", `{ book(title: "x) { title } }`, "unterminated string"},
+		{"unknown field", `{ books { isbn } }`, `cannot query field "isbn" on type "Book"`},
+		{"unknown argument", `{ books(last: 1) { title } }`, `unknown argument "last"`},
+		{"missing selection", `{ books }`, "must have a selection set"},
+		{"selection on scalar", `{ books { title { x } } }`, "is a scalar"},
+		{"too deep", `{ books { author { books { author { books { author { name } } } } } } }`, "nested more than 5 levels"},
+		{"fragment cycle", `{ books { ...A } } fragment A on Book { ...A }`, "spreads itself"},
+		{"wrong fragment type", `{ books { ...A } } fragment A on Author { name }`, `can't be used on type "Book"`},
+		{"mutation", `mutation { books { title } }`, "mutation operations are not supported"},
+		{"introspection", `{ __schema { types { name } } }`, "introspection"},
+		{"missing variable", `query($t: String!) { book(title: $t) { title } }`, "$t of type String! is required"},
+		{"several operations", `query A { books { title } } query B { books { title } }`, "operationName is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := newLibrarySchema().Execute(context.Background(), graphql.Request{Query: tt.query})
+			assert.Nil(t, resp.Data, "request errors mean no data")
+			require.NotEmpty(t, resp.Errors)
+			assert.Contains(t, resp.Errors[0].Message, tt.wantMsg)
+		})
+	}
+}
+
+func TestExecute_MaxFields(t *testing.T) {
+	schema := newLibrarySchema()
+	schema.MaxFields = 3
+
+	resp := schema.Execute(context.Background(), graphql.Request{Query: `{ books { title } }`})
+	require.Len(t, resp.Errors, 1, "the limit is reported once")
+	assert.Contains(t, resp.Errors[0].Message, "more than 3 fields")
+}
@@ -0,0 +1,689 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PARSING:
+// A GraphQL document is text like:
+//
+//	query Recent($n: Int = 10) {
+//	  snippets(first: $n) { edges { node { id name } } }
+//	}
+//
+// The lexer turns it into tokens (names, numbers, strings, punctuation) and a
+// recursive-descent parser turns the tokens into the small AST below — one
+// function per grammar rule (parseOperation, parseSelectionSet, parseValue…).
+// Only executable documents are supported: operations and fragments, no
+// schema definitions.
+
+// Document is a parsed query document.
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation is a query (or mutation/subscription, which the executor rejects).
+type Operation struct {
+	Type       string // "query", "mutation" or "subscription"
+	Name       string
+	Variables  []*VariableDefinition
+	Selections []Selection
+	Loc        Location
+}
+
+// VariableDefinition declares an operation variable: ($name: Type = default).
+type VariableDefinition struct {
+	Name       string
+	Type       string // as written, e.g. "Int!" or "[ID]"
+	Default    any
+	HasDefault bool
+}
+
+// Fragment is a named fragment: fragment Name on Type { ... }.
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	Selections    []Selection
+	Loc           Location
+}
+
+// Selection is a *SelectedField, *FragmentSpread or *InlineFragment.
+type Selection interface {
+	location() Location
+}
+
+// SelectedField selects one field, optionally aliased: alias: name(args) { ... }.
+type SelectedField struct {
+	Alias      string
+	Name       string
+	Arguments  map[string]any
+	Directives []*Directive
+	Selections []Selection
+	Loc        Location
+}
+
+// ResponseKey is the key the field's value appears under in the result.
+func (f *SelectedField) ResponseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// FragmentSpread includes a named fragment: ...Name.
+type FragmentSpread struct {
+	Name       string
+	Directives []*Directive
+	Loc        Location
+}
+
+// InlineFragment groups selections: ... on Type { ... }.
+type InlineFragment struct {
+	TypeCondition string // empty when omitted
+	Directives    []*Directive
+	Selections    []Selection
+	Loc           Location
+}
+
+// Directive is an annotation like @include(if: $flag).
+type Directive struct {
+	Name      string
+	Arguments map[string]any
+}
+
+// Variable is a reference to an operation variable inside a value.
+type Variable string
+
+// Location is a 1-based position in the query text, used in error messages.
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (f *SelectedField) location() Location  { return f.Loc }
+func (f *FragmentSpread) location() Location { return f.Loc }
+func (f *InlineFragment) location() Location { return f.Loc }
+
+// Parse parses a query document.
+func Parse(query string) (*Document, error) {
+	p := &parser{lex: lexer{src: query, line: 1, col: 1}}
+	p.next()
+	return p.parseDocument()
+}
+
+// === Lexer ===
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	loc   Location
+}
+
+type lexer struct {
+	src       string
+	pos       int
+	line, col int
+}
+
+// advance consumes n bytes, keeping line and column up to date.
+func (l *lexer) advance(n int) {
+	for i := 0; i < n && l.pos < len(l.src); i++ {
+		if l.src[l.pos] == '\n' {
+			l.line++
+			l.col = 1
+		} else {
+			l.col++
+		}
+		l.pos++
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	// Whitespace, commas and # comments are insignificant.
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			l.advance(1)
+		} else if c == '#' {
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.advance(1)
+			}
+		} else if strings.HasPrefix(l.src[l.pos:], "\uFEFF") {
+			l.pos += len("\uFEFF") // byte order mark
+		} else {
+			break
+		}
+	}
+
+	loc := Location{Line: l.line, Column: l.col}
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, loc: loc}, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.advance(3)
+		return token{kind: tokPunct, value: "...", loc: loc}, nil
+	case strings.IndexByte("!$()&:=@[]{}|", c) >= 0:
+		l.advance(1)
+		return token{kind: tokPunct, value: string(c), loc: loc}, nil
+	case c == '_' || isLetter(c):
+		start := l.pos
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.advance(1)
+		}
+		return token{kind: tokName, value: l.src[start:l.pos], loc: loc}, nil
+	case c == '-' || isDigit(c):
+		return l.number(loc)
+	case c == '"':
+		return l.string(loc)
+	}
+	return token{}, syntaxError(loc, "unexpected character %q", c)
+}
+
+func (l *lexer) number(loc Location) (token, error) {
+	start := l.pos
+	kind := tokInt
+	if l.src[l.pos] == '-' {
+		l.advance(1)
+	}
+	digits := func() {
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.advance(1)
+		}
+	}
+	digits()
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokFloat
+		l.advance(1)
+		digits()
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokFloat
+		l.advance(1)
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.advance(1)
+		}
+		digits()
+	}
+	text := l.src[start:l.pos]
+	if text == "-" || strings.HasSuffix(text, ".") || strings.HasSuffix(text, "e") || strings.HasSuffix(text, "E") {
+		return token{}, syntaxError(loc, "invalid number %q", text)
+	}
+	return token{kind: kind, value: text, loc: loc}, nil
+}
+
+func (l *lexer) string(loc Location) (token, error) {
+	if strings.HasPrefix(l.src[l.pos:], `"""`) {
+		return token{}, syntaxError(loc, "block strings are not supported")
+	}
+	l.advance(1)
+
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.advance(1)
+			return token{kind: tokString, value: b.String(), loc: loc}, nil
+		case c == '\n' || c == '\r':
+			return token{}, syntaxError(loc, "unterminated string")
+		case c == '\\' && l.pos+1 < len(l.src):
+			esc := l.src[l.pos+1]
+			switch esc {
+			case '"', '\\', '/':
+				b.WriteByte(esc)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if l.pos+6 > len(l.src) {
+					return token{}, syntaxError(loc, "invalid unicode escape")
+				}
+				r, err := strconv.ParseUint(l.src[l.pos+2:l.pos+6], 16, 32)
+				if err != nil {
+					return token{}, syntaxError(loc, "invalid unicode escape")
+				}
+				b.WriteRune(rune(r))
+				l.advance(4)
+			default:
+				return token{}, syntaxError(loc, "invalid escape \\%c", esc)
+			}
+			l.advance(2)
+		default:
+			b.WriteByte(c)
+			l.advance(1)
+		}
+	}
+	return token{}, syntaxError(loc, "unterminated string")
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+// === Parser ===
+
+type parser struct {
+	lex lexer
+	tok token
+	err error
+}
+
+// next moves to the following token. A lexing error is kept and reported by
+// the parse function that next looks at the token.
+func (p *parser) next() {
+	if p.err != nil {
+		return
+	}
+	p.tok, p.err = p.lex.next()
+}
+
+// peek reports whether the current token is the punctuator or keyword s.
+func (p *parser) peek(s string) bool {
+	return p.err == nil && (p.tok.kind == tokPunct || p.tok.kind == tokName) && p.tok.value == s
+}
+
+// skip consumes the current token if it is s.
+func (p *parser) skip(s string) bool {
+	if p.peek(s) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if p.err != nil {
+		return p.err
+	}
+	if !p.skip(s) {
+		return p.unexpected("expected %q", s)
+	}
+	return p.err
+}
+
+func (p *parser) name() (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	if p.tok.kind != tokName {
+		return "", p.unexpected("expected a name")
+	}
+	name := p.tok.value
+	p.next()
+	return name, p.err
+}
+
+func (p *parser) unexpected(format string, args ...any) error {
+	what := p.tok.value
+	if p.tok.kind == tokEOF {
+		what = "end of query"
+	}
+	return syntaxError(p.tok.loc, "%s, found %q", fmt.Sprintf(format, args...), what)
+}
+
+func (p *parser) parseDocument() (*Document, error) {
+	doc := &Document{Fragments: map[string]*Fragment{}}
+	for p.err == nil && p.tok.kind != tokEOF {
+		switch {
+		case p.peek("{"), p.peek("query"), p.peek("mutation"), p.peek("subscription"):
+			op, err := p.parseOperation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, op)
+		case p.peek("fragment"):
+			frag, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := doc.Fragments[frag.Name]; dup {
+				return nil, syntaxError(frag.Loc, "fragment %q is defined more than once", frag.Name)
+			}
+			doc.Fragments[frag.Name] = frag
+		default:
+			return nil, p.unexpected("expected an operation or fragment")
+		}
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	if len(doc.Operations) == 0 {
+		return nil, syntaxError(Location{Line: 1, Column: 1}, "document contains no operations")
+	}
+	return doc, nil
+}
+
+func (p *parser) parseOperation() (*Operation, error) {
+	op := &Operation{Type: "query", Loc: p.tok.loc}
+
+	// Shorthand: a bare selection set is an anonymous query.
+	if !p.peek("{") {
+		op.Type = p.tok.value
+		p.next()
+		if p.tok.kind == tokName {
+			op.Name, _ = p.name()
+		}
+		if p.skip("(") {
+			for !p.skip(")") {
+				def, err := p.parseVariableDefinition()
+				if err != nil {
+					return nil, err
+				}
+				op.Variables = append(op.Variables, def)
+			}
+		}
+		if _, err := p.parseDirectives(); err != nil {
+			return nil, err
+		}
+	}
+
+	sels, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.Selections = sels
+	return op, nil
+}
+
+func (p *parser) parseVariableDefinition() (*VariableDefinition, error) {
+	if err := p.expect("$"); err != nil {
+		return nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	typ, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+
+	def := &VariableDefinition{Name: name, Type: typ}
+	if p.skip("=") {
+		v, err := p.parseValue(true)
+		if err != nil {
+			return nil, err
+		}
+		def.Default, def.HasDefault = v, true
+	}
+	return def, p.err
+}
+
+// parseType reads a type reference such as ID!, [Int] or [String!]!.
+func (p *parser) parseType() (string, error) {
+	var typ string
+	if p.skip("[") {
+		inner, err := p.parseType()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect("]"); err != nil {
+			return "", err
+		}
+		typ = "[" + inner + "]"
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		typ = name
+	}
+	if p.skip("!") {
+		typ += "!"
+	}
+	return typ, p.err
+}
+
+func (p *parser) parseFragment() (*Fragment, error) {
+	frag := &Fragment{Loc: p.tok.loc}
+	p.next() // "fragment"
+
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if name == "on" {
+		return nil, syntaxError(frag.Loc, "a fragment can't be named \"on\"")
+	}
+	frag.Name = name
+	if err := p.expect("on"); err != nil {
+		return nil, err
+	}
+	if frag.TypeCondition, err = p.name(); err != nil {
+		return nil, err
+	}
+	if _, err := p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if frag.Selections, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	return frag, nil
+}
+
+func (p *parser) parseSelectionSet() ([]Selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var sels []Selection
+	for !p.skip("}") {
+		if p.err != nil {
+			return nil, p.err
+		}
+		if p.tok.kind == tokEOF {
+			return nil, p.unexpected("expected \"}\"")
+		}
+		sel, err := p.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+	}
+	if len(sels) == 0 {
+		return nil, syntaxError(p.tok.loc, "selection set must not be empty")
+	}
+	return sels, p.err
+}
+
+func (p *parser) parseSelection() (Selection, error) {
+	loc := p.tok.loc
+	if p.skip("...") {
+		if p.tok.kind == tokName && p.tok.value != "on" {
+			name, _ := p.name()
+			dirs, err := p.parseDirectives()
+			if err != nil {
+				return nil, err
+			}
+			return &FragmentSpread{Name: name, Directives: dirs, Loc: loc}, nil
+		}
+
+		frag := &InlineFragment{Loc: loc}
+		if p.skip("on") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			frag.TypeCondition = name
+		}
+		var err error
+		if frag.Directives, err = p.parseDirectives(); err != nil {
+			return nil, err
+		}
+		if frag.Selections, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+		return frag, nil
+	}
+
+	field := &SelectedField{Loc: loc}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if p.skip(":") {
+		field.Alias = name
+		if name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	field.Name = name
+
+	if field.Arguments, err = p.parseArguments(); err != nil {
+		return nil, err
+	}
+	if field.Directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		if field.Selections, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return field, p.err
+}
+
+func (p *parser) parseArguments() (map[string]any, error) {
+	if !p.skip("(") {
+		return nil, p.err
+	}
+	args := map[string]any{}
+	for !p.skip(")") {
+		loc := p.tok.loc
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		v, err := p.parseValue(false)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := args[name]; dup {
+			return nil, syntaxError(loc, "argument %q is given more than once", name)
+		}
+		args[name] = v
+	}
+	return args, p.err
+}
+
+func (p *parser) parseDirectives() ([]*Directive, error) {
+	var dirs []*Directive
+	for p.skip("@") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.parseArguments()
+		if err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, &Directive{Name: name, Arguments: args})
+	}
+	return dirs, p.err
+}
+
+// parseValue reads an argument or default value. Literals become Go values
+// (string, int, float64, bool, nil, []any, map[string]any); enum values become
+// strings; $name becomes a Variable. const forbids variables, as in defaults.
+func (p *parser) parseValue(constant bool) (any, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	tok := p.tok
+	switch tok.kind {
+	case tokPunct:
+		switch tok.value {
+		case "$":
+			if constant {
+				return nil, syntaxError(tok.loc, "variables are not allowed here")
+			}
+			p.next()
+			name, err := p.name()
+			return Variable(name), err
+		case "[":
+			p.next()
+			list := []any{}
+			for !p.skip("]") {
+				v, err := p.parseValue(constant)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			}
+			return list, p.err
+		case "{":
+			p.next()
+			obj := map[string]any{}
+			for !p.skip("}") {
+				name, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+				if obj[name], err = p.parseValue(constant); err != nil {
+					return nil, err
+				}
+			}
+			return obj, p.err
+		}
+	case tokInt:
+		p.next()
+		n, err := strconv.Atoi(tok.value)
+		if err != nil {
+			return nil, syntaxError(tok.loc, "integer %s is out of range", tok.value)
+		}
+		return n, p.err
+	case tokFloat:
+		p.next()
+		f, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, syntaxError(tok.loc, "invalid number %s", tok.value)
+		}
+		return f, p.err
+	case tokString:
+		p.next()
+		return tok.value, p.err
+	case tokName:
+		p.next()
+		switch tok.value {
+		case "true":
+			return true, p.err
+		case "false":
+			return false, p.err
+		case "null":
+			return nil, p.err
+		}
+		return tok.value, p.err // enum value
+	}
+	return nil, p.unexpected("expected a value")
+}
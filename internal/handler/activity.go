@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// ActivityHandler serves the public activity feed: new public snippets, and
+// forks and stars of them.
+type ActivityHandler struct {
+	service *service.ActivityService
+	logger  *slog.Logger
+}
+
+// NewActivityHandler creates a new ActivityHandler.
+func NewActivityHandler(svc *service.ActivityService, logger *slog.Logger) *ActivityHandler {
+	return &ActivityHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// HandleFeed returns a page of everyone's activity, newest first.
+//
+// HTTP: GET /api/v1/feed?cursor=...&limit=20
+func (h *ActivityHandler) HandleFeed(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	page, err := h.service.Feed(r.Context(), r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, page)
+}
+
+// HandleUser returns a page of one user's activity, newest first.
+//
+// HTTP: GET /api/v1/users/{login}/activity?cursor=...&limit=20
+func (h *ActivityHandler) HandleUser(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	page, err := h.service.ForUser(r.Context(), r.PathValue("login"), r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, page)
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/assets"
+	"github.com/sakif/coding-playground/internal/errreport"
+	"github.com/sakif/coding-playground/internal/executor"
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// dashboardErrors is how many recent errors the dashboard itself lists; the
+// errors page shows all that are kept.
+const dashboardErrors = 10
+
+// AdminHandler serves the server-rendered admin pages: a dashboard of user,
+// snippet and execution counts, sandbox pool status and recent errors.
+//
+// WHY SERVER-RENDERED?
+// Everything else the playground shows is drawn by JavaScript from the JSON
+// API. The admin pages are read-only snapshots for an operator, so plain
+// templates are enough: no extra endpoints, no client code, and they work
+// with scripts disabled.
+type AdminHandler struct {
+	dashboard *service.DashboardService
+	pool      executor.StatsProvider // nil when the executor doesn't report stats
+	errors    *errreport.Recent
+
+	// One template set per page: each page defines its own "admin-content"
+	// for the shared "admin" layout.
+	pages       map[string]*template.Template
+	templateDir string
+	devMode     bool
+	assets      *assets.Manifest
+	logger      *slog.Logger
+}
+
+// adminPages maps each page to its template file.
+var adminPages = map[string]string{
+	"dashboard": "admin_dashboard.html",
+	"errors":    "admin_errors.html",
+}
+
+// NewAdminHandler creates an AdminHandler and parses its templates. pool may
+// be nil. Like NewPlaygroundHandler, devMode re-parses templates per request.
+func NewAdminHandler(templateDir string, manifest *assets.Manifest, dashboard *service.DashboardService, pool executor.StatsProvider, errors *errreport.Recent, logger *slog.Logger, devMode bool) (*AdminHandler, error) {
+	h := &AdminHandler{
+		dashboard:   dashboard,
+		pool:        pool,
+		errors:      errors,
+		templateDir: templateDir,
+		devMode:     devMode,
+		assets:      manifest,
+		logger:      logger,
+	}
+
+	pages, err := h.parseTemplates()
+	if err != nil {
+		return nil, err
+	}
+	h.pages = pages
+	return h, nil
+}
+
+func (h *AdminHandler) parseTemplates() (map[string]*template.Template, error) {
+	funcs := template.FuncMap{"asset": h.assets.Path}
+	pages := make(map[string]*template.Template, len(adminPages))
+	for name, file := range adminPages {
+		tmpl, err := template.New("").Funcs(funcs).ParseFiles(
+			filepath.Join(h.templateDir, "admin.html"),
+			filepath.Join(h.templateDir, file),
+		)
+		if err != nil {
+			return nil, err
+		}
+		pages[name] = tmpl
+	}
+	return pages, nil
+}
+
+// hourBar is one bar of the executions-per-hour chart.
+type hourBar struct {
+	Hour    string // "15:00"
+	Count   int
+	Percent int // bar height relative to the busiest hour
+}
+
+// HandleDashboard renders the admin dashboard.
+//
+// HTTP: GET /admin
+func (h *AdminHandler) HandleDashboard(w http.ResponseWriter, r *http.Request) {
+	summary, err := h.dashboard.Summary(r.Context())
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to build admin dashboard", slog.String("error", err.Error()))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	total, busiest := 0, 0
+	for _, hc := range summary.ExecutionsPerHour {
+		total += hc.Count
+		busiest = max(busiest, hc.Count)
+	}
+	bars := make([]hourBar, len(summary.ExecutionsPerHour))
+	for i, hc := range summary.ExecutionsPerHour {
+		bars[i] = hourBar{Hour: hc.Hour.Format("15:04"), Count: hc.Count}
+		if busiest > 0 {
+			bars[i].Percent = hc.Count * 100 / busiest
+		}
+	}
+
+	var pool *executor.Stats
+	if h.pool != nil {
+		stats := h.pool.Stats()
+		pool = &stats
+	}
+
+	errs := h.errors.Entries()
+	more := len(errs) > dashboardErrors
+	if more {
+		errs = errs[:dashboardErrors]
+	}
+
+	h.render(w, r, "dashboard", map[string]any{
+		"Title":           "Dashboard",
+		"Dashboard":       summary,
+		"Executions":      bars,
+		"ExecutionsTotal": total,
+		"ExecutionsMax":   busiest,
+		"Pool":            pool,
+		"Errors":          errs,
+		"MoreErrors":      more,
+	})
+}
+
+// HandleErrors lists every recent error kept in memory.
+//
+// HTTP: GET /admin/errors
+func (h *AdminHandler) HandleErrors(w http.ResponseWriter, r *http.Request) {
+	h.render(w, r, "errors", map[string]any{
+		"Title":  "Recent errors",
+		"Errors": h.errors.Entries(),
+	})
+}
+
+func (h *AdminHandler) render(w http.ResponseWriter, r *http.Request, page string, data map[string]any) {
+	pages := h.pages
+	if h.devMode {
+		var err error
+		if pages, err = h.parseTemplates(); err != nil {
+			h.logger.ErrorContext(r.Context(), "failed to reload templates", slog.String("error", err.Error()))
+			http.Error(w, "Template error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	data["GeneratedAt"] = time.Now()
+
+	// The numbers are a live snapshot, and only admins may see them: never
+	// let a shared cache keep a copy.
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+
+	if err := pages[page].ExecuteTemplate(w, "admin", data); err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to render template", slog.String("error", err.Error()))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// ArtifactHandler serves the files runs left behind.
+type ArtifactHandler struct {
+	service *service.ArtifactService
+	logger  *slog.Logger
+}
+
+// NewArtifactHandler creates a new ArtifactHandler.
+func NewArtifactHandler(svc *service.ArtifactService, logger *slog.Logger) *ArtifactHandler {
+	return &ArtifactHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// inlineArtifactTypes are shown in the browser; anything else downloads.
+var inlineArtifactTypes = map[string]bool{
+	"image/png": true, "image/jpeg": true, "image/gif": true, "image/webp": true,
+	"text/plain": true, "text/csv": true, "application/json": true,
+}
+
+// HandleGet sends an artifact.
+//
+// The files were written by whoever ran the code, so they're served as
+// untrusted: never sniffed, sandboxed by CSP, and — for HTML, SVG and the
+// like, which could run script on our origin — only as downloads.
+//
+// HTTP: GET /api/v1/artifacts/{run}/{name}
+func (h *ArtifactHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	rc, info, err := h.service.Open(r.Context(), r.PathValue("run"), name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer rc.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(info.ContentType)
+	disposition := "inline"
+	if !inlineArtifactTypes[mediaType] {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Type", info.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox; default-src 'none'")
+	// An artifact never changes once stored.
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	if info.Size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	}
+	if _, err := io.Copy(w, rc); err != nil {
+		h.logger.WarnContext(r.Context(), "sending artifact failed", slog.String("name", name), slog.String("error", err.Error()))
+	}
+}
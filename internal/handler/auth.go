@@ -39,7 +39,7 @@ func (h *AuthHandler) HandleGitHubLogin(w http.ResponseWriter, r *http.Request)
 	// Generate a cryptographically random state parameter
 	stateBytes := make([]byte, 16)
 	if _, err := rand.Read(stateBytes); err != nil {
-		h.logger.Error("failed to generate OAuth state", slog.String("error", err.Error()))
+		h.logger.ErrorContext(r.Context(), "failed to generate OAuth state", slog.String("error", err.Error()))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -66,14 +66,14 @@ func (h *AuthHandler) HandleGitHubCallback(w http.ResponseWriter, r *http.Reques
 	// 1. Validate CSRF state
 	stateCookie, err := r.Cookie("oauth_state")
 	if err != nil {
-		h.logger.Warn("missing OAuth state cookie")
+		h.logger.WarnContext(r.Context(), "missing OAuth state cookie")
 		http.Error(w, "Invalid OAuth state", http.StatusBadRequest)
 		return
 	}
 
 	queryState := r.URL.Query().Get("state")
 	if queryState == "" || queryState != stateCookie.Value {
-		h.logger.Warn("OAuth state mismatch")
+		h.logger.WarnContext(r.Context(), "OAuth state mismatch")
 		http.Error(w, "Invalid OAuth state", http.StatusBadRequest)
 		return
 	}
@@ -89,7 +89,7 @@ func (h *AuthHandler) HandleGitHubCallback(w http.ResponseWriter, r *http.Reques
 
 	// 2. Check for OAuth errors from GitHub
 	if errMsg := r.URL.Query().Get("error"); errMsg != "" {
-		h.logger.Warn("GitHub OAuth error",
+		h.logger.WarnContext(r.Context(), "GitHub OAuth error",
 			slog.String("error", errMsg),
 			slog.String("description", r.URL.Query().Get("error_description")),
 		)
@@ -106,33 +106,39 @@ func (h *AuthHandler) HandleGitHubCallback(w http.ResponseWriter, r *http.Reques
 
 	result, err := h.authService.LoginOrRegisterGitHub(r.Context(), code)
 	if err != nil {
-		h.logger.Error("login/register failed", slog.String("error", err.Error()))
+		h.logger.ErrorContext(r.Context(), "login/register failed", slog.String("error", err.Error()))
 		http.Error(w, "Authentication failed", http.StatusInternalServerError)
 		return
 	}
 
 	// 4. Set the JWT in an HttpOnly cookie
+	setSessionCookie(w, result.Token)
+
+	h.logger.InfoContext(r.Context(), "user logged in",
+		slog.String("user_id", result.User.ID),
+		slog.String("login", result.User.Login),
+	)
+
+	// 5. Redirect to the playground
+	http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+}
+
+// setSessionCookie signs the browser in with a JWT.
+func setSessionCookie(w http.ResponseWriter, token string) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     auth.CookieName,
-		Value:    result.Token,
+		Value:    token,
 		Path:     "/",
 		MaxAge:   3600, // 1 hour (matches JWT expiry)
 		HttpOnly: true,
 		SameSite: http.SameSiteLaxMode,
 		// Secure:   true, // uncomment in production (requires HTTPS)
 	})
-
-	h.logger.Info("user logged in",
-		slog.String("user_id", result.User.ID),
-		slog.String("login", result.User.Login),
-	)
-
-	// 5. Redirect to the playground
-	http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
 }
 
-// HandleLogout clears the JWT cookie.
-func (h *AuthHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
+// HandleLogout clears the JWT cookie. It needs no AuthHandler, so it works
+// however the user signed in.
+func HandleLogout(w http.ResponseWriter, r *http.Request) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     auth.CookieName,
 		Value:    "",
@@ -159,7 +165,7 @@ func (h *AuthHandler) HandleMe(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.authService.GetUserByID(r.Context(), userID)
 	if err != nil {
-		h.logger.Error("failed to get user", slog.String("error", err.Error()))
+		h.logger.ErrorContext(r.Context(), "failed to get user", slog.String("error", err.Error()))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
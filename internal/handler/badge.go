@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// BadgeHandler serves the badges users have earned. They're public, like a
+// user's public snippets.
+type BadgeHandler struct {
+	service *service.BadgeService
+	logger  *slog.Logger
+}
+
+// NewBadgeHandler creates a new BadgeHandler.
+func NewBadgeHandler(svc *service.BadgeService, logger *slog.Logger) *BadgeHandler {
+	return &BadgeHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// HandleList returns the badges a user has earned, oldest first.
+//
+// HTTP: GET /api/v1/users/{login}/badges
+func (h *BadgeHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	badges, err := h.service.ForUser(r.Context(), r.PathValue("login"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, badges)
+}
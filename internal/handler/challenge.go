@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// ChallengeHandler serves the daily challenge: today's problem, the schedule
+// authors set up, submissions and streaks.
+type ChallengeHandler struct {
+	service *service.ChallengeService
+	logger  *slog.Logger
+}
+
+// NewChallengeHandler creates a new ChallengeHandler.
+func NewChallengeHandler(svc *service.ChallengeService, logger *slog.Logger) *ChallengeHandler {
+	return &ChallengeHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// ScheduleChallengeRequest is the expected JSON body for scheduling a challenge.
+type ScheduleChallengeRequest struct {
+	ExerciseID string `json:"exerciseId"`
+}
+
+// TodayResponse is today's challenge, with the caller's streak when signed in.
+type TodayResponse struct {
+	Challenge *model.Challenge `json:"challenge"`
+	Streak    *model.Streak    `json:"streak,omitempty"`
+}
+
+// ChallengeListResponse is the envelope for GET /challenges.
+type ChallengeListResponse struct {
+	Items []model.Challenge `json:"items"`
+}
+
+// HandleToday returns today's challenge, and the caller's streak if signed in.
+//
+// HTTP: GET /api/v1/challenges/today
+func (h *ChallengeHandler) HandleToday(w http.ResponseWriter, r *http.Request) {
+	challenge, err := h.service.Today(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	resp := TodayResponse{Challenge: challenge}
+	if userID, _ := auth.UserIDFromContext(r.Context()); userID != "" {
+		if resp.Streak, err = h.service.Streak(r.Context(), userID); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		// The streak is the caller's own, so no shared cache may reuse it.
+		w.Header().Set("Cache-Control", "private, no-cache")
+	}
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// HandleList returns the challenges in a date range, newest first.
+//
+// HTTP: GET /api/v1/challenges?from=2026-01-01&to=2026-01-31
+func (h *ChallengeHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.UserIDFromContext(r.Context())
+	q := r.URL.Query()
+	challenges, err := h.service.List(r.Context(), userID, q.Get("from"), q.Get("to"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if challenges == nil {
+		challenges = []model.Challenge{}
+	}
+	writeJSON(w, r, http.StatusOK, ChallengeListResponse{Items: challenges})
+}
+
+// HandleGet returns the challenge for one date. Future dates are only
+// visible to authors.
+//
+// HTTP: GET /api/v1/challenges/{date}
+func (h *ChallengeHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.UserIDFromContext(r.Context())
+	challenge, err := h.service.Get(r.Context(), userID, r.PathValue("date"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, challenge)
+}
+
+// HandleSchedule sets the exercise for a date, replacing any already set.
+//
+// HTTP: PUT /api/v1/challenges/{date}
+// Request body: {"exerciseId": "..."}
+func (h *ChallengeHandler) HandleSchedule(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req ScheduleChallengeRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	challenge, err := h.service.Schedule(r.Context(), userID, r.PathValue("date"), req.ExerciseID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, challenge)
+}
+
+// HandleUnschedule removes the challenge for a date.
+//
+// HTTP: DELETE /api/v1/challenges/{date}
+func (h *ChallengeHandler) HandleUnschedule(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.Unschedule(r.Context(), userID, r.PathValue("date")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleSubmit grades a solution to today's challenge. Like exercise
+// submissions, a solution that fails is still a 201.
+//
+// HTTP: POST /api/v1/challenges/{date}/submit
+// Request body: {"code": "..."}
+func (h *ChallengeHandler) HandleSubmit(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req SubmitRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	submission, err := h.service.Submit(r.Context(), userID, r.PathValue("date"), req.Code)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, submission)
+}
+
+// HandleStreak returns the signed-in user's daily challenge streak.
+//
+// HTTP: GET /api/v1/me/streak
+func (h *ChallengeHandler) HandleStreak(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	streak, err := h.service.Streak(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, streak)
+}
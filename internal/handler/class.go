@@ -0,0 +1,323 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// ClassHandler serves classroom mode. Every route sits behind
+// auth.RequireAuth; who may see what inside a class is the service's call.
+type ClassHandler struct {
+	service *service.ClassService
+	logger  *slog.Logger
+}
+
+// NewClassHandler creates a new ClassHandler.
+func NewClassHandler(svc *service.ClassService, logger *slog.Logger) *ClassHandler {
+	return &ClassHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// CreateClassRequest is the expected JSON body for creating a class.
+type CreateClassRequest struct {
+	Name string `json:"name"`
+}
+
+// JoinClassRequest is the expected JSON body for joining a class.
+type JoinClassRequest struct {
+	Code string `json:"code"`
+}
+
+// AssignmentRequest is the expected JSON body for setting an exercise or
+// changing an assignment. Everything but exerciseId is optional; on update,
+// exerciseId is ignored and the other fields replace the current ones.
+type AssignmentRequest struct {
+	ExerciseID  string     `json:"exerciseId"`
+	Title       string     `json:"title"`
+	OpensAt     *time.Time `json:"opensAt"` // RFC 3339
+	DueAt       *time.Time `json:"dueAt"`
+	LatePolicy  string     `json:"latePolicy"` // accept (default), penalize or reject
+	LatePenalty int        `json:"latePenalty"`
+}
+
+func (req AssignmentRequest) input() service.AssignmentInput {
+	return service.AssignmentInput{
+		ExerciseID:  req.ExerciseID,
+		Title:       req.Title,
+		OpensAt:     req.OpensAt,
+		DueAt:       req.DueAt,
+		LatePolicy:  req.LatePolicy,
+		LatePenalty: req.LatePenalty,
+	}
+}
+
+// HandleCreate creates a class taught by the signed-in user.
+//
+// HTTP: POST /api/v1/classes
+// Request body: {"name": "Intro to Python"}
+func (h *ClassHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	var req CreateClassRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	class, err := h.service.Create(r.Context(), userID, req.Name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, class)
+}
+
+// HandleJoin adds the signed-in user to a class as a student.
+//
+// HTTP: POST /api/v1/classes/join
+// Request body: {"code": "K7QX2MPA"}
+func (h *ClassHandler) HandleJoin(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	var req JoinClassRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	class, err := h.service.Join(r.Context(), userID, req.Code)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, class)
+}
+
+// HandleList returns the classes the signed-in user belongs to.
+//
+// HTTP: GET /api/v1/classes
+func (h *ClassHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	classes, err := h.service.List(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, classes)
+}
+
+// HandleGet returns one of the signed-in user's classes.
+//
+// HTTP: GET /api/v1/classes/{id}
+func (h *ClassHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	class, err := h.service.Get(r.Context(), userID, r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, class)
+}
+
+// HandleMembers lists a class's members (teachers only).
+//
+// HTTP: GET /api/v1/classes/{id}/members
+func (h *ClassHandler) HandleMembers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	members, err := h.service.Members(r.Context(), userID, r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, members)
+}
+
+// HandleCreateAssignment sets an exercise for a class (teachers only).
+//
+// HTTP: POST /api/v1/classes/{id}/assignments
+// Request body: {"exerciseId": "...", "dueAt": "2025-03-01T17:00:00Z", "latePolicy": "penalize", "latePenalty": 20}
+func (h *ClassHandler) HandleCreateAssignment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	var req AssignmentRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	assignment, err := h.service.CreateAssignment(r.Context(), userID, r.PathValue("id"), req.input())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, assignment)
+}
+
+// HandleUpdateAssignment changes an assignment's title, window or late
+// policy (teachers only).
+//
+// HTTP: PUT /api/v1/classes/{id}/assignments/{assignmentID}
+// Request body: same as HandleCreateAssignment, without exerciseId
+func (h *ClassHandler) HandleUpdateAssignment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	var req AssignmentRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	assignment, err := h.service.UpdateAssignment(r.Context(), userID, r.PathValue("id"), r.PathValue("assignmentID"), req.input())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, assignment)
+}
+
+// HandleSubmit grades the signed-in user's solution to an assignment. It's
+// refused (403) before the assignment opens, and after it's due if the late
+// policy is "reject".
+//
+// HTTP: POST /api/v1/classes/{id}/assignments/{assignmentID}/submit
+// Request body: {"code": "..."}
+func (h *ClassHandler) HandleSubmit(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	var req SubmitRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	submission, err := h.service.Submit(r.Context(), userID, r.PathValue("id"), r.PathValue("assignmentID"), req.Code)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, submission)
+}
+
+// HandleProgress lists each student's completion status for an assignment
+// (teachers only).
+//
+// HTTP: GET /api/v1/classes/{id}/assignments/{assignmentID}/progress
+func (h *ClassHandler) HandleProgress(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	progress, err := h.service.Progress(r.Context(), userID, r.PathValue("id"), r.PathValue("assignmentID"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, progress)
+}
+
+// HandleAssignments lists a class's assignments.
+//
+// HTTP: GET /api/v1/classes/{id}/assignments
+func (h *ClassHandler) HandleAssignments(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	assignments, err := h.service.Assignments(r.Context(), userID, r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, assignments)
+}
+
+// HandleSubmissions lists submissions for an assignment: all students' for
+// a teacher, the caller's own for a student.
+//
+// HTTP: GET /api/v1/classes/{id}/assignments/{assignmentID}/submissions
+func (h *ClassHandler) HandleSubmissions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	submissions, err := h.service.AssignmentSubmissions(r.Context(), userID, r.PathValue("id"), r.PathValue("assignmentID"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, submissions)
+}
+
+// SimilarityRequest is the optional JSON body for requesting a similarity
+// report.
+type SimilarityRequest struct {
+	Threshold float64 `json:"threshold"` // 0–1; defaults to 0.8
+}
+
+// HandleRequestSimilarity queues a similarity report comparing the
+// students' latest submissions to an assignment (teachers only). It answers
+// 202 with the pending report; poll HandleSimilarity for the result.
+//
+// HTTP: POST /api/v1/classes/{id}/assignments/{assignmentID}/similarity
+// Request body (optional): {"threshold": 0.7}
+func (h *ClassHandler) HandleRequestSimilarity(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	var req SimilarityRequest
+	if err := decodeBody(r, &req); err != nil && !errors.Is(err, io.EOF) { // no body: the defaults
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	report, err := h.service.RequestSimilarity(r.Context(), userID, r.PathValue("id"), r.PathValue("assignmentID"), req.Threshold)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusAccepted, report)
+}
+
+// HandleSimilarity returns an assignment's latest similarity report
+// (teachers only), or 404 if none was requested.
+//
+// HTTP: GET /api/v1/classes/{id}/assignments/{assignmentID}/similarity
+func (h *ClassHandler) HandleSimilarity(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	report, err := h.service.SimilarityReport(r.Context(), userID, r.PathValue("id"), r.PathValue("assignmentID"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, report)
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MESSAGEPACK:
+// JSON is the API's default format, but programmatic clients that run lots of
+// executions can ask for MessagePack instead — a binary encoding of the same
+// data model that's smaller and faster to parse:
+//
+//	Accept: application/msgpack          → responses in MessagePack
+//	Content-Type: application/msgpack    → request body in MessagePack
+//
+// Field names are the same as in JSON (the encoder reads the json struct
+// tags), so the documented schemas apply to both formats.
+//
+// CONTENT NEGOTIATION:
+// The Accept header can list several formats with preferences ("q" values):
+//
+//	Accept: application/msgpack, application/json;q=0.5
+//
+// MessagePack is used only when it's explicitly listed and preferred at least
+// as much as JSON. Browsers send "*/*" and keep getting JSON. Because the
+// same URL can now produce different bodies, responses say "Vary: Accept" so
+// caches keep the formats apart.
+
+// ContentTypeMsgpack is the MessagePack media type.
+const ContentTypeMsgpack = "application/msgpack"
+
+// msgpackTypes are the media types accepted as MessagePack; the format has no
+// registered type, and clients use all of these.
+var msgpackTypes = []string{ContentTypeMsgpack, "application/x-msgpack", "application/vnd.msgpack"}
+
+// wantsMsgpack reports whether the Accept header prefers MessagePack over JSON.
+func wantsMsgpack(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return false
+	}
+
+	var qMsgpack, qJSON float64
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		switch {
+		case isMsgpack(mediaType):
+			qMsgpack = max(qMsgpack, q)
+		case mediaType == "application/json", mediaType == "application/*", mediaType == "*/*",
+			strings.HasSuffix(mediaType, "+json"):
+			qJSON = max(qJSON, q)
+		}
+	}
+	return qMsgpack > 0 && qMsgpack >= qJSON
+}
+
+// isMsgpack reports whether mediaType is one of the MessagePack types.
+func isMsgpack(mediaType string) bool {
+	for _, t := range msgpackTypes {
+		if mediaType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// isMsgpackBody reports whether the request body is MessagePack.
+func isMsgpackBody(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && isMsgpack(mediaType)
+}
+
+// decodeBody reads the request body into v, as MessagePack if the
+// Content-Type says so and as JSON otherwise. Report errors with
+// writeDecodeError.
+func decodeBody(r *http.Request, v any) error {
+	if isMsgpackBody(r) {
+		return newMsgpackDecoder(r.Body).Decode(v)
+	}
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
+// newMsgpackEncoder returns an encoder that follows the json struct tags.
+func newMsgpackEncoder(w io.Writer) *msgpack.Encoder {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+	return enc
+}
+
+// newMsgpackDecoder returns a decoder that follows the json struct tags.
+func newMsgpackDecoder(r io.Reader) *msgpack.Decoder {
+	dec := msgpack.NewDecoder(r)
+	dec.SetCustomStructTag("json")
+	return dec
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// CommentHandler serves comments on snippets. Anyone who can see a snippet
+// can read its comments; writing one takes a signed-in user.
+type CommentHandler struct {
+	service *service.CommentService
+	logger  *slog.Logger
+}
+
+// NewCommentHandler creates a new CommentHandler.
+func NewCommentHandler(svc *service.CommentService, logger *slog.Logger) *CommentHandler {
+	return &CommentHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// CommentRequest is the expected JSON body for commenting on a snippet.
+// Leave out the lines to comment on the whole snippet.
+type CommentRequest struct {
+	Body      string `json:"body"`
+	Version   int    `json:"version"`   // the snippet version being read; 409 if it's no longer current
+	LineStart int    `json:"lineStart"` // 1-based
+	LineEnd   int    `json:"lineEnd"`   // inclusive; defaults to lineStart
+}
+
+// HandleList returns a snippet's comments, oldest first. Comments whose
+// lines refer to an older version of the code are marked "outdated".
+//
+// HTTP: GET /api/v1/snippets/{id}/comments
+func (h *CommentHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	comments, err := h.service.List(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, comments)
+}
+
+// HandleCreate comments on a snippet as the signed-in user.
+//
+// HTTP: POST /api/v1/snippets/{id}/comments
+// Request body: {"body": "off-by-one", "version": 3, "lineStart": 12}
+func (h *CommentHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	var req CommentRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	comment, err := h.service.Create(r.Context(), userID, r.PathValue("id"), service.CommentInput{
+		Body:      req.Body,
+		Version:   req.Version,
+		LineStart: req.LineStart,
+		LineEnd:   req.LineEnd,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, comment)
+}
+
+// HandleDelete removes a comment: the signed-in user's own, or any on a
+// snippet they own.
+//
+// HTTP: DELETE /api/v1/snippets/{id}/comments/{commentID}
+func (h *CommentHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(r.Context(), userID, r.PathValue("id"), r.PathValue("commentID")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
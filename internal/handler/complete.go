@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// CompleteHandler serves the editor's autocomplete.
+type CompleteHandler struct {
+	service *service.CompletionService
+	logger  *slog.Logger
+}
+
+// NewCompleteHandler creates a new CompleteHandler.
+func NewCompleteHandler(svc *service.CompletionService, logger *slog.Logger) *CompleteHandler {
+	return &CompleteHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// HandleComplete returns what could be typed at a position in some code.
+//
+// HTTP: POST /api/v1/complete
+// Body: {"code":"import math\nmath.sq","line":2,"column":7}
+func (h *CompleteHandler) HandleComplete(w http.ResponseWriter, r *http.Request) {
+	var req service.CompletionRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+	completions, err := h.service.Complete(r.Context(), req)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "completion failed", slog.String("error", err.Error()))
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, completions)
+}
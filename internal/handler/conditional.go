@@ -0,0 +1,87 @@
+package handler
+
+// CONDITIONAL REQUESTS:
+// An editor that polls GET /api/v1/snippets/{id} usually gets back exactly what
+// it already has. Conditional requests let it say "only send the body if it
+// changed":
+//
+//  1. Our response carries validators:
+//       ETag: W/"cv37rs3pp9olc6atsptg-17a3b5c9e0f1d200"
+//       Last-Modified: Tue, 14 Oct 2025 09:12:44 GMT
+//  2. The client sends them back on the next request:
+//       If-None-Match: W/"cv37rs3pp9olc6atsptg-17a3b5c9e0f1d200"
+//       If-Modified-Since: Tue, 14 Oct 2025 09:12:44 GMT
+//  3. If nothing changed, we answer 304 Not Modified with NO body.
+//
+// WEAK vs STRONG ETAGS:
+// A strong ETag promises byte-identical bodies. Ours is derived from the
+// snippet's ID and UpdatedAt rather than a hash of the JSON, so it's marked weak
+// (W/) — "semantically the same", which is all a 304 needs.
+//
+// If-None-Match wins over If-Modified-Since when both are sent (RFC 9110 §13.2.2):
+// Last-Modified only has one-second resolution, the ETag is exact.
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/cdn"
+	"github.com/sakif/coding-playground/internal/model"
+)
+
+// snippetETag returns a weak ETag that changes whenever the snippet is updated.
+func snippetETag(s *model.Snippet) string {
+	return fmt.Sprintf(`W/"%s-%x"`, s.ID, s.UpdatedAt.UnixNano())
+}
+
+// setSnippetValidators sets the ETag and Last-Modified headers for a snippet.
+// Cache-Control: no-cache lets clients store the response but makes them
+// revalidate (with the validators) before reusing it.
+func setSnippetValidators(w http.ResponseWriter, s *model.Snippet) {
+	w.Header().Set("ETag", snippetETag(s))
+	w.Header().Set("Last-Modified", s.UpdatedAt.UTC().Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "no-cache")
+}
+
+// setCDNHeaders lets a CDN keep a public snippet's response for maxAge,
+// tagged for purging (see internal/cdn). Private snippets, and a maxAge of
+// 0, keep the no-cache setSnippetValidators sets.
+func setCDNHeaders(w http.ResponseWriter, s *model.Snippet, maxAge time.Duration) {
+	if maxAge > 0 && s.Public {
+		cdn.SetHeaders(w.Header(), maxAge, cdn.SnippetKey(s.ID))
+	}
+}
+
+// notModified reports whether the request's conditional headers match the
+// current validators, meaning the client's copy is still fresh.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, etag)
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		t, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		// HTTP dates have one-second resolution, so compare at that precision.
+		return !lastModified.Truncate(time.Second).After(t)
+	}
+
+	return false
+}
+
+// etagMatches implements the weak comparison used by If-None-Match: the
+// header may list several ETags (or "*"), and the W/ prefix is ignored.
+func etagMatches(header, etag string) bool {
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// DebugHandler serves debugging sessions: the code runs under pdb while
+// the browser drives it over the WebSocket hub.
+type DebugHandler struct {
+	service *service.DebugService
+	logger  *slog.Logger
+}
+
+// NewDebugHandler creates a new DebugHandler.
+func NewDebugHandler(svc *service.DebugService, logger *slog.Logger) *DebugHandler {
+	return &DebugHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// HandleStart starts debugging some code. The response names the topic to
+// subscribe to for its events and send its commands on.
+//
+// HTTP: POST /api/v1/debug
+// Body: {"code":"x = 1\nprint(x)","breakpoints":[2]}
+func (h *DebugHandler) HandleStart(w http.ResponseWriter, r *http.Request) {
+	var req service.DebugRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+	session, err := h.service.Start(r.Context(), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/api/v1/debug/"+session.ID)
+	writeJSON(w, r, http.StatusCreated, session)
+}
+
+// HandleGet returns a session: where the program is paused, and its output
+// so far.
+//
+// HTTP: GET /api/v1/debug/{id}
+func (h *DebugHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	session, err := h.service.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, session)
+}
+
+// HandleStop ends a session.
+//
+// HTTP: DELETE /api/v1/debug/{id}
+func (h *DebugHandler) HandleStop(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Stop(r.Context(), r.PathValue("id")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
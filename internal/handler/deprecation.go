@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// API DEPRECATION:
+// Before a route or a field goes away — in a v2, say — clients are told,
+// in the response to every request that uses it:
+//
+//	Deprecation: @1792108800                        (RFC 9745: deprecated since then)
+//	Sunset: Sat, 01 May 2027 00:00:00 GMT           (RFC 8594: gone after then)
+//	Link: <https://…/docs/v1-to-v2>; rel="deprecation"
+//
+//	{"id":"…", …, "warnings":[{"code":"DEPRECATED_ROUTE","message":"Use /api/v1.","sunset":"2027-05-01T00:00:00Z"}]}
+//
+// The headers are for tools and proxies; the warnings field is for a human
+// reading a response. It's added to JSON objects only: a response that's a
+// bare array, or MessagePack, has just the headers. A deprecated field gets
+// a warning naming it, but no headers — those would say the whole route is
+// going.
+//
+// Every use is logged ("deprecated API used", with the route, the field
+// and the client's User-Agent), so the move off an old route can be
+// followed, and the sunset date picked from who's still on it.
+//
+// Usage:
+//
+//	r.With(handler.Deprecated(handler.Deprecation{Since: …, Message: "Use /api/v1."})).Get(…)
+//	handler.DeprecateField(w, r, "tags", handler.Deprecation{Since: …, Message: "Use labels."})
+//
+// Both rely on Deprecations, which the API's routes run first.
+
+// Deprecation describes what's deprecated and what to do about it.
+type Deprecation struct {
+	Since   time.Time // when it was deprecated
+	Sunset  time.Time // when it goes away; zero if that's not decided
+	Link    string    // a page about the replacement, if there is one
+	Message string    // what to use instead
+}
+
+// Warning is an entry in a response's "warnings" field.
+type Warning struct {
+	Code    string     `json:"code"`
+	Message string     `json:"message"`
+	Field   string     `json:"field,omitempty"`
+	Sunset  *time.Time `json:"sunset,omitempty"`
+}
+
+// Warning codes.
+const (
+	WarningDeprecatedRoute = "DEPRECATED_ROUTE"
+	WarningDeprecatedField = "DEPRECATED_FIELD"
+)
+
+// warnings collects a request's warnings until its response is written.
+type warnings struct {
+	logger *slog.Logger
+	mu     sync.Mutex
+	list   []Warning
+}
+
+type warningsKey struct{}
+
+// Deprecations is middleware that lets the handlers behind it warn about
+// deprecated routes and fields (see Deprecated and DeprecateField), logging
+// each use with logger. Running it twice for a request is harmless.
+func Deprecations(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.Context().Value(warningsKey{}).(*warnings); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx := context.WithValue(r.Context(), warningsKey{}, &warnings{logger: logger})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Deprecated is middleware that marks every route behind it deprecated.
+func Deprecated(d Deprecation) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+			if !d.Sunset.IsZero() {
+				h.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if d.Link != "" {
+				h.Add("Link", "<"+d.Link+`>; rel="deprecation"`)
+			}
+			warn(r, "", d)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// DeprecateField warns that the request used field, which is deprecated.
+// Call it before writing the response.
+func DeprecateField(w http.ResponseWriter, r *http.Request, field string, d Deprecation) {
+	if d.Link != "" {
+		w.Header().Add("Link", "<"+d.Link+`>; rel="deprecation"`)
+	}
+	warn(r, field, d)
+}
+
+// warn records and logs a warning about d — about field, or the route if
+// field is "".
+func warn(r *http.Request, field string, d Deprecation) {
+	ws, _ := r.Context().Value(warningsKey{}).(*warnings)
+	if ws == nil {
+		return
+	}
+	warning := Warning{Code: WarningDeprecatedRoute, Message: d.Message, Field: field}
+	if field != "" {
+		warning.Code = WarningDeprecatedField
+	}
+	if !d.Sunset.IsZero() {
+		sunset := d.Sunset.UTC()
+		warning.Sunset = &sunset
+	}
+	ws.mu.Lock()
+	ws.list = append(ws.list, warning)
+	ws.mu.Unlock()
+
+	attrs := []any{
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("user_agent", r.UserAgent()),
+	}
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		attrs = append(attrs, slog.String("route", rctx.RoutePattern()))
+	}
+	if field != "" {
+		attrs = append(attrs, slog.String("field", field))
+	}
+	ws.logger.InfoContext(r.Context(), "deprecated API used", attrs...)
+}
+
+// withWarnings returns data as JSON with the request's warnings added, if
+// it has any and data is a JSON object.
+func withWarnings(r *http.Request, data any) (json.RawMessage, bool) {
+	ws, _ := r.Context().Value(warningsKey{}).(*warnings)
+	if ws == nil {
+		return nil, false
+	}
+	ws.mu.Lock()
+	list := ws.list
+	ws.mu.Unlock()
+	if len(list) == 0 {
+		return nil, false
+	}
+
+	body, err := json.Marshal(data)
+	if err != nil || len(body) < 2 || body[0] != '{' {
+		return nil, false
+	}
+	field, err := json.Marshal(list)
+	if err != nil {
+		return nil, false
+	}
+	var out bytes.Buffer
+	out.Write(body[:len(body)-1]) // all but the closing }
+	if len(bytes.TrimSpace(body[1:len(body)-1])) > 0 {
+		out.WriteByte(',')
+	}
+	out.WriteString(`"warnings":`)
+	out.Write(field)
+	out.WriteByte('}')
+	return out.Bytes(), true
+}
@@ -0,0 +1,72 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sakif/coding-playground/internal/handler"
+)
+
+func TestDeprecation(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	d := handler.Deprecation{
+		Since:   time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+		Sunset:  time.Date(2027, 5, 1, 0, 0, 0, 0, time.UTC),
+		Link:    "https://play.example.com/docs/v2",
+		Message: "Use /api/v2/codes.",
+	}
+	serve := func(h http.Handler) (*httptest.ResponseRecorder, map[string]json.RawMessage) {
+		rr := httptest.NewRecorder()
+		handler.Deprecations(logger)(h).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/errors", nil))
+		var body map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		return rr, body
+	}
+
+	t.Run("a deprecated route", func(t *testing.T) {
+		logs.Reset()
+		rr, body := serve(handler.Deprecated(d)(http.HandlerFunc(handler.HandleErrorCodes)))
+		assert.Equal(t, "@1792108800", rr.Header().Get("Deprecation"))
+		assert.Equal(t, "Sat, 01 May 2027 00:00:00 GMT", rr.Header().Get("Sunset"))
+		assert.Equal(t, `<https://play.example.com/docs/v2>; rel="deprecation"`, rr.Header().Get("Link"))
+		assert.Contains(t, body, "codes", "the response itself is still there")
+
+		var warnings []handler.Warning
+		require.NoError(t, json.Unmarshal(body["warnings"], &warnings))
+		require.Len(t, warnings, 1)
+		assert.Equal(t, handler.WarningDeprecatedRoute, warnings[0].Code)
+		assert.Equal(t, d.Message, warnings[0].Message)
+		require.NotNil(t, warnings[0].Sunset)
+		assert.True(t, warnings[0].Sunset.Equal(d.Sunset))
+		assert.Contains(t, logs.String(), `msg="deprecated API used"`)
+	})
+
+	t.Run("a deprecated field", func(t *testing.T) {
+		logs.Reset()
+		rr, body := serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler.DeprecateField(w, r, "legacy", d)
+			handler.HandleErrorCodes(w, r)
+		}))
+		assert.Empty(t, rr.Header().Get("Deprecation"), "the route isn't deprecated")
+		var warnings []handler.Warning
+		require.NoError(t, json.Unmarshal(body["warnings"], &warnings))
+		require.Len(t, warnings, 1)
+		assert.Equal(t, handler.WarningDeprecatedField, warnings[0].Code)
+		assert.Equal(t, "legacy", warnings[0].Field)
+		assert.Contains(t, logs.String(), "field=legacy")
+	})
+
+	t.Run("nothing deprecated", func(t *testing.T) {
+		_, body := serve(http.HandlerFunc(handler.HandleErrorCodes))
+		assert.NotContains(t, body, "warnings")
+	})
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// DocsHandler serves the editor's hover docs.
+type DocsHandler struct {
+	service *service.DocsService
+	logger  *slog.Logger
+}
+
+// NewDocsHandler creates a new DocsHandler.
+func NewDocsHandler(svc *service.DocsService, logger *slog.Logger) *DocsHandler {
+	return &DocsHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// HandleGet returns the signature and docstring of a Python name.
+//
+// HTTP: GET /api/v1/docs?symbol=os.path.join
+func (h *DocsHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	doc, err := h.service.LookUp(r.Context(), r.URL.Query().Get("symbol"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, doc)
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// EmailHandler serves email preferences, and the unsubscribe links at the
+// bottom of emails.
+type EmailHandler struct {
+	service *service.EmailService
+	logger  *slog.Logger
+}
+
+// NewEmailHandler creates a new EmailHandler.
+func NewEmailHandler(svc *service.EmailService, logger *slog.Logger) *EmailHandler {
+	return &EmailHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// EmailPreferencesRequest is the expected JSON body for changing email
+// preferences. Fields left out stay as they are.
+type EmailPreferencesRequest struct {
+	Grading *bool `json:"grading"`
+	Digest  *bool `json:"digest"`
+}
+
+// HandleGetPreferences returns the signed-in user's email preferences.
+//
+// HTTP: GET /api/v1/me/email-preferences
+func (h *EmailHandler) HandleGetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	prefs, err := h.service.Preferences(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, prefs)
+}
+
+// HandleSetPreferences changes the signed-in user's email preferences.
+//
+// HTTP: PUT /api/v1/me/email-preferences
+// Request body: {"grading": true, "digest": false}
+func (h *EmailHandler) HandleSetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	var req EmailPreferencesRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+	prefs, err := h.service.SetPreferences(r.Context(), userID, service.EmailPreferencesInput{
+		Grading: req.Grading,
+		Digest:  req.Digest,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, prefs)
+}
+
+// HandleUnsubscribe turns one kind of email off from a link in an email.
+// People click it in a mail client, so it answers in plain text.
+//
+// HTTP: GET /email/unsubscribe?user=<id>&kind=digest&sig=<hex>
+func (h *EmailHandler) HandleUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	err := h.service.Unsubscribe(r.Context(), q.Get("user"), q.Get("kind"), q.Get("sig"))
+	switch {
+	case err == nil:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		what := "grading emails"
+		if q.Get("kind") == service.EmailDigest {
+			what = "the weekly digest"
+		}
+		io.WriteString(w, "You're unsubscribed: you won't get "+what+" any more.\n")
+	case errors.Is(err, apperror.ErrValidation), errors.Is(err, apperror.ErrForbidden):
+		http.Error(w, "This unsubscribe link is not valid.", http.StatusBadRequest)
+	default:
+		h.logger.ErrorContext(r.Context(), "unsubscribe failed", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+)
+
+// errorCodeResponse is one entry of the error code catalog.
+type errorCodeResponse struct {
+	Code        apperror.Code `json:"code"`
+	Error       string        `json:"error,omitempty"`  // the error type it comes with, if always the same
+	Status      int           `json:"status,omitempty"` // the HTTP status it comes with, if always the same
+	Description string        `json:"description"`
+}
+
+// HandleErrorCodes lists every error code the API can return, so clients can
+// branch on codes (see apperror/codes.go) instead of parsing messages.
+//
+// HTTP: GET /api/v1/errors
+func HandleErrorCodes(w http.ResponseWriter, r *http.Request) {
+	catalog := apperror.Catalog()
+	resp := make([]errorCodeResponse, len(catalog))
+	for i, e := range catalog {
+		resp[i] = errorCodeResponse{Code: e.Code, Description: e.Description}
+		if e.Kind != nil {
+			resp[i].Status, resp[i].Error = errorStatus(e.Kind)
+		}
+	}
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	writeJSON(w, r, http.StatusOK, map[string]any{"codes": resp})
+}
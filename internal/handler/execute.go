@@ -1,19 +1,39 @@
 package handler
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"time"
 
+	"github.com/sakif/coding-playground/internal/abuse"
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
 	"github.com/sakif/coding-playground/internal/executor"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/service"
 )
 
 // ExecuteHandler handles code execution requests.
 type ExecuteHandler struct {
-	exec   executor.Executor
-	logger *slog.Logger
+	exec       executor.Executor
+	logger     *slog.Logger
+	events     service.EventPublisher    // optional; see PublishEvents
+	abuse      *abuse.Detector           // optional; see DetectAbuse
+	artifacts  *service.ArtifactService  // optional; see SaveArtifacts
+	runs       *service.RunService       // optional; see RecordRuns
+	executions *service.ExecutionService // optional; see RunInBackground
+	packages   executor.PackageAllowlist // see AllowPackages
+	gpu        executor.Executor         // optional; see UseGPUs
+	gpuAccess  GPUEntitlement            // who may use gpu
 }
 
+// GPUEntitlement reports whether a user may run code on a GPU. The server
+// backs it with a role lookup, keeping storage out of the handler.
+type GPUEntitlement func(ctx context.Context, userID string) (bool, error)
+
 // NewExecuteHandler creates a new ExecuteHandler.
 func NewExecuteHandler(exec executor.Executor, logger *slog.Logger) *ExecuteHandler {
 	return &ExecuteHandler{
@@ -22,31 +42,245 @@ func NewExecuteHandler(exec executor.Executor, logger *slog.Logger) *ExecuteHand
 	}
 }
 
+// PublishEvents makes the handler report execution.completed to p.
+func (h *ExecuteHandler) PublishEvents(p service.EventPublisher) {
+	h.events = p
+}
+
+// DetectAbuse makes the handler check code with d before running it.
+func (h *ExecuteHandler) DetectAbuse(d *abuse.Detector) {
+	h.abuse = d
+}
+
+// SaveArtifacts lets runs keep the files they write, storing them with a.
+// Without it, {"artifacts": true} is ignored.
+func (h *ExecuteHandler) SaveArtifacts(a *service.ArtifactService) {
+	h.artifacts = a
+}
+
+// AllowPackages lets runs install the packages in allow (see
+// executor.ExecutionRequest.Requirements). Without it they can install
+// none.
+func (h *ExecuteHandler) AllowPackages(allow executor.PackageAllowlist) {
+	h.packages = allow
+}
+
+// UseGPUs runs code that asks for a GPU ({"gpu": true}) on exec, for the
+// signed-in users entitled says may. Without it, such runs are refused.
+func (h *ExecuteHandler) UseGPUs(exec executor.Executor, entitled GPUEntitlement) {
+	h.gpu = exec
+	h.gpuAccess = entitled
+}
+
+// RecordRuns makes the handler record signed-in users' runs with runs, so
+// they can be replayed, and serve POST /runs/{id}/replay.
+func (h *ExecuteHandler) RecordRuns(runs *service.RunService) {
+	h.runs = runs
+}
+
+// RunInBackground makes the handler serve /executions, running code in the
+// background with e.
+func (h *ExecuteHandler) RunInBackground(e *service.ExecutionService) {
+	h.executions = e
+}
+
 // HandleExecute processes an incoming Python code execution request.
 func (h *ExecuteHandler) HandleExecute(w http.ResponseWriter, r *http.Request) {
 	var req executor.ExecutionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid execution request body", slog.String("error", err.Error()))
-		http.Error(w, "invalid request configuration", http.StatusBadRequest)
+	if err := decodeBody(r, &req); err != nil {
+		h.logger.WarnContext(r.Context(), "invalid execution request body", slog.String("error", err.Error()))
+		writeDecodeError(w, r, err)
 		return
 	}
 
 	if req.Code == "" {
-		http.Error(w, "code cannot be empty", http.StatusBadRequest)
+		writeError(w, r, apperror.ValidationFailed("code", "code cannot be empty"))
+		return
+	}
+	h.execute(w, r, req, "")
+}
+
+// HandleReplay runs one of the signed-in user's earlier runs again, linking
+// the new run to it (see service.RunService).
+func (h *ExecuteHandler) HandleReplay(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	req, err := h.runs.Replay(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.execute(w, r, req, id)
+}
+
+// execute runs req and writes the result. replayOf is the run req replays,
+// if any.
+func (h *ExecuteHandler) execute(w http.ResponseWriter, r *http.Request, req executor.ExecutionRequest, replayOf string) {
+	if !h.check(w, r, &req) {
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "executing python code snippet")
+
+	result, err := h.run(r.Context(), req, replayOf)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "code execution failed", slog.String("error", err.Error()))
+		// 503: every backend is down, for now; 400: too large to profile;
+		// anything else is a generic 500, the details left to the logs.
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, result)
+}
+
+// check vets req before it runs, writing the error if it mustn't.
+func (h *ExecuteHandler) check(w http.ResponseWriter, r *http.Request, req *executor.ExecutionRequest) bool {
+	if h.abuse != nil {
+		if err := h.abuse.Check(r.Context(), abuse.SourceExecute, req.Code); err != nil {
+			writeError(w, r, err)
+			return false
+		}
+	}
+	if _, err := h.packages.Check(req.Requirements); err != nil {
+		writeError(w, r, apperror.ValidationFailed("requirements", err.Error()))
+		return false
+	}
+	if req.GPU && !h.checkGPU(w, r) {
+		return false
+	}
+	if req.Profile != nil {
+		// 0, like leaving top out, asks for DefaultHotspots.
+		if req.Profile.Top < 0 || req.Profile.Top > executor.MaxHotspots {
+			writeError(w, r, apperror.ValidationFailed("profile.top", fmt.Sprintf("top must be between 1 and %d, or 0 for the default", executor.MaxHotspots)))
+			return false
+		}
+		// A profile comes with its raw pstats file, where files can be kept.
+		req.Artifacts = true
+	}
+	if h.artifacts == nil {
+		req.Artifacts = false
+	}
+	return true
+}
+
+// checkGPU makes sure the user may have a GPU, writing the error if not.
+// GPUs are scarce, so anonymous runs never get one.
+func (h *ExecuteHandler) checkGPU(w http.ResponseWriter, r *http.Request) bool {
+	if h.gpu == nil {
+		writeError(w, r, apperror.ValidationFailed("gpu", "this server has no GPUs"))
+		return false
+	}
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, &apperror.AppError{Err: apperror.ErrForbidden, Message: "sign in to run code on a GPU"})
+		return false
+	}
+	entitled, err := h.gpuAccess(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return false
+	}
+	if !entitled {
+		writeError(w, r, &apperror.AppError{Err: apperror.ErrForbidden, Message: "your account can't run code on a GPU"})
+		return false
+	}
+	return true
+}
+
+// run runs req and does everything that follows a run: saving artifacts,
+// recording the run, publishing execution.completed.
+func (h *ExecuteHandler) run(ctx context.Context, req executor.ExecutionRequest, replayOf string) (*executor.ExecutionResult, error) {
+	exec := h.exec
+	if req.GPU {
+		exec = h.gpu
+	}
+	var result *executor.ExecutionResult
+	var err error
+	if req.Profile != nil {
+		result, err = executor.RunProfiled(ctx, exec, req)
+	} else {
+		result, err = exec.Execute(ctx, req)
+	}
+	if errors.Is(err, executor.ErrProfileProgramTooLarge) {
+		return nil, apperror.ValidationFailed("code", "code is too large to profile").WithCode(apperror.CodeCodeTooLong)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if h.artifacts != nil {
+		if err := h.artifacts.Save(ctx, result); err != nil {
+			h.logger.ErrorContext(ctx, "saving artifacts failed", slog.String("error", err.Error()))
+		}
+	}
+	if h.abuse != nil {
+		h.abuse.Observe(ctx, req.Code, result.Duration)
+	}
+	if h.runs != nil {
+		h.runs.Record(ctx, req, result, replayOf)
+	}
+	if h.events != nil {
+		h.events.Publish(ctx, model.EventExecutionCompleted, result)
+	}
+	return result, nil
+}
+
+// HandleStart starts running code in the background and answers at once
+// with the execution to poll (see service.ExecutionService). The body is
+// /execute's.
+//
+// HTTP: POST /api/v1/executions
+func (h *ExecuteHandler) HandleStart(w http.ResponseWriter, r *http.Request) {
+	var req executor.ExecutionRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+	if req.Code == "" {
+		writeError(w, r, apperror.ValidationFailed("code", "code cannot be empty"))
+		return
+	}
+	if !h.check(w, r, &req) {
 		return
 	}
 
-	h.logger.Info("executing python code snippet")
+	h.logger.InfoContext(r.Context(), "executing python code snippet in the background")
+	execution := h.executions.Start(r.Context(), func(ctx context.Context) (*executor.ExecutionResult, error) {
+		return h.run(ctx, req, "")
+	})
+	w.Header().Set("Location", "/api/v1/executions/"+execution.ID)
+	writeJSON(w, r, http.StatusAccepted, execution)
+}
 
-	result, err := h.exec.Execute(r.Context(), req)
+// HandleGet returns a background execution. With ?wait=30s it long-polls:
+// it answers when the run ends or the wait is up, whichever is first.
+//
+// HTTP: GET /api/v1/executions/{id}?wait=30s
+func (h *ExecuteHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	var wait time.Duration
+	if v := r.URL.Query().Get("wait"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			writeError(w, r, apperror.ValidationFailed("wait", "wait must be a duration such as 30s"))
+			return
+		}
+		wait = d
+	}
+	execution, err := h.executions.Get(r.Context(), r.PathValue("id"), wait)
 	if err != nil {
-		h.logger.Error("code execution failed", slog.String("error", err.Error()))
-		http.Error(w, "internal server error during execution", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
+	writeJSON(w, r, http.StatusOK, execution)
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(result); err != nil {
-		h.logger.Error("failed to encode execution result", slog.String("error", err.Error()))
+// HandleCancel stops a background execution.
+//
+// HTTP: DELETE /api/v1/executions/{id}
+func (h *ExecuteHandler) HandleCancel(w http.ResponseWriter, r *http.Request) {
+	if err := h.executions.Cancel(r.Context(), r.PathValue("id")); err != nil {
+		writeError(w, r, err)
+		return
 	}
+	w.WriteHeader(http.StatusNoContent)
 }
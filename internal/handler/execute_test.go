@@ -4,16 +4,26 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/sakif/coding-playground/internal/abuse"
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/blob"
 	"github.com/sakif/coding-playground/internal/executor"
 	"github.com/sakif/coding-playground/internal/handler"
+	"github.com/sakif/coding-playground/internal/repository/sqlite"
+	"github.com/sakif/coding-playground/internal/service"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // MockExecutor implements a fast, mock executor for handler testing without Docker overhead.
@@ -88,5 +98,364 @@ func TestExecuteHandler_HandleExecute(t *testing.T) {
 		h.HandleExecute(rr, req)
 
 		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		var resp handler.ErrorResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.Equal(t, apperror.CodeValidation, resp.Code)
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, "code", resp.Errors[0].Field)
 	})
+
+	t.Run("body too large", func(t *testing.T) {
+		mockExec := &MockExecutor{}
+		h := handler.NewExecuteHandler(mockExec, logger)
+
+		reqBody := `{"code":"print('this body is longer than the limit')"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/execute", bytes.NewBufferString(reqBody))
+		rr := httptest.NewRecorder()
+		req.Body = http.MaxBytesReader(rr, req.Body, 16)
+
+		h.HandleExecute(rr, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Contains(t, rr.Body.String(), "request_too_large")
+	})
+
+	t.Run("abuse blocked", func(t *testing.T) {
+		mockExec := &MockExecutor{}
+		h := handler.NewExecuteHandler(mockExec, logger)
+		h.DetectAbuse(abuse.New(abuse.Options{}, logger))
+
+		reqBody := `{"code":"import os\nwhile True:\n    os.fork()"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/execute", bytes.NewBufferString(reqBody))
+		rr := httptest.NewRecorder()
+
+		h.HandleExecute(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Contains(t, rr.Body.String(), "fork")
+		assert.Empty(t, mockExec.CapturedReq.Code, "blocked code must not reach the executor")
+	})
+
+	t.Run("every backend down", func(t *testing.T) {
+		down := &MockExecutor{ReturnErr: errors.New("Cannot connect to the Docker daemon")}
+		h := handler.NewExecuteHandler(executor.NewChain(logger, executor.Backend{Name: "docker", Executor: down}), logger)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/execute", bytes.NewBufferString(`{"code":"print(1)"}`))
+		rr := httptest.NewRecorder()
+
+		h.HandleExecute(rr, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		assert.Equal(t, "30", rr.Header().Get("Retry-After"))
+		assert.Contains(t, rr.Body.String(), "code execution is temporarily unavailable")
+		assert.NotContains(t, rr.Body.String(), "Docker", "backend errors stay in the logs")
+	})
+
+	t.Run("executor fails", func(t *testing.T) {
+		broken := &MockExecutor{ReturnErr: errors.New("failed to create exec: no such container")}
+		h := handler.NewExecuteHandler(broken, logger)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/execute", bytes.NewBufferString(`{"code":"print(1)"}`))
+		rr := httptest.NewRecorder()
+
+		h.HandleExecute(rr, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		var resp handler.ErrorResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.Equal(t, apperror.CodeInternal, resp.Code)
+		assert.NotContains(t, rr.Body.String(), "no such container", "executor errors stay in the logs")
+	})
+}
+
+func TestExecuteHandler_Artifacts(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	store, err := blob.NewFS(t.TempDir())
+	assert.NoError(t, err)
+	artifacts := service.NewArtifactService(store, logger)
+
+	newResult := func() *executor.ExecutionResult {
+		return &executor.ExecutionResult{Artifacts: []executor.Artifact{
+			{Name: "plot.png", Size: 4, ContentType: "image/png", Data: []byte("\x89PNG")},
+			{Name: "page.html", Size: 15, ContentType: "text/html", Data: []byte("<script></script>")},
+		}}
+	}
+	run := func(h *handler.ExecuteHandler) executor.ExecutionResult {
+		req := httptest.NewRequest(http.MethodPost, "/api/execute", bytes.NewBufferString(`{"code":"savefig()","artifacts":true}`))
+		rr := httptest.NewRecorder()
+		h.HandleExecute(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var res executor.ExecutionResult
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&res))
+		return res
+	}
+
+	t.Run("without storage", func(t *testing.T) {
+		mockExec := &MockExecutor{ReturnRes: &executor.ExecutionResult{}}
+		run(handler.NewExecuteHandler(mockExec, logger))
+		assert.False(t, mockExec.CapturedReq.Artifacts, "artifacts requested with nowhere to keep them")
+	})
+
+	mockExec := &MockExecutor{ReturnRes: newResult()}
+	h := handler.NewExecuteHandler(mockExec, logger)
+	h.SaveArtifacts(artifacts)
+	res := run(h)
+	assert.True(t, mockExec.CapturedReq.Artifacts)
+	if !assert.Len(t, res.Artifacts, 2) {
+		return
+	}
+	assert.Regexp(t, `^/api/v1/artifacts/[0-9a-f]{32}/plot\.png$`, res.Artifacts[0].URL)
+
+	// Fetch them back through ArtifactHandler.
+	get := func(url string) *httptest.ResponseRecorder {
+		runID, name, _ := strings.Cut(strings.TrimPrefix(url, "/api/v1/artifacts/"), "/")
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.SetPathValue("run", runID)
+		req.SetPathValue("name", name)
+		rr := httptest.NewRecorder()
+		handler.NewArtifactHandler(artifacts, logger).HandleGet(rr, req)
+		return rr
+	}
+	rr := get(res.Artifacts[0].URL)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "\x89PNG", rr.Body.String())
+	assert.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "nosniff", rr.Header().Get("X-Content-Type-Options"))
+	assert.True(t, strings.HasPrefix(rr.Header().Get("Content-Disposition"), "inline"))
+
+	rr = get(res.Artifacts[1].URL)
+	assert.True(t, strings.HasPrefix(rr.Header().Get("Content-Disposition"), "attachment"), "HTML must download, not render")
+
+	assert.Equal(t, http.StatusNotFound, get("/api/v1/artifacts/0123/plot.png").Code)
+	assert.Equal(t, http.StatusNotFound, get("/api/v1/artifacts/../secret").Code)
+}
+
+func TestExecuteHandler_Replay(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	mockExec := &MockExecutor{ReturnRes: &executor.ExecutionResult{Stdout: "1\n"}}
+	h := handler.NewExecuteHandler(mockExec, logger)
+	h.RecordRuns(service.NewRunService(db, logger))
+	asAnn := auth.WithUserID(context.Background(), "ann-id")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/execute", strings.NewReader(`{"code":"print(1)"}`)).WithContext(asAnn)
+	rr := httptest.NewRecorder()
+	h.HandleExecute(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var first executor.ExecutionResult
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&first))
+	require.NotEmpty(t, first.RunID)
+
+	replay := func(ctx context.Context, id string) *httptest.ResponseRecorder {
+		mockExec.CapturedReq = executor.ExecutionRequest{}
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/runs/"+id+"/replay", nil).WithContext(ctx)
+		req.SetPathValue("id", id)
+		rr := httptest.NewRecorder()
+		h.HandleReplay(rr, req)
+		return rr
+	}
+
+	rr = replay(asAnn, first.RunID)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var again executor.ExecutionResult
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&again))
+	assert.Equal(t, "print(1)", mockExec.CapturedReq.Code)
+	assert.Equal(t, first.RunID, again.ReplayOf)
+	assert.NotEqual(t, first.RunID, again.RunID)
+
+	t.Run("someone else's run", func(t *testing.T) {
+		rr := replay(auth.WithUserID(context.Background(), "bob-id"), first.RunID)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Empty(t, mockExec.CapturedReq.Code, "bob ran ann's code")
+	})
+
+	t.Run("a run that doesn't exist", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, replay(asAnn, "nope").Code)
+	})
+
+	t.Run("requirements", func(t *testing.T) {
+		h.AllowPackages(executor.NewPackageAllowlist([]string{"numpy"}))
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/execute", strings.NewReader(`{"code":"import numpy","requirements":"numpy"}`)).WithContext(asAnn)
+		rr := httptest.NewRecorder()
+		h.HandleExecute(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		var run executor.ExecutionResult
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&run))
+
+		require.Equal(t, http.StatusOK, replay(asAnn, run.RunID).Code)
+		assert.Equal(t, "numpy", mockExec.CapturedReq.Requirements)
+
+		h.AllowPackages(nil)
+		assert.Equal(t, http.StatusBadRequest, replay(asAnn, run.RunID).Code, "numpy is no longer allowed")
+		assert.Empty(t, mockExec.CapturedReq.Code)
+	})
+
+	t.Run("a GPU run", func(t *testing.T) {
+		gpu := &MockExecutor{ReturnRes: &executor.ExecutionResult{Stdout: "gpu\n"}}
+		entitled := true
+		h.UseGPUs(gpu, func(context.Context, string) (bool, error) { return entitled, nil })
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/execute", strings.NewReader(`{"code":"import torch","gpu":true}`)).WithContext(asAnn)
+		rr := httptest.NewRecorder()
+		h.HandleExecute(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		var run executor.ExecutionResult
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&run))
+
+		gpu.CapturedReq = executor.ExecutionRequest{}
+		require.Equal(t, http.StatusOK, replay(asAnn, run.RunID).Code)
+		assert.True(t, gpu.CapturedReq.GPU, "the replay didn't run on the GPU executor")
+		assert.Empty(t, mockExec.CapturedReq.Code)
+
+		entitled = false
+		assert.Equal(t, http.StatusForbidden, replay(asAnn, run.RunID).Code, "GPU access was taken away")
+	})
+}
+
+func TestExecuteHandler_Background(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	mockExec := &MockExecutor{ReturnRes: &executor.ExecutionResult{Stdout: "1\n"}}
+	h := handler.NewExecuteHandler(mockExec, logger)
+	executions := service.NewExecutionService(logger)
+	h.RunInBackground(executions)
+	t.Cleanup(func() { executions.Shutdown(context.Background()) })
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/executions", strings.NewReader(`{"code":"print(1)"}`))
+	rr := httptest.NewRecorder()
+	h.HandleStart(rr, req)
+	require.Equal(t, http.StatusAccepted, rr.Code)
+	var started service.Execution
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&started))
+	require.NotEmpty(t, started.ID)
+	assert.Equal(t, "/api/v1/executions/"+started.ID, rr.Header().Get("Location"))
+
+	get := func(id, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/executions/"+id+query, nil)
+		req.SetPathValue("id", id)
+		rr := httptest.NewRecorder()
+		h.HandleGet(rr, req)
+		return rr
+	}
+
+	rr = get(started.ID, "?wait=5s")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var done service.Execution
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&done))
+	assert.Equal(t, service.ExecutionDone, done.Status)
+	require.NotNil(t, done.Result)
+	assert.Equal(t, "1\n", done.Result.Stdout)
+	assert.Equal(t, "print(1)", mockExec.CapturedReq.Code)
+
+	t.Run("a bad wait", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get(started.ID, "?wait=soon").Code)
+	})
+
+	t.Run("an execution that doesn't exist", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, get("nope", "").Code)
+	})
+
+	t.Run("cancelling", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/executions/"+started.ID, nil)
+		req.SetPathValue("id", started.ID)
+		rr := httptest.NewRecorder()
+		h.HandleCancel(rr, req)
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+	})
+
+	t.Run("empty code", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/executions", strings.NewReader(`{"code":""}`))
+		rr := httptest.NewRecorder()
+		h.HandleStart(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestExecuteHandler_Profile(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	post := func(h *handler.ExecuteHandler, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/execute", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		h.HandleExecute(rr, req)
+		return rr
+	}
+
+	mockExec := &MockExecutor{ReturnRes: &executor.ExecutionResult{Stdout: "1\n"}}
+	h := handler.NewExecuteHandler(mockExec, logger)
+	rr := post(h, `{"code":"print(1)","profile":{"top":5}}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, mockExec.CapturedReq.Code, "cProfile", "the code runs inside the profiling harness")
+	assert.Contains(t, mockExec.CapturedReq.Code, "TOP = 5")
+	assert.False(t, mockExec.CapturedReq.Artifacts, "artifacts requested with nowhere to keep them")
+
+	for _, body := range []string{`{"code":"print(1)","profile":{"top":-1}}`, `{"code":"print(1)","profile":{"top":101}}`} {
+		rr := post(h, body)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+		assert.Contains(t, rr.Body.String(), "or 0 for the default", body)
+	}
+
+	rr = post(h, `{"code":"print(1)","profile":{"top":0}}`)
+	assert.Equal(t, http.StatusOK, rr.Code, "0 asks for the default")
+	assert.Contains(t, mockExec.CapturedReq.Code, "TOP = 20")
+
+	// With somewhere to keep it, the raw profile comes back too.
+	store, err := blob.NewFS(t.TempDir())
+	require.NoError(t, err)
+	h.SaveArtifacts(service.NewArtifactService(store, logger))
+	post(h, `{"code":"print(1)","profile":{}}`)
+	assert.True(t, mockExec.CapturedReq.Artifacts)
+	assert.Contains(t, mockExec.CapturedReq.Code, "TOP = 20")
+}
+
+func TestExecuteHandler_Requirements(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	mockExec := &MockExecutor{ReturnRes: &executor.ExecutionResult{}}
+	h := handler.NewExecuteHandler(mockExec, logger)
+	post := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/execute", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		h.HandleExecute(rr, req)
+		return rr.Code
+	}
+
+	const body = `{"code":"import numpy","requirements":"numpy==1.26.4\n"}`
+	assert.Equal(t, http.StatusBadRequest, post(body), "no packages are allowed by default")
+
+	h.AllowPackages(executor.NewPackageAllowlist([]string{"numpy"}))
+	assert.Equal(t, http.StatusOK, post(body))
+	assert.Equal(t, "numpy==1.26.4\n", mockExec.CapturedReq.Requirements)
+	assert.Equal(t, http.StatusBadRequest, post(`{"code":"import requests","requirements":"requests"}`))
+}
+
+func TestExecuteHandler_GPU(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	cpu := &MockExecutor{ReturnRes: &executor.ExecutionResult{Stdout: "cpu\n"}}
+	gpu := &MockExecutor{ReturnRes: &executor.ExecutionResult{Stdout: "gpu\n"}}
+	h := handler.NewExecuteHandler(cpu, logger)
+	post := func(ctx context.Context, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/execute", bytes.NewBufferString(body)).WithContext(ctx)
+		rr := httptest.NewRecorder()
+		h.HandleExecute(rr, req)
+		return rr
+	}
+	const body = `{"code":"import torch","gpu":true}`
+	asAnn := auth.WithUserID(context.Background(), "ann-id")
+
+	assert.Equal(t, http.StatusBadRequest, post(asAnn, body).Code, "no GPU executor")
+
+	h.UseGPUs(gpu, func(_ context.Context, userID string) (bool, error) {
+		return userID == "ann-id", nil
+	})
+	assert.Equal(t, http.StatusForbidden, post(context.Background(), body).Code, "anonymous")
+	assert.Equal(t, http.StatusForbidden, post(auth.WithUserID(context.Background(), "bob-id"), body).Code, "not entitled")
+
+	rr := post(asAnn, body)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "gpu")
+	assert.True(t, gpu.CapturedReq.GPU)
+
+	rr = post(asAnn, `{"code":"print(1)"}`)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "cpu", "runs that don't ask stay on the CPU executor")
 }
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// ExerciseHandler serves exercises. Reading is open to everyone; writing
+// needs a signed-in user with the author role (checked by the service).
+//
+// HIDDEN TESTS:
+// model.Exercise leaves TestCode out of its JSON, so what learners get never
+// includes the tests. Responses for someone who may edit the exercise wrap it
+// in AuthorExercise, which adds them back.
+type ExerciseHandler struct {
+	service *service.ExerciseService
+	logger  *slog.Logger
+}
+
+// NewExerciseHandler creates a new ExerciseHandler.
+func NewExerciseHandler(svc *service.ExerciseService, logger *slog.Logger) *ExerciseHandler {
+	return &ExerciseHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// ExerciseRequest is the expected JSON body for creating or updating an exercise.
+type ExerciseRequest struct {
+	Title       string   `json:"title"`
+	Prompt      string   `json:"prompt"`
+	StarterCode string   `json:"starterCode"`
+	TestCode    string   `json:"testCode"`
+	Hints       []string `json:"hints"`
+	HintPenalty int      `json:"hintPenalty"`
+	Coverage    bool     `json:"coverage"`
+}
+
+func (req ExerciseRequest) input() service.ExerciseInput {
+	return service.ExerciseInput{
+		Title:       req.Title,
+		Prompt:      req.Prompt,
+		StarterCode: req.StarterCode,
+		TestCode:    req.TestCode,
+		Hints:       req.Hints,
+		HintPenalty: req.HintPenalty,
+		Coverage:    req.Coverage,
+	}
+}
+
+// AuthorExercise is an exercise as its author sees it: hidden tests and
+// every hint included.
+type AuthorExercise struct {
+	*model.Exercise
+	TestCode string   `json:"testCode"`
+	Hints    []string `json:"hints"`
+}
+
+func authorExercise(exercise *model.Exercise) AuthorExercise {
+	hints := exercise.Hints
+	if hints == nil {
+		hints = []string{}
+	}
+	return AuthorExercise{Exercise: exercise, TestCode: exercise.TestCode, Hints: hints}
+}
+
+// ExerciseListResponse is the envelope for GET /exercises, shaped like
+// SnippetListResponse.
+type ExerciseListResponse struct {
+	Items   []model.Exercise `json:"items"`
+	Total   int              `json:"total"`
+	Limit   int              `json:"limit"`
+	Offset  int              `json:"offset"`
+	HasMore bool             `json:"hasMore"`
+}
+
+// HandleList returns a page of exercises, without their hidden tests.
+//
+// HTTP: GET /api/v1/exercises?limit=20&offset=0
+func (h *ExerciseHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	page, err := h.service.List(r.Context(), limit, offset)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, ExerciseListResponse{
+		Items:   page.Items,
+		Total:   page.Total,
+		Limit:   page.Limit,
+		Offset:  page.Offset,
+		HasMore: page.HasMore(),
+	})
+}
+
+// HandleGet returns one exercise. Its author and admins also get the hidden
+// tests and hints; everyone else gets the learner's view.
+//
+// HTTP: GET /api/v1/exercises/{id}
+func (h *ExerciseHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	exercise, err := h.service.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// The body depends on who's asking, so no shared cache may reuse it.
+	w.Header().Set("Cache-Control", "private, no-cache")
+	userID, _ := auth.UserIDFromContext(r.Context())
+	if h.service.CanEdit(r.Context(), userID, exercise) {
+		writeJSON(w, r, http.StatusOK, authorExercise(exercise))
+		return
+	}
+	writeJSON(w, r, http.StatusOK, exercise)
+}
+
+// HandleCreate saves a new exercise.
+//
+// HTTP: POST /api/v1/exercises
+// Request body: {"title": "...", "prompt": "...", "starterCode": "...", "testCode": "def test_x(): ...", "hints": ["..."], "hintPenalty": 10}
+func (h *ExerciseHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req ExerciseRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	exercise, err := h.service.Create(r.Context(), userID, req.input())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, authorExercise(exercise))
+}
+
+// HandleUpdate replaces an exercise's content.
+//
+// HTTP: PUT /api/v1/exercises/{id}
+// Request body: same as HandleCreate
+func (h *ExerciseHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req ExerciseRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	exercise, err := h.service.Update(r.Context(), userID, r.PathValue("id"), req.input())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, authorExercise(exercise))
+}
+
+// HandleDelete removes an exercise.
+//
+// HTTP: DELETE /api/v1/exercises/{id}
+func (h *ExerciseHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), userID, r.PathValue("id")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
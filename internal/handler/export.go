@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"log/slog"
+	"mime"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// ExportHandler serves snippet exports as .tar.gz downloads.
+type ExportHandler struct {
+	service *service.ExportService
+	logger  *slog.Logger
+}
+
+// NewExportHandler creates a new ExportHandler.
+func NewExportHandler(svc *service.ExportService, logger *slog.Logger) *ExportHandler {
+	return &ExportHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// HandleUser downloads the caller's personal snippets.
+//
+// HTTP: GET /api/v1/me/export
+func (h *ExportHandler) HandleUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	export, err := h.service.ForUser(r.Context(), userID)
+	h.stream(w, r, export, err)
+}
+
+// HandleOrg downloads an org's snippets. Members only.
+//
+// HTTP: GET /api/v1/orgs/{id}/export
+func (h *ExportHandler) HandleOrg(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	export, err := h.service.ForOrg(r.Context(), userID, r.PathValue("id"))
+	h.stream(w, r, export, err)
+}
+
+// stream sends the archive. Once it has started the status is already
+// 200, so a failure part way can only be logged (Stream does that) and the
+// response cut short.
+func (h *ExportHandler) stream(w http.ResponseWriter, r *http.Request, export *service.Export, err error) {
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": export.Filename}))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	export.Stream(r.Context(), w)
+}
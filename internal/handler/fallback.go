@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/middleware"
+)
+
+// FALLBACK HANDLERS:
+// When no route matches, chi answers with plain text ("404 page not found")
+// or an empty 405. API clients then get a body they can't parse as JSON.
+// These replacements return the standard ErrorResponse instead.
+//
+// They're logged at debug level only: scanners and typos hit unknown paths
+// all day, and the access log already records every request.
+
+// NotFoundHandler answers requests for unknown paths with a 404 JSON error.
+func NotFoundHandler(logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), "no route matched",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+		)
+		writeJSON(w, r, http.StatusNotFound, ErrorResponse{
+			Error:     "not_found",
+			Code:      apperror.CodeRouteNotFound,
+			Message:   fmt.Sprintf("no such endpoint: %s", r.URL.Path),
+			RequestID: middleware.RequestID(r.Context()),
+		})
+	}
+}
+
+// allowCandidates are the methods checked when building the Allow header.
+var allowCandidates = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// MethodNotAllowedHandler answers requests whose path exists but not for the
+// method used. The Allow header lists the methods that would work — chi's
+// default handler sets it, but a custom one has to work it out from routes.
+func MethodNotAllowedHandler(routes chi.Routes, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+		for _, m := range allowCandidates {
+			if routes.Match(chi.NewRouteContext(), m, r.URL.Path) {
+				allowed = append(allowed, m)
+			}
+		}
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+		}
+
+		logger.DebugContext(r.Context(), "method not allowed",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+		)
+		writeJSON(w, r, http.StatusMethodNotAllowed, ErrorResponse{
+			Error:     "method_not_allowed",
+			Code:      apperror.CodeMethodNotAllowed,
+			Message:   fmt.Sprintf("%s is not allowed on %s", r.Method, r.URL.Path),
+			RequestID: middleware.RequestID(r.Context()),
+		})
+	}
+}
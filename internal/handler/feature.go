@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/feature"
+)
+
+// FeatureHandler exposes the admin API for listing and toggling feature flags.
+type FeatureHandler struct {
+	flags  *feature.Flags
+	logger *slog.Logger
+}
+
+// NewFeatureHandler creates a new FeatureHandler.
+func NewFeatureHandler(flags *feature.Flags, logger *slog.Logger) *FeatureHandler {
+	return &FeatureHandler{
+		flags:  flags,
+		logger: logger,
+	}
+}
+
+// HandleList returns every flag with its current value and where it came from.
+//
+// HTTP: GET /api/v1/admin/features
+func (h *FeatureHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, h.flags.All())
+}
+
+// setFeatureRequest is the body of PUT /api/v1/admin/features/{name}.
+// Enabled is a pointer so a missing field is a validation error rather than
+// silently meaning "false".
+type setFeatureRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// HandleSet turns a flag on or off. The change takes effect immediately and
+// is persisted, so it survives restarts.
+//
+// HTTP: PUT /api/v1/admin/features/{name}
+func (h *FeatureHandler) HandleSet(w http.ResponseWriter, r *http.Request) {
+	name := feature.Flag(r.PathValue("name"))
+	if !h.flags.Known(name) {
+		writeError(w, r, apperror.NotFound("feature flag", string(name)))
+		return
+	}
+
+	var req setFeatureRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+	if req.Enabled == nil {
+		writeError(w, r, apperror.ValidationFailed("enabled", "is required"))
+		return
+	}
+
+	state, err := h.flags.Set(r.Context(), name, *req.Enabled)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to set feature flag",
+			slog.String("flag", string(name)),
+			slog.String("error", err.Error()),
+		)
+		writeError(w, r, err)
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "feature flag changed",
+		slog.String("flag", string(name)),
+		slog.Bool("enabled", state.Enabled),
+	)
+	writeJSON(w, r, http.StatusOK, state)
+}
@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// ATOM FEEDS:
+// Feed readers and aggregators follow sites through Atom (or RSS) feeds: an
+// XML document listing recent entries that the reader polls every so often.
+//
+//	GET /feed.atom                 → recently published snippets from everyone
+//	GET /users/{login}/feed.atom   → recently published snippets from one user
+//
+// Only snippets their owner made public (PUT /api/v1/snippets/{id}/visibility)
+// appear. Each entry links to the playground with the snippet open
+// (/?snippet=<id>).
+//
+// CACHING:
+// Readers poll, usually far more often than anything changes. The feed's
+// ETag and Last-Modified come from its newest entry, so a poll with
+// If-None-Match gets an empty 304, and Cache-Control lets proxies and readers
+// reuse a copy for a few minutes without asking at all.
+
+// feedMaxAge is how long clients and proxies may reuse a feed without revalidating.
+const feedMaxAge = 5 * time.Minute
+
+// FeedHandler serves the Atom feeds of public snippets.
+type FeedHandler struct {
+	snippets *service.SnippetService
+	users    *service.UserService
+	logger   *slog.Logger
+}
+
+// NewFeedHandler creates a new FeedHandler.
+func NewFeedHandler(snippets *service.SnippetService, users *service.UserService, logger *slog.Logger) *FeedHandler {
+	return &FeedHandler{
+		snippets: snippets,
+		users:    users,
+		logger:   logger,
+	}
+}
+
+// atomFeed and friends mirror the Atom (RFC 4287) elements we use.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Link      atomLink   `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Author    atomAuthor `xml:"author"`
+	Summary   string     `xml:"summary,omitempty"`
+	Content   atomText   `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// HandleFeed serves the feed of everyone's public snippets.
+//
+// HTTP: GET /feed.atom
+func (h *FeedHandler) HandleFeed(w http.ResponseWriter, r *http.Request) {
+	snippets, err := h.snippets.Feed(r.Context(), "")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.writeFeed(w, r, "PyPlayground — public snippets", snippets)
+}
+
+// HandleUserFeed serves the feed of one user's public snippets.
+//
+// HTTP: GET /users/{login}/feed.atom
+func (h *FeedHandler) HandleUserFeed(w http.ResponseWriter, r *http.Request) {
+	user, err := h.users.GetByLogin(r.Context(), r.PathValue("login"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	snippets, err := h.snippets.Feed(r.Context(), user.ID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.writeFeed(w, r, fmt.Sprintf("PyPlayground — snippets by %s", user.Login), snippets)
+}
+
+// writeFeed renders snippets (newest first) as an Atom feed.
+func (h *FeedHandler) writeFeed(w http.ResponseWriter, r *http.Request, title string, snippets []model.Snippet) {
+	// The newest change to any entry versions the whole feed.
+	var lastModified time.Time
+	for _, s := range snippets {
+		if s.UpdatedAt.After(lastModified) {
+			lastModified = s.UpdatedAt
+		}
+	}
+	etag := fmt.Sprintf(`W/"feed-%d-%x"`, len(snippets), lastModified.UnixNano())
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(feedMaxAge.Seconds())))
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if notModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	base := baseURL(r)
+	self := base + r.URL.Path
+	feed := atomFeed{
+		ID:      self,
+		Title:   title,
+		Updated: lastModified.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: self},
+			{Rel: "alternate", Type: "text/html", Href: base + "/"},
+		},
+		Entries: make([]atomEntry, 0, len(snippets)),
+	}
+
+	logins := make(map[string]string) // user ID → login, looked up once per feed
+	for _, s := range snippets {
+		page := base + "/?snippet=" + url.QueryEscape(s.ID)
+		published := s.CreatedAt
+		if s.PublishedAt != nil {
+			published = *s.PublishedAt
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        page,
+			Title:     s.Name,
+			Link:      atomLink{Rel: "alternate", Type: "text/html", Href: page},
+			Published: published.UTC().Format(time.RFC3339),
+			Updated:   s.UpdatedAt.UTC().Format(time.RFC3339),
+			Author:    atomAuthor{Name: h.login(r, logins, s.UserID)},
+			Summary:   s.Description,
+			Content:   atomText{Type: "text", Body: s.Code},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(xml.Header))
+	if err := xml.NewEncoder(w).Encode(feed); err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to encode feed", slog.String("error", err.Error()))
+	}
+}
+
+// login returns the GitHub login of userID, caching lookups in logins.
+func (h *FeedHandler) login(r *http.Request, logins map[string]string, userID string) string {
+	if login, ok := logins[userID]; ok {
+		return login
+	}
+	login := "unknown"
+	user, err := h.users.GetByID(r.Context(), userID)
+	switch {
+	case err == nil:
+		login = user.Login
+	case !errors.Is(err, apperror.ErrNotFound):
+		h.logger.WarnContext(r.Context(), "feed author lookup failed",
+			slog.String("user_id", userID),
+			slog.String("error", err.Error()),
+		)
+	}
+	logins[userID] = login
+	return login
+}
+
+// baseURL returns the scheme and host the client used to reach us, so feed
+// links are absolute (Atom requires it). Behind a TLS-terminating proxy the
+// scheme comes from X-Forwarded-Proto.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
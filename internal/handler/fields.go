@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+)
+
+// SPARSE FIELDSETS:
+// ?fields=id,name,updatedAt asks for only those fields of each snippet:
+//
+//	GET /api/v1/snippets?fields=id,name
+//	→ {"items": [{"id": "abc", "name": "hello"}, ...], "total": ...}
+//
+// A sidebar listing 100 snippets only needs their names; without this it
+// would download 100 code bodies it never shows. When "code" isn't asked for,
+// the list isn't even loaded from the database (repository.ListOptions.OmitCode).
+//
+// Without ?fields=, responses are unchanged.
+
+// snippetFields maps each selectable JSON field to its value.
+var snippetFields = map[string]func(s *model.Snippet) any{
+	"id":          func(s *model.Snippet) any { return s.ID },
+	"name":        func(s *model.Snippet) any { return s.Name },
+	"code":        func(s *model.Snippet) any { return s.Code },
+	"description": func(s *model.Snippet) any { return s.Description },
+	"userId":      func(s *model.Snippet) any { return s.UserID },
+	"public":      func(s *model.Snippet) any { return s.Public },
+	"publishedAt": func(s *model.Snippet) any { return s.PublishedAt },
+	"createdAt":   func(s *model.Snippet) any { return s.CreatedAt },
+	"updatedAt":   func(s *model.Snippet) any { return s.UpdatedAt },
+	"author":      func(s *model.Snippet) any { return s.Author },
+}
+
+// parseFields reads ?fields=. It returns nil when every field is wanted.
+func parseFields(q url.Values) ([]string, error) {
+	raw := q.Get("fields")
+	if raw == "" {
+		return nil, nil
+	}
+
+	var fields []string
+	for _, f := range strings.Split(raw, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" || slices.Contains(fields, f) {
+			continue
+		}
+		if _, ok := snippetFields[f]; !ok {
+			return nil, apperror.ValidationFailed("fields", fmt.Sprintf("unknown field %q", f))
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// wantsField reports whether fields (as returned by parseFields) includes name.
+func wantsField(fields []string, name string) bool {
+	return fields == nil || slices.Contains(fields, name)
+}
+
+// projectSnippet returns only the requested fields of s.
+func projectSnippet(s *model.Snippet, fields []string) map[string]any {
+	out := make(map[string]any, len(fields))
+	for _, f := range fields {
+		out[f] = snippetFields[f](s)
+	}
+	return out
+}
+
+// projectSnippets applies projectSnippet to a list. With no fields it returns
+// the snippets unchanged.
+func projectSnippets(snippets []model.Snippet, fields []string) any {
+	if fields == nil {
+		return snippets
+	}
+	out := make([]map[string]any, len(snippets))
+	for i := range snippets {
+		out[i] = projectSnippet(&snippets[i], fields)
+	}
+	return out
+}
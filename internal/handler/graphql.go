@@ -0,0 +1,495 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/errreport"
+	"github.com/sakif/coding-playground/internal/graphql"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// GRAPHQL:
+// The REST API returns fixed shapes, so a page showing a user, their snippets
+// and each snippet's author takes several round trips. POST /api/graphql
+// lets the frontend ask for exactly that in one request:
+//
+//	{
+//	  user(login: "octocat") {
+//	    login avatarUrl
+//	    snippets(first: 10) {
+//	      totalCount
+//	      edges { cursor node { id name updatedAt } }
+//	      pageInfo { hasNextPage endCursor }
+//	    }
+//	  }
+//	}
+//
+// The schema is read-only and sits on the same services as the REST routes,
+// so validation, ownership and error handling are shared.
+//
+// CURSOR PAGINATION:
+// Lists are "connections": each item comes with an opaque cursor, and passing
+// the last cursor back as after: fetches the next page. Clients must not
+// parse cursors — today they encode an offset, but that may change.
+//
+// FIELD-LEVEL AUTH:
+// Anyone may query public profile fields, but User.email, User.role and
+// User.runs are only visible to that user and to admins. For everyone else
+// those fields are null with a FORBIDDEN error, and the rest of the query
+// still works. Stars are public, both ways: who starred a snippet
+// (Snippet.stars) and what a user starred (User.stars).
+//
+// The request body is {"query": "...", "variables": {...}, "operationName": "..."};
+// GET with ?query= works too. Responses are always JSON.
+
+// graphQLDefaultFirst is the page size when a connection's first: is omitted.
+const graphQLDefaultFirst = 20
+
+// GraphQLHandler serves the GraphQL endpoint.
+type GraphQLHandler struct {
+	schema   *graphql.Schema
+	snippets *service.SnippetService
+	users    *service.UserService
+	runs     *service.RunService  // optional; see AddRunsAndStars
+	stars    *service.StarService // optional; see AddRunsAndStars
+	logger   *slog.Logger
+}
+
+// NewGraphQLHandler creates a new GraphQLHandler.
+func NewGraphQLHandler(snippets *service.SnippetService, users *service.UserService, logger *slog.Logger) *GraphQLHandler {
+	h := &GraphQLHandler{
+		snippets: snippets,
+		users:    users,
+		logger:   logger,
+	}
+	h.schema = h.buildSchema()
+	return h
+}
+
+// AddRunsAndStars adds User.runs, User.stars and Snippet.stars to the
+// schema. Without it, as when auth is disabled, those fields don't exist.
+// Call it before serving requests.
+func (h *GraphQLHandler) AddRunsAndStars(runs *service.RunService, stars *service.StarService) {
+	h.runs, h.stars = runs, stars
+	h.schema = h.buildSchema()
+}
+
+// HandleQuery executes a GraphQL query.
+//
+// HTTP: POST /api/v1/graphql (or GET /api/v1/graphql?query=...)
+// Request body: {"query": "{ viewer { login } }", "variables": {}}
+func (h *GraphQLHandler) HandleQuery(w http.ResponseWriter, r *http.Request) {
+	var req graphql.Request
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				writeGraphQL(w, http.StatusBadRequest, &graphql.Response{Errors: []*graphql.Error{{Message: "variables must be a JSON object"}}})
+				return
+			}
+		}
+	} else if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		writeGraphQL(w, http.StatusBadRequest, &graphql.Response{Errors: []*graphql.Error{{Message: "query is required"}}})
+		return
+	}
+
+	// Each request gets its own copy of the schema so errors can be reported
+	// against it, and its own user cache.
+	schema := *h.schema
+	schema.PresentError = func(ctx context.Context, err error) *graphql.Error {
+		return h.presentError(ctx, r, err)
+	}
+	ctx := context.WithValue(r.Context(), userCacheKey{}, map[string]*model.User{})
+
+	resp := schema.Execute(ctx, req)
+
+	// A request that couldn't run at all (bad syntax, unknown field) is the
+	// client's fault; one that ran, even with field errors, succeeded.
+	status := http.StatusOK
+	if resp.Data == nil {
+		status = http.StatusBadRequest
+	}
+	writeGraphQL(w, status, resp)
+}
+
+// writeGraphQL sends a GraphQL response. Unlike writeJSON it doesn't offer
+// MessagePack: GraphQL clients expect JSON.
+func writeGraphQL(w http.ResponseWriter, status int, resp *graphql.Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("failed to encode GraphQL response", slog.String("error", err.Error()))
+	}
+}
+
+// presentError turns a service error into a GraphQL error with a machine-
+// readable extensions.code, following the same mapping as writeError, and
+// the REST API's specific code (apperror.CodeOf) as extensions.errorCode.
+// Unexpected errors are reported and hidden behind a generic message.
+func (h *GraphQLHandler) presentError(ctx context.Context, r *http.Request, err error) *graphql.Error {
+	var appErr *apperror.AppError
+	if errors.As(err, &appErr) {
+		code := ""
+		switch {
+		case errors.Is(err, apperror.ErrValidation):
+			code = "BAD_USER_INPUT"
+		case errors.Is(err, apperror.ErrForbidden):
+			code = "FORBIDDEN"
+		case errors.Is(err, apperror.ErrNotFound):
+			code = "NOT_FOUND"
+		}
+		if code != "" {
+			return &graphql.Error{Message: appErr.Message, Extensions: map[string]any{"code": code, "errorCode": apperror.CodeOf(err)}}
+		}
+	}
+
+	h.logger.ErrorContext(ctx, "graphql resolver failed", slog.String("error", err.Error()))
+	errreport.FromContext(ctx).Report(ctx, err, r)
+	return &graphql.Error{
+		Message:    "An internal error occurred",
+		Extensions: map[string]any{"code": "INTERNAL_SERVER_ERROR"},
+	}
+}
+
+// === Schema ===
+
+// connection is one page of a list, as a connection: its items, and where
+// they sit in the whole list.
+type connection struct {
+	items  []any
+	total  int
+	offset int
+}
+
+// edge is an item and its cursor.
+type edge struct {
+	cursor string
+	node   any
+}
+
+// newConnection makes a connection from one of the services' pages.
+func newConnection[T any](items []T, total, offset int) *connection {
+	conn := &connection{items: make([]any, len(items)), total: total, offset: offset}
+	for i := range items {
+		conn.items[i] = &items[i]
+	}
+	return conn
+}
+
+// pageArgs reads a connection field's first: and after: arguments as a
+// limit and an offset.
+func pageArgs(p graphql.ResolveParams) (first, offset int, err error) {
+	first, err = p.Int("first")
+	if err != nil {
+		return 0, 0, err
+	}
+	if first < 1 || first > service.MaxListLimit {
+		return 0, 0, apperror.ValidationFailed("first", "first must be between 1 and "+strconv.Itoa(service.MaxListLimit))
+	}
+	after, err := p.String("after")
+	if err != nil {
+		return 0, 0, err
+	}
+	if after != "" {
+		n, err := decodeCursor(after)
+		if err != nil {
+			return 0, 0, err
+		}
+		offset = n + 1
+	}
+	return first, offset, nil
+}
+
+func (h *GraphQLHandler) buildSchema() *graphql.Schema {
+	snippets := h.snippets
+	userType := &graphql.Object{Name: "User"}
+	snippetType := &graphql.Object{Name: "Snippet"}
+	runType := &graphql.Object{Name: "Run"}
+	starType := &graphql.Object{Name: "Star"}
+	pageInfoType := &graphql.Object{Name: "PageInfo"}
+
+	scalar := func(get func(p graphql.ResolveParams) any) *graphql.Field {
+		return &graphql.Field{Resolve: func(p graphql.ResolveParams) (any, error) { return get(p), nil }}
+	}
+	snippetOf := func(p graphql.ResolveParams) *model.Snippet { return p.Source.(*model.Snippet) }
+	userOf := func(p graphql.ResolveParams) *model.User { return p.Source.(*model.User) }
+	runOf := func(p graphql.ResolveParams) *model.Run { return p.Source.(*model.Run) }
+	starOf := func(p graphql.ResolveParams) *model.Star { return p.Source.(*model.Star) }
+	connOf := func(p graphql.ResolveParams) *connection { return p.Source.(*connection) }
+
+	// connectionOf makes the NodeConnection and NodeEdge types for a list
+	// of node; every list in the schema pages the same way.
+	connectionArgs := map[string]any{"first": graphQLDefaultFirst, "after": nil}
+	connectionOf := func(node *graphql.Object) *graphql.Object {
+		edgeType := &graphql.Object{Name: node.Name + "Edge", Fields: map[string]*graphql.Field{
+			"cursor": scalar(func(p graphql.ResolveParams) any { return p.Source.(*edge).cursor }),
+			"node": {Type: node, Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(*edge).node, nil
+			}},
+		}}
+		return &graphql.Object{Name: node.Name + "Connection", Fields: map[string]*graphql.Field{
+			"totalCount": scalar(func(p graphql.ResolveParams) any { return connOf(p).total }),
+			"nodes": {Type: node, List: true, Resolve: func(p graphql.ResolveParams) (any, error) {
+				return connOf(p).items, nil
+			}},
+			"edges": {Type: edgeType, List: true, Resolve: func(p graphql.ResolveParams) (any, error) {
+				conn := connOf(p)
+				edges := make([]any, len(conn.items))
+				for i, item := range conn.items {
+					edges[i] = &edge{cursor: encodeCursor(conn.offset + i), node: item}
+				}
+				return edges, nil
+			}},
+			"pageInfo": {Type: pageInfoType, Resolve: func(p graphql.ResolveParams) (any, error) {
+				return connOf(p), nil
+			}},
+		}}
+	}
+	snippetConnectionType := connectionOf(snippetType)
+
+	// listSnippets resolves a snippet connection field, optionally for one owner.
+	listSnippets := func(p graphql.ResolveParams, filter service.ListFilter) (any, error) {
+		first, offset, err := pageArgs(p)
+		if err != nil {
+			return nil, err
+		}
+		page, err := snippets.List(p.Context, first, offset, filter)
+		if err != nil {
+			return nil, err
+		}
+		return newConnection(page.Items, page.Total, page.Offset), nil
+	}
+
+	snippetType.Fields = map[string]*graphql.Field{
+		"id":          scalar(func(p graphql.ResolveParams) any { return snippetOf(p).ID }),
+		"name":        scalar(func(p graphql.ResolveParams) any { return snippetOf(p).Name }),
+		"description": scalar(func(p graphql.ResolveParams) any { return snippetOf(p).Description }),
+		"code":        scalar(func(p graphql.ResolveParams) any { return snippetOf(p).Code }),
+		"public":      scalar(func(p graphql.ResolveParams) any { return snippetOf(p).Public }),
+		"publishedAt": scalar(func(p graphql.ResolveParams) any { return snippetOf(p).PublishedAt }),
+		"createdAt":   scalar(func(p graphql.ResolveParams) any { return snippetOf(p).CreatedAt }),
+		"updatedAt":   scalar(func(p graphql.ResolveParams) any { return snippetOf(p).UpdatedAt }),
+		"author": {Type: userType, Resolve: func(p graphql.ResolveParams) (any, error) {
+			return h.optionalUser(p.Context, snippetOf(p).UserID)
+		}},
+	}
+
+	// private guards a field only its owner and admins may see.
+	private := func(get func(u *model.User) any) *graphql.Field {
+		return &graphql.Field{Resolve: func(p graphql.ResolveParams) (any, error) {
+			user := userOf(p)
+			if err := h.canSeePrivate(p.Context, user); err != nil {
+				return nil, err
+			}
+			return get(user), nil
+		}}
+	}
+	userType.Fields = map[string]*graphql.Field{
+		"id":        scalar(func(p graphql.ResolveParams) any { return userOf(p).ID }),
+		"login":     scalar(func(p graphql.ResolveParams) any { return userOf(p).Login }),
+		"avatarUrl": scalar(func(p graphql.ResolveParams) any { return userOf(p).AvatarURL }),
+		"createdAt": scalar(func(p graphql.ResolveParams) any { return userOf(p).CreatedAt }),
+		"email":     private(func(u *model.User) any { return u.Email }),
+		"role":      private(func(u *model.User) any { return u.Role }),
+		"snippets": {Type: snippetConnectionType, Args: connectionArgs, Resolve: func(p graphql.ResolveParams) (any, error) {
+			return listSnippets(p, service.ListFilter{OwnerID: userOf(p).ID})
+		}},
+	}
+
+	pageInfoType.Fields = map[string]*graphql.Field{
+		"hasNextPage": scalar(func(p graphql.ResolveParams) any {
+			conn := connOf(p)
+			return conn.offset+len(conn.items) < conn.total
+		}),
+		"endCursor": scalar(func(p graphql.ResolveParams) any {
+			conn := connOf(p)
+			if len(conn.items) == 0 {
+				return nil
+			}
+			return encodeCursor(conn.offset + len(conn.items) - 1)
+		}),
+	}
+
+	// Runs and stars are only there when the server records them (see
+	// AddRunsAndStars).
+	if h.runs != nil {
+		runType.Fields = map[string]*graphql.Field{
+			"id":         scalar(func(p graphql.ResolveParams) any { return runOf(p).ID }),
+			"language":   scalar(func(p graphql.ResolveParams) any { return runOf(p).Language }),
+			"exitCode":   scalar(func(p graphql.ResolveParams) any { return runOf(p).ExitCode }),
+			"durationMs": scalar(func(p graphql.ResolveParams) any { return runOf(p).DurationMS }),
+			"createdAt":  scalar(func(p graphql.ResolveParams) any { return runOf(p).CreatedAt }),
+			"replayOf": scalar(func(p graphql.ResolveParams) any {
+				if id := runOf(p).ReplayOf; id != "" {
+					return id
+				}
+				return nil
+			}),
+		}
+		userType.Fields["runs"] = &graphql.Field{Type: connectionOf(runType), Args: connectionArgs, Resolve: func(p graphql.ResolveParams) (any, error) {
+			user := userOf(p)
+			if err := h.canSeePrivate(p.Context, user); err != nil {
+				return nil, err
+			}
+			first, offset, err := pageArgs(p)
+			if err != nil {
+				return nil, err
+			}
+			page, err := h.runs.List(p.Context, user.ID, first, offset)
+			if err != nil {
+				return nil, err
+			}
+			return newConnection(page.Items, page.Total, page.Offset), nil
+		}}
+	}
+	if h.stars != nil {
+		starType.Fields = map[string]*graphql.Field{
+			"createdAt": scalar(func(p graphql.ResolveParams) any { return starOf(p).CreatedAt }),
+			"user": {Type: userType, Resolve: func(p graphql.ResolveParams) (any, error) {
+				return h.optionalUser(p.Context, starOf(p).UserID)
+			}},
+			"snippet": {Type: snippetType, Resolve: func(p graphql.ResolveParams) (any, error) {
+				return snippets.GetByID(p.Context, starOf(p).SnippetID)
+			}},
+		}
+		starConnectionType := connectionOf(starType)
+		userType.Fields["stars"] = &graphql.Field{Type: starConnectionType, Args: connectionArgs, Resolve: func(p graphql.ResolveParams) (any, error) {
+			first, offset, err := pageArgs(p)
+			if err != nil {
+				return nil, err
+			}
+			page, err := h.stars.ListByUser(p.Context, userOf(p).ID, first, offset)
+			if err != nil {
+				return nil, err
+			}
+			return newConnection(page.Items, page.Total, page.Offset), nil
+		}}
+		snippetType.Fields["stars"] = &graphql.Field{Type: starConnectionType, Args: connectionArgs, Resolve: func(p graphql.ResolveParams) (any, error) {
+			first, offset, err := pageArgs(p)
+			if err != nil {
+				return nil, err
+			}
+			page, err := h.stars.ListBySnippet(p.Context, snippetOf(p).ID, first, offset)
+			if err != nil {
+				return nil, err
+			}
+			return newConnection(page.Items, page.Total, page.Offset), nil
+		}}
+	}
+
+	query := &graphql.Object{Name: "Query", Fields: map[string]*graphql.Field{
+		"viewer": {Type: userType, Resolve: func(p graphql.ResolveParams) (any, error) {
+			userID, ok := auth.UserIDFromContext(p.Context)
+			if !ok {
+				return nil, nil
+			}
+			return h.user(p.Context, userID)
+		}},
+		"user": {Type: userType, Args: map[string]any{"login": nil}, Resolve: func(p graphql.ResolveParams) (any, error) {
+			login, err := p.String("login")
+			if err != nil {
+				return nil, err
+			}
+			return h.users.GetByLogin(p.Context, login)
+		}},
+		"snippet": {Type: snippetType, Args: map[string]any{"id": nil}, Resolve: func(p graphql.ResolveParams) (any, error) {
+			id, err := p.String("id")
+			if err != nil {
+				return nil, err
+			}
+			return snippets.GetByID(p.Context, id)
+		}},
+		"snippets": {Type: snippetConnectionType, Args: connectionArgs, Resolve: func(p graphql.ResolveParams) (any, error) {
+			return listSnippets(p, service.ListFilter{})
+		}},
+	}}
+
+	return &graphql.Schema{Query: query}
+}
+
+// userCacheKey holds the per-request user cache in the context.
+type userCacheKey struct{}
+
+// user loads a user by ID, at most once per request: a list of 100 snippets
+// by the same author makes one query, not 100. Resolvers run one at a time,
+// so the cache needs no locking.
+func (h *GraphQLHandler) user(ctx context.Context, id string) (*model.User, error) {
+	cache, _ := ctx.Value(userCacheKey{}).(map[string]*model.User)
+	if user, ok := cache[id]; ok {
+		return user, nil
+	}
+	user, err := h.users.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if cache != nil {
+		cache[id] = user
+	}
+	return user, nil
+}
+
+// optionalUser loads the user a snippet or star points at: nil for an
+// anonymous snippet's author, or a user who has since been deleted.
+func (h *GraphQLHandler) optionalUser(ctx context.Context, id string) (*model.User, error) {
+	if id == "" {
+		return nil, nil
+	}
+	user, err := h.user(ctx, id)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, nil
+	}
+	return user, err
+}
+
+// canSeePrivate allows the user themselves and admins to read user's private fields.
+func (h *GraphQLHandler) canSeePrivate(ctx context.Context, user *model.User) error {
+	forbidden := &apperror.AppError{Err: apperror.ErrForbidden, Message: "only the user and admins can see this field"}
+
+	viewerID, ok := auth.UserIDFromContext(ctx)
+	if !ok {
+		return forbidden
+	}
+	if viewerID == user.ID {
+		return nil
+	}
+	viewer, err := h.user(ctx, viewerID)
+	if err != nil || !viewer.IsAdmin() {
+		return forbidden
+	}
+	return nil
+}
+
+// encodeCursor makes the opaque cursor for the item at offset.
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+// decodeCursor reverses encodeCursor.
+func decodeCursor(cursor string) (int, error) {
+	invalid := apperror.ValidationFailed("after", "after is not a valid cursor")
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, invalid
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(string(raw), "offset:"))
+	if err != nil || n < 0 || !strings.HasPrefix(string(raw), "offset:") {
+		return 0, invalid
+	}
+	return n, nil
+}
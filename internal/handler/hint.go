@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// HintHandler lets signed-in learners unlock an exercise's hints.
+type HintHandler struct {
+	service *service.HintService
+	logger  *slog.Logger
+}
+
+// NewHintHandler creates a new HintHandler.
+func NewHintHandler(svc *service.HintService, logger *slog.Logger) *HintHandler {
+	return &HintHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// HandleGet returns the hints the signed-in user has unlocked so far.
+//
+// HTTP: GET /api/v1/exercises/{id}/hints
+func (h *HintHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	progress, err := h.service.Progress(r.Context(), userID, r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, progress)
+}
+
+// HandleUnlock reveals the signed-in user's next hint. Once every hint is
+// unlocked it answers 409.
+//
+// HTTP: POST /api/v1/exercises/{id}/hints
+func (h *HintHandler) HandleUnlock(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	progress, err := h.service.Unlock(r.Context(), userID, r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, progress)
+}
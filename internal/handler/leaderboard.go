@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// LeaderboardHandler serves exercise and class leaderboards, and lets users
+// opt out of them.
+type LeaderboardHandler struct {
+	service *service.LeaderboardService
+	logger  *slog.Logger
+}
+
+// NewLeaderboardHandler creates a new LeaderboardHandler.
+func NewLeaderboardHandler(svc *service.LeaderboardService, logger *slog.Logger) *LeaderboardHandler {
+	return &LeaderboardHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// LeaderboardOptOutRequest is the expected JSON body for changing the
+// opt-out setting.
+type LeaderboardOptOutRequest struct {
+	OptOut bool `json:"optOut"`
+}
+
+// HandleExercise returns an exercise's leaderboard. It's public.
+//
+// HTTP: GET /api/v1/exercises/{id}/leaderboard?limit=20
+func (h *LeaderboardHandler) HandleExercise(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	board, err := h.service.Exercise(r.Context(), r.PathValue("id"), limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, board)
+}
+
+// HandleClass returns a class's leaderboard (members only).
+//
+// HTTP: GET /api/v1/classes/{id}/leaderboard?limit=20
+func (h *LeaderboardHandler) HandleClass(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	board, err := h.service.Class(r.Context(), userID, r.PathValue("id"), limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, board)
+}
+
+// HandleSetOptOut takes the signed-in user off leaderboards, or puts them
+// back, and returns their profile.
+//
+// HTTP: PUT /api/v1/me/leaderboard
+// Request body: {"optOut": true}
+func (h *LeaderboardHandler) HandleSetOptOut(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	var req LeaderboardOptOutRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	user, err := h.service.SetOptOut(r.Context(), userID, req.OptOut)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, user)
+}
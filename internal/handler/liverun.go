@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// LiveRunHandler serves live runs: a snippet's owner runs it while viewers
+// watch the output stream in over the WebSocket hub.
+type LiveRunHandler struct {
+	service *service.LiveRunService
+	logger  *slog.Logger
+}
+
+// NewLiveRunHandler creates a new LiveRunHandler.
+func NewLiveRunHandler(svc *service.LiveRunService, logger *slog.Logger) *LiveRunHandler {
+	return &LiveRunHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// HandleStart starts a live run of the snippet's saved code. The response
+// names the topic viewers subscribe to for the output.
+//
+// HTTP: POST /api/v1/snippets/{id}/live-run
+func (h *LiveRunHandler) HandleStart(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	run, err := h.service.Start(r.Context(), userID, r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusAccepted, run)
+}
+
+// HandleGet returns the snippet's current or most recent live run, with
+// the output so far.
+//
+// HTTP: GET /api/v1/snippets/{id}/live-run
+func (h *LiveRunHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	run, err := h.service.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, run)
+}
+
+// HandleStop stops the snippet's live run.
+//
+// HTTP: DELETE /api/v1/snippets/{id}/live-run
+func (h *LiveRunHandler) HandleStop(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.Stop(r.Context(), userID, r.PathValue("id")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
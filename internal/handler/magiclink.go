@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// magicStateCookie holds the CSRF state between the sign-in page and its
+// form post, like oauth_state does for GitHub.
+const magicStateCookie = "magic_state"
+
+// magicLinkPage is where a sign-in link lands: one button that posts the
+// token back. It's small, so a constant is simpler than a template file.
+var magicLinkPage = template.Must(template.New("magic").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Sign in — PyPlayground</title>
+</head>
+<body style="font-family: sans-serif; text-align: center; margin-top: 4em">
+    <form method="post" action="/auth/magic">
+        <input type="hidden" name="token" value="{{.Token}}">
+        <input type="hidden" name="state" value="{{.State}}">
+        <button type="submit" style="font-size: 1.2em; padding: 0.5em 1.5em">Sign in to PyPlayground</button>
+    </form>
+</body>
+</html>
+`))
+
+// MagicLinkHandler serves sign-in by emailed link.
+type MagicLinkHandler struct {
+	service *service.MagicLinkService
+	logger  *slog.Logger
+}
+
+// NewMagicLinkHandler creates a new MagicLinkHandler.
+func NewMagicLinkHandler(svc *service.MagicLinkService, logger *slog.Logger) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// MagicLinkRequest is the expected JSON body for asking for a sign-in link.
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+// HandleRequest emails a sign-in link. The answer is the same whether or
+// not the address has an account.
+//
+// HTTP: POST /auth/magic-link
+// Request body: {"email": "ann@example.com"}
+func (h *MagicLinkHandler) HandleRequest(w http.ResponseWriter, r *http.Request) {
+	var req MagicLinkRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+	if err := h.service.Request(r.Context(), req.Email); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusAccepted, map[string]string{
+		"message": "If that address belongs to an account, a sign-in link is on its way.",
+	})
+}
+
+// HandlePage shows the sign-in button for a link's token. It doesn't use
+// the token up; see service.MagicLinkService.
+//
+// HTTP: GET /auth/magic?token=...
+func (h *MagicLinkHandler) HandlePage(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "Missing sign-in token", http.StatusBadRequest)
+		return
+	}
+	stateBytes := make([]byte, 16)
+	if _, err := rand.Read(stateBytes); err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to generate sign-in state", slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	state := hex.EncodeToString(stateBytes)
+	http.SetCookie(w, &http.Cookie{
+		Name:     magicStateCookie,
+		Value:    state,
+		Path:     "/auth/magic",
+		MaxAge:   300, // 5 minutes
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Referrer-Policy", "no-referrer") // the URL holds the token
+	magicLinkPage.Execute(w, struct{ Token, State string }{token, state})
+}
+
+// HandleSignIn uses up a sign-in link and sets the session cookie.
+//
+// HTTP: POST /auth/magic (form: token, state)
+func (h *MagicLinkHandler) HandleSignIn(w http.ResponseWriter, r *http.Request) {
+	stateCookie, err := r.Cookie(magicStateCookie)
+	if err != nil || stateCookie.Value == "" || r.PostFormValue("state") != stateCookie.Value {
+		h.logger.WarnContext(r.Context(), "sign-in link state mismatch")
+		http.Error(w, "Invalid sign-in state", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     magicStateCookie,
+		Value:    "",
+		Path:     "/auth/magic",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+
+	result, err := h.service.SignIn(r.Context(), r.PostFormValue("token"))
+	if errors.Is(err, apperror.ErrNotFound) {
+		http.Error(w, "This sign-in link is invalid, used or expired. Ask for a new one.", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "sign-in by link failed", slog.String("error", err.Error()))
+		http.Error(w, "Authentication failed", http.StatusInternalServerError)
+		return
+	}
+	setSessionCookie(w, result.Token)
+
+	h.logger.InfoContext(r.Context(), "user logged in",
+		slog.String("user_id", result.User.ID),
+		slog.String("login", result.User.Login),
+		slog.String("method", "email"),
+	)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
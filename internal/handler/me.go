@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/middleware"
+	"github.com/sakif/coding-playground/internal/model"
+)
+
+// MeResponse is the signed-in user, as GET /me shows them.
+type MeResponse struct {
+	ID                string `json:"id"`
+	Login             string `json:"login"`
+	Email             string `json:"email"`
+	AvatarURL         string `json:"avatarUrl"`
+	Role              string `json:"role"`
+	LeaderboardOptOut bool   `json:"leaderboardOptOut"`
+	Suspended         bool   `json:"suspended"`
+}
+
+// UserLookup returns a user by ID, or nil if there's no such user.
+type UserLookup func(ctx context.Context, id string) (*model.User, error)
+
+// HandleMe returns the signed-in user. It works without the GitHub
+// sign-in handler, so API tokens can use it on servers without GitHub
+// credentials. It must run behind auth.RequireAuth.
+//
+// HTTP: GET /api/v1/me
+func HandleMe(users UserLookup) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+		user, err := users(r.Context(), userID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if user == nil {
+			// A valid token for a user who's since been deleted.
+			writeJSON(w, r, http.StatusUnauthorized, ErrorResponse{
+				Error:     "unauthorized",
+				Code:      apperror.CodeUnauthorized,
+				Message:   "user not found",
+				RequestID: middleware.RequestID(r.Context()),
+			})
+			return
+		}
+		writeJSON(w, r, http.StatusOK, MeResponse{
+			ID:                user.ID,
+			Login:             user.Login,
+			Email:             user.Email,
+			AvatarURL:         user.AvatarURL,
+			Role:              user.Role,
+			LeaderboardOptOut: user.LeaderboardOptOut,
+			Suspended:         user.Suspended,
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// NotificationHandler serves the signed-in user's notifications. New ones
+// are also pushed over the WebSocket hub, on the "user:<id>" topic.
+type NotificationHandler struct {
+	service *service.NotificationService
+	logger  *slog.Logger
+}
+
+// NewNotificationHandler creates a new NotificationHandler.
+func NewNotificationHandler(svc *service.NotificationService, logger *slog.Logger) *NotificationHandler {
+	return &NotificationHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// MarkReadRequest is the expected JSON body for marking notifications read.
+// No IDs marks them all.
+type MarkReadRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// MarkReadResponse is the unread count after marking.
+type MarkReadResponse struct {
+	Unread int `json:"unread"`
+}
+
+// HandleList returns the newest notifications and the unread count.
+//
+// HTTP: GET /api/v1/me/notifications?unread=true&limit=20
+func (h *NotificationHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	unreadOnly, _ := strconv.ParseBool(r.URL.Query().Get("unread"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	list, err := h.service.List(r.Context(), userID, unreadOnly, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, list)
+}
+
+// HandleMarkRead marks notifications read.
+//
+// HTTP: POST /api/v1/me/notifications/read
+// Request body: {"ids": ["..."]} (or {} for all)
+func (h *NotificationHandler) HandleMarkRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	var req MarkReadRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+	unread, err := h.service.MarkRead(r.Context(), userID, req.IDs)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, MarkReadResponse{Unread: unread})
+}
@@ -0,0 +1,56 @@
+package handler
+
+// OPENAPI DOCUMENTATION:
+// OpenAPI (formerly Swagger) is a standard JSON/YAML format describing an HTTP
+// API: its paths, parameters, request bodies and response shapes. Tools read it
+// to render interactive docs, generate client libraries and validate requests.
+//
+// HAND-MAINTAINED, EMBEDDED:
+// The document lives next to the handlers in openapi.json and is compiled INTO
+// the binary with //go:embed — no file to deploy alongside the server, and it
+// can't drift out of sync with the binary serving it. When you add or change
+// an endpoint, update openapi.json in the same commit.
+
+import (
+	_ "embed" // required for //go:embed
+	"net/http"
+)
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+// swaggerUIPage loads Swagger UI from a CDN and points it at our spec.
+// It's static HTML, so a constant is simpler than a template.
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>PyPlayground API — Swagger UI</title>
+    <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+    <div id="swagger-ui"></div>
+    <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+    <script>
+        window.ui = SwaggerUIBundle({ url: "/api/v1/openapi.json", dom_id: "#swagger-ui" });
+    </script>
+</body>
+</html>
+`
+
+// HandleOpenAPISpec serves the OpenAPI 3 document.
+//
+// HTTP: GET /api/v1/openapi.json
+func HandleOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+// HandleSwaggerUI serves an interactive API explorer for the OpenAPI document.
+//
+// HTTP: GET /swagger
+func HandleSwaggerUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(swaggerUIPage))
+}
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// OrgHandler serves organizations. Every route sits behind auth.RequireAuth;
+// who may see and change what inside an org is the service's call.
+type OrgHandler struct {
+	service *service.OrgService
+	logger  *slog.Logger
+}
+
+// NewOrgHandler creates a new OrgHandler.
+func NewOrgHandler(svc *service.OrgService, logger *slog.Logger) *OrgHandler {
+	return &OrgHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// CreateOrgRequest is the expected JSON body for creating an org.
+type CreateOrgRequest struct {
+	Name string `json:"name"`
+}
+
+// AddOrgMemberRequest is the expected JSON body for adding a member.
+type AddOrgMemberRequest struct {
+	Login string `json:"login"`
+	Role  string `json:"role"` // owner, member (default) or viewer
+}
+
+// SetOrgRoleRequest is the expected JSON body for changing a member's role.
+type SetOrgRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// HandleCreate creates an org owned by the signed-in user.
+//
+// HTTP: POST /api/v1/orgs
+// Request body: {"name": "Data team"}
+func (h *OrgHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	var req CreateOrgRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	org, err := h.service.Create(r.Context(), userID, req.Name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, org)
+}
+
+// HandleList returns the orgs the signed-in user belongs to.
+//
+// HTTP: GET /api/v1/orgs
+func (h *OrgHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	orgs, err := h.service.List(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, orgs)
+}
+
+// HandleGet returns one of the signed-in user's orgs.
+//
+// HTTP: GET /api/v1/orgs/{id}
+func (h *OrgHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	org, err := h.service.Get(r.Context(), userID, r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, org)
+}
+
+// HandleDelete deletes an org (owners only). Its snippets go back to their
+// creators.
+//
+// HTTP: DELETE /api/v1/orgs/{id}
+func (h *OrgHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(r.Context(), userID, r.PathValue("id")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleMembers lists an org's members.
+//
+// HTTP: GET /api/v1/orgs/{id}/members
+func (h *OrgHandler) HandleMembers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	members, err := h.service.Members(r.Context(), userID, r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, members)
+}
+
+// HandleAddMember adds a user to an org (owners only).
+//
+// HTTP: POST /api/v1/orgs/{id}/members
+// Request body: {"login": "bob", "role": "member"}
+func (h *OrgHandler) HandleAddMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	var req AddOrgMemberRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	member, err := h.service.AddMember(r.Context(), userID, r.PathValue("id"), req.Login, req.Role)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, member)
+}
+
+// HandleSetRole changes a member's role (owners only).
+//
+// HTTP: PUT /api/v1/orgs/{id}/members/{login}
+// Request body: {"role": "owner"}
+func (h *OrgHandler) HandleSetRole(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	var req SetOrgRoleRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	member, err := h.service.SetRole(r.Context(), userID, r.PathValue("id"), r.PathValue("login"), req.Role)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, member)
+}
+
+// HandleRemoveMember takes a member out of an org: owners can remove
+// anyone, and members can remove themselves to leave.
+//
+// HTTP: DELETE /api/v1/orgs/{id}/members/{login}
+func (h *OrgHandler) HandleRemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.RemoveMember(r.Context(), userID, r.PathValue("id"), r.PathValue("login")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
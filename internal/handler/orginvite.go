@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// OrgInviteHandler serves invitations to orgs: owners manage them under
+// /orgs/{id}/invites, and whoever has a link looks at and accepts it
+// under /invites/{token}.
+type OrgInviteHandler struct {
+	service *service.OrgInviteService
+	logger  *slog.Logger
+}
+
+// NewOrgInviteHandler creates a new OrgInviteHandler.
+func NewOrgInviteHandler(svc *service.OrgInviteService, logger *slog.Logger) *OrgInviteHandler {
+	return &OrgInviteHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// CreateOrgInviteRequest is the expected JSON body for creating an invite.
+// Every field is optional.
+type CreateOrgInviteRequest struct {
+	Role  string `json:"role"`  // owner, member (default) or viewer
+	Email string `json:"email"` // email the link here too
+	Days  int    `json:"days"`  // how long it works (default 7)
+}
+
+// HandleCreate creates an invite and returns its link (owners only). This
+// is the only time the link is shown.
+//
+// HTTP: POST /api/v1/orgs/{id}/invites
+// Request body: {"role": "member", "email": "bob@example.com", "days": 7}
+func (h *OrgInviteHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	var req CreateOrgInviteRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	created, err := h.service.Create(r.Context(), userID, r.PathValue("id"), service.OrgInviteInput{
+		Role:  req.Role,
+		Email: req.Email,
+		Days:  req.Days,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, created)
+}
+
+// HandleList returns an org's pending invites (owners only).
+//
+// HTTP: GET /api/v1/orgs/{id}/invites
+func (h *OrgInviteHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	invites, err := h.service.Pending(r.Context(), userID, r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, invites)
+}
+
+// HandleRevoke revokes a pending invite (owners only).
+//
+// HTTP: DELETE /api/v1/orgs/{id}/invites/{inviteID}
+func (h *OrgInviteHandler) HandleRevoke(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.Revoke(r.Context(), userID, r.PathValue("id"), r.PathValue("inviteID")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGet shows which org an invite link is for, and with what role.
+//
+// HTTP: GET /api/v1/invites/{token}
+func (h *OrgInviteHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	invite, err := h.service.Get(r.Context(), r.PathValue("token"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Referrer-Policy", "no-referrer") // the URL holds the token
+	writeJSON(w, r, http.StatusOK, invite)
+}
+
+// HandleAccept adds the signed-in user to an invite's org.
+//
+// HTTP: POST /api/v1/invites/{token}/accept
+func (h *OrgInviteHandler) HandleAccept(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	org, err := h.service.Accept(r.Context(), userID, r.PathValue("token"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, org)
+}
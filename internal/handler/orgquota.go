@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// OrgQuotaHandler shows orgs their quota usage and lets admins change
+// their quotas.
+type OrgQuotaHandler struct {
+	snippets *service.SnippetService
+	logger   *slog.Logger
+}
+
+// NewOrgQuotaHandler creates a new OrgQuotaHandler.
+func NewOrgQuotaHandler(snippets *service.SnippetService, logger *slog.Logger) *OrgQuotaHandler {
+	return &OrgQuotaHandler{
+		snippets: snippets,
+		logger:   logger,
+	}
+}
+
+// HandleUsage returns how much of its quota an org has used, for any member.
+//
+// HTTP: GET /api/v1/orgs/{id}/usage
+func (h *OrgQuotaHandler) HandleUsage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	usage, err := h.snippets.OrgUsage(r.Context(), userID, r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, usage)
+}
+
+// HandleSetQuota overrides an org's quotas (admins only). A null field goes
+// back to the deployment's default.
+//
+// HTTP: PUT /api/v1/admin/orgs/{id}/quota
+// Request body: {"snippets": 1000, "runsPerDay": null}
+func (h *OrgQuotaHandler) HandleSetQuota(w http.ResponseWriter, r *http.Request) {
+	var req model.OrgQuota
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+	usage, err := h.snippets.SetOrgQuota(r.Context(), r.PathValue("id"), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, usage)
+}
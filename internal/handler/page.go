@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/chroma/v2/styles"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/assets"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// PUBLIC SNIPPET PAGES:
+// The playground itself is a JavaScript app: a crawler or a chat app
+// unfurling a link sees an empty editor. Published snippets therefore also
+// get a plain server-rendered page,
+//
+//	GET /s/{id}        → the snippet, highlighted, with a link into the playground
+//	GET /sitemap.xml   → every published snippet's page, for search engines
+//	GET /robots.txt    → points crawlers at the sitemap
+//
+// LINK PREVIEWS:
+// Slack, Discord, Twitter/X and friends don't run JavaScript either; they
+// read <meta> tags from the page's <head>. Open Graph (og:title,
+// og:description, og:url, ...) is the common vocabulary, and Twitter reads its
+// own twitter:* tags, falling back to Open Graph for most of them.
+//
+// Only published snippets have pages. Anything else is a 404, exactly like a
+// snippet that doesn't exist.
+
+// previewLength caps og:description; previews cut longer text anyway.
+const previewLength = 200
+
+// PageHandler serves the public snippet pages, the sitemap and robots.txt.
+type PageHandler struct {
+	snippets *service.SnippetService
+	users    *service.UserService
+	tmpl     *template.Template
+	logger   *slog.Logger
+
+	cdnMaxAge time.Duration // see CacheOnCDN
+}
+
+// NewPageHandler creates a PageHandler and parses the snippet page template.
+func NewPageHandler(templateDir string, manifest *assets.Manifest, snippets *service.SnippetService, users *service.UserService, logger *slog.Logger) (*PageHandler, error) {
+	tmpl, err := template.New("").Funcs(template.FuncMap{"asset": manifest.Path}).ParseFiles(
+		filepath.Join(templateDir, "snippet.html"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &PageHandler{
+		snippets: snippets,
+		users:    users,
+		tmpl:     tmpl,
+		logger:   logger,
+	}, nil
+}
+
+// CacheOnCDN lets a CDN keep snippet pages for maxAge (see internal/cdn).
+// Call it before serving requests.
+func (h *PageHandler) CacheOnCDN(maxAge time.Duration) {
+	h.cdnMaxAge = maxAge
+}
+
+// HandleSnippetPage renders a published snippet.
+//
+// HTTP: GET /s/{id}
+func (h *PageHandler) HandleSnippetPage(w http.ResponseWriter, r *http.Request) {
+	snippet, err := h.snippets.GetPublic(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			http.Error(w, "Snippet not found", http.StatusNotFound)
+			return
+		}
+		h.logger.ErrorContext(r.Context(), "failed to load public snippet", slog.String("error", err.Error()))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	etag := fmt.Sprintf(`W/"page-%s-%x"`, snippet.ID, snippet.UpdatedAt.UnixNano())
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", snippet.UpdatedAt.UTC().Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "no-cache")
+	setCDNHeaders(w, snippet, h.cdnMaxAge)
+	if notModified(r, etag, snippet.UpdatedAt) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	code, err := highlightSnippet(snippet, styles.Get(defaultHighlightStyle))
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to highlight snippet", slog.String("error", err.Error()))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	author := ""
+	if user, err := h.users.GetByID(r.Context(), snippet.UserID); err == nil {
+		author = user.Login
+	}
+
+	base := baseURL(r)
+	data := map[string]any{
+		"Snippet":     snippet,
+		"Author":      author,
+		"Description": previewText(snippet),
+		"URL":         base + "/s/" + url.PathEscape(snippet.ID),
+		"OpenURL":     "/?snippet=" + url.QueryEscape(snippet.ID),
+		"Code":        template.HTML(code), // chroma escapes every token
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.tmpl.ExecuteTemplate(w, "snippet", data); err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to render template", slog.String("error", err.Error()))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// previewText is the description shown in link previews: the snippet's own
+// description, or else the start of its code.
+func previewText(s *model.Snippet) string {
+	text := strings.TrimSpace(s.Description)
+	if text == "" {
+		text = strings.Join(strings.Fields(s.Code), " ")
+	}
+	if len([]rune(text)) > previewLength {
+		text = string([]rune(text)[:previewLength-1]) + "…"
+	}
+	return text
+}
+
+// sitemapURLSet mirrors the sitemap protocol's <urlset> (sitemaps.org).
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// HandleSitemap lists the playground and every published snippet's page.
+//
+// HTTP: GET /sitemap.xml
+func (h *PageHandler) HandleSitemap(w http.ResponseWriter, r *http.Request) {
+	snippets, err := h.snippets.Sitemap(r.Context())
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to build sitemap", slog.String("error", err.Error()))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	base := baseURL(r)
+	set := sitemapURLSet{URLs: make([]sitemapURL, 0, len(snippets)+1)}
+	set.URLs = append(set.URLs, sitemapURL{Loc: base + "/"})
+	for _, s := range snippets {
+		set.URLs = append(set.URLs, sitemapURL{
+			Loc:     base + "/s/" + url.PathEscape(s.ID),
+			LastMod: s.UpdatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(feedMaxAge.Seconds())))
+	w.Write([]byte(xml.Header))
+	if err := xml.NewEncoder(w).Encode(set); err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to encode sitemap", slog.String("error", err.Error()))
+	}
+}
+
+// HandleRobots tells crawlers where the sitemap is, and to keep out of the
+// API and admin pages.
+//
+// HTTP: GET /robots.txt
+func (h *PageHandler) HandleRobots(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "User-agent: *\nDisallow: /api/\nDisallow: /admin\n\nSitemap: %s/sitemap.xml\n", baseURL(r))
+}
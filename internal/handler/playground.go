@@ -23,6 +23,8 @@ import (
 	"log/slog"
 	"net/http"
 	"path/filepath"
+
+	"github.com/sakif/coding-playground/internal/assets"
 )
 
 // PlaygroundHandler manages the main playground page.
@@ -34,8 +36,11 @@ import (
 // 2. Inject dependencies (logger, config) without global variables
 // 3. Group related handlers together
 type PlaygroundHandler struct {
-	templates *template.Template
-	logger    *slog.Logger
+	templates   *template.Template
+	templateDir string
+	devMode     bool // re-parse templates on every request (see NewPlaygroundHandler)
+	assets      *assets.Manifest
+	logger      *slog.Logger
 }
 
 // NewPlaygroundHandler creates a new PlaygroundHandler and parses the HTML templates.
@@ -47,20 +52,48 @@ type PlaygroundHandler struct {
 //   - playground.html defines {{define "content"}}...{{end}} to fill that placeholder
 //
 // This is Go's template composition model — similar to "extends" in Jinja2 or "layouts" in Rails.
-func NewPlaygroundHandler(templateDir string, logger *slog.Logger) (*PlaygroundHandler, error) {
-	// filepath.Join handles OS-specific path separators (\ on Windows, / on Linux)
-	tmpl, err := template.ParseFiles(
-		filepath.Join(templateDir, "base.html"),
-		filepath.Join(templateDir, "playground.html"),
-	)
+//
+// DEV MODE (HOT RELOAD):
+// Parsing once at startup means every HTML tweak needs a server restart.
+// With devMode=true the templates are re-parsed on every request instead, so
+// a browser refresh picks up edits immediately. Parsing a couple of small files
+// is cheap enough for development, but in production we keep parse-once:
+// it's faster, and a broken template fails at startup instead of per request.
+//
+// ASSET URLS:
+// Templates reference static files with {{asset "css/style.css"}}, which
+// expands to a content-hashed URL from the manifest (see internal/assets).
+func NewPlaygroundHandler(templateDir string, manifest *assets.Manifest, logger *slog.Logger, devMode bool) (*PlaygroundHandler, error) {
+	h := &PlaygroundHandler{
+		templateDir: templateDir,
+		devMode:     devMode,
+		assets:      manifest,
+		logger:      logger,
+	}
+
+	// Parse once even in dev mode so a broken template still fails fast at startup.
+	tmpl, err := h.parseTemplates()
 	if err != nil {
 		return nil, err
 	}
+	h.templates = tmpl
 
-	return &PlaygroundHandler{
-		templates: tmpl,
-		logger:    logger,
-	}, nil
+	return h, nil
+}
+
+// parseTemplates reads and compiles the page templates from disk.
+//
+// TEMPLATE FUNCTIONS:
+// Funcs must be registered BEFORE parsing — the parser rejects calls to
+// functions it doesn't know about.
+func (h *PlaygroundHandler) parseTemplates() (*template.Template, error) {
+	funcs := template.FuncMap{"asset": h.assets.Path}
+
+	// filepath.Join handles OS-specific path separators (\ on Windows, / on Linux)
+	return template.New("").Funcs(funcs).ParseFiles(
+		filepath.Join(h.templateDir, "base.html"),
+		filepath.Join(h.templateDir, "playground.html"),
+	)
 }
 
 // HandlePlayground serves the main playground page.
@@ -76,13 +109,26 @@ func (h *PlaygroundHandler) HandlePlayground(w http.ResponseWriter, r *http.Requ
 		"Title": "PyPlayground — Python Coding Playground",
 	}
 
-	// Set content type header BEFORE writing the body
+	tmpl := h.templates
+	if h.devMode {
+		var err error
+		if tmpl, err = h.parseTemplates(); err != nil {
+			h.logger.ErrorContext(r.Context(), "failed to reload templates", slog.String("error", err.Error()))
+			http.Error(w, "Template error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Set content type header BEFORE writing the body.
+	// no-cache: the page embeds the current asset URLs, so browsers must
+	// revalidate it to discover a new deploy.
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
 
 	// Execute the "base" template with our data
 	// If template execution fails, log the error and send a 500 response
-	if err := h.templates.ExecuteTemplate(w, "base", data); err != nil {
-		h.logger.Error("failed to render template",
+	if err := tmpl.ExecuteTemplate(w, "base", data); err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to render template",
 			slog.String("error", err.Error()),
 		)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -0,0 +1,75 @@
+package handler_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sakif/coding-playground/internal/assets"
+	"github.com/sakif/coding-playground/internal/handler"
+)
+
+// writeTemplates writes a minimal base/playground pair into dir.
+func writeTemplates(t *testing.T, dir, content string) {
+	t.Helper()
+
+	base := `{{define "base"}}<main>{{template "content" .}}</main>{{end}}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "base.html"), []byte(base), 0o644))
+	playground := `{{define "content"}}` + content + `{{end}}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "playground.html"), []byte(playground), 0o644))
+}
+
+func TestPlaygroundHandler_TemplateReload(t *testing.T) {
+	render := func(t *testing.T, h *handler.PlaygroundHandler) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.HandlePlayground(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w
+	}
+
+	newHandler := func(t *testing.T, devMode bool) (*handler.PlaygroundHandler, string) {
+		t.Helper()
+		dir := t.TempDir()
+		writeTemplates(t, dir, "first version")
+
+		manifest, err := assets.NewManifest(t.TempDir(), true)
+		require.NoError(t, err)
+		logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+		h, err := handler.NewPlaygroundHandler(dir, manifest, logger, devMode)
+		require.NoError(t, err)
+		return h, dir
+	}
+
+	t.Run("dev mode picks up edits", func(t *testing.T) {
+		h, dir := newHandler(t, true)
+		assert.Contains(t, render(t, h).Body.String(), "first version")
+
+		writeTemplates(t, dir, "second version")
+		w := render(t, h)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "second version")
+	})
+
+	t.Run("dev mode reports a broken edit", func(t *testing.T) {
+		h, dir := newHandler(t, true)
+
+		writeTemplates(t, dir, "{{.Unclosed")
+		assert.Equal(t, http.StatusInternalServerError, render(t, h).Code)
+	})
+
+	t.Run("production keeps the startup templates", func(t *testing.T) {
+		h, dir := newHandler(t, false)
+
+		writeTemplates(t, dir, "second version")
+		body := render(t, h).Body.String()
+		assert.Contains(t, body, "first version")
+		assert.NotContains(t, body, "second version")
+	})
+}
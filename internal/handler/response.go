@@ -10,12 +10,18 @@ package handler
 //   json.NewEncoder(w).Encode(data)
 //
 // With helpers, handlers are cleaner and more consistent:
-//   writeJSON(w, http.StatusOK, data)
-//   writeError(w, err)
+//   writeJSON(w, r, http.StatusOK, data)
+//   writeError(w, r, err)
 //
 // CONSISTENT ERROR FORMAT:
 // Every error response from our API has the same shape:
-//   {"error": "not_found", "message": "snippet not found with id abc123"}
+//   {"error": "not_found", "code": "SNIPPET_NOT_FOUND", "message": "snippet not found with id abc123", "requestId": "host/abc-000042"}
+
+// "code" is the one to branch on: it's stable, specific and listed with
+// every other code at GET /api/v1/errors (see apperror/codes.go).
+//
+// requestId matches the X-Request-ID response header and the request_id field
+// in the server logs, so a user's error report can be traced to its log lines.
 //
 // This makes it easy for the frontend to parse errors — it always knows
 // what fields to expect, regardless of whether it's a 400, 404, or 500.
@@ -23,20 +29,37 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/errreport"
+	"github.com/sakif/coding-playground/internal/i18n"
+	"github.com/sakif/coding-playground/internal/middleware"
 )
 
 // ErrorResponse is the standard error format returned by all API endpoints.
 // Having a struct ensures consistent JSON shape across all error responses.
 type ErrorResponse struct {
-	Error   string `json:"error"`   // Machine-readable error type (e.g., "not_found")
-	Message string `json:"message"` // Human-readable description
+	Error     string               `json:"error"`               // Machine-readable error type (e.g., "not_found")
+	Code      apperror.Code        `json:"code"`                // Stable, specific error code (e.g., "SNIPPET_NOT_FOUND")
+	Message   string               `json:"message"`             // Human-readable description
+	Errors    []FieldErrorResponse `json:"errors,omitempty"`    // Every invalid field, for validation errors
+	RequestID string               `json:"requestId,omitempty"` // Correlates the error with server logs
+}
+
+// FieldErrorResponse describes one invalid field of a request.
+type FieldErrorResponse struct {
+	Field   string        `json:"field"`
+	Code    apperror.Code `json:"code,omitempty"`
+	Message string        `json:"message"`
 }
 
-// writeJSON sends a JSON response with the given status code.
+// writeJSON sends a JSON response with the given status code — or MessagePack,
+// if the request's Accept header asks for it (see codec.go).
 //
 // HEADER ORDER MATTERS:
 // You MUST set headers and status code BEFORE writing the body.
@@ -47,7 +70,22 @@ type ErrorResponse struct {
 //  1. w.Header().Set(...)     ← set headers
 //  2. w.WriteHeader(status)   ← send status + headers
 //  3. json.Encode(data)       ← send body
-func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	w.Header().Add("Vary", "Accept")
+	if wantsMsgpack(r) {
+		w.Header().Set("Content-Type", ContentTypeMsgpack)
+		w.WriteHeader(status)
+		if data != nil {
+			if err := newMsgpackEncoder(w).Encode(data); err != nil {
+				slog.Error("failed to encode MessagePack response", slog.String("error", err.Error()))
+			}
+		}
+		return
+	}
+
+	if body, ok := withWarnings(r, data); ok {
+		data = body // see deprecation.go
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	if data != nil {
@@ -80,7 +118,9 @@ func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 //	service returns: fmt.Errorf("creating snippet: %w", apperror.ValidationFailed(...))
 //	which wraps:     AppError{Err: ErrValidation, Message: "..."}
 //	errors.Is walks: outer error → AppError → ErrValidation ✓ match!
-func writeError(w http.ResponseWriter, err error) {
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	requestID := middleware.RequestID(r.Context())
+
 	// Try to extract our AppError for the human-readable message
 	var appErr *apperror.AppError
 
@@ -88,36 +128,181 @@ func writeError(w http.ResponseWriter, err error) {
 	// It walks the chain and fills appErr if it finds an *AppError.
 	if errors.As(err, &appErr) {
 		// We have a typed application error — map it to HTTP
-		status := http.StatusInternalServerError
-		errorType := "internal_error"
-
-		switch {
-		case errors.Is(err, apperror.ErrValidation):
-			status = http.StatusBadRequest // 400
-			errorType = "validation_error"
-		case errors.Is(err, apperror.ErrForbidden):
-			status = http.StatusForbidden // 403
-			errorType = "forbidden"
-		case errors.Is(err, apperror.ErrNotFound):
-			status = http.StatusNotFound // 404
-			errorType = "not_found"
-		case errors.Is(err, apperror.ErrConflict):
-			status = http.StatusConflict // 409
-			errorType = "conflict"
+		status, errorType := errorStatus(err)
+		if status == http.StatusServiceUnavailable {
+			w.Header().Set("Retry-After", "30")
+		}
+		if status == http.StatusInternalServerError {
+			writeInternalError(w, r, err)
+			return
 		}
 
-		writeJSON(w, status, ErrorResponse{
-			Error:   errorType,
-			Message: appErr.Message,
-		})
+		resp := ErrorResponse{
+			Error:     errorType,
+			Code:      apperror.CodeOf(err),
+			Message:   appErr.Message,
+			RequestID: requestID,
+		}
+		if status == http.StatusBadRequest {
+			resp.Errors = fieldErrors(err, appErr)
+		}
+		localizeError(w, r, &resp, err)
+		writeJSON(w, r, status, resp)
 		return
 	}
 
 	// Unknown error — return a generic 500
-	// NEVER expose internal error details to the client in production!
-	// The raw error message might contain SQL queries, file paths, or other sensitive info.
-	writeJSON(w, http.StatusInternalServerError, ErrorResponse{
-		Error:   "internal_error",
-		Message: "An internal error occurred",
-	})
+	writeInternalError(w, r, err)
+}
+
+// writeInternalError sends a generic 500 for err.
+//
+// NEVER expose internal error details to the client in production!
+// The raw error message might contain SQL queries, file paths, or other sensitive info.
+// The full error goes to the logs and the error tracker instead, where only
+// we can see it — with any metadata attached by apperror.Wrap and, if stack
+// capture is on, where it was wrapped.
+func writeInternalError(w http.ResponseWriter, r *http.Request, err error) {
+	requestID := middleware.RequestID(r.Context())
+
+	attrs := []any{
+		slog.String("error", err.Error()),
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("request_id", requestID),
+	}
+	if meta := apperror.Meta(err); len(meta) > 0 {
+		attrs = append(attrs, slog.Attr{Key: "meta", Value: slog.GroupValue(meta...)})
+	}
+	if stack := apperror.StackTrace(err); stack != "" {
+		attrs = append(attrs, slog.String("stack", stack))
+	}
+	slog.ErrorContext(r.Context(), "request failed", attrs...)
+	errreport.FromContext(r.Context()).Report(r.Context(), err, r)
+
+	resp := ErrorResponse{
+		Error:     "internal_error",
+		Code:      apperror.CodeInternal,
+		Message:   "An internal error occurred",
+		RequestID: requestID,
+	}
+	localize(w, r, &resp, nil)
+	writeJSON(w, r, http.StatusInternalServerError, resp)
+}
+
+// localizeError translates resp's messages, and its fields', into the
+// language the request asks for, where there's a translation (see
+// internal/i18n). A validation error's message is its fields' messages
+// joined, as in English.
+func localizeError(w http.ResponseWriter, r *http.Request, resp *ErrorResponse, err error) {
+	var verrs *apperror.ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs.Fields) != len(resp.Errors) {
+		localize(w, r, resp, apperror.Params(err))
+		for i := range resp.Errors {
+			resp.Errors[i].Message = resp.Message // the one field the error is about
+		}
+		return
+	}
+
+	w.Header().Add("Vary", "Accept-Language")
+	t := i18n.For(r.Header.Get("Accept-Language"))
+	translated := false
+	msgs := make([]string, len(verrs.Fields))
+	for i, f := range verrs.Fields {
+		code, params := f.Code, f.Params
+		if code == "" {
+			code, params = apperror.CodeValidation, map[string]string{"field": f.Field}
+		}
+		msg, ok := t.Message(code, params, f.Message)
+		resp.Errors[i].Message, msgs[i] = msg, msg
+		translated = translated || ok
+	}
+	resp.Message = strings.Join(msgs, "; ")
+	if translated {
+		w.Header().Set("Content-Language", t.Language().String())
+	}
+}
+
+// localize translates resp.Message by its code, filling in params.
+func localize(w http.ResponseWriter, r *http.Request, resp *ErrorResponse, params map[string]string) {
+	w.Header().Add("Vary", "Accept-Language")
+	t := i18n.For(r.Header.Get("Accept-Language"))
+	if msg, ok := t.Message(resp.Code, params, resp.Message); ok {
+		resp.Message = msg
+		w.Header().Set("Content-Language", t.Language().String())
+	}
+}
+
+// errorStatus maps an error's kind (apperror.ErrNotFound, ...) to its HTTP
+// status and error type.
+func errorStatus(err error) (status int, errorType string) {
+	switch {
+	case errors.Is(err, apperror.ErrValidation):
+		return http.StatusBadRequest, "validation_error" // 400
+	case errors.Is(err, apperror.ErrForbidden):
+		return http.StatusForbidden, "forbidden" // 403
+	case errors.Is(err, apperror.ErrNotFound):
+		return http.StatusNotFound, "not_found" // 404
+	case errors.Is(err, apperror.ErrConflict):
+		return http.StatusConflict, "conflict" // 409
+	case errors.Is(err, apperror.ErrUnavailable):
+		return http.StatusServiceUnavailable, "unavailable" // 503
+	}
+	return http.StatusInternalServerError, "internal_error"
+}
+
+// fieldErrors lists the invalid fields of a validation error: all of them for
+// apperror.ValidationErrors, or the single Field of a plain AppError.
+func fieldErrors(err error, appErr *apperror.AppError) []FieldErrorResponse {
+	var verrs *apperror.ValidationErrors
+	if errors.As(err, &verrs) {
+		out := make([]FieldErrorResponse, len(verrs.Fields))
+		for i, f := range verrs.Fields {
+			out[i] = FieldErrorResponse{Field: f.Field, Code: f.Code, Message: f.Message}
+		}
+		return out
+	}
+	if appErr.Field != "" {
+		code := appErr.Code
+		if code == apperror.CodeValidation {
+			code = "" // nothing more specific to say about the field
+		}
+		return []FieldErrorResponse{{Field: appErr.Field, Code: code, Message: appErr.Message}}
+	}
+	return nil
+}
+
+// writeDecodeError responds to a failed JSON body decode.
+//
+// Two different things can go wrong while decoding a request body:
+//   - The body exceeded the limit set by middleware.MaxBodySize. The reader
+//     returns *http.MaxBytesError → 413 Request Entity Too Large.
+//   - The body isn't valid JSON (or MessagePack) → 400 Bad Request.
+func writeDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	requestID := middleware.RequestID(r.Context())
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		resp := ErrorResponse{
+			Error:     "request_too_large",
+			Code:      apperror.CodeRequestTooLarge,
+			Message:   fmt.Sprintf("Request body must be %d bytes or less", tooLarge.Limit),
+			RequestID: requestID,
+		}
+		localize(w, r, &resp, map[string]string{"limit": strconv.FormatInt(tooLarge.Limit, 10)})
+		writeJSON(w, r, http.StatusRequestEntityTooLarge, resp)
+		return
+	}
+
+	resp := ErrorResponse{
+		Error:     "invalid_json",
+		Code:      apperror.CodeInvalidBody,
+		Message:   "Request body must be valid JSON",
+		RequestID: requestID,
+	}
+	if isMsgpackBody(r) {
+		resp.Error, resp.Message = "invalid_msgpack", "Request body must be valid MessagePack"
+	}
+	localize(w, r, &resp, nil)
+	writeJSON(w, r, http.StatusBadRequest, resp)
 }
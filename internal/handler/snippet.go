@@ -27,19 +27,24 @@
 package handler
 
 import (
-	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
 	"github.com/sakif/coding-playground/internal/service"
 )
 
 // SnippetHandler manages HTTP endpoints for code snippets.
 // It delegates all business logic to the SnippetService.
 type SnippetHandler struct {
-	service *service.SnippetService
-	logger  *slog.Logger
+	service   *service.SnippetService
+	logger    *slog.Logger
+	cdnMaxAge time.Duration // see CacheOnCDN
 }
 
 // NewSnippetHandler creates a new SnippetHandler.
@@ -57,6 +62,23 @@ func NewSnippetHandler(svc *service.SnippetService, logger *slog.Logger) *Snippe
 	}
 }
 
+// CacheOnCDN lets a CDN keep public snippets and their embeds for maxAge
+// (see internal/cdn). Call it before serving requests.
+func (h *SnippetHandler) CacheOnCDN(maxAge time.Duration) {
+	h.cdnMaxAge = maxAge
+}
+
+// --- Response Types ---
+
+// SnippetListResponse is the v1 envelope for GET /snippets.
+type SnippetListResponse struct {
+	Items   any  `json:"items"`   // []model.Snippet, or partial snippets with ?fields=
+	Total   int  `json:"total"`   // Snippets across all pages
+	Limit   int  `json:"limit"`   // Page size used
+	Offset  int  `json:"offset"`  // Snippets skipped before this page
+	HasMore bool `json:"hasMore"` // Whether offset+limit has more to fetch
+}
+
 // --- Request Types ---
 // These define the shape of JSON that clients send.
 // They are distinct from model.Snippet to control exactly what's accepted.
@@ -66,6 +88,7 @@ type CreateSnippetRequest struct {
 	Name        string `json:"name"`
 	Code        string `json:"code"`
 	Description string `json:"description"`
+	OrgID       string `json:"orgId"` // optional: create it in this org
 }
 
 // UpdateSnippetRequest is the expected JSON body for updating a snippet.
@@ -75,10 +98,33 @@ type UpdateSnippetRequest struct {
 	Description string `json:"description"`
 }
 
-// HandleList returns all saved snippets.
+// SetVisibilityRequest is the expected JSON body for publishing a snippet.
+// Public is a pointer so a missing field is an error, not "false".
+type SetVisibilityRequest struct {
+	Public *bool `json:"public"`
+}
+
+// SetRequirementsRequest is the expected JSON body for changing a
+// snippet's requirements. Requirements is a pointer so a missing field is
+// an error, not "no packages".
+type SetRequirementsRequest struct {
+	Requirements *string `json:"requirements"`
+}
+
+// HandleList returns a page of saved snippets.
 //
-// HTTP: GET /api/snippets
+// HTTP: GET /api/v1/snippets
 // Query params: ?limit=20&offset=0
+// Filters: ?createdAfter=, ?createdBefore=, ?updatedAfter= (RFC 3339 timestamp
+// or YYYY-MM-DD date, exclusive), ?hasOwner=true|false and ?org=<org id>
+// Sparse fieldsets: ?fields=id,name,updatedAt (see fields.go)
+// Authors: ?include=author adds each snippet's owner (login, avatar), so
+// the list can show them without fetching every user separately.
+//
+// RESPONSE ENVELOPE:
+// v1 wraps the page in an object — {"items": [...], "total": 42, ...} — so
+// paging metadata (and anything we add later) has somewhere to live. The
+// legacy unversioned /api alias still gets the bare array it always had.
 //
 // QUERY PARAMETER PARSING:
 // r.URL.Query().Get("param") returns the parameter as a string (or "" if absent).
@@ -89,19 +135,99 @@ func (h *SnippetHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
 
+	// Filters are stricter than paging: a typo in a date should be a 400,
+	// not silently return everything.
+	filter, err := parseListFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	fields, err := parseFields(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	filter.OmitCode = !wantsField(fields, "code")
+	switch include := r.URL.Query().Get("include"); include {
+	case "":
+	case "author":
+		filter.WithAuthors = wantsField(fields, "author")
+	default:
+		writeError(w, r, apperror.ValidationFailed("include", fmt.Sprintf("can't include %q (only \"author\")", include)))
+		return
+	}
+
 	// Delegate to the service (it handles defaults and clamping)
-	snippets, err := h.service.List(r.Context(), limit, offset)
+	page, err := h.service.List(r.Context(), limit, offset, filter)
 	if err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, snippets)
+	items := projectSnippets(page.Items, fields)
+
+	if APIVersionFromContext(r.Context()) == APIVersionUnversioned {
+		writeJSON(w, r, http.StatusOK, items)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, SnippetListResponse{
+		Items:   items,
+		Total:   page.Total,
+		Limit:   page.Limit,
+		Offset:  page.Offset,
+		HasMore: page.HasMore(),
+	})
+}
+
+// parseListFilter reads the snippet list filters from the query string.
+func parseListFilter(q url.Values) (service.ListFilter, error) {
+	var f service.ListFilter
+	var err error
+
+	if f.CreatedAfter, err = parseTimeParam(q, "createdAfter"); err != nil {
+		return f, err
+	}
+	if f.CreatedBefore, err = parseTimeParam(q, "createdBefore"); err != nil {
+		return f, err
+	}
+	if f.UpdatedAfter, err = parseTimeParam(q, "updatedAfter"); err != nil {
+		return f, err
+	}
+
+	if v := q.Get("hasOwner"); v != "" {
+		hasOwner, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, apperror.ValidationFailed("hasOwner", "hasOwner must be true or false")
+		}
+		f.HasOwner = &hasOwner
+	}
+	f.OrgID = q.Get("org")
+	return f, nil
+}
+
+// parseTimeParam parses an optional timestamp query parameter. It accepts a
+// full RFC 3339 timestamp (2024-05-01T12:00:00Z) or a plain date (2024-05-01,
+// meaning midnight UTC). A missing parameter is the zero time.
+func parseTimeParam(q url.Values, name string) (time.Time, error) {
+	v := q.Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, apperror.ValidationFailed(name,
+		fmt.Sprintf("%s must be an RFC 3339 timestamp or a YYYY-MM-DD date", name))
 }
 
 // HandleGetByID retrieves a single snippet by its ID.
 //
 // HTTP: GET /api/snippets/{id}
+// Query params: ?fields=id,name (optional, see fields.go)
 //
 // URL PARAMETERS:
 // Chi extracts named URL parameters from the path pattern.
@@ -110,19 +236,39 @@ func (h *SnippetHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 func (h *SnippetHandler) HandleGetByID(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 
+	fields, err := parseFields(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
 	snippet, err := h.service.GetByID(r.Context(), id)
 	if err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
+		return
+	}
+
+	// Conditional GET: skip the body if the client already has this version.
+	// A 304 must still carry the validators (see conditional.go).
+	setSnippetValidators(w, snippet)
+	setCDNHeaders(w, snippet, h.cdnMaxAge)
+	if notModified(r, snippetETag(snippet), snippet.UpdatedAt) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, snippet)
+	if fields != nil {
+		writeJSON(w, r, http.StatusOK, projectSnippet(snippet, fields))
+		return
+	}
+	writeJSON(w, r, http.StatusOK, snippet)
 }
 
 // HandleCreate saves a new snippet.
 //
 // HTTP: POST /api/snippets
 // Request body: {"name": "my snippet", "code": "print('hello')"}
+// With "orgId", the snippet belongs to that org (the user must be a member).
 //
 // REQUEST PARSING FLOW:
 // 1. json.NewDecoder(r.Body) creates a streaming JSON decoder
@@ -143,26 +289,29 @@ func (h *SnippetHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
 	var req CreateSnippetRequest
 
 	// Parse JSON body
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid snippet JSON",
+	if err := decodeBody(r, &req); err != nil {
+		h.logger.WarnContext(r.Context(), "invalid snippet JSON",
 			slog.String("error", err.Error()),
 		)
-		writeJSON(w, http.StatusBadRequest, ErrorResponse{
-			Error:   "invalid_json",
-			Message: "Request body must be valid JSON",
-		})
+		writeDecodeError(w, r, err)
 		return
 	}
 
 	// Delegate to service (handles validation, ID generation, persistence)
-	snippet, err := h.service.Create(r.Context(), req.Name, req.Code, req.Description)
+	var snippet *model.Snippet
+	var err error
+	if req.OrgID != "" {
+		snippet, err = h.service.CreateInOrg(r.Context(), req.OrgID, req.Name, req.Code, req.Description)
+	} else {
+		snippet, err = h.service.Create(r.Context(), req.Name, req.Code, req.Description)
+	}
 	if err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
 	// 201 Created — the standard status code for successful resource creation
-	writeJSON(w, http.StatusCreated, snippet)
+	writeJSON(w, r, http.StatusCreated, snippet)
 }
 
 // HandleUpdate modifies an existing snippet.
@@ -179,25 +328,88 @@ func (h *SnippetHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 
 	var req UpdateSnippetRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid snippet JSON",
+	if err := decodeBody(r, &req); err != nil {
+		h.logger.WarnContext(r.Context(), "invalid snippet JSON",
 			slog.String("error", err.Error()),
 			slog.String("id", id),
 		)
-		writeJSON(w, http.StatusBadRequest, ErrorResponse{
-			Error:   "invalid_json",
-			Message: "Request body must be valid JSON",
-		})
+		writeDecodeError(w, r, err)
 		return
 	}
 
 	snippet, err := h.service.Update(r.Context(), id, req.Name, req.Code, req.Description)
 	if err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
+		return
+	}
+
+	// Return the new validators so the editor can poll with them right away.
+	setSnippetValidators(w, snippet)
+	writeJSON(w, r, http.StatusOK, snippet)
+}
+
+// HandleSetVisibility publishes a snippet to the Atom feeds or unpublishes it.
+// Only the snippet's owner may do this.
+//
+// HTTP: PUT /api/v1/snippets/{id}/visibility
+// Request body: {"public": true}
+func (h *SnippetHandler) HandleSetVisibility(w http.ResponseWriter, r *http.Request) {
+	var req SetVisibilityRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+	if req.Public == nil {
+		writeError(w, r, apperror.ValidationFailed("public", "public must be true or false"))
+		return
+	}
+
+	snippet, err := h.service.SetPublic(r.Context(), r.PathValue("id"), *req.Public)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	setSnippetValidators(w, snippet)
+	writeJSON(w, r, http.StatusOK, snippet)
+}
+
+// HandleSetRequirements replaces the packages a snippet installs before
+// it runs. Anyone who may edit the snippet may change them.
+//
+// HTTP: PUT /api/v1/snippets/{id}/requirements
+// Request body: {"requirements": "numpy==1.26.4\n"}
+func (h *SnippetHandler) HandleSetRequirements(w http.ResponseWriter, r *http.Request) {
+	var req SetRequirementsRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+	if req.Requirements == nil {
+		writeError(w, r, apperror.ValidationFailed("requirements", "requirements must be a string, empty for none"))
 		return
 	}
 
-	writeJSON(w, http.StatusOK, snippet)
+	snippet, err := h.service.SetRequirements(r.Context(), r.PathValue("id"), *req.Requirements)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	setSnippetValidators(w, snippet)
+	writeJSON(w, r, http.StatusOK, snippet)
+}
+
+// HandleFork copies a snippet into a new one owned by the signed-in user.
+//
+// HTTP: POST /api/v1/snippets/{id}/fork
+func (h *SnippetHandler) HandleFork(w http.ResponseWriter, r *http.Request) {
+	fork, err := h.service.Fork(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, fork)
 }
 
 // HandleDelete removes a saved snippet.
@@ -212,7 +424,7 @@ func (h *SnippetHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 
 	if err := h.service.Delete(r.Context(), id); err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
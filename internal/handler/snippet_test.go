@@ -0,0 +1,421 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/handler"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository/sqlite"
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// newSnippetRouter wires a SnippetHandler to a throwaway database.
+func newSnippetRouter(t *testing.T) (http.Handler, *service.SnippetService) {
+	t.Helper()
+
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	svc := service.NewSnippetService(db, logger)
+	h := handler.NewSnippetHandler(svc, logger)
+
+	r := chi.NewRouter()
+	r.Get("/snippets", h.HandleList) // no version middleware: legacy shape
+	r.Get("/snippets/{id}", h.HandleGetByID)
+	r.Get("/snippets/{id}/html", h.HandleGetHTML)
+	r.Post("/snippets", h.HandleCreate)
+	r.With(handler.WithAPIVersion(handler.APIVersion1)).Get("/v1/snippets", h.HandleList)
+	return r, svc
+}
+
+func TestSnippetHandler_ListEnvelope(t *testing.T) {
+	router, svc := newSnippetRouter(t)
+	for _, name := range []string{"one", "two", "three"} {
+		_, err := svc.Create(context.Background(), name, "print(1)", "")
+		require.NoError(t, err)
+	}
+
+	t.Run("v1 returns a page envelope", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/snippets?limit=2", nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var page handler.SnippetListResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&page))
+		assert.Len(t, page.Items, 2)
+		assert.Equal(t, 3, page.Total)
+		assert.Equal(t, 2, page.Limit)
+		assert.Equal(t, 0, page.Offset)
+		assert.True(t, page.HasMore)
+	})
+
+	t.Run("unversioned returns a bare array", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/snippets", nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var items []model.Snippet
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&items))
+		assert.Len(t, items, 3)
+	})
+}
+
+func TestSnippetHandler_ConditionalGet(t *testing.T) {
+	router, svc := newSnippetRouter(t)
+	snippet, err := svc.Create(context.Background(), "hello", "print('hi')", "")
+	require.NoError(t, err)
+	path := "/snippets/" + snippet.ID
+
+	get := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	first := get("", "")
+	require.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	lastModified := first.Header().Get("Last-Modified")
+	assert.Regexp(t, `^W/".+"$`, etag)
+	assert.NotEmpty(t, lastModified)
+
+	t.Run("matching If-None-Match returns 304", func(t *testing.T) {
+		rr := get("If-None-Match", etag)
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+		assert.Empty(t, rr.Body.String())
+		assert.Equal(t, etag, rr.Header().Get("ETag"))
+	})
+
+	t.Run("If-None-Match list and wildcard", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotModified, get("If-None-Match", `"other", `+etag).Code)
+		assert.Equal(t, http.StatusNotModified, get("If-None-Match", "*").Code)
+	})
+
+	t.Run("stale If-None-Match returns 200", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, get("If-None-Match", `W/"stale"`).Code)
+	})
+
+	t.Run("If-Modified-Since", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotModified, get("If-Modified-Since", lastModified).Code)
+		assert.Equal(t, http.StatusOK, get("If-Modified-Since", "Mon, 01 Jan 2001 00:00:00 GMT").Code)
+	})
+
+	t.Run("update changes the ETag", func(t *testing.T) {
+		_, err := svc.Update(context.Background(), snippet.ID, "hello", "print('changed')", "")
+		require.NoError(t, err)
+
+		rr := get("If-None-Match", etag)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.NotEqual(t, etag, rr.Header().Get("ETag"))
+	})
+}
+
+func TestSnippetHandler_ListFilters(t *testing.T) {
+	router, svc := newSnippetRouter(t)
+	_, err := svc.Create(context.Background(), "one", "print(1)", "")
+	require.NoError(t, err)
+
+	list := func(query string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/snippets?"+query, nil))
+		return rr
+	}
+
+	t.Run("valid filters", func(t *testing.T) {
+		rr := list("createdAfter=2000-01-01&updatedAfter=2000-01-01T00:00:00Z&hasOwner=false")
+		require.Equal(t, http.StatusOK, rr.Code)
+		var page handler.SnippetListResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&page))
+		assert.Equal(t, 1, page.Total)
+	})
+
+	t.Run("future createdAfter matches nothing", func(t *testing.T) {
+		rr := list("createdAfter=2999-01-01")
+		require.Equal(t, http.StatusOK, rr.Code)
+		var page handler.SnippetListResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&page))
+		assert.Empty(t, page.Items)
+		assert.Zero(t, page.Total)
+	})
+
+	for _, query := range []string{
+		"createdAfter=yesterday",
+		"createdBefore=2024-13-01",
+		"hasOwner=maybe",
+		"createdAfter=2024-05-02&createdBefore=2024-05-01",
+	} {
+		t.Run("rejects "+query, func(t *testing.T) {
+			rr := list(query)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Contains(t, rr.Body.String(), "validation_error")
+		})
+	}
+}
+
+func TestSnippetHandler_SparseFieldsets(t *testing.T) {
+	router, svc := newSnippetRouter(t)
+	snippet, err := svc.Create(context.Background(), "hello", "print('hi')", "greets")
+	require.NoError(t, err)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+
+	t.Run("list returns only the requested fields", func(t *testing.T) {
+		rr := get("/v1/snippets?fields=id,name")
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var page struct {
+			Items []map[string]any `json:"items"`
+		}
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&page))
+		require.Len(t, page.Items, 1)
+		assert.Equal(t, map[string]any{"id": snippet.ID, "name": "hello"}, page.Items[0])
+	})
+
+	t.Run("list includes code when asked for", func(t *testing.T) {
+		rr := get("/v1/snippets?fields=code")
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"code":"print('hi')"`)
+	})
+
+	t.Run("single snippet", func(t *testing.T) {
+		rr := get("/snippets/" + snippet.ID + "?fields=name,updatedAt")
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var got map[string]any
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&got))
+		assert.Len(t, got, 2)
+		assert.Equal(t, "hello", got["name"])
+		assert.Contains(t, got, "updatedAt")
+	})
+
+	t.Run("unknown field is a 400", func(t *testing.T) {
+		rr := get("/v1/snippets?fields=id,password")
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "password")
+	})
+}
+
+func TestSnippetHandler_IncludeAuthor(t *testing.T) {
+	router, svc := newSnippetRouter(t)
+	_, err := svc.Create(context.Background(), "anonymous", "print(1)", "")
+	require.NoError(t, err)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/snippets?"+query, nil))
+		return rr
+	}
+
+	// An anonymous snippet has no author to include.
+	rr := get("include=author")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), `"author"`)
+
+	rr = get("include=author&fields=id,author")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"author":null`)
+
+	rr = get("include=comments")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "include")
+}
+
+func TestSnippetHandler_CreateReportsAllInvalidFields(t *testing.T) {
+	router, _ := newSnippetRouter(t)
+
+	body := `{"name": "", "code": "` + strings.Repeat("x", service.MaxCodeLength+1) + `"}`
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/snippets", strings.NewReader(body)))
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var resp handler.ErrorResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, "validation_error", resp.Error)
+	assert.Equal(t, apperror.CodeValidation, resp.Code)
+	assert.Equal(t, []handler.FieldErrorResponse{
+		{Field: "name", Code: apperror.CodeNameRequired, Message: "snippet name is required"},
+		{Field: "code", Code: apperror.CodeCodeTooLong, Message: fmt.Sprintf("code must be %d characters or less", service.MaxCodeLength)},
+	}, resp.Errors)
+}
+
+func TestSnippetHandler_LocalizedErrors(t *testing.T) {
+	router, _ := newSnippetRouter(t)
+	send := func(method, path, body, lang string) (*httptest.ResponseRecorder, handler.ErrorResponse) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Accept-Language", lang)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var resp handler.ErrorResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		return rr, resp
+	}
+
+	body := `{"name": "", "code": "` + strings.Repeat("x", service.MaxCodeLength+1) + `"}`
+	rr, resp := send(http.MethodPost, "/snippets", body, "fr-CA, fr;q=0.9, en;q=0.5")
+	assert.Equal(t, "fr", rr.Header().Get("Content-Language"))
+	assert.Equal(t, []handler.FieldErrorResponse{
+		{Field: "name", Code: apperror.CodeNameRequired, Message: "Un nom est obligatoire."},
+		{Field: "code", Code: apperror.CodeCodeTooLong, Message: fmt.Sprintf("Le code doit faire au plus %d caractères.", service.MaxCodeLength)},
+	}, resp.Errors)
+	assert.Equal(t, resp.Errors[0].Message+"; "+resp.Errors[1].Message, resp.Message)
+
+	rr, resp = send(http.MethodGet, "/snippets/missing", "", "es")
+	assert.Equal(t, "es", rr.Header().Get("Content-Language"))
+	assert.Equal(t, "Ningún fragmento tiene el identificador missing, o es privado.", resp.Message)
+
+	// No translation: English, and no Content-Language.
+	rr, resp = send(http.MethodGet, "/snippets/missing", "", "ja")
+	assert.Empty(t, rr.Header().Get("Content-Language"))
+	assert.Equal(t, "snippet not found with id missing", resp.Message)
+	assert.Contains(t, rr.Header().Values("Vary"), "Accept-Language")
+}
+
+func TestSnippetHandler_ErrorCodes(t *testing.T) {
+	router, _ := newSnippetRouter(t)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/snippets/missing", nil))
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	var resp handler.ErrorResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, apperror.CodeSnippetNotFound, resp.Code)
+
+	rr = httptest.NewRecorder()
+	handler.HandleErrorCodes(rr, httptest.NewRequest(http.MethodGet, "/api/v1/errors", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	var catalog struct {
+		Codes []struct {
+			Code   apperror.Code `json:"code"`
+			Status int           `json:"status"`
+		} `json:"codes"`
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&catalog))
+	statuses := map[apperror.Code]int{}
+	for _, c := range catalog.Codes {
+		statuses[c.Code] = c.Status
+	}
+	assert.Equal(t, http.StatusNotFound, statuses[apperror.CodeSnippetNotFound])
+	assert.Equal(t, http.StatusBadRequest, statuses[apperror.CodeNameTooLong])
+	assert.Contains(t, statuses, apperror.CodeExecutionTimeout)
+}
+
+func TestSnippetHandler_HTML(t *testing.T) {
+	router, svc := newSnippetRouter(t)
+	snippet, err := svc.Create(context.Background(), "xss", "print('</pre><script>alert(1)</script>')\nx = 1", "")
+	require.NoError(t, err)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/snippets/"+snippet.ID+"/html"+query, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Header().Get("Content-Security-Policy"), "default-src 'none'")
+
+	body := rr.Body.String()
+	assert.NotContains(t, body, "<script>", "code must be escaped")
+	assert.Contains(t, body, "&lt;script&gt;")
+	assert.Contains(t, body, `id="L2"`, "lines should be numbered and linkable")
+
+	t.Run("style changes the output and the ETag", func(t *testing.T) {
+		other := get("?style=github")
+		require.Equal(t, http.StatusOK, other.Code)
+		assert.NotEqual(t, body, other.Body.String())
+		assert.NotEqual(t, rr.Header().Get("ETag"), other.Header().Get("ETag"))
+	})
+
+	t.Run("unknown style is a 400", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get("?style=nope").Code)
+	})
+
+	t.Run("unknown snippet is a 404", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/snippets/missing/html", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestSnippetHandler_Msgpack(t *testing.T) {
+	router, _ := newSnippetRouter(t)
+
+	body, err := msgpack.Marshal(map[string]string{"name": "packed", "code": "print(1)"})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/snippets", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/msgpack")
+	req.Header.Set("Accept", "application/msgpack")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "application/msgpack", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", rr.Header().Get("Vary"))
+
+	var created map[string]any
+	require.NoError(t, msgpack.Unmarshal(rr.Body.Bytes(), &created))
+	assert.Equal(t, "packed", created["name"], "fields use the JSON names")
+	id, _ := created["id"].(string)
+	require.NotEmpty(t, id)
+
+	get := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/snippets/"+id, nil)
+		req.Header.Set("Accept", accept)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		return rr
+	}
+
+	t.Run("preferred over JSON", func(t *testing.T) {
+		rr := get("application/json;q=0.5, application/x-msgpack")
+		assert.Equal(t, "application/msgpack", rr.Header().Get("Content-Type"))
+	})
+
+	t.Run("browsers keep getting JSON", func(t *testing.T) {
+		rr := get("text/html,application/xhtml+xml,*/*;q=0.8")
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	})
+
+	t.Run("JSON wins when preferred", func(t *testing.T) {
+		rr := get("application/json, application/msgpack;q=0.9")
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	})
+
+	t.Run("invalid body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/snippets", strings.NewReader("\xc1"))
+		req.Header.Set("Content-Type", "application/msgpack")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "invalid_msgpack")
+	})
+}
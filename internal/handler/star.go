@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// StarHandler lets signed-in users star and unstar snippets.
+type StarHandler struct {
+	service *service.StarService
+	logger  *slog.Logger
+}
+
+// NewStarHandler creates a new StarHandler.
+func NewStarHandler(svc *service.StarService, logger *slog.Logger) *StarHandler {
+	return &StarHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// HandleStar stars a snippet. Starring it again changes nothing.
+//
+// HTTP: PUT /api/v1/snippets/{id}/star
+func (h *StarHandler) HandleStar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	status, err := h.service.Star(r.Context(), userID, r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, status)
+}
+
+// HandleUnstar removes the caller's star from a snippet.
+//
+// HTTP: DELETE /api/v1/snippets/{id}/star
+func (h *StarHandler) HandleUnstar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	status, err := h.service.Unstar(r.Context(), userID, r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, status)
+}
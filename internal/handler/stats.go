@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// StatsHandler serves the signed-in user's dashboard statistics.
+type StatsHandler struct {
+	service *service.StatsService
+	logger  *slog.Logger
+}
+
+// NewStatsHandler creates a new StatsHandler.
+func NewStatsHandler(svc *service.StatsService, logger *slog.Logger) *StatsHandler {
+	return &StatsHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// HandleMe returns the signed-in user's stats.
+//
+// HTTP: GET /api/v1/me/stats
+func (h *StatsHandler) HandleMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	stats, err := h.service.ForUser(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, stats)
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// StorageHandler shows admins the database's size and checkpoints its WAL.
+type StorageHandler struct {
+	service *service.StorageService
+	logger  *slog.Logger
+}
+
+// NewStorageHandler creates a new StorageHandler.
+func NewStorageHandler(svc *service.StorageService, logger *slog.Logger) *StorageHandler {
+	return &StorageHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// HandleStats returns the database's page counts and WAL size.
+//
+// HTTP: GET /api/v1/admin/db
+func (h *StorageHandler) HandleStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.service.Stats(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, stats)
+}
+
+// HandleCheckpoint checkpoints and truncates the WAL now, rather than at
+// the next scheduled run.
+//
+// HTTP: POST /api/v1/admin/db/checkpoint
+func (h *StorageHandler) HandleCheckpoint(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.Checkpoint(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, result)
+}
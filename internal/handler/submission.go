@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// SubmissionHandler grades learners' solutions to exercises.
+type SubmissionHandler struct {
+	grading *service.GradingService
+	logger  *slog.Logger
+}
+
+// NewSubmissionHandler creates a new SubmissionHandler.
+func NewSubmissionHandler(grading *service.GradingService, logger *slog.Logger) *SubmissionHandler {
+	return &SubmissionHandler{
+		grading: grading,
+		logger:  logger,
+	}
+}
+
+// SubmitRequest is the expected JSON body for submitting a solution.
+type SubmitRequest struct {
+	Code string `json:"code"`
+}
+
+// HandleSubmit runs the signed-in user's code against an exercise's hidden
+// tests and returns the graded submission. A solution that fails or crashes
+// is still a 201: the submission was made, it just didn't pass.
+//
+// HTTP: POST /api/v1/exercises/{id}/submit
+// Request body: {"code": "def add(a, b):\n    return a + b"}
+func (h *SubmissionHandler) HandleSubmit(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req SubmitRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	submission, err := h.grading.Submit(r.Context(), userID, r.PathValue("id"), req.Code)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, submission)
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/scheduler"
+)
+
+// TaskHandler exposes the status of scheduled maintenance tasks to admins.
+type TaskHandler struct {
+	sched *scheduler.Scheduler
+}
+
+// NewTaskHandler creates a new TaskHandler.
+func NewTaskHandler(sched *scheduler.Scheduler) *TaskHandler {
+	return &TaskHandler{sched: sched}
+}
+
+// HandleList returns each task's schedule, last run and next run.
+//
+// HTTP: GET /api/v1/admin/tasks
+func (h *TaskHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, h.sched.Statuses())
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/auth"
+)
+
+// TokenResponse is an API token for programmatic clients.
+type TokenResponse struct {
+	Token     string    `json:"token"`
+	TokenType string    `json:"tokenType"` // always "Bearer"
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// HandleIssueToken returns a fresh token for the signed-in user, to send as
+// "Authorization: Bearer <token>" from scripts and the Go client, which can't
+// use the browser's session cookie. It must run behind auth.RequireAuth.
+//
+// HTTP: POST /api/v1/me/token
+func HandleIssueToken(tokens *auth.TokenService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := requireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		expiresAt := time.Now().Add(auth.DefaultTokenDuration).UTC().Truncate(time.Second)
+		token, err := tokens.Generate(userID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeJSON(w, r, http.StatusCreated, TokenResponse{Token: token, TokenType: "Bearer", ExpiresAt: expiresAt})
+	}
+}
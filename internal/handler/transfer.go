@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// TransferHandler serves snippet transfers. Every route sits behind
+// auth.RequireAuth.
+type TransferHandler struct {
+	service *service.TransferService
+	logger  *slog.Logger
+}
+
+// NewTransferHandler creates a new TransferHandler.
+func NewTransferHandler(svc *service.TransferService, logger *slog.Logger) *TransferHandler {
+	return &TransferHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// TransferRequest is the expected JSON body for offering a snippet: a
+// user's login or an org's ID, not both.
+type TransferRequest struct {
+	Login string `json:"login"`
+	OrgID string `json:"orgId"`
+}
+
+// HandleOffer offers a snippet to another user or an org.
+//
+// HTTP: POST /api/v1/snippets/{id}/transfer
+// Request body: {"login": "bob"} or {"orgId": "…"}
+func (h *TransferHandler) HandleOffer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	var req TransferRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	t, err := h.service.Offer(r.Context(), userID, r.PathValue("id"), service.TransferTarget{Login: req.Login, OrgID: req.OrgID})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, t)
+}
+
+// HandleList returns the signed-in user's pending transfers, both ways.
+//
+// HTTP: GET /api/v1/me/transfers
+func (h *TransferHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.service.List(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, list)
+}
+
+// HandleAccept takes a snippet that was offered to the signed-in user, or
+// to an org they own, and returns it.
+//
+// HTTP: POST /api/v1/transfers/{id}/accept
+func (h *TransferHandler) HandleAccept(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	snippet, err := h.service.Accept(r.Context(), userID, r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, snippet)
+}
+
+// HandleDelete declines an offer, or withdraws one the signed-in user made.
+//
+// HTTP: DELETE /api/v1/transfers/{id}
+func (h *TransferHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(r.Context(), userID, r.PathValue("id")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,108 @@
+package handler
+
+// API VERSIONING:
+// Once other programs depend on our JSON shapes, we can't change them freely.
+// Versioning lets old clients keep working while new ones opt into changes.
+//
+// We version by URL prefix — /api/v1/snippets — because it's the most visible
+// and cache-friendly scheme. The router pins each prefix to a version with
+// WithAPIVersion, and handlers ask APIVersionFromContext when their behaviour
+// differs between versions.
+//
+// The unversioned /api prefix is a temporary alias for the latest stable version.
+// Clients on that alias may still pick a version explicitly with a vendor media
+// type, which NegotiateAPIVersion understands:
+//
+//	Accept: application/vnd.pyplayground.v1+json
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+)
+
+// APIVersion identifies a major version of the HTTP API.
+type APIVersion int
+
+const (
+	// APIVersionUnversioned marks requests made through the legacy /api alias
+	// without an explicit version. They are served by the latest version's
+	// handlers but keep pre-versioning response shapes where those differ.
+	APIVersionUnversioned APIVersion = 0
+	APIVersion1           APIVersion = 1
+
+	// LatestAPIVersion is the newest version clients may request.
+	LatestAPIVersion = APIVersion1
+)
+
+// String renders the version the way it appears in URLs and headers ("v1").
+func (v APIVersion) String() string {
+	if v == APIVersionUnversioned {
+		return "unversioned"
+	}
+	return "v" + strconv.Itoa(int(v))
+}
+
+type apiVersionKey struct{}
+
+// WithAPIVersion returns middleware that pins every request to version v
+// and advertises it in the API-Version response header.
+func WithAPIVersion(v APIVersion) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("API-Version", v.String())
+			ctx := context.WithValue(r.Context(), apiVersionKey{}, v)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// NegotiateAPIVersion is middleware for unversioned prefixes: it reads a vendor
+// media type from the Accept header and pins the request to that version.
+// Without one, the request is marked APIVersionUnversioned. Asking for a
+// version we don't serve is a 406 Not Acceptable.
+func NegotiateAPIVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v, ok := RequestedAPIVersion(r)
+		if !ok {
+			WithAPIVersion(APIVersionUnversioned)(next).ServeHTTP(w, r)
+			return
+		}
+		if v < APIVersion1 || v > LatestAPIVersion {
+			writeJSON(w, r, http.StatusNotAcceptable, ErrorResponse{
+				Error:   "unsupported_version",
+				Code:    apperror.CodeUnsupportedAPI,
+				Message: fmt.Sprintf("API version %s is not supported (latest is %s)", v, LatestAPIVersion),
+			})
+			return
+		}
+		WithAPIVersion(v)(next).ServeHTTP(w, r)
+	})
+}
+
+// vendorMediaType matches "application/vnd.pyplayground.v<N>+json".
+var vendorMediaType = regexp.MustCompile(`application/vnd\.pyplayground\.v(\d+)\+json`)
+
+// RequestedAPIVersion extracts an explicitly requested version from the Accept header.
+func RequestedAPIVersion(r *http.Request) (APIVersion, bool) {
+	m := vendorMediaType.FindStringSubmatch(r.Header.Get("Accept"))
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, false
+	}
+	return APIVersion(n), true
+}
+
+// APIVersionFromContext returns the version the request was routed to.
+// Requests that never passed through version middleware count as unversioned.
+func APIVersionFromContext(ctx context.Context) APIVersion {
+	v, _ := ctx.Value(apiVersionKey{}).(APIVersion)
+	return v
+}
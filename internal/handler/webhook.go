@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/middleware"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// WebhookHandler lets signed-in users manage their webhooks. Every route sits
+// behind auth.RequireAuth, and users only ever see their own webhooks.
+type WebhookHandler struct {
+	service *service.WebhookService
+	logger  *slog.Logger
+}
+
+// NewWebhookHandler creates a new WebhookHandler.
+func NewWebhookHandler(svc *service.WebhookService, logger *slog.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		service: svc,
+		logger:  logger,
+	}
+}
+
+// CreateWebhookRequest is the expected JSON body for registering a webhook.
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// CreateWebhookResponse is the created webhook plus its signing secret, which
+// is only ever returned here.
+type CreateWebhookResponse struct {
+	*model.Webhook
+	Secret string `json:"secret"`
+}
+
+// HandleCreate registers a webhook.
+//
+// HTTP: POST /api/v1/webhooks
+// Request body: {"url": "https://example.com/hook", "events": ["snippet.created"]}
+func (h *WebhookHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	webhook, err := h.service.Create(r.Context(), userID, req.URL, req.Events)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, CreateWebhookResponse{Webhook: webhook, Secret: webhook.Secret})
+}
+
+// HandleList returns the signed-in user's webhooks (without secrets).
+//
+// HTTP: GET /api/v1/webhooks
+func (h *WebhookHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	webhooks, err := h.service.List(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, webhooks)
+}
+
+// HandleDelete removes a webhook.
+//
+// HTTP: DELETE /api/v1/webhooks/{id}
+func (h *WebhookHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), userID, r.PathValue("id")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleDeliveries returns a webhook's recent delivery attempts, newest first.
+//
+// HTTP: GET /api/v1/webhooks/{id}/deliveries?limit=20
+func (h *WebhookHandler) HandleDeliveries(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	deliveries, err := h.service.Deliveries(r.Context(), userID, r.PathValue("id"), limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, deliveries)
+}
+
+// requireUserID returns the signed-in user's ID, or answers 401 if there is
+// none (which RequireAuth should already have prevented).
+func requireUserID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		writeJSON(w, r, http.StatusUnauthorized, ErrorResponse{
+			Error:     "unauthorized",
+			Code:      apperror.CodeUnauthorized,
+			Message:   "not authenticated",
+			RequestID: middleware.RequestID(r.Context()),
+		})
+		return "", false
+	}
+	return userID, true
+}
@@ -0,0 +1,167 @@
+// Package i18n translates the messages of error responses into the
+// language a client asks for with Accept-Language.
+//
+// WHAT'S TRANSLATED:
+// An error response's message, and each invalid field's, is looked up by
+// its error code (see apperror.Code) — never by its English text, which
+// gets reworded. The English messages are the ones in the code; a message
+// with no translation in any language the client accepts stays English.
+//
+//	Accept-Language: fr-CA, fr;q=0.9, en;q=0.5
+//	→ Content-Language: fr
+//	  {"code":"NAME_TOO_LONG","message":"Le nom doit faire au plus 100 caractères."}
+//
+// TRANSLATION FILES:
+// One JSON file per language, named by its BCP 47 tag (fr.json, pt-BR.json),
+// mapping error codes to messages. A message can use the error's
+// parameters (see apperror.Params) as {name}:
+//
+//	{
+//	  "SNIPPET_NOT_FOUND": "Aucun extrait n'a l'identifiant {id}, ou il est privé.",
+//	  "NAME_TOO_LONG": "Le nom doit faire au plus {max} caractères."
+//	}
+//
+// The files in locales/ are built in. AddDir loads more from a directory
+// (TRANSLATIONS_DIR), adding languages or overriding built-in messages, so
+// a classroom can add its own without a rebuild. Every key must be a
+// catalogued code, so a typo fails at startup rather than going unused; a
+// message whose parameter the error doesn't have falls back to English.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+)
+
+//go:embed locales/*.json
+var builtin embed.FS
+
+// placeholder matches a {name} in a message.
+var placeholder = regexp.MustCompile(`\{([a-zA-Z_]+)\}`)
+
+var (
+	mu       sync.RWMutex
+	tags     = []language.Tag{language.English} // English first: the fallback
+	messages = map[language.Tag]map[apperror.Code]string{}
+	matcher  language.Matcher
+)
+
+func init() {
+	if err := load(builtin, "locales"); err != nil {
+		panic("i18n: built-in translations: " + err.Error())
+	}
+}
+
+// AddDir loads the translation files (*.json) in dir, on top of the
+// built-in ones. Call it before serving requests.
+func AddDir(dir string) error {
+	return load(os.DirFS(dir), ".")
+}
+
+func load(fsys fs.FS, dir string) error {
+	paths, err := fs.Glob(fsys, filepath.ToSlash(filepath.Join(dir, "*.json")))
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, path := range paths {
+		tag, err := language.Parse(strings.TrimSuffix(filepath.Base(path), ".json"))
+		if err != nil {
+			return fmt.Errorf("%s: file name isn't a language tag: %w", path, err)
+		}
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		var file map[apperror.Code]string
+		if err := json.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		for code := range file {
+			if _, ok := apperror.Lookup(code); !ok {
+				return fmt.Errorf("%s: %s isn't an error code", path, code)
+			}
+		}
+		if messages[tag] == nil {
+			messages[tag] = map[apperror.Code]string{}
+			tags = append(tags, tag)
+		}
+		for code, msg := range file {
+			messages[tag][code] = msg
+		}
+	}
+	matcher = language.NewMatcher(tags)
+	return nil
+}
+
+// Languages returns the languages messages can be translated into,
+// English first.
+func Languages() []language.Tag {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]language.Tag(nil), tags...)
+}
+
+// Translator translates messages into one language.
+type Translator struct {
+	lang     language.Tag
+	messages map[apperror.Code]string
+}
+
+// For returns a Translator for the best language for acceptLanguage (an
+// Accept-Language header), which may be English.
+func For(acceptLanguage string) Translator {
+	if acceptLanguage == "" {
+		return Translator{lang: language.English}
+	}
+	accepted, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(accepted) == 0 {
+		return Translator{lang: language.English}
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	_, i, confidence := matcher.Match(accepted...)
+	if confidence == language.No {
+		return Translator{lang: language.English}
+	}
+	return Translator{lang: tags[i], messages: messages[tags[i]]}
+}
+
+// Language is the language t translates into.
+func (t Translator) Language() language.Tag {
+	return t.lang
+}
+
+// Message returns the message for code with params filled in, or
+// fallback if there's no translation for code or it uses a parameter
+// params doesn't have. translated says which it was.
+func (t Translator) Message(code apperror.Code, params map[string]string, fallback string) (msg string, translated bool) {
+	tmpl, ok := t.messages[code]
+	if !ok {
+		return fallback, false
+	}
+	missing := false
+	msg = placeholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+		v, ok := params[m[1:len(m)-1]]
+		if !ok {
+			missing = true
+		}
+		return v
+	})
+	if missing {
+		return fallback, false
+	}
+	return msg, true
+}
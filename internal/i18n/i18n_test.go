@@ -0,0 +1,85 @@
+package i18n
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/text/language"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+)
+
+func TestFor(t *testing.T) {
+	for _, tt := range []struct {
+		header string
+		want   language.Tag
+	}{
+		{"", language.English},
+		{"fr", language.French},
+		{"fr-CA, fr;q=0.9, en;q=0.5", language.French},
+		{"it, es;q=0.8", language.Spanish},
+		{"en-GB, fr;q=0.5", language.English},
+		{"it", language.English},
+		{"not a header;;", language.English},
+	} {
+		if got := For(tt.header).Language(); got != tt.want {
+			t.Errorf("For(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestMessage(t *testing.T) {
+	fr := For("fr")
+	msg, ok := fr.Message(apperror.CodeNameTooLong, map[string]string{"max": "100"}, "name must be 100 characters or less")
+	if !ok || msg != "Le nom doit faire au plus 100 caractères." {
+		t.Errorf("Message(NAME_TOO_LONG) = %q, %v", msg, ok)
+	}
+	// Without the parameter the translation needs, English it is.
+	if msg, ok := fr.Message(apperror.CodeNameTooLong, nil, "too long"); ok || msg != "too long" {
+		t.Errorf("Message() without max = %q, %v; want the fallback", msg, ok)
+	}
+	if msg, ok := For("en").Message(apperror.CodeNameRequired, nil, "name is required"); ok || msg != "name is required" {
+		t.Errorf("English Message() = %q, %v; want the message as it is", msg, ok)
+	}
+}
+
+// Every built-in file must cover every code, so no built-in language
+// silently falls back to English for some of them.
+func TestBuiltinCoverage(t *testing.T) {
+	paths, _ := fs.Glob(builtin, "locales/*.json")
+	if len(paths) == 0 {
+		t.Fatal("no built-in translations")
+	}
+	for _, path := range paths {
+		data, _ := fs.ReadFile(builtin, path)
+		var file map[apperror.Code]string
+		if err := json.Unmarshal(data, &file); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		for _, e := range apperror.Catalog() {
+			if file[e.Code] == "" {
+				t.Errorf("%s has no message for %s", path, e.Code)
+			}
+		}
+	}
+}
+
+func TestAddDir(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "de.json"), []byte(`{"NAME_REQUIRED": "Ein Name ist erforderlich."}`), 0o644)
+	if err := AddDir(dir); err != nil {
+		t.Fatalf("AddDir() error = %v", err)
+	}
+	if msg, _ := For("de").Message(apperror.CodeNameRequired, nil, ""); msg != "Ein Name ist erforderlich." {
+		t.Errorf("German message = %q", msg)
+	}
+
+	bad := t.TempDir()
+	os.WriteFile(filepath.Join(bad, "nl.json"), []byte(`{"NAME_TOO_SHORT": "…"}`), 0o644)
+	if err := AddDir(bad); err == nil {
+		t.Error("AddDir() accepted a code that doesn't exist")
+	}
+}
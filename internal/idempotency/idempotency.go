@@ -0,0 +1,242 @@
+// Package idempotency makes POST requests safe to retry with an
+// Idempotency-Key header.
+//
+// THE PROBLEM:
+// A client sends POST /snippets, the network drops before the response
+// arrives, and the client retries. Did the first request succeed? It can't
+// know — so without help, a retry (or an impatient double-click) creates a
+// duplicate snippet or runs the code twice.
+//
+// THE FIX:
+// The client generates a unique key per logical operation (a UUID is
+// typical) and sends it with every attempt:
+//
+//	POST /api/v1/snippets
+//	Idempotency-Key: 6f1c0d9e-...
+//
+// The first request with a key runs normally and its response is stored.
+// Any retry with the same key gets the stored response back — with an
+// Idempotent-Replayed: true header — instead of running again.
+//
+// Keys are scoped to the signed-in user (or the client IP for anonymous
+// requests), so one client can't replay another's responses. Reusing a key
+// with a different request body is a client bug and gets 422; a retry that
+// arrives while the first attempt is still running gets 409.
+//
+// Server errors (5xx) aren't stored: the operation may not have happened, so
+// a retry should really retry.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/middleware"
+)
+
+const (
+	// Header is the request header carrying the client's key.
+	Header = "Idempotency-Key"
+	// ReplayedHeader marks a response served from the store.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+	// maxStoredBody caps what we keep per key; bigger responses aren't stored.
+	maxStoredBody = 1 << 20
+)
+
+// Record is a stored key and, once the first request finishes, its response.
+type Record struct {
+	Scope       string // user ID or client IP
+	Key         string
+	RequestHash string // fingerprint of method, path and body
+	Status      int    // 0 while the first request is still running
+	ContentType string
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+// Store persists idempotency records.
+type Store interface {
+	// ReserveIdempotencyKey claims rec's scope+key for a new request. If the
+	// key is already held by an unexpired record, that record is returned
+	// with reserved == false.
+	ReserveIdempotencyKey(ctx context.Context, rec Record) (existing *Record, reserved bool, err error)
+	// CompleteIdempotencyKey stores the response for a reserved key.
+	CompleteIdempotencyKey(ctx context.Context, scope, key string, status int, contentType string, body []byte) error
+	// ReleaseIdempotencyKey forgets a key so the request can be retried.
+	ReleaseIdempotencyKey(ctx context.Context, scope, key string) error
+}
+
+// Middleware returns middleware that honours Idempotency-Key on the routes it
+// wraps, remembering responses for ttl. Requests without the header pass
+// straight through.
+//
+// Place it after authentication middleware, so keys are scoped to the user.
+func Middleware(store Store, ttl time.Duration, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if key == "" || r.Method != http.MethodPost {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxKeyLength {
+				writeError(w, r, http.StatusBadRequest, "invalid_idempotency_key",
+					"Idempotency-Key must be 255 characters or less")
+				return
+			}
+
+			// The body is read here to fingerprint it, then put back for the handler.
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					writeError(w, r, http.StatusRequestEntityTooLarge, "request_too_large",
+						fmt.Sprintf("Request body must be %d bytes or less", tooLarge.Limit))
+					return
+				}
+				writeError(w, r, http.StatusBadRequest, "invalid_body", "could not read request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// Storage calls must outlive a request whose context is cancelled
+			// (client gone, deadline passed): the outcome still needs recording.
+			ctx := context.WithoutCancel(r.Context())
+			scope := scopeOf(r)
+			rec := Record{
+				Scope:       scope,
+				Key:         key,
+				RequestHash: fingerprint(r, body),
+				ExpiresAt:   time.Now().Add(ttl),
+			}
+
+			existing, reserved, err := store.ReserveIdempotencyKey(ctx, rec)
+			if err != nil {
+				// Fail open: better a possible duplicate than a failed request.
+				logger.ErrorContext(ctx, "idempotency store unavailable", slog.String("error", err.Error()))
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !reserved {
+				replay(w, r, existing, rec.RequestHash)
+				return
+			}
+
+			rw := &recorder{ResponseWriter: w}
+			completed := false
+			defer func() {
+				// A panic (or a response we decided not to keep) frees the key.
+				if !completed {
+					if err := store.ReleaseIdempotencyKey(ctx, scope, key); err != nil {
+						logger.ErrorContext(ctx, "failed to release idempotency key", slog.String("error", err.Error()))
+					}
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+
+			status := rw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status >= 500 || rw.body.Len() > maxStoredBody {
+				return
+			}
+			if err := store.CompleteIdempotencyKey(ctx, scope, key, status, w.Header().Get("Content-Type"), rw.body.Bytes()); err != nil {
+				logger.ErrorContext(ctx, "failed to store idempotent response", slog.String("error", err.Error()))
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// replay answers a retry from the stored record.
+func replay(w http.ResponseWriter, r *http.Request, rec *Record, requestHash string) {
+	switch {
+	case rec.RequestHash != requestHash:
+		writeError(w, r, http.StatusUnprocessableEntity, "idempotency_key_reused",
+			"this Idempotency-Key was already used for a different request")
+	case rec.Status == 0:
+		writeError(w, r, http.StatusConflict, "request_in_progress",
+			"a request with this Idempotency-Key is still being processed")
+	default:
+		if rec.ContentType != "" {
+			w.Header().Set("Content-Type", rec.ContentType)
+		}
+		w.Header().Set(ReplayedHeader, "true")
+		w.WriteHeader(rec.Status)
+		w.Write(rec.Body)
+	}
+}
+
+// scopeOf returns who a key belongs to: the signed-in user, or the client IP.
+func scopeOf(r *http.Request) string {
+	if userID, ok := auth.UserIDFromContext(r.Context()); ok {
+		return "user:" + userID
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// fingerprint identifies a request by method, path and body.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder passes the response through while keeping a copy.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *recorder) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recorder) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rw *recorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// writeError sends the API's standard JSON error body.
+func writeError(w http.ResponseWriter, r *http.Request, status int, errorType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Error     string `json:"error"`
+		Message   string `json:"message"`
+		RequestID string `json:"requestId,omitempty"`
+	}{errorType, message, middleware.RequestID(r.Context())})
+}
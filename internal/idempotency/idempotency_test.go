@@ -0,0 +1,181 @@
+package idempotency_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/idempotency"
+)
+
+// memStore is an in-memory idempotency.Store.
+type memStore struct {
+	mu   sync.Mutex
+	recs map[string]*idempotency.Record
+}
+
+func newMemStore() *memStore {
+	return &memStore{recs: make(map[string]*idempotency.Record)}
+}
+
+func (m *memStore) ReserveIdempotencyKey(_ context.Context, rec idempotency.Record) (*idempotency.Record, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := rec.Scope + "|" + rec.Key
+	if existing, ok := m.recs[id]; ok && time.Now().Before(existing.ExpiresAt) {
+		cp := *existing
+		return &cp, false, nil
+	}
+	m.recs[id] = &rec
+	return nil, true, nil
+}
+
+func (m *memStore) CompleteIdempotencyKey(_ context.Context, scope, key string, status int, contentType string, body []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec := m.recs[scope+"|"+key]
+	rec.Status, rec.ContentType, rec.Body = status, contentType, body
+	return nil
+}
+
+func (m *memStore) ReleaseIdempotencyKey(_ context.Context, scope, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.recs, scope+"|"+key)
+	return nil
+}
+
+// countingHandler counts calls and answers with status and the call number.
+func countingHandler(status int) (http.Handler, *int) {
+	calls := 0
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"call":%d}`, calls)
+	}), &calls
+}
+
+func post(h http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/snippets", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(idempotency.Header, key)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func newMiddleware(store idempotency.Store) func(http.Handler) http.Handler {
+	return idempotency.Middleware(store, time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestMiddleware_ReplaysStoredResponse(t *testing.T) {
+	next, calls := countingHandler(http.StatusCreated)
+	h := newMiddleware(newMemStore())(next)
+
+	first := post(h, "key-1", `{"name":"a"}`)
+	second := post(h, "key-1", `{"name":"a"}`)
+
+	if *calls != 1 {
+		t.Fatalf("handler called %d times, want 1", *calls)
+	}
+	if second.Code != http.StatusCreated {
+		t.Errorf("replay status = %d, want 201", second.Code)
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Errorf("replay body = %q, want %q", second.Body.String(), first.Body.String())
+	}
+	if second.Header().Get(idempotency.ReplayedHeader) != "true" {
+		t.Error("replay is missing the Idempotent-Replayed header")
+	}
+	if first.Header().Get(idempotency.ReplayedHeader) != "" {
+		t.Error("first response should not be marked as replayed")
+	}
+}
+
+func TestMiddleware_KeyReusedWithDifferentBody(t *testing.T) {
+	next, calls := countingHandler(http.StatusCreated)
+	h := newMiddleware(newMemStore())(next)
+
+	post(h, "key-1", `{"name":"a"}`)
+	rec := post(h, "key-1", `{"name":"b"}`)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want 422", rec.Code)
+	}
+	if *calls != 1 {
+		t.Errorf("handler called %d times, want 1", *calls)
+	}
+}
+
+func TestMiddleware_InProgress(t *testing.T) {
+	blocked := make(chan struct{})
+	release := make(chan struct{})
+	h := newMiddleware(newMemStore())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(blocked)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		post(h, "key-1", `{}`)
+		close(done)
+	}()
+	<-blocked
+
+	rec := post(h, "key-1", `{}`)
+	close(release)
+	<-done
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("status = %d, want 409", rec.Code)
+	}
+}
+
+func TestMiddleware_ServerErrorsAreNotStored(t *testing.T) {
+	next, calls := countingHandler(http.StatusInternalServerError)
+	h := newMiddleware(newMemStore())(next)
+
+	post(h, "key-1", `{}`)
+	post(h, "key-1", `{}`)
+
+	if *calls != 2 {
+		t.Errorf("handler called %d times, want 2 (a 500 must be retryable)", *calls)
+	}
+}
+
+func TestMiddleware_WithoutKeyPassesThrough(t *testing.T) {
+	next, calls := countingHandler(http.StatusCreated)
+	h := newMiddleware(newMemStore())(next)
+
+	post(h, "", `{}`)
+	post(h, "", `{}`)
+
+	if *calls != 2 {
+		t.Errorf("handler called %d times, want 2", *calls)
+	}
+}
+
+func TestMiddleware_KeyTooLong(t *testing.T) {
+	next, calls := countingHandler(http.StatusCreated)
+	h := newMiddleware(newMemStore())(next)
+
+	rec := post(h, strings.Repeat("k", 256), `{}`)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+	if *calls != 0 {
+		t.Errorf("handler called %d times, want 0", *calls)
+	}
+}
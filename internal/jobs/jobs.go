@@ -0,0 +1,116 @@
+// Package jobs runs background work outside the request/response cycle.
+//
+// WHY A JOB QUEUE?
+// Some work shouldn't make an HTTP request wait: sending an email, cleaning up
+// old rows, importing a gist. A handler instead ENQUEUES a job (a small row in
+// SQLite) and returns immediately. A pool of worker goroutines picks jobs up and
+// runs them.
+//
+// DURABILITY:
+// Jobs are persisted before Enqueue returns, so a restart doesn't lose them.
+// A job that was mid-flight when the process died is put back to "pending" on
+// the next Start — handlers must therefore be safe to run more than once
+// ("at-least-once delivery").
+//
+// RETRIES:
+// A handler that returns an error is retried with exponential backoff
+// (base, 2×base, 4×base, ... capped at MaxBackoff) until MaxAttempts is reached,
+// after which the job is marked "failed" and left in the table for inspection.
+// Return Permanent(err) to fail immediately without retrying.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Status is the lifecycle state of a persisted job.
+type Status string
+
+const (
+	StatusPending Status = "pending" // waiting for RunAt
+	StatusRunning Status = "running" // claimed by a worker
+	StatusFailed  Status = "failed"  // gave up after MaxAttempts (or a permanent error)
+)
+
+// Job is a unit of background work.
+type Job struct {
+	ID          string
+	Kind        string          // selects the Handler, e.g. "cleanup.snippets"
+	Payload     json.RawMessage // handler-specific arguments
+	Status      Status
+	Attempts    int // number of times the job has been started
+	MaxAttempts int
+	RunAt       time.Time // earliest time the job may run
+	LastError   string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Decode unmarshals the job's payload into v.
+func (j *Job) Decode(v any) error {
+	if err := json.Unmarshal(j.Payload, v); err != nil {
+		return Permanent(fmt.Errorf("decoding %s payload: %w", j.Kind, err))
+	}
+	return nil
+}
+
+// Handler runs one job. The context is cancelled when the queue shuts down
+// and its grace period runs out.
+type Handler func(ctx context.Context, job *Job) error
+
+// Store persists jobs. The SQLite repository implements it.
+type Store interface {
+	// EnqueueJob inserts a new pending job. It sets job.ID if empty.
+	EnqueueJob(ctx context.Context, job *Job) error
+	// ClaimJob atomically marks the oldest due pending job as running,
+	// increments its attempt count and returns it. Returns nil, nil if no job is due.
+	ClaimJob(ctx context.Context, now time.Time) (*Job, error)
+	// CompleteJob removes a finished job.
+	CompleteJob(ctx context.Context, id string) error
+	// RetryJob puts a job back to pending, to run again at runAt.
+	RetryJob(ctx context.Context, id string, runAt time.Time, lastErr string) error
+	// FailJob marks a job as permanently failed.
+	FailJob(ctx context.Context, id string, lastErr string) error
+	// ResetRunningJobs returns jobs left running by a crashed process to pending.
+	ResetRunningJobs(ctx context.Context) (int, error)
+	// CountDueJobs counts the pending jobs due to run at now.
+	CountDueJobs(ctx context.Context, now time.Time) (int, error)
+}
+
+// permanentError marks an error as not worth retrying.
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the queue fails the job without further retries.
+// Use it for errors a retry can't fix, such as a malformed payload.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was wrapped with Permanent.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// backoff returns the delay before retry number `attempt` (1-based):
+// base × 2^(attempt-1), capped at max.
+func backoff(attempt int, base, max time.Duration) time.Duration {
+	d := base
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= max {
+			return max
+		}
+	}
+	return min(d, max)
+}
@@ -0,0 +1,293 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Options configures a Queue. Zero values fall back to the defaults below.
+type Options struct {
+	Workers      int           // concurrent jobs (default 2)
+	PollInterval time.Duration // how often idle workers check for due jobs (default 1s)
+	MaxAttempts  int           // default attempts per job (default 5)
+	BaseBackoff  time.Duration // delay before the first retry (default 5s)
+	MaxBackoff   time.Duration // cap on the retry delay (default 10m)
+}
+
+func (o Options) withDefaults() Options {
+	if o.Workers <= 0 {
+		o.Workers = 2
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = time.Second
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 5
+	}
+	if o.BaseBackoff <= 0 {
+		o.BaseBackoff = 5 * time.Second
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = 10 * time.Minute
+	}
+	return o
+}
+
+// Queue dispatches persisted jobs to registered handlers on a worker pool.
+//
+// LIFECYCLE:
+//
+//	q := jobs.New(db, logger, jobs.Options{})
+//	q.Register("email.welcome", sendWelcomeEmail)   // before Start
+//	q.Start(ctx)
+//	q.Enqueue(ctx, "email.welcome", payload)         // any time, from any goroutine
+//	q.Shutdown(ctx)                                  // waits for running jobs
+type Queue struct {
+	store  Store
+	logger *slog.Logger
+	opts   Options
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+
+	wake    chan struct{} // nudges an idle worker when a job is enqueued
+	stop    chan struct{} // closed by Shutdown: stop claiming new jobs
+	stopped sync.Once
+	cancel  context.CancelFunc // cancels running handlers when the grace period ends
+	wg      sync.WaitGroup
+	started bool
+
+	now func() time.Time // injectable for tests
+}
+
+// New creates a Queue. Call Register for each job kind, then Start.
+func New(store Store, logger *slog.Logger, opts Options) *Queue {
+	return &Queue{
+		store:    store,
+		logger:   logger,
+		opts:     opts.withDefaults(),
+		handlers: make(map[string]Handler),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		now:      time.Now,
+	}
+}
+
+// Register associates a job kind with its handler. Registering the same kind
+// twice replaces the earlier handler.
+func (q *Queue) Register(kind string, h Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[kind] = h
+}
+
+// EnqueueOption customises a single Enqueue call.
+type EnqueueOption func(*Job)
+
+// After delays the job's first run by d.
+func After(d time.Duration) EnqueueOption {
+	return func(j *Job) { j.RunAt = j.RunAt.Add(d) }
+}
+
+// MaxAttempts overrides the queue's default attempt limit for one job.
+func MaxAttempts(n int) EnqueueOption {
+	return func(j *Job) { j.MaxAttempts = n }
+}
+
+// Enqueue persists a job of the given kind. payload is encoded as JSON and
+// handed back to the handler via Job.Decode. It returns the new job's ID.
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload any, opts ...EnqueueOption) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("jobs: encoding %s payload: %w", kind, err)
+	}
+
+	job := &Job{
+		Kind:        kind,
+		Payload:     data,
+		Status:      StatusPending,
+		MaxAttempts: q.opts.MaxAttempts,
+		RunAt:       q.now().UTC(),
+	}
+	for _, opt := range opts {
+		opt(job)
+	}
+
+	if err := q.store.EnqueueJob(ctx, job); err != nil {
+		return "", fmt.Errorf("jobs: enqueueing %s: %w", kind, err)
+	}
+
+	// Non-blocking send: if a wake-up is already pending, one is enough.
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	return job.ID, nil
+}
+
+// Backlog counts the jobs that are due but not yet claimed. A backlog that
+// keeps growing means the workers can't keep up.
+func (q *Queue) Backlog(ctx context.Context) (int, error) {
+	n, err := q.store.CountDueJobs(ctx, q.now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("jobs: counting backlog: %w", err)
+	}
+	return n, nil
+}
+
+// Start recovers jobs interrupted by a previous crash and launches the workers.
+func (q *Queue) Start(ctx context.Context) error {
+	if q.started {
+		return errors.New("jobs: queue already started")
+	}
+
+	n, err := q.store.ResetRunningJobs(ctx)
+	if err != nil {
+		return fmt.Errorf("jobs: recovering interrupted jobs: %w", err)
+	}
+	if n > 0 {
+		q.logger.Warn("requeued jobs interrupted by a previous shutdown", slog.Int("count", n))
+	}
+
+	// Handlers get a context that outlives ctx: Start's caller may cancel its
+	// own context long before we're asked to shut down.
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	q.cancel = cancel
+	q.started = true
+
+	for i := 0; i < q.opts.Workers; i++ {
+		q.wg.Add(1)
+		go q.worker(runCtx)
+	}
+
+	q.logger.Info("job queue started", slog.Int("workers", q.opts.Workers))
+	return nil
+}
+
+// Shutdown stops claiming new jobs and waits for running ones to finish.
+// If ctx expires first, running handlers are cancelled and their jobs are
+// retried on the next Start. Calling Shutdown more than once is safe.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	if !q.started {
+		return nil
+	}
+	q.stopped.Do(func() { close(q.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		q.logger.Info("job queue stopped")
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		<-done
+		return fmt.Errorf("jobs: shutdown: %w", ctx.Err())
+	}
+}
+
+// worker claims and runs jobs until Shutdown is called.
+// When no job is due it sleeps until the next poll tick or an Enqueue wake-up.
+func (q *Queue) worker(ctx context.Context) {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+
+		job, err := q.store.ClaimJob(ctx, q.now().UTC())
+		if err != nil {
+			q.logger.Error("claiming job failed", slog.String("error", err.Error()))
+		}
+		if job != nil {
+			q.run(ctx, job)
+			continue // there may be more due jobs — don't wait for the ticker
+		}
+
+		select {
+		case <-q.stop:
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// run executes one claimed job and records the outcome.
+func (q *Queue) run(ctx context.Context, job *Job) {
+	logger := q.logger.With(
+		slog.String("job_id", job.ID),
+		slog.String("kind", job.Kind),
+		slog.Int("attempt", job.Attempts),
+	)
+
+	q.mu.RLock()
+	h, ok := q.handlers[job.Kind]
+	q.mu.RUnlock()
+
+	var err error
+	if !ok {
+		err = Permanent(fmt.Errorf("no handler registered for job kind %q", job.Kind))
+	} else {
+		err = safeCall(ctx, h, job)
+	}
+
+	// The outcome is recorded even if ctx was cancelled mid-job.
+	storeCtx := context.WithoutCancel(ctx)
+
+	switch {
+	case err == nil:
+		if err := q.store.CompleteJob(storeCtx, job.ID); err != nil {
+			logger.Error("marking job complete failed", slog.String("error", err.Error()))
+			return
+		}
+		logger.Debug("job completed")
+
+	case IsPermanent(err) || job.Attempts >= job.MaxAttempts:
+		if err := q.store.FailJob(storeCtx, job.ID, err.Error()); err != nil {
+			logger.Error("recording job failure failed", slog.String("error", err.Error()))
+			return
+		}
+		logger.Error("job failed permanently", slog.String("error", err.Error()))
+
+	default:
+		delay := backoff(job.Attempts, q.opts.BaseBackoff, q.opts.MaxBackoff)
+		runAt := q.now().UTC().Add(delay)
+		if err := q.store.RetryJob(storeCtx, job.ID, runAt, err.Error()); err != nil {
+			logger.Error("rescheduling job failed", slog.String("error", err.Error()))
+			return
+		}
+		logger.Warn("job failed, will retry",
+			slog.String("error", err.Error()),
+			slog.Duration("retry_in", delay),
+		)
+	}
+}
+
+// safeCall runs a handler, converting a panic into an error so one bad job
+// can't take down the worker (or the whole server).
+func safeCall(ctx context.Context, h Handler, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return h(ctx, job)
+}
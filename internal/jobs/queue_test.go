@@ -0,0 +1,188 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/jobs"
+	"github.com/sakif/coding-playground/internal/repository/sqlite"
+)
+
+// newStore opens a file-backed database: the queue's workers use several pooled
+// connections, and each connection to ":memory:" would see its own empty database.
+func newStore(t *testing.T) *sqlite.DB {
+	t.Helper()
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newQueue(t *testing.T, store jobs.Store) *jobs.Queue {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return jobs.New(store, logger, jobs.Options{
+		Workers:      2,
+		PollInterval: 10 * time.Millisecond,
+		BaseBackoff:  time.Millisecond,
+		MaxBackoff:   time.Millisecond,
+	})
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestQueue_RunsJobWithPayload(t *testing.T) {
+	q := newQueue(t, newStore(t))
+
+	got := make(chan string, 1)
+	q.Register("greet", func(ctx context.Context, job *jobs.Job) error {
+		var p struct{ Name string }
+		if err := job.Decode(&p); err != nil {
+			return err
+		}
+		got <- p.Name
+		return nil
+	})
+
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { q.Shutdown(context.Background()) })
+
+	if _, err := q.Enqueue(context.Background(), "greet", map[string]string{"Name": "gopher"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	select {
+	case name := <-got:
+		if name != "gopher" {
+			t.Errorf("payload name = %q, want gopher", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("job did not run")
+	}
+}
+
+func TestQueue_RetriesThenFails(t *testing.T) {
+	store := newStore(t)
+	q := newQueue(t, store)
+
+	var calls atomic.Int32
+	q.Register("flaky", func(ctx context.Context, job *jobs.Job) error {
+		calls.Add(1)
+		return errors.New("boom")
+	})
+
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { q.Shutdown(context.Background()) })
+
+	if _, err := q.Enqueue(context.Background(), "flaky", nil, jobs.MaxAttempts(3)); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	waitFor(t, func() bool { return calls.Load() >= 3 })
+	if err := q.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	if n := calls.Load(); n != 3 {
+		t.Errorf("handler calls = %d, want 3", n)
+	}
+	// A failed job is never claimed again.
+	if job, err := store.ClaimJob(context.Background(), time.Now()); err != nil || job != nil {
+		t.Errorf("ClaimJob() = %v, %v; want nil, nil", job, err)
+	}
+}
+
+func TestQueue_PermanentErrorSkipsRetries(t *testing.T) {
+	q := newQueue(t, newStore(t))
+
+	var calls atomic.Int32
+	q.Register("bad", func(ctx context.Context, job *jobs.Job) error {
+		calls.Add(1)
+		return jobs.Permanent(errors.New("malformed"))
+	})
+
+	q.Start(context.Background())
+	q.Enqueue(context.Background(), "bad", nil)
+
+	waitFor(t, func() bool { return calls.Load() >= 1 })
+	time.Sleep(50 * time.Millisecond) // give a wrong retry a chance to happen
+	q.Shutdown(context.Background())
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler calls = %d, want 1", n)
+	}
+}
+
+func TestQueue_StartRequeuesInterruptedJobs(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+
+	// Simulate a crash: a job is claimed but never completed.
+	if err := store.EnqueueJob(ctx, &jobs.Job{Kind: "resume", Payload: []byte("null"), MaxAttempts: 5, RunAt: time.Now()}); err != nil {
+		t.Fatalf("EnqueueJob() error = %v", err)
+	}
+	if job, err := store.ClaimJob(ctx, time.Now()); err != nil || job == nil {
+		t.Fatalf("ClaimJob() = %v, %v", job, err)
+	}
+
+	q := newQueue(t, store)
+	done := make(chan struct{})
+	q.Register("resume", func(ctx context.Context, job *jobs.Job) error {
+		if job.Attempts != 2 {
+			t.Errorf("attempts = %d, want 2", job.Attempts)
+		}
+		close(done)
+		return nil
+	})
+	q.Start(ctx)
+	t.Cleanup(func() { q.Shutdown(ctx) })
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("interrupted job was not resumed")
+	}
+}
+
+func TestQueue_Backlog(t *testing.T) {
+	q := newQueue(t, newStore(t))
+	ctx := context.Background()
+
+	// Not started, so nothing is claimed: two due jobs and one for later.
+	q.Enqueue(ctx, "a", nil)
+	q.Enqueue(ctx, "b", nil)
+	q.Enqueue(ctx, "c", nil, jobs.After(time.Hour))
+	if n, err := q.Backlog(ctx); err != nil || n != 2 {
+		t.Errorf("Backlog() = %d, %v; want 2", n, err)
+	}
+
+	q.Register("a", func(context.Context, *jobs.Job) error { return nil })
+	q.Register("b", func(context.Context, *jobs.Job) error { return nil })
+	q.Start(ctx)
+	t.Cleanup(func() { q.Shutdown(ctx) })
+	waitFor(t, func() bool {
+		n, _ := q.Backlog(ctx)
+		return n == 0
+	})
+}
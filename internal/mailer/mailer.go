@@ -0,0 +1,159 @@
+// Package mailer sends email: sign-in links, grading results and weekly
+// digests.
+//
+// HOW AN EMAIL IS MADE:
+// Every email is a template in templates/ that defines three parts:
+//
+//	{{define "subject"}}…{{end}}   one line, plain text
+//	{{define "text"}}…{{end}}      the plain-text body
+//	{{define "html"}}…{{end}}      the HTML body (escaped by html/template)
+//
+// Render fills them in from a data value and returns a Message; a Mailer
+// delivers it. Mail clients show the HTML part if they can and the text part
+// otherwise, so both say the same thing.
+//
+// Nothing here retries or queues: a Send that fails just returns the error.
+// Callers send through the job queue (see service.EmailService), which
+// retries with backoff and keeps requests from waiting on an SMTP server.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"log/slog"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Message is one email, ready to send.
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html,omitempty"`
+}
+
+// Mailer delivers messages.
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Log is a Mailer that logs messages instead of sending them. It's handy in
+// development, where sign-in links can be copied out of the log.
+type Log struct {
+	Logger *slog.Logger
+}
+
+// Send logs msg.
+func (l Log) Send(ctx context.Context, msg *Message) error {
+	l.Logger.InfoContext(ctx, "email not sent (no SMTP server configured)",
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+		slog.String("text", msg.Text),
+	)
+	return nil
+}
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// Template names.
+const (
+	TemplateMagicLink = "magic_link.tmpl" // data: MagicLinkData
+	TemplateGraded    = "graded.tmpl"     // data: GradedData
+	TemplateDigest    = "digest.tmpl"     // data: DigestData
+	TemplateOrgInvite = "org_invite.tmpl" // data: OrgInviteData
+)
+
+// MagicLinkData fills in TemplateMagicLink.
+type MagicLinkData struct {
+	Login   string
+	URL     string
+	Minutes int // how long the link works
+}
+
+// GradedData fills in TemplateGraded.
+type GradedData struct {
+	Login          string
+	Exercise       string
+	Outcome        string // "passed", "scored 50%", …
+	URL            string // the exercise
+	PreferencesURL string
+}
+
+// DigestData fills in TemplateDigest.
+type DigestData struct {
+	Login          string
+	Unread         int
+	Messages       []string // the newest unread notifications
+	URL            string
+	PreferencesURL string
+}
+
+// More is how many unread notifications aren't listed.
+func (d DigestData) More() int {
+	return d.Unread - len(d.Messages)
+}
+
+// OrgInviteData fills in TemplateOrgInvite.
+type OrgInviteData struct {
+	Inviter string // login
+	Org     string
+	Role    string
+	URL     string
+	Days    int // how long the invite works
+}
+
+// template is one email's parts. Every file defines the same three names,
+// so each is parsed on its own; and twice, because html/template escapes
+// what it inserts and text/template doesn't.
+type template struct {
+	text *texttemplate.Template // "subject" and "text"
+	html *htmltemplate.Template // "html"
+}
+
+var templates = parseTemplates()
+
+func parseTemplates() map[string]template {
+	names, err := fs.Glob(templateFS, "templates/*.tmpl")
+	if err != nil {
+		panic(err)
+	}
+	parsed := make(map[string]template, len(names))
+	for _, file := range names {
+		parsed[path.Base(file)] = template{
+			text: texttemplate.Must(texttemplate.ParseFS(templateFS, file)),
+			html: htmltemplate.Must(htmltemplate.ParseFS(templateFS, file)),
+		}
+	}
+	return parsed
+}
+
+// Render fills in the template called name for an email to to.
+func Render(name, to string, data any) (*Message, error) {
+	tmpl, ok := templates[name]
+	if !ok {
+		return nil, fmt.Errorf("mailer: no template %q", name)
+	}
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("mailer: rendering %s: %w", name, err)
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return nil, fmt.Errorf("mailer: rendering %s: %w", name, err)
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, "html", data); err != nil {
+		return nil, fmt.Errorf("mailer: rendering %s: %w", name, err)
+	}
+	return &Message{
+		To:      to,
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    strings.TrimSpace(html.String()) + "\n",
+	}, nil
+}
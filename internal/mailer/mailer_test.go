@@ -0,0 +1,70 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    any
+		subject string
+		text    []string
+		html    []string
+	}{
+		{
+			name:    TemplateMagicLink,
+			data:    MagicLinkData{Login: "ann", URL: "https://play.example/auth/magic?token=abc&x=1", Minutes: 15},
+			subject: "Your PyPlayground sign-in link",
+			text:    []string{"Hi ann,", "https://play.example/auth/magic?token=abc&x=1", "15 minutes"},
+			html:    []string{`href="https://play.example/auth/magic?token=abc&amp;x=1"`},
+		},
+		{
+			name:    TemplateGraded,
+			data:    GradedData{Login: "ann", Exercise: "“<Add>”", Outcome: "scored 50%", URL: "https://play.example/exercises/e1"},
+			subject: "Your solution to “<Add>” scored 50%",
+			text:    []string{"Your solution to “<Add>” scored 50%."},
+			html:    []string{"&lt;Add&gt;"},
+		},
+		{
+			name:    TemplateDigest,
+			data:    DigestData{Login: "ann", Unread: 3, Messages: []string{"bob starred “loop”", "bob forked “loop”"}},
+			subject: "Your week on PyPlayground: 3 unread",
+			text:    []string{"- bob starred “loop”\n- bob forked “loop”", "…and 1 more."},
+			html:    []string{"<li>bob forked “loop”</li>"},
+		},
+		{
+			name:    TemplateOrgInvite,
+			data:    OrgInviteData{Inviter: "ann", Org: "<Data> team", Role: "member", URL: "https://play.example/api/v1/invites/abc", Days: 7},
+			subject: "ann invited you to <Data> team on PyPlayground",
+			text:    []string{"join <Data> team on PyPlayground as member", "https://play.example/api/v1/invites/abc", "7 days"},
+			html:    []string{"<strong>&lt;Data&gt; team</strong>"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := Render(tt.name, "ann@example.com", tt.data)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if msg.To != "ann@example.com" || msg.Subject != tt.subject {
+				t.Errorf("To, Subject = %q, %q; want %q", msg.To, msg.Subject, tt.subject)
+			}
+			for _, want := range tt.text {
+				if !strings.Contains(msg.Text, want) {
+					t.Errorf("Text = %q, want it to contain %q", msg.Text, want)
+				}
+			}
+			for _, want := range tt.html {
+				if !strings.Contains(msg.HTML, want) {
+					t.Errorf("HTML = %q, want it to contain %q", msg.HTML, want)
+				}
+			}
+		})
+	}
+
+	if _, err := Render("nope.tmpl", "ann@example.com", nil); err == nil {
+		t.Error("Render() of an unknown template succeeded")
+	}
+}
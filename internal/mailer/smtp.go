@@ -0,0 +1,191 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTP SENDING:
+// One Send is one SMTP conversation:
+//
+//	connect → EHLO → STARTTLS (if offered) → AUTH (if configured)
+//	→ MAIL FROM → RCPT TO → DATA <message> → QUIT
+//
+// Port 465 servers expect TLS from the first byte ("implicit TLS") instead
+// of upgrading with STARTTLS; SMTPConfig.ImplicitTLS handles those.
+// Credentials are only ever sent over TLS — net/smtp's PlainAuth refuses
+// otherwise, except to localhost.
+//
+// The message is multipart/alternative with a text and an HTML part, both
+// quoted-printable so long lines and non-ASCII text survive any relay.
+
+// SMTPConfig configures an SMTP mailer.
+type SMTPConfig struct {
+	Host        string
+	Port        int    // default 587
+	Username    string // "" skips AUTH
+	Password    string
+	From        string        // e.g. "PyPlayground <noreply@example.com>"
+	ImplicitTLS bool          // TLS from the start (usually port 465) instead of STARTTLS
+	Timeout     time.Duration // per send (default 30s)
+}
+
+// SMTP is a Mailer that sends through an SMTP server.
+type SMTP struct {
+	cfg  SMTPConfig
+	from *mail.Address
+}
+
+// NewSMTP creates an SMTP mailer. Nothing is dialled until the first Send.
+func NewSMTP(cfg SMTPConfig) (*SMTP, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("mailer: SMTP host is required")
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("mailer: invalid from address %q: %w", cfg.From, err)
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	return &SMTP{cfg: cfg, from: from}, nil
+}
+
+// Send delivers msg, giving up when ctx is done or the timeout passes.
+func (m *SMTP) Send(ctx context.Context, msg *Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("mailer: invalid recipient %q: %w", msg.To, err)
+	}
+	body, err := m.compose(to, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	defer cancel()
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("mailer: connecting to %s: %w", addr, err)
+	}
+	// net/smtp has no contexts, so the deadline goes on the connection.
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	tlsConfig := &tls.Config{ServerName: m.cfg.Host}
+	if m.cfg.ImplicitTLS {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	c, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("mailer: %w", err)
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok && !m.cfg.ImplicitTLS {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("mailer: starttls: %w", err)
+		}
+	}
+	if m.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			return fmt.Errorf("mailer: auth: %w", err)
+		}
+	}
+	if err := c.Mail(m.from.Address); err != nil {
+		return fmt.Errorf("mailer: mail from: %w", err)
+	}
+	if err := c.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("mailer: rcpt to: %w", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("mailer: data: %w", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("mailer: writing message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("mailer: sending message: %w", err)
+	}
+	return c.Quit()
+}
+
+// compose builds the message as it goes over the wire: headers, then the
+// text and HTML parts.
+func (m *SMTP) compose(to *mail.Address, msg *Message, now time.Time) ([]byte, error) {
+	boundary, err := randomHex(12)
+	if err != nil {
+		return nil, err
+	}
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	domain := m.from.Address[strings.LastIndex(m.from.Address, "@")+1:]
+
+	var b bytes.Buffer
+	header := func(name, value string) { fmt.Fprintf(&b, "%s: %s\r\n", name, value) }
+	header("From", m.from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", "<"+id+"@"+domain+">")
+	header("MIME-Version", "1.0")
+	if msg.HTML == "" {
+		header("Content-Type", `text/plain; charset="utf-8"`)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		b.WriteString("\r\n")
+		if err := writeQP(&b, msg.Text); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	}
+	header("Content-Type", `multipart/alternative; boundary="`+boundary+`"`)
+	for _, part := range []struct{ kind, body string }{{"text/plain", msg.Text}, {"text/html", msg.HTML}} {
+		fmt.Fprintf(&b, "\r\n--%s\r\n", boundary)
+		header("Content-Type", part.kind+`; charset="utf-8"`)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		b.WriteString("\r\n")
+		if err := writeQP(&b, part.body); err != nil {
+			return nil, err
+		}
+	}
+	fmt.Fprintf(&b, "\r\n--%s--\r\n", boundary)
+	return b.Bytes(), nil
+}
+
+// writeQP appends s quoted-printable encoded.
+func writeQP(b *bytes.Buffer, s string) error {
+	w := quotedprintable.NewWriter(b)
+	if _, err := w.Write([]byte(s)); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("mailer: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
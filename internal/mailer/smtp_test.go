@@ -0,0 +1,134 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// fakeSMTP accepts one message and sends what it received to got: the
+// envelope commands, then the message itself.
+func fakeSMTP(t *testing.T) (port int, got <-chan []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	ch := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+		var received []string
+		reply("220 fake ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			switch cmd := strings.ToUpper(strings.Fields(line + " x")[0]); cmd {
+			case "EHLO":
+				reply("250 fake")
+			case "MAIL", "RCPT":
+				received = append(received, line)
+				reply("250 ok")
+			case "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					line, _ := r.ReadString('\n')
+					if line == ".\r\n" || line == "" {
+						break
+					}
+					data.WriteString(line)
+				}
+				received = append(received, data.String())
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				ch <- received
+				return
+			default:
+				reply("502 unknown")
+			}
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, ch
+}
+
+func TestSMTP_Send(t *testing.T) {
+	port, got := fakeSMTP(t)
+	m, err := NewSMTP(SMTPConfig{Host: "127.0.0.1", Port: port, From: "PyPlayground <noreply@play.example>"})
+	if err != nil {
+		t.Fatalf("NewSMTP() error = %v", err)
+	}
+	msg := &Message{
+		To:      "ann@example.com",
+		Subject: "Your solution to “Add” passed",
+		Text:    "Well done, ann. " + strings.Repeat("long line ", 20) + "end\n",
+		HTML:    "<p>Well done, ann.</p>\n",
+	}
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	received := <-got
+	if len(received) != 3 || received[0] != "MAIL FROM:<noreply@play.example>" || received[1] != "RCPT TO:<ann@example.com>" {
+		t.Fatalf("envelope = %q", received)
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(received[2]))
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if subject != msg.Subject || parsed.Header.Get("To") != "<ann@example.com>" || parsed.Header.Get("Message-Id") == "" {
+		t.Errorf("headers = %v", parsed.Header)
+	}
+
+	_, params, _ := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+	for _, want := range []string{msg.Text, msg.HTML} {
+		part, err := parts.NextPart()
+		if err != nil {
+			t.Fatalf("NextPart() error = %v", err)
+		}
+		body, _ := io.ReadAll(quotedprintable.NewReader(part))
+		// Lines travel with CRLF endings.
+		if string(body) != strings.ReplaceAll(want, "\n", "\r\n") {
+			t.Errorf("part %s = %q, want %q", part.Header.Get("Content-Type"), body, want)
+		}
+	}
+}
+
+func TestSMTP_Errors(t *testing.T) {
+	if _, err := NewSMTP(SMTPConfig{From: "noreply@play.example"}); err == nil {
+		t.Error("NewSMTP() without a host succeeded")
+	}
+	if _, err := NewSMTP(SMTPConfig{Host: "localhost", From: "not an address"}); err == nil {
+		t.Error("NewSMTP() with a bad from address succeeded")
+	}
+
+	// Nothing listens on a port we just closed.
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	ln.Close()
+	m, _ := NewSMTP(SMTPConfig{Host: "127.0.0.1", Port: ln.Addr().(*net.TCPAddr).Port, From: "noreply@play.example"})
+	if err := m.Send(context.Background(), &Message{To: "ann@example.com", Text: "hi"}); err == nil {
+		t.Error("Send() to a closed port succeeded")
+	}
+	if err := m.Send(context.Background(), &Message{To: "ann", Text: "hi"}); err == nil || !strings.Contains(err.Error(), "invalid recipient") {
+		t.Errorf("Send() to a bad address: error = %v", err)
+	}
+}
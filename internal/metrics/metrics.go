@@ -0,0 +1,154 @@
+// Package metrics collects Prometheus metrics for the HTTP server and its dependencies.
+//
+// PROMETHEUS IN ONE PARAGRAPH:
+// Prometheus is a pull-based monitoring system. Instead of our server pushing
+// numbers somewhere, Prometheus periodically scrapes GET /metrics and stores
+// the values as time series. The response is a plain-text format like:
+//
+//	http_requests_total{method="GET",route="/api/snippets",status="200"} 42
+//
+// METRIC TYPES USED HERE:
+//   - Counter:   only goes up (requests served). Rates are computed at query time.
+//   - Histogram: buckets of observations (request durations) → percentiles.
+//   - Gauge:     goes up and down (open DB connections, warm containers).
+//
+// SATURATION:
+// Timeouts are the last symptom of a busy server; queues growing are the
+// first. Besides the Go collector's go_goroutines, the server exports how
+// many executions wait for a sandbox (and for how long), how many due jobs
+// are unclaimed and how many database connections are in use — see
+// Server.newMetrics.
+//
+// WHY A CUSTOM REGISTRY?
+// client_golang has a global default registry, but a package-level global makes
+// tests leak state between each other. Owning a *prometheus.Registry per Server
+// keeps everything injectable — the same reason we inject loggers and repositories.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes every application metric (e.g. playground_http_requests_total).
+const Namespace = "playground"
+
+// Registry owns the Prometheus registry and the HTTP metrics recorded by Middleware.
+type Registry struct {
+	reg      *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// New creates a Registry with Go runtime and process collectors pre-registered.
+func New() *Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	r := &Registry{
+		reg: reg,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "http_requests_total",
+			Help:      "Total HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+	}
+	reg.MustRegister(r.requests, r.duration)
+
+	return r
+}
+
+// Prometheus exposes the underlying registry so other packages can register
+// their own collectors.
+func (r *Registry) Prometheus() *prometheus.Registry {
+	return r.reg
+}
+
+// Handler returns the http.Handler that serves the /metrics scrape endpoint.
+func (r *Registry) Handler() http.Handler {
+	return promhttp.HandlerFor(r.reg, promhttp.HandlerOpts{Registry: r.reg})
+}
+
+// Middleware records request count, status and duration for every request.
+//
+// ROUTE PATTERNS, NOT PATHS:
+// We label by chi's route pattern ("/api/snippets/{id}") rather than the raw
+// path ("/api/snippets/cv37rs3pp9olc6atsptg"). Every distinct label value creates
+// a new time series, so raw paths with IDs would grow without bound.
+// The pattern is only known after routing, which is why we read it AFTER
+// calling next.ServeHTTP.
+func (r *Registry) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		ww := chimiddleware.NewWrapResponseWriter(w, req.ProtoMajor)
+
+		next.ServeHTTP(ww, req)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(req.Context()); rctx != nil {
+			if pattern := rctx.RoutePattern(); pattern != "" {
+				route = pattern
+			}
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK // handler wrote nothing — net/http sends 200
+		}
+
+		r.requests.WithLabelValues(req.Method, route, strconv.Itoa(status)).Inc()
+		r.duration.WithLabelValues(req.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// GaugeFunc registers a gauge whose value is read from fn on every scrape.
+// Use it for values another component already tracks (pool sizes, queue depths).
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	r.reg.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      name,
+		Help:      help,
+	}, fn))
+}
+
+// CounterFunc registers a counter whose value is read from fn on every
+// scrape. fn must never go down: use it for running totals another
+// component keeps.
+func (r *Registry) CounterFunc(name, help string, fn func() float64) {
+	r.reg.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      name,
+		Help:      help,
+	}, fn))
+}
+
+// RegisterDBStats exposes database/sql connection pool statistics as gauges.
+func (r *Registry) RegisterDBStats(stats func() sql.DBStats) {
+	r.GaugeFunc("db_open_connections", "Established database connections (in use + idle).",
+		func() float64 { return float64(stats().OpenConnections) })
+	r.GaugeFunc("db_in_use_connections", "Database connections currently in use.",
+		func() float64 { return float64(stats().InUse) })
+	r.GaugeFunc("db_idle_connections", "Idle database connections.",
+		func() float64 { return float64(stats().Idle) })
+	r.CounterFunc("db_wait_count_total", "Total number of times a query waited for a free connection.",
+		func() float64 { return float64(stats().WaitCount) })
+	r.CounterFunc("db_wait_seconds_total", "Total time queries spent waiting for a free connection.",
+		func() float64 { return stats().WaitDuration.Seconds() })
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+)
+
+// MaxBodySize returns middleware that caps request bodies at limit bytes.
+//
+// WHY?
+// json.NewDecoder(r.Body) happily reads as much as the client sends. Without a
+// cap, one POST with a 1GB body ties up memory and a connection for as long as
+// the upload takes. http.MaxBytesReader makes reads fail once the limit is hit
+// (and tells net/http to close the connection afterwards).
+//
+// TWO LINES OF DEFENCE:
+//  1. If the client declares a Content-Length over the limit, we reject with
+//     413 immediately — no need to read a single byte.
+//  2. Otherwise (chunked uploads, or a lying Content-Length) the body is wrapped
+//     in MaxBytesReader. Handlers see an *http.MaxBytesError from Decode and
+//     translate it to the same 413 response.
+//
+// A limit <= 0 disables the middleware.
+func MaxBodySize(limit int64) func(http.Handler) http.Handler {
+	if limit <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				writeJSONError(w, r, http.StatusRequestEntityTooLarge, "request_too_large", apperror.CodeRequestTooLarge,
+					fmt.Sprintf("Request body must be %d bytes or less", limit))
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package middleware
+
+// BODY LOGGING (DEBUG MODE):
+// When a client integration misbehaves, the access log says THAT a request
+// failed but not WHAT was sent or answered. This middleware logs the bodies
+// too — but only while an operator has switched it on (the body_logging
+// feature flag), only for a sample of requests, and only the first few KB.
+//
+// SECRETS:
+// Bodies carry tokens, webhook secrets and the like. Before logging, values of
+// JSON fields and form parameters whose names look secret ("token",
+// "password", "secret", ...) are replaced with [REDACTED], as is anything
+// shaped like a JWT. Redaction works on text, so it also covers bodies cut
+// off by the size cap, which no longer parse as JSON.
+//
+// The handler's view is unchanged: the request body is copied as the handler
+// reads it, and the response as it's written, so nothing is buffered or
+// consumed on the handler's behalf.
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// BodyLogConfig tunes body logging.
+type BodyLogConfig struct {
+	SampleRate float64 // fraction of requests logged, 0–1
+	MaxBytes   int     // bytes kept per body; the rest is counted but dropped
+}
+
+// DefaultBodyLogMaxBytes is used when MaxBytes is 0.
+const DefaultBodyLogMaxBytes = 4 << 10
+
+// BodyLog logs sampled request and response bodies while enabled() is true.
+// enabled is checked on every request, so the mode can be toggled at runtime.
+func BodyLog(logger *slog.Logger, enabled func() bool, cfg BodyLogConfig) func(http.Handler) http.Handler {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultBodyLogMaxBytes
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !enabled() || rand.Float64() >= cfg.SampleRate {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			reqBody := &capBuffer{max: cfg.MaxBytes}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = &teeBody{ReadCloser: r.Body, w: reqBody}
+			}
+			respBody := &capBuffer{max: cfg.MaxBytes}
+			ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(respBody)
+
+			next.ServeHTTP(ww, r)
+
+			logger.InfoContext(r.Context(), "http bodies",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", ww.Status()),
+				slog.Group("request",
+					slog.String("content_type", r.Header.Get("Content-Type")),
+					slog.String("body", loggableBody(r.Header.Get("Content-Type"), reqBody)),
+					slog.Int64("bytes", reqBody.total),
+				),
+				slog.Group("response",
+					slog.String("content_type", ww.Header().Get("Content-Type")),
+					slog.String("body", loggableBody(ww.Header().Get("Content-Type"), respBody)),
+					slog.Int64("bytes", respBody.total),
+				),
+			)
+		})
+	}
+}
+
+// capBuffer keeps the first max bytes written to it and counts the rest.
+type capBuffer struct {
+	buf   bytes.Buffer
+	max   int
+	total int64
+}
+
+func (c *capBuffer) Write(p []byte) (int, error) {
+	c.total += int64(len(p))
+	if room := c.max - c.buf.Len(); room > 0 {
+		c.buf.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
+
+// teeBody copies what the handler reads from the request body into w.
+type teeBody struct {
+	io.ReadCloser
+	w io.Writer
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 {
+		t.w.Write(p[:n])
+	}
+	return n, err
+}
+
+// loggableBody renders a captured body for the log: redacted text, or a
+// placeholder for binary formats such as MessagePack.
+func loggableBody(contentType string, c *capBuffer) string {
+	if c.total == 0 {
+		return ""
+	}
+	if !isTextual(contentType) {
+		return "[binary body omitted]"
+	}
+	body := redactSecrets(c.buf.String())
+	if c.total > int64(c.buf.Len()) {
+		body += "…[truncated]"
+	}
+	return body
+}
+
+// isTextual reports whether a Content-Type is worth logging as text. An
+// absent Content-Type is treated as text: clients often leave it off JSON.
+func isTextual(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "json") || strings.HasSuffix(mediaType, "+json") ||
+		strings.HasSuffix(mediaType, "xml") ||
+		mediaType == "application/x-www-form-urlencoded"
+}
+
+// secretKey matches names of fields that hold credentials.
+const secretKey = `[\w-]*(?:token|secret|password|passwd|api[_-]?key|authorization|cookie|signature|credential)[\w-]*`
+
+var (
+	// "accessToken": "abc" → "accessToken": "[REDACTED]" (the closing quote
+	// may be missing when the body was truncated).
+	jsonSecret = regexp.MustCompile(`(?i)("` + secretKey + `"\s*:\s*)"(?:[^"\\]|\\.)*"?`)
+	// token=abc&x=1 → token=[REDACTED]&x=1
+	formSecret = regexp.MustCompile(`(?i)((?:^|[&?])` + secretKey + `=)[^&\s]*`)
+	// JWTs are three base64url segments, the first always starting "eyJ".
+	jwt = regexp.MustCompile(`eyJ[\w-]+\.[\w-]+\.[\w-]*`)
+)
+
+// redactSecrets blanks out credentials in a JSON or form-encoded body.
+func redactSecrets(body string) string {
+	body = jsonSecret.ReplaceAllString(body, `$1"[REDACTED]"`)
+	body = formSecret.ReplaceAllString(body, `$1[REDACTED]`)
+	return jwt.ReplaceAllString(body, "[REDACTED]")
+}
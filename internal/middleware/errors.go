@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+)
+
+// errorBody mirrors handler.ErrorResponse so middleware rejections (429, 413, ...)
+// have exactly the same JSON shape as errors returned by handlers.
+//
+// WHY NOT IMPORT handler.ErrorResponse?
+// Middleware sits BELOW handlers in the dependency graph — handlers may one day
+// use middleware helpers, and Go forbids import cycles. Duplicating a two-field
+// struct is cheaper than introducing a shared package just for it.
+type errorBody struct {
+	Error     string        `json:"error"`
+	Code      apperror.Code `json:"code"`
+	Message   string        `json:"message"`
+	RequestID string        `json:"requestId,omitempty"`
+}
+
+// writeJSONError sends the standard {"error": ..., "message": ..., "requestId": ...} body.
+func writeJSONError(w http.ResponseWriter, r *http.Request, status int, errorType string, code apperror.Code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(errorBody{
+		Error:     errorType,
+		Code:      code,
+		Message:   message,
+		RequestID: RequestID(r.Context()),
+	})
+}
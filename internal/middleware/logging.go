@@ -46,6 +46,12 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Unwrap exposes the underlying ResponseWriter so http.ResponseController
+// and the WebSocket upgrade can reach its Hijack and deadline methods.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // Logger returns an HTTP middleware that logs each request using Go's slog package.
 //
 // slog (structured logging) was added in Go 1.21. It produces structured log output
@@ -68,7 +74,7 @@ func Logger(logger *slog.Logger) func(http.Handler) http.Handler {
 			next.ServeHTTP(wrapped, r)
 
 			// Log the completed request with structured fields
-			logger.Info("request completed",
+			logger.InfoContext(r.Context(), "request completed",
 				slog.String("method", r.Method),
 				slog.String("path", r.URL.Path),
 				slog.Int("status", wrapped.statusCode),
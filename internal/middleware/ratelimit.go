@@ -0,0 +1,228 @@
+package middleware
+
+// RATE LIMITING:
+// A rate limiter caps how many requests a single client can make in a time window.
+// It protects the server from scrapers, runaway scripts and simple abuse.
+//
+// TOKEN BUCKET ALGORITHM:
+// Each client gets a "bucket" that holds up to Burst tokens. Tokens refill at
+// Rate per second. Every request takes one token; an empty bucket means 429.
+//
+//	Burst=5, Rate=1/s:  a client can fire 5 requests instantly,
+//	                    then sustain 1 request per second afterwards.
+//
+// golang.org/x/time/rate implements the bucket for us; we keep one limiter per IP.
+//
+// SHARED BUCKETS:
+// Buckets kept in this process only work for one instance: behind a load
+// balancer with three, a client gets three buckets and three times the
+// limit. RateLimitShared keeps them in a RateLimitStore instead — Redis (see
+// RedisRateLimitStore) — so every instance draws from the same bucket. If
+// the store fails, requests are limited in this process until it's back:
+// looser, but the site stays up.
+//
+// CLIENT IDENTITY:
+// We key buckets by r.RemoteAddr. Chi's RealIP middleware (registered earlier in
+// the chain) rewrites RemoteAddr from X-Forwarded-For / X-Real-IP, so clients
+// behind our reverse proxy are told apart correctly.
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+)
+
+// RateLimitConfig configures a token-bucket limiter for one route group.
+type RateLimitConfig struct {
+	Rate  float64 // tokens added per second; 0 disables limiting
+	Burst int     // bucket capacity (max requests in a burst)
+}
+
+// Enabled reports whether the config actually limits anything.
+func (c RateLimitConfig) Enabled() bool {
+	return c.Rate > 0 && c.Burst > 0
+}
+
+// visitorTTL is how long an idle client's bucket is remembered.
+// After that its bucket would be full again anyway, so forgetting it is free.
+const visitorTTL = 10 * time.Minute
+
+type visitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// ipLimiter holds one token bucket per client IP.
+type ipLimiter struct {
+	cfg       RateLimitConfig
+	mu        sync.Mutex
+	visitors  map[string]*visitor
+	lastSweep time.Time
+	now       func() time.Time // injectable clock for tests
+}
+
+func newIPLimiter(cfg RateLimitConfig) *ipLimiter {
+	return &ipLimiter{
+		cfg:       cfg,
+		visitors:  make(map[string]*visitor),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// allow takes a token for ip and returns whether the request may proceed,
+// the tokens remaining, and how long until the next token is available.
+func (l *ipLimiter) allow(ip string) (ok bool, remaining int, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	// Sweep idle visitors at most once per TTL so the map can't grow forever.
+	if now.Sub(l.lastSweep) > visitorTTL {
+		for key, v := range l.visitors {
+			if now.Sub(v.lastSeen) > visitorTTL {
+				delete(l.visitors, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	v, exists := l.visitors[ip]
+	if !exists {
+		v = &visitor{limiter: rate.NewLimiter(rate.Limit(l.cfg.Rate), l.cfg.Burst)}
+		l.visitors[ip] = v
+	}
+	v.lastSeen = now
+
+	ok = v.limiter.AllowN(now, 1)
+	tokens := v.limiter.TokensAt(now)
+	remaining = int(math.Max(0, math.Floor(tokens)))
+	if !ok {
+		// Time until one whole token has refilled.
+		retryAfter = time.Duration((1 - tokens) / l.cfg.Rate * float64(time.Second))
+	}
+	return ok, remaining, retryAfter
+}
+
+// RateLimitResult is the outcome of taking a token from a bucket.
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int           // whole tokens left
+	RetryAfter time.Duration // until the next token, if not Allowed
+}
+
+// RateLimitStore keeps token buckets, by key, where every instance of the
+// app can share them.
+type RateLimitStore interface {
+	// Take takes a token from key's bucket, which holds cfg.Burst tokens and
+	// refills at cfg.Rate a second.
+	Take(ctx context.Context, key string, cfg RateLimitConfig) (RateLimitResult, error)
+}
+
+// MemoryRateLimitStore is a RateLimitStore in this process: for one
+// instance, or tests.
+type MemoryRateLimitStore struct {
+	mu       sync.Mutex
+	limiters map[RateLimitConfig]*ipLimiter
+}
+
+// NewMemoryRateLimitStore creates an empty MemoryRateLimitStore.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{limiters: make(map[RateLimitConfig]*ipLimiter)}
+}
+
+// Take takes a token from key's bucket.
+func (m *MemoryRateLimitStore) Take(_ context.Context, key string, cfg RateLimitConfig) (RateLimitResult, error) {
+	m.mu.Lock()
+	l, ok := m.limiters[cfg]
+	if !ok {
+		l = newIPLimiter(cfg)
+		m.limiters[cfg] = l
+	}
+	m.mu.Unlock()
+	allowed, remaining, retryAfter := l.allow(key)
+	return RateLimitResult{Allowed: allowed, Remaining: remaining, RetryAfter: retryAfter}, nil
+}
+
+// RateLimit returns middleware that limits each client IP using a token bucket.
+//
+// Every response carries the standard-ish informational headers:
+//
+//	X-RateLimit-Limit:     bucket capacity
+//	X-RateLimit-Remaining: tokens left after this request
+//	X-RateLimit-Reset:     seconds until the next token is available (0 if not limited)
+//
+// Rejected requests get 429 Too Many Requests with a Retry-After header and
+// the standard JSON error body.
+func RateLimit(cfg RateLimitConfig) func(http.Handler) http.Handler {
+	return RateLimitShared(cfg, NewMemoryRateLimitStore(), "", nil)
+}
+
+// RateLimitShared is RateLimit with the buckets in store, under name (e.g.
+// "api") so route groups sharing a store have separate buckets. When the
+// store fails, the error is logged (at most once a minute) and the request
+// is limited in this process instead.
+func RateLimitShared(cfg RateLimitConfig, store RateLimitStore, name string, logger *slog.Logger) func(http.Handler) http.Handler {
+	if !cfg.Enabled() {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	fallback := newIPLimiter(cfg)
+	var lastWarning atomic.Int64 // unix seconds
+
+	take := func(r *http.Request) RateLimitResult {
+		ip := clientIP(r)
+		res, err := store.Take(r.Context(), name+":"+ip, cfg)
+		if err == nil {
+			return res
+		}
+		if now := time.Now().Unix(); logger != nil && now-lastWarning.Load() >= 60 {
+			lastWarning.Store(now)
+			logger.WarnContext(r.Context(), "rate limit store failed; limiting in this process",
+				slog.String("limiter", name), slog.String("error", err.Error()))
+		}
+		ok, remaining, retryAfter := fallback.allow(ip)
+		return RateLimitResult{Allowed: ok, Remaining: remaining, RetryAfter: retryAfter}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res := take(r)
+
+			resetSeconds := int(math.Ceil(res.RetryAfter.Seconds()))
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(cfg.Burst))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.Itoa(resetSeconds))
+
+			if !res.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(resetSeconds))
+				writeJSONError(w, r, http.StatusTooManyRequests, "rate_limited", apperror.CodeRateLimited,
+					fmt.Sprintf("Too many requests — try again in %d seconds", resetSeconds))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP extracts the host part of r.RemoteAddr ("1.2.3.4:5678" → "1.2.3.4").
+// After chi's RealIP middleware, RemoteAddr may already be a bare IP.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/redis"
+)
+
+// RedisRateLimitStore keeps token buckets in Redis, shared by every
+// instance of the app.
+//
+// WHY A LUA SCRIPT?
+// Taking a token is read, refill, subtract, write. Done as separate
+// commands, two instances can read the same bucket, both see a token and
+// both let a request through. Redis runs a script with nothing else in
+// between, so the bucket can't be read twice before it's written. The
+// script reads the clock with TIME, so instances with skewed clocks still
+// agree; that needs Redis 5 or later.
+//
+// A bucket is one string, "tokens milliseconds", that expires once it would
+// have refilled — a full bucket and no bucket are the same.
+type RedisRateLimitStore struct {
+	client *redis.Client
+	prefix string
+}
+
+var _ RateLimitStore = (*RedisRateLimitStore)(nil)
+
+// NewRedisRateLimitStore creates a store putting prefix before every key
+// (e.g. "playground:ratelimit:").
+func NewRedisRateLimitStore(client *redis.Client, prefix string) *RedisRateLimitStore {
+	return &RedisRateLimitStore{client: client, prefix: prefix}
+}
+
+// tokenBucketScript takes a token from the bucket KEYS[1], which holds
+// ARGV[2] tokens and refills at ARGV[1] a second. It returns {allowed (0 or
+// 1), whole tokens left, milliseconds until the next token}.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local tokens = burst
+local state = redis.call('GET', KEYS[1])
+if state then
+  local sep = string.find(state, ' ', 1, true)
+  local last = tonumber(string.sub(state, sep + 1))
+  tokens = math.min(burst, tonumber(string.sub(state, 1, sep - 1)) + math.max(0, now - last) * rate / 1000)
+end
+
+local allowed, retry = 0, 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry = math.ceil((1 - tokens) * 1000 / rate)
+end
+
+local full = math.ceil((burst - tokens) * 1000 / rate)
+redis.call('SET', KEYS[1], string.format('%.6f %d', tokens, now), 'PX', math.max(full, 1))
+return {allowed, math.floor(tokens), retry}
+`)
+
+// Take runs the token bucket script on key's bucket.
+func (s *RedisRateLimitStore) Take(ctx context.Context, key string, cfg RateLimitConfig) (RateLimitResult, error) {
+	reply, err := tokenBucketScript.Run(ctx, s.client, []string{s.prefix + key},
+		strconv.FormatFloat(cfg.Rate, 'f', -1, 64), strconv.Itoa(cfg.Burst))
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	items, ok := reply.([]any)
+	if !ok || len(items) != 3 {
+		return RateLimitResult{}, fmt.Errorf("rate limit script: unexpected reply %v", reply)
+	}
+	var n [3]int64
+	for i, item := range items {
+		if n[i], err = redis.Int(item, nil); err != nil {
+			return RateLimitResult{}, fmt.Errorf("rate limit script: %w", err)
+		}
+	}
+	return RateLimitResult{
+		Allowed:    n[0] == 1,
+		Remaining:  int(n[1]),
+		RetryAfter: time.Duration(n[2]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,210 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/redis/redistest"
+)
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestRateLimit_AllowsBurstThenRejects(t *testing.T) {
+	h := RateLimit(RateLimitConfig{Rate: 1, Burst: 3})(okHandler())
+
+	for i := 0; i < 3; i++ {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/snippets", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		h.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i+1, rr.Code)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/snippets", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	h.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header on 429")
+	}
+	if got := rr.Header().Get("X-RateLimit-Remaining"); got != "0" {
+		t.Errorf("X-RateLimit-Remaining = %q, want 0", got)
+	}
+	if got := rr.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+}
+
+func TestRateLimit_SeparateBucketsPerIP(t *testing.T) {
+	h := RateLimit(RateLimitConfig{Rate: 1, Burst: 1})(okHandler())
+
+	for _, addr := range []string{"10.0.0.1:1", "10.0.0.2:1"} {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = addr
+		h.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, want 200", addr, rr.Code)
+		}
+	}
+}
+
+func TestRateLimit_Refills(t *testing.T) {
+	l := newIPLimiter(RateLimitConfig{Rate: 2, Burst: 1})
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	if ok, _, _ := l.allow("ip"); !ok {
+		t.Fatal("first request should be allowed")
+	}
+	ok, _, retry := l.allow("ip")
+	if ok {
+		t.Fatal("second immediate request should be rejected")
+	}
+	if retry <= 0 || retry > time.Second {
+		t.Errorf("retryAfter = %v, want (0, 1s]", retry)
+	}
+
+	now = now.Add(600 * time.Millisecond) // 2 tokens/s → a full token after 500ms
+	if ok, _, _ := l.allow("ip"); !ok {
+		t.Error("request after refill should be allowed")
+	}
+}
+
+func TestRateLimit_DisabledIsPassthrough(t *testing.T) {
+	h := RateLimit(RateLimitConfig{})(okHandler())
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rr.Header().Get("X-RateLimit-Limit") != "" {
+		t.Error("disabled limiter should not set rate limit headers")
+	}
+}
+
+// hit sends one request from addr and returns the status.
+func hit(h http.Handler, addr string) int {
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = addr
+	h.ServeHTTP(rr, req)
+	return rr.Code
+}
+
+// fakeTokenBucket does in Go what tokenBucketScript does in Lua, for
+// redistest, which can't run Lua.
+func fakeTokenBucket(tx *redistest.Tx, keys, args []string) any {
+	rate, _ := strconv.ParseFloat(args[0], 64)
+	burst, _ := strconv.ParseFloat(args[1], 64)
+	now := tx.Now().UnixMilli()
+
+	tokens := burst
+	if state, ok := tx.Get(keys[0]); ok {
+		var last int64
+		fmt.Sscanf(state, "%g %d", &tokens, &last)
+		tokens = math.Min(burst, tokens+float64(max(0, now-last))*rate/1000)
+	}
+	allowed, retry := int64(0), int64(0)
+	if tokens >= 1 {
+		tokens--
+		allowed = 1
+	} else {
+		retry = int64(math.Ceil((1 - tokens) * 1000 / rate))
+	}
+	full := time.Duration(math.Ceil((burst-tokens)*1000/rate)) * time.Millisecond
+	tx.Set(keys[0], fmt.Sprintf("%.6f %d", tokens, now), max(full, time.Millisecond))
+	return []any{allowed, int64(math.Floor(tokens)), retry}
+}
+
+func TestRateLimitShared_InstancesShareBuckets(t *testing.T) {
+	srv := redistest.NewServer(t)
+	srv.HandleScript(tokenBucketScript, fakeTokenBucket)
+	now := time.Now()
+	srv.SetNow(func() time.Time { return now })
+
+	stores := map[string]RateLimitStore{
+		"memory": NewMemoryRateLimitStore(),
+		"redis":  NewRedisRateLimitStore(srv.Client(t), "test:"),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			cfg := RateLimitConfig{Rate: 1, Burst: 2}
+			// Two instances of the app behind a load balancer.
+			a := RateLimitShared(cfg, store, "api", nil)(okHandler())
+			b := RateLimitShared(cfg, store, "api", nil)(okHandler())
+			// Another route group's limiter in the same store.
+			other := RateLimitShared(cfg, store, "execute", nil)(okHandler())
+
+			if hit(a, "10.0.0.1:1") != http.StatusOK || hit(b, "10.0.0.1:1") != http.StatusOK {
+				t.Fatal("the first two requests should be allowed")
+			}
+			if code := hit(a, "10.0.0.1:1"); code != http.StatusTooManyRequests {
+				t.Errorf("third request, across both instances: status = %d, want 429", code)
+			}
+			if code := hit(other, "10.0.0.1:1"); code != http.StatusOK {
+				t.Errorf("another limiter's bucket: status = %d, want 200", code)
+			}
+		})
+	}
+
+	t.Run("redis refills", func(t *testing.T) {
+		store := NewRedisRateLimitStore(srv.Client(t), "refill:")
+		cfg := RateLimitConfig{Rate: 2, Burst: 1}
+		ctx := context.Background()
+		if res, err := store.Take(ctx, "ip", cfg); err != nil || !res.Allowed {
+			t.Fatalf("Take() = %+v, %v; want allowed", res, err)
+		}
+		res, err := store.Take(ctx, "ip", cfg)
+		if err != nil || res.Allowed || res.RetryAfter != 500*time.Millisecond {
+			t.Errorf("Take(empty) = %+v, %v; want refused, retry in 500ms", res, err)
+		}
+		now = now.Add(500 * time.Millisecond)
+		if res, err := store.Take(ctx, "ip", cfg); err != nil || !res.Allowed {
+			t.Errorf("Take(refilled) = %+v, %v; want allowed", res, err)
+		}
+	})
+}
+
+// failingStore is a RateLimitStore whose backend is down.
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, RateLimitConfig) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("connection refused")
+}
+
+func TestRateLimitShared_FallsBackWhenStoreFails(t *testing.T) {
+	var logs strings.Builder
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	h := RateLimitShared(RateLimitConfig{Rate: 1, Burst: 1}, failingStore{}, "api", logger)(okHandler())
+
+	if code := hit(h, "10.0.0.1:1"); code != http.StatusOK {
+		t.Errorf("first request: status = %d, want 200", code)
+	}
+	if code := hit(h, "10.0.0.1:1"); code != http.StatusTooManyRequests {
+		t.Errorf("second request: status = %d, want 429 from the in-process limiter", code)
+	}
+	if n := strings.Count(logs.String(), "rate limit store failed"); n != 1 {
+		t.Errorf("logged the failure %d times, want once", n)
+	}
+}
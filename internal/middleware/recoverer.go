@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/errreport"
+)
+
+// Recoverer returns middleware that turns a panicking handler into a 500 and
+// reports the panic to rep.
+//
+// It replaces chi's Recoverer, which only prints the stack to stderr. Here the
+// panic is logged (with the request ID), sent to the error tracker, and the
+// client gets the standard JSON error body.
+//
+// Recoverer also puts rep into the request context, so handlers can report
+// the errors they turn into 500s (see handler.writeError).
+func Recoverer(rep errreport.Reporter, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(errreport.NewContext(r.Context(), rep))
+
+			defer func() {
+				rvr := recover()
+				if rvr == nil {
+					return
+				}
+				// http.ErrAbortHandler is the sanctioned way to abort a
+				// response; let net/http handle it quietly.
+				if rvr == http.ErrAbortHandler {
+					panic(rvr)
+				}
+
+				err := fmt.Errorf("panic: %v", rvr)
+				logger.ErrorContext(r.Context(), "handler panicked",
+					slog.String("error", err.Error()),
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("stack", string(debug.Stack())),
+				)
+				rep.Report(r.Context(), err, r)
+
+				// Upgraded connections (WebSockets) have no HTTP response to write.
+				if r.Header.Get("Connection") != "Upgrade" {
+					writeJSONError(w, r, http.StatusInternalServerError, "internal_error", apperror.CodeInternal, "An internal error occurred")
+				}
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// recordingReporter remembers the errors reported to it.
+type recordingReporter struct {
+	errs []error
+}
+
+func (r *recordingReporter) Report(_ context.Context, err error, _ *http.Request) {
+	r.errs = append(r.errs, err)
+}
+
+func (r *recordingReporter) Flush(context.Context) error { return nil }
+
+func TestRecoverer(t *testing.T) {
+	rep := &recordingReporter{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := Recoverer(rep, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rr.Code)
+	}
+	var body errorBody
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if body.Error != "internal_error" {
+		t.Errorf("error = %q, want internal_error", body.Error)
+	}
+
+	if len(rep.errs) != 1 || rep.errs[0].Error() != "panic: boom" {
+		t.Errorf("reported %v, want one \"panic: boom\"", rep.errs)
+	}
+}
+
+func TestRecoverer_AbortHandlerPropagates(t *testing.T) {
+	rep := &recordingReporter{}
+	h := Recoverer(rep, slog.New(slog.NewTextHandler(io.Discard, nil)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if rvr := recover(); !errors.Is(rvr.(error), http.ErrAbortHandler) {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", rvr)
+		}
+		if len(rep.errs) != 0 {
+			t.Errorf("aborted request was reported: %v", rep.errs)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
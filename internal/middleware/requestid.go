@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestIDHeader is the response header that carries the request ID.
+const RequestIDHeader = "X-Request-ID"
+
+// REQUEST IDS:
+// chi's RequestID middleware gives every request a unique ID (or reuses the
+// client's X-Request-ID) and stores it in the request context. On its own that
+// ID never leaves the server. The pieces below surface it everywhere:
+//
+//   - EchoRequestID sends it back as the X-Request-ID response header
+//   - error bodies include it as "requestId" (see writeJSONError and
+//     handler.writeError)
+//   - WithRequestID adds it to every log line written with a request context
+//
+// So when a user reports "I got an error", the ID on their screen finds the
+// exact log lines for that request.
+
+// EchoRequestID returns the request ID in the X-Request-ID response header.
+// It must run after chi's RequestID middleware.
+func EchoRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := chimiddleware.GetReqID(r.Context()); id != "" {
+			w.Header().Set(RequestIDHeader, id)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequestID returns the request ID stored in ctx, or "" if there is none.
+func RequestID(ctx context.Context) string {
+	return chimiddleware.GetReqID(ctx)
+}
+
+// WithRequestID wraps a slog.Handler so records logged with a request context
+// (logger.InfoContext(r.Context(), ...)) carry a request_id attribute.
+//
+// SLOG HANDLER WRAPPING:
+// A slog.Handler receives each record together with the context passed to the
+// *Context logging methods. Wrapping lets us add attributes from that context
+// without every call site having to remember to do it.
+func WithRequestID(h slog.Handler) slog.Handler {
+	return requestIDHandler{h}
+}
+
+type requestIDHandler struct {
+	slog.Handler
+}
+
+func (h requestIDHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		rec.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, rec)
+}
+
+// WithAttrs and WithGroup must re-wrap, or loggers derived with .With(...)
+// would silently lose the request ID.
+func (h requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestIDHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h requestIDHandler) WithGroup(name string) slog.Handler {
+	return requestIDHandler{h.Handler.WithGroup(name)}
+}
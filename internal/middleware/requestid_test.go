@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+func TestEchoRequestID(t *testing.T) {
+	var seen string
+	h := chimiddleware.RequestID(EchoRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+	})))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	got := rr.Header().Get(RequestIDHeader)
+	if got == "" || got != seen {
+		t.Errorf("X-Request-ID = %q, want the context's ID %q", got, seen)
+	}
+}
+
+func TestWithRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(WithRequestID(slog.NewTextHandler(&buf, nil))).With("component", "test")
+
+	ctx := context.WithValue(context.Background(), chimiddleware.RequestIDKey, "req-42")
+	logger.InfoContext(ctx, "with request")
+	logger.Info("without request")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2", len(lines))
+	}
+	if !strings.Contains(lines[0], "request_id=req-42") {
+		t.Errorf("line with context lacks request_id: %s", lines[0])
+	}
+	if strings.Contains(lines[1], "request_id") {
+		t.Errorf("line without context has request_id: %s", lines[1])
+	}
+}
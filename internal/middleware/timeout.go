@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+)
+
+// Timeout returns middleware that gives each request a deadline of d.
+//
+// WHY PER ROUTE GROUP?
+// http.Server's ReadTimeout/WriteTimeout apply to every request alike. But a
+// snippet lookup that takes more than a couple of seconds is a bug, while code
+// execution legitimately takes several. Per-group deadlines let each kind of
+// route fail fast at its own pace.
+//
+// HOW IT WORKS:
+//  1. The request context gets a deadline (context.WithTimeout). Everything
+//     that honours the context — database queries, the executor, outgoing
+//     HTTP calls — is cancelled when it passes.
+//  2. The handler then usually fails with a context error and tries to write
+//     its own error (often a 500). The wrapped ResponseWriter notices the
+//     deadline has passed and answers 504 Gateway Timeout JSON instead,
+//     discarding whatever the handler writes afterwards.
+//
+// The handler runs on the request goroutine, so responses are never buffered
+// (streaming still works). The flip side: a handler that ignores its context
+// isn't interrupted — it just can't send anything but the 504 once it's late.
+//
+// A d <= 0 disables the middleware.
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	if d <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			r = r.WithContext(ctx)
+			tw := &timeoutWriter{ResponseWriter: w, req: r}
+			next.ServeHTTP(tw, r)
+
+			// Handler returned without writing anything after the deadline
+			// (e.g. it just gave up): make sure the client gets the 504.
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			if !tw.wroteHeader && tw.expired() {
+				tw.writeTimeout()
+			}
+		})
+	}
+}
+
+// timeoutWriter replaces late responses with a 504.
+type timeoutWriter struct {
+	http.ResponseWriter
+	req *http.Request
+
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool // a 504 was sent; swallow the handler's output
+}
+
+func (tw *timeoutWriter) expired() bool {
+	return errors.Is(tw.req.Context().Err(), context.DeadlineExceeded)
+}
+
+// writeTimeout sends the 504 body. Callers hold tw.mu.
+func (tw *timeoutWriter) writeTimeout() {
+	tw.wroteHeader = true
+	tw.timedOut = true
+	writeJSONError(tw.ResponseWriter, tw.req, http.StatusGatewayTimeout, "timeout", apperror.CodeRequestTimeout,
+		"The request took too long to process")
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.wroteHeader {
+		return
+	}
+	if tw.expired() {
+		tw.writeTimeout()
+		return
+	}
+	tw.wroteHeader = true
+	tw.ResponseWriter.WriteHeader(status)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	wrote := tw.wroteHeader
+	tw.mu.Unlock()
+
+	if !wrote {
+		tw.WriteHeader(http.StatusOK)
+	}
+
+	tw.mu.Lock()
+	timedOut := tw.timedOut
+	tw.mu.Unlock()
+	if timedOut {
+		// Pretend the write succeeded so the handler finishes quietly.
+		return len(b), nil
+	}
+	return tw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer (flushing,
+// deadlines).
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout_LateHandlerGets504(t *testing.T) {
+	// Like a handler whose DB query was cancelled: it waits for the context,
+	// then writes its own 500 — which must be replaced by the 504.
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		http.Error(w, "db: context deadline exceeded", http.StatusInternalServerError)
+	})
+
+	rr := httptest.NewRecorder()
+	Timeout(10*time.Millisecond)(slow).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/snippets", nil))
+
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504", rr.Code)
+	}
+	var body errorBody
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("body is not the JSON error shape: %v", err)
+	}
+	if body.Error != "timeout" {
+		t.Errorf("error = %q, want timeout", body.Error)
+	}
+}
+
+func TestTimeout_SilentLateHandlerGets504(t *testing.T) {
+	silent := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	rr := httptest.NewRecorder()
+	Timeout(10*time.Millisecond)(silent).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want 504", rr.Code)
+	}
+}
+
+func TestTimeout_FastHandlerUnaffected(t *testing.T) {
+	fast := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); !ok {
+			t.Error("request context has no deadline")
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("ok"))
+	})
+
+	rr := httptest.NewRecorder()
+	Timeout(time.Second)(fast).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", nil))
+
+	if rr.Code != http.StatusCreated || rr.Body.String() != "ok" {
+		t.Errorf("got %d %q, want 201 ok", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,19 @@
+package model
+
+// AbuseFinding is one thing about a piece of code that looks like abuse of
+// the sandbox.
+type AbuseFinding struct {
+	Rule   string `json:"rule"`   // e.g. "cryptominer", "fork_bomb"
+	Detail string `json:"detail"` // what matched, for the audit trail
+	Severe bool   `json:"severe"` // high confidence; blocked unless only monitoring
+}
+
+// AbuseReport is published with EventExecutionFlagged when code trips one
+// or more abuse checks. It never holds the code itself, only its hash.
+type AbuseReport struct {
+	UserID   string         `json:"userId,omitempty"` // "" for anonymous users
+	Source   string         `json:"source"`           // "execute", "live_run" or "grading"
+	CodeHash string         `json:"codeHash"`         // hex SHA-256 of the code
+	Findings []AbuseFinding `json:"findings"`
+	Blocked  bool           `json:"blocked"` // false: flagged, but the code ran
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Activity kinds.
+const (
+	ActivityPublished = "published" // the actor made their snippet public
+	ActivityFork      = "fork"      // the actor forked a public snippet
+	ActivityStar      = "star"      // the actor starred a public snippet
+)
+
+// Activity is one entry in the public activity feed: someone did something
+// to a public snippet. IDs only ever increase, so they order the feed and
+// make its cursors.
+type Activity struct {
+	ID          int64     `json:"id"          db:"id"`
+	Kind        string    `json:"kind"        db:"kind"`
+	ActorID     string    `json:"-"           db:"actor_id"`
+	ActorLogin  string    `json:"actorLogin"  db:"-"` // joined from users
+	SnippetID   string    `json:"snippetId"   db:"snippet_id"`
+	SnippetName string    `json:"snippetName" db:"-"` // joined from snippets
+	CreatedAt   time.Time `json:"createdAt"   db:"created_at"`
+}
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// Badge IDs.
+const (
+	BadgeFirstSnippet = "first-snippet"
+	BadgeHundredRuns  = "hundred-runs"
+	BadgeStreak10     = "streak-10"
+	BadgeFirstFork    = "first-fork"
+)
+
+// Badge is an achievement a user earns once.
+type Badge struct {
+	ID          string `json:"id"          db:"id"`
+	Name        string `json:"name"        db:"name"`
+	Description string `json:"description" db:"description"`
+}
+
+// Badges is every badge there is. The database's badges table is kept in
+// step with it when it opens.
+var Badges = []Badge{
+	{ID: BadgeFirstSnippet, Name: "Hello, world", Description: "Saved your first snippet"},
+	{ID: BadgeHundredRuns, Name: "Century", Description: "Ran code 100 times"},
+	{ID: BadgeStreak10, Name: "On a roll", Description: "Solved the daily challenge 10 days in a row"},
+	{ID: BadgeFirstFork, Name: "Trendsetter", Description: "Someone forked one of your snippets"},
+}
+
+// UserBadge is a badge a user has earned, and when.
+type UserBadge struct {
+	Badge
+	AwardedAt time.Time `json:"awardedAt" db:"awarded_at"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// ChallengeDateLayout is how challenge dates are written: one challenge per
+// UTC day.
+const ChallengeDateLayout = "2006-01-02"
+
+// Challenge schedules an exercise as the daily challenge for one date. The
+// exercise supplies the statement, starter code and hidden tests.
+type Challenge struct {
+	Date       string    `json:"date"               db:"date"` // YYYY-MM-DD, UTC
+	ExerciseID string    `json:"exerciseId"         db:"exercise_id"`
+	Exercise   *Exercise `json:"exercise,omitempty" db:"-"`
+	CreatedBy  string    `json:"createdBy"          db:"created_by"`
+	CreatedAt  time.Time `json:"createdAt"          db:"created_at"`
+}
+
+// Streak is a user's run of consecutive days solving the daily challenge.
+// A streak survives until the end of the first day without a solve, so
+// today's challenge still counts toward it until midnight UTC.
+type Streak struct {
+	Current     int    `json:"current"`
+	Longest     int    `json:"longest"`
+	LastSolved  string `json:"lastSolved,omitempty"` // date of the most recent solve
+	SolvedToday bool   `json:"solvedToday"`
+}
@@ -0,0 +1,79 @@
+package model
+
+import "time"
+
+// Class roles. They're per class, unrelated to User.Role: whoever creates a
+// class teaches it, and everyone who joins with its code is a student.
+const (
+	ClassTeacher = "teacher"
+	ClassStudent = "student"
+)
+
+// Class is a group of learners working through assignments together.
+// JoinCode is only shown to the class's teachers.
+type Class struct {
+	ID        string    `json:"id"                 db:"id"`
+	Name      string    `json:"name"               db:"name"`
+	JoinCode  string    `json:"joinCode,omitempty" db:"join_code"`
+	OwnerID   string    `json:"ownerId"            db:"owner_id"`
+	CreatedAt time.Time `json:"createdAt"          db:"created_at"`
+}
+
+// ClassMember is one user's place in a class.
+type ClassMember struct {
+	ClassID  string    `json:"classId"  db:"class_id"`
+	UserID   string    `json:"userId"   db:"user_id"`
+	Login    string    `json:"login"    db:"login"` // from users, for display
+	Role     string    `json:"role"     db:"role"`
+	JoinedAt time.Time `json:"joinedAt" db:"joined_at"`
+}
+
+// ClassMembership is a class as seen by one of its members.
+type ClassMembership struct {
+	Class
+	Role string `json:"role"`
+}
+
+// Late policies: what happens to a submission after an assignment's due date.
+const (
+	LateAccept   = "accept"   // accepted and marked late
+	LatePenalize = "penalize" // accepted, marked late, and its score reduced by LatePenalty percent
+	LateReject   = "reject"   // refused: the assignment is closed
+)
+
+// LatePolicies lists every valid late policy.
+var LatePolicies = []string{LateAccept, LatePenalize, LateReject}
+
+// Assignment sets an exercise for a class. OpensAt and DueAt are optional:
+// without them the assignment is open from the start and never late.
+type Assignment struct {
+	ID          string     `json:"id"          db:"id"`
+	ClassID     string     `json:"classId"     db:"class_id"`
+	ExerciseID  string     `json:"exerciseId"  db:"exercise_id"`
+	Title       string     `json:"title"       db:"title"`
+	OpensAt     *time.Time `json:"opensAt"     db:"opens_at"`
+	DueAt       *time.Time `json:"dueAt"       db:"due_at"`
+	LatePolicy  string     `json:"latePolicy"  db:"late_policy"`
+	LatePenalty int        `json:"latePenalty" db:"late_penalty"` // percent, for LatePenalize
+	CreatedBy   string     `json:"createdBy"   db:"created_by"`
+	CreatedAt   time.Time  `json:"createdAt"   db:"created_at"`
+}
+
+// Completion statuses for one student on one assignment.
+const (
+	CompletionNotStarted    = "not_started"    // no submissions, not yet due
+	CompletionMissing       = "missing"        // no submissions, past due
+	CompletionAttempted     = "attempted"      // submitted, but nothing passed
+	CompletionCompleted     = "completed"      // passed on time
+	CompletionCompletedLate = "completed_late" // passed, but only after the due date
+)
+
+// AssignmentProgress is how far one student has got with an assignment.
+type AssignmentProgress struct {
+	UserID          string     `json:"userId"`
+	Login           string     `json:"login"`
+	Status          string     `json:"status"`
+	Submissions     int        `json:"submissions"`
+	BestScore       int        `json:"bestScore"`
+	LastSubmittedAt *time.Time `json:"lastSubmittedAt"`
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// Comment is feedback on a snippet. It may be anchored to a range of lines,
+// which always refer to the code as it was at Version: once the snippet is
+// edited, the lines may have moved, so the comment is marked Outdated.
+type Comment struct {
+	ID        string    `json:"id"                  db:"id"`
+	SnippetID string    `json:"snippetId"           db:"snippet_id"`
+	UserID    string    `json:"userId"              db:"user_id"`
+	Login     string    `json:"login"               db:"-"` // the author's, joined from users
+	Body      string    `json:"body"                db:"body"`
+	Version   int       `json:"version"             db:"version"`    // the snippet version commented on
+	LineStart int       `json:"lineStart,omitempty" db:"line_start"` // 1-based; 0 for a comment on the whole snippet
+	LineEnd   int       `json:"lineEnd,omitempty"   db:"line_end"`   // inclusive
+	Outdated  bool      `json:"outdated"            db:"-"`          // the snippet has changed since
+	CreatedAt time.Time `json:"createdAt"           db:"created_at"`
+}
@@ -0,0 +1,16 @@
+package model
+
+// EmailPreferences say which emails a user wants. Sign-in links aren't
+// listed: they're only sent when the user asks for one.
+type EmailPreferences struct {
+	UserID  string `json:"-"       db:"user_id"`
+	Grading bool   `json:"grading" db:"grading"` // the result of each graded submission
+	Digest  bool   `json:"digest"  db:"digest"`  // a weekly summary of unread notifications
+}
+
+// DefaultEmailPreferences are a user's preferences until they change them.
+// Grading results are already on screen when they arrive, so emailing each
+// one is opt-in; the digest only goes out in weeks with something unread.
+func DefaultEmailPreferences(userID string) *EmailPreferences {
+	return &EmailPreferences{UserID: userID, Grading: false, Digest: true}
+}
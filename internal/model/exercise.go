@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// Exercise is a programming task for learners: a prompt, code to start from,
+// and hidden pytest tests that decide whether a solution is correct.
+//
+// TestCode is the hidden part. It's never serialised: learners must not see
+// the tests they're graded against, so handlers add it explicitly to the
+// responses meant for the exercise's author. Hints are held back the same
+// way; learners unlock them one at a time, and only HintCount is public.
+type Exercise struct {
+	ID          string    `json:"id"          db:"id"`
+	Title       string    `json:"title"       db:"title"`
+	Prompt      string    `json:"prompt"      db:"prompt"` // Markdown
+	StarterCode string    `json:"starterCode" db:"starter_code"`
+	TestCode    string    `json:"-"           db:"test_code"`
+	Hints       []string  `json:"-"           db:"hints"` // in unlock order; stored as JSON
+	HintCount   int       `json:"hintCount"   db:"-"`
+	HintPenalty int       `json:"hintPenalty" db:"hint_penalty"` // percent off the score per hint unlocked
+	Coverage    bool      `json:"coverage"    db:"coverage"`     // report line coverage with each submission
+	AuthorID    string    `json:"authorId"    db:"author_id"`
+	CreatedAt   time.Time `json:"createdAt"   db:"created_at"`
+	UpdatedAt   time.Time `json:"updatedAt"   db:"updated_at"`
+}
+
+// HintProgress is how far one learner has got through an exercise's hints.
+type HintProgress struct {
+	Total          int      `json:"total"`
+	Unlocked       int      `json:"unlocked"`
+	PenaltyPerHint int      `json:"penaltyPerHint"` // percent
+	ScorePenalty   int      `json:"scorePenalty"`   // percent taken off submissions from now on
+	Hints          []string `json:"hints"`          // the unlocked ones, in order
+}
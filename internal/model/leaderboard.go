@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// LeaderboardEntry is one user's line on a leaderboard. On an exercise's
+// board it's their best submission; on a class's board it sums their best
+// submissions across the class's assigned exercises.
+type LeaderboardEntry struct {
+	Rank        int       `json:"rank"`
+	UserID      string    `json:"userId"`
+	Login       string    `json:"login"`
+	AvatarURL   string    `json:"avatarUrl"`
+	Score       int       `json:"score"`      // best score; a class's board adds them up
+	Solved      int       `json:"solved"`     // exercises with every test passed
+	DurationMS  float64   `json:"durationMs"` // tests' running time on the best attempt(s)
+	Submissions int       `json:"submissions"`
+	AchievedAt  time.Time `json:"achievedAt"` // when the best attempt (the latest of them, for a class) was made
+}
+
+// Leaderboard is a ranked list, as of the last time it was materialized.
+type Leaderboard struct {
+	Entries     []LeaderboardEntry `json:"entries"`
+	RefreshedAt *time.Time         `json:"refreshedAt"` // nil until the first refresh
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// Notification kinds.
+const (
+	NotificationComment  = "comment"  // someone commented on your snippet
+	NotificationFork     = "fork"     // someone forked your snippet
+	NotificationStar     = "star"     // someone starred your snippet
+	NotificationGrading  = "grading"  // your submission was graded
+	NotificationTransfer = "transfer" // someone offered you their snippet
+)
+
+// Notification tells a user about something that happened to their work.
+// The IDs say what it's about; Message says it in words.
+type Notification struct {
+	ID           string     `json:"id"                     db:"id"`
+	UserID       string     `json:"-"                      db:"user_id"` // who it's for
+	Kind         string     `json:"kind"                   db:"kind"`
+	ActorID      string     `json:"actorId,omitempty"      db:"actor_id"` // who did it; "" for grading
+	ActorLogin   string     `json:"actorLogin,omitempty"   db:"-"`        // joined from users
+	SnippetID    string     `json:"snippetId,omitempty"    db:"snippet_id"`
+	SubmissionID string     `json:"submissionId,omitempty" db:"submission_id"`
+	Message      string     `json:"message"                db:"message"`
+	ReadAt       *time.Time `json:"readAt,omitempty"       db:"read_at"`
+	CreatedAt    time.Time  `json:"createdAt"              db:"created_at"`
+}
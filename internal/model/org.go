@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+// Organization roles. Viewers can read and run the org's snippets; members
+// can also create and change them; owners also manage who's in the org and
+// can delete its snippets and the org.
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleMember = "member"
+	OrgRoleViewer = "viewer"
+)
+
+// Org is a team whose members share a library of snippets.
+type Org struct {
+	ID        string    `json:"id"        db:"id"`
+	Name      string    `json:"name"      db:"name"`
+	CreatedBy string    `json:"createdBy" db:"created_by"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	Quota     OrgQuota  `json:"-"` // see OrgUsage for the limits in force
+}
+
+// OrgQuota overrides the deployment's limits for one org. Nil fields use
+// the defaults; 0 means no limit.
+type OrgQuota struct {
+	Snippets   *int `json:"snippets"   db:"snippet_quota"`
+	RunsPerDay *int `json:"runsPerDay" db:"run_quota"`
+}
+
+// OrgUsage is how much of its quota an org has used. A limit of 0 means
+// there isn't one.
+type OrgUsage struct {
+	OrgID        string `json:"orgId"`
+	Snippets     int    `json:"snippets"`
+	SnippetLimit int    `json:"snippetLimit"`
+	RunsToday    int    `json:"runsToday"` // live runs since midnight UTC
+	RunLimit     int    `json:"runLimit"`
+}
+
+// OrgMember is one user's place in an org.
+type OrgMember struct {
+	OrgID    string    `json:"orgId"    db:"org_id"`
+	UserID   string    `json:"userId"   db:"user_id"`
+	Login    string    `json:"login"    db:"login"` // from users, for display
+	Role     string    `json:"role"     db:"role"`
+	JoinedAt time.Time `json:"joinedAt" db:"joined_at"`
+}
+
+// OrgMembership is an org as seen by one of its members.
+type OrgMembership struct {
+	Org
+	Role string `json:"role"`
+}
+
+// OrgInvite lets whoever has its link join an org with Role, until
+// ExpiresAt. The link's token is only known to whoever created the invite.
+type OrgInvite struct {
+	ID        string    `json:"id"              db:"id"`
+	OrgID     string    `json:"orgId"           db:"org_id"`
+	OrgName   string    `json:"orgName"         db:"org_name"` // from orgs, for display
+	Role      string    `json:"role"            db:"role"`
+	Email     string    `json:"email,omitempty" db:"email"` // who it was emailed to, if anyone
+	CreatedBy string    `json:"createdBy"       db:"created_by"`
+	CreatedAt time.Time `json:"createdAt"       db:"created_at"`
+	ExpiresAt time.Time `json:"expiresAt"       db:"expires_at"`
+}
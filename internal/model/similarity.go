@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// Similarity report statuses.
+const (
+	SimilarityPending = "pending" // queued or running
+	SimilarityDone    = "done"
+)
+
+// SimilarityReport compares the students' solutions to one assignment and
+// lists the pairs that look alike. It's computed in the background, so it
+// starts out pending.
+type SimilarityReport struct {
+	AssignmentID string           `json:"assignmentId" db:"assignment_id"`
+	Status       string           `json:"status"       db:"status"`
+	Threshold    float64          `json:"threshold"    db:"threshold"` // pairs at least this similar are flagged, 0–1
+	Compared     int              `json:"compared"     db:"compared"`  // submissions compared: each student's latest
+	Pairs        []SimilarityPair `json:"pairs"        db:"pairs"`     // flagged pairs, most similar first; stored as JSON
+	RequestedAt  time.Time        `json:"requestedAt"  db:"requested_at"`
+	CompletedAt  *time.Time       `json:"completedAt"  db:"completed_at"`
+}
+
+// SimilarityPair is two students whose submissions share a large part of
+// their fingerprints. Similarity is a hint for a teacher to look closer, not
+// proof of copying.
+type SimilarityPair struct {
+	Similarity float64              `json:"similarity"` // 0–1
+	A          SimilaritySubmission `json:"a"`
+	B          SimilaritySubmission `json:"b"`
+}
+
+// SimilaritySubmission identifies one side of a SimilarityPair.
+type SimilaritySubmission struct {
+	SubmissionID string `json:"submissionId"`
+	UserID       string `json:"userId"`
+	Login        string `json:"login"`
+}
@@ -1,6 +1,12 @@
 // Package model defines the data structures used throughout the application.
 // In Go, we use structs to represent our data — similar to classes in other languages,
 // but without inheritance. Go favours composition over inheritance.
+//
+// TIMESTAMPS:
+// Every time.Time in a model is in UTC — the repository stores and reads
+// them that way (see repository/sqlite/timestamp.go) — and encoding/json
+// writes a time.Time as RFC 3339, so the API's timestamps all look like
+// "2026-03-08T06:45:00.123456789Z", whatever the server's time zone.
 package model
 
 import "time"
@@ -14,10 +20,55 @@ import "time"
 //	snippet := Snippet{ID: "abc", Name: "hello"}
 //	json.Marshal(snippet) → {"id":"abc","name":"hello",...}
 type Snippet struct {
-    ID          string    `json:"id"          db:"id"`
-    Name        string    `json:"name"        db:"name"`
-    Code        string    `json:"code"        db:"code"`
-    Description string    `json:"description" db:"description"`
-    CreatedAt   time.Time `json:"createdAt"   db:"created_at"`
-    UpdatedAt   time.Time `json:"updatedAt"   db:"updated_at"`
-}
\ No newline at end of file
+	ID          string    `json:"id"          db:"id"`
+	Name        string    `json:"name"        db:"name"`
+	Code        string    `json:"code"        db:"code"`
+	Description string    `json:"description" db:"description"`
+	CreatedAt   time.Time `json:"createdAt"   db:"created_at"`
+	UpdatedAt   time.Time `json:"updatedAt"   db:"updated_at"`
+
+	// UserID is the owner, or "" for snippets saved without signing in.
+	UserID string `json:"userId,omitempty" db:"user_id"`
+
+	// Public snippets appear in the Atom feeds. Only the owner can publish;
+	// PublishedAt is when the snippet was (last) made public.
+	Public      bool       `json:"public"                db:"public"`
+	PublishedAt *time.Time `json:"publishedAt,omitempty" db:"published_at"`
+
+	// Version starts at 1 and goes up each time the code changes. Line
+	// comments (see Comment) refer to the code at a particular version.
+	Version int `json:"version" db:"version"`
+
+	// ForkedFrom is the ID of the snippet this one was forked from, or "".
+	ForkedFrom string `json:"forkedFrom,omitempty" db:"forked_from"`
+
+	// OrgID is the org that owns the snippet, or "" for a personal one. An
+	// org's snippets are maintained by all its members; UserID is then
+	// whoever created it.
+	OrgID string `json:"orgId,omitempty" db:"org_id"`
+
+	// Requirements lists the packages the code needs, in requirements.txt
+	// format (see executor/requirements.go), or "". Forks copy it, so
+	// they run the way the original did.
+	Requirements string `json:"requirements,omitempty" db:"requirements"`
+
+	// Author is the owner's public profile, filled in only by listings that
+	// ask for it (see repository.SnippetRepository.ListWithAuthors); nil
+	// otherwise, and for anonymous snippets.
+	Author *Author `json:"author,omitempty" db:"-"`
+}
+
+// Star is a user's star on a snippet.
+type Star struct {
+	SnippetID string    `json:"snippetId" db:"snippet_id"`
+	UserID    string    `json:"userId"    db:"user_id"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// Author is as much of a user as a list of snippets shows next to each
+// one: enough for a name and an avatar, without a request per snippet.
+type Author struct {
+	ID        string `json:"id"`
+	Login     string `json:"login"`
+	AvatarURL string `json:"avatarUrl"`
+}
@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// Run is one run of code from the playground. Its outcome is kept, and for
+// a signed-in user's run its code too, so it can be replayed; never its
+// output. UserID is "" for an anonymous run.
+type Run struct {
+	ID           string    `json:"id"                 db:"id"`
+	UserID       string    `json:"-"                  db:"user_id"`
+	Code         string    `json:"-"                  db:"code"`
+	Requirements string    `json:"-"                  db:"requirements"` // the run's requirements manifest, if any
+	GPU          bool      `json:"-"                  db:"gpu"`          // whether it ran on a GPU
+	ReplayOf     string    `json:"replayOf,omitempty" db:"replay_of"`    // the run this one replayed
+	Language     string    `json:"language"           db:"language"`
+	ExitCode     int       `json:"exitCode"           db:"exit_code"`
+	DurationMS   int64     `json:"durationMs"         db:"duration_ms"`
+	CreatedAt    time.Time `json:"createdAt"          db:"created_at"`
+}
+
+// UserStats sums up a user's snippets and runs for their dashboard.
+type UserStats struct {
+	Snippets         int     `json:"snippets"`
+	Runs             int     `json:"runs"`
+	SuccessfulRuns   int     `json:"successfulRuns"`   // exited with status 0
+	FailedRuns       int     `json:"failedRuns"`       // anything else
+	SuccessRate      float64 `json:"successRate"`      // SuccessfulRuns / Runs; 0 without runs
+	AverageRuntimeMS float64 `json:"averageRuntimeMs"` // 0 without runs
+	MostUsedLanguage string  `json:"mostUsedLanguage,omitempty"`
+	// Activity is one entry per day, oldest first.
+	Activity []DayActivity `json:"activity"`
+}
+
+// DayActivity is what a user did on one day.
+type DayActivity struct {
+	Date     string `json:"date"` // YYYY-MM-DD
+	Snippets int    `json:"snippets"`
+	Runs     int    `json:"runs"`
+}
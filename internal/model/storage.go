@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// StorageStats describes the database file and its write-ahead log.
+type StorageStats struct {
+	Path          string `json:"path"`          // "" for an in-memory database
+	JournalMode   string `json:"journalMode"`   // "wal" normally
+	PageSize      int64  `json:"pageSize"`      // bytes
+	PageCount     int64  `json:"pageCount"`     // pages in the database file
+	FreelistCount int64  `json:"freelistCount"` // unused pages, which VACUUM would reclaim
+	SizeBytes     int64  `json:"sizeBytes"`     // PageSize × PageCount
+	WALBytes      int64  `json:"walBytes"`      // size of the -wal file
+}
+
+// Checkpoint is the outcome of a WAL checkpoint.
+type Checkpoint struct {
+	// Busy is true if readers or writers kept it from finishing; the WAL
+	// then wasn't truncated, and the next checkpoint will try again.
+	Busy bool `json:"busy"`
+	// LogFrames is how many frames the WAL held, and CheckpointedFrames how
+	// many of them were copied into the database.
+	LogFrames          int           `json:"logFrames"`
+	CheckpointedFrames int           `json:"checkpointedFrames"`
+	WALBytesBefore     int64         `json:"walBytesBefore"`
+	WALBytesAfter      int64         `json:"walBytesAfter"`
+	Duration           time.Duration `json:"duration"`
+}
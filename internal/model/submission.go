@@ -0,0 +1,52 @@
+package model
+
+import "time"
+
+// Submission statuses.
+const (
+	SubmissionPassed = "passed" // every test passed
+	SubmissionFailed = "failed" // some tests failed
+	SubmissionError  = "error"  // no tests ran: the code crashed or timed out
+)
+
+// Submission is one attempt at an exercise: the learner's code and how it
+// did against the hidden tests.
+type Submission struct {
+	ID            string       `json:"id"                      db:"id"`
+	ExerciseID    string       `json:"exerciseId"              db:"exercise_id"`
+	AssignmentID  string       `json:"assignmentId,omitempty"  db:"assignment_id"`  // empty for practice outside a class
+	ChallengeDate string       `json:"challengeDate,omitempty" db:"challenge_date"` // set when solving that day's challenge
+	UserID        string       `json:"userId"                  db:"user_id"`
+	Code          string       `json:"code"                    db:"code"`
+	Status        string       `json:"status"                  db:"status"`
+	Score         int          `json:"score"                   db:"score"` // percentage of tests passed, 0–100, less any late or hint penalty
+	Passed        int          `json:"passed"                  db:"passed"`
+	Total         int          `json:"total"                   db:"total"`
+	Results       []TestResult `json:"results"                 db:"results"` // stored as JSON
+	Error         string       `json:"error,omitempty"         db:"error"`
+	Output        string       `json:"output"                  db:"output"`      // what the code printed
+	Late          bool         `json:"late"                    db:"late"`        // made after the assignment's due date
+	DurationMS    float64      `json:"durationMs"              db:"duration_ms"` // the tests' total running time
+	HintsUsed     int          `json:"hintsUsed"               db:"hints_used"`  // hints unlocked before submitting
+	Coverage      *Coverage    `json:"coverage,omitempty"      db:"coverage"`    // when the exercise measures it; stored as JSON
+	CreatedAt     time.Time    `json:"createdAt"               db:"created_at"`
+}
+
+// TestResult is how a submission did on one hidden test. Message explains a
+// failure without revealing the test itself.
+type TestResult struct {
+	Name       string  `json:"name"`
+	Passed     bool    `json:"passed"`
+	Message    string  `json:"message,omitempty"`
+	DurationMS float64 `json:"durationMs"`
+}
+
+// Coverage is which lines of a submission's code ran while it was graded.
+// Engine is "coverage.py", which also reports MissingBranches, or "trace".
+type Coverage struct {
+	Engine          string   `json:"engine"`
+	Executed        []int    `json:"executed"`
+	Missing         []int    `json:"missing"`
+	MissingBranches [][2]int `json:"missingBranches,omitempty"` // [from, to]; a negative to exits the function on line -to
+	Percent         float64  `json:"percent"`
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// SnippetTransfer is an offer to hand a snippet to another user or an org,
+// waiting for the recipient to accept it. Exactly one of ToUserID and
+// ToOrgID is set. FromUserID and FromOrgID are who owned the snippet when
+// the offer was made; accepting fails if that has changed since.
+type SnippetTransfer struct {
+	ID          string    `json:"id"                  db:"id"`
+	SnippetID   string    `json:"snippetId"           db:"snippet_id"`
+	SnippetName string    `json:"snippetName"         db:"snippet_name"` // from snippets, for display
+	FromUserID  string    `json:"fromUserId"          db:"from_user_id"`
+	FromOrgID   string    `json:"fromOrgId,omitempty" db:"from_org_id"`
+	ToUserID    string    `json:"toUserId,omitempty"  db:"to_user_id"`
+	ToOrgID     string    `json:"toOrgId,omitempty"   db:"to_org_id"`
+	CreatedBy   string    `json:"createdBy"           db:"created_by"`
+	CreatedAt   time.Time `json:"createdAt"           db:"created_at"`
+}
@@ -2,13 +2,49 @@ package model
 
 import "time"
 
+// User roles. Every user starts as RoleUser; authors can also write
+// exercises; admins can reach /api/v1/admin routes and do what authors can.
+const (
+	RoleUser   = "user"
+	RoleAuthor = "author"
+	RoleAdmin  = "admin"
+)
+
+// Roles lists every valid role.
+var Roles = []string{RoleUser, RoleAuthor, RoleAdmin}
+
+// Ways to sign in.
+const (
+	LoginGitHub = "github"
+	LoginEmail  = "email" // an emailed sign-in link
+)
+
+// Login is a successful sign-in, as published with EventUserLoggedIn.
+type Login struct {
+	UserID string
+	Method string // LoginGitHub or LoginEmail
+}
+
 // User represents an authenticated user (linked via GitHub OAuth).
 type User struct {
-	ID        string    `json:"id"        db:"id"`
-	GitHubID  int64     `json:"githubId"  db:"github_id"`
-	Login     string    `json:"login"     db:"login"`
-	Email     string    `json:"email"     db:"email"`
-	AvatarURL string    `json:"avatarUrl" db:"avatar_url"`
-	CreatedAt time.Time `json:"createdAt" db:"created_at"`
-	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+	ID                string    `json:"id"                db:"id"`
+	GitHubID          int64     `json:"githubId"          db:"github_id"`
+	Login             string    `json:"login"             db:"login"`
+	Email             string    `json:"email"             db:"email"`
+	AvatarURL         string    `json:"avatarUrl"         db:"avatar_url"`
+	Role              string    `json:"role"              db:"role"`
+	LeaderboardOptOut bool      `json:"leaderboardOptOut" db:"leaderboard_opt_out"` // keeps them off every leaderboard
+	Suspended         bool      `json:"suspended"         db:"suspended"`           // read-only: may look and export, not change or run
+	CreatedAt         time.Time `json:"createdAt"         db:"created_at"`
+	UpdatedAt         time.Time `json:"updatedAt"         db:"updated_at"`
+}
+
+// IsAdmin reports whether the user has the admin role.
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// CanAuthor reports whether the user may create and edit exercises.
+func (u *User) CanAuthor() bool {
+	return u.Role == RoleAuthor || u.Role == RoleAdmin
 }
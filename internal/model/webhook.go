@@ -0,0 +1,68 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// Webhook events. Each is delivered to the webhooks subscribed to it.
+const (
+	EventSnippetCreated     = "snippet.created"
+	EventSnippetUpdated     = "snippet.updated"
+	EventExecutionCompleted = "execution.completed"
+)
+
+// Events published inside the app (to award badges and notify users) that
+// webhooks can't subscribe to yet.
+const (
+	EventSnippetForked    = "snippet.forked"    // data: the fork
+	EventSnippetStarred   = "snippet.starred"   // data: the snippet
+	EventCommentCreated   = "comment.created"   // data: the comment
+	EventSubmissionGraded = "submission.graded" // data: the submission
+	EventChallengeSolved  = "challenge.solved"  // data: the passing submission
+	EventSnippetPublished = "snippet.published" // data: the snippet, just made public
+	EventUserLoggedIn     = "user.logged_in"    // data: a *Login
+	EventTransferOffered  = "transfer.offered"  // data: the *SnippetTransfer
+	EventExecutionFlagged = "execution.flagged" // data: the *AbuseReport
+)
+
+// WebhookEvents lists every event a webhook can subscribe to.
+var WebhookEvents = []string{EventSnippetCreated, EventSnippetUpdated, EventExecutionCompleted}
+
+// Webhook is a URL a user registered to be notified of events.
+//
+// Secret signs every delivery (see service.SignWebhook) so the receiver can
+// check it came from us. It's shown once, when the webhook is created, and
+// never serialised after that.
+type Webhook struct {
+	ID        string    `json:"id"        db:"id"`
+	UserID    string    `json:"-"         db:"user_id"`
+	URL       string    `json:"url"       db:"url"`
+	Secret    string    `json:"-"         db:"secret"`
+	Events    []string  `json:"events"    db:"events"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// Subscribes reports whether the webhook wants event.
+func (w *Webhook) Subscribes(event string) bool {
+	return slices.Contains(w.Events, event)
+}
+
+// WebhookDelivery is one attempt to deliver an event to a webhook, kept so
+// users can see why their endpoint isn't receiving anything.
+type WebhookDelivery struct {
+	ID         string    `json:"id"                   db:"id"`
+	WebhookID  string    `json:"webhookId"            db:"webhook_id"`
+	EventID    string    `json:"eventId"              db:"event_id"` // same for every retry of an event
+	Event      string    `json:"event"                db:"event"`
+	Attempt    int       `json:"attempt"              db:"attempt"`
+	StatusCode int       `json:"statusCode,omitempty" db:"status_code"` // 0 if no response arrived
+	Error      string    `json:"error,omitempty"      db:"error"`
+	DurationMS int64     `json:"durationMs"           db:"duration_ms"`
+	CreatedAt  time.Time `json:"createdAt"            db:"created_at"`
+}
+
+// Succeeded reports whether the endpoint answered with a 2xx status.
+func (d *WebhookDelivery) Succeeded() bool {
+	return d.StatusCode >= 200 && d.StatusCode < 300
+}
@@ -0,0 +1,267 @@
+// Package ot implements operational transformation (OT) for plain text: the
+// technique that lets several people edit the same document at once and
+// still all end up with the same text.
+//
+// OPERATIONS:
+// An edit is an Op: a walk over the whole document, made of components that
+// each retain (skip over), insert or delete characters. Typing "!" at the
+// end of "hi" is
+//
+//	[2, "!"]        retain 2, insert "!"
+//
+// and deleting the "h" of "hi!" is [-1, 2]. In JSON, as in the ot.js
+// library, a positive number retains, a negative one deletes and a string
+// inserts. Lengths count Unicode code points (in JavaScript, [...str].length),
+// not bytes or UTF-16 units.
+//
+// TRANSFORM:
+// Two people edit revision 5 at the same time: A inserts at the start, B
+// deletes at the end. Whichever reaches the server second was written
+// against a document that no longer exists. Transform(a, b) rewrites each
+// so it applies after the other:
+//
+//	apply(apply(doc, a), b') == apply(apply(doc, b), a')
+//
+// The server keeps a history of applied ops and transforms each incoming op
+// past everything its author hadn't seen yet (see service.CollabService).
+// When both insert at the same place, a's text comes first.
+package ot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Component is one step of an Op. Exactly one field is set.
+type Component struct {
+	Retain int    // > 0: keep this many characters
+	Insert string // non-empty: insert this text
+	Delete int    // > 0: remove this many characters
+}
+
+// Op is an edit of a whole document. Build one with Retain, Insert and
+// Delete, which merge adjacent components of the same kind.
+type Op []Component
+
+// ErrLengthMismatch means an op doesn't fit the document (or the other op)
+// it was applied to: it was written against a different revision.
+var ErrLengthMismatch = errors.New("ot: operation doesn't match the document length")
+
+// Retain appends "keep n characters".
+func (o Op) Retain(n int) Op {
+	if n <= 0 {
+		return o
+	}
+	if last := len(o) - 1; last >= 0 && o[last].Retain > 0 {
+		o[last].Retain += n
+		return o
+	}
+	return append(o, Component{Retain: n})
+}
+
+// Insert appends "insert s". An insert next to a delete goes before it, so
+// equivalent ops always look the same.
+func (o Op) Insert(s string) Op {
+	if s == "" {
+		return o
+	}
+	last := len(o) - 1
+	switch {
+	case last >= 0 && o[last].Insert != "":
+		o[last].Insert += s
+		return o
+	case last >= 0 && o[last].Delete > 0:
+		if last > 0 && o[last-1].Insert != "" {
+			o[last-1].Insert += s
+			return o
+		}
+		o = append(o, o[last])
+		o[last] = Component{Insert: s}
+		return o
+	}
+	return append(o, Component{Insert: s})
+}
+
+// Delete appends "remove n characters".
+func (o Op) Delete(n int) Op {
+	if n <= 0 {
+		return o
+	}
+	if last := len(o) - 1; last >= 0 && o[last].Delete > 0 {
+		o[last].Delete += n
+		return o
+	}
+	return append(o, Component{Delete: n})
+}
+
+// BaseLen is the length of document the op applies to.
+func (o Op) BaseLen() int {
+	n := 0
+	for _, c := range o {
+		n += c.Retain + c.Delete
+	}
+	return n
+}
+
+// TargetLen is the length of the document after applying the op.
+func (o Op) TargetLen() int {
+	n := 0
+	for _, c := range o {
+		n += c.Retain + utf8.RuneCountInString(c.Insert)
+	}
+	return n
+}
+
+// IsNoop reports whether the op leaves the document as it is.
+func (o Op) IsNoop() bool {
+	for _, c := range o {
+		if c.Insert != "" || c.Delete > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns doc with the op applied.
+func (o Op) Apply(doc string) (string, error) {
+	src := []rune(doc)
+	if o.BaseLen() != len(src) {
+		return "", fmt.Errorf("%w: op expects %d characters, document has %d", ErrLengthMismatch, o.BaseLen(), len(src))
+	}
+	out := make([]rune, 0, o.TargetLen())
+	pos := 0
+	for _, c := range o {
+		switch {
+		case c.Retain > 0:
+			out = append(out, src[pos:pos+c.Retain]...)
+			pos += c.Retain
+		case c.Insert != "":
+			out = append(out, []rune(c.Insert)...)
+		case c.Delete > 0:
+			pos += c.Delete
+		}
+	}
+	return string(out), nil
+}
+
+// Transform takes two ops made concurrently on the same document and
+// returns a' and b', such that applying a then b' gives the same document
+// as applying b then a'. At the same position, a's inserts come first.
+func Transform(a, b Op) (aPrime, bPrime Op, err error) {
+	if a.BaseLen() != b.BaseLen() {
+		return nil, nil, fmt.Errorf("%w: transforming ops on %d and %d characters", ErrLengthMismatch, a.BaseLen(), b.BaseLen())
+	}
+
+	// Work on copies: components are consumed a piece at a time.
+	ia, ib := 0, 0
+	var ca, cb *Component
+	next := func(op Op, i *int) *Component {
+		if *i >= len(op) {
+			return nil
+		}
+		c := op[*i]
+		*i++
+		return &c
+	}
+	ca, cb = next(a, &ia), next(b, &ib)
+
+	for ca != nil || cb != nil {
+		// Inserts don't consume anything of the other op: the other side
+		// just has to skip over the new text.
+		if ca != nil && ca.Insert != "" {
+			aPrime = aPrime.Insert(ca.Insert)
+			bPrime = bPrime.Retain(utf8.RuneCountInString(ca.Insert))
+			ca = next(a, &ia)
+			continue
+		}
+		if cb != nil && cb.Insert != "" {
+			aPrime = aPrime.Retain(utf8.RuneCountInString(cb.Insert))
+			bPrime = bPrime.Insert(cb.Insert)
+			cb = next(b, &ib)
+			continue
+		}
+		if ca == nil || cb == nil {
+			return nil, nil, ErrLengthMismatch // unreachable: the base lengths matched
+		}
+
+		// Both retain or delete: consume the shorter, keep the rest.
+		na, nb := ca.Retain+ca.Delete, cb.Retain+cb.Delete
+		n := min(na, nb)
+		switch {
+		case ca.Retain > 0 && cb.Retain > 0:
+			aPrime = aPrime.Retain(n)
+			bPrime = bPrime.Retain(n)
+		case ca.Delete > 0 && cb.Retain > 0:
+			aPrime = aPrime.Delete(n)
+		case ca.Retain > 0 && cb.Delete > 0:
+			bPrime = bPrime.Delete(n)
+		}
+		// Both deleting the same characters: they're gone either way.
+
+		ca = shorten(ca, n, next, a, &ia)
+		cb = shorten(cb, n, next, b, &ib)
+	}
+	return aPrime, bPrime, nil
+}
+
+// shorten consumes n characters of a retain or delete, moving on to the
+// op's next component once it's used up.
+func shorten(c *Component, n int, next func(Op, *int) *Component, op Op, i *int) *Component {
+	if c.Retain > 0 {
+		c.Retain -= n
+		if c.Retain > 0 {
+			return c
+		}
+	} else {
+		c.Delete -= n
+		if c.Delete > 0 {
+			return c
+		}
+	}
+	return next(op, i)
+}
+
+// MarshalJSON encodes the op in the ot.js format: [3, "abc", -2].
+func (o Op) MarshalJSON() ([]byte, error) {
+	parts := make([]any, len(o))
+	for i, c := range o {
+		switch {
+		case c.Retain > 0:
+			parts[i] = c.Retain
+		case c.Insert != "":
+			parts[i] = c.Insert
+		default:
+			parts[i] = -c.Delete
+		}
+	}
+	return json.Marshal(parts)
+}
+
+// UnmarshalJSON decodes the ot.js format, normalizing as it goes.
+func (o *Op) UnmarshalJSON(data []byte) error {
+	var parts []json.RawMessage
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return fmt.Errorf("ot: an op is a JSON array: %w", err)
+	}
+	var op Op
+	for _, p := range parts {
+		var s string
+		if err := json.Unmarshal(p, &s); err == nil {
+			op = op.Insert(s)
+			continue
+		}
+		var n int
+		if err := json.Unmarshal(p, &n); err != nil || n == 0 {
+			return fmt.Errorf("ot: op components are strings or non-zero integers, not %s", p)
+		}
+		if n > 0 {
+			op = op.Retain(n)
+		} else {
+			op = op.Delete(-n)
+		}
+	}
+	*o = op
+	return nil
+}
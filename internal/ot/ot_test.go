@@ -0,0 +1,138 @@
+package ot
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		doc  string
+		op   Op
+		want string
+	}{
+		{"hi", Op{}.Retain(2).Insert("!"), "hi!"},
+		{"hi!", Op{}.Delete(1).Retain(2), "i!"},
+		{"héllo", Op{}.Retain(1).Delete(1).Insert("e").Retain(3), "hello"}, // code points, not bytes
+		{"", Op{}.Insert("print(1)"), "print(1)"},
+	}
+	for _, tt := range tests {
+		got, err := tt.op.Apply(tt.doc)
+		if err != nil || got != tt.want {
+			t.Errorf("%v.Apply(%q) = %q, %v; want %q", tt.op, tt.doc, got, err, tt.want)
+		}
+	}
+
+	if _, err := (Op{}).Retain(5).Apply("hi"); !errors.Is(err, ErrLengthMismatch) {
+		t.Errorf("Apply() with the wrong base length: error = %v, want ErrLengthMismatch", err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var op Op
+	if err := json.Unmarshal([]byte(`[3, "ab", "c", -2, -1, 1]`), &op); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	data, _ := json.Marshal(op)
+	if string(data) != `[3,"abc",-3,1]` {
+		t.Errorf("round trip = %s, want the components merged", data)
+	}
+	for _, bad := range []string{`{}`, `[0]`, `[1.5]`, `[true]`} {
+		if err := json.Unmarshal([]byte(bad), &op); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestTransform(t *testing.T) {
+	doc := "abcdef"
+	tests := []struct {
+		name string
+		a, b Op
+		want string
+	}{
+		{"inserts apart", Op{}.Insert("X").Retain(6), Op{}.Retain(6).Insert("Y"), "XabcdefY"},
+		{"inserts at the same place", Op{}.Retain(3).Insert("A").Retain(3), Op{}.Retain(3).Insert("B").Retain(3), "abcABdef"},
+		{"overlapping deletes", Op{}.Retain(1).Delete(3).Retain(2), Op{}.Retain(2).Delete(3).Retain(1), "af"},
+		{"insert inside a delete", Op{}.Retain(2).Insert("X").Retain(4), Op{}.Retain(1).Delete(4).Retain(1), "aXf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aPrime, bPrime, err := Transform(tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("Transform() error = %v", err)
+			}
+			ab := mustApply(t, mustApply(t, doc, tt.a), bPrime)
+			ba := mustApply(t, mustApply(t, doc, tt.b), aPrime)
+			if ab != tt.want || ba != tt.want {
+				t.Errorf("a then b' = %q, b then a' = %q; want both %q", ab, ba, tt.want)
+			}
+		})
+	}
+
+	if _, _, err := Transform(Op{}.Retain(1), Op{}.Retain(2)); !errors.Is(err, ErrLengthMismatch) {
+		t.Errorf("Transform() of ops on different documents: error = %v, want ErrLengthMismatch", err)
+	}
+}
+
+// TestTransform_Converges checks the defining property on random edits.
+func TestTransform_Converges(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 500; i++ {
+		doc := randomText(rng, rng.IntN(20))
+		a, b := randomOp(rng, doc), randomOp(rng, doc)
+		aPrime, bPrime, err := Transform(a, b)
+		if err != nil {
+			t.Fatalf("Transform(%v, %v) error = %v", a, b, err)
+		}
+		ab := mustApply(t, mustApply(t, doc, a), bPrime)
+		ba := mustApply(t, mustApply(t, doc, b), aPrime)
+		if ab != ba {
+			t.Fatalf("doc %q, a %v, b %v: a then b' = %q, b then a' = %q", doc, a, b, ab, ba)
+		}
+	}
+}
+
+func mustApply(t *testing.T, doc string, op Op) string {
+	t.Helper()
+	out, err := op.Apply(doc)
+	if err != nil {
+		t.Fatalf("%v.Apply(%q) error = %v", op, doc, err)
+	}
+	return out
+}
+
+func randomText(rng *rand.Rand, n int) string {
+	const letters = "abcdé\n "
+	r := []rune(letters)
+	out := make([]rune, n)
+	for i := range out {
+		out[i] = r[rng.IntN(len(r))]
+	}
+	return string(out)
+}
+
+// randomOp makes a random edit of doc.
+func randomOp(rng *rand.Rand, doc string) Op {
+	var op Op
+	left := len([]rune(doc))
+	for left > 0 {
+		n := 1 + rng.IntN(left)
+		switch rng.IntN(3) {
+		case 0:
+			op = op.Retain(n)
+			left -= n
+		case 1:
+			op = op.Delete(n)
+			left -= n
+		default:
+			op = op.Insert(randomText(rng, 1+rng.IntN(3)))
+		}
+	}
+	if rng.IntN(2) == 0 {
+		op = op.Insert(randomText(rng, 1+rng.IntN(3)))
+	}
+	return op
+}
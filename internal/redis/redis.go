@@ -0,0 +1,383 @@
+// Package redis is a small Redis client: enough of the protocol to send
+// commands and read their replies, with a pool of connections.
+//
+// WHY NOT A CLIENT LIBRARY?
+// Like the Sentry and SMTP code, we only need a sliver of the protocol —
+// GET, SET, DEL, INCR, EVAL — and RESP, the wire format, is simple:
+//
+//	→ *3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n   a command is an array of bulk strings
+//	← +OK\r\n                                     simple string
+//	← -ERR wrong type\r\n                         error
+//	← :42\r\n                                     integer
+//	← $5\r\nhello\r\n  or  $-1\r\n                bulk string, or nil
+//	← *2\r\n…                                     array of any of these
+//
+// Do returns these as string, Error, int64, string, nil and []any.
+//
+// The server is given as a URL:
+//
+//	redis://[user:password@]host:port[/db]
+//	rediss://…   the same over TLS
+package redis
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Error is an error reply from the server, such as "ERR unknown command".
+// The connection is still fine after one.
+type Error string
+
+func (e Error) Error() string { return "redis: " + string(e) }
+
+// Options says how to reach a Redis server.
+type Options struct {
+	Addr        string // host:port
+	Username    string // "" for the default user
+	Password    string // "" if the server needs none
+	DB          int    // database number, selected on every new connection
+	TLS         bool
+	DialTimeout time.Duration // default 5s
+	MaxIdle     int           // idle connections kept for reuse (default 8)
+}
+
+// ParseURL reads Options from a redis:// or rediss:// URL.
+func ParseURL(raw string) (Options, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Options{}, fmt.Errorf("redis: invalid URL: %w", err)
+	}
+	var opts Options
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		opts.TLS = true
+	default:
+		return Options{}, fmt.Errorf("redis: URL must start with redis:// or rediss://, not %q", u.Scheme+"://")
+	}
+	opts.Addr = u.Host
+	if u.Port() == "" {
+		opts.Addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		opts.Username = u.User.Username()
+		opts.Password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if opts.DB, err = strconv.Atoi(db); err != nil {
+			return Options{}, fmt.Errorf("redis: database must be a number, not %q", db)
+		}
+	}
+	return opts, nil
+}
+
+// Client sends commands to one Redis server. It's safe for concurrent use:
+// each command borrows a connection from the pool.
+type Client struct {
+	opts Options
+
+	mu     sync.Mutex
+	idle   []*conn
+	closed bool
+}
+
+// New creates a Client. It doesn't connect until the first command.
+func New(opts Options) *Client {
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = 5 * time.Second
+	}
+	if opts.MaxIdle <= 0 {
+		opts.MaxIdle = 8
+	}
+	return &Client{opts: opts}
+}
+
+// conn is one connection to the server.
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+// Do sends a command and returns its reply. A reply that's an error comes
+// back as an Error; anything else going wrong (the network, ctx) as some
+// other error.
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := cn.do(ctx, args)
+	var redisErr Error
+	if err != nil && !errors.As(err, &redisErr) {
+		// We can't tell what's left unread on the connection.
+		cn.Close()
+		return nil, err
+	}
+	c.put(cn)
+	return reply, err
+}
+
+// Ping checks that the server answers.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.Do(ctx, "PING")
+	return err
+}
+
+// Close closes the idle connections; ones in use are closed when they're
+// returned.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for _, cn := range c.idle {
+		cn.Close()
+	}
+	c.idle = nil
+	return nil
+}
+
+// get takes an idle connection, or dials a new one.
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, errors.New("redis: client is closed")
+	}
+	if n := len(c.idle); n > 0 {
+		cn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return cn, nil
+	}
+	c.mu.Unlock()
+	return c.dial(ctx)
+}
+
+// put returns a connection to the pool, or closes it if the pool is full.
+func (c *Client) put(cn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || len(c.idle) >= c.opts.MaxIdle {
+		cn.Close()
+		return
+	}
+	c.idle = append(c.idle, cn)
+}
+
+// dial connects, then signs in and picks the database if the options ask.
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	dialer := &net.Dialer{Timeout: c.opts.DialTimeout}
+	var nc net.Conn
+	var err error
+	if c.opts.TLS {
+		host, _, _ := net.SplitHostPort(c.opts.Addr)
+		td := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}}
+		nc, err = td.DialContext(ctx, "tcp", c.opts.Addr)
+	} else {
+		nc, err = dialer.DialContext(ctx, "tcp", c.opts.Addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis: connecting to %s: %w", c.opts.Addr, err)
+	}
+	cn := &conn{Conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+
+	if c.opts.Password != "" {
+		args := []string{"AUTH", c.opts.Password}
+		if c.opts.Username != "" {
+			args = []string{"AUTH", c.opts.Username, c.opts.Password}
+		}
+		if _, err := cn.do(ctx, args); err != nil {
+			cn.Close()
+			return nil, fmt.Errorf("redis: signing in: %w", err)
+		}
+	}
+	if c.opts.DB != 0 {
+		if _, err := cn.do(ctx, []string{"SELECT", strconv.Itoa(c.opts.DB)}); err != nil {
+			cn.Close()
+			return nil, fmt.Errorf("redis: selecting database %d: %w", c.opts.DB, err)
+		}
+	}
+	return cn, nil
+}
+
+// do writes one command and reads its reply, giving up at ctx's deadline.
+func (cn *conn) do(ctx context.Context, args []string) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Time{}
+	}
+	if err := cn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	// A deadline in the past interrupts a blocked read when ctx is
+	// cancelled. The connection is useless after that.
+	stop := context.AfterFunc(ctx, func() { cn.SetDeadline(time.Unix(1, 0)) })
+	reply, err := cn.roundTrip(args)
+	if !stop() {
+		return nil, ctx.Err()
+	}
+	return reply, err
+}
+
+// roundTrip writes a command and reads its reply.
+func (cn *conn) roundTrip(args []string) (any, error) {
+	fmt.Fprintf(cn.w, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(cn.w, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if err := cn.w.Flush(); err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	reply, err := ReadReply(cn.r)
+	var redisErr Error
+	if err != nil && !errors.As(err, &redisErr) {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	return reply, err
+}
+
+// Script is a Lua script, run on the server so that its reads and writes
+// happen together with nothing in between. Run sends only the script's
+// SHA-1 (EVALSHA), and the whole source the first time a server hasn't seen
+// it.
+type Script struct {
+	src string
+	sha string
+}
+
+// NewScript prepares a Lua script.
+func NewScript(src string) *Script {
+	sum := sha1.Sum([]byte(src))
+	return &Script{src: src, sha: hex.EncodeToString(sum[:])}
+}
+
+// SHA is the script's SHA-1, as EVALSHA and SCRIPT LOAD use.
+func (s *Script) SHA() string { return s.sha }
+
+// Run runs the script with keys as KEYS and args as ARGV.
+func (s *Script) Run(ctx context.Context, c *Client, keys []string, args ...string) (any, error) {
+	cmd := make([]string, 0, 3+len(keys)+len(args))
+	cmd = append(cmd, "EVALSHA", s.sha, strconv.Itoa(len(keys)))
+	cmd = append(cmd, keys...)
+	cmd = append(cmd, args...)
+	reply, err := c.Do(ctx, cmd...)
+	var redisErr Error
+	if errors.As(err, &redisErr) && strings.HasPrefix(string(redisErr), "NOSCRIPT") {
+		cmd[0], cmd[1] = "EVAL", s.src
+		return c.Do(ctx, cmd...)
+	}
+	return reply, err
+}
+
+// ReadReply reads one RESP reply. An error reply is returned as an Error.
+// It's exported for redistest, which speaks the same protocol.
+func ReadReply(r *bufio.Reader) (any, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, errors.New("empty reply line")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < -1 {
+			return nil, fmt.Errorf("bad bulk length %q", line)
+		}
+		if n == -1 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < -1 {
+			return nil, fmt.Errorf("bad array length %q", line)
+		}
+		if n == -1 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			// An error inside an array (from EXEC, say) is an item, not the
+			// reply's error.
+			item, err := ReadReply(r)
+			var redisErr Error
+			if errors.As(err, &redisErr) {
+				item, err = redisErr, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unknown reply type %q", line[0])
+	}
+}
+
+// readLine reads up to "\r\n" and returns what came before it.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// Int reads an integer reply (or a string holding one).
+func Int(reply any, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	switch v := reply.(type) {
+	case int64:
+		return v, nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	case nil:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("redis: expected an integer, got %T", reply)
+	}
+}
+
+// String reads a string reply. ok is false for nil (a missing key).
+func String(reply any, err error) (s string, ok bool, _ error) {
+	if err != nil {
+		return "", false, err
+	}
+	switch v := reply.(type) {
+	case string:
+		return v, true, nil
+	case nil:
+		return "", false, nil
+	default:
+		return "", false, fmt.Errorf("redis: expected a string, got %T", reply)
+	}
+}
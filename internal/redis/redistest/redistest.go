@@ -0,0 +1,358 @@
+// Package redistest runs a fake Redis server in-process, for tests of code
+// that talks to Redis — the way net/http/httptest does for HTTP.
+//
+// It keeps strings in memory and understands the commands the app uses:
+// PING, AUTH, SELECT, GET, SET (with EX, PX and NX), DEL, EXISTS, INCR,
+// INCRBY, EXPIRE, PEXPIRE, PTTL and FLUSHALL. Lua isn't available, so
+// EVAL and EVALSHA run Go functions registered with HandleScript instead.
+package redistest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/redis"
+)
+
+// ScriptFunc stands in for a Lua script: it gets KEYS and ARGV and returns
+// a reply (string, int64, nil, []any or redis.Error). It runs with the
+// server locked, so it's atomic like a real script, and reads and writes
+// through Tx.
+type ScriptFunc func(tx *Tx, keys, args []string) any
+
+// Tx is the server as a script sees it.
+type Tx struct{ s *Server }
+
+// Get returns a key's value.
+func (tx *Tx) Get(key string) (string, bool) { return tx.s.get(key) }
+
+// Set stores a value, expiring after ttl (0: never).
+func (tx *Tx) Set(key, value string, ttl time.Duration) { tx.s.put(key, value, ttl) }
+
+// Now is the server's clock, as TIME reads it.
+func (tx *Tx) Now() time.Time { return tx.s.now() }
+
+// Server is a fake Redis server listening on localhost.
+type Server struct {
+	ln net.Listener
+
+	mu      sync.Mutex
+	data    map[string]entry
+	scripts map[string]ScriptFunc // by SHA-1, as EVALSHA finds them
+	loaded  map[string]bool       // scripts sent with EVAL, so EVALSHA works
+	now     func() time.Time
+	calls   int
+}
+
+type entry struct {
+	value   string
+	expires time.Time // zero: never
+}
+
+// NewServer starts a server and stops it when the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("redistest: listening: %v", err)
+	}
+	s := &Server{
+		ln:      ln,
+		data:    make(map[string]entry),
+		scripts: make(map[string]ScriptFunc),
+		loaded:  make(map[string]bool),
+		now:     time.Now,
+	}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+// Addr is the server's host:port.
+func (s *Server) Addr() string { return s.ln.Addr().String() }
+
+// URL is a redis:// URL for the server.
+func (s *Server) URL() string { return "redis://" + s.Addr() }
+
+// Client returns a client for the server, closed when the test ends.
+func (s *Server) Client(t testing.TB) *redis.Client {
+	c := redis.New(redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// SetNow replaces the server's clock, so tests can expire keys.
+func (s *Server) SetNow(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// Calls counts the commands the server has handled.
+func (s *Server) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+// HandleScript makes EVAL and EVALSHA of script run fn.
+func (s *Server) HandleScript(script *redis.Script, fn ScriptFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[script.SHA()] = fn
+}
+
+// get returns a key's value, dropping it if it has expired. s.mu must be
+// held.
+func (s *Server) get(key string) (string, bool) {
+	e, ok := s.data[key]
+	if !ok || (!e.expires.IsZero() && !s.now().Before(e.expires)) {
+		delete(s.data, key)
+		return "", false
+	}
+	return e.value, true
+}
+
+// put stores a value, expiring after ttl (0: never). s.mu must be held.
+func (s *Server) put(key, value string, ttl time.Duration) {
+	e := entry{value: value}
+	if ttl > 0 {
+		e.expires = s.now().Add(ttl)
+	}
+	s.data[key] = e
+}
+
+func (s *Server) serve() {
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(c)
+	}
+}
+
+func (s *Server) handle(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	w := bufio.NewWriter(c)
+	for {
+		req, err := redis.ReadReply(r)
+		if err != nil {
+			return
+		}
+		items, _ := req.([]any)
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i], _ = item.(string)
+		}
+		writeReply(w, s.exec(args))
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// exec runs one command.
+func (s *Server) exec(args []string) any {
+	if len(args) == 0 {
+		return redis.Error("ERR empty command")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+
+	cmd, args := strings.ToUpper(args[0]), args[1:]
+	switch cmd {
+	case "PING":
+		return "PONG"
+	case "AUTH", "SELECT":
+		return "OK"
+	case "FLUSHALL":
+		s.data = make(map[string]entry)
+		return "OK"
+	case "GET":
+		if len(args) != 1 {
+			return wrongArgs(cmd)
+		}
+		if v, ok := s.get(args[0]); ok {
+			return v
+		}
+		return nil
+	case "SET":
+		return s.set(args)
+	case "DEL", "EXISTS":
+		n := int64(0)
+		for _, k := range args {
+			if _, ok := s.get(k); ok {
+				n++
+				if cmd == "DEL" {
+					delete(s.data, k)
+				}
+			}
+		}
+		return n
+	case "INCR", "INCRBY":
+		if len(args) != 1+boolInt(cmd == "INCRBY") {
+			return wrongArgs(cmd)
+		}
+		by := int64(1)
+		if cmd == "INCRBY" {
+			var err error
+			if by, err = strconv.ParseInt(args[1], 10, 64); err != nil {
+				return redis.Error("ERR value is not an integer or out of range")
+			}
+		}
+		e := s.data[args[0]]
+		v, _ := s.get(args[0])
+		n, err := strconv.ParseInt(orZero(v), 10, 64)
+		if err != nil {
+			return redis.Error("ERR value is not an integer or out of range")
+		}
+		n += by
+		e.value = strconv.FormatInt(n, 10)
+		s.data[args[0]] = e
+		return n
+	case "EXPIRE", "PEXPIRE":
+		if len(args) != 2 {
+			return wrongArgs(cmd)
+		}
+		n, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return redis.Error("ERR value is not an integer or out of range")
+		}
+		unit := time.Second
+		if cmd == "PEXPIRE" {
+			unit = time.Millisecond
+		}
+		if _, ok := s.get(args[0]); !ok {
+			return int64(0)
+		}
+		e := s.data[args[0]]
+		e.expires = s.now().Add(time.Duration(n) * unit)
+		s.data[args[0]] = e
+		return int64(1)
+	case "PTTL":
+		if len(args) != 1 {
+			return wrongArgs(cmd)
+		}
+		if _, ok := s.get(args[0]); !ok {
+			return int64(-2)
+		}
+		e := s.data[args[0]]
+		if e.expires.IsZero() {
+			return int64(-1)
+		}
+		return e.expires.Sub(s.now()).Milliseconds()
+	case "EVAL", "EVALSHA":
+		return s.eval(cmd, args)
+	default:
+		return redis.Error(fmt.Sprintf("ERR unknown command '%s'", strings.ToLower(cmd)))
+	}
+}
+
+// eval handles EVAL script numkeys key… arg… and EVALSHA sha1 numkeys ….
+// Like a real server, EVALSHA only knows scripts it was sent with EVAL.
+func (s *Server) eval(cmd string, args []string) any {
+	if len(args) < 2 {
+		return wrongArgs(cmd)
+	}
+	sha := args[0]
+	if cmd == "EVAL" {
+		sha = redis.NewScript(args[0]).SHA()
+		s.loaded[sha] = true
+	} else if !s.loaded[sha] {
+		return redis.Error("NOSCRIPT No matching script. Please use EVAL.")
+	}
+	fn, ok := s.scripts[sha]
+	if !ok {
+		return redis.Error("ERR redistest: no Go function for this script; see HandleScript")
+	}
+	numKeys, err := strconv.Atoi(args[1])
+	if err != nil || numKeys < 0 || numKeys > len(args)-2 {
+		return redis.Error("ERR Number of keys can't be greater than number of args")
+	}
+	return fn(&Tx{s}, args[2:2+numKeys], args[2+numKeys:])
+}
+
+// set handles SET key value [EX seconds | PX milliseconds] [NX].
+func (s *Server) set(args []string) any {
+	if len(args) < 2 {
+		return wrongArgs("SET")
+	}
+	var ttl time.Duration
+	nx := false
+	for i := 2; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); opt {
+		case "NX":
+			nx = true
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				return redis.Error("ERR syntax error")
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				return redis.Error("ERR invalid expire time in 'set' command")
+			}
+			ttl = time.Duration(n) * time.Second
+			if opt == "PX" {
+				ttl = time.Duration(n) * time.Millisecond
+			}
+			i++
+		default:
+			return redis.Error("ERR syntax error")
+		}
+	}
+	if _, exists := s.get(args[0]); nx && exists {
+		return nil
+	}
+	s.put(args[0], args[1], ttl)
+	return "OK"
+}
+
+// writeReply writes a reply in RESP.
+func writeReply(w *bufio.Writer, reply any) {
+	switch v := reply.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case redis.Error:
+		fmt.Fprintf(w, "-%s\r\n", string(v))
+	case int64:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case int:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case string:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case []any:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, item := range v {
+			writeReply(w, item)
+		}
+	default:
+		fmt.Fprintf(w, "-ERR redistest: can't send a %T\r\n", reply)
+	}
+}
+
+func wrongArgs(cmd string) redis.Error {
+	return redis.Error(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(cmd)))
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func orZero(s string) string {
+	if s == "" {
+		return "0"
+	}
+	return s
+}
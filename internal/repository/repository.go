@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"iter"
+	"time"
 
 	"github.com/sakif/coding-playground/internal/model"
 )
@@ -9,14 +11,59 @@ import (
 type ListOptions struct {
 	Limit  int
 	Offset int
+
+	// Filters. Zero values don't filter.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	HasOwner      *bool  // true: only snippets with an owner; false: only anonymous ones
+	OwnerID       string // only snippets owned by this user
+	OrgID         string // only snippets owned by this org
+	PersonalOnly  bool   // only snippets no org owns
+	PublicOnly    bool   // only snippets their owner published
+
+	// OmitCode leaves Snippet.Code empty instead of loading it. Code is by far
+	// the largest column, and list views that only show names don't need it.
+	OmitCode bool
 }
 
 type SnippetRepository interface {
 	Create(ctx context.Context, snippet *model.Snippet) error
 	GetByID(ctx context.Context, id string) (*model.Snippet, error)
 	List(ctx context.Context, opts ListOptions) ([]model.Snippet, error)
+	// ListWithAuthors is List with each owned snippet's Author filled in,
+	// joined in the same query.
+	ListWithAuthors(ctx context.Context, opts ListOptions) ([]model.Snippet, error)
+	// Count returns how many snippets List would find without Limit and Offset.
+	Count(ctx context.Context, opts ListOptions) (int, error)
 	Update(ctx context.Context, snippet *model.Snippet) error
 	Delete(ctx context.Context, id string) error
+	// SetPublic saves the snippet's Public and PublishedAt fields.
+	SetPublic(ctx context.Context, snippet *model.Snippet) error
+	// ListPublic returns the newest public snippets, optionally only userID's.
+	ListPublic(ctx context.Context, userID string, limit int) ([]model.Snippet, error)
+}
+
+// SnippetCursor reads every matching snippet without holding them all in
+// memory, for exports that may cover thousands.
+type SnippetCursor interface {
+	// EachSnippet yields the snippets matching opts in ID order (roughly
+	// oldest first); Limit and Offset are ignored. They're read a batch at
+	// a time, so no query stays open while the caller works. An error is
+	// yielded once, and ends the sequence; so does ctx being done.
+	EachSnippet(ctx context.Context, opts ListOptions) iter.Seq2[*model.Snippet, error]
+}
+
+// CommentRepository stores comments on snippets.
+type CommentRepository interface {
+	// CreateComment saves a new comment, setting its ID and CreatedAt.
+	CreateComment(ctx context.Context, comment *model.Comment) error
+	// GetComment returns apperror.ErrNotFound if the comment doesn't exist.
+	GetComment(ctx context.Context, id string) (*model.Comment, error)
+	// ListComments returns a snippet's comments, oldest first.
+	ListComments(ctx context.Context, snippetID string) ([]model.Comment, error)
+	// DeleteComment removes a comment.
+	DeleteComment(ctx context.Context, id string) error
 }
 
 // UserRepository manages user persistence (backed by SQLite).
@@ -25,4 +72,331 @@ type UserRepository interface {
 	Upsert(ctx context.Context, user *model.User) error
 	// GetUserByID retrieves a user by internal ID.
 	GetUserByID(ctx context.Context, id string) (*model.User, error)
+	// GetUserByLogin retrieves a user by GitHub login (case-insensitive).
+	GetUserByLogin(ctx context.Context, login string) (*model.User, error)
+	// GetUserByEmail retrieves the oldest user with this email (case-insensitive).
+	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
+	// SetUserRole changes a user's role.
+	SetUserRole(ctx context.Context, id, role string) error
+	// SetLeaderboardOptOut keeps a user off leaderboards (or puts them back).
+	SetLeaderboardOptOut(ctx context.Context, id string, optOut bool) error
+	// SetUserSuspended suspends a user (or lifts it).
+	SetUserSuspended(ctx context.Context, id string, suspended bool) error
+	// DeleteUser removes a user and the snippets, webhooks, submissions and comments they own.
+	DeleteUser(ctx context.Context, id string) error
+	// CountUsers counts users who signed up after createdAfter (zero: all users).
+	CountUsers(ctx context.Context, createdAfter time.Time) (int, error)
+}
+
+// EmailRepository manages email preferences and sign-in links.
+type EmailRepository interface {
+	// GetEmailPreferences returns model.DefaultEmailPreferences for a user
+	// who never saved any.
+	GetEmailPreferences(ctx context.Context, userID string) (*model.EmailPreferences, error)
+	// SaveEmailPreferences creates or replaces a user's preferences.
+	SaveEmailPreferences(ctx context.Context, prefs *model.EmailPreferences) error
+	// ListDigestRecipients returns the users with an email address who
+	// haven't turned the weekly digest off.
+	ListDigestRecipients(ctx context.Context) ([]model.User, error)
+	// CreateMagicLink saves a sign-in link by the hash of its token.
+	CreateMagicLink(ctx context.Context, tokenHash, userID string, expiresAt time.Time) error
+	// ConsumeMagicLink uses up a sign-in link and returns its user's ID.
+	// Unknown, used and expired links are apperror.ErrNotFound.
+	ConsumeMagicLink(ctx context.Context, tokenHash string) (string, error)
+}
+
+// WebhookRepository manages webhooks and their delivery log.
+type WebhookRepository interface {
+	// CreateWebhook saves a new webhook, setting its ID and CreatedAt.
+	CreateWebhook(ctx context.Context, webhook *model.Webhook) error
+	// GetWebhook returns apperror.ErrNotFound if the webhook doesn't exist.
+	GetWebhook(ctx context.Context, id string) (*model.Webhook, error)
+	// ListWebhooks returns a user's webhooks, oldest first.
+	ListWebhooks(ctx context.Context, userID string) ([]model.Webhook, error)
+	// ListWebhooksForEvent returns a user's webhooks subscribed to event.
+	ListWebhooksForEvent(ctx context.Context, userID, event string) ([]model.Webhook, error)
+	// DeleteWebhook removes a webhook and its delivery log.
+	DeleteWebhook(ctx context.Context, id string) error
+	// RecordWebhookDelivery appends to a webhook's delivery log.
+	RecordWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+	// ListWebhookDeliveries returns a webhook's most recent deliveries, newest first.
+	ListWebhookDeliveries(ctx context.Context, webhookID string, limit int) ([]model.WebhookDelivery, error)
+}
+
+// ExerciseRepository manages exercises.
+type ExerciseRepository interface {
+	// CreateExercise saves a new exercise, setting its ID and timestamps.
+	CreateExercise(ctx context.Context, exercise *model.Exercise) error
+	// GetExercise returns apperror.ErrNotFound if the exercise doesn't exist.
+	GetExercise(ctx context.Context, id string) (*model.Exercise, error)
+	// ListExercises returns a page of exercises, newest first.
+	ListExercises(ctx context.Context, limit, offset int) ([]model.Exercise, error)
+	// CountExercises returns how many exercises there are.
+	CountExercises(ctx context.Context) (int, error)
+	// UpdateExercise saves the exercise's content and bumps UpdatedAt.
+	UpdateExercise(ctx context.Context, exercise *model.Exercise) error
+	// DeleteExercise removes an exercise.
+	DeleteExercise(ctx context.Context, id string) error
+}
+
+// HintRepository tracks which of an exercise's hints each learner has
+// unlocked. Hints unlock in order, so a count is all it needs.
+type HintRepository interface {
+	// HintsUnlocked returns how many hints userID has unlocked (0 if none).
+	HintsUnlocked(ctx context.Context, exerciseID, userID string) (int, error)
+	// UnlockHint unlocks userID's next hint, unless they already have total,
+	// and returns how many they have now.
+	UnlockHint(ctx context.Context, exerciseID, userID string, total int) (int, error)
+}
+
+// SubmissionFilter narrows ListSubmissions. Zero values don't filter.
+type SubmissionFilter struct {
+	ExerciseID   string
+	AssignmentID string
+	UserID       string
+	ClassID      string // only submissions by the class's students
+}
+
+// SubmissionRepository stores graded exercise submissions.
+type SubmissionRepository interface {
+	// CreateSubmission saves a graded submission, setting its ID and CreatedAt.
+	CreateSubmission(ctx context.Context, submission *model.Submission) error
+	// ListSubmissions returns matching submissions, newest first.
+	ListSubmissions(ctx context.Context, filter SubmissionFilter) ([]model.Submission, error)
+}
+
+// OrgRepository manages organizations and their members.
+type OrgRepository interface {
+	// CreateOrg saves a new org and makes its creator an owner of it.
+	CreateOrg(ctx context.Context, org *model.Org) error
+	// GetOrg returns apperror.ErrNotFound if the org doesn't exist.
+	GetOrg(ctx context.Context, id string) (*model.Org, error)
+	// ListOrgsForUser returns the orgs userID belongs to, newest first.
+	ListOrgsForUser(ctx context.Context, userID string) ([]model.OrgMembership, error)
+	// DeleteOrg deletes an org and its memberships. Its snippets go back to
+	// being personal snippets of whoever created them.
+	DeleteOrg(ctx context.Context, id string) error
+
+	// AddOrgMember adds a user to an org. It returns apperror.ErrConflict if
+	// they're already a member.
+	AddOrgMember(ctx context.Context, member *model.OrgMember) error
+	// GetOrgMember returns apperror.ErrNotFound if userID isn't in the org.
+	GetOrgMember(ctx context.Context, orgID, userID string) (*model.OrgMember, error)
+	// ListOrgMembers returns an org's members, owners first.
+	ListOrgMembers(ctx context.Context, orgID string) ([]model.OrgMember, error)
+	// SetOrgMemberRole changes a member's role.
+	SetOrgMemberRole(ctx context.Context, orgID, userID, role string) error
+	// RemoveOrgMember takes a user out of an org.
+	RemoveOrgMember(ctx context.Context, orgID, userID string) error
+
+	// SetOrgQuota replaces an org's quota overrides. It returns
+	// apperror.ErrNotFound if the org doesn't exist.
+	SetOrgQuota(ctx context.Context, orgID string, quota model.OrgQuota) error
+	// AddOrgRun counts one run for an org on day ("2006-01-02"), unless it
+	// already has limit runs that day (limit 0: no limit). It reports
+	// whether the run was counted.
+	AddOrgRun(ctx context.Context, orgID, day string, limit int) (bool, error)
+	// CountOrgRuns returns how many runs AddOrgRun counted for an org on day.
+	CountOrgRuns(ctx context.Context, orgID, day string) (int, error)
+}
+
+// TransferRepository keeps pending offers to hand snippets over. A snippet
+// has at most one at a time.
+type TransferRepository interface {
+	// CreateTransfer saves a new offer, setting its ID and CreatedAt. It
+	// returns apperror.ErrConflict if the snippet already has one.
+	CreateTransfer(ctx context.Context, t *model.SnippetTransfer) error
+	// GetTransfer returns apperror.ErrNotFound if there's no such offer.
+	GetTransfer(ctx context.Context, id string) (*model.SnippetTransfer, error)
+	// ListTransfersTo returns offers userID can accept: those to them and
+	// to orgs they own. ListTransfersBy returns the offers they made. Both
+	// are newest first.
+	ListTransfersTo(ctx context.Context, userID string) ([]model.SnippetTransfer, error)
+	ListTransfersBy(ctx context.Context, userID string) ([]model.SnippetTransfer, error)
+	// DeleteTransfer withdraws or declines an offer.
+	DeleteTransfer(ctx context.Context, id string) error
+	// CompleteTransfer gives the snippet to userID and orgID ("" for a
+	// personal snippet) and deletes the offer, in one step. It returns
+	// apperror.ErrConflict, changing nothing, if the snippet no longer
+	// belongs to who it did when the offer was made.
+	CompleteTransfer(ctx context.Context, t *model.SnippetTransfer, userID, orgID string) error
+}
+
+// OrgInviteRepository keeps invitations to join orgs. Invites are found by
+// the SHA-256 of their token; only pending ones (not used, not expired)
+// are ever returned.
+type OrgInviteRepository interface {
+	// CreateOrgInvite saves a new invite, setting its ID and CreatedAt.
+	CreateOrgInvite(ctx context.Context, invite *model.OrgInvite, tokenHash string) error
+	// ListOrgInvites returns an org's pending invites, newest first.
+	ListOrgInvites(ctx context.Context, orgID string) ([]model.OrgInvite, error)
+	// GetOrgInviteByToken returns apperror.ErrNotFound unless the invite is pending.
+	GetOrgInviteByToken(ctx context.Context, tokenHash string) (*model.OrgInvite, error)
+	// DeleteOrgInvite revokes an invite. It returns apperror.ErrNotFound if
+	// the org has no such pending invite.
+	DeleteOrgInvite(ctx context.Context, orgID, id string) error
+	// AcceptOrgInvite uses up a pending invite and adds userID to its org
+	// with its role, in one step. It returns apperror.ErrNotFound unless the
+	// invite is pending, and apperror.ErrConflict — leaving the invite
+	// unused — if userID is already a member.
+	AcceptOrgInvite(ctx context.Context, tokenHash, userID string) (*model.OrgMember, error)
+}
+
+// ClassRepository manages classes, their members and their assignments.
+type ClassRepository interface {
+	// CreateClass saves a new class and makes its owner a teacher of it. It
+	// returns apperror.ErrConflict if the join code is taken.
+	CreateClass(ctx context.Context, class *model.Class) error
+	// GetClass returns apperror.ErrNotFound if the class doesn't exist.
+	GetClass(ctx context.Context, id string) (*model.Class, error)
+	// GetClassByJoinCode returns apperror.ErrNotFound for an unknown code.
+	GetClassByJoinCode(ctx context.Context, code string) (*model.Class, error)
+	// ListClassesForUser returns the classes userID belongs to, newest first.
+	ListClassesForUser(ctx context.Context, userID string) ([]model.ClassMembership, error)
+
+	// AddClassMember adds a user to a class; an existing member keeps their role.
+	AddClassMember(ctx context.Context, member *model.ClassMember) error
+	// GetClassMember returns apperror.ErrNotFound if userID isn't in the class.
+	GetClassMember(ctx context.Context, classID, userID string) (*model.ClassMember, error)
+	// ListClassMembers returns a class's members, teachers first.
+	ListClassMembers(ctx context.Context, classID string) ([]model.ClassMember, error)
+
+	// CreateAssignment saves a new assignment, setting its ID and CreatedAt.
+	CreateAssignment(ctx context.Context, assignment *model.Assignment) error
+	// UpdateAssignment saves an assignment's title, dates and late policy.
+	UpdateAssignment(ctx context.Context, assignment *model.Assignment) error
+	// GetAssignment returns apperror.ErrNotFound if the assignment doesn't exist.
+	GetAssignment(ctx context.Context, id string) (*model.Assignment, error)
+	// ListAssignments returns a class's assignments, oldest first.
+	ListAssignments(ctx context.Context, classID string) ([]model.Assignment, error)
+}
+
+// SimilarityRepository stores assignments' similarity reports.
+type SimilarityRepository interface {
+	// SaveSimilarityReport creates or replaces an assignment's report.
+	SaveSimilarityReport(ctx context.Context, report *model.SimilarityReport) error
+	// GetSimilarityReport returns apperror.ErrNotFound if no report was requested.
+	GetSimilarityReport(ctx context.Context, assignmentID string) (*model.SimilarityReport, error)
+}
+
+// ChallengeRepository stores the daily challenge schedule.
+type ChallengeRepository interface {
+	// SaveChallenge schedules a challenge, replacing any on the same date.
+	SaveChallenge(ctx context.Context, challenge *model.Challenge) error
+	// GetChallenge returns apperror.ErrNotFound if no challenge is set for date.
+	GetChallenge(ctx context.Context, date string) (*model.Challenge, error)
+	// ListChallenges returns the challenges dated from through to
+	// (inclusive), newest first.
+	ListChallenges(ctx context.Context, from, to string) ([]model.Challenge, error)
+	// DeleteChallenge returns apperror.ErrNotFound if no challenge is set for date.
+	DeleteChallenge(ctx context.Context, date string) error
+	// SolvedChallengeDates returns the dates of the challenges userID has
+	// passed, newest first.
+	SolvedChallengeDates(ctx context.Context, userID string) ([]string, error)
+}
+
+// StarRepository stores which users starred which snippets.
+type StarRepository interface {
+	// StarSnippet stars a snippet for userID, reporting false if they
+	// already had.
+	StarSnippet(ctx context.Context, snippetID, userID string) (bool, error)
+	// UnstarSnippet removes userID's star, reporting false if there wasn't one.
+	UnstarSnippet(ctx context.Context, snippetID, userID string) (bool, error)
+	// CountStars counts a snippet's stars.
+	CountStars(ctx context.Context, snippetID string) (int, error)
+	// ListSnippetStars returns a page of a snippet's stars, newest first.
+	ListSnippetStars(ctx context.Context, snippetID string, limit, offset int) ([]model.Star, error)
+	// ListUserStars returns a page of the stars userID gave, newest first.
+	ListUserStars(ctx context.Context, userID string, limit, offset int) ([]model.Star, error)
+	// CountUserStars counts the stars userID gave.
+	CountUserStars(ctx context.Context, userID string) (int, error)
+}
+
+// NotificationRepository stores users' in-app notifications.
+type NotificationRepository interface {
+	// CreateNotification saves a notification, setting its ID and CreatedAt.
+	CreateNotification(ctx context.Context, n *model.Notification) error
+	// ListNotifications returns userID's newest notifications first, with
+	// ActorLogin filled in; only unread ones if unreadOnly is set.
+	ListNotifications(ctx context.Context, userID string, unreadOnly bool, limit int) ([]model.Notification, error)
+	// CountUnreadNotifications counts userID's unread notifications.
+	CountUnreadNotifications(ctx context.Context, userID string) (int, error)
+	// MarkNotificationsRead marks userID's notifications with these IDs
+	// read, or all of them when ids is empty. Other users' IDs are ignored.
+	MarkNotificationsRead(ctx context.Context, userID string, ids []string) error
+}
+
+// StatsRepository stores users' runs and sums up their activity.
+type StatsRepository interface {
+	// CreateRun saves a run, setting its ID and CreatedAt.
+	CreateRun(ctx context.Context, run *model.Run) error
+	// GetUserStats sums up userID's snippets and runs. Activity lists only
+	// the days since since that had any, oldest first, as days in since's
+	// location.
+	GetUserStats(ctx context.Context, userID string, since time.Time) (*model.UserStats, error)
+}
+
+// RunRepository stores the run history, for replaying runs.
+type RunRepository interface {
+	// CreateRun saves a run, setting its ID and CreatedAt.
+	CreateRun(ctx context.Context, run *model.Run) error
+	// GetRun returns a run, or an apperror.NotFound error.
+	GetRun(ctx context.Context, id string) (*model.Run, error)
+	// ListRuns returns a page of userID's runs, newest first.
+	ListRuns(ctx context.Context, userID string, limit, offset int) ([]model.Run, error)
+	// CountRuns counts userID's runs.
+	CountRuns(ctx context.Context, userID string) (int, error)
+}
+
+// ActivityRepository stores the public activity feed.
+type ActivityRepository interface {
+	// CreateActivity saves an activity, setting its ID and CreatedAt.
+	CreateActivity(ctx context.Context, a *model.Activity) error
+	// ListActivity returns activity newest first, with ActorLogin and
+	// SnippetName filled in. Only activity on snippets that are public now
+	// is listed. actorID "" means everyone's; before, when not 0, lists only
+	// activity with smaller IDs.
+	ListActivity(ctx context.Context, actorID string, before int64, limit int) ([]model.Activity, error)
+}
+
+// BadgeRepository stores the badges users have earned, and the counters
+// some badges are awarded on.
+type BadgeRepository interface {
+	// AwardBadge gives userID a badge, reporting false if they already had it.
+	AwardBadge(ctx context.Context, userID, badgeID string) (bool, error)
+	// ListUserBadges returns the badges userID has earned, oldest first.
+	ListUserBadges(ctx context.Context, userID string) ([]model.UserBadge, error)
+	// IncrementBadgeCounter adds one to a per-user counter and returns the
+	// new count.
+	IncrementBadgeCounter(ctx context.Context, userID, counter string) (int, error)
+}
+
+// LeaderboardFilter picks which leaderboard entries ListLeaderboardEntries
+// returns. Set exactly one field.
+type LeaderboardFilter struct {
+	ExerciseID string
+	ClassID    string // the class's students, on the class's assigned exercises
+}
+
+// LeaderboardRepository materializes and reads leaderboards.
+type LeaderboardRepository interface {
+	// RefreshLeaderboard rebuilds every user's best attempt per exercise from
+	// submissions and returns how many entries there now are.
+	RefreshLeaderboard(ctx context.Context) (int, error)
+	// LeaderboardRefreshedAt returns when RefreshLeaderboard last ran, or nil
+	// if it never has (or found nothing).
+	LeaderboardRefreshedAt(ctx context.Context) (*time.Time, error)
+	// ListLeaderboardEntries returns matching entries, best first (highest
+	// score, then fastest, then earliest), leaving out users who opted out.
+	// limit 0 means no limit.
+	ListLeaderboardEntries(ctx context.Context, filter LeaderboardFilter, limit int) ([]model.LeaderboardEntry, error)
+}
+
+// StorageRepository reports on the database's own files and keeps its
+// write-ahead log in check.
+type StorageRepository interface {
+	// StorageStats describes the database file and its WAL.
+	StorageStats(ctx context.Context) (*model.StorageStats, error)
+	// Checkpoint copies the WAL into the database file and truncates it.
+	Checkpoint(ctx context.Context) (*model.Checkpoint, error)
 }
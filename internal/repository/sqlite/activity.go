@@ -0,0 +1,66 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+var _ repository.ActivityRepository = (*DB)(nil)
+
+// CreateActivity saves an activity.
+func (db *DB) CreateActivity(ctx context.Context, a *model.Activity) error {
+	a.CreatedAt = time.Now().UTC()
+	err := db.conn.QueryRowContext(ctx,
+		`INSERT INTO activity_events (kind, actor_id, snippet_id, created_at)
+		 VALUES (?, ?, ?, ?)
+		 RETURNING id`,
+		a.Kind, a.ActorID, a.SnippetID, a.CreatedAt,
+	).Scan(&a.ID)
+	if err != nil {
+		return fmt.Errorf("sqlite: create activity: %w", err)
+	}
+	return nil
+}
+
+// ListActivity returns activity on public snippets, newest first.
+func (db *DB) ListActivity(ctx context.Context, actorID string, before int64, limit int) ([]model.Activity, error) {
+	// Visibility is checked now, not when the activity happened: a snippet
+	// taken back down (or deleted) takes its activity with it.
+	where := ` WHERE 1 = 1`
+	args := []any{}
+	if actorID != "" {
+		where += ` AND a.actor_id = ?`
+		args = append(args, actorID)
+	}
+	if before > 0 {
+		where += ` AND a.id < ?`
+		args = append(args, before)
+	}
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT a.id, a.kind, a.actor_id, COALESCE(u.login, ''), a.snippet_id, s.name, a.created_at
+		 FROM activity_events a
+		 JOIN snippets s ON s.id = a.snippet_id AND s.public = 1
+		 LEFT JOIN users u ON u.id = a.actor_id`+where+`
+		 ORDER BY a.id DESC
+		 LIMIT ?`,
+		append(args, limit)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list activity: %w", err)
+	}
+	defer rows.Close()
+
+	activity := []model.Activity{}
+	for rows.Next() {
+		var a model.Activity
+		if err := rows.Scan(&a.ID, &a.Kind, &a.ActorID, &a.ActorLogin, &a.SnippetID, &a.SnippetName, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("sqlite: scan activity: %w", err)
+		}
+		activity = append(activity, a)
+	}
+	return activity, rows.Err()
+}
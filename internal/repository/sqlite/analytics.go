@@ -0,0 +1,40 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sakif/coding-playground/internal/analytics"
+)
+
+var _ analytics.Store = (*DB)(nil)
+
+// SaveAnalyticsEvents saves a batch of events in one transaction, which is
+// far cheaper in SQLite than one commit per event.
+func (db *DB) SaveAnalyticsEvents(ctx context.Context, events []analytics.Event) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite: save analytics events: %w", err)
+	}
+	defer tx.Rollback() // no-op after a successful Commit
+
+	stmt, err := tx.PrepareContext(ctx,
+		`INSERT INTO analytics_events (name, user_id, properties, created_at) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("sqlite: save analytics events: %w", err)
+	}
+	defer stmt.Close()
+	for _, e := range events {
+		props := []byte("{}")
+		if len(e.Properties) > 0 {
+			if props, err = json.Marshal(e.Properties); err != nil {
+				return fmt.Errorf("sqlite: encoding %s properties: %w", e.Name, err)
+			}
+		}
+		if _, err := stmt.ExecContext(ctx, e.Name, e.UserID, string(props), e.Time); err != nil {
+			return fmt.Errorf("sqlite: save analytics event: %w", err)
+		}
+	}
+	return tx.Commit()
+}
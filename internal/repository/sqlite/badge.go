@@ -0,0 +1,87 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+var _ repository.BadgeRepository = (*DB)(nil)
+
+// syncBadges makes the badges table match model.Badges.
+func (db *DB) syncBadges() error {
+	for _, b := range model.Badges {
+		_, err := db.conn.Exec(
+			`INSERT INTO badges (id, name, description) VALUES (?, ?, ?)
+			 ON CONFLICT (id) DO UPDATE SET name = excluded.name, description = excluded.description`,
+			b.ID, b.Name, b.Description,
+		)
+		if err != nil {
+			return fmt.Errorf("saving badge %s: %w", b.ID, err)
+		}
+	}
+	return nil
+}
+
+// AwardBadge gives userID a badge, reporting false if they already had it.
+func (db *DB) AwardBadge(ctx context.Context, userID, badgeID string) (bool, error) {
+	res, err := db.exec(ctx,
+		`INSERT INTO user_badges (user_id, badge_id, awarded_at) VALUES (?, ?, ?)
+		 ON CONFLICT (user_id, badge_id) DO NOTHING`,
+		userID, badgeID, time.Now().UTC(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("sqlite: award badge: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("sqlite: award badge: %w", err)
+	}
+	return n == 1, nil
+}
+
+// ListUserBadges returns the badges userID has earned, oldest first.
+func (db *DB) ListUserBadges(ctx context.Context, userID string) ([]model.UserBadge, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT b.id, b.name, b.description, ub.awarded_at
+		 FROM user_badges ub JOIN badges b ON b.id = ub.badge_id
+		 WHERE ub.user_id = ?
+		 ORDER BY ub.awarded_at, b.id`, userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list user badges: %w", err)
+	}
+	defer rows.Close()
+
+	badges := []model.UserBadge{}
+	for rows.Next() {
+		var b model.UserBadge
+		if err := rows.Scan(&b.ID, &b.Name, &b.Description, &b.AwardedAt); err != nil {
+			return nil, fmt.Errorf("sqlite: scanning user badge: %w", err)
+		}
+		badges = append(badges, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite: list user badges: %w", err)
+	}
+	return badges, nil
+}
+
+// IncrementBadgeCounter adds one to a per-user counter and returns the new
+// count.
+func (db *DB) IncrementBadgeCounter(ctx context.Context, userID, counter string) (int, error) {
+	var n int
+	err := db.conn.QueryRowContext(ctx,
+		`INSERT INTO badge_counters (user_id, counter, count) VALUES (?, ?, 1)
+		 ON CONFLICT (user_id, counter) DO UPDATE SET count = count + 1
+		 RETURNING count`,
+		userID, counter,
+	).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("sqlite: increment badge counter: %w", err)
+	}
+	return n, nil
+}
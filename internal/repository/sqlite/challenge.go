@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+var _ repository.ChallengeRepository = (*DB)(nil)
+
+// SaveChallenge schedules a challenge, replacing any on the same date.
+func (db *DB) SaveChallenge(ctx context.Context, c *model.Challenge) error {
+	c.CreatedAt = time.Now().UTC()
+	_, err := db.exec(ctx,
+		`INSERT INTO challenges (date, exercise_id, created_by, created_at)
+		 VALUES (?, ?, ?, ?)
+		 ON CONFLICT (date) DO UPDATE SET
+		     exercise_id = excluded.exercise_id,
+		     created_by  = excluded.created_by,
+		     created_at  = excluded.created_at`,
+		c.Date, c.ExerciseID, c.CreatedBy, c.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: save challenge: %w", err)
+	}
+	return nil
+}
+
+// GetChallenge returns the challenge set for date.
+func (db *DB) GetChallenge(ctx context.Context, date string) (*model.Challenge, error) {
+	var c model.Challenge
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT date, exercise_id, created_by, created_at FROM challenges WHERE date = ?`, date,
+	).Scan(&c.Date, &c.ExerciseID, &c.CreatedBy, &c.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("challenge", date)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: get challenge: %w", err)
+	}
+	return &c, nil
+}
+
+// ListChallenges returns the challenges dated from through to, newest
+// first. Dates are YYYY-MM-DD, so they compare correctly as text.
+func (db *DB) ListChallenges(ctx context.Context, from, to string) ([]model.Challenge, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT date, exercise_id, created_by, created_at FROM challenges
+		 WHERE date BETWEEN ? AND ? ORDER BY date DESC`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list challenges: %w", err)
+	}
+	defer rows.Close()
+
+	challenges := []model.Challenge{}
+	for rows.Next() {
+		var c model.Challenge
+		if err := rows.Scan(&c.Date, &c.ExerciseID, &c.CreatedBy, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("sqlite: scan challenge: %w", err)
+		}
+		challenges = append(challenges, c)
+	}
+	return challenges, rows.Err()
+}
+
+// DeleteChallenge unschedules the challenge set for date.
+func (db *DB) DeleteChallenge(ctx context.Context, date string) error {
+	result, err := db.exec(ctx, `DELETE FROM challenges WHERE date = ?`, date)
+	if err != nil {
+		return fmt.Errorf("sqlite: delete challenge: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperror.NotFound("challenge", date)
+	}
+	return nil
+}
+
+// SolvedChallengeDates returns the dates of the challenges userID passed,
+// newest first.
+func (db *DB) SolvedChallengeDates(ctx context.Context, userID string) ([]string, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT DISTINCT challenge_date FROM submissions
+		 WHERE user_id = ? AND challenge_date != '' AND status = ?
+		 ORDER BY challenge_date DESC`, userID, model.SubmissionPassed)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list solved challenges: %w", err)
+	}
+	defer rows.Close()
+
+	dates := []string{}
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			return nil, fmt.Errorf("sqlite: scan solved challenge: %w", err)
+		}
+		dates = append(dates, date)
+	}
+	return dates, rows.Err()
+}
@@ -0,0 +1,238 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+var _ repository.ClassRepository = (*DB)(nil)
+
+// CreateClass saves a new class and its owner's teacher membership in one
+// transaction.
+func (db *DB) CreateClass(ctx context.Context, class *model.Class) error {
+	class.ID = xid.New().String()
+	class.CreatedAt = time.Now().UTC()
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite: create class: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO classes (id, name, join_code, owner_id, created_at) VALUES (?, ?, ?, ?, ?)`,
+		class.ID, class.Name, class.JoinCode, class.OwnerID, class.CreatedAt,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return apperror.Conflict("class join code", class.JoinCode)
+		}
+		return fmt.Errorf("sqlite: create class: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO class_members (class_id, user_id, role, joined_at) VALUES (?, ?, ?, ?)`,
+		class.ID, class.OwnerID, model.ClassTeacher, class.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: add class owner: %w", err)
+	}
+	return tx.Commit()
+}
+
+// GetClass returns a class by ID.
+func (db *DB) GetClass(ctx context.Context, id string) (*model.Class, error) {
+	return db.getClass(ctx, "id", id)
+}
+
+// GetClassByJoinCode returns the class a join code belongs to.
+func (db *DB) GetClassByJoinCode(ctx context.Context, code string) (*model.Class, error) {
+	return db.getClass(ctx, "join_code", code)
+}
+
+// getClass looks a class up by one of its unique columns.
+func (db *DB) getClass(ctx context.Context, column, value string) (*model.Class, error) {
+	var c model.Class
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT id, name, join_code, owner_id, created_at FROM classes WHERE `+column+` = ?`, value,
+	).Scan(&c.ID, &c.Name, &c.JoinCode, &c.OwnerID, &c.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("class", value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: get class: %w", err)
+	}
+	return &c, nil
+}
+
+// ListClassesForUser returns the classes userID belongs to, with their role.
+func (db *DB) ListClassesForUser(ctx context.Context, userID string) ([]model.ClassMembership, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT c.id, c.name, c.join_code, c.owner_id, c.created_at, m.role
+		 FROM classes c JOIN class_members m ON m.class_id = c.id
+		 WHERE m.user_id = ? ORDER BY c.created_at DESC, c.id DESC`, userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list classes: %w", err)
+	}
+	defer rows.Close()
+
+	classes := []model.ClassMembership{}
+	for rows.Next() {
+		var c model.ClassMembership
+		if err := rows.Scan(&c.ID, &c.Name, &c.JoinCode, &c.OwnerID, &c.CreatedAt, &c.Role); err != nil {
+			return nil, fmt.Errorf("sqlite: scan class: %w", err)
+		}
+		classes = append(classes, c)
+	}
+	return classes, rows.Err()
+}
+
+// AddClassMember adds a user to a class. Joining twice is harmless: the
+// existing membership, and its role, are kept.
+func (db *DB) AddClassMember(ctx context.Context, m *model.ClassMember) error {
+	m.JoinedAt = time.Now().UTC()
+	_, err := db.exec(ctx,
+		`INSERT INTO class_members (class_id, user_id, role, joined_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT (class_id, user_id) DO NOTHING`,
+		m.ClassID, m.UserID, m.Role, m.JoinedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: add class member: %w", err)
+	}
+	return nil
+}
+
+const classMemberQuery = `SELECT m.class_id, m.user_id, COALESCE(u.login, ''), m.role, m.joined_at
+	FROM class_members m LEFT JOIN users u ON u.id = m.user_id`
+
+// GetClassMember returns userID's membership of a class.
+func (db *DB) GetClassMember(ctx context.Context, classID, userID string) (*model.ClassMember, error) {
+	var m model.ClassMember
+	err := db.conn.QueryRowContext(ctx,
+		classMemberQuery+` WHERE m.class_id = ? AND m.user_id = ?`, classID, userID,
+	).Scan(&m.ClassID, &m.UserID, &m.Login, &m.Role, &m.JoinedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("class member", userID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: get class member: %w", err)
+	}
+	return &m, nil
+}
+
+// ListClassMembers returns a class's members: teachers first, then students
+// in the order they joined.
+func (db *DB) ListClassMembers(ctx context.Context, classID string) ([]model.ClassMember, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		classMemberQuery+` WHERE m.class_id = ?
+		 ORDER BY m.role = 'teacher' DESC, m.joined_at, m.user_id`, classID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list class members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []model.ClassMember{}
+	for rows.Next() {
+		var m model.ClassMember
+		if err := rows.Scan(&m.ClassID, &m.UserID, &m.Login, &m.Role, &m.JoinedAt); err != nil {
+			return nil, fmt.Errorf("sqlite: scan class member: %w", err)
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// CreateAssignment saves a new assignment.
+func (db *DB) CreateAssignment(ctx context.Context, a *model.Assignment) error {
+	a.ID = xid.New().String()
+	a.CreatedAt = time.Now().UTC()
+	_, err := db.exec(ctx,
+		`INSERT INTO assignments (`+assignmentColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.ID, a.ClassID, a.ExerciseID, a.Title, a.OpensAt, a.DueAt, a.LatePolicy, a.LatePenalty,
+		a.CreatedBy, a.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: create assignment: %w", err)
+	}
+	return nil
+}
+
+// UpdateAssignment saves an assignment's title, dates and late policy.
+func (db *DB) UpdateAssignment(ctx context.Context, a *model.Assignment) error {
+	result, err := db.exec(ctx,
+		`UPDATE assignments SET title = ?, opens_at = ?, due_at = ?, late_policy = ?, late_penalty = ?
+		 WHERE id = ?`,
+		a.Title, a.OpensAt, a.DueAt, a.LatePolicy, a.LatePenalty, a.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: update assignment: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperror.NotFound("assignment", a.ID)
+	}
+	return nil
+}
+
+const assignmentColumns = `id, class_id, exercise_id, title, opens_at, due_at, late_policy, late_penalty, created_by, created_at`
+
+// GetAssignment returns an assignment by ID.
+func (db *DB) GetAssignment(ctx context.Context, id string) (*model.Assignment, error) {
+	row := db.conn.QueryRowContext(ctx, `SELECT `+assignmentColumns+` FROM assignments WHERE id = ?`, id)
+	a, err := scanAssignment(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("assignment", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: get assignment: %w", err)
+	}
+	return a, nil
+}
+
+// ListAssignments returns a class's assignments, oldest first.
+func (db *DB) ListAssignments(ctx context.Context, classID string) ([]model.Assignment, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT `+assignmentColumns+` FROM assignments WHERE class_id = ? ORDER BY created_at, id`, classID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list assignments: %w", err)
+	}
+	defer rows.Close()
+
+	assignments := []model.Assignment{}
+	for rows.Next() {
+		a, err := scanAssignment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("sqlite: scan assignment: %w", err)
+		}
+		assignments = append(assignments, *a)
+	}
+	return assignments, rows.Err()
+}
+
+func scanAssignment(row interface{ Scan(...any) error }) (*model.Assignment, error) {
+	var a model.Assignment
+	var opensAt, dueAt sql.NullTime
+	err := row.Scan(&a.ID, &a.ClassID, &a.ExerciseID, &a.Title, &opensAt, &dueAt,
+		&a.LatePolicy, &a.LatePenalty, &a.CreatedBy, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if opensAt.Valid {
+		a.OpensAt = &opensAt.Time
+	}
+	if dueAt.Valid {
+		a.DueAt = &dueAt.Time
+	}
+	return &a, nil
+}
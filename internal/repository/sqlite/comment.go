@@ -0,0 +1,95 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+var _ repository.CommentRepository = (*DB)(nil)
+
+// commentQuery selects comments with their author's login, in the order
+// scanComment reads them.
+const commentQuery = `SELECT c.id, c.snippet_id, c.user_id, COALESCE(u.login, ''), c.body,
+		c.version, c.line_start, c.line_end, c.created_at
+	FROM comments c LEFT JOIN users u ON u.id = c.user_id`
+
+func scanComment(row interface{ Scan(...any) error }) (*model.Comment, error) {
+	var c model.Comment
+	err := row.Scan(&c.ID, &c.SnippetID, &c.UserID, &c.Login, &c.Body,
+		&c.Version, &c.LineStart, &c.LineEnd, &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// CreateComment saves a new comment.
+func (db *DB) CreateComment(ctx context.Context, c *model.Comment) error {
+	c.ID = xid.New().String()
+	c.CreatedAt = time.Now().UTC()
+	_, err := db.exec(ctx,
+		`INSERT INTO comments (id, snippet_id, user_id, body, version, line_start, line_end, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.ID, c.SnippetID, c.UserID, c.Body, c.Version, c.LineStart, c.LineEnd, c.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: create comment: %w", err)
+	}
+	return nil
+}
+
+// GetComment returns a comment by ID.
+func (db *DB) GetComment(ctx context.Context, id string) (*model.Comment, error) {
+	c, err := scanComment(db.conn.QueryRowContext(ctx, commentQuery+` WHERE c.id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("comment", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: get comment: %w", err)
+	}
+	return c, nil
+}
+
+// ListComments returns a snippet's comments, oldest first.
+func (db *DB) ListComments(ctx context.Context, snippetID string) ([]model.Comment, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		commentQuery+` WHERE c.snippet_id = ? ORDER BY c.created_at, c.id`, snippetID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []model.Comment{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("sqlite: scan comment: %w", err)
+		}
+		comments = append(comments, *c)
+	}
+	return comments, rows.Err()
+}
+
+// DeleteComment removes a comment.
+func (db *DB) DeleteComment(ctx context.Context, id string) error {
+	result, err := db.exec(ctx, `DELETE FROM comments WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("sqlite: delete comment: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("sqlite: checking rows affected: %w", err)
+	} else if n == 0 {
+		return apperror.NotFound("comment", id)
+	}
+	return nil
+}
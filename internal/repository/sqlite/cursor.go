@@ -0,0 +1,73 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"iter"
+
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+var _ repository.SnippetCursor = (*DB)(nil)
+
+// cursorBatch is how many snippets EachSnippet reads per query.
+const cursorBatch = 200
+
+// EachSnippet pages through the matching snippets by ID (keyset
+// pagination: WHERE id > last ORDER BY id), which the primary key index
+// answers directly however deep into the table it gets — unlike OFFSET,
+// which rereads every row it skips.
+//
+// Each batch is a query of its own, closed before anything is yielded: a
+// slow consumer, like a client downloading an export, never holds a read
+// transaction open on the database.
+func (db *DB) EachSnippet(ctx context.Context, opts repository.ListOptions) iter.Seq2[*model.Snippet, error] {
+	return func(yield func(*model.Snippet, error) bool) {
+		where, args := snippetFilter(opts)
+		if where == "" {
+			where = " WHERE id > ?"
+		} else {
+			where += " AND id > ?"
+		}
+		code := "code"
+		if opts.OmitCode {
+			code = "'' AS code"
+		}
+
+		after := ""
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			rows, err := db.conn.QueryContext(ctx,
+				`SELECT id, name, `+code+`, description, COALESCE(user_id, ''), public, published_at, created_at, updated_at, version, forked_from, org_id, requirements
+				 FROM snippets`+where+`
+				 ORDER BY id
+				 LIMIT ?`,
+				append(args, after, cursorBatch)...,
+			)
+			if err != nil {
+				yield(nil, fmt.Errorf("sqlite: reading snippets after %q: %w", after, err))
+				return
+			}
+			batch, err := scanSnippets(rows, cursorBatch)
+			rows.Close()
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for i := range batch {
+				if !yield(&batch[i], nil) {
+					return
+				}
+			}
+			if len(batch) < cursorBatch {
+				return
+			}
+			after = batch[len(batch)-1].ID
+		}
+	}
+}
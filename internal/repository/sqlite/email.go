@@ -0,0 +1,109 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+var _ repository.EmailRepository = (*DB)(nil)
+
+// GetEmailPreferences returns a user's email preferences, or the defaults if
+// they never saved any.
+func (db *DB) GetEmailPreferences(ctx context.Context, userID string) (*model.EmailPreferences, error) {
+	prefs := model.DefaultEmailPreferences(userID)
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT grading, digest FROM email_preferences WHERE user_id = ?`, userID,
+	).Scan(&prefs.Grading, &prefs.Digest)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("sqlite: get email preferences: %w", err)
+	}
+	return prefs, nil
+}
+
+// SaveEmailPreferences creates or replaces a user's email preferences.
+func (db *DB) SaveEmailPreferences(ctx context.Context, prefs *model.EmailPreferences) error {
+	_, err := db.exec(ctx,
+		`INSERT INTO email_preferences (user_id, grading, digest, updated_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(user_id) DO UPDATE SET
+		     grading    = excluded.grading,
+		     digest     = excluded.digest,
+		     updated_at = excluded.updated_at`,
+		prefs.UserID, prefs.Grading, prefs.Digest, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: save email preferences: %w", err)
+	}
+	return nil
+}
+
+// ListDigestRecipients returns users with an email address whose digest is
+// on — explicitly, or by default because they never saved preferences.
+func (db *DB) ListDigestRecipients(ctx context.Context) ([]model.User, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT u.id, u.github_id, u.login, u.email, u.avatar_url, u.role, u.leaderboard_opt_out, u.suspended, u.created_at, u.updated_at
+		 FROM users u LEFT JOIN email_preferences p ON p.user_id = u.id
+		 WHERE u.email != '' AND COALESCE(p.digest, ?)
+		 ORDER BY u.created_at`,
+		model.DefaultEmailPreferences("").Digest,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list digest recipients: %w", err)
+	}
+	defer rows.Close()
+
+	var users []model.User
+	for rows.Next() {
+		var u model.User
+		if err := rows.Scan(&u.ID, &u.GitHubID, &u.Login, &u.Email,
+			&u.AvatarURL, &u.Role, &u.LeaderboardOptOut, &u.Suspended, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("sqlite: scan digest recipient: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// CreateMagicLink saves a sign-in link. Expired links are cleared out at
+// the same time; nothing else would.
+func (db *DB) CreateMagicLink(ctx context.Context, tokenHash, userID string, expiresAt time.Time) error {
+	if _, err := db.exec(ctx,
+		`DELETE FROM magic_links WHERE expires_at <= ?`, time.Now().Unix(),
+	); err != nil {
+		return fmt.Errorf("sqlite: clearing expired sign-in links: %w", err)
+	}
+	_, err := db.exec(ctx,
+		`INSERT INTO magic_links (token_hash, user_id, expires_at) VALUES (?, ?, ?)`,
+		tokenHash, userID, expiresAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: create sign-in link: %w", err)
+	}
+	return nil
+}
+
+// ConsumeMagicLink marks a sign-in link used and returns its user. The
+// check and the update are one statement, so a link clicked twice at once
+// still signs in only once.
+func (db *DB) ConsumeMagicLink(ctx context.Context, tokenHash string) (string, error) {
+	var userID string
+	err := db.conn.QueryRowContext(ctx,
+		`UPDATE magic_links SET used_at = ?
+		 WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?
+		 RETURNING user_id`,
+		time.Now().UTC(), tokenHash, time.Now().Unix(),
+	).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", &apperror.AppError{Err: apperror.ErrNotFound, Message: "sign-in link is invalid, used or expired"}
+	}
+	if err != nil {
+		return "", fmt.Errorf("sqlite: consume sign-in link: %w", err)
+	}
+	return userID, nil
+}
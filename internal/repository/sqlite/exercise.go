@@ -0,0 +1,156 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+var _ repository.ExerciseRepository = (*DB)(nil)
+
+const exerciseColumns = `id, title, prompt, starter_code, test_code, hints, hint_penalty, coverage, author_id, created_at, updated_at`
+
+// CreateExercise saves a new exercise.
+func (db *DB) CreateExercise(ctx context.Context, exercise *model.Exercise) error {
+	exercise.ID = xid.New().String()
+	exercise.CreatedAt = time.Now().UTC()
+	exercise.UpdatedAt = exercise.CreatedAt
+
+	hints, err := encodeHints(exercise)
+	if err != nil {
+		return err
+	}
+	_, err = db.exec(ctx,
+		`INSERT INTO exercises (`+exerciseColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		exercise.ID, exercise.Title, exercise.Prompt, exercise.StarterCode, exercise.TestCode,
+		hints, exercise.HintPenalty, exercise.Coverage, exercise.AuthorID, exercise.CreatedAt, exercise.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: create exercise: %w", err)
+	}
+	return nil
+}
+
+// GetExercise returns an exercise by ID.
+func (db *DB) GetExercise(ctx context.Context, id string) (*model.Exercise, error) {
+	row := db.conn.QueryRowContext(ctx,
+		`SELECT `+exerciseColumns+` FROM exercises WHERE id = ?`, id,
+	)
+	exercise, err := scanExercise(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("exercise", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: get exercise: %w", err)
+	}
+	return exercise, nil
+}
+
+// ListExercises returns a page of exercises, newest first.
+func (db *DB) ListExercises(ctx context.Context, limit, offset int) ([]model.Exercise, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT `+exerciseColumns+` FROM exercises
+		 ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list exercises: %w", err)
+	}
+	defer rows.Close()
+
+	exercises := []model.Exercise{}
+	for rows.Next() {
+		exercise, err := scanExercise(rows)
+		if err != nil {
+			return nil, fmt.Errorf("sqlite: scan exercise: %w", err)
+		}
+		exercises = append(exercises, *exercise)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite: list exercises: %w", err)
+	}
+	return exercises, nil
+}
+
+// CountExercises returns how many exercises there are.
+func (db *DB) CountExercises(ctx context.Context) (int, error) {
+	var n int
+	if err := db.conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM exercises`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("sqlite: count exercises: %w", err)
+	}
+	return n, nil
+}
+
+// UpdateExercise saves an exercise's title, prompt, code, hints and
+// settings. The
+// author and CreatedAt never change.
+func (db *DB) UpdateExercise(ctx context.Context, exercise *model.Exercise) error {
+	exercise.UpdatedAt = time.Now().UTC()
+
+	hints, err := encodeHints(exercise)
+	if err != nil {
+		return err
+	}
+	result, err := db.exec(ctx,
+		`UPDATE exercises SET title = ?, prompt = ?, starter_code = ?, test_code = ?,
+		     hints = ?, hint_penalty = ?, coverage = ?, updated_at = ?
+		 WHERE id = ?`,
+		exercise.Title, exercise.Prompt, exercise.StarterCode, exercise.TestCode,
+		hints, exercise.HintPenalty, exercise.Coverage, exercise.UpdatedAt, exercise.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: update exercise: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperror.NotFound("exercise", exercise.ID)
+	}
+	return nil
+}
+
+// DeleteExercise removes an exercise.
+func (db *DB) DeleteExercise(ctx context.Context, id string) error {
+	result, err := db.exec(ctx, `DELETE FROM exercises WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("sqlite: delete exercise: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperror.NotFound("exercise", id)
+	}
+	return nil
+}
+
+func scanExercise(row interface{ Scan(...any) error }) (*model.Exercise, error) {
+	var e model.Exercise
+	var hints string
+	err := row.Scan(&e.ID, &e.Title, &e.Prompt, &e.StarterCode, &e.TestCode,
+		&hints, &e.HintPenalty, &e.Coverage, &e.AuthorID, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(hints), &e.Hints); err != nil {
+		return nil, fmt.Errorf("decode hints: %w", err)
+	}
+	e.HintCount = len(e.Hints)
+	return &e, nil
+}
+
+// encodeHints returns the exercise's hints as a JSON array ("[]" for none).
+func encodeHints(exercise *model.Exercise) (string, error) {
+	hints := exercise.Hints
+	if hints == nil {
+		hints = []string{}
+	}
+	b, err := json.Marshal(hints)
+	if err != nil {
+		return "", fmt.Errorf("sqlite: encode exercise hints: %w", err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,49 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/feature"
+)
+
+var _ feature.Store = (*DB)(nil)
+
+// LoadFeatureFlags returns every persisted admin toggle keyed by flag name.
+func (db *DB) LoadFeatureFlags(ctx context.Context) (map[string]bool, error) {
+	rows, err := db.conn.QueryContext(ctx, `SELECT name, enabled FROM feature_flags`)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: loading feature flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		var enabled bool
+		if err := rows.Scan(&name, &enabled); err != nil {
+			return nil, fmt.Errorf("sqlite: scanning feature flag: %w", err)
+		}
+		flags[name] = enabled
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite: iterating feature flags: %w", err)
+	}
+	return flags, nil
+}
+
+// SaveFeatureFlag stores (or overwrites) an admin toggle.
+func (db *DB) SaveFeatureFlag(ctx context.Context, name string, enabled bool) error {
+	_, err := db.exec(ctx,
+		`INSERT INTO feature_flags (name, enabled, updated_at) VALUES (?, ?, ?)
+		 ON CONFLICT(name) DO UPDATE SET
+		     enabled    = excluded.enabled,
+		     updated_at = excluded.updated_at`,
+		name, enabled, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: saving feature flag %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+var _ repository.HintRepository = (*DB)(nil)
+
+// HintsUnlocked returns how many of an exercise's hints userID has unlocked.
+func (db *DB) HintsUnlocked(ctx context.Context, exerciseID, userID string) (int, error) {
+	var n int
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT unlocked FROM hint_unlocks WHERE exercise_id = ? AND user_id = ?`, exerciseID, userID,
+	).Scan(&n)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("sqlite: hints unlocked: %w", err)
+	}
+	return n, nil
+}
+
+// UnlockHint bumps userID's unlocked count, capped at total.
+//
+// The cap is in the UPDATE's WHERE clause rather than a read-then-write in
+// Go, so two quick clicks can't both slip under it.
+func (db *DB) UnlockHint(ctx context.Context, exerciseID, userID string, total int) (int, error) {
+	_, err := db.exec(ctx,
+		`INSERT INTO hint_unlocks (exercise_id, user_id, unlocked, updated_at) VALUES (?, ?, 1, ?)
+		 ON CONFLICT (exercise_id, user_id) DO UPDATE SET
+		     unlocked   = unlocked + 1,
+		     updated_at = excluded.updated_at
+		 WHERE unlocked < ?`,
+		exerciseID, userID, time.Now().UTC(), total,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("sqlite: unlock hint: %w", err)
+	}
+	return db.HintsUnlocked(ctx, exerciseID, userID)
+}
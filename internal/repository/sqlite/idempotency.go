@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/idempotency"
+)
+
+var _ idempotency.Store = (*DB)(nil)
+
+// ReserveIdempotencyKey inserts a placeholder for rec unless an unexpired
+// record already holds its scope and key, in which case that record is
+// returned. INSERT OR IGNORE makes the check-and-claim a single statement, so
+// two concurrent requests with the same key can't both win.
+func (db *DB) ReserveIdempotencyKey(ctx context.Context, rec idempotency.Record) (*idempotency.Record, bool, error) {
+	now := time.Now().Unix()
+
+	// An expired record no longer counts; clear it so the key can be reused.
+	if _, err := db.exec(ctx,
+		`DELETE FROM idempotency_keys WHERE scope = ? AND key = ? AND expires_at <= ?`,
+		rec.Scope, rec.Key, now,
+	); err != nil {
+		return nil, false, fmt.Errorf("sqlite: clearing expired idempotency key: %w", err)
+	}
+
+	res, err := db.exec(ctx,
+		`INSERT OR IGNORE INTO idempotency_keys (scope, key, request_hash, expires_at)
+		 VALUES (?, ?, ?, ?)`,
+		rec.Scope, rec.Key, rec.RequestHash, rec.ExpiresAt.Unix(),
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("sqlite: reserving idempotency key: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, false, fmt.Errorf("sqlite: reserving idempotency key: %w", err)
+	} else if n == 1 {
+		return nil, true, nil
+	}
+
+	existing := idempotency.Record{Scope: rec.Scope, Key: rec.Key}
+	var expiresAt int64
+	err = db.conn.QueryRowContext(ctx,
+		`SELECT request_hash, status, content_type, body, expires_at
+		 FROM idempotency_keys WHERE scope = ? AND key = ?`,
+		rec.Scope, rec.Key,
+	).Scan(&existing.RequestHash, &existing.Status, &existing.ContentType, &existing.Body, &expiresAt)
+	if err != nil {
+		return nil, false, fmt.Errorf("sqlite: loading idempotency key: %w", err)
+	}
+	existing.ExpiresAt = time.Unix(expiresAt, 0)
+	return &existing, false, nil
+}
+
+// CompleteIdempotencyKey stores the response of the request holding the key.
+func (db *DB) CompleteIdempotencyKey(ctx context.Context, scope, key string, status int, contentType string, body []byte) error {
+	_, err := db.exec(ctx,
+		`UPDATE idempotency_keys SET status = ?, content_type = ?, body = ?
+		 WHERE scope = ? AND key = ?`,
+		status, contentType, body, scope, key,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: completing idempotency key: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey deletes a key so a retry runs the request again.
+func (db *DB) ReleaseIdempotencyKey(ctx context.Context, scope, key string) error {
+	_, err := db.exec(ctx,
+		`DELETE FROM idempotency_keys WHERE scope = ? AND key = ?`, scope, key,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: releasing idempotency key: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpiredIdempotencyKeys deletes expired keys and returns how many.
+func (db *DB) PurgeExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	res, err := db.exec(ctx,
+		`DELETE FROM idempotency_keys WHERE expires_at <= ?`, time.Now().Unix(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("sqlite: purging idempotency keys: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,131 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/sakif/coding-playground/internal/jobs"
+)
+
+var _ jobs.Store = (*DB)(nil)
+
+// EnqueueJob inserts a new pending job.
+func (db *DB) EnqueueJob(ctx context.Context, job *jobs.Job) error {
+	if job.ID == "" {
+		job.ID = xid.New().String()
+	}
+	now := time.Now().UTC()
+	job.CreatedAt, job.UpdatedAt = now, now
+
+	_, err := db.exec(ctx,
+		`INSERT INTO jobs (id, kind, payload, status, attempts, max_attempts, run_at, last_error, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, 0, ?, ?, '', ?, ?)`,
+		job.ID, job.Kind, string(job.Payload), jobs.StatusPending, job.MaxAttempts,
+		job.RunAt.UTC(), now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: enqueue job: %w", err)
+	}
+	return nil
+}
+
+// ClaimJob marks the oldest due pending job as running and returns it.
+//
+// ATOMIC CLAIM:
+// The UPDATE picks its row with a subquery and flips the status in a single
+// statement, so two workers can never claim the same job. RETURNING hands the
+// claimed row back without a second query.
+func (db *DB) ClaimJob(ctx context.Context, now time.Time) (*jobs.Job, error) {
+	row := db.conn.QueryRowContext(ctx,
+		`UPDATE jobs SET status = ?, attempts = attempts + 1, updated_at = ?
+		 WHERE id = (
+		     SELECT id FROM jobs
+		     WHERE status = ? AND run_at <= ?
+		     ORDER BY run_at
+		     LIMIT 1
+		 )
+		 RETURNING id, kind, payload, status, attempts, max_attempts, run_at, last_error, created_at, updated_at`,
+		jobs.StatusRunning, now.UTC(), jobs.StatusPending, now.UTC(),
+	)
+
+	var job jobs.Job
+	var payload string
+	err := row.Scan(
+		&job.ID, &job.Kind, &payload, &job.Status, &job.Attempts, &job.MaxAttempts,
+		&job.RunAt, &job.LastError, &job.CreatedAt, &job.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: claim job: %w", err)
+	}
+	job.Payload = []byte(payload)
+	return &job, nil
+}
+
+// CompleteJob deletes a finished job — successful jobs aren't kept.
+func (db *DB) CompleteJob(ctx context.Context, id string) error {
+	if _, err := db.exec(ctx, `DELETE FROM jobs WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("sqlite: complete job: %w", err)
+	}
+	return nil
+}
+
+// RetryJob puts a job back to pending, to run again at runAt.
+func (db *DB) RetryJob(ctx context.Context, id string, runAt time.Time, lastErr string) error {
+	_, err := db.exec(ctx,
+		`UPDATE jobs SET status = ?, run_at = ?, last_error = ?, updated_at = ? WHERE id = ?`,
+		jobs.StatusPending, runAt.UTC(), lastErr, time.Now().UTC(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: retry job: %w", err)
+	}
+	return nil
+}
+
+// FailJob marks a job as permanently failed.
+func (db *DB) FailJob(ctx context.Context, id string, lastErr string) error {
+	_, err := db.exec(ctx,
+		`UPDATE jobs SET status = ?, last_error = ?, updated_at = ? WHERE id = ?`,
+		jobs.StatusFailed, lastErr, time.Now().UTC(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: fail job: %w", err)
+	}
+	return nil
+}
+
+// ResetRunningJobs returns jobs left running by a crashed process to pending.
+func (db *DB) ResetRunningJobs(ctx context.Context) (int, error) {
+	res, err := db.exec(ctx,
+		`UPDATE jobs SET status = ?, updated_at = ? WHERE status = ?`,
+		jobs.StatusPending, time.Now().UTC(), jobs.StatusRunning,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("sqlite: reset running jobs: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("sqlite: reset running jobs: %w", err)
+	}
+	return int(n), nil
+}
+
+// CountDueJobs counts the pending jobs due to run at now. It's served by the
+// same (status, run_at) index ClaimJob uses.
+func (db *DB) CountDueJobs(ctx context.Context, now time.Time) (int, error) {
+	var n int
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM jobs WHERE status = ? AND run_at <= ?`,
+		jobs.StatusPending, now.UTC(),
+	).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("sqlite: count due jobs: %w", err)
+	}
+	return n, nil
+}
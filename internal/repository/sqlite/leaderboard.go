@@ -0,0 +1,121 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+var _ repository.LeaderboardRepository = (*DB)(nil)
+
+// RefreshLeaderboard rebuilds leaderboard_entries from submissions.
+//
+// MATERIALIZING:
+// Ranking means finding each user's best attempt at each exercise — a scan
+// over every submission. Doing that on every page view would get slower as
+// submissions pile up, so a scheduled task does it once and stores the
+// answer; reads are then a cheap indexed lookup. The price is that boards
+// lag behind by up to one refresh interval.
+//
+// ROW_NUMBER() numbers each user's attempts at an exercise from best to
+// worst (score, then speed, then who got there first); row 1 is the keeper.
+// The DELETE and INSERT share a transaction, so readers never see a
+// half-built board.
+func (db *DB) RefreshLeaderboard(ctx context.Context) (int, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("sqlite: refresh leaderboard: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM leaderboard_entries`); err != nil {
+		return 0, fmt.Errorf("sqlite: clear leaderboard: %w", err)
+	}
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO leaderboard_entries
+		     (exercise_id, user_id, score, solved, duration_ms, submissions, achieved_at, refreshed_at)
+		 SELECT exercise_id, user_id, score, solved, duration_ms, attempts, created_at, ?
+		 FROM (
+		     SELECT s.exercise_id, s.user_id, s.score, s.duration_ms, s.created_at,
+		            ROW_NUMBER() OVER attempts AS n,
+		            COUNT(*) OVER (PARTITION BY s.exercise_id, s.user_id) AS attempts,
+		            MAX(s.status = 'passed') OVER (PARTITION BY s.exercise_id, s.user_id) AS solved
+		     FROM submissions s
+		     JOIN users u ON u.id = s.user_id
+		     WHERE u.leaderboard_opt_out = 0
+		     WINDOW attempts AS (PARTITION BY s.exercise_id, s.user_id
+		                         ORDER BY s.score DESC, s.duration_ms, s.created_at)
+		 )
+		 WHERE n = 1 AND score > 0`,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("sqlite: fill leaderboard: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("sqlite: fill leaderboard: %w", err)
+	}
+	return int(n), tx.Commit()
+}
+
+// LeaderboardRefreshedAt returns when the entries were materialized. Every
+// entry carries the same time, so any row will do.
+func (db *DB) LeaderboardRefreshedAt(ctx context.Context) (*time.Time, error) {
+	var t time.Time
+	err := db.conn.QueryRowContext(ctx, `SELECT refreshed_at FROM leaderboard_entries LIMIT 1`).Scan(&t)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: leaderboard refreshed at: %w", err)
+	}
+	return &t, nil
+}
+
+// ListLeaderboardEntries returns per-exercise entries, best first. Users who
+// opted out since the last refresh are left out here too, so opting out
+// takes effect at once.
+func (db *DB) ListLeaderboardEntries(ctx context.Context, f repository.LeaderboardFilter, limit int) ([]model.LeaderboardEntry, error) {
+	query := `SELECT e.user_id, u.login, u.avatar_url, e.score, e.solved, e.duration_ms, e.submissions, e.achieved_at
+	          FROM leaderboard_entries e
+	          JOIN users u ON u.id = e.user_id
+	          WHERE u.leaderboard_opt_out = 0`
+	var args []any
+	if f.ExerciseID != "" {
+		query += ` AND e.exercise_id = ?`
+		args = append(args, f.ExerciseID)
+	}
+	if f.ClassID != "" {
+		query += ` AND e.exercise_id IN (SELECT exercise_id FROM assignments WHERE class_id = ?)
+		           AND e.user_id IN (SELECT user_id FROM class_members WHERE class_id = ? AND role = 'student')`
+		args = append(args, f.ClassID, f.ClassID)
+	}
+	query += ` ORDER BY e.score DESC, e.duration_ms, e.achieved_at`
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list leaderboard: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []model.LeaderboardEntry{}
+	for rows.Next() {
+		var e model.LeaderboardEntry
+		if err := rows.Scan(&e.UserID, &e.Login, &e.AvatarURL, &e.Score, &e.Solved,
+			&e.DurationMS, &e.Submissions, &e.AchievedAt); err != nil {
+			return nil, fmt.Errorf("sqlite: scan leaderboard entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,73 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/executor"
+)
+
+var _ executor.LeaseStore = (*DB)(nil)
+
+// AcquireLease takes a lease if fewer than limit unexpired ones of its kind
+// are held.
+//
+// ATOMIC CLAIM:
+// The count and the insert are one statement, and SQLite runs one writer at
+// a time, so two servers can't both see the last free lease and take it.
+// An id that's already held is renewed by the ON CONFLICT clause, as long as
+// the count leaves room for it — which it always does, since it's counted.
+func (db *DB) AcquireLease(ctx context.Context, kind, id string, limit int, ttl time.Duration) (bool, error) {
+	now := time.Now().UTC()
+	if _, err := db.exec(ctx,
+		`DELETE FROM pool_leases WHERE kind = ? AND expires_at <= ?`, kind, now,
+	); err != nil {
+		return false, fmt.Errorf("sqlite: acquire lease: %w", err)
+	}
+	res, err := db.exec(ctx,
+		`INSERT INTO pool_leases (kind, id, expires_at)
+		 SELECT ?, ?, ?
+		 WHERE (SELECT COUNT(*) FROM pool_leases WHERE kind = ? AND id <> ? AND expires_at > ?) < ?
+		 ON CONFLICT (kind, id) DO UPDATE SET expires_at = excluded.expires_at`,
+		kind, id, now.Add(ttl), kind, id, now, limit,
+	)
+	if err != nil {
+		return false, fmt.Errorf("sqlite: acquire lease: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("sqlite: acquire lease: %w", err)
+	}
+	return n == 1, nil
+}
+
+// RenewLeases pushes back the expiry of the unexpired leases in ids.
+func (db *DB) RenewLeases(ctx context.Context, kind string, ids []string, ttl time.Duration) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	args := []any{now.Add(ttl), kind, now}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	_, err := db.exec(ctx,
+		`UPDATE pool_leases SET expires_at = ?
+		 WHERE kind = ? AND expires_at > ? AND id IN (?`+strings.Repeat(", ?", len(ids)-1)+`)`,
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: renew leases: %w", err)
+	}
+	return nil
+}
+
+// ReleaseLease deletes a lease.
+func (db *DB) ReleaseLease(ctx context.Context, kind, id string) error {
+	if _, err := db.exec(ctx, `DELETE FROM pool_leases WHERE kind = ? AND id = ?`, kind, id); err != nil {
+		return fmt.Errorf("sqlite: release lease: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// PurgeAnonymousSnippets deletes snippets without an owner that were last
+// updated before cutoff. It returns the number of snippets deleted.
+func (db *DB) PurgeAnonymousSnippets(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := db.exec(ctx,
+		`DELETE FROM snippets WHERE user_id IS NULL AND updated_at < ?`, cutoff,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("sqlite: purge anonymous snippets: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// PurgeRuns applies a retention policy to the run history: anonymous runs
+// (those without a user) or signed-in users' runs, as anonymous says. Runs
+// created before cutoff are deleted — none by age if cutoff is zero — and
+// then all but the newest keep of each user's, or of the anonymous runs
+// together (0 keeps them all). It returns the number of runs deleted.
+func (db *DB) PurgeRuns(ctx context.Context, anonymous bool, cutoff time.Time, keep int) (int64, error) {
+	owner := `user_id != ''`
+	if anonymous {
+		owner = `user_id = ''`
+	}
+
+	var purged int64
+	if !cutoff.IsZero() {
+		res, err := db.exec(ctx, `DELETE FROM runs WHERE `+owner+` AND created_at < ?`, cutoff)
+		if err != nil {
+			return 0, fmt.Errorf("sqlite: purge old runs: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		purged += n
+	}
+	if keep > 0 {
+		res, err := db.exec(ctx,
+			`DELETE FROM runs WHERE id IN (
+			     SELECT id FROM (
+			         SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_at DESC, id DESC) AS newest
+			         FROM runs WHERE `+owner+`
+			     ) WHERE newest > ?
+			 )`, keep)
+		if err != nil {
+			return purged, fmt.Errorf("sqlite: purge runs over the limit: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		purged += n
+	}
+	return purged, nil
+}
+
+var _ repository.StorageRepository = (*DB)(nil)
+
+// Checkpoint copies the write-ahead log back into the main database file and
+// truncates it.
+//
+// WHY CHECKPOINT?
+// In WAL mode, writes go to a separate -wal file first. SQLite checkpoints it
+// automatically, but only when no reader is holding it open — on a busy server
+// the file can grow large. A periodic TRUNCATE checkpoint keeps it small.
+//
+// A checkpoint that finds the WAL in use still copies what it can, but
+// reports Busy and leaves the file its size; that isn't an error.
+func (db *DB) Checkpoint(ctx context.Context) (*model.Checkpoint, error) {
+	start := time.Now()
+	var result model.Checkpoint
+	path, err := db.path(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result.WALBytesBefore = walSize(path)
+
+	// The pragma returns (busy, frames in the log, frames checkpointed).
+	var busy int
+	err = db.conn.QueryRowContext(ctx, `PRAGMA wal_checkpoint(TRUNCATE)`).
+		Scan(&busy, &result.LogFrames, &result.CheckpointedFrames)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: wal checkpoint: %w", err)
+	}
+	result.Busy = busy != 0
+	result.WALBytesAfter = walSize(path)
+	result.Duration = time.Since(start)
+	return &result, nil
+}
+
+// StorageStats reads the database's page counts from its pragmas and the
+// WAL's size from the file system.
+func (db *DB) StorageStats(ctx context.Context) (*model.StorageStats, error) {
+	var stats model.StorageStats
+	var err error
+	if stats.Path, err = db.path(ctx); err != nil {
+		return nil, err
+	}
+	for _, p := range []struct {
+		pragma string
+		dst    any
+	}{
+		{"journal_mode", &stats.JournalMode},
+		{"page_size", &stats.PageSize},
+		{"page_count", &stats.PageCount},
+		{"freelist_count", &stats.FreelistCount},
+	} {
+		if err := db.conn.QueryRowContext(ctx, `PRAGMA `+p.pragma).Scan(p.dst); err != nil {
+			return nil, fmt.Errorf("sqlite: reading %s: %w", p.pragma, err)
+		}
+	}
+	stats.SizeBytes = stats.PageSize * stats.PageCount
+	stats.WALBytes = walSize(stats.Path)
+	return &stats, nil
+}
+
+// path returns the main database's file, or "" if it's in memory.
+func (db *DB) path(ctx context.Context) (string, error) {
+	var path string
+	err := db.conn.QueryRowContext(ctx, `SELECT file FROM pragma_database_list WHERE name = 'main'`).Scan(&path)
+	if err != nil {
+		return "", fmt.Errorf("sqlite: reading database path: %w", err)
+	}
+	return path, nil
+}
+
+// walSize is the size of the WAL beside the database at path: 0 if there's
+// none (or no file at all).
+func walSize(path string) int64 {
+	if path == "" {
+		return 0
+	}
+	info, err := os.Stat(path + "-wal")
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// Backup writes a consistent copy of the database to path.
+//
+// VACUUM INTO produces a compacted snapshot while the database stays online —
+// unlike copying the file, it can't capture a half-written transaction.
+// The target file must not already exist.
+func (db *DB) Backup(ctx context.Context, path string) error {
+	if _, err := db.exec(ctx, `VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("sqlite: backup to %s: %w", path, err)
+	}
+	return nil
+}
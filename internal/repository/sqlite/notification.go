@@ -0,0 +1,95 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+var _ repository.NotificationRepository = (*DB)(nil)
+
+// CreateNotification saves a notification.
+func (db *DB) CreateNotification(ctx context.Context, n *model.Notification) error {
+	n.ID = xid.New().String()
+	n.CreatedAt = time.Now().UTC()
+	_, err := db.exec(ctx,
+		`INSERT INTO notifications (id, user_id, kind, actor_id, snippet_id, submission_id, message, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		n.ID, n.UserID, n.Kind, n.ActorID, n.SnippetID, n.SubmissionID, n.Message, n.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: create notification: %w", err)
+	}
+	return nil
+}
+
+// ListNotifications returns userID's newest notifications first.
+func (db *DB) ListNotifications(ctx context.Context, userID string, unreadOnly bool, limit int) ([]model.Notification, error) {
+	where := ` WHERE n.user_id = ?`
+	if unreadOnly {
+		where += ` AND n.read_at IS NULL`
+	}
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT n.id, n.user_id, n.kind, n.actor_id, COALESCE(u.login, ''), n.snippet_id,
+		        n.submission_id, n.message, n.read_at, n.created_at
+		 FROM notifications n LEFT JOIN users u ON u.id = n.actor_id`+where+`
+		 ORDER BY n.created_at DESC, n.id DESC
+		 LIMIT ?`,
+		userID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []model.Notification{}
+	for rows.Next() {
+		var n model.Notification
+		var readAt sql.NullTime
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Kind, &n.ActorID, &n.ActorLogin, &n.SnippetID,
+			&n.SubmissionID, &n.Message, &readAt, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("sqlite: scan notification: %w", err)
+		}
+		if readAt.Valid {
+			n.ReadAt = &readAt.Time
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// CountUnreadNotifications counts userID's unread notifications.
+func (db *DB) CountUnreadNotifications(ctx context.Context, userID string) (int, error) {
+	var n int
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read_at IS NULL`, userID,
+	).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("sqlite: count unread notifications: %w", err)
+	}
+	return n, nil
+}
+
+// MarkNotificationsRead marks userID's notifications with these IDs read,
+// or all of them when ids is empty.
+func (db *DB) MarkNotificationsRead(ctx context.Context, userID string, ids []string) error {
+	query := `UPDATE notifications SET read_at = ? WHERE user_id = ? AND read_at IS NULL`
+	args := []any{time.Now().UTC(), userID}
+	if len(ids) > 0 {
+		query += ` AND id IN (?` + strings.Repeat(`, ?`, len(ids)-1) + `)`
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+	if _, err := db.exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("sqlite: mark notifications read: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+var _ repository.OrgRepository = (*DB)(nil)
+
+// CreateOrg saves a new org and its creator's owner membership in one
+// transaction.
+func (db *DB) CreateOrg(ctx context.Context, org *model.Org) error {
+	org.ID = xid.New().String()
+	org.CreatedAt = time.Now().UTC()
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite: create org: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO orgs (id, name, created_by, created_at) VALUES (?, ?, ?, ?)`,
+		org.ID, org.Name, org.CreatedBy, org.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: create org: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO org_members (org_id, user_id, role, joined_at) VALUES (?, ?, ?, ?)`,
+		org.ID, org.CreatedBy, model.OrgRoleOwner, org.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: add org owner: %w", err)
+	}
+	return tx.Commit()
+}
+
+// GetOrg returns an org by ID.
+func (db *DB) GetOrg(ctx context.Context, id string) (*model.Org, error) {
+	var o model.Org
+	var snippetQuota, runQuota sql.NullInt64
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT id, name, created_by, created_at, snippet_quota, run_quota FROM orgs WHERE id = ?`, id,
+	).Scan(&o.ID, &o.Name, &o.CreatedBy, &o.CreatedAt, &snippetQuota, &runQuota)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("org", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: get org: %w", err)
+	}
+	if snippetQuota.Valid {
+		n := int(snippetQuota.Int64)
+		o.Quota.Snippets = &n
+	}
+	if runQuota.Valid {
+		n := int(runQuota.Int64)
+		o.Quota.RunsPerDay = &n
+	}
+	return &o, nil
+}
+
+// ListOrgsForUser returns the orgs userID belongs to, with their role.
+func (db *DB) ListOrgsForUser(ctx context.Context, userID string) ([]model.OrgMembership, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT o.id, o.name, o.created_by, o.created_at, m.role
+		 FROM orgs o JOIN org_members m ON m.org_id = o.id
+		 WHERE m.user_id = ? ORDER BY o.created_at DESC, o.id DESC`, userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list orgs: %w", err)
+	}
+	defer rows.Close()
+
+	orgs := []model.OrgMembership{}
+	for rows.Next() {
+		var o model.OrgMembership
+		if err := rows.Scan(&o.ID, &o.Name, &o.CreatedBy, &o.CreatedAt, &o.Role); err != nil {
+			return nil, fmt.Errorf("sqlite: scan org: %w", err)
+		}
+		orgs = append(orgs, o)
+	}
+	return orgs, rows.Err()
+}
+
+// DeleteOrg deletes an org, and its memberships by cascade, and turns its
+// snippets back into personal ones, all in one transaction.
+func (db *DB) DeleteOrg(ctx context.Context, id string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite: delete org: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE snippets SET org_id = '' WHERE org_id = ?`, id); err != nil {
+		return fmt.Errorf("sqlite: release org snippets: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM orgs WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("sqlite: delete org: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("sqlite: delete org: %w", err)
+	} else if n == 0 {
+		return apperror.NotFound("org", id)
+	}
+	return tx.Commit()
+}
+
+// AddOrgMember adds a user to an org.
+func (db *DB) AddOrgMember(ctx context.Context, m *model.OrgMember) error {
+	m.JoinedAt = time.Now().UTC()
+	_, err := db.exec(ctx,
+		`INSERT INTO org_members (org_id, user_id, role, joined_at) VALUES (?, ?, ?, ?)`,
+		m.OrgID, m.UserID, m.Role, m.JoinedAt,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return &apperror.AppError{Err: apperror.ErrConflict, Message: "already a member of this org"}
+		}
+		return fmt.Errorf("sqlite: add org member: %w", err)
+	}
+	return nil
+}
+
+const orgMemberQuery = `SELECT m.org_id, m.user_id, COALESCE(u.login, ''), m.role, m.joined_at
+	FROM org_members m LEFT JOIN users u ON u.id = m.user_id`
+
+// GetOrgMember returns userID's membership of an org.
+func (db *DB) GetOrgMember(ctx context.Context, orgID, userID string) (*model.OrgMember, error) {
+	var m model.OrgMember
+	err := db.conn.QueryRowContext(ctx,
+		orgMemberQuery+` WHERE m.org_id = ? AND m.user_id = ?`, orgID, userID,
+	).Scan(&m.OrgID, &m.UserID, &m.Login, &m.Role, &m.JoinedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("org member", userID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: get org member: %w", err)
+	}
+	return &m, nil
+}
+
+// ListOrgMembers returns an org's members: owners first, then everyone else
+// in the order they joined.
+func (db *DB) ListOrgMembers(ctx context.Context, orgID string) ([]model.OrgMember, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		orgMemberQuery+` WHERE m.org_id = ?
+		 ORDER BY m.role = 'owner' DESC, m.joined_at, m.user_id`, orgID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list org members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []model.OrgMember{}
+	for rows.Next() {
+		var m model.OrgMember
+		if err := rows.Scan(&m.OrgID, &m.UserID, &m.Login, &m.Role, &m.JoinedAt); err != nil {
+			return nil, fmt.Errorf("sqlite: scan org member: %w", err)
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// SetOrgMemberRole changes a member's role.
+func (db *DB) SetOrgMemberRole(ctx context.Context, orgID, userID, role string) error {
+	result, err := db.exec(ctx,
+		`UPDATE org_members SET role = ? WHERE org_id = ? AND user_id = ?`, role, orgID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: set org member role: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("sqlite: set org member role: %w", err)
+	} else if n == 0 {
+		return apperror.NotFound("org member", userID)
+	}
+	return nil
+}
+
+// RemoveOrgMember takes a user out of an org.
+func (db *DB) RemoveOrgMember(ctx context.Context, orgID, userID string) error {
+	result, err := db.exec(ctx,
+		`DELETE FROM org_members WHERE org_id = ? AND user_id = ?`, orgID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: remove org member: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("sqlite: remove org member: %w", err)
+	} else if n == 0 {
+		return apperror.NotFound("org member", userID)
+	}
+	return nil
+}
+
+// SetOrgQuota replaces an org's quota overrides; nil fields are stored as
+// NULL, meaning the default.
+func (db *DB) SetOrgQuota(ctx context.Context, orgID string, quota model.OrgQuota) error {
+	result, err := db.exec(ctx,
+		`UPDATE orgs SET snippet_quota = ?, run_quota = ? WHERE id = ?`,
+		quota.Snippets, quota.RunsPerDay, orgID,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: set org quota: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("sqlite: set org quota: %w", err)
+	} else if n == 0 {
+		return apperror.NotFound("org", orgID)
+	}
+	return nil
+}
+
+// AddOrgRun counts a run in a single statement, so two runs racing for the
+// last one of the day can't both get it: the upsert only touches the row
+// while it's under the limit, and RETURNING yields nothing otherwise.
+func (db *DB) AddOrgRun(ctx context.Context, orgID, day string, limit int) (bool, error) {
+	var runs int
+	err := db.conn.QueryRowContext(ctx,
+		`INSERT INTO org_runs (org_id, day, runs) VALUES (?1, ?2, 1)
+		 ON CONFLICT (org_id, day) DO UPDATE SET runs = runs + 1
+		 WHERE ?3 = 0 OR runs < ?3
+		 RETURNING runs`,
+		orgID, day, limit,
+	).Scan(&runs)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("sqlite: add org run: %w", err)
+	}
+	return true, nil
+}
+
+// CountOrgRuns returns an org's runs on day.
+func (db *DB) CountOrgRuns(ctx context.Context, orgID, day string) (int, error) {
+	var runs int
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(runs), 0) FROM org_runs WHERE org_id = ? AND day = ?`, orgID, day,
+	).Scan(&runs)
+	if err != nil {
+		return 0, fmt.Errorf("sqlite: count org runs: %w", err)
+	}
+	return runs, nil
+}
@@ -0,0 +1,144 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+var _ repository.OrgInviteRepository = (*DB)(nil)
+
+// CreateOrgInvite saves a new invite under the hash of its token.
+func (db *DB) CreateOrgInvite(ctx context.Context, invite *model.OrgInvite, tokenHash string) error {
+	invite.ID = xid.New().String()
+	invite.CreatedAt = time.Now().UTC()
+	_, err := db.exec(ctx,
+		`INSERT INTO org_invites (id, org_id, token_hash, role, email, created_by, created_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		invite.ID, invite.OrgID, tokenHash, invite.Role, invite.Email, invite.CreatedBy, invite.CreatedAt, invite.ExpiresAt.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: create org invite: %w", err)
+	}
+	return nil
+}
+
+// orgInviteQuery selects pending invites; callers add "AND …" conditions
+// and pass the current Unix time first.
+const orgInviteQuery = `SELECT i.id, i.org_id, o.name, i.role, i.email, i.created_by, i.created_at, i.expires_at
+	FROM org_invites i JOIN orgs o ON o.id = i.org_id
+	WHERE i.accepted_at IS NULL AND i.expires_at > ?`
+
+// scanOrgInvite reads one row of orgInviteQuery.
+func scanOrgInvite(row interface{ Scan(...any) error }) (*model.OrgInvite, error) {
+	var inv model.OrgInvite
+	var expiresAt int64
+	if err := row.Scan(&inv.ID, &inv.OrgID, &inv.OrgName, &inv.Role, &inv.Email, &inv.CreatedBy, &inv.CreatedAt, &expiresAt); err != nil {
+		return nil, err
+	}
+	inv.ExpiresAt = time.Unix(expiresAt, 0).UTC()
+	return &inv, nil
+}
+
+// ListOrgInvites returns an org's pending invites, newest first.
+func (db *DB) ListOrgInvites(ctx context.Context, orgID string) ([]model.OrgInvite, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		orgInviteQuery+` AND i.org_id = ? ORDER BY i.created_at DESC, i.id DESC`,
+		time.Now().Unix(), orgID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list org invites: %w", err)
+	}
+	defer rows.Close()
+
+	invites := []model.OrgInvite{}
+	for rows.Next() {
+		inv, err := scanOrgInvite(rows)
+		if err != nil {
+			return nil, fmt.Errorf("sqlite: scan org invite: %w", err)
+		}
+		invites = append(invites, *inv)
+	}
+	return invites, rows.Err()
+}
+
+// GetOrgInviteByToken returns the pending invite with this token hash.
+func (db *DB) GetOrgInviteByToken(ctx context.Context, tokenHash string) (*model.OrgInvite, error) {
+	inv, err := scanOrgInvite(db.conn.QueryRowContext(ctx,
+		orgInviteQuery+` AND i.token_hash = ?`, time.Now().Unix(), tokenHash,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errInviteNotPending
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: get org invite: %w", err)
+	}
+	return inv, nil
+}
+
+var errInviteNotPending = &apperror.AppError{Err: apperror.ErrNotFound, Message: "invite is invalid, used or expired"}
+
+// DeleteOrgInvite deletes one of an org's pending invites.
+func (db *DB) DeleteOrgInvite(ctx context.Context, orgID, id string) error {
+	result, err := db.exec(ctx,
+		`DELETE FROM org_invites WHERE id = ? AND org_id = ? AND accepted_at IS NULL AND expires_at > ?`,
+		id, orgID, time.Now().Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: delete org invite: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("sqlite: delete org invite: %w", err)
+	} else if n == 0 {
+		return apperror.NotFound("invite", id)
+	}
+	return nil
+}
+
+// AcceptOrgInvite marks a pending invite used and adds the member in one
+// transaction, so an invite can't be used twice.
+func (db *DB) AcceptOrgInvite(ctx context.Context, tokenHash, userID string) (*model.OrgMember, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: accept org invite: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	member := &model.OrgMember{UserID: userID, JoinedAt: now}
+	err = tx.QueryRowContext(ctx,
+		`UPDATE org_invites SET accepted_by = ?, accepted_at = ?
+		 WHERE token_hash = ? AND accepted_at IS NULL AND expires_at > ?
+		 RETURNING org_id, role`,
+		userID, now, tokenHash, now.Unix(),
+	).Scan(&member.OrgID, &member.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errInviteNotPending
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: accept org invite: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO org_members (org_id, user_id, role, joined_at) VALUES (?, ?, ?, ?)`,
+		member.OrgID, member.UserID, member.Role, member.JoinedAt,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, &apperror.AppError{Err: apperror.ErrConflict, Message: "already a member of this org"}
+		}
+		return nil, fmt.Errorf("sqlite: add org member: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("sqlite: accept org invite: %w", err)
+	}
+	return member, nil
+}
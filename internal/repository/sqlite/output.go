@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// STORED RUN OUTPUT:
+// What a program prints is by far the bulkiest thing saved with a run, and
+// it's mostly repetitive text that compresses five- to tenfold. So output
+// over compressMinBytes is stored gzipped, as a BLOB, with the row's
+// output_encoding set to "gzip". Smaller output isn't worth the CPU and is
+// stored as it is, as is every row written before compression — so reads
+// go by output_encoding, not by guessing. Callers never see the difference:
+// decodeOutput hands back the text.
+//
+// Output is also capped at maxStoredOutput bytes before it's stored, with
+// outputTruncated on the end, so a program printing in a loop can't
+// balloon the database however it's run.
+
+const (
+	maxStoredOutput    = 64 << 10 // 64 KB of text per run
+	compressMinBytes   = 512
+	outputEncodingGzip = "gzip"
+)
+
+// outputTruncated marks output cut short by maxStoredOutput.
+const outputTruncated = "\n… output truncated"
+
+// encodeOutput returns the output to store and its encoding.
+func encodeOutput(output string) (stored any, encoding string, err error) {
+	output = capOutput(output)
+	if len(output) < compressMinBytes {
+		return output, "", nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(zw, output); err != nil {
+		return nil, "", fmt.Errorf("compressing output: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, "", fmt.Errorf("compressing output: %w", err)
+	}
+	return buf.Bytes(), outputEncodingGzip, nil
+}
+
+// decodeOutput turns stored output back into text.
+func decodeOutput(stored []byte, encoding string) (string, error) {
+	switch encoding {
+	case "":
+		return string(stored), nil
+	case outputEncodingGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(stored))
+		if err != nil {
+			return "", fmt.Errorf("decompressing output: %w", err)
+		}
+		defer zr.Close()
+		text, err := io.ReadAll(io.LimitReader(zr, maxStoredOutput))
+		if err != nil {
+			return "", fmt.Errorf("decompressing output: %w", err)
+		}
+		return string(text), nil
+	}
+	return "", fmt.Errorf("unknown output encoding %q", encoding)
+}
+
+// capOutput cuts output to maxStoredOutput bytes, on a character boundary,
+// marking the cut.
+func capOutput(output string) string {
+	if len(output) <= maxStoredOutput {
+		return output
+	}
+	cut := maxStoredOutput - len(outputTruncated)
+	for cut > 0 && !utf8.RuneStart(output[cut]) {
+		cut--
+	}
+	return output[:cut] + outputTruncated
+}
@@ -0,0 +1,50 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	sqlitedriver "modernc.org/sqlite"
+	sqlitelib "modernc.org/sqlite/lib"
+
+	"github.com/sakif/coding-playground/internal/retry"
+)
+
+// BUSY DATABASES:
+// SQLite allows one writer at a time. A write that arrives while another
+// holds the lock fails at once with SQLITE_BUSY (or SQLITE_LOCKED) — not
+// because anything is wrong, just unlucky timing. exec retries those writes
+// with backoff (see internal/retry) instead of letting them become 500s.
+//
+// Statements inside a transaction aren't retried one by one: after a busy
+// error the transaction as a whole has to be rolled back and started again.
+
+// SetRetryPolicy changes how writes are retried. Only busy and locked errors
+// are ever retried, whatever p.Retryable says.
+func (db *DB) SetRetryPolicy(p retry.Policy) {
+	p.Retryable = isBusy
+	db.retry = p
+}
+
+// exec is conn.ExecContext, retried while the database is busy.
+func (db *DB) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return retry.DoValue(ctx, db.retry, func(ctx context.Context) (sql.Result, error) {
+		return db.conn.ExecContext(ctx, query, args...)
+	})
+}
+
+// isBusy reports whether err means another connection holds the lock.
+func isBusy(err error) bool {
+	var sqliteErr *sqlitedriver.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	// Extended codes (SQLITE_BUSY_SNAPSHOT, ...) keep the primary code in
+	// the low byte.
+	switch sqliteErr.Code() & 0xff {
+	case sqlitelib.SQLITE_BUSY, sqlitelib.SQLITE_LOCKED:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+var _ repository.SimilarityRepository = (*DB)(nil)
+
+// SaveSimilarityReport creates or replaces an assignment's report.
+func (db *DB) SaveSimilarityReport(ctx context.Context, r *model.SimilarityReport) error {
+	pairs, err := json.Marshal(r.Pairs)
+	if err != nil {
+		return fmt.Errorf("sqlite: encode similarity pairs: %w", err)
+	}
+	_, err = db.exec(ctx,
+		`INSERT INTO similarity_reports (assignment_id, status, threshold, compared, pairs, requested_at, completed_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (assignment_id) DO UPDATE SET
+		     status       = excluded.status,
+		     threshold    = excluded.threshold,
+		     compared     = excluded.compared,
+		     pairs        = excluded.pairs,
+		     requested_at = excluded.requested_at,
+		     completed_at = excluded.completed_at`,
+		r.AssignmentID, r.Status, r.Threshold, r.Compared, string(pairs), r.RequestedAt, r.CompletedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: save similarity report: %w", err)
+	}
+	return nil
+}
+
+// GetSimilarityReport returns an assignment's report.
+func (db *DB) GetSimilarityReport(ctx context.Context, assignmentID string) (*model.SimilarityReport, error) {
+	var r model.SimilarityReport
+	var pairs string
+	var completedAt sql.NullTime
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT assignment_id, status, threshold, compared, pairs, requested_at, completed_at
+		 FROM similarity_reports WHERE assignment_id = ?`, assignmentID,
+	).Scan(&r.AssignmentID, &r.Status, &r.Threshold, &r.Compared, &pairs, &r.RequestedAt, &completedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("similarity report", assignmentID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: get similarity report: %w", err)
+	}
+	if err := json.Unmarshal([]byte(pairs), &r.Pairs); err != nil {
+		return nil, fmt.Errorf("sqlite: decode similarity pairs: %w", err)
+	}
+	if completedAt.Valid {
+		r.CompletedAt = &completedAt.Time
+	}
+	return &r, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/rs/xid"
@@ -57,22 +58,30 @@ func (db *DB) Create(ctx context.Context, snippet *model.Snippet) error {
 	snippet.ID = xid.New().String()
 
 	// Set timestamps
-	now := time.Now()
+	now := time.Now().UTC()
 	snippet.CreatedAt = now
 	snippet.UpdatedAt = now
+	snippet.Version = 1
 
 	// INSERT the snippet into the database.
 	// The ? placeholders are filled in order by the arguments after the SQL string.
 	// The driver handles escaping to prevent SQL injection.
-	_, err := db.conn.ExecContext(ctx,
-		`INSERT INTO snippets (id, name, code, description, created_at, updated_at)
-		 VALUES (?, ?, ?, ?, ?, ?)`,
+	_, err := db.exec(ctx,
+		`INSERT INTO snippets (id, name, code, description, user_id, public, published_at, created_at, updated_at, version, forked_from, org_id, requirements)
+		 VALUES (?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?, ?)`,
 		snippet.ID,
 		snippet.Name,
 		snippet.Code,
 		snippet.Description,
+		snippet.UserID, // NULLIF stores anonymous snippets' owner as NULL
+		snippet.Public,
+		snippet.PublishedAt,
 		snippet.CreatedAt,
 		snippet.UpdatedAt,
+		snippet.Version,
+		snippet.ForkedFrom,
+		snippet.OrgID,
+		snippet.Requirements,
 	)
 	if err != nil {
 		// ERROR WRAPPING:
@@ -108,11 +117,12 @@ func (db *DB) Create(ctx context.Context, snippet *model.Snippet) error {
 //    This is a common pattern: translate database errors into domain errors.
 func (db *DB) GetByID(ctx context.Context, id string) (*model.Snippet, error) {
 	var snippet model.Snippet
+	var publishedAt sql.NullTime
 
 	// QueryRowContext runs a SELECT and returns at most one row.
 	// The Scan() call reads column values into our struct fields.
 	err := db.conn.QueryRowContext(ctx,
-		`SELECT id, name, code, description, created_at, updated_at
+		`SELECT id, name, code, description, COALESCE(user_id, ''), public, published_at, created_at, updated_at, version, forked_from, org_id, requirements
 		 FROM snippets
 		 WHERE id = ?`,
 		id,
@@ -121,8 +131,15 @@ func (db *DB) GetByID(ctx context.Context, id string) (*model.Snippet, error) {
 		&snippet.Name,
 		&snippet.Code,
 		&snippet.Description,
+		&snippet.UserID,
+		&snippet.Public,
+		&publishedAt,
 		&snippet.CreatedAt,
 		&snippet.UpdatedAt,
+		&snippet.Version,
+		&snippet.ForkedFrom,
+		&snippet.OrgID,
+		&snippet.Requirements,
 	)
 
 	if err != nil {
@@ -135,6 +152,9 @@ func (db *DB) GetByID(ctx context.Context, id string) (*model.Snippet, error) {
 		// Any other error is a real database problem
 		return nil, fmt.Errorf("sqlite: getting snippet %s: %w", id, err)
 	}
+	if publishedAt.Valid {
+		snippet.PublishedAt = &publishedAt.Time
+	}
 
 	return &snippet, nil
 }
@@ -180,14 +200,22 @@ func (db *DB) List(ctx context.Context, opts repository.ListOptions) ([]model.Sn
 		offset = 0
 	}
 
+	where, args := snippetFilter(opts)
+
+	// A lightweight projection: '' stands in for the code column, so SQLite
+	// never reads the code bodies and the scan below stays the same.
+	code := "code"
+	if opts.OmitCode {
+		code = "'' AS code"
+	}
+
 	// ORDER BY created_at DESC = newest first
 	rows, err := db.conn.QueryContext(ctx,
-		`SELECT id, name, code, description, created_at, updated_at
-		 FROM snippets
+		`SELECT id, name, `+code+`, description, COALESCE(user_id, ''), public, published_at, created_at, updated_at, version, forked_from, org_id, requirements
+		 FROM snippets`+where+`
 		 ORDER BY created_at DESC
 		 LIMIT ? OFFSET ?`,
-		limit,
-		offset,
+		append(args, limit, offset)...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("sqlite: listing snippets: %w", err)
@@ -195,6 +223,12 @@ func (db *DB) List(ctx context.Context, opts repository.ListOptions) ([]model.Sn
 	// CRITICAL: always close rows when done!
 	defer rows.Close()
 
+	return scanSnippets(rows, limit)
+}
+
+// scanSnippets reads the rows of a snippet list query. The columns must be
+// those selected by List.
+func scanSnippets(rows *sql.Rows, limit int) ([]model.Snippet, error) {
 	// PRE-ALLOCATE THE SLICE:
 	// make([]model.Snippet, 0, limit) creates a slice with:
 	//   - length 0 (no elements yet)
@@ -206,12 +240,17 @@ func (db *DB) List(ctx context.Context, opts repository.ListOptions) ([]model.Sn
 
 	for rows.Next() {
 		var s model.Snippet
+		var publishedAt sql.NullTime
 		if err := rows.Scan(
 			&s.ID, &s.Name, &s.Code, &s.Description,
-			&s.CreatedAt, &s.UpdatedAt,
+			&s.UserID, &s.Public, &publishedAt,
+			&s.CreatedAt, &s.UpdatedAt, &s.Version, &s.ForkedFrom, &s.OrgID, &s.Requirements,
 		); err != nil {
 			return nil, fmt.Errorf("sqlite: scanning snippet row: %w", err)
 		}
+		if publishedAt.Valid {
+			s.PublishedAt = &publishedAt.Time
+		}
 		snippets = append(snippets, s)
 	}
 
@@ -225,6 +264,166 @@ func (db *DB) List(ctx context.Context, opts repository.ListOptions) ([]model.Sn
 	return snippets, nil
 }
 
+// ListWithAuthors is List with each owned snippet's Author filled in.
+//
+// AVOIDING N+1:
+// A list page showing avatars would otherwise need the page (1 query) and
+// then each snippet's owner (N more). One LEFT JOIN against users does it
+// all; LEFT so anonymous snippets, with no user to join, are still listed.
+//
+// The page is picked in a subquery first, so the join only touches the
+// rows being returned, and the filter's unqualified column names can't
+// clash with the users table's.
+func (db *DB) ListWithAuthors(ctx context.Context, opts repository.ListOptions) ([]model.Snippet, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	limit = min(limit, 100)
+	offset := max(opts.Offset, 0)
+
+	where, args := snippetFilter(opts)
+	code := "code"
+	if opts.OmitCode {
+		code = "'' AS code"
+	}
+
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT s.id, s.name, s.code, s.description, COALESCE(s.user_id, ''), s.public, s.published_at,
+		        s.created_at, s.updated_at, s.version, s.forked_from, s.org_id, s.requirements,
+		        u.id, u.login, u.avatar_url
+		 FROM (SELECT id, name, `+code+`, description, user_id, public, published_at,
+		              created_at, updated_at, version, forked_from, org_id, requirements
+		       FROM snippets`+where+`
+		       ORDER BY created_at DESC
+		       LIMIT ? OFFSET ?) AS s
+		 LEFT JOIN users u ON u.id = s.user_id
+		 ORDER BY s.created_at DESC`,
+		append(args, limit, offset)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: listing snippets with authors: %w", err)
+	}
+	defer rows.Close()
+
+	snippets := make([]model.Snippet, 0, limit)
+	for rows.Next() {
+		var s model.Snippet
+		var publishedAt sql.NullTime
+		var authorID, login, avatarURL sql.NullString
+		if err := rows.Scan(
+			&s.ID, &s.Name, &s.Code, &s.Description,
+			&s.UserID, &s.Public, &publishedAt,
+			&s.CreatedAt, &s.UpdatedAt, &s.Version, &s.ForkedFrom, &s.OrgID, &s.Requirements,
+			&authorID, &login, &avatarURL,
+		); err != nil {
+			return nil, fmt.Errorf("sqlite: scanning snippet row: %w", err)
+		}
+		if publishedAt.Valid {
+			s.PublishedAt = &publishedAt.Time
+		}
+		if authorID.Valid {
+			s.Author = &model.Author{ID: authorID.String, Login: login.String, AvatarURL: avatarURL.String}
+		}
+		snippets = append(snippets, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite: iterating snippets: %w", err)
+	}
+	return snippets, nil
+}
+
+// ListPublic returns the most recently published public snippets, newest
+// first — all of them, or only userID's when userID isn't empty.
+func (db *DB) ListPublic(ctx context.Context, userID string, limit int) ([]model.Snippet, error) {
+	where := ` WHERE public = 1`
+	args := []any{}
+	if userID != "" {
+		where += ` AND user_id = ?`
+		args = append(args, userID)
+	}
+
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT id, name, code, description, COALESCE(user_id, ''), public, published_at, created_at, updated_at, version, forked_from, org_id, requirements
+		 FROM snippets`+where+`
+		 ORDER BY published_at DESC
+		 LIMIT ?`,
+		append(args, limit)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: listing public snippets: %w", err)
+	}
+	defer rows.Close()
+
+	return scanSnippets(rows, limit)
+}
+
+// Count returns the number of snippets List would return without paging.
+// The list endpoint reports it as "total" so clients can render page numbers.
+func (db *DB) Count(ctx context.Context, opts repository.ListOptions) (int, error) {
+	where, args := snippetFilter(opts)
+
+	var n int
+	if err := db.conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM snippets`+where, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("sqlite: counting snippets: %w", err)
+	}
+	return n, nil
+}
+
+// snippetFilter builds the WHERE clause for the filters in opts, shared by
+// List and Count so the total always matches the items.
+//
+// BUILDING SQL SAFELY:
+// Only fixed strings are concatenated into the query; every value goes in as
+// a ? placeholder. The clause is assembled dynamically, but user input never
+// becomes SQL.
+//
+// Timestamps are stored as UTC text of a fixed width (see timestamp.go), so
+// comparing the strings is the same as comparing the instants.
+func snippetFilter(opts repository.ListOptions) (string, []any) {
+	var conds []string
+	var args []any
+
+	if !opts.CreatedAfter.IsZero() {
+		conds = append(conds, "created_at > ?")
+		args = append(args, opts.CreatedAfter)
+	}
+	if !opts.CreatedBefore.IsZero() {
+		conds = append(conds, "created_at < ?")
+		args = append(args, opts.CreatedBefore)
+	}
+	if !opts.UpdatedAfter.IsZero() {
+		conds = append(conds, "updated_at > ?")
+		args = append(args, opts.UpdatedAfter)
+	}
+	if opts.HasOwner != nil {
+		if *opts.HasOwner {
+			conds = append(conds, "user_id IS NOT NULL")
+		} else {
+			conds = append(conds, "user_id IS NULL")
+		}
+	}
+	if opts.OwnerID != "" {
+		conds = append(conds, "user_id = ?")
+		args = append(args, opts.OwnerID)
+	}
+	if opts.OrgID != "" {
+		conds = append(conds, "org_id = ?")
+		args = append(args, opts.OrgID)
+	}
+	if opts.PersonalOnly {
+		conds = append(conds, "org_id = ''")
+	}
+	if opts.PublicOnly {
+		conds = append(conds, "public = 1")
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
 // Update modifies an existing snippet in the database.
 //
 // KEY CONCEPTS:
@@ -235,21 +434,23 @@ func (db *DB) List(ctx context.Context, opts repository.ListOptions) ([]model.Sn
 //    This is more efficient than doing a SELECT + UPDATE (one query vs two).
 //
 // 2. UPDATING ONLY CHANGED FIELDS:
-//    We update name, code, description, and updated_at.
+//    We update name, code, description, version, requirements and updated_at.
 //    We do NOT update id or created_at (those are immutable).
 //    updated_at is always set to "now" so we know when it was last modified.
 func (db *DB) Update(ctx context.Context, snippet *model.Snippet) error {
 	// Set the updated timestamp
-	snippet.UpdatedAt = time.Now()
+	snippet.UpdatedAt = time.Now().UTC()
 
-	result, err := db.conn.ExecContext(ctx,
+	result, err := db.exec(ctx,
 		`UPDATE snippets
-		 SET name = ?, code = ?, description = ?, updated_at = ?
+		 SET name = ?, code = ?, description = ?, updated_at = ?, version = ?, requirements = ?
 		 WHERE id = ?`,
 		snippet.Name,
 		snippet.Code,
 		snippet.Description,
 		snippet.UpdatedAt,
+		snippet.Version,
+		snippet.Requirements,
 		snippet.ID,
 	)
 	if err != nil {
@@ -269,11 +470,31 @@ func (db *DB) Update(ctx context.Context, snippet *model.Snippet) error {
 	return nil
 }
 
+// SetPublic saves snippet.Public and snippet.PublishedAt. Visibility is part
+// of the snippet's representation, so updated_at moves too (and with it the ETag).
+func (db *DB) SetPublic(ctx context.Context, snippet *model.Snippet) error {
+	snippet.UpdatedAt = time.Now().UTC()
+
+	result, err := db.exec(ctx,
+		`UPDATE snippets SET public = ?, published_at = ?, updated_at = ? WHERE id = ?`,
+		snippet.Public, snippet.PublishedAt, snippet.UpdatedAt, snippet.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: setting visibility of snippet %s: %w", snippet.ID, err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("sqlite: checking rows affected: %w", err)
+	} else if n == 0 {
+		return apperror.NotFound("snippet", snippet.ID)
+	}
+	return nil
+}
+
 // Delete removes a snippet from the database by its ID.
 //
 // Same pattern as Update — check RowsAffected to detect "not found".
 func (db *DB) Delete(ctx context.Context, id string) error {
-	result, err := db.conn.ExecContext(ctx,
+	result, err := db.exec(ctx,
 		`DELETE FROM snippets WHERE id = ?`,
 		id,
 	)
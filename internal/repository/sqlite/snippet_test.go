@@ -2,12 +2,22 @@ package sqlite
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
+	"github.com/sakif/coding-playground/internal/analytics"
 	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/idempotency"
 	"github.com/sakif/coding-playground/internal/model"
 	"github.com/sakif/coding-playground/internal/repository"
+	"github.com/sakif/coding-playground/internal/retry"
 )
 
 // TESTING WITH IN-MEMORY SQLITE:
@@ -223,6 +233,234 @@ func TestList_DefaultLimit(t *testing.T) {
 	}
 }
 
+func TestListAndCount_Filters(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	// Three snippets created a day apart; only the newest has an owner, who
+	// published it.
+	day := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	old := createTestSnippet(t, db, "old", "code")
+	mid := createTestSnippet(t, db, "mid", "code")
+	recent := createTestSnippet(t, db, "recent", "code")
+	for i, s := range []*model.Snippet{old, mid, recent} {
+		at := day.AddDate(0, 0, i).Local()
+		if _, err := db.exec(ctx,
+			`UPDATE snippets SET created_at = ?, updated_at = ? WHERE id = ?`, at, at, s.ID); err != nil {
+			t.Fatalf("backdating snippet: %v", err)
+		}
+	}
+	if _, err := db.exec(ctx, `UPDATE snippets SET user_id = 'u1', public = 1 WHERE id = ?`, recent.ID); err != nil {
+		t.Fatalf("setting owner: %v", err)
+	}
+
+	yes, no := true, false
+	tests := []struct {
+		name string
+		opts repository.ListOptions
+		want []string
+	}{
+		{"no filter", repository.ListOptions{}, []string{"recent", "mid", "old"}},
+		{"created after", repository.ListOptions{CreatedAfter: day}, []string{"recent", "mid"}},
+		{"created before", repository.ListOptions{CreatedBefore: day.AddDate(0, 0, 1)}, []string{"old"}},
+		{"created range", repository.ListOptions{CreatedAfter: day, CreatedBefore: day.AddDate(0, 0, 2)}, []string{"mid"}},
+		{"updated after", repository.ListOptions{UpdatedAfter: day.AddDate(0, 0, 1)}, []string{"recent"}},
+		{"has owner", repository.ListOptions{HasOwner: &yes}, []string{"recent"}},
+		{"anonymous", repository.ListOptions{HasOwner: &no}, []string{"mid", "old"}},
+		{"owner", repository.ListOptions{OwnerID: "u1"}, []string{"recent"}},
+		{"other owner", repository.ListOptions{OwnerID: "u2"}, nil},
+		{"public", repository.ListOptions{PublicOnly: true}, []string{"recent"}},
+		{"combined", repository.ListOptions{CreatedAfter: day, HasOwner: &no}, []string{"mid"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snippets, err := db.List(ctx, tt.opts)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			var names []string
+			for _, s := range snippets {
+				names = append(names, s.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("List() = %v, want %v", names, tt.want)
+			}
+
+			total, err := db.Count(ctx, tt.opts)
+			if err != nil {
+				t.Fatalf("Count() error = %v", err)
+			}
+			if total != len(tt.want) {
+				t.Errorf("Count() = %d, want %d", total, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestList_OmitCode(t *testing.T) {
+	db := newTestDB(t)
+	createTestSnippet(t, db, "hello", "print('hi')")
+
+	snippets, err := db.List(context.Background(), repository.ListOptions{OmitCode: true})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(snippets) != 1 {
+		t.Fatalf("List() returned %d items, want 1", len(snippets))
+	}
+	if snippets[0].Code != "" {
+		t.Errorf("Code = %q, want empty with OmitCode", snippets[0].Code)
+	}
+	if snippets[0].Name != "hello" {
+		t.Errorf("Name = %q, want hello", snippets[0].Name)
+	}
+}
+
+func TestListWithAuthors(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	user := &model.User{ID: "u1", GitHubID: 1, Login: "octocat", AvatarURL: "https://example.com/a.png"}
+	if err := db.Upsert(ctx, user); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	owned := &model.Snippet{Name: "owned", Code: "print(1)", UserID: user.ID}
+	if err := db.Create(ctx, owned); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	createTestSnippet(t, db, "anonymous", "print(2)")
+
+	snippets, err := db.ListWithAuthors(ctx, repository.ListOptions{OmitCode: true})
+	if err != nil {
+		t.Fatalf("ListWithAuthors() error = %v", err)
+	}
+	if len(snippets) != 2 {
+		t.Fatalf("ListWithAuthors() returned %d items, want 2", len(snippets))
+	}
+	byName := map[string]model.Snippet{}
+	for _, s := range snippets {
+		byName[s.Name] = s
+	}
+	want := model.Author{ID: "u1", Login: "octocat", AvatarURL: "https://example.com/a.png"}
+	if a := byName["owned"].Author; a == nil || *a != want {
+		t.Errorf("owned snippet's Author = %+v, want %+v", a, want)
+	}
+	if a := byName["anonymous"].Author; a != nil {
+		t.Errorf("anonymous snippet's Author = %+v, want nil", a)
+	}
+	if byName["owned"].Code != "" || byName["owned"].CreatedAt.IsZero() {
+		t.Errorf("columns not scanned as List would: %+v", byName["owned"])
+	}
+
+	// Filters and paging apply as in List.
+	mine, err := db.ListWithAuthors(ctx, repository.ListOptions{OwnerID: user.ID, Limit: 1})
+	if err != nil || len(mine) != 1 || mine[0].ID != owned.ID || mine[0].Code != "print(1)" {
+		t.Errorf("ListWithAuthors(OwnerID) = %+v, %v; want just the owned snippet", mine, err)
+	}
+}
+
+func TestEachSnippet(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	// More than one batch, with every third snippet owned by u1.
+	total := cursorBatch + 50
+	owned := 0
+	for i := range total {
+		snippet := &model.Snippet{Name: fmt.Sprintf("s%d", i), Code: "print(1)"}
+		if i%3 == 0 {
+			snippet.UserID = "u1"
+			owned++
+		}
+		if err := db.Create(ctx, snippet); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	var ids []string
+	for snippet, err := range db.EachSnippet(ctx, repository.ListOptions{Limit: 1}) {
+		if err != nil {
+			t.Fatalf("EachSnippet() error = %v", err)
+		}
+		if snippet.Code != "print(1)" {
+			t.Fatalf("snippet %s Code = %q, want it loaded", snippet.ID, snippet.Code)
+		}
+		ids = append(ids, snippet.ID)
+	}
+	if !slices.IsSorted(ids) || len(slices.Compact(slices.Clone(ids))) != total {
+		t.Errorf("EachSnippet() yielded %d snippets (sorted: %v), want all %d once each in ID order", len(ids), slices.IsSorted(ids), total)
+	}
+
+	n := 0
+	for snippet, err := range db.EachSnippet(ctx, repository.ListOptions{OwnerID: "u1", OmitCode: true}) {
+		if err != nil || snippet.UserID != "u1" || snippet.Code != "" {
+			t.Fatalf("EachSnippet(OwnerID) yielded %+v, %v", snippet, err)
+		}
+		n++
+	}
+	if n != owned {
+		t.Errorf("EachSnippet(OwnerID) yielded %d snippets, want %d", n, owned)
+	}
+
+	// Stopping early, and a canceled context.
+	n = 0
+	for range db.EachSnippet(ctx, repository.ListOptions{}) {
+		if n++; n == 3 {
+			break
+		}
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	var errs []error
+	for _, err := range db.EachSnippet(canceled, repository.ListOptions{}) {
+		errs = append(errs, err)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("EachSnippet(canceled) yielded errors %v, want just context.Canceled", errs)
+	}
+}
+
+func TestStorageStatsAndCheckpoint(t *testing.T) {
+	// WAL needs a real file; ":memory:" has none.
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	for i := range 50 {
+		createTestSnippet(t, db, fmt.Sprintf("s%d", i), strings.Repeat("x", 1000))
+	}
+	stats, err := db.StorageStats(ctx)
+	if err != nil {
+		t.Fatalf("StorageStats() error = %v", err)
+	}
+	if stats.JournalMode != "wal" || stats.PageCount == 0 || stats.SizeBytes != stats.PageSize*stats.PageCount {
+		t.Errorf("StorageStats() = %+v", stats)
+	}
+	if stats.WALBytes == 0 || !strings.HasSuffix(stats.Path, "test.db") {
+		t.Errorf("StorageStats() = %+v, want a WAL beside test.db after 50 writes", stats)
+	}
+
+	result, err := db.Checkpoint(ctx)
+	if err != nil {
+		t.Fatalf("Checkpoint() error = %v", err)
+	}
+	if result.Busy || result.WALBytesBefore != stats.WALBytes || result.WALBytesAfter != 0 {
+		t.Errorf("Checkpoint() = %+v, want the WAL truncated from %d bytes", result, stats.WALBytes)
+	}
+	if stats, _ := db.StorageStats(ctx); stats.WALBytes != 0 {
+		t.Errorf("WALBytes after checkpoint = %d, want 0", stats.WALBytes)
+	}
+
+	// In memory, there's no file to report on.
+	if stats, err := newTestDB(t).StorageStats(ctx); err != nil || stats.Path != "" || stats.WALBytes != 0 {
+		t.Errorf("in-memory StorageStats() = %+v, %v", stats, err)
+	}
+}
+
 // =========================================================================
 // UPDATE TESTS
 // =========================================================================
@@ -383,3 +621,887 @@ func TestFullCRUDLifecycle(t *testing.T) {
 
 	t.Log("Full CRUD lifecycle passed!")
 }
+
+func TestIdempotencyKeys(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	rec := idempotency.Record{Scope: "ip:192.0.2.1", Key: "k", RequestHash: "h", ExpiresAt: time.Now().Add(time.Hour)}
+
+	if _, reserved, err := db.ReserveIdempotencyKey(ctx, rec); err != nil || !reserved {
+		t.Fatalf("first reserve = (%v, %v), want reserved", reserved, err)
+	}
+	existing, reserved, err := db.ReserveIdempotencyKey(ctx, rec)
+	if err != nil || reserved {
+		t.Fatalf("second reserve = (%v, %v), want existing record", reserved, err)
+	}
+	if existing.Status != 0 || existing.RequestHash != "h" {
+		t.Errorf("in-progress record = %+v", existing)
+	}
+
+	if err := db.CompleteIdempotencyKey(ctx, rec.Scope, rec.Key, 201, "application/json", []byte(`{}`)); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	existing, _, _ = db.ReserveIdempotencyKey(ctx, rec)
+	if existing.Status != 201 || string(existing.Body) != `{}` || existing.ContentType != "application/json" {
+		t.Errorf("completed record = %+v", existing)
+	}
+
+	if err := db.ReleaseIdempotencyKey(ctx, rec.Scope, rec.Key); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if _, reserved, _ := db.ReserveIdempotencyKey(ctx, rec); !reserved {
+		t.Error("released key should be reservable again")
+	}
+
+	// An expired record doesn't block a new reservation and is purged.
+	old := idempotency.Record{Scope: rec.Scope, Key: "old", RequestHash: "h", ExpiresAt: time.Now().Add(-time.Minute)}
+	db.ReserveIdempotencyKey(ctx, old)
+	if _, reserved, _ := db.ReserveIdempotencyKey(ctx, old); !reserved {
+		t.Error("expired key should be reservable again")
+	}
+	if n, err := db.PurgeExpiredIdempotencyKeys(ctx); err != nil || n != 1 {
+		t.Errorf("purge = (%d, %v), want (1, nil)", n, err)
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	created := &model.Webhook{UserID: "u1", URL: "https://example.com/a", Secret: "s",
+		Events: []string{model.EventSnippetCreated, model.EventSnippetUpdated}}
+	if err := db.CreateWebhook(ctx, created); err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	db.CreateWebhook(ctx, &model.Webhook{UserID: "u1", URL: "https://example.com/b", Secret: "s",
+		Events: []string{model.EventExecutionCompleted}})
+
+	got, err := db.GetWebhook(ctx, created.ID)
+	if err != nil || got.URL != created.URL || len(got.Events) != 2 || got.Secret != "s" {
+		t.Fatalf("GetWebhook = %+v, %v", got, err)
+	}
+
+	// Event matching is on whole names: "snippet.create" must not match "snippet.created".
+	for event, want := range map[string]int{
+		model.EventSnippetCreated:     1,
+		model.EventExecutionCompleted: 1,
+		"snippet.create":              0,
+	} {
+		hooks, err := db.ListWebhooksForEvent(ctx, "u1", event)
+		if err != nil || len(hooks) != want {
+			t.Errorf("ListWebhooksForEvent(%q) = %d hooks, %v; want %d", event, len(hooks), err, want)
+		}
+	}
+	if hooks, _ := db.ListWebhooksForEvent(ctx, "u2", model.EventSnippetCreated); len(hooks) != 0 {
+		t.Errorf("another user's webhooks matched: %+v", hooks)
+	}
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		d := &model.WebhookDelivery{WebhookID: created.ID, EventID: "e1", Event: model.EventSnippetCreated, Attempt: attempt}
+		if err := db.RecordWebhookDelivery(ctx, d); err != nil {
+			t.Fatalf("RecordWebhookDelivery: %v", err)
+		}
+	}
+	deliveries, err := db.ListWebhookDeliveries(ctx, created.ID, 2)
+	if err != nil || len(deliveries) != 2 || deliveries[0].Attempt != 3 {
+		t.Fatalf("ListWebhookDeliveries = %+v, %v; want the 2 newest", deliveries, err)
+	}
+
+	if err := db.DeleteWebhook(ctx, created.ID); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	if _, err := db.GetWebhook(ctx, created.ID); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("GetWebhook after delete: err = %v, want not found", err)
+	}
+	if deliveries, _ := db.ListWebhookDeliveries(ctx, created.ID, 10); len(deliveries) != 0 {
+		t.Errorf("deliveries survived their webhook: %d", len(deliveries))
+	}
+}
+
+func TestPublicSnippets(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	owned := &model.Snippet{Name: "owned", UserID: "u1"}
+	anon := &model.Snippet{Name: "anon"}
+	for _, s := range []*model.Snippet{owned, anon} {
+		if err := db.Create(ctx, s); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	got, _ := db.GetByID(ctx, owned.ID)
+	if got.UserID != "u1" || got.Public || got.PublishedAt != nil {
+		t.Fatalf("owned snippet = %+v", got)
+	}
+	if got, _ := db.GetByID(ctx, anon.ID); got.UserID != "" {
+		t.Errorf("anonymous snippet has owner %q", got.UserID)
+	}
+
+	// NULLIF keeps anonymous snippets' user_id NULL, so the owner filter still works.
+	no := false
+	if n, _ := db.Count(ctx, repository.ListOptions{HasOwner: &no}); n != 1 {
+		t.Errorf("anonymous count = %d, want 1", n)
+	}
+
+	now := time.Now()
+	owned.Public, owned.PublishedAt = true, &now
+	if err := db.SetPublic(ctx, owned); err != nil {
+		t.Fatalf("SetPublic: %v", err)
+	}
+
+	for userID, want := range map[string]int{"": 1, "u1": 1, "u2": 0} {
+		list, err := db.ListPublic(ctx, userID, 10)
+		if err != nil || len(list) != want {
+			t.Errorf("ListPublic(%q) = %d, %v; want %d", userID, len(list), err, want)
+		}
+	}
+	list, _ := db.ListPublic(ctx, "", 10)
+	if !list[0].Public || list[0].PublishedAt == nil || !list[0].PublishedAt.Equal(now) {
+		t.Errorf("public snippet = %+v", list[0])
+	}
+
+	if err := db.SetPublic(ctx, &model.Snippet{ID: "missing"}); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("SetPublic(missing) = %v, want not found", err)
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	for i, id := range []string{"fast", "slow", "shy"} {
+		if err := db.Upsert(ctx, &model.User{ID: id, GitHubID: int64(i + 1), Login: id}); err != nil {
+			t.Fatalf("Upsert: %v", err)
+		}
+	}
+	ex := &model.Exercise{Title: "Add", Prompt: "p", TestCode: "t", AuthorID: "fast"}
+	if err := db.CreateExercise(ctx, ex); err != nil {
+		t.Fatalf("CreateExercise: %v", err)
+	}
+	for _, s := range []model.Submission{
+		{UserID: "fast", Status: model.SubmissionFailed, Score: 50, DurationMS: 1},
+		{UserID: "fast", Status: model.SubmissionPassed, Score: 100, DurationMS: 20},
+		{UserID: "slow", Status: model.SubmissionPassed, Score: 100, DurationMS: 90},
+		{UserID: "shy", Status: model.SubmissionPassed, Score: 100, DurationMS: 5},
+	} {
+		s.ExerciseID = ex.ID
+		if err := db.CreateSubmission(ctx, &s); err != nil {
+			t.Fatalf("CreateSubmission: %v", err)
+		}
+	}
+	if err := db.SetLeaderboardOptOut(ctx, "shy", true); err != nil {
+		t.Fatalf("SetLeaderboardOptOut: %v", err)
+	}
+
+	if at, err := db.LeaderboardRefreshedAt(ctx); at != nil || err != nil {
+		t.Errorf("LeaderboardRefreshedAt before a refresh = %v, %v; want nil", at, err)
+	}
+	if n, err := db.RefreshLeaderboard(ctx); n != 2 || err != nil {
+		t.Fatalf("RefreshLeaderboard = %d, %v; want 2 (shy opted out)", n, err)
+	}
+	entries, err := db.ListLeaderboardEntries(ctx, repository.LeaderboardFilter{ExerciseID: ex.ID}, 10)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("ListLeaderboardEntries = %+v, %v", entries, err)
+	}
+	if e := entries[0]; e.UserID != "fast" || e.Score != 100 || e.DurationMS != 20 || e.Solved != 1 || e.Submissions != 2 {
+		t.Errorf("top entry = %+v, want fast's passing attempt out of 2", e)
+	}
+
+	// Opting out hides a user at once, before the next refresh.
+	db.SetLeaderboardOptOut(ctx, "fast", true)
+	if entries, _ := db.ListLeaderboardEntries(ctx, repository.LeaderboardFilter{ExerciseID: ex.ID}, 10); len(entries) != 1 || entries[0].UserID != "slow" {
+		t.Errorf("after opt-out = %+v, want only slow", entries)
+	}
+	if at, err := db.LeaderboardRefreshedAt(ctx); at == nil || err != nil {
+		t.Errorf("LeaderboardRefreshedAt = %v, %v; want the refresh time", at, err)
+	}
+}
+
+func TestHintUnlocks(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	ex := &model.Exercise{Title: "Add", Prompt: "p", TestCode: "t", Hints: []string{"a", "b"}, HintPenalty: 10}
+	if err := db.CreateExercise(ctx, ex); err != nil {
+		t.Fatalf("CreateExercise: %v", err)
+	}
+	if got, err := db.GetExercise(ctx, ex.ID); err != nil || got.HintCount != 2 || got.Hints[1] != "b" || got.HintPenalty != 10 {
+		t.Fatalf("GetExercise = %+v, %v; want the hints back", got, err)
+	}
+
+	if n, err := db.HintsUnlocked(ctx, ex.ID, "u1"); n != 0 || err != nil {
+		t.Errorf("HintsUnlocked before any = %d, %v", n, err)
+	}
+	for _, want := range []int{1, 2, 2} {
+		n, err := db.UnlockHint(ctx, ex.ID, "u1", 2)
+		if err != nil || n != want {
+			t.Errorf("UnlockHint = %d, %v; want %d (capped at 2)", n, err, want)
+		}
+	}
+	if n, _ := db.HintsUnlocked(ctx, ex.ID, "u2"); n != 0 {
+		t.Errorf("another user's unlocks = %d, want 0", n)
+	}
+}
+
+func TestComments(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	snippet := &model.Snippet{Name: "loop", Code: "for i in range(10):\n    print(i)", UserID: "owner"}
+	if err := db.Create(ctx, snippet); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if snippet.Version != 1 {
+		t.Fatalf("new snippet Version = %d, want 1", snippet.Version)
+	}
+	snippet.Version++
+	if err := db.Update(ctx, snippet); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got, _ := db.GetByID(ctx, snippet.ID); got.Version != 2 {
+		t.Errorf("Version after Update = %d, want 2", got.Version)
+	}
+
+	db.Upsert(ctx, &model.User{GitHubID: 7, Login: "teacher"})
+	teacher, _ := db.GetUserByLogin(ctx, "teacher")
+	first := &model.Comment{SnippetID: snippet.ID, UserID: teacher.ID, Body: "off-by-one", Version: 2, LineStart: 1, LineEnd: 1}
+	second := &model.Comment{SnippetID: snippet.ID, UserID: "ghost", Body: "nice", Version: 2}
+	for _, c := range []*model.Comment{first, second} {
+		if err := db.CreateComment(ctx, c); err != nil {
+			t.Fatalf("CreateComment: %v", err)
+		}
+	}
+
+	comments, err := db.ListComments(ctx, snippet.ID)
+	if err != nil || len(comments) != 2 {
+		t.Fatalf("ListComments = %+v, %v", comments, err)
+	}
+	if c := comments[0]; c.ID != first.ID || c.Login != "teacher" || c.LineStart != 1 || c.LineEnd != 1 || c.Version != 2 {
+		t.Errorf("first comment = %+v, want teacher's line comment", c)
+	}
+	if err := db.DeleteComment(ctx, second.ID); err != nil {
+		t.Fatalf("DeleteComment: %v", err)
+	}
+	if _, err := db.GetComment(ctx, second.ID); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("GetComment after delete: error = %v, want ErrNotFound", err)
+	}
+	if err := db.DeleteComment(ctx, second.ID); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("DeleteComment twice: error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSimilarityReports(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	ex := &model.Exercise{Title: "Add", Prompt: "p", TestCode: "t"}
+	class := &model.Class{Name: "Intro", JoinCode: "ABCD2345", OwnerID: "teacher"}
+	if err := db.CreateExercise(ctx, ex); err != nil {
+		t.Fatalf("CreateExercise: %v", err)
+	}
+	if err := db.CreateClass(ctx, class); err != nil {
+		t.Fatalf("CreateClass: %v", err)
+	}
+	assignment := &model.Assignment{ClassID: class.ID, ExerciseID: ex.ID, Title: "Add", LatePolicy: model.LateAccept, CreatedBy: "teacher"}
+	if err := db.CreateAssignment(ctx, assignment); err != nil {
+		t.Fatalf("CreateAssignment: %v", err)
+	}
+
+	if _, err := db.GetSimilarityReport(ctx, assignment.ID); !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("GetSimilarityReport before saving: error = %v, want ErrNotFound", err)
+	}
+	report := &model.SimilarityReport{
+		AssignmentID: assignment.ID, Status: model.SimilarityPending, Threshold: 0.8,
+		Pairs: []model.SimilarityPair{}, RequestedAt: time.Now().UTC(),
+	}
+	if err := db.SaveSimilarityReport(ctx, report); err != nil {
+		t.Fatalf("SaveSimilarityReport: %v", err)
+	}
+
+	done := time.Now().UTC()
+	report.Status, report.Compared, report.CompletedAt = model.SimilarityDone, 2, &done
+	report.Pairs = []model.SimilarityPair{{
+		Similarity: 0.9,
+		A:          model.SimilaritySubmission{SubmissionID: "s1", UserID: "u1", Login: "ann"},
+		B:          model.SimilaritySubmission{SubmissionID: "s2", UserID: "u2", Login: "bob"},
+	}}
+	if err := db.SaveSimilarityReport(ctx, report); err != nil {
+		t.Fatalf("SaveSimilarityReport (replace): %v", err)
+	}
+	got, err := db.GetSimilarityReport(ctx, assignment.ID)
+	if err != nil {
+		t.Fatalf("GetSimilarityReport: %v", err)
+	}
+	if got.Status != model.SimilarityDone || got.Compared != 2 || got.CompletedAt == nil || len(got.Pairs) != 1 || got.Pairs[0].B.Login != "bob" {
+		t.Errorf("GetSimilarityReport = %+v, want the replaced, done report", got)
+	}
+}
+
+func TestChallenges(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	ex := &model.Exercise{Title: "Add", Prompt: "p", TestCode: "t"}
+	if err := db.CreateExercise(ctx, ex); err != nil {
+		t.Fatalf("CreateExercise: %v", err)
+	}
+	for _, date := range []string{"2026-03-01", "2026-03-02", "2026-03-03"} {
+		if err := db.SaveChallenge(ctx, &model.Challenge{Date: date, ExerciseID: ex.ID, CreatedBy: "author"}); err != nil {
+			t.Fatalf("SaveChallenge(%s): %v", date, err)
+		}
+	}
+	if err := db.SaveChallenge(ctx, &model.Challenge{Date: "2026-03-02", ExerciseID: ex.ID, CreatedBy: "other"}); err != nil {
+		t.Fatalf("SaveChallenge (replace): %v", err)
+	}
+	if got, err := db.GetChallenge(ctx, "2026-03-02"); err != nil || got.CreatedBy != "other" {
+		t.Errorf("GetChallenge = %+v, %v; want the replacement", got, err)
+	}
+	list, err := db.ListChallenges(ctx, "2026-03-02", "2026-03-31")
+	if err != nil || len(list) != 2 || list[0].Date != "2026-03-03" {
+		t.Errorf("ListChallenges = %+v, %v; want the last two, newest first", list, err)
+	}
+	if err := db.DeleteChallenge(ctx, "2026-03-01"); err != nil {
+		t.Fatalf("DeleteChallenge: %v", err)
+	}
+	if _, err := db.GetChallenge(ctx, "2026-03-01"); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("GetChallenge after delete: error = %v, want ErrNotFound", err)
+	}
+
+	subs := []*model.Submission{
+		{ExerciseID: ex.ID, ChallengeDate: "2026-03-02", UserID: "u1", Status: model.SubmissionFailed},
+		{ExerciseID: ex.ID, ChallengeDate: "2026-03-02", UserID: "u1", Status: model.SubmissionPassed},
+		{ExerciseID: ex.ID, ChallengeDate: "2026-03-02", UserID: "u1", Status: model.SubmissionPassed},
+		{ExerciseID: ex.ID, ChallengeDate: "2026-03-03", UserID: "u1", Status: model.SubmissionPassed},
+		{ExerciseID: ex.ID, UserID: "u1", Status: model.SubmissionPassed}, // practice, not a challenge
+		{ExerciseID: ex.ID, ChallengeDate: "2026-03-03", UserID: "u2", Status: model.SubmissionPassed},
+	}
+	for _, s := range subs {
+		if err := db.CreateSubmission(ctx, s); err != nil {
+			t.Fatalf("CreateSubmission: %v", err)
+		}
+	}
+	dates, err := db.SolvedChallengeDates(ctx, "u1")
+	if err != nil || !slices.Equal(dates, []string{"2026-03-03", "2026-03-02"}) {
+		t.Errorf("SolvedChallengeDates = %v, %v; want each solved day once, newest first", dates, err)
+	}
+}
+
+func TestBadges(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	fork := &model.Snippet{Name: "copy", Code: "print(1)", UserID: "u2", ForkedFrom: "original"}
+	if err := db.Create(ctx, fork); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if got, _ := db.GetByID(ctx, fork.ID); got.ForkedFrom != "original" {
+		t.Errorf("ForkedFrom = %q, want it saved", got.ForkedFrom)
+	}
+
+	for _, want := range []bool{true, false} {
+		if awarded, err := db.AwardBadge(ctx, "u1", model.BadgeFirstSnippet); err != nil || awarded != want {
+			t.Errorf("AwardBadge = %v, %v; want %v", awarded, err, want)
+		}
+	}
+	if _, err := db.AwardBadge(ctx, "u1", "no-such-badge"); err == nil {
+		t.Error("AwardBadge of an unknown badge succeeded")
+	}
+	badges, err := db.ListUserBadges(ctx, "u1")
+	if err != nil || len(badges) != 1 || badges[0].Name != "Hello, world" || badges[0].AwardedAt.IsZero() {
+		t.Errorf("ListUserBadges = %+v, %v; want first-snippet with its name", badges, err)
+	}
+
+	for want := 1; want <= 3; want++ {
+		if n, err := db.IncrementBadgeCounter(ctx, "u1", "runs"); err != nil || n != want {
+			t.Errorf("IncrementBadgeCounter = %d, %v; want %d", n, err, want)
+		}
+	}
+	if n, _ := db.IncrementBadgeCounter(ctx, "u2", "runs"); n != 1 {
+		t.Errorf("another user's counter = %d, want 1", n)
+	}
+
+	if err := db.DeleteUser(ctx, "u1"); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if badges, _ := db.ListUserBadges(ctx, "u1"); len(badges) != 0 {
+		t.Errorf("badges after DeleteUser = %+v, want none", badges)
+	}
+}
+
+func TestStarsAndNotifications(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	snippet := &model.Snippet{Name: "loop", Code: "print(1)", UserID: "u1"}
+	if err := db.Create(ctx, snippet); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	for _, want := range []bool{true, false} {
+		if added, err := db.StarSnippet(ctx, snippet.ID, "u2"); err != nil || added != want {
+			t.Errorf("StarSnippet = %v, %v; want %v", added, err, want)
+		}
+	}
+	if _, err := db.StarSnippet(ctx, "missing", "u2"); err == nil {
+		t.Error("StarSnippet of a missing snippet succeeded")
+	}
+	if n, _ := db.CountStars(ctx, snippet.ID); n != 1 {
+		t.Errorf("CountStars = %d, want 1", n)
+	}
+	if removed, err := db.UnstarSnippet(ctx, snippet.ID, "u2"); err != nil || !removed {
+		t.Errorf("UnstarSnippet = %v, %v; want true", removed, err)
+	}
+
+	if err := db.Upsert(ctx, &model.User{ID: "u2", GitHubID: 2, Login: "bob"}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	for _, msg := range []string{"first", "second"} {
+		n := &model.Notification{UserID: "u1", Kind: model.NotificationStar, ActorID: "u2", SnippetID: snippet.ID, Message: msg}
+		if err := db.CreateNotification(ctx, n); err != nil || n.ID == "" {
+			t.Fatalf("CreateNotification = %v, ID %q", err, n.ID)
+		}
+	}
+	list, err := db.ListNotifications(ctx, "u1", false, 10)
+	if err != nil || len(list) != 2 || list[0].Message != "second" || list[0].ActorLogin != "bob" {
+		t.Fatalf("ListNotifications = %+v, %v; want newest first with the actor's login", list, err)
+	}
+	if err := db.MarkNotificationsRead(ctx, "u1", []string{list[1].ID}); err != nil {
+		t.Fatalf("MarkNotificationsRead: %v", err)
+	}
+	if n, _ := db.CountUnreadNotifications(ctx, "u1"); n != 1 {
+		t.Errorf("unread after marking one = %d, want 1", n)
+	}
+	if unread, _ := db.ListNotifications(ctx, "u1", true, 10); len(unread) != 1 || unread[0].Message != "second" {
+		t.Errorf("unread only = %+v, want the second", unread)
+	}
+	if err := db.MarkNotificationsRead(ctx, "u1", nil); err != nil {
+		t.Fatalf("MarkNotificationsRead(all): %v", err)
+	}
+	if n, _ := db.CountUnreadNotifications(ctx, "u1"); n != 0 {
+		t.Errorf("unread after marking all = %d, want 0", n)
+	}
+
+	if err := db.DeleteUser(ctx, "u1"); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if list, _ := db.ListNotifications(ctx, "u1", false, 10); len(list) != 0 {
+		t.Errorf("notifications after DeleteUser = %+v, want none", list)
+	}
+}
+
+func TestEmail(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	for i, u := range []*model.User{
+		{ID: "u1", GitHubID: 1, Login: "ann", Email: "Ann@Example.com"},
+		{ID: "u2", GitHubID: 2, Login: "bob", Email: "bob@example.com"},
+		{ID: "u3", GitHubID: 3, Login: "cat"},
+	} {
+		if err := db.Upsert(ctx, u); err != nil {
+			t.Fatalf("Upsert %d: %v", i, err)
+		}
+	}
+	if u, err := db.GetUserByEmail(ctx, "ann@example.COM"); err != nil || u == nil || u.ID != "u1" {
+		t.Errorf("GetUserByEmail = %+v, %v; want ann, whatever the case", u, err)
+	}
+	if u, _ := db.GetUserByEmail(ctx, ""); u != nil {
+		t.Errorf("GetUserByEmail(\"\") = %+v, want nobody", u)
+	}
+
+	prefs, err := db.GetEmailPreferences(ctx, "u1")
+	if err != nil || *prefs != *model.DefaultEmailPreferences("u1") {
+		t.Errorf("GetEmailPreferences before saving = %+v, %v; want the defaults", prefs, err)
+	}
+	if err := db.SaveEmailPreferences(ctx, &model.EmailPreferences{UserID: "u2", Grading: true, Digest: false}); err != nil {
+		t.Fatalf("SaveEmailPreferences: %v", err)
+	}
+	if prefs, _ := db.GetEmailPreferences(ctx, "u2"); !prefs.Grading || prefs.Digest {
+		t.Errorf("GetEmailPreferences after saving = %+v", prefs)
+	}
+	// bob turned the digest off and cat has no address.
+	if users, err := db.ListDigestRecipients(ctx); err != nil || len(users) != 1 || users[0].ID != "u1" {
+		t.Errorf("ListDigestRecipients = %+v, %v; want only ann", users, err)
+	}
+
+	if err := db.CreateMagicLink(ctx, "hash", "u1", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("CreateMagicLink: %v", err)
+	}
+	if err := db.CreateMagicLink(ctx, "old", "u1", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("CreateMagicLink: %v", err)
+	}
+	if userID, err := db.ConsumeMagicLink(ctx, "hash"); err != nil || userID != "u1" {
+		t.Errorf("ConsumeMagicLink = %q, %v; want u1", userID, err)
+	}
+	for _, hash := range []string{"hash", "old", "unknown"} {
+		if _, err := db.ConsumeMagicLink(ctx, hash); !errors.Is(err, apperror.ErrNotFound) {
+			t.Errorf("ConsumeMagicLink(%s): error = %v, want ErrNotFound", hash, err)
+		}
+	}
+
+	if err := db.DeleteUser(ctx, "u2"); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if prefs, _ := db.GetEmailPreferences(ctx, "u2"); prefs.Grading {
+		t.Errorf("preferences after DeleteUser = %+v, want the defaults", prefs)
+	}
+}
+
+func TestAnalyticsEvents(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	err := db.SaveAnalyticsEvents(ctx, []analytics.Event{
+		{Name: analytics.EventLogin, UserID: "u1", Properties: map[string]any{"method": "github"}, Time: now},
+		{Name: analytics.EventSnippetCreated, Time: now},
+	})
+	if err != nil {
+		t.Fatalf("SaveAnalyticsEvents: %v", err)
+	}
+
+	rows, err := db.conn.QueryContext(ctx, `SELECT name, user_id, properties FROM analytics_events ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var name, userID, props string
+		rows.Scan(&name, &userID, &props)
+		got = append(got, name+" "+userID+" "+props)
+	}
+	want := []string{`login u1 {"method":"github"}`, `snippet_created  {}`}
+	if !slices.Equal(got, want) {
+		t.Errorf("rows = %q, want %q", got, want)
+	}
+
+	if err := db.DeleteUser(ctx, "u1"); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	var n int
+	db.conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM analytics_events WHERE user_id = 'u1'`).Scan(&n)
+	if n != 0 {
+		t.Errorf("%d events left after DeleteUser, want 0", n)
+	}
+}
+
+func TestOrgs_DeleteUser(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	// u1 and u2 share one org; u1 alone is in another.
+	shared := &model.Org{Name: "shared", CreatedBy: "u1"}
+	solo := &model.Org{Name: "solo", CreatedBy: "u1"}
+	for _, org := range []*model.Org{shared, solo} {
+		if err := db.CreateOrg(ctx, org); err != nil {
+			t.Fatalf("CreateOrg: %v", err)
+		}
+	}
+	if err := db.AddOrgMember(ctx, &model.OrgMember{OrgID: shared.ID, UserID: "u2", Role: model.OrgRoleMember}); err != nil {
+		t.Fatalf("AddOrgMember: %v", err)
+	}
+	kept := &model.Snippet{Name: "kept", UserID: "u1", OrgID: shared.ID}
+	gone := &model.Snippet{Name: "gone", UserID: "u1", OrgID: solo.ID}
+	for _, s := range []*model.Snippet{kept, gone} {
+		if err := db.Create(ctx, s); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	if err := db.DeleteUser(ctx, "u1"); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if got, err := db.GetByID(ctx, kept.ID); err != nil || got.OrgID != shared.ID {
+		t.Errorf("shared org's snippet = %+v, %v; want it kept", got, err)
+	}
+	if _, err := db.GetByID(ctx, gone.ID); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("empty org's snippet: error = %v, want not found", err)
+	}
+	if _, err := db.GetOrg(ctx, solo.ID); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("empty org: error = %v, want not found", err)
+	}
+	if members, _ := db.ListOrgMembers(ctx, shared.ID); len(members) != 1 || members[0].UserID != "u2" {
+		t.Errorf("shared org members = %+v, want only u2", members)
+	}
+}
+
+func TestExecRetriesBusyWrites(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "busy.db")
+	db, err := New(path)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer db.Close()
+
+	// Another process holding the write lock.
+	other, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	lock, err := other.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Close()
+	if _, err := lock.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		t.Fatal(err)
+	}
+
+	insert := `INSERT INTO feature_flags (name, enabled) VALUES ('busy', 1)`
+	db.SetRetryPolicy(retry.Policy{Attempts: 1})
+	if _, err := db.exec(ctx, insert); !isBusy(err) {
+		t.Fatalf("exec() with the lock held = %v, want a busy error", err)
+	}
+
+	db.SetRetryPolicy(retry.Policy{Attempts: 50, Base: 5 * time.Millisecond, Max: 20 * time.Millisecond})
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		lock.ExecContext(ctx, "COMMIT")
+	}()
+	if _, err := db.exec(ctx, insert); err != nil {
+		t.Errorf("exec() after the lock is released = %v, want it retried until it succeeds", err)
+	}
+}
+
+func TestSubmissionOutputIsCompressed(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	ex := &model.Exercise{Title: "Loop", Prompt: "p", TestCode: "t", AuthorID: "u1"}
+	if err := db.CreateExercise(ctx, ex); err != nil {
+		t.Fatalf("CreateExercise: %v", err)
+	}
+	outputs := map[string]string{
+		"short":     "hello\n",
+		"long":      strings.Repeat("step 1 of many\n", 200),
+		"too long":  strings.Repeat("é", maxStoredOutput), // 2 bytes a character: the cap falls mid-character
+		"old plain": "",                                   // stands in for a row from before compression
+	}
+	for name, output := range outputs {
+		if err := db.CreateSubmission(ctx, &model.Submission{ExerciseID: ex.ID, UserID: name, Status: model.SubmissionPassed, Output: output}); err != nil {
+			t.Fatalf("CreateSubmission(%s): %v", name, err)
+		}
+	}
+	if _, err := db.conn.ExecContext(ctx, `UPDATE submissions SET output = 'legacy', output_encoding = '' WHERE user_id = 'old plain'`); err != nil {
+		t.Fatal(err)
+	}
+	outputs["old plain"] = "legacy"
+
+	var stored int
+	if err := db.conn.QueryRowContext(ctx, `SELECT length(output) FROM submissions WHERE user_id = 'long' AND output_encoding = 'gzip'`).Scan(&stored); err != nil {
+		t.Fatalf("long output wasn't stored gzipped: %v", err)
+	}
+	if stored >= len(outputs["long"])/4 {
+		t.Errorf("long output stored in %d bytes, from %d", stored, len(outputs["long"]))
+	}
+
+	subs, err := db.ListSubmissions(ctx, repository.SubmissionFilter{ExerciseID: ex.ID})
+	if err != nil {
+		t.Fatalf("ListSubmissions: %v", err)
+	}
+	for _, s := range subs {
+		want := outputs[s.UserID]
+		if s.UserID == "too long" {
+			if len(s.Output) > maxStoredOutput || !strings.HasSuffix(s.Output, outputTruncated) || !utf8.ValidString(s.Output) {
+				t.Errorf("too long: got %d bytes ending %q, want at most %d ending with the marker", len(s.Output), s.Output[len(s.Output)-30:], maxStoredOutput)
+			}
+			continue
+		}
+		if s.Output != want {
+			t.Errorf("%s: Output = %q, want %q", s.UserID, s.Output, want)
+		}
+	}
+}
+
+func TestPurgeRuns(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	// ann and bob have a run a day for five days; two anonymous runs are
+	// a day and ten days old.
+	addRun := func(userID string, daysAgo int) {
+		run := &model.Run{UserID: userID, Language: "python"}
+		if err := db.CreateRun(ctx, run); err != nil {
+			t.Fatalf("CreateRun: %v", err)
+		}
+		if _, err := db.conn.ExecContext(ctx, `UPDATE runs SET created_at = ? WHERE id = ?`, now.AddDate(0, 0, -daysAgo), run.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, user := range []string{"ann", "bob"} {
+		for day := range 5 {
+			addRun(user, day)
+		}
+	}
+	addRun("", 1)
+	addRun("", 10)
+	count := func(where string) int {
+		var n int
+		if err := db.conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM runs WHERE `+where).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	n, err := db.PurgeRuns(ctx, true, now.AddDate(0, 0, -7), 0)
+	if err != nil || n != 1 {
+		t.Fatalf("PurgeRuns(anonymous, 7 days) = %d, %v; want 1", n, err)
+	}
+	if count(`user_id = ''`) != 1 || count(`user_id != ''`) != 10 {
+		t.Error("the anonymous purge deleted the wrong runs")
+	}
+
+	// Over 3 days old goes first (one each), then all but the newest 2.
+	n, err = db.PurgeRuns(ctx, false, now.AddDate(0, 0, -3).Add(-time.Hour), 2)
+	if err != nil || n != 6 {
+		t.Fatalf("PurgeRuns(signed in, 3 days, keep 2) = %d, %v; want 6", n, err)
+	}
+	for _, user := range []string{"ann", "bob"} {
+		if got := count(`user_id = '` + user + `'`); got != 2 {
+			t.Errorf("%s kept %d runs, want 2", user, got)
+		}
+	}
+	var oldest int
+	if err := db.conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM runs WHERE user_id != '' AND created_at < ?`, now.AddDate(0, 0, -1).Add(-time.Hour)).Scan(&oldest); err != nil || oldest != 0 {
+		t.Errorf("%d runs older than a day were kept (%v), want the newest 2 each", oldest, err)
+	}
+	if count(`user_id = ''`) != 1 {
+		t.Error("the signed-in purge deleted anonymous runs")
+	}
+}
+
+func TestTimestamps(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	stored := func(id string) string {
+		var s string
+		if err := db.conn.QueryRowContext(ctx, `SELECT CAST(created_at AS TEXT) FROM snippets WHERE id = ?`, id).Scan(&s); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	t.Run("stored and read in UTC", func(t *testing.T) {
+		s := createTestSnippet(t, db, "utc", "code")
+		at := time.Date(2026, 3, 8, 1, 30, 0, 0, newYork)
+		if _, err := db.exec(ctx, `UPDATE snippets SET created_at = ? WHERE id = ?`, at, s.ID); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := stored(s.ID), "2026-03-08 06:30:00.000000000Z"; got != want {
+			t.Errorf("stored %q, want %q", got, want)
+		}
+		got, err := db.GetByID(ctx, s.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.CreatedAt.Location() != time.UTC || !got.CreatedAt.Equal(at) {
+			t.Errorf("CreatedAt = %v, want %v in UTC", got.CreatedAt, at)
+		}
+	})
+
+	t.Run("filters compare instants across a DST change", func(t *testing.T) {
+		// 01:30 EST and 03:10 EDT are 40 minutes apart; 06:45 UTC is
+		// between them, though its local text would sort first.
+		before := createTestSnippet(t, db, "before", "code")
+		after := createTestSnippet(t, db, "after", "code")
+		for s, at := range map[*model.Snippet]time.Time{
+			before: time.Date(2026, 3, 8, 1, 30, 0, 0, newYork),
+			after:  time.Date(2026, 3, 8, 3, 10, 0, 0, newYork),
+		} {
+			if _, err := db.exec(ctx, `UPDATE snippets SET created_at = ? WHERE id = ?`, at, s.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		snippets, err := db.List(ctx, repository.ListOptions{
+			CreatedAfter:  time.Date(2026, 3, 8, 6, 45, 0, 0, time.UTC),
+			CreatedBefore: time.Date(2026, 3, 9, 0, 0, 0, 0, newYork),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(snippets) != 1 || snippets[0].ID != after.ID {
+			t.Errorf("List() = %v, want only %q", snippets, after.Name)
+		}
+	})
+
+	t.Run("activity days are in since's zone", func(t *testing.T) {
+		// In New York: the 7th at 23:30 EST, the 8th at 03:10 EDT, and the
+		// 1st of November at 01:30 twice (EDT, then EST an hour later).
+		for _, at := range []time.Time{
+			time.Date(2026, 3, 8, 4, 30, 0, 0, time.UTC),
+			time.Date(2026, 3, 8, 7, 10, 0, 0, time.UTC),
+			time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC),
+			time.Date(2026, 11, 1, 6, 30, 0, 0, time.UTC),
+		} {
+			run := &model.Run{UserID: "ann", Language: "python"}
+			if err := db.CreateRun(ctx, run); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := db.exec(ctx, `UPDATE runs SET created_at = ? WHERE id = ?`, at, run.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		stats, err := db.GetUserStats(ctx, "ann", time.Date(2026, 3, 1, 0, 0, 0, 0, newYork))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []model.DayActivity{
+			{Date: "2026-03-07", Runs: 1},
+			{Date: "2026-03-08", Runs: 1},
+			{Date: "2026-11-01", Runs: 2},
+		}
+		if !slices.Equal(stats.Activity, want) {
+			t.Errorf("Activity = %v, want %v", stats.Activity, want)
+		}
+	})
+
+	t.Run("older rows are rewritten once", func(t *testing.T) {
+		// As the driver wrote them before: time.Time.String() in local
+		// time or UTC, and CURRENT_TIMESTAMP's zoneless UTC.
+		old := map[string]string{
+			"2026-03-08 01:30:00 -0500 EST":                "2026-03-08 06:30:00.000000000Z",
+			"2026-03-08 03:10:00.25 -0400 EDT m=+0.000001": "2026-03-08 07:10:00.250000000Z",
+			"2026-03-08 06:45:00":                          "2026-03-08 06:45:00.000000000Z",
+			"2026-03-08 06:45:00.123456789 +0000 UTC":      "2026-03-08 06:45:00.123456789Z",
+		}
+		ids := map[string]string{}
+		for text := range old {
+			s := createTestSnippet(t, db, "old", "code")
+			if _, err := db.exec(ctx, `UPDATE snippets SET created_at = ? WHERE id = ?`, text, s.ID); err != nil {
+				t.Fatal(err)
+			}
+			ids[text] = s.ID
+		}
+		if _, err := db.exec(ctx, `PRAGMA user_version = 0`); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.normalizeTimestamps(); err != nil {
+			t.Fatalf("normalizeTimestamps() error = %v", err)
+		}
+		for text, want := range old {
+			if got := stored(ids[text]); got != want {
+				t.Errorf("%q was rewritten as %q, want %q", text, got, want)
+			}
+		}
+
+		// Done once: a row written the old way now stays as it is.
+		s := createTestSnippet(t, db, "later", "code")
+		if _, err := db.exec(ctx, `UPDATE snippets SET created_at = '2026-03-08 06:45:00' WHERE id = ?`, s.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.normalizeTimestamps(); err != nil {
+			t.Fatal(err)
+		}
+		if got := stored(s.ID); got != "2026-03-08 06:45:00" {
+			t.Errorf("the second run rewrote %q", got)
+		}
+	})
+}
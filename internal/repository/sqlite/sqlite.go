@@ -31,14 +31,15 @@ import (
 	"database/sql"
 	"fmt"
 
-	// BLANK IMPORT:
-	// The underscore import `_ "modernc.org/sqlite"` is a "side-effect only" import.
-	// It doesn't give us any symbols to use directly. Instead, the sqlite package's
-	// init() function registers itself with database/sql as a driver named "sqlite".
-	// After this import, sql.Open("sqlite", ...) knows how to talk to SQLite.
-	//
-	// This is Go's plugin pattern — database drivers register themselves at init time.
-	_ "modernc.org/sqlite"
+	// DRIVERS:
+	// Importing modernc.org/sqlite registers it with database/sql as a driver
+	// named "sqlite", so sql.Open("sqlite", ...) knows how to talk to SQLite —
+	// Go's plugin pattern, drivers registering themselves at init time. We use
+	// the driver directly instead, wrapped so that timestamps are always
+	// stored and read in UTC (see timestamp.go), and hand it to sql.OpenDB.
+	sqlitedriver "modernc.org/sqlite"
+
+	"github.com/sakif/coding-playground/internal/retry"
 )
 
 // DB wraps a sql.DB connection pool and provides repository methods.
@@ -49,7 +50,8 @@ import (
 // 3. It implements the SnippetRepository interface from repository.go
 // 4. We control the lifecycle (New creates it, Close destroys it)
 type DB struct {
-	conn *sql.DB
+	conn  *sql.DB
+	retry retry.Policy // for writes that meet a busy database (see retry.go)
 }
 
 // New creates a new SQLite database connection and runs migrations.
@@ -59,16 +61,13 @@ type DB struct {
 //   - ":memory:"            → in-memory database (great for tests, lost on close)
 //
 // CONNECTION POOL:
-// sql.Open() does NOT actually open a connection — it just creates a pool manager.
+// sql.OpenDB() does NOT actually open a connection — it just creates a pool manager.
 // The first real connection happens when you run your first query.
 // We call db.Ping() to force an immediate connection and verify it works.
 func New(dbPath string) (*DB, error) {
-	// Open a connection pool to the SQLite database.
-	// "sqlite" is the driver name registered by the blank import above.
-	conn, err := sql.Open("sqlite", dbPath)
-	if err != nil {
-		return nil, fmt.Errorf("sqlite: opening database: %w", err)
-	}
+	// Open a connection pool to the SQLite database, through the UTC
+	// wrapper around the driver (see timestamp.go).
+	conn := sql.OpenDB(connector{dsn: dbPath, driver: &sqlitedriver.Driver{}})
 
 	// Ping verifies the connection actually works.
 	// Without this, a bad path or permissions issue would only surface
@@ -98,7 +97,7 @@ func New(dbPath string) (*DB, error) {
 		return nil, fmt.Errorf("sqlite: enabling foreign keys: %w", err)
 	}
 
-	db := &DB{conn: conn}
+	db := &DB{conn: conn, retry: retry.Policy{Retryable: isBusy}}
 
 	// Run database migrations to create/update tables
 	if err := db.migrate(); err != nil {
@@ -123,6 +122,11 @@ func (db *DB) Close() error {
 	return db.conn.Close()
 }
 
+// Stats returns connection pool statistics (exposed on the metrics endpoint).
+func (db *DB) Stats() sql.DBStats {
+	return db.conn.Stats()
+}
+
 // migrate runs all database migrations.
 //
 // MIGRATIONS IN PRODUCTION:
@@ -171,19 +175,626 @@ func (db *DB) migrate() error {
 	}
 
 	// Add user_id column to existing snippets table if it doesn't exist yet.
-	// SQLite doesn't have IF NOT EXISTS for ALTER TABLE, so we check first.
+	if err := db.addColumnIfMissing("snippets", "user_id", "TEXT"); err != nil {
+		return err
+	}
+
+	// Snippets can be published to the Atom feeds.
+	if err := db.addColumnIfMissing("snippets", "public", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := db.addColumnIfMissing("snippets", "published_at", "DATETIME"); err != nil {
+		return err
+	}
+	if _, err := db.conn.Exec(`CREATE INDEX IF NOT EXISTS idx_snippets_public ON snippets(public, published_at)`); err != nil {
+		return fmt.Errorf("creating public snippets index: %w", err)
+	}
+
+	// Users gained a role (user/admin) for admin-only endpoints.
+	if err := db.addColumnIfMissing("users", "role", "TEXT NOT NULL DEFAULT 'user'"); err != nil {
+		return err
+	}
+
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS feature_flags (
+			name       TEXT PRIMARY KEY,
+			enabled    INTEGER NOT NULL,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating feature_flags table: %w", err)
+	}
+
+	// Background jobs (see internal/jobs). Completed jobs are deleted, so the
+	// table only holds pending, running and failed work.
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS jobs (
+			id           TEXT PRIMARY KEY,
+			kind         TEXT NOT NULL,
+			payload      TEXT NOT NULL DEFAULT 'null',
+			status       TEXT NOT NULL,
+			attempts     INTEGER NOT NULL DEFAULT 0,
+			max_attempts INTEGER NOT NULL,
+			run_at       DATETIME NOT NULL,
+			last_error   TEXT NOT NULL DEFAULT '',
+			created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_jobs_status_run_at ON jobs(status, run_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating jobs table: %w", err)
+	}
+
+	// Webhooks and their delivery log (see service.WebhookService). events is
+	// a comma-separated list. Deleting a webhook deletes its deliveries.
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS webhooks (
+			id         TEXT PRIMARY KEY,
+			user_id    TEXT NOT NULL,
+			url        TEXT NOT NULL,
+			secret     TEXT NOT NULL,
+			events     TEXT NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks(user_id);
+
+		CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id          TEXT PRIMARY KEY,
+			webhook_id  TEXT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+			event_id    TEXT NOT NULL,
+			event       TEXT NOT NULL,
+			attempt     INTEGER NOT NULL,
+			status_code INTEGER NOT NULL DEFAULT 0,
+			error       TEXT NOT NULL DEFAULT '',
+			duration_ms INTEGER NOT NULL DEFAULT 0,
+			created_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, created_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating webhook tables: %w", err)
+	}
+
+	// Exercises (see service.ExerciseService). test_code holds the hidden
+	// pytest tests; author_id is kept when the author is deleted, since
+	// exercises outlive the people who wrote them.
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS exercises (
+			id           TEXT PRIMARY KEY,
+			title        TEXT NOT NULL,
+			prompt       TEXT NOT NULL,
+			starter_code TEXT NOT NULL DEFAULT '',
+			test_code    TEXT NOT NULL,
+			author_id    TEXT NOT NULL,
+			created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_exercises_created_at ON exercises(created_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating exercises table: %w", err)
+	}
+
+	// Graded submissions. results is the per-test outcome as a JSON array.
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS submissions (
+			id          TEXT PRIMARY KEY,
+			exercise_id TEXT NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+			user_id     TEXT NOT NULL,
+			code        TEXT NOT NULL,
+			status      TEXT NOT NULL,
+			score       INTEGER NOT NULL DEFAULT 0,
+			passed      INTEGER NOT NULL DEFAULT 0,
+			total       INTEGER NOT NULL DEFAULT 0,
+			results     TEXT NOT NULL DEFAULT '[]',
+			error       TEXT NOT NULL DEFAULT '',
+			output      TEXT NOT NULL DEFAULT '',
+			created_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_submissions_exercise_user ON submissions(exercise_id, user_id, created_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating submissions table: %w", err)
+	}
+
+	// Classes. Membership roles (teacher/student) are per class; join codes
+	// are what students type to join, so they must be unique.
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS classes (
+			id         TEXT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			join_code  TEXT NOT NULL UNIQUE,
+			owner_id   TEXT NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS class_members (
+			class_id  TEXT NOT NULL REFERENCES classes(id) ON DELETE CASCADE,
+			user_id   TEXT NOT NULL,
+			role      TEXT NOT NULL,
+			joined_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (class_id, user_id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_class_members_user_id ON class_members(user_id);
+		CREATE TABLE IF NOT EXISTS assignments (
+			id          TEXT PRIMARY KEY,
+			class_id    TEXT NOT NULL REFERENCES classes(id) ON DELETE CASCADE,
+			exercise_id TEXT NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+			title       TEXT NOT NULL,
+			created_by  TEXT NOT NULL,
+			created_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_assignments_class_id ON assignments(class_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating class tables: %w", err)
+	}
+
+	// Assignments gained open/due dates and a late policy; submissions made
+	// to an assignment record it, and whether they came in late.
+	for _, col := range []struct{ table, column, definition string }{
+		{"assignments", "opens_at", "DATETIME"},
+		{"assignments", "due_at", "DATETIME"},
+		{"assignments", "late_policy", "TEXT NOT NULL DEFAULT 'accept'"},
+		{"assignments", "late_penalty", "INTEGER NOT NULL DEFAULT 0"},
+		{"submissions", "assignment_id", "TEXT NOT NULL DEFAULT ''"},
+		{"submissions", "late", "INTEGER NOT NULL DEFAULT 0"},
+	} {
+		if err := db.addColumnIfMissing(col.table, col.column, col.definition); err != nil {
+			return err
+		}
+	}
+	if _, err := db.conn.Exec(`CREATE INDEX IF NOT EXISTS idx_submissions_assignment ON submissions(assignment_id, user_id)`); err != nil {
+		return fmt.Errorf("creating assignment submissions index: %w", err)
+	}
+
+	// Leaderboards. leaderboard_entries is materialized by RefreshLeaderboard
+	// (a scheduled task) from submissions: one row per user per exercise,
+	// holding their best attempt and whether any attempt passed. submissions.duration_ms (the tests' total
+	// running time) breaks ties between equal scores.
+	for _, col := range []struct{ table, column, definition string }{
+		{"submissions", "duration_ms", "REAL NOT NULL DEFAULT 0"},
+		{"users", "leaderboard_opt_out", "INTEGER NOT NULL DEFAULT 0"},
+	} {
+		if err := db.addColumnIfMissing(col.table, col.column, col.definition); err != nil {
+			return err
+		}
+	}
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS leaderboard_entries (
+			exercise_id  TEXT NOT NULL,
+			user_id      TEXT NOT NULL,
+			score        INTEGER NOT NULL,
+			solved       INTEGER NOT NULL,
+			duration_ms  REAL NOT NULL,
+			submissions  INTEGER NOT NULL,
+			achieved_at  DATETIME NOT NULL,
+			refreshed_at DATETIME NOT NULL,
+			PRIMARY KEY (exercise_id, user_id)
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating leaderboard_entries table: %w", err)
+	}
+
+	// Hints: an exercise's hints (a JSON array, in unlock order) and the
+	// score penalty per hint; hint_unlocks counts how many each learner has
+	// unlocked. Submissions record how many hints were used.
+	for _, col := range []struct{ table, column, definition string }{
+		{"exercises", "hints", "TEXT NOT NULL DEFAULT '[]'"},
+		{"exercises", "hint_penalty", "INTEGER NOT NULL DEFAULT 0"},
+		{"submissions", "hints_used", "INTEGER NOT NULL DEFAULT 0"},
+	} {
+		if err := db.addColumnIfMissing(col.table, col.column, col.definition); err != nil {
+			return err
+		}
+	}
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS hint_unlocks (
+			exercise_id TEXT NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+			user_id     TEXT NOT NULL,
+			unlocked    INTEGER NOT NULL,
+			updated_at  DATETIME NOT NULL,
+			PRIMARY KEY (exercise_id, user_id)
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating hint_unlocks table: %w", err)
+	}
+
+	// Comments on snippets. snippets.version counts code changes, and a
+	// comment records the version it was written against, so its line
+	// range (line_start = 0: the whole snippet) can be told apart from
+	// lines that have since moved.
+	if err := db.addColumnIfMissing("snippets", "version", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+		return err
+	}
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS comments (
+			id         TEXT PRIMARY KEY,
+			snippet_id TEXT NOT NULL REFERENCES snippets(id) ON DELETE CASCADE,
+			user_id    TEXT NOT NULL,
+			body       TEXT NOT NULL,
+			version    INTEGER NOT NULL,
+			line_start INTEGER NOT NULL DEFAULT 0,
+			line_end   INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_comments_snippet ON comments(snippet_id, created_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating comments table: %w", err)
+	}
+
+	// Similarity reports, one per assignment (see service/similarity.go).
+	// pairs holds the flagged pairs as a JSON array.
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS similarity_reports (
+			assignment_id TEXT PRIMARY KEY REFERENCES assignments(id) ON DELETE CASCADE,
+			status        TEXT NOT NULL,
+			threshold     REAL NOT NULL,
+			compared      INTEGER NOT NULL DEFAULT 0,
+			pairs         TEXT NOT NULL DEFAULT '[]',
+			requested_at  DATETIME NOT NULL,
+			completed_at  DATETIME
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating similarity_reports table: %w", err)
+	}
+
+	// The daily challenge schedule: one exercise per UTC date. Submissions
+	// made to a day's challenge record the date, which is what streaks count.
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS challenges (
+			date        TEXT PRIMARY KEY,
+			exercise_id TEXT NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+			created_by  TEXT NOT NULL,
+			created_at  DATETIME NOT NULL
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating challenges table: %w", err)
+	}
+	if err := db.addColumnIfMissing("submissions", "challenge_date", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if _, err := db.conn.Exec(`CREATE INDEX IF NOT EXISTS idx_submissions_challenge ON submissions(user_id, challenge_date)`); err != nil {
+		return fmt.Errorf("creating challenge submissions index: %w", err)
+	}
+
+	// Forks point back at the snippet they were copied from.
+	if err := db.addColumnIfMissing("snippets", "forked_from", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	// Badges (see service/badge.go). The badges table mirrors model.Badges,
+	// so a badge renamed in code is renamed here on the next start;
+	// badge_counters counts the things badges are awarded for.
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS badges (
+			id          TEXT PRIMARY KEY,
+			name        TEXT NOT NULL,
+			description TEXT NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS user_badges (
+			user_id    TEXT NOT NULL,
+			badge_id   TEXT NOT NULL REFERENCES badges(id) ON DELETE CASCADE,
+			awarded_at DATETIME NOT NULL,
+			PRIMARY KEY (user_id, badge_id)
+		);
+		CREATE TABLE IF NOT EXISTS badge_counters (
+			user_id TEXT NOT NULL,
+			counter TEXT NOT NULL,
+			count   INTEGER NOT NULL,
+			PRIMARY KEY (user_id, counter)
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating badge tables: %w", err)
+	}
+	if err := db.syncBadges(); err != nil {
+		return err
+	}
+
+	// Stars on snippets, and notifications (see service/notification.go).
+	// A notification's read_at is NULL until it's read.
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS snippet_stars (
+			snippet_id TEXT NOT NULL REFERENCES snippets(id) ON DELETE CASCADE,
+			user_id    TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			PRIMARY KEY (snippet_id, user_id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_snippet_stars_user ON snippet_stars(user_id, created_at);
+		CREATE TABLE IF NOT EXISTS notifications (
+			id            TEXT PRIMARY KEY,
+			user_id       TEXT NOT NULL,
+			kind          TEXT NOT NULL,
+			actor_id      TEXT NOT NULL DEFAULT '',
+			snippet_id    TEXT NOT NULL DEFAULT '',
+			submission_id TEXT NOT NULL DEFAULT '',
+			message       TEXT NOT NULL,
+			read_at       DATETIME,
+			created_at    DATETIME NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, created_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating star and notification tables: %w", err)
+	}
+
+	// Email preferences (see model.EmailPreferences; no row means the
+	// defaults) and sign-in links. A link is stored by the SHA-256 of its
+	// token, so the table alone can't sign anyone in; expires_at is Unix
+	// seconds, like idempotency_keys.
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS email_preferences (
+			user_id    TEXT PRIMARY KEY,
+			grading    INTEGER NOT NULL,
+			digest     INTEGER NOT NULL,
+			updated_at DATETIME NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS magic_links (
+			token_hash TEXT PRIMARY KEY,
+			user_id    TEXT NOT NULL,
+			expires_at INTEGER NOT NULL,
+			used_at    DATETIME
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating email tables: %w", err)
+	}
+
+	// The public activity feed (see service/activity.go). id is the feed's
+	// order and its cursor, so it's an ever-increasing integer rather than
+	// an xid.
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS activity_events (
+			id         INTEGER PRIMARY KEY AUTOINCREMENT,
+			kind       TEXT NOT NULL,
+			actor_id   TEXT NOT NULL,
+			snippet_id TEXT NOT NULL,
+			created_at DATETIME NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_activity_events_actor ON activity_events(actor_id, id);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating activity table: %w", err)
+	}
+
+	// Product analytics (see internal/analytics). properties is a JSON object.
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS analytics_events (
+			id         INTEGER PRIMARY KEY AUTOINCREMENT,
+			name       TEXT NOT NULL,
+			user_id    TEXT NOT NULL DEFAULT '',
+			properties TEXT NOT NULL DEFAULT '{}',
+			created_at DATETIME NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_analytics_events_name ON analytics_events(name, created_at);
+		CREATE INDEX IF NOT EXISTS idx_analytics_events_user ON analytics_events(user_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating analytics table: %w", err)
+	}
+
+	// Outcomes of playground runs, for users' stats (see service/stats.go),
+	// with the code of signed-in users' runs, for replaying them (see
+	// service/run.go). user_id is '' for an anonymous run. created_at is
+	// local time, like snippets', so both group by the same days.
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS runs (
+			id          TEXT PRIMARY KEY,
+			user_id     TEXT NOT NULL,
+			language    TEXT NOT NULL,
+			exit_code   INTEGER NOT NULL,
+			duration_ms INTEGER NOT NULL,
+			created_at  DATETIME NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_runs_user ON runs(user_id, created_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating runs table: %w", err)
+	}
+
+	// Organizations (see service/org.go). A snippet's org_id is '' for a
+	// personal snippet; deleting an org hands its snippets back to their
+	// creators, so there's no foreign key to cascade.
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS orgs (
+			id         TEXT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			created_by TEXT NOT NULL,
+			created_at DATETIME NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS org_members (
+			org_id    TEXT NOT NULL REFERENCES orgs(id) ON DELETE CASCADE,
+			user_id   TEXT NOT NULL,
+			role      TEXT NOT NULL,
+			joined_at DATETIME NOT NULL,
+			PRIMARY KEY (org_id, user_id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_org_members_user_id ON org_members(user_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating orgs tables: %w", err)
+	}
+	// Invitations to join an org. Like sign-in links, only the token's
+	// SHA-256 is stored, and expires_at is Unix seconds.
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS org_invites (
+			id          TEXT PRIMARY KEY,
+			org_id      TEXT NOT NULL REFERENCES orgs(id) ON DELETE CASCADE,
+			token_hash  TEXT NOT NULL UNIQUE,
+			role        TEXT NOT NULL,
+			email       TEXT NOT NULL DEFAULT '',
+			created_by  TEXT NOT NULL,
+			created_at  DATETIME NOT NULL,
+			expires_at  INTEGER NOT NULL,
+			accepted_by TEXT NOT NULL DEFAULT '',
+			accepted_at DATETIME
+		);
+		CREATE INDEX IF NOT EXISTS idx_org_invites_org_id ON org_invites(org_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating org invites table: %w", err)
+	}
+	if err := db.addColumnIfMissing("snippets", "org_id", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if _, err := db.conn.Exec(`CREATE INDEX IF NOT EXISTS idx_snippets_org_id ON snippets(org_id)`); err != nil {
+		return fmt.Errorf("creating snippets org index: %w", err)
+	}
+	// Per-org quota overrides (NULL: the deployment's default) and live runs
+	// counted per UTC day, as "2006-01-02".
+	for _, col := range []string{"snippet_quota", "run_quota"} {
+		if err := db.addColumnIfMissing("orgs", col, "INTEGER"); err != nil {
+			return err
+		}
+	}
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS org_runs (
+			org_id TEXT NOT NULL REFERENCES orgs(id) ON DELETE CASCADE,
+			day    TEXT NOT NULL,
+			runs   INTEGER NOT NULL,
+			PRIMARY KEY (org_id, day)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("creating org runs table: %w", err)
+	}
+	// Pending snippet transfers (see service/transfer.go); accepting or
+	// declining one deletes it. to_org_id has no foreign key: an offer to an
+	// org that has since been deleted just can't be accepted.
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS snippet_transfers (
+			id           TEXT PRIMARY KEY,
+			snippet_id   TEXT NOT NULL UNIQUE REFERENCES snippets(id) ON DELETE CASCADE,
+			from_user_id TEXT NOT NULL,
+			from_org_id  TEXT NOT NULL DEFAULT '',
+			to_user_id   TEXT NOT NULL DEFAULT '',
+			to_org_id    TEXT NOT NULL DEFAULT '',
+			created_by   TEXT NOT NULL,
+			created_at   DATETIME NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_snippet_transfers_to_user_id ON snippet_transfers(to_user_id);
+		CREATE INDEX IF NOT EXISTS idx_snippet_transfers_to_org_id ON snippet_transfers(to_org_id);
+		CREATE INDEX IF NOT EXISTS idx_snippet_transfers_created_by ON snippet_transfers(created_by);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating snippet transfers table: %w", err)
+	}
+
+	// Suspended users keep their account and data but can't change or run
+	// anything (see auth.RejectSuspended).
+	if err := db.addColumnIfMissing("users", "suspended", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	// Leases on sandbox capacity shared by servers using one Docker host (see
+	// executor.LeaseStore). Expired rows are deleted as leases are taken.
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS pool_leases (
+			kind       TEXT NOT NULL,
+			id         TEXT NOT NULL,
+			expires_at DATETIME NOT NULL,
+			PRIMARY KEY (kind, id)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("creating pool leases table: %w", err)
+	}
+
+	// Stored responses for Idempotency-Key (see internal/idempotency).
+	// expires_at is a Unix timestamp so expiry checks are plain integer compares.
+	_, err = db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS idempotency_keys (
+			scope        TEXT NOT NULL,
+			key          TEXT NOT NULL,
+			request_hash TEXT NOT NULL,
+			status       INTEGER NOT NULL DEFAULT 0,
+			content_type TEXT NOT NULL DEFAULT '',
+			body         BLOB,
+			expires_at   INTEGER NOT NULL,
+			PRIMARY KEY (scope, key)
+		);
+		CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating idempotency_keys table: %w", err)
+	}
+
+	// Runs keep their code for replaying (see service/run.go); runs from
+	// before have none and can't be replayed.
+	if err := db.addColumnIfMissing("runs", "code", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := db.addColumnIfMissing("runs", "replay_of", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	// A run's requirements manifest, '' when it installed nothing, so a
+	// replay installs the same packages.
+	if err := db.addColumnIfMissing("runs", "requirements", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	// Whether a run asked for a GPU, so a replay asks again.
+	if err := db.addColumnIfMissing("runs", "gpu", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	// Run output may be stored gzipped (see output.go); output_encoding says
+	// how a row's output is stored, '' being plain text.
+	if err := db.addColumnIfMissing("submissions", "output_encoding", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	// Exercises may measure line coverage; a submission keeps what was
+	// measured as JSON, '' when nothing was.
+	for _, col := range []struct{ table, column, definition string }{
+		{"exercises", "coverage", "INTEGER NOT NULL DEFAULT 0"},
+		{"submissions", "coverage", "TEXT NOT NULL DEFAULT ''"},
+	} {
+		if err := db.addColumnIfMissing(col.table, col.column, col.definition); err != nil {
+			return err
+		}
+	}
+
+	// A snippet's requirements manifest (see executor/requirements.go), ''
+	// when it needs no packages.
+	if err := db.addColumnIfMissing("snippets", "requirements", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	// Timestamps written before they were all UTC are rewritten once (see
+	// timestamp.go).
+	if err := db.normalizeTimestamps(); err != nil {
+		return fmt.Errorf("normalizing timestamps: %w", err)
+	}
+
+	return nil
+}
+
+// addColumnIfMissing adds a column to an existing table.
+// SQLite doesn't have IF NOT EXISTS for ALTER TABLE, so we check first.
+func (db *DB) addColumnIfMissing(table, column, definition string) error {
 	var colCount int
 	row := db.conn.QueryRow(
-		`SELECT COUNT(*) FROM pragma_table_info('snippets') WHERE name = 'user_id'`,
+		`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column,
 	)
 	if err := row.Scan(&colCount); err != nil {
-		return fmt.Errorf("checking user_id column: %w", err)
+		return fmt.Errorf("checking %s.%s column: %w", table, column, err)
 	}
-	if colCount == 0 {
-		if _, err := db.conn.Exec(`ALTER TABLE snippets ADD COLUMN user_id TEXT`); err != nil {
-			return fmt.Errorf("adding user_id column: %w", err)
-		}
+	if colCount > 0 {
+		return nil
 	}
 
+	// Identifiers can't be bound as ? parameters, but table/column/definition
+	// are constants from this file — never user input.
+	stmt := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition)
+	if _, err := db.conn.Exec(stmt); err != nil {
+		return fmt.Errorf("adding %s.%s column: %w", table, column, err)
+	}
 	return nil
 }
@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+var _ repository.StarRepository = (*DB)(nil)
+
+// StarSnippet stars a snippet for userID, reporting false if they already had.
+func (db *DB) StarSnippet(ctx context.Context, snippetID, userID string) (bool, error) {
+	res, err := db.exec(ctx,
+		`INSERT INTO snippet_stars (snippet_id, user_id, created_at) VALUES (?, ?, ?)
+		 ON CONFLICT (snippet_id, user_id) DO NOTHING`,
+		snippetID, userID, time.Now().UTC(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("sqlite: star snippet: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("sqlite: star snippet: %w", err)
+	}
+	return n == 1, nil
+}
+
+// UnstarSnippet removes userID's star, reporting false if there wasn't one.
+func (db *DB) UnstarSnippet(ctx context.Context, snippetID, userID string) (bool, error) {
+	res, err := db.exec(ctx,
+		`DELETE FROM snippet_stars WHERE snippet_id = ? AND user_id = ?`, snippetID, userID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("sqlite: unstar snippet: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("sqlite: unstar snippet: %w", err)
+	}
+	return n == 1, nil
+}
+
+// CountStars counts a snippet's stars.
+func (db *DB) CountStars(ctx context.Context, snippetID string) (int, error) {
+	var n int
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM snippet_stars WHERE snippet_id = ?`, snippetID,
+	).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("sqlite: count stars: %w", err)
+	}
+	return n, nil
+}
+
+// ListSnippetStars returns a page of a snippet's stars, newest first.
+func (db *DB) ListSnippetStars(ctx context.Context, snippetID string, limit, offset int) ([]model.Star, error) {
+	return db.listStars(ctx, `snippet_id = ?`, snippetID, limit, offset)
+}
+
+// ListUserStars returns a page of the stars userID gave, newest first.
+func (db *DB) ListUserStars(ctx context.Context, userID string, limit, offset int) ([]model.Star, error) {
+	return db.listStars(ctx, `user_id = ?`, userID, limit, offset)
+}
+
+// listStars lists the stars matching where, a condition with one argument.
+func (db *DB) listStars(ctx context.Context, where string, arg any, limit, offset int) ([]model.Star, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT snippet_id, user_id, created_at FROM snippet_stars WHERE `+where+`
+		 ORDER BY created_at DESC, snippet_id DESC, user_id DESC LIMIT ? OFFSET ?`, arg, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list stars: %w", err)
+	}
+	defer rows.Close()
+
+	stars := []model.Star{}
+	for rows.Next() {
+		var star model.Star
+		if err := rows.Scan(&star.SnippetID, &star.UserID, &star.CreatedAt); err != nil {
+			return nil, fmt.Errorf("sqlite: scan star: %w", err)
+		}
+		stars = append(stars, star)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite: list stars: %w", err)
+	}
+	return stars, nil
+}
+
+// CountUserStars counts the stars userID gave.
+func (db *DB) CountUserStars(ctx context.Context, userID string) (int, error) {
+	var n int
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM snippet_stars WHERE user_id = ?`, userID,
+	).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("sqlite: count user stars: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,160 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+var (
+	_ repository.StatsRepository = (*DB)(nil)
+	_ repository.RunRepository   = (*DB)(nil)
+)
+
+// CreateRun saves a run.
+func (db *DB) CreateRun(ctx context.Context, run *model.Run) error {
+	run.ID = xid.New().String()
+	run.CreatedAt = time.Now().UTC()
+	_, err := db.exec(ctx,
+		`INSERT INTO runs (id, user_id, code, requirements, gpu, replay_of, language, exit_code, duration_ms, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		run.ID, run.UserID, run.Code, run.Requirements, run.GPU, run.ReplayOf, run.Language, run.ExitCode, run.DurationMS, run.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: create run: %w", err)
+	}
+	return nil
+}
+
+// GetRun returns a run, its code included.
+func (db *DB) GetRun(ctx context.Context, id string) (*model.Run, error) {
+	run := &model.Run{}
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT id, user_id, code, requirements, gpu, replay_of, language, exit_code, duration_ms, created_at FROM runs WHERE id = ?`, id,
+	).Scan(&run.ID, &run.UserID, &run.Code, &run.Requirements, &run.GPU, &run.ReplayOf, &run.Language, &run.ExitCode, &run.DurationMS, &run.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("run", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: get run: %w", err)
+	}
+	return run, nil
+}
+
+// ListRuns returns a page of userID's runs, newest first, their code included.
+func (db *DB) ListRuns(ctx context.Context, userID string, limit, offset int) ([]model.Run, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT id, user_id, code, requirements, gpu, replay_of, language, exit_code, duration_ms, created_at FROM runs
+		 WHERE user_id = ? ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`, userID, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []model.Run{}
+	for rows.Next() {
+		var run model.Run
+		if err := rows.Scan(&run.ID, &run.UserID, &run.Code, &run.Requirements, &run.GPU, &run.ReplayOf, &run.Language, &run.ExitCode, &run.DurationMS, &run.CreatedAt); err != nil {
+			return nil, fmt.Errorf("sqlite: scan run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite: list runs: %w", err)
+	}
+	return runs, nil
+}
+
+// CountRuns counts userID's runs.
+func (db *DB) CountRuns(ctx context.Context, userID string) (int, error) {
+	var n int
+	err := db.conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM runs WHERE user_id = ?`, userID).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("sqlite: count runs: %w", err)
+	}
+	return n, nil
+}
+
+// GetUserStats sums up userID's snippets and runs. Every number is an
+// aggregate computed by SQLite, so no run or snippet rows are loaded.
+func (db *DB) GetUserStats(ctx context.Context, userID string, since time.Time) (*model.UserStats, error) {
+	stats := &model.UserStats{Activity: []model.DayActivity{}}
+
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM snippets WHERE user_id = ?`, userID,
+	).Scan(&stats.Snippets)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: count user snippets: %w", err)
+	}
+
+	err = db.conn.QueryRowContext(ctx,
+		`SELECT COUNT(*), COALESCE(SUM(exit_code = 0), 0), COALESCE(AVG(duration_ms), 0)
+		 FROM runs WHERE user_id = ?`, userID,
+	).Scan(&stats.Runs, &stats.SuccessfulRuns, &stats.AverageRuntimeMS)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: sum user runs: %w", err)
+	}
+	stats.FailedRuns = stats.Runs - stats.SuccessfulRuns
+	if stats.Runs > 0 {
+		stats.SuccessRate = float64(stats.SuccessfulRuns) / float64(stats.Runs)
+	}
+
+	err = db.conn.QueryRowContext(ctx,
+		`SELECT language FROM runs WHERE user_id = ?
+		 GROUP BY language ORDER BY COUNT(*) DESC, language LIMIT 1`, userID,
+	).Scan(&stats.MostUsedLanguage)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("sqlite: most used language: %w", err)
+	}
+
+	// Timestamps are stored in UTC, but a day on the activity grid is a day
+	// where since is (the server's local time, for StatsService): a run at
+	// 23:30 in New York is on the 14th there and the 15th in UTC. So the
+	// days are counted here rather than by SQLite, from the creation times
+	// alone — a few hundred at most, within the window.
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT created_at, 1 FROM snippets WHERE user_id = ? AND created_at >= ?
+		 UNION ALL
+		 SELECT created_at, 0 FROM runs WHERE user_id = ? AND created_at >= ?`,
+		userID, since, userID, since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: user activity: %w", err)
+	}
+	defer rows.Close()
+	byDate := map[string]int{}
+	for rows.Next() {
+		var createdAt time.Time
+		var isSnippet bool
+		if err := rows.Scan(&createdAt, &isSnippet); err != nil {
+			return nil, fmt.Errorf("sqlite: scan user activity: %w", err)
+		}
+		date := createdAt.In(since.Location()).Format(time.DateOnly)
+		i, ok := byDate[date]
+		if !ok {
+			i = len(stats.Activity)
+			byDate[date] = i
+			stats.Activity = append(stats.Activity, model.DayActivity{Date: date})
+		}
+		if isSnippet {
+			stats.Activity[i].Snippets++
+		} else {
+			stats.Activity[i].Runs++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite: user activity: %w", err)
+	}
+	slices.SortFunc(stats.Activity, func(a, b model.DayActivity) int { return strings.Compare(a.Date, b.Date) })
+	return stats, nil
+}
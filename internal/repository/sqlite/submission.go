@@ -0,0 +1,119 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+var _ repository.SubmissionRepository = (*DB)(nil)
+
+// CreateSubmission saves a graded submission.
+func (db *DB) CreateSubmission(ctx context.Context, s *model.Submission) error {
+	s.ID = xid.New().String()
+	s.CreatedAt = time.Now().UTC()
+
+	results, err := json.Marshal(s.Results)
+	if err != nil {
+		return fmt.Errorf("sqlite: encode submission results: %w", err)
+	}
+	coverage, err := encodeCoverage(s.Coverage)
+	if err != nil {
+		return err
+	}
+	output, outputEncoding, err := encodeOutput(s.Output)
+	if err != nil {
+		return fmt.Errorf("sqlite: encode submission output: %w", err)
+	}
+	_, err = db.exec(ctx,
+		`INSERT INTO submissions
+		     (id, exercise_id, assignment_id, challenge_date, user_id, code, status, score, passed, total,
+		      results, error, output, output_encoding, late, duration_ms, hints_used, coverage, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.ID, s.ExerciseID, s.AssignmentID, s.ChallengeDate, s.UserID, s.Code, s.Status, s.Score, s.Passed, s.Total,
+		string(results), s.Error, output, outputEncoding, s.Late, s.DurationMS, s.HintsUsed, coverage, s.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: create submission: %w", err)
+	}
+	return nil
+}
+
+// ListSubmissions returns matching submissions, newest first.
+func (db *DB) ListSubmissions(ctx context.Context, f repository.SubmissionFilter) ([]model.Submission, error) {
+	var where []string
+	var args []any
+	if f.ExerciseID != "" {
+		where = append(where, "s.exercise_id = ?")
+		args = append(args, f.ExerciseID)
+	}
+	if f.AssignmentID != "" {
+		where = append(where, "s.assignment_id = ?")
+		args = append(args, f.AssignmentID)
+	}
+	if f.UserID != "" {
+		where = append(where, "s.user_id = ?")
+		args = append(args, f.UserID)
+	}
+	if f.ClassID != "" {
+		where = append(where, `s.user_id IN (SELECT user_id FROM class_members WHERE class_id = ? AND role = 'student')`)
+		args = append(args, f.ClassID)
+	}
+	query := `SELECT s.id, s.exercise_id, s.assignment_id, s.challenge_date, s.user_id, s.code, s.status, s.score,
+	                 s.passed, s.total, s.results, s.error, s.output, s.output_encoding, s.late, s.duration_ms, s.hints_used, s.coverage, s.created_at
+	          FROM submissions s`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY s.created_at DESC, s.id DESC"
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list submissions: %w", err)
+	}
+	defer rows.Close()
+
+	submissions := []model.Submission{}
+	for rows.Next() {
+		var s model.Submission
+		var results, outputEncoding, coverage string
+		var output []byte
+		if err := rows.Scan(&s.ID, &s.ExerciseID, &s.AssignmentID, &s.ChallengeDate, &s.UserID, &s.Code, &s.Status, &s.Score,
+			&s.Passed, &s.Total, &results, &s.Error, &output, &outputEncoding, &s.Late, &s.DurationMS, &s.HintsUsed, &coverage, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("sqlite: scan submission: %w", err)
+		}
+		if s.Output, err = decodeOutput(output, outputEncoding); err != nil {
+			return nil, fmt.Errorf("sqlite: decode submission %s: %w", s.ID, err)
+		}
+		if err := json.Unmarshal([]byte(results), &s.Results); err != nil {
+			return nil, fmt.Errorf("sqlite: decode submission results: %w", err)
+		}
+		if coverage != "" {
+			if err := json.Unmarshal([]byte(coverage), &s.Coverage); err != nil {
+				return nil, fmt.Errorf("sqlite: decode submission coverage: %w", err)
+			}
+		}
+		submissions = append(submissions, s)
+	}
+	return submissions, rows.Err()
+}
+
+// encodeCoverage stores a submission's coverage as JSON, or an empty string
+// when there's none.
+func encodeCoverage(c *model.Coverage) (string, error) {
+	if c == nil {
+		return "", nil
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("sqlite: encode submission coverage: %w", err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,246 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	sqlitedriver "modernc.org/sqlite"
+)
+
+// TIMESTAMPS:
+// SQLite has no time type: a DATETIME column holds text, and the driver
+// writes a time.Time as whatever time.Time.String() gives — in the time's
+// own zone. Times written in local time and in UTC then sit side by side,
+// and since queries compare timestamps as text ("created_at > ?"), a
+// comparison across zones, or across a DST change in local time, is off by
+// the difference:
+//
+//	2026-03-08 01:30:00 -0500 EST    (06:30 UTC)
+//	2026-03-08 03:10:00 -0400 EDT    (07:10 UTC)
+//	2026-03-08 06:45:00 +0000 UTC    sorts first, though it's in between
+//
+// So every connection goes through a thin wrapper around the driver:
+//
+//   - every time.Time argument is written in UTC, in one fixed-width
+//     layout (timeLayout), so comparing the text is comparing the instants
+//     whatever the server's zone, and SQLite's own date functions read it;
+//   - every time.Time read back is in UTC, whatever offset the row was
+//     written with, so models always hold UTC (and JSON shows
+//     "2026-03-08T06:45:00Z").
+//
+// Rows written before this (with local offsets, or CURRENT_TIMESTAMP's
+// zoneless UTC) are rewritten once, at migration (normalizeTimestamps).
+// Turning a UTC time into a local day, as the activity grid does, is up to
+// whoever asks — see GetUserStats.
+
+// timeLayout is how timestamps are stored: RFC 3339 with a space for the
+// T, as SQLite writes them itself, and always nine fractional digits so
+// that every value is the same length and sorts as text.
+const timeLayout = "2006-01-02 15:04:05.000000000Z07:00"
+
+// connector opens connections to the database at dsn, wrapped in utcConn.
+type connector struct {
+	dsn    string
+	driver *sqlitedriver.Driver
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	inner, ok := conn.(sqliteConn)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("sqlite: driver connection %T is missing methods", conn)
+	}
+	return utcConn{inner}, nil
+}
+
+func (c connector) Driver() driver.Driver { return c.driver }
+
+// sqliteConn is what database/sql uses of a modernc.org/sqlite connection.
+type sqliteConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+// utcConn writes time.Time arguments in UTC and reads them back in UTC.
+type utcConn struct {
+	sqliteConn
+}
+
+// CheckNamedValue stores a time — a time.Time, *time.Time or sql.NullTime
+// — as UTC text in timeLayout. Every other value is left to database/sql's
+// usual conversion.
+func (utcConn) CheckNamedValue(nv *driver.NamedValue) error {
+	v, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return driver.ErrSkip
+	}
+	t, ok := v.(time.Time)
+	if !ok {
+		return driver.ErrSkip
+	}
+	nv.Value = formatTime(t)
+	return nil
+}
+
+func (c utcConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c utcConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.sqliteConn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return utcStmt{stmt.(sqliteStmt)}, nil
+}
+
+func (c utcConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := c.sqliteConn.QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return utcRows{rows}, nil
+}
+
+// sqliteStmt is what database/sql uses of a modernc.org/sqlite statement.
+type sqliteStmt interface {
+	driver.Stmt
+	driver.StmtExecContext
+	driver.StmtQueryContext
+}
+
+type utcStmt struct {
+	sqliteStmt
+}
+
+func (s utcStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := s.sqliteStmt.QueryContext(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return utcRows{rows}, nil
+}
+
+type utcRows struct {
+	driver.Rows
+}
+
+func (r utcRows) Next(dest []driver.Value) error {
+	if err := r.Rows.Next(dest); err != nil {
+		return err
+	}
+	for i, v := range dest {
+		if t, ok := v.(time.Time); ok {
+			dest[i] = t.UTC()
+		}
+	}
+	return nil
+}
+
+// formatTime is t as stored.
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+// normalizeTimestamps rewrites every timestamp stored before timeLayout, in
+// every DATETIME column, to timeLayout in UTC. PRAGMA user_version records
+// that it's done, so it runs once per database.
+func (db *DB) normalizeTimestamps() error {
+	var version int
+	if err := db.conn.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+	if version >= 1 {
+		return nil
+	}
+
+	rows, err := db.conn.Query(
+		`SELECT m.name, c.name FROM sqlite_master m, pragma_table_info(m.name) c
+		 WHERE m.type = 'table' AND upper(c.type) IN ('DATETIME', 'TIMESTAMP')`,
+	)
+	if err != nil {
+		return fmt.Errorf("listing timestamp columns: %w", err)
+	}
+	var columns [][2]string
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			rows.Close()
+			return fmt.Errorf("listing timestamp columns: %w", err)
+		}
+		columns = append(columns, [2]string{table, column})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("listing timestamp columns: %w", err)
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, tc := range columns {
+		if err := normalizeColumn(tx, tc[0], tc[1]); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`PRAGMA user_version = 1`); err != nil {
+		return fmt.Errorf("recording schema version: %w", err)
+	}
+	return tx.Commit()
+}
+
+// normalizeColumn rewrites table.column's text timestamps. The driver has
+// already parsed them (into UTC, by utcRows); writing them back stores
+// them in timeLayout. Text that isn't a timestamp is left alone.
+func normalizeColumn(tx *sql.Tx, table, column string) error {
+	// Identifiers can't be bound as ? parameters, but these come from the
+	// schema itself — never user input.
+	rows, err := tx.Query(fmt.Sprintf(`SELECT rowid, %s FROM %s WHERE typeof(%s) = 'text'`, column, table, column))
+	if err != nil {
+		return fmt.Errorf("reading %s.%s: %w", table, column, err)
+	}
+	type stamp struct {
+		rowid int64
+		t     time.Time
+	}
+	var stamps []stamp
+	for rows.Next() {
+		var s stamp
+		var v any
+		if err := rows.Scan(&s.rowid, &v); err != nil {
+			rows.Close()
+			return fmt.Errorf("reading %s.%s: %w", table, column, err)
+		}
+		if t, ok := v.(time.Time); ok {
+			s.t = t
+			stamps = append(stamps, s)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reading %s.%s: %w", table, column, err)
+	}
+
+	update := fmt.Sprintf(`UPDATE %s SET %s = ? WHERE rowid = ?`, table, column)
+	for _, s := range stamps {
+		if _, err := tx.Exec(update, s.t, s.rowid); err != nil {
+			return fmt.Errorf("rewriting %s.%s: %w", table, column, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+var _ repository.TransferRepository = (*DB)(nil)
+
+// CreateTransfer saves a new offer. The UNIQUE snippet_id keeps it to one
+// per snippet.
+func (db *DB) CreateTransfer(ctx context.Context, t *model.SnippetTransfer) error {
+	t.ID = xid.New().String()
+	t.CreatedAt = time.Now().UTC()
+	_, err := db.exec(ctx,
+		`INSERT INTO snippet_transfers (id, snippet_id, from_user_id, from_org_id, to_user_id, to_org_id, created_by, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.ID, t.SnippetID, t.FromUserID, t.FromOrgID, t.ToUserID, t.ToOrgID, t.CreatedBy, t.CreatedAt,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return &apperror.AppError{Err: apperror.ErrConflict, Message: "this snippet already has a transfer waiting to be accepted"}
+		}
+		return fmt.Errorf("sqlite: create transfer: %w", err)
+	}
+	return nil
+}
+
+const transferQuery = `SELECT t.id, t.snippet_id, s.name, t.from_user_id, t.from_org_id, t.to_user_id, t.to_org_id, t.created_by, t.created_at
+	FROM snippet_transfers t JOIN snippets s ON s.id = t.snippet_id`
+
+// scanTransfer reads one row of transferQuery.
+func scanTransfer(row interface{ Scan(...any) error }) (*model.SnippetTransfer, error) {
+	var t model.SnippetTransfer
+	err := row.Scan(&t.ID, &t.SnippetID, &t.SnippetName, &t.FromUserID, &t.FromOrgID, &t.ToUserID, &t.ToOrgID, &t.CreatedBy, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetTransfer returns an offer by ID.
+func (db *DB) GetTransfer(ctx context.Context, id string) (*model.SnippetTransfer, error) {
+	t, err := scanTransfer(db.conn.QueryRowContext(ctx, transferQuery+` WHERE t.id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("transfer", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: get transfer: %w", err)
+	}
+	return t, nil
+}
+
+// ListTransfersTo returns the offers userID can accept.
+func (db *DB) ListTransfersTo(ctx context.Context, userID string) ([]model.SnippetTransfer, error) {
+	return db.listTransfers(ctx,
+		` WHERE t.to_user_id = ?
+		     OR t.to_org_id IN (SELECT org_id FROM org_members WHERE user_id = ? AND role = 'owner')`,
+		userID, userID,
+	)
+}
+
+// ListTransfersBy returns the offers userID made.
+func (db *DB) ListTransfersBy(ctx context.Context, userID string) ([]model.SnippetTransfer, error) {
+	return db.listTransfers(ctx, ` WHERE t.created_by = ?`, userID)
+}
+
+func (db *DB) listTransfers(ctx context.Context, where string, args ...any) ([]model.SnippetTransfer, error) {
+	rows, err := db.conn.QueryContext(ctx, transferQuery+where+` ORDER BY t.created_at DESC, t.id DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list transfers: %w", err)
+	}
+	defer rows.Close()
+
+	transfers := []model.SnippetTransfer{}
+	for rows.Next() {
+		t, err := scanTransfer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("sqlite: scan transfer: %w", err)
+		}
+		transfers = append(transfers, *t)
+	}
+	return transfers, rows.Err()
+}
+
+// DeleteTransfer deletes an offer.
+func (db *DB) DeleteTransfer(ctx context.Context, id string) error {
+	result, err := db.exec(ctx, `DELETE FROM snippet_transfers WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("sqlite: delete transfer: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("sqlite: delete transfer: %w", err)
+	} else if n == 0 {
+		return apperror.NotFound("transfer", id)
+	}
+	return nil
+}
+
+// CompleteTransfer moves the snippet and deletes the offer in one
+// transaction. The UPDATE only matches while the snippet still has the
+// owners the offer was made from. updated_at moves too, so cached copies
+// (see handler/conditional.go) pick up the new owner.
+func (db *DB) CompleteTransfer(ctx context.Context, t *model.SnippetTransfer, userID, orgID string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite: complete transfer: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE snippets SET user_id = ?, org_id = ?, updated_at = ?
+		 WHERE id = ? AND user_id = ? AND org_id = ?`,
+		userID, orgID, time.Now().UTC(), t.SnippetID, t.FromUserID, t.FromOrgID,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: transfer snippet: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("sqlite: transfer snippet: %w", err)
+	} else if n == 0 {
+		return &apperror.AppError{Err: apperror.ErrConflict, Message: "the snippet has changed hands since this transfer was offered"}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM snippet_transfers WHERE id = ?`, t.ID); err != nil {
+		return fmt.Errorf("sqlite: delete transfer: %w", err)
+	}
+	return tx.Commit()
+}
@@ -14,11 +14,12 @@ import (
 // UPSERT PATTERN (INSERT ... ON CONFLICT DO UPDATE):
 // If a user with this github_id already exists, we update their profile fields
 // (login, email, avatar_url) to stay in sync with GitHub — users can change
-// their username/email on GitHub at any time.
+// their username/email on GitHub at any time. The role is deliberately NOT
+// updated — it is managed by admins, not by GitHub.
 func (db *DB) Upsert(ctx context.Context, user *model.User) error {
-	now := time.Now()
+	now := time.Now().UTC()
 
-	_, err := db.conn.ExecContext(ctx,
+	_, err := db.exec(ctx,
 		`INSERT INTO users (id, github_id, login, email, avatar_url, created_at, updated_at)
 		 VALUES (?, ?, ?, ?, ?, ?, ?)
 		 ON CONFLICT(github_id) DO UPDATE SET
@@ -34,23 +35,23 @@ func (db *DB) Upsert(ctx context.Context, user *model.User) error {
 
 	// Retrieve the actual row (in case it was an update, the ID is the existing one)
 	row := db.conn.QueryRowContext(ctx,
-		`SELECT id, created_at, updated_at FROM users WHERE github_id = ?`,
+		`SELECT id, role, leaderboard_opt_out, suspended, created_at, updated_at FROM users WHERE github_id = ?`,
 		user.GitHubID,
 	)
-	return row.Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	return row.Scan(&user.ID, &user.Role, &user.LeaderboardOptOut, &user.Suspended, &user.CreatedAt, &user.UpdatedAt)
 }
 
 // GetUserByID retrieves a user by their internal ID.
 func (db *DB) GetUserByID(ctx context.Context, id string) (*model.User, error) {
 	row := db.conn.QueryRowContext(ctx,
-		`SELECT id, github_id, login, email, avatar_url, created_at, updated_at
+		`SELECT id, github_id, login, email, avatar_url, role, leaderboard_opt_out, suspended, created_at, updated_at
 		 FROM users WHERE id = ?`, id,
 	)
 
 	var user model.User
 	err := row.Scan(
 		&user.ID, &user.GitHubID, &user.Login, &user.Email,
-		&user.AvatarURL, &user.CreatedAt, &user.UpdatedAt,
+		&user.AvatarURL, &user.Role, &user.LeaderboardOptOut, &user.Suspended, &user.CreatedAt, &user.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -60,3 +61,184 @@ func (db *DB) GetUserByID(ctx context.Context, id string) (*model.User, error) {
 	}
 	return &user, nil
 }
+
+// GetUserByLogin retrieves a user by their GitHub login.
+// GitHub logins are case-insensitive, so the comparison is too.
+func (db *DB) GetUserByLogin(ctx context.Context, login string) (*model.User, error) {
+	row := db.conn.QueryRowContext(ctx,
+		`SELECT id, github_id, login, email, avatar_url, role, leaderboard_opt_out, suspended, created_at, updated_at
+		 FROM users WHERE login = ? COLLATE NOCASE`, login,
+	)
+
+	var user model.User
+	err := row.Scan(
+		&user.ID, &user.GitHubID, &user.Login, &user.Email,
+		&user.AvatarURL, &user.Role, &user.LeaderboardOptOut, &user.Suspended, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: get user by login: %w", err)
+	}
+	return &user, nil
+}
+
+// GetUserByEmail retrieves the oldest user with this email address. Email
+// addresses are compared case-insensitively, and "" matches nobody.
+func (db *DB) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	if email == "" {
+		return nil, nil
+	}
+	row := db.conn.QueryRowContext(ctx,
+		`SELECT id, github_id, login, email, avatar_url, role, leaderboard_opt_out, suspended, created_at, updated_at
+		 FROM users WHERE email = ? COLLATE NOCASE ORDER BY created_at LIMIT 1`, email,
+	)
+
+	var user model.User
+	err := row.Scan(
+		&user.ID, &user.GitHubID, &user.Login, &user.Email,
+		&user.AvatarURL, &user.Role, &user.LeaderboardOptOut, &user.Suspended, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: get user by email: %w", err)
+	}
+	return &user, nil
+}
+
+// SetUserRole changes a user's role.
+func (db *DB) SetUserRole(ctx context.Context, id, role string) error {
+	_, err := db.exec(ctx,
+		`UPDATE users SET role = ?, updated_at = ? WHERE id = ?`, role, time.Now().UTC(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: set user role: %w", err)
+	}
+	return nil
+}
+
+// SetLeaderboardOptOut keeps a user off leaderboards, or puts them back.
+func (db *DB) SetLeaderboardOptOut(ctx context.Context, id string, optOut bool) error {
+	_, err := db.exec(ctx,
+		`UPDATE users SET leaderboard_opt_out = ?, updated_at = ? WHERE id = ?`, optOut, time.Now().UTC(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: set leaderboard opt-out: %w", err)
+	}
+	return nil
+}
+
+// SetUserSuspended suspends a user, or lifts their suspension.
+func (db *DB) SetUserSuspended(ctx context.Context, id string, suspended bool) error {
+	_, err := db.exec(ctx,
+		`UPDATE users SET suspended = ?, updated_at = ? WHERE id = ?`, suspended, time.Now().UTC(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: set user suspended: %w", err)
+	}
+	return nil
+}
+
+// DeleteUser removes a user, the snippets, webhooks and exercise submissions
+// they own, their class memberships, leaderboard entries and unlocked hints.
+// Snippets they created in an org stay with the org, unless they were its
+// last member.
+//
+// TRANSACTIONS:
+// All the DELETEs run in one transaction: either the user and all their data
+// are gone, or (if anything fails) nothing changed. Without it, a crash between
+// the statements could leave orphaned snippets behind.
+func (db *DB) DeleteUser(ctx context.Context, id string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite: delete user: %w", err)
+	}
+	defer tx.Rollback() // no-op after a successful Commit
+
+	// Their org memberships. An org left with nobody in it is deleted, and
+	// its snippets become personal ones again, like with DeleteOrg.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM org_members WHERE user_id = ?`, id); err != nil {
+		return fmt.Errorf("sqlite: delete user org memberships: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE snippets SET org_id = '' WHERE org_id <> '' AND org_id NOT IN (SELECT org_id FROM org_members)`); err != nil {
+		return fmt.Errorf("sqlite: release empty orgs' snippets: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM orgs WHERE id NOT IN (SELECT org_id FROM org_members)`); err != nil {
+		return fmt.Errorf("sqlite: delete empty orgs: %w", err)
+	}
+
+	// Transfers they offered or were offered. Offers of their personal
+	// snippets go with the snippets, by cascade.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM snippet_transfers WHERE created_by = ? OR to_user_id = ?`, id, id); err != nil {
+		return fmt.Errorf("sqlite: delete user snippet transfers: %w", err)
+	}
+
+	// Comments they wrote, and comments on their personal snippets.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM comments WHERE user_id = ? OR snippet_id IN (SELECT id FROM snippets WHERE user_id = ? AND org_id = '')`, id, id); err != nil {
+		return fmt.Errorf("sqlite: delete user comments: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM snippets WHERE user_id = ? AND org_id = ''`, id); err != nil {
+		return fmt.Errorf("sqlite: delete user snippets: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM webhooks WHERE user_id = ?`, id); err != nil {
+		return fmt.Errorf("sqlite: delete user webhooks: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM submissions WHERE user_id = ?`, id); err != nil {
+		return fmt.Errorf("sqlite: delete user submissions: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM class_members WHERE user_id = ?`, id); err != nil {
+		return fmt.Errorf("sqlite: delete user class memberships: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM leaderboard_entries WHERE user_id = ?`, id); err != nil {
+		return fmt.Errorf("sqlite: delete user leaderboard entries: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM hint_unlocks WHERE user_id = ?`, id); err != nil {
+		return fmt.Errorf("sqlite: delete user hint unlocks: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_badges WHERE user_id = ?`, id); err != nil {
+		return fmt.Errorf("sqlite: delete user badges: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM badge_counters WHERE user_id = ?`, id); err != nil {
+		return fmt.Errorf("sqlite: delete user badge counters: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM snippet_stars WHERE user_id = ?`, id); err != nil {
+		return fmt.Errorf("sqlite: delete user stars: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM notifications WHERE user_id = ?`, id); err != nil {
+		return fmt.Errorf("sqlite: delete user notifications: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM email_preferences WHERE user_id = ?`, id); err != nil {
+		return fmt.Errorf("sqlite: delete user email preferences: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM magic_links WHERE user_id = ?`, id); err != nil {
+		return fmt.Errorf("sqlite: delete user sign-in links: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM activity_events WHERE actor_id = ?`, id); err != nil {
+		return fmt.Errorf("sqlite: delete user activity: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM analytics_events WHERE user_id = ?`, id); err != nil {
+		return fmt.Errorf("sqlite: delete user analytics events: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM runs WHERE user_id = ?`, id); err != nil {
+		return fmt.Errorf("sqlite: delete user runs: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("sqlite: delete user: %w", err)
+	}
+	return tx.Commit()
+}
+
+// CountUsers counts users created after createdAfter, or all users when it's zero.
+func (db *DB) CountUsers(ctx context.Context, createdAfter time.Time) (int, error) {
+	var n int
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM users WHERE created_at > ?`, createdAfter,
+	).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("sqlite: count users: %w", err)
+	}
+	return n, nil
+}
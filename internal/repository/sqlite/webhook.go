@@ -0,0 +1,152 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+var _ repository.WebhookRepository = (*DB)(nil)
+
+// CreateWebhook saves a new webhook.
+func (db *DB) CreateWebhook(ctx context.Context, webhook *model.Webhook) error {
+	webhook.ID = xid.New().String()
+	webhook.CreatedAt = time.Now().UTC()
+
+	_, err := db.exec(ctx,
+		`INSERT INTO webhooks (id, user_id, url, secret, events, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		webhook.ID, webhook.UserID, webhook.URL, webhook.Secret,
+		strings.Join(webhook.Events, ","), webhook.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: create webhook: %w", err)
+	}
+	return nil
+}
+
+// GetWebhook returns a webhook by ID.
+func (db *DB) GetWebhook(ctx context.Context, id string) (*model.Webhook, error) {
+	row := db.conn.QueryRowContext(ctx,
+		`SELECT id, user_id, url, secret, events, created_at FROM webhooks WHERE id = ?`, id,
+	)
+	webhook, err := scanWebhook(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("webhook", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: get webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// ListWebhooks returns a user's webhooks, oldest first.
+func (db *DB) ListWebhooks(ctx context.Context, userID string) ([]model.Webhook, error) {
+	return db.queryWebhooks(ctx,
+		`SELECT id, user_id, url, secret, events, created_at FROM webhooks
+		 WHERE user_id = ? ORDER BY created_at, id`, userID,
+	)
+}
+
+// ListWebhooksForEvent returns a user's webhooks subscribed to event.
+// Wrapping both sides in commas makes the LIKE match whole event names only.
+func (db *DB) ListWebhooksForEvent(ctx context.Context, userID, event string) ([]model.Webhook, error) {
+	return db.queryWebhooks(ctx,
+		`SELECT id, user_id, url, secret, events, created_at FROM webhooks
+		 WHERE user_id = ? AND ',' || events || ',' LIKE '%,' || ? || ',%'
+		 ORDER BY created_at, id`, userID, event,
+	)
+}
+
+// DeleteWebhook removes a webhook; its deliveries go with it (ON DELETE CASCADE).
+func (db *DB) DeleteWebhook(ctx context.Context, id string) error {
+	result, err := db.exec(ctx, `DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("sqlite: delete webhook: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperror.NotFound("webhook", id)
+	}
+	return nil
+}
+
+// RecordWebhookDelivery appends to a webhook's delivery log.
+func (db *DB) RecordWebhookDelivery(ctx context.Context, d *model.WebhookDelivery) error {
+	d.ID = xid.New().String()
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now().UTC()
+	}
+
+	_, err := db.exec(ctx,
+		`INSERT INTO webhook_deliveries
+		     (id, webhook_id, event_id, event, attempt, status_code, error, duration_ms, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		d.ID, d.WebhookID, d.EventID, d.Event, d.Attempt, d.StatusCode, d.Error, d.DurationMS, d.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("sqlite: record webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns a webhook's most recent deliveries, newest first.
+// xids sort by creation time, so id breaks ties within the same timestamp.
+func (db *DB) ListWebhookDeliveries(ctx context.Context, webhookID string, limit int) ([]model.WebhookDelivery, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT id, webhook_id, event_id, event, attempt, status_code, error, duration_ms, created_at
+		 FROM webhook_deliveries WHERE webhook_id = ?
+		 ORDER BY created_at DESC, id DESC LIMIT ?`, webhookID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []model.WebhookDelivery{}
+	for rows.Next() {
+		var d model.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.Event, &d.Attempt,
+			&d.StatusCode, &d.Error, &d.DurationMS, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("sqlite: scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (db *DB) queryWebhooks(ctx context.Context, query string, args ...any) ([]model.Webhook, error) {
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []model.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("sqlite: scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// scanWebhook reads a webhook row, splitting the events column.
+func scanWebhook(row interface{ Scan(...any) error }) (*model.Webhook, error) {
+	var w model.Webhook
+	var events string
+	if err := row.Scan(&w.ID, &w.UserID, &w.URL, &w.Secret, &events, &w.CreatedAt); err != nil {
+		return nil, err
+	}
+	w.Events = strings.Split(events, ",")
+	return &w, nil
+}
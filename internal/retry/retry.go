@@ -0,0 +1,140 @@
+// Package retry runs an operation again when it fails in a way that's
+// likely to pass on its own: a busy SQLite database, a Docker daemon that
+// dropped a connection.
+//
+// WHY RETRY IN-PROCESS?
+// Without it, a write that meets SQLITE_BUSY, or a container create that
+// meets a restarting daemon, turns straight into a 500, though the same call
+// a few milliseconds later would have worked. A short retry loop hides that
+// from the user. It's only for errors known to be transient: retrying a
+// constraint violation or a malformed request wastes time and hides the bug.
+//
+// BACKOFF WITH JITTER:
+// The delay doubles after each failure (Base, 2×Base, 4×Base, ... capped at
+// Max), and each delay is randomised between half and all of that. Without
+// the jitter, requests that failed together retry together and collide
+// again — the "thundering herd".
+//
+// Usage:
+//
+//	err := retry.Do(ctx, policy, func(ctx context.Context) error {
+//		_, err := db.ExecContext(ctx, query, args...)
+//		return err
+//	})
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// Policy says which errors to retry, how often and how long to wait.
+type Policy struct {
+	// Attempts is how many tries to make in all, the first included
+	// (default 3). 1 turns retrying off.
+	Attempts int
+	// Base is the delay before the first retry (default 10ms); Max caps any
+	// one delay (default 1s).
+	Base time.Duration
+	Max  time.Duration
+	// Retryable reports whether an error is worth another try. nil retries
+	// every error. Context errors are never retried.
+	Retryable func(error) bool
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.Attempts <= 0 {
+		p.Attempts = 3
+	}
+	if p.Base <= 0 {
+		p.Base = 10 * time.Millisecond
+	}
+	if p.Max <= 0 {
+		p.Max = time.Second
+	}
+	return p
+}
+
+// Stats counts retries across the process, for the metrics endpoint.
+type Stats struct {
+	Retries   int64 // tries after the first
+	Recovered int64 // operations that failed at first and then succeeded
+	Exhausted int64 // operations that were retried and still failed
+}
+
+var retries, recovered, exhausted atomic.Int64
+
+// ReadStats returns the counts so far.
+func ReadStats() Stats {
+	return Stats{
+		Retries:   retries.Load(),
+		Recovered: recovered.Load(),
+		Exhausted: exhausted.Load(),
+	}
+}
+
+// Do calls fn until it succeeds, returns an error p doesn't retry, runs out
+// of attempts or ctx is done. It returns fn's last error — or ctx's, if ctx
+// ended while waiting to retry.
+func Do(ctx context.Context, p Policy, fn func(context.Context) error) error {
+	p = p.withDefaults()
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			if attempt > 1 {
+				recovered.Add(1)
+			}
+			return nil
+		}
+		if !p.retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		if attempt == p.Attempts {
+			exhausted.Add(1)
+			return err
+		}
+
+		t := time.NewTimer(p.delay(attempt))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+		retries.Add(1)
+	}
+}
+
+// DoValue is Do for a function that returns a value too.
+func DoValue[T any](ctx context.Context, p Policy, fn func(context.Context) (T, error)) (T, error) {
+	var v T
+	err := Do(ctx, p, func(ctx context.Context) error {
+		var err error
+		v, err = fn(ctx)
+		return err
+	})
+	return v, err
+}
+
+func (p Policy) retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// delay returns how long to wait after failed try number attempt (1-based):
+// Base × 2^(attempt-1), capped at Max, then jittered to between half and
+// all of that.
+func (p Policy) delay(attempt int) time.Duration {
+	d := p.Base
+	for i := 1; i < attempt && d < p.Max; i++ {
+		d *= 2
+	}
+	d = min(d, p.Max)
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
@@ -0,0 +1,92 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errBusy = errors.New("database is locked")
+
+func TestDo(t *testing.T) {
+	ctx := context.Background()
+	fast := Policy{Attempts: 3, Base: time.Microsecond, Max: time.Millisecond}
+
+	t.Run("succeeds after transient failures", func(t *testing.T) {
+		before := ReadStats()
+		calls := 0
+		err := Do(ctx, fast, func(context.Context) error {
+			if calls++; calls < 3 {
+				return errBusy
+			}
+			return nil
+		})
+		if err != nil || calls != 3 {
+			t.Fatalf("Do() = %v after %d calls, want success on the third", err, calls)
+		}
+		after := ReadStats()
+		if after.Retries-before.Retries != 2 || after.Recovered-before.Recovered != 1 {
+			t.Errorf("stats went from %+v to %+v", before, after)
+		}
+	})
+
+	t.Run("gives up after Attempts", func(t *testing.T) {
+		before := ReadStats()
+		calls := 0
+		err := Do(ctx, fast, func(context.Context) error { calls++; return errBusy })
+		if !errors.Is(err, errBusy) || calls != 3 {
+			t.Errorf("Do() = %v after %d calls, want the last error after 3", err, calls)
+		}
+		if ReadStats().Exhausted-before.Exhausted != 1 {
+			t.Error("exhausted retries weren't counted")
+		}
+	})
+
+	t.Run("errors that aren't retryable return at once", func(t *testing.T) {
+		p := fast
+		p.Retryable = func(err error) bool { return errors.Is(err, errBusy) }
+		calls := 0
+		constraint := errors.New("UNIQUE constraint failed")
+		if err := Do(ctx, p, func(context.Context) error { calls++; return constraint }); err != constraint || calls != 1 {
+			t.Errorf("Do() = %v after %d calls", err, calls)
+		}
+	})
+
+	t.Run("stops when the context ends", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		p := Policy{Attempts: 5, Base: time.Hour, Max: time.Hour}
+		calls := 0
+		err := Do(ctx, p, func(context.Context) error { calls++; cancel(); return errBusy })
+		if err != errBusy || calls != 1 {
+			t.Errorf("Do() = %v after %d calls, want the error without waiting an hour", err, calls)
+		}
+	})
+
+	t.Run("DoValue", func(t *testing.T) {
+		calls := 0
+		n, err := DoValue(ctx, fast, func(context.Context) (int, error) {
+			if calls++; calls == 1 {
+				return 0, errBusy
+			}
+			return 42, nil
+		})
+		if n != 42 || err != nil {
+			t.Errorf("DoValue() = %d, %v", n, err)
+		}
+	})
+}
+
+func TestDelay(t *testing.T) {
+	p := Policy{Base: 10 * time.Millisecond, Max: 50 * time.Millisecond}
+	for _, tt := range []struct {
+		attempt int
+		max     time.Duration
+	}{{1, 10 * time.Millisecond}, {2, 20 * time.Millisecond}, {3, 40 * time.Millisecond}, {4, 50 * time.Millisecond}, {30, 50 * time.Millisecond}} {
+		for range 20 {
+			if d := p.delay(tt.attempt); d < tt.max/2 || d > tt.max {
+				t.Errorf("delay(%d) = %v, want between %v and %v", tt.attempt, d, tt.max/2, tt.max)
+			}
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Package scheduler runs recurring maintenance tasks on cron schedules.
+//
+// CRON EXPRESSIONS:
+// A cron expression has five space-separated fields:
+//
+//	┌───────── minute (0-59)
+//	│ ┌─────── hour (0-23)
+//	│ │ ┌───── day of month (1-31)
+//	│ │ │ ┌─── month (1-12)
+//	│ │ │ │ ┌─ day of week (0-6, Sunday = 0)
+//	│ │ │ │ │
+//	0 3 * * *     → every day at 03:00
+//	*/15 * * * *  → every 15 minutes
+//
+// Descriptors such as "@hourly", "@daily" and "@every 10m" also work.
+// Schedules are evaluated in UTC so they don't shift with daylight saving.
+//
+// SCHEDULER vs JOB QUEUE:
+// The job queue (internal/jobs) runs work that something ASKED for, once.
+// The scheduler runs work because the CLOCK says so, again and again.
+// Tasks run in-process and aren't persisted — a run missed while the server
+// was down is simply skipped.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// TaskFunc is the body of a scheduled task.
+type TaskFunc func(ctx context.Context) error
+
+// Status describes a task's schedule and its most recent run.
+type Status struct {
+	Name         string     `json:"name"`
+	Schedule     string     `json:"schedule"`
+	Running      bool       `json:"running"`
+	Runs         int        `json:"runs"`
+	LastRun      *time.Time `json:"lastRun,omitempty"`
+	LastDuration string     `json:"lastDuration,omitempty"`
+	LastError    string     `json:"lastError,omitempty"`
+	NextRun      *time.Time `json:"nextRun,omitempty"`
+}
+
+// task is a registered task plus its run bookkeeping (guarded by Scheduler.mu).
+type task struct {
+	fn      TaskFunc
+	entryID cron.EntryID
+	status  Status
+}
+
+// Scheduler runs registered tasks on their cron schedules.
+type Scheduler struct {
+	cron   *cron.Cron
+	logger *slog.Logger
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu    sync.Mutex
+	tasks map[string]*task
+}
+
+// New creates a Scheduler. Register tasks with Add, then call Start.
+func New(logger *slog.Logger) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		cron:   cron.New(cron.WithLocation(time.UTC)),
+		logger: logger,
+		ctx:    ctx,
+		cancel: cancel,
+		tasks:  make(map[string]*task),
+	}
+}
+
+// Add registers a task. An empty spec or "off" leaves the task disabled,
+// which lets deployments switch individual tasks off from config.
+func (s *Scheduler) Add(name, spec string, fn TaskFunc) error {
+	if spec == "" || spec == "off" {
+		s.logger.Info("scheduled task disabled", slog.String("task", name))
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.tasks[name]; exists {
+		return fmt.Errorf("scheduler: task %q already registered", name)
+	}
+
+	t := &task{fn: fn, status: Status{Name: name, Schedule: spec}}
+	id, err := s.cron.AddFunc(spec, func() { s.run(name, t) })
+	if err != nil {
+		return fmt.Errorf("scheduler: invalid schedule %q for task %q: %w", spec, name, err)
+	}
+	t.entryID = id
+	s.tasks[name] = t
+	return nil
+}
+
+// Start begins running tasks in the background.
+func (s *Scheduler) Start() {
+	s.cron.Start()
+	s.logger.Info("scheduler started", slog.Int("tasks", len(s.tasks)))
+}
+
+// Stop prevents new runs, cancels the context passed to running tasks and
+// waits for them to return (or for ctx to expire).
+func (s *Scheduler) Stop(ctx context.Context) error {
+	done := s.cron.Stop()
+	s.cancel()
+
+	select {
+	case <-done.Done():
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("scheduler: stop: %w", ctx.Err())
+	}
+}
+
+// run executes one task, skipping the tick if the previous run is still going.
+func (s *Scheduler) run(name string, t *task) {
+	s.mu.Lock()
+	if t.status.Running {
+		s.mu.Unlock()
+		s.logger.Warn("skipping scheduled task: previous run still in progress", slog.String("task", name))
+		return
+	}
+	t.status.Running = true
+	s.mu.Unlock()
+
+	start := time.Now()
+	err := safeRun(s.ctx, t.fn)
+	elapsed := time.Since(start)
+
+	s.mu.Lock()
+	t.status.Running = false
+	t.status.Runs++
+	t.status.LastRun = &start
+	t.status.LastDuration = elapsed.Round(time.Millisecond).String()
+	t.status.LastError = ""
+	if err != nil {
+		t.status.LastError = err.Error()
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		s.logger.Error("scheduled task failed",
+			slog.String("task", name),
+			slog.Duration("duration", elapsed),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	s.logger.Info("scheduled task finished",
+		slog.String("task", name),
+		slog.Duration("duration", elapsed),
+	)
+}
+
+// Statuses returns every registered task's status, sorted by name.
+func (s *Scheduler) Statuses() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all := make([]Status, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		st := t.status
+		if next := s.cron.Entry(t.entryID).Next; !next.IsZero() {
+			st.NextRun = &next
+		}
+		all = append(all, st)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
+
+// safeRun converts a panicking task into an error so the scheduler keeps going.
+func safeRun(ctx context.Context, fn TaskFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("task panicked: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func newTestScheduler() *Scheduler {
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestAdd_DisabledAndInvalid(t *testing.T) {
+	s := newTestScheduler()
+	noop := func(ctx context.Context) error { return nil }
+
+	if err := s.Add("off", "off", noop); err != nil {
+		t.Fatalf("Add(off) error = %v", err)
+	}
+	if err := s.Add("empty", "", noop); err != nil {
+		t.Fatalf("Add(empty) error = %v", err)
+	}
+	if got := len(s.Statuses()); got != 0 {
+		t.Errorf("disabled tasks registered: %d statuses", got)
+	}
+
+	if err := s.Add("bad", "every tuesday", noop); err == nil {
+		t.Error("Add() with invalid spec should fail")
+	}
+
+	if err := s.Add("ok", "@hourly", noop); err != nil {
+		t.Fatalf("Add(@hourly) error = %v", err)
+	}
+	if err := s.Add("ok", "@daily", noop); err == nil {
+		t.Error("Add() with duplicate name should fail")
+	}
+}
+
+func TestRun_RecordsStatus(t *testing.T) {
+	s := newTestScheduler()
+
+	fail := true
+	if err := s.Add("flaky", "@hourly", func(ctx context.Context) error {
+		if fail {
+			return errors.New("disk full")
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	s.Start()
+	defer s.Stop(context.Background())
+
+	// Invoke the task directly rather than waiting an hour for the tick.
+	s.run("flaky", s.tasks["flaky"])
+
+	st := s.Statuses()[0]
+	if st.Runs != 1 || st.LastError != "disk full" || st.LastRun == nil {
+		t.Errorf("after failure: %+v", st)
+	}
+	if st.NextRun == nil {
+		t.Error("NextRun should be set once the scheduler is running")
+	}
+
+	fail = false
+	s.run("flaky", s.tasks["flaky"])
+
+	st = s.Statuses()[0]
+	if st.Runs != 2 || st.LastError != "" {
+		t.Errorf("after success: %+v", st)
+	}
+}
+
+func TestRun_RecoversPanic(t *testing.T) {
+	s := newTestScheduler()
+	s.Add("boom", "@hourly", func(ctx context.Context) error { panic("oops") })
+
+	s.run("boom", s.tasks["boom"])
+
+	if st := s.Statuses()[0]; st.LastError != "task panicked: oops" {
+		t.Errorf("LastError = %q", st.LastError)
+	}
+}
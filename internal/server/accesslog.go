@@ -0,0 +1,96 @@
+package server
+
+// ACCESS LOGS vs APPLICATION LOGS:
+// Application logs describe what the program is doing ("snippet created",
+// "docker image is ready"). Access logs are one line per HTTP request
+// (method, path, status, duration). They have very different volumes and
+// consumers — access logs usually go to traffic analysis, application logs to
+// alerting — so operators want to ship them to different sinks.
+//
+// AccessLogPath selects the destination:
+//   - ""        → request lines go to the application logger (development default)
+//   - "stdout"  → a separate JSON logger on stdout
+//   - any path  → a JSON log file, rotated by size and age
+//
+// A file that can't be opened (a missing mount, a read-only directory) is
+// logged as a warning and the access log goes to stdout instead: losing
+// request lines to a typo is worse than finding them in the wrong place.
+//
+// ROTATION:
+// A log file that grows forever eventually fills the disk. lumberjack is an
+// io.Writer that renames the current file once it reaches MaxSize megabytes
+// (app-access.log → app-access-2024-01-02T15-04-05.000.log), starts a new one,
+// and deletes old backups by count (MaxBackups) and age (MaxAge days).
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/sakif/coding-playground/internal/middleware"
+)
+
+// Access log defaults applied when rotation limits are left at zero.
+const (
+	DefaultAccessLogMaxSizeMB  = 100
+	DefaultAccessLogMaxBackups = 5
+	DefaultAccessLogMaxAgeDays = 14
+)
+
+// newAccessLogger builds the logger used by the request logging middleware.
+// The returned io.Closer must be closed on shutdown (it may be a no-op).
+func newAccessLogger(cfg Config, appLogger *slog.Logger) (*slog.Logger, io.Closer) {
+	switch cfg.AccessLogPath {
+	case "":
+		return appLogger, nopCloser{}
+	case "stdout":
+		return slog.New(middleware.WithRequestID(slog.NewJSONHandler(os.Stdout, nil))), nopCloser{}
+	}
+
+	// lumberjack opens the file on the first write, so try it now.
+	if err := checkWritable(cfg.AccessLogPath); err != nil {
+		appLogger.Warn("access log file can't be opened; writing access logs to stdout",
+			slog.String("path", cfg.AccessLogPath),
+			slog.String("error", err.Error()),
+		)
+		return slog.New(middleware.WithRequestID(slog.NewJSONHandler(os.Stdout, nil))), nopCloser{}
+	}
+
+	rotator := &lumberjack.Logger{
+		Filename:   cfg.AccessLogPath,
+		MaxSize:    orDefault(cfg.AccessLogMaxSizeMB, DefaultAccessLogMaxSizeMB),
+		MaxBackups: orDefault(cfg.AccessLogMaxBackups, DefaultAccessLogMaxBackups),
+		MaxAge:     orDefault(cfg.AccessLogMaxAgeDays, DefaultAccessLogMaxAgeDays),
+		Compress:   true,
+	}
+	return slog.New(middleware.WithRequestID(slog.NewJSONHandler(rotator, nil))), rotator
+}
+
+// checkWritable makes sure path's directory exists and path can be opened
+// for appending, as lumberjack will.
+func checkWritable(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// orDefault returns v, or def when v is zero or negative.
+func orDefault(v, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
+
+// nopCloser is returned when the access log shares an existing stream.
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
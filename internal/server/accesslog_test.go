@@ -0,0 +1,125 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+func TestNewAccessLogger(t *testing.T) {
+	var appLogs bytes.Buffer
+	appLogger := slog.New(slog.NewTextHandler(&appLogs, nil))
+
+	t.Run("unset shares the application logger", func(t *testing.T) {
+		logger, closer := newAccessLogger(Config{}, appLogger)
+		defer closer.Close()
+		if logger != appLogger {
+			t.Error("got a logger of its own, want the application logger")
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "logs", "access.log")
+		logger, closer := newAccessLogger(Config{AccessLogPath: path}, appLogger)
+		logger.Info("request", slog.String("path", "/api/v1/snippets"), slog.Int("status", 200))
+		if err := closer.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("reading the access log: %v", err)
+		}
+		var line map[string]any
+		if err := json.Unmarshal(bytes.TrimSpace(data), &line); err != nil {
+			t.Fatalf("access log line isn't JSON: %v; got %q", err, data)
+		}
+		if line["msg"] != "request" || line["path"] != "/api/v1/snippets" {
+			t.Errorf("access log line = %v", line)
+		}
+	})
+
+	t.Run("rotation settings", func(t *testing.T) {
+		dir := t.TempDir()
+		for _, tt := range []struct {
+			name                     string
+			cfg                      Config
+			size, backups, ageInDays int
+		}{
+			{"defaults", Config{}, DefaultAccessLogMaxSizeMB, DefaultAccessLogMaxBackups, DefaultAccessLogMaxAgeDays},
+			{"set", Config{AccessLogMaxSizeMB: 10, AccessLogMaxBackups: 2, AccessLogMaxAgeDays: 3}, 10, 2, 3},
+			{"negative", Config{AccessLogMaxSizeMB: -1, AccessLogMaxBackups: -1, AccessLogMaxAgeDays: -1}, DefaultAccessLogMaxSizeMB, DefaultAccessLogMaxBackups, DefaultAccessLogMaxAgeDays},
+		} {
+			tt.cfg.AccessLogPath = filepath.Join(dir, tt.name+".log")
+			_, closer := newAccessLogger(tt.cfg, appLogger)
+			rotator, ok := closer.(*lumberjack.Logger)
+			if !ok {
+				t.Fatalf("%s: closer is %T, want a *lumberjack.Logger", tt.name, closer)
+			}
+			if rotator.Filename != tt.cfg.AccessLogPath || rotator.MaxSize != tt.size ||
+				rotator.MaxBackups != tt.backups || rotator.MaxAge != tt.ageInDays || !rotator.Compress {
+				t.Errorf("%s: rotator = %+v, want %s, %dMB, %d backups, %d days, compressed",
+					tt.name, rotator, tt.cfg.AccessLogPath, tt.size, tt.backups, tt.ageInDays)
+			}
+			closer.Close()
+		}
+	})
+
+	// stdout swaps os.Stdout for a pipe while fn runs, returning what was
+	// written to it.
+	stdout := func(t *testing.T, fn func()) string {
+		t.Helper()
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		orig := os.Stdout
+		os.Stdout = w
+		defer func() { os.Stdout = orig }()
+		fn()
+		w.Close()
+		out, _ := io.ReadAll(r)
+		return string(out)
+	}
+
+	t.Run("stdout", func(t *testing.T) {
+		out := stdout(t, func() {
+			logger, closer := newAccessLogger(Config{AccessLogPath: "stdout"}, appLogger)
+			defer closer.Close()
+			logger.Info("request")
+		})
+		if !strings.Contains(out, `"msg":"request"`) {
+			t.Errorf("stdout = %q, want the JSON request line", out)
+		}
+	})
+
+	t.Run("falls back to stdout when the file can't be opened", func(t *testing.T) {
+		// A regular file where the log's directory should be.
+		blocker := filepath.Join(t.TempDir(), "not-a-dir")
+		if err := os.WriteFile(blocker, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		appLogs.Reset()
+		out := stdout(t, func() {
+			logger, closer := newAccessLogger(Config{AccessLogPath: filepath.Join(blocker, "access.log")}, appLogger)
+			defer closer.Close()
+			if _, ok := closer.(*lumberjack.Logger); ok {
+				t.Error("got a file logger for a path that can't be opened")
+			}
+			logger.Info("request")
+		})
+		if !strings.Contains(out, `"msg":"request"`) {
+			t.Errorf("stdout = %q, want the JSON request line", out)
+		}
+		if !strings.Contains(appLogs.String(), "access log file can't be opened") {
+			t.Errorf("application log = %q, want a warning", appLogs.String())
+		}
+	})
+}
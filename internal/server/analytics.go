@@ -0,0 +1,28 @@
+package server
+
+import (
+	"log/slog"
+
+	"github.com/sakif/coding-playground/internal/analytics"
+)
+
+// newAnalytics creates the analytics recorder, or returns nil when analytics
+// are off. Events always go to the local table; AnalyticsFile and
+// AnalyticsURL add exporters.
+func (s *Server) newAnalytics() *analytics.Recorder {
+	if !s.config.AnalyticsEnabled {
+		return nil
+	}
+	var exporters []analytics.Exporter
+	if s.config.AnalyticsFile != "" {
+		exporters = append(exporters, &analytics.File{Path: s.config.AnalyticsFile})
+	}
+	if s.config.AnalyticsURL != "" {
+		exporters = append(exporters, &analytics.HTTP{URL: s.config.AnalyticsURL, Token: s.config.AnalyticsToken})
+	}
+	s.logger.Info("analytics enabled", slog.Int("exporters", len(exporters)))
+	return analytics.New(s.db, s.logger, analytics.Options{
+		FlushInterval: s.config.AnalyticsFlushInterval,
+		Exporters:     exporters,
+	})
+}
@@ -0,0 +1,121 @@
+package server
+
+// CACHING PUBLIC SNIPPETS:
+// With SnippetCacheTTL set, SnippetService reads public snippets through
+// cache.SnippetRepository. The cache lives in Redis when RedisURL is set —
+// shared by every app server, so one's write invalidates the others' — and
+// in this process otherwise.
+//
+// With ResponseCacheTTL set as well, the pages built from public snippets
+// (/s/{id}, the feeds, the sitemap) are cached whole in the same store,
+// and purged whenever the snippet cache sees a change.
+//
+// Past the server, CDNMaxAge lets a CDN keep public snippets too, and
+// CDNPurgeURL purges them from it when they change (see internal/cdn).
+//
+// Hover docs (GET /docs) are cached for DocsCacheTTL in a store of their
+// own: Redis again when there is one, or a smaller in-memory cache.
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/cache"
+	"github.com/sakif/coding-playground/internal/cdn"
+	"github.com/sakif/coding-playground/internal/redis"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+const (
+	// redisKeyPrefix starts every key the app writes to Redis, so it can
+	// share a server.
+	redisKeyPrefix = "playground:"
+	// snippetCacheEntries caps the in-memory cache.
+	snippetCacheEntries = 10_000
+	// docsCacheEntries caps the in-memory hover docs cache.
+	docsCacheEntries = 2_000
+)
+
+// newRedis creates the Redis client for RedisURL, or returns nil if it's
+// unset. A server that doesn't answer yet is only a warning: everything
+// using Redis falls back or retries.
+func newRedis(cfg Config, logger *slog.Logger) (*redis.Client, error) {
+	if cfg.RedisURL == "" {
+		return nil, nil
+	}
+	opts, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.New(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx); err != nil {
+		logger.Warn("redis not reachable yet", slog.String("addr", opts.Addr), slog.String("error", err.Error()))
+	} else {
+		logger.Info("redis connected", slog.String("addr", opts.Addr))
+	}
+	return client, nil
+}
+
+// snippetRepository is the repository SnippetService reads and writes
+// through: the database, behind a cache unless SnippetCacheTTL is 0, and
+// purging the CDN if CDNPurgeURL is set. It also sets up s.responses,
+// which the cache purges.
+func (s *Server) snippetRepository() repository.SnippetRepository {
+	repo := s.cachedSnippetRepository()
+	if s.config.CDNPurgeURL == "" {
+		return repo
+	}
+	purger := cdn.Queued(s.jobs, &cdn.HTTPPurger{URL: s.config.CDNPurgeURL, Token: s.config.CDNPurgeToken})
+	return cdn.NewSnippetRepository(repo, purger, s.logger)
+}
+
+func (s *Server) cachedSnippetRepository() repository.SnippetRepository {
+	if s.config.SnippetCacheTTL <= 0 {
+		return s.db
+	}
+	var store cache.Store = cache.NewMemory(snippetCacheEntries)
+	if s.redis != nil {
+		store = cache.NewRedis(s.redis, redisKeyPrefix+"cache:")
+	}
+	repo := cache.NewSnippetRepository(s.db, store, s.config.SnippetCacheTTL, s.logger)
+	if s.config.ResponseCacheTTL > 0 {
+		s.responses = cache.NewResponses(store, s.config.ResponseCacheTTL, authState, s.logger)
+		repo.OnChange(s.responses.Purge)
+	}
+	return repo
+}
+
+// cachePublic serves a public page from s.responses, if it's on.
+func (s *Server) cachePublic(next http.Handler) http.Handler {
+	if s.responses == nil {
+		return next
+	}
+	return s.responses.Middleware(next)
+}
+
+// authState tells cached responses for signed-in users apart. It only sees
+// a user on routes behind auth.OptionalAuth; the rest look the same to
+// everyone, and are cached once.
+func authState(r *http.Request) string {
+	if userID, ok := auth.UserIDFromContext(r.Context()); ok {
+		return "user:" + userID
+	}
+	return "anon"
+}
+
+// docsCache is where hover docs are kept, or nil if DocsCacheTTL is 0.
+func (s *Server) docsCache() cache.Store {
+	if s.config.DocsCacheTTL <= 0 {
+		return nil
+	}
+	if s.redis != nil {
+		return cache.NewRedis(s.redis, redisKeyPrefix+"cache:")
+	}
+	return cache.NewMemory(docsCacheEntries)
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/sakif/coding-playground/internal/mailer"
+	"github.com/sakif/coding-playground/internal/service"
+)
+
+// newEmailService picks the mailer and creates the email service, or returns
+// nil when there's nothing to send: without auth there are no users, and
+// without SMTP there's no way to reach them. In dev mode emails without an
+// SMTP server are logged instead, so sign-in links can be copied from the log.
+func (s *Server) newEmailService() (*service.EmailService, error) {
+	if s.config.JWTSecret == "" {
+		return nil, nil
+	}
+	m := s.config.Mailer
+	switch {
+	case m != nil:
+	case s.config.SMTPHost != "":
+		smtp, err := mailer.NewSMTP(mailer.SMTPConfig{
+			Host:        s.config.SMTPHost,
+			Port:        s.config.SMTPPort,
+			Username:    s.config.SMTPUsername,
+			Password:    s.config.SMTPPassword,
+			From:        s.config.SMTPFrom,
+			ImplicitTLS: s.config.SMTPImplicitTLS,
+		})
+		if err != nil {
+			return nil, err
+		}
+		m = smtp
+		s.logger.Info("email enabled", slog.String("smtp_host", s.config.SMTPHost))
+	case s.config.DevMode:
+		m = mailer.Log{Logger: s.logger}
+		s.logger.Info("email enabled: no SMTP server, so emails are logged")
+	default:
+		return nil, nil
+	}
+
+	return service.NewEmailService(s.db, s.db, s.db, s.db, m, s.jobs, s.publicURL(), s.config.JWTSecret, s.logger), nil
+}
+
+// publicURL is where links we hand out point, without a trailing slash.
+func (s *Server) publicURL() string {
+	if u := strings.TrimSuffix(s.config.PublicURL, "/"); u != "" {
+		return u
+	}
+	return fmt.Sprintf("http://localhost:%d", s.config.Port)
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LISTENERS:
+// By default the server listens on TCP port Config.Port. Config.Listen can
+// pick something else:
+//
+//	""                          → TCP on all interfaces, port Config.Port
+//	"127.0.0.1:8080"            → TCP on a specific address
+//	"unix:/run/playground.sock" → a Unix domain socket
+//	"systemd"                   → a socket passed in by systemd (socket activation)
+//
+// WHY UNIX SOCKETS?
+// Behind a reverse proxy (nginx, Caddy) on the same machine, a Unix socket
+// avoids exposing a TCP port at all, and file permissions decide who may
+// connect. The socket is created with mode 0660, so the proxy's user must be
+// in the server's group.
+//
+// SYSTEMD SOCKET ACTIVATION:
+// With a playground.socket unit, systemd opens the listening socket itself
+// and hands it to the server as file descriptor 3, announcing it through the
+// LISTEN_PID and LISTEN_FDS environment variables. Connections that arrive
+// while the server restarts queue up in the socket instead of being refused.
+
+// unixSocketMode is the permission of sockets created for "unix:" listeners.
+const unixSocketMode = 0o660
+
+// listenFDsStart is the first file descriptor systemd passes (after stdin,
+// stdout and stderr).
+const listenFDsStart = 3
+
+// listen opens the listener described by Config.Listen.
+func (c Config) listen() (net.Listener, error) {
+	switch {
+	case c.Listen == "":
+		return net.Listen("tcp", fmt.Sprintf(":%d", c.Port))
+	case c.Listen == "systemd":
+		return systemdListener()
+	case strings.HasPrefix(c.Listen, "unix:"):
+		return unixListener(strings.TrimPrefix(c.Listen, "unix:"))
+	default:
+		return net.Listen("tcp", c.Listen)
+	}
+}
+
+// unixListener listens on a Unix socket at path, replacing a stale socket
+// left behind by a crashed server. The socket file is removed on Close.
+func unixListener(path string) (net.Listener, error) {
+	if path == "" {
+		return nil, errors.New("unix listener: empty socket path")
+	}
+
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&fs.ModeSocket == 0 {
+			return nil, fmt.Errorf("unix listener: %s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("unix listener: removing stale socket: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, unixSocketMode); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("unix listener: %w", err)
+	}
+	return ln, nil
+}
+
+// systemdListener returns the first socket passed by systemd.
+func systemdListener() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, errors.New("systemd listener: no socket passed to this process (is the .socket unit enabled?)")
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, errors.New("systemd listener: LISTEN_FDS is not a positive number")
+	}
+
+	// The variables are meant for this process only; don't leak them to
+	// anything we start (e.g. sandbox helpers).
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	f := os.NewFile(uintptr(listenFDsStart), "systemd-socket")
+	defer f.Close() // FileListener dups the descriptor
+
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("systemd listener: %w", err)
+	}
+	return ln, nil
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/model"
+)
+
+// PROFILING WITH PPROF:
+// net/http/pprof exposes the Go runtime's profilers over HTTP. With it you can
+// capture what a LIVE server is doing, without restarting it:
+//
+//	go tool pprof https://host/debug/pprof/profile?seconds=30   # CPU
+//	go tool pprof https://host/debug/pprof/heap                 # memory
+//	curl https://host/debug/pprof/goroutine?debug=2             # stack dump
+//
+// Profiles reveal source paths, command-line flags and memory contents, so the
+// endpoints are only mounted for signed-in admins. Add a session cookie to the
+// commands above (e.g. curl -b "pyplayground_token=...").
+
+// mountProfiler mounts /debug/pprof/* behind the admin role.
+// It requires auth to be configured; without it profiling stays off.
+func (s *Server) mountProfiler(tokens *auth.TokenService) {
+	if tokens == nil {
+		s.logger.Warn("PPROF_ENABLED is set but authentication is disabled — profiling endpoints not mounted")
+		return
+	}
+
+	s.router.Route("/debug", func(r chi.Router) {
+		r.Use(auth.RequireAuth(tokens))
+		r.Use(auth.RequireRole(s.userRole, model.RoleAdmin))
+		r.Use(noWriteDeadline)
+		r.Mount("/", chimiddleware.Profiler())
+	})
+	s.logger.Info("pprof endpoints enabled at /debug/pprof (admin only)")
+}
+
+// noWriteDeadline lifts the server's WriteTimeout for one request.
+// A CPU profile or trace streams for ?seconds=N (30 by default) — longer than
+// the 15s WriteTimeout, which would otherwise cut every profile short.
+func noWriteDeadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Errors mean the ResponseWriter can't change deadlines (e.g. in tests);
+		// the request still works, just with the normal timeout.
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,18 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/sakif/coding-playground/internal/middleware"
+)
+
+// rateLimit limits a route group to cfg per client IP. With RedisURL set
+// the buckets are in Redis, so all the app's instances share them; without
+// it each instance has its own.
+func (s *Server) rateLimit(name string, cfg middleware.RateLimitConfig) func(http.Handler) http.Handler {
+	if s.redis == nil {
+		return middleware.RateLimit(cfg)
+	}
+	store := middleware.NewRedisRateLimitStore(s.redis, redisKeyPrefix+"ratelimit:")
+	return middleware.RateLimitShared(cfg, store, name, s.logger)
+}
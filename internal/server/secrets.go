@@ -0,0 +1,62 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// ROTATING SECRETS:
+// The JWT secrets and the GitHub OAuth credentials can be changed while the
+// server runs — the main program rereads them on SIGHUP and calls
+// ReloadSecrets — so rotating one doesn't mean a restart:
+//
+//	echo "$NEW" > /run/secrets/jwt_secret    # JWT_SECRET_FILE
+//	kill -HUP $(pidof server)
+//
+// Nobody is signed out. Sessions signed with the old JWT secret stay valid
+// until they expire (see auth.TokenService's KEY ROTATION); new ones are
+// signed with the new secret. With several servers behind a load balancer,
+// a session signed by one that has the new secret must be accepted by those
+// that don't yet, so rotate in two steps: first add the new secret to
+// JWT_PREVIOUS_SECRETS everywhere and reload, then make it JWT_SECRET.
+//
+// Only secrets change this way. Turning sign-in on or off changes which
+// routes exist, which needs a restart; so does anything else in Config.
+// Unsubscribe links in emails keep being signed with the JWT secret the
+// server started with, as links already sent must keep working.
+
+// Secrets are the settings ReloadSecrets can change.
+type Secrets struct {
+	JWTSecret          string
+	JWTPreviousSecrets []string
+	GitHubClientID     string
+	GitHubClientSecret string
+}
+
+// ReloadSecrets switches to new secrets. It changes nothing if any of them
+// can't be used: a JWT secret that's too short, or one that's missing when
+// sign-in is on.
+func (s *Server) ReloadSecrets(secrets Secrets) error {
+	if (s.tokens == nil) != (secrets.JWTSecret == "") {
+		return errors.New("reloading secrets: JWT_SECRET can't be set or unset without a restart")
+	}
+	github := secrets.GitHubClientID != "" && secrets.GitHubClientSecret != ""
+	if (s.github == nil) == github {
+		return errors.New("reloading secrets: GitHub sign-in can't be turned on or off without a restart")
+	}
+
+	if s.tokens != nil {
+		if err := s.tokens.SetSecrets(secrets.JWTSecret, secrets.JWTPreviousSecrets...); err != nil {
+			return fmt.Errorf("reloading secrets: %w", err)
+		}
+	}
+	if s.github != nil {
+		s.github.SetCredentials(secrets.GitHubClientID, secrets.GitHubClientSecret)
+	}
+	s.logger.Info("secrets reloaded",
+		slog.Int("jwt_previous_secrets", len(secrets.JWTPreviousSecrets)),
+		slog.Bool("github", s.github != nil),
+	)
+	return nil
+}
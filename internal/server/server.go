@@ -20,22 +20,42 @@ package server
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 
+	"github.com/sakif/coding-playground/internal/abuse"
+	"github.com/sakif/coding-playground/internal/analytics"
+	"github.com/sakif/coding-playground/internal/assets"
 	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/blob"
+	"github.com/sakif/coding-playground/internal/cache"
+	"github.com/sakif/coding-playground/internal/errreport"
 	"github.com/sakif/coding-playground/internal/executor"
+	"github.com/sakif/coding-playground/internal/feature"
 	"github.com/sakif/coding-playground/internal/handler"
+	"github.com/sakif/coding-playground/internal/idempotency"
+	"github.com/sakif/coding-playground/internal/jobs"
+	"github.com/sakif/coding-playground/internal/mailer"
+	"github.com/sakif/coding-playground/internal/metrics"
 	"github.com/sakif/coding-playground/internal/middleware"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/redis"
 	sqliteRepo "github.com/sakif/coding-playground/internal/repository/sqlite"
+	"github.com/sakif/coding-playground/internal/retry"
+	"github.com/sakif/coding-playground/internal/scheduler"
 	"github.com/sakif/coding-playground/internal/service"
+	"github.com/sakif/coding-playground/internal/ws"
 )
 
 // Config holds server configuration.
@@ -45,28 +65,272 @@ type Config struct {
 	StaticDir   string
 	DBPath      string
 
+	// Listen overrides Port: a TCP address, "unix:<path>" or "systemd".
+	// See listen.go.
+	Listen string
+
+	// DevMode enables development conveniences such as re-parsing HTML templates
+	// on every request. Never enable it in production.
+	DevMode bool
+
 	// Auth configuration (all optional — auth is disabled if JWTSecret is empty)
+	// JWTPreviousSecrets are accepted for tokens, but not signed with: see
+	// secrets.go for rotating them.
 	JWTSecret          string
+	JWTPreviousSecrets []string
 	GitHubClientID     string
 	GitHubClientSecret string
 	GitHubCallbackURL  string
+
+	// TLS configuration (all optional — plain HTTP is served if none are set).
+	// Either set TLSCertFile + TLSKeyFile, or AutocertDomains for Let's Encrypt.
+	TLSCertFile      string
+	TLSKeyFile       string
+	AutocertDomains  []string
+	AutocertCacheDir string
+	AutocertEmail    string
+	// HTTPRedirectPort is the plain-HTTP port that redirects to HTTPS.
+	// 0 disables the redirect listener (autocert always uses one, defaulting to 80).
+	HTTPRedirectPort int
+
+	// Metrics configuration. When MetricsEnabled is true, Prometheus metrics are
+	// served at /metrics, protected by basic auth if a username is set.
+	MetricsEnabled  bool
+	MetricsUsername string
+	MetricsPassword string
+
+	// Per-IP rate limits for each route group (zero Rate disables limiting).
+	// ExecuteRateLimit applies on top of APIRateLimit to the routes that run
+	// code. With RedisURL set, every instance shares the buckets.
+	APIRateLimit     middleware.RateLimitConfig
+	AuthRateLimit    middleware.RateLimitConfig
+	ExecuteRateLimit middleware.RateLimitConfig
+
+	// Sampling and size cap for API body logging, which the body_logging
+	// feature flag switches on and off.
+	BodyLog middleware.BodyLogConfig
+
+	// Maximum request body sizes in bytes per route group (0 disables the cap).
+	APIMaxBodyBytes  int64
+	AuthMaxBodyBytes int64
+
+	// Per-route-group request deadlines (0 disables). Requests that run past
+	// theirs get 504 Gateway Timeout.
+	APITimeout     time.Duration
+	ExecuteTimeout time.Duration
+	AuthTimeout    time.Duration
+
+	// Access log destination: "" (application logger), "stdout", or a file path.
+	// File logs are rotated by size (MB), number of backups and age (days).
+	AccessLogPath       string
+	AccessLogMaxSizeMB  int
+	AccessLogMaxBackups int
+	AccessLogMaxAgeDays int
+
+	// FeatureFlags overrides the built-in flag defaults for this deployment.
+	// Admin toggles stored in the database take precedence over these.
+	FeatureFlags map[feature.Flag]bool
+
+	// JobWorkers is the number of background job workers (0 uses the default).
+	JobWorkers int
+
+	// IdempotencyTTL is how long responses to requests with an Idempotency-Key
+	// are remembered (0 ignores the header).
+	IdempotencyTTL time.Duration
+
+	// Maintenance task schedules as cron expressions ("" or "off" disables).
+	// Anonymous snippets are purged only when AnonymousSnippetTTL is non-zero.
+	SnippetPurgeSchedule     string
+	WALCheckpointSchedule    string
+	IdempotencyPurgeSchedule string
+	LeaderboardSchedule      string
+	AnonymousSnippetTTL      time.Duration
+
+	// Run history retention, for signed-in users' runs and anonymous ones,
+	// enforced on RunPurgeSchedule. The task runs only if either policy is
+	// non-zero.
+	RunRetention          RunRetention
+	AnonymousRunRetention RunRetention
+	RunPurgeSchedule      string
+
+	// PprofEnabled mounts net/http/pprof at /debug/pprof for admins.
+	PprofEnabled bool
+
+	// Email (optional). Without an SMTP host, emails aren't sent — except in
+	// dev mode, where they're logged. PublicURL is where links in emails,
+	// and org invite links, point (default http://localhost:<Port>). DigestSchedule is when the
+	// weekly digest goes out, as a cron expression ("" or "off" disables).
+	SMTPHost        string
+	SMTPPort        int
+	SMTPUsername    string
+	SMTPPassword    string
+	SMTPFrom        string
+	SMTPImplicitTLS bool
+	PublicURL       string
+	DigestSchedule  string
+	// Mailer, if set, is used instead of SMTP. Tests use it to see what
+	// would have been sent.
+	Mailer mailer.Mailer
+
+	// Product analytics (see internal/analytics). Events are saved to the
+	// database, and also appended to AnalyticsFile (JSON Lines) and POSTed to
+	// AnalyticsURL when those are set. AnalyticsFlushInterval is how often
+	// batches are written (0 uses the default).
+	AnalyticsEnabled       bool
+	AnalyticsFile          string
+	AnalyticsURL           string
+	AnalyticsToken         string
+	AnalyticsFlushInterval time.Duration
+
+	// Default quotas for each org (0: no limit): how many snippets it may
+	// keep, and how many live runs of them it may start per UTC day. Admins
+	// can override them per org.
+	OrgSnippetQuota int
+	OrgRunQuota     int
+
+	// AbuseDetection says what to do about code that looks like abuse of the
+	// sandbox (see internal/abuse); "" is abuse.Standard.
+	AbuseDetection abuse.Strictness
+
+	// RedisURL (redis://…) is a Redis server shared by all app servers; ""
+	// for none. SnippetCacheTTL is how long public snippets are cached, in
+	// Redis if there is one and in memory otherwise (0: not cached).
+	// ResponseCacheTTL is how long the rendered public pages and feeds are
+	// kept; it needs the snippet cache, which purges them. See cache.go.
+	RedisURL         string
+	SnippetCacheTTL  time.Duration
+	ResponseCacheTTL time.Duration
+
+	// CDNMaxAge lets a CDN keep public snippets, their embeds and pages for
+	// that long (s-maxage; 0 leaves them no-cache). A change to a snippet is
+	// purged from the CDN by POSTing to CDNPurgeURL, with CDNPurgeToken as
+	// a bearer token ("" purges nothing). See internal/cdn.
+	CDNMaxAge     time.Duration
+	CDNPurgeURL   string
+	CDNPurgeToken string
+
+	// LegacyAPI is how the unversioned /api prefix is deprecated: its
+	// responses carry Deprecation and Sunset headers and a warning, and
+	// its use is logged (see handler/deprecation.go). A zero Since leaves
+	// it unmarked.
+	LegacyAPI handler.Deprecation
+
+	// SentryDSN sends panics and 500s to a Sentry-compatible error tracker
+	// ("" disables reporting). SentryEnvironment tags the events.
+	SentryDSN         string
+	SentryEnvironment string
+
+	// Blobs is where the files runs leave behind (artifacts) are kept: a
+	// directory or an S3-compatible bucket (see internal/blob). With no
+	// Backend, runs can't keep files.
+	Blobs blob.Config
+
+	// Retry is how database writes that meet a busy database are retried
+	// (see internal/retry). The zero value makes 3 attempts.
+	Retry retry.Policy
+
+	// Deadlines bounds each database call and code run a service makes
+	// within a request (see service/deadline.go). The zero value uses
+	// service.DefaultDeadlines.
+	Deadlines service.Deadlines
+
+	// Completer runs the editor's autocomplete and hover docs (POST
+	// /complete and GET /docs; see service/completion.go and docs.go). nil
+	// shares the executor code runs on. DocsCacheTTL is how long docs are
+	// cached (0: not cached); see cache.go.
+	Completer    executor.Executor
+	DocsCacheTTL time.Duration
+
+	// Packages are the packages snippets and runs may install (see
+	// executor/requirements.go); the executor needs a wheelhouse to install
+	// them from. nil allows none.
+	Packages executor.PackageAllowlist
+
+	// Debugger runs debugging sessions (POST /debug; see service/debug.go),
+	// which need a timeout of minutes rather than seconds. nil shares the
+	// executor code runs on, and its timeout.
+	Debugger executor.Executor
+
+	// GPU runs code that asks for a GPU (see executor.ExecutionRequest.GPU)
+	// in sandboxes that have one. Only signed-in users whose role is in
+	// GPURoles may use it. nil refuses such runs.
+	GPU      executor.Executor
+	GPURoles []string
 }
 
+// recentErrorsKept is how many errors the admin dashboard can show.
+const recentErrorsKept = 100
+
 // Server represents the HTTP server and all its dependencies.
 type Server struct {
-	router *chi.Mux
-	config Config
-	logger *slog.Logger
-	db     *sqliteRepo.DB
-	exec   executor.Executor
+	router  *chi.Mux
+	config  Config
+	logger  *slog.Logger
+	db      *sqliteRepo.DB
+	exec    executor.Executor
+	metrics *metrics.Registry
+	flags   *feature.Flags
+	jobs    *jobs.Queue
+
+	reporter     errreport.Reporter
+	recentErrors *errreport.Recent // also in reporter; shown on the admin dashboard
+
+	scheduler *scheduler.Scheduler
+
+	// hub serves WebSocket connections at /ws; features push events through it.
+	hub *ws.Hub
+	// collab runs collaborative editing sessions over the hub.
+	collab *service.CollabService
+	// liveRuns streams snippet runs to viewers over the hub; nil without an executor.
+	liveRuns *service.LiveRunService
+	// executions runs code in the background for /executions; nil without an executor.
+	executions *service.ExecutionService
+	// debugger runs debugging sessions over the hub; nil without an executor.
+	debugger *service.DebugService
+	// email queues emails through the job queue; nil when email is off (see email.go).
+	email *service.EmailService
+	// analytics records product events; nil when analytics are off (see analytics.go).
+	analytics *analytics.Recorder
+	// suspensions caches which users are suspended (see suspension.go).
+	suspensions *suspensionCache
+	// redis is the shared Redis server; nil without RedisURL (see cache.go).
+	redis *redis.Client
+	// responses caches public pages; nil when off (see cache.go).
+	responses *cache.Responses
+	// blobs keeps execution artifacts; nil when Blobs is unset.
+	blobs blob.Store
+	// tokens signs and checks sessions, and github signs users in; nil when
+	// they're off. Their secrets can be reloaded (see secrets.go).
+	tokens *auth.TokenService
+	github *auth.GitHubProvider
+	// storage checkpoints the database's WAL, on a schedule and for admins.
+	storage *service.StorageService
+	// runsPurged and anonymousRunsPurged count runs the run_purge task
+	// deleted (see tasks.go).
+	runsPurged, anonymousRunsPurged atomic.Int64
+
+	accessLog       *slog.Logger
+	accessLogCloser io.Closer
+
+	shutdownHooks      []shutdownHook
+	afterShutdownHooks []shutdownHook // see AfterShutdown
+	shutdownOnce       sync.Once
 }
 
 // New creates a new Server with the given config.
 func New(cfg Config, logger *slog.Logger, exec executor.Executor) (*Server, error) {
+	if err := cfg.validateTLS(); err != nil {
+		return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+	}
+	if cfg.Deadlines == (service.Deadlines{}) {
+		cfg.Deadlines = service.DefaultDeadlines()
+	}
+
 	db, err := sqliteRepo.New(cfg.DBPath)
 	if err != nil {
 		return nil, fmt.Errorf("opening database: %w", err)
 	}
+	db.SetRetryPolicy(cfg.Retry)
 
 	s := &Server{
 		router: chi.NewRouter(),
@@ -75,12 +339,106 @@ func New(cfg Config, logger *slog.Logger, exec executor.Executor) (*Server, erro
 		db:     db,
 		exec:   exec,
 	}
+	s.suspensions = newSuspensionCache(db, suspensionCacheTTL)
+
+	flags, err := feature.New(db, cfg.FeatureFlags)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("configuring feature flags: %w", err)
+	}
+	if err := flags.Load(context.Background()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("loading feature flags: %w", err)
+	}
+	s.flags = flags
+
+	s.jobs = jobs.New(db, logger, jobs.Options{
+		Workers: cfg.JobWorkers,
+	})
+
+	if s.email, err = s.newEmailService(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("configuring email: %w", err)
+	}
+
+	s.analytics = s.newAnalytics()
+
+	if cfg.Blobs.Backend != "" {
+		if s.blobs, err = blob.Open(cfg.Blobs); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("configuring blob storage: %w", err)
+		}
+	}
+
+	s.storage = service.NewStorageService(db, logger)
+	if s.scheduler, err = s.newScheduler(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("configuring scheduler: %w", err)
+	}
+
+	// Recent errors are always kept for the admin dashboard; Sentry, when
+	// configured, gets them too.
+	s.recentErrors = errreport.NewRecent(recentErrorsKept)
+	s.reporter = s.recentErrors
+	if cfg.SentryDSN != "" {
+		sentry, err := errreport.NewSentry(cfg.SentryDSN, errreport.SentryOptions{
+			Environment: cfg.SentryEnvironment,
+			Logger:      logger,
+		})
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("configuring error reporting: %w", err)
+		}
+		s.reporter = errreport.Multi{s.recentErrors, sentry}
+		logger.Info("error reporting enabled", slog.String("environment", cfg.SentryEnvironment))
+	}
+
+	if s.redis, err = newRedis(cfg, logger); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("configuring redis: %w", err)
+	}
+
+	s.accessLog, s.accessLogCloser = newAccessLogger(cfg, logger)
+
+	s.hub = ws.NewHub(logger, ws.Options{})
+
+	if cfg.MetricsEnabled {
+		s.metrics = s.newMetrics()
+	}
 
 	if err := s.setupRoutes(); err != nil {
+		s.accessLogCloser.Close()
 		db.Close()
 		return nil, fmt.Errorf("setting up routes: %w", err)
 	}
 
+	// Teardown runs in the reverse of this order (see shutdown.go): background
+	// work stops first, the database closes last.
+	s.OnShutdown("database", func(context.Context) error { return db.Close() })
+	if s.redis != nil {
+		s.OnShutdown("redis", func(context.Context) error { return s.redis.Close() })
+	}
+	if s.analytics != nil {
+		s.OnShutdown("analytics", s.analytics.Shutdown) // writes the last batch
+	}
+	s.OnShutdown("access log", func(context.Context) error { return s.accessLogCloser.Close() })
+	s.OnShutdown("error reports", s.reporter.Flush)
+	s.OnShutdown("job queue", s.jobs.Shutdown) // jobs cut short are retried on the next start
+	s.OnShutdown("scheduler", s.scheduler.Stop)
+	s.OnShutdown("collaboration", s.collab.Shutdown) // saves what the editors left
+	if s.liveRuns != nil {
+		s.OnShutdown("live runs", s.liveRuns.Shutdown)
+	}
+	if s.executions != nil {
+		s.OnShutdown("background executions", s.executions.Shutdown)
+	}
+	if s.debugger != nil {
+		s.OnShutdown("debugging sessions", s.debugger.Shutdown)
+	}
+	// http.Server.Shutdown doesn't wait for upgraded connections, so the hub
+	// closes its own — first, while the services they use are still up.
+	s.OnShutdown("websockets", s.hub.Shutdown)
+
 	return s, nil
 }
 
@@ -88,49 +446,193 @@ func New(cfg Config, logger *slog.Logger, exec executor.Executor) (*Server, erro
 //
 // ROUTE STRUCTURE:
 // GET    /                             → Playground page (HTML)
+// GET    /feed.atom                    → Atom feed of public snippets
+// GET    /users/{login}/feed.atom      → Atom feed of one user's public snippets
+// GET    /s/{id}                       → Public snippet page with Open Graph tags (HTML)
+// GET    /sitemap.xml                  → Sitemap of public snippet pages
+// GET    /robots.txt                   → Crawler rules, pointing at the sitemap
 // GET    /static/*                     → Static files (CSS, JS, images)
+// GET    /metrics                      → Prometheus metrics (if enabled, optional basic auth)
+// GET    /swagger                      → Swagger UI for the OpenAPI document (api_docs flag)
+// GET    /debug/pprof/*                → Go runtime profiles (if enabled, admin only)
+// GET    /ws                           → WebSocket connection for live events and collaborative editing (OptionalAuth)
+// GET    /admin                        → Admin dashboard (HTML, admin only)
+// GET    /admin/errors                 → Recent server errors (HTML, admin only)
+// GET    /email/unsubscribe            → Turn off one kind of email from a signed link (if email is set up)
 //
 // AUTH ROUTES (only if JWTSecret is set):
 // GET    /auth/github/login            → Redirect to GitHub OAuth
 // GET    /auth/github/callback         → Handle OAuth callback
+// POST   /auth/magic-link              → Email a sign-in link (if email is set up)
+// GET    /auth/magic                   → Sign-in page for an emailed link
+// POST   /auth/magic                   → Use up the link and set the JWT cookie
 // POST   /auth/logout                  → Clear JWT cookie
-// GET    /api/me                       → Current user profile (RequireAuth)
 //
-// API ROUTES:
-// GET    /api/snippets                 → List snippets
-// GET    /api/snippets/{id}            → Get snippet
-// POST   /api/snippets                 → Create snippet (OptionalAuth)
-// PUT    /api/snippets/{id}            → Update snippet (OptionalAuth)
-// DELETE /api/snippets/{id}            → Delete snippet (OptionalAuth)
-// POST   /api/execute                  → Execute code (if Docker available)
+// API ROUTES (v1, mounted at /api/v1 and the deprecated /api alias):
+// Suspended users may only GET (and query GraphQL); see suspension.go.
+// GET    /api/v1/openapi.json          → OpenAPI 3 document (api_docs flag)
+// GET    /api/v1/errors                → Catalog of machine-readable error codes
+// GET    /api/v1/me                    → Current user profile (RequireAuth)
+// PUT    /api/v1/me/leaderboard        → Opt out of (or back into) leaderboards (RequireAuth)
+// GET    /api/v1/admin/features        → List feature flags (admin)
+// PUT    /api/v1/admin/features/{name} → Toggle a feature flag (admin)
+// GET    /api/v1/admin/tasks           → Scheduled task status (admin)
+// GET    /api/v1/admin/db              → Database page counts, freelist and WAL size (admin)
+// POST   /api/v1/admin/db/checkpoint   → Checkpoint and truncate the WAL now (admin)
+// PUT    /api/v1/admin/orgs/{id}/quota → Override an org's snippet and run quotas (admin, if auth enabled)
+// GET    /api/v1/users/{login}/badges → Badges a user has earned (if auth enabled)
+// GET    /api/v1/feed                  → Recent activity on public snippets, cursor-paginated (if auth enabled)
+// GET    /api/v1/users/{login}/activity → One user's activity on public snippets (if auth enabled)
+// GET    /api/v1/webhooks              → List own webhooks (RequireAuth)
+// POST   /api/v1/webhooks              → Register a webhook (RequireAuth)
+// DELETE /api/v1/webhooks/{id}         → Delete a webhook (RequireAuth)
+// GET    /api/v1/webhooks/{id}/deliveries → Webhook delivery log (RequireAuth)
+// GET    /api/v1/exercises             → List exercises (without hidden tests)
+// GET    /api/v1/exercises/{id}        → Get exercise (hidden tests for its author)
+// POST   /api/v1/exercises             → Create exercise (RequireAuth, author role)
+// PUT    /api/v1/exercises/{id}        → Update own exercise (RequireAuth, author role)
+// DELETE /api/v1/exercises/{id}        → Delete own exercise (RequireAuth, author role)
+// POST   /api/v1/exercises/{id}/submit → Grade a solution against the hidden tests (RequireAuth, execution flag)
+// GET    /api/v1/exercises/{id}/leaderboard → Best solvers of an exercise
+// GET    /api/v1/exercises/{id}/hints  → Hints unlocked so far (RequireAuth)
+// POST   /api/v1/exercises/{id}/hints  → Unlock the next hint (RequireAuth)
+// GET    /api/v1/challenges/today      → Today's challenge, with the caller's streak if signed in
+// GET    /api/v1/challenges            → Challenges from..to, newest first (last 30 days by default)
+// GET    /api/v1/challenges/{date}     → Challenge for a date (future ones for authors only)
+// PUT    /api/v1/challenges/{date}     → Schedule an exercise for a date (RequireAuth, author role)
+// DELETE /api/v1/challenges/{date}     → Unschedule a date (RequireAuth, author role)
+// POST   /api/v1/challenges/{date}/submit → Grade a solution to today's challenge (RequireAuth, execution flag)
+// GET    /api/v1/me/streak             → Own daily challenge streak (RequireAuth)
+// GET    /api/v1/me/stats              → Own snippet and run totals, and activity by day for 90 days (RequireAuth)
+// GET    /api/v1/me/export             → Download own personal snippets as a streamed .tar.gz (RequireAuth)
+// GET    /api/v1/me/notifications      → Own notifications and unread count; new ones are pushed on "user:<id>" (RequireAuth)
+// POST   /api/v1/me/notifications/read → Mark some or all notifications read (RequireAuth)
+// GET    /api/v1/me/email-preferences  → Own email preferences (RequireAuth, if email is set up)
+// PUT    /api/v1/me/email-preferences  → Turn grading emails and the weekly digest on or off (RequireAuth)
+// GET    /api/v1/classes               → List own classes (RequireAuth)
+// POST   /api/v1/classes               → Create a class and teach it (RequireAuth)
+// POST   /api/v1/classes/join          → Join a class by code as a student (RequireAuth)
+// GET    /api/v1/classes/{id}          → Get a class (members)
+// GET    /api/v1/classes/{id}/members  → List members (teachers)
+// GET    /api/v1/classes/{id}/leaderboard → Students ranked across the class's assignments (members)
+// GET    /api/v1/classes/{id}/assignments → List assignments (members)
+// POST   /api/v1/classes/{id}/assignments → Set an exercise (teachers)
+// PUT    /api/v1/classes/{id}/assignments/{assignmentID} → Change title, dates or late policy (teachers)
+// POST   /api/v1/classes/{id}/assignments/{assignmentID}/submit → Grade a solution within the window (members, execution flag)
+// GET    /api/v1/classes/{id}/assignments/{assignmentID}/submissions → All (teachers) or own (students)
+// GET    /api/v1/classes/{id}/assignments/{assignmentID}/progress → Completion status per student (teachers)
+// POST   /api/v1/classes/{id}/assignments/{assignmentID}/similarity → Queue a similarity report on the latest submissions (teachers)
+// GET    /api/v1/classes/{id}/assignments/{assignmentID}/similarity → Latest similarity report, flagged pairs first (teachers)
+// GET    /api/v1/orgs                  → List own orgs (RequireAuth)
+// POST   /api/v1/orgs                  → Create an org and own it (RequireAuth)
+// GET    /api/v1/orgs/{id}             → Get an org (members)
+// DELETE /api/v1/orgs/{id}             → Delete an org; its snippets go back to their creators (owners)
+// GET    /api/v1/orgs/{id}/members     → List members (members)
+// POST   /api/v1/orgs/{id}/members     → Add a user by login (owners)
+// PUT    /api/v1/orgs/{id}/members/{login} → Change a member's role (owners)
+// DELETE /api/v1/orgs/{id}/members/{login} → Remove a member (owners), or leave (self)
+// GET    /api/v1/orgs/{id}/usage       → Snippets and today's runs against the org's quotas (members)
+// GET    /api/v1/orgs/{id}/export      → Download the org's snippets as a streamed .tar.gz (members)
+// GET    /api/v1/orgs/{id}/invites     → Pending invites (owners)
+// POST   /api/v1/orgs/{id}/invites     → Create an invite link, optionally emailed (owners)
+// DELETE /api/v1/orgs/{id}/invites/{inviteID} → Revoke a pending invite (owners)
+// GET    /api/v1/invites/{token}       → Which org and role an invite link is for
+// POST   /api/v1/invites/{token}/accept → Join the org with the invite's role (RequireAuth)
+// GET    /api/v1/me/transfers          → Pending snippet transfers to and from you (RequireAuth)
+// POST   /api/v1/transfers/{id}/accept → Take a snippet offered to you or an org you own (RequireAuth)
+// DELETE /api/v1/transfers/{id}        → Decline an offer, or withdraw your own (RequireAuth)
+// GET    /api/v1/snippets              → List snippets (?org= for an org's library)
+// GET    /api/v1/snippets/{id}         → Get snippet
+// GET    /api/v1/snippets/{id}/html    → Get snippet code as highlighted HTML
+// POST   /api/v1/snippets              → Create snippet, optionally in an org (OptionalAuth)
+// PUT    /api/v1/snippets/{id}         → Update snippet; an org's only by its members (OptionalAuth)
+// PUT    /api/v1/snippets/{id}/requirements → Set the packages a snippet installs (OptionalAuth)
+// DELETE /api/v1/snippets/{id}         → Delete snippet; an org's only by its owner or the snippet's creator (OptionalAuth)
+// PUT    /api/v1/snippets/{id}/visibility → Publish/unpublish own snippet (RequireAuth)
+// POST   /api/v1/snippets/{id}/fork    → Copy a snippet into one of your own (RequireAuth)
+// PUT    /api/v1/snippets/{id}/star    → Star a snippet (RequireAuth)
+// DELETE /api/v1/snippets/{id}/star    → Unstar a snippet (RequireAuth)
+// POST   /api/v1/snippets/{id}/transfer → Offer own snippet, or an owned org's, to a user or org (RequireAuth)
+// GET    /api/v1/snippets/{id}/comments → List comments, line-anchored ones marked if outdated
+// POST   /api/v1/snippets/{id}/comments → Comment, optionally on a line range (RequireAuth)
+// DELETE /api/v1/snippets/{id}/comments/{commentID} → Delete own comment, or any on own snippet (RequireAuth)
+// GET    /api/v1/snippets/{id}/live-run → Current or last live run, with its output so far (if executor available)
+// POST   /api/v1/snippets/{id}/live-run → Run own snippet for viewers on the "live:<id>" topic (RequireAuth)
+// DELETE /api/v1/snippets/{id}/live-run → Stop own snippet's live run (RequireAuth)
+// POST   /api/v1/execute               → Execute code (if Docker available, execution flag, OptionalAuth)
+// POST   /api/v1/runs/{id}/replay      → Run one of own earlier runs again (if Docker available, execution flag, RequireAuth)
+// POST   /api/v1/executions            → Start running code in the background (if Docker available, execution flag, OptionalAuth)
+// GET    /api/v1/executions/{id}       → A background run; ?wait=30s long-polls until it ends
+// DELETE /api/v1/executions/{id}       → Cancel a background run
+// GET    /api/v1/artifacts/{run}/{name} → A file a run left behind ({"artifacts": true} on /execute)
+// POST   /api/v1/complete              → Autocomplete at a position in some code (if an executor is available, execution flag)
+// GET    /api/v1/docs?symbol=os.path.join → A Python name's signature and docstring (if an executor is available, execution flag)
+// POST   /api/v1/debug                 → Start debugging code under pdb, driven on the "debug:<id>" topic (if an executor is available, execution flag, OptionalAuth)
+// GET    /api/v1/debug/{id}            → A debugging session: where it's paused, and its output so far
+// DELETE /api/v1/debug/{id}            → Stop a debugging session
 func (s *Server) setupRoutes() error {
 	// === Global Middleware ===
 	s.router.Use(chimiddleware.RequestID)
+	s.router.Use(middleware.EchoRequestID)
 	s.router.Use(chimiddleware.RealIP)
-	s.router.Use(chimiddleware.Recoverer)
-	s.router.Use(middleware.Logger(s.logger))
+	s.router.Use(middleware.Recoverer(s.reporter, s.logger))
+	s.router.Use(middleware.Logger(s.accessLog))
+	if s.metrics != nil {
+		s.router.Use(s.metrics.Middleware)
+	}
+
+	// JSON errors for unmatched routes. Set before any Route/Mount call:
+	// sub-routers copy these handlers when they're created.
+	s.router.NotFound(handler.NotFoundHandler(s.logger))
+	s.router.MethodNotAllowed(handler.MethodNotAllowedHandler(s.router, s.logger))
+
+	// === Metrics ===
+	if s.metrics != nil {
+		metricsHandler := s.metrics.Handler()
+		if s.config.MetricsUsername != "" {
+			metricsHandler = chimiddleware.BasicAuth("metrics", map[string]string{
+				s.config.MetricsUsername: s.config.MetricsPassword,
+			})(metricsHandler)
+		}
+		s.router.Handle("/metrics", metricsHandler)
+	}
 
 	// === Static Files ===
-	fileServer := http.FileServer(http.Dir(s.config.StaticDir))
-	s.router.Handle("/static/*", http.StripPrefix("/static/", fileServer))
+	// Files are fingerprinted at startup so they can be cached for a year;
+	// see internal/assets. Dev mode serves plain, uncached names instead.
+	manifest, err := assets.NewManifest(s.config.StaticDir, s.config.DevMode)
+	if err != nil {
+		return fmt.Errorf("building asset manifest: %w", err)
+	}
+	s.router.Handle(assets.URLPrefix+"*", http.StripPrefix(assets.URLPrefix, manifest.Handler()))
 
 	// === Page Routes ===
-	playgroundHandler, err := handler.NewPlaygroundHandler(s.config.TemplateDir, s.logger)
+	playgroundHandler, err := handler.NewPlaygroundHandler(s.config.TemplateDir, manifest, s.logger, s.config.DevMode)
 	if err != nil {
 		return fmt.Errorf("creating playground handler: %w", err)
 	}
 	s.router.Get("/", playgroundHandler.HandlePlayground)
+	s.router.With(feature.Require(s.flags, feature.APIDocs)).Get("/swagger", handler.HandleSwaggerUI)
+
+	// Product analytics hear about sign-ins, new snippets and runs, whether
+	// or not the listeners that need auth are wired up below.
+	var product service.Publishers
+	if s.analytics != nil {
+		product = service.Publishers{service.NewAnalyticsPublisher(s.analytics)}
+	}
 
 	// === Auth Setup (optional — enabled when JWTSecret is configured) ===
 	var tokenService *auth.TokenService
 	if s.config.JWTSecret != "" {
-		ts, err := auth.NewTokenService(s.config.JWTSecret)
+		ts, err := auth.NewTokenService(s.config.JWTSecret, s.config.JWTPreviousSecrets...)
 		if err != nil {
 			return fmt.Errorf("creating token service: %w", err)
 		}
 		tokenService = ts
+		s.tokens = ts
 
 		// Only wire GitHub OAuth routes if all credentials are present
+		var authHandler *handler.AuthHandler
 		if s.config.GitHubClientID != "" && s.config.GitHubClientSecret != "" {
 			callbackURL := s.config.GitHubCallbackURL
 			if callbackURL == "" {
@@ -143,100 +645,788 @@ func (s *Server) setupRoutes() error {
 				callbackURL,
 			)
 
+			s.github = githubProvider
 			authService := service.NewAuthService(s.db, githubProvider, tokenService, s.logger)
-			authHandler := handler.NewAuthHandler(authService, githubProvider, s.logger)
+			authService.PublishEvents(product)
+			authHandler = handler.NewAuthHandler(authService, githubProvider, s.logger)
+			s.logger.Info("GitHub OAuth enabled")
+		}
 
-			// Auth routes
-			s.router.Get("/auth/github/login", authHandler.HandleGitHubLogin)
-			s.router.Get("/auth/github/callback", authHandler.HandleGitHubCallback)
-			s.router.Post("/auth/logout", authHandler.HandleLogout)
+		// Sign-in links need a way to email them.
+		var magicLinks *handler.MagicLinkHandler
+		if s.email != nil {
+			magicLinkService := service.NewMagicLinkService(s.email, s.db, s.db, tokenService, s.logger)
+			magicLinkService.PublishEvents(product)
+			magicLinks = handler.NewMagicLinkHandler(magicLinkService, s.logger)
+		}
 
-			s.logger.Info("GitHub OAuth enabled")
+		if authHandler != nil || magicLinks != nil {
+			// Auth routes (stricter rate limit — logins should be rare)
+			s.router.Route("/auth", func(r chi.Router) {
+				r.Use(s.rateLimit("auth", s.config.AuthRateLimit))
+				r.Use(middleware.MaxBodySize(s.config.AuthMaxBodyBytes))
+				r.Use(middleware.Timeout(s.config.AuthTimeout))
+				if authHandler != nil {
+					r.Get("/github/login", authHandler.HandleGitHubLogin)
+					r.Get("/github/callback", authHandler.HandleGitHubCallback)
+				}
+				if magicLinks != nil {
+					r.Post("/magic-link", magicLinks.HandleRequest)
+					r.Get("/magic", magicLinks.HandlePage)
+					r.Post("/magic", magicLinks.HandleSignIn)
+				}
+				r.Post("/logout", handler.HandleLogout)
+			})
 		} else {
-			s.logger.Warn("JWT configured but GitHub OAuth credentials missing — auth routes disabled")
+			s.logger.Warn("JWT configured but neither GitHub OAuth credentials nor email set up — auth routes disabled")
 		}
 	} else {
 		s.logger.Warn("JWT_SECRET not set — authentication disabled")
 	}
 
+	// === Profiling (admin only) ===
+	if s.config.PprofEnabled {
+		s.mountProfiler(tokenService)
+	}
+
 	// === API Routes ===
-	snippetService := service.NewSnippetService(s.db, s.logger)
-	snippetHandler := handler.NewSnippetHandler(snippetService, s.logger)
+	snippetService := service.NewSnippetService(s.snippetRepository(), s.logger)
+	snippetService.SetDeadlines(s.config.Deadlines)
+	snippetService.AllowPackages(s.config.Packages)
+
+	userService := service.NewUserService(s.db, s.logger)
+
+	// === Atom feeds of public snippets ===
+	feedHandler := handler.NewFeedHandler(snippetService, userService, s.logger)
+	s.router.With(s.cachePublic).Get("/feed.atom", feedHandler.HandleFeed)
+	s.router.With(s.cachePublic).Get("/users/{login}/feed.atom", feedHandler.HandleUserFeed)
+
+	// === Public snippet pages, for search engines and link previews ===
+	pageHandler, err := handler.NewPageHandler(s.config.TemplateDir, manifest, snippetService, userService, s.logger)
+	if err != nil {
+		return fmt.Errorf("creating page handler: %w", err)
+	}
+	pageHandler.CacheOnCDN(s.config.CDNMaxAge)
+	s.router.With(s.cachePublic).Get("/s/{id}", pageHandler.HandleSnippetPage)
+	s.router.With(s.cachePublic).Get("/sitemap.xml", pageHandler.HandleSitemap)
+	s.router.Get("/robots.txt", pageHandler.HandleRobots)
+
+	api := apiHandlers{
+		tokens:   tokenService,
+		snippets: handler.NewSnippetHandler(snippetService, s.logger),
+		graphql:  handler.NewGraphQLHandler(snippetService, userService, s.logger),
+		features: handler.NewFeatureHandler(s.flags, s.logger),
+		tasks:    handler.NewTaskHandler(s.scheduler),
+		storage:  handler.NewStorageHandler(s.storage, s.logger),
+		exercises: handler.NewExerciseHandler(
+			service.NewExerciseService(s.db, s.db, s.logger), s.logger),
+		leaderboards: handler.NewLeaderboardHandler(
+			service.NewLeaderboardService(s.db, s.db, s.db, s.db, s.logger), s.logger),
+		hints: handler.NewHintHandler(service.NewHintService(s.db, s.db, s.logger), s.logger),
+	}
+	api.snippets.CacheOnCDN(s.config.CDNMaxAge)
+	commentService := service.NewCommentService(s.db, s.db, s.logger)
+	api.comments = handler.NewCommentHandler(commentService, s.logger)
+	var executions *service.ExecutionCounter
+	var grading *service.GradingService
+	if s.exec != nil {
+		api.execute = handler.NewExecuteHandler(s.exec, s.logger)
+		api.execute.AllowPackages(s.config.Packages)
+		if s.config.GPU != nil {
+			api.execute.UseGPUs(s.config.GPU, s.gpuEntitled)
+		}
+		s.executions = service.NewExecutionService(s.logger)
+		s.executions.SetDeadlines(s.config.Deadlines)
+		api.execute.RunInBackground(s.executions)
+		grading = service.NewGradingService(s.db, s.db, s.db, s.exec, s.logger)
+		grading.SetDeadlines(s.config.Deadlines)
+		api.submissions = handler.NewSubmissionHandler(grading, s.logger)
+		executions = service.NewExecutionCounter()
+		s.liveRuns = service.NewLiveRunService(snippetService, s.exec, s.hub, s.logger)
+		api.liveRuns = handler.NewLiveRunHandler(s.liveRuns, s.logger)
+		if s.blobs != nil {
+			artifacts := service.NewArtifactService(s.blobs, s.logger)
+			api.execute.SaveArtifacts(artifacts)
+			api.artifacts = handler.NewArtifactHandler(artifacts, s.logger)
+		}
+	}
+	// Autocomplete and hover docs get an executor of their own when there
+	// is one, so keystrokes and runs don't queue for the same sandboxes.
+	completer := s.config.Completer
+	if completer == nil {
+		completer = s.exec
+	}
+	if completer != nil {
+		completions := service.NewCompletionService(completer, s.logger)
+		completions.SetDeadlines(s.config.Deadlines)
+		api.complete = handler.NewCompleteHandler(completions, s.logger)
+		docs := service.NewDocsService(completer, s.logger)
+		docs.SetDeadlines(s.config.Deadlines)
+		if store := s.docsCache(); store != nil {
+			docs.Cache(store, s.config.DocsCacheTTL)
+		}
+		api.docs = handler.NewDocsHandler(docs, s.logger)
+	}
+	debugger := s.config.Debugger
+	if debugger == nil {
+		debugger = s.exec
+	}
+	if debugger != nil {
+		s.debugger = service.NewDebugService(debugger, s.hub, s.logger)
+		api.debug = handler.NewDebugHandler(s.debugger, s.logger)
+	}
+	if s.exec != nil && s.config.AbuseDetection != abuse.Off {
+		detector := abuse.New(abuse.Options{Strictness: s.config.AbuseDetection}, s.logger)
+		detector.PublishEvents(product)
+		api.execute.DetectAbuse(detector)
+		grading.DetectAbuse(detector)
+		s.liveRuns.DetectAbuse(detector)
+		s.debugger.DetectAbuse(detector)
+	}
+	challengeService := service.NewChallengeService(s.db, s.db, s.db, grading, s.logger)
+	api.challenges = handler.NewChallengeHandler(challengeService, s.logger)
+
+	// Webhooks fire for the signed-in user's actions, and the admin pages
+	// are for admins, so both need auth.
+	snippetEvents := slices.Clone(product)
+	executeEvents := slices.Clone(product)
+	if tokenService != nil {
+		webhookService := service.NewWebhookService(s.db, s.jobs, s.logger)
+		api.webhooks = handler.NewWebhookHandler(webhookService, s.logger)
+		classService := service.NewClassService(s.db, s.db, s.db, grading, s.logger)
+		classService.DetectSimilarity(s.db, s.jobs)
+		api.classes = handler.NewClassHandler(classService, s.logger)
+		orgService := service.NewOrgService(s.db, s.db, s.logger)
+		api.orgs = handler.NewOrgHandler(orgService, s.logger)
+		api.exports = handler.NewExportHandler(
+			service.NewExportService(s.db, s.db, orgService, s.logger), s.logger)
+		api.orgInvites = handler.NewOrgInviteHandler(
+			service.NewOrgInviteService(s.db, orgService, s.db, s.email, s.publicURL(), s.logger), s.logger)
+		snippetService.EnableOrgs(s.db)
+		snippetService.LimitOrgs(service.OrgLimits{Snippets: s.config.OrgSnippetQuota, RunsPerDay: s.config.OrgRunQuota})
+		api.orgQuotas = handler.NewOrgQuotaHandler(snippetService, s.logger)
+		commentService.EnableOrgs(s.db)
+		badgeService := service.NewBadgeService(s.db, s.db, s.db, s.db, s.jobs, s.logger)
+		api.badges = handler.NewBadgeHandler(badgeService, s.logger)
+		notificationService := service.NewNotificationService(s.db, s.db, s.db, s.db, s.hub, s.logger)
+		api.notifications = handler.NewNotificationHandler(notificationService, s.logger)
+		transferService := service.NewTransferService(s.db, snippetService, s.db, s.db, s.logger)
+		transferService.PublishEvents(notificationService)
+		api.transfers = handler.NewTransferHandler(transferService, s.logger)
+		starService := service.NewStarService(snippetService, s.db, s.logger)
+		api.stars = handler.NewStarHandler(starService, s.logger)
+		activityService := service.NewActivityService(s.db, s.db, s.db, s.logger)
+		api.activity = handler.NewActivityHandler(activityService, s.logger)
+		statsService := service.NewStatsService(s.db, s.logger)
+		api.stats = handler.NewStatsHandler(statsService, s.logger)
+		snippetEvents = append(snippetEvents, webhookService, badgeService, notificationService, activityService)
+		challengeService.PublishEvents(badgeService)
+		commentService.PublishEvents(notificationService)
+		starService.PublishEvents(service.Publishers{notificationService, activityService})
+		gradingEvents := service.Publishers{notificationService}
+		if s.email != nil {
+			gradingEvents = append(gradingEvents, s.email)
+			api.email = handler.NewEmailHandler(s.email, s.logger)
+			s.router.Get("/email/unsubscribe", api.email.HandleUnsubscribe)
+		}
+		if grading != nil {
+			grading.PublishEvents(gradingEvents)
+		}
+		executeEvents = append(executeEvents, executions, webhookService, badgeService, statsService)
+		runService := service.NewRunService(s.db, s.logger)
+		if api.execute != nil {
+			api.execute.RecordRuns(runService)
+		}
+		api.graphql.AddRunsAndStars(runService, starService)
 
+		// === Admin pages ===
+		pool, _ := s.exec.(executor.StatsProvider)
+		dashboard := service.NewDashboardService(s.db, s.db, executions, s.logger)
+		adminHandler, err := handler.NewAdminHandler(s.config.TemplateDir, manifest, dashboard, pool, s.recentErrors, s.logger, s.config.DevMode)
+		if err != nil {
+			return fmt.Errorf("creating admin handler: %w", err)
+		}
+		s.router.Route("/admin", func(r chi.Router) {
+			r.Use(auth.RequireAuth(tokenService))
+			r.Use(auth.RequireRole(s.userRole, model.RoleAdmin))
+			r.Get("/", adminHandler.HandleDashboard)
+			r.Get("/errors", adminHandler.HandleErrors)
+		})
+	}
+
+	snippetService.PublishEvents(snippetEvents)
+	if api.execute != nil {
+		api.execute.PublishEvents(executeEvents)
+	}
+
+	// === WebSocket hub ===
+	// Mounted outside /api: an upgraded connection outlives any request
+	// timeout or body limit. "user:<id>" carries a user's own notifications;
+	// "collab:<snippet id>" is a collaborative editing session, and
+	// "snippet:<snippet id>" shows who has the snippet open.
+	s.hub.Authorize(service.UserTopicPrefix, func(_ context.Context, userID, topic string) error {
+		if userID == "" || topic != service.UserTopicPrefix+userID {
+			return ws.ErrForbidden
+		}
+		return nil
+	})
+	s.collab = service.NewCollabService(snippetService, s.hub, s.logger)
+	s.collab.RejectSuspended(s.suspensions.IsSuspended)
+	service.NewPresenceService(snippetService, userService, s.hub, s.logger)
+	if tokenService != nil {
+		s.router.With(auth.OptionalAuth(tokenService)).Get("/ws", s.hub.ServeHTTP)
+	} else {
+		s.router.Get("/ws", s.hub.ServeHTTP)
+	}
+
+	// VERSIONED MOUNTS:
+	// Each API version is a function that registers routes on a chi.Router.
+	// Mounting the same function under several prefixes is how /api stays an
+	// alias of v1; a future v2 gets its own routesV2 mounted at /api/v2.
+	//
+	// The rate limiters are built here, once, rather than in routesV1: each
+	// limiter keeps its own buckets, so a pair per mount would give a client
+	// alternating /api and /api/v1 twice its budget.
+	api.apiLimit = s.rateLimit("api", s.config.APIRateLimit)
+	api.executeLimit = s.rateLimit("execute", s.config.ExecuteRateLimit)
+	v1 := s.routesV1(api)
+
+	s.router.Route("/api/v1", func(r chi.Router) {
+		r.Use(handler.WithAPIVersion(handler.APIVersion1))
+		v1(r)
+	})
+
+	// Deprecated: the unversioned /api prefix is kept for one release so existing
+	// clients keep working. New clients should use /api/v1.
 	s.router.Route("/api", func(r chi.Router) {
-		// /api/me requires authentication
-		if tokenService != nil {
-			r.With(auth.RequireAuth(tokenService)).Get("/me", func(w http.ResponseWriter, req *http.Request) {
-				// We need the auth handler for HandleMe, but it might not exist if GitHub creds are missing.
-				// Create a minimal handler just for /api/me.
-				userID, ok := auth.UserIDFromContext(req.Context())
-				if !ok {
-					http.Error(w, `{"error":"not authenticated"}`, http.StatusUnauthorized)
+		r.Use(handler.NegotiateAPIVersion)
+		if !s.config.LegacyAPI.Since.IsZero() {
+			r.Use(handler.Deprecations(s.logger), handler.Deprecated(s.config.LegacyAPI))
+		}
+		v1(r)
+	})
+
+	return nil
+}
+
+// apiHandlers bundles the dependencies shared by the versioned API route tables.
+type apiHandlers struct {
+	tokens        *auth.TokenService // nil when auth is disabled
+	snippets      *handler.SnippetHandler
+	execute       *handler.ExecuteHandler // nil when no executor is available
+	features      *handler.FeatureHandler
+	tasks         *handler.TaskHandler
+	storage       *handler.StorageHandler
+	webhooks      *handler.WebhookHandler   // nil when auth is disabled
+	classes       *handler.ClassHandler     // nil when auth is disabled
+	orgs          *handler.OrgHandler       // nil when auth is disabled
+	orgInvites    *handler.OrgInviteHandler // nil when auth is disabled
+	orgQuotas     *handler.OrgQuotaHandler  // nil when auth is disabled
+	transfers     *handler.TransferHandler  // nil when auth is disabled
+	graphql       *handler.GraphQLHandler
+	exercises     *handler.ExerciseHandler
+	submissions   *handler.SubmissionHandler // nil when no executor is available
+	leaderboards  *handler.LeaderboardHandler
+	hints         *handler.HintHandler
+	comments      *handler.CommentHandler
+	liveRuns      *handler.LiveRunHandler // nil when no executor is available
+	challenges    *handler.ChallengeHandler
+	badges        *handler.BadgeHandler        // nil when auth is disabled
+	notifications *handler.NotificationHandler // nil when auth is disabled
+	stars         *handler.StarHandler         // nil when auth is disabled
+	email         *handler.EmailHandler        // nil when email is off
+	activity      *handler.ActivityHandler     // nil when auth is disabled
+	stats         *handler.StatsHandler        // nil when auth is disabled
+	exports       *handler.ExportHandler       // nil when auth is disabled
+	artifacts     *handler.ArtifactHandler     // nil without an executor and blob storage
+	complete      *handler.CompleteHandler     // nil when no executor is available
+	docs          *handler.DocsHandler         // nil when no executor is available
+	debug         *handler.DebugHandler        // nil when no executor is available
+
+	// Shared by every mount of the route table, so the limits are per
+	// client, not per prefix.
+	apiLimit     func(http.Handler) http.Handler
+	executeLimit func(http.Handler) http.Handler
+}
+
+// routesV1 returns the route table for version 1 of the API.
+func (s *Server) routesV1(h apiHandlers) func(r chi.Router) {
+	return func(r chi.Router) {
+		r.Use(handler.Deprecations(s.logger))
+		r.Use(h.apiLimit)
+		executeLimit := h.executeLimit
+		r.Use(middleware.MaxBodySize(s.config.APIMaxBodyBytes))
+		r.Use(middleware.BodyLog(s.logger, func() bool { return s.flags.Enabled(feature.BodyLogging) }, s.config.BodyLog))
+		if h.tokens != nil {
+			r.Use(auth.RejectSuspended(h.tokens, s.suspensions.IsSuspended, readOnlyPost))
+		}
+
+		// Everything except /execute should answer quickly; a slow request
+		// here means something is wrong, so fail fast with a 504.
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Timeout(s.config.APITimeout))
+
+			r.With(feature.Require(s.flags, feature.APIDocs)).Get("/openapi.json", handler.HandleOpenAPISpec)
+			r.Get("/errors", handler.HandleErrorCodes)
+
+			// /me requires authentication
+			if h.tokens != nil {
+				r.With(auth.RequireAuth(h.tokens)).Get("/me", handler.HandleMe(s.db.GetUserByID))
+
+				r.With(auth.RequireAuth(h.tokens)).Post("/me/token", handler.HandleIssueToken(h.tokens))
+				r.With(auth.RequireAuth(h.tokens)).Put("/me/leaderboard", h.leaderboards.HandleSetOptOut)
+				r.With(auth.RequireAuth(h.tokens)).Get("/me/streak", h.challenges.HandleStreak)
+				r.With(auth.RequireAuth(h.tokens)).Get("/me/stats", h.stats.HandleMe)
+				r.With(auth.RequireAuth(h.tokens)).Get("/me/notifications", h.notifications.HandleList)
+				r.With(auth.RequireAuth(h.tokens)).Post("/me/notifications/read", h.notifications.HandleMarkRead)
+				if h.email != nil {
+					r.With(auth.RequireAuth(h.tokens)).Get("/me/email-preferences", h.email.HandleGetPreferences)
+					r.With(auth.RequireAuth(h.tokens)).Put("/me/email-preferences", h.email.HandleSetPreferences)
+				}
+
+				// Admin routes: signed in AND role=admin
+				r.Route("/admin", func(r chi.Router) {
+					r.Use(auth.RequireAuth(h.tokens))
+					r.Use(auth.RequireRole(s.userRole, model.RoleAdmin))
+					r.Get("/features", h.features.HandleList)
+					r.Put("/features/{name}", h.features.HandleSet)
+					r.Get("/tasks", h.tasks.HandleList)
+					r.Get("/db", h.storage.HandleStats)
+					r.Post("/db/checkpoint", h.storage.HandleCheckpoint)
+					r.Put("/orgs/{id}/quota", h.orgQuotas.HandleSetQuota)
+				})
+
+				// Badges are public, like the users who earned them
+				r.Get("/users/{login}/badges", h.badges.HandleList)
+
+				// So is activity on public snippets
+				r.Get("/feed", h.activity.HandleFeed)
+				r.Get("/users/{login}/activity", h.activity.HandleUser)
+
+				// Webhooks: each signed-in user manages their own
+				r.Route("/webhooks", func(r chi.Router) {
+					r.Use(auth.RequireAuth(h.tokens))
+					r.Get("/", h.webhooks.HandleList)
+					r.Post("/", h.webhooks.HandleCreate)
+					r.Delete("/{id}", h.webhooks.HandleDelete)
+					r.Get("/{id}/deliveries", h.webhooks.HandleDeliveries)
+				})
+
+				// Orgs: membership decides what each user sees
+				r.Route("/orgs", func(r chi.Router) {
+					r.Use(auth.RequireAuth(h.tokens))
+					r.Get("/", h.orgs.HandleList)
+					r.Post("/", h.orgs.HandleCreate)
+					r.Get("/{id}", h.orgs.HandleGet)
+					r.Delete("/{id}", h.orgs.HandleDelete)
+					r.Get("/{id}/members", h.orgs.HandleMembers)
+					r.Post("/{id}/members", h.orgs.HandleAddMember)
+					r.Put("/{id}/members/{login}", h.orgs.HandleSetRole)
+					r.Delete("/{id}/members/{login}", h.orgs.HandleRemoveMember)
+					r.Get("/{id}/usage", h.orgQuotas.HandleUsage)
+					r.Get("/{id}/invites", h.orgInvites.HandleList)
+					r.Post("/{id}/invites", h.orgInvites.HandleCreate)
+					r.Delete("/{id}/invites/{inviteID}", h.orgInvites.HandleRevoke)
+				})
+				r.Get("/invites/{token}", h.orgInvites.HandleGet)
+				r.With(auth.RequireAuth(h.tokens)).Post("/invites/{token}/accept", h.orgInvites.HandleAccept)
+
+				// Snippet transfers: the recipient has to accept
+				r.With(auth.RequireAuth(h.tokens)).Get("/me/transfers", h.transfers.HandleList)
+				r.With(auth.RequireAuth(h.tokens)).Post("/transfers/{id}/accept", h.transfers.HandleAccept)
+				r.With(auth.RequireAuth(h.tokens)).Delete("/transfers/{id}", h.transfers.HandleDelete)
+			}
+
+			// GraphQL: read-only, with a viewer when signed in
+			graphql := r.With()
+			if h.tokens != nil {
+				graphql = r.With(auth.OptionalAuth(h.tokens))
+			}
+			graphql.Get("/graphql", h.graphql.HandleQuery)
+			graphql.Post("/graphql", h.graphql.HandleQuery)
+
+			// Read-only snippet routes (no auth needed)
+			r.Get("/snippets", h.snippets.HandleList)
+			r.Get("/snippets/{id}", h.snippets.HandleGetByID)
+			r.Get("/snippets/{id}/html", h.snippets.HandleGetHTML)
+			r.Get("/snippets/{id}/comments", h.comments.HandleList)
+
+			// Mutating snippet routes — apply OptionalAuth if available
+			if h.tokens != nil {
+				r.With(auth.OptionalAuth(h.tokens), s.idempotent).Post("/snippets", h.snippets.HandleCreate)
+				r.With(auth.OptionalAuth(h.tokens)).Put("/snippets/{id}", h.snippets.HandleUpdate)
+				r.With(auth.OptionalAuth(h.tokens)).Delete("/snippets/{id}", h.snippets.HandleDelete)
+				r.With(auth.OptionalAuth(h.tokens)).Put("/snippets/{id}/requirements", h.snippets.HandleSetRequirements)
+				r.With(auth.RequireAuth(h.tokens)).Put("/snippets/{id}/visibility", h.snippets.HandleSetVisibility)
+				r.With(auth.RequireAuth(h.tokens)).Post("/snippets/{id}/fork", h.snippets.HandleFork)
+				r.With(auth.RequireAuth(h.tokens)).Put("/snippets/{id}/star", h.stars.HandleStar)
+				r.With(auth.RequireAuth(h.tokens)).Delete("/snippets/{id}/star", h.stars.HandleUnstar)
+				r.With(auth.RequireAuth(h.tokens)).Post("/snippets/{id}/transfer", h.transfers.HandleOffer)
+				r.With(auth.RequireAuth(h.tokens)).Post("/snippets/{id}/comments", h.comments.HandleCreate)
+				r.With(auth.RequireAuth(h.tokens)).Delete("/snippets/{id}/comments/{commentID}", h.comments.HandleDelete)
+			} else {
+				r.With(s.idempotent).Post("/snippets", h.snippets.HandleCreate)
+				r.Put("/snippets/{id}", h.snippets.HandleUpdate)
+				r.Delete("/snippets/{id}", h.snippets.HandleDelete)
+				r.Put("/snippets/{id}/requirements", h.snippets.HandleSetRequirements)
+			}
+
+			// Live runs: the owner runs a snippet, anyone can watch.
+			if h.liveRuns != nil {
+				r.Get("/snippets/{id}/live-run", h.liveRuns.HandleGet)
+				if h.tokens != nil {
+					r.With(executeLimit, feature.Require(s.flags, feature.Execution), auth.RequireAuth(h.tokens)).Post("/snippets/{id}/live-run", h.liveRuns.HandleStart)
+					r.With(auth.RequireAuth(h.tokens)).Delete("/snippets/{id}/live-run", h.liveRuns.HandleStop)
+				}
+			}
+		})
+
+		// Exercises: anyone can read them; authors write them, and signed-in
+		// learners submit solutions. Grading runs code, so like /execute it
+		// gets the execution deadline instead of the API one.
+		r.Route("/exercises", func(r chi.Router) {
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.Timeout(s.config.APITimeout))
+				r.Get("/", h.exercises.HandleList)
+				r.Get("/{id}/leaderboard", h.leaderboards.HandleExercise)
+				if h.tokens == nil {
+					r.Get("/{id}", h.exercises.HandleGet)
 					return
 				}
-				user, err := s.db.GetUserByID(req.Context(), userID)
-				if err != nil || user == nil {
-					http.Error(w, `{"error":"user not found"}`, http.StatusUnauthorized)
+				r.With(auth.OptionalAuth(h.tokens)).Get("/{id}", h.exercises.HandleGet)
+				r.With(auth.RequireAuth(h.tokens)).Post("/", h.exercises.HandleCreate)
+				r.With(auth.RequireAuth(h.tokens)).Put("/{id}", h.exercises.HandleUpdate)
+				r.With(auth.RequireAuth(h.tokens)).Delete("/{id}", h.exercises.HandleDelete)
+				r.With(auth.RequireAuth(h.tokens)).Get("/{id}/hints", h.hints.HandleGet)
+				r.With(auth.RequireAuth(h.tokens)).Post("/{id}/hints", h.hints.HandleUnlock)
+			})
+			if h.submissions != nil && h.tokens != nil {
+				r.With(
+					middleware.Timeout(s.config.ExecuteTimeout),
+					executeLimit,
+					feature.Require(s.flags, feature.Execution),
+					auth.RequireAuth(h.tokens),
+				).Post("/{id}/submit", h.submissions.HandleSubmit)
+			}
+		})
+
+		// Daily challenges: anyone can see today's and past ones; authors
+		// schedule them. Submitting runs code, so it gets the execution
+		// deadline like exercise submissions.
+		r.Route("/challenges", func(r chi.Router) {
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.Timeout(s.config.APITimeout))
+				if h.tokens == nil {
+					r.Get("/", h.challenges.HandleList)
+					r.Get("/today", h.challenges.HandleToday)
+					r.Get("/{date}", h.challenges.HandleGet)
 					return
 				}
-				w.Header().Set("Content-Type", "application/json")
-				json := fmt.Sprintf(`{"id":"%s","login":"%s","email":"%s","avatarUrl":"%s"}`,
-					user.ID, user.Login, user.Email, user.AvatarURL)
-				w.Write([]byte(json))
+				r.With(auth.OptionalAuth(h.tokens)).Get("/", h.challenges.HandleList)
+				r.With(auth.OptionalAuth(h.tokens)).Get("/today", h.challenges.HandleToday)
+				r.With(auth.OptionalAuth(h.tokens)).Get("/{date}", h.challenges.HandleGet)
+				r.With(auth.RequireAuth(h.tokens)).Put("/{date}", h.challenges.HandleSchedule)
+				r.With(auth.RequireAuth(h.tokens)).Delete("/{date}", h.challenges.HandleUnschedule)
+			})
+			if h.submissions != nil && h.tokens != nil {
+				r.With(
+					middleware.Timeout(s.config.ExecuteTimeout),
+					executeLimit,
+					feature.Require(s.flags, feature.Execution),
+					auth.RequireAuth(h.tokens),
+				).Post("/{date}/submit", h.challenges.HandleSubmit)
+			}
+		})
+
+		// Classes: membership decides what each user sees. Submitting to an
+		// assignment runs code, so it gets the execution deadline.
+		if h.classes != nil {
+			r.Route("/classes", func(r chi.Router) {
+				r.Use(auth.RequireAuth(h.tokens))
+				r.Group(func(r chi.Router) {
+					r.Use(middleware.Timeout(s.config.APITimeout))
+					r.Get("/", h.classes.HandleList)
+					r.Post("/", h.classes.HandleCreate)
+					r.Post("/join", h.classes.HandleJoin)
+					r.Get("/{id}", h.classes.HandleGet)
+					r.Get("/{id}/members", h.classes.HandleMembers)
+					r.Get("/{id}/leaderboard", h.leaderboards.HandleClass)
+					r.Get("/{id}/assignments", h.classes.HandleAssignments)
+					r.Post("/{id}/assignments", h.classes.HandleCreateAssignment)
+					r.Put("/{id}/assignments/{assignmentID}", h.classes.HandleUpdateAssignment)
+					r.Get("/{id}/assignments/{assignmentID}/submissions", h.classes.HandleSubmissions)
+					r.Get("/{id}/assignments/{assignmentID}/progress", h.classes.HandleProgress)
+					r.Get("/{id}/assignments/{assignmentID}/similarity", h.classes.HandleSimilarity)
+					r.Post("/{id}/assignments/{assignmentID}/similarity", h.classes.HandleRequestSimilarity)
+				})
+				if h.submissions != nil {
+					r.With(
+						middleware.Timeout(s.config.ExecuteTimeout),
+						executeLimit,
+						feature.Require(s.flags, feature.Execution),
+					).Post("/{id}/assignments/{assignmentID}/submit", h.classes.HandleSubmit)
+				}
 			})
 		}
 
-		// Read-only snippet routes (no auth needed)
-		r.Get("/snippets", snippetHandler.HandleList)
-		r.Get("/snippets/{id}", snippetHandler.HandleGetByID)
+		// /execute only available when Docker executor is running.
+		// Running code legitimately takes seconds, so it gets its own deadline.
+		// Anyone may run code; a signed-in user's runs count towards their stats.
+		if h.execute != nil {
+			execute := r.With(
+				middleware.Timeout(s.config.ExecuteTimeout),
+				executeLimit,
+				feature.Require(s.flags, feature.Execution),
+			)
+			if h.tokens != nil {
+				execute = execute.With(auth.OptionalAuth(h.tokens)) // before s.idempotent, which keys on the user
+			}
+			execute.With(s.idempotent).Post("/execute", h.execute.HandleExecute)
+			if h.tokens != nil {
+				r.With(
+					middleware.Timeout(s.config.ExecuteTimeout),
+					executeLimit,
+					feature.Require(s.flags, feature.Execution),
+					auth.RequireAuth(h.tokens),
+					s.idempotent,
+				).Post("/runs/{id}/replay", h.execute.HandleReplay)
+			}
 
-		// Mutating snippet routes — apply OptionalAuth if available
-		if tokenService != nil {
-			r.With(auth.OptionalAuth(tokenService)).Post("/snippets", snippetHandler.HandleCreate)
-			r.With(auth.OptionalAuth(tokenService)).Put("/snippets/{id}", snippetHandler.HandleUpdate)
-			r.With(auth.OptionalAuth(tokenService)).Delete("/snippets/{id}", snippetHandler.HandleDelete)
-		} else {
-			r.Post("/snippets", snippetHandler.HandleCreate)
-			r.Put("/snippets/{id}", snippetHandler.HandleUpdate)
-			r.Delete("/snippets/{id}", snippetHandler.HandleDelete)
+			// Running in the background: the POST answers at once, and a
+			// long poll waits up to a minute — past the API timeout and the
+			// server's WriteTimeout both (see service.ExecutionService).
+			background := r.With(feature.Require(s.flags, feature.Execution))
+			if h.tokens != nil {
+				background = background.With(auth.OptionalAuth(h.tokens))
+			}
+			background.With(middleware.Timeout(s.config.APITimeout), executeLimit, s.idempotent).Post("/executions", h.execute.HandleStart)
+			background.With(noWriteDeadline).Get("/executions/{id}", h.execute.HandleGet)
+			background.With(middleware.Timeout(s.config.APITimeout)).Delete("/executions/{id}", h.execute.HandleCancel)
 		}
 
-		// /api/execute only available when Docker executor is running
-		if s.exec != nil {
-			executeHandler := handler.NewExecuteHandler(s.exec, s.logger)
-			r.Post("/execute", executeHandler.HandleExecute)
+		// Autocomplete and hover docs run in a sandbox, so they get the
+		// execute deadline, but they're asked for as the user types and
+		// points: the API rate limit applies, not the stricter one for runs.
+		if h.complete != nil {
+			editor := r.With(
+				middleware.Timeout(s.config.ExecuteTimeout),
+				feature.Require(s.flags, feature.Execution),
+			)
+			editor.Post("/complete", h.complete.HandleComplete)
+			editor.Get("/docs", h.docs.HandleGet)
 		}
-	})
 
-	return nil
+		// A debugging session outlives the POST that starts it, like a
+		// background run; the learner drives it over the hub.
+		if h.debug != nil {
+			debug := r.With(middleware.Timeout(s.config.APITimeout), feature.Require(s.flags, feature.Execution))
+			if h.tokens != nil {
+				debug = debug.With(auth.OptionalAuth(h.tokens))
+			}
+			debug.With(executeLimit).Post("/debug", h.debug.HandleStart)
+			debug.Get("/debug/{id}", h.debug.HandleGet)
+			debug.Delete("/debug/{id}", h.debug.HandleStop)
+		}
+
+		// Downloads take as long as they take, so they skip the API timeout
+		// and the server's WriteTimeout.
+		if h.artifacts != nil {
+			r.With(noWriteDeadline).Get("/artifacts/{run}/{name}", h.artifacts.HandleGet)
+		}
+		if h.exports != nil {
+			export := r.With(noWriteDeadline, auth.RequireAuth(h.tokens))
+			export.Get("/me/export", h.exports.HandleUser)
+			export.Get("/orgs/{id}/export", h.exports.HandleOrg)
+		}
+	}
+}
+
+// idempotent applies Idempotency-Key handling to a route, or passes requests
+// straight through when IdempotencyTTL is zero.
+func (s *Server) idempotent(next http.Handler) http.Handler {
+	if s.config.IdempotencyTTL <= 0 {
+		return next
+	}
+	return idempotency.Middleware(s.db, s.config.IdempotencyTTL, s.logger)(next)
+}
+
+// userRole looks up a user's role for auth.RequireRole.
+func (s *Server) userRole(ctx context.Context, userID string) (string, error) {
+	user, err := s.db.GetUserByID(ctx, userID)
+	if err != nil || user == nil {
+		return "", err
+	}
+	return user.Role, nil
+}
+
+// gpuEntitled reports whether a user's role is one of GPURoles. Like
+// auth.RequireRole it looks the role up every time, so a demoted user
+// loses the GPU at once.
+func (s *Server) gpuEntitled(ctx context.Context, userID string) (bool, error) {
+	role, err := s.userRole(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return role != "" && slices.Contains(s.config.GPURoles, role), nil
+}
+
+// newMetrics creates the metrics registry and registers gauges for the
+// database pool, job queue, WebSocket hub and, when supported, the executor
+// pool.
+//
+// The average wait for a sandbox over the last five minutes is
+//
+//	rate(playground_executor_wait_seconds_total[5m]) / rate(playground_executor_waits_total[5m])
+//
+// and the share of runs spared a package install is
+//
+//	rate(playground_executor_env_cache_hits_total[5m]) / (rate(playground_executor_env_cache_hits_total[5m]) + rate(playground_executor_env_cache_misses_total[5m]))
+func (s *Server) newMetrics() *metrics.Registry {
+	reg := metrics.New()
+	reg.RegisterDBStats(s.db.Stats)
+
+	if sp, ok := s.exec.(executor.StatsProvider); ok {
+		reg.GaugeFunc("executor_pool_size", "Pre-warmed sandboxes the pool keeps ready; moves between POOL_MIN_SIZE and POOL_MAX_SIZE when adaptive.",
+			func() float64 { return float64(sp.Stats().PoolSize) })
+		reg.GaugeFunc("executor_pool_available", "Pre-warmed sandboxes ready for use.",
+			func() float64 { return float64(sp.Stats().Available) })
+		reg.GaugeFunc("executor_in_flight", "Code executions currently running.",
+			func() float64 { return float64(sp.Stats().InFlight) })
+		reg.GaugeFunc("executor_queue_depth", "Code executions waiting for a sandbox.",
+			func() float64 { return float64(sp.Stats().Waiting) })
+		reg.CounterFunc("executor_waits_total", "Code executions that have been given a sandbox.",
+			func() float64 { return float64(sp.Stats().Waits) })
+		reg.CounterFunc("executor_wait_seconds_total", "Total time code executions spent waiting for a sandbox.",
+			func() float64 { return sp.Stats().WaitTime.Seconds() })
+		reg.CounterFunc("executor_env_cache_hits_total", "Runs whose requirements were restored from the environment cache.",
+			func() float64 { return float64(sp.Stats().Environments.Hits) })
+		reg.CounterFunc("executor_env_cache_misses_total", "Runs whose requirements had to be installed.",
+			func() float64 { return float64(sp.Stats().Environments.Misses) })
+		reg.CounterFunc("executor_env_cache_evictions_total", "Cached environments dropped to make room; see ENV_CACHE_MB.",
+			func() float64 { return float64(sp.Stats().Environments.Evictions) })
+		reg.GaugeFunc("executor_env_cache_entries", "Installed requirement sets the environment cache holds.",
+			func() float64 { return float64(sp.Stats().Environments.Entries) })
+		reg.GaugeFunc("executor_env_cache_bytes", "Packed size of the cached environments.",
+			func() float64 { return float64(sp.Stats().Environments.Bytes) })
+	}
+
+	// Transient failures retried by internal/retry, database and Docker
+	// alike. A rising exhausted count means they've stopped being transient.
+	reg.CounterFunc("retries_total", "Operations tried again after a transient error.",
+		func() float64 { return float64(retry.ReadStats().Retries) })
+	reg.CounterFunc("retries_recovered_total", "Operations that succeeded after failing transiently.",
+		func() float64 { return float64(retry.ReadStats().Recovered) })
+	reg.CounterFunc("retries_exhausted_total", "Operations that still failed after their last retry.",
+		func() float64 { return float64(retry.ReadStats().Exhausted) })
+
+	reg.CounterFunc("runs_purged_total", "Signed-in users' runs deleted by the run history retention policy.",
+		func() float64 { return float64(s.runsPurged.Load()) })
+	reg.CounterFunc("anonymous_runs_purged_total", "Anonymous runs deleted by the run history retention policy.",
+		func() float64 { return float64(s.anonymousRunsPurged.Load()) })
+
+	reg.GaugeFunc("jobs_backlog", "Background jobs due to run but not yet picked up by a worker.",
+		func() float64 {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			n, err := s.jobs.Backlog(ctx)
+			if err != nil {
+				return 0
+			}
+			return float64(n)
+		})
+
+	reg.GaugeFunc("db_wal_bytes", "Size of the database's write-ahead log; see the wal_checkpoint task.",
+		func() float64 {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			stats, err := s.storage.Stats(ctx)
+			if err != nil {
+				return 0
+			}
+			return float64(stats.WALBytes)
+		})
+
+	reg.GaugeFunc("websocket_connections", "Open WebSocket connections.",
+		func() float64 { return float64(s.hub.Stats().Connections) })
+	reg.GaugeFunc("websocket_subscriptions", "WebSocket topic subscriptions across all connections.",
+		func() float64 { return float64(s.hub.Stats().Subscriptions) })
+
+	return reg
 }
 
 // Start starts the HTTP server and handles graceful shutdown.
+//
+// When TLS is configured, the main listener serves HTTPS and a second,
+// optional listener redirects plain HTTP to it (see tls.go).
 func (s *Server) Start() error {
-	defer s.db.Close()
+	// Error paths tear everything down too. After a graceful shutdown the
+	// hooks have already run and this is a no-op.
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		defer cancel()
+		s.runShutdownHooks(ctx)
+	}()
+
+	// Job workers start before the listener so handlers can enqueue right away.
+	if err := s.jobs.Start(context.Background()); err != nil {
+		return fmt.Errorf("starting job queue: %w", err)
+	}
+	s.scheduler.Start()
+	s.collab.Start()
+	if s.analytics != nil {
+		s.analytics.Start()
+	}
+
+	ln, err := s.config.listen()
+	if err != nil {
+		return fmt.Errorf("opening listener: %w", err)
+	}
 
 	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%d", s.config.Port),
 		Handler:      s.router,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
+	var redirectSrv *http.Server
+	scheme := "http"
+	if s.config.tlsEnabled() {
+		redirectSrv = s.configureTLS(srv)
+		scheme = "https"
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
-	serverErrors := make(chan error, 1)
+	serverErrors := make(chan error, 2)
 
 	go func() {
-		s.logger.Info("server starting",
-			slog.Int("port", s.config.Port),
-			slog.String("url", fmt.Sprintf("http://localhost:%d", s.config.Port)),
+		attrs := []any{
+			slog.String("network", ln.Addr().Network()),
+			slog.String("addr", ln.Addr().String()),
 			slog.String("database", s.config.DBPath),
-		)
-		serverErrors <- srv.ListenAndServe()
+		}
+		if s.config.Listen == "" {
+			attrs = append(attrs, slog.String("url", fmt.Sprintf("%s://localhost:%d", scheme, s.config.Port)))
+		}
+		s.logger.Info("server starting", attrs...)
+
+		if s.config.tlsEnabled() {
+			// Empty file names make ServeTLS use srv.TLSConfig (autocert).
+			serverErrors <- srv.ServeTLS(ln, s.config.TLSCertFile, s.config.TLSKeyFile)
+			return
+		}
+		serverErrors <- srv.Serve(ln)
 	}()
 
+	if redirectSrv != nil {
+		go func() {
+			s.logger.Info("HTTP redirect listener starting", slog.String("addr", redirectSrv.Addr))
+			serverErrors <- redirectSrv.ListenAndServe()
+		}()
+	}
+
 	select {
 	case err := <-serverErrors:
 		if err != http.ErrServerClosed {
@@ -246,12 +1436,22 @@ func (s *Server) Start() error {
 	case sig := <-quit:
 		s.logger.Info("shutdown signal received", slog.String("signal", sig.String()))
 
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
 		defer cancel()
 
+		if redirectSrv != nil {
+			if err := redirectSrv.Shutdown(ctx); err != nil {
+				s.logger.Warn("redirect listener shutdown failed", slog.String("error", err.Error()))
+			}
+		}
 		if err := srv.Shutdown(ctx); err != nil {
+			s.runShutdownHooks(ctx)
 			return fmt.Errorf("graceful shutdown failed: %w", err)
 		}
+
+		// Run the hooks only after HTTP requests have drained, since in-flight
+		// requests may still enqueue jobs or use the executor.
+		s.runShutdownHooks(ctx)
 		s.logger.Info("server stopped gracefully")
 	}
 
@@ -0,0 +1,2540 @@
+package server
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/executor"
+	"github.com/sakif/coding-playground/internal/handler"
+	"github.com/sakif/coding-playground/internal/mailer"
+	"github.com/sakif/coding-playground/internal/middleware"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/service"
+	"github.com/sakif/coding-playground/internal/ws"
+)
+
+// newTestServer builds a Server backed by an in-memory database and the real
+// templates, without starting a listener. Requests are sent straight to the router.
+func newTestServer(t *testing.T, mutate func(*Config)) *Server {
+	t.Helper()
+
+	cfg := Config{
+		Port:        8080,
+		TemplateDir: filepath.Join("..", "..", "web", "templates"),
+		StaticDir:   filepath.Join("..", "..", "web", "static"),
+		DBPath:      ":memory:",
+	}
+	if mutate != nil {
+		mutate(&cfg)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	srv, err := New(cfg, logger, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { srv.db.Close() })
+	return srv
+}
+
+func (s *Server) do(t *testing.T, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	s.router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRoutes_VersionedAndLegacyPrefixes(t *testing.T) {
+	srv := newTestServer(t, nil)
+
+	tests := []struct {
+		path        string
+		wantVersion string
+	}{
+		{"/api/v1/snippets", "v1"},
+		{"/api/snippets", "unversioned"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rr := srv.do(t, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rr.Code)
+			}
+			if got := rr.Header().Get("API-Version"); got != tt.wantVersion {
+				t.Errorf("API-Version = %q, want %q", got, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestRoutes_LegacyPrefixNegotiatesVersion(t *testing.T) {
+	srv := newTestServer(t, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/snippets", nil)
+	req.Header.Set("Accept", "application/vnd.pyplayground.v1+json")
+	rr := srv.do(t, req)
+	if got := rr.Header().Get("API-Version"); got != "v1" {
+		t.Errorf("API-Version = %q, want v1", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/snippets", nil)
+	req.Header.Set("Accept", "application/vnd.pyplayground.v9+json")
+	rr = srv.do(t, req)
+	if rr.Code != http.StatusNotAcceptable {
+		t.Errorf("status = %d, want 406 for unsupported version", rr.Code)
+	}
+}
+
+// sessionCookie creates a user with the given role and returns a valid session cookie.
+func (s *Server) sessionCookie(t *testing.T, githubID int64, role string) *http.Cookie {
+	t.Helper()
+	ctx := context.Background()
+
+	user := &model.User{ID: role + "-id", GitHubID: githubID, Login: role}
+	if err := s.db.Upsert(ctx, user); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if err := s.db.SetUserRole(ctx, user.ID, role); err != nil {
+		t.Fatalf("SetUserRole() error = %v", err)
+	}
+
+	ts, err := auth.NewTokenService(s.config.JWTSecret)
+	if err != nil {
+		t.Fatalf("NewTokenService() error = %v", err)
+	}
+	token, err := ts.Generate(user.ID)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	return &http.Cookie{Name: auth.CookieName, Value: token}
+}
+
+func TestRoutes_AdminOnly(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+		cfg.PprofEnabled = true
+	})
+	userCookie := srv.sessionCookie(t, 1, model.RoleUser)
+	adminCookie := srv.sessionCookie(t, 2, model.RoleAdmin)
+
+	for _, path := range []string{"/api/v1/admin/features", "/api/v1/admin/tasks", "/api/v1/admin/db", "/debug/pprof/", "/admin", "/admin/errors"} {
+		t.Run(path, func(t *testing.T) {
+			tests := []struct {
+				name   string
+				cookie *http.Cookie
+				want   int
+			}{
+				{"anonymous", nil, http.StatusUnauthorized},
+				{"user", userCookie, http.StatusForbidden},
+				{"admin", adminCookie, http.StatusOK},
+			}
+			for _, tt := range tests {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				if tt.cookie != nil {
+					req.AddCookie(tt.cookie)
+				}
+				if rr := srv.do(t, req); rr.Code != tt.want {
+					t.Errorf("%s: status = %d, want %d", tt.name, rr.Code, tt.want)
+				}
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/db/checkpoint", nil)
+	req.AddCookie(adminCookie)
+	rr := srv.do(t, req)
+	var checkpoint model.Checkpoint
+	if err := json.Unmarshal(rr.Body.Bytes(), &checkpoint); rr.Code != http.StatusOK || err != nil || checkpoint.WALBytesAfter != 0 {
+		t.Errorf("checkpoint: status = %d, body = %s", rr.Code, rr.Body)
+	}
+}
+
+func TestRoutes_AdminDashboard(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+	})
+	srv.sessionCookie(t, 1, model.RoleUser)
+	adminCookie := srv.sessionCookie(t, 2, model.RoleAdmin)
+
+	// A 500 elsewhere shows up under recent errors.
+	failing := httptest.NewRequest(http.MethodGet, "/api/v1/snippets", nil)
+	srv.reporter.Report(context.Background(), errors.New("disk on fire"), failing)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.AddCookie(adminCookie)
+	rr := srv.do(t, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body: %s", rr.Code, rr.Body)
+	}
+	if cc := rr.Header().Get("Cache-Control"); cc != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", cc)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		`<div class="admin-card-value">2</div>`, // two users
+		"execution disabled",
+		"disk on fire",
+		"GET /api/v1/snippets",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("dashboard is missing %q", want)
+		}
+	}
+}
+
+func TestRoutes_Webhooks(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+	})
+	owner := srv.sessionCookie(t, 1, model.RoleUser)
+	other := srv.sessionCookie(t, 2, model.RoleAdmin)
+
+	if rr := srv.do(t, httptest.NewRequest(http.MethodGet, "/api/v1/webhooks", nil)); rr.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous list: status = %d, want 401", rr.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/webhooks",
+		strings.NewReader(`{"url":"https://example.com/hook","events":["snippet.created"]}`))
+	req.AddCookie(owner)
+	rr := srv.do(t, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, want 201; body: %s", rr.Code, rr.Body)
+	}
+	var created struct{ ID, Secret string }
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	if created.ID == "" || !strings.HasPrefix(created.Secret, "whsec_") {
+		t.Fatalf("create response = %s, want an id and a secret", rr.Body)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/webhooks", nil)
+	req.AddCookie(owner)
+	rr = srv.do(t, req)
+	if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), created.Secret) {
+		t.Errorf("list: status = %d, body = %s; want 200 without the secret", rr.Code, rr.Body)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/webhooks/"+created.ID, nil)
+	req.AddCookie(other)
+	if rr := srv.do(t, req); rr.Code != http.StatusNotFound {
+		t.Errorf("delete by another user: status = %d, want 404", rr.Code)
+	}
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/webhooks/"+created.ID, nil)
+	req.AddCookie(owner)
+	if rr := srv.do(t, req); rr.Code != http.StatusNoContent {
+		t.Errorf("delete by owner: status = %d, want 204", rr.Code)
+	}
+}
+
+func TestRoutes_Exercises(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+	})
+	learner := srv.sessionCookie(t, 1, model.RoleUser)
+	author := srv.sessionCookie(t, 2, model.RoleAuthor)
+
+	send := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		return srv.do(t, req)
+	}
+
+	const exercise = `{"title":"Add","prompt":"Write add(a, b).","starterCode":"def add(a, b):\n    pass","testCode":"def test_add():\n    assert add(1, 2) == 3","hints":["Use the + operator","return a + b"],"hintPenalty":25}`
+	if rr := send(http.MethodPost, "/api/v1/exercises", exercise, learner); rr.Code != http.StatusForbidden {
+		t.Errorf("create as learner: status = %d, want 403", rr.Code)
+	}
+	rr := send(http.MethodPost, "/api/v1/exercises", exercise, author)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create as author: status = %d, want 201; body: %s", rr.Code, rr.Body)
+	}
+	var created struct{ ID, TestCode string }
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	if created.ID == "" || created.TestCode == "" {
+		t.Fatalf("create response = %s, want an id and the tests", rr.Body)
+	}
+
+	// Learners (signed in or not) never see the hidden tests or locked
+	// hints; the author does.
+	for _, cookie := range []*http.Cookie{nil, learner} {
+		rr = send(http.MethodGet, "/api/v1/exercises/"+created.ID, "", cookie)
+		body := rr.Body.String()
+		if rr.Code != http.StatusOK || strings.Contains(body, "test_add") || strings.Contains(body, "+ operator") || !strings.Contains(body, `"hintCount":2`) {
+			t.Errorf("learner get: status = %d, body = %s; want 200 with a hint count but no tests or hints", rr.Code, body)
+		}
+	}
+	rr = send(http.MethodGet, "/api/v1/exercises", "", nil)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"total":1`) || strings.Contains(rr.Body.String(), "test_add") {
+		t.Errorf("list: status = %d, body = %s; want one exercise without tests", rr.Code, rr.Body)
+	}
+	rr = send(http.MethodGet, "/api/v1/exercises/"+created.ID, "", author)
+	if !strings.Contains(rr.Body.String(), "test_add") || !strings.Contains(rr.Body.String(), "+ operator") {
+		t.Errorf("author get: body = %s, want the tests and hints", rr.Body)
+	}
+
+	hints := "/api/v1/exercises/" + created.ID + "/hints"
+	if rr := send(http.MethodGet, hints, "", nil); rr.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous hints: status = %d, want 401", rr.Code)
+	}
+	for i, want := range []string{"Use the + operator", "return a + b"} {
+		rr := send(http.MethodPost, hints, "", learner)
+		var progress struct {
+			Unlocked, ScorePenalty int
+			Hints                  []string
+		}
+		json.Unmarshal(rr.Body.Bytes(), &progress)
+		if rr.Code != http.StatusOK || progress.Unlocked != i+1 || progress.Hints[i] != want || progress.ScorePenalty != 25*(i+1) {
+			t.Fatalf("unlock hint %d: status = %d, body = %s", i+1, rr.Code, rr.Body)
+		}
+	}
+	if rr := send(http.MethodPost, hints, "", learner); rr.Code != http.StatusConflict {
+		t.Errorf("unlock past the last hint: status = %d, want 409", rr.Code)
+	}
+	if rr := send(http.MethodGet, hints, "", author); !strings.Contains(rr.Body.String(), `"unlocked":0`) {
+		t.Errorf("author's own hint progress = %s, want none unlocked", rr.Body)
+	}
+
+	if rr := send(http.MethodDelete, "/api/v1/exercises/"+created.ID, "", learner); rr.Code != http.StatusForbidden {
+		t.Errorf("delete as learner: status = %d, want 403", rr.Code)
+	}
+	if rr := send(http.MethodDelete, "/api/v1/exercises/"+created.ID, "", author); rr.Code != http.StatusNoContent {
+		t.Errorf("delete as author: status = %d, want 204", rr.Code)
+	}
+}
+
+func TestRoutes_Challenges(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+	})
+	learner := srv.sessionCookie(t, 1, model.RoleUser)
+	author := srv.sessionCookie(t, 2, model.RoleAuthor)
+
+	send := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		return srv.do(t, req)
+	}
+
+	if rr := send(http.MethodGet, "/api/v1/challenges/today", "", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("today before scheduling: status = %d, want 404", rr.Code)
+	}
+	rr := send(http.MethodPost, "/api/v1/exercises", `{"title":"Add","prompt":"Write add(a, b).","testCode":"def test_add():\n    assert add(1, 2) == 3"}`, author)
+	var exercise struct{ ID string }
+	json.Unmarshal(rr.Body.Bytes(), &exercise)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create exercise: status = %d, body = %s", rr.Code, rr.Body)
+	}
+
+	today := time.Now().UTC().Format(model.ChallengeDateLayout)
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format(model.ChallengeDateLayout)
+	schedule := `{"exerciseId":"` + exercise.ID + `"}`
+	if rr := send(http.MethodPut, "/api/v1/challenges/"+today, schedule, learner); rr.Code != http.StatusForbidden {
+		t.Errorf("schedule as learner: status = %d, want 403", rr.Code)
+	}
+	if rr := send(http.MethodPut, "/api/v1/challenges/yesterday", schedule, author); rr.Code != http.StatusBadRequest {
+		t.Errorf("schedule a bad date: status = %d, want 400", rr.Code)
+	}
+	for _, date := range []string{today, tomorrow} {
+		if rr := send(http.MethodPut, "/api/v1/challenges/"+date, schedule, author); rr.Code != http.StatusOK {
+			t.Fatalf("schedule %s: status = %d, body = %s", date, rr.Code, rr.Body)
+		}
+	}
+
+	rr = send(http.MethodGet, "/api/v1/challenges/today", "", nil)
+	if body := rr.Body.String(); rr.Code != http.StatusOK || !strings.Contains(body, `"date":"`+today+`"`) || strings.Contains(body, "test_add") || strings.Contains(body, "streak") {
+		t.Errorf("anonymous today: status = %d, body = %s; want the challenge without tests or a streak", rr.Code, body)
+	}
+	rr = send(http.MethodGet, "/api/v1/challenges/today", "", learner)
+	if !strings.Contains(rr.Body.String(), `"streak":{"current":0`) {
+		t.Errorf("signed-in today: body = %s, want the learner's streak", rr.Body)
+	}
+
+	// Tomorrow's challenge is the authors' secret until then.
+	if rr := send(http.MethodGet, "/api/v1/challenges/"+tomorrow, "", learner); rr.Code != http.StatusNotFound {
+		t.Errorf("learner gets tomorrow: status = %d, want 404", rr.Code)
+	}
+	if rr := send(http.MethodGet, "/api/v1/challenges/"+tomorrow, "", author); rr.Code != http.StatusOK {
+		t.Errorf("author gets tomorrow: status = %d, want 200", rr.Code)
+	}
+	rr = send(http.MethodGet, "/api/v1/challenges?to="+tomorrow, "", nil)
+	if rr.Code != http.StatusOK || strings.Count(rr.Body.String(), `"date"`) != 1 {
+		t.Errorf("anonymous list: status = %d, body = %s; want only today", rr.Code, rr.Body)
+	}
+
+	if rr := send(http.MethodGet, "/api/v1/me/streak", "", nil); rr.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous streak: status = %d, want 401", rr.Code)
+	}
+	if rr := send(http.MethodGet, "/api/v1/me/streak", "", learner); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"longest":0`) {
+		t.Errorf("streak: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	if rr := send(http.MethodDelete, "/api/v1/challenges/"+tomorrow, "", author); rr.Code != http.StatusNoContent {
+		t.Errorf("unschedule: status = %d, want 204", rr.Code)
+	}
+}
+
+func TestRoutes_ForksAndBadges(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+	})
+	owner := srv.sessionCookie(t, 1, model.RoleUser)
+	forker := srv.sessionCookie(t, 2, model.RoleAuthor)
+
+	send := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		return srv.do(t, req)
+	}
+
+	rr := send(http.MethodPost, "/api/v1/snippets", `{"name":"loop","code":"print(1)"}`, owner)
+	var snippet struct{ ID string }
+	json.Unmarshal(rr.Body.Bytes(), &snippet)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create snippet: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	if rr := send(http.MethodPost, "/api/v1/snippets/"+snippet.ID+"/fork", "", nil); rr.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous fork: status = %d, want 401", rr.Code)
+	}
+	rr = send(http.MethodPost, "/api/v1/snippets/"+snippet.ID+"/fork", "", forker)
+	var fork struct{ ID, UserID, ForkedFrom, Code string }
+	json.Unmarshal(rr.Body.Bytes(), &fork)
+	if rr.Code != http.StatusCreated || fork.ID == snippet.ID || fork.UserID != "author-id" || fork.ForkedFrom != snippet.ID || fork.Code != "print(1)" {
+		t.Errorf("fork: status = %d, body = %s; want a copy owned by the forker", rr.Code, rr.Body)
+	}
+
+	if rr := send(http.MethodGet, "/api/v1/users/nobody/badges", "", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("badges of an unknown user: status = %d, want 404", rr.Code)
+	}
+	if rr := send(http.MethodGet, "/api/v1/users/user/badges", "", nil); rr.Code != http.StatusOK || !strings.HasPrefix(rr.Body.String(), "[") {
+		t.Errorf("badges: status = %d, body = %s; want a list", rr.Code, rr.Body)
+	}
+}
+
+func TestRoutes_StarsAndNotifications(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+	})
+	ts := httptest.NewServer(srv.router)
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { srv.hub.Shutdown(context.Background()) })
+	owner := srv.sessionCookie(t, 1, model.RoleUser)
+	fan := srv.sessionCookie(t, 2, model.RoleAuthor)
+
+	send := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		return srv.do(t, req)
+	}
+
+	// The owner has the page open, listening on their own topic.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", &websocket.DialOptions{
+		HTTPHeader: http.Header{"Cookie": {owner.String()}},
+	})
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.CloseNow()
+	read := func() ws.Message {
+		t.Helper()
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		var msg ws.Message
+		json.Unmarshal(data, &msg)
+		return msg
+	}
+	read() // ready
+	data, _ := json.Marshal(ws.Message{Type: ws.TypeSubscribe, Topic: "user:user-id"})
+	conn.Write(ctx, websocket.MessageText, data)
+	if msg := read(); msg.Type != ws.TypeSubscribed {
+		t.Fatalf("subscribe: got %+v", msg)
+	}
+
+	rr := send(http.MethodPost, "/api/v1/snippets", `{"name":"loop","code":"print(1)"}`, owner)
+	var snippet struct{ ID string }
+	json.Unmarshal(rr.Body.Bytes(), &snippet)
+	star := "/api/v1/snippets/" + snippet.ID + "/star"
+
+	if rr := send(http.MethodPut, star, "", nil); rr.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous star: status = %d, want 401", rr.Code)
+	}
+	for range 2 {
+		if rr := send(http.MethodPut, star, "", fan); rr.Code != http.StatusOK || rr.Body.String() != `{"starred":true,"stars":1}`+"\n" {
+			t.Errorf("star: status = %d, body = %s", rr.Code, rr.Body)
+		}
+	}
+	send(http.MethodPut, star, "", owner) // starring your own snippet notifies nobody
+	if msg := read(); msg.Type != ws.TypeEvent || !strings.Contains(string(msg.Data), `"kind":"notification"`) || !strings.Contains(string(msg.Data), `"unread":1`) {
+		t.Errorf("pushed = %+v, want the star notification", msg)
+	}
+
+	rr = send(http.MethodGet, "/api/v1/me/notifications", "", owner)
+	var list struct {
+		Items []struct {
+			ID, Kind, Message, ActorLogin string
+		}
+		Unread int
+	}
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	if rr.Code != http.StatusOK || list.Unread != 1 || len(list.Items) != 1 || list.Items[0].Message != "author starred “loop”" || list.Items[0].ActorLogin != "author" {
+		t.Fatalf("notifications: status = %d, body = %s; want the one star", rr.Code, rr.Body)
+	}
+	if rr := send(http.MethodPost, "/api/v1/me/notifications/read", `{}`, owner); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"unread":0`) {
+		t.Errorf("mark read: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	if msg := read(); !strings.Contains(string(msg.Data), `"kind":"read"`) {
+		t.Errorf("pushed = %+v, want the read event", msg)
+	}
+	if rr := send(http.MethodDelete, star, "", fan); !strings.Contains(rr.Body.String(), `"stars":1`) {
+		t.Errorf("unstar: body = %s, want only the owner's star left", rr.Body)
+	}
+}
+
+func TestRoutes_ActivityFeed(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+	})
+	owner := srv.sessionCookie(t, 1, model.RoleUser)
+	fan := srv.sessionCookie(t, 2, model.RoleAuthor)
+	send := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		return srv.do(t, req)
+	}
+
+	rr := send(http.MethodPost, "/api/v1/snippets", `{"name":"loop","code":"print(1)"}`, owner)
+	var snippet struct{ ID string }
+	json.Unmarshal(rr.Body.Bytes(), &snippet)
+	send(http.MethodPut, "/api/v1/snippets/"+snippet.ID+"/visibility", `{"public":true}`, owner)
+	send(http.MethodPut, "/api/v1/snippets/"+snippet.ID+"/star", "", fan)
+
+	type page struct {
+		Items []struct {
+			Kind, ActorLogin, SnippetName string
+		}
+		NextCursor string
+	}
+	get := func(path string) page {
+		t.Helper()
+		rr := send(http.MethodGet, path, "", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, body = %s", path, rr.Code, rr.Body)
+		}
+		var p page
+		json.Unmarshal(rr.Body.Bytes(), &p)
+		return p
+	}
+	first := get("/api/v1/feed?limit=1")
+	if len(first.Items) != 1 || first.Items[0].Kind != model.ActivityStar || first.Items[0].ActorLogin != "author" || first.NextCursor == "" {
+		t.Fatalf("first page = %+v, want the star and a cursor", first)
+	}
+	second := get("/api/v1/feed?limit=1&cursor=" + first.NextCursor)
+	if len(second.Items) != 1 || second.Items[0].Kind != model.ActivityPublished || second.Items[0].SnippetName != "loop" || second.NextCursor != "" {
+		t.Errorf("second page = %+v, want the publish and no cursor", second)
+	}
+	if p := get("/api/v1/users/user/activity"); len(p.Items) != 1 || p.Items[0].Kind != model.ActivityPublished {
+		t.Errorf("owner's activity = %+v, want just the publish", p)
+	}
+	if rr := send(http.MethodGet, "/api/v1/users/nobody/activity", "", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown user: status = %d, want 404", rr.Code)
+	}
+	if rr := send(http.MethodGet, "/api/v1/feed?cursor=nonsense", "", nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("bad cursor: status = %d, want 400", rr.Code)
+	}
+}
+
+func TestRoutes_MyStats(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+	})
+	if rr := srv.do(t, httptest.NewRequest(http.MethodGet, "/api/v1/me/stats", nil)); rr.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous: status = %d, want 401", rr.Code)
+	}
+
+	cookie := srv.sessionCookie(t, 1, model.RoleUser)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/snippets", strings.NewReader(`{"name":"loop","code":"print(1)"}`))
+	req.AddCookie(cookie)
+	srv.do(t, req)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/me/stats", nil)
+	req.AddCookie(cookie)
+	rr := srv.do(t, req)
+	var stats model.UserStats
+	if err := json.Unmarshal(rr.Body.Bytes(), &stats); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rr.Code, rr.Body)
+	}
+	if stats.Snippets != 1 || stats.Runs != 0 || len(stats.Activity) != service.StatsDays || stats.Activity[len(stats.Activity)-1].Snippets != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestRoutes_Analytics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "analytics.jsonl")
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+		cfg.AnalyticsEnabled = true
+		cfg.AnalyticsFile = path
+	})
+	cookie := srv.sessionCookie(t, 1, model.RoleUser)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/snippets", strings.NewReader(`{"name":"loop","code":"print(1)"}`))
+	req.AddCookie(cookie)
+	if rr := srv.do(t, req); rr.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	srv.do(t, httptest.NewRequest(http.MethodPost, "/api/v1/snippets", strings.NewReader(`{"name":"anon","code":""}`)))
+	if err := srv.analytics.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading export: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("exported %q, want two events", data)
+	}
+	for i, want := range []string{`"userId":"user-id"`, `"code_bytes":0`} {
+		if !strings.Contains(lines[i], `"name":"snippet_created"`) || !strings.Contains(lines[i], want) {
+			t.Errorf("event %d = %s, want snippet_created with %s", i, lines[i], want)
+		}
+	}
+	if strings.Contains(string(data), "print(1)") {
+		t.Error("analytics events contain the snippet's code")
+	}
+}
+
+// recordingMailer keeps what it was asked to send.
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (m *recordingMailer) Send(_ context.Context, msg *mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, *msg)
+	return nil
+}
+
+func (m *recordingMailer) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sent)
+}
+
+func TestRoutes_Email(t *testing.T) {
+	m := &recordingMailer{}
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+		cfg.Mailer = m
+		cfg.PublicURL = "https://play.example/"
+	})
+	if err := srv.jobs.Start(context.Background()); err != nil {
+		t.Fatalf("starting job queue: %v", err)
+	}
+	t.Cleanup(func() { srv.jobs.Shutdown(context.Background()) })
+	ann := &model.User{ID: "ann-id", GitHubID: 42, Login: "ann", Email: "ann@example.com"}
+	if err := srv.db.Upsert(context.Background(), ann); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+
+	post := func(path, contentType, body string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		return srv.do(t, req)
+	}
+
+	// Known and unknown addresses get the same answer.
+	for _, email := range []string{"nobody@example.com", "ann@example.com"} {
+		if rr := post("/auth/magic-link", "application/json", `{"email":"`+email+`"}`); rr.Code != http.StatusAccepted {
+			t.Errorf("request link for %s: status = %d, body = %s", email, rr.Code, rr.Body)
+		}
+	}
+	if rr := post("/auth/magic-link", "application/json", `{"email":"nope"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("request link for a bad address: status = %d, want 400", rr.Code)
+	}
+	for deadline := time.Now().Add(5 * time.Second); m.count() == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if m.count() != 1 {
+		t.Fatalf("sent %d emails, want one sign-in link", m.count())
+	}
+	text := m.sent[0].Text
+	link, err := url.Parse(regexp.MustCompile(`https://play\.example/auth/magic\?\S+`).FindString(text))
+	if err != nil || link.Host == "" {
+		t.Fatalf("no sign-in link in %q", text)
+	}
+
+	// Following the link only shows the button.
+	rr := srv.do(t, httptest.NewRequest(http.MethodGet, link.RequestURI(), nil))
+	token := link.Query().Get("token")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `value="`+token+`"`) {
+		t.Fatalf("sign-in page: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	var state *http.Cookie
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == "magic_state" {
+			state = c
+		}
+	}
+	if state == nil {
+		t.Fatal("sign-in page set no state cookie")
+	}
+	form := url.Values{"token": {token}, "state": {state.Value}}.Encode()
+	if rr := post("/auth/magic", "application/x-www-form-urlencoded", form); rr.Code != http.StatusBadRequest {
+		t.Errorf("sign in without the state cookie: status = %d, want 400", rr.Code)
+	}
+	rr = post("/auth/magic", "application/x-www-form-urlencoded", form, state)
+	var session *http.Cookie
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == auth.CookieName {
+			session = c
+		}
+	}
+	if rr.Code != http.StatusSeeOther || session == nil {
+		t.Fatalf("sign in: status = %d, cookies = %v", rr.Code, rr.Result().Cookies())
+	}
+	if rr := post("/auth/magic", "application/x-www-form-urlencoded", form, state); rr.Code != http.StatusBadRequest {
+		t.Errorf("sign in with a used link: status = %d, want 400", rr.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me/email-preferences", nil)
+	req.AddCookie(session)
+	if rr := srv.do(t, req); rr.Code != http.StatusOK || rr.Body.String() != `{"grading":false,"digest":true}`+"\n" {
+		t.Errorf("preferences: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/me/email-preferences", strings.NewReader(`{"grading":true}`))
+	req.AddCookie(session)
+	if rr := srv.do(t, req); rr.Code != http.StatusOK || rr.Body.String() != `{"grading":true,"digest":true}`+"\n" {
+		t.Errorf("set preferences: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	if rr := srv.do(t, httptest.NewRequest(http.MethodGet, "/email/unsubscribe?user=ann-id&kind=digest&sig=00", nil)); rr.Code != http.StatusBadRequest {
+		t.Errorf("unsubscribe with a forged link: status = %d, want 400", rr.Code)
+	}
+}
+
+func TestRoutes_Classes(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+	})
+	teacher := srv.sessionCookie(t, 1, model.RoleAuthor)
+	student := srv.sessionCookie(t, 2, model.RoleUser)
+	other := srv.sessionCookie(t, 3, model.RoleAdmin) // site admins are just students in a class
+
+	send := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(cookie)
+		return srv.do(t, req)
+	}
+	decode := func(rr *httptest.ResponseRecorder, v any) {
+		t.Helper()
+		if err := json.Unmarshal(rr.Body.Bytes(), v); err != nil {
+			t.Fatalf("decoding %s: %v", rr.Body, err)
+		}
+	}
+
+	rr := send(http.MethodPost, "/api/v1/classes", `{"name":"Intro to Python"}`, teacher)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create class: status = %d; body: %s", rr.Code, rr.Body)
+	}
+	var class struct{ ID, JoinCode, Role string }
+	decode(rr, &class)
+	if class.JoinCode == "" || class.Role != model.ClassTeacher {
+		t.Fatalf("created class = %+v, want a join code and the teacher role", class)
+	}
+
+	// Outsiders can't see the class at all.
+	if rr := send(http.MethodGet, "/api/v1/classes/"+class.ID, "", student); rr.Code != http.StatusNotFound {
+		t.Errorf("outsider get: status = %d, want 404", rr.Code)
+	}
+	for _, cookie := range []*http.Cookie{student, other} {
+		rr := send(http.MethodPost, "/api/v1/classes/join", `{"code":"`+strings.ToLower(class.JoinCode)+`"}`, cookie)
+		if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), class.JoinCode) {
+			t.Fatalf("join: status = %d, body = %s; want 200 without the join code", rr.Code, rr.Body)
+		}
+	}
+	if rr := send(http.MethodGet, "/api/v1/classes/"+class.ID+"/members", "", student); rr.Code != http.StatusForbidden {
+		t.Errorf("student lists members: status = %d, want 403", rr.Code)
+	}
+	rr = send(http.MethodGet, "/api/v1/classes/"+class.ID+"/members", "", teacher)
+	var members []struct{ Login, Role string }
+	decode(rr, &members)
+	if len(members) != 3 || members[0].Role != model.ClassTeacher {
+		t.Errorf("members = %+v, want the teacher first and two students", members)
+	}
+
+	rr = send(http.MethodPost, "/api/v1/exercises",
+		`{"title":"Add","prompt":"Write add(a, b).","testCode":"def test_add():\n    assert add(1, 2) == 3"}`, teacher)
+	var exercise struct{ ID string }
+	decode(rr, &exercise)
+	if rr := send(http.MethodPost, "/api/v1/classes/"+class.ID+"/assignments", `{"exerciseId":"`+exercise.ID+`"}`, student); rr.Code != http.StatusForbidden {
+		t.Errorf("student creates assignment: status = %d, want 403", rr.Code)
+	}
+	rr = send(http.MethodPost, "/api/v1/classes/"+class.ID+"/assignments", `{"exerciseId":"`+exercise.ID+`"}`, teacher)
+	var assignment struct{ ID, Title string }
+	decode(rr, &assignment)
+	if rr.Code != http.StatusCreated || assignment.Title != "Add" {
+		t.Fatalf("create assignment: status = %d, body = %s", rr.Code, rr.Body)
+	}
+
+	for userID, status := range map[string]string{model.RoleUser + "-id": model.SubmissionPassed, model.RoleAdmin + "-id": model.SubmissionFailed} {
+		score := map[string]int{model.SubmissionPassed: 100}[status]
+		err := srv.db.CreateSubmission(context.Background(), &model.Submission{
+			ExerciseID: exercise.ID, AssignmentID: assignment.ID, UserID: userID, Code: "def add(a, b): return a + b", Status: status, Score: score,
+		})
+		if err != nil {
+			t.Fatalf("CreateSubmission() error = %v", err)
+		}
+	}
+	submissions := "/api/v1/classes/" + class.ID + "/assignments/" + assignment.ID + "/submissions"
+	for _, tt := range []struct {
+		cookie *http.Cookie
+		want   int
+	}{{teacher, 2}, {student, 1}} {
+		var got []struct{ UserID string }
+		decode(send(http.MethodGet, submissions, "", tt.cookie), &got)
+		if len(got) != tt.want {
+			t.Errorf("submissions = %+v, want %d", got, tt.want)
+		}
+	}
+
+	path := "/api/v1/classes/" + class.ID + "/assignments/" + assignment.ID
+	if rr := send(http.MethodPut, path, `{"latePolicy":"accept","latePenalty":10}`, teacher); rr.Code != http.StatusBadRequest {
+		t.Errorf("penalty without the penalize policy: status = %d, want 400", rr.Code)
+	}
+	rr = send(http.MethodPut, path, `{"dueAt":"2000-01-01T00:00:00Z","latePolicy":"reject"}`, teacher)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"latePolicy":"reject"`) {
+		t.Fatalf("update assignment: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	if rr := send(http.MethodGet, path+"/progress", "", student); rr.Code != http.StatusForbidden {
+		t.Errorf("student reads progress: status = %d, want 403", rr.Code)
+	}
+	var progress []struct{ Login, Status string }
+	decode(send(http.MethodGet, path+"/progress", "", teacher), &progress)
+	got := make(map[string]string)
+	for _, p := range progress {
+		got[p.Login] = p.Status
+	}
+	want := map[string]string{model.RoleUser: model.CompletionCompleted, model.RoleAdmin: model.CompletionAttempted}
+	if !maps.Equal(got, want) {
+		t.Errorf("progress = %v, want %v", got, want)
+	}
+
+	// Both students handed in the same code.
+	if rr := send(http.MethodPost, path+"/similarity", "", student); rr.Code != http.StatusForbidden {
+		t.Errorf("student requests a similarity report: status = %d, want 403", rr.Code)
+	}
+	if rr := send(http.MethodGet, path+"/similarity", "", teacher); rr.Code != http.StatusNotFound {
+		t.Errorf("similarity report before one was requested: status = %d, want 404", rr.Code)
+	}
+	rr = send(http.MethodPost, path+"/similarity", `{"threshold":0.9}`, teacher)
+	if rr.Code != http.StatusAccepted || !strings.Contains(rr.Body.String(), `"status":"pending"`) {
+		t.Fatalf("request similarity report: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	if err := srv.jobs.Start(context.Background()); err != nil {
+		t.Fatalf("starting job queue: %v", err)
+	}
+	t.Cleanup(func() { srv.jobs.Shutdown(context.Background()) })
+	var report struct {
+		Status   string
+		Compared int
+		Pairs    []struct {
+			Similarity float64
+			A, B       struct{ Login string }
+		}
+	}
+	for deadline := time.Now().Add(5 * time.Second); report.Status != model.SimilarityDone && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		decode(send(http.MethodGet, path+"/similarity", "", teacher), &report)
+	}
+	if report.Status != model.SimilarityDone || report.Compared != 2 || len(report.Pairs) != 1 || report.Pairs[0].Similarity != 1 {
+		t.Errorf("similarity report = %+v, want the two identical submissions flagged", report)
+	}
+
+	if _, err := srv.db.RefreshLeaderboard(context.Background()); err != nil {
+		t.Fatalf("RefreshLeaderboard() error = %v", err)
+	}
+	var board struct{ Entries []struct{ Login string } }
+	decode(send(http.MethodGet, "/api/v1/classes/"+class.ID+"/leaderboard", "", student), &board)
+	if len(board.Entries) != 1 || board.Entries[0].Login != model.RoleUser {
+		t.Errorf("class leaderboard = %+v, want only the student who scored", board.Entries)
+	}
+}
+
+func TestRoutes_Leaderboards(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+	})
+	author := srv.sessionCookie(t, 1, model.RoleAuthor)
+	learner := srv.sessionCookie(t, 2, model.RoleUser)
+
+	send := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		return srv.do(t, req)
+	}
+	board := func(path string) (logins []string) {
+		t.Helper()
+		rr := send(http.MethodGet, path, "", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d; body: %s", path, rr.Code, rr.Body)
+		}
+		var got struct {
+			Entries []struct {
+				Rank  int
+				Login string
+			}
+		}
+		json.Unmarshal(rr.Body.Bytes(), &got)
+		for _, e := range got.Entries {
+			logins = append(logins, e.Login)
+		}
+		return logins
+	}
+
+	rr := send(http.MethodPost, "/api/v1/exercises",
+		`{"title":"Add","prompt":"Write add(a, b).","testCode":"def test_add():\n    assert add(1, 2) == 3"}`, author)
+	var exercise struct{ ID string }
+	json.Unmarshal(rr.Body.Bytes(), &exercise)
+	path := "/api/v1/exercises/" + exercise.ID + "/leaderboard"
+
+	for userID, ms := range map[string]float64{model.RoleAuthor + "-id": 40, model.RoleUser + "-id": 10} {
+		err := srv.db.CreateSubmission(context.Background(), &model.Submission{
+			ExerciseID: exercise.ID, UserID: userID, Status: model.SubmissionPassed, Score: 100, DurationMS: ms,
+		})
+		if err != nil {
+			t.Fatalf("CreateSubmission() error = %v", err)
+		}
+	}
+	if got := board(path); len(got) != 0 {
+		t.Errorf("board before the first refresh = %v, want empty", got)
+	}
+	if _, err := srv.db.RefreshLeaderboard(context.Background()); err != nil {
+		t.Fatalf("RefreshLeaderboard() error = %v", err)
+	}
+	if got := board(path); !slices.Equal(got, []string{model.RoleUser, model.RoleAuthor}) {
+		t.Errorf("board = %v, want the faster solver first", got)
+	}
+
+	if rr := send(http.MethodPut, "/api/v1/me/leaderboard", `{"optOut":true}`, nil); rr.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous opt-out: status = %d, want 401", rr.Code)
+	}
+	rr = send(http.MethodPut, "/api/v1/me/leaderboard", `{"optOut":true}`, learner)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"leaderboardOptOut":true`) {
+		t.Fatalf("opt out: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	if got := board(path); !slices.Equal(got, []string{model.RoleAuthor}) {
+		t.Errorf("board after opting out = %v, want only the author", got)
+	}
+
+	if rr := send(http.MethodGet, "/api/v1/exercises/nope/leaderboard", "", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown exercise: status = %d, want 404", rr.Code)
+	}
+}
+
+func TestRoutes_SnippetComments(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+	})
+	owner := srv.sessionCookie(t, 1, model.RoleUser)
+	teacher := srv.sessionCookie(t, 2, model.RoleAuthor)
+
+	send := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		return srv.do(t, req)
+	}
+
+	rr := send(http.MethodPost, "/api/v1/snippets", `{"name":"loop","code":"for i in range(10):\n    print(i)"}`, owner)
+	var snippet struct {
+		ID      string
+		Version int
+	}
+	json.Unmarshal(rr.Body.Bytes(), &snippet)
+	if rr.Code != http.StatusCreated || snippet.Version != 1 {
+		t.Fatalf("create snippet: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	path := "/api/v1/snippets/" + snippet.ID + "/comments"
+
+	if rr := send(http.MethodPost, path, `{"body":"hi"}`, nil); rr.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous comment: status = %d, want 401", rr.Code)
+	}
+	if rr := send(http.MethodPost, path, `{"body":"x","lineStart":3}`, teacher); rr.Code != http.StatusBadRequest {
+		t.Errorf("line past the end: status = %d, want 400", rr.Code)
+	}
+	rr = send(http.MethodPost, path, `{"body":"off-by-one","version":1,"lineStart":1}`, teacher)
+	var comment struct{ ID string }
+	json.Unmarshal(rr.Body.Bytes(), &comment)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("line comment: status = %d, body = %s", rr.Code, rr.Body)
+	}
+
+	// Editing the code moves the snippet on to version 2.
+	if rr := send(http.MethodPut, "/api/v1/snippets/"+snippet.ID, `{"name":"loop","code":"for i in range(1, 11):\n    print(i)"}`, owner); rr.Code != http.StatusOK {
+		t.Fatalf("update snippet: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	if rr := send(http.MethodPost, path, `{"body":"x","version":1,"lineStart":1}`, teacher); rr.Code != http.StatusConflict {
+		t.Errorf("comment on a stale version: status = %d, want 409", rr.Code)
+	}
+
+	rr = send(http.MethodGet, path, "", nil)
+	var comments []struct {
+		Login     string
+		Version   int
+		LineStart int
+		Outdated  bool
+	}
+	json.Unmarshal(rr.Body.Bytes(), &comments)
+	if rr.Code != http.StatusOK || len(comments) != 1 {
+		t.Fatalf("list comments: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	if c := comments[0]; c.Login != model.RoleAuthor || c.Version != 1 || c.LineStart != 1 || !c.Outdated {
+		t.Errorf("comment = %+v, want the teacher's line 1 comment on version 1, outdated", c)
+	}
+
+	if rr := send(http.MethodDelete, path+"/"+comment.ID, "", owner); rr.Code != http.StatusNoContent {
+		t.Errorf("owner deletes a comment on their snippet: status = %d, want 204", rr.Code)
+	}
+	if rr := send(http.MethodGet, "/api/v1/snippets/nope/comments", "", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown snippet: status = %d, want 404", rr.Code)
+	}
+}
+
+func TestRoutes_AtomFeeds(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+	})
+	owner := srv.sessionCookie(t, 1, model.RoleUser) // login "user"
+
+	send := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		return srv.do(t, req)
+	}
+
+	rr := send(http.MethodPost, "/api/v1/snippets", `{"name":"Feed me","code":"print(1)"}`, owner)
+	var created struct{ ID string }
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	send(http.MethodPost, "/api/v1/snippets", `{"name":"Private","code":"print(2)"}`, owner)
+
+	if rr := send(http.MethodPut, "/api/v1/snippets/"+created.ID+"/visibility", `{"public":true}`, nil); rr.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous publish: status = %d, want 401", rr.Code)
+	}
+	if rr := send(http.MethodPut, "/api/v1/snippets/"+created.ID+"/visibility", `{"public":true}`, owner); rr.Code != http.StatusOK {
+		t.Fatalf("publish: status = %d, want 200; body: %s", rr.Code, rr.Body)
+	}
+
+	for _, path := range []string{"/feed.atom", "/users/user/feed.atom"} {
+		rr := send(http.MethodGet, path, "", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want 200", path, rr.Code)
+		}
+		if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/atom+xml") {
+			t.Errorf("%s: Content-Type = %q", path, ct)
+		}
+		body := rr.Body.String()
+		if !strings.Contains(body, "<title>Feed me</title>") || strings.Contains(body, "Private") {
+			t.Errorf("%s: feed should list only the published snippet:\n%s", path, body)
+		}
+		if !strings.Contains(body, "/?snippet="+created.ID) || !strings.Contains(body, "<name>user</name>") {
+			t.Errorf("%s: entry is missing its link or author:\n%s", path, body)
+		}
+
+		cached := httptest.NewRequest(http.MethodGet, path, nil)
+		cached.Header.Set("If-None-Match", rr.Header().Get("ETag"))
+		if rr := srv.do(t, cached); rr.Code != http.StatusNotModified {
+			t.Errorf("%s with If-None-Match: status = %d, want 304", path, rr.Code)
+		}
+	}
+
+	if rr := send(http.MethodGet, "/users/nobody/feed.atom", "", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown user feed: status = %d, want 404", rr.Code)
+	}
+}
+
+func TestRoutes_PublicSnippetPages(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+	})
+	owner := srv.sessionCookie(t, 1, model.RoleUser) // login "user"
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(owner)
+		return srv.do(t, req)
+	}
+	var public, private struct{ ID string }
+	json.Unmarshal(send(http.MethodPost, "/api/v1/snippets", `{"name":"Fizz <buzz>","code":"print(1)","description":"Counts & shouts"}`).Body.Bytes(), &public)
+	json.Unmarshal(send(http.MethodPost, "/api/v1/snippets", `{"name":"Secret","code":"print(2)"}`).Body.Bytes(), &private)
+	send(http.MethodPut, "/api/v1/snippets/"+public.ID+"/visibility", `{"public":true}`)
+
+	rr := srv.do(t, httptest.NewRequest(http.MethodGet, "/s/"+public.ID, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("public page: status = %d, want 200; body: %s", rr.Code, rr.Body)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		`<meta property="og:title" content="Fizz &lt;buzz&gt;">`,
+		`<meta property="og:description" content="Counts &amp; shouts">`,
+		`<meta property="og:url" content="http://example.com/s/` + public.ID + `">`,
+		`<meta name="twitter:card" content="summary">`,
+		`<meta property="article:author" content="user">`,
+		`href="/?snippet=` + public.ID + `"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("public page is missing %s", want)
+		}
+	}
+
+	for _, path := range []string{"/s/" + private.ID, "/s/does-not-exist"} {
+		if rr := srv.do(t, httptest.NewRequest(http.MethodGet, path, nil)); rr.Code != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want 404", path, rr.Code)
+		}
+	}
+
+	rr = srv.do(t, httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("sitemap: status = %d, want 200", rr.Code)
+	}
+	body = rr.Body.String()
+	if !strings.Contains(body, "<loc>http://example.com/s/"+public.ID+"</loc>") || strings.Contains(body, private.ID) {
+		t.Errorf("sitemap should list only the published snippet:\n%s", body)
+	}
+
+	rr = srv.do(t, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+	if !strings.Contains(rr.Body.String(), "Sitemap: http://example.com/sitemap.xml") {
+		t.Errorf("robots.txt doesn't point at the sitemap:\n%s", rr.Body)
+	}
+}
+
+func TestRoutes_ResponseCache(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+		cfg.SnippetCacheTTL = time.Minute
+		cfg.ResponseCacheTTL = time.Minute
+	})
+	owner := srv.sessionCookie(t, 1, model.RoleUser)
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(owner)
+		return srv.do(t, req)
+	}
+	var snippet struct{ ID string }
+	json.Unmarshal(send(http.MethodPost, "/api/v1/snippets", `{"name":"Before","code":"print(1)"}`).Body.Bytes(), &snippet)
+	send(http.MethodPut, "/api/v1/snippets/"+snippet.ID+"/visibility", `{"public":true}`)
+
+	page := func() *httptest.ResponseRecorder {
+		return srv.do(t, httptest.NewRequest(http.MethodGet, "/s/"+snippet.ID, nil))
+	}
+	if rr := page(); rr.Code != http.StatusOK || rr.Header().Get("X-Cache") != "" {
+		t.Fatalf("first view: status %d, X-Cache %q; want a rendered 200", rr.Code, rr.Header().Get("X-Cache"))
+	}
+	if rr := page(); rr.Header().Get("X-Cache") != "HIT" || !strings.Contains(rr.Body.String(), "Before") {
+		t.Errorf("second view wasn't served from the cache: X-Cache %q", rr.Header().Get("X-Cache"))
+	}
+
+	// An edit purges it.
+	send(http.MethodPut, "/api/v1/snippets/"+snippet.ID, `{"name":"After"}`)
+	if rr := page(); rr.Header().Get("X-Cache") == "HIT" || !strings.Contains(rr.Body.String(), "After") {
+		t.Errorf("view after an edit: X-Cache %q, want the new name rendered", rr.Header().Get("X-Cache"))
+	}
+}
+
+func TestRoutes_LegacyAPIDeprecation(t *testing.T) {
+	since := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.LegacyAPI = handler.Deprecation{
+			Since:   since,
+			Sunset:  time.Date(2027, 5, 1, 0, 0, 0, 0, time.UTC),
+			Message: "Use /api/v1.",
+		}
+	})
+	create := func(path string) *httptest.ResponseRecorder {
+		return srv.do(t, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"name":"a","code":"print(1)"}`)))
+	}
+
+	rr := create("/api/snippets")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rr.Code, rr.Body)
+	}
+	if got := rr.Header().Get("Deprecation"); got != "@1792108800" {
+		t.Errorf("Deprecation = %q", got)
+	}
+	if got := rr.Header().Get("Sunset"); got != "Sat, 01 May 2027 00:00:00 GMT" {
+		t.Errorf("Sunset = %q", got)
+	}
+	var body struct {
+		ID       string
+		Warnings []handler.Warning
+	}
+	json.Unmarshal(rr.Body.Bytes(), &body)
+	if body.ID == "" || len(body.Warnings) != 1 || body.Warnings[0].Code != handler.WarningDeprecatedRoute {
+		t.Errorf("body = %s, want the snippet with a deprecation warning", rr.Body)
+	}
+
+	rr = create("/api/v1/snippets")
+	if rr.Header().Get("Deprecation") != "" || strings.Contains(rr.Body.String(), "warnings") {
+		t.Errorf("/api/v1 is marked deprecated: %v %s", rr.Header(), rr.Body)
+	}
+}
+
+func TestRoutes_CDNHeaders(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+		cfg.CDNMaxAge = 5 * time.Minute
+		cfg.CDNPurgeURL = "http://cdn.invalid/purge"
+	})
+	owner := srv.sessionCookie(t, 1, model.RoleUser)
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(owner)
+		return srv.do(t, req)
+	}
+	var public, private struct{ ID string }
+	json.Unmarshal(send(http.MethodPost, "/api/v1/snippets", `{"name":"Viral","code":"print(1)"}`).Body.Bytes(), &public)
+	json.Unmarshal(send(http.MethodPost, "/api/v1/snippets", `{"name":"Secret","code":"print(2)"}`).Body.Bytes(), &private)
+	send(http.MethodPut, "/api/v1/snippets/"+public.ID+"/visibility", `{"public":true}`)
+
+	for _, path := range []string{"/api/v1/snippets/" + public.ID, "/api/v1/snippets/" + public.ID + "/html", "/s/" + public.ID} {
+		rr := srv.do(t, httptest.NewRequest(http.MethodGet, path, nil))
+		if got := rr.Header().Get("Cache-Control"); got != "public, max-age=0, s-maxage=300" {
+			t.Errorf("GET %s: Cache-Control = %q", path, got)
+		}
+		if got := rr.Header().Get("Surrogate-Key"); got != "snippet-"+public.ID {
+			t.Errorf("GET %s: Surrogate-Key = %q", path, got)
+		}
+	}
+	rr := srv.do(t, httptest.NewRequest(http.MethodGet, "/api/v1/snippets/"+private.ID, nil))
+	if rr.Header().Get("Cache-Control") != "no-cache" || rr.Header().Get("Surrogate-Key") != "" {
+		t.Errorf("a private snippet is cacheable: %v", rr.Header())
+	}
+
+	// Publishing queued a purge; so does an edit.
+	before, _ := srv.jobs.Backlog(context.Background())
+	send(http.MethodPut, "/api/v1/snippets/"+public.ID, `{"name":"Still viral"}`)
+	if after, _ := srv.jobs.Backlog(context.Background()); after != before+1 {
+		t.Errorf("jobs went from %d to %d after an edit, want a purge queued", before, after)
+	}
+}
+
+func TestRoutes_GraphQL(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+	})
+	owner := srv.sessionCookie(t, 1, model.RoleUser)  // login "user"
+	admin := srv.sessionCookie(t, 2, model.RoleAdmin) // login "admin"
+	for _, name := range []string{"one", "two", "three"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/snippets", strings.NewReader(`{"name":"`+name+`","code":"print(1)"}`))
+		req.AddCookie(owner)
+		srv.do(t, req)
+	}
+
+	query := func(q string, vars map[string]any, cookie *http.Cookie) map[string]any {
+		t.Helper()
+		body, _ := json.Marshal(map[string]any{"query": q, "variables": vars})
+		req := httptest.NewRequest(http.MethodPost, "/api/graphql", bytes.NewReader(body))
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rr := srv.do(t, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200; body: %s", rr.Code, rr.Body)
+		}
+		var resp map[string]any
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("nested data and cursor pagination", func(t *testing.T) {
+		const q = `query($after: String) {
+			user(login: "user") {
+				snippets(first: 2, after: $after) {
+					totalCount
+					edges { cursor node { name author { login } } }
+					pageInfo { hasNextPage endCursor }
+				}
+			}
+		}`
+		var names []string
+		var after any
+		for page := 0; page < 3; page++ {
+			resp := query(q, map[string]any{"after": after}, nil)
+			conn := resp["data"].(map[string]any)["user"].(map[string]any)["snippets"].(map[string]any)
+			if conn["totalCount"] != float64(3) {
+				t.Fatalf("totalCount = %v, want 3", conn["totalCount"])
+			}
+			for _, e := range conn["edges"].([]any) {
+				node := e.(map[string]any)["node"].(map[string]any)
+				names = append(names, node["name"].(string))
+				if login := node["author"].(map[string]any)["login"]; login != "user" {
+					t.Errorf("author = %v, want user", login)
+				}
+			}
+			info := conn["pageInfo"].(map[string]any)
+			if info["hasNextPage"] != true {
+				break
+			}
+			after = info["endCursor"]
+		}
+		if strings.Join(names, ",") != "three,two,one" {
+			t.Errorf("paged through %v, want every snippet once, newest first", names)
+		}
+	})
+
+	t.Run("private fields", func(t *testing.T) {
+		const q = `{ user(login: "user") { login email } }`
+		for _, tt := range []struct {
+			name    string
+			cookie  *http.Cookie
+			allowed bool
+		}{
+			{"anonymous", nil, false},
+			{"self", owner, true},
+			{"admin", admin, true},
+		} {
+			resp := query(q, nil, tt.cookie)
+			_, hasErrors := resp["errors"]
+			if hasErrors == tt.allowed {
+				t.Errorf("%s: errors = %v, want allowed=%v", tt.name, resp["errors"], tt.allowed)
+			}
+			if login := resp["data"].(map[string]any)["user"].(map[string]any)["login"]; login != "user" {
+				t.Errorf("%s: public fields should still resolve, got login %v", tt.name, login)
+			}
+		}
+
+		resp := query(q, nil, srv.sessionCookie(t, 3, "other"))
+		errs, _ := resp["errors"].([]any)
+		if len(errs) != 1 || errs[0].(map[string]any)["extensions"].(map[string]any)["code"] != "FORBIDDEN" {
+			t.Errorf("another user: errors = %v, want one FORBIDDEN", resp["errors"])
+		}
+	})
+
+	t.Run("runs and stars", func(t *testing.T) {
+		ownerID := "user-id" // see sessionCookie
+		for range 3 {
+			if err := srv.db.CreateRun(context.Background(), &model.Run{UserID: ownerID, Code: "print(1)", Language: "python"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		resp := query(`{ snippets(first: 1) { nodes { id } } }`, nil, nil)
+		id := resp["data"].(map[string]any)["snippets"].(map[string]any)["nodes"].([]any)[0].(map[string]any)["id"].(string)
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/snippets/"+id+"/star", nil)
+		req.AddCookie(admin)
+		if rr := srv.do(t, req); rr.Code != http.StatusOK {
+			t.Fatalf("starring: status = %d; body: %s", rr.Code, rr.Body)
+		}
+
+		const runs = `{ user(login: "user") { runs(first: 2) { totalCount nodes { language exitCode } pageInfo { hasNextPage } } } }`
+		for _, tt := range []struct {
+			name    string
+			cookie  *http.Cookie
+			allowed bool
+		}{
+			{"anonymous", nil, false},
+			{"self", owner, true},
+			{"admin", admin, true},
+			{"another user", srv.sessionCookie(t, 3, "other"), false},
+		} {
+			resp := query(runs, nil, tt.cookie)
+			got := resp["data"].(map[string]any)["user"].(map[string]any)["runs"]
+			if !tt.allowed {
+				errs, _ := resp["errors"].([]any)
+				if got != nil || len(errs) != 1 || errs[0].(map[string]any)["extensions"].(map[string]any)["code"] != "FORBIDDEN" {
+					t.Errorf("%s: runs = %v, errors = %v; want null and one FORBIDDEN", tt.name, got, resp["errors"])
+				}
+				continue
+			}
+			conn, _ := got.(map[string]any)
+			if conn["totalCount"] != float64(3) || len(conn["nodes"].([]any)) != 2 || conn["pageInfo"].(map[string]any)["hasNextPage"] != true {
+				t.Errorf("%s: runs = %v, errors = %v; want 2 of 3", tt.name, got, resp["errors"])
+			}
+		}
+
+		// Stars are public, from either end.
+		resp = query(`{
+			snippet(id: "`+id+`") { stars { totalCount nodes { user { login } } } }
+			user(login: "admin") { stars { nodes { snippet { id } } } }
+		}`, nil, nil)
+		if _, ok := resp["errors"]; ok {
+			t.Fatalf("errors = %v", resp["errors"])
+		}
+		data := resp["data"].(map[string]any)
+		stars := data["snippet"].(map[string]any)["stars"].(map[string]any)
+		if stars["totalCount"] != float64(1) || stars["nodes"].([]any)[0].(map[string]any)["user"].(map[string]any)["login"] != "admin" {
+			t.Errorf("snippet stars = %v, want one by admin", stars)
+		}
+		starred := data["user"].(map[string]any)["stars"].(map[string]any)["nodes"].([]any)
+		if len(starred) != 1 || starred[0].(map[string]any)["snippet"].(map[string]any)["id"] != id {
+			t.Errorf("admin's stars = %v, want the snippet %s", starred, id)
+		}
+	})
+
+	t.Run("viewer", func(t *testing.T) {
+		resp := query(`{ viewer { login } }`, nil, owner)
+		if got := resp["data"].(map[string]any)["viewer"].(map[string]any)["login"]; got != "user" {
+			t.Errorf("viewer login = %v, want user", got)
+		}
+		resp = query(`{ viewer { login } }`, nil, nil)
+		if got := resp["data"].(map[string]any)["viewer"]; got != nil {
+			t.Errorf("anonymous viewer = %v, want null", got)
+		}
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		rr := srv.do(t, httptest.NewRequest(http.MethodGet, "/api/v1/graphql?query="+url.QueryEscape("{ snippets { password } }"), nil))
+		if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), `cannot query field \"password\"`) {
+			t.Errorf("status = %d, body = %s; want 400 naming the field", rr.Code, rr.Body)
+		}
+	})
+}
+
+func TestRoutes_BearerToken(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+	})
+
+	if rr := srv.do(t, httptest.NewRequest(http.MethodPost, "/api/v1/me/token", nil)); rr.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous: status = %d, want 401", rr.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/me/token", nil)
+	req.AddCookie(srv.sessionCookie(t, 1, model.RoleUser))
+	rr := srv.do(t, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201; body: %s", rr.Code, rr.Body)
+	}
+	var issued struct{ Token string }
+	json.Unmarshal(rr.Body.Bytes(), &issued)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/me", nil)
+	req.Header.Set("Authorization", "Bearer "+issued.Token)
+	rr = srv.do(t, req)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"login":"user"`) {
+		t.Errorf("GET /me with bearer token: status = %d, body = %s", rr.Code, rr.Body)
+	}
+}
+
+// echoExecutor runs nothing, answering every request with its code.
+type echoExecutor struct{}
+
+func (echoExecutor) Execute(_ context.Context, req executor.ExecutionRequest) (*executor.ExecutionResult, error) {
+	return &executor.ExecutionResult{Stdout: req.Code}, nil
+}
+
+func TestRoutes_RateLimitSharedAcrossPrefixes(t *testing.T) {
+	cfg := Config{
+		TemplateDir:      filepath.Join("..", "..", "web", "templates"),
+		StaticDir:        filepath.Join("..", "..", "web", "static"),
+		DBPath:           ":memory:",
+		ExecuteRateLimit: middleware.RateLimitConfig{Rate: 0.001, Burst: 2},
+	}
+	srv, err := New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), echoExecutor{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { srv.db.Close() })
+
+	run := func(path string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"code":"print(1)"}`))
+		req.Header.Set("Content-Type", "application/json")
+		return srv.do(t, req).Code
+	}
+	for _, path := range []string{"/api/execute", "/api/v1/execute"} {
+		if code := run(path); code != http.StatusOK {
+			t.Fatalf("POST %s: status = %d, want 200", path, code)
+		}
+	}
+	for _, path := range []string{"/api/execute", "/api/v1/execute"} {
+		if code := run(path); code != http.StatusTooManyRequests {
+			t.Errorf("POST %s after the budget was spent on both prefixes: status = %d, want 429", path, code)
+		}
+	}
+}
+
+func TestRoutes_MeEscapesFields(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+	})
+	cookie := srv.sessionCookie(t, 1, model.RoleUser)
+	odd := &model.User{ID: "user-id", GitHubID: 1, Login: `say "hi"`, Email: `back\slash@example.com`}
+	if err := srv.db.Upsert(context.Background(), odd); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me", nil)
+	req.AddCookie(cookie)
+	rr := srv.do(t, req)
+	var me struct{ Login, Email string }
+	if err := json.Unmarshal(rr.Body.Bytes(), &me); err != nil {
+		t.Fatalf("GET /me isn't valid JSON: %v; body: %s", err, rr.Body)
+	}
+	if me.Login != odd.Login || me.Email != odd.Email {
+		t.Errorf("GET /me = %+v, want the login and email as stored", me)
+	}
+}
+
+func TestRoutes_WebSocket(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+		cfg.MetricsEnabled = true // the upgrade must get through every wrapped ResponseWriter
+	})
+	ts := httptest.NewServer(srv.router)
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { srv.hub.Shutdown(context.Background()) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cookie := srv.sessionCookie(t, 1, model.RoleUser)
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", &websocket.DialOptions{
+		HTTPHeader: http.Header{"Cookie": {cookie.String()}},
+	})
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.CloseNow()
+
+	read := func() ws.Message {
+		t.Helper()
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		var msg ws.Message
+		json.Unmarshal(data, &msg)
+		return msg
+	}
+	subscribe := func(topic string) ws.Message {
+		t.Helper()
+		data, _ := json.Marshal(ws.Message{Type: ws.TypeSubscribe, Topic: topic})
+		if err := conn.Write(ctx, websocket.MessageText, data); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		return read()
+	}
+
+	if msg := read(); msg.Type != ws.TypeReady || !strings.Contains(string(msg.Data), `"userId":"user-id"`) {
+		t.Fatalf("first message = %+v, want ready for user-id", msg)
+	}
+	if msg := subscribe("user:admin-id"); msg.Type != ws.TypeError {
+		t.Errorf("subscribing to another user's topic: got %+v, want an error", msg)
+	}
+	if msg := subscribe("user:user-id"); msg.Type != ws.TypeSubscribed {
+		t.Fatalf("subscribing to own topic: got %+v", msg)
+	}
+
+	srv.hub.Publish("user:user-id", map[string]string{"kind": "test"})
+	if msg := read(); msg.Type != ws.TypeEvent || string(msg.Data) != `{"kind":"test"}` {
+		t.Errorf("event = %+v", msg)
+	}
+}
+
+func TestRoutes_Collaboration(t *testing.T) {
+	srv := newTestServer(t, nil)
+	ts := httptest.NewServer(srv.router)
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { srv.hub.Shutdown(context.Background()) })
+
+	rr := srv.do(t, httptest.NewRequest(http.MethodPost, "/api/v1/snippets", strings.NewReader(`{"name":"pair","code":"print(x)"}`)))
+	var snippet struct{ ID string }
+	json.Unmarshal(rr.Body.Bytes(), &snippet)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create snippet: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	topic := service.CollabTopicPrefix + snippet.ID
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	connect := func() *websocket.Conn {
+		t.Helper()
+		conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", nil)
+		if err != nil {
+			t.Fatalf("Dial() error = %v", err)
+		}
+		t.Cleanup(func() { conn.CloseNow() })
+		return conn
+	}
+	write := func(conn *websocket.Conn, msg ws.Message) {
+		t.Helper()
+		data, _ := json.Marshal(msg)
+		if err := conn.Write(ctx, websocket.MessageText, data); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	// next reads until a message of type typ, skipping presence events.
+	next := func(conn *websocket.Conn, typ string) ws.Message {
+		t.Helper()
+		for {
+			_, data, err := conn.Read(ctx)
+			if err != nil {
+				t.Fatalf("Read() waiting for %s: error = %v", typ, err)
+			}
+			var msg ws.Message
+			json.Unmarshal(data, &msg)
+			if msg.Type == typ || msg.Type == ws.TypeError {
+				return msg
+			}
+		}
+	}
+
+	alice, bob := connect(), connect()
+	write(alice, ws.Message{Type: service.MsgCollabJoin, Topic: service.CollabTopicPrefix + "missing"})
+	if msg := next(alice, service.MsgCollabJoined); msg.Type != ws.TypeError {
+		t.Errorf("joining a missing snippet: got %+v, want an error", msg)
+	}
+	var joined struct {
+		Session  string
+		Revision int
+		Code     *string
+		Members  []service.CollabMember
+	}
+	write(alice, ws.Message{Type: service.MsgCollabJoin, Topic: topic})
+	json.Unmarshal(next(alice, service.MsgCollabJoined).Data, &joined)
+	if joined.Code == nil || *joined.Code != "print(x)" || joined.Revision != 0 {
+		t.Fatalf("alice joined = %+v, want the code at revision 0", joined)
+	}
+	write(bob, ws.Message{Type: service.MsgCollabJoin, Topic: topic})
+	json.Unmarshal(next(bob, service.MsgCollabJoined).Data, &joined)
+	if len(joined.Members) != 2 {
+		t.Errorf("bob joined with members %+v, want both", joined.Members)
+	}
+
+	// Both edit revision 0 at once; the server puts bob's op after alice's.
+	write(alice, ws.Message{Type: service.MsgCollabOp, Topic: topic, Data: json.RawMessage(`{"revision":0,"opId":"a1","op":[6,-1,"y",1]}`)})
+	if msg := next(alice, service.MsgCollabAck); !strings.Contains(string(msg.Data), `"revision":1`) {
+		t.Fatalf("alice's ack = %+v", msg)
+	}
+	write(bob, ws.Message{Type: service.MsgCollabOp, Topic: topic, Data: json.RawMessage(`{"revision":0,"opId":"b1","op":["# hi\n",8]}`)})
+	if msg := next(bob, service.MsgCollabAck); !strings.Contains(string(msg.Data), `"revision":2`) {
+		t.Fatalf("bob's ack = %+v", msg)
+	}
+	if msg := next(alice, ws.TypeEvent); !strings.Contains(string(msg.Data), `"opId":"a1"`) {
+		t.Errorf("alice's first event = %s, want her own op", msg.Data)
+	}
+	if msg := next(alice, ws.TypeEvent); !strings.Contains(string(msg.Data), `"op":["# hi\n",8]`) {
+		t.Errorf("alice's second event = %s, want bob's op", msg.Data)
+	}
+
+	// Bob drops and reconnects: he's caught up with ops, not a snapshot,
+	// and his resent op isn't applied twice.
+	bob.Close(websocket.StatusNormalClosure, "")
+	bob = connect()
+	write(bob, ws.Message{Type: service.MsgCollabJoin, Topic: topic, Data: json.RawMessage(`{"session":"` + joined.Session + `","revision":1}`)})
+	var caughtUp struct {
+		Code *string
+		Ops  []json.RawMessage
+	}
+	json.Unmarshal(next(bob, service.MsgCollabJoined).Data, &caughtUp)
+	if caughtUp.Code != nil || len(caughtUp.Ops) != 1 {
+		t.Errorf("rejoin = %+v, want one op and no code", caughtUp)
+	}
+	write(bob, ws.Message{Type: service.MsgCollabOp, Topic: topic, Data: json.RawMessage(`{"revision":0,"opId":"b1","op":["# hi\n",8]}`)})
+	if msg := next(bob, service.MsgCollabAck); !strings.Contains(string(msg.Data), `"revision":2`) {
+		t.Errorf("resent op's ack = %+v, want the original revision", msg)
+	}
+
+	if err := srv.collab.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	rr = srv.do(t, httptest.NewRequest(http.MethodGet, "/api/v1/snippets/"+snippet.ID, nil))
+	var saved struct {
+		Code    string
+		Version int
+	}
+	json.Unmarshal(rr.Body.Bytes(), &saved)
+	if saved.Code != "# hi\nprint(y)" || saved.Version != 2 {
+		t.Errorf("saved snippet = %+v, want both edits as version 2", saved)
+	}
+}
+
+func TestRoutes_Presence(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+	})
+	ts := httptest.NewServer(srv.router)
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { srv.hub.Shutdown(context.Background()) })
+
+	rr := srv.do(t, httptest.NewRequest(http.MethodPost, "/api/v1/snippets", strings.NewReader(`{"name":"watched","code":"print(1)"}`)))
+	var snippet struct{ ID string }
+	json.Unmarshal(rr.Body.Bytes(), &snippet)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create snippet: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	topic := service.PresenceTopicPrefix + snippet.ID
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	connect := func(cookie *http.Cookie) *websocket.Conn {
+		t.Helper()
+		opts := &websocket.DialOptions{}
+		if cookie != nil {
+			opts.HTTPHeader = http.Header{"Cookie": {cookie.String()}}
+		}
+		conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", opts)
+		if err != nil {
+			t.Fatalf("Dial() error = %v", err)
+		}
+		t.Cleanup(func() { conn.CloseNow() })
+		return conn
+	}
+	write := func(conn *websocket.Conn, msg ws.Message) {
+		t.Helper()
+		data, _ := json.Marshal(msg)
+		if err := conn.Write(ctx, websocket.MessageText, data); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	read := func(conn *websocket.Conn) ws.Message {
+		t.Helper()
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		var msg ws.Message
+		json.Unmarshal(data, &msg)
+		return msg
+	}
+
+	anon := connect(nil)
+	read(anon) // ready
+	write(anon, ws.Message{Type: service.MsgPresenceJoin, Topic: topic})
+	if msg := read(anon); msg.Type != ws.TypeError {
+		t.Errorf("anonymous join: got %+v, want an error", msg)
+	}
+	// Anonymous users can still watch.
+	write(anon, ws.Message{Type: ws.TypeSubscribe, Topic: topic})
+	if msg := read(anon); msg.Type != ws.TypeSubscribed {
+		t.Fatalf("anonymous subscribe: got %+v", msg)
+	}
+
+	student := connect(srv.sessionCookie(t, 1, model.RoleUser))
+	read(student)
+	write(student, ws.Message{Type: service.MsgPresenceJoin, Topic: topic, ID: "1"})
+	if msg := read(student); msg.Type != service.MsgPresenceJoined || !strings.Contains(string(msg.Data), `"login":"user"`) {
+		t.Fatalf("join = %+v, want the student among the viewers", msg)
+	}
+	if msg := read(anon); !strings.Contains(string(msg.Data), `"kind":"join"`) {
+		t.Errorf("watcher got %s, want a join event", msg.Data)
+	}
+
+	write(student, ws.Message{Type: service.MsgPresenceCursor, Topic: topic, Data: json.RawMessage(`{"line":1,"column":7}`)})
+	if msg := read(anon); !strings.Contains(string(msg.Data), `"kind":"cursor"`) || !strings.Contains(string(msg.Data), `"cursor":{"line":1,"column":7}`) {
+		t.Errorf("watcher got %s, want the student's cursor", msg.Data)
+	}
+
+	student.Close(websocket.StatusNormalClosure, "")
+	if msg := read(anon); !strings.Contains(string(msg.Data), `"kind":"leave"`) {
+		t.Errorf("watcher got %s, want a leave event", msg.Data)
+	}
+}
+
+func TestRoutes_PlaygroundUsesFingerprintedAssets(t *testing.T) {
+	srv := newTestServer(t, nil)
+
+	rr := srv.do(t, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rr.Code)
+	}
+	if got := rr.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("page Cache-Control = %q, want no-cache", got)
+	}
+	if !regexp.MustCompile(`/static/css/style\.[0-9a-f]{10}\.css`).MatchString(rr.Body.String()) {
+		t.Error("page does not reference the fingerprinted stylesheet")
+	}
+}
+
+func TestRoutes_ErrorsCarryRequestID(t *testing.T) {
+	srv := newTestServer(t, nil)
+
+	rr := srv.do(t, httptest.NewRequest(http.MethodGet, "/api/v1/snippets/does-not-exist", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rr.Code)
+	}
+
+	header := rr.Header().Get("X-Request-ID")
+	if header == "" {
+		t.Fatal("X-Request-ID header missing")
+	}
+
+	var body struct {
+		RequestID string `json:"requestId"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if body.RequestID != header {
+		t.Errorf("requestId = %q, want header value %q", body.RequestID, header)
+	}
+}
+
+func TestRunShutdownHooks(t *testing.T) {
+	srv := newTestServer(t, nil)
+	srv.shutdownHooks = nil // drop the built-in hooks; test only ours
+
+	var order []string
+	hook := func(name string, err error) func(context.Context) error {
+		return func(context.Context) error {
+			order = append(order, name)
+			return err
+		}
+	}
+	srv.OnShutdown("first", hook("first", nil))
+	srv.OnShutdown("second", hook("second", errors.New("stuck")))
+	srv.OnShutdown("third", hook("third", nil))
+
+	srv.runShutdownHooks(context.Background())
+	srv.runShutdownHooks(context.Background()) // second call is a no-op
+
+	// Reverse registration order, and a failing hook doesn't stop the rest.
+	want := []string{"third", "second", "first"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("hooks ran as %v, want %v", order, want)
+	}
+}
+
+func TestRunShutdownHooks_AfterShutdown(t *testing.T) {
+	srv := newTestServer(t, nil)
+	srv.shutdownHooks = nil
+
+	var order []string
+	hook := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			order = append(order, name)
+			return nil
+		}
+	}
+	srv.OnShutdown("debugging sessions", hook("debugging sessions"))
+	// As main.go does, after New: the executor's owner registers it last.
+	srv.AfterShutdown("sandbox leases", hook("sandbox leases"))
+	srv.AfterShutdown("executor", hook("executor"))
+	srv.OnShutdown("live runs", hook("live runs"))
+
+	srv.runShutdownHooks(context.Background())
+
+	want := []string{"live runs", "debugging sessions", "executor", "sandbox leases"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("hooks ran as %v, want %v: sessions before their sandboxes", order, want)
+	}
+}
+
+func TestConfigListen_UnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "playground.sock")
+
+	// A stale socket from a crashed server is replaced.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("creating stale socket: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, err := Config{Listen: "unix:" + path}.listen()
+	if err != nil {
+		t.Fatalf("listen() error = %v", err)
+	}
+	defer ln.Close()
+
+	if ln.Addr().Network() != "unix" {
+		t.Errorf("network = %q, want unix", ln.Addr().Network())
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat socket: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != unixSocketMode {
+		t.Errorf("socket mode = %o, want %o", perm, unixSocketMode)
+	}
+
+	go http.Serve(ln, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://playground/")
+	if err != nil {
+		t.Fatalf("request over unix socket: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestConfigListen_RefusesToReplaceRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "not-a-socket")
+	if err := os.WriteFile(path, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := (Config{Listen: "unix:" + path}).listen(); err == nil {
+		t.Fatal("listen() replaced a regular file")
+	}
+}
+
+func TestConfigListen_SystemdWithoutSocket(t *testing.T) {
+	t.Setenv("LISTEN_PID", "")
+	t.Setenv("LISTEN_FDS", "")
+
+	if _, err := (Config{Listen: "systemd"}).listen(); err == nil {
+		t.Fatal("listen() succeeded without a systemd socket")
+	}
+}
+
+func TestRoutes_UnmatchedReturnJSON(t *testing.T) {
+	srv := newTestServer(t, nil)
+
+	t.Run("unknown path", func(t *testing.T) {
+		rr := srv.do(t, httptest.NewRequest(http.MethodGet, "/api/v1/nope", nil))
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("status = %d, want 404", rr.Code)
+		}
+		var body struct{ Error, RequestID string }
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatalf("body is not JSON: %v", err)
+		}
+		if body.Error != "not_found" || body.RequestID == "" {
+			t.Errorf("body = %+v, want not_found with a request ID", body)
+		}
+	})
+
+	t.Run("wrong method", func(t *testing.T) {
+		rr := srv.do(t, httptest.NewRequest(http.MethodPatch, "/api/v1/snippets", nil))
+		if rr.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("status = %d, want 405", rr.Code)
+		}
+		if got := rr.Header().Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", got)
+		}
+		if allow := rr.Header().Get("Allow"); !strings.Contains(allow, "GET") || !strings.Contains(allow, "POST") {
+			t.Errorf("Allow = %q, want GET and POST", allow)
+		}
+	})
+}
+
+func TestRoutes_Orgs(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+	})
+	owner := srv.sessionCookie(t, 1, model.RoleUser)
+	teammate := srv.sessionCookie(t, 2, model.RoleAuthor)
+	outsider := srv.sessionCookie(t, 3, model.RoleAdmin)
+	send := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		return srv.do(t, req)
+	}
+
+	rr := send(http.MethodPost, "/api/v1/orgs", `{"name":"Data team"}`, owner)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create org: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	var org struct{ ID, Role string }
+	json.Unmarshal(rr.Body.Bytes(), &org)
+	if org.Role != model.OrgRoleOwner {
+		t.Errorf("create org: role = %q, want owner", org.Role)
+	}
+	if rr := send(http.MethodPost, "/api/v1/orgs/"+org.ID+"/members", `{"login":"author"}`, owner); rr.Code != http.StatusCreated {
+		t.Fatalf("add member: status = %d, body = %s", rr.Code, rr.Body)
+	}
+
+	rr = send(http.MethodPost, "/api/v1/snippets", `{"name":"shared","code":"print(1)","orgId":"`+org.ID+`"}`, owner)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create org snippet: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	var snippet struct{ ID, OrgID string }
+	json.Unmarshal(rr.Body.Bytes(), &snippet)
+	if snippet.OrgID != org.ID {
+		t.Errorf("orgId = %q, want %q", snippet.OrgID, org.ID)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		cookie *http.Cookie
+		want   int
+	}{
+		{"teammate", teammate, http.StatusOK},
+		{"outsider", outsider, http.StatusForbidden},
+		{"anonymous", nil, http.StatusForbidden},
+	} {
+		if rr := send(http.MethodPut, "/api/v1/snippets/"+snippet.ID, `{"code":"print(2)"}`, tc.cookie); rr.Code != tc.want {
+			t.Errorf("update by %s: status = %d, want %d", tc.name, rr.Code, tc.want)
+		}
+	}
+
+	if rr := send(http.MethodGet, "/api/v1/orgs/"+org.ID, "", outsider); rr.Code != http.StatusNotFound {
+		t.Errorf("get org as outsider: status = %d, want 404", rr.Code)
+	}
+	if rr := send(http.MethodDelete, "/api/v1/orgs/"+org.ID+"/members/user", "", owner); rr.Code != http.StatusConflict {
+		t.Errorf("last owner leaving: status = %d, want 409", rr.Code)
+	}
+	if rr := send(http.MethodGet, "/api/v1/snippets?org="+org.ID, "", nil); !strings.Contains(rr.Body.String(), `"total":1`) {
+		t.Errorf("list org snippets: body = %s, want one", rr.Body)
+	}
+
+	// Exports sit outside the /orgs route table (they skip its timeout);
+	// both must still route.
+	rr = send(http.MethodGet, "/api/v1/orgs/"+org.ID+"/export", "", teammate)
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/gzip" ||
+		rr.Header().Get("Content-Disposition") != `attachment; filename=snippets-data-team.tar.gz` {
+		t.Errorf("export org: status = %d, headers = %v", rr.Code, rr.Header())
+	}
+	if gz, err := gzip.NewReader(rr.Body); err != nil {
+		t.Errorf("export org: not gzip: %v", err)
+	} else if hdr, err := tar.NewReader(gz).Next(); err != nil || !strings.HasPrefix(hdr.Name, "snippets-data-team/shared-") {
+		t.Errorf("export org: first file = %+v, %v", hdr, err)
+	}
+	if rr := send(http.MethodGet, "/api/v1/orgs/"+org.ID+"/export", "", outsider); rr.Code != http.StatusNotFound {
+		t.Errorf("export org as outsider: status = %d, want 404", rr.Code)
+	}
+	if rr := send(http.MethodGet, "/api/v1/orgs/"+org.ID+"/members", "", teammate); rr.Code != http.StatusOK {
+		t.Errorf("members beside export: status = %d, want 200", rr.Code)
+	}
+	if rr := send(http.MethodGet, "/api/v1/me/export", "", owner); rr.Code != http.StatusOK {
+		t.Errorf("export own: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	if rr := send(http.MethodGet, "/api/v1/me/export", "", nil); rr.Code != http.StatusUnauthorized {
+		t.Errorf("export own anonymously: status = %d, want 401", rr.Code)
+	}
+}
+
+func TestRoutes_OrgInvites(t *testing.T) {
+	m := &recordingMailer{}
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+		cfg.Mailer = m
+		cfg.PublicURL = "https://play.example"
+	})
+	if err := srv.jobs.Start(context.Background()); err != nil {
+		t.Fatalf("starting job queue: %v", err)
+	}
+	t.Cleanup(func() { srv.jobs.Shutdown(context.Background()) })
+	owner := srv.sessionCookie(t, 1, model.RoleUser)
+	invitee := srv.sessionCookie(t, 2, model.RoleAuthor)
+	send := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		return srv.do(t, req)
+	}
+
+	var org struct{ ID string }
+	json.Unmarshal(send(http.MethodPost, "/api/v1/orgs", `{"name":"Data team"}`, owner).Body.Bytes(), &org)
+	rr := send(http.MethodPost, "/api/v1/orgs/"+org.ID+"/invites", `{"email":"bob@example.com"}`, owner)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create invite: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	var created struct{ URL string }
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	path := strings.TrimPrefix(created.URL, "https://play.example")
+
+	for deadline := time.Now().Add(5 * time.Second); m.count() == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if m.count() != 1 || m.sent[0].To != "bob@example.com" || !strings.Contains(m.sent[0].Text, created.URL) {
+		t.Fatalf("sent %+v, want the invite link to bob", m.sent)
+	}
+
+	if rr := send(http.MethodGet, "/api/v1/orgs/"+org.ID+"/invites", "", invitee); rr.Code != http.StatusNotFound {
+		t.Errorf("list invites as outsider: status = %d, want 404", rr.Code)
+	}
+	if rr := send(http.MethodGet, "/api/v1/orgs/"+org.ID+"/invites", "", owner); !strings.Contains(rr.Body.String(), "bob@example.com") {
+		t.Errorf("list invites: body = %s, want the pending one", rr.Body)
+	}
+	if rr := send(http.MethodGet, path, "", nil); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"orgName":"Data team"`) {
+		t.Errorf("get invite: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	if rr := send(http.MethodPost, path+"/accept", "", nil); rr.Code != http.StatusUnauthorized {
+		t.Errorf("accept signed out: status = %d, want 401", rr.Code)
+	}
+	if rr := send(http.MethodPost, path+"/accept", "", invitee); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"role":"member"`) {
+		t.Errorf("accept: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	if rr := send(http.MethodGet, path, "", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("get used invite: status = %d, want 404", rr.Code)
+	}
+}
+
+func TestRoutes_OrgQuotas(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+		cfg.OrgSnippetQuota = 1
+	})
+	owner := srv.sessionCookie(t, 1, model.RoleUser)
+	viewer := srv.sessionCookie(t, 2, model.RoleAuthor)
+	admin := srv.sessionCookie(t, 3, model.RoleAdmin)
+	send := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(cookie)
+		return srv.do(t, req)
+	}
+
+	var org struct{ ID string }
+	json.Unmarshal(send(http.MethodPost, "/api/v1/orgs", `{"name":"Data team"}`, owner).Body.Bytes(), &org)
+	if rr := send(http.MethodPost, "/api/v1/orgs/"+org.ID+"/members", `{"login":"author","role":"viewer"}`, owner); rr.Code != http.StatusCreated {
+		t.Fatalf("add viewer: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	create := `{"name":"shared","code":"print(1)","orgId":"` + org.ID + `"}`
+	if rr := send(http.MethodPost, "/api/v1/snippets", create, viewer); rr.Code != http.StatusForbidden {
+		t.Errorf("create as viewer: status = %d, want 403", rr.Code)
+	}
+	if rr := send(http.MethodPost, "/api/v1/snippets", create, owner); rr.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	if rr := send(http.MethodPost, "/api/v1/snippets", create, owner); rr.Code != http.StatusForbidden {
+		t.Errorf("create over quota: status = %d, want 403", rr.Code)
+	}
+
+	rr := send(http.MethodGet, "/api/v1/orgs/"+org.ID+"/usage", "", viewer)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"snippets":1,"snippetLimit":1`) {
+		t.Errorf("usage: status = %d, body = %s", rr.Code, rr.Body)
+	}
+
+	quota := "/api/v1/admin/orgs/" + org.ID + "/quota"
+	if rr := send(http.MethodPut, quota, `{"snippets":10}`, owner); rr.Code != http.StatusForbidden {
+		t.Errorf("set quota as non-admin: status = %d, want 403", rr.Code)
+	}
+	if rr := send(http.MethodPut, quota, `{"snippets":10}`, admin); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"snippetLimit":10`) {
+		t.Errorf("set quota: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	if rr := send(http.MethodPost, "/api/v1/snippets", create, owner); rr.Code != http.StatusCreated {
+		t.Errorf("create under raised quota: status = %d, body = %s", rr.Code, rr.Body)
+	}
+}
+
+func TestRoutes_Transfers(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+	})
+	owner := srv.sessionCookie(t, 1, model.RoleUser)
+	recipient := srv.sessionCookie(t, 2, model.RoleAuthor)
+	send := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(cookie)
+		return srv.do(t, req)
+	}
+
+	var snippet struct{ ID string }
+	json.Unmarshal(send(http.MethodPost, "/api/v1/snippets", `{"name":"loop","code":"print(1)"}`, owner).Body.Bytes(), &snippet)
+
+	// The deprecated /api alias works too.
+	rr := send(http.MethodPost, "/api/snippets/"+snippet.ID+"/transfer", `{"login":"author"}`, owner)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("offer: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	var offer struct{ ID string }
+	json.Unmarshal(rr.Body.Bytes(), &offer)
+
+	rr = send(http.MethodGet, "/api/v1/me/transfers", "", recipient)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"snippetName":"loop"`) {
+		t.Errorf("list: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	rr = send(http.MethodPost, "/api/v1/transfers/"+offer.ID+"/accept", "", recipient)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("accept: status = %d, body = %s", rr.Code, rr.Body)
+	}
+
+	// The snippet is the recipient's now, under the same ID.
+	if rr := send(http.MethodPut, "/api/v1/snippets/"+snippet.ID+"/visibility", `{"public":true}`, owner); rr.Code != http.StatusForbidden {
+		t.Errorf("publish as old owner: status = %d, want 403", rr.Code)
+	}
+	if rr := send(http.MethodPut, "/api/v1/snippets/"+snippet.ID+"/visibility", `{"public":true}`, recipient); rr.Code != http.StatusOK {
+		t.Errorf("publish as new owner: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	if rr := send(http.MethodDelete, "/api/v1/transfers/"+offer.ID, "", owner); rr.Code != http.StatusNotFound {
+		t.Errorf("withdraw accepted offer: status = %d, want 404", rr.Code)
+	}
+}
+
+func TestRoutes_SuspendedUsers(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+	})
+	cookie := srv.sessionCookie(t, 1, model.RoleUser)
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(cookie)
+		return srv.do(t, req)
+	}
+
+	var snippet struct{ ID string }
+	json.Unmarshal(send(http.MethodPost, "/api/v1/snippets", `{"name":"loop","code":"print(1)"}`).Body.Bytes(), &snippet)
+
+	// Suspend them the way the admin CLI does.
+	users := service.NewUserService(srv.db, srv.logger)
+	if _, err := users.SetSuspended(context.Background(), model.RoleUser, true); err != nil {
+		t.Fatalf("SetSuspended() error = %v", err)
+	}
+	// The server trusts what it last saw for a while.
+	if rr := send(http.MethodPost, "/api/v1/snippets", `{"name":"cached"}`); rr.Code != http.StatusCreated {
+		t.Errorf("create within the cache TTL: status = %d, want 201", rr.Code)
+	}
+	later := time.Now().Add(suspensionCacheTTL)
+	srv.suspensions.now = func() time.Time { return later }
+
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodGet, "/api/v1/snippets/" + snippet.ID, "", http.StatusOK},
+		{http.MethodGet, "/api/v1/me", "", http.StatusOK},
+		{http.MethodPost, "/api/v1/graphql", `{"query":"{ snippet(id: \"` + snippet.ID + `\") { name } }"}`, http.StatusOK},
+		{http.MethodPost, "/api/v1/snippets", `{"name":"new"}`, http.StatusForbidden},
+		{http.MethodPut, "/api/snippets/" + snippet.ID, `{"code":"print(2)"}`, http.StatusForbidden},
+		{http.MethodDelete, "/api/v1/snippets/" + snippet.ID, "", http.StatusForbidden},
+		{http.MethodPost, "/api/v1/snippets/" + snippet.ID + "/fork", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		rr := send(tt.method, tt.path, tt.body)
+		if rr.Code != tt.want {
+			t.Errorf("%s %s: status = %d, want %d; body = %s", tt.method, tt.path, rr.Code, tt.want, rr.Body)
+		}
+	}
+	if rr := send(http.MethodGet, "/api/v1/me", ""); !strings.Contains(rr.Body.String(), `"suspended":true`) {
+		t.Errorf("/me = %s, want suspended", rr.Body)
+	}
+
+	// Anonymous requests aren't affected.
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/snippets", strings.NewReader(`{"name":"anon"}`))
+	if rr := srv.do(t, req); rr.Code != http.StatusCreated {
+		t.Errorf("anonymous create: status = %d, want 201", rr.Code)
+	}
+
+	// Nor can they edit over /ws: they may join a collaborative session,
+	// but not change the snippet through it.
+	ts := httptest.NewServer(srv.router)
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { srv.hub.Shutdown(context.Background()) })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(ts.URL, "http")+"/ws",
+		&websocket.DialOptions{HTTPHeader: http.Header{"Cookie": {cookie.String()}}})
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.CloseNow()
+	next := func(typ string) ws.Message {
+		t.Helper()
+		for {
+			_, data, err := conn.Read(ctx)
+			if err != nil {
+				t.Fatalf("Read() waiting for %s: error = %v", typ, err)
+			}
+			var msg ws.Message
+			json.Unmarshal(data, &msg)
+			if msg.Type == typ || msg.Type == ws.TypeError {
+				return msg
+			}
+		}
+	}
+	topic := service.CollabTopicPrefix + snippet.ID
+	for _, msg := range []ws.Message{
+		{Type: service.MsgCollabJoin, Topic: topic},
+		{Type: service.MsgCollabOp, Topic: topic, Data: json.RawMessage(`{"revision":0,"opId":"s1","op":["# mine\n",8]}`)},
+	} {
+		data, _ := json.Marshal(msg)
+		if err := conn.Write(ctx, websocket.MessageText, data); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if msg.Type == service.MsgCollabJoin {
+			if got := next(service.MsgCollabJoined); got.Type != service.MsgCollabJoined {
+				t.Fatalf("join = %+v, want to watch the session", got)
+			}
+		}
+	}
+	if got := next(service.MsgCollabAck); got.Type != ws.TypeError || !strings.Contains(got.Error, "suspended") {
+		t.Errorf("op from a suspended user = %+v, want it refused", got)
+	}
+}
+
+func TestMetrics_Saturation(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) { cfg.MetricsEnabled = true })
+	if _, err := srv.jobs.Enqueue(context.Background(), "noop", nil); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	rr := srv.do(t, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GET /metrics = %d", rr.Code)
+	}
+	for _, want := range []string{
+		"\ngo_goroutines ",
+		"\nplayground_db_in_use_connections ",
+		"\nplayground_db_wait_seconds_total ",
+		"\nplayground_jobs_backlog 1\n",
+		"\nplayground_retries_total ",
+	} {
+		if !strings.Contains(rr.Body.String(), want) {
+			t.Errorf("/metrics has no %q", strings.TrimSpace(want))
+		}
+	}
+}
+
+func TestRunRetention(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.MetricsEnabled = true
+		cfg.AnonymousRunRetention = RunRetention{Keep: 1}
+		cfg.RunPurgeSchedule = "0 * * * *"
+	})
+	ctx := context.Background()
+	registered := false
+	for _, st := range srv.scheduler.Statuses() {
+		registered = registered || st.Name == taskRunPurge
+	}
+	if !registered {
+		t.Fatal("run_purge isn't scheduled")
+	}
+
+	for _, userID := range []string{"", "", "", "ann"} {
+		if err := srv.db.CreateRun(ctx, &model.Run{UserID: userID, Language: "python"}); err != nil {
+			t.Fatalf("CreateRun() error = %v", err)
+		}
+	}
+	if err := srv.purgeRuns(ctx); err != nil {
+		t.Fatalf("purgeRuns() error = %v", err)
+	}
+
+	rr := srv.do(t, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		"\nplayground_anonymous_runs_purged_total 2\n",
+		"\nplayground_runs_purged_total 0\n", // no policy for signed-in users
+	} {
+		if !strings.Contains(rr.Body.String(), want) {
+			t.Errorf("/metrics has no %q", strings.TrimSpace(want))
+		}
+	}
+}
+
+func TestReloadSecrets(t *testing.T) {
+	srv := newTestServer(t, func(cfg *Config) {
+		cfg.DBPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.JWTSecret = "test-secret-that-is-at-least-32-bytes-long"
+		cfg.GitHubClientID = "old-id"
+		cfg.GitHubClientSecret = "old-secret"
+	})
+	session := srv.sessionCookie(t, 1, model.RoleUser)
+	me := func(cookie *http.Cookie) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/me", nil)
+		req.AddCookie(cookie)
+		return srv.do(t, req).Code
+	}
+
+	rotated := Secrets{
+		JWTSecret:          "a-rotated-secret-that-is-at-least-32-bytes",
+		GitHubClientID:     "new-id",
+		GitHubClientSecret: "new-secret",
+	}
+	if err := srv.ReloadSecrets(rotated); err != nil {
+		t.Fatalf("ReloadSecrets() error = %v", err)
+	}
+	if code := me(session); code != http.StatusOK {
+		t.Errorf("a session from before the reload: status = %d, want 200", code)
+	}
+	if !strings.Contains(srv.github.AuthURL("state"), "client_id=new-id") {
+		t.Errorf("sign-in still uses the old client ID: %s", srv.github.AuthURL("state"))
+	}
+	fresh, err := auth.NewTokenService(rotated.JWTSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _ := fresh.Generate("user-id")
+	if code := me(&http.Cookie{Name: auth.CookieName, Value: token}); code != http.StatusOK {
+		t.Errorf("a session signed with the new secret: status = %d, want 200", code)
+	}
+
+	for name, bad := range map[string]Secrets{
+		"a short secret":   {JWTSecret: "short", GitHubClientID: "id", GitHubClientSecret: "secret"},
+		"no JWT secret":    {GitHubClientID: "id", GitHubClientSecret: "secret"},
+		"no GitHub secret": {JWTSecret: rotated.JWTSecret, GitHubClientID: "id"},
+	} {
+		if err := srv.ReloadSecrets(bad); err == nil {
+			t.Errorf("%s: ReloadSecrets() succeeded", name)
+		}
+	}
+	if code := me(session); code != http.StatusOK {
+		t.Errorf("after failed reloads: status = %d, want 200", code)
+	}
+}
+
+// cannedCompleter answers the autocomplete and docs harnesses with the
+// same reports every time.
+type cannedCompleter struct{ completions, docs string }
+
+func (c cannedCompleter) Execute(_ context.Context, req executor.ExecutionRequest) (*executor.ExecutionResult, error) {
+	marker := regexp.MustCompile(`MARKER = "(.*)"`).FindStringSubmatch(req.Code)[1]
+	report := c.completions
+	if strings.Contains(req.Code, "import pydoc") {
+		report = c.docs
+	}
+	return &executor.ExecutionResult{Stdout: "\n" + marker + report + "\n"}, nil
+}
+
+func TestRoutes_Editor(t *testing.T) {
+	body := `{"code":"import math\nmath.sq","line":2,"column":7}`
+
+	srv := newTestServer(t, nil)
+	if rr := srv.do(t, httptest.NewRequest(http.MethodPost, "/api/v1/complete", strings.NewReader(body))); rr.Code != http.StatusNotFound {
+		t.Errorf("without an executor: status = %d, want 404", rr.Code)
+	}
+
+	srv = newTestServer(t, func(c *Config) {
+		c.Completer = cannedCompleter{
+			completions: `{"engine":"basic","completions":[{"name":"sqrt","complete":"rt","type":"function"}]}`,
+			docs:        `{"symbol":"os.path.join","found":true,"kind":"function","signature":"join(a, *p)","doc":"Join paths.","module":"posixpath"}`,
+		}
+	})
+	for _, path := range []string{"/api/v1/complete", "/api/complete"} {
+		rr := srv.do(t, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("POST %s: status = %d, body = %s", path, rr.Code, rr.Body)
+		}
+		var got executor.Completions
+		if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Engine != "basic" || len(got.Completions) != 1 || got.Completions[0].Complete != "rt" {
+			t.Errorf("POST %s = %+v", path, got)
+		}
+	}
+
+	rr := srv.do(t, httptest.NewRequest(http.MethodPost, "/api/v1/complete", strings.NewReader(`{"code":"x","line":2,"column":0}`)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("a line past the end: status = %d, want 400", rr.Code)
+	}
+	rr = srv.do(t, httptest.NewRequest(http.MethodGet, "/api/v1/docs?symbol=os.path.join", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"signature":"join(a, *p)"`) {
+		t.Errorf("GET /docs: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	rr = srv.do(t, httptest.NewRequest(http.MethodGet, "/api/v1/docs?symbol=os;rm", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("GET /docs with a bad symbol: status = %d, want 400", rr.Code)
+	}
+	// The completer runs nothing else: /execute still needs an executor.
+	rr = srv.do(t, httptest.NewRequest(http.MethodPost, "/api/v1/execute", strings.NewReader(`{"code":"print(1)"}`)))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("POST /execute with only a completer: status = %d, want 404", rr.Code)
+	}
+}
+
+// idleDebugger is a program that never ends until it's stopped.
+type idleDebugger struct{}
+
+func (idleDebugger) Execute(ctx context.Context, _ executor.ExecutionRequest) (*executor.ExecutionResult, error) {
+	<-ctx.Done()
+	return &executor.ExecutionResult{ExitCode: 124}, nil
+}
+
+func TestRoutes_Debug(t *testing.T) {
+	body := `{"code":"x = 1\nprint(x)","breakpoints":[2]}`
+
+	srv := newTestServer(t, nil)
+	if rr := srv.do(t, httptest.NewRequest(http.MethodPost, "/api/v1/debug", strings.NewReader(body))); rr.Code != http.StatusNotFound {
+		t.Errorf("without an executor: status = %d, want 404", rr.Code)
+	}
+
+	srv = newTestServer(t, func(c *Config) { c.Debugger = idleDebugger{} })
+	t.Cleanup(func() { srv.debugger.Shutdown(context.Background()) })
+	rr := srv.do(t, httptest.NewRequest(http.MethodPost, "/api/v1/debug", strings.NewReader(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("POST /debug: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	var session service.DebugSession
+	if err := json.Unmarshal(rr.Body.Bytes(), &session); err != nil {
+		t.Fatal(err)
+	}
+	if session.Topic != "debug:"+session.ID || rr.Header().Get("Location") != "/api/v1/debug/"+session.ID {
+		t.Errorf("POST /debug = %+v, Location %q", session, rr.Header().Get("Location"))
+	}
+
+	if rr := srv.do(t, httptest.NewRequest(http.MethodGet, "/api/v1/debug/"+session.ID, nil)); rr.Code != http.StatusOK {
+		t.Errorf("GET /debug/{id}: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	if rr := srv.do(t, httptest.NewRequest(http.MethodDelete, "/api/v1/debug/"+session.ID, nil)); rr.Code != http.StatusNoContent {
+		t.Errorf("DELETE /debug/{id}: status = %d, body = %s", rr.Code, rr.Body)
+	}
+	rr = srv.do(t, httptest.NewRequest(http.MethodPost, "/api/v1/debug", strings.NewReader(`{"code":"x = 1","breakpoints":[5]}`)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("a breakpoint past the end: status = %d, want 400", rr.Code)
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// ShutdownTimeout bounds the whole graceful shutdown: draining HTTP requests
+// and then running every shutdown hook.
+const ShutdownTimeout = 30 * time.Second
+
+// SHUTDOWN HOOKS:
+// Everything the server owns that needs tearing down — background workers,
+// the access log, the database — registers a hook with OnShutdown. When the
+// server stops, hooks run in REVERSE order of registration, like deferred
+// calls: something registered later may depend on something registered
+// earlier (jobs use the database), so it must stop first.
+//
+// Dependencies handed to the server from outside — executors, the sandbox
+// lease store — are registered by their owner after New, so OnShutdown
+// would stop them before the live runs, background executions and
+// debugging sessions that hold their sandboxes. Their owner registers them
+// with AfterShutdown instead: those hooks run once every OnShutdown hook
+// has, newest first among themselves.
+//
+// Hooks share one deadline. A hook that fails or runs out of time is logged
+// and the remaining hooks still run — a stuck job queue must not stop the
+// database from being closed cleanly.
+
+// shutdownHook is a named teardown step.
+type shutdownHook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// OnShutdown registers fn to run during shutdown, after in-flight HTTP
+// requests have finished. name identifies the hook in logs. Register hooks
+// before calling Start.
+func (s *Server) OnShutdown(name string, fn func(ctx context.Context) error) {
+	s.shutdownHooks = append(s.shutdownHooks, shutdownHook{name: name, fn: fn})
+}
+
+// AfterShutdown registers fn to run during shutdown, after every hook
+// registered with OnShutdown: use it for things the server's services use
+// until their own hooks have run, like the executors. Register hooks
+// before calling Start.
+func (s *Server) AfterShutdown(name string, fn func(ctx context.Context) error) {
+	s.afterShutdownHooks = append(s.afterShutdownHooks, shutdownHook{name: name, fn: fn})
+}
+
+// runShutdownHooks runs every OnShutdown hook, newest first, then every
+// AfterShutdown hook, newest first. It only does anything the first time
+// it's called.
+func (s *Server) runShutdownHooks(ctx context.Context) {
+	s.shutdownOnce.Do(func() {
+		hooks := slices.Concat(s.afterShutdownHooks, s.shutdownHooks)
+		for i := len(hooks) - 1; i >= 0; i-- {
+			hook := hooks[i]
+			start := time.Now()
+			if err := hook.fn(ctx); err != nil {
+				s.logger.Warn("shutdown hook failed",
+					slog.String("hook", hook.name),
+					slog.String("error", err.Error()),
+				)
+				continue
+			}
+			s.logger.Debug("shutdown hook finished",
+				slog.String("hook", hook.name),
+				slog.Duration("duration", time.Since(start)),
+			)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package server
+
+// SUSPENDED USERS:
+// An operator suspends a user with the admin CLI (admin suspend <login>).
+// Their account and data stay, and they can still sign in, read and export
+// everything; auth.RejectSuspended turns away every API request that would
+// change something or run code.
+//
+// Checking the flag means a database read per write, so answers are cached
+// for suspensionCacheTTL. The CLI runs in another process and can't clear
+// this cache: a suspension (or lifting one) takes effect within that time.
+//
+// Collaborative editing over /ws goes through the same cache: a suspended
+// user can join a session and watch, but their edits are refused (see
+// service.CollabService.RejectSuspended).
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// suspensionCacheTTL is how long a user's suspension flag is trusted.
+const suspensionCacheTTL = 30 * time.Second
+
+// suspensionCache remembers which users are suspended, for
+// auth.RejectSuspended.
+type suspensionCache struct {
+	users repository.UserRepository
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]suspensionEntry
+}
+
+type suspensionEntry struct {
+	suspended bool
+	expires   time.Time
+}
+
+func newSuspensionCache(users repository.UserRepository, ttl time.Duration) *suspensionCache {
+	return &suspensionCache{
+		users:   users,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]suspensionEntry),
+	}
+}
+
+// IsSuspended is an auth.SuspensionLookup. A user who no longer exists isn't
+// suspended; their token is refused further on.
+func (c *suspensionCache) IsSuspended(ctx context.Context, userID string) (bool, error) {
+	now := c.now()
+	c.mu.Lock()
+	e, ok := c.entries[userID]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.suspended, nil
+	}
+
+	user, err := c.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	suspended := user != nil && user.Suspended
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Drop expired entries now and then, so users who stopped visiting
+	// don't stay in memory.
+	if len(c.entries) >= 1024 {
+		for id, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, id)
+			}
+		}
+	}
+	c.entries[userID] = suspensionEntry{suspended: suspended, expires: now.Add(c.ttl)}
+	return suspended, nil
+}
+
+// readOnlyPost reports whether a POST only reads: GraphQL supports queries
+// alone, so suspended users may still use it.
+func readOnlyPost(r *http.Request) bool {
+	return path.Base(r.URL.Path) == "graphql"
+}
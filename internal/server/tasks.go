@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/scheduler"
+)
+
+// Names of the built-in maintenance tasks, as shown on /api/v1/admin/tasks.
+const (
+	taskSnippetPurge     = "snippet_purge"
+	taskWALCheckpoint    = "wal_checkpoint"
+	taskIdempotencyPurge = "idempotency_purge"
+	taskLeaderboard      = "leaderboard_refresh"
+	taskWeeklyDigest     = "weekly_digest"
+	taskRunPurge         = "run_purge"
+)
+
+// newScheduler registers the built-in maintenance tasks on their configured
+// schedules. A task with an empty schedule (or "off") is not registered.
+func (s *Server) newScheduler() (*scheduler.Scheduler, error) {
+	sched := scheduler.New(s.logger)
+
+	// Anonymous snippets are only purged when a TTL is configured — without
+	// one, deleting data on a timer would be a surprising default.
+	if s.config.AnonymousSnippetTTL > 0 {
+		err := sched.Add(taskSnippetPurge, s.config.SnippetPurgeSchedule, func(ctx context.Context) error {
+			cutoff := time.Now().Add(-s.config.AnonymousSnippetTTL)
+			n, err := s.db.PurgeAnonymousSnippets(ctx, cutoff)
+			if err != nil {
+				return err
+			}
+			s.logger.Info("purged anonymous snippets", slog.Int64("count", n))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := sched.Add(taskWALCheckpoint, s.config.WALCheckpointSchedule, func(ctx context.Context) error {
+		_, err := s.storage.Checkpoint(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Expired keys are already ignored on lookup; this just reclaims the space.
+	if s.config.IdempotencyTTL > 0 {
+		err := sched.Add(taskIdempotencyPurge, s.config.IdempotencyPurgeSchedule, func(ctx context.Context) error {
+			n, err := s.db.PurgeExpiredIdempotencyKeys(ctx)
+			if err != nil {
+				return err
+			}
+			s.logger.Info("purged expired idempotency keys", slog.Int64("count", n))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !s.config.RunRetention.off() || !s.config.AnonymousRunRetention.off() {
+		if err := sched.Add(taskRunPurge, s.config.RunPurgeSchedule, s.purgeRuns); err != nil {
+			return nil, err
+		}
+	}
+
+	// Leaderboards are read from a materialized table; see
+	// sqlite.RefreshLeaderboard for why.
+	err = sched.Add(taskLeaderboard, s.config.LeaderboardSchedule, func(ctx context.Context) error {
+		n, err := s.db.RefreshLeaderboard(ctx)
+		if err != nil {
+			return err
+		}
+		s.logger.Info("refreshed leaderboards", slog.Int("entries", n))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The digest only goes out when there's a way to send it.
+	if s.email != nil {
+		if err := sched.Add(taskWeeklyDigest, s.config.DigestSchedule, s.email.SendDigests); err != nil {
+			return nil, err
+		}
+	}
+
+	return sched, nil
+}
+
+// RunRetention says how much of the run history to keep. A run is deleted
+// once it's older than MaxAge, or once its owner has Keep newer runs. Runs
+// aren't tied to snippets, so Keep counts per user — and for anonymous
+// runs, across all of them together. 0 turns either limit off.
+type RunRetention struct {
+	MaxAge time.Duration
+	Keep   int
+}
+
+func (r RunRetention) off() bool { return r.MaxAge <= 0 && r.Keep <= 0 }
+
+// cutoff returns when runs kept under r must have been created, or the
+// zero time if age doesn't matter.
+func (r RunRetention) cutoff(now time.Time) time.Time {
+	if r.MaxAge <= 0 {
+		return time.Time{}
+	}
+	return now.Add(-r.MaxAge)
+}
+
+// purgeRuns applies both run retention policies, counting what it deletes
+// for the runs_purged_total metrics.
+func (s *Server) purgeRuns(ctx context.Context) error {
+	now := time.Now()
+	for _, p := range []struct {
+		anonymous bool
+		retention RunRetention
+		purged    *atomic.Int64
+	}{
+		{false, s.config.RunRetention, &s.runsPurged},
+		{true, s.config.AnonymousRunRetention, &s.anonymousRunsPurged},
+	} {
+		if p.retention.off() {
+			continue
+		}
+		n, err := s.db.PurgeRuns(ctx, p.anonymous, p.retention.cutoff(now), p.retention.Keep)
+		p.purged.Add(n)
+		if err != nil {
+			return err
+		}
+		s.logger.Info("purged runs", slog.Bool("anonymous", p.anonymous), slog.Int64("count", n))
+	}
+	return nil
+}
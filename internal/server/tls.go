@@ -0,0 +1,125 @@
+package server
+
+// TLS SUPPORT:
+// Small deployments often sit directly on the internet without a reverse proxy
+// (nginx, Caddy, a cloud load balancer) in front. For those, the server can
+// terminate HTTPS itself in one of two ways:
+//
+//  1. CERTIFICATE FILES: TLSCertFile + TLSKeyFile point at a PEM certificate
+//     and private key you obtained elsewhere (certbot, your CA, a self-signed pair).
+//
+//  2. AUTOCERT: AutocertDomains lists the host names we're allowed to request
+//     certificates for. golang.org/x/crypto/acme/autocert talks to Let's Encrypt,
+//     answers the HTTP-01 challenge, caches the certificates on disk and renews
+//     them before they expire — no cron job required.
+//
+// In both modes an optional plain-HTTP listener redirects http:// → https://.
+// Under autocert that listener is mandatory, because Let's Encrypt validates
+// domain ownership by fetching a token over port 80.
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Default values used when autocert is enabled.
+const (
+	DefaultAutocertCacheDir = "data/autocert"
+	DefaultHTTPRedirectPort = 80
+)
+
+// tlsEnabled reports whether the server should serve HTTPS.
+func (c Config) tlsEnabled() bool {
+	return c.autocertEnabled() || c.TLSCertFile != "" || c.TLSKeyFile != ""
+}
+
+// autocertEnabled reports whether certificates should be obtained from Let's Encrypt.
+func (c Config) autocertEnabled() bool {
+	return len(c.AutocertDomains) > 0
+}
+
+// validateTLS checks that the TLS settings are complete and not contradictory,
+// and that the certificate files load. Loading them here turns a missing file
+// or a key that doesn't match its certificate into a startup error, rather than
+// one from the listener after everything else has started.
+func (c Config) validateTLS() error {
+	if c.autocertEnabled() && (c.TLSCertFile != "" || c.TLSKeyFile != "") {
+		return errors.New("autocert domains and TLS certificate files are mutually exclusive")
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return errors.New("both TLS certificate and key files must be set")
+	}
+	if c.TLSCertFile != "" {
+		if _, err := tls.LoadX509KeyPair(c.TLSCertFile, c.TLSKeyFile); err != nil {
+			return fmt.Errorf("loading TLS certificate: %w", err)
+		}
+	}
+	return nil
+}
+
+// configureTLS prepares srv for HTTPS and returns the optional HTTP redirect server.
+//
+// The returned *http.Server is nil when no redirect listener is wanted
+// (certificate-file mode with HTTPRedirectPort = 0).
+func (s *Server) configureTLS(srv *http.Server) *http.Server {
+	redirect := redirectToHTTPS(s.config.Port)
+	redirectPort := s.config.HTTPRedirectPort
+
+	if s.config.autocertEnabled() {
+		cacheDir := s.config.AutocertCacheDir
+		if cacheDir == "" {
+			cacheDir = DefaultAutocertCacheDir
+		}
+
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(s.config.AutocertDomains...),
+			Cache:      autocert.DirCache(cacheDir),
+			Email:      s.config.AutocertEmail,
+		}
+		srv.TLSConfig = manager.TLSConfig()
+
+		// HTTPHandler answers ACME challenges and hands every other request
+		// to our redirect handler.
+		redirect = manager.HTTPHandler(redirect)
+		if redirectPort == 0 {
+			redirectPort = DefaultHTTPRedirectPort
+		}
+	} else {
+		srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	if redirectPort == 0 {
+		return nil
+	}
+
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", redirectPort),
+		Handler:           redirect,
+		ReadHeaderTimeout: srv.ReadTimeout,
+		IdleTimeout:       srv.IdleTimeout,
+	}
+}
+
+// redirectToHTTPS returns a handler that permanently redirects to the HTTPS listener.
+// The port is only included in the target URL when it isn't the default 443.
+func redirectToHTTPS(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeKeyPair writes a self-signed certificate and its key as PEM files in
+// dir, returning their paths.
+func writeKeyPair(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestConfig_ValidateTLS(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := writeKeyPair(t, dir, "a.example")
+	_, otherKey := writeKeyPair(t, dir, "b.example")
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string // "" for valid
+	}{
+		{"no TLS", Config{}, ""},
+		{"certificate files", Config{TLSCertFile: cert, TLSKeyFile: key}, ""},
+		{"autocert", Config{AutocertDomains: []string{"a.example"}}, ""},
+		{"certificate without key", Config{TLSCertFile: cert}, "both TLS certificate and key files"},
+		{"key without certificate", Config{TLSKeyFile: key}, "both TLS certificate and key files"},
+		{"autocert and files", Config{AutocertDomains: []string{"a.example"}, TLSCertFile: cert, TLSKeyFile: key}, "mutually exclusive"},
+		{"missing certificate file", Config{TLSCertFile: filepath.Join(dir, "nope.crt"), TLSKeyFile: key}, "no such file"},
+		{"missing key file", Config{TLSCertFile: cert, TLSKeyFile: filepath.Join(dir, "nope.key")}, "no such file"},
+		{"key doesn't match certificate", Config{TLSCertFile: cert, TLSKeyFile: otherKey}, "does not match"},
+		{"key file is the certificate", Config{TLSCertFile: cert, TLSKeyFile: cert}, "loading TLS certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validateTLS()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("validateTLS() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("validateTLS() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		name      string
+		httpsPort int
+		url       string
+		want      string
+	}{
+		{"default port", 443, "http://example.com/s/abc?theme=dark&x=1", "https://example.com/s/abc?theme=dark&x=1"},
+		{"other port", 8443, "http://example.com/api/v1/snippets?limit=5", "https://example.com:8443/api/v1/snippets?limit=5"},
+		{"request on a port", 443, "http://example.com:80/feed.atom", "https://example.com/feed.atom"},
+		{"IPv6 host", 8443, "http://[::1]:8080/", "https://[::1]:8443/"},
+		{"escaped path", 443, "http://example.com/users/a%2Fb/feed.atom", "https://example.com/users/a%2Fb/feed.atom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			redirectToHTTPS(tt.httpsPort).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if rr.Code != http.StatusMovedPermanently {
+				t.Errorf("status = %d, want %d", rr.Code, http.StatusMovedPermanently)
+			}
+			if got := rr.Header().Get("Location"); got != tt.want {
+				t.Errorf("Location = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
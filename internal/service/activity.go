@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// ACTIVITY FEED:
+// The feed shows what people are doing with public snippets:
+//
+//	snippet.published → "ann published “loop”"
+//	snippet.forked    → "bob forked “loop”"   (only forks of public snippets)
+//	snippet.starred   → "bob starred “loop”"  (only stars on public snippets)
+//
+// ActivityService is an EventPublisher: each event becomes a row in the
+// activity_events table as it happens. Private snippets never make it in,
+// and when a snippet is taken back down its activity drops out of the feed,
+// since the feed only lists activity on snippets that are public now.
+//
+// CURSOR PAGINATION:
+// The feed grows at the top while people read it, so offsets would repeat
+// or skip entries. Each page instead ends with a cursor naming the last
+// entry, and the next page starts just after it:
+//
+//	GET /api/v1/feed                 → {"items": [...], "nextCursor": "YmVmb3JlOjQy"}
+//	GET /api/v1/feed?cursor=YmVmb3JlOjQy → the next, older page
+//
+// The last page has no nextCursor. Clients must not parse cursors.
+
+const (
+	DefaultActivityLimit = 20
+	MaxActivityLimit     = 100
+)
+
+// ActivityPage is one page of the activity feed.
+type ActivityPage struct {
+	Items      []model.Activity `json:"items"`
+	NextCursor string           `json:"nextCursor,omitempty"`
+}
+
+// ActivityService records public activity and serves the feed.
+type ActivityService struct {
+	repo     repository.ActivityRepository
+	snippets repository.SnippetRepository
+	users    repository.UserRepository
+	logger   *slog.Logger
+}
+
+// NewActivityService creates an ActivityService.
+func NewActivityService(repo repository.ActivityRepository, snippets repository.SnippetRepository, users repository.UserRepository, logger *slog.Logger) *ActivityService {
+	return &ActivityService{
+		repo:     repo,
+		snippets: snippets,
+		users:    users,
+		logger:   logger,
+	}
+}
+
+// Feed returns a page of everyone's activity, starting after cursor ("" for
+// the newest). limit is clamped like notification limits.
+func (s *ActivityService) Feed(ctx context.Context, cursor string, limit int) (*ActivityPage, error) {
+	return s.page(ctx, "", cursor, limit)
+}
+
+// ForUser returns a page of the activity of the user with this login.
+func (s *ActivityService) ForUser(ctx context.Context, login, cursor string, limit int) (*ActivityPage, error) {
+	user, err := s.users.GetUserByLogin(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, apperror.NotFound("user", login)
+	}
+	return s.page(ctx, user.ID, cursor, limit)
+}
+
+func (s *ActivityService) page(ctx context.Context, actorID, cursor string, limit int) (*ActivityPage, error) {
+	var before int64
+	if cursor != "" {
+		var err error
+		if before, err = decodeActivityCursor(cursor); err != nil {
+			return nil, err
+		}
+	}
+	if limit <= 0 {
+		limit = DefaultActivityLimit
+	}
+	limit = min(limit, MaxActivityLimit)
+
+	// One more than asked for says whether there's another page.
+	items, err := s.repo.ListActivity(ctx, actorID, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &ActivityPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.NextCursor = encodeActivityCursor(page.Items[limit-1].ID)
+	}
+	return page, nil
+}
+
+// Publish records activity on public snippets. Like every EventPublisher,
+// it never fails the caller: problems are logged.
+func (s *ActivityService) Publish(ctx context.Context, event string, data any) {
+	ctx = context.WithoutCancel(ctx)
+	a, err := s.build(ctx, event, data)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to build activity",
+			slog.String("event", event),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	if a == nil {
+		return
+	}
+	if err := s.repo.CreateActivity(ctx, a); err != nil {
+		s.logger.ErrorContext(ctx, "failed to save activity",
+			slog.String("event", event),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// build works out the activity for an event, or nil when it isn't public.
+func (s *ActivityService) build(ctx context.Context, event string, data any) (*model.Activity, error) {
+	snippet, ok := data.(*model.Snippet)
+	if !ok {
+		return nil, nil
+	}
+	switch event {
+	case model.EventSnippetPublished:
+		if !snippet.Public || snippet.UserID == "" {
+			return nil, nil
+		}
+		return &model.Activity{Kind: model.ActivityPublished, ActorID: snippet.UserID, SnippetID: snippet.ID}, nil
+
+	case model.EventSnippetForked:
+		// The fork itself starts out private; the activity is about the
+		// snippet it was forked from.
+		original, err := s.snippets.GetByID(ctx, snippet.ForkedFrom)
+		if err != nil || !original.Public || snippet.UserID == "" {
+			return nil, err
+		}
+		return &model.Activity{Kind: model.ActivityFork, ActorID: snippet.UserID, SnippetID: original.ID}, nil
+
+	case model.EventSnippetStarred:
+		actorID, _ := auth.UserIDFromContext(ctx)
+		if !snippet.Public || actorID == "" {
+			return nil, nil
+		}
+		return &model.Activity{Kind: model.ActivityStar, ActorID: actorID, SnippetID: snippet.ID}, nil
+	}
+	return nil, nil
+}
+
+// encodeActivityCursor makes the cursor for the page after the activity with this ID.
+func encodeActivityCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("before:" + strconv.FormatInt(id, 10)))
+}
+
+// decodeActivityCursor reverses encodeActivityCursor.
+func decodeActivityCursor(cursor string) (int64, error) {
+	invalid := apperror.ValidationFailed("cursor", "cursor is not a valid cursor")
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, invalid
+	}
+	id, ok := strings.CutPrefix(string(raw), "before:")
+	if !ok {
+		return 0, invalid
+	}
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, invalid
+	}
+	return n, nil
+}
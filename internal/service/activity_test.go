@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository/sqlite"
+)
+
+func TestActivityService(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	activity := NewActivityService(db, db, db, logger)
+	snippets := NewSnippetService(db, logger)
+	snippets.PublishEvents(activity)
+	stars := NewStarService(snippets, db, logger)
+	stars.PublishEvents(activity)
+
+	for i, login := range []string{"ann", "bob"} {
+		if err := db.Upsert(context.Background(), &model.User{ID: login + "-id", GitHubID: int64(i + 1), Login: login}); err != nil {
+			t.Fatalf("Upsert: %v", err)
+		}
+	}
+	ctx := context.Background()
+	asAnn := auth.WithUserID(ctx, "ann-id")
+	asBob := auth.WithUserID(ctx, "bob-id")
+
+	// Nothing that happens to a private snippet is public activity.
+	private, _ := snippets.Create(asAnn, "secret", "print(0)", "")
+	stars.Star(asBob, "bob-id", private.ID)
+	snippets.Fork(asBob, private.ID)
+
+	loop, _ := snippets.Create(asAnn, "loop", "print(1)", "")
+	if _, err := snippets.SetPublic(asAnn, loop.ID, true); err != nil {
+		t.Fatalf("SetPublic() error = %v", err)
+	}
+	stars.Star(asBob, "bob-id", loop.ID)
+	snippets.Fork(asBob, loop.ID)
+	hello, _ := snippets.Create(asBob, "hello", "print(2)", "")
+	snippets.SetPublic(asBob, hello.ID, true)
+
+	type entry struct{ kind, actor, snippet string }
+	collect := func(fetch func(cursor string) (*ActivityPage, error)) []entry {
+		t.Helper()
+		var got []entry
+		cursor := ""
+		for range 10 {
+			page, err := fetch(cursor)
+			if err != nil {
+				t.Fatalf("fetching page: %v", err)
+			}
+			if len(page.Items) > 2 {
+				t.Fatalf("page has %d items, want at most 2", len(page.Items))
+			}
+			for _, a := range page.Items {
+				got = append(got, entry{a.Kind, a.ActorLogin, a.SnippetName})
+			}
+			if cursor = page.NextCursor; cursor == "" {
+				return got
+			}
+		}
+		t.Fatal("feed never ended")
+		return nil
+	}
+	equal := func(name string, got, want []entry) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("%s = %v, want %v", name, got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s[%d] = %v, want %v", name, i, got[i], want[i])
+			}
+		}
+	}
+
+	feed := func(cursor string) (*ActivityPage, error) { return activity.Feed(ctx, cursor, 2) }
+	equal("Feed", collect(feed), []entry{
+		{model.ActivityPublished, "bob", "hello"},
+		{model.ActivityFork, "bob", "loop"},
+		{model.ActivityStar, "bob", "loop"},
+		{model.ActivityPublished, "ann", "loop"},
+	})
+	bobs := func(cursor string) (*ActivityPage, error) { return activity.ForUser(ctx, "bob", cursor, 2) }
+	equal("ForUser(bob)", collect(bobs), []entry{
+		{model.ActivityPublished, "bob", "hello"},
+		{model.ActivityFork, "bob", "loop"},
+		{model.ActivityStar, "bob", "loop"},
+	})
+
+	// Taking a snippet down takes its activity out of the feed.
+	snippets.SetPublic(asAnn, loop.ID, false)
+	equal("Feed after unpublishing", collect(feed), []entry{{model.ActivityPublished, "bob", "hello"}})
+
+	if _, err := activity.ForUser(ctx, "nobody", "", 0); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("ForUser(unknown) error = %v, want ErrNotFound", err)
+	}
+	for _, cursor := range []string{"!!", "b2Zmc2V0OjE", encodeActivityCursor(0)} {
+		if _, err := activity.Feed(ctx, cursor, 0); !errors.Is(err, apperror.ErrValidation) {
+			t.Errorf("Feed(cursor %q) error = %v, want ErrValidation", cursor, err)
+		}
+	}
+}
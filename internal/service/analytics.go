@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+
+	"github.com/sakif/coding-playground/internal/analytics"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/executor"
+	"github.com/sakif/coding-playground/internal/model"
+)
+
+// PRODUCT ANALYTICS:
+// The services already announce what happens through EventPublisher, so
+// analytics listen there instead of each handler recording its own events:
+//
+//	snippet.created     → snippet_created {snippet_id, code_bytes}
+//	execution.completed → execution_run   {exit_code, duration_ms}
+//	user.logged_in      → login           {method: "github" | "email"}
+//	execution.flagged   → execution_flagged {source, rules, blocked}
+//
+// Code, output and names never go into analytics — sizes and outcomes only.
+
+// AnalyticsPublisher is an EventPublisher that records product analytics.
+type AnalyticsPublisher struct {
+	recorder *analytics.Recorder
+}
+
+// NewAnalyticsPublisher creates an AnalyticsPublisher that records to recorder.
+func NewAnalyticsPublisher(recorder *analytics.Recorder) *AnalyticsPublisher {
+	return &AnalyticsPublisher{recorder: recorder}
+}
+
+// Publish records the analytics event for event, if it has one. Recording
+// never blocks, so this is cheap to call on every request.
+func (p *AnalyticsPublisher) Publish(ctx context.Context, event string, data any) {
+	userID, _ := auth.UserIDFromContext(ctx)
+	switch event {
+	case model.EventSnippetCreated:
+		snippet, ok := data.(*model.Snippet)
+		if !ok {
+			return
+		}
+		p.recorder.Record(analytics.EventSnippetCreated, snippet.UserID, map[string]any{
+			"snippet_id": snippet.ID,
+			"code_bytes": len(snippet.Code),
+		})
+
+	case model.EventExecutionCompleted:
+		result, ok := data.(*executor.ExecutionResult)
+		if !ok {
+			return
+		}
+		p.recorder.Record(analytics.EventExecutionRun, userID, map[string]any{
+			"exit_code":   result.ExitCode,
+			"duration_ms": result.Duration.Milliseconds(),
+		})
+
+	case model.EventUserLoggedIn:
+		login, ok := data.(*model.Login)
+		if !ok {
+			return
+		}
+		p.recorder.Record(analytics.EventLogin, login.UserID, map[string]any{"method": login.Method})
+
+	case model.EventExecutionFlagged:
+		report, ok := data.(*model.AbuseReport)
+		if !ok {
+			return
+		}
+		rules := make([]string, len(report.Findings))
+		for i, f := range report.Findings {
+			rules[i] = f.Rule
+		}
+		p.recorder.Record(analytics.EventExecutionFlagged, report.UserID, map[string]any{
+			"source":  report.Source,
+			"rules":   rules,
+			"blocked": report.Blocked,
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/blob"
+	"github.com/sakif/coding-playground/internal/executor"
+)
+
+// ARTIFACTS:
+// A run asked to keep its files ({"artifacts": true} on /execute) comes
+// back from the executor with their contents (executor.Artifact.Data).
+// ArtifactService moves them into the blob store, under
+//
+//	artifacts/<run>/<name>
+//
+// and swaps each one's contents for a URL to fetch it from, so a response
+// never carries a plot inline and the database never holds one.
+//
+// <run> is 128 random bits. There's no owner check on fetching an
+// artifact: like an unlisted video, knowing the link is the permission.
+
+// ArtifactService stores and serves execution artifacts.
+type ArtifactService struct {
+	store  blob.Store
+	logger *slog.Logger
+}
+
+// NewArtifactService creates an ArtifactService keeping artifacts in store.
+func NewArtifactService(store blob.Store, logger *slog.Logger) *ArtifactService {
+	return &ArtifactService{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// Save stores result's artifacts and points their URLs at them. Artifacts
+// that fail to store are left out of result, and the first failure returned.
+func (s *ArtifactService) Save(ctx context.Context, result *executor.ExecutionResult) error {
+	if len(result.Artifacts) == 0 {
+		return nil
+	}
+	run := make([]byte, 16)
+	rand.Read(run)
+	runID := hex.EncodeToString(run)
+
+	var firstErr error
+	saved := result.Artifacts[:0]
+	for _, a := range result.Artifacts {
+		name := artifactName(a.Name)
+		key := "artifacts/" + runID + "/" + name
+		if err := s.store.Put(ctx, key, bytes.NewReader(a.Data), int64(len(a.Data))); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("storing artifact %s: %w", name, err)
+			}
+			continue
+		}
+		a.Name, a.Data = name, nil
+		a.URL = "/api/v1/artifacts/" + runID + "/" + name
+		saved = append(saved, a)
+	}
+	result.Artifacts = saved
+
+	s.logger.InfoContext(ctx, "artifacts saved",
+		slog.String("run", runID),
+		slog.Int("count", len(saved)),
+	)
+	return firstErr
+}
+
+// Open returns an artifact's contents, which the caller closes.
+func (s *ArtifactService) Open(ctx context.Context, runID, name string) (io.ReadCloser, blob.Info, error) {
+	rc, info, err := s.store.Get(ctx, "artifacts/"+runID+"/"+name)
+	if errors.Is(err, blob.ErrNotFound) || errors.Is(err, blob.ErrInvalidKey) {
+		return nil, blob.Info{}, apperror.NotFound("artifact", runID+"/"+name)
+	}
+	if err != nil {
+		return nil, blob.Info{}, fmt.Errorf("opening artifact: %w", err)
+	}
+	return rc, info, nil
+}
+
+// artifactName makes a file name safe as the last part of a blob key:
+// anything but letters, digits, '.', '-' and '_' becomes '_', and a name
+// of only dots gets a prefix.
+func artifactName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	if strings.Trim(name, ".") == "" {
+		name = "artifact" + name
+	}
+	return name
+}
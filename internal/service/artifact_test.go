@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/blob"
+	"github.com/sakif/coding-playground/internal/executor"
+)
+
+// failingStore is a blob.Store that refuses one key.
+type failingStore struct {
+	blob.Store
+	refuse string
+}
+
+func (s failingStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	if strings.HasSuffix(key, "/"+s.refuse) {
+		return errors.New("disk full")
+	}
+	return s.Store.Put(ctx, key, r, size)
+}
+
+func TestArtifactService(t *testing.T) {
+	fs, err := blob.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFS() error = %v", err)
+	}
+	artifacts := NewArtifactService(failingStore{Store: fs, refuse: "big.csv"}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	result := &executor.ExecutionResult{Artifacts: []executor.Artifact{
+		{Name: "my plot (1).png", Data: []byte("png")},
+		{Name: "big.csv", Data: []byte("a,b")},
+		{Name: "..", Data: []byte("dots")},
+	}}
+	if err := artifacts.Save(ctx, result); err == nil || !strings.Contains(err.Error(), "big.csv") {
+		t.Errorf("Save() error = %v, want big.csv's failure", err)
+	}
+	if len(result.Artifacts) != 2 {
+		t.Fatalf("Artifacts = %+v, want the two that were stored", result.Artifacts)
+	}
+	if a := result.Artifacts[0]; a.Name != "my_plot__1_.png" || a.Data != nil || !strings.HasSuffix(a.URL, "/my_plot__1_.png") {
+		t.Errorf("first artifact = %+v, want a safe name, a URL and no data", a)
+	}
+	if name := result.Artifacts[1].Name; name != "artifact.." {
+		t.Errorf("dots-only name became %q", name)
+	}
+
+	parts := strings.Split(result.Artifacts[0].URL, "/")
+	runID, name := parts[len(parts)-2], parts[len(parts)-1]
+	rc, info, err := artifacts.Open(ctx, runID, name)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "png" || info.ContentType != "image/png" {
+		t.Errorf("Open() = %q (%s), want the stored png", data, info.ContentType)
+	}
+	for _, name := range []string{"missing.png", "../../etc/passwd"} {
+		if _, _, err := artifacts.Open(ctx, runID, name); !errors.Is(err, apperror.ErrNotFound) {
+			t.Errorf("Open(%q) error = %v, want not found", name, err)
+		}
+	}
+}
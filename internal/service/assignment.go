@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// ASSIGNMENTS:
+// A teacher sets an exercise for a class, optionally with a window:
+//
+//	opensAt  submissions before this are refused
+//	dueAt    submissions after this are late, and the late policy decides:
+//	         accept   → accepted, marked late
+//	         penalize → accepted, marked late, score cut by latePenalty percent
+//	         reject   → refused: the assignment is closed
+//
+// Students submit to the assignment (not the bare exercise), so each
+// submission records which assignment it was for and whether it was late.
+// Teachers get a per-student completion summary (Progress).
+
+// AssignmentInput is what a teacher sets. On update, ExerciseID is ignored:
+// an assignment's exercise never changes.
+type AssignmentInput struct {
+	ExerciseID  string
+	Title       string // defaults to the exercise's title
+	OpensAt     *time.Time
+	DueAt       *time.Time
+	LatePolicy  string // defaults to model.LateAccept
+	LatePenalty int    // percent, for model.LatePenalize
+}
+
+// validate checks the window and late policy, filling in defaults.
+func (in *AssignmentInput) validate() error {
+	var verrs apperror.ValidationErrors
+	in.Title = strings.TrimSpace(in.Title)
+	if len(in.Title) > MaxExerciseTitleLength {
+		verrs.Add("title", fmt.Sprintf("title must be %d characters or less", MaxExerciseTitleLength))
+	}
+	if in.OpensAt != nil && in.DueAt != nil && !in.DueAt.After(*in.OpensAt) {
+		verrs.Add("dueAt", "dueAt must be later than opensAt")
+	}
+	if in.LatePolicy == "" {
+		in.LatePolicy = model.LateAccept
+	}
+	if !slices.Contains(model.LatePolicies, in.LatePolicy) {
+		verrs.Add("latePolicy", fmt.Sprintf("latePolicy must be one of %s", strings.Join(model.LatePolicies, ", ")))
+	}
+	switch {
+	case in.LatePolicy == model.LatePenalize && (in.LatePenalty < 1 || in.LatePenalty > 100):
+		verrs.Add("latePenalty", "latePenalty must be between 1 and 100 percent")
+	case in.LatePolicy != model.LatePenalize && in.LatePenalty != 0:
+		verrs.Add("latePenalty", "latePenalty only applies to the penalize policy")
+	}
+	if in.OpensAt != nil {
+		utc := in.OpensAt.UTC()
+		in.OpensAt = &utc
+	}
+	if in.DueAt != nil {
+		utc := in.DueAt.UTC()
+		in.DueAt = &utc
+	}
+	return verrs.Err()
+}
+
+// CreateAssignment sets an exercise for a class. Teachers only.
+func (s *ClassService) CreateAssignment(ctx context.Context, userID, classID string, in AssignmentInput) (*model.Assignment, error) {
+	if _, err := s.teacher(ctx, userID, classID); err != nil {
+		return nil, err
+	}
+	if err := in.validate(); err != nil {
+		return nil, err
+	}
+	exercise, err := s.exercises.GetExercise(ctx, strings.TrimSpace(in.ExerciseID))
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, apperror.ValidationFailed("exerciseId", "no exercise has that ID")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if in.Title == "" {
+		in.Title = exercise.Title
+	}
+
+	assignment := &model.Assignment{
+		ClassID:     classID,
+		ExerciseID:  exercise.ID,
+		Title:       in.Title,
+		OpensAt:     in.OpensAt,
+		DueAt:       in.DueAt,
+		LatePolicy:  in.LatePolicy,
+		LatePenalty: in.LatePenalty,
+		CreatedBy:   userID,
+	}
+	if err := s.classes.CreateAssignment(ctx, assignment); err != nil {
+		return nil, fmt.Errorf("creating assignment: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "assignment created",
+		slog.String("id", assignment.ID),
+		slog.String("class_id", classID),
+		slog.String("exercise_id", exercise.ID),
+	)
+	return assignment, nil
+}
+
+// UpdateAssignment changes an assignment's title, window or late policy —
+// e.g. to extend a deadline. Teachers only. Submissions already made keep
+// their late flag and score.
+func (s *ClassService) UpdateAssignment(ctx context.Context, userID, classID, assignmentID string, in AssignmentInput) (*model.Assignment, error) {
+	if _, err := s.teacher(ctx, userID, classID); err != nil {
+		return nil, err
+	}
+	assignment, err := s.assignment(ctx, classID, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := in.validate(); err != nil {
+		return nil, err
+	}
+
+	if in.Title != "" {
+		assignment.Title = in.Title
+	}
+	assignment.OpensAt = in.OpensAt
+	assignment.DueAt = in.DueAt
+	assignment.LatePolicy = in.LatePolicy
+	assignment.LatePenalty = in.LatePenalty
+	if err := s.classes.UpdateAssignment(ctx, assignment); err != nil {
+		return nil, fmt.Errorf("updating assignment: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "assignment updated",
+		slog.String("id", assignment.ID),
+		slog.String("class_id", classID),
+	)
+	return assignment, nil
+}
+
+// Assignments lists a class's assignments. Members only.
+func (s *ClassService) Assignments(ctx context.Context, userID, classID string) ([]model.Assignment, error) {
+	if _, _, err := s.member(ctx, userID, classID); err != nil {
+		return nil, err
+	}
+	return s.classes.ListAssignments(ctx, classID)
+}
+
+// Submit grades userID's code for an assignment, enforcing its window and
+// late policy. Members only.
+func (s *ClassService) Submit(ctx context.Context, userID, classID, assignmentID, code string) (*model.Submission, error) {
+	if s.grading == nil {
+		return nil, errors.New("grading is unavailable: no executor")
+	}
+	if err := validateSubmissionCode(code); err != nil {
+		return nil, err
+	}
+	if _, _, err := s.member(ctx, userID, classID); err != nil {
+		return nil, err
+	}
+	assignment, err := s.assignment(ctx, classID, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	late, err := submissionWindow(assignment, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	exercise, err := s.exercises.GetExercise(ctx, assignment.ExerciseID)
+	if err != nil {
+		return nil, err
+	}
+
+	penalty := 0
+	if assignment.LatePolicy == model.LatePenalize {
+		penalty = assignment.LatePenalty
+	}
+	sub := &model.Submission{AssignmentID: assignment.ID, UserID: userID, Code: code, Late: late}
+	return s.grading.submit(ctx, sub, exercise, penalty)
+}
+
+// submissionWindow reports whether a submission at now is late, or refuses
+// it if the assignment isn't open.
+func submissionWindow(a *model.Assignment, now time.Time) (late bool, err error) {
+	if a.OpensAt != nil && now.Before(*a.OpensAt) {
+		return false, &apperror.AppError{
+			Err:     apperror.ErrForbidden,
+			Message: fmt.Sprintf("this assignment opens at %s", a.OpensAt.UTC().Format(time.RFC3339)),
+		}
+	}
+	late = a.DueAt != nil && now.After(*a.DueAt)
+	if late && a.LatePolicy == model.LateReject {
+		return false, &apperror.AppError{
+			Err:     apperror.ErrForbidden,
+			Message: fmt.Sprintf("this assignment closed at %s", a.DueAt.UTC().Format(time.RFC3339)),
+		}
+	}
+	return late, nil
+}
+
+// AssignmentSubmissions returns the submissions made to an assignment:
+// every student's for a teacher, only their own for a student.
+func (s *ClassService) AssignmentSubmissions(ctx context.Context, userID, classID, assignmentID string) ([]model.Submission, error) {
+	_, member, err := s.member(ctx, userID, classID)
+	if err != nil {
+		return nil, err
+	}
+	assignment, err := s.assignment(ctx, classID, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := repository.SubmissionFilter{AssignmentID: assignment.ID, ClassID: classID}
+	if member.Role != model.ClassTeacher {
+		filter.UserID = userID
+	}
+	return s.submissions.ListSubmissions(ctx, filter)
+}
+
+// Progress summarises each student's completion of an assignment. Teachers only.
+func (s *ClassService) Progress(ctx context.Context, userID, classID, assignmentID string) ([]model.AssignmentProgress, error) {
+	if _, err := s.teacher(ctx, userID, classID); err != nil {
+		return nil, err
+	}
+	assignment, err := s.assignment(ctx, classID, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.classes.ListClassMembers(ctx, classID)
+	if err != nil {
+		return nil, err
+	}
+	submissions, err := s.submissions.ListSubmissions(ctx, repository.SubmissionFilter{AssignmentID: assignment.ID, ClassID: classID})
+	if err != nil {
+		return nil, err
+	}
+	return assignmentProgress(assignment, members, submissions, time.Now()), nil
+}
+
+// assignmentProgress works out each student's completion status from their
+// submissions (newest first, as ListSubmissions returns them).
+func assignmentProgress(a *model.Assignment, members []model.ClassMember, submissions []model.Submission, now time.Time) []model.AssignmentProgress {
+	byUser := make(map[string][]model.Submission)
+	for _, sub := range submissions {
+		byUser[sub.UserID] = append(byUser[sub.UserID], sub)
+	}
+
+	progress := []model.AssignmentProgress{}
+	for _, m := range members {
+		if m.Role != model.ClassStudent {
+			continue
+		}
+		p := model.AssignmentProgress{UserID: m.UserID, Login: m.Login}
+		subs := byUser[m.UserID]
+		p.Submissions = len(subs)
+		if len(subs) > 0 {
+			p.LastSubmittedAt = &subs[0].CreatedAt
+		}
+
+		passedOnTime, passedLate := false, false
+		for _, sub := range subs {
+			p.BestScore = max(p.BestScore, sub.Score)
+			if sub.Status == model.SubmissionPassed {
+				passedOnTime = passedOnTime || !sub.Late
+				passedLate = passedLate || sub.Late
+			}
+		}
+		switch {
+		case passedOnTime:
+			p.Status = model.CompletionCompleted
+		case passedLate:
+			p.Status = model.CompletionCompletedLate
+		case len(subs) > 0:
+			p.Status = model.CompletionAttempted
+		case a.DueAt != nil && now.After(*a.DueAt):
+			p.Status = model.CompletionMissing
+		default:
+			p.Status = model.CompletionNotStarted
+		}
+		progress = append(progress, p)
+	}
+	return progress
+}
+
+// assignment returns one of a class's assignments.
+func (s *ClassService) assignment(ctx context.Context, classID, assignmentID string) (*model.Assignment, error) {
+	assignment, err := s.classes.GetAssignment(ctx, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	if assignment.ClassID != classID {
+		return nil, apperror.NotFound("assignment", assignmentID)
+	}
+	return assignment, nil
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+)
+
+func TestAssignmentInput_Validate(t *testing.T) {
+	opens := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	due := opens.Add(7 * 24 * time.Hour)
+
+	tests := []struct {
+		name      string
+		in        AssignmentInput
+		wantField string // "" means valid
+	}{
+		{"no window", AssignmentInput{}, ""},
+		{"window", AssignmentInput{OpensAt: &opens, DueAt: &due}, ""},
+		{"due before open", AssignmentInput{OpensAt: &due, DueAt: &opens}, "dueAt"},
+		{"penalize", AssignmentInput{DueAt: &due, LatePolicy: model.LatePenalize, LatePenalty: 25}, ""},
+		{"penalize without penalty", AssignmentInput{LatePolicy: model.LatePenalize}, "latePenalty"},
+		{"penalty over 100", AssignmentInput{LatePolicy: model.LatePenalize, LatePenalty: 101}, "latePenalty"},
+		{"penalty without penalize", AssignmentInput{LatePolicy: model.LateReject, LatePenalty: 10}, "latePenalty"},
+		{"unknown policy", AssignmentInput{LatePolicy: "forgive"}, "latePolicy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.in.validate()
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("validate() error = %v", err)
+				}
+				if tt.in.LatePolicy == "" {
+					t.Error("validate() left LatePolicy empty, want the default")
+				}
+				return
+			}
+			var verrs *apperror.ValidationErrors
+			if !errors.As(err, &verrs) || len(verrs.Fields) != 1 || verrs.Fields[0].Field != tt.wantField {
+				t.Errorf("validate() error = %v, want one error on %s", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestSubmissionWindow(t *testing.T) {
+	opens := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	due := opens.Add(7 * 24 * time.Hour)
+	before, during, after := opens.Add(-time.Minute), opens.Add(time.Hour), due.Add(time.Minute)
+
+	tests := []struct {
+		name      string
+		policy    string
+		now       time.Time
+		wantLate  bool
+		wantError bool
+	}{
+		{"before opening", model.LateAccept, before, false, true},
+		{"on time", model.LateAccept, during, false, false},
+		{"late, accepted", model.LateAccept, after, true, false},
+		{"late, penalized", model.LatePenalize, after, true, false},
+		{"late, rejected", model.LateReject, after, false, true},
+		{"on time, reject policy", model.LateReject, during, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &model.Assignment{OpensAt: &opens, DueAt: &due, LatePolicy: tt.policy}
+			late, err := submissionWindow(a, tt.now)
+			if tt.wantError {
+				if !errors.Is(err, apperror.ErrForbidden) {
+					t.Errorf("submissionWindow() error = %v, want ErrForbidden", err)
+				}
+				return
+			}
+			if err != nil || late != tt.wantLate {
+				t.Errorf("submissionWindow() = %v, %v; want %v, nil", late, err, tt.wantLate)
+			}
+		})
+	}
+
+	if late, err := submissionWindow(&model.Assignment{LatePolicy: model.LateReject}, after); late || err != nil {
+		t.Errorf("no window: submissionWindow() = %v, %v; want always open", late, err)
+	}
+}
+
+func TestAssignmentProgress(t *testing.T) {
+	due := time.Date(2025, 3, 8, 17, 0, 0, 0, time.UTC)
+	a := &model.Assignment{ID: "a1", DueAt: &due}
+	members := []model.ClassMember{
+		{UserID: "teach", Role: model.ClassTeacher},
+		{UserID: "ontime", Role: model.ClassStudent},
+		{UserID: "late", Role: model.ClassStudent},
+		{UserID: "trying", Role: model.ClassStudent},
+		{UserID: "idle", Role: model.ClassStudent},
+	}
+	// Newest first, like ListSubmissions.
+	submissions := []model.Submission{
+		{UserID: "ontime", Status: model.SubmissionFailed, Score: 50, Late: true},
+		{UserID: "late", Status: model.SubmissionPassed, Score: 100, Late: true},
+		{UserID: "trying", Status: model.SubmissionFailed, Score: 50},
+		{UserID: "ontime", Status: model.SubmissionPassed, Score: 100},
+		{UserID: "trying", Status: model.SubmissionError},
+	}
+
+	tests := []struct {
+		now  time.Time
+		want map[string]string
+	}{
+		{due.Add(-time.Hour), map[string]string{
+			"ontime": model.CompletionCompleted, "late": model.CompletionCompletedLate,
+			"trying": model.CompletionAttempted, "idle": model.CompletionNotStarted,
+		}},
+		{due.Add(time.Hour), map[string]string{
+			"ontime": model.CompletionCompleted, "late": model.CompletionCompletedLate,
+			"trying": model.CompletionAttempted, "idle": model.CompletionMissing,
+		}},
+	}
+	for _, tt := range tests {
+		progress := assignmentProgress(a, members, submissions, tt.now)
+		if len(progress) != len(tt.want) {
+			t.Fatalf("assignmentProgress() = %d entries, want %d (students only)", len(progress), len(tt.want))
+		}
+		for _, p := range progress {
+			if p.Status != tt.want[p.UserID] {
+				t.Errorf("at %s: %s status = %q, want %q", tt.now.Format(time.Kitchen), p.UserID, p.Status, tt.want[p.UserID])
+			}
+		}
+	}
+
+	progress := assignmentProgress(a, members, submissions, due)
+	if p := progress[2]; p.UserID != "trying" || p.Submissions != 2 || p.BestScore != 50 {
+		t.Errorf("trying = %+v, want 2 submissions with a best score of 50", p)
+	}
+}
@@ -28,6 +28,7 @@ type AuthService struct {
 	github *auth.GitHubProvider
 	tokens *auth.TokenService
 	logger *slog.Logger
+	events EventPublisher // optional; see PublishEvents
 }
 
 // NewAuthService creates an AuthService.
@@ -45,6 +46,12 @@ func NewAuthService(
 	}
 }
 
+// PublishEvents makes the service report user.logged_in to p. Call it
+// before serving requests.
+func (s *AuthService) PublishEvents(p EventPublisher) {
+	s.events = p
+}
+
 // LoginResult holds the JWT token and user profile after a successful login.
 type LoginResult struct {
 	Token string
@@ -66,7 +73,7 @@ func (s *AuthService) LoginOrRegisterGitHub(ctx context.Context, code string) (*
 		return nil, fmt.Errorf("github get user: %w", err)
 	}
 
-	s.logger.Info("GitHub user authenticated",
+	s.logger.InfoContext(ctx, "GitHub user authenticated",
 		slog.String("login", ghUser.Login),
 		slog.Int64("github_id", ghUser.ID),
 	)
@@ -90,6 +97,9 @@ func (s *AuthService) LoginOrRegisterGitHub(ctx context.Context, code string) (*
 		return nil, fmt.Errorf("generate token: %w", err)
 	}
 
+	if s.events != nil {
+		s.events.Publish(ctx, model.EventUserLoggedIn, &model.Login{UserID: user.ID, Method: model.LoginGitHub})
+	}
 	return &LoginResult{Token: token, User: user}, nil
 }
 
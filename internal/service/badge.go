@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/jobs"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// BADGES:
+// Badges (model.Badges) reward milestones, and each is earned by reacting
+// to an event:
+//
+//	snippet.created     → first-snippet, for the creator
+//	execution.completed → hundred-runs, once the runner's 100th run is counted
+//	challenge.solved    → streak-10, when the solver's streak reaches 10 days
+//	snippet.forked      → first-fork, for the owner of the snippet forked
+//
+// BadgeService is an EventPublisher, so it hears about these the way
+// webhooks do. Publish only queues a "badge.check" job; the job queue runs
+// the check later, so the request that caused the event never waits on it.
+// Awarding is idempotent: a badge earned twice is still earned once.
+
+const (
+	// JobBadgeCheck is the job kind that checks one event for badges.
+	JobBadgeCheck = "badge.check"
+
+	// hundredRuns is how many runs BadgeHundredRuns takes.
+	hundredRuns = 100
+	// streakBadgeDays is how long a streak BadgeStreak10 takes.
+	streakBadgeDays = 10
+)
+
+// badgeJob is the payload of a JobBadgeCheck job.
+type badgeJob struct {
+	Event     string `json:"event"`
+	UserID    string `json:"userId"`              // who caused the event
+	SnippetID string `json:"snippetId,omitempty"` // snippet.forked: the original
+}
+
+// BadgeService awards badges and lists the ones users have earned.
+type BadgeService struct {
+	repo       repository.BadgeRepository
+	users      repository.UserRepository
+	snippets   repository.SnippetRepository
+	challenges repository.ChallengeRepository
+	queue      *jobs.Queue
+	logger     *slog.Logger
+}
+
+// NewBadgeService creates a BadgeService and registers its check job on
+// queue, so it must be called before queue.Start.
+func NewBadgeService(repo repository.BadgeRepository, users repository.UserRepository, snippets repository.SnippetRepository, challenges repository.ChallengeRepository, queue *jobs.Queue, logger *slog.Logger) *BadgeService {
+	s := &BadgeService{
+		repo:       repo,
+		users:      users,
+		snippets:   snippets,
+		challenges: challenges,
+		queue:      queue,
+		logger:     logger,
+	}
+	queue.Register(JobBadgeCheck, s.check)
+	return s
+}
+
+// ForUser returns the badges the user with this login has earned.
+func (s *BadgeService) ForUser(ctx context.Context, login string) ([]model.UserBadge, error) {
+	user, err := s.users.GetUserByLogin(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, apperror.NotFound("user", login)
+	}
+	return s.repo.ListUserBadges(ctx, user.ID)
+}
+
+// Publish queues a badge check for events that can earn one. Anonymous
+// actions earn nothing.
+func (s *BadgeService) Publish(ctx context.Context, event string, data any) {
+	userID, ok := auth.UserIDFromContext(ctx)
+	if !ok || userID == "" {
+		return
+	}
+	job := badgeJob{Event: event, UserID: userID}
+	switch event {
+	case model.EventSnippetCreated, model.EventExecutionCompleted, model.EventChallengeSolved:
+	case model.EventSnippetForked:
+		fork, ok := data.(*model.Snippet)
+		if !ok {
+			return
+		}
+		job.SnippetID = fork.ForkedFrom
+	default:
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	if _, err := s.queue.Enqueue(ctx, JobBadgeCheck, job); err != nil {
+		s.logger.ErrorContext(ctx, "failed to queue badge check",
+			slog.String("event", event),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// check is the JobBadgeCheck handler. Returning an error makes the queue
+// retry later.
+func (s *BadgeService) check(ctx context.Context, job *jobs.Job) error {
+	var p badgeJob
+	if err := job.Decode(&p); err != nil {
+		return err
+	}
+
+	switch p.Event {
+	case model.EventSnippetCreated:
+		return s.award(ctx, p.UserID, model.BadgeFirstSnippet)
+
+	case model.EventExecutionCompleted:
+		// A retry after a failed award counts the run again; being a run
+		// early to a badge worth 100 is fine.
+		runs, err := s.repo.IncrementBadgeCounter(ctx, p.UserID, "runs")
+		if err != nil {
+			return err
+		}
+		if runs >= hundredRuns {
+			return s.award(ctx, p.UserID, model.BadgeHundredRuns)
+		}
+
+	case model.EventChallengeSolved:
+		dates, err := s.challenges.SolvedChallengeDates(ctx, p.UserID)
+		if err != nil {
+			return err
+		}
+		if computeStreak(dates, today()).Current >= streakBadgeDays {
+			return s.award(ctx, p.UserID, model.BadgeStreak10)
+		}
+
+	case model.EventSnippetForked:
+		original, err := s.snippets.GetByID(ctx, p.SnippetID)
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil // deleted since it was forked
+		}
+		if err != nil {
+			return err
+		}
+		// Forking your own snippet, or an anonymous one, earns nobody anything.
+		if original.UserID != "" && original.UserID != p.UserID {
+			return s.award(ctx, original.UserID, model.BadgeFirstFork)
+		}
+	}
+	return nil
+}
+
+// award gives userID a badge, logging the first time.
+func (s *BadgeService) award(ctx context.Context, userID, badgeID string) error {
+	awarded, err := s.repo.AwardBadge(ctx, userID, badgeID)
+	if err != nil {
+		return err
+	}
+	if awarded {
+		s.logger.InfoContext(ctx, "badge awarded",
+			slog.String("user_id", userID),
+			slog.String("badge", badgeID),
+		)
+	}
+	return nil
+}
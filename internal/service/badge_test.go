@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/executor"
+	"github.com/sakif/coding-playground/internal/jobs"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository/sqlite"
+)
+
+func TestBadgeService(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	queue := jobs.New(db, logger, jobs.Options{PollInterval: 10 * time.Millisecond})
+	badges := NewBadgeService(db, db, db, db, queue, logger)
+	snippets := NewSnippetService(db, logger)
+	snippets.PublishEvents(badges)
+	if err := queue.Start(context.Background()); err != nil {
+		t.Fatalf("starting queue: %v", err)
+	}
+	t.Cleanup(func() {
+		queue.Shutdown(context.Background())
+		db.Close()
+	})
+
+	for i, login := range []string{"ann", "bob"} {
+		if err := db.Upsert(context.Background(), &model.User{ID: login + "-id", GitHubID: int64(i + 1), Login: login}); err != nil {
+			t.Fatalf("Upsert: %v", err)
+		}
+	}
+	asAnn := auth.WithUserID(context.Background(), "ann-id")
+	asBob := auth.WithUserID(context.Background(), "bob-id")
+
+	earned := func(login string) []string {
+		list, err := badges.ForUser(context.Background(), login)
+		if err != nil {
+			t.Fatalf("ForUser(%s) error = %v", login, err)
+		}
+		var ids []string
+		for _, b := range list {
+			ids = append(ids, b.ID)
+		}
+		return ids
+	}
+
+	snippet, err := snippets.Create(asAnn, "hello", "print('hi')", "")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := snippets.Fork(asAnn, snippet.ID); err != nil {
+		t.Fatalf("Fork() of own snippet error = %v", err)
+	}
+	if _, err := snippets.Fork(asBob, snippet.ID); err != nil {
+		t.Fatalf("Fork() error = %v", err)
+	}
+	waitUntil(t, func() bool { return len(earned("ann")) == 2 })
+	if got := earned("ann"); !slices.Contains(got, model.BadgeFirstSnippet) || !slices.Contains(got, model.BadgeFirstFork) {
+		t.Errorf("ann's badges = %v, want first-snippet and first-fork", got)
+	}
+	if got := earned("bob"); len(got) != 0 {
+		t.Errorf("bob's badges = %v, want none: forking earns nothing", got)
+	}
+
+	for range hundredRuns {
+		badges.Publish(asBob, model.EventExecutionCompleted, &executor.ExecutionResult{})
+	}
+	badges.Publish(context.Background(), model.EventExecutionCompleted, &executor.ExecutionResult{}) // anonymous
+	waitUntil(t, func() bool { return slices.Contains(earned("bob"), model.BadgeHundredRuns) })
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// DAILY CHALLENGES:
+// Every UTC day can have one challenge: an exercise that authors schedule
+// for that date. Everyone gets the same problem, and solving it on the day
+// extends your STREAK — the number of consecutive days you've solved it.
+//
+//	Mon ✓  Tue ✓  Wed ✓  Thu (today, not yet)   → streak 3, still alive
+//	Mon ✓  Tue ✓  Wed ✗  Thu ✓                   → streak 1
+//
+// Only a submission made on the challenge's own date counts, and only one
+// that passes. Past challenges stay readable (and can be practised through
+// /exercises/{id}/submit), but their day is over. Future ones are hidden
+// from everyone but authors, so nobody can get a head start.
+
+const (
+	// MaxChallengeListDays caps the date range of one listing.
+	MaxChallengeListDays = 366
+	// defaultChallengeListDays is how far back a listing goes by default.
+	defaultChallengeListDays = 30
+)
+
+// ChallengeService schedules daily challenges, grades them and keeps streaks.
+type ChallengeService struct {
+	challenges repository.ChallengeRepository
+	exercises  repository.ExerciseRepository
+	users      repository.UserRepository
+	grading    *GradingService // nil without an executor
+	logger     *slog.Logger
+	events     EventPublisher // optional; see PublishEvents
+}
+
+// NewChallengeService creates a ChallengeService. grading may be nil, which
+// disables Submit.
+func NewChallengeService(challenges repository.ChallengeRepository, exercises repository.ExerciseRepository, users repository.UserRepository, grading *GradingService, logger *slog.Logger) *ChallengeService {
+	return &ChallengeService{
+		challenges: challenges,
+		exercises:  exercises,
+		users:      users,
+		grading:    grading,
+		logger:     logger,
+	}
+}
+
+// PublishEvents makes the service report challenge.solved to p. Call it
+// before serving requests.
+func (s *ChallengeService) PublishEvents(p EventPublisher) {
+	s.events = p
+}
+
+// today is the current challenge date.
+func today() string {
+	return time.Now().UTC().Format(model.ChallengeDateLayout)
+}
+
+// parseChallengeDate checks that date is a YYYY-MM-DD date.
+func parseChallengeDate(date string) (time.Time, error) {
+	t, err := time.Parse(model.ChallengeDateLayout, date)
+	if err != nil {
+		return time.Time{}, apperror.ValidationFailed("date", "date must be YYYY-MM-DD")
+	}
+	return t, nil
+}
+
+// Schedule sets exerciseID as the challenge for date, replacing any already
+// set. Authors and admins only.
+func (s *ChallengeService) Schedule(ctx context.Context, userID, date, exerciseID string) (*model.Challenge, error) {
+	if err := s.requireAuthor(ctx, userID); err != nil {
+		return nil, err
+	}
+	if _, err := parseChallengeDate(date); err != nil {
+		return nil, err
+	}
+	exercise, err := s.exercises.GetExercise(ctx, exerciseID)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, apperror.ValidationFailed("exerciseId", "no such exercise")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := &model.Challenge{Date: date, ExerciseID: exercise.ID, CreatedBy: userID}
+	if err := s.challenges.SaveChallenge(ctx, challenge); err != nil {
+		return nil, fmt.Errorf("saving challenge: %w", err)
+	}
+	challenge.Exercise = exercise
+
+	s.logger.InfoContext(ctx, "challenge scheduled",
+		slog.String("date", date),
+		slog.String("exercise_id", exercise.ID),
+		slog.String("user_id", userID),
+	)
+	return challenge, nil
+}
+
+// Unschedule removes the challenge set for date. Authors and admins only.
+func (s *ChallengeService) Unschedule(ctx context.Context, userID, date string) error {
+	if err := s.requireAuthor(ctx, userID); err != nil {
+		return err
+	}
+	if _, err := parseChallengeDate(date); err != nil {
+		return err
+	}
+	return s.challenges.DeleteChallenge(ctx, date)
+}
+
+// Today returns today's challenge with its exercise.
+func (s *ChallengeService) Today(ctx context.Context) (*model.Challenge, error) {
+	return s.Get(ctx, "", today())
+}
+
+// Get returns the challenge for date with its exercise. Future challenges
+// are only shown to authors.
+func (s *ChallengeService) Get(ctx context.Context, userID, date string) (*model.Challenge, error) {
+	if _, err := parseChallengeDate(date); err != nil {
+		return nil, err
+	}
+	if date > today() && !s.isAuthor(ctx, userID) {
+		return nil, apperror.NotFound("challenge", date)
+	}
+	challenge, err := s.challenges.GetChallenge(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.Exercise, err = s.exercises.GetExercise(ctx, challenge.ExerciseID); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// List returns the challenges dated from through to, newest first, without
+// their exercises. Empty dates default to the last 30 days; to is capped at
+// today except for authors.
+func (s *ChallengeService) List(ctx context.Context, userID, from, to string) ([]model.Challenge, error) {
+	now := today()
+	if to == "" {
+		to = now
+	}
+	end, err := parseChallengeDate(to)
+	if err != nil {
+		return nil, apperror.ValidationFailed("to", "to must be YYYY-MM-DD")
+	}
+	if from == "" {
+		from = end.AddDate(0, 0, -(defaultChallengeListDays - 1)).Format(model.ChallengeDateLayout)
+	}
+	start, err := parseChallengeDate(from)
+	if err != nil {
+		return nil, apperror.ValidationFailed("from", "from must be YYYY-MM-DD")
+	}
+	switch days := int(end.Sub(start).Hours()/24) + 1; {
+	case days < 1:
+		return nil, apperror.ValidationFailed("from", "from must not be after to")
+	case days > MaxChallengeListDays:
+		return nil, apperror.ValidationFailed("from", fmt.Sprintf("list at most %d days at a time", MaxChallengeListDays))
+	}
+	if to > now && !s.isAuthor(ctx, userID) {
+		to = now
+	}
+	return s.challenges.ListChallenges(ctx, from, to)
+}
+
+// Submit grades userID's solution to the challenge for date, which must be
+// today's: earlier days are over, and later ones haven't started.
+func (s *ChallengeService) Submit(ctx context.Context, userID, date, code string) (*model.Submission, error) {
+	if s.grading == nil {
+		return nil, errors.New("grading is unavailable: no executor")
+	}
+	if err := validateSubmissionCode(code); err != nil {
+		return nil, err
+	}
+	if _, err := parseChallengeDate(date); err != nil {
+		return nil, err
+	}
+	now := today()
+	if date > now {
+		return nil, apperror.NotFound("challenge", date)
+	}
+	challenge, err := s.challenges.GetChallenge(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+	if date < now {
+		return nil, &apperror.AppError{
+			Err:     apperror.ErrForbidden,
+			Message: fmt.Sprintf("the challenge for %s is over; practise it at /exercises/%s/submit", date, challenge.ExerciseID),
+		}
+	}
+	exercise, err := s.exercises.GetExercise(ctx, challenge.ExerciseID)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := s.grading.submit(ctx, &model.Submission{ChallengeDate: date, UserID: userID, Code: code}, exercise, 0)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Status == model.SubmissionPassed && s.events != nil {
+		s.events.Publish(ctx, model.EventChallengeSolved, sub)
+	}
+	return sub, nil
+}
+
+// Streak returns userID's daily challenge streak.
+func (s *ChallengeService) Streak(ctx context.Context, userID string) (*model.Streak, error) {
+	dates, err := s.challenges.SolvedChallengeDates(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	streak := computeStreak(dates, today())
+	return &streak, nil
+}
+
+// computeStreak works out a streak from the dates of solved challenges,
+// newest first, as of today.
+func computeStreak(dates []string, today string) model.Streak {
+	var streak model.Streak
+	days := make([]time.Time, 0, len(dates))
+	for _, date := range dates {
+		if t, err := time.Parse(model.ChallengeDateLayout, date); err == nil {
+			days = append(days, t)
+		}
+	}
+	if len(days) == 0 {
+		return streak
+	}
+	streak.LastSolved = days[0].Format(model.ChallengeDateLayout)
+	streak.SolvedToday = streak.LastSolved == today
+
+	// The newest run is the current streak if it reaches today, or
+	// yesterday: today's challenge can still keep it going.
+	now, _ := time.Parse(model.ChallengeDateLayout, today)
+	alive := !days[0].Before(now.AddDate(0, 0, -1))
+	run := 0
+	for i, day := range days {
+		if i > 0 && days[i-1].AddDate(0, 0, -1).Equal(day) {
+			run++
+		} else {
+			run = 1
+		}
+		if alive && run == i+1 {
+			streak.Current = run
+		}
+		streak.Longest = max(streak.Longest, run)
+	}
+	return streak
+}
+
+// requireAuthor refuses users without the author or admin role.
+func (s *ChallengeService) requireAuthor(ctx context.Context, userID string) error {
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("looking up user: %w", err)
+	}
+	if user == nil || !user.CanAuthor() {
+		return &apperror.AppError{
+			Err:     apperror.ErrForbidden,
+			Message: "scheduling challenges requires the author role",
+		}
+	}
+	return nil
+}
+
+// isAuthor reports whether userID ("" when signed out) may see the
+// schedule ahead of time.
+func (s *ChallengeService) isAuthor(ctx context.Context, userID string) bool {
+	if userID == "" {
+		return false
+	}
+	return s.requireAuthor(ctx, userID) == nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+)
+
+// mockChallengeRepo keeps challenges in memory. SolvedChallengeDates
+// returns solved, newest first, whoever asks.
+type mockChallengeRepo struct {
+	challenges map[string]*model.Challenge
+	solved     []string
+}
+
+func (m *mockChallengeRepo) SaveChallenge(_ context.Context, c *model.Challenge) error {
+	m.challenges[c.Date] = c
+	return nil
+}
+
+func (m *mockChallengeRepo) GetChallenge(_ context.Context, date string) (*model.Challenge, error) {
+	c, ok := m.challenges[date]
+	if !ok {
+		return nil, apperror.NotFound("challenge", date)
+	}
+	copied := *c
+	return &copied, nil
+}
+
+func (m *mockChallengeRepo) ListChallenges(_ context.Context, from, to string) ([]model.Challenge, error) {
+	var out []model.Challenge
+	for date, c := range m.challenges {
+		if date >= from && date <= to {
+			out = append(out, *c)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockChallengeRepo) DeleteChallenge(_ context.Context, date string) error {
+	if _, ok := m.challenges[date]; !ok {
+		return apperror.NotFound("challenge", date)
+	}
+	delete(m.challenges, date)
+	return nil
+}
+
+func (m *mockChallengeRepo) SolvedChallengeDates(context.Context, string) ([]string, error) {
+	return m.solved, nil
+}
+
+func TestComputeStreak(t *testing.T) {
+	const today = "2026-03-10"
+	tests := []struct {
+		name  string
+		dates []string
+		want  model.Streak
+	}{
+		{"never solved", nil, model.Streak{}},
+		{"solved today", []string{"2026-03-10", "2026-03-09", "2026-03-08"},
+			model.Streak{Current: 3, Longest: 3, LastSolved: "2026-03-10", SolvedToday: true}},
+		{"today still open", []string{"2026-03-09", "2026-03-08"},
+			model.Streak{Current: 2, Longest: 2, LastSolved: "2026-03-09"}},
+		{"missed yesterday", []string{"2026-03-08", "2026-03-07"},
+			model.Streak{Current: 0, Longest: 2, LastSolved: "2026-03-08"}},
+		{"gap", []string{"2026-03-10", "2026-03-08", "2026-03-07", "2026-03-06"},
+			model.Streak{Current: 1, Longest: 3, LastSolved: "2026-03-10", SolvedToday: true}},
+		{"across a month", []string{"2026-03-01", "2026-02-28"},
+			model.Streak{Current: 0, Longest: 2, LastSolved: "2026-03-01"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := computeStreak(tt.dates, today); got != tt.want {
+				t.Errorf("computeStreak() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChallengeService_Submit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	now := time.Now().UTC()
+	date := func(days int) string { return now.AddDate(0, 0, days).Format(model.ChallengeDateLayout) }
+
+	exercises := &mockExerciseRepo{exercises: map[string]*model.Exercise{
+		"ex1": {ID: "ex1", TestCode: "def test_x():\n    assert True\n"},
+	}}
+	challenges := &mockChallengeRepo{challenges: map[string]*model.Challenge{}}
+	users := newMockUserRepo(
+		&model.User{ID: "learner", Role: model.RoleUser},
+		&model.User{ID: "author", Role: model.RoleAuthor},
+	)
+	submissions := &mockSubmissionRepo{}
+	grading := NewGradingService(exercises, submissions, &mockHintRepo{}, &timeoutExecutor{}, logger)
+	svc := NewChallengeService(challenges, exercises, users, grading, logger)
+	ctx := context.Background()
+
+	if _, err := svc.Schedule(ctx, "learner", date(0), "ex1"); !errors.Is(err, apperror.ErrForbidden) {
+		t.Errorf("Schedule() by a learner: error = %v, want ErrForbidden", err)
+	}
+	if _, err := svc.Schedule(ctx, "author", date(0), "missing"); !errors.Is(err, apperror.ErrValidation) {
+		t.Errorf("Schedule() of a missing exercise: error = %v, want ErrValidation", err)
+	}
+	for _, days := range []int{-1, 0, 1} {
+		if _, err := svc.Schedule(ctx, "author", date(days), "ex1"); err != nil {
+			t.Fatalf("Schedule(%s) error = %v", date(days), err)
+		}
+	}
+
+	if _, err := svc.Get(ctx, "learner", date(1)); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("Get() of tomorrow by a learner: error = %v, want ErrNotFound", err)
+	}
+	if c, err := svc.Get(ctx, "author", date(1)); err != nil || c.Exercise == nil {
+		t.Errorf("Get() of tomorrow by an author = %+v, %v", c, err)
+	}
+	if list, err := svc.List(ctx, "learner", date(-1), date(1)); err != nil || len(list) != 2 {
+		t.Errorf("List() by a learner = %+v, %v; want yesterday and today", list, err)
+	}
+
+	if _, err := svc.Submit(ctx, "learner", date(1), "x = 1"); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("Submit() for tomorrow: error = %v, want ErrNotFound", err)
+	}
+	if _, err := svc.Submit(ctx, "learner", date(-1), "x = 1"); !errors.Is(err, apperror.ErrForbidden) {
+		t.Errorf("Submit() for yesterday: error = %v, want ErrForbidden", err)
+	}
+	sub, err := svc.Submit(ctx, "learner", date(0), "x = 1")
+	if err != nil {
+		t.Fatalf("Submit() for today error = %v", err)
+	}
+	if sub.ChallengeDate != date(0) || sub.ExerciseID != "ex1" || len(submissions.saved) != 1 {
+		t.Errorf("submission = %+v, want today's challenge recorded", sub)
+	}
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/jobs"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// CLASSROOM MODE:
+// A signed-in user creates a class and becomes its teacher. The class gets a
+// short join code; students type it in to join:
+//
+//	POST /api/v1/classes           {"name": "Intro to Python"}  → teacher
+//	POST /api/v1/classes/join      {"code": "K7QX2MPA"}         → student
+//
+// Teachers turn exercises into assignments for the class and see every
+// student's submissions; students see the assignments and only their own
+// submissions. To anyone outside a class, it doesn't exist (404, not 403), so
+// class IDs can't be probed.
+//
+// Class roles are separate from User.Role: a plain user can teach a class,
+// and an admin who joins one is just a student there.
+
+const (
+	MaxClassNameLength = 100
+
+	// joinCodeLength characters from joinCodeAlphabet give ~40 bits: plenty to
+	// make guessing a code hopeless, short enough to read out in a classroom.
+	joinCodeLength = 8
+)
+
+// joinCodeAlphabet leaves out look-alikes (0/O, 1/I/L) so codes survive
+// being read aloud or copied from a whiteboard.
+const joinCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// ClassService manages classes, membership and assignments (assignment.go).
+type ClassService struct {
+	classes     repository.ClassRepository
+	exercises   repository.ExerciseRepository
+	submissions repository.SubmissionRepository
+	grading     *GradingService // nil when no executor is available
+	logger      *slog.Logger
+
+	// Set by DetectSimilarity (similarity.go).
+	reports repository.SimilarityRepository
+	queue   *jobs.Queue
+}
+
+// NewClassService creates a ClassService. grading may be nil, in which case
+// assignments can be set but not submitted to.
+func NewClassService(classes repository.ClassRepository, exercises repository.ExerciseRepository, submissions repository.SubmissionRepository, grading *GradingService, logger *slog.Logger) *ClassService {
+	return &ClassService{
+		classes:     classes,
+		exercises:   exercises,
+		submissions: submissions,
+		grading:     grading,
+		logger:      logger,
+	}
+}
+
+// Create makes a new class taught by userID.
+func (s *ClassService) Create(ctx context.Context, userID, name string) (*model.ClassMembership, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, apperror.ValidationFailed("name", "class name is required").WithCode(apperror.CodeNameRequired)
+	}
+	if len(name) > MaxClassNameLength {
+		return nil, apperror.ValidationFailed("name", fmt.Sprintf("class name must be %d characters or less", MaxClassNameLength)).
+			WithCode(apperror.CodeNameTooLong).WithParam("max", strconv.Itoa(MaxClassNameLength))
+	}
+
+	class := &model.Class{Name: name, OwnerID: userID}
+	// A clash is vanishingly rare, but cheap to retry.
+	for attempt := 0; ; attempt++ {
+		class.JoinCode = newJoinCode()
+		err := s.classes.CreateClass(ctx, class)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, apperror.ErrConflict) || attempt == 2 {
+			return nil, fmt.Errorf("creating class: %w", err)
+		}
+	}
+
+	s.logger.InfoContext(ctx, "class created",
+		slog.String("id", class.ID),
+		slog.String("owner_id", userID),
+	)
+	return &model.ClassMembership{Class: *class, Role: model.ClassTeacher}, nil
+}
+
+// Join adds userID to the class with the given join code as a student. A
+// member joining again keeps their role.
+func (s *ClassService) Join(ctx context.Context, userID, code string) (*model.ClassMembership, error) {
+	code = strings.ToUpper(strings.Join(strings.Fields(code), ""))
+	if code == "" {
+		return nil, apperror.ValidationFailed("code", "join code is required")
+	}
+	class, err := s.classes.GetClassByJoinCode(ctx, code)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, apperror.ValidationFailed("code", "no class has that join code")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.classes.AddClassMember(ctx, &model.ClassMember{ClassID: class.ID, UserID: userID, Role: model.ClassStudent})
+	if err != nil {
+		return nil, fmt.Errorf("joining class: %w", err)
+	}
+	member, err := s.classes.GetClassMember(ctx, class.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "class joined",
+		slog.String("class_id", class.ID),
+		slog.String("user_id", userID),
+		slog.String("role", member.Role),
+	)
+	return membershipView(class, member.Role), nil
+}
+
+// List returns the classes userID belongs to.
+func (s *ClassService) List(ctx context.Context, userID string) ([]model.ClassMembership, error) {
+	classes, err := s.classes.ListClassesForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range classes {
+		classes[i] = *membershipView(&classes[i].Class, classes[i].Role)
+	}
+	return classes, nil
+}
+
+// Get returns a class as userID sees it.
+func (s *ClassService) Get(ctx context.Context, userID, classID string) (*model.ClassMembership, error) {
+	class, member, err := s.member(ctx, userID, classID)
+	if err != nil {
+		return nil, err
+	}
+	return membershipView(class, member.Role), nil
+}
+
+// Members lists a class's teachers and students. Teachers only.
+func (s *ClassService) Members(ctx context.Context, userID, classID string) ([]model.ClassMember, error) {
+	if _, err := s.teacher(ctx, userID, classID); err != nil {
+		return nil, err
+	}
+	return s.classes.ListClassMembers(ctx, classID)
+}
+
+// member returns the class and userID's membership of it. Non-members get
+// the same NotFound as for a class that doesn't exist.
+func (s *ClassService) member(ctx context.Context, userID, classID string) (*model.Class, *model.ClassMember, error) {
+	class, err := s.classes.GetClass(ctx, classID)
+	if err != nil {
+		return nil, nil, err
+	}
+	member, err := s.classes.GetClassMember(ctx, classID, userID)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, nil, apperror.NotFound("class", classID)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return class, member, nil
+}
+
+// teacher returns the class if userID teaches it.
+func (s *ClassService) teacher(ctx context.Context, userID, classID string) (*model.Class, error) {
+	class, member, err := s.member(ctx, userID, classID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role != model.ClassTeacher {
+		return nil, &apperror.AppError{
+			Err:     apperror.ErrForbidden,
+			Message: "only the class's teachers can do this",
+		}
+	}
+	return class, nil
+}
+
+// membershipView is a class as someone with role sees it: only teachers
+// see the join code.
+func membershipView(class *model.Class, role string) *model.ClassMembership {
+	view := &model.ClassMembership{Class: *class, Role: role}
+	if role != model.ClassTeacher {
+		view.JoinCode = ""
+	}
+	return view
+}
+
+// newJoinCode returns a random join code. Bytes past the last whole multiple
+// of the alphabet's length are skipped, so every character is equally likely.
+func newJoinCode() string {
+	const limit = 256 / len(joinCodeAlphabet) * len(joinCodeAlphabet)
+	code := make([]byte, 0, joinCodeLength)
+	buf := make([]byte, joinCodeLength)
+	for len(code) < joinCodeLength {
+		rand.Read(buf) // never fails: crypto/rand crashes the program instead
+		for _, b := range buf {
+			if int(b) < limit && len(code) < joinCodeLength {
+				code = append(code, joinCodeAlphabet[int(b)%len(joinCodeAlphabet)])
+			}
+		}
+	}
+	return string(code)
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sakif/coding-playground/internal/model"
+)
+
+func TestNewJoinCode(t *testing.T) {
+	seen := make(map[string]bool)
+	for range 100 {
+		code := newJoinCode()
+		if len(code) != joinCodeLength || strings.Trim(code, joinCodeAlphabet) != "" {
+			t.Fatalf("newJoinCode() = %q, want %d characters from %s", code, joinCodeLength, joinCodeAlphabet)
+		}
+		if seen[code] {
+			t.Fatalf("newJoinCode() repeated %q", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestMembershipView_HidesJoinCodeFromStudents(t *testing.T) {
+	class := &model.Class{ID: "c1", JoinCode: "K7QX2MPA"}
+	if got := membershipView(class, model.ClassStudent); got.JoinCode != "" {
+		t.Errorf("student sees join code %q", got.JoinCode)
+	}
+	if got := membershipView(class, model.ClassTeacher); got.JoinCode != "K7QX2MPA" {
+		t.Errorf("teacher sees join code %q, want K7QX2MPA", got.JoinCode)
+	}
+	if class.JoinCode == "" {
+		t.Error("membershipView modified the class")
+	}
+}
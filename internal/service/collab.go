@@ -0,0 +1,478 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/ot"
+	"github.com/sakif/coding-playground/internal/ws"
+)
+
+// COLLABORATIVE EDITING:
+// Several people open the same snippet and type at once. Each edit travels
+// over the WebSocket hub as an operational-transformation op (see package
+// ot), and the server is the referee: it numbers the edits it accepts —
+// the document's REVISION — and rewrites late ones so everyone converges.
+//
+//	→ {"type":"collab.join","topic":"collab:<snippet id>","id":"1"}
+//	← {"type":"collab.joined","topic":"collab:…","id":"1",
+//	   "data":{"session":"…","revision":0,"code":"…","members":[…]}}
+//	→ {"type":"collab.op","topic":"collab:…","id":"2",
+//	   "data":{"revision":0,"opId":"c1-1","op":[5,"!"]}}
+//	← {"type":"collab.ack","topic":"collab:…","id":"2","data":{"revision":1,"opId":"c1-1"}}
+//	← {"type":"event","topic":"collab:…","data":{"kind":"op","revision":1,"op":[5,"!"],…}}
+//
+// An op names the revision it was written against. If others' ops were
+// accepted since, the server transforms it past each of them before
+// applying it. A client keeps at most one op in flight and buffers what
+// it types meanwhile — the ot.js client algorithm. Every member gets every
+// accepted op as an event, the author included (it can skip its own by
+// opId); events at or before the revision in "collab.joined" are already in
+// the code it was sent. "join" and "leave" events carry the member list.
+//
+// RECONNECTING:
+// A connection that drops and comes back joins again with the session and
+// revision it last saw. If the session is still open and remembers that
+// far back, the reply carries the ops since ("ops" instead of "code"), and
+// the client resends its unacknowledged op under the same opId: an op the
+// server already applied is acknowledged again rather than applied twice.
+// Otherwise the client gets a fresh snapshot and must redo its pending
+// edits on top of it.
+//
+// PERSISTENCE:
+// The session, not the database, holds the live document. Every
+// CollabSaveInterval, edited sessions are written back to the snippet (a new
+// version, as with PUT); idle sessions whose members have all left are then
+// closed. While a session is open it owns the code: a PUT in the meantime
+// is overwritten by the next save.
+
+const (
+	// CollabTopicPrefix starts a snippet's collaboration topic,
+	// "collab:<snippet id>".
+	CollabTopicPrefix = "collab:"
+
+	// Client message types, and the replies to them.
+	MsgCollabJoin   = "collab.join"
+	MsgCollabJoined = "collab.joined"
+	MsgCollabLeave  = "collab.leave"
+	MsgCollabOp     = "collab.op"
+	MsgCollabAck    = "collab.ack"
+
+	// CollabSaveInterval is how often edited sessions are saved.
+	CollabSaveInterval = 5 * time.Second
+
+	// collabHistory is how many recent ops a session keeps for transforming
+	// late ops and catching reconnecting clients up.
+	collabHistory = 500
+)
+
+// CollabMember is one connection taking part in a session.
+type CollabMember struct {
+	ClientID string `json:"clientId"`
+	UserID   string `json:"userId,omitempty"`
+}
+
+// collabEntry is an accepted op, as sent to members.
+type collabEntry struct {
+	Kind     string `json:"kind"` // "op"
+	Revision int    `json:"revision"`
+	Op       ot.Op  `json:"op"`
+	OpID     string `json:"opId"`
+	ClientID string `json:"clientId"`
+}
+
+// collabSession is the live document of one snippet.
+type collabSession struct {
+	id        string
+	snippetID string
+	doc       string
+	revision  int
+	history   []collabEntry // the ops that made revisions revision-len(history)+1 … revision
+	members   map[*ws.Client]bool
+	dirty     bool // edited since the last save
+}
+
+// CollabService runs collaborative editing sessions over the WebSocket hub.
+type CollabService struct {
+	snippets *SnippetService
+	hub      *ws.Hub
+	logger   *slog.Logger
+
+	// suspended, if set, reports whether a user is suspended; see
+	// RejectSuspended.
+	suspended func(ctx context.Context, userID string) (bool, error)
+
+	mu       sync.Mutex
+	sessions map[string]*collabSession // by snippet ID
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewCollabService registers the "collab:" topics and message types on hub.
+// Call Start to save sessions periodically and Shutdown to save them last.
+func NewCollabService(snippets *SnippetService, hub *ws.Hub, logger *slog.Logger) *CollabService {
+	s := &CollabService{
+		snippets: snippets,
+		hub:      hub,
+		logger:   logger,
+		sessions: make(map[string]*collabSession),
+	}
+	// Anyone who can load a snippet may edit it, as with PUT — except an
+	// org's snippets, which only its members may.
+	hub.Authorize(CollabTopicPrefix, func(ctx context.Context, userID, topic string) error {
+		snippet, err := snippets.GetByID(ctx, strings.TrimPrefix(topic, CollabTopicPrefix))
+		if err != nil || snippet.OrgID == "" {
+			return err
+		}
+		if ok, err := snippets.CanManage(ctx, userID, snippet); err != nil {
+			return err
+		} else if !ok {
+			return ws.ErrForbidden
+		}
+		return nil
+	})
+	hub.HandleMessage(MsgCollabJoin, s.handleJoin)
+	hub.HandleMessage(MsgCollabLeave, s.handleLeave)
+	hub.HandleMessage(MsgCollabOp, s.handleOp)
+	hub.OnDisconnect(s.leaveAll)
+	return s
+}
+
+// RejectSuspended keeps suspended users read-only in sessions, as the HTTP
+// API does: they may join and watch, but their ops are refused. lookup is
+// asked on every op, so a suspension takes effect mid-session. Call it
+// before serving requests.
+func (s *CollabService) RejectSuspended(lookup func(ctx context.Context, userID string) (bool, error)) {
+	s.suspended = lookup
+}
+
+// Start saves edited sessions every CollabSaveInterval until Shutdown.
+func (s *CollabService) Start() {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(CollabSaveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Flush(context.Background()); err != nil {
+					s.logger.Error("failed to save collaboration sessions", slog.String("error", err.Error()))
+				}
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Shutdown stops the periodic saves and saves every edited session.
+func (s *CollabService) Shutdown(ctx context.Context) error {
+	if s.stop != nil {
+		close(s.stop)
+		select {
+		case <-s.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return s.Flush(ctx)
+}
+
+// Flush saves every edited session to its snippet, then closes the
+// sessions nobody is in any more.
+func (s *CollabService) Flush(ctx context.Context) error {
+	type save struct {
+		sess *collabSession
+		doc  string
+	}
+	var saves []save
+	s.mu.Lock()
+	for id, sess := range s.sessions {
+		switch {
+		case sess.dirty:
+			saves = append(saves, save{sess, sess.doc})
+			sess.dirty = false
+		case len(sess.members) == 0:
+			delete(s.sessions, id)
+		}
+	}
+	s.mu.Unlock()
+
+	var errs []error
+	for _, sv := range saves {
+		err := s.save(ctx, sv.sess.snippetID, sv.doc)
+		switch {
+		case errors.Is(err, apperror.ErrNotFound):
+			// The snippet was deleted under the session: nothing to save to.
+			s.mu.Lock()
+			delete(s.sessions, sv.sess.snippetID)
+			s.mu.Unlock()
+		case err != nil:
+			s.mu.Lock()
+			sv.sess.dirty = true // try again next time
+			s.mu.Unlock()
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// save writes doc back as the snippet's code, keeping its name and
+// description. Members were checked when they joined, so it doesn't check
+// again.
+func (s *CollabService) save(ctx context.Context, snippetID, doc string) error {
+	snippet, err := s.snippets.GetByID(ctx, snippetID)
+	if err != nil {
+		return fmt.Errorf("saving collaboration on %s: %w", snippetID, err)
+	}
+	if snippet.Code == doc {
+		return nil
+	}
+	if _, err := s.snippets.update(ctx, snippet, "", doc, snippet.Description); err != nil {
+		return fmt.Errorf("saving collaboration on %s: %w", snippetID, err)
+	}
+	return nil
+}
+
+// collabJoin is the data of a "collab.join": empty for a first join, the
+// last session and revision seen when reconnecting.
+type collabJoin struct {
+	Session  string `json:"session,omitempty"`
+	Revision int    `json:"revision,omitempty"`
+}
+
+// collabJoined is the data of a "collab.joined": either the whole code, or
+// the ops since the revision the client asked for.
+type collabJoined struct {
+	Session  string         `json:"session"`
+	Revision int            `json:"revision"`
+	Code     *string        `json:"code,omitempty"`
+	Ops      []collabEntry  `json:"ops,omitempty"`
+	Members  []CollabMember `json:"members"`
+}
+
+// collabOp is the data of a "collab.op".
+type collabOp struct {
+	Revision int    `json:"revision"`
+	OpID     string `json:"opId"`
+	Op       ot.Op  `json:"op"`
+}
+
+// collabPresence is the event sent when a member joins or leaves.
+type collabPresence struct {
+	Kind     string         `json:"kind"` // "join" or "leave"
+	ClientID string         `json:"clientId"`
+	Members  []CollabMember `json:"members"`
+}
+
+func (s *CollabService) handleJoin(ctx context.Context, c *ws.Client, msg ws.Message) {
+	var in collabJoin
+	if len(msg.Data) > 0 {
+		if err := json.Unmarshal(msg.Data, &in); err != nil {
+			collabError(c, msg, "invalid join: "+err.Error())
+			return
+		}
+	}
+	snippetID, ok := strings.CutPrefix(msg.Topic, CollabTopicPrefix)
+	if !ok || snippetID == "" {
+		collabError(c, msg, "topic must be collab:<snippet id>")
+		return
+	}
+	// Subscribing checks that the snippet exists.
+	if err := c.Subscribe(ctx, msg.Topic); err != nil {
+		collabError(c, msg, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := s.sessions[snippetID]
+	if sess == nil {
+		snippet, err := s.snippets.GetByID(ctx, snippetID)
+		if err != nil {
+			collabError(c, msg, err.Error())
+			return
+		}
+		sess = &collabSession{
+			id:        xid.New().String(),
+			snippetID: snippetID,
+			doc:       snippet.Code,
+			members:   make(map[*ws.Client]bool),
+		}
+		s.sessions[snippetID] = sess
+	}
+
+	rejoining := sess.members[c]
+	sess.members[c] = true
+	out := collabJoined{Session: sess.id, Revision: sess.revision, Members: sess.memberList()}
+	if ops, ok := sess.since(in.Session, in.Revision); ok {
+		out.Ops = ops
+	} else {
+		out.Code = &sess.doc
+	}
+	data, _ := json.Marshal(out)
+	c.Send(ws.Message{Type: MsgCollabJoined, Topic: msg.Topic, ID: msg.ID, Data: data})
+	if !rejoining {
+		s.publish(sess, collabPresence{Kind: "join", ClientID: c.ID(), Members: out.Members})
+	}
+}
+
+func (s *CollabService) handleLeave(_ context.Context, c *ws.Client, msg ws.Message) {
+	snippetID := strings.TrimPrefix(msg.Topic, CollabTopicPrefix)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sess := s.sessions[snippetID]; sess != nil {
+		s.leave(sess, c)
+	}
+	c.Send(ws.Message{Type: ws.TypeUnsubscribed, Topic: msg.Topic, ID: msg.ID})
+}
+
+// leaveAll drops a disconnected client from its sessions.
+func (s *CollabService) leaveAll(c *ws.Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sess := range s.sessions {
+		s.leave(sess, c)
+	}
+}
+
+// leave drops c from sess. The session itself stays open until the next
+// Flush, so a client that reconnects quickly can pick up where it was.
+// Callers hold s.mu.
+func (s *CollabService) leave(sess *collabSession, c *ws.Client) {
+	if !sess.members[c] {
+		return
+	}
+	delete(sess.members, c)
+	s.publish(sess, collabPresence{Kind: "leave", ClientID: c.ID(), Members: sess.memberList()})
+}
+
+func (s *CollabService) handleOp(ctx context.Context, c *ws.Client, msg ws.Message) {
+	var in collabOp
+	if err := json.Unmarshal(msg.Data, &in); err != nil {
+		collabError(c, msg, "invalid op: "+err.Error())
+		return
+	}
+	if s.suspended != nil && c.UserID() != "" {
+		suspended, err := s.suspended(ctx, c.UserID())
+		if err != nil {
+			s.logger.ErrorContext(ctx, "checking suspension failed", slog.String("error", err.Error()))
+			collabError(c, msg, "internal server error")
+			return
+		}
+		if suspended {
+			collabError(c, msg, "your account is suspended: you can watch this session, but not edit")
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := s.sessions[strings.TrimPrefix(msg.Topic, CollabTopicPrefix)]
+	if sess == nil || !sess.members[c] {
+		collabError(c, msg, "join the session first")
+		return
+	}
+	entry, applied, err := sess.apply(in, c.ID())
+	if err != nil {
+		collabError(c, msg, err.Error())
+		return
+	}
+
+	ack, _ := json.Marshal(map[string]any{"revision": entry.Revision, "opId": entry.OpID})
+	c.Send(ws.Message{Type: MsgCollabAck, Topic: msg.Topic, ID: msg.ID, Data: ack})
+	if applied {
+		// Still under s.mu, so members see ops in revision order.
+		s.publish(sess, entry)
+	}
+}
+
+// publish sends an event to the session's members. Callers hold s.mu.
+func (s *CollabService) publish(sess *collabSession, event any) {
+	if err := s.hub.Publish(CollabTopicPrefix+sess.snippetID, event); err != nil {
+		s.logger.Error("failed to publish collaboration event",
+			slog.String("snippet_id", sess.snippetID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+func collabError(c *ws.Client, msg ws.Message, text string) {
+	c.Send(ws.Message{Type: ws.TypeError, Topic: msg.Topic, ID: msg.ID, Error: text})
+}
+
+// apply transforms in past the ops its author hadn't seen and applies it.
+// An op already applied (the same opId, resent after a reconnect) isn't
+// applied again: applied is false and entry is the original.
+func (sess *collabSession) apply(in collabOp, clientID string) (entry collabEntry, applied bool, err error) {
+	oldest := sess.revision - len(sess.history)
+	if in.Revision < oldest || in.Revision > sess.revision {
+		return collabEntry{}, false, fmt.Errorf("revision %d is unknown (the session is at %d): join again", in.Revision, sess.revision)
+	}
+	if in.OpID == "" {
+		return collabEntry{}, false, errors.New("opId is required")
+	}
+	for _, h := range sess.history[in.Revision-oldest:] {
+		if h.OpID == in.OpID {
+			return h, false, nil
+		}
+	}
+
+	op := in.Op
+	for _, h := range sess.history[in.Revision-oldest:] {
+		pair, _, err := ot.Transform(op, h.Op)
+		if err != nil {
+			return collabEntry{}, false, err
+		}
+		op = pair
+	}
+	doc, err := op.Apply(sess.doc)
+	if err != nil {
+		return collabEntry{}, false, err
+	}
+	if len(doc) > MaxCodeLength {
+		return collabEntry{}, false, fmt.Errorf("code must be %d characters or less", MaxCodeLength)
+	}
+
+	sess.doc = doc
+	sess.revision++
+	sess.dirty = true
+	entry = collabEntry{Kind: "op", Revision: sess.revision, Op: op, OpID: in.OpID, ClientID: clientID}
+	sess.history = append(sess.history, entry)
+	if len(sess.history) > collabHistory {
+		sess.history = sess.history[len(sess.history)-collabHistory:]
+	}
+	return entry, true, nil
+}
+
+// since returns the ops after revision, if session is this one and it
+// still has them all.
+func (sess *collabSession) since(session string, revision int) ([]collabEntry, bool) {
+	oldest := sess.revision - len(sess.history)
+	if session != sess.id || revision < oldest || revision > sess.revision {
+		return nil, false
+	}
+	return sess.history[revision-oldest:], true
+}
+
+func (sess *collabSession) memberList() []CollabMember {
+	members := make([]CollabMember, 0, len(sess.members))
+	for c := range sess.members {
+		members = append(members, CollabMember{ClientID: c.ID(), UserID: c.UserID()})
+	}
+	slices.SortFunc(members, func(a, b CollabMember) int { return strings.Compare(a.ClientID, b.ClientID) })
+	return members
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sakif/coding-playground/internal/ot"
+)
+
+func TestCollabSession_Apply(t *testing.T) {
+	sess := &collabSession{id: "s1", doc: "print(x)"}
+
+	// Alice and Bob both edit revision 0.
+	alice := collabOp{Revision: 0, OpID: "a1", Op: ot.Op{}.Retain(6).Delete(1).Insert("y").Retain(1)}
+	bob := collabOp{Revision: 0, OpID: "b1", Op: ot.Op{}.Insert("# hi\n").Retain(8)}
+	if _, _, err := sess.apply(alice, "alice"); err != nil {
+		t.Fatalf("apply(alice) error = %v", err)
+	}
+	entry, applied, err := sess.apply(bob, "bob")
+	if err != nil || !applied {
+		t.Fatalf("apply(bob) = %v, %v", applied, err)
+	}
+	if sess.doc != "# hi\nprint(y)" || sess.revision != 2 || entry.Revision != 2 || !sess.dirty {
+		t.Errorf("after both: doc %q at revision %d, want both edits at revision 2", sess.doc, sess.revision)
+	}
+
+	// Bob reconnects and resends his op: it isn't applied twice.
+	again, applied, err := sess.apply(bob, "bob-again")
+	if err != nil || applied || again.Revision != 2 || sess.revision != 2 {
+		t.Errorf("resent op = %+v, %v, %v; want the original entry, not applied", again, applied, err)
+	}
+
+	if _, _, err := sess.apply(collabOp{Revision: 3, OpID: "x", Op: ot.Op{}.Retain(13)}, "c"); err == nil {
+		t.Error("op on a future revision succeeded")
+	}
+	if _, _, err := sess.apply(collabOp{Revision: 2, OpID: "x", Op: ot.Op{}.Retain(3)}, "c"); err == nil {
+		t.Error("op on the wrong length of document succeeded")
+	}
+	if _, _, err := sess.apply(collabOp{Revision: 2, Op: ot.Op{}.Retain(13)}, "c"); err == nil {
+		t.Error("op without an opId succeeded")
+	}
+}
+
+func TestCollabSession_HistoryIsBounded(t *testing.T) {
+	sess := &collabSession{id: "s1"}
+	for i := 0; i < collabHistory+10; i++ {
+		op := ot.Op{}.Retain(i).Insert("x")
+		if _, _, err := sess.apply(collabOp{Revision: i, OpID: fmt.Sprintf("op%d", i), Op: op}, "c"); err != nil {
+			t.Fatalf("apply() #%d error = %v", i, err)
+		}
+	}
+	if len(sess.history) != collabHistory {
+		t.Fatalf("history has %d ops, want %d", len(sess.history), collabHistory)
+	}
+
+	if ops, ok := sess.since("s1", sess.revision-2); !ok || len(ops) != 2 || ops[1].Revision != sess.revision {
+		t.Errorf("since(2 back) = %d ops, %v; want the last 2", len(ops), ok)
+	}
+	if _, ok := sess.since("s1", 5); ok {
+		t.Error("since() a revision older than the history succeeded; want a snapshot instead")
+	}
+	if _, ok := sess.since("old-session", sess.revision); ok {
+		t.Error("since() for another session succeeded")
+	}
+	if _, _, err := sess.apply(collabOp{Revision: 5, OpID: "late", Op: ot.Op{}.Retain(5)}, "c"); err == nil {
+		t.Error("op older than the history succeeded")
+	}
+}
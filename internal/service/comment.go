@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// MaxCommentLength caps a comment's body.
+const MaxCommentLength = 5000
+
+// LINE COMMENTS:
+// A comment can be about a whole snippet, or anchored to a range of its
+// lines ("line 12: off-by-one"), so the UI can show it next to the code.
+//
+// Line numbers only mean something for one version of the code, so every
+// comment records the snippet version it was written against (see
+// model.Snippet.Version). Once the code changes, the comment is Outdated:
+// the UI can still show it, just not pinned to lines that may have moved.
+// For the same reason, a new comment must be written against the current
+// version — a client holding an older copy gets a 409 and should reload.
+
+// CommentInput is a new comment. Version is the snippet version the
+// commenter was looking at (0: the current one). LineStart 0 comments on
+// the whole snippet; LineEnd 0 means a single line.
+type CommentInput struct {
+	Body      string
+	Version   int
+	LineStart int
+	LineEnd   int
+}
+
+// validate checks the body and line range against the code being commented
+// on, filling in LineEnd for a single line.
+func (in *CommentInput) validate(code string) error {
+	var verrs apperror.ValidationErrors
+	in.Body = strings.TrimSpace(in.Body)
+	switch {
+	case in.Body == "":
+		verrs.Add("body", "body is required")
+	case len(in.Body) > MaxCommentLength:
+		verrs.Add("body", fmt.Sprintf("body must be %d characters or less", MaxCommentLength))
+	}
+
+	if in.LineStart == 0 && in.LineEnd == 0 {
+		return verrs.Err()
+	}
+	if in.LineEnd == 0 {
+		in.LineEnd = in.LineStart
+	}
+	lines := lineCount(code)
+	switch {
+	case in.LineStart < 1 || in.LineStart > lines:
+		verrs.Add("lineStart", fmt.Sprintf("lineStart must be between 1 and %d", lines))
+	case in.LineEnd < in.LineStart || in.LineEnd > lines:
+		verrs.Add("lineEnd", fmt.Sprintf("lineEnd must be between lineStart and %d", lines))
+	}
+	return verrs.Err()
+}
+
+// lineCount is how many lines code has; a trailing newline doesn't start
+// another one.
+func lineCount(code string) int {
+	if code == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(code, "\n"), "\n") + 1
+}
+
+// CommentService manages comments on snippets.
+type CommentService struct {
+	snippets repository.SnippetRepository
+	comments repository.CommentRepository
+	logger   *slog.Logger
+	events   EventPublisher           // optional; see PublishEvents
+	orgs     repository.OrgRepository // optional; see EnableOrgs
+}
+
+// NewCommentService creates a CommentService.
+func NewCommentService(snippets repository.SnippetRepository, comments repository.CommentRepository, logger *slog.Logger) *CommentService {
+	return &CommentService{
+		snippets: snippets,
+		comments: comments,
+		logger:   logger,
+	}
+}
+
+// PublishEvents makes the service report comment.created to p. Call it
+// before serving requests.
+func (s *CommentService) PublishEvents(p EventPublisher) {
+	s.events = p
+}
+
+// EnableOrgs lets members of an org moderate comments on its snippets, as
+// owners do on theirs. Call it before serving requests.
+func (s *CommentService) EnableOrgs(orgs repository.OrgRepository) {
+	s.orgs = orgs
+}
+
+// List returns a snippet's comments, oldest first, each marked Outdated if
+// the code has changed since it was written.
+func (s *CommentService) List(ctx context.Context, snippetID string) ([]model.Comment, error) {
+	snippet, err := s.snippets.GetByID(ctx, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := s.comments.ListComments(ctx, snippet.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range comments {
+		comments[i].Outdated = comments[i].Version != snippet.Version
+	}
+	return comments, nil
+}
+
+// Create adds userID's comment to a snippet.
+func (s *CommentService) Create(ctx context.Context, userID, snippetID string, in CommentInput) (*model.Comment, error) {
+	snippet, err := s.snippets.GetByID(ctx, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	if in.Version != 0 && in.Version != snippet.Version {
+		return nil, &apperror.AppError{
+			Err:     apperror.ErrConflict,
+			Message: fmt.Sprintf("the snippet has changed since version %d (it's at version %d now); reload it and try again", in.Version, snippet.Version),
+		}
+	}
+	if err := in.validate(snippet.Code); err != nil {
+		return nil, err
+	}
+
+	comment := &model.Comment{
+		SnippetID: snippet.ID,
+		UserID:    userID,
+		Body:      in.Body,
+		Version:   snippet.Version,
+		LineStart: in.LineStart,
+		LineEnd:   in.LineEnd,
+	}
+	if err := s.comments.CreateComment(ctx, comment); err != nil {
+		return nil, fmt.Errorf("creating comment: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "comment created",
+		slog.String("id", comment.ID),
+		slog.String("snippet_id", snippet.ID),
+		slog.Int("line_start", comment.LineStart),
+	)
+	if s.events != nil {
+		s.events.Publish(ctx, model.EventCommentCreated, comment)
+	}
+	return comment, nil
+}
+
+// Delete removes a comment. Its author can delete it, and so can the
+// snippet's owner, or any member of the org that owns it.
+func (s *CommentService) Delete(ctx context.Context, userID, snippetID, commentID string) error {
+	comment, err := s.comments.GetComment(ctx, commentID)
+	if err != nil {
+		return err
+	}
+	if comment.SnippetID != snippetID {
+		return apperror.NotFound("comment", commentID)
+	}
+	if comment.UserID != userID {
+		snippet, err := s.snippets.GetByID(ctx, snippetID)
+		if err != nil {
+			return err
+		}
+		if ok, err := canManageSnippet(ctx, s.orgs, userID, snippet); err != nil {
+			return err
+		} else if !ok {
+			return &apperror.AppError{
+				Err:     apperror.ErrForbidden,
+				Message: "only the comment's author or the snippet's owner can delete it",
+			}
+		}
+	}
+	if err := s.comments.DeleteComment(ctx, comment.ID); err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "comment deleted",
+		slog.String("id", comment.ID),
+		slog.String("snippet_id", snippetID),
+	)
+	return nil
+}
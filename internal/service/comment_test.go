@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+)
+
+// mockCommentRepo keeps comments in memory, in the order they were made.
+type mockCommentRepo struct{ comments []model.Comment }
+
+func (m *mockCommentRepo) CreateComment(_ context.Context, c *model.Comment) error {
+	c.ID = fmt.Sprintf("c%d", len(m.comments)+1)
+	m.comments = append(m.comments, *c)
+	return nil
+}
+
+func (m *mockCommentRepo) GetComment(_ context.Context, id string) (*model.Comment, error) {
+	for _, c := range m.comments {
+		if c.ID == id {
+			return &c, nil
+		}
+	}
+	return nil, apperror.NotFound("comment", id)
+}
+
+func (m *mockCommentRepo) ListComments(_ context.Context, snippetID string) ([]model.Comment, error) {
+	var out []model.Comment
+	for _, c := range m.comments {
+		if c.SnippetID == snippetID {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockCommentRepo) DeleteComment(_ context.Context, id string) error {
+	for i, c := range m.comments {
+		if c.ID == id {
+			m.comments = append(m.comments[:i], m.comments[i+1:]...)
+			return nil
+		}
+	}
+	return apperror.NotFound("comment", id)
+}
+
+func TestCommentInput_Validate(t *testing.T) {
+	code := "a = 1\nb = 2\nprint(a + b)\n" // 3 lines
+
+	tests := []struct {
+		name      string
+		in        CommentInput
+		wantField string // "" means valid
+		wantEnd   int
+	}{
+		{"whole snippet", CommentInput{Body: "nice"}, "", 0},
+		{"one line", CommentInput{Body: "off-by-one", LineStart: 2}, "", 2},
+		{"range", CommentInput{Body: "x", LineStart: 1, LineEnd: 3}, "", 3},
+		{"blank body", CommentInput{Body: "  ", LineStart: 1}, "body", 1},
+		{"past the end", CommentInput{Body: "x", LineStart: 4}, "lineStart", 4},
+		{"end before start", CommentInput{Body: "x", LineStart: 2, LineEnd: 1}, "lineEnd", 1},
+		{"end past the end", CommentInput{Body: "x", LineStart: 2, LineEnd: 4}, "lineEnd", 4},
+		{"end without start", CommentInput{Body: "x", LineEnd: 2}, "lineStart", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.in.validate(code)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("validate() error = %v", err)
+				}
+				if tt.in.LineEnd != tt.wantEnd {
+					t.Errorf("LineEnd = %d, want %d", tt.in.LineEnd, tt.wantEnd)
+				}
+				return
+			}
+			var verrs *apperror.ValidationErrors
+			if !errors.As(err, &verrs) || len(verrs.Fields) != 1 || verrs.Fields[0].Field != tt.wantField {
+				t.Errorf("validate() error = %v, want one error on %s", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestLineCount(t *testing.T) {
+	for code, want := range map[string]int{"": 0, "x": 1, "x\n": 1, "x\ny": 2, "x\n\n": 2} {
+		if got := lineCount(code); got != want {
+			t.Errorf("lineCount(%q) = %d, want %d", code, got, want)
+		}
+	}
+}
+
+func TestCommentService(t *testing.T) {
+	snippets := newMockRepo()
+	snippets.snippets["s1"] = &model.Snippet{ID: "s1", UserID: "owner", Code: "a\nb\nc", Version: 1}
+	comments := &mockCommentRepo{}
+	svc := NewCommentService(snippets, comments, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	line, err := svc.Create(ctx, "teacher", "s1", CommentInput{Body: "off-by-one", Version: 1, LineStart: 2})
+	if err != nil || line.Version != 1 || line.LineEnd != 2 {
+		t.Fatalf("Create() = %+v, %v; want line 2 of version 1", line, err)
+	}
+	if _, err := svc.Create(ctx, "teacher", "s1", CommentInput{Body: "x", Version: 2}); !errors.Is(err, apperror.ErrConflict) {
+		t.Errorf("comment on a version that isn't current: error = %v, want ErrConflict", err)
+	}
+
+	// The code changes: the line comment is now outdated.
+	snippets.snippets["s1"].Version = 2
+	if _, err := svc.Create(ctx, "student", "s1", CommentInput{Body: "fixed?"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	list, err := svc.List(ctx, "s1")
+	if err != nil || len(list) != 2 || !list[0].Outdated || list[1].Outdated {
+		t.Fatalf("List() = %+v, %v; want the first outdated, the second current", list, err)
+	}
+
+	if err := svc.Delete(ctx, "student", "s1", line.ID); !errors.Is(err, apperror.ErrForbidden) {
+		t.Errorf("delete someone else's comment: error = %v, want ErrForbidden", err)
+	}
+	if err := svc.Delete(ctx, "owner", "s1", line.ID); err != nil {
+		t.Errorf("snippet owner deletes a comment: error = %v", err)
+	}
+	if err := svc.Delete(ctx, "student", "other", list[1].ID); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("delete via the wrong snippet: error = %v, want ErrNotFound", err)
+	}
+	if err := svc.Delete(ctx, "student", "s1", list[1].ID); err != nil {
+		t.Errorf("author deletes own comment: error = %v", err)
+	}
+}
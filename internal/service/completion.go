@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/executor"
+)
+
+// AUTOCOMPLETE:
+// POST /api/v1/complete is what the editor asks as the learner types:
+//
+//	{"code":"import math\nmath.sq","line":2,"column":7}
+//	→ {"engine":"jedi","completions":[{"name":"sqrt","complete":"rt","type":"function"}]}
+//
+// line counts from 1 and column from 0, in characters — jedi's convention,
+// and the editor's cursor once its line is made 1-based. Completions run in
+// the sandbox (executor.Complete), on an executor of their own in production
+// so a burst of keystrokes never waits behind, or holds up, someone's run.
+
+// CompletionRequest is where in the code to complete.
+type CompletionRequest struct {
+	Code   string `json:"code"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// CompletionService finds completions for the editor.
+type CompletionService struct {
+	exec      executor.Executor
+	logger    *slog.Logger
+	deadlines Deadlines // see SetDeadlines
+}
+
+// NewCompletionService creates a CompletionService that runs the completer
+// on exec.
+func NewCompletionService(exec executor.Executor, logger *slog.Logger) *CompletionService {
+	return &CompletionService{
+		exec:      exec,
+		logger:    logger,
+		deadlines: DefaultDeadlines(),
+	}
+}
+
+// SetDeadlines changes how long finding completions may take (d.Execute).
+// Call it before serving requests.
+func (s *CompletionService) SetDeadlines(d Deadlines) {
+	s.deadlines = d
+}
+
+// Complete returns the completions at req's position in req.Code.
+func (s *CompletionService) Complete(ctx context.Context, req CompletionRequest) (*executor.Completions, error) {
+	if err := validateCompletionRequest(req); err != nil {
+		return nil, err
+	}
+	completions, err := withExecute(ctx, s.deadlines, func(ctx context.Context) (*executor.Completions, error) {
+		return executor.Complete(ctx, s.exec, req.Code, req.Line, req.Column)
+	})
+	if errors.Is(err, executor.ErrCompletionProgramTooLarge) {
+		return nil, apperror.ValidationFailed("code", "code is too large to complete").WithCode(apperror.CodeCodeTooLong)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("finding completions: %w", err)
+	}
+	s.logger.DebugContext(ctx, "completions found",
+		slog.String("engine", completions.Engine),
+		slog.Int("count", len(completions.Completions)),
+	)
+	return completions, nil
+}
+
+// validateCompletionRequest checks that req's position is in its code.
+func validateCompletionRequest(req CompletionRequest) error {
+	var verrs apperror.ValidationErrors
+	if len(req.Code) > MaxCodeLength {
+		verrs.AddCode(apperror.CodeCodeTooLong, "code", fmt.Sprintf("code must be %d characters or less", MaxCodeLength), "max", strconv.Itoa(MaxCodeLength))
+		return verrs.Err()
+	}
+	lines := strings.Split(req.Code, "\n")
+	if req.Line < 1 || req.Line > len(lines) {
+		verrs.Add("line", fmt.Sprintf("line must be between 1 and %d, the code's last line", len(lines)))
+		return verrs.Err()
+	}
+	if n := utf8.RuneCountInString(lines[req.Line-1]); req.Column < 0 || req.Column > n {
+		verrs.Add("column", fmt.Sprintf("column must be between 0 and %d, the end of line %d", n, req.Line))
+	}
+	return verrs.Err()
+}
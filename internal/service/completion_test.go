@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+)
+
+func TestCompletionService_Validation(t *testing.T) {
+	exec := &timeoutExecutor{}
+	svc := NewCompletionService(exec, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tests := []struct {
+		name  string
+		req   CompletionRequest
+		field string
+	}{
+		{"line 0", CompletionRequest{Code: "x", Line: 0}, "line"},
+		{"past the last line", CompletionRequest{Code: "x\ny", Line: 3}, "line"},
+		{"negative column", CompletionRequest{Code: "x", Line: 1, Column: -1}, "column"},
+		{"past the end of the line", CompletionRequest{Code: "héllo\nx", Line: 1, Column: 6}, "column"},
+		{"too much code", CompletionRequest{Code: strings.Repeat("x", MaxCodeLength+1), Line: 1}, "code"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Complete(context.Background(), tt.req)
+			var verrs *apperror.ValidationErrors
+			if !errors.As(err, &verrs) || len(verrs.Fields) != 1 || verrs.Fields[0].Field != tt.field {
+				t.Errorf("error = %v, want a validation error on %s", err, tt.field)
+			}
+		})
+	}
+	if exec.runs != 0 {
+		t.Errorf("the completer ran %d times for invalid requests", exec.runs)
+	}
+
+	// Columns count characters, not bytes: the end of "héllo" is 5.
+	_, err := svc.Complete(context.Background(), CompletionRequest{Code: "héllo", Line: 1, Column: 5})
+	if err == nil || errors.Is(err, apperror.ErrValidation) {
+		t.Errorf("error = %v, want the run's failure (it timed out), not a validation error", err)
+	}
+}
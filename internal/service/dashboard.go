@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// Dashboard is the summary shown on the admin dashboard.
+type Dashboard struct {
+	Users         int
+	NewUsers      int // signed up in the last 7 days
+	Snippets      int
+	Anonymous     int // snippets without an owner
+	SnippetsToday int // created in the last 24 hours
+
+	// ExecutionsPerHour covers the last 24 hours, oldest first.
+	ExecutionsPerHour []HourlyCount
+}
+
+// HourlyCount is how many times something happened in the hour from Hour.
+type HourlyCount struct {
+	Hour  time.Time
+	Count int
+}
+
+// DashboardService gathers the numbers for the admin dashboard.
+type DashboardService struct {
+	snippets   repository.SnippetRepository
+	users      repository.UserRepository
+	executions *ExecutionCounter
+	logger     *slog.Logger
+}
+
+// NewDashboardService creates a DashboardService. executions may be nil when
+// code execution is unavailable.
+func NewDashboardService(snippets repository.SnippetRepository, users repository.UserRepository, executions *ExecutionCounter, logger *slog.Logger) *DashboardService {
+	return &DashboardService{
+		snippets:   snippets,
+		users:      users,
+		executions: executions,
+		logger:     logger,
+	}
+}
+
+// Summary counts users, snippets and recent executions.
+func (s *DashboardService) Summary(ctx context.Context) (*Dashboard, error) {
+	now := time.Now()
+	anonymous := false
+	d := &Dashboard{}
+
+	counts := []struct {
+		dst   *int
+		count func() (int, error)
+	}{
+		{&d.Users, func() (int, error) { return s.users.CountUsers(ctx, time.Time{}) }},
+		{&d.NewUsers, func() (int, error) { return s.users.CountUsers(ctx, now.AddDate(0, 0, -7)) }},
+		{&d.Snippets, func() (int, error) { return s.snippets.Count(ctx, repository.ListOptions{}) }},
+		{&d.Anonymous, func() (int, error) {
+			return s.snippets.Count(ctx, repository.ListOptions{HasOwner: &anonymous})
+		}},
+		{&d.SnippetsToday, func() (int, error) {
+			return s.snippets.Count(ctx, repository.ListOptions{CreatedAfter: now.Add(-24 * time.Hour)})
+		}},
+	}
+	for _, c := range counts {
+		n, err := c.count()
+		if err != nil {
+			return nil, fmt.Errorf("building dashboard: %w", err)
+		}
+		*c.dst = n
+	}
+
+	if s.executions != nil {
+		d.ExecutionsPerHour = s.executions.Hourly()
+	}
+	return d, nil
+}
+
+// ExecutionCounter counts completed executions per hour for the last day.
+// It's an EventPublisher, so it hears about executions the same way webhooks
+// do. Counts live in memory and restart from zero with the server.
+type ExecutionCounter struct {
+	mu    sync.Mutex
+	hours [24]HourlyCount // ring buffer indexed by UTC hour of the day
+	now   func() time.Time
+}
+
+// NewExecutionCounter creates an empty ExecutionCounter.
+func NewExecutionCounter() *ExecutionCounter {
+	return &ExecutionCounter{now: time.Now}
+}
+
+// Publish counts execution.completed events and ignores the rest.
+func (c *ExecutionCounter) Publish(_ context.Context, event string, _ any) {
+	if event != model.EventExecutionCompleted {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hour := c.now().Truncate(time.Hour)
+	slot := &c.hours[hour.UTC().Hour()]
+	if !slot.Hour.Equal(hour) {
+		// The slot still holds the count from a day ago.
+		*slot = HourlyCount{Hour: hour}
+	}
+	slot.Count++
+}
+
+// Hourly returns the counts for the last 24 hours, oldest first. Hours with
+// no executions are included with a zero count.
+func (c *ExecutionCounter) Hourly() []HourlyCount {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current := c.now().Truncate(time.Hour)
+	out := make([]HourlyCount, len(c.hours))
+	for i := range out {
+		hour := current.Add(time.Duration(i-len(out)+1) * time.Hour)
+		out[i] = HourlyCount{Hour: hour}
+		if slot := c.hours[hour.UTC().Hour()]; slot.Hour.Equal(hour) {
+			out[i].Count = slot.Count
+		}
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/model"
+)
+
+func TestExecutionCounter(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)
+	c := NewExecutionCounter()
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	c.Publish(ctx, model.EventExecutionCompleted, nil)
+	c.Publish(ctx, model.EventExecutionCompleted, nil)
+	c.Publish(ctx, model.EventSnippetCreated, nil) // not an execution
+
+	now = now.Add(3 * time.Hour)
+	c.Publish(ctx, model.EventExecutionCompleted, nil)
+
+	hourly := c.Hourly()
+	if len(hourly) != 24 {
+		t.Fatalf("len(Hourly()) = %d, want 24", len(hourly))
+	}
+	last := hourly[23]
+	if !last.Hour.Equal(time.Date(2026, 3, 1, 15, 0, 0, 0, time.UTC)) || last.Count != 1 {
+		t.Errorf("current hour = %+v, want 15:00 with 1 execution", last)
+	}
+	if hourly[20].Count != 2 {
+		t.Errorf("12:00 count = %d, want 2", hourly[20].Count)
+	}
+
+	// A day later the 12:00 slot is reused, not added to.
+	now = now.Add(21 * time.Hour)
+	c.Publish(ctx, model.EventExecutionCompleted, nil)
+	hourly = c.Hourly()
+	if hourly[23].Count != 1 {
+		t.Errorf("next day's 12:00 count = %d, want 1", hourly[23].Count)
+	}
+	if hourly[2].Count != 1 {
+		t.Errorf("yesterday's 15:00 count = %d, want 1", hourly[2].Count)
+	}
+}
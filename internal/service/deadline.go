@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+)
+
+// DEADLINES PER LAYER:
+// A request's own deadline (middleware.Timeout, the server's WriteTimeout)
+// bounds the request as a whole. Within it, each call a service makes to a
+// layer below gets a deadline of its own: a repository call gets
+// Deadlines.DB, a code run Deadlines.Execute. A SQLite lock that's never
+// released, or a Docker daemon that stopped answering, then fails that one
+// call — as 503 Unavailable, naming what hung — instead of silently eating
+// the rest of the request's time.
+//
+// Usage, in a service:
+//
+//	snippet, err := withDB(ctx, s.deadlines, func(ctx context.Context) (*model.Snippet, error) {
+//		return s.repo.GetByID(ctx, id)
+//	})
+
+// Deadlines bounds one call into each layer below the services. 0 leaves a
+// layer bounded only by the request.
+type Deadlines struct {
+	DB      time.Duration // one repository call
+	Execute time.Duration // one code run, waiting for a sandbox included
+}
+
+// DefaultDeadlines returns the deadlines services start with.
+func DefaultDeadlines() Deadlines {
+	return Deadlines{
+		DB:      2 * time.Second,
+		Execute: 15 * time.Second,
+	}
+}
+
+// withDB runs fn, one repository call, under the DB deadline.
+func withDB[T any](ctx context.Context, d Deadlines, fn func(context.Context) (T, error)) (T, error) {
+	return within(ctx, d.DB, "the database", fn)
+}
+
+// runDB is withDB for a call that only returns an error.
+func runDB(ctx context.Context, d Deadlines, fn func(context.Context) error) error {
+	_, err := withDB(ctx, d, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// withExecute runs fn, one code run, under the Execute deadline.
+func withExecute[T any](ctx context.Context, d Deadlines, fn func(context.Context) (T, error)) (T, error) {
+	return within(ctx, d.Execute, "code execution", fn)
+}
+
+// within runs fn with ctx limited to timeout. If fn fails because that
+// deadline passed — not the caller's — the error says so and is reported
+// as unavailable.
+func within[T any](ctx context.Context, timeout time.Duration, what string, fn func(context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	v, err := fn(callCtx)
+	// Check the contexts rather than err: a driver interrupted by its
+	// context may report that in words of its own.
+	if err != nil && callCtx.Err() != nil && ctx.Err() == nil {
+		return v, fmt.Errorf("%s didn't answer within %s (%v): %w", what, timeout, err, apperror.Unavailable(what))
+	}
+	return v, err
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// hungRepository never answers: every GetByID waits for its context, the
+// way a query behind a lock that's never released does.
+type hungRepository struct {
+	repository.SnippetRepository
+}
+
+func (hungRepository) GetByID(ctx context.Context, _ string) (*model.Snippet, error) {
+	<-ctx.Done()
+	return nil, errors.New("interrupted (9)") // what SQLite says, not ctx.Err()
+}
+
+func TestDeadlines(t *testing.T) {
+	svc := NewSnippetService(hungRepository{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	svc.SetDeadlines(Deadlines{DB: 20 * time.Millisecond})
+
+	t.Run("a hung call is unavailable", func(t *testing.T) {
+		start := time.Now()
+		_, err := svc.GetByID(context.Background(), "abc")
+		if !errors.Is(err, apperror.ErrUnavailable) {
+			t.Fatalf("GetByID() error = %v, want unavailable", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("GetByID() took %v, want about the 20ms deadline", elapsed)
+		}
+	})
+
+	t.Run("the caller's own deadline is its own", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		defer cancel()
+		if _, err := svc.GetByID(ctx, "abc"); err == nil || errors.Is(err, apperror.ErrUnavailable) {
+			t.Errorf("GetByID() error = %v, want the repository's, not unavailable", err)
+		}
+	})
+
+	t.Run("0 leaves a layer to the request", func(t *testing.T) {
+		ran := false
+		v, err := withDB(context.Background(), Deadlines{}, func(ctx context.Context) (int, error) {
+			if _, ok := ctx.Deadline(); ok {
+				t.Error("a deadline was set")
+			}
+			ran = true
+			return 1, nil
+		})
+		if !ran || v != 1 || err != nil {
+			t.Errorf("withDB() = %d, %v", v, err)
+		}
+	})
+}
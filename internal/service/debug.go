@@ -0,0 +1,377 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/abuse"
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/executor"
+	"github.com/sakif/coding-playground/internal/ws"
+)
+
+// DEBUGGING:
+// A learner steps through their program under pdb (see executor.Debug)
+// from the browser. The session starts over HTTP and is driven over the
+// WebSocket hub:
+//
+//	POST /api/v1/debug {"code":"…","breakpoints":[4]} → 201 {"id":"9f3c…","topic":"debug:9f3c…",…}
+//	→ {"type":"subscribe","topic":"debug:9f3c…"}
+//	GET  /api/v1/debug/9f3c…                         → where the program is paused, and its output so far
+//	← {"type":"event","topic":"debug:9f3c…","data":{"kind":"paused","line":4,"locals":{…},…}}
+//	→ {"type":"debug.command","topic":"debug:9f3c…","id":"7","data":{"command":"next"}}
+//	← {"type":"debug.sent","topic":"debug:9f3c…","id":"7"}
+//	← {"type":"event","topic":"debug:9f3c…","data":{"kind":"running"}}
+//	…
+//	← {"type":"event","topic":"debug:9f3c…","data":{"kind":"done","result":{"exitCode":0,…}}}
+//
+// The program may pause before the client has subscribed, which is what
+// the GET is for. A command is any line pdb understands; sent while the
+// program is running rather than paused, it's the program's input().
+//
+// Sessions are kept here, like background executions: IDs are random, a
+// signed-in user's session is only theirs to watch and drive, and an
+// anonymous one is open to whoever has the ID. A session ends when the
+// program does, when it's stopped (DELETE), or when the executor's timeout
+// passes — a debugging executor's is minutes, not seconds (DEBUG_TIMEOUT).
+
+const (
+	// DebugTopicPrefix starts a session's topic, "debug:<id>".
+	DebugTopicPrefix = "debug:"
+
+	// MsgDebugCommand sends pdb a command; MsgDebugSent acknowledges it.
+	MsgDebugCommand = "debug.command"
+	MsgDebugSent    = "debug.sent"
+
+	// MaxDebugSessions caps the sessions running on this server at once.
+	// Each holds a sandbox for as long as the learner takes.
+	MaxDebugSessions = 20
+
+	// MaxBreakpoints caps a session's starting breakpoints.
+	MaxBreakpoints = 100
+
+	// MaxDebugCommandLength caps a command.
+	MaxDebugCommandLength = 1000
+
+	// MaxDebugOutput caps how much output a session keeps for the GET.
+	// Events carry all of it.
+	MaxDebugOutput = 256 << 10
+)
+
+// DebugRequest is the code to debug.
+type DebugRequest struct {
+	Code        string `json:"code"`
+	Breakpoints []int  `json:"breakpoints,omitempty"` // 1-based lines; none stops on the first
+}
+
+// DebugSession is a program being debugged.
+type DebugSession struct {
+	ID    string `json:"id"`
+	Topic string `json:"topic"` // subscribe to this on /ws
+	// Paused is where the program is waiting for a command; nil while it
+	// runs.
+	Paused    *executor.DebugEvent `json:"paused,omitempty"`
+	Stdout    string               `json:"stdout"`
+	Stderr    string               `json:"stderr"`
+	Truncated bool                 `json:"truncated,omitempty"` // past MaxDebugOutput
+	StartedAt time.Time            `json:"startedAt"`
+
+	userID string         // "" for an anonymous session
+	stdin  *io.PipeWriter // pdb's commands
+}
+
+// debugEvent is published on a session's topic: an executor.DebugEvent, or
+// "done" with the result.
+type debugEvent struct {
+	executor.DebugEvent
+	Result *executor.ExecutionResult `json:"result,omitempty"`
+	Error  string                    `json:"error,omitempty"` // the sandbox failed
+}
+
+// DebugService runs debugging sessions.
+type DebugService struct {
+	exec   executor.Executor
+	hub    *ws.Hub
+	logger *slog.Logger
+	abuse  *abuse.Detector // optional; see DetectAbuse
+
+	mu       sync.Mutex
+	sessions map[string]*DebugSession // the running ones
+	cancels  map[string]context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewDebugService registers the "debug:" topics and the debug.command
+// message on hub. Sessions run on exec.
+func NewDebugService(exec executor.Executor, hub *ws.Hub, logger *slog.Logger) *DebugService {
+	s := &DebugService{
+		exec:     exec,
+		hub:      hub,
+		logger:   logger,
+		sessions: make(map[string]*DebugSession),
+		cancels:  make(map[string]context.CancelFunc),
+	}
+	hub.Authorize(DebugTopicPrefix, func(_ context.Context, userID, topic string) error {
+		_, err := s.find(userID, strings.TrimPrefix(topic, DebugTopicPrefix))
+		return err
+	})
+	hub.HandleMessage(MsgDebugCommand, s.handleCommand)
+	return s
+}
+
+// DetectAbuse makes the service check code with d before debugging it.
+// Call it before serving requests.
+func (s *DebugService) DetectAbuse(d *abuse.Detector) {
+	s.abuse = d
+}
+
+// Start begins debugging req.Code for the user in ctx (if any) and returns
+// at once; the program runs until its first stop with no one watching.
+func (s *DebugService) Start(ctx context.Context, req DebugRequest) (*DebugSession, error) {
+	if err := validateDebugRequest(req); err != nil {
+		return nil, err
+	}
+	if s.abuse != nil {
+		if err := s.abuse.Check(ctx, abuse.SourceDebug, req.Code); err != nil {
+			return nil, err
+		}
+	}
+
+	userID, _ := auth.UserIDFromContext(ctx)
+	id := make([]byte, 16)
+	rand.Read(id) // never fails: crypto/rand crashes the program instead
+	commands, stdin := io.Pipe()
+	sess := &DebugSession{
+		ID:        hex.EncodeToString(id),
+		StartedAt: time.Now().UTC(),
+		userID:    userID,
+		stdin:     stdin,
+	}
+	sess.Topic = DebugTopicPrefix + sess.ID
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.sessions) >= MaxDebugSessions {
+		return nil, apperror.Unavailable("debugging")
+	}
+	// The session outlives the request that started it.
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	s.sessions[sess.ID] = sess
+	s.cancels[sess.ID] = cancel
+
+	s.wg.Add(1)
+	go s.debug(runCtx, sess, req, commands)
+
+	s.logger.InfoContext(ctx, "debugging session started", slog.String("session_id", sess.ID))
+	return sess.copy(), nil
+}
+
+// Get returns session id as it is now.
+func (s *DebugService) Get(ctx context.Context, id string) (*DebugSession, error) {
+	userID, _ := auth.UserIDFromContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, err := s.findLocked(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return sess.copy(), nil
+}
+
+// Stop ends session id, and the program with it.
+func (s *DebugService) Stop(ctx context.Context, id string) error {
+	userID, _ := auth.UserIDFromContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.findLocked(userID, id); err != nil {
+		return err
+	}
+	s.cancels[id]()
+	return nil
+}
+
+// Send gives pdb — or the program, if it's running — a line of input.
+func (s *DebugService) Send(userID, id, command string) error {
+	if len(command) > MaxDebugCommandLength {
+		return apperror.ValidationFailed("command", fmt.Sprintf("command must be %d characters or less", MaxDebugCommandLength))
+	}
+	if strings.ContainsAny(command, "\r\n") {
+		return apperror.ValidationFailed("command", "command must be a single line")
+	}
+	sess, err := s.find(userID, id)
+	if err != nil {
+		return err
+	}
+	// The pipe passes the line on once the sandbox reads it; after the
+	// session ends it's closed, and this fails instead of waiting.
+	if _, err := io.WriteString(sess.stdin, command+"\n"); err != nil {
+		return apperror.NotFound("debugging session", id)
+	}
+	return nil
+}
+
+// Shutdown ends every session and waits for them to end, or for ctx.
+func (s *DebugService) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	for _, cancel := range s.cancels {
+		cancel()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// debug runs the session's program, publishing what happens.
+func (s *DebugService) debug(ctx context.Context, sess *DebugSession, req DebugRequest, commands *io.PipeReader) {
+	defer s.wg.Done()
+	result, err := executor.Debug(ctx, s.exec, req.Code, req.Breakpoints, commands, func(e executor.DebugEvent) {
+		s.event(sess, e)
+	})
+	commands.Close()
+
+	if err == nil && s.abuse != nil {
+		s.abuse.Observe(ctx, req.Code, result.Duration)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	done := debugEvent{DebugEvent: executor.DebugEvent{Kind: "done"}, Result: result}
+	switch {
+	case errors.Is(err, executor.ErrDebugProgramTooLarge):
+		done.Error = "the code is too large to debug"
+	case err != nil:
+		done.Error = "the sandbox failed to run the code"
+		s.logger.Error("debugging session failed",
+			slog.String("session_id", sess.ID),
+			slog.String("error", err.Error()),
+		)
+	}
+	s.cancels[sess.ID]()
+	delete(s.cancels, sess.ID)
+	delete(s.sessions, sess.ID)
+	s.publish(sess.Topic, done)
+}
+
+// event records what happened and tells the session's client.
+func (s *DebugService) event(sess *DebugSession, e executor.DebugEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch e.Kind {
+	case "paused":
+		sess.Paused = &e
+	case "running":
+		sess.Paused = nil
+	case "output":
+		kept := &sess.Stdout
+		if e.Stream == "stderr" {
+			kept = &sess.Stderr
+		}
+		if len(sess.Stdout)+len(sess.Stderr)+len(e.Text) <= MaxDebugOutput {
+			*kept += e.Text
+		} else {
+			sess.Truncated = true
+		}
+	}
+	s.publish(sess.Topic, debugEvent{DebugEvent: e})
+}
+
+// handleCommand passes a debug.command on to its session.
+func (s *DebugService) handleCommand(_ context.Context, c *ws.Client, msg ws.Message) {
+	var data struct {
+		Command string `json:"command"`
+	}
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		c.Send(ws.Message{Type: ws.TypeError, Topic: msg.Topic, ID: msg.ID, Error: `data must be {"command":"…"}`})
+		return
+	}
+	id, ok := strings.CutPrefix(msg.Topic, DebugTopicPrefix)
+	if !ok {
+		c.Send(ws.Message{Type: ws.TypeError, Topic: msg.Topic, ID: msg.ID, Error: "topic must be a debugging session's"})
+		return
+	}
+	if err := s.Send(c.UserID(), id, data.Command); err != nil {
+		var appErr *apperror.AppError
+		text := err.Error()
+		if errors.As(err, &appErr) {
+			text = appErr.Message
+		}
+		c.Send(ws.Message{Type: ws.TypeError, Topic: msg.Topic, ID: msg.ID, Error: text})
+		return
+	}
+	c.Send(ws.Message{Type: MsgDebugSent, Topic: msg.Topic, ID: msg.ID})
+}
+
+// find returns running session id if userID may drive it.
+func (s *DebugService) find(userID, id string) (*DebugSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.findLocked(userID, id)
+}
+
+// findLocked is find for callers holding s.mu.
+func (s *DebugService) findLocked(userID, id string) (*DebugSession, error) {
+	sess := s.sessions[id]
+	if sess == nil || sess.userID != "" && sess.userID != userID {
+		return nil, apperror.NotFound("debugging session", id)
+	}
+	return sess, nil
+}
+
+// publish sends an event to a session's client. Callers hold s.mu, so
+// events go out in order.
+func (s *DebugService) publish(topic string, event debugEvent) {
+	if err := s.hub.Publish(topic, event); err != nil {
+		s.logger.Error("failed to publish debugging event", slog.String("topic", topic), slog.String("error", err.Error()))
+	}
+}
+
+// copy snapshots the session for callers outside s.mu.
+func (sess *DebugSession) copy() *DebugSession {
+	c := *sess
+	return &c
+}
+
+// validateDebugRequest checks the code and that every breakpoint is on one
+// of its lines.
+func validateDebugRequest(req DebugRequest) error {
+	var verrs apperror.ValidationErrors
+	switch {
+	case strings.TrimSpace(req.Code) == "":
+		verrs.Add("code", "code is required")
+	case len(req.Code) > MaxCodeLength:
+		verrs.AddCode(apperror.CodeCodeTooLong, "code", fmt.Sprintf("code must be %d characters or less", MaxCodeLength), "max", strconv.Itoa(MaxCodeLength))
+	}
+	lines := strings.Count(req.Code, "\n") + 1
+	if len(req.Breakpoints) > MaxBreakpoints {
+		verrs.Add("breakpoints", fmt.Sprintf("at most %d breakpoints", MaxBreakpoints))
+	} else {
+		for _, line := range req.Breakpoints {
+			if line < 1 || line > lines {
+				verrs.Add("breakpoints", fmt.Sprintf("line %d isn't in the code, which has %d", line, lines))
+				break
+			}
+		}
+	}
+	return verrs.Err()
+}
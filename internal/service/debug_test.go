@@ -0,0 +1,167 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/executor"
+	"github.com/sakif/coding-playground/internal/ws"
+)
+
+// pdbExecutor stands in for the debugger harness: it pauses, and answers
+// each command by printing it, until stdin closes or it's told "quit".
+type pdbExecutor struct{}
+
+var harnessMarker = regexp.MustCompile(`MARKER = "(@@debug-[0-9a-f]+@@)"`)
+
+func (pdbExecutor) Execute(ctx context.Context, req executor.ExecutionRequest) (*executor.ExecutionResult, error) {
+	return pdbExecutor{}.ExecuteStream(ctx, req, func(executor.Output) {})
+}
+
+func (pdbExecutor) ExecuteStream(ctx context.Context, req executor.ExecutionRequest, out func(executor.Output)) (*executor.ExecutionResult, error) {
+	marker := "\n" + harnessMarker.FindStringSubmatch(req.Code)[1]
+	event := func(json string) { out(executor.Output{Stream: "stdout", Text: marker + json + "\n"}) }
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(req.Stdin)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	event(`{"kind":"paused","line":1,"function":"<module>"}`)
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok || line == "quit" {
+				return &executor.ExecutionResult{}, nil
+			}
+			out(executor.Output{Stream: "stdout", Text: "got " + line + "\n"})
+			event(`{"kind":"running"}`)
+		case <-ctx.Done():
+			return &executor.ExecutionResult{ExitCode: 124}, nil
+		}
+	}
+}
+
+func newDebugService(t *testing.T) *DebugService {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := NewDebugService(pdbExecutor{}, ws.NewHub(logger, ws.Options{}), logger)
+	t.Cleanup(func() { svc.Shutdown(context.Background()) })
+	return svc
+}
+
+// waitForSession polls session id until ok says it's arrived.
+func waitForSession(t *testing.T, svc *DebugService, ctx context.Context, id string, ok func(*DebugSession, error) bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		sess, err := svc.Get(ctx, id)
+		if ok(sess, err) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("session = %+v, %v; gave up waiting", sess, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDebugService(t *testing.T) {
+	svc := newDebugService(t)
+	asAnn := auth.WithUserID(context.Background(), "ann")
+	asBob := auth.WithUserID(context.Background(), "bob")
+
+	sess, err := svc.Start(asAnn, DebugRequest{Code: "x = 1\nprint(x)\n", Breakpoints: []int{2}})
+	if err != nil || sess.Topic != "debug:"+sess.ID {
+		t.Fatalf("Start() = %+v, %v", sess, err)
+	}
+	waitForSession(t, svc, asAnn, sess.ID, func(s *DebugSession, err error) bool {
+		return err == nil && s.Paused != nil && s.Paused.Line == 1
+	})
+
+	if _, err := svc.Get(asBob, sess.ID); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("Get() by someone else: error = %v, want ErrNotFound", err)
+	}
+	if err := svc.Send("bob", sess.ID, "next"); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("Send() by someone else: error = %v, want ErrNotFound", err)
+	}
+	if err := svc.Send("ann", sess.ID, "p x\nimport os"); !errors.Is(err, apperror.ErrValidation) {
+		t.Errorf("Send() of two lines: error = %v, want ErrValidation", err)
+	}
+
+	if err := svc.Send("ann", sess.ID, "next"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	waitForSession(t, svc, asAnn, sess.ID, func(s *DebugSession, err error) bool {
+		return err == nil && s.Paused == nil && s.Stdout == "got next\n"
+	})
+
+	if err := svc.Send("ann", sess.ID, "quit"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	waitForSession(t, svc, asAnn, sess.ID, func(_ *DebugSession, err error) bool {
+		return errors.Is(err, apperror.ErrNotFound) // ended, and forgotten
+	})
+	if err := svc.Send("ann", sess.ID, "next"); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("Send() after the end: error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDebugService_Stop(t *testing.T) {
+	svc := newDebugService(t)
+	ctx := context.Background() // anonymous: anyone with the ID may stop it
+
+	sess, err := svc.Start(ctx, DebugRequest{Code: "print(1)"})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := svc.Stop(auth.WithUserID(ctx, "bob"), sess.ID); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	waitForSession(t, svc, ctx, sess.ID, func(_ *DebugSession, err error) bool {
+		return errors.Is(err, apperror.ErrNotFound)
+	})
+	if err := svc.Stop(ctx, sess.ID); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("Stop() after the end: error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDebugService_Validation(t *testing.T) {
+	svc := newDebugService(t)
+	tests := []struct {
+		name  string
+		req   DebugRequest
+		field string
+	}{
+		{"no code", DebugRequest{Code: "  "}, "code"},
+		{"too much code", DebugRequest{Code: strings.Repeat("x", MaxCodeLength+1)}, "code"},
+		{"breakpoint past the end", DebugRequest{Code: "x = 1\n", Breakpoints: []int{3}}, "breakpoints"},
+		{"breakpoint before the start", DebugRequest{Code: "x = 1", Breakpoints: []int{0}}, "breakpoints"},
+		{"too many breakpoints", DebugRequest{Code: "x = 1", Breakpoints: make([]int, MaxBreakpoints+1)}, "breakpoints"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Start(context.Background(), tt.req)
+			var verrs *apperror.ValidationErrors
+			if !errors.As(err, &verrs) || verrs.Fields[0].Field != tt.field {
+				t.Errorf("Start() error = %v, want a validation error on %s", err, tt.field)
+			}
+		})
+	}
+}
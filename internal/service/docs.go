@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/cache"
+	"github.com/sakif/coding-playground/internal/executor"
+)
+
+// HOVER DOCS:
+// GET /api/v1/docs?symbol=os.path.join returns what Python's own help()
+// would say — the signature and the docstring — for the editor to show on
+// hover:
+//
+//	{"symbol":"os.path.join","kind":"function","signature":"join(a, *p)","doc":"Join two or more…","module":"posixpath"}
+//
+// The lookup runs in the sandbox (executor.LookUpDocs), so the docs match
+// the Python the code will run on. A standard library's docs don't change
+// between requests, and the editor asks for the same few hundred names over
+// and over, so answers are cached (see Cache): one sandbox run per name per
+// TTL, shared by every app server when the cache is in Redis.
+
+// MaxSymbolLength caps a symbol looked up.
+const MaxSymbolLength = 200
+
+// DocsService looks up Python documentation.
+type DocsService struct {
+	exec      executor.Executor
+	logger    *slog.Logger
+	deadlines Deadlines   // see SetDeadlines
+	store     cache.Store // optional; see Cache
+	ttl       time.Duration
+}
+
+// NewDocsService creates a DocsService that runs lookups on exec.
+func NewDocsService(exec executor.Executor, logger *slog.Logger) *DocsService {
+	return &DocsService{
+		exec:      exec,
+		logger:    logger,
+		deadlines: DefaultDeadlines(),
+	}
+}
+
+// SetDeadlines changes how long a lookup may take (d.Execute). Call it
+// before serving requests.
+func (s *DocsService) SetDeadlines(d Deadlines) {
+	s.deadlines = d
+}
+
+// Cache keeps the docs found in store for ttl. Names that weren't found
+// aren't cached: a typo shouldn't be remembered. Call it before serving
+// requests.
+func (s *DocsService) Cache(store cache.Store, ttl time.Duration) {
+	s.store, s.ttl = store, ttl
+}
+
+// LookUp returns the docs for symbol, a dotted name like "os.path.join".
+func (s *DocsService) LookUp(ctx context.Context, symbol string) (*executor.Doc, error) {
+	switch {
+	case symbol == "":
+		return nil, apperror.ValidationFailed("symbol", "symbol is required")
+	case len(symbol) > MaxSymbolLength:
+		return nil, apperror.ValidationFailed("symbol", fmt.Sprintf("symbol must be %d characters or less", MaxSymbolLength))
+	case !executor.ValidSymbol(symbol):
+		return nil, apperror.ValidationFailed("symbol", "symbol must be a dotted Python name, like os.path.join")
+	}
+
+	key := "docs:" + symbol
+	if s.store != nil {
+		if doc, ok := s.cached(ctx, key); ok {
+			return doc, nil
+		}
+	}
+	doc, err := withExecute(ctx, s.deadlines, func(ctx context.Context) (*executor.Doc, error) {
+		return executor.LookUpDocs(ctx, s.exec, symbol)
+	})
+	if errors.Is(err, executor.ErrSymbolNotFound) {
+		return nil, apperror.NotFound("symbol", symbol)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("looking up docs: %w", err)
+	}
+	if s.store != nil {
+		data, _ := json.Marshal(doc) // a Doc is only strings
+		if err := s.store.Set(ctx, key, data, s.ttl); err != nil {
+			s.logger.WarnContext(ctx, "caching docs failed", slog.String("symbol", symbol), slog.String("error", err.Error()))
+		}
+	}
+	return doc, nil
+}
+
+// cached returns the docs under key, if the cache has them. A cache that
+// fails is logged and treated as a miss.
+func (s *DocsService) cached(ctx context.Context, key string) (*executor.Doc, bool) {
+	data, ok, err := s.store.Get(ctx, key)
+	if err != nil {
+		s.logger.WarnContext(ctx, "reading docs from the cache failed", slog.String("key", key), slog.String("error", err.Error()))
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+	var doc executor.Doc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, false
+	}
+	return &doc, true
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/cache"
+	"github.com/sakif/coding-playground/internal/executor"
+)
+
+// docsExecutor answers the docs harness as if only os.path.join existed.
+type docsExecutor struct{ runs int }
+
+func (e *docsExecutor) Execute(_ context.Context, req executor.ExecutionRequest) (*executor.ExecutionResult, error) {
+	e.runs++
+	m := regexp.MustCompile(`(?m)^MARKER = "(.*)"\nSYMBOL = "(.*)"`).FindStringSubmatch(req.Code)
+	report := `{"symbol":"` + m[2] + `","found":false}`
+	if m[2] == "os.path.join" {
+		report = `{"symbol":"os.path.join","found":true,"kind":"function","signature":"join(a, *p)","doc":"Join paths.","module":"posixpath"}`
+	}
+	return &executor.ExecutionResult{Stdout: "\n" + m[1] + report + "\n"}, nil
+}
+
+func TestDocsService(t *testing.T) {
+	exec := &docsExecutor{}
+	svc := NewDocsService(exec, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	svc.Cache(cache.NewMemory(0), time.Hour)
+	ctx := context.Background()
+
+	for range 3 {
+		doc, err := svc.LookUp(ctx, "os.path.join")
+		if err != nil {
+			t.Fatalf("LookUp() error = %v", err)
+		}
+		if doc.Signature != "join(a, *p)" || doc.Doc != "Join paths." || doc.Module != "posixpath" {
+			t.Errorf("doc = %+v", doc)
+		}
+	}
+	if exec.runs != 1 {
+		t.Errorf("the sandbox ran %d times for one symbol, want 1 (then the cache)", exec.runs)
+	}
+
+	// Names that don't exist are 404s, and asked about again each time.
+	for range 2 {
+		_, err := svc.LookUp(ctx, "os.path.jion")
+		if apperror.CodeOf(err) != apperror.CodeSymbolNotFound {
+			t.Errorf("a missing symbol: error = %v, want SYMBOL_NOT_FOUND", err)
+		}
+	}
+	if exec.runs != 3 {
+		t.Errorf("runs = %d, want a missing symbol not to be cached", exec.runs)
+	}
+
+	for _, symbol := range []string{"", "os.path.", "__import__('os').system('id')"} {
+		if _, err := svc.LookUp(ctx, symbol); !errors.Is(err, apperror.ErrValidation) {
+			t.Errorf("LookUp(%q) error = %v, want a validation error", symbol, err)
+		}
+	}
+	if exec.runs != 3 {
+		t.Errorf("invalid symbols reached the sandbox")
+	}
+}
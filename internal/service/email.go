@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/jobs"
+	"github.com/sakif/coding-playground/internal/mailer"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// EMAIL:
+// Three kinds of email go out:
+//
+//	sign-in link     → when someone asks for one (see MagicLinkService)
+//	grading result   → on submission.graded, if the submitter turned them on
+//	weekly digest    → from a scheduled task, to users with unread
+//	                   notifications who haven't turned it off
+//
+// Emails are rendered when they're queued and sent by an "email.send" job,
+// so a request never waits on the SMTP server and a failed send is retried
+// with backoff. Rendered messages sit in the jobs table until they're sent;
+// that includes sign-in links, which is why those expire within minutes.
+//
+// UNSUBSCRIBING:
+// Every grading email and digest ends with a link that turns that email off
+// without signing in:
+//
+//	/email/unsubscribe?user=<id>&kind=digest&sig=<hex HMAC-SHA256 of "<id>:digest">
+//
+// The HMAC key is the server's secret, so only we can make a link, and it
+// can only ever turn one kind of email off for one user.
+
+const (
+	// JobEmailSend is the job kind that sends one rendered email.
+	JobEmailSend = "email.send"
+
+	// Kinds of email a user can turn off; the names of model.EmailPreferences' fields.
+	EmailGrading = "grading"
+	EmailDigest  = "digest"
+
+	// digestPeriod is how far back a digest looks.
+	digestPeriod = 7 * 24 * time.Hour
+	// digestMessages is how many notifications a digest lists.
+	digestMessages = 10
+)
+
+// EmailPreferencesInput changes some email preferences; nil fields stay as
+// they are.
+type EmailPreferencesInput struct {
+	Grading *bool
+	Digest  *bool
+}
+
+// EmailService queues emails and keeps email preferences.
+type EmailService struct {
+	repo          repository.EmailRepository
+	users         repository.UserRepository
+	exercises     repository.ExerciseRepository
+	notifications repository.NotificationRepository
+	mailer        mailer.Mailer
+	queue         *jobs.Queue
+	baseURL       string // where links in emails point, without a trailing slash
+	secret        []byte // signs unsubscribe links
+	logger        *slog.Logger
+}
+
+// NewEmailService creates an EmailService and registers its send job on
+// queue, so it must be called before queue.Start. Links in emails point at
+// baseURL, e.g. "https://play.example.com".
+func NewEmailService(repo repository.EmailRepository, users repository.UserRepository, exercises repository.ExerciseRepository, notifications repository.NotificationRepository, m mailer.Mailer, queue *jobs.Queue, baseURL, secret string, logger *slog.Logger) *EmailService {
+	s := &EmailService{
+		repo:          repo,
+		users:         users,
+		exercises:     exercises,
+		notifications: notifications,
+		mailer:        m,
+		queue:         queue,
+		baseURL:       baseURL,
+		secret:        []byte(secret),
+		logger:        logger,
+	}
+	queue.Register(JobEmailSend, s.send)
+	return s
+}
+
+// Preferences returns userID's email preferences.
+func (s *EmailService) Preferences(ctx context.Context, userID string) (*model.EmailPreferences, error) {
+	return s.repo.GetEmailPreferences(ctx, userID)
+}
+
+// SetPreferences changes userID's email preferences and returns them.
+func (s *EmailService) SetPreferences(ctx context.Context, userID string, in EmailPreferencesInput) (*model.EmailPreferences, error) {
+	prefs, err := s.repo.GetEmailPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if in.Grading != nil {
+		prefs.Grading = *in.Grading
+	}
+	if in.Digest != nil {
+		prefs.Digest = *in.Digest
+	}
+	if err := s.repo.SaveEmailPreferences(ctx, prefs); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// Unsubscribe turns one kind of email off for userID, if sig is the
+// signature from one of their emails.
+func (s *EmailService) Unsubscribe(ctx context.Context, userID, kind, sig string) error {
+	if kind != EmailGrading && kind != EmailDigest {
+		return apperror.ValidationFailed("kind", "kind must be grading or digest")
+	}
+	want := s.sign(userID, kind)
+	if got, err := hex.DecodeString(sig); err != nil || !hmac.Equal(got, want) {
+		return &apperror.AppError{Err: apperror.ErrForbidden, Message: "this unsubscribe link is not valid"}
+	}
+	off := false
+	in := EmailPreferencesInput{Grading: &off}
+	if kind == EmailDigest {
+		in = EmailPreferencesInput{Digest: &off}
+	}
+	_, err := s.SetPreferences(ctx, userID, in)
+	return err
+}
+
+// sign is the HMAC in an unsubscribe link.
+func (s *EmailService) sign(userID, kind string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(userID + ":" + kind))
+	return mac.Sum(nil)
+}
+
+// unsubscribeURL is the link that turns kind off for userID.
+func (s *EmailService) unsubscribeURL(userID, kind string) string {
+	q := url.Values{"user": {userID}, "kind": {kind}, "sig": {hex.EncodeToString(s.sign(userID, kind))}}
+	return s.baseURL + "/email/unsubscribe?" + q.Encode()
+}
+
+// Queue renders an email and queues it for sending.
+func (s *EmailService) Queue(ctx context.Context, template, to string, data any) error {
+	msg, err := mailer.Render(template, to, data)
+	if err != nil {
+		return err
+	}
+	if _, err := s.queue.Enqueue(ctx, JobEmailSend, msg); err != nil {
+		return fmt.Errorf("queueing email: %w", err)
+	}
+	return nil
+}
+
+// send is the JobEmailSend handler. Returning an error makes the queue
+// retry later.
+func (s *EmailService) send(ctx context.Context, job *jobs.Job) error {
+	var msg mailer.Message
+	if err := job.Decode(&msg); err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, &msg)
+}
+
+// Publish emails grading results to submitters who asked for them. Like
+// every EventPublisher, it never fails the caller: problems are logged.
+func (s *EmailService) Publish(ctx context.Context, event string, data any) {
+	sub, ok := data.(*model.Submission)
+	if event != model.EventSubmissionGraded || !ok || sub.UserID == "" {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	if err := s.emailGraded(ctx, sub); err != nil {
+		s.logger.ErrorContext(ctx, "failed to queue grading email",
+			slog.String("submission_id", sub.ID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// emailGraded queues the grading email for sub, unless its submitter has
+// no address or doesn't want one.
+func (s *EmailService) emailGraded(ctx context.Context, sub *model.Submission) error {
+	user, err := s.users.GetUserByID(ctx, sub.UserID)
+	if err != nil || user == nil || user.Email == "" {
+		return err
+	}
+	prefs, err := s.repo.GetEmailPreferences(ctx, user.ID)
+	if err != nil || !prefs.Grading {
+		return err
+	}
+	title := "an exercise"
+	if exercise, err := s.exercises.GetExercise(ctx, sub.ExerciseID); err == nil {
+		title = "“" + exercise.Title + "”"
+	}
+	return s.Queue(ctx, mailer.TemplateGraded, user.Email, mailer.GradedData{
+		Login:          user.Login,
+		Exercise:       title,
+		Outcome:        gradingOutcome(sub),
+		URL:            s.baseURL + "/",
+		PreferencesURL: s.unsubscribeURL(user.ID, EmailGrading),
+	})
+}
+
+// SendDigests queues this week's digest for everyone who gets one and has
+// unread notifications from the last seven days. It's a scheduled task; one
+// user's failure doesn't stop the others.
+func (s *EmailService) SendDigests(ctx context.Context) error {
+	users, err := s.repo.ListDigestRecipients(ctx)
+	if err != nil {
+		return err
+	}
+	since := time.Now().Add(-digestPeriod)
+	var errs []error
+	sent := 0
+	for _, user := range users {
+		ok, err := s.emailDigest(ctx, &user, since)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("digest for %s: %w", user.ID, err))
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	s.logger.InfoContext(ctx, "queued weekly digests", slog.Int("count", sent))
+	return errors.Join(errs...)
+}
+
+// emailDigest queues user's digest of unread notifications since since,
+// reporting whether there was anything to send.
+func (s *EmailService) emailDigest(ctx context.Context, user *model.User, since time.Time) (bool, error) {
+	// Listed newest first, so the week's unread ones are a prefix. A week
+	// with more than MaxNotificationLimit of them is counted as that many.
+	recent, err := s.notifications.ListNotifications(ctx, user.ID, true, MaxNotificationLimit)
+	if err != nil {
+		return false, err
+	}
+	var messages []string
+	unread := 0
+	for _, n := range recent {
+		if n.CreatedAt.Before(since) {
+			break
+		}
+		unread++
+		if len(messages) < digestMessages {
+			messages = append(messages, n.Message)
+		}
+	}
+	if unread == 0 {
+		return false, nil
+	}
+	return true, s.Queue(ctx, mailer.TemplateDigest, user.Email, mailer.DigestData{
+		Login:          user.Login,
+		Unread:         unread,
+		Messages:       messages,
+		URL:            s.baseURL + "/",
+		PreferencesURL: s.unsubscribeURL(user.ID, EmailDigest),
+	})
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/jobs"
+	"github.com/sakif/coding-playground/internal/mailer"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository/sqlite"
+)
+
+// recordingMailer keeps what it was asked to send.
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (m *recordingMailer) Send(_ context.Context, msg *mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, *msg)
+	return nil
+}
+
+func (m *recordingMailer) Sent() []mailer.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]mailer.Message(nil), m.sent...)
+}
+
+// newEmailTestService returns an EmailService on a real database, with its
+// job queue running, and the mailer it sends through. Users ann (with an
+// address) and bob (without) exist.
+func newEmailTestService(t *testing.T) (*EmailService, *sqlite.DB, *recordingMailer) {
+	t.Helper()
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	queue := jobs.New(db, logger, jobs.Options{PollInterval: 10 * time.Millisecond})
+	m := &recordingMailer{}
+	svc := NewEmailService(db, db, db, db, m, queue, "https://play.example", "test-secret", logger)
+	if err := queue.Start(context.Background()); err != nil {
+		t.Fatalf("starting queue: %v", err)
+	}
+	t.Cleanup(func() {
+		queue.Shutdown(context.Background())
+		db.Close()
+	})
+	for i, u := range []*model.User{
+		{ID: "ann-id", GitHubID: 1, Login: "ann", Email: "ann@example.com"},
+		{ID: "bob-id", GitHubID: 2, Login: "bob"},
+	} {
+		if err := db.Upsert(context.Background(), u); err != nil {
+			t.Fatalf("Upsert %d: %v", i, err)
+		}
+	}
+	return svc, db, m
+}
+
+func TestEmailService_Graded(t *testing.T) {
+	svc, db, m := newEmailTestService(t)
+	ctx := context.Background()
+	exercise := &model.Exercise{Title: "Add", Prompt: "p", TestCode: "t", AuthorID: "bob-id"}
+	if err := db.CreateExercise(ctx, exercise); err != nil {
+		t.Fatalf("CreateExercise: %v", err)
+	}
+	graded := &model.Submission{ID: "s1", UserID: "ann-id", ExerciseID: exercise.ID, Status: model.SubmissionPassed}
+
+	// Grading emails are off by default.
+	svc.Publish(ctx, model.EventSubmissionGraded, graded)
+	on := true
+	if _, err := svc.SetPreferences(ctx, "ann-id", EmailPreferencesInput{Grading: &on}); err != nil {
+		t.Fatalf("SetPreferences() error = %v", err)
+	}
+	svc.Publish(ctx, model.EventSubmissionGraded, graded)
+	svc.Publish(ctx, model.EventSubmissionGraded, &model.Submission{UserID: "bob-id", Status: model.SubmissionPassed}) // no address
+	waitUntil(t, func() bool { return len(m.Sent()) > 0 })
+	time.Sleep(50 * time.Millisecond) // anything else queued would have gone too
+
+	sent := m.Sent()
+	if len(sent) != 1 || sent[0].To != "ann@example.com" || sent[0].Subject != "Your solution to “Add” passed" {
+		t.Fatalf("sent = %+v, want ann's grading email", sent)
+	}
+
+	// The link at the bottom turns them off again.
+	i := strings.Index(sent[0].Text, "https://play.example/email/unsubscribe?")
+	if i < 0 {
+		t.Fatalf("no unsubscribe link in %q", sent[0].Text)
+	}
+	link, _ := url.Parse(strings.Fields(sent[0].Text[i:])[0])
+	q := link.Query()
+	if err := svc.Unsubscribe(ctx, q.Get("user"), EmailDigest, q.Get("sig")); !errors.Is(err, apperror.ErrForbidden) {
+		t.Errorf("Unsubscribe() from the digest with the grading link: error = %v, want ErrForbidden", err)
+	}
+	if err := svc.Unsubscribe(ctx, q.Get("user"), q.Get("kind"), q.Get("sig")); err != nil {
+		t.Fatalf("Unsubscribe() error = %v", err)
+	}
+	if prefs, _ := svc.Preferences(ctx, "ann-id"); prefs.Grading || !prefs.Digest {
+		t.Errorf("preferences after unsubscribing = %+v, want only grading off", prefs)
+	}
+}
+
+func TestEmailService_SendDigests(t *testing.T) {
+	svc, db, m := newEmailTestService(t)
+	ctx := context.Background()
+	for i := range digestMessages + 2 {
+		n := &model.Notification{UserID: "ann-id", Kind: model.NotificationStar, Message: "starred " + string(rune('a'+i))}
+		if err := db.CreateNotification(ctx, n); err != nil {
+			t.Fatalf("CreateNotification: %v", err)
+		}
+	}
+	db.CreateNotification(ctx, &model.Notification{UserID: "bob-id", Kind: model.NotificationStar, Message: "no address"})
+
+	if err := svc.SendDigests(ctx); err != nil {
+		t.Fatalf("SendDigests() error = %v", err)
+	}
+	waitUntil(t, func() bool { return len(m.Sent()) > 0 })
+	digest := m.Sent()[0]
+	if digest.To != "ann@example.com" || digest.Subject != "Your week on PyPlayground: 12 unread" || !strings.Contains(digest.Text, "…and 2 more.") {
+		t.Errorf("digest = %+v", digest)
+	}
+
+	// Nothing unread, nothing sent.
+	db.MarkNotificationsRead(ctx, "ann-id", nil)
+	if err := svc.SendDigests(ctx); err != nil {
+		t.Fatalf("SendDigests() error = %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := len(m.Sent()); n != 1 {
+		t.Errorf("sent %d emails, want just the first digest", n)
+	}
+}
+
+func TestMagicLinkService(t *testing.T) {
+	emails, db, m := newEmailTestService(t)
+	tokens, _ := auth.NewTokenService("test-secret-that-is-at-least-32-bytes-long")
+	svc := NewMagicLinkService(emails, db, db, tokens, emails.logger)
+	ctx := context.Background()
+
+	if err := svc.Request(ctx, "not an address"); !errors.Is(err, apperror.ErrValidation) {
+		t.Errorf("Request() of a bad address: error = %v, want ErrValidation", err)
+	}
+	if err := svc.Request(ctx, "nobody@example.com"); err != nil {
+		t.Errorf("Request() for an unknown address: error = %v, want none", err)
+	}
+	if err := svc.Request(ctx, " ANN@example.com "); err != nil {
+		t.Fatalf("Request() error = %v", err)
+	}
+	waitUntil(t, func() bool { return len(m.Sent()) > 0 })
+	sent := m.Sent()
+	if len(sent) != 1 || sent[0].To != "ann@example.com" {
+		t.Fatalf("sent = %+v, want one link for ann", sent)
+	}
+	i := strings.Index(sent[0].Text, "https://play.example/auth/magic?token=")
+	if i < 0 {
+		t.Fatalf("no link in %q", sent[0].Text)
+	}
+	link, _ := url.Parse(strings.Fields(sent[0].Text[i:])[0])
+	token := link.Query().Get("token")
+
+	result, err := svc.SignIn(ctx, token)
+	if err != nil || result.User.ID != "ann-id" {
+		t.Fatalf("SignIn() = %+v, %v; want ann", result, err)
+	}
+	if claims, err := tokens.Validate(result.Token); err != nil || claims.UserID != "ann-id" {
+		t.Errorf("session token: claims = %+v, %v", claims, err)
+	}
+	for _, tok := range []string{token, "", "forged"} {
+		if _, err := svc.SignIn(ctx, tok); !errors.Is(err, apperror.ErrNotFound) {
+			t.Errorf("SignIn(%q): error = %v, want ErrNotFound", tok, err)
+		}
+	}
+}
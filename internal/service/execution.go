@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/executor"
+)
+
+// ASYNC EXECUTIONS:
+// POST /execute holds the request open for the whole run, and live output
+// needs a WebSocket. Networks that cut long requests or block WebSockets
+// (and SSE) get a third way — start the run, then poll for it:
+//
+//	POST   /api/v1/executions              → 202 {"id":"9f3c…","status":"running",…}
+//	GET    /api/v1/executions/9f3c…?wait=30s → 200 {"id":"9f3c…","status":"done","result":{…}}
+//	DELETE /api/v1/executions/9f3c…        → 204, the run is cancelled
+//
+// ?wait makes the GET a long poll: it answers as soon as the run ends, or
+// when wait has passed with the run still going (status "running"), and
+// the client asks again. Without wait, it answers at once. One poll every
+// wait is all a client sends, however long the run takes.
+//
+// The service is the registry of runs in the background: polling and
+// cancelling both find a run here by its ID. IDs are random, and a run a
+// signed-in user started is only theirs to see; an anonymous run is seen
+// by whoever has its ID. A finished run is kept for ExecutionRetention,
+// then forgotten (404).
+
+const (
+	// ExecutionRunning and the rest are an Execution's statuses.
+	ExecutionRunning  = "running"
+	ExecutionDone     = "done"     // the code ran; Result says how it went
+	ExecutionFailed   = "failed"   // the sandbox failed; see Error
+	ExecutionCanceled = "canceled" // cancelled before it finished
+
+	// ExecutionRetention is how long a finished execution can still be
+	// fetched.
+	ExecutionRetention = 10 * time.Minute
+
+	// MaxExecutionWait caps a long poll's ?wait.
+	MaxExecutionWait = time.Minute
+)
+
+// Execution is a run in the background.
+type Execution struct {
+	ID        string                    `json:"id"`
+	Status    string                    `json:"status"`
+	Result    *executor.ExecutionResult `json:"result,omitempty"`
+	Error     string                    `json:"error,omitempty"`
+	StartedAt time.Time                 `json:"startedAt"`
+	EndedAt   *time.Time                `json:"endedAt,omitempty"`
+
+	userID string        // "" for an anonymous run
+	done   chan struct{} // closed when it ends
+}
+
+// ExecutionService runs code in the background and keeps track of it.
+type ExecutionService struct {
+	logger    *slog.Logger
+	deadlines Deadlines
+
+	mu         sync.Mutex
+	executions map[string]*Execution
+	cancels    map[string]context.CancelFunc // of the running ones
+	wg         sync.WaitGroup
+}
+
+// NewExecutionService creates an ExecutionService.
+func NewExecutionService(logger *slog.Logger) *ExecutionService {
+	return &ExecutionService{
+		logger:     logger,
+		deadlines:  DefaultDeadlines(),
+		executions: make(map[string]*Execution),
+		cancels:    make(map[string]context.CancelFunc),
+	}
+}
+
+// SetDeadlines changes how long a run may take (d.Execute). Call it before
+// serving requests.
+func (s *ExecutionService) SetDeadlines(d Deadlines) {
+	s.deadlines = d
+}
+
+// Start runs run in the background for the user in ctx (if any) and
+// returns at once. run gets a context that outlives ctx and is cancelled
+// by Cancel; its values (the user, the request ID) are ctx's.
+func (s *ExecutionService) Start(ctx context.Context, run func(context.Context) (*executor.ExecutionResult, error)) *Execution {
+	userID, _ := auth.UserIDFromContext(ctx)
+	id := make([]byte, 16)
+	rand.Read(id) // never fails: crypto/rand crashes the program instead
+	e := &Execution{
+		ID:        hex.EncodeToString(id),
+		Status:    ExecutionRunning,
+		StartedAt: time.Now().UTC(),
+		userID:    userID,
+		done:      make(chan struct{}),
+	}
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+	s.mu.Lock()
+	s.forgetExpired()
+	s.executions[e.ID] = e
+	s.cancels[e.ID] = cancel
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.execute(runCtx, e, run)
+	return s.snapshot(e)
+}
+
+// Get returns execution id. If it's still running, Get waits up to wait
+// (at most MaxExecutionWait) for it to end, returning early if ctx ends.
+func (s *ExecutionService) Get(ctx context.Context, id string, wait time.Duration) (*Execution, error) {
+	e, err := s.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if wait > 0 {
+		timer := time.NewTimer(min(wait, MaxExecutionWait))
+		defer timer.Stop()
+		select {
+		case <-e.done:
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+	}
+	return s.snapshot(e), nil
+}
+
+// Cancel stops execution id. Cancelling a run that has ended is a no-op.
+func (s *ExecutionService) Cancel(ctx context.Context, id string) error {
+	if _, err := s.find(ctx, id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel := s.cancels[id]; cancel != nil {
+		cancel()
+	}
+	return nil
+}
+
+// Shutdown cancels every running execution and waits for them to end, or
+// for ctx.
+func (s *ExecutionService) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	for _, cancel := range s.cancels {
+		cancel()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// execute runs run and records how it ended.
+func (s *ExecutionService) execute(ctx context.Context, e *Execution, run func(context.Context) (*executor.ExecutionResult, error)) {
+	defer s.wg.Done()
+	result, err := withExecute(ctx, s.deadlines, run)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	e.EndedAt = &now
+	switch {
+	case ctx.Err() != nil:
+		e.Status = ExecutionCanceled
+	case err != nil:
+		e.Status = ExecutionFailed
+		e.Error = "the sandbox failed to run the code"
+		var appErr *apperror.AppError
+		if errors.As(err, &appErr) && appErr.Message != "" {
+			e.Error = appErr.Message
+		}
+		s.logger.Error("background execution failed",
+			slog.String("execution_id", e.ID),
+			slog.String("error", err.Error()),
+		)
+	default:
+		e.Status = ExecutionDone
+		e.Result = result
+	}
+	s.cancels[e.ID]()
+	delete(s.cancels, e.ID)
+	close(e.done)
+}
+
+// find returns execution id if the user in ctx may see it.
+func (s *ExecutionService) find(ctx context.Context, id string) (*Execution, error) {
+	userID, _ := auth.UserIDFromContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.executions[id]
+	if e == nil || e.userID != "" && e.userID != userID || s.expired(e) {
+		return nil, apperror.NotFound("execution", id)
+	}
+	return e, nil
+}
+
+// forgetExpired drops the executions past ExecutionRetention. Callers hold
+// s.mu.
+func (s *ExecutionService) forgetExpired() {
+	for id, e := range s.executions {
+		if s.expired(e) {
+			delete(s.executions, id)
+		}
+	}
+}
+
+// expired reports whether e ended over ExecutionRetention ago. Callers
+// hold s.mu.
+func (s *ExecutionService) expired(e *Execution) bool {
+	return e.EndedAt != nil && time.Since(*e.EndedAt) > ExecutionRetention
+}
+
+// snapshot copies e for callers outside s.mu.
+func (s *ExecutionService) snapshot(e *Execution) *Execution {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *e
+	return &c
+}
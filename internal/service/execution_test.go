@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/executor"
+)
+
+func TestExecutionService(t *testing.T) {
+	svc := NewExecutionService(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.Cleanup(func() { svc.Shutdown(context.Background()) })
+	asAnn := auth.WithUserID(context.Background(), "ann")
+	exec := &streamingExecutor{release: make(chan struct{})}
+	start := func(ctx context.Context) *Execution {
+		return svc.Start(ctx, func(ctx context.Context) (*executor.ExecutionResult, error) {
+			return exec.Execute(ctx, executor.ExecutionRequest{})
+		})
+	}
+
+	t.Run("a long poll answers when the run ends", func(t *testing.T) {
+		e := start(asAnn)
+		if e.Status != ExecutionRunning || e.ID == "" {
+			t.Fatalf("Start() = %+v, want a running execution", e)
+		}
+		polled := time.Now()
+		got, err := svc.Get(asAnn, e.ID, 20*time.Millisecond)
+		if err != nil || got.Status != ExecutionRunning {
+			t.Fatalf("Get(wait 20ms) = %+v, %v; want still running", got, err)
+		}
+		if time.Since(polled) < 20*time.Millisecond {
+			t.Error("Get() answered before the wait was up")
+		}
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			exec.release <- struct{}{}
+		}()
+		got, err = svc.Get(asAnn, e.ID, 5*time.Second)
+		if err != nil || got.Status != ExecutionDone || got.Result.Stdout != "one\ntwo\n" || got.EndedAt == nil {
+			t.Errorf("Get(wait 5s) = %+v, %v; want done with the output", got, err)
+		}
+	})
+
+	t.Run("cancelling stops the run", func(t *testing.T) {
+		e := start(asAnn)
+		if err := svc.Cancel(asAnn, e.ID); err != nil {
+			t.Fatalf("Cancel() error = %v", err)
+		}
+		got, err := svc.Get(asAnn, e.ID, 5*time.Second)
+		if err != nil || got.Status != ExecutionCanceled {
+			t.Errorf("Get() = %+v, %v; want canceled", got, err)
+		}
+		if err := svc.Cancel(asAnn, e.ID); err != nil {
+			t.Errorf("cancelling again: %v, want a no-op", err)
+		}
+	})
+
+	t.Run("a signed-in user's runs are theirs", func(t *testing.T) {
+		e := start(asAnn)
+		defer svc.Cancel(asAnn, e.ID)
+		for name, ctx := range map[string]context.Context{
+			"bob":       auth.WithUserID(context.Background(), "bob"),
+			"anonymous": context.Background(),
+		} {
+			if _, err := svc.Get(ctx, e.ID, 0); !errors.Is(err, apperror.ErrNotFound) {
+				t.Errorf("Get() as %s error = %v, want not found", name, err)
+			}
+			if err := svc.Cancel(ctx, e.ID); !errors.Is(err, apperror.ErrNotFound) {
+				t.Errorf("Cancel() as %s error = %v, want not found", name, err)
+			}
+		}
+	})
+
+	t.Run("an anonymous run is whoever has the ID's", func(t *testing.T) {
+		e := start(context.Background())
+		defer svc.Cancel(context.Background(), e.ID)
+		if _, err := svc.Get(asAnn, e.ID, 0); err != nil {
+			t.Errorf("Get() error = %v", err)
+		}
+	})
+
+	t.Run("a sandbox failure", func(t *testing.T) {
+		e := svc.Start(context.Background(), func(context.Context) (*executor.ExecutionResult, error) {
+			return nil, errors.New("docker: no such container")
+		})
+		got, err := svc.Get(context.Background(), e.ID, 5*time.Second)
+		if err != nil || got.Status != ExecutionFailed || got.Error == "" {
+			t.Errorf("Get() = %+v, %v; want failed", got, err)
+		}
+	})
+}
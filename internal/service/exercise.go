@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// EXERCISES:
+// An exercise is a small programming task: a Markdown prompt, starter code
+// for the editor, and HIDDEN pytest tests that decide whether a solution is
+// correct. Anyone can list and read exercises; only users with the author (or
+// admin) role can write them, and only an exercise's own author (or an admin)
+// can change or delete it.
+//
+// The hidden tests stay hidden: model.Exercise never serialises TestCode, and
+// the handlers only add it to responses for people allowed to edit the
+// exercise. A learner who could read the tests could just code to them.
+
+const (
+	MaxExerciseTitleLength  = 200
+	MaxExercisePromptLength = 20000
+	MaxExerciseHints        = 10
+	MaxHintLength           = 2000
+)
+
+// ExerciseInput is what an author writes. Update replaces all of it.
+type ExerciseInput struct {
+	Title       string
+	Prompt      string
+	StarterCode string
+	TestCode    string
+	Hints       []string // optional, in the order learners unlock them
+	HintPenalty int      // percent off the score per hint unlocked
+	Coverage    bool     // report line coverage with each submission
+}
+
+// ExerciseService manages exercises.
+type ExerciseService struct {
+	repo   repository.ExerciseRepository
+	users  repository.UserRepository
+	logger *slog.Logger
+}
+
+// NewExerciseService creates an ExerciseService.
+func NewExerciseService(repo repository.ExerciseRepository, users repository.UserRepository, logger *slog.Logger) *ExerciseService {
+	return &ExerciseService{
+		repo:   repo,
+		users:  users,
+		logger: logger,
+	}
+}
+
+// Create saves a new exercise written by userID, who must be an author.
+func (s *ExerciseService) Create(ctx context.Context, userID string, in ExerciseInput) (*model.Exercise, error) {
+	if _, err := s.requireAuthor(ctx, userID); err != nil {
+		return nil, err
+	}
+	in, err := in.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	exercise := &model.Exercise{
+		Title:       in.Title,
+		Prompt:      in.Prompt,
+		StarterCode: in.StarterCode,
+		TestCode:    in.TestCode,
+		Hints:       in.Hints,
+		HintCount:   len(in.Hints),
+		HintPenalty: in.HintPenalty,
+		Coverage:    in.Coverage,
+		AuthorID:    userID,
+	}
+	if err := s.repo.CreateExercise(ctx, exercise); err != nil {
+		return nil, fmt.Errorf("creating exercise: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "exercise created",
+		slog.String("id", exercise.ID),
+		slog.String("author_id", userID),
+	)
+	return exercise, nil
+}
+
+// Get returns an exercise, including its hidden tests: callers decide what
+// to show (see CanEdit).
+func (s *ExerciseService) Get(ctx context.Context, id string) (*model.Exercise, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return nil, apperror.ValidationFailed("id", "exercise ID is required")
+	}
+	return s.repo.GetExercise(ctx, id)
+}
+
+// ExercisePage is one page of the exercise listing.
+type ExercisePage struct {
+	Items  []model.Exercise
+	Total  int
+	Limit  int
+	Offset int
+}
+
+// HasMore reports whether there are exercises after this page.
+func (p *ExercisePage) HasMore() bool {
+	return p.Offset+len(p.Items) < p.Total
+}
+
+// List returns a page of exercises, newest first. limit and offset are
+// clamped like SnippetService.List's.
+func (s *ExerciseService) List(ctx context.Context, limit, offset int) (*ExercisePage, error) {
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	limit = min(limit, MaxListLimit)
+	offset = max(offset, 0)
+
+	exercises, err := s.repo.ListExercises(ctx, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("listing exercises: %w", err)
+	}
+	total, err := s.repo.CountExercises(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("counting exercises: %w", err)
+	}
+	return &ExercisePage{Items: exercises, Total: total, Limit: limit, Offset: offset}, nil
+}
+
+// Update replaces an exercise's content. Only its author or an admin may.
+func (s *ExerciseService) Update(ctx context.Context, userID, id string, in ExerciseInput) (*model.Exercise, error) {
+	exercise, err := s.editable(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	in, err = in.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	exercise.Title = in.Title
+	exercise.Prompt = in.Prompt
+	exercise.StarterCode = in.StarterCode
+	exercise.TestCode = in.TestCode
+	exercise.Hints = in.Hints
+	exercise.HintCount = len(in.Hints)
+	exercise.HintPenalty = in.HintPenalty
+	exercise.Coverage = in.Coverage
+	if err := s.repo.UpdateExercise(ctx, exercise); err != nil {
+		return nil, fmt.Errorf("updating exercise: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "exercise updated", slog.String("id", exercise.ID))
+	return exercise, nil
+}
+
+// Delete removes an exercise. Only its author or an admin may.
+func (s *ExerciseService) Delete(ctx context.Context, userID, id string) error {
+	if _, err := s.editable(ctx, userID, id); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteExercise(ctx, id); err != nil {
+		return fmt.Errorf("deleting exercise: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "exercise deleted", slog.String("id", id))
+	return nil
+}
+
+// CanEdit reports whether userID may edit the exercise — and so see its
+// hidden tests. Anonymous callers (userID "") never can.
+func (s *ExerciseService) CanEdit(ctx context.Context, userID string, exercise *model.Exercise) bool {
+	if userID == "" {
+		return false
+	}
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil || user == nil {
+		return false
+	}
+	return user.IsAdmin() || (user.CanAuthor() && exercise.AuthorID == userID)
+}
+
+// editable returns the exercise if userID may edit it.
+func (s *ExerciseService) editable(ctx context.Context, userID, id string) (*model.Exercise, error) {
+	user, err := s.requireAuthor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	exercise, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if exercise.AuthorID != userID && !user.IsAdmin() {
+		return nil, &apperror.AppError{
+			Err:     apperror.ErrForbidden,
+			Message: "only the author of an exercise can change it",
+		}
+	}
+	return exercise, nil
+}
+
+// requireAuthor returns userID's user if they have the author or admin role.
+func (s *ExerciseService) requireAuthor(ctx context.Context, userID string) (*model.User, error) {
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("looking up user: %w", err)
+	}
+	if user == nil || !user.CanAuthor() {
+		return nil, &apperror.AppError{
+			Err:     apperror.ErrForbidden,
+			Message: "writing exercises requires the author role",
+		}
+	}
+	return user, nil
+}
+
+// validate trims the title and hints and checks every field, returning the
+// cleaned input.
+func (in ExerciseInput) validate() (ExerciseInput, error) {
+	var verrs apperror.ValidationErrors
+	in.Title = strings.TrimSpace(in.Title)
+	switch {
+	case in.Title == "":
+		verrs.Add("title", "title is required")
+	case len(in.Title) > MaxExerciseTitleLength:
+		verrs.Add("title", fmt.Sprintf("title must be %d characters or less", MaxExerciseTitleLength))
+	}
+	switch {
+	case strings.TrimSpace(in.Prompt) == "":
+		verrs.Add("prompt", "prompt is required")
+	case len(in.Prompt) > MaxExercisePromptLength:
+		verrs.Add("prompt", fmt.Sprintf("prompt must be %d characters or less", MaxExercisePromptLength))
+	}
+	if len(in.StarterCode) > MaxCodeLength {
+		verrs.Add("starterCode", fmt.Sprintf("starter code must be %d characters or less", MaxCodeLength))
+	}
+	switch {
+	case strings.TrimSpace(in.TestCode) == "":
+		verrs.Add("testCode", "hidden tests are required")
+	case len(in.TestCode) > MaxCodeLength:
+		verrs.Add("testCode", fmt.Sprintf("tests must be %d characters or less", MaxCodeLength))
+	}
+	if len(in.Hints) > MaxExerciseHints {
+		verrs.Add("hints", fmt.Sprintf("an exercise can have at most %d hints", MaxExerciseHints))
+	}
+	hints := make([]string, len(in.Hints))
+	for i, hint := range in.Hints {
+		hints[i] = strings.TrimSpace(hint)
+		if hints[i] == "" || len(hints[i]) > MaxHintLength {
+			verrs.Add("hints", fmt.Sprintf("hint %d must be 1 to %d characters", i+1, MaxHintLength))
+			break
+		}
+	}
+	in.Hints = hints
+	if in.HintPenalty < 0 || in.HintPenalty > 100 {
+		verrs.Add("hintPenalty", "hintPenalty must be between 0 and 100 percent")
+	}
+	return in, verrs.Err()
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/rs/xid"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+)
+
+// mockExerciseRepo is an in-memory repository.ExerciseRepository.
+type mockExerciseRepo struct {
+	exercises map[string]*model.Exercise
+}
+
+func (m *mockExerciseRepo) CreateExercise(_ context.Context, e *model.Exercise) error {
+	e.ID = xid.New().String()
+	m.exercises[e.ID] = e
+	return nil
+}
+
+func (m *mockExerciseRepo) GetExercise(_ context.Context, id string) (*model.Exercise, error) {
+	e, ok := m.exercises[id]
+	if !ok {
+		return nil, apperror.NotFound("exercise", id)
+	}
+	copied := *e
+	return &copied, nil
+}
+
+func (m *mockExerciseRepo) ListExercises(_ context.Context, limit, offset int) ([]model.Exercise, error) {
+	var out []model.Exercise
+	for _, e := range m.exercises {
+		out = append(out, *e)
+	}
+	return out[min(offset, len(out)):min(offset+limit, len(out))], nil
+}
+
+func (m *mockExerciseRepo) CountExercises(context.Context) (int, error) {
+	return len(m.exercises), nil
+}
+
+func (m *mockExerciseRepo) UpdateExercise(_ context.Context, e *model.Exercise) error {
+	m.exercises[e.ID] = e
+	return nil
+}
+
+func (m *mockExerciseRepo) DeleteExercise(_ context.Context, id string) error {
+	delete(m.exercises, id)
+	return nil
+}
+
+func newTestExerciseService() (*ExerciseService, *mockExerciseRepo) {
+	repo := &mockExerciseRepo{exercises: make(map[string]*model.Exercise)}
+	users := newMockUserRepo(
+		&model.User{ID: "learner", Role: model.RoleUser},
+		&model.User{ID: "author", Role: model.RoleAuthor},
+		&model.User{ID: "other-author", Role: model.RoleAuthor},
+		&model.User{ID: "admin", Role: model.RoleAdmin},
+	)
+	return NewExerciseService(repo, users, slog.New(slog.NewTextHandler(io.Discard, nil))), repo
+}
+
+var validExercise = ExerciseInput{
+	Title:    "  Add two numbers ",
+	Prompt:   "Write `add(a, b)`.",
+	TestCode: "def test_add():\n    assert add(1, 2) == 3\n",
+}
+
+func TestExerciseService_Create(t *testing.T) {
+	svc, _ := newTestExerciseService()
+	ctx := context.Background()
+
+	if _, err := svc.Create(ctx, "learner", validExercise); !errors.Is(err, apperror.ErrForbidden) {
+		t.Errorf("learner: error = %v, want ErrForbidden", err)
+	}
+
+	exercise, err := svc.Create(ctx, "author", validExercise)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if exercise.Title != "Add two numbers" || exercise.AuthorID != "author" {
+		t.Errorf("exercise = %+v, want trimmed title and author set", exercise)
+	}
+
+	_, err = svc.Create(ctx, "author", ExerciseInput{Title: "x", Prompt: "y"})
+	var verrs *apperror.ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs.Fields) != 1 || verrs.Fields[0].Field != "testCode" {
+		t.Errorf("missing tests: error = %v, want a testCode validation error", err)
+	}
+
+	withHints := validExercise
+	withHints.Hints = []string{" Use + ", ""}
+	withHints.HintPenalty = 150
+	_, err = svc.Create(ctx, "author", withHints)
+	if !errors.As(err, &verrs) || len(verrs.Fields) != 2 || verrs.Fields[0].Field != "hints" || verrs.Fields[1].Field != "hintPenalty" {
+		t.Errorf("bad hints: error = %v, want hints and hintPenalty validation errors", err)
+	}
+	withHints.Hints, withHints.HintPenalty = []string{" Use + "}, 20
+	exercise, err = svc.Create(ctx, "author", withHints)
+	if err != nil || exercise.HintCount != 1 || exercise.Hints[0] != "Use +" {
+		t.Errorf("Create() with hints = %+v, %v; want one trimmed hint", exercise, err)
+	}
+}
+
+func TestExerciseService_OnlyAuthorEdits(t *testing.T) {
+	svc, _ := newTestExerciseService()
+	ctx := context.Background()
+	exercise, err := svc.Create(ctx, "author", validExercise)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	edit := validExercise
+	edit.Title = "Add three numbers"
+	for _, userID := range []string{"learner", "other-author"} {
+		if _, err := svc.Update(ctx, userID, exercise.ID, edit); !errors.Is(err, apperror.ErrForbidden) {
+			t.Errorf("Update by %s: error = %v, want ErrForbidden", userID, err)
+		}
+		if svc.CanEdit(ctx, userID, exercise) {
+			t.Errorf("CanEdit(%s) = true, want false", userID)
+		}
+	}
+	if svc.CanEdit(ctx, "", exercise) {
+		t.Error("CanEdit(anonymous) = true, want false")
+	}
+
+	updated, err := svc.Update(ctx, "admin", exercise.ID, edit)
+	if err != nil || updated.Title != "Add three numbers" || updated.AuthorID != "author" {
+		t.Errorf("Update by admin = %+v, %v; want new title, same author", updated, err)
+	}
+	if err := svc.Delete(ctx, "author", exercise.ID); err != nil {
+		t.Errorf("Delete by author: error = %v", err)
+	}
+	if _, err := svc.Get(ctx, exercise.ID); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("Get after delete: error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// EXPORTS:
+// A user can download all their personal snippets, and an org's members all
+// the org's, as one .tar.gz:
+//
+//	snippets-ada/
+//	  fizzbuzz-cq1vd8f2b5ls73ee5n3g.py     the code
+//	  fizzbuzz-cq1vd8f2b5ls73ee5n3g.json   everything else (name, dates, …)
+//
+// STREAMING:
+// Someone with thousands of snippets would make a sizeable archive, so it's
+// never built in memory. Export.Stream reads snippets from a
+// repository.SnippetCursor a batch at a time and writes each straight
+// through tar and gzip to the response: memory use is one batch, however
+// many there are, and the download starts at once.
+//
+// The catch is that the response status is sent before the first snippet
+// is read. Anything that can fail up front (the org doesn't exist, the user
+// isn't in it) fails in ForUser or ForOrg instead, while an error response
+// can still be sent; an error mid-stream can only cut the download short,
+// which leaves the client with a truncated archive that gzip rejects.
+//
+// If the client goes away, the request's context is canceled, the cursor
+// stops, and so does the export.
+
+// exportProgressEvery is how often (in snippets) a running export logs its
+// progress.
+const exportProgressEvery = 1000
+
+// ExportService builds snippet exports.
+type ExportService struct {
+	snippets repository.SnippetCursor
+	users    repository.UserRepository
+	orgs     *OrgService
+	logger   *slog.Logger
+}
+
+// NewExportService creates an ExportService.
+func NewExportService(snippets repository.SnippetCursor, users repository.UserRepository, orgs *OrgService, logger *slog.Logger) *ExportService {
+	return &ExportService{
+		snippets: snippets,
+		users:    users,
+		orgs:     orgs,
+		logger:   logger,
+	}
+}
+
+// Export is an archive ready to be streamed.
+type Export struct {
+	// Filename is what to save the archive as, e.g. "snippets-ada.tar.gz".
+	Filename string
+
+	dir     string
+	opts    repository.ListOptions
+	service *ExportService
+	attrs   []any // log attributes naming what's exported
+}
+
+// ForUser prepares an export of userID's personal snippets (not those of
+// their orgs).
+func (s *ExportService) ForUser(ctx context.Context, userID string) (*Export, error) {
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.export("snippets-"+slugify(user.Login, "user"),
+		repository.ListOptions{OwnerID: userID, PersonalOnly: true},
+		slog.String("user_id", userID)), nil
+}
+
+// ForOrg prepares an export of an org's snippets. Members only; to anyone
+// else the org doesn't exist.
+func (s *ExportService) ForOrg(ctx context.Context, userID, orgID string) (*Export, error) {
+	org, err := s.orgs.Get(ctx, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return s.export("snippets-"+slugify(org.Name, "org"),
+		repository.ListOptions{OrgID: orgID},
+		slog.String("user_id", userID), slog.String("org_id", orgID)), nil
+}
+
+func (s *ExportService) export(dir string, opts repository.ListOptions, attrs ...any) *Export {
+	return &Export{Filename: dir + ".tar.gz", dir: dir, opts: opts, service: s, attrs: attrs}
+}
+
+// exportMetadata is the .json file written alongside each snippet's code.
+type exportMetadata struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	File        string     `json:"file"`
+	Public      bool       `json:"public"`
+	PublishedAt *time.Time `json:"publishedAt,omitempty"`
+	Version     int        `json:"version"`
+	ForkedFrom  string     `json:"forkedFrom,omitempty"`
+	OrgID       string     `json:"orgId,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// Stream writes the archive to w, returning how many snippets it holds. It
+// stops with ctx's error if ctx is done first.
+func (e *Export) Stream(ctx context.Context, w io.Writer) (int, error) {
+	logger := e.service.logger
+	start := time.Now()
+	counter := &countingWriter{w: w}
+	gz := gzip.NewWriter(counter)
+	tw := tar.NewWriter(gz)
+
+	count := 0
+	err := func() error {
+		for snippet, err := range e.service.snippets.EachSnippet(ctx, e.opts) {
+			if err != nil {
+				return err
+			}
+			if err := e.writeSnippet(tw, snippet); err != nil {
+				return err
+			}
+			count++
+			if count%exportProgressEvery == 0 {
+				logger.InfoContext(ctx, "export in progress", append(e.attrs,
+					slog.Int("snippets", count),
+					slog.Int64("bytes", counter.n),
+				)...)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			return err
+		}
+		return gz.Close()
+	}()
+	if err != nil {
+		logger.WarnContext(ctx, "export failed", append(e.attrs,
+			slog.Int("snippets", count),
+			slog.Int64("bytes", counter.n),
+			slog.String("error", err.Error()),
+		)...)
+		return count, fmt.Errorf("exporting snippets: %w", err)
+	}
+
+	logger.InfoContext(ctx, "export finished", append(e.attrs,
+		slog.Int("snippets", count),
+		slog.Int64("bytes", counter.n),
+		slog.Duration("duration", time.Since(start)),
+	)...)
+	return count, nil
+}
+
+// writeSnippet adds one snippet's code and metadata files to the archive.
+func (e *Export) writeSnippet(tw *tar.Writer, snippet *model.Snippet) error {
+	base := e.dir + "/" + slugify(snippet.Name, "snippet") + "-" + snippet.ID
+	meta, err := json.MarshalIndent(exportMetadata{
+		ID:          snippet.ID,
+		Name:        snippet.Name,
+		Description: snippet.Description,
+		File:        base[len(e.dir)+1:] + ".py",
+		Public:      snippet.Public,
+		PublishedAt: snippet.PublishedAt,
+		Version:     snippet.Version,
+		ForkedFrom:  snippet.ForkedFrom,
+		OrgID:       snippet.OrgID,
+		CreatedAt:   snippet.CreatedAt,
+		UpdatedAt:   snippet.UpdatedAt,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{
+		{base + ".py", []byte(snippet.Code)},
+		{base + ".json", append(meta, '\n')},
+	} {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    file.name,
+			Mode:    0o644,
+			Size:    int64(len(file.data)),
+			ModTime: snippet.UpdatedAt,
+			Format:  tar.FormatPAX,
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(file.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countingWriter counts the (compressed) bytes written, for the logs.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// maxSlugLength keeps file names in an export readable.
+const maxSlugLength = 50
+
+// slugify turns a name into something safe in a file name: lowercase ASCII
+// letters and digits, joined by single hyphens. A name with nothing usable
+// in it becomes fallback.
+func slugify(name, fallback string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteRune(r)
+			if b.Len() >= maxSlugLength {
+				break
+			}
+			continue
+		}
+		hyphen = true
+	}
+	if b.Len() == 0 {
+		return fallback
+	}
+	return b.String()
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository/sqlite"
+)
+
+func TestExportService(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	orgs := NewOrgService(db, db, logger)
+	exports := NewExportService(db, db, orgs, logger)
+
+	ctx := context.Background()
+	for i, login := range []string{"ann", "bob"} {
+		if err := db.Upsert(ctx, &model.User{ID: login + "-id", GitHubID: int64(i + 1), Login: login}); err != nil {
+			t.Fatalf("creating %s: %v", login, err)
+		}
+	}
+	org, err := orgs.Create(ctx, "ann-id", "Data Team!")
+	if err != nil {
+		t.Fatalf("creating org: %v", err)
+	}
+	for _, s := range []*model.Snippet{
+		{Name: "Fizz Buzz", Code: "print('fizz')", UserID: "ann-id"},
+		{Name: "???", Code: "print(2)", UserID: "ann-id"},
+		{Name: "shared", Code: "print(3)", UserID: "ann-id", OrgID: org.ID},
+		{Name: "bob's", Code: "print(4)", UserID: "bob-id"},
+	} {
+		if err := db.Create(ctx, s); err != nil {
+			t.Fatalf("creating snippet: %v", err)
+		}
+	}
+
+	// read streams export and returns the archive's files by name.
+	read := func(t *testing.T, export *Export) map[string]string {
+		t.Helper()
+		var buf bytes.Buffer
+		if _, err := export.Stream(ctx, &buf); err != nil {
+			t.Fatalf("Stream() error = %v", err)
+		}
+		gz, err := gzip.NewReader(&buf)
+		if err != nil {
+			t.Fatalf("not gzip: %v", err)
+		}
+		files := map[string]string{}
+		tr := tar.NewReader(gz)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("reading tar: %v", err)
+			}
+			data, _ := io.ReadAll(tr)
+			files[hdr.Name] = string(data)
+		}
+		return files
+	}
+
+	t.Run("user", func(t *testing.T) {
+		export, err := exports.ForUser(ctx, "ann-id")
+		if err != nil {
+			t.Fatalf("ForUser() error = %v", err)
+		}
+		if export.Filename != "snippets-ann.tar.gz" {
+			t.Errorf("Filename = %q", export.Filename)
+		}
+		files := read(t, export)
+		if len(files) != 4 {
+			t.Fatalf("archive has %v, want code and metadata for ann's 2 personal snippets", slices.Sorted(maps.Keys(files)))
+		}
+		var code, meta string
+		var unnamed int
+		for name, data := range files {
+			switch {
+			case filepath.Dir(name) != "snippets-ann":
+				t.Errorf("%s is outside snippets-ann/", name)
+			case filepath.Ext(name) == ".py" && data == "print('fizz')":
+				code = name
+			case filepath.Ext(name) == ".json" && bytes.Contains([]byte(data), []byte(`"Fizz Buzz"`)):
+				meta = data
+			}
+			if matched, _ := filepath.Match("snippets-ann/snippet-*", name); matched {
+				unnamed++
+			}
+		}
+		if code == "" || unnamed != 2 {
+			t.Errorf("archive has %v, want fizz-buzz-<id>.py and snippet-<id>.py/.json for the unnamable one", slices.Sorted(maps.Keys(files)))
+		}
+		var m exportMetadata
+		if err := json.Unmarshal([]byte(meta), &m); err != nil || m.File != filepath.Base(code) || m.Version != 1 {
+			t.Errorf("metadata = %s (%v), want it to name %s", meta, err, filepath.Base(code))
+		}
+	})
+
+	t.Run("org", func(t *testing.T) {
+		if _, err := exports.ForOrg(ctx, "bob-id", org.ID); !errors.Is(err, apperror.ErrNotFound) {
+			t.Errorf("ForOrg(outsider) error = %v, want not found", err)
+		}
+		export, err := exports.ForOrg(ctx, "ann-id", org.ID)
+		if err != nil {
+			t.Fatalf("ForOrg() error = %v", err)
+		}
+		if export.Filename != "snippets-data-team.tar.gz" {
+			t.Errorf("Filename = %q", export.Filename)
+		}
+		if files := read(t, export); len(files) != 2 {
+			t.Errorf("archive has %v, want just the org's snippet", slices.Sorted(maps.Keys(files)))
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		export, err := exports.ForUser(ctx, "ann-id")
+		if err != nil {
+			t.Fatalf("ForUser() error = %v", err)
+		}
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, err := export.Stream(canceled, io.Discard); !errors.Is(err, context.Canceled) {
+			t.Errorf("Stream(canceled) error = %v, want context.Canceled", err)
+		}
+	})
+}
+
+func TestSlugify(t *testing.T) {
+	tests := []struct{ name, want string }{
+		{"Fizz Buzz", "fizz-buzz"},
+		{"  --Hello,   World!-- ", "hello-world"},
+		{"über", "ber"},
+		{"???", "snippet"},
+		{"", "snippet"},
+	}
+	for _, tt := range tests {
+		if got := slugify(tt.name, "snippet"); got != tt.want {
+			t.Errorf("slugify(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/abuse"
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/executor"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// AUTO-GRADING:
+// A learner submits code for an exercise; we run it against the exercise's
+// hidden tests in the sandbox (executor.RunTests) and keep the outcome as a
+// submission:
+//
+//	status     passed | failed | error (nothing ran: the code crashed or timed out)
+//	score      percentage of tests passed
+//	results    one entry per test: name, passed, and why it failed
+//	durationMs the tests' total running time, which breaks leaderboard ties
+//
+// Failure messages come from the test author's assert messages; the tests'
+// source is never sent back. An exercise can also ask for line coverage
+// (executor.TestOptions), which shows the learner what the tests never
+// reached. Unlocking the exercise's hints (hint.go) or
+// submitting an assignment late can cost part of the score.
+
+// maxSubmissionOutput caps how much of the program's printed output is kept.
+const maxSubmissionOutput = 10000
+
+// GradingService grades exercise submissions.
+type GradingService struct {
+	exercises   repository.ExerciseRepository
+	submissions repository.SubmissionRepository
+	hints       repository.HintRepository
+	exec        executor.Executor
+	logger      *slog.Logger
+	events      EventPublisher  // optional; see PublishEvents
+	abuse       *abuse.Detector // optional; see DetectAbuse
+	deadlines   Deadlines       // see SetDeadlines
+}
+
+// NewGradingService creates a GradingService that runs code on exec.
+func NewGradingService(exercises repository.ExerciseRepository, submissions repository.SubmissionRepository, hints repository.HintRepository, exec executor.Executor, logger *slog.Logger) *GradingService {
+	return &GradingService{
+		exercises:   exercises,
+		submissions: submissions,
+		hints:       hints,
+		exec:        exec,
+		logger:      logger,
+		deadlines:   DefaultDeadlines(),
+	}
+}
+
+// SetDeadlines changes how long a test run and each repository call may
+// take (see deadline.go). Call it before serving requests.
+func (s *GradingService) SetDeadlines(d Deadlines) {
+	s.deadlines = d
+}
+
+// PublishEvents makes the service report submission.graded to p. Call it
+// before serving requests.
+func (s *GradingService) PublishEvents(p EventPublisher) {
+	s.events = p
+}
+
+// DetectAbuse makes the service check solutions with d before running them.
+// Call it before serving requests.
+func (s *GradingService) DetectAbuse(d *abuse.Detector) {
+	s.abuse = d
+}
+
+// Submit grades userID's code for an exercise and saves the submission.
+// This is practice: class assignments go through ClassService.Submit, which
+// enforces their submission windows.
+func (s *GradingService) Submit(ctx context.Context, userID, exerciseID, code string) (*model.Submission, error) {
+	if err := validateSubmissionCode(code); err != nil {
+		return nil, err
+	}
+	exercise, err := withDB(ctx, s.deadlines, func(ctx context.Context) (*model.Exercise, error) {
+		return s.exercises.GetExercise(ctx, exerciseID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.submit(ctx, &model.Submission{UserID: userID, Code: code}, exercise, 0)
+}
+
+// submit runs sub.Code against the exercise's tests, fills in the results
+// and saves sub. The score loses the hint penalty for every hint the user
+// unlocked, and then, if sub is late, latePenalty percent.
+func (s *GradingService) submit(ctx context.Context, sub *model.Submission, exercise *model.Exercise, latePenalty int) (*model.Submission, error) {
+	hintsUsed, err := withDB(ctx, s.deadlines, func(ctx context.Context) (int, error) {
+		return s.hints.HintsUnlocked(ctx, exercise.ID, sub.UserID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("counting hints: %w", err)
+	}
+	if s.abuse != nil {
+		if err := s.abuse.Check(ctx, abuse.SourceGrading, sub.Code); err != nil {
+			return nil, err
+		}
+	}
+	report, err := withExecute(ctx, s.deadlines, func(ctx context.Context) (*executor.TestReport, error) {
+		return executor.RunTests(ctx, s.exec, sub.Code, exercise.TestCode, executor.TestOptions{Coverage: exercise.Coverage})
+	})
+	if errors.Is(err, executor.ErrTestProgramTooLarge) {
+		return nil, apperror.ValidationFailed("code", "code is too large to grade").WithCode(apperror.CodeCodeTooLong)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("running tests: %w", err)
+	}
+
+	graded := grade(report)
+	if s.abuse != nil {
+		s.abuse.Observe(ctx, sub.Code, time.Duration(graded.DurationMS*float64(time.Millisecond)))
+	}
+	graded.ExerciseID = exercise.ID
+	graded.AssignmentID = sub.AssignmentID
+	graded.ChallengeDate = sub.ChallengeDate
+	graded.UserID = sub.UserID
+	graded.Code = sub.Code
+	graded.Late = sub.Late
+	graded.HintsUsed = min(hintsUsed, len(exercise.Hints))
+	if penalty := hintPenalty(exercise, hintsUsed); penalty > 0 {
+		graded.Score = graded.Score * (100 - penalty) / 100
+	}
+	if graded.Late && latePenalty > 0 {
+		graded.Score = graded.Score * (100 - latePenalty) / 100
+	}
+	if err := runDB(ctx, s.deadlines, func(ctx context.Context) error { return s.submissions.CreateSubmission(ctx, graded) }); err != nil {
+		return nil, fmt.Errorf("saving submission: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "submission graded",
+		slog.String("id", graded.ID),
+		slog.String("exercise_id", exercise.ID),
+		slog.String("assignment_id", graded.AssignmentID),
+		slog.String("challenge_date", graded.ChallengeDate),
+		slog.String("user_id", graded.UserID),
+		slog.String("status", graded.Status),
+		slog.Int("score", graded.Score),
+		slog.Bool("late", graded.Late),
+		slog.Int("hints_used", graded.HintsUsed),
+	)
+	if s.events != nil {
+		s.events.Publish(ctx, model.EventSubmissionGraded, graded)
+	}
+	return graded, nil
+}
+
+// validateSubmissionCode checks submitted code before anything runs it.
+func validateSubmissionCode(code string) error {
+	if strings.TrimSpace(code) == "" {
+		return apperror.ValidationFailed("code", "code is required")
+	}
+	if len(code) > MaxCodeLength {
+		return apperror.ValidationFailed("code", fmt.Sprintf("code must be %d characters or less", MaxCodeLength)).
+			WithCode(apperror.CodeCodeTooLong).WithParam("max", strconv.Itoa(MaxCodeLength))
+	}
+	return nil
+}
+
+// grade turns a test report into an (unsaved) submission.
+func grade(report *executor.TestReport) *model.Submission {
+	sub := &model.Submission{
+		Results: make([]model.TestResult, len(report.Tests)),
+		Passed:  report.Passed(),
+		Total:   len(report.Tests),
+		Error:   report.Error,
+		Output:  report.Output,
+	}
+	if len(sub.Output) > maxSubmissionOutput {
+		sub.Output = sub.Output[:maxSubmissionOutput] + "\n… output truncated"
+	}
+	for i, t := range report.Tests {
+		sub.Results[i] = model.TestResult(t)
+		sub.DurationMS += t.DurationMS
+	}
+	if report.Coverage != nil {
+		coverage := model.Coverage(*report.Coverage)
+		sub.Coverage = &coverage
+	}
+
+	switch {
+	case sub.Error != "" || sub.Total == 0:
+		sub.Status = model.SubmissionError
+		if sub.Error == "" {
+			sub.Error = "the exercise has no tests"
+		}
+	case sub.Passed == sub.Total:
+		sub.Status = model.SubmissionPassed
+	default:
+		sub.Status = model.SubmissionFailed
+	}
+	if sub.Total > 0 {
+		sub.Score = sub.Passed * 100 / sub.Total
+	}
+	return sub
+}
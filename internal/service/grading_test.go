@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/executor"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// timeoutExecutor answers every run like a sandbox whose deadline passed.
+type timeoutExecutor struct{ runs int }
+
+func (e *timeoutExecutor) Execute(context.Context, executor.ExecutionRequest) (*executor.ExecutionResult, error) {
+	e.runs++
+	return &executor.ExecutionResult{Stderr: "\nExecution timed out.\n", ExitCode: 124}, nil
+}
+
+// mockSubmissionRepo keeps submissions in memory. ListSubmissions ignores
+// ClassID: tests that care put only the class's students' submissions in.
+type mockSubmissionRepo struct{ saved []*model.Submission }
+
+func (m *mockSubmissionRepo) CreateSubmission(_ context.Context, s *model.Submission) error {
+	s.ID = fmt.Sprintf("sub-%d", len(m.saved)+1)
+	m.saved = append(m.saved, s)
+	return nil
+}
+
+func (m *mockSubmissionRepo) ListSubmissions(_ context.Context, f repository.SubmissionFilter) ([]model.Submission, error) {
+	var out []model.Submission
+	for _, s := range slices.Backward(m.saved) {
+		if (f.ExerciseID == "" || s.ExerciseID == f.ExerciseID) && (f.UserID == "" || s.UserID == f.UserID) {
+			out = append(out, *s)
+		}
+	}
+	return out, nil
+}
+
+func TestGrade(t *testing.T) {
+	tests := []struct {
+		name       string
+		report     executor.TestReport
+		wantStatus string
+		wantScore  int
+	}{
+		{"all pass", executor.TestReport{Tests: []executor.TestResult{{Passed: true}, {Passed: true}}}, model.SubmissionPassed, 100},
+		{"some pass", executor.TestReport{Tests: []executor.TestResult{{Passed: true}, {}, {}}}, model.SubmissionFailed, 33},
+		{"crashed", executor.TestReport{Error: "your code raised ValueError"}, model.SubmissionError, 0},
+		{"no tests", executor.TestReport{}, model.SubmissionError, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := grade(&tt.report)
+			if sub.Status != tt.wantStatus || sub.Score != tt.wantScore {
+				t.Errorf("grade() = %s/%d, want %s/%d", sub.Status, sub.Score, tt.wantStatus, tt.wantScore)
+			}
+			if sub.Status == model.SubmissionError && sub.Error == "" {
+				t.Error("error submission without an explanation")
+			}
+			if sub.Coverage != nil {
+				t.Errorf("coverage = %+v, want none when it wasn't measured", sub.Coverage)
+			}
+		})
+	}
+
+	report := executor.TestReport{
+		Tests:    []executor.TestResult{{Passed: true}},
+		Coverage: &executor.Coverage{Engine: "trace", Executed: []int{1, 2}, Missing: []int{3}, Percent: 66.7},
+	}
+	if sub := grade(&report); sub.Coverage == nil || sub.Coverage.Percent != 66.7 || !slices.Equal(sub.Coverage.Missing, []int{3}) {
+		t.Errorf("grade() coverage = %+v, want the report's", sub.Coverage)
+	}
+}
+
+func TestGradingService_Submit(t *testing.T) {
+	exercises := &mockExerciseRepo{exercises: map[string]*model.Exercise{
+		"ex1": {ID: "ex1", TestCode: "def test_x():\n    assert True\n"},
+	}}
+	submissions := &mockSubmissionRepo{}
+	exec := &timeoutExecutor{}
+	svc := NewGradingService(exercises, submissions, &mockHintRepo{}, exec, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	if _, err := svc.Submit(ctx, "u1", "ex1", "  "); !errors.Is(err, apperror.ErrValidation) {
+		t.Errorf("empty code: error = %v, want ErrValidation", err)
+	}
+	if _, err := svc.Submit(ctx, "u1", "nope", "x = 1"); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("unknown exercise: error = %v, want ErrNotFound", err)
+	}
+	if exec.runs != 0 {
+		t.Fatalf("ran code %d times for invalid submissions", exec.runs)
+	}
+
+	sub, err := svc.Submit(ctx, "u1", "ex1", "while True: pass")
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if sub.Status != model.SubmissionError || sub.Error != "your code timed out" {
+		t.Errorf("submission = %+v, want a timed-out error", sub)
+	}
+	if len(submissions.saved) != 1 || submissions.saved[0].UserID != "u1" || submissions.saved[0].ExerciseID != "ex1" {
+		t.Errorf("saved = %+v, want one submission by u1 for ex1", submissions.saved)
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// HINTS:
+// An exercise can carry a few hints, written by its author in order from a
+// gentle nudge to nearly the answer. A learner unlocks them one at a time:
+//
+//	GET  /api/v1/exercises/{id}/hints  → the hints unlocked so far
+//	POST /api/v1/exercises/{id}/hints  → unlock the next one
+//
+// Unlocks are per user and permanent. If the exercise sets a hint penalty,
+// every later submission loses that many percent of its score per hint
+// unlocked (see GradingService.submit), so hints cost something to use.
+
+// HintService hands out an exercise's hints.
+type HintService struct {
+	exercises repository.ExerciseRepository
+	hints     repository.HintRepository
+	logger    *slog.Logger
+}
+
+// NewHintService creates a HintService.
+func NewHintService(exercises repository.ExerciseRepository, hints repository.HintRepository, logger *slog.Logger) *HintService {
+	return &HintService{
+		exercises: exercises,
+		hints:     hints,
+		logger:    logger,
+	}
+}
+
+// Progress returns the hints userID has unlocked for an exercise.
+func (s *HintService) Progress(ctx context.Context, userID, exerciseID string) (*model.HintProgress, error) {
+	exercise, err := s.exercises.GetExercise(ctx, exerciseID)
+	if err != nil {
+		return nil, err
+	}
+	unlocked, err := s.hints.HintsUnlocked(ctx, exercise.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return hintProgress(exercise, unlocked), nil
+}
+
+// Unlock reveals userID's next hint for an exercise.
+func (s *HintService) Unlock(ctx context.Context, userID, exerciseID string) (*model.HintProgress, error) {
+	exercise, err := s.exercises.GetExercise(ctx, exerciseID)
+	if err != nil {
+		return nil, err
+	}
+	before, err := s.hints.HintsUnlocked(ctx, exercise.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if before >= len(exercise.Hints) {
+		return nil, &apperror.AppError{
+			Err:     apperror.ErrConflict,
+			Message: "there are no more hints for this exercise",
+		}
+	}
+	unlocked, err := s.hints.UnlockHint(ctx, exercise.ID, userID, len(exercise.Hints))
+	if err != nil {
+		return nil, fmt.Errorf("unlocking hint: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "hint unlocked",
+		slog.String("exercise_id", exercise.ID),
+		slog.String("user_id", userID),
+		slog.Int("unlocked", unlocked),
+	)
+	return hintProgress(exercise, unlocked), nil
+}
+
+// hintProgress is what a learner who has unlocked n hints gets to see. An
+// author may have removed hints since, so n is capped at what's there now.
+func hintProgress(exercise *model.Exercise, n int) *model.HintProgress {
+	n = min(n, len(exercise.Hints))
+	return &model.HintProgress{
+		Total:          len(exercise.Hints),
+		Unlocked:       n,
+		PenaltyPerHint: exercise.HintPenalty,
+		ScorePenalty:   hintPenalty(exercise, n),
+		Hints:          append([]string{}, exercise.Hints[:n]...),
+	}
+}
+
+// hintPenalty is the percentage taken off a score after n hints.
+func hintPenalty(exercise *model.Exercise, n int) int {
+	return min(100, min(n, len(exercise.Hints))*exercise.HintPenalty)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+)
+
+// mockHintRepo counts unlocks in memory, keyed by exercise and user.
+type mockHintRepo struct{ unlocked map[[2]string]int }
+
+func (m *mockHintRepo) HintsUnlocked(_ context.Context, exerciseID, userID string) (int, error) {
+	return m.unlocked[[2]string{exerciseID, userID}], nil
+}
+
+func (m *mockHintRepo) UnlockHint(_ context.Context, exerciseID, userID string, total int) (int, error) {
+	if m.unlocked == nil {
+		m.unlocked = make(map[[2]string]int)
+	}
+	key := [2]string{exerciseID, userID}
+	m.unlocked[key] = min(m.unlocked[key]+1, total)
+	return m.unlocked[key], nil
+}
+
+func TestHintService(t *testing.T) {
+	exercises := &mockExerciseRepo{exercises: map[string]*model.Exercise{
+		"ex1": {ID: "ex1", Hints: []string{"Think about +", "return a + b"}, HintPenalty: 30},
+		"ex2": {ID: "ex2"},
+	}}
+	hints := &mockHintRepo{}
+	svc := NewHintService(exercises, hints, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	p, err := svc.Progress(ctx, "u1", "ex1")
+	if err != nil || p.Total != 2 || p.Unlocked != 0 || len(p.Hints) != 0 {
+		t.Fatalf("Progress() = %+v, %v; want 2 hints, none unlocked", p, err)
+	}
+	for want := 1; want <= 2; want++ {
+		p, err = svc.Unlock(ctx, "u1", "ex1")
+		if err != nil || p.Unlocked != want || p.ScorePenalty != 30*want {
+			t.Fatalf("Unlock() #%d = %+v, %v", want, p, err)
+		}
+	}
+	if !slices.Equal(p.Hints, []string{"Think about +", "return a + b"}) {
+		t.Errorf("hints = %q, want both, in order", p.Hints)
+	}
+	if _, err := svc.Unlock(ctx, "u1", "ex1"); !errors.Is(err, apperror.ErrConflict) {
+		t.Errorf("unlock past the last hint: error = %v, want ErrConflict", err)
+	}
+	if _, err := svc.Unlock(ctx, "u1", "ex2"); !errors.Is(err, apperror.ErrConflict) {
+		t.Errorf("unlock on an exercise without hints: error = %v, want ErrConflict", err)
+	}
+	if p, _ := svc.Progress(ctx, "u2", "ex1"); p.Unlocked != 0 {
+		t.Errorf("another user's progress = %+v, want their own (none)", p)
+	}
+	if _, err := svc.Progress(ctx, "u1", "nope"); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("unknown exercise: error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestHintProgress_HintsRemoved(t *testing.T) {
+	// The learner unlocked 3, then the author cut the exercise down to 1.
+	p := hintProgress(&model.Exercise{Hints: []string{"only"}, HintPenalty: 60}, 3)
+	if p.Unlocked != 1 || len(p.Hints) != 1 || p.ScorePenalty != 60 {
+		t.Errorf("hintProgress() = %+v, want capped at the 1 remaining hint", p)
+	}
+	if got := hintPenalty(&model.Exercise{Hints: make([]string, 4), HintPenalty: 40}, 4); got != 100 {
+		t.Errorf("hintPenalty() = %d, want capped at 100", got)
+	}
+}
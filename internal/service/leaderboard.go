@@ -0,0 +1,153 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// LEADERBOARDS:
+// Each exercise has a board of its solvers, best first: highest score, then
+// fastest tests, then whoever got there first. A class's board adds up each
+// student's best scores across the class's assigned exercises.
+//
+// Boards are read from a table a scheduled task rebuilds (the
+// leaderboard_refresh task), so they lag submissions by up to one refresh. Users can opt out; they
+// vanish from every board straight away, without waiting for a refresh.
+
+// LeaderboardService serves leaderboards and the opt-out setting.
+type LeaderboardService struct {
+	boards    repository.LeaderboardRepository
+	exercises repository.ExerciseRepository
+	classes   repository.ClassRepository
+	users     repository.UserRepository
+	logger    *slog.Logger
+}
+
+// NewLeaderboardService creates a LeaderboardService.
+func NewLeaderboardService(boards repository.LeaderboardRepository, exercises repository.ExerciseRepository, classes repository.ClassRepository, users repository.UserRepository, logger *slog.Logger) *LeaderboardService {
+	return &LeaderboardService{
+		boards:    boards,
+		exercises: exercises,
+		classes:   classes,
+		users:     users,
+		logger:    logger,
+	}
+}
+
+// Exercise returns an exercise's leaderboard, top limit entries.
+func (s *LeaderboardService) Exercise(ctx context.Context, exerciseID string, limit int) (*model.Leaderboard, error) {
+	if _, err := s.exercises.GetExercise(ctx, exerciseID); err != nil {
+		return nil, err
+	}
+	entries, err := s.boards.ListLeaderboardEntries(ctx, repository.LeaderboardFilter{ExerciseID: exerciseID}, leaderboardLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	return s.leaderboard(ctx, rank(entries))
+}
+
+// Class returns a class's leaderboard, top limit entries. Members only: to
+// anyone else the class doesn't exist.
+func (s *LeaderboardService) Class(ctx context.Context, userID, classID string, limit int) (*model.Leaderboard, error) {
+	if _, err := s.classes.GetClass(ctx, classID); err != nil {
+		return nil, err
+	}
+	_, err := s.classes.GetClassMember(ctx, classID, userID)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, apperror.NotFound("class", classID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.boards.ListLeaderboardEntries(ctx, repository.LeaderboardFilter{ClassID: classID}, 0)
+	if err != nil {
+		return nil, err
+	}
+	totals := rank(classTotals(entries))
+	return s.leaderboard(ctx, totals[:min(len(totals), leaderboardLimit(limit))])
+}
+
+// SetOptOut keeps userID off leaderboards, or puts them back, and returns
+// the updated user.
+func (s *LeaderboardService) SetOptOut(ctx context.Context, userID string, optOut bool) (*model.User, error) {
+	if err := s.users.SetLeaderboardOptOut(ctx, userID, optOut); err != nil {
+		return nil, fmt.Errorf("setting leaderboard opt-out: %w", err)
+	}
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("looking up user: %w", err)
+	}
+	if user == nil {
+		return nil, apperror.NotFound("user", userID)
+	}
+
+	s.logger.InfoContext(ctx, "leaderboard opt-out changed",
+		slog.String("user_id", userID),
+		slog.Bool("opt_out", optOut),
+	)
+	return user, nil
+}
+
+func (s *LeaderboardService) leaderboard(ctx context.Context, entries []model.LeaderboardEntry) (*model.Leaderboard, error) {
+	refreshedAt, err := s.boards.LeaderboardRefreshedAt(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Leaderboard{Entries: entries, RefreshedAt: refreshedAt}, nil
+}
+
+// leaderboardLimit applies the list defaults to a requested board size.
+func leaderboardLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultListLimit
+	}
+	return min(limit, MaxListLimit)
+}
+
+// classTotals folds per-exercise entries into one per student.
+func classTotals(entries []model.LeaderboardEntry) []model.LeaderboardEntry {
+	totals := []model.LeaderboardEntry{}
+	index := make(map[string]int)
+	for _, e := range entries {
+		i, ok := index[e.UserID]
+		if !ok {
+			index[e.UserID] = len(totals)
+			totals = append(totals, e)
+			continue
+		}
+		t := &totals[i]
+		t.Score += e.Score
+		t.Solved += e.Solved
+		t.DurationMS += e.DurationMS
+		t.Submissions += e.Submissions
+		if e.AchievedAt.After(t.AchievedAt) {
+			t.AchievedAt = e.AchievedAt
+		}
+	}
+	return totals
+}
+
+// rank sorts entries best first and numbers them from 1. Ties keep
+// distinct ranks: the earlier achiever goes first.
+func rank(entries []model.LeaderboardEntry) []model.LeaderboardEntry {
+	slices.SortStableFunc(entries, func(a, b model.LeaderboardEntry) int {
+		return cmp.Or(
+			cmp.Compare(b.Score, a.Score),
+			cmp.Compare(a.DurationMS, b.DurationMS),
+			a.AchievedAt.Compare(b.AchievedAt),
+		)
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+	return entries
+}
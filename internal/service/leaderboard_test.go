@@ -0,0 +1,49 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/model"
+)
+
+func TestRank(t *testing.T) {
+	t0 := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	entries := []model.LeaderboardEntry{
+		{UserID: "partial", Score: 50, DurationMS: 1, AchievedAt: t0},
+		{UserID: "slow", Score: 100, DurationMS: 90, AchievedAt: t0},
+		{UserID: "second", Score: 100, DurationMS: 20, AchievedAt: t0.Add(time.Minute)},
+		{UserID: "first", Score: 100, DurationMS: 20, AchievedAt: t0},
+	}
+
+	want := []string{"first", "second", "slow", "partial"}
+	for i, e := range rank(entries) {
+		if e.UserID != want[i] || e.Rank != i+1 {
+			t.Errorf("rank %d = %s (Rank %d), want %s", i+1, e.UserID, e.Rank, want[i])
+		}
+	}
+}
+
+func TestClassTotals(t *testing.T) {
+	t0 := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	entries := []model.LeaderboardEntry{
+		{UserID: "ana", Score: 100, Solved: 1, DurationMS: 10, Submissions: 2, AchievedAt: t0},
+		{UserID: "ben", Score: 100, Solved: 1, DurationMS: 5, Submissions: 1, AchievedAt: t0},
+		{UserID: "ana", Score: 60, DurationMS: 30, Submissions: 3, AchievedAt: t0.Add(time.Hour)},
+	}
+
+	totals := rank(classTotals(entries))
+	if len(totals) != 2 {
+		t.Fatalf("classTotals() = %d entries, want one per student", len(totals))
+	}
+	ana := totals[0]
+	if ana.UserID != "ana" || ana.Score != 160 || ana.Solved != 1 || ana.DurationMS != 40 || ana.Submissions != 5 {
+		t.Errorf("ana = %+v, want her two exercises added up", ana)
+	}
+	if !ana.AchievedAt.Equal(t0.Add(time.Hour)) {
+		t.Errorf("ana.AchievedAt = %v, want her latest best attempt", ana.AchievedAt)
+	}
+	if len(classTotals(nil)) != 0 || classTotals(nil) == nil {
+		t.Error("classTotals(nil) should be an empty, non-nil list")
+	}
+}
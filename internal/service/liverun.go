@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/sakif/coding-playground/internal/abuse"
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/executor"
+	"github.com/sakif/coding-playground/internal/ws"
+)
+
+// LIVE RUNS:
+// A teacher demoing a snippet starts a live run; everyone watching sees the
+// program's output appear as it's printed, as if they were looking over the
+// teacher's shoulder. Viewers subscribe to the snippet's "live:<id>" topic
+// on the WebSocket hub and receive:
+//
+//	{"kind":"start","run":{…}}
+//	{"kind":"output","runId":"…","stream":"stdout","text":"1\n"}
+//	{"kind":"done","run":{…,"result":{"exitCode":0,…}}}
+//
+// Someone who tunes in halfway through fetches the run (GET
+// /snippets/{id}/live-run) for the output so far, then follows the events.
+// A snippet has one live run at a time, started and stopped by its owner.
+// With an executor that can't stream (see executor.Streamer), all of the
+// output arrives in one piece at the end.
+
+const (
+	// LiveRunTopicPrefix starts a snippet's live-run topic, "live:<snippet id>".
+	LiveRunTopicPrefix = "live:"
+
+	// MaxLiveRunOutput caps how much output a run keeps for late viewers.
+	// Events carry all of it.
+	MaxLiveRunOutput = 256 << 10
+)
+
+// LiveRun is a snippet's current or most recent live run.
+type LiveRun struct {
+	ID        string                    `json:"id"`
+	SnippetID string                    `json:"snippetId"`
+	StartedBy string                    `json:"startedBy"`
+	Topic     string                    `json:"topic"` // subscribe to this on /ws
+	Running   bool                      `json:"running"`
+	Stdout    string                    `json:"stdout"`
+	Stderr    string                    `json:"stderr"`
+	Truncated bool                      `json:"truncated,omitempty"` // past MaxLiveRunOutput
+	Result    *executor.ExecutionResult `json:"result,omitempty"`
+	Error     string                    `json:"error,omitempty"` // the sandbox failed
+	StartedAt time.Time                 `json:"startedAt"`
+	EndedAt   *time.Time                `json:"endedAt,omitempty"`
+}
+
+// liveRunEvent is published on the run's topic.
+type liveRunEvent struct {
+	Kind   string   `json:"kind"` // "start", "output" or "done"
+	Run    *LiveRun `json:"run,omitempty"`
+	RunID  string   `json:"runId,omitempty"`
+	Stream string   `json:"stream,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+// LiveRunService runs snippets for an audience.
+type LiveRunService struct {
+	snippets *SnippetService
+	exec     executor.Executor
+	hub      *ws.Hub
+	logger   *slog.Logger
+	abuse    *abuse.Detector // optional; see DetectAbuse
+
+	mu      sync.Mutex
+	runs    map[string]*LiveRun // by snippet ID, kept after they end
+	cancels map[string]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewLiveRunService registers the "live:" topics on hub. Anyone who can
+// load a snippet may watch its runs.
+func NewLiveRunService(snippets *SnippetService, exec executor.Executor, hub *ws.Hub, logger *slog.Logger) *LiveRunService {
+	hub.Authorize(LiveRunTopicPrefix, func(ctx context.Context, _, topic string) error {
+		_, err := snippets.GetByID(ctx, strings.TrimPrefix(topic, LiveRunTopicPrefix))
+		return err
+	})
+	return &LiveRunService{
+		snippets: snippets,
+		exec:     exec,
+		hub:      hub,
+		logger:   logger,
+		runs:     make(map[string]*LiveRun),
+		cancels:  make(map[string]context.CancelFunc),
+	}
+}
+
+// DetectAbuse makes the service check snippets with d before running them.
+// Call it before serving requests.
+func (s *LiveRunService) DetectAbuse(d *abuse.Detector) {
+	s.abuse = d
+}
+
+// Start runs a snippet's saved code for its viewers. Only the owner (or
+// anyone in the org that owns it) may, only one run per snippet at a time,
+// and an org's runs count against its daily quota.
+func (s *LiveRunService) Start(ctx context.Context, userID, snippetID string) (*LiveRun, error) {
+	snippet, err := s.snippets.GetByID(ctx, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := s.snippets.CanRun(ctx, userID, snippet); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, &apperror.AppError{Err: apperror.ErrForbidden, Message: "only the snippet's owner can start a live run"}
+	}
+	if strings.TrimSpace(snippet.Code) == "" {
+		return nil, apperror.ValidationFailed("code", "the snippet has no code to run")
+	}
+	if s.abuse != nil {
+		if err := s.abuse.Check(ctx, abuse.SourceLiveRun, snippet.Code); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if run := s.runs[snippet.ID]; run != nil && run.Running {
+		return nil, &apperror.AppError{Err: apperror.ErrConflict, Message: "a live run of this snippet is already going"}
+	}
+	if err := s.snippets.chargeRun(ctx, snippet); err != nil {
+		return nil, err
+	}
+	run := &LiveRun{
+		ID:        xid.New().String(),
+		SnippetID: snippet.ID,
+		StartedBy: userID,
+		Topic:     LiveRunTopicPrefix + snippet.ID,
+		Running:   true,
+		StartedAt: time.Now().UTC(),
+	}
+	s.runs[snippet.ID] = run
+	// The run outlives the request that started it.
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	s.cancels[snippet.ID] = cancel
+	s.publish(run.Topic, liveRunEvent{Kind: "start", Run: run.copy()})
+
+	s.wg.Add(1)
+	go s.execute(runCtx, run, executor.ExecutionRequest{Code: snippet.Code, Requirements: snippet.Requirements})
+
+	s.logger.InfoContext(ctx, "live run started",
+		slog.String("snippet_id", snippet.ID),
+		slog.String("run_id", run.ID),
+	)
+	return run.copy(), nil
+}
+
+// Get returns a snippet's current or most recent live run.
+func (s *LiveRunService) Get(ctx context.Context, snippetID string) (*LiveRun, error) {
+	if _, err := s.snippets.GetByID(ctx, snippetID); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run := s.runs[snippetID]
+	if run == nil {
+		return nil, apperror.NotFound("live run", snippetID)
+	}
+	return run.copy(), nil
+}
+
+// Stop cuts a snippet's live run short. Only those who may start one may.
+func (s *LiveRunService) Stop(ctx context.Context, userID, snippetID string) error {
+	snippet, err := s.snippets.GetByID(ctx, snippetID)
+	if err != nil {
+		return err
+	}
+	if ok, err := s.snippets.CanRun(ctx, userID, snippet); err != nil {
+		return err
+	} else if !ok {
+		return &apperror.AppError{Err: apperror.ErrForbidden, Message: "only the snippet's owner can stop a live run"}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cancel := s.cancels[snippet.ID]
+	if cancel == nil {
+		return apperror.NotFound("live run", snippet.ID)
+	}
+	cancel()
+	return nil
+}
+
+// Shutdown stops every live run and waits for them to end, or for ctx.
+func (s *LiveRunService) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	for _, cancel := range s.cancels {
+		cancel()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// execute runs the snippet's code, publishing output as it comes.
+func (s *LiveRunService) execute(ctx context.Context, run *LiveRun, req executor.ExecutionRequest) {
+	defer s.wg.Done()
+
+	// A run gets the same Execute deadline as the snippet service's others.
+	result, err := withExecute(ctx, s.snippets.deadlines, func(ctx context.Context) (*executor.ExecutionResult, error) {
+		if streamer, ok := s.exec.(executor.Streamer); ok {
+			return streamer.ExecuteStream(ctx, req, func(o executor.Output) { s.output(run, o) })
+		}
+		result, err := s.exec.Execute(ctx, req)
+		if err == nil {
+			s.output(run, executor.Output{Stream: "stdout", Text: result.Stdout})
+			s.output(run, executor.Output{Stream: "stderr", Text: result.Stderr})
+		}
+		return result, err
+	})
+
+	if err == nil && s.abuse != nil {
+		s.abuse.Observe(ctx, req.Code, result.Duration)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run.Running = false
+	now := time.Now().UTC()
+	run.EndedAt = &now
+	run.Result = result
+	if err != nil {
+		run.Error = "the sandbox failed to run the code"
+		s.logger.Error("live run failed",
+			slog.String("snippet_id", run.SnippetID),
+			slog.String("run_id", run.ID),
+			slog.String("error", err.Error()),
+		)
+	}
+	s.cancels[run.SnippetID]()
+	delete(s.cancels, run.SnippetID)
+	s.publish(run.Topic, liveRunEvent{Kind: "done", Run: run.copy()})
+}
+
+// output records a piece of output and sends it to the viewers.
+func (s *LiveRunService) output(run *LiveRun, o executor.Output) {
+	if o.Text == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := &run.Stdout
+	if o.Stream == "stderr" {
+		kept = &run.Stderr
+	}
+	if len(run.Stdout)+len(run.Stderr)+len(o.Text) <= MaxLiveRunOutput {
+		*kept += o.Text
+	} else {
+		run.Truncated = true
+	}
+	s.publish(run.Topic, liveRunEvent{Kind: "output", RunID: run.ID, Stream: o.Stream, Text: o.Text})
+}
+
+// publish sends an event to a run's viewers. Callers hold s.mu, so events
+// go out in order.
+func (s *LiveRunService) publish(topic string, event liveRunEvent) {
+	if err := s.hub.Publish(topic, event); err != nil {
+		s.logger.Error("failed to publish live run event", slog.String("topic", topic), slog.String("error", err.Error()))
+	}
+}
+
+// copy snapshots the run for callers outside s.mu.
+func (r *LiveRun) copy() *LiveRun {
+	c := *r
+	return &c
+}
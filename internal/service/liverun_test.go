@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/executor"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/ws"
+)
+
+// streamingExecutor prints one line, then waits to be released (or
+// stopped) before printing another.
+type streamingExecutor struct{ release chan struct{} }
+
+func (e *streamingExecutor) Execute(ctx context.Context, req executor.ExecutionRequest) (*executor.ExecutionResult, error) {
+	return e.ExecuteStream(ctx, req, func(executor.Output) {})
+}
+
+func (e *streamingExecutor) ExecuteStream(ctx context.Context, _ executor.ExecutionRequest, out func(executor.Output)) (*executor.ExecutionResult, error) {
+	out(executor.Output{Stream: "stdout", Text: "one\n"})
+	select {
+	case <-e.release:
+		out(executor.Output{Stream: "stdout", Text: "two\n"})
+		return &executor.ExecutionResult{Stdout: "one\ntwo\n"}, nil
+	case <-ctx.Done():
+		return &executor.ExecutionResult{Stdout: "one\n", Stderr: "\nExecution timed out.\n", ExitCode: 124}, nil
+	}
+}
+
+func newLiveRunService(t *testing.T, exec executor.Executor) *LiveRunService {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := newMockRepo()
+	repo.snippets["s1"] = &model.Snippet{ID: "s1", UserID: "teacher", Code: "print('one')"}
+	repo.snippets["anon"] = &model.Snippet{ID: "anon", Code: "print(1)"}
+	return NewLiveRunService(NewSnippetService(repo, logger), exec, ws.NewHub(logger, ws.Options{}), logger)
+}
+
+// waitForEnd polls until the snippet's live run has finished.
+func waitForEnd(t *testing.T, svc *LiveRunService, snippetID string) *LiveRun {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		run, err := svc.Get(context.Background(), snippetID)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if !run.Running {
+			return run
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("live run still going: %+v", run)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLiveRunService(t *testing.T) {
+	exec := &streamingExecutor{release: make(chan struct{})}
+	svc := newLiveRunService(t, exec)
+	ctx := context.Background()
+
+	if _, err := svc.Get(ctx, "s1"); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("Get() before any run: error = %v, want ErrNotFound", err)
+	}
+	if _, err := svc.Start(ctx, "student", "s1"); !errors.Is(err, apperror.ErrForbidden) {
+		t.Errorf("Start() by someone else: error = %v, want ErrForbidden", err)
+	}
+	if _, err := svc.Start(ctx, "", "anon"); !errors.Is(err, apperror.ErrForbidden) {
+		t.Errorf("Start() of an anonymous snippet: error = %v, want ErrForbidden", err)
+	}
+
+	run, err := svc.Start(ctx, "teacher", "s1")
+	if err != nil || !run.Running || run.Topic != "live:s1" {
+		t.Fatalf("Start() = %+v, %v", run, err)
+	}
+	if _, err := svc.Start(ctx, "teacher", "s1"); !errors.Is(err, apperror.ErrConflict) {
+		t.Errorf("second Start() while running: error = %v, want ErrConflict", err)
+	}
+
+	close(exec.release)
+	done := waitForEnd(t, svc, "s1")
+	if done.Stdout != "one\ntwo\n" || done.Result == nil || done.EndedAt == nil {
+		t.Errorf("finished run = %+v, want both lines and a result", done)
+	}
+	if err := svc.Stop(ctx, "teacher", "s1"); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("Stop() after the run ended: error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLiveRunService_Stop(t *testing.T) {
+	svc := newLiveRunService(t, &streamingExecutor{release: make(chan struct{})})
+	ctx := context.Background()
+
+	if _, err := svc.Start(ctx, "teacher", "s1"); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := svc.Stop(ctx, "student", "s1"); !errors.Is(err, apperror.ErrForbidden) {
+		t.Errorf("Stop() by someone else: error = %v, want ErrForbidden", err)
+	}
+	if err := svc.Stop(ctx, "teacher", "s1"); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if run := waitForEnd(t, svc, "s1"); run.Result.ExitCode != 124 {
+		t.Errorf("stopped run = %+v, want it cut short", run)
+	}
+}
+
+func TestLiveRunService_WithoutStreaming(t *testing.T) {
+	svc := newLiveRunService(t, &timeoutExecutor{})
+	if _, err := svc.Start(context.Background(), "teacher", "s1"); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if run := waitForEnd(t, svc, "s1"); run.Stderr != "\nExecution timed out.\n" {
+		t.Errorf("run = %+v, want the whole output at the end", run)
+	}
+	if err := svc.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+}
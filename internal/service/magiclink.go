@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/mailer"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// SIGN-IN LINKS ("magic links"):
+// Anyone whose account has an email address can sign in without GitHub:
+//
+//	POST /auth/magic-link {"email": "ann@example.com"}   → a link is emailed
+//	GET  /auth/magic?token=…                             → a "Sign in" button
+//	POST /auth/magic                                     → the session cookie
+//
+// The token is 32 random bytes. Only its SHA-256 is stored, it works once,
+// and it expires after MagicLinkTTL. The GET doesn't sign in by itself:
+// mail scanners follow the links in emails and would use them up.
+//
+// Asking for a link always looks the same, whether or not the address
+// belongs to an account — otherwise the form would tell anyone who has
+// signed up.
+
+// MagicLinkTTL is how long a sign-in link works.
+const MagicLinkTTL = 15 * time.Minute
+
+// MagicLinkService emails sign-in links and signs in with them.
+type MagicLinkService struct {
+	emails *EmailService
+	repo   repository.EmailRepository
+	users  repository.UserRepository
+	tokens *auth.TokenService
+	logger *slog.Logger
+	events EventPublisher // optional; see PublishEvents
+}
+
+// NewMagicLinkService creates a MagicLinkService that sends through emails.
+func NewMagicLinkService(emails *EmailService, repo repository.EmailRepository, users repository.UserRepository, tokens *auth.TokenService, logger *slog.Logger) *MagicLinkService {
+	return &MagicLinkService{
+		emails: emails,
+		repo:   repo,
+		users:  users,
+		tokens: tokens,
+		logger: logger,
+	}
+}
+
+// PublishEvents makes the service report user.logged_in to p. Call it
+// before serving requests.
+func (s *MagicLinkService) PublishEvents(p EventPublisher) {
+	s.events = p
+}
+
+// Request emails a sign-in link to address if it belongs to an account.
+// An address without one is not an error.
+func (s *MagicLinkService) Request(ctx context.Context, address string) error {
+	address = strings.TrimSpace(address)
+	if parsed, err := mail.ParseAddress(address); err != nil || parsed.Address != address {
+		return apperror.ValidationFailed("email", "email must be an email address")
+	}
+	user, err := s.users.GetUserByEmail(ctx, address)
+	if err != nil {
+		return fmt.Errorf("looking up user: %w", err)
+	}
+	if user == nil {
+		s.logger.InfoContext(ctx, "sign-in link requested for an unknown address")
+		return nil
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Errorf("generating sign-in token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+	if err := s.repo.CreateMagicLink(ctx, hashToken(token), user.ID, time.Now().Add(MagicLinkTTL)); err != nil {
+		return err
+	}
+	err = s.emails.Queue(ctx, mailer.TemplateMagicLink, user.Email, mailer.MagicLinkData{
+		Login:   user.Login,
+		URL:     s.emails.baseURL + "/auth/magic?" + url.Values{"token": {token}}.Encode(),
+		Minutes: int(MagicLinkTTL / time.Minute),
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "sign-in link sent", slog.String("user_id", user.ID))
+	return nil
+}
+
+// SignIn uses up a sign-in link's token and returns a session for its user.
+// Unknown, used and expired tokens are apperror.ErrNotFound.
+func (s *MagicLinkService) SignIn(ctx context.Context, token string) (*LoginResult, error) {
+	if token == "" {
+		return nil, &apperror.AppError{Err: apperror.ErrNotFound, Message: "sign-in link is invalid, used or expired"}
+	}
+	userID, err := s.repo.ConsumeMagicLink(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("looking up user: %w", err)
+	}
+	if user == nil {
+		return nil, apperror.NotFound("user", userID)
+	}
+	jwt, err := s.tokens.Generate(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("generate token: %w", err)
+	}
+	if s.events != nil {
+		s.events.Publish(ctx, model.EventUserLoggedIn, &model.Login{UserID: user.ID, Method: model.LoginEmail})
+	}
+	return &LoginResult{Token: jwt, User: user}, nil
+}
+
+// hashToken is how a sign-in token is stored.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
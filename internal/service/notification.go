@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+	"github.com/sakif/coding-playground/internal/ws"
+)
+
+// NOTIFICATIONS:
+// Users hear about what happens to their work:
+//
+//	comment.created   → the snippet's owner: "ann commented on “loop”"
+//	snippet.forked    → the original's owner: "ann forked “loop”"
+//	snippet.starred   → the snippet's owner: "ann starred “loop”"
+//	submission.graded → the submitter: "Your solution to “Add” passed"
+//	transfer.offered  → the recipient: "ann wants to transfer “loop” to you"
+//
+// Nobody is notified about their own actions on their own snippets.
+// NotificationService is an EventPublisher, so it hears about these the
+// way webhooks and badges do. Each notification is saved, then pushed to
+// the user's "user:<id>" topic on the WebSocket hub with the new unread
+// count, so an open page can update its bell without polling:
+//
+//	{"kind":"notification","notification":{…},"unread":3}
+//	{"kind":"read","unread":0}   ← after marking read, for the other tabs
+
+const (
+	// UserTopicPrefix starts a user's own topic, "user:<user id>".
+	UserTopicPrefix = "user:"
+
+	DefaultNotificationLimit = 20
+	MaxNotificationLimit     = 100
+)
+
+// NotificationList is a page of notifications and the unread count.
+type NotificationList struct {
+	Items  []model.Notification `json:"items"`
+	Unread int                  `json:"unread"`
+}
+
+// notificationEvent is pushed to a user's topic.
+type notificationEvent struct {
+	Kind         string              `json:"kind"` // "notification" or "read"
+	Notification *model.Notification `json:"notification,omitempty"`
+	Unread       int                 `json:"unread"`
+}
+
+// NotificationService turns events into notifications.
+type NotificationService struct {
+	repo      repository.NotificationRepository
+	snippets  repository.SnippetRepository
+	exercises repository.ExerciseRepository
+	users     repository.UserRepository
+	hub       *ws.Hub
+	logger    *slog.Logger
+}
+
+// NewNotificationService creates a NotificationService that pushes to hub.
+func NewNotificationService(repo repository.NotificationRepository, snippets repository.SnippetRepository, exercises repository.ExerciseRepository, users repository.UserRepository, hub *ws.Hub, logger *slog.Logger) *NotificationService {
+	return &NotificationService{
+		repo:      repo,
+		snippets:  snippets,
+		exercises: exercises,
+		users:     users,
+		hub:       hub,
+		logger:    logger,
+	}
+}
+
+// List returns userID's newest notifications, only unread ones if
+// unreadOnly is set. limit is clamped like snippet list limits.
+func (s *NotificationService) List(ctx context.Context, userID string, unreadOnly bool, limit int) (*NotificationList, error) {
+	if limit <= 0 {
+		limit = DefaultNotificationLimit
+	}
+	limit = min(limit, MaxNotificationLimit)
+	items, err := s.repo.ListNotifications(ctx, userID, unreadOnly, limit)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.repo.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &NotificationList{Items: items, Unread: unread}, nil
+}
+
+// MarkRead marks userID's notifications with these IDs read, or all of
+// them when ids is empty, and returns how many are still unread.
+func (s *NotificationService) MarkRead(ctx context.Context, userID string, ids []string) (int, error) {
+	if len(ids) > MaxNotificationLimit {
+		return 0, apperror.ValidationFailed("ids", fmt.Sprintf("mark at most %d notifications at a time", MaxNotificationLimit))
+	}
+	if err := s.repo.MarkNotificationsRead(ctx, userID, ids); err != nil {
+		return 0, err
+	}
+	unread, err := s.repo.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	s.push(userID, notificationEvent{Kind: "read", Unread: unread})
+	return unread, nil
+}
+
+// Publish notifies whoever an event concerns. Like every EventPublisher,
+// it never fails the caller: problems are logged.
+func (s *NotificationService) Publish(ctx context.Context, event string, data any) {
+	// The event has happened; a client hanging up now shouldn't lose it.
+	ctx = context.WithoutCancel(ctx)
+	actorID, _ := auth.UserIDFromContext(ctx)
+
+	n, err := s.build(ctx, event, actorID, data)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to build notification",
+			slog.String("event", event),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	if n == nil {
+		return
+	}
+	if err := s.repo.CreateNotification(ctx, n); err != nil {
+		s.logger.ErrorContext(ctx, "failed to save notification",
+			slog.String("event", event),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	unread, err := s.repo.CountUnreadNotifications(ctx, n.UserID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to count notifications", slog.String("error", err.Error()))
+		return
+	}
+	if n.ActorID != "" {
+		n.ActorLogin = s.login(ctx, n.ActorID)
+	}
+	s.push(n.UserID, notificationEvent{Kind: "notification", Notification: n, Unread: unread})
+}
+
+// build works out the notification for an event, or nil when nobody needs
+// to hear about it.
+func (s *NotificationService) build(ctx context.Context, event, actorID string, data any) (*model.Notification, error) {
+	switch event {
+	case model.EventCommentCreated:
+		comment, ok := data.(*model.Comment)
+		if !ok {
+			return nil, nil
+		}
+		snippet, err := s.snippets.GetByID(ctx, comment.SnippetID)
+		if err != nil {
+			return nil, err
+		}
+		return s.aboutSnippet(ctx, model.NotificationComment, comment.UserID, snippet, snippet.ID, "commented on"), nil
+
+	case model.EventSnippetForked:
+		fork, ok := data.(*model.Snippet)
+		if !ok {
+			return nil, nil
+		}
+		original, err := s.snippets.GetByID(ctx, fork.ForkedFrom)
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return s.aboutSnippet(ctx, model.NotificationFork, fork.UserID, original, fork.ID, "forked"), nil
+
+	case model.EventSnippetStarred:
+		snippet, ok := data.(*model.Snippet)
+		if !ok || actorID == "" {
+			return nil, nil
+		}
+		return s.aboutSnippet(ctx, model.NotificationStar, actorID, snippet, snippet.ID, "starred"), nil
+
+	case model.EventTransferOffered:
+		t, ok := data.(*model.SnippetTransfer)
+		if !ok || t.ToUserID == "" {
+			return nil, nil
+		}
+		return &model.Notification{
+			UserID:    t.ToUserID,
+			Kind:      model.NotificationTransfer,
+			ActorID:   t.CreatedBy,
+			SnippetID: t.SnippetID,
+			Message:   fmt.Sprintf("%s wants to transfer “%s” to you", s.login(ctx, t.CreatedBy), t.SnippetName),
+		}, nil
+
+	case model.EventSubmissionGraded:
+		sub, ok := data.(*model.Submission)
+		if !ok || sub.UserID == "" {
+			return nil, nil
+		}
+		title := "an exercise"
+		if exercise, err := s.exercises.GetExercise(ctx, sub.ExerciseID); err == nil {
+			title = "“" + exercise.Title + "”"
+		}
+		return &model.Notification{
+			UserID:       sub.UserID,
+			Kind:         model.NotificationGrading,
+			SubmissionID: sub.ID,
+			Message:      fmt.Sprintf("Your solution to %s %s", title, gradingOutcome(sub)),
+		}, nil
+	}
+	return nil, nil
+}
+
+// aboutSnippet builds a notification for snippet's owner that actorID did
+// something to it, or returns nil if the snippet has no owner or the actor
+// is the owner. snippetID is what the notification links to.
+func (s *NotificationService) aboutSnippet(ctx context.Context, kind, actorID string, snippet *model.Snippet, snippetID, verb string) *model.Notification {
+	if snippet.UserID == "" || snippet.UserID == actorID {
+		return nil
+	}
+	return &model.Notification{
+		UserID:    snippet.UserID,
+		Kind:      kind,
+		ActorID:   actorID,
+		SnippetID: snippetID,
+		Message:   fmt.Sprintf("%s %s “%s”", s.login(ctx, actorID), verb, snippet.Name),
+	}
+}
+
+// login returns a user's login for messages.
+func (s *NotificationService) login(ctx context.Context, userID string) string {
+	if user, err := s.users.GetUserByID(ctx, userID); err == nil && user != nil {
+		return user.Login
+	}
+	return "Someone"
+}
+
+// gradingOutcome says how a submission went, to finish a sentence.
+func gradingOutcome(sub *model.Submission) string {
+	switch sub.Status {
+	case model.SubmissionPassed:
+		return "passed"
+	case model.SubmissionFailed:
+		return fmt.Sprintf("scored %d%%", sub.Score)
+	default:
+		return "didn't run: " + sub.Error
+	}
+}
+
+// push sends an event to the user's topic. Nobody listening is fine.
+func (s *NotificationService) push(userID string, event notificationEvent) {
+	if err := s.hub.Publish(UserTopicPrefix+userID, event); err != nil {
+		s.logger.Error("failed to push notification",
+			slog.String("user_id", userID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository/sqlite"
+	"github.com/sakif/coding-playground/internal/ws"
+)
+
+func TestNotificationService(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	notifications := NewNotificationService(db, db, db, db, ws.NewHub(logger, ws.Options{}), logger)
+	snippets := NewSnippetService(db, logger)
+	snippets.PublishEvents(notifications)
+	comments := NewCommentService(db, db, logger)
+	comments.PublishEvents(notifications)
+	stars := NewStarService(snippets, db, logger)
+	stars.PublishEvents(notifications)
+
+	for i, login := range []string{"ann", "bob"} {
+		if err := db.Upsert(context.Background(), &model.User{ID: login + "-id", GitHubID: int64(i + 1), Login: login}); err != nil {
+			t.Fatalf("Upsert: %v", err)
+		}
+	}
+	ctx := context.Background()
+	asAnn := auth.WithUserID(ctx, "ann-id")
+	asBob := auth.WithUserID(ctx, "bob-id")
+
+	snippet, err := snippets.Create(asAnn, "loop", "print(1)", "")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	// Ann's own actions on her snippet notify nobody.
+	stars.Star(asAnn, "ann-id", snippet.ID)
+	comments.Create(asAnn, "ann-id", snippet.ID, CommentInput{Body: "note to self"})
+	snippets.Fork(asAnn, snippet.ID)
+
+	if _, err := comments.Create(asBob, "bob-id", snippet.ID, CommentInput{Body: "nice"}); err != nil {
+		t.Fatalf("Create comment error = %v", err)
+	}
+	stars.Star(asBob, "bob-id", snippet.ID)
+	stars.Star(asBob, "bob-id", snippet.ID) // already starred
+	fork, err := snippets.Fork(asBob, snippet.ID)
+	if err != nil {
+		t.Fatalf("Fork() error = %v", err)
+	}
+
+	list, err := notifications.List(ctx, "ann-id", false, 0)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	want := []string{"bob forked “loop”", "bob starred “loop”", "bob commented on “loop”"}
+	if len(list.Items) != len(want) || list.Unread != len(want) {
+		t.Fatalf("List() = %+v, want %d unread", list, len(want))
+	}
+	for i, n := range list.Items {
+		if n.Message != want[i] || n.ActorLogin != "bob" {
+			t.Errorf("Items[%d] = %q by %q, want %q by bob", i, n.Message, n.ActorLogin, want[i])
+		}
+	}
+	if list.Items[0].SnippetID != fork.ID {
+		t.Errorf("fork notification links to %q, want the fork %q", list.Items[0].SnippetID, fork.ID)
+	}
+	if list, _ := notifications.List(ctx, "bob-id", false, 0); len(list.Items) != 0 {
+		t.Errorf("bob's notifications = %+v, want none", list.Items)
+	}
+
+	if unread, err := notifications.MarkRead(ctx, "ann-id", []string{list.Items[0].ID}); err != nil || unread != 2 {
+		t.Errorf("MarkRead(one) = %d, %v; want 2 left", unread, err)
+	}
+	if list, _ := notifications.List(ctx, "ann-id", true, 0); len(list.Items) != 2 || list.Items[0].ReadAt != nil {
+		t.Errorf("unread only = %+v, want the other two", list.Items)
+	}
+	if unread, err := notifications.MarkRead(ctx, "bob-id", nil); err != nil || unread != 0 {
+		t.Errorf("MarkRead() of someone else's = %d, %v", unread, err)
+	}
+	if unread, err := notifications.MarkRead(ctx, "ann-id", nil); err != nil || unread != 0 {
+		t.Errorf("MarkRead(all) = %d, %v; want none left", unread, err)
+	}
+}
+
+func TestNotificationService_Graded(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	notifications := NewNotificationService(db, db, db, db, ws.NewHub(logger, ws.Options{}), logger)
+	ctx := context.Background()
+
+	tests := []struct {
+		sub  model.Submission
+		want string
+	}{
+		{model.Submission{Status: model.SubmissionPassed, Score: 100}, "Your solution to an exercise passed"},
+		{model.Submission{Status: model.SubmissionFailed, Score: 50}, "Your solution to an exercise scored 50%"},
+		{model.Submission{Status: model.SubmissionError, Error: "timed out"}, "Your solution to an exercise didn't run: timed out"},
+	}
+	for _, tt := range tests {
+		sub := tt.sub
+		sub.ID, sub.UserID, sub.ExerciseID = "sub-"+string(sub.Status), "ann-id", "gone"
+		notifications.Publish(ctx, model.EventSubmissionGraded, &sub)
+		list, err := notifications.List(ctx, "ann-id", false, 1)
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		if len(list.Items) != 1 || list.Items[0].Message != tt.want || list.Items[0].SubmissionID != sub.ID {
+			t.Errorf("%s: got %+v, want %q", sub.Status, list.Items, tt.want)
+		}
+	}
+}
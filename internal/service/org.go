@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// ORGANIZATIONS:
+// An org lets a team keep a shared library of snippets. Whoever creates an
+// org owns it, and owners add teammates by login:
+//
+//	POST /api/v1/orgs                       {"name": "Data team"}  → owner
+//	POST /api/v1/orgs/{id}/members          {"login": "bob"}       → member
+//	POST /api/v1/snippets                   {"name": "…", "orgId": "…"}
+//
+// A snippet created with an orgId belongs to the org rather than to its
+// creator: members and owners can edit it, publish it, run it live and
+// moderate its comments, while deleting it takes an owner or whoever created
+// it. Viewers ({"role": "viewer"}) can only read and run the org's snippets
+// (see orgquota.go). People outside the org can still read and fork its
+// snippets, like any other, but can't change them.
+//
+// As with classes, to anyone outside an org it doesn't exist (404, not 403).
+// An org always keeps at least one owner: the last one can't leave or step
+// down, only delete the org, which hands its snippets back to their creators.
+
+const MaxOrgNameLength = 100
+
+// OrgService manages organizations and their members.
+type OrgService struct {
+	orgs   repository.OrgRepository
+	users  repository.UserRepository
+	logger *slog.Logger
+}
+
+// NewOrgService creates an OrgService.
+func NewOrgService(orgs repository.OrgRepository, users repository.UserRepository, logger *slog.Logger) *OrgService {
+	return &OrgService{
+		orgs:   orgs,
+		users:  users,
+		logger: logger,
+	}
+}
+
+// Create makes a new org owned by userID.
+func (s *OrgService) Create(ctx context.Context, userID, name string) (*model.OrgMembership, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, apperror.ValidationFailed("name", "org name is required").WithCode(apperror.CodeNameRequired)
+	}
+	if len(name) > MaxOrgNameLength {
+		return nil, apperror.ValidationFailed("name", fmt.Sprintf("org name must be %d characters or less", MaxOrgNameLength)).
+			WithCode(apperror.CodeNameTooLong).WithParam("max", strconv.Itoa(MaxOrgNameLength))
+	}
+
+	org := &model.Org{Name: name, CreatedBy: userID}
+	if err := s.orgs.CreateOrg(ctx, org); err != nil {
+		return nil, fmt.Errorf("creating org: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "org created",
+		slog.String("id", org.ID),
+		slog.String("owner_id", userID),
+	)
+	return &model.OrgMembership{Org: *org, Role: model.OrgRoleOwner}, nil
+}
+
+// List returns the orgs userID belongs to.
+func (s *OrgService) List(ctx context.Context, userID string) ([]model.OrgMembership, error) {
+	return s.orgs.ListOrgsForUser(ctx, userID)
+}
+
+// Get returns an org as userID sees it.
+func (s *OrgService) Get(ctx context.Context, userID, orgID string) (*model.OrgMembership, error) {
+	org, member, err := s.member(ctx, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.OrgMembership{Org: *org, Role: member.Role}, nil
+}
+
+// Delete deletes an org. Owners only.
+func (s *OrgService) Delete(ctx context.Context, userID, orgID string) error {
+	if _, err := s.owner(ctx, userID, orgID); err != nil {
+		return err
+	}
+	if err := s.orgs.DeleteOrg(ctx, orgID); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "org deleted",
+		slog.String("id", orgID),
+		slog.String("user_id", userID),
+	)
+	return nil
+}
+
+// Members lists an org's members, for any of them.
+func (s *OrgService) Members(ctx context.Context, userID, orgID string) ([]model.OrgMember, error) {
+	if _, _, err := s.member(ctx, userID, orgID); err != nil {
+		return nil, err
+	}
+	return s.orgs.ListOrgMembers(ctx, orgID)
+}
+
+// AddMember adds the user with this login to an org with role ("" for a
+// plain member). Owners only.
+func (s *OrgService) AddMember(ctx context.Context, userID, orgID, login, role string) (*model.OrgMember, error) {
+	if role == "" {
+		role = model.OrgRoleMember
+	}
+	if err := validateOrgRole(role); err != nil {
+		return nil, err
+	}
+	if _, err := s.owner(ctx, userID, orgID); err != nil {
+		return nil, err
+	}
+	user, err := s.userByLogin(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.orgs.AddOrgMember(ctx, &model.OrgMember{OrgID: orgID, UserID: user.ID, Role: role})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "org member added",
+		slog.String("org_id", orgID),
+		slog.String("user_id", user.ID),
+		slog.String("role", role),
+	)
+	return s.orgs.GetOrgMember(ctx, orgID, user.ID)
+}
+
+// SetRole changes the role of the member with this login. Owners only, and
+// the last owner can't step down.
+func (s *OrgService) SetRole(ctx context.Context, userID, orgID, login, role string) (*model.OrgMember, error) {
+	if err := validateOrgRole(role); err != nil {
+		return nil, err
+	}
+	if _, err := s.owner(ctx, userID, orgID); err != nil {
+		return nil, err
+	}
+	target, err := s.memberByLogin(ctx, orgID, login)
+	if err != nil {
+		return nil, err
+	}
+	if target.Role == role {
+		return target, nil
+	}
+	if target.Role == model.OrgRoleOwner {
+		if err := s.keepAnOwner(ctx, orgID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.orgs.SetOrgMemberRole(ctx, orgID, target.UserID, role); err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "org member role changed",
+		slog.String("org_id", orgID),
+		slog.String("user_id", target.UserID),
+		slog.String("role", role),
+	)
+	target.Role = role
+	return target, nil
+}
+
+// RemoveMember takes the member with this login out of an org. Owners can
+// remove anyone, and anyone can leave; the last owner can't.
+func (s *OrgService) RemoveMember(ctx context.Context, userID, orgID, login string) error {
+	_, me, err := s.member(ctx, userID, orgID)
+	if err != nil {
+		return err
+	}
+	target, err := s.memberByLogin(ctx, orgID, login)
+	if err != nil {
+		return err
+	}
+	if target.UserID != userID && me.Role != model.OrgRoleOwner {
+		return errOrgOwnersOnly
+	}
+	if target.Role == model.OrgRoleOwner {
+		if err := s.keepAnOwner(ctx, orgID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.orgs.RemoveOrgMember(ctx, orgID, target.UserID); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "org member removed",
+		slog.String("org_id", orgID),
+		slog.String("user_id", target.UserID),
+	)
+	return nil
+}
+
+// keepAnOwner refuses to take away an owner when they're the only one.
+func (s *OrgService) keepAnOwner(ctx context.Context, orgID string) error {
+	members, err := s.orgs.ListOrgMembers(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	owners := 0
+	for _, m := range members {
+		if m.Role == model.OrgRoleOwner {
+			owners++
+		}
+	}
+	if owners <= 1 {
+		return &apperror.AppError{
+			Err:     apperror.ErrConflict,
+			Message: "an org needs at least one owner: make someone else an owner first, or delete the org",
+		}
+	}
+	return nil
+}
+
+// member returns the org and userID's membership of it. Non-members get
+// the same NotFound as for an org that doesn't exist.
+func (s *OrgService) member(ctx context.Context, userID, orgID string) (*model.Org, *model.OrgMember, error) {
+	org, err := s.orgs.GetOrg(ctx, orgID)
+	if err != nil {
+		return nil, nil, err
+	}
+	member, err := s.orgs.GetOrgMember(ctx, orgID, userID)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, nil, apperror.NotFound("org", orgID)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return org, member, nil
+}
+
+// owner returns the org if userID owns it.
+func (s *OrgService) owner(ctx context.Context, userID, orgID string) (*model.Org, error) {
+	org, member, err := s.member(ctx, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role != model.OrgRoleOwner {
+		return nil, errOrgOwnersOnly
+	}
+	return org, nil
+}
+
+var errOrgOwnersOnly = &apperror.AppError{
+	Err:     apperror.ErrForbidden,
+	Message: "only the org's owners can do this",
+}
+
+// userByLogin looks up the user with this login.
+func (s *OrgService) userByLogin(ctx context.Context, login string) (*model.User, error) {
+	login = strings.TrimSpace(login)
+	if login == "" {
+		return nil, apperror.ValidationFailed("login", "login is required")
+	}
+	user, err := s.users.GetUserByLogin(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, apperror.NotFound("user", login)
+	}
+	return user, nil
+}
+
+// memberByLogin returns the membership of the user with this login.
+func (s *OrgService) memberByLogin(ctx context.Context, orgID, login string) (*model.OrgMember, error) {
+	user, err := s.userByLogin(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	member, err := s.orgs.GetOrgMember(ctx, orgID, user.ID)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, apperror.NotFound("org member", login)
+	}
+	return member, err
+}
+
+func validateOrgRole(role string) error {
+	switch role {
+	case model.OrgRoleOwner, model.OrgRoleMember, model.OrgRoleViewer:
+		return nil
+	}
+	return apperror.ValidationFailed("role", "role must be owner, member or viewer")
+}
+
+// canManageSnippet reports whether userID may change snippet: its owner for
+// a personal snippet, any member but a viewer for an org's. orgs may be nil
+// when orgs aren't enabled.
+func canManageSnippet(ctx context.Context, orgs repository.OrgRepository, userID string, snippet *model.Snippet) (bool, error) {
+	if userID == "" {
+		return false, nil
+	}
+	if snippet.OrgID == "" {
+		return snippet.UserID == userID, nil
+	}
+	if orgs == nil {
+		return false, nil
+	}
+	member, err := orgs.GetOrgMember(ctx, snippet.OrgID, userID)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return member.Role != model.OrgRoleViewer, nil
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository/sqlite"
+)
+
+func TestOrgService(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	orgs := NewOrgService(db, db, logger)
+	snippets := NewSnippetService(db, logger)
+	snippets.EnableOrgs(db)
+
+	ctx := context.Background()
+	for i, login := range []string{"ann", "bob", "cat"} {
+		if err := db.Upsert(ctx, &model.User{ID: login + "-id", GitHubID: int64(i + 1), Login: login}); err != nil {
+			t.Fatalf("creating %s: %v", login, err)
+		}
+	}
+	asAnn := auth.WithUserID(ctx, "ann-id")
+	asBob := auth.WithUserID(ctx, "bob-id")
+	asCat := auth.WithUserID(ctx, "cat-id")
+
+	org, err := orgs.Create(ctx, "ann-id", " Data team ")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if org.Name != "Data team" || org.Role != model.OrgRoleOwner {
+		t.Errorf("Create() = %+v, want Data team owned by ann", org)
+	}
+	if _, err := orgs.Create(ctx, "ann-id", ""); !errors.Is(err, apperror.ErrValidation) {
+		t.Errorf("Create(no name) error = %v, want validation error", err)
+	}
+
+	t.Run("membership", func(t *testing.T) {
+		if _, err := orgs.AddMember(ctx, "bob-id", org.ID, "cat", ""); !errors.Is(err, apperror.ErrNotFound) {
+			t.Errorf("AddMember(by outsider) error = %v, want not found", err)
+		}
+		bob, err := orgs.AddMember(ctx, "ann-id", org.ID, "bob", "")
+		if err != nil {
+			t.Fatalf("AddMember() error = %v", err)
+		}
+		if bob.Login != "bob" || bob.Role != model.OrgRoleMember {
+			t.Errorf("AddMember() = %+v, want bob as a member", bob)
+		}
+		if _, err := orgs.AddMember(ctx, "ann-id", org.ID, "bob", ""); !errors.Is(err, apperror.ErrConflict) {
+			t.Errorf("AddMember(again) error = %v, want conflict", err)
+		}
+		if _, err := orgs.AddMember(ctx, "bob-id", org.ID, "cat", ""); !errors.Is(err, apperror.ErrForbidden) {
+			t.Errorf("AddMember(by member) error = %v, want forbidden", err)
+		}
+		members, err := orgs.Members(ctx, "bob-id", org.ID)
+		if err != nil || len(members) != 2 || members[0].Login != "ann" {
+			t.Errorf("Members() = %+v, %v; want ann then bob", members, err)
+		}
+		if _, err := orgs.Get(ctx, "cat-id", org.ID); !errors.Is(err, apperror.ErrNotFound) {
+			t.Errorf("Get(by outsider) error = %v, want not found", err)
+		}
+	})
+
+	t.Run("last owner stays", func(t *testing.T) {
+		if err := orgs.RemoveMember(ctx, "ann-id", org.ID, "ann"); !errors.Is(err, apperror.ErrConflict) {
+			t.Errorf("RemoveMember(last owner) error = %v, want conflict", err)
+		}
+		if _, err := orgs.SetRole(ctx, "ann-id", org.ID, "ann", model.OrgRoleMember); !errors.Is(err, apperror.ErrConflict) {
+			t.Errorf("SetRole(last owner) error = %v, want conflict", err)
+		}
+		if _, err := orgs.SetRole(ctx, "ann-id", org.ID, "bob", "admin"); !errors.Is(err, apperror.ErrValidation) {
+			t.Errorf("SetRole(admin) error = %v, want validation error", err)
+		}
+	})
+
+	t.Run("shared snippets", func(t *testing.T) {
+		if _, err := snippets.CreateInOrg(asCat, org.ID, "x", "", ""); !errors.Is(err, apperror.ErrNotFound) {
+			t.Errorf("CreateInOrg(by outsider) error = %v, want not found", err)
+		}
+		shared, err := snippets.CreateInOrg(asAnn, org.ID, "shared", "print(1)", "")
+		if err != nil {
+			t.Fatalf("CreateInOrg() error = %v", err)
+		}
+		if shared.OrgID != org.ID || shared.UserID != "ann-id" {
+			t.Errorf("CreateInOrg() = %+v", shared)
+		}
+
+		// Any member edits and publishes it; outsiders and anonymous users can't.
+		if _, err := snippets.Update(asBob, shared.ID, "", "print(2)", ""); err != nil {
+			t.Errorf("Update(by member) error = %v", err)
+		}
+		if _, err := snippets.SetPublic(asBob, shared.ID, true); err != nil {
+			t.Errorf("SetPublic(by member) error = %v", err)
+		}
+		for name, c := range map[string]context.Context{"outsider": asCat, "anonymous": ctx} {
+			if _, err := snippets.Update(c, shared.ID, "", "print(3)", ""); !errors.Is(err, apperror.ErrForbidden) {
+				t.Errorf("Update(by %s) error = %v, want forbidden", name, err)
+			}
+		}
+
+		page, err := snippets.List(ctx, 0, 0, ListFilter{OrgID: org.ID})
+		if err != nil || page.Total != 1 || page.Items[0].ID != shared.ID {
+			t.Errorf("List(org) = %+v, %v; want the shared snippet", page, err)
+		}
+
+		// Deleting takes an owner or the creator.
+		bobs, err := snippets.CreateInOrg(asBob, org.ID, "bob's", "", "")
+		if err != nil {
+			t.Fatalf("CreateInOrg() error = %v", err)
+		}
+		if err := snippets.Delete(asBob, shared.ID); !errors.Is(err, apperror.ErrForbidden) {
+			t.Errorf("Delete(by member) error = %v, want forbidden", err)
+		}
+		if err := snippets.Delete(asBob, bobs.ID); err != nil {
+			t.Errorf("Delete(by creator) error = %v", err)
+		}
+	})
+
+	t.Run("leave and delete", func(t *testing.T) {
+		if err := orgs.RemoveMember(ctx, "bob-id", org.ID, "ann"); !errors.Is(err, apperror.ErrForbidden) {
+			t.Errorf("RemoveMember(other, by member) error = %v, want forbidden", err)
+		}
+		if err := orgs.RemoveMember(ctx, "bob-id", org.ID, "bob"); err != nil {
+			t.Errorf("RemoveMember(self) error = %v", err)
+		}
+		if err := orgs.Delete(ctx, "ann-id", org.ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		mine, err := snippets.List(ctx, 0, 0, ListFilter{OwnerID: "ann-id"})
+		if err != nil || mine.Total != 1 || mine.Items[0].OrgID != "" {
+			t.Errorf("ann's snippets after Delete() = %+v, %v; want the shared one, now personal", mine, err)
+		}
+	})
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/mailer"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// ORG INVITES:
+// Adding a member by login needs them to have signed in before. An invite
+// works for anyone: an owner creates one, and gets back a link to share —
+// or has it emailed:
+//
+//	POST /api/v1/orgs/{id}/invites   {"role": "member", "email": "bob@example.com"}
+//	   → {"invite": {...}, "url": "https://…/api/v1/invites/<token>"}
+//	GET  /api/v1/invites/<token>          → which org, which role, until when
+//	POST /api/v1/invites/<token>/accept   → the signed-in user joins
+//
+// The token is 32 random bytes and, as with sign-in links, only its SHA-256
+// is stored: the link is shown once, when the invite is made. Whoever holds
+// it can use it, once, before it expires (OrgInviteTTL unless the owner
+// picks another number of days). Owners see the pending invites and can
+// revoke any of them.
+
+const (
+	// OrgInviteTTL is how long an invite works by default.
+	OrgInviteTTL = 7 * 24 * time.Hour
+	// MaxOrgInviteDays caps how long an owner can make an invite last.
+	MaxOrgInviteDays = 30
+)
+
+// OrgInviteInput describes an invite to create. Role "" is a plain member,
+// Email "" means no email is sent, and Days 0 is OrgInviteTTL.
+type OrgInviteInput struct {
+	Role  string
+	Email string
+	Days  int
+}
+
+// CreatedOrgInvite is a new invite and the one-time view of its link.
+type CreatedOrgInvite struct {
+	Invite *model.OrgInvite `json:"invite"`
+	URL    string           `json:"url"`
+}
+
+// OrgInviteService creates, revokes and accepts invitations to orgs.
+type OrgInviteService struct {
+	repo    repository.OrgInviteRepository
+	orgs    *OrgService
+	users   repository.UserRepository
+	emails  *EmailService // nil when email is off
+	baseURL string        // where invite links point, without a trailing slash
+	logger  *slog.Logger
+}
+
+// NewOrgInviteService creates an OrgInviteService. emails may be nil, in
+// which case invites can only be shared as links.
+func NewOrgInviteService(repo repository.OrgInviteRepository, orgs *OrgService, users repository.UserRepository, emails *EmailService, baseURL string, logger *slog.Logger) *OrgInviteService {
+	return &OrgInviteService{
+		repo:    repo,
+		orgs:    orgs,
+		users:   users,
+		emails:  emails,
+		baseURL: baseURL,
+		logger:  logger,
+	}
+}
+
+// Create makes an invite to an org, emailing it if in.Email is set.
+// Owners only.
+func (s *OrgInviteService) Create(ctx context.Context, userID, orgID string, in OrgInviteInput) (*CreatedOrgInvite, error) {
+	in.Email = strings.TrimSpace(in.Email)
+	if in.Role == "" {
+		in.Role = model.OrgRoleMember
+	}
+	var verrs apperror.ValidationErrors
+	if err := validateOrgRole(in.Role); err != nil {
+		verrs.Add("role", "role must be owner, member or viewer")
+	}
+	if in.Email != "" {
+		if parsed, err := mail.ParseAddress(in.Email); err != nil || parsed.Address != in.Email {
+			verrs.Add("email", "email must be an email address")
+		} else if s.emails == nil {
+			verrs.Add("email", "email isn't set up on this server; share the link instead")
+		}
+	}
+	if in.Days < 0 || in.Days > MaxOrgInviteDays {
+		verrs.Add("days", fmt.Sprintf("days must be between 1 and %d", MaxOrgInviteDays))
+	}
+	if err := verrs.Err(); err != nil {
+		return nil, err
+	}
+	org, err := s.orgs.owner(ctx, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := OrgInviteTTL
+	if in.Days > 0 {
+		ttl = time.Duration(in.Days) * 24 * time.Hour
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("generating invite token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+	invite := &model.OrgInvite{
+		OrgID:     org.ID,
+		OrgName:   org.Name,
+		Role:      in.Role,
+		Email:     in.Email,
+		CreatedBy: userID,
+		ExpiresAt: time.Now().Add(ttl).UTC().Truncate(time.Second),
+	}
+	if err := s.repo.CreateOrgInvite(ctx, invite, hashToken(token)); err != nil {
+		return nil, err
+	}
+	created := &CreatedOrgInvite{Invite: invite, URL: s.baseURL + "/api/v1/invites/" + token}
+
+	if in.Email != "" {
+		inviter := "Someone"
+		if user, err := s.users.GetUserByID(ctx, userID); err == nil && user != nil {
+			inviter = user.Login
+		}
+		err := s.emails.Queue(ctx, mailer.TemplateOrgInvite, in.Email, mailer.OrgInviteData{
+			Inviter: inviter,
+			Org:     org.Name,
+			Role:    in.Role,
+			URL:     created.URL,
+			Days:    int(ttl / (24 * time.Hour)),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.logger.InfoContext(ctx, "org invite created",
+		slog.String("id", invite.ID),
+		slog.String("org_id", org.ID),
+		slog.String("role", invite.Role),
+		slog.Bool("emailed", in.Email != ""),
+	)
+	return created, nil
+}
+
+// Pending lists an org's invites that are neither used nor expired.
+// Owners only.
+func (s *OrgInviteService) Pending(ctx context.Context, userID, orgID string) ([]model.OrgInvite, error) {
+	if _, err := s.orgs.owner(ctx, userID, orgID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListOrgInvites(ctx, orgID)
+}
+
+// Revoke deletes a pending invite, so its link stops working. Owners only.
+func (s *OrgInviteService) Revoke(ctx context.Context, userID, orgID, inviteID string) error {
+	if _, err := s.orgs.owner(ctx, userID, orgID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteOrgInvite(ctx, orgID, inviteID); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "org invite revoked",
+		slog.String("id", inviteID),
+		slog.String("org_id", orgID),
+	)
+	return nil
+}
+
+// Get returns the pending invite for a token, for the page a link opens.
+// Who it was emailed to isn't shown.
+func (s *OrgInviteService) Get(ctx context.Context, token string) (*model.OrgInvite, error) {
+	if token == "" {
+		return nil, apperror.NotFound("invite", "")
+	}
+	invite, err := s.repo.GetOrgInviteByToken(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	invite.Email = ""
+	return invite, nil
+}
+
+// Accept uses up an invite's token and adds userID to its org.
+func (s *OrgInviteService) Accept(ctx context.Context, userID, token string) (*model.OrgMembership, error) {
+	if token == "" {
+		return nil, apperror.NotFound("invite", "")
+	}
+	member, err := s.repo.AcceptOrgInvite(ctx, hashToken(token), userID)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "org invite accepted",
+		slog.String("org_id", member.OrgID),
+		slog.String("user_id", userID),
+		slog.String("role", member.Role),
+	)
+	return s.orgs.Get(ctx, userID, member.OrgID)
+}
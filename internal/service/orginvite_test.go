@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository/sqlite"
+)
+
+func TestOrgInviteService(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	orgs := NewOrgService(db, db, logger)
+	invites := NewOrgInviteService(db, orgs, db, nil, "https://play.example", logger)
+
+	ctx := context.Background()
+	for i, login := range []string{"ann", "bob", "cat"} {
+		if err := db.Upsert(ctx, &model.User{ID: login + "-id", GitHubID: int64(i + 1), Login: login}); err != nil {
+			t.Fatalf("creating %s: %v", login, err)
+		}
+	}
+	org, err := orgs.Create(ctx, "ann-id", "Data team")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	// token is the last part of an invite link.
+	token := func(c *CreatedOrgInvite) string {
+		return c.URL[strings.LastIndex(c.URL, "/")+1:]
+	}
+
+	if _, err := invites.Create(ctx, "ann-id", org.ID, OrgInviteInput{Email: "bob@example.com"}); !errors.Is(err, apperror.ErrValidation) {
+		t.Errorf("Create(email, no email set up) error = %v, want validation error", err)
+	}
+	if _, err := invites.Create(ctx, "ann-id", org.ID, OrgInviteInput{Role: "admin", Days: 99}); !errors.Is(err, apperror.ErrValidation) {
+		t.Errorf("Create(bad role and days) error = %v, want validation error", err)
+	}
+	if _, err := invites.Create(ctx, "bob-id", org.ID, OrgInviteInput{}); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("Create(by outsider) error = %v, want not found", err)
+	}
+
+	created, err := invites.Create(ctx, "ann-id", org.ID, OrgInviteInput{Role: model.OrgRoleOwner})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !strings.HasPrefix(created.URL, "https://play.example/api/v1/invites/") || created.Invite.Role != model.OrgRoleOwner {
+		t.Errorf("Create() = %+v", created)
+	}
+	if got, err := invites.Get(ctx, token(created)); err != nil || got.OrgName != "Data team" {
+		t.Errorf("Get() = %+v, %v; want the Data team invite", got, err)
+	}
+	if pending, err := invites.Pending(ctx, "ann-id", org.ID); err != nil || len(pending) != 1 {
+		t.Errorf("Pending() = %+v, %v; want one", pending, err)
+	}
+
+	// ann is already a member: the invite stays usable.
+	if _, err := invites.Accept(ctx, "ann-id", token(created)); !errors.Is(err, apperror.ErrConflict) {
+		t.Errorf("Accept(by member) error = %v, want conflict", err)
+	}
+	joined, err := invites.Accept(ctx, "bob-id", token(created))
+	if err != nil {
+		t.Fatalf("Accept() error = %v", err)
+	}
+	if joined.ID != org.ID || joined.Role != model.OrgRoleOwner {
+		t.Errorf("Accept() = %+v, want owner of %s", joined, org.ID)
+	}
+	if _, err := invites.Accept(ctx, "cat-id", token(created)); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("Accept(used) error = %v, want not found", err)
+	}
+
+	revoked, err := invites.Create(ctx, "bob-id", org.ID, OrgInviteInput{Days: 1})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := invites.Revoke(ctx, "ann-id", org.ID, revoked.Invite.ID); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if _, err := invites.Accept(ctx, "cat-id", token(revoked)); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("Accept(revoked) error = %v, want not found", err)
+	}
+	if pending, err := invites.Pending(ctx, "ann-id", org.ID); err != nil || len(pending) != 0 {
+		t.Errorf("Pending() = %+v, %v; want none", pending, err)
+	}
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// ORG QUOTAS AND ROLES:
+// What a member may do with an org's snippets depends on their role:
+//
+//	viewer → read and run them (live runs)
+//	member → also create, edit and publish them
+//	owner  → also delete them and manage the org
+//
+// Orgs share limits, rather than each member having their own: how many
+// snippets the org may keep, and how many live runs of them it may start
+// per UTC day. The deployment sets the defaults (see LimitOrgs); an admin
+// can raise or lower them for one org:
+//
+//	PUT /api/v1/admin/orgs/{id}/quota {"snippets": 1000, "runsPerDay": null}
+//
+// null goes back to the default, and a limit of 0 means there isn't one.
+// Members see where they stand at GET /api/v1/orgs/{id}/usage. Going over
+// a limit is ErrForbidden, with a message saying which one.
+
+// OrgLimits are the quotas every org gets unless an admin overrides them.
+// 0 means no limit.
+type OrgLimits struct {
+	Snippets   int
+	RunsPerDay int
+}
+
+// apply returns the limits in force for org.
+func (l OrgLimits) apply(org *model.Org) OrgLimits {
+	if org.Quota.Snippets != nil {
+		l.Snippets = *org.Quota.Snippets
+	}
+	if org.Quota.RunsPerDay != nil {
+		l.RunsPerDay = *org.Quota.RunsPerDay
+	}
+	return l
+}
+
+// LimitOrgs sets the default org quotas; without it orgs have none. Call it
+// before serving requests.
+func (s *SnippetService) LimitOrgs(limits OrgLimits) {
+	s.orgLimits = limits
+}
+
+// CanRun reports whether userID may run snippet live: anyone who may manage
+// it, and an org's viewers too.
+func (s *SnippetService) CanRun(ctx context.Context, userID string, snippet *model.Snippet) (bool, error) {
+	if snippet.OrgID == "" || userID == "" {
+		return s.CanManage(ctx, userID, snippet)
+	}
+	_, err := s.orgMember(ctx, snippet.OrgID, userID)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// OrgUsage returns how much of its quota an org has used. Any member may ask.
+func (s *SnippetService) OrgUsage(ctx context.Context, userID, orgID string) (*model.OrgUsage, error) {
+	if _, err := s.orgMember(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+	org, err := s.orgs.GetOrg(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return s.usage(ctx, org)
+}
+
+// SetOrgQuota overrides an org's quotas, for admins; nil fields go back to
+// the defaults. It returns the org's usage under its new limits.
+func (s *SnippetService) SetOrgQuota(ctx context.Context, orgID string, quota model.OrgQuota) (*model.OrgUsage, error) {
+	var verrs apperror.ValidationErrors
+	if quota.Snippets != nil && *quota.Snippets < 0 {
+		verrs.Add("snippets", "snippets must be 0 (no limit) or more")
+	}
+	if quota.RunsPerDay != nil && *quota.RunsPerDay < 0 {
+		verrs.Add("runsPerDay", "runsPerDay must be 0 (no limit) or more")
+	}
+	if err := verrs.Err(); err != nil {
+		return nil, err
+	}
+	if s.orgs == nil {
+		return nil, apperror.NotFound("org", orgID)
+	}
+	if err := s.orgs.SetOrgQuota(ctx, orgID, quota); err != nil {
+		return nil, err
+	}
+	org, err := s.orgs.GetOrg(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "org quota changed", slog.String("org_id", orgID))
+	return s.usage(ctx, org)
+}
+
+// usage counts an org's snippets and today's runs against its limits.
+func (s *SnippetService) usage(ctx context.Context, org *model.Org) (*model.OrgUsage, error) {
+	limits := s.orgLimits.apply(org)
+	snippets, err := s.repo.Count(ctx, repository.ListOptions{OrgID: org.ID})
+	if err != nil {
+		return nil, fmt.Errorf("counting org snippets: %w", err)
+	}
+	runs, err := s.orgs.CountOrgRuns(ctx, org.ID, orgRunDay(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	return &model.OrgUsage{
+		OrgID:        org.ID,
+		Snippets:     snippets,
+		SnippetLimit: limits.Snippets,
+		RunsToday:    runs,
+		RunLimit:     limits.RunsPerDay,
+	}, nil
+}
+
+// checkSnippetQuota refuses a new snippet in an org that has as many as
+// it may keep. Two members racing for the last slot can both get it; the
+// quota is a budget, not a hard guarantee.
+func (s *SnippetService) checkSnippetQuota(ctx context.Context, orgID string) error {
+	org, err := s.orgs.GetOrg(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	limit := s.orgLimits.apply(org).Snippets
+	if limit == 0 {
+		return nil
+	}
+	n, err := s.repo.Count(ctx, repository.ListOptions{OrgID: orgID})
+	if err != nil {
+		return fmt.Errorf("counting org snippets: %w", err)
+	}
+	if n >= limit {
+		return &apperror.AppError{
+			Err:     apperror.ErrForbidden,
+			Message: fmt.Sprintf("the org has reached its quota of %d snippets", limit),
+		}
+	}
+	return nil
+}
+
+// chargeRun counts a live run of snippet against its org's daily quota,
+// refusing it if the quota is used up. Personal snippets aren't limited.
+func (s *SnippetService) chargeRun(ctx context.Context, snippet *model.Snippet) error {
+	if snippet.OrgID == "" || s.orgs == nil {
+		return nil
+	}
+	org, err := s.orgs.GetOrg(ctx, snippet.OrgID)
+	if err != nil {
+		return err
+	}
+	limit := s.orgLimits.apply(org).RunsPerDay
+	ok, err := s.orgs.AddOrgRun(ctx, org.ID, orgRunDay(time.Now()), limit)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &apperror.AppError{
+			Err:     apperror.ErrForbidden,
+			Message: fmt.Sprintf("the org has used its %d runs for today", limit),
+		}
+	}
+	return nil
+}
+
+// orgMember returns userID's membership of an org, or ErrNotFound for the
+// org when they have none.
+func (s *SnippetService) orgMember(ctx context.Context, orgID, userID string) (*model.OrgMember, error) {
+	if s.orgs == nil || userID == "" {
+		return nil, apperror.NotFound("org", orgID)
+	}
+	member, err := s.orgs.GetOrgMember(ctx, orgID, userID)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, apperror.NotFound("org", orgID)
+	}
+	return member, err
+}
+
+// orgRunDay is the day a run at t counts towards.
+func orgRunDay(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
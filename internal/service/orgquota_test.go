@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository/sqlite"
+)
+
+func TestOrgQuotasAndRoles(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	orgs := NewOrgService(db, db, logger)
+	snippets := NewSnippetService(db, logger)
+	snippets.EnableOrgs(db)
+	snippets.LimitOrgs(OrgLimits{Snippets: 2, RunsPerDay: 3})
+
+	ctx := context.Background()
+	for i, login := range []string{"ann", "vic", "eve"} {
+		if err := db.Upsert(ctx, &model.User{ID: login + "-id", GitHubID: int64(i + 1), Login: login}); err != nil {
+			t.Fatalf("creating %s: %v", login, err)
+		}
+	}
+	asAnn := auth.WithUserID(ctx, "ann-id")
+	asVic := auth.WithUserID(ctx, "vic-id")
+
+	org, err := orgs.Create(ctx, "ann-id", "Data team")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := orgs.AddMember(ctx, "ann-id", org.ID, "vic", model.OrgRoleViewer); err != nil {
+		t.Fatalf("AddMember(viewer) error = %v", err)
+	}
+	shared, err := snippets.CreateInOrg(asAnn, org.ID, "shared", "print(1)", "")
+	if err != nil {
+		t.Fatalf("CreateInOrg() error = %v", err)
+	}
+
+	t.Run("viewers run but don't edit", func(t *testing.T) {
+		if _, err := snippets.CreateInOrg(asVic, org.ID, "mine", "", ""); !errors.Is(err, apperror.ErrForbidden) {
+			t.Errorf("CreateInOrg(viewer) error = %v, want forbidden", err)
+		}
+		if _, err := snippets.Update(asVic, shared.ID, "", "print(2)", ""); !errors.Is(err, apperror.ErrForbidden) {
+			t.Errorf("Update(viewer) error = %v, want forbidden", err)
+		}
+		if _, err := snippets.SetPublic(asVic, shared.ID, true); !errors.Is(err, apperror.ErrForbidden) {
+			t.Errorf("SetPublic(viewer) error = %v, want forbidden", err)
+		}
+		for userID, want := range map[string]bool{"ann-id": true, "vic-id": true, "eve-id": false, "": false} {
+			if ok, err := snippets.CanRun(ctx, userID, shared); err != nil || ok != want {
+				t.Errorf("CanRun(%q) = %v, %v; want %v", userID, ok, err, want)
+			}
+		}
+		if ok, _ := snippets.CanManage(ctx, "vic-id", shared); ok {
+			t.Error("CanManage(viewer) = true, want false")
+		}
+	})
+
+	t.Run("snippet quota", func(t *testing.T) {
+		if _, err := snippets.CreateInOrg(asAnn, org.ID, "second", "", ""); err != nil {
+			t.Fatalf("CreateInOrg(under quota) error = %v", err)
+		}
+		if _, err := snippets.CreateInOrg(asAnn, org.ID, "third", "", ""); !errors.Is(err, apperror.ErrForbidden) {
+			t.Errorf("CreateInOrg(over quota) error = %v, want forbidden", err)
+		}
+		// Personal snippets don't count.
+		if _, err := snippets.Create(asAnn, "personal", "", ""); err != nil {
+			t.Errorf("Create(personal) error = %v", err)
+		}
+	})
+
+	t.Run("run quota", func(t *testing.T) {
+		for i := range 3 {
+			if err := snippets.chargeRun(ctx, shared); err != nil {
+				t.Fatalf("chargeRun() #%d error = %v", i+1, err)
+			}
+		}
+		if err := snippets.chargeRun(ctx, shared); !errors.Is(err, apperror.ErrForbidden) {
+			t.Errorf("chargeRun(over quota) error = %v, want forbidden", err)
+		}
+		usage, err := snippets.OrgUsage(ctx, "vic-id", org.ID)
+		if err != nil {
+			t.Fatalf("OrgUsage() error = %v", err)
+		}
+		want := model.OrgUsage{OrgID: org.ID, Snippets: 2, SnippetLimit: 2, RunsToday: 3, RunLimit: 3}
+		if *usage != want {
+			t.Errorf("OrgUsage() = %+v, want %+v", *usage, want)
+		}
+		if _, err := snippets.OrgUsage(ctx, "eve-id", org.ID); !errors.Is(err, apperror.ErrNotFound) {
+			t.Errorf("OrgUsage(outsider) error = %v, want not found", err)
+		}
+	})
+
+	t.Run("admin override", func(t *testing.T) {
+		unlimited, more := 0, 5
+		usage, err := snippets.SetOrgQuota(ctx, org.ID, model.OrgQuota{Snippets: &more, RunsPerDay: &unlimited})
+		if err != nil {
+			t.Fatalf("SetOrgQuota() error = %v", err)
+		}
+		if usage.SnippetLimit != 5 || usage.RunLimit != 0 {
+			t.Errorf("SetOrgQuota() = %+v, want limits 5 and none", usage)
+		}
+		if err := snippets.chargeRun(ctx, shared); err != nil {
+			t.Errorf("chargeRun(no limit) error = %v", err)
+		}
+		if _, err := snippets.CreateInOrg(asAnn, org.ID, "third", "", ""); err != nil {
+			t.Errorf("CreateInOrg(raised quota) error = %v", err)
+		}
+
+		// Back to the defaults.
+		usage, err = snippets.SetOrgQuota(ctx, org.ID, model.OrgQuota{})
+		if err != nil || usage.SnippetLimit != 2 || usage.RunLimit != 3 {
+			t.Errorf("SetOrgQuota(defaults) = %+v, %v; want limits 2 and 3", usage, err)
+		}
+		negative := -1
+		if _, err := snippets.SetOrgQuota(ctx, org.ID, model.OrgQuota{Snippets: &negative}); !errors.Is(err, apperror.ErrValidation) {
+			t.Errorf("SetOrgQuota(-1) error = %v, want validation error", err)
+		}
+		if _, err := snippets.SetOrgQuota(ctx, "nope", model.OrgQuota{}); !errors.Is(err, apperror.ErrNotFound) {
+			t.Errorf("SetOrgQuota(unknown org) error = %v, want not found", err)
+		}
+	})
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/sakif/coding-playground/internal/ws"
+)
+
+// PRESENCE:
+// Signed-in users who have a snippet open announce themselves on its
+// "snippet:<id>" topic, so collaborators and teachers can see who's there
+// and where their cursor is:
+//
+//	→ {"type":"presence.join","topic":"snippet:<id>","id":"1"}
+//	← {"type":"presence.joined","topic":"snippet:…","id":"1","data":{"viewers":[…]}}
+//	→ {"type":"presence.cursor","topic":"snippet:…","data":{"line":3,"column":8}}
+//	← {"type":"event","topic":"snippet:…","data":{"kind":"cursor","viewer":{…}}}
+//
+// Everyone on the topic gets "join", "leave" and "cursor" events; join and
+// leave carry the full list of viewers. Anyone who can load the snippet may
+// subscribe and watch, but only signed-in users appear in the list. A
+// viewer leaves with "presence.leave" or by disconnecting. Presence lives
+// in memory only: it's about who's here now.
+
+const (
+	// PresenceTopicPrefix starts a snippet's presence topic,
+	// "snippet:<snippet id>".
+	PresenceTopicPrefix = "snippet:"
+
+	// Client message types, and the reply to joining.
+	MsgPresenceJoin   = "presence.join"
+	MsgPresenceJoined = "presence.joined"
+	MsgPresenceCursor = "presence.cursor"
+	MsgPresenceLeave  = "presence.leave"
+)
+
+// PresenceCursor is a viewer's caret position, 1-based. A selection also
+// sets where it ends.
+type PresenceCursor struct {
+	Line      int `json:"line"`
+	Column    int `json:"column"`
+	EndLine   int `json:"endLine,omitempty"`
+	EndColumn int `json:"endColumn,omitempty"`
+}
+
+func (c PresenceCursor) validate() error {
+	if c.Line < 1 || c.Column < 1 {
+		return errors.New("line and column start at 1")
+	}
+	if c.EndLine != 0 && (c.EndLine < c.Line || c.EndColumn < 1 || (c.EndLine == c.Line && c.EndColumn < c.Column)) {
+		return errors.New("a selection can't end before it starts")
+	}
+	return nil
+}
+
+// PresenceViewer is one connection with the snippet open. A user with two
+// tabs open appears twice, with different client IDs.
+type PresenceViewer struct {
+	ClientID  string          `json:"clientId"`
+	UserID    string          `json:"userId"`
+	Login     string          `json:"login"`
+	AvatarURL string          `json:"avatarUrl,omitempty"`
+	Cursor    *PresenceCursor `json:"cursor,omitempty"`
+}
+
+// presenceEvent is published on the topic when someone joins, leaves or
+// moves their cursor.
+type presenceEvent struct {
+	Kind    string           `json:"kind"` // "join", "leave" or "cursor"
+	Viewer  PresenceViewer   `json:"viewer"`
+	Viewers []PresenceViewer `json:"viewers,omitempty"` // join and leave only
+}
+
+// PresenceService tracks who has which snippet open.
+type PresenceService struct {
+	snippets *SnippetService
+	users    *UserService
+	hub      *ws.Hub
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	viewers map[string]map[*ws.Client]*PresenceViewer // by snippet ID
+}
+
+// NewPresenceService registers the "snippet:" topics and presence message
+// types on hub.
+func NewPresenceService(snippets *SnippetService, users *UserService, hub *ws.Hub, logger *slog.Logger) *PresenceService {
+	s := &PresenceService{
+		snippets: snippets,
+		users:    users,
+		hub:      hub,
+		logger:   logger,
+		viewers:  make(map[string]map[*ws.Client]*PresenceViewer),
+	}
+	hub.Authorize(PresenceTopicPrefix, func(ctx context.Context, _, topic string) error {
+		_, err := snippets.GetByID(ctx, strings.TrimPrefix(topic, PresenceTopicPrefix))
+		return err
+	})
+	hub.HandleMessage(MsgPresenceJoin, s.handleJoin)
+	hub.HandleMessage(MsgPresenceCursor, s.handleCursor)
+	hub.HandleMessage(MsgPresenceLeave, s.handleLeave)
+	hub.OnDisconnect(s.leaveAll)
+	return s
+}
+
+func (s *PresenceService) handleJoin(ctx context.Context, c *ws.Client, msg ws.Message) {
+	if c.UserID() == "" {
+		presenceError(c, msg, "sign in to appear as a viewer")
+		return
+	}
+	snippetID, ok := strings.CutPrefix(msg.Topic, PresenceTopicPrefix)
+	if !ok || snippetID == "" {
+		presenceError(c, msg, "topic must be snippet:<snippet id>")
+		return
+	}
+	if err := c.Subscribe(ctx, msg.Topic); err != nil {
+		presenceError(c, msg, err.Error())
+		return
+	}
+	user, err := s.users.GetByID(ctx, c.UserID())
+	if err != nil {
+		presenceError(c, msg, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.viewers[snippetID] == nil {
+		s.viewers[snippetID] = make(map[*ws.Client]*PresenceViewer)
+	}
+	_, rejoining := s.viewers[snippetID][c]
+	viewer := &PresenceViewer{ClientID: c.ID(), UserID: user.ID, Login: user.Login, AvatarURL: user.AvatarURL}
+	if !rejoining {
+		s.viewers[snippetID][c] = viewer
+	}
+	viewers := s.list(snippetID)
+
+	data, _ := json.Marshal(map[string]any{"viewers": viewers})
+	c.Send(ws.Message{Type: MsgPresenceJoined, Topic: msg.Topic, ID: msg.ID, Data: data})
+	if !rejoining {
+		s.publish(snippetID, presenceEvent{Kind: "join", Viewer: *viewer, Viewers: viewers})
+	}
+}
+
+func (s *PresenceService) handleCursor(_ context.Context, c *ws.Client, msg ws.Message) {
+	var cursor PresenceCursor
+	if err := json.Unmarshal(msg.Data, &cursor); err != nil {
+		presenceError(c, msg, "invalid cursor: "+err.Error())
+		return
+	}
+	if err := cursor.validate(); err != nil {
+		presenceError(c, msg, err.Error())
+		return
+	}
+
+	snippetID := strings.TrimPrefix(msg.Topic, PresenceTopicPrefix)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	viewer := s.viewers[snippetID][c]
+	if viewer == nil {
+		presenceError(c, msg, "join first")
+		return
+	}
+	viewer.Cursor = &cursor
+	s.publish(snippetID, presenceEvent{Kind: "cursor", Viewer: *viewer})
+}
+
+func (s *PresenceService) handleLeave(_ context.Context, c *ws.Client, msg ws.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.leave(strings.TrimPrefix(msg.Topic, PresenceTopicPrefix), c)
+	c.Send(ws.Message{Type: ws.TypeUnsubscribed, Topic: msg.Topic, ID: msg.ID})
+}
+
+// leaveAll drops a disconnected client from every snippet it had open.
+func (s *PresenceService) leaveAll(c *ws.Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for snippetID := range s.viewers {
+		s.leave(snippetID, c)
+	}
+}
+
+// leave drops c from a snippet's viewers. Callers hold s.mu.
+func (s *PresenceService) leave(snippetID string, c *ws.Client) {
+	viewer := s.viewers[snippetID][c]
+	if viewer == nil {
+		return
+	}
+	delete(s.viewers[snippetID], c)
+	if len(s.viewers[snippetID]) == 0 {
+		delete(s.viewers, snippetID)
+	}
+	s.publish(snippetID, presenceEvent{Kind: "leave", Viewer: *viewer, Viewers: s.list(snippetID)})
+}
+
+// list copies a snippet's viewers. Callers hold s.mu.
+func (s *PresenceService) list(snippetID string) []PresenceViewer {
+	viewers := make([]PresenceViewer, 0, len(s.viewers[snippetID]))
+	for _, v := range s.viewers[snippetID] {
+		viewers = append(viewers, *v)
+	}
+	// Client IDs count up, so the shorter one connected first.
+	slices.SortFunc(viewers, func(a, b PresenceViewer) int {
+		if n := len(a.ClientID) - len(b.ClientID); n != 0 {
+			return n
+		}
+		return strings.Compare(a.ClientID, b.ClientID)
+	})
+	return viewers
+}
+
+// publish sends an event to the snippet's topic. Callers hold s.mu, so
+// events go out in order.
+func (s *PresenceService) publish(snippetID string, event presenceEvent) {
+	if err := s.hub.Publish(PresenceTopicPrefix+snippetID, event); err != nil {
+		s.logger.Error("failed to publish presence event",
+			slog.String("snippet_id", snippetID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+func presenceError(c *ws.Client, msg ws.Message, text string) {
+	c.Send(ws.Message{Type: ws.TypeError, Topic: msg.Topic, ID: msg.ID, Error: text})
+}
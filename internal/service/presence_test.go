@@ -0,0 +1,24 @@
+package service
+
+import "testing"
+
+func TestPresenceCursor_Validate(t *testing.T) {
+	tests := []struct {
+		cursor PresenceCursor
+		valid  bool
+	}{
+		{PresenceCursor{Line: 1, Column: 1}, true},
+		{PresenceCursor{Line: 3, Column: 5, EndLine: 4, EndColumn: 1}, true},
+		{PresenceCursor{Line: 3, Column: 5, EndLine: 3, EndColumn: 5}, true},
+		{PresenceCursor{Line: 0, Column: 1}, false},
+		{PresenceCursor{Line: 1, Column: 0}, false},
+		{PresenceCursor{Line: 3, Column: 5, EndLine: 2, EndColumn: 9}, false},
+		{PresenceCursor{Line: 3, Column: 5, EndLine: 3, EndColumn: 4}, false},
+		{PresenceCursor{Line: 3, Column: 5, EndLine: 4}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.cursor.validate(); (err == nil) != tt.valid {
+			t.Errorf("%+v.validate() = %v, want valid %v", tt.cursor, err, tt.valid)
+		}
+	}
+}
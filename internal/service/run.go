@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/executor"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// RUN REPLAY:
+// "It worked yesterday." To check, a signed-in user can run yesterday's run
+// again, exactly as it was:
+//
+//	POST /api/v1/execute               → {"stdout":"…","runId":"cn0a…"}
+//	POST /api/v1/runs/cn0a…/replay     → {"stdout":"…","runId":"cn2f…","replayOf":"cn0a…"}
+//
+// Every signed-in user's run is recorded with its code, the packages it
+// installed (its requirements manifest) and whether it had a GPU, and a
+// replay runs that code again, with the same packages on the same kind of
+// sandbox, and is recorded in turn, linked to the run it replayed. Runs
+// take no stdin, so a different result points at the sandbox image or the
+// packages' wheels, not the run. A replay is vetted like a new run:
+// requirements no longer on the allowlist are refused, and so is a GPU run
+// by a user who has since lost their GPU access.
+//
+// Only the user who made a run can replay it; anyone else gets a 404, as
+// if it didn't exist. Anonymous runs are recorded without their code (see
+// StatsService) and can't be replayed. Runs go when the run history's
+// retention policy says so (see server.RunRetention).
+
+// RunService records runs for replay and replays them.
+type RunService struct {
+	repo   repository.RunRepository
+	logger *slog.Logger
+}
+
+// NewRunService creates a RunService.
+func NewRunService(repo repository.RunRepository, logger *slog.Logger) *RunService {
+	return &RunService{repo: repo, logger: logger}
+}
+
+// Record saves a signed-in user's finished run, with its code, requirements
+// and GPU profile, and sets result.RunID (and ReplayOf, if it replayed
+// another run). Anonymous runs are left to StatsService. Like publishing an
+// event, it never fails the caller: problems are logged.
+func (s *RunService) Record(ctx context.Context, req executor.ExecutionRequest, result *executor.ExecutionResult, replayOf string) {
+	userID, _ := auth.UserIDFromContext(ctx)
+	if userID == "" {
+		return
+	}
+	run := &model.Run{
+		UserID:       userID,
+		Code:         req.Code,
+		Requirements: req.Requirements,
+		GPU:          req.GPU,
+		ReplayOf:     replayOf,
+		Language:     executor.Language,
+		ExitCode:     result.ExitCode,
+		DurationMS:   result.Duration.Milliseconds(),
+	}
+	if err := s.repo.CreateRun(context.WithoutCancel(ctx), run); err != nil {
+		s.logger.ErrorContext(ctx, "failed to record run",
+			slog.String("user_id", userID),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	result.RunID, result.ReplayOf = run.ID, replayOf
+}
+
+// Replay returns the request that runs the signed-in user's run id again.
+func (s *RunService) Replay(ctx context.Context, id string) (executor.ExecutionRequest, error) {
+	userID, _ := auth.UserIDFromContext(ctx)
+	run, err := s.repo.GetRun(ctx, id)
+	if err != nil {
+		return executor.ExecutionRequest{}, err
+	}
+	if userID == "" || run.UserID != userID {
+		return executor.ExecutionRequest{}, apperror.NotFound("run", id)
+	}
+	if run.Code == "" {
+		return executor.ExecutionRequest{}, apperror.ValidationFailed("id", "this run was recorded without its code and can't be replayed")
+	}
+	return executor.ExecutionRequest{Code: run.Code, Requirements: run.Requirements, GPU: run.GPU}, nil
+}
+
+// RunPage is one page of a user's run history.
+type RunPage struct {
+	Items  []model.Run
+	Total  int
+	Limit  int
+	Offset int
+}
+
+// HasMore reports whether there are runs after this page.
+func (p *RunPage) HasMore() bool {
+	return p.Offset+len(p.Items) < p.Total
+}
+
+// List returns a page of userID's runs, newest first. limit and offset are
+// clamped like SnippetService.List's. It doesn't check who's asking: callers
+// show a user's runs only to that user and to admins.
+func (s *RunService) List(ctx context.Context, userID string, limit, offset int) (*RunPage, error) {
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	limit = min(limit, MaxListLimit)
+	offset = max(offset, 0)
+
+	runs, err := s.repo.ListRuns(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("listing runs: %w", err)
+	}
+	total, err := s.repo.CountRuns(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("counting runs: %w", err)
+	}
+	return &RunPage{Items: runs, Total: total, Limit: limit, Offset: offset}, nil
+}
@@ -0,0 +1,371 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/jobs"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// SIMILARITY DETECTION:
+// A teacher asks for a similarity report on an assignment; a background job
+// compares every student's latest submission with every other's and flags
+// the pairs that share most of their code.
+//
+// Comparing raw text is easy to fool: rename the variables, reflow the
+// lines, add a comment, and nothing matches. So, like MOSS, we compare
+// FINGERPRINTS (Schleimer, Wilkerson & Aiken, "Winnowing", 2003):
+//
+//  1. TOKENIZE: drop comments and whitespace, and replace every name with
+//     "I", number with "N" and string with "S". Keywords and punctuation stay.
+//     total = a + b  and  s = x + y  both become  I = I + I.
+//  2. K-GRAMS: hash every run of k consecutive tokens.
+//  3. WINNOW: slide a window of w hashes along and keep the smallest in each.
+//     Any match of at least k+w-1 tokens is guaranteed to share a kept hash,
+//     while the set stays small.
+//
+// Two submissions' similarity is the Jaccard index of their fingerprint
+// sets (shared / all). The exercise's starter code is everyone's starting
+// point, so its fingerprints are removed first. A high score is a reason to
+// look closer, not proof of copying.
+
+const (
+	// JobSimilarityReport is the job kind that computes an assignment's
+	// similarity report. Its payload is a similarityJob.
+	JobSimilarityReport = "similarity.report"
+
+	// DefaultSimilarityThreshold flags pairs sharing 80% of their fingerprints.
+	DefaultSimilarityThreshold = 0.8
+
+	similarityK = 5 // tokens per k-gram: shorter runs are too common to mean anything
+	similarityW = 4 // winnowing window: matches of k+w-1 = 8 tokens are always caught
+)
+
+// similarityJob is the payload of a JobSimilarityReport job.
+type similarityJob struct {
+	AssignmentID string `json:"assignmentId"`
+}
+
+// DetectSimilarity enables similarity reports, computed by jobs on queue.
+// Call it before queue.Start.
+func (s *ClassService) DetectSimilarity(reports repository.SimilarityRepository, queue *jobs.Queue) {
+	s.reports = reports
+	s.queue = queue
+	queue.Register(JobSimilarityReport, s.similarityReport)
+}
+
+// RequestSimilarity queues a similarity report on an assignment, replacing
+// any earlier one, and returns it pending. threshold 0 means the default.
+// Teachers only.
+func (s *ClassService) RequestSimilarity(ctx context.Context, userID, classID, assignmentID string, threshold float64) (*model.SimilarityReport, error) {
+	if s.reports == nil {
+		return nil, errors.New("similarity reports are unavailable: no job queue")
+	}
+	if _, err := s.teacher(ctx, userID, classID); err != nil {
+		return nil, err
+	}
+	assignment, err := s.assignment(ctx, classID, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	if threshold == 0 {
+		threshold = DefaultSimilarityThreshold
+	}
+	if threshold < 0 || threshold > 1 {
+		return nil, apperror.ValidationFailed("threshold", "threshold must be between 0 and 1")
+	}
+
+	report := &model.SimilarityReport{
+		AssignmentID: assignment.ID,
+		Status:       model.SimilarityPending,
+		Threshold:    threshold,
+		Pairs:        []model.SimilarityPair{},
+		RequestedAt:  time.Now().UTC(),
+	}
+	if err := s.reports.SaveSimilarityReport(ctx, report); err != nil {
+		return nil, fmt.Errorf("saving similarity report: %w", err)
+	}
+	if _, err := s.queue.Enqueue(ctx, JobSimilarityReport, similarityJob{AssignmentID: assignment.ID}); err != nil {
+		return nil, fmt.Errorf("queueing similarity report: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "similarity report requested",
+		slog.String("assignment_id", assignment.ID),
+		slog.Float64("threshold", threshold),
+	)
+	return report, nil
+}
+
+// SimilarityReport returns an assignment's latest similarity report.
+// Teachers only.
+func (s *ClassService) SimilarityReport(ctx context.Context, userID, classID, assignmentID string) (*model.SimilarityReport, error) {
+	if s.reports == nil {
+		return nil, errors.New("similarity reports are unavailable: no job queue")
+	}
+	if _, err := s.teacher(ctx, userID, classID); err != nil {
+		return nil, err
+	}
+	assignment, err := s.assignment(ctx, classID, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	return s.reports.GetSimilarityReport(ctx, assignment.ID)
+}
+
+// similarityReport is the JobSimilarityReport handler. It's safe to run
+// twice: it recomputes the report from scratch.
+func (s *ClassService) similarityReport(ctx context.Context, job *jobs.Job) error {
+	var p similarityJob
+	if err := job.Decode(&p); err != nil {
+		return err
+	}
+	report, err := s.reports.GetSimilarityReport(ctx, p.AssignmentID)
+	if err != nil {
+		return notFoundIsDone(err)
+	}
+	assignment, err := s.classes.GetAssignment(ctx, p.AssignmentID)
+	if err != nil {
+		return notFoundIsDone(err)
+	}
+	exercise, err := s.exercises.GetExercise(ctx, assignment.ExerciseID)
+	if err != nil {
+		return err
+	}
+	members, err := s.classes.ListClassMembers(ctx, assignment.ClassID)
+	if err != nil {
+		return err
+	}
+	submissions, err := s.submissions.ListSubmissions(ctx, repository.SubmissionFilter{
+		AssignmentID: assignment.ID,
+		ClassID:      assignment.ClassID,
+	})
+	if err != nil {
+		return err
+	}
+
+	latest := latestPerUser(submissions)
+	report.Compared = len(latest)
+	report.Pairs = similarPairs(latest, members, exercise.StarterCode, report.Threshold)
+	report.Status = model.SimilarityDone
+	now := time.Now().UTC()
+	report.CompletedAt = &now
+	if err := s.reports.SaveSimilarityReport(ctx, report); err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "similarity report done",
+		slog.String("assignment_id", assignment.ID),
+		slog.Int("compared", report.Compared),
+		slog.Int("flagged", len(report.Pairs)),
+	)
+	return nil
+}
+
+// notFoundIsDone ends a job quietly when what it was for has been deleted
+// since it was queued; other errors are retried.
+func notFoundIsDone(err error) error {
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// latestPerUser keeps each user's newest submission, given submissions
+// newest first (as ListSubmissions returns them).
+func latestPerUser(submissions []model.Submission) []model.Submission {
+	seen := make(map[string]bool)
+	var latest []model.Submission
+	for _, sub := range submissions {
+		if !seen[sub.UserID] {
+			seen[sub.UserID] = true
+			latest = append(latest, sub)
+		}
+	}
+	return latest
+}
+
+// similarPairs compares every pair of submissions and returns those at
+// least threshold similar, most similar first.
+func similarPairs(submissions []model.Submission, members []model.ClassMember, starterCode string, threshold float64) []model.SimilarityPair {
+	logins := make(map[string]string, len(members))
+	for _, m := range members {
+		logins[m.UserID] = m.Login
+	}
+	starter := fingerprint(starterCode)
+	prints := make([]map[uint64]bool, len(submissions))
+	for i, sub := range submissions {
+		prints[i] = fingerprint(sub.Code)
+		for h := range starter {
+			delete(prints[i], h)
+		}
+	}
+	side := func(sub model.Submission) model.SimilaritySubmission {
+		return model.SimilaritySubmission{SubmissionID: sub.ID, UserID: sub.UserID, Login: logins[sub.UserID]}
+	}
+
+	pairs := []model.SimilarityPair{}
+	for i := range submissions {
+		for j := i + 1; j < len(submissions); j++ {
+			sim := jaccard(prints[i], prints[j])
+			if sim >= threshold && sim > 0 {
+				pairs = append(pairs, model.SimilarityPair{Similarity: sim, A: side(submissions[i]), B: side(submissions[j])})
+			}
+		}
+	}
+	slices.SortStableFunc(pairs, func(a, b model.SimilarityPair) int {
+		return cmp.Compare(b.Similarity, a.Similarity)
+	})
+	return pairs
+}
+
+// jaccard is |a ∩ b| / |a ∪ b|, or 0 when both are empty.
+func jaccard(a, b map[uint64]bool) float64 {
+	shared := 0
+	for h := range a {
+		if b[h] {
+			shared++
+		}
+	}
+	union := len(a) + len(b) - shared
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+// fingerprint winnows code's k-gram hashes down to its fingerprint set.
+// Code shorter than k tokens gets one hash of all of them.
+func fingerprint(code string) map[uint64]bool {
+	tokens := tokenize(code)
+	prints := make(map[uint64]bool)
+	if len(tokens) == 0 {
+		return prints
+	}
+	if len(tokens) < similarityK {
+		prints[hashTokens(tokens)] = true
+		return prints
+	}
+
+	hashes := make([]uint64, 0, len(tokens)-similarityK+1)
+	for i := 0; i+similarityK <= len(tokens); i++ {
+		hashes = append(hashes, hashTokens(tokens[i:i+similarityK]))
+	}
+	if len(hashes) <= similarityW {
+		for _, h := range hashes {
+			prints[h] = true
+		}
+		return prints
+	}
+	for i := 0; i+similarityW <= len(hashes); i++ {
+		prints[slices.Min(hashes[i:i+similarityW])] = true
+	}
+	return prints
+}
+
+func hashTokens(tokens []string) uint64 {
+	h := fnv.New64a()
+	for _, t := range tokens {
+		h.Write([]byte(t))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
+// pythonKeywords survive tokenizing as themselves: they're the structure
+// of the code, which renaming can't change.
+var pythonKeywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true, "assert": true,
+	"async": true, "await": true, "break": true, "class": true, "continue": true,
+	"def": true, "del": true, "elif": true, "else": true, "except": true,
+	"finally": true, "for": true, "from": true, "global": true, "if": true,
+	"import": true, "in": true, "is": true, "lambda": true, "nonlocal": true,
+	"not": true, "or": true, "pass": true, "raise": true, "return": true,
+	"try": true, "while": true, "with": true, "yield": true,
+}
+
+// tokenize turns Python code into normalized tokens: keywords and
+// punctuation as they are, names as "I", numbers as "N", strings as "S".
+// Comments and whitespace disappear. It doesn't need to be a full Python
+// lexer, only to treat equivalent code the same way.
+func tokenize(code string) []string {
+	var tokens []string
+	src := []rune(code)
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '"' || c == '\'':
+			i = skipString(src, i)
+			tokens = append(tokens, "S")
+		case unicode.IsDigit(c):
+			for i < len(src) && (unicode.IsDigit(src[i]) || unicode.IsLetter(src[i]) || src[i] == '.' || src[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, "N")
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(src) && (unicode.IsLetter(src[i]) || unicode.IsDigit(src[i]) || src[i] == '_') {
+				i++
+			}
+			word := string(src[start:i])
+			if i < len(src) && (src[i] == '"' || src[i] == '\'') && len(word) <= 2 && strings.Trim(strings.ToLower(word), "rbfu") == "" {
+				continue // a string prefix, as in f"..." — the string is the token
+			}
+			if pythonKeywords[word] {
+				tokens = append(tokens, word)
+			} else {
+				tokens = append(tokens, "I")
+			}
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens
+}
+
+// skipString returns the index just past the string literal starting at
+// src[i], which is a quote. Triple-quoted strings run to the matching
+// triple quote; an unterminated string runs to the end of the line.
+func skipString(src []rune, i int) int {
+	quote := src[i]
+	if i+2 < len(src) && src[i+1] == quote && src[i+2] == quote {
+		for j := i + 3; j+2 < len(src); j++ {
+			if src[j] == '\\' {
+				j++
+				continue
+			}
+			if src[j] == quote && src[j+1] == quote && src[j+2] == quote {
+				return j + 3
+			}
+		}
+		return len(src)
+	}
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case quote:
+			return j + 1
+		case '\n':
+			return j
+		}
+	}
+	return len(src)
+}
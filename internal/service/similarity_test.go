@@ -0,0 +1,70 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/sakif/coding-playground/internal/model"
+)
+
+func TestTokenize(t *testing.T) {
+	code := "def add(a, b):  # sum\n    return a + 1.5 * f\"{b}\" + '''x\n'''\n"
+	want := []string{"def", "I", "(", "I", ",", "I", ")", ":", "return", "I", "+", "N", "*", "S", "+", "S"}
+	if got := tokenize(code); !slices.Equal(got, want) {
+		t.Errorf("tokenize() = %q\nwant %q", got, want)
+	}
+}
+
+func TestFingerprint_Similarity(t *testing.T) {
+	original := `def mean(values):
+    total = 0
+    for v in values:
+        total += v
+    return total / len(values)
+`
+	// Renamed, recommented and reformatted: the same program.
+	disguised := `def average(xs):
+    # add them up
+    s = 0
+    for x in xs: s += x
+    return s / len(xs)
+`
+	different := `def mean(values):
+    if not values:
+        raise ValueError("empty")
+    return sum(values) / len(values)
+`
+	if got := jaccard(fingerprint(original), fingerprint(disguised)); got != 1 {
+		t.Errorf("similarity to a disguised copy = %.2f, want 1", got)
+	}
+	if got := jaccard(fingerprint(original), fingerprint(different)); got >= 0.5 {
+		t.Errorf("similarity to a different solution = %.2f, want under 0.5", got)
+	}
+	if got := jaccard(fingerprint(""), fingerprint("")); got != 0 {
+		t.Errorf("similarity of two empty submissions = %.2f, want 0", got)
+	}
+}
+
+func TestSimilarPairs(t *testing.T) {
+	starter := "def solve(n):\n    pass\n"
+	copied := "def solve(n):\n    result = []\n    for i in range(n):\n        result.append(i * i)\n    return result\n"
+	submissions := latestPerUser([]model.Submission{ // newest first
+		{ID: "s4", UserID: "ann", Code: copied},
+		{ID: "s3", UserID: "bob", Code: copied},
+		{ID: "s2", UserID: "cat", Code: starter}, // handed in the starter code as is
+		{ID: "s1", UserID: "ann", Code: "old attempt"},
+		{ID: "s0", UserID: "dan", Code: starter},
+	})
+	if len(submissions) != 4 || submissions[0].ID != "s4" {
+		t.Fatalf("latestPerUser() = %+v, want each user's newest", submissions)
+	}
+	members := []model.ClassMember{{UserID: "ann", Login: "ann"}, {UserID: "bob", Login: "bob"}}
+
+	pairs := similarPairs(submissions, members, starter, 0.8)
+	if len(pairs) != 1 {
+		t.Fatalf("similarPairs() = %+v, want only ann and bob (starter code doesn't count)", pairs)
+	}
+	if p := pairs[0]; p.A.SubmissionID != "s4" || p.B.Login != "bob" || p.Similarity != 1 {
+		t.Errorf("pair = %+v, want ann's s4 and bob's copy", p)
+	}
+}
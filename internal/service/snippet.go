@@ -37,11 +37,16 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/executor"
 	"github.com/sakif/coding-playground/internal/model"
 	"github.com/sakif/coding-playground/internal/repository"
 )
@@ -69,6 +74,12 @@ const (
 type SnippetService struct {
 	repo   repository.SnippetRepository
 	logger *slog.Logger
+	events EventPublisher           // optional; see PublishEvents
+	orgs   repository.OrgRepository // optional; see EnableOrgs
+
+	orgLimits OrgLimits                 // see LimitOrgs
+	deadlines Deadlines                 // see SetDeadlines
+	packages  executor.PackageAllowlist // see AllowPackages
 }
 
 // NewSnippetService creates a new SnippetService.
@@ -81,8 +92,62 @@ type SnippetService struct {
 // repository implementation to use (SQLite, Postgres, mock for tests).
 func NewSnippetService(repo repository.SnippetRepository, logger *slog.Logger) *SnippetService {
 	return &SnippetService{
-		repo:   repo,
-		logger: logger,
+		repo:      repo,
+		logger:    logger,
+		deadlines: DefaultDeadlines(),
+	}
+}
+
+// SetDeadlines changes how long each repository call may take (see
+// deadline.go). Call it before serving requests.
+func (s *SnippetService) SetDeadlines(d Deadlines) {
+	s.deadlines = d
+}
+
+// PublishEvents makes the service report snippet.created, snippet.updated,
+// snippet.forked and snippet.published to p (e.g. to deliver webhooks). Call it before serving requests.
+func (s *SnippetService) PublishEvents(p EventPublisher) {
+	s.events = p
+}
+
+// EnableOrgs lets snippets belong to organizations (see org.go), whose
+// members then share them. Call it before serving requests.
+func (s *SnippetService) EnableOrgs(orgs repository.OrgRepository) {
+	s.orgs = orgs
+}
+
+// AllowPackages lets snippets list packages from allow in their
+// requirements (see SetRequirements). Without it they can list none. Call
+// it before serving requests.
+func (s *SnippetService) AllowPackages(allow executor.PackageAllowlist) {
+	s.packages = allow
+}
+
+// CanManage reports whether userID may change snippet and moderate it:
+// the owner of a personal snippet, or any member of the org that owns it
+// except its viewers.
+func (s *SnippetService) CanManage(ctx context.Context, userID string, snippet *model.Snippet) (bool, error) {
+	return canManageSnippet(ctx, s.orgs, userID, snippet)
+}
+
+// publish reports an event if a publisher is set.
+func (s *SnippetService) publish(ctx context.Context, event string, snippet *model.Snippet) {
+	if s.events != nil {
+		s.events.Publish(ctx, event, snippet)
+	}
+}
+
+// invalidator is a repository that caches snippets (see cache.SnippetRepository)
+// and must be told when one changes behind its back.
+type invalidator interface {
+	Invalidate(ctx context.Context, id string)
+}
+
+// forget drops snippet id from the repository's cache, if it has one, after
+// it was changed through another repository.
+func (s *SnippetService) forget(ctx context.Context, id string) {
+	if c, ok := s.repo.(invalidator); ok {
+		c.Invalidate(ctx, id)
 	}
 }
 
@@ -110,20 +175,45 @@ func NewSnippetService(repo repository.SnippetRepository, logger *slog.Logger) *
 //    The handler translates domain errors to HTTP status codes.
 //    This keeps the service layer HTTP-agnostic.
 func (s *SnippetService) Create(ctx context.Context, name, code, description string) (*model.Snippet, error) {
+	return s.create(ctx, name, code, description, "")
+}
+
+// CreateInOrg saves a new snippet owned by an org. The signed-in user must
+// be one of its members or owners, and the org must be under its snippet
+// quota.
+func (s *SnippetService) CreateInOrg(ctx context.Context, orgID, name, code, description string) (*model.Snippet, error) {
+	userID, ok := auth.UserIDFromContext(ctx)
+	if !ok || userID == "" {
+		return nil, &apperror.AppError{Err: apperror.ErrForbidden, Message: "sign in to create a snippet in an org"}
+	}
+	member, err := s.orgMember(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role == model.OrgRoleViewer {
+		return nil, &apperror.AppError{Err: apperror.ErrForbidden, Message: "viewers can't create snippets in an org"}
+	}
+	if err := s.checkSnippetQuota(ctx, orgID); err != nil {
+		return nil, err
+	}
+	return s.create(ctx, name, code, description, orgID)
+}
+
+// create is Create for an org's snippet, or a personal one when orgID is "".
+func (s *SnippetService) create(ctx context.Context, name, code, description, orgID string) (*model.Snippet, error) {
 	// === VALIDATION ===
 	// Trim whitespace first — " hello " becomes "hello"
 	name = strings.TrimSpace(name)
 
+	// Check every field before returning, so the client sees all problems at once.
+	var verrs apperror.ValidationErrors
 	if name == "" {
-		return nil, apperror.ValidationFailed("name", "snippet name is required")
+		verrs.AddCode(apperror.CodeNameRequired, "name", "snippet name is required")
 	}
-	if len(name) > MaxSnippetNameLength {
-		return nil, apperror.ValidationFailed("name",
-			fmt.Sprintf("snippet name must be %d characters or less", MaxSnippetNameLength))
-	}
-	if len(code) > MaxCodeLength {
-		return nil, apperror.ValidationFailed("code",
-			fmt.Sprintf("code must be %d characters or less", MaxCodeLength))
+	validateNameLength(&verrs, name)
+	validateCodeLength(&verrs, code)
+	if err := verrs.Err(); err != nil {
+		return nil, err
 	}
 
 	// === CREATE THE MODEL ===
@@ -132,27 +222,82 @@ func (s *SnippetService) Create(ctx context.Context, name, code, description str
 		Name:        name,
 		Code:        code,
 		Description: strings.TrimSpace(description),
+		OrgID:       orgID,
+	}
+	// A signed-in creator owns the snippet; anonymous snippets have no owner.
+	if userID, ok := auth.UserIDFromContext(ctx); ok {
+		snippet.UserID = userID
 	}
 
 	// === DELEGATE TO REPOSITORY ===
 	// The repo handles ID generation, timestamps, and SQL.
 	// We pass ctx so the operation can be cancelled if the HTTP request is aborted.
-	if err := s.repo.Create(ctx, snippet); err != nil {
-		s.logger.Error("failed to create snippet",
+	if err := runDB(ctx, s.deadlines, func(ctx context.Context) error { return s.repo.Create(ctx, snippet) }); err != nil {
+		s.logger.ErrorContext(ctx, "failed to create snippet",
 			slog.String("name", name),
 			slog.String("error", err.Error()),
 		)
 		return nil, fmt.Errorf("creating snippet: %w", err)
 	}
 
-	s.logger.Info("snippet created",
+	s.logger.InfoContext(ctx, "snippet created",
 		slog.String("id", snippet.ID),
 		slog.String("name", snippet.Name),
 	)
+	s.publish(ctx, model.EventSnippetCreated, snippet)
 
 	return snippet, nil
 }
 
+// Fork copies a snippet into a new one owned by the signed-in user, who
+// can then change it freely. The fork remembers where it came from.
+func (s *SnippetService) Fork(ctx context.Context, id string) (*model.Snippet, error) {
+	userID, ok := auth.UserIDFromContext(ctx)
+	if !ok || userID == "" {
+		return nil, &apperror.AppError{Err: apperror.ErrForbidden, Message: "sign in to fork a snippet"}
+	}
+	original, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	fork := &model.Snippet{
+		Name:        original.Name,
+		Code:        original.Code,
+		Description: original.Description,
+		UserID:      userID,
+		ForkedFrom:  original.ID,
+
+		Requirements: original.Requirements,
+	}
+	if err := runDB(ctx, s.deadlines, func(ctx context.Context) error { return s.repo.Create(ctx, fork) }); err != nil {
+		return nil, apperror.Wrap(fmt.Errorf("forking snippet: %w", err)).
+			WithMeta("snippet_id", original.ID).
+			WithMeta("user_id", userID)
+	}
+
+	s.logger.InfoContext(ctx, "snippet forked",
+		slog.String("id", fork.ID),
+		slog.String("forked_from", original.ID),
+	)
+	s.publish(ctx, model.EventSnippetForked, fork)
+	return fork, nil
+}
+
+// validateNameLength records an error if name is too long.
+func validateNameLength(verrs *apperror.ValidationErrors, name string) {
+	if len(name) > MaxSnippetNameLength {
+		verrs.AddCode(apperror.CodeNameTooLong, "name", fmt.Sprintf("snippet name must be %d characters or less", MaxSnippetNameLength), "max", strconv.Itoa(MaxSnippetNameLength))
+	}
+}
+
+// validateCodeLength records an error if code is too long.
+func validateCodeLength(verrs *apperror.ValidationErrors, code string) {
+	if len(code) > MaxCodeLength {
+		verrs.AddCode(apperror.CodeCodeTooLong, "code", fmt.Sprintf("code must be %d characters or less", MaxCodeLength), "max", strconv.Itoa(MaxCodeLength))
+	}
+}
+
 // GetByID retrieves a snippet by its ID.
 // Returns apperror.ErrNotFound if the snippet doesn't exist.
 func (s *SnippetService) GetByID(ctx context.Context, id string) (*model.Snippet, error) {
@@ -162,7 +307,9 @@ func (s *SnippetService) GetByID(ctx context.Context, id string) (*model.Snippet
 		return nil, apperror.ValidationFailed("id", "snippet ID is required")
 	}
 
-	snippet, err := s.repo.GetByID(ctx, id)
+	snippet, err := withDB(ctx, s.deadlines, func(ctx context.Context) (*model.Snippet, error) {
+		return s.repo.GetByID(ctx, id)
+	})
 	if err != nil {
 		// Don't log NotFound as an error — it's a normal "not found" response.
 		// Only log actual database failures.
@@ -183,7 +330,15 @@ func (s *SnippetService) GetByID(ctx context.Context, id string) (*model.Snippet
 //
 // Example: page 3 with 20 items → limit=20, offset=40
 // The service enforces sane limits so callers can't request 1 million rows.
-func (s *SnippetService) List(ctx context.Context, limit, offset int) ([]model.Snippet, error) {
+//
+// The page also carries the total count, so clients know how many pages
+// there are without fetching past the end. filter narrows both the items and
+// the total.
+func (s *SnippetService) List(ctx context.Context, limit, offset int, filter ListFilter) (*SnippetPage, error) {
+	if err := filter.validate(); err != nil {
+		return nil, err
+	}
+
 	// Clamp limit to a sane range
 	if limit <= 0 {
 		limit = DefaultListLimit
@@ -197,16 +352,80 @@ func (s *SnippetService) List(ctx context.Context, limit, offset int) ([]model.S
 		offset = 0
 	}
 
-	snippets, err := s.repo.List(ctx, repository.ListOptions{
-		Limit:  limit,
-		Offset: offset,
+	opts := repository.ListOptions{
+		Limit:         limit,
+		Offset:        offset,
+		CreatedAfter:  filter.CreatedAfter,
+		CreatedBefore: filter.CreatedBefore,
+		UpdatedAfter:  filter.UpdatedAfter,
+		HasOwner:      filter.HasOwner,
+		OwnerID:       filter.OwnerID,
+		OrgID:         filter.OrgID,
+		OmitCode:      filter.OmitCode,
+	}
+	list := s.repo.List
+	if filter.WithAuthors {
+		list = s.repo.ListWithAuthors
+	}
+	snippets, err := withDB(ctx, s.deadlines, func(ctx context.Context) ([]model.Snippet, error) {
+		return list(ctx, opts)
 	})
 	if err != nil {
-		s.logger.Error("failed to list snippets", slog.String("error", err.Error()))
+		s.logger.ErrorContext(ctx, "failed to list snippets", slog.String("error", err.Error()))
 		return nil, fmt.Errorf("listing snippets: %w", err)
 	}
 
-	return snippets, nil
+	total, err := withDB(ctx, s.deadlines, func(ctx context.Context) (int, error) {
+		return s.repo.Count(ctx, opts)
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to count snippets", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("counting snippets: %w", err)
+	}
+
+	return &SnippetPage{
+		Items:  snippets,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
+
+// ListFilter narrows a snippet listing. Zero values don't filter.
+type ListFilter struct {
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	HasOwner      *bool  // true: owned snippets only; false: anonymous only
+	OwnerID       string // owned by this user only
+	OrgID         string // owned by this org only
+
+	// OmitCode skips loading code bodies. It doesn't change which snippets
+	// match; the returned snippets just have an empty Code.
+	OmitCode bool
+	// WithAuthors fills in each owned snippet's Author, in the same query.
+	WithAuthors bool
+}
+
+// validate rejects filters that can never match.
+func (f ListFilter) validate() error {
+	if !f.CreatedAfter.IsZero() && !f.CreatedBefore.IsZero() && !f.CreatedAfter.Before(f.CreatedBefore) {
+		return apperror.ValidationFailed("createdBefore", "createdBefore must be later than createdAfter")
+	}
+	return nil
+}
+
+// SnippetPage is one page of a snippet listing.
+type SnippetPage struct {
+	Items  []model.Snippet
+	Total  int // matching snippets across all pages
+	Limit  int // page size actually used (after clamping)
+	Offset int
+}
+
+// HasMore reports whether there are snippets after this page.
+func (p *SnippetPage) HasMore() bool {
+	return p.Offset+len(p.Items) < p.Total
 }
 
 // Update modifies an existing snippet.
@@ -228,57 +447,261 @@ func (s *SnippetService) Update(ctx context.Context, id, name, code, description
 	}
 
 	// Fetch existing snippet — returns NotFound if it doesn't exist
-	snippet, err := s.repo.GetByID(ctx, id)
+	snippet, err := withDB(ctx, s.deadlines, func(ctx context.Context) (*model.Snippet, error) {
+		return s.repo.GetByID(ctx, id)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Apply updates (only if provided — empty string means "don't change")
-	if name = strings.TrimSpace(name); name != "" {
-		if len(name) > MaxSnippetNameLength {
-			return nil, apperror.ValidationFailed("name",
-				fmt.Sprintf("snippet name must be %d characters or less", MaxSnippetNameLength))
+	if err := s.checkEditable(ctx, snippet); err != nil {
+		return nil, err
+	}
+	return s.update(ctx, snippet, name, code, description)
+}
+
+// checkEditable says whether the signed-in user may change snippet.
+// Personal snippets stay open to edit; an org's are its members' to change.
+func (s *SnippetService) checkEditable(ctx context.Context, snippet *model.Snippet) error {
+	if snippet.OrgID == "" {
+		return nil
+	}
+	userID, _ := auth.UserIDFromContext(ctx)
+	if ok, err := s.CanManage(ctx, userID, snippet); err != nil {
+		return err
+	} else if !ok {
+		return &apperror.AppError{
+			Err:     apperror.ErrForbidden,
+			Message: "only members and owners of the snippet's org can change it",
 		}
+	}
+	return nil
+}
+
+// SetRequirements replaces the packages a snippet installs before it runs
+// with manifest, in requirements.txt format; "" installs none. Every
+// package must be on the allowlist (see AllowPackages). Whoever may edit
+// the snippet may change them.
+func (s *SnippetService) SetRequirements(ctx context.Context, id, manifest string) (*model.Snippet, error) {
+	snippet, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkEditable(ctx, snippet); err != nil {
+		return nil, err
+	}
+	manifest = strings.TrimSpace(manifest)
+	if _, err := s.packages.Check(manifest); err != nil {
+		return nil, apperror.ValidationFailed("requirements", err.Error())
+	}
+	if manifest != "" {
+		manifest += "\n"
+	}
+	if manifest == snippet.Requirements {
+		return snippet, nil
+	}
+
+	snippet.Requirements = manifest
+	if err := runDB(ctx, s.deadlines, func(ctx context.Context) error { return s.repo.Update(ctx, snippet) }); err != nil {
+		return nil, apperror.Wrap(fmt.Errorf("setting snippet requirements: %w", err)).WithMeta("snippet_id", snippet.ID)
+	}
+
+	s.logger.InfoContext(ctx, "snippet requirements changed", slog.String("id", snippet.ID))
+	s.publish(ctx, model.EventSnippetUpdated, snippet)
+	return snippet, nil
+}
+
+// update applies Update's changes to snippet, without checking who's asking.
+func (s *SnippetService) update(ctx context.Context, snippet *model.Snippet, name, code, description string) (*model.Snippet, error) {
+	// Validate all fields first, then apply.
+	name = strings.TrimSpace(name)
+	var verrs apperror.ValidationErrors
+	validateNameLength(&verrs, name)
+	validateCodeLength(&verrs, code)
+	if err := verrs.Err(); err != nil {
+		return nil, err
+	}
+
+	// Apply updates (only if provided — empty string means "don't change")
+	if name != "" {
 		snippet.Name = name
 	}
 
-	// Code CAN be empty (user might want to clear it), so always update it
-	if len(code) > MaxCodeLength {
-		return nil, apperror.ValidationFailed("code",
-			fmt.Sprintf("code must be %d characters or less", MaxCodeLength))
+	// Code CAN be empty (user might want to clear it), so always update it.
+	// A change of code is a new version: line comments on the old one may
+	// no longer point at the right lines.
+	if code != snippet.Code {
+		snippet.Version++
 	}
 	snippet.Code = code
 	snippet.Description = strings.TrimSpace(description)
 
 	// Save to database
-	if err := s.repo.Update(ctx, snippet); err != nil {
-		s.logger.Error("failed to update snippet",
-			slog.String("id", id),
+	if err := runDB(ctx, s.deadlines, func(ctx context.Context) error { return s.repo.Update(ctx, snippet) }); err != nil {
+		s.logger.ErrorContext(ctx, "failed to update snippet",
+			slog.String("id", snippet.ID),
 			slog.String("error", err.Error()),
 		)
-		return nil, fmt.Errorf("updating snippet: %w", err)
+		return nil, apperror.Wrap(fmt.Errorf("updating snippet: %w", err)).WithMeta("snippet_id", snippet.ID)
 	}
 
-	s.logger.Info("snippet updated",
+	s.logger.InfoContext(ctx, "snippet updated",
 		slog.String("id", snippet.ID),
 		slog.String("name", snippet.Name),
 	)
+	s.publish(ctx, model.EventSnippetUpdated, snippet)
 
 	return snippet, nil
 }
 
+// SetPublic publishes a snippet to the Atom feeds, or takes it back down.
+// Only the signed-in owner (or, for an org's snippet, a member) may do this;
+// anonymous snippets can't be published, since there's nobody to vouch for them.
+func (s *SnippetService) SetPublic(ctx context.Context, id string, public bool) (*model.Snippet, error) {
+	snippet, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _ := auth.UserIDFromContext(ctx)
+	if ok, err := s.CanManage(ctx, userID, snippet); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, &apperror.AppError{
+			Err:     apperror.ErrForbidden,
+			Message: "only the owner of a snippet can change its visibility",
+		}
+	}
+	if snippet.Public == public {
+		return snippet, nil // nothing to do; keep the original PublishedAt
+	}
+
+	snippet.Public = public
+	snippet.PublishedAt = nil
+	if public {
+		now := time.Now()
+		snippet.PublishedAt = &now
+	}
+	if err := runDB(ctx, s.deadlines, func(ctx context.Context) error { return s.repo.SetPublic(ctx, snippet) }); err != nil {
+		return nil, fmt.Errorf("setting snippet visibility: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "snippet visibility changed",
+		slog.String("id", snippet.ID),
+		slog.Bool("public", public),
+	)
+	if public {
+		s.publish(ctx, model.EventSnippetPublished, snippet)
+	}
+	return snippet, nil
+}
+
+// FeedSize is how many snippets the Atom feeds list.
+const FeedSize = 20
+
+// Feed returns the most recently published snippets for the Atom feeds: from
+// everyone, or only from userID when it isn't empty.
+func (s *SnippetService) Feed(ctx context.Context, userID string) ([]model.Snippet, error) {
+	snippets, err := withDB(ctx, s.deadlines, func(ctx context.Context) ([]model.Snippet, error) {
+		return s.repo.ListPublic(ctx, userID, FeedSize)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing public snippets: %w", err)
+	}
+	return snippets, nil
+}
+
+// GetPublic retrieves a snippet for its public page. Snippets that aren't
+// published are reported as not found, so their IDs can't be probed.
+func (s *SnippetService) GetPublic(ctx context.Context, id string) (*model.Snippet, error) {
+	snippet, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !snippet.Public {
+		return nil, apperror.NotFound("snippet", id)
+	}
+	return snippet, nil
+}
+
+// SitemapSize caps the sitemap: the sitemap protocol allows 50,000 URLs per file.
+const SitemapSize = 50000
+
+// Sitemap returns published snippets for the sitemap, newest first, without
+// their code. It pages through the repository, which caps each List call.
+func (s *SnippetService) Sitemap(ctx context.Context) ([]model.Snippet, error) {
+	var all []model.Snippet
+	for len(all) < SitemapSize {
+		opts := repository.ListOptions{
+			Limit:      MaxListLimit,
+			Offset:     len(all),
+			PublicOnly: true,
+			OmitCode:   true,
+		}
+		page, err := withDB(ctx, s.deadlines, func(ctx context.Context) ([]model.Snippet, error) {
+			return s.repo.List(ctx, opts)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("listing public snippets: %w", err)
+		}
+		all = append(all, page...)
+		if len(page) < MaxListLimit {
+			break
+		}
+	}
+	return all, nil
+}
+
 // Delete removes a snippet by its ID.
-// Returns apperror.ErrNotFound if the snippet doesn't exist.
+// Returns apperror.ErrNotFound if the snippet doesn't exist. An org's
+// snippet can only be deleted by one of the org's owners or by the member
+// who created it.
 func (s *SnippetService) Delete(ctx context.Context, id string) error {
 	id = strings.TrimSpace(id)
 	if id == "" {
 		return apperror.ValidationFailed("id", "snippet ID is required")
 	}
 
-	if err := s.repo.Delete(ctx, id); err != nil {
+	snippet, err := withDB(ctx, s.deadlines, func(ctx context.Context) (*model.Snippet, error) {
+		return s.repo.GetByID(ctx, id)
+	})
+	if err != nil {
+		return err
+	}
+	if snippet.OrgID != "" {
+		if err := s.canDeleteOrgSnippet(ctx, snippet); err != nil {
+			return err
+		}
+	}
+
+	if err := runDB(ctx, s.deadlines, func(ctx context.Context) error { return s.repo.Delete(ctx, id) }); err != nil {
 		return err
 	}
 
-	s.logger.Info("snippet deleted", slog.String("id", id))
+	s.logger.InfoContext(ctx, "snippet deleted", slog.String("id", id))
+	return nil
+}
+
+// canDeleteOrgSnippet refuses to delete an org's snippet unless the
+// signed-in user owns the org, or created the snippet and is still a member.
+func (s *SnippetService) canDeleteOrgSnippet(ctx context.Context, snippet *model.Snippet) error {
+	forbidden := &apperror.AppError{
+		Err:     apperror.ErrForbidden,
+		Message: "only the org's owners or the snippet's creator can delete it",
+	}
+	userID, _ := auth.UserIDFromContext(ctx)
+	if userID == "" || s.orgs == nil {
+		return forbidden
+	}
+	member, err := s.orgs.GetOrgMember(ctx, snippet.OrgID, userID)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return forbidden
+	}
+	if err != nil {
+		return err
+	}
+	if member.Role != model.OrgRoleOwner && snippet.UserID != userID {
+		return forbidden
+	}
 	return nil
 }
@@ -4,12 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
 	"log/slog"
 	"os"
 
 	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/executor"
 	"github.com/sakif/coding-playground/internal/model"
 	"github.com/sakif/coding-playground/internal/repository"
 )
@@ -84,6 +87,14 @@ func (m *mockSnippetRepo) List(_ context.Context, opts repository.ListOptions) (
 	return result, nil
 }
 
+func (m *mockSnippetRepo) ListWithAuthors(ctx context.Context, opts repository.ListOptions) ([]model.Snippet, error) {
+	return m.List(ctx, opts)
+}
+
+func (m *mockSnippetRepo) Count(_ context.Context, _ repository.ListOptions) (int, error) {
+	return len(m.snippets), nil
+}
+
 func (m *mockSnippetRepo) Update(_ context.Context, snippet *model.Snippet) error {
 	if _, ok := m.snippets[snippet.ID]; !ok {
 		return apperror.NotFound("snippet", snippet.ID)
@@ -93,6 +104,23 @@ func (m *mockSnippetRepo) Update(_ context.Context, snippet *model.Snippet) erro
 	return nil
 }
 
+func (m *mockSnippetRepo) SetPublic(ctx context.Context, snippet *model.Snippet) error {
+	return m.Update(ctx, snippet)
+}
+
+func (m *mockSnippetRepo) ListPublic(_ context.Context, userID string, limit int) ([]model.Snippet, error) {
+	var result []model.Snippet
+	for _, s := range m.snippets {
+		if s.Public && (userID == "" || s.UserID == userID) {
+			result = append(result, *s)
+		}
+	}
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
 func (m *mockSnippetRepo) Delete(_ context.Context, id string) error {
 	if _, ok := m.snippets[id]; !ok {
 		return apperror.NotFound("snippet", id)
@@ -197,6 +225,24 @@ func TestCreate_NameTooLong(t *testing.T) {
 	}
 }
 
+func TestCreate_ReportsEveryInvalidField(t *testing.T) {
+	svc, _ := newTestService(t)
+
+	longCode := strings.Repeat("x", MaxCodeLength+1)
+	_, err := svc.Create(context.Background(), "   ", longCode, "")
+
+	var verrs *apperror.ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("error = %v, want *apperror.ValidationErrors", err)
+	}
+	if len(verrs.Fields) != 2 || verrs.Fields[0].Field != "name" || verrs.Fields[1].Field != "code" {
+		t.Errorf("fields = %+v, want name and code", verrs.Fields)
+	}
+	if !errors.Is(err, apperror.ErrValidation) {
+		t.Errorf("error = %v, want ErrValidation", err)
+	}
+}
+
 // =========================================================================
 // GET BY ID TESTS
 // =========================================================================
@@ -251,12 +297,44 @@ func TestGetByID_EmptyID(t *testing.T) {
 func TestList_Empty(t *testing.T) {
 	svc, _ := newTestService(t)
 
-	snippets, err := svc.List(context.Background(), 0, 0)
+	page, err := svc.List(context.Background(), 0, 0, ListFilter{})
 	if err != nil {
 		t.Fatalf("List() error = %v", err)
 	}
-	if len(snippets) != 0 {
-		t.Errorf("List() returned %d items, want 0", len(snippets))
+	if len(page.Items) != 0 || page.Total != 0 {
+		t.Errorf("List() returned %d items (total %d), want 0", len(page.Items), page.Total)
+	}
+	if page.HasMore() {
+		t.Error("HasMore() = true for an empty list")
+	}
+}
+
+func TestList_PageMetadata(t *testing.T) {
+	svc, _ := newTestService(t)
+	for i := 0; i < 5; i++ {
+		if _, err := svc.Create(context.Background(), fmt.Sprintf("snippet %d", i), "print(1)", ""); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	page, err := svc.List(context.Background(), 2, 2, ListFilter{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(page.Items) != 2 || page.Total != 5 || page.Limit != 2 || page.Offset != 2 {
+		t.Errorf("page = %d items, total %d, limit %d, offset %d; want 2, 5, 2, 2",
+			len(page.Items), page.Total, page.Limit, page.Offset)
+	}
+	if !page.HasMore() {
+		t.Error("HasMore() = false, want true (one snippet left)")
+	}
+
+	last, err := svc.List(context.Background(), 2, 4, ListFilter{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if last.HasMore() {
+		t.Error("HasMore() = true on the last page")
 	}
 }
 
@@ -264,7 +342,7 @@ func TestList_ClampsBadValues(t *testing.T) {
 	svc, _ := newTestService(t)
 
 	// Should not error even with negative values
-	_, err := svc.List(context.Background(), -5, -10)
+	_, err := svc.List(context.Background(), -5, -10, ListFilter{})
 	if err != nil {
 		t.Fatalf("List() should handle negative values gracefully, got error = %v", err)
 	}
@@ -292,6 +370,27 @@ func TestUpdate_Success(t *testing.T) {
 	}
 }
 
+func TestUpdate_VersionsCodeChanges(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+
+	created, _ := svc.Create(ctx, "versions", "print(1)", "")
+	renamed, err := svc.Update(ctx, created.ID, "renamed", "print(1)", "now described")
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if renamed.Version != created.Version {
+		t.Errorf("Version = %d after a rename, want %d (unchanged)", renamed.Version, created.Version)
+	}
+	edited, err := svc.Update(ctx, created.ID, "", "print(2)", "")
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if edited.Version != created.Version+1 {
+		t.Errorf("Version = %d after a code change, want %d", edited.Version, created.Version+1)
+	}
+}
+
 func TestUpdate_NotFound(t *testing.T) {
 	svc, _ := newTestService(t)
 
@@ -335,3 +434,87 @@ func TestDelete_EmptyID(t *testing.T) {
 		t.Errorf("error = %v, want ErrValidation", err)
 	}
 }
+
+func TestSetPublic_OwnerOnly(t *testing.T) {
+	svc, _ := newTestService(t)
+	owner := auth.WithUserID(context.Background(), "owner")
+
+	created, err := svc.Create(owner, "mine", "print(1)", "")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.UserID != "owner" {
+		t.Fatalf("UserID = %q, want the signed-in creator", created.UserID)
+	}
+
+	for name, ctx := range map[string]context.Context{
+		"anonymous":  context.Background(),
+		"other user": auth.WithUserID(context.Background(), "someone-else"),
+	} {
+		if _, err := svc.SetPublic(ctx, created.ID, true); !errors.Is(err, apperror.ErrForbidden) {
+			t.Errorf("%s: error = %v, want ErrForbidden", name, err)
+		}
+	}
+
+	published, err := svc.SetPublic(owner, created.ID, true)
+	if err != nil {
+		t.Fatalf("SetPublic(true) error = %v", err)
+	}
+	if !published.Public || published.PublishedAt == nil {
+		t.Errorf("published snippet = %+v, want Public with PublishedAt", published)
+	}
+	if feed, _ := svc.Feed(context.Background(), "owner"); len(feed) != 1 {
+		t.Errorf("owner feed has %d snippets, want 1", len(feed))
+	}
+
+	hidden, err := svc.SetPublic(owner, created.ID, false)
+	if err != nil {
+		t.Fatalf("SetPublic(false) error = %v", err)
+	}
+	if hidden.Public || hidden.PublishedAt != nil {
+		t.Errorf("unpublished snippet = %+v", hidden)
+	}
+}
+
+func TestSetPublic_AnonymousSnippet(t *testing.T) {
+	svc, _ := newTestService(t)
+
+	created, _ := svc.Create(context.Background(), "anon", "print(1)", "")
+	_, err := svc.SetPublic(auth.WithUserID(context.Background(), "anyone"), created.ID, true)
+	if !errors.Is(err, apperror.ErrForbidden) {
+		t.Errorf("error = %v, want ErrForbidden", err)
+	}
+}
+
+func TestSetRequirements(t *testing.T) {
+	svc, _ := newTestService(t)
+	owner := auth.WithUserID(context.Background(), "owner")
+	created, _ := svc.Create(owner, "plot", "import numpy", "")
+
+	if _, err := svc.SetRequirements(owner, created.ID, "numpy"); !errors.Is(err, apperror.ErrValidation) {
+		t.Errorf("no allowlist: error = %v, want ErrValidation", err)
+	}
+
+	svc.AllowPackages(executor.NewPackageAllowlist([]string{"numpy"}))
+	for _, bad := range []string{"requests", "-e git+https://example.com/x.git"} {
+		if _, err := svc.SetRequirements(owner, created.ID, bad); !errors.Is(err, apperror.ErrValidation) {
+			t.Errorf("SetRequirements(%q) error = %v, want ErrValidation", bad, err)
+		}
+	}
+
+	updated, err := svc.SetRequirements(owner, created.ID, "  numpy==1.26.4  ")
+	if err != nil {
+		t.Fatalf("SetRequirements() error = %v", err)
+	}
+	if updated.Requirements != "numpy==1.26.4\n" || updated.Version != created.Version {
+		t.Errorf("snippet = %+v, want the manifest saved and the code's version kept", updated)
+	}
+
+	fork, err := svc.Fork(auth.WithUserID(context.Background(), "fan"), created.ID)
+	if err != nil {
+		t.Fatalf("Fork() error = %v", err)
+	}
+	if fork.Requirements != updated.Requirements {
+		t.Errorf("fork requirements = %q, want %q", fork.Requirements, updated.Requirements)
+	}
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// StarStatus is whether the caller has starred a snippet, and how many
+// users have.
+type StarStatus struct {
+	Starred bool `json:"starred"`
+	Stars   int  `json:"stars"`
+}
+
+// StarService lets signed-in users star snippets they like.
+type StarService struct {
+	snippets *SnippetService
+	repo     repository.StarRepository
+	logger   *slog.Logger
+	events   EventPublisher // optional; see PublishEvents
+}
+
+// NewStarService creates a StarService.
+func NewStarService(snippets *SnippetService, repo repository.StarRepository, logger *slog.Logger) *StarService {
+	return &StarService{
+		snippets: snippets,
+		repo:     repo,
+		logger:   logger,
+	}
+}
+
+// PublishEvents makes the service report snippet.starred to p. Call it
+// before serving requests.
+func (s *StarService) PublishEvents(p EventPublisher) {
+	s.events = p
+}
+
+// Star stars a snippet for userID. Starring twice is harmless.
+func (s *StarService) Star(ctx context.Context, userID, snippetID string) (*StarStatus, error) {
+	snippet, err := s.snippets.GetByID(ctx, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	added, err := s.repo.StarSnippet(ctx, snippet.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if added && s.events != nil {
+		s.events.Publish(ctx, model.EventSnippetStarred, snippet)
+	}
+	return s.status(ctx, snippet.ID, true)
+}
+
+// Unstar removes userID's star from a snippet, if there was one.
+func (s *StarService) Unstar(ctx context.Context, userID, snippetID string) (*StarStatus, error) {
+	snippet, err := s.snippets.GetByID(ctx, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.UnstarSnippet(ctx, snippet.ID, userID); err != nil {
+		return nil, err
+	}
+	return s.status(ctx, snippet.ID, false)
+}
+
+func (s *StarService) status(ctx context.Context, snippetID string, starred bool) (*StarStatus, error) {
+	stars, err := s.repo.CountStars(ctx, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	return &StarStatus{Starred: starred, Stars: stars}, nil
+}
+
+// StarPage is one page of stars, on a snippet or by a user.
+type StarPage struct {
+	Items  []model.Star
+	Total  int
+	Limit  int
+	Offset int
+}
+
+// HasMore reports whether there are stars after this page.
+func (p *StarPage) HasMore() bool {
+	return p.Offset+len(p.Items) < p.Total
+}
+
+// ListBySnippet returns a page of a snippet's stars, newest first. limit and
+// offset are clamped like SnippetService.List's.
+func (s *StarService) ListBySnippet(ctx context.Context, snippetID string, limit, offset int) (*StarPage, error) {
+	limit, offset = clampStarPage(limit, offset)
+	stars, err := s.repo.ListSnippetStars(ctx, snippetID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("listing snippet stars: %w", err)
+	}
+	total, err := s.repo.CountStars(ctx, snippetID)
+	if err != nil {
+		return nil, fmt.Errorf("counting snippet stars: %w", err)
+	}
+	return &StarPage{Items: stars, Total: total, Limit: limit, Offset: offset}, nil
+}
+
+// ListByUser returns a page of the stars userID gave, newest first.
+func (s *StarService) ListByUser(ctx context.Context, userID string, limit, offset int) (*StarPage, error) {
+	limit, offset = clampStarPage(limit, offset)
+	stars, err := s.repo.ListUserStars(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("listing user stars: %w", err)
+	}
+	total, err := s.repo.CountUserStars(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("counting user stars: %w", err)
+	}
+	return &StarPage{Items: stars, Total: total, Limit: limit, Offset: offset}, nil
+}
+
+func clampStarPage(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	return min(limit, MaxListLimit), max(offset, 0)
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/executor"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// USER STATS:
+// GET /api/v1/me/stats sums up what a user has done: snippets saved, code
+// run, how often it ran cleanly, how long it took, and a day-by-day count
+// for the last StatsDays days — enough for a dashboard and a GitHub-style
+// activity grid:
+//
+//	{"snippets":12,"runs":340,"successfulRuns":301,"failedRuns":39,
+//	 "successRate":0.885,"averageRuntimeMs":182.4,"mostUsedLanguage":"python",
+//	 "activity":[{"date":"2024-02-02","snippets":0,"runs":0}, …]}
+//
+// Runs are recorded as they finish, from execution.completed. Anonymous
+// runs are recorded too, without a user, so the run history has them for
+// retention and totals, but no one's stats count them. The outcome is kept
+// — exit code and duration, never output — and, for replaying a signed-in
+// user's run, its code (see RunService). How long runs are kept is up to
+// the run_purge task (see server.RunRetention).
+
+// StatsDays is how many days of activity stats cover, today included.
+const StatsDays = 90
+
+// StatsService records runs and sums up users' stats.
+type StatsService struct {
+	repo   repository.StatsRepository
+	logger *slog.Logger
+	now    func() time.Time // injectable for tests
+}
+
+// NewStatsService creates a StatsService.
+func NewStatsService(repo repository.StatsRepository, logger *slog.Logger) *StatsService {
+	return &StatsService{repo: repo, logger: logger, now: time.Now}
+}
+
+// ForUser returns userID's stats. Activity has an entry for every one of
+// the last StatsDays days, oldest first, including days with nothing.
+func (s *StatsService) ForUser(ctx context.Context, userID string) (*model.UserStats, error) {
+	now := s.now().Local()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	since := today.AddDate(0, 0, -(StatsDays - 1))
+
+	stats, err := s.repo.GetUserStats(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	byDate := make(map[string]model.DayActivity, len(stats.Activity))
+	for _, day := range stats.Activity {
+		byDate[day.Date] = day
+	}
+	stats.Activity = make([]model.DayActivity, StatsDays)
+	for i := range stats.Activity {
+		date := since.AddDate(0, 0, i).Format(time.DateOnly)
+		day := byDate[date]
+		day.Date = date
+		stats.Activity[i] = day
+	}
+	return stats, nil
+}
+
+// Publish records runs, signed-in users' with their user ID and anonymous
+// ones with none — unless a RunService has recorded the run already, code
+// and all, and given it a RunID. Like every EventPublisher, it never fails
+// the caller: problems are logged.
+func (s *StatsService) Publish(ctx context.Context, event string, data any) {
+	result, ok := data.(*executor.ExecutionResult)
+	if event != model.EventExecutionCompleted || !ok || result.RunID != "" {
+		return
+	}
+	userID, _ := auth.UserIDFromContext(ctx)
+	ctx = context.WithoutCancel(ctx)
+	run := &model.Run{
+		UserID:     userID,
+		Language:   executor.Language,
+		ExitCode:   result.ExitCode,
+		DurationMS: result.Duration.Milliseconds(),
+	}
+	if err := s.repo.CreateRun(ctx, run); err != nil {
+		s.logger.ErrorContext(ctx, "failed to record run",
+			slog.String("user_id", userID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
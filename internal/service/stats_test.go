@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/executor"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository/sqlite"
+)
+
+func TestStatsService(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	stats := NewStatsService(db, logger)
+	snippets := NewSnippetService(db, logger)
+
+	ctx := context.Background()
+	asAnn := auth.WithUserID(ctx, "ann-id")
+	snippets.Create(asAnn, "one", "print(1)", "")
+	snippets.Create(asAnn, "two", "print(2)", "")
+	snippets.Create(auth.WithUserID(ctx, "bob-id"), "bob's", "", "")
+
+	for _, run := range []struct {
+		exit     int
+		duration time.Duration
+	}{{0, 100 * time.Millisecond}, {0, 200 * time.Millisecond}, {1, 300 * time.Millisecond}, {0, 400 * time.Millisecond}} {
+		stats.Publish(asAnn, model.EventExecutionCompleted, &executor.ExecutionResult{ExitCode: run.exit, Duration: run.duration})
+	}
+	// Anonymous runs, and other events, count for nobody.
+	stats.Publish(ctx, model.EventExecutionCompleted, &executor.ExecutionResult{})
+	stats.Publish(asAnn, model.EventSnippetCreated, &executor.ExecutionResult{})
+
+	got, err := stats.ForUser(ctx, "ann-id")
+	if err != nil {
+		t.Fatalf("ForUser() error = %v", err)
+	}
+	if got.Snippets != 2 || got.Runs != 4 || got.SuccessfulRuns != 3 || got.FailedRuns != 1 ||
+		got.SuccessRate != 0.75 || got.AverageRuntimeMS != 250 || got.MostUsedLanguage != "python" {
+		t.Errorf("ForUser() = %+v", got)
+	}
+	if len(got.Activity) != StatsDays {
+		t.Fatalf("len(Activity) = %d, want %d", len(got.Activity), StatsDays)
+	}
+	today := got.Activity[StatsDays-1]
+	if today.Date != time.Now().Format(time.DateOnly) || today.Snippets != 2 || today.Runs != 4 {
+		t.Errorf("today = %+v, want 2 snippets and 4 runs", today)
+	}
+	if first := got.Activity[0]; first.Date != time.Now().AddDate(0, 0, -(StatsDays-1)).Format(time.DateOnly) || first.Runs != 0 {
+		t.Errorf("first day = %+v", first)
+	}
+
+	// Someone who has done nothing gets zeros, not an error.
+	if got, err := stats.ForUser(ctx, "nobody"); err != nil || got.Runs != 0 || got.SuccessRate != 0 || got.MostUsedLanguage != "" {
+		t.Errorf("ForUser(nobody) = %+v, %v", got, err)
+	}
+}
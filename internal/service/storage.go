@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// DATABASE STORAGE:
+// SQLite in WAL mode appends every write to a -wal file and copies it back
+// into the database in "checkpoints". It does that by itself, but only
+// while nobody is reading, so a server that's never idle can grow a WAL of
+// gigabytes — slowing every read, which has to search it. The wal_checkpoint
+// task (SCHEDULE_WAL_CHECKPOINT, every 15 minutes by default) forces a
+// TRUNCATE checkpoint; admins can also run one by hand and watch the sizes:
+//
+//	GET  /api/v1/admin/db             → page count, freelist, WAL size
+//	POST /api/v1/admin/db/checkpoint  → checkpoint now, and what it did
+
+// StorageService reports on the database's files and checkpoints its WAL.
+type StorageService struct {
+	repo   repository.StorageRepository
+	logger *slog.Logger
+}
+
+// NewStorageService creates a StorageService.
+func NewStorageService(repo repository.StorageRepository, logger *slog.Logger) *StorageService {
+	return &StorageService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Stats describes the database file and its WAL.
+func (s *StorageService) Stats(ctx context.Context) (*model.StorageStats, error) {
+	return s.repo.StorageStats(ctx)
+}
+
+// Checkpoint runs a TRUNCATE checkpoint and logs what it did. One kept
+// from finishing by busy readers is logged as a warning, not returned as an
+// error: the next will try again.
+func (s *StorageService) Checkpoint(ctx context.Context) (*model.Checkpoint, error) {
+	result, err := s.repo.Checkpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	attrs := []any{
+		slog.Int("log_frames", result.LogFrames),
+		slog.Int("checkpointed_frames", result.CheckpointedFrames),
+		slog.Int64("wal_bytes_before", result.WALBytesBefore),
+		slog.Int64("wal_bytes_after", result.WALBytesAfter),
+		slog.Duration("duration", result.Duration),
+	}
+	if result.Busy {
+		s.logger.WarnContext(ctx, "wal checkpoint could not finish: database busy", attrs...)
+	} else {
+		s.logger.InfoContext(ctx, "wal checkpoint", attrs...)
+	}
+	return result, nil
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// SNIPPET TRANSFERS:
+// A snippet can be handed to another user or to an org, but only if they
+// want it — an offer waits until the recipient accepts:
+//
+//	POST   /api/v1/snippets/{id}/transfer {"login": "bob"} or {"orgId": "…"}
+//	GET    /api/v1/me/transfers           → offers to you, and yours to others
+//	POST   /api/v1/transfers/{id}/accept  → the snippet is theirs
+//	DELETE /api/v1/transfers/{id}         → declined, or withdrawn
+//
+// A personal snippet is offered by its owner; an org's by one of the org's
+// owners. An offer to an org is accepted by one of its owners, and counts
+// against its snippet quota. The snippet keeps its ID, so its share links,
+// public page, versions, comments, stars and forks all come along; only who
+// owns it changes. Moved into an org, its creator stays on it as UserID,
+// like any org snippet.
+//
+// Each snippet has at most one offer out at a time. If the snippet changes
+// hands some other way before an offer is accepted, accepting fails with
+// ErrConflict and the offer can only be declined.
+
+// TransferTarget says who a snippet is offered to: a user by login, or an
+// org by ID. Exactly one must be set.
+type TransferTarget struct {
+	Login string
+	OrgID string
+}
+
+// TransferList is the signed-in user's pending transfers.
+type TransferList struct {
+	Incoming []model.SnippetTransfer `json:"incoming"` // to them, or to orgs they own
+	Outgoing []model.SnippetTransfer `json:"outgoing"` // offered by them
+}
+
+// TransferService offers snippets to new owners and hands them over.
+type TransferService struct {
+	repo     repository.TransferRepository
+	snippets *SnippetService
+	orgs     repository.OrgRepository
+	users    repository.UserRepository
+	logger   *slog.Logger
+	events   EventPublisher // optional; see PublishEvents
+}
+
+// NewTransferService creates a TransferService. snippets must have orgs
+// enabled for offers to orgs to be accepted.
+func NewTransferService(repo repository.TransferRepository, snippets *SnippetService, orgs repository.OrgRepository, users repository.UserRepository, logger *slog.Logger) *TransferService {
+	return &TransferService{
+		repo:     repo,
+		snippets: snippets,
+		orgs:     orgs,
+		users:    users,
+		logger:   logger,
+	}
+}
+
+// PublishEvents makes the service report transfer.offered to p. Call it
+// before serving requests.
+func (s *TransferService) PublishEvents(p EventPublisher) {
+	s.events = p
+}
+
+// Offer offers a snippet to a new owner on behalf of userID.
+func (s *TransferService) Offer(ctx context.Context, userID, snippetID string, to TransferTarget) (*model.SnippetTransfer, error) {
+	to.Login = strings.TrimSpace(to.Login)
+	to.OrgID = strings.TrimSpace(to.OrgID)
+	if (to.Login == "") == (to.OrgID == "") {
+		return nil, apperror.ValidationFailed("login", "give either the login of a user or the orgId of an org")
+	}
+	snippet, err := s.snippets.GetByID(ctx, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.canOffer(ctx, userID, snippet); err != nil {
+		return nil, err
+	}
+
+	t := &model.SnippetTransfer{
+		SnippetID:   snippet.ID,
+		SnippetName: snippet.Name,
+		FromUserID:  snippet.UserID,
+		FromOrgID:   snippet.OrgID,
+		CreatedBy:   userID,
+	}
+	if to.Login != "" {
+		user, err := s.users.GetUserByLogin(ctx, to.Login)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, apperror.NotFound("user", to.Login)
+		}
+		if snippet.OrgID == "" && user.ID == snippet.UserID {
+			return nil, apperror.ValidationFailed("login", "the snippet already belongs to "+user.Login)
+		}
+		t.ToUserID = user.ID
+	} else {
+		if _, err := s.orgs.GetOrg(ctx, to.OrgID); err != nil {
+			return nil, err
+		}
+		if to.OrgID == snippet.OrgID {
+			return nil, apperror.ValidationFailed("orgId", "the snippet already belongs to this org")
+		}
+		t.ToOrgID = to.OrgID
+	}
+	if err := s.repo.CreateTransfer(ctx, t); err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "snippet transfer offered",
+		slog.String("id", t.ID),
+		slog.String("snippet_id", snippet.ID),
+		slog.String("to_user_id", t.ToUserID),
+		slog.String("to_org_id", t.ToOrgID),
+	)
+	if s.events != nil {
+		s.events.Publish(ctx, model.EventTransferOffered, t)
+	}
+	return t, nil
+}
+
+// canOffer allows the owner of a personal snippet, or an owner of the org
+// that owns it, to give it away.
+func (s *TransferService) canOffer(ctx context.Context, userID string, snippet *model.Snippet) error {
+	if snippet.OrgID == "" {
+		if snippet.UserID == "" || snippet.UserID != userID {
+			return &apperror.AppError{Err: apperror.ErrForbidden, Message: "only the snippet's owner can transfer it"}
+		}
+		return nil
+	}
+	if ok, err := s.isOrgOwner(ctx, snippet.OrgID, userID); err != nil {
+		return err
+	} else if !ok {
+		return &apperror.AppError{Err: apperror.ErrForbidden, Message: "only the org's owners can transfer its snippets"}
+	}
+	return nil
+}
+
+// List returns the offers userID can accept and the ones they made.
+func (s *TransferService) List(ctx context.Context, userID string) (*TransferList, error) {
+	incoming, err := s.repo.ListTransfersTo(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	outgoing, err := s.repo.ListTransfersBy(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &TransferList{Incoming: incoming, Outgoing: outgoing}, nil
+}
+
+// Accept hands the snippet over to the recipient of an offer, which
+// userID must be (or own, for an org), and returns it.
+func (s *TransferService) Accept(ctx context.Context, userID, id string) (*model.Snippet, error) {
+	t, err := s.repo.GetTransfer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := s.isRecipient(ctx, userID, t); err != nil {
+		return nil, err
+	} else if !ok {
+		if t.CreatedBy == userID {
+			return nil, &apperror.AppError{Err: apperror.ErrForbidden, Message: "only the recipient can accept a transfer"}
+		}
+		return nil, apperror.NotFound("transfer", id)
+	}
+
+	newUserID, newOrgID := t.ToUserID, ""
+	if t.ToOrgID != "" {
+		if err := s.snippets.checkSnippetQuota(ctx, t.ToOrgID); err != nil {
+			return nil, err
+		}
+		newUserID, newOrgID = t.FromUserID, t.ToOrgID
+	}
+	if err := s.repo.CompleteTransfer(ctx, t, newUserID, newOrgID); err != nil {
+		return nil, err
+	}
+	s.snippets.forget(ctx, t.SnippetID)
+
+	s.logger.InfoContext(ctx, "snippet transferred",
+		slog.String("id", t.ID),
+		slog.String("snippet_id", t.SnippetID),
+		slog.String("user_id", newUserID),
+		slog.String("org_id", newOrgID),
+	)
+	return s.snippets.GetByID(ctx, t.SnippetID)
+}
+
+// Delete declines an offer, for its recipient, or withdraws it, for whoever
+// made it.
+func (s *TransferService) Delete(ctx context.Context, userID, id string) error {
+	t, err := s.repo.GetTransfer(ctx, id)
+	if err != nil {
+		return err
+	}
+	if t.CreatedBy != userID {
+		if ok, err := s.isRecipient(ctx, userID, t); err != nil {
+			return err
+		} else if !ok {
+			return apperror.NotFound("transfer", id)
+		}
+	}
+	if err := s.repo.DeleteTransfer(ctx, id); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "snippet transfer withdrawn or declined", slog.String("id", id))
+	return nil
+}
+
+// isRecipient reports whether userID may accept t: they're who it's for,
+// or an owner of the org it's for.
+func (s *TransferService) isRecipient(ctx context.Context, userID string, t *model.SnippetTransfer) (bool, error) {
+	if t.ToUserID != "" {
+		return t.ToUserID == userID, nil
+	}
+	return s.isOrgOwner(ctx, t.ToOrgID, userID)
+}
+
+// isOrgOwner reports whether userID is one of an org's owners.
+func (s *TransferService) isOrgOwner(ctx context.Context, orgID, userID string) (bool, error) {
+	member, err := s.orgs.GetOrgMember(ctx, orgID, userID)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return member.Role == model.OrgRoleOwner, nil
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/cache"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository/sqlite"
+	"github.com/sakif/coding-playground/internal/ws"
+)
+
+func TestTransferService(t *testing.T) {
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	orgs := NewOrgService(db, db, logger)
+	// Through the cache, so a transfer must invalidate the snippet's entry
+	// for its new owner to be seen.
+	snippets := NewSnippetService(cache.NewSnippetRepository(db, cache.NewMemory(0), time.Minute, logger), logger)
+	snippets.EnableOrgs(db)
+	transfers := NewTransferService(db, snippets, db, db, logger)
+	notifications := NewNotificationService(db, db, db, db, ws.NewHub(logger, ws.Options{}), logger)
+	transfers.PublishEvents(notifications)
+
+	ctx := context.Background()
+	for i, login := range []string{"ann", "bob", "cat"} {
+		if err := db.Upsert(ctx, &model.User{ID: login + "-id", GitHubID: int64(i + 1), Login: login}); err != nil {
+			t.Fatalf("creating %s: %v", login, err)
+		}
+	}
+	asAnn := auth.WithUserID(ctx, "ann-id")
+
+	loop, err := snippets.Create(asAnn, "loop", "for i in range(3): print(i)", "")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := snippets.SetPublic(asAnn, loop.ID, true); err != nil {
+		t.Fatalf("SetPublic() error = %v", err)
+	}
+
+	t.Run("to a user", func(t *testing.T) {
+		if _, err := transfers.Offer(ctx, "bob-id", loop.ID, TransferTarget{Login: "cat"}); !errors.Is(err, apperror.ErrForbidden) {
+			t.Errorf("Offer(not owner) error = %v, want forbidden", err)
+		}
+		if _, err := transfers.Offer(ctx, "ann-id", loop.ID, TransferTarget{Login: "ann"}); !errors.Is(err, apperror.ErrValidation) {
+			t.Errorf("Offer(to self) error = %v, want validation error", err)
+		}
+		if _, err := transfers.Offer(ctx, "ann-id", loop.ID, TransferTarget{}); !errors.Is(err, apperror.ErrValidation) {
+			t.Errorf("Offer(no recipient) error = %v, want validation error", err)
+		}
+		offer, err := transfers.Offer(ctx, "ann-id", loop.ID, TransferTarget{Login: "bob"})
+		if err != nil {
+			t.Fatalf("Offer() error = %v", err)
+		}
+		if offer.ToUserID != "bob-id" || offer.SnippetName != "loop" {
+			t.Errorf("Offer() = %+v, want loop to bob", offer)
+		}
+		if list, err := notifications.List(ctx, "bob-id", true, 0); err != nil || len(list.Items) != 1 || list.Items[0].Message != "ann wants to transfer “loop” to you" {
+			t.Errorf("bob's notifications = %+v, %v; want the offer", list, err)
+		}
+		if _, err := transfers.Offer(ctx, "ann-id", loop.ID, TransferTarget{Login: "cat"}); !errors.Is(err, apperror.ErrConflict) {
+			t.Errorf("Offer(second) error = %v, want conflict", err)
+		}
+
+		list, err := transfers.List(ctx, "bob-id")
+		if err != nil || len(list.Incoming) != 1 || len(list.Outgoing) != 0 {
+			t.Errorf("List(bob) = %+v, %v; want one incoming", list, err)
+		}
+		if _, err := transfers.Accept(ctx, "cat-id", offer.ID); !errors.Is(err, apperror.ErrNotFound) {
+			t.Errorf("Accept(stranger) error = %v, want not found", err)
+		}
+		if _, err := transfers.Accept(ctx, "ann-id", offer.ID); !errors.Is(err, apperror.ErrForbidden) {
+			t.Errorf("Accept(sender) error = %v, want forbidden", err)
+		}
+		moved, err := transfers.Accept(ctx, "bob-id", offer.ID)
+		if err != nil {
+			t.Fatalf("Accept() error = %v", err)
+		}
+		// Same ID, still public, same version: links and history carry over.
+		if moved.ID != loop.ID || moved.UserID != "bob-id" || !moved.Public || moved.Version != loop.Version {
+			t.Errorf("Accept() = %+v, want loop owned by bob, still public", moved)
+		}
+		if _, err := transfers.Accept(ctx, "bob-id", offer.ID); !errors.Is(err, apperror.ErrNotFound) {
+			t.Errorf("Accept(again) error = %v, want not found", err)
+		}
+	})
+
+	t.Run("to an org", func(t *testing.T) {
+		org, err := orgs.Create(ctx, "cat-id", "Data team")
+		if err != nil {
+			t.Fatalf("Create(org) error = %v", err)
+		}
+		offer, err := transfers.Offer(ctx, "bob-id", loop.ID, TransferTarget{OrgID: org.ID})
+		if err != nil {
+			t.Fatalf("Offer(org) error = %v", err)
+		}
+		if list, _ := transfers.List(ctx, "cat-id"); len(list.Incoming) != 1 {
+			t.Errorf("List(org owner).Incoming = %v, want the offer", list.Incoming)
+		}
+		moved, err := transfers.Accept(ctx, "cat-id", offer.ID)
+		if err != nil {
+			t.Fatalf("Accept(org) error = %v", err)
+		}
+		if moved.OrgID != org.ID || moved.UserID != "bob-id" {
+			t.Errorf("Accept(org) = %+v, want in the org, created by bob", moved)
+		}
+
+		// Only the org's owners can give its snippets away.
+		if _, err := orgs.AddMember(ctx, "cat-id", org.ID, "ann", ""); err != nil {
+			t.Fatalf("AddMember() error = %v", err)
+		}
+		if _, err := transfers.Offer(ctx, "ann-id", loop.ID, TransferTarget{Login: "ann"}); !errors.Is(err, apperror.ErrForbidden) {
+			t.Errorf("Offer(org member) error = %v, want forbidden", err)
+		}
+		back, err := transfers.Offer(ctx, "cat-id", loop.ID, TransferTarget{Login: "ann"})
+		if err != nil {
+			t.Fatalf("Offer(org owner) error = %v", err)
+		}
+		if err := transfers.Delete(ctx, "bob-id", back.ID); !errors.Is(err, apperror.ErrNotFound) {
+			t.Errorf("Delete(stranger) error = %v, want not found", err)
+		}
+		if err := transfers.Delete(ctx, "ann-id", back.ID); err != nil {
+			t.Errorf("Delete(recipient declines) error = %v", err)
+		}
+		if list, _ := transfers.List(ctx, "cat-id"); len(list.Outgoing) != 0 {
+			t.Errorf("List(after decline).Outgoing = %v, want none", list.Outgoing)
+		}
+	})
+
+	t.Run("stale offer", func(t *testing.T) {
+		mine, err := snippets.Create(asAnn, "mine", "", "")
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		offer, err := transfers.Offer(ctx, "ann-id", mine.ID, TransferTarget{Login: "bob"})
+		if err != nil {
+			t.Fatalf("Offer() error = %v", err)
+		}
+		// The snippet moves some other way first.
+		if err := db.CompleteTransfer(ctx, &model.SnippetTransfer{SnippetID: mine.ID, FromUserID: "ann-id"}, "cat-id", ""); err != nil {
+			t.Fatalf("CompleteTransfer() error = %v", err)
+		}
+		if _, err := transfers.Accept(ctx, "bob-id", offer.ID); !errors.Is(err, apperror.ErrConflict) {
+			t.Errorf("Accept(stale) error = %v, want conflict", err)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// UserService handles user administration: changing roles and deleting accounts.
+// It's used by the admin CLI (cmd/admin), so operators never need raw SQL.
+type UserService struct {
+	users  repository.UserRepository
+	logger *slog.Logger
+}
+
+// NewUserService creates a UserService.
+func NewUserService(users repository.UserRepository, logger *slog.Logger) *UserService {
+	return &UserService{
+		users:  users,
+		logger: logger,
+	}
+}
+
+// GetByLogin looks a user up by GitHub login.
+func (s *UserService) GetByLogin(ctx context.Context, login string) (*model.User, error) {
+	user, err := s.users.GetUserByLogin(ctx, login)
+	if err != nil {
+		return nil, fmt.Errorf("looking up user: %w", err)
+	}
+	if user == nil {
+		return nil, &apperror.AppError{
+			Err:     apperror.ErrNotFound,
+			Message: fmt.Sprintf("no user with login %q", login),
+		}
+	}
+	return user, nil
+}
+
+// GetByID looks a user up by internal ID.
+func (s *UserService) GetByID(ctx context.Context, id string) (*model.User, error) {
+	user, err := s.users.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("looking up user: %w", err)
+	}
+	if user == nil {
+		return nil, apperror.NotFound("user", id)
+	}
+	return user, nil
+}
+
+// SetRole changes the role of the user with the given login.
+func (s *UserService) SetRole(ctx context.Context, login, role string) (*model.User, error) {
+	if !slices.Contains(model.Roles, role) {
+		return nil, apperror.ValidationFailed("role", fmt.Sprintf("role must be one of %s", strings.Join(model.Roles, ", ")))
+	}
+
+	user, err := s.GetByLogin(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.users.SetUserRole(ctx, user.ID, role); err != nil {
+		return nil, fmt.Errorf("setting role: %w", err)
+	}
+	user.Role = role
+
+	s.logger.InfoContext(ctx, "user role changed",
+		slog.String("login", user.Login),
+		slog.String("role", role),
+	)
+	return user, nil
+}
+
+// SetSuspended suspends the user with the given login, or lifts their
+// suspension. A suspended user can still sign in, read and export their
+// data; the API refuses everything else (see auth.RejectSuspended).
+func (s *UserService) SetSuspended(ctx context.Context, login string, suspended bool) (*model.User, error) {
+	user, err := s.GetByLogin(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.users.SetUserSuspended(ctx, user.ID, suspended); err != nil {
+		return nil, fmt.Errorf("setting suspension: %w", err)
+	}
+	user.Suspended = suspended
+
+	s.logger.InfoContext(ctx, "user suspension changed",
+		slog.String("login", user.Login),
+		slog.Bool("suspended", suspended),
+	)
+	return user, nil
+}
+
+// Delete removes the user with the given login, along with their snippets.
+func (s *UserService) Delete(ctx context.Context, login string) error {
+	user, err := s.GetByLogin(ctx, login)
+	if err != nil {
+		return err
+	}
+
+	if err := s.users.DeleteUser(ctx, user.ID); err != nil {
+		return fmt.Errorf("deleting user: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "user deleted", slog.String("login", user.Login), slog.String("id", user.ID))
+	return nil
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/model"
+)
+
+// mockUserRepo is an in-memory repository.UserRepository keyed by user ID.
+type mockUserRepo struct {
+	users map[string]*model.User
+}
+
+func newMockUserRepo(users ...*model.User) *mockUserRepo {
+	m := &mockUserRepo{users: make(map[string]*model.User)}
+	for _, u := range users {
+		m.users[u.ID] = u
+	}
+	return m
+}
+
+func (m *mockUserRepo) Upsert(_ context.Context, user *model.User) error {
+	m.users[user.ID] = user
+	return nil
+}
+
+func (m *mockUserRepo) GetUserByID(_ context.Context, id string) (*model.User, error) {
+	return m.users[id], nil
+}
+
+func (m *mockUserRepo) GetUserByLogin(_ context.Context, login string) (*model.User, error) {
+	for _, u := range m.users {
+		if strings.EqualFold(u.Login, login) {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockUserRepo) GetUserByEmail(_ context.Context, email string) (*model.User, error) {
+	for _, u := range m.users {
+		if strings.EqualFold(u.Email, email) {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockUserRepo) SetUserRole(_ context.Context, id, role string) error {
+	m.users[id].Role = role
+	return nil
+}
+
+func (m *mockUserRepo) SetLeaderboardOptOut(_ context.Context, id string, optOut bool) error {
+	m.users[id].LeaderboardOptOut = optOut
+	return nil
+}
+
+func (m *mockUserRepo) SetUserSuspended(_ context.Context, id string, suspended bool) error {
+	m.users[id].Suspended = suspended
+	return nil
+}
+
+func (m *mockUserRepo) DeleteUser(_ context.Context, id string) error {
+	delete(m.users, id)
+	return nil
+}
+
+func (m *mockUserRepo) CountUsers(_ context.Context, createdAfter time.Time) (int, error) {
+	n := 0
+	for _, u := range m.users {
+		if u.CreatedAt.After(createdAfter) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func newTestUserService(repo *mockUserRepo) *UserService {
+	return NewUserService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestUserService_SetRole(t *testing.T) {
+	repo := newMockUserRepo(&model.User{ID: "u1", Login: "Octocat", Role: model.RoleUser})
+	svc := newTestUserService(repo)
+
+	user, err := svc.SetRole(context.Background(), "octocat", model.RoleAdmin)
+	if err != nil {
+		t.Fatalf("SetRole() error = %v", err)
+	}
+	if user.Role != model.RoleAdmin || repo.users["u1"].Role != model.RoleAdmin {
+		t.Errorf("role = %q, want admin", repo.users["u1"].Role)
+	}
+
+	if _, err := svc.SetRole(context.Background(), "octocat", "superuser"); !errors.Is(err, apperror.ErrValidation) {
+		t.Errorf("invalid role: error = %v, want ErrValidation", err)
+	}
+	if _, err := svc.SetRole(context.Background(), "ghost", model.RoleAdmin); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("unknown login: error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestUserService_SetSuspended(t *testing.T) {
+	repo := newMockUserRepo(&model.User{ID: "u1", Login: "octocat"})
+	svc := newTestUserService(repo)
+
+	user, err := svc.SetSuspended(context.Background(), "Octocat", true)
+	if err != nil {
+		t.Fatalf("SetSuspended() error = %v", err)
+	}
+	if !user.Suspended || !repo.users["u1"].Suspended {
+		t.Error("user not suspended")
+	}
+	if _, err := svc.SetSuspended(context.Background(), "octocat", false); err != nil || repo.users["u1"].Suspended {
+		t.Errorf("lifting suspension: error = %v, suspended = %v", err, repo.users["u1"].Suspended)
+	}
+	if _, err := svc.SetSuspended(context.Background(), "ghost", true); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("unknown login: error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestUserService_Delete(t *testing.T) {
+	repo := newMockUserRepo(&model.User{ID: "u1", Login: "octocat"})
+	svc := newTestUserService(repo)
+
+	if err := svc.Delete(context.Background(), "octocat"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if len(repo.users) != 0 {
+		t.Errorf("user not deleted: %v", repo.users)
+	}
+	if err := svc.Delete(context.Background(), "octocat"); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("second Delete() error = %v, want ErrNotFound", err)
+	}
+}
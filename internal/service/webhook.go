@@ -0,0 +1,471 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/jobs"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository"
+)
+
+// WEBHOOKS:
+// A signed-in user registers a URL and the events it wants:
+//
+//	POST /api/v1/webhooks {"url": "https://example.com/hook", "events": ["snippet.created"]}
+//
+// When one of those events happens because of something that user did, we
+// POST a JSON envelope to the URL:
+//
+//	{"id": "<event id>", "event": "snippet.created", "createdAt": "...", "data": {...snippet...}}
+//
+// DELIVERY:
+// Publish doesn't call the URL itself — a slow or dead endpoint must not slow
+// down the request that caused the event. It enqueues one "webhook.deliver"
+// job per webhook, and the job queue (internal/jobs) does the HTTP call,
+// retrying with backoff when the endpoint is down or answers non-2xx. Every
+// attempt is written to the delivery log (GET /api/v1/webhooks/{id}/deliveries).
+//
+// Retries mean an endpoint can see the same event more than once; the "id"
+// field (also sent as X-Webhook-Id) stays the same across retries, so
+// receivers can de-duplicate.
+//
+// SIGNATURES:
+// Anyone can POST to a public URL, so each delivery is signed with the
+// webhook's secret (shown once, when the webhook is created):
+//
+//	X-Webhook-Timestamp: 1760000000
+//	X-Webhook-Signature: sha256=<hex HMAC-SHA256 of "1760000000." + body>
+//
+// The receiver recomputes the HMAC with its copy of the secret and compares
+// (with a constant-time compare). Signing the timestamp too lets it reject
+// old deliveries replayed by an attacker.
+//
+// PRIVATE ADDRESSES:
+// The server POSTs wherever a user points it, and the delivery log shows
+// what came back, so an unchecked URL would let anyone probe the server's
+// own network: http://127.0.0.1:6379, http://169.254.169.254/ (the cloud
+// metadata service), a 10.x admin panel. Loopback, private, link-local,
+// unspecified and multicast addresses, and the shared and reserved ranges
+// in nonPublicPrefixes (carrier-grade NAT, benchmarking and the like), are
+// refused twice: by Create, for URLs that name one, and by the client's
+// dialer, for hostnames that resolve to one — checked on the address
+// actually dialled, so a name that resolves somewhere harmless at Create
+// and to 127.0.0.1 later (DNS rebinding) is still caught.
+
+const (
+	// JobWebhookDelivery is the job kind that delivers one event to one webhook.
+	JobWebhookDelivery = "webhook.deliver"
+
+	// MaxWebhooksPerUser caps how many webhooks one user can register.
+	MaxWebhooksPerUser = 10
+
+	DefaultDeliveryLimit = 20
+	MaxDeliveryLimit     = 100
+
+	// webhookTimeout bounds one delivery attempt.
+	webhookTimeout = 10 * time.Second
+)
+
+// Webhook request headers.
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookIDHeader        = "X-Webhook-Id"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// EventPublisher is told about things that happened, e.g. a snippet being
+// created. Publishing is best effort: it never fails the caller.
+type EventPublisher interface {
+	Publish(ctx context.Context, event string, data any)
+}
+
+// Publishers is an EventPublisher that tells each of its publishers in turn.
+type Publishers []EventPublisher
+
+func (p Publishers) Publish(ctx context.Context, event string, data any) {
+	for _, pub := range p {
+		pub.Publish(ctx, event, data)
+	}
+}
+
+// errWebhookAddress is why a delivery to a private address fails.
+var errWebhookAddress = errors.New("webhooks can't be delivered to loopback, private, link-local, multicast or reserved addresses")
+
+// WebhookService manages webhooks and delivers events to them.
+type WebhookService struct {
+	repo   repository.WebhookRepository
+	queue  *jobs.Queue
+	client *http.Client
+	logger *slog.Logger
+
+	// allowPrivate lets webhooks reach private addresses, for tests whose
+	// endpoints listen on 127.0.0.1.
+	allowPrivate bool
+}
+
+// NewWebhookService creates a WebhookService and registers its delivery job
+// on queue, so it must be called before queue.Start.
+func NewWebhookService(repo repository.WebhookRepository, queue *jobs.Queue, logger *slog.Logger) *WebhookService {
+	s := &WebhookService{
+		repo:   repo,
+		queue:  queue,
+		logger: logger,
+	}
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		// Control runs once the name is resolved, on each address tried.
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			if !s.allowPrivate && privateAddr(ip) {
+				return errWebhookAddress
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// No proxy: the dialer would check the proxy's address, not the
+	// webhook's.
+	transport.Proxy = nil
+	s.client = &http.Client{
+		Timeout:   webhookTimeout,
+		Transport: transport,
+		// A redirect would re-send the payload somewhere the user didn't
+		// register; treat it as a failed delivery instead.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	queue.Register(JobWebhookDelivery, s.deliver)
+	return s
+}
+
+// Create registers a webhook for userID. The returned webhook carries the
+// signing secret; it can't be retrieved again later.
+func (s *WebhookService) Create(ctx context.Context, userID, rawURL string, events []string) (*model.Webhook, error) {
+	var verrs apperror.ValidationErrors
+	rawURL = strings.TrimSpace(rawURL)
+	if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		verrs.Add("url", "url must be an absolute http or https URL")
+	} else if !s.allowPrivate && privateHost(u.Hostname()) {
+		verrs.Add("url", "url must not point at a loopback, private, link-local, multicast or reserved address")
+	}
+	events = slices.Compact(slices.Sorted(slices.Values(events)))
+	if len(events) == 0 {
+		verrs.Add("events", "at least one event is required")
+	}
+	for _, e := range events {
+		if !slices.Contains(model.WebhookEvents, e) {
+			verrs.Add("events", fmt.Sprintf("unknown event %q (known: %s)", e, strings.Join(model.WebhookEvents, ", ")))
+		}
+	}
+	if err := verrs.Err(); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.ListWebhooks(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhooks: %w", err)
+	}
+	if len(existing) >= MaxWebhooksPerUser {
+		return nil, apperror.ValidationFailed("url", fmt.Sprintf("a user can have at most %d webhooks", MaxWebhooksPerUser))
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	webhook := &model.Webhook{
+		UserID: userID,
+		URL:    rawURL,
+		Secret: secret,
+		Events: events,
+	}
+	if err := s.repo.CreateWebhook(ctx, webhook); err != nil {
+		return nil, fmt.Errorf("creating webhook: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "webhook created",
+		slog.String("id", webhook.ID),
+		slog.String("user_id", userID),
+		slog.String("events", strings.Join(events, ",")),
+	)
+	return webhook, nil
+}
+
+// List returns userID's webhooks.
+func (s *WebhookService) List(ctx context.Context, userID string) ([]model.Webhook, error) {
+	return s.repo.ListWebhooks(ctx, userID)
+}
+
+// Get returns one of userID's webhooks. Someone else's webhook is reported as
+// not found, so IDs can't be probed.
+func (s *WebhookService) Get(ctx context.Context, userID, id string) (*model.Webhook, error) {
+	webhook, err := s.repo.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if webhook.UserID != userID {
+		return nil, apperror.NotFound("webhook", id)
+	}
+	return webhook, nil
+}
+
+// Delete removes one of userID's webhooks and its delivery log. Deliveries
+// already queued are dropped when they run.
+func (s *WebhookService) Delete(ctx context.Context, userID, id string) error {
+	if _, err := s.Get(ctx, userID, id); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteWebhook(ctx, id); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "webhook deleted", slog.String("id", id))
+	return nil
+}
+
+// Deliveries returns the most recent delivery attempts for one of userID's
+// webhooks. limit is clamped like snippet list limits.
+func (s *WebhookService) Deliveries(ctx context.Context, userID, id string, limit int) ([]model.WebhookDelivery, error) {
+	if _, err := s.Get(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultDeliveryLimit
+	}
+	limit = min(limit, MaxDeliveryLimit)
+	return s.repo.ListWebhookDeliveries(ctx, id, limit)
+}
+
+// webhookEnvelope is the JSON body POSTed to webhook URLs.
+type webhookEnvelope struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      any       `json:"data"`
+}
+
+// webhookJob is the payload of a JobWebhookDelivery job. The body is built
+// once at publish time, so every retry sends exactly the same bytes.
+type webhookJob struct {
+	WebhookID string          `json:"webhookId"`
+	EventID   string          `json:"eventId"`
+	Event     string          `json:"event"`
+	Body      json.RawMessage `json:"body"`
+}
+
+// Publish queues event for every webhook of the signed-in user (taken from
+// ctx) that subscribes to it. Anonymous actions have no webhooks to notify.
+func (s *WebhookService) Publish(ctx context.Context, event string, data any) {
+	userID, ok := auth.UserIDFromContext(ctx)
+	if !ok {
+		return
+	}
+	// The event has happened; a client hanging up now shouldn't lose it.
+	ctx = context.WithoutCancel(ctx)
+
+	webhooks, err := s.repo.ListWebhooksForEvent(ctx, userID, event)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to look up webhooks",
+			slog.String("event", event),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	envelope := webhookEnvelope{
+		ID:        xid.New().String(),
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to encode webhook event",
+			slog.String("event", event),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	for _, webhook := range webhooks {
+		_, err := s.queue.Enqueue(ctx, JobWebhookDelivery, webhookJob{
+			WebhookID: webhook.ID,
+			EventID:   envelope.ID,
+			Event:     event,
+			Body:      body,
+		})
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to queue webhook delivery",
+				slog.String("webhook_id", webhook.ID),
+				slog.String("event", event),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
+
+// deliver is the JobWebhookDelivery handler: one attempt to POST an event.
+// Returning an error makes the queue retry later.
+func (s *WebhookService) deliver(ctx context.Context, job *jobs.Job) error {
+	var p webhookJob
+	if err := job.Decode(&p); err != nil {
+		return err
+	}
+
+	webhook, err := s.repo.GetWebhook(ctx, p.WebhookID)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil // deleted since the event was published
+	}
+	if err != nil {
+		return err
+	}
+
+	timestamp := time.Now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(p.Body))
+	if err != nil {
+		return jobs.Permanent(fmt.Errorf("building webhook request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "coding-playground-webhooks/1")
+	req.Header.Set(WebhookEventHeader, p.Event)
+	req.Header.Set(WebhookIDHeader, p.EventID)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(webhook.Secret, timestamp, p.Body))
+
+	delivery := model.WebhookDelivery{
+		WebhookID: webhook.ID,
+		EventID:   p.EventID,
+		Event:     p.Event,
+		Attempt:   job.Attempts,
+	}
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	delivery.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		delivery.Error = err.Error()
+	} else {
+		// Drain a little of the body so the connection can be reused.
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		delivery.StatusCode = resp.StatusCode
+		if !delivery.Succeeded() {
+			delivery.Error = "unexpected status " + resp.Status
+		}
+	}
+
+	if err := s.repo.RecordWebhookDelivery(context.WithoutCancel(ctx), &delivery); err != nil {
+		s.logger.ErrorContext(ctx, "failed to record webhook delivery",
+			slog.String("webhook_id", webhook.ID),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	if errors.Is(err, errWebhookAddress) {
+		// Retrying won't make the address public.
+		return jobs.Permanent(fmt.Errorf("delivering %s to webhook %s: %w", p.Event, webhook.ID, err))
+	}
+	if delivery.Error != "" {
+		return fmt.Errorf("delivering %s to webhook %s: %s", p.Event, webhook.ID, delivery.Error)
+	}
+	return nil
+}
+
+// privateHost reports whether a URL's host is an address, or a name, that
+// webhooks mustn't reach. Other names are checked when they're dialled.
+func privateHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip, err := netip.ParseAddr(host)
+	return err == nil && privateAddr(ip)
+}
+
+// nonPublicPrefixes are the ranges netip's Is* methods don't cover that
+// are still no place for a webhook: shared, reserved or special-purpose
+// space (RFC 6890) a cloud or carrier may route internally.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT (RFC 6598)
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // TEST-NET-1
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking (RFC 2544)
+	netip.MustParsePrefix("198.51.100.0/24"), // TEST-NET-2
+	netip.MustParsePrefix("203.0.113.0/24"),  // TEST-NET-3
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, which can reach IPv4 inside
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local-use NAT64
+	netip.MustParsePrefix("100::/64"),        // discard-only
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+}
+
+// privateAddr reports whether ip is loopback, private, link-local,
+// unspecified, multicast or in nonPublicPrefixes — anything but a public
+// unicast address. Create and the delivery dialler both ask it.
+func privateAddr(ip netip.Addr) bool {
+	ip = ip.Unmap() // ::ffff:127.0.0.1 is 127.0.0.1
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsUnspecified() || ip.IsMulticast() || !ip.IsValid() {
+		return true
+	}
+	for _, p := range nonPublicPrefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// SignWebhook returns the X-Webhook-Signature value for a delivery: the
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook's secret.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newWebhookSecret returns a random secret, prefixed so it's recognisable
+// (e.g. to secret scanners) if it leaks.
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
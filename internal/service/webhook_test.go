@@ -0,0 +1,348 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sakif/coding-playground/internal/apperror"
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/jobs"
+	"github.com/sakif/coding-playground/internal/model"
+	"github.com/sakif/coding-playground/internal/repository/sqlite"
+)
+
+// newWebhookTestService returns a WebhookService backed by a real SQLite
+// database and a running job queue with near-zero retry backoff.
+func newWebhookTestService(t *testing.T) (*WebhookService, *sqlite.DB) {
+	t.Helper()
+	db, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening db: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	queue := jobs.New(db, logger, jobs.Options{
+		PollInterval: 10 * time.Millisecond,
+		MaxAttempts:  3,
+		BaseBackoff:  time.Millisecond,
+		MaxBackoff:   time.Millisecond,
+	})
+
+	svc := NewWebhookService(db, queue, logger)
+	svc.allowPrivate = true // the endpoints are httptest servers on 127.0.0.1
+	if err := queue.Start(context.Background()); err != nil {
+		t.Fatalf("starting queue: %v", err)
+	}
+	t.Cleanup(func() {
+		queue.Shutdown(context.Background())
+		db.Close()
+	})
+	return svc, db
+}
+
+// receiver is a webhook endpoint that answers with the queued statuses in
+// order (200 once they run out) and records what it received.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (rc *receiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWebhookService_CreateValidation(t *testing.T) {
+	svc, _ := newWebhookTestService(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		url    string
+		events []string
+		fields []string
+	}{
+		{"relative url", "/hook", []string{model.EventSnippetCreated}, []string{"url"}},
+		{"ftp url", "ftp://example.com/hook", []string{model.EventSnippetCreated}, []string{"url"}},
+		{"no events", "https://example.com/hook", nil, []string{"events"}},
+		{"unknown event", "https://example.com/hook", []string{"snippet.deleted"}, []string{"events"}},
+		{"everything wrong", "nope", nil, []string{"url", "events"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Create(ctx, "user-1", tt.url, tt.events)
+			var verrs *apperror.ValidationErrors
+			if !errors.As(err, &verrs) {
+				t.Fatalf("err = %v, want validation errors", err)
+			}
+			if len(verrs.Fields) != len(tt.fields) {
+				t.Fatalf("fields = %+v, want %v", verrs.Fields, tt.fields)
+			}
+			for i, f := range verrs.Fields {
+				if f.Field != tt.fields[i] {
+					t.Errorf("field %d = %q, want %q", i, f.Field, tt.fields[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookService_RefusesPrivateAddresses(t *testing.T) {
+	svc, _ := newWebhookTestService(t)
+	svc.allowPrivate = false
+	ctx := context.Background()
+
+	for _, u := range []string{
+		"http://127.0.0.1:6379/",
+		"http://[::1]/hook",
+		"http://10.1.2.3/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://192.168.0.1/",
+		"http://0.0.0.0:8080/",
+		"http://[::ffff:127.0.0.1]/",
+		"http://localhost:8080/",
+		"http://100.64.0.1/",
+		"http://198.18.0.1/",
+	} {
+		_, err := svc.Create(ctx, "user-1", u, []string{model.EventSnippetCreated})
+		var verrs *apperror.ValidationErrors
+		if !errors.As(err, &verrs) || verrs.Fields[0].Field != "url" {
+			t.Errorf("Create(%s) error = %v, want the url refused", u, err)
+		}
+	}
+	if _, err := svc.Create(ctx, "user-1", "https://example.com/hook", []string{model.EventSnippetCreated}); err != nil {
+		t.Errorf("Create(public URL) error = %v", err)
+	}
+}
+
+func TestPrivateAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		private bool
+	}{
+		{"127.0.0.1", true},
+		{"10.0.0.1", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"0.0.0.0", true},
+		{"0.1.2.3", true},         // 0.0.0.0/8
+		{"100.64.0.1", true},      // carrier-grade NAT
+		{"100.127.255.254", true}, // ...to the end of 100.64.0.0/10
+		{"192.0.0.8", true},       // IETF protocol assignments
+		{"198.18.0.1", true},      // benchmarking
+		{"198.19.255.255", true},  // ...to the end of 198.18.0.0/15
+		{"203.0.113.7", true},     // documentation
+		{"255.255.255.255", true}, // broadcast
+		{"224.0.0.1", true},       // multicast
+		{"::1", true},
+		{"fe80::1", true},
+		{"fc00::1", true},
+		{"64:ff9b::a9fe:a9fe", true}, // NAT64 of 169.254.169.254
+		{"2001:db8::1", true},
+		{"::ffff:100.64.0.1", true},
+		{"100.63.255.255", false},
+		{"100.128.0.0", false},
+		{"192.0.1.1", false},
+		{"198.20.0.1", false},
+		{"1.1.1.1", false},
+		{"93.184.216.34", false},
+		{"2606:4700:4700::1111", false},
+	}
+	for _, tt := range tests {
+		if got := privateAddr(netip.MustParseAddr(tt.addr)); got != tt.private {
+			t.Errorf("privateAddr(%s) = %v, want %v", tt.addr, got, tt.private)
+		}
+	}
+}
+
+func TestWebhookService_ChecksAddressWhenDialling(t *testing.T) {
+	svc, _ := newWebhookTestService(t)
+	rc := &receiver{}
+	endpoint := httptest.NewServer(rc)
+	defer endpoint.Close()
+
+	ctx := auth.WithUserID(context.Background(), "user-1")
+	webhook, err := svc.Create(ctx, "user-1", endpoint.URL, []string{model.EventSnippetCreated})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	// As if the name had passed at Create and then been rebound to 127.0.0.1.
+	svc.allowPrivate = false
+
+	svc.Publish(ctx, model.EventSnippetCreated, &model.Snippet{ID: "s1"})
+	var deliveries []model.WebhookDelivery
+	waitUntil(t, func() bool {
+		deliveries, _ = svc.Deliveries(ctx, "user-1", webhook.ID, 0)
+		return len(deliveries) > 0
+	})
+	time.Sleep(50 * time.Millisecond) // long enough for a retry, if there were one
+
+	if n := rc.count(); n != 0 {
+		t.Errorf("receiver got %d requests, want none", n)
+	}
+	deliveries, _ = svc.Deliveries(ctx, "user-1", webhook.ID, 0)
+	if len(deliveries) != 1 || !strings.Contains(deliveries[0].Error, "private") {
+		t.Errorf("deliveries = %+v, want one refused attempt, not retried", deliveries)
+	}
+}
+
+func TestWebhookService_OtherUsersWebhooksAreHidden(t *testing.T) {
+	svc, _ := newWebhookTestService(t)
+	ctx := context.Background()
+
+	webhook, err := svc.Create(ctx, "owner", "https://example.com/hook", []string{model.EventSnippetCreated})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if _, err := svc.Get(ctx, "someone-else", webhook.ID); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("Get by another user: err = %v, want not found", err)
+	}
+	if err := svc.Delete(ctx, "someone-else", webhook.ID); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("Delete by another user: err = %v, want not found", err)
+	}
+	if list, _ := svc.List(ctx, "someone-else"); len(list) != 0 {
+		t.Errorf("List by another user = %d webhooks, want 0", len(list))
+	}
+}
+
+func TestWebhookService_DeliversSignedEvent(t *testing.T) {
+	svc, _ := newWebhookTestService(t)
+	rc := &receiver{}
+	endpoint := httptest.NewServer(rc)
+	defer endpoint.Close()
+
+	ctx := auth.WithUserID(context.Background(), "user-1")
+	webhook, err := svc.Create(ctx, "user-1", endpoint.URL, []string{model.EventSnippetCreated})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	svc.Publish(ctx, model.EventSnippetUpdated, nil) // not subscribed
+	svc.Publish(ctx, model.EventSnippetCreated, &model.Snippet{ID: "s1", Name: "hello"})
+	waitUntil(t, func() bool { return rc.count() == 1 })
+
+	req, body := rc.requests[0], rc.bodies[0]
+	if got := req.Header.Get(WebhookEventHeader); got != model.EventSnippetCreated {
+		t.Errorf("%s = %q", WebhookEventHeader, got)
+	}
+	ts, _ := strconv.ParseInt(req.Header.Get(WebhookTimestampHeader), 10, 64)
+	if got, want := req.Header.Get(WebhookSignatureHeader), SignWebhook(webhook.Secret, ts, body); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+
+	var envelope struct {
+		ID    string        `json:"id"`
+		Event string        `json:"event"`
+		Data  model.Snippet `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if envelope.Event != model.EventSnippetCreated || envelope.Data.ID != "s1" {
+		t.Errorf("envelope = %+v", envelope)
+	}
+	if envelope.ID != req.Header.Get(WebhookIDHeader) {
+		t.Errorf("envelope id %q != %s header %q", envelope.ID, WebhookIDHeader, req.Header.Get(WebhookIDHeader))
+	}
+
+	var deliveries []model.WebhookDelivery
+	waitUntil(t, func() bool {
+		deliveries, _ = svc.Deliveries(ctx, "user-1", webhook.ID, 0)
+		return len(deliveries) == 1
+	})
+	if !deliveries[0].Succeeded() || deliveries[0].Attempt != 1 {
+		t.Errorf("delivery = %+v, want a successful first attempt", deliveries[0])
+	}
+}
+
+func TestWebhookService_RetriesFailedDelivery(t *testing.T) {
+	svc, _ := newWebhookTestService(t)
+	rc := &receiver{statuses: []int{http.StatusInternalServerError}}
+	endpoint := httptest.NewServer(rc)
+	defer endpoint.Close()
+
+	ctx := auth.WithUserID(context.Background(), "user-1")
+	webhook, err := svc.Create(ctx, "user-1", endpoint.URL, []string{model.EventExecutionCompleted})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	svc.Publish(ctx, model.EventExecutionCompleted, map[string]int{"exitCode": 0})
+
+	var deliveries []model.WebhookDelivery
+	waitUntil(t, func() bool {
+		deliveries, _ = svc.Deliveries(ctx, "user-1", webhook.ID, 0)
+		return len(deliveries) == 2
+	})
+	// Newest first: the retry succeeded after the first attempt got a 500.
+	if !deliveries[0].Succeeded() || deliveries[0].Attempt != 2 {
+		t.Errorf("latest delivery = %+v, want a successful second attempt", deliveries[0])
+	}
+	if deliveries[1].StatusCode != http.StatusInternalServerError || deliveries[1].Error == "" {
+		t.Errorf("first delivery = %+v, want a recorded 500", deliveries[1])
+	}
+	if deliveries[0].EventID != deliveries[1].EventID {
+		t.Error("retries should keep the same event ID")
+	}
+	if rc.bodies[0] == nil || string(rc.bodies[0]) != string(rc.bodies[1]) {
+		t.Error("retries should send the same body")
+	}
+}
+
+func TestWebhookService_AnonymousActionsPublishNothing(t *testing.T) {
+	svc, _ := newWebhookTestService(t)
+	rc := &receiver{}
+	endpoint := httptest.NewServer(rc)
+	defer endpoint.Close()
+
+	if _, err := svc.Create(context.Background(), "user-1", endpoint.URL, []string{model.EventSnippetCreated}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	svc.Publish(context.Background(), model.EventSnippetCreated, &model.Snippet{ID: "s1"})
+	time.Sleep(50 * time.Millisecond)
+
+	if n := rc.count(); n != 0 {
+		t.Errorf("receiver got %d requests, want 0", n)
+	}
+}
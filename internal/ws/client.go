@@ -0,0 +1,153 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// Client is one WebSocket connection.
+type Client struct {
+	id     string
+	userID string
+	hub    *Hub
+	conn   *websocket.Conn
+	send   chan []byte
+	cancel context.CancelFunc
+
+	topics map[string]struct{} // guarded by hub.mu
+
+	closeOnce sync.Once
+}
+
+// ID identifies the connection in logs and in the "ready" message.
+func (c *Client) ID() string { return c.id }
+
+// UserID is the signed-in user, or "" for an anonymous connection.
+func (c *Client) UserID() string { return c.userID }
+
+// Subscribe subscribes the client to topic on the server's initiative, e.g.
+// when a MessageHandler admits it to something. The topic's Authorizer
+// still decides.
+func (c *Client) Subscribe(ctx context.Context, topic string) error {
+	return c.hub.subscribe(ctx, c, topic)
+}
+
+// Send queues a message for this client only, e.g. a reply from a
+// MessageHandler. Like Publish, it never blocks.
+func (c *Client) Send(msg Message) {
+	c.reply(msg)
+}
+
+func (c *Client) reply(msg Message) {
+	frame, err := json.Marshal(msg)
+	if err != nil {
+		c.hub.logger.Error("failed to encode websocket message", slog.String("error", err.Error()))
+		return
+	}
+	c.enqueue(frame)
+}
+
+// enqueue adds a frame to the send buffer, disconnecting the client if the
+// buffer is full.
+func (c *Client) enqueue(frame []byte) {
+	select {
+	case c.send <- frame:
+	default:
+		c.hub.logger.Warn("websocket client too slow, disconnecting", slog.String("client", c.id))
+		c.abort()
+	}
+}
+
+// abort drops the connection without a closing handshake. A client that
+// can't keep up wouldn't read the close frame in time either.
+func (c *Client) abort() {
+	c.closeOnce.Do(func() {
+		c.cancel()
+		c.conn.CloseNow()
+	})
+}
+
+// close starts the closing handshake once. It doesn't wait for it: Close
+// can block for seconds on an unresponsive peer, and callers may hold hub.mu.
+func (c *Client) close(status websocket.StatusCode, reason string) {
+	c.closeOnce.Do(func() {
+		go func() {
+			c.conn.Close(status, reason)
+			c.cancel()
+		}()
+	})
+}
+
+// readLoop handles incoming messages until the connection ends.
+func (c *Client) readLoop(ctx context.Context) error {
+	for {
+		typ, data, err := c.conn.Read(ctx)
+		if err != nil {
+			return err
+		}
+		if typ != websocket.MessageText {
+			c.reply(Message{Type: TypeError, Error: "messages must be JSON text"})
+			continue
+		}
+
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.reply(Message{Type: TypeError, Error: "invalid JSON message"})
+			continue
+		}
+
+		switch msg.Type {
+		case TypeSubscribe:
+			if err := c.hub.subscribe(ctx, c, msg.Topic); err != nil {
+				c.reply(Message{Type: TypeError, Topic: msg.Topic, ID: msg.ID, Error: err.Error()})
+				continue
+			}
+			c.reply(Message{Type: TypeSubscribed, Topic: msg.Topic, ID: msg.ID})
+		case TypeUnsubscribe:
+			c.hub.unsubscribe(c, msg.Topic)
+			c.reply(Message{Type: TypeUnsubscribed, Topic: msg.Topic, ID: msg.ID})
+		default:
+			if fn := c.hub.handlerFor(msg.Type); fn != nil {
+				fn(ctx, c, msg)
+				continue
+			}
+			c.reply(Message{Type: TypeError, ID: msg.ID, Error: "unknown message type " + msg.Type})
+		}
+	}
+}
+
+// writeLoop sends queued messages and pings an otherwise quiet connection.
+// Only this goroutine writes to the connection.
+func (c *Client) writeLoop(ctx context.Context) {
+	ping := time.NewTicker(c.hub.opts.PingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case frame := <-c.send:
+			wctx, cancel := context.WithTimeout(ctx, c.hub.opts.WriteTimeout)
+			err := c.conn.Write(wctx, websocket.MessageText, frame)
+			cancel()
+			if err != nil {
+				c.abort()
+				return
+			}
+		case <-ping.C:
+			pctx, cancel := context.WithTimeout(ctx, c.hub.opts.PongTimeout)
+			err := c.conn.Ping(pctx)
+			cancel()
+			if err != nil {
+				c.hub.logger.Debug("websocket ping failed, disconnecting", slog.String("client", c.id))
+				c.abort()
+				return
+			}
+		}
+	}
+}
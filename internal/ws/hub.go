@@ -0,0 +1,419 @@
+// Package ws is the server's WebSocket hub: one long-lived connection per
+// browser tab, over which the server pushes events on topics the client has
+// subscribed to.
+//
+// WHY A HUB?
+// Execution output, collaborative editing and notifications all need the
+// server to push data as it happens. Rather than each feature running its own
+// WebSocket endpoint, they share this one:
+//
+//	features ──Publish("user:42", data)──▶ Hub ──▶ every client subscribed to "user:42"
+//
+// The hub keeps the registry of connections and who listens to what, checks
+// that a client may subscribe to a topic, keeps idle connections alive with
+// pings, and protects itself from clients that can't keep up.
+//
+// PROTOCOL:
+// Every frame is a JSON Message. Right after connecting the server says
+// who the client is:
+//
+//	← {"type":"ready","data":{"clientId":"c1","userId":"42"}}
+//
+// The client then subscribes (the optional id is echoed back, so the client
+// can match replies to requests):
+//
+//	→ {"type":"subscribe","topic":"user:42","id":"1"}
+//	← {"type":"subscribed","topic":"user:42","id":"1"}
+//	← {"type":"event","topic":"user:42","data":{...}}
+//	→ {"type":"unsubscribe","topic":"user:42"}
+//
+// Failures come back as {"type":"error","error":"...","id":"1"}. Features can
+// accept their own message types with HandleMessage.
+//
+// AUTHENTICATION:
+// The upgrade request is an ordinary HTTP request, so the browser sends the
+// session cookie with it; the route sits behind auth.OptionalAuth and the
+// hub reads the user from the request context. Anonymous connections are
+// allowed — each topic's Authorizer decides who may listen. Because cookies
+// are sent automatically, the upgrade also checks the Origin header so other
+// websites can't open a connection as the signed-in user.
+//
+// BACKPRESSURE:
+// Publish never waits for a client. Each connection has a small send buffer;
+// a client so slow that its buffer fills is disconnected (it can reconnect
+// and resubscribe) instead of making the publisher — and every other
+// subscriber — wait.
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/sakif/coding-playground/internal/auth"
+)
+
+// Message is one frame of the protocol, in either direction.
+type Message struct {
+	Type  string          `json:"type"`
+	Topic string          `json:"topic,omitempty"`
+	ID    string          `json:"id,omitempty"` // set by the client, echoed in replies
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+// Message types the hub itself handles or sends.
+const (
+	TypeReady        = "ready"
+	TypeSubscribe    = "subscribe"
+	TypeSubscribed   = "subscribed"
+	TypeUnsubscribe  = "unsubscribe"
+	TypeUnsubscribed = "unsubscribed"
+	TypeEvent        = "event"
+	TypeError        = "error"
+)
+
+// Options tunes a Hub. Zero values use the defaults below.
+type Options struct {
+	// SendBuffer is how many outgoing messages may queue per client before
+	// it's disconnected as too slow.
+	SendBuffer int
+	// PingInterval is how often idle connections are pinged; a client that
+	// doesn't answer within PongTimeout is disconnected.
+	PingInterval time.Duration
+	PongTimeout  time.Duration
+	// WriteTimeout bounds sending a single message.
+	WriteTimeout time.Duration
+	// MaxMessageBytes caps incoming frames.
+	MaxMessageBytes int64
+	// MaxTopics caps subscriptions per client.
+	MaxTopics int
+	// OriginPatterns lists other origins (host patterns such as
+	// "app.example.com") allowed to connect. The server's own origin always is.
+	OriginPatterns []string
+}
+
+// Defaults for Options.
+const (
+	DefaultSendBuffer      = 64
+	DefaultPingInterval    = 30 * time.Second
+	DefaultPongTimeout     = 10 * time.Second
+	DefaultWriteTimeout    = 10 * time.Second
+	DefaultMaxMessageBytes = 64 << 10
+	DefaultMaxTopics       = 100
+)
+
+func (o *Options) setDefaults() {
+	if o.SendBuffer <= 0 {
+		o.SendBuffer = DefaultSendBuffer
+	}
+	if o.PingInterval <= 0 {
+		o.PingInterval = DefaultPingInterval
+	}
+	if o.PongTimeout <= 0 {
+		o.PongTimeout = DefaultPongTimeout
+	}
+	if o.WriteTimeout <= 0 {
+		o.WriteTimeout = DefaultWriteTimeout
+	}
+	if o.MaxMessageBytes <= 0 {
+		o.MaxMessageBytes = DefaultMaxMessageBytes
+	}
+	if o.MaxTopics <= 0 {
+		o.MaxTopics = DefaultMaxTopics
+	}
+}
+
+// Authorizer decides whether a user ("" when anonymous) may subscribe to a
+// topic. A non-nil error refuses, and its message is shown to the client.
+type Authorizer func(ctx context.Context, userID, topic string) error
+
+// MessageHandler handles a client message of a type registered with
+// HandleMessage.
+type MessageHandler func(ctx context.Context, c *Client, msg Message)
+
+// Hub tracks connections and their topic subscriptions.
+type Hub struct {
+	opts   Options
+	logger *slog.Logger
+
+	mu       sync.RWMutex
+	clients  map[*Client]struct{}
+	topics   map[string]map[*Client]struct{}
+	closed   bool
+	prefixes map[string]Authorizer     // topic prefix → who may subscribe
+	handlers map[string]MessageHandler // custom message type → handler
+	onClose  []func(*Client)           // run after a client disconnects
+
+	nextID atomic.Uint64
+	wg     sync.WaitGroup // one per open connection
+}
+
+// NewHub creates an empty hub. Register topics with Authorize before serving.
+func NewHub(logger *slog.Logger, opts Options) *Hub {
+	opts.setDefaults()
+	return &Hub{
+		opts:     opts,
+		logger:   logger,
+		clients:  map[*Client]struct{}{},
+		topics:   map[string]map[*Client]struct{}{},
+		prefixes: map[string]Authorizer{},
+		handlers: map[string]MessageHandler{},
+	}
+}
+
+// Authorize makes topics starting with prefix (e.g. "user:") available,
+// checked by fn. Subscribing to a topic no prefix matches fails.
+func (h *Hub) Authorize(prefix string, fn Authorizer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.prefixes[prefix] = fn
+}
+
+// HandleMessage routes client messages of type msgType to fn. The built-in
+// types can't be overridden.
+func (h *Hub) HandleMessage(msgType string, fn MessageHandler) {
+	switch msgType {
+	case TypeSubscribe, TypeUnsubscribe:
+		panic("ws: can't override built-in message type " + msgType)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handlers[msgType] = fn
+}
+
+// OnDisconnect registers fn to run after each client disconnects, e.g. to
+// drop it from a feature's own bookkeeping. Register before serving.
+func (h *Hub) OnDisconnect(fn func(*Client)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onClose = append(h.onClose, fn)
+}
+
+// Publish sends data to every client subscribed to topic. It never blocks on
+// a client: ones whose send buffer is full are disconnected.
+func (h *Hub) Publish(topic string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("ws: encoding event: %w", err)
+	}
+	frame, err := json.Marshal(Message{Type: TypeEvent, Topic: topic, Data: payload})
+	if err != nil {
+		return fmt.Errorf("ws: encoding event: %w", err)
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for c := range h.topics[topic] {
+		c.enqueue(frame)
+	}
+	return nil
+}
+
+// Stats is a snapshot of the hub, for metrics.
+type Stats struct {
+	Connections   int
+	Topics        int
+	Subscriptions int
+}
+
+// Stats returns current connection and subscription counts.
+func (h *Hub) Stats() Stats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	s := Stats{Connections: len(h.clients), Topics: len(h.topics)}
+	for _, subs := range h.topics {
+		s.Subscriptions += len(subs)
+	}
+	return s
+}
+
+// Shutdown disconnects every client with "going away" and waits for their
+// connections to finish closing, or for ctx to end. New connections are
+// refused from the start. http.Server.Shutdown doesn't track upgraded
+// connections, so the server calls this itself.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closed = true
+	for c := range h.clients {
+		c.close(websocket.StatusGoingAway, "server shutting down")
+	}
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ServeHTTP upgrades the request to a WebSocket and serves it until either
+// side closes. Put auth.OptionalAuth in front so signed-in users are known.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	closed := h.closed
+	h.mu.RUnlock()
+	if closed {
+		http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	// The http.Server's read and write timeouts would otherwise carry over to
+	// the upgraded connection and cut it off after a few seconds.
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: h.opts.OriginPatterns})
+	if err != nil {
+		h.logger.DebugContext(r.Context(), "websocket upgrade failed", slog.String("error", err.Error()))
+		return // Accept has already responded
+	}
+	conn.SetReadLimit(h.opts.MaxMessageBytes)
+
+	userID, _ := auth.UserIDFromContext(r.Context())
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	c := &Client{
+		id:     "c" + strconv.FormatUint(h.nextID.Add(1), 10),
+		userID: userID,
+		hub:    h,
+		conn:   conn,
+		send:   make(chan []byte, h.opts.SendBuffer),
+		topics: map[string]struct{}{},
+		cancel: cancel,
+	}
+	if !h.register(c) {
+		cancel()
+		conn.Close(websocket.StatusGoingAway, "server shutting down")
+		return
+	}
+	defer h.wg.Done()
+	defer h.unregister(c)
+	defer cancel()
+
+	h.logger.DebugContext(ctx, "websocket connected", slog.String("client", c.id), slog.String("user_id", userID))
+
+	ready, _ := json.Marshal(map[string]string{"clientId": c.id, "userId": userID})
+	c.reply(Message{Type: TypeReady, Data: ready})
+
+	go c.writeLoop(ctx)
+	err = c.readLoop(ctx)
+
+	status := websocket.CloseStatus(err)
+	h.logger.DebugContext(ctx, "websocket disconnected",
+		slog.String("client", c.id),
+		slog.Int("status", int(status)),
+	)
+	if status == -1 {
+		c.abort() // not a clean close: a network error or a dropped client
+	}
+}
+
+func (h *Hub) register(c *Client) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return false
+	}
+	h.clients[c] = struct{}{}
+	h.wg.Add(1)
+	return true
+}
+
+func (h *Hub) unregister(c *Client) {
+	h.mu.Lock()
+	delete(h.clients, c)
+	for topic := range c.topics {
+		h.removeSubscriber(topic, c)
+	}
+	onClose := h.onClose
+	h.mu.Unlock()
+
+	for _, fn := range onClose {
+		fn(c)
+	}
+}
+
+// removeSubscriber drops c from topic. Callers hold h.mu.
+func (h *Hub) removeSubscriber(topic string, c *Client) {
+	subs := h.topics[topic]
+	delete(subs, c)
+	if len(subs) == 0 {
+		delete(h.topics, topic)
+	}
+}
+
+// subscribe checks and records c's subscription to topic.
+func (h *Hub) subscribe(ctx context.Context, c *Client, topic string) error {
+	authorize, err := h.authorizerFor(topic)
+	if err != nil {
+		return err
+	}
+	if err := authorize(ctx, c.userID, topic); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := c.topics[topic]; ok {
+		return nil
+	}
+	if len(c.topics) >= h.opts.MaxTopics {
+		return fmt.Errorf("at most %d subscriptions per connection", h.opts.MaxTopics)
+	}
+	c.topics[topic] = struct{}{}
+	if h.topics[topic] == nil {
+		h.topics[topic] = map[*Client]struct{}{}
+	}
+	h.topics[topic][c] = struct{}{}
+	return nil
+}
+
+func (h *Hub) unsubscribe(c *Client, topic string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(c.topics, topic)
+	h.removeSubscriber(topic, c)
+}
+
+// authorizerFor finds the Authorizer of the longest prefix matching topic.
+func (h *Hub) authorizerFor(topic string) (Authorizer, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var best string
+	var fn Authorizer
+	for prefix, f := range h.prefixes {
+		if strings.HasPrefix(topic, prefix) && len(prefix) >= len(best) {
+			best, fn = prefix, f
+		}
+	}
+	if fn == nil {
+		return nil, fmt.Errorf("unknown topic %q", topic)
+	}
+	return fn, nil
+}
+
+func (h *Hub) handlerFor(msgType string) MessageHandler {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.handlers[msgType]
+}
+
+// ErrForbidden is a convenient Authorizer refusal.
+var ErrForbidden = errors.New("not allowed to subscribe to this topic")
@@ -0,0 +1,207 @@
+package ws_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sakif/coding-playground/internal/auth"
+	"github.com/sakif/coding-playground/internal/ws"
+)
+
+// newHub serves a hub whose "user:" topics are readable only by their user.
+// The ?user= query parameter stands in for the auth middleware.
+func newHub(t *testing.T, opts ws.Options) (*ws.Hub, string) {
+	t.Helper()
+	hub := ws.NewHub(slog.New(slog.NewTextHandler(io.Discard, nil)), opts)
+	hub.Authorize("user:", func(_ context.Context, userID, topic string) error {
+		if topic != "user:"+userID {
+			return ws.ErrForbidden
+		}
+		return nil
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := r.URL.Query().Get("user"); id != "" {
+			r = r.WithContext(auth.WithUserID(r.Context(), id))
+		}
+		hub.ServeHTTP(w, r)
+	}))
+	t.Cleanup(func() {
+		hub.Shutdown(context.Background())
+		srv.Close()
+	})
+	return hub, "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func dial(t *testing.T, url string) *websocket.Conn {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, url, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.CloseNow() })
+	return conn
+}
+
+func send(t *testing.T, conn *websocket.Conn, msg ws.Message) {
+	t.Helper()
+	data, err := json.Marshal(msg)
+	require.NoError(t, err)
+	require.NoError(t, conn.Write(context.Background(), websocket.MessageText, data))
+}
+
+func recv(t *testing.T, conn *websocket.Conn) ws.Message {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, data, err := conn.Read(ctx)
+	require.NoError(t, err)
+	var msg ws.Message
+	require.NoError(t, json.Unmarshal(data, &msg))
+	return msg
+}
+
+func TestHub_SubscribeAndPublish(t *testing.T) {
+	hub, url := newHub(t, ws.Options{})
+	conn := dial(t, url+"?user=42")
+
+	ready := recv(t, conn)
+	assert.Equal(t, ws.TypeReady, ready.Type)
+	assert.JSONEq(t, `{"clientId":"c1","userId":"42"}`, string(ready.Data))
+
+	send(t, conn, ws.Message{Type: ws.TypeSubscribe, Topic: "user:42", ID: "1"})
+	assert.Equal(t, ws.Message{Type: ws.TypeSubscribed, Topic: "user:42", ID: "1"}, recv(t, conn))
+	assert.Equal(t, ws.Stats{Connections: 1, Topics: 1, Subscriptions: 1}, hub.Stats())
+
+	require.NoError(t, hub.Publish("user:42", map[string]string{"hello": "world"}))
+	require.NoError(t, hub.Publish("user:7", "not for this client"))
+	event := recv(t, conn)
+	assert.Equal(t, ws.TypeEvent, event.Type)
+	assert.Equal(t, "user:42", event.Topic)
+	assert.JSONEq(t, `{"hello":"world"}`, string(event.Data))
+
+	send(t, conn, ws.Message{Type: ws.TypeUnsubscribe, Topic: "user:42"})
+	assert.Equal(t, ws.TypeUnsubscribed, recv(t, conn).Type)
+	assert.Equal(t, 0, hub.Stats().Subscriptions)
+}
+
+func TestHub_RefusedSubscriptions(t *testing.T) {
+	_, url := newHub(t, ws.Options{MaxTopics: 1})
+	conn := dial(t, url) // anonymous
+	recv(t, conn)        // ready
+
+	send(t, conn, ws.Message{Type: ws.TypeSubscribe, Topic: "user:42", ID: "1"})
+	msg := recv(t, conn)
+	assert.Equal(t, ws.TypeError, msg.Type)
+	assert.Equal(t, "1", msg.ID)
+	assert.Equal(t, ws.ErrForbidden.Error(), msg.Error)
+
+	send(t, conn, ws.Message{Type: ws.TypeSubscribe, Topic: "secret:1"})
+	assert.Equal(t, `unknown topic "secret:1"`, recv(t, conn).Error)
+
+	send(t, conn, ws.Message{Type: "shout"})
+	assert.Equal(t, "unknown message type shout", recv(t, conn).Error)
+
+	require.NoError(t, conn.Write(context.Background(), websocket.MessageText, []byte("{")))
+	assert.Equal(t, "invalid JSON message", recv(t, conn).Error)
+}
+
+func TestHub_HandleMessage(t *testing.T) {
+	hub, url := newHub(t, ws.Options{})
+	hub.HandleMessage("echo", func(_ context.Context, c *ws.Client, msg ws.Message) {
+		c.Send(ws.Message{Type: "echoed", ID: msg.ID, Data: msg.Data})
+	})
+	conn := dial(t, url)
+	recv(t, conn)
+
+	send(t, conn, ws.Message{Type: "echo", ID: "9", Data: json.RawMessage(`[1,2]`)})
+	assert.Equal(t, ws.Message{Type: "echoed", ID: "9", Data: json.RawMessage(`[1,2]`)}, recv(t, conn))
+}
+
+func TestHub_ServerSideSubscribeAndDisconnect(t *testing.T) {
+	hub, url := newHub(t, ws.Options{})
+	gone := make(chan string, 1)
+	hub.OnDisconnect(func(c *ws.Client) { gone <- c.ID() })
+	hub.HandleMessage("join", func(ctx context.Context, c *ws.Client, msg ws.Message) {
+		if err := c.Subscribe(ctx, msg.Topic); err != nil {
+			c.Send(ws.Message{Type: ws.TypeError, ID: msg.ID, Error: err.Error()})
+			return
+		}
+		c.Send(ws.Message{Type: "joined", ID: msg.ID})
+	})
+	conn := dial(t, url+"?user=42")
+	recv(t, conn)
+
+	send(t, conn, ws.Message{Type: "join", Topic: "user:7", ID: "1"})
+	assert.Equal(t, ws.ErrForbidden.Error(), recv(t, conn).Error, "the topic's Authorizer still applies")
+	send(t, conn, ws.Message{Type: "join", Topic: "user:42", ID: "2"})
+	assert.Equal(t, "joined", recv(t, conn).Type)
+	assert.Equal(t, 1, hub.Stats().Subscriptions)
+
+	conn.Close(websocket.StatusNormalClosure, "")
+	select {
+	case id := <-gone:
+		assert.Equal(t, "c1", id)
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnDisconnect callback didn't run")
+	}
+	assert.Equal(t, 0, hub.Stats().Subscriptions)
+}
+
+func TestHub_DisconnectsSlowClients(t *testing.T) {
+	hub, url := newHub(t, ws.Options{SendBuffer: 1})
+	conn := dial(t, url+"?user=42")
+	recv(t, conn)
+	send(t, conn, ws.Message{Type: ws.TypeSubscribe, Topic: "user:42"})
+	recv(t, conn)
+
+	// The client stops reading. Once the socket buffers and the one-message
+	// send buffer are full, the next publish drops the client rather than
+	// waiting for it.
+	big := strings.Repeat("x", 256<<10)
+	require.Eventually(t, func() bool {
+		hub.Publish("user:42", big)
+		return hub.Stats().Connections == 0
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestHub_Shutdown(t *testing.T) {
+	hub, url := newHub(t, ws.Options{})
+	conn := dial(t, url)
+	recv(t, conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go func() {
+		// The client answers the close handshake by reading.
+		conn.Read(ctx)
+	}()
+	require.NoError(t, hub.Shutdown(ctx))
+	assert.Equal(t, 0, hub.Stats().Connections)
+
+	_, _, err := websocket.Dial(ctx, url, nil)
+	assert.Error(t, err, "new connections are refused after shutdown")
+}
+
+func TestHub_RejectsCrossOriginUpgrade(t *testing.T) {
+	_, url := newHub(t, ws.Options{})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, resp, err := websocket.Dial(ctx, url, &websocket.DialOptions{
+		HTTPHeader: http.Header{"Origin": {"https://evil.example"}},
+	})
+	require.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
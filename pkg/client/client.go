@@ -0,0 +1,306 @@
+// Package client is a Go client for the PyPlayground HTTP API.
+//
+// Usage:
+//
+//	c, err := client.New("https://play.example.com", client.WithToken(token))
+//	if err != nil {
+//		return err
+//	}
+//	snippet, err := c.CreateSnippet(ctx, client.SnippetInput{Name: "hello", Code: "print('hi')"})
+//	result, err := c.Execute(ctx, snippet.Code)
+//	fmt.Print(result.Stdout)
+//
+// AUTHENTICATION:
+// Anonymous use works for everything the API allows anonymously. To act as a
+// user, get a token from POST /api/v1/me/token while signed in (or call
+// Client.IssueToken from an already authenticated client) and pass it with
+// WithToken. It's sent as "Authorization: Bearer <token>". Tokens expire
+// after an hour.
+//
+// RETRIES:
+// Requests that fail in a way worth retrying — a network error, 429 Too Many
+// Requests, 502, 503 or 504 — are retried with exponential backoff and
+// jitter, honouring the server's Retry-After header. Creating a snippet and
+// running code aren't naturally idempotent, so the client sends an
+// Idempotency-Key with them: a retry after a lost response returns the
+// original result instead of doing the work twice.
+//
+// ERRORS:
+// Error responses are returned as *APIError, which carries the status code,
+// the machine-readable error type ("not_found", "validation_error", …) and
+// the request ID to quote when reporting a problem.
+package client
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults for New.
+const (
+	DefaultTimeout    = 60 * time.Second
+	DefaultMaxRetries = 3
+	DefaultRetryWait  = 250 * time.Millisecond
+	maxRetryWait      = 10 * time.Second
+)
+
+// Client calls the PyPlayground API. It's safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	token      string
+	userAgent  string
+	maxRetries int
+	retryWait  time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithToken authenticates requests with an API token.
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithHTTPClient replaces the default HTTP client (60s timeout).
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithUserAgent sets the User-Agent header, so server logs can tell your
+// program apart.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// WithRetries sets how many times a failed request is retried and the wait
+// before the first retry (it doubles after each attempt). WithRetries(0, 0)
+// disables retrying.
+func WithRetries(max int, wait time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = max
+		c.retryWait = wait
+	}
+}
+
+// New creates a client for the server at baseURL, e.g.
+// "https://play.example.com". Requests go to the /api/v1 routes under it.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("client: base URL must be http or https, got %q", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+		userAgent:  "pyplayground-go-client",
+		maxRetries: DefaultMaxRetries,
+		retryWait:  DefaultRetryWait,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// APIError is an error response from the API.
+type APIError struct {
+	StatusCode int
+	Type       string // e.g. "not_found", "validation_error"; may be empty
+	Message    string
+	RequestID  string
+	Fields     []FieldError // invalid fields, for validation errors
+}
+
+// FieldError is one invalid field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if e.RequestID != "" {
+		return fmt.Sprintf("pyplayground: %s (status %d, request %s)", msg, e.StatusCode, e.RequestID)
+	}
+	return fmt.Sprintf("pyplayground: %s (status %d)", msg, e.StatusCode)
+}
+
+// IsNotFound reports whether err is a 404 from the API.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// request describes one API call.
+type request struct {
+	method     string
+	path       string // relative to /api/v1
+	query      url.Values
+	body       any
+	idempotent bool // safe to retry; POSTs get an Idempotency-Key to make them so
+}
+
+// do sends req, retrying transient failures, and decodes the JSON response
+// into out (if non-nil).
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return fmt.Errorf("client: encoding request: %w", err)
+		}
+	}
+
+	u := c.baseURL.JoinPath("api", "v1", req.path)
+	u.RawQuery = req.query.Encode()
+
+	header := http.Header{}
+	header.Set("Accept", "application/json")
+	header.Set("User-Agent", c.userAgent)
+	if body != nil {
+		header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		header.Set("Authorization", "Bearer "+c.token)
+	}
+	retryable := req.idempotent || req.method == http.MethodGet || req.method == http.MethodPut || req.method == http.MethodDelete
+	if req.idempotent && req.method == http.MethodPost {
+		header.Set("Idempotency-Key", newIdempotencyKey())
+	}
+
+	for attempt := 0; ; attempt++ {
+		httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("client: %w", err)
+		}
+		httpReq.Header = header.Clone()
+
+		resp, err := c.httpClient.Do(httpReq)
+		if err != nil {
+			if ctx.Err() != nil || !retryable || attempt >= c.maxRetries {
+				return fmt.Errorf("client: %s %s: %w", req.method, u.Path, err)
+			}
+			if err := c.sleep(ctx, c.backoff(attempt, 0)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if shouldRetry(resp.StatusCode) && retryable && attempt < c.maxRetries {
+			wait := c.backoff(attempt, retryAfter(resp))
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16)) // let the connection be reused
+			resp.Body.Close()
+			if err := c.sleep(ctx, wait); err != nil {
+				return err
+			}
+			continue
+		}
+
+		return decodeResponse(resp, out)
+	}
+}
+
+// decodeResponse turns a response into out or an *APIError.
+func decodeResponse(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		apiErr := &APIError{StatusCode: resp.StatusCode, RequestID: resp.Header.Get("X-Request-ID")}
+		var body struct {
+			Error     string       `json:"error"`
+			Message   string       `json:"message"`
+			RequestID string       `json:"requestId"`
+			Errors    []FieldError `json:"errors"`
+		}
+		if json.Unmarshal(data, &body) == nil {
+			apiErr.Type, apiErr.Message, apiErr.Fields = body.Error, body.Message, body.Errors
+			if apiErr.Message == "" {
+				apiErr.Message = body.Error // some errors only carry "error"
+			}
+			if body.RequestID != "" {
+				apiErr.RequestID = body.RequestID
+			}
+		} else {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return apiErr
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("client: decoding response: %w", err)
+	}
+	return nil
+}
+
+// shouldRetry reports whether a status means "try again later".
+func shouldRetry(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter reads a Retry-After header given in seconds.
+func retryAfter(resp *http.Response) time.Duration {
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// backoff is the wait before retry number attempt+1: exponential with full
+// jitter, so many clients failing at once don't retry in lockstep. The
+// server's Retry-After, when longer, wins.
+func (c *Client) backoff(attempt int, serverWait time.Duration) time.Duration {
+	ceiling := min(c.retryWait<<attempt, maxRetryWait)
+	wait := time.Duration(0)
+	if ceiling > 0 {
+		wait = ceiling/2 + rand.N(ceiling/2+1)
+	}
+	return max(wait, serverWait)
+}
+
+func (c *Client) sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	if _, err := crand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,170 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sakif/coding-playground/pkg/client"
+)
+
+// newClient points a client at handler, with fast retries.
+func newClient(t *testing.T, handler http.HandlerFunc, opts ...client.Option) *client.Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	c, err := client.New(srv.URL, append([]client.Option{client.WithRetries(3, time.Millisecond)}, opts...)...)
+	require.NoError(t, err)
+	return c
+}
+
+func TestCreateSnippet(t *testing.T) {
+	var calls atomic.Int32
+	var keys []string
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/snippets", r.URL.Path)
+		assert.Equal(t, "Bearer tok", r.Header.Get("Authorization"))
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+
+		// The first attempt "fails"; the retry succeeds.
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var in client.SnippetInput
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(client.Snippet{ID: "abc", Name: in.Name, Code: in.Code})
+	}, client.WithToken("tok"))
+
+	s, err := c.CreateSnippet(context.Background(), client.SnippetInput{Name: "hello", Code: "print(1)"})
+	require.NoError(t, err)
+	assert.Equal(t, "abc", s.ID)
+	assert.Equal(t, "hello", s.Name)
+
+	require.Len(t, keys, 2)
+	assert.NotEmpty(t, keys[0])
+	assert.Equal(t, keys[0], keys[1], "a retry reuses the Idempotency-Key")
+}
+
+func TestErrors(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/snippets/missing":
+			w.Header().Set("X-Request-ID", "req-1")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"not_found","message":"snippet not found with id missing"}`))
+		case "/api/v1/snippets":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"validation_error","message":"name is required","requestId":"req-2","errors":[{"field":"name","message":"name is required"}]}`))
+		default:
+			http.Error(w, "code cannot be empty", http.StatusBadRequest)
+		}
+	})
+	ctx := context.Background()
+
+	_, err := c.GetSnippet(ctx, "missing")
+	assert.True(t, client.IsNotFound(err))
+	assert.EqualError(t, err, "pyplayground: snippet not found with id missing (status 404, request req-1)")
+
+	_, err = c.CreateSnippet(ctx, client.SnippetInput{})
+	var apiErr *client.APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "validation_error", apiErr.Type)
+	assert.Equal(t, "req-2", apiErr.RequestID)
+	assert.Equal(t, []client.FieldError{{Field: "name", Message: "name is required"}}, apiErr.Fields)
+
+	_, err = c.Execute(ctx, "")
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "code cannot be empty", apiErr.Message, "plain-text bodies become the message")
+}
+
+func TestRetries(t *testing.T) {
+	t.Run("gives up after the limit", func(t *testing.T) {
+		var calls atomic.Int32
+		c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusBadGateway)
+		})
+		_, err := c.GetSnippet(context.Background(), "x")
+		var apiErr *client.APIError
+		require.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+		assert.Equal(t, int32(4), calls.Load(), "one attempt plus three retries")
+	})
+
+	t.Run("POSTs without an Idempotency-Key aren't retried", func(t *testing.T) {
+		var calls atomic.Int32
+		c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			assert.Empty(t, r.Header.Get("Idempotency-Key"))
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+		_, err := c.IssueToken(context.Background())
+		require.Error(t, err)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("client errors aren't retried", func(t *testing.T) {
+		var calls atomic.Int32
+		c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusForbidden)
+		})
+		require.Error(t, c.DeleteSnippet(context.Background(), "x"))
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("Retry-After is honoured until the context ends", func(t *testing.T) {
+		c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := c.ListSnippets(ctx, client.ListOptions{})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+}
+
+func TestExecute(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/execute", r.URL.Path)
+		assert.NotEmpty(t, r.Header.Get("Idempotency-Key"))
+		w.Write([]byte(`{"stdout":"hi\n","stderr":"","exitCode":0,"duration":1500000}`))
+	})
+
+	res, err := c.Execute(context.Background(), "print('hi')")
+	require.NoError(t, err)
+	assert.Equal(t, "hi\n", res.Stdout)
+	assert.Equal(t, 1500*time.Microsecond, res.Duration)
+}
+
+func TestListSnippets(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "limit=2&offset=4", r.URL.RawQuery)
+		w.Write([]byte(`{"items":[{"id":"a"},{"id":"b"}],"total":7,"limit":2,"offset":4,"hasMore":true}`))
+	})
+
+	list, err := c.ListSnippets(context.Background(), client.ListOptions{Limit: 2, Offset: 4})
+	require.NoError(t, err)
+	assert.Len(t, list.Items, 2)
+	assert.True(t, list.HasMore)
+}
+
+func TestNew_RejectsBadBaseURL(t *testing.T) {
+	_, err := client.New("ftp://example.com")
+	assert.Error(t, err)
+}
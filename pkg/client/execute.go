@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// ExecutionResult is the outcome of running code. A non-zero ExitCode (an
+// exception, sys.exit(1), a timeout) is a normal result, not an error.
+type ExecutionResult struct {
+	Stdout   string        `json:"stdout"`
+	Stderr   string        `json:"stderr"`
+	ExitCode int           `json:"exitCode"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Execute runs Python code in the server's sandbox and waits for it to finish.
+func (c *Client) Execute(ctx context.Context, code string) (*ExecutionResult, error) {
+	var res ExecutionResult
+	body := map[string]string{"code": code}
+	if err := c.do(ctx, request{method: http.MethodPost, path: "execute", body: body, idempotent: true}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// User is a signed-in user's profile.
+type User struct {
+	ID        string `json:"id"`
+	Login     string `json:"login"`
+	Email     string `json:"email"`
+	AvatarURL string `json:"avatarUrl"`
+	Role      string `json:"role"`
+}
+
+// Me returns the user the client's token belongs to.
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	var u User
+	if err := c.do(ctx, request{method: http.MethodGet, path: "me"}, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// Token is an API token, as returned by IssueToken.
+type Token struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// IssueToken gets a fresh token for the client's user. Tokens expire, so a
+// long-running program can call this before the current one runs out and
+// switch to the new one with a new Client.
+func (c *Client) IssueToken(ctx context.Context) (*Token, error) {
+	var t Token
+	if err := c.do(ctx, request{method: http.MethodPost, path: "me/token"}, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
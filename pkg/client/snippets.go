@@ -0,0 +1,107 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Snippet is a saved code snippet.
+type Snippet struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Code        string     `json:"code"`
+	Description string     `json:"description"`
+	UserID      string     `json:"userId,omitempty"` // "" for anonymous snippets
+	Public      bool       `json:"public"`
+	PublishedAt *time.Time `json:"publishedAt,omitempty"`
+	Version     int        `json:"version"` // goes up each time the code changes
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// SnippetInput is the content of a snippet to create or update.
+type SnippetInput struct {
+	Name        string `json:"name"`
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+// SnippetList is one page of snippets.
+type SnippetList struct {
+	Items   []Snippet `json:"items"`
+	Total   int       `json:"total"`
+	Limit   int       `json:"limit"`
+	Offset  int       `json:"offset"`
+	HasMore bool      `json:"hasMore"`
+}
+
+// ListOptions selects a page of snippets. Zero values use the server's
+// defaults (20 per page, from the start).
+type ListOptions struct {
+	Limit  int
+	Offset int
+}
+
+// CreateSnippet saves a new snippet, owned by the token's user if there is one.
+func (c *Client) CreateSnippet(ctx context.Context, in SnippetInput) (*Snippet, error) {
+	var s Snippet
+	if err := c.do(ctx, request{method: http.MethodPost, path: "snippets", body: in, idempotent: true}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetSnippet fetches a snippet by ID. A missing snippet is an *APIError for
+// which IsNotFound is true.
+func (c *Client) GetSnippet(ctx context.Context, id string) (*Snippet, error) {
+	var s Snippet
+	if err := c.do(ctx, request{method: http.MethodGet, path: "snippets/" + url.PathEscape(id)}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ListSnippets fetches a page of snippets, newest first.
+func (c *Client) ListSnippets(ctx context.Context, opts ListOptions) (*SnippetList, error) {
+	q := url.Values{}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		q.Set("offset", strconv.Itoa(opts.Offset))
+	}
+
+	var list SnippetList
+	if err := c.do(ctx, request{method: http.MethodGet, path: "snippets", query: q}, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// UpdateSnippet replaces a snippet's name, code and description.
+func (c *Client) UpdateSnippet(ctx context.Context, id string, in SnippetInput) (*Snippet, error) {
+	var s Snippet
+	if err := c.do(ctx, request{method: http.MethodPut, path: "snippets/" + url.PathEscape(id), body: in}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// SetSnippetPublic publishes a snippet to the public feeds, or unpublishes
+// it. Only the owner may; it needs a token.
+func (c *Client) SetSnippetPublic(ctx context.Context, id string, public bool) (*Snippet, error) {
+	var s Snippet
+	body := map[string]bool{"public": public}
+	if err := c.do(ctx, request{method: http.MethodPut, path: "snippets/" + url.PathEscape(id) + "/visibility", body: body}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// DeleteSnippet deletes a snippet.
+func (c *Client) DeleteSnippet(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "snippets/" + url.PathEscape(id)}, nil)
+}
@@ -91,6 +91,9 @@ document.addEventListener('DOMContentLoaded', async function () {
     // 6. Load saved snippets into the dropdown (async — fetches from server)
     await refreshSnippetList();
 
+    // 6b. Open a snippet linked by ?snippet=<id> (e.g. from the Atom feed)
+    await openLinkedSnippet();
+
     // 7. Restore theme preference
     restoreTheme();
 
@@ -419,6 +422,20 @@ async function loadSelectedSnippet() {
     }
 }
 
+// URLSearchParams parses the query string: for "/?snippet=abc",
+// params.get('snippet') returns "abc" (or null when it's missing).
+async function openLinkedSnippet() {
+    const id = new URLSearchParams(window.location.search).get('snippet');
+    if (!id) return;
+
+    const snippet = await loadSnippet(id);
+    if (snippet) {
+        setEditorCode(snippet.code);
+        elements.snippetSelect.value = id;
+        showToast(`Loaded "${snippet.name}"`, 'success');
+    }
+}
+
 async function deleteSelectedSnippet() {
     const id = elements.snippetSelect.value;
     if (!id) {
@@ -5,7 +5,7 @@
 // save-prompt flow that encourages users to sign in before saving.
 //
 // ON PAGE LOAD:
-//   1. checkAuthStatus() calls GET /api/me
+//   1. checkAuthStatus() calls GET /api/v1/me
 //   2. If 200 → user is logged in, show avatar + username
 //   3. If 401/404/error → not logged in, show "Sign in" button
 //
@@ -21,16 +21,16 @@
 
 let currentUser = null;
 // Always treat auth as available — show the sign-in button by default.
-// Only switch to "logged in" when /api/me returns 200.
+// Only switch to "logged in" when /api/v1/me returns 200.
 let authAvailable = true;
 
 /**
- * Check the current authentication status by calling /api/me.
+ * Check the current authentication status by calling /api/v1/me.
  * Updates the navbar UI accordingly.
  */
 async function checkAuthStatus() {
     try {
-        const response = await fetch('/api/me');
+        const response = await fetch('/api/v1/me');
 
         if (response.ok) {
             // User is authenticated — show avatar + username
@@ -28,29 +28,33 @@
 //   - By default, fetch() uses GET. Pass { method: 'POST', ... } for others.
 // ===================================================================
 
-const API_BASE = '/api';
+const API_BASE = '/api/v1';
 
 /**
  * Get all saved snippets from the server.
  *
  * fetch() FLOW:
- * 1. Browser sends GET /api/snippets to the Go server
+ * 1. Browser sends GET /api/v1/snippets to the Go server
  * 2. Go handler calls service.List() → repository.List() → SQLite SELECT
- * 3. Server responds with JSON array of snippets
- * 4. We parse the JSON and return the array
+ * 3. Server responds with a page: {items: [...], total, limit, offset, hasMore}
+ * 4. We parse the JSON and return the items array
+ *
+ * The list only needs names for the dropdown, so ?fields=id,name skips the
+ * code bodies. getSnippet() fetches the full snippet when one is loaded.
  *
  * @returns {Promise<Array>} Array of snippet objects
  */
 async function getSnippets() {
     try {
-        const response = await fetch(`${API_BASE}/snippets`);
+        const response = await fetch(`${API_BASE}/snippets?fields=id,name`);
 
         if (!response.ok) {
             const error = await response.json();
             throw new Error(error.message || 'Failed to load snippets');
         }
 
-        return await response.json();
+        const page = await response.json();
+        return page.items;
     } catch (err) {
         console.error('Failed to fetch snippets:', err);
         return [];
@@ -104,7 +108,7 @@ async function saveSnippet(name, code) {
  * Load a snippet by its ID from the server.
  *
  * URL PARAMETERS:
- * We append the ID to the URL: /api/snippets/{id}
+ * We append the ID to the URL: /api/v1/snippets/{id}
  * The Go router extracts {id} using r.PathValue("id")
  *
  * @param {string} id - The snippet ID